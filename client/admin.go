@@ -20,6 +20,7 @@ import (
 	"time"
 
 	"github.com/golang/glog"
+	"github.com/golang/protobuf/ptypes/any"
 	"github.com/google/trillian"
 	"github.com/google/trillian/client/backoff"
 	"google.golang.org/grpc/codes"
@@ -33,12 +34,27 @@ import (
 // Internally, the function will continue to retry failed requests until either
 // the tree is created (and if necessary, initialised) successfully, or ctx is
 // cancelled.
+// If req has an external_id, the whole function may be retried: a tree created
+// by an earlier attempt is returned, and only initialised if it isn't yet.
 func CreateAndInitTree(
 	ctx context.Context,
 	req *trillian.CreateTreeRequest,
 	adminClient trillian.TrillianAdminClient,
 	mapClient trillian.TrillianMapClient,
 	logClient trillian.TrillianLogClient) (*trillian.Tree, error) {
+	return CreateAndInitTreeWithLogMetadata(ctx, req, adminClient, mapClient, logClient, nil)
+}
+
+// CreateAndInitTreeWithLogMetadata is like CreateAndInitTree, but if req
+// describes a LOG tree, logMetadata is signed in its initial root, see
+// InitLogWithMetadata.
+func CreateAndInitTreeWithLogMetadata(
+	ctx context.Context,
+	req *trillian.CreateTreeRequest,
+	adminClient trillian.TrillianAdminClient,
+	mapClient trillian.TrillianMapClient,
+	logClient trillian.TrillianLogClient,
+	logMetadata *any.Any) (*trillian.Tree, error) {
 
 	b := &backoff.Backoff{
 		Min:    100 * time.Millisecond,
@@ -65,13 +81,21 @@ func CreateAndInitTree(
 		return nil, err
 	}
 
+	if req.ExternalId != "" {
+		if initialised, err := treeInitialised(ctx, tree, mapClient, logClient); err != nil {
+			return nil, err
+		} else if initialised {
+			return tree, nil
+		}
+	}
+
 	switch tree.TreeType {
 	case trillian.TreeType_MAP:
 		if err := InitMap(ctx, tree, mapClient); err != nil {
 			return nil, err
 		}
 	case trillian.TreeType_LOG:
-		if err := InitLog(ctx, tree, logClient); err != nil {
+		if err := InitLogWithMetadata(ctx, tree, logClient, logMetadata); err != nil {
 			return nil, err
 		}
 	case trillian.TreeType_PREORDERED_LOG:
@@ -83,6 +107,27 @@ func CreateAndInitTree(
 	return tree, nil
 }
 
+// treeInitialised returns whether tree, which may have been created by an
+// earlier request with the same external_id, is already initialised.
+func treeInitialised(ctx context.Context, tree *trillian.Tree, mapClient trillian.TrillianMapClient, logClient trillian.TrillianLogClient) (bool, error) {
+	var err error
+	switch tree.TreeType {
+	case trillian.TreeType_MAP:
+		_, err = mapClient.GetSignedMapRootByRevision(ctx, &trillian.GetSignedMapRootByRevisionRequest{MapId: tree.TreeId, Revision: 0})
+	case trillian.TreeType_LOG:
+		_, err = logClient.GetLatestSignedLogRoot(ctx, &trillian.GetLatestSignedLogRootRequest{LogId: tree.TreeId})
+	default:
+		return false, nil
+	}
+	switch status.Code(err) {
+	case codes.OK:
+		return true, nil
+	case codes.FailedPrecondition, codes.NotFound:
+		return false, nil
+	}
+	return false, err
+}
+
 // InitMap initialises a freshly created Map tree.
 func InitMap(ctx context.Context, tree *trillian.Tree, mapClient trillian.TrillianMapClient) error {
 	if tree.TreeType != trillian.TreeType_MAP {
@@ -132,6 +177,13 @@ func InitMap(ctx context.Context, tree *trillian.Tree, mapClient trillian.Trilli
 
 // InitLog initialises a freshly created Log tree.
 func InitLog(ctx context.Context, tree *trillian.Tree, logClient trillian.TrillianLogClient) error {
+	return InitLogWithMetadata(ctx, tree, logClient, nil)
+}
+
+// InitLogWithMetadata initialises a freshly created Log tree, signing metadata
+// in its initial root if it's set, e.g. a trillian.LogRootMetadata which
+// identifies the log.
+func InitLogWithMetadata(ctx context.Context, tree *trillian.Tree, logClient trillian.TrillianLogClient, metadata *any.Any) error {
 	if tree.TreeType != trillian.TreeType_LOG {
 		return fmt.Errorf("InitLog called with tree of type %v", tree.TreeType)
 	}
@@ -145,7 +197,7 @@ func InitLog(ctx context.Context, tree *trillian.Tree, logClient trillian.Trilli
 
 	err := b.Retry(ctx, func() error {
 		glog.Infof("Initialising Log %x...", tree.TreeId)
-		req := &trillian.InitLogRequest{LogId: tree.TreeId}
+		req := &trillian.InitLogRequest{LogId: tree.TreeId, Metadata: metadata}
 		resp, err := logClient.InitLog(ctx, req)
 		if err != nil {
 			switch s, ok := status.FromError(err); {
@@ -0,0 +1,104 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"fmt"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/crypto/keys/der"
+	"github.com/google/trillian/crypto/sigpb"
+	"github.com/google/trillian/merkle/hashers"
+
+	// Register the hashers of all the hash strategies a tree may have.
+	_ "github.com/google/trillian/merkle/coniks"
+	_ "github.com/google/trillian/merkle/maphasher"
+	_ "github.com/google/trillian/merkle/objhasher"
+	_ "github.com/google/trillian/merkle/rfc6962"
+)
+
+// GetTree fetches the tree with treeID through admin, and checks that its
+// parameters are supported by the client: that it has a hasher for its hash
+// strategy, and that its public key matches its signature algorithm.
+func GetTree(ctx context.Context, admin trillian.TrillianAdminClient, treeID int64) (*trillian.Tree, error) {
+	tree, err := admin.GetTree(ctx, &trillian.GetTreeRequest{TreeId: treeID})
+	if err != nil {
+		return nil, fmt.Errorf("client: GetTree(%d): %v", treeID, err)
+	}
+	if err := checkTree(tree); err != nil {
+		return nil, fmt.Errorf("client: tree %d: %v", treeID, err)
+	}
+	return tree, nil
+}
+
+// NewFromTreeID returns a LogClient for the log with treeID, with a verifier
+// using the hasher and public key of the tree fetched through admin.
+func NewFromTreeID(ctx context.Context, admin trillian.TrillianAdminClient, client trillian.TrillianLogClient, treeID int64) (*LogClient, error) {
+	tree, err := GetTree(ctx, admin, treeID)
+	if err != nil {
+		return nil, err
+	}
+	return NewFromTree(client, tree)
+}
+
+// NewMapVerifierFromTreeID returns a MapVerifier for the map with treeID,
+// using the hasher and public key of the tree fetched through admin.
+func NewMapVerifierFromTreeID(ctx context.Context, admin trillian.TrillianAdminClient, treeID int64) (*MapVerifier, error) {
+	tree, err := GetTree(ctx, admin, treeID)
+	if err != nil {
+		return nil, err
+	}
+	return NewMapVerifierFromTree(tree)
+}
+
+// checkTree returns an error if the client can't verify the output of tree.
+func checkTree(tree *trillian.Tree) error {
+	switch tree.TreeType {
+	case trillian.TreeType_LOG, trillian.TreeType_PREORDERED_LOG:
+		if _, err := hashers.NewLogHasher(tree.HashStrategy); err != nil {
+			return fmt.Errorf("unsupported hash strategy %v for a %v", tree.HashStrategy, tree.TreeType)
+		}
+	case trillian.TreeType_MAP:
+		if _, err := hashers.NewMapHasher(tree.HashStrategy); err != nil {
+			return fmt.Errorf("unsupported hash strategy %v for a %v", tree.HashStrategy, tree.TreeType)
+		}
+	default:
+		return fmt.Errorf("unsupported tree type %v", tree.TreeType)
+	}
+
+	if tree.HashAlgorithm != sigpb.DigitallySigned_SHA256 {
+		return fmt.Errorf("unsupported hash algorithm %v", tree.HashAlgorithm)
+	}
+	pubKey, err := der.UnmarshalPublicKey(tree.GetPublicKey().GetDer())
+	if err != nil {
+		return fmt.Errorf("failed parsing public key: %v", err)
+	}
+	var keyAlgorithm sigpb.DigitallySigned_SignatureAlgorithm
+	switch pubKey.(type) {
+	case *ecdsa.PublicKey:
+		keyAlgorithm = sigpb.DigitallySigned_ECDSA
+	case *rsa.PublicKey:
+		keyAlgorithm = sigpb.DigitallySigned_RSA
+	default:
+		return fmt.Errorf("unsupported public key type %T", pubKey)
+	}
+	if tree.SignatureAlgorithm != keyAlgorithm {
+		return fmt.Errorf("signature algorithm is %v, but the public key is for %v", tree.SignatureAlgorithm, keyAlgorithm)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian"
+	"github.com/google/trillian/crypto/sigpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	stestonly "github.com/google/trillian/storage/testonly"
+)
+
+// fakeAdmin serves a single tree.
+type fakeAdmin struct {
+	trillian.TrillianAdminClient
+	tree *trillian.Tree
+}
+
+func (f *fakeAdmin) GetTree(ctx context.Context, in *trillian.GetTreeRequest, opts ...grpc.CallOption) (*trillian.Tree, error) {
+	if in.TreeId != f.tree.TreeId {
+		return nil, status.Errorf(codes.NotFound, "tree %d not found", in.TreeId)
+	}
+	return proto.Clone(f.tree).(*trillian.Tree), nil
+}
+
+func TestNewFromTreeID(t *testing.T) {
+	ctx := context.Background()
+	for _, test := range []struct {
+		desc    string
+		tree    *trillian.Tree
+		modify  func(*trillian.Tree)
+		wantErr bool
+	}{
+		{desc: "log", tree: stestonly.LogTree},
+		{desc: "preorderedLog", tree: stestonly.PreorderedLogTree},
+		{desc: "objectHasher", tree: stestonly.LogTree, modify: func(t *trillian.Tree) { t.HashStrategy = trillian.HashStrategy_OBJECT_RFC6962_SHA256 }},
+		{desc: "map", tree: stestonly.MapTree, wantErr: true},
+		{desc: "notFound", tree: stestonly.LogTree, modify: func(t *trillian.Tree) { t.TreeId = 2 }, wantErr: true},
+		{desc: "mapHasher", tree: stestonly.LogTree, modify: func(t *trillian.Tree) { t.HashStrategy = trillian.HashStrategy_CONIKS_SHA512_256 }, wantErr: true},
+		{desc: "unknownHasher", tree: stestonly.LogTree, modify: func(t *trillian.Tree) { t.HashStrategy = trillian.HashStrategy_UNKNOWN_HASH_STRATEGY }, wantErr: true},
+		{desc: "wrongSignatureAlgorithm", tree: stestonly.LogTree, modify: func(t *trillian.Tree) { t.SignatureAlgorithm = sigpb.DigitallySigned_RSA }, wantErr: true},
+		{desc: "unknownHashAlgorithm", tree: stestonly.LogTree, modify: func(t *trillian.Tree) { t.HashAlgorithm = sigpb.DigitallySigned_NONE }, wantErr: true},
+		{desc: "noPublicKey", tree: stestonly.LogTree, modify: func(t *trillian.Tree) { t.PublicKey = nil }, wantErr: true},
+	} {
+		tree := proto.Clone(test.tree).(*trillian.Tree)
+		tree.TreeId = 1
+		if test.modify != nil {
+			test.modify(tree)
+		}
+		c, err := NewFromTreeID(ctx, &fakeAdmin{tree: tree}, nil, 1)
+		if gotErr := err != nil; gotErr != test.wantErr {
+			t.Errorf("%v: NewFromTreeID(): %v, want error: %v", test.desc, err, test.wantErr)
+			continue
+		}
+		if err == nil && c.LogID != 1 {
+			t.Errorf("%v: NewFromTreeID().LogID = %v, want 1", test.desc, c.LogID)
+		}
+	}
+}
+
+func TestNewMapVerifierFromTreeID(t *testing.T) {
+	ctx := context.Background()
+	for _, test := range []struct {
+		desc    string
+		tree    *trillian.Tree
+		modify  func(*trillian.Tree)
+		wantErr bool
+	}{
+		{desc: "map", tree: stestonly.MapTree},
+		{desc: "coniks", tree: stestonly.MapTree, modify: func(t *trillian.Tree) { t.HashStrategy = trillian.HashStrategy_CONIKS_SHA512_256 }},
+		{desc: "log", tree: stestonly.LogTree, wantErr: true},
+		{desc: "logHasher", tree: stestonly.MapTree, modify: func(t *trillian.Tree) { t.HashStrategy = trillian.HashStrategy_RFC6962_SHA256 }, wantErr: true},
+	} {
+		tree := proto.Clone(test.tree).(*trillian.Tree)
+		tree.TreeId = 1
+		if test.modify != nil {
+			test.modify(tree)
+		}
+		v, err := NewMapVerifierFromTreeID(ctx, &fakeAdmin{tree: tree}, 1)
+		if gotErr := err != nil; gotErr != test.wantErr {
+			t.Errorf("%v: NewMapVerifierFromTreeID(): %v, want error: %v", test.desc, err, test.wantErr)
+			continue
+		}
+		if err == nil && v.Hasher == nil {
+			t.Errorf("%v: NewMapVerifierFromTreeID().Hasher = nil", test.desc)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package envelope verifies the signed envelopes which Trillian log servers
+// configured with a proof signing key return alongside proof responses.
+//
+// An envelope binds a response to the request it answers with a signature by
+// a serving key, distinct from the keys of the trees the server hosts. It
+// lets clients behind untrusted proxies detect responses which have been
+// tampered with before they go on to verify the proofs themselves. It's not
+// a substitute for that verification: the serving key vouches only that the
+// server sent the response, not that the response is correct.
+package envelope
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian"
+	"github.com/google/trillian/crypto/keys/der"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	tcrypto "github.com/google/trillian/crypto"
+)
+
+// MetadataKey is the gRPC trailer metadata key carrying the serialized
+// trillian.SignedProofEnvelope of a response.
+const MetadataKey = "x-trillian-proof-envelope-bin"
+
+// Methods are the full names of the RPCs whose responses are signed.
+var Methods = map[string]bool{
+	"/trillian.TrillianLog/GetInclusionProof":        true,
+	"/trillian.TrillianLog/GetInclusionProofByHash":  true,
+	"/trillian.TrillianLog/GetConsistencyProof":      true,
+	"/trillian.TrillianLog/GetConsistencyProofChain": true,
+	"/trillian.TrillianLog/GetEntryAndProof":         true,
+}
+
+// Digest returns the SHA-256 hash of the serialized message m, as it's
+// recorded in envelopes.
+func Digest(m proto.Message) ([]byte, error) {
+	b, err := proto.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	d := sha256.Sum256(b)
+	return d[:], nil
+}
+
+// KeyID returns the ID of the public key pub, as it's recorded in envelopes,
+// which is the SHA-256 hash of its DER encoding.
+func KeyID(pub crypto.PublicKey) ([]byte, error) {
+	b, err := der.MarshalPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	id := sha256.Sum256(b)
+	return id[:], nil
+}
+
+// Verify checks that trailer, the trailer metadata of a call to method,
+// carries an envelope signed by pub which binds resp to req. It returns the
+// envelope, whose timestamp callers may check for freshness.
+func Verify(pub crypto.PublicKey, method string, req, resp proto.Message, trailer metadata.MD) (*trillian.ProofEnvelope, error) {
+	vals := trailer[MetadataKey]
+	if len(vals) != 1 {
+		return nil, fmt.Errorf("got %d proof envelopes, want 1", len(vals))
+	}
+	var signed trillian.SignedProofEnvelope
+	if err := proto.Unmarshal([]byte(vals[0]), &signed); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal signed proof envelope: %v", err)
+	}
+	if err := tcrypto.Verify(pub, signed.Envelope, signed.Signature); err != nil {
+		return nil, fmt.Errorf("invalid proof envelope signature: %v", err)
+	}
+	var env trillian.ProofEnvelope
+	if err := proto.Unmarshal(signed.Envelope, &env); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal proof envelope: %v", err)
+	}
+
+	keyID, err := KeyID(pub)
+	if err != nil {
+		return nil, err
+	}
+	reqDigest, err := Digest(req)
+	if err != nil {
+		return nil, err
+	}
+	respDigest, err := Digest(resp)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case env.Method != method:
+		return nil, fmt.Errorf("proof envelope is for method %q, want %q", env.Method, method)
+	case !bytes.Equal(env.KeyId, keyID):
+		return nil, fmt.Errorf("proof envelope is for key ID %x, want %x", env.KeyId, keyID)
+	case !bytes.Equal(env.RequestDigest, reqDigest):
+		return nil, fmt.Errorf("proof envelope is for a different request")
+	case !bytes.Equal(env.ResponseDigest, respDigest):
+		return nil, fmt.Errorf("proof envelope is for a different response")
+	}
+	return &env, nil
+}
+
+// UnaryClientInterceptor returns a grpc.UnaryClientInterceptor which verifies
+// the envelopes of the responses to Methods with pub, failing calls whose
+// responses don't have valid ones with codes.DataLoss.
+func UnaryClientInterceptor(pub crypto.PublicKey) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if !Methods[method] {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		var trailer metadata.MD
+		if err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Trailer(&trailer))...); err != nil {
+			return err
+		}
+		reqMsg, ok := req.(proto.Message)
+		if !ok {
+			return status.Errorf(codes.Internal, "%s request is a %T, not a proto.Message", method, req)
+		}
+		replyMsg, ok := reply.(proto.Message)
+		if !ok {
+			return status.Errorf(codes.Internal, "%s response is a %T, not a proto.Message", method, reply)
+		}
+		if _, err := Verify(pub, method, reqMsg, replyMsg, trailer); err != nil {
+			return status.Errorf(codes.DataLoss, "%s: %v", method, err)
+		}
+		return nil
+	}
+}
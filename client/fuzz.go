@@ -0,0 +1,55 @@
+// +build gofuzz
+
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian"
+	"github.com/google/trillian/merkle/rfc6962"
+)
+
+var fuzzVerifier LogVerifier
+
+func init() {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	fuzzVerifier = NewLogVerifier(rfc6962.DefaultHasher, key.Public())
+}
+
+// FuzzSignedLogRoot is a go-fuzz entry point for the unmarshaling and
+// verification of the SignedLogRoots which clients get from untrusted logs.
+// data is a serialized SignedLogRoot, which is verified as the successor of
+// a trusted root of a non-empty log.
+//
+// Build with go-fuzz-build -tags gofuzz -func FuzzSignedLogRoot.
+func FuzzSignedLogRoot(data []byte) int {
+	var root trillian.SignedLogRoot
+	if err := proto.Unmarshal(data, &root); err != nil {
+		return 0
+	}
+	trusted := &trillian.SignedLogRoot{TreeSize: 1, RootHash: rfc6962.DefaultHasher.EmptyRoot()}
+	if err := fuzzVerifier.VerifyRoot(trusted, &root, nil); err != nil {
+		return 0
+	}
+	return 1
+}
@@ -20,10 +20,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"time"
 
+	"github.com/golang/glog"
 	"github.com/google/trillian"
 	"github.com/google/trillian/client/backoff"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -34,6 +37,7 @@ type LogClient struct {
 	LogID  int64
 	client trillian.TrillianLogClient
 	root   trillian.SignedLogRoot
+	cache  *ProofCache
 }
 
 // New returns a new LogClient.
@@ -55,6 +59,27 @@ func NewFromTree(client trillian.TrillianLogClient, config *trillian.Tree) (*Log
 	return New(config.GetTreeId(), client, verifier), nil
 }
 
+// SetProofCache makes the client cache the roots and proofs it verifies in
+// cache, so they can be verified again offline. The latest root of the log in
+// cache, if any, becomes the client's trusted root once its signature is
+// verified.
+func (c *LogClient) SetProofCache(cache *ProofCache) error {
+	c.cache = cache
+	root, err := cache.LatestRoot(c.LogID)
+	if err == ErrNotCached {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if err := c.VerifyRoot(&trillian.SignedLogRoot{}, root, nil); err != nil {
+		return fmt.Errorf("cached root of size %d: %v", root.TreeSize, err)
+	}
+	if root.TreeSize > c.root.TreeSize {
+		c.root = *root
+	}
+	return nil
+}
+
 // AddLeaf adds leaf to the append only log.
 // Blocks until it gets a verifiable response.
 func (c *LogClient) AddLeaf(ctx context.Context, data []byte) error {
@@ -82,28 +107,80 @@ func (c *LogClient) GetByIndex(ctx context.Context, index int64) (*trillian.LogL
 	return resp.Leaves[0], nil
 }
 
-// ListByIndex returns the requested leaves by index.
+// ListByIndex returns the requested leaves by index. Ranges too large for a
+// single response of the server are streamed instead.
 func (c *LogClient) ListByIndex(ctx context.Context, start, count int64) ([]*trillian.LogLeaf, error) {
-	resp, err := c.client.GetLeavesByRange(ctx,
-		&trillian.GetLeavesByRangeRequest{
-			LogId:      c.LogID,
-			StartIndex: start,
-			Count:      count,
-		})
+	req := &trillian.GetLeavesByRangeRequest{
+		LogId:      c.LogID,
+		StartIndex: start,
+		Count:      count,
+	}
+	var leaves []*trillian.LogLeaf
+	resp, err := c.client.GetLeavesByRange(ctx, req)
+	if isResponseTooLarge(err) {
+		leaves, err = c.streamLeaves(ctx, req)
+	} else if err == nil {
+		leaves = resp.Leaves
+	}
 	if err != nil {
 		return nil, err
 	}
 	// Verify that we got back the requested leaves.
-	if len(resp.Leaves) < int(count) {
-		return nil, fmt.Errorf("len(Leaves)=%d, want %d", len(resp.Leaves), count)
+	if len(leaves) < int(count) {
+		return nil, fmt.Errorf("len(Leaves)=%d, want %d", len(leaves), count)
 	}
-	for i, l := range resp.Leaves {
+	for i, l := range leaves {
 		if want := start + int64(i); l.LeafIndex != want {
 			return nil, fmt.Errorf("Leaves[%d].LeafIndex=%d, want %d", i, l.LeafIndex, want)
 		}
 	}
 
-	return resp.Leaves, nil
+	return leaves, nil
+}
+
+// responseTooLargeViolation is the type of the PreconditionFailure violation
+// in the errors of requests whose response would exceed the server's memory
+// limits, as set by server.ResponseTooLargeViolation.
+const responseTooLargeViolation = "RESPONSE_TOO_LARGE"
+
+// isResponseTooLarge returns whether err is that of a request refused because
+// its response would be too large, which may be streamed instead.
+func isResponseTooLarge(err error) bool {
+	s, ok := status.FromError(err)
+	if !ok || s.Code() != codes.ResourceExhausted {
+		return false
+	}
+	for _, d := range s.Details() {
+		if pf, ok := d.(*errdetails.PreconditionFailure); ok {
+			for _, v := range pf.GetViolations() {
+				if v.GetType() == responseTooLargeViolation {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// streamLeaves returns the leaves of the range of req, read with
+// StreamLeavesByRange.
+func (c *LogClient) streamLeaves(ctx context.Context, req *trillian.GetLeavesByRangeRequest) ([]*trillian.LogLeaf, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := c.client.StreamLeavesByRange(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	var leaves []*trillian.LogLeaf
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return leaves, nil
+		} else if err != nil {
+			return nil, err
+		}
+		leaves = append(leaves, resp.Leaves...)
+	}
 }
 
 // WaitForRootUpdate repeatedly fetches the Root until the fetched tree size >=
@@ -172,6 +249,11 @@ func (c *LogClient) getLatestRoot(ctx context.Context, trusted *trillian.SignedL
 			return nil, err
 		}
 	}
+	if c.cache != nil && consistency != nil {
+		if err := c.cache.PutConsistencyProof(c.LogID, trusted.TreeSize, resp.SignedLogRoot.TreeSize, consistency.GetProof().GetHashes()); err != nil {
+			glog.Warningf("Failed to cache consistency proof of log %d: %v", c.LogID, err)
+		}
+	}
 	return resp.SignedLogRoot, nil
 }
 
@@ -183,13 +265,46 @@ func (c *LogClient) UpdateRoot(ctx context.Context) (*trillian.SignedLogRoot, er
 	if err != nil {
 		return nil, err
 	}
-	if newTrusted.TimestampNanos > currentlyTrusted.TimestampNanos &&
-		newTrusted.TreeSize >= currentlyTrusted.TreeSize {
+	return c.trust(newTrusted), nil
+}
+
+// UpdateRootByChain is like UpdateRoot, but verifies the current SignedLogRoot
+// through a chain of consistency proofs from the currently trusted root, see
+// GetConsistencyProofChain. Clients which haven't updated their root for a
+// long time may use it to catch up.
+func (c *LogClient) UpdateRootByChain(ctx context.Context) (*trillian.SignedLogRoot, error) {
+	if c.root.TreeSize == 0 {
+		return c.UpdateRoot(ctx)
+	}
+	resp, err := c.client.GetConsistencyProofChain(ctx,
+		&trillian.GetConsistencyProofChainRequest{
+			LogId:         c.LogID,
+			FirstTreeSize: c.root.TreeSize,
+		})
+	if err != nil {
+		return nil, err
+	}
+	if err := c.VerifyRootChain(&c.root, resp.GetSignedLogRoot(), resp.Steps); err != nil {
+		return nil, err
+	}
+	return c.trust(resp.SignedLogRoot), nil
+}
+
+// trust makes newTrusted, a verified root, the currently trusted root if it's
+// newer, and returns a copy of the currently trusted root.
+func (c *LogClient) trust(newTrusted *trillian.SignedLogRoot) *trillian.SignedLogRoot {
+	if newTrusted.TimestampNanos > c.root.TimestampNanos &&
+		newTrusted.TreeSize >= c.root.TreeSize {
 		c.root = *newTrusted
+		if c.cache != nil {
+			if err := c.cache.PutRoot(newTrusted); err != nil {
+				glog.Warningf("Failed to cache root of log %d: %v", c.LogID, err)
+			}
+		}
 	}
 	// Copy the internal trusted root in order to prevent clients from modifying it.
 	ret := c.root
-	return &ret, nil
+	return &ret
 }
 
 // WaitForInclusion blocks until the requested data has been verified with an inclusion proof.
@@ -252,7 +367,17 @@ func (c *LogClient) GetAndVerifyInclusionAtIndex(ctx context.Context, data []byt
 	if err != nil {
 		return err
 	}
-	return c.VerifyInclusionAtIndex(root, data, index, resp.Proof.Hashes)
+	if err := c.VerifyInclusionAtIndex(root, data, index, resp.Proof.Hashes); err != nil {
+		return err
+	}
+	if c.cache != nil {
+		leaf, err := c.BuildLeaf(data)
+		if err != nil {
+			return err
+		}
+		c.cacheInclusionProof(leaf.MerkleLeafHash, root.TreeSize, &trillian.Proof{LeafIndex: index, Hashes: resp.Proof.Hashes})
+	}
+	return nil
 }
 
 func (c *LogClient) getAndVerifyInclusionProof(ctx context.Context, leafHash []byte, sth *trillian.SignedLogRoot) error {
@@ -273,9 +398,72 @@ func (c *LogClient) getAndVerifyInclusionProof(ctx context.Context, leafHash []b
 			return fmt.Errorf("VerifyInclusionByHash(): %v", err)
 		}
 	}
+	c.cacheInclusionProof(leafHash, sth.TreeSize, resp.Proof[0])
 	return nil
 }
 
+// cacheInclusionProof caches a verified inclusion proof, if the client has a
+// ProofCache.
+func (c *LogClient) cacheInclusionProof(leafHash []byte, treeSize int64, proof *trillian.Proof) {
+	if c.cache == nil {
+		return
+	}
+	if err := c.cache.PutInclusionProof(c.LogID, leafHash, treeSize, proof); err != nil {
+		glog.Warningf("Failed to cache inclusion proof of log %d: %v", c.LogID, err)
+	}
+}
+
+// VerifyCachedInclusion verifies offline that data is included in the log,
+// using the root with treeSize leaves and the inclusion proof in the client's
+// ProofCache. It returns ErrNotCached if either isn't cached.
+func (c *LogClient) VerifyCachedInclusion(data []byte, treeSize int64) error {
+	if c.cache == nil {
+		return errors.New("client has no proof cache")
+	}
+	leaf, err := c.BuildLeaf(data)
+	if err != nil {
+		return err
+	}
+	root, err := c.cache.Root(c.LogID, treeSize)
+	if err != nil {
+		return err
+	}
+	if err := c.VerifyRoot(&trillian.SignedLogRoot{}, root, nil); err != nil {
+		return fmt.Errorf("cached root of size %d: %v", treeSize, err)
+	}
+	proof, err := c.cache.InclusionProof(c.LogID, leaf.MerkleLeafHash, treeSize)
+	if err != nil {
+		return err
+	}
+	return c.VerifyInclusionByHash(root, leaf.MerkleLeafHash, proof)
+}
+
+// VerifyCachedConsistency verifies offline that the roots of the log with
+// first and second leaves are consistent, using the roots and consistency
+// proof in the client's ProofCache. It returns ErrNotCached if any of them
+// isn't cached.
+func (c *LogClient) VerifyCachedConsistency(first, second int64) error {
+	if c.cache == nil {
+		return errors.New("client has no proof cache")
+	}
+	firstRoot, err := c.cache.Root(c.LogID, first)
+	if err != nil {
+		return err
+	}
+	secondRoot, err := c.cache.Root(c.LogID, second)
+	if err != nil {
+		return err
+	}
+	proof, err := c.cache.ConsistencyProof(c.LogID, first, second)
+	if err != nil {
+		return err
+	}
+	if err := c.VerifyRoot(&trillian.SignedLogRoot{}, firstRoot, nil); err != nil {
+		return fmt.Errorf("cached root of size %d: %v", first, err)
+	}
+	return c.VerifyRoot(firstRoot, secondRoot, proof)
+}
+
 // QueueLeaf adds a leaf to a Trillian log without blocking.
 // AlreadyExists is considered a success case by this function.
 func (c *LogClient) QueueLeaf(ctx context.Context, data []byte) error {
@@ -298,3 +298,46 @@ func TestUpdateRoot(t *testing.T) {
 		t.Errorf("Tree size after add Leaf: %v, want > %v", got, want)
 	}
 }
+
+func TestUpdateRootByChain(t *testing.T) {
+	ctx := context.Background()
+	env, err := integration.NewLogEnv(ctx, 1, "unused")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer env.Close()
+	tree, err := CreateAndInitTree(ctx,
+		&trillian.CreateTreeRequest{Tree: stestonly.LogTree},
+		env.Admin, nil, env.Log)
+	if err != nil {
+		t.Fatalf("Failed to create log: %v", err)
+	}
+
+	client, err := NewFromTree(env.Log, tree)
+	if err != nil {
+		t.Fatalf("NewFromTree(): %v", err)
+	}
+	if err := addSequencedLeaves(ctx, env, client, [][]byte{[]byte("A"), []byte("B"), []byte("C")}); err != nil {
+		t.Fatalf("Failed to add leaves: %v", err)
+	}
+
+	// Another client grows the log while the first one is away.
+	other, err := NewFromTree(env.Log, tree)
+	if err != nil {
+		t.Fatalf("NewFromTree(): %v", err)
+	}
+	if err := addSequencedLeaves(ctx, env, other, [][]byte{[]byte("D"), []byte("E"), []byte("F"), []byte("G"), []byte("H"), []byte("I")}); err != nil {
+		t.Fatalf("Failed to add leaves: %v", err)
+	}
+
+	root, err := client.UpdateRootByChain(ctx)
+	if err != nil {
+		t.Fatalf("UpdateRootByChain(): %v", err)
+	}
+	if got, want := root.TreeSize, int64(9); got != want {
+		t.Errorf("UpdateRootByChain().TreeSize = %v, want %v", got, want)
+	}
+	if got, want := client.root.TreeSize, int64(9); got != want {
+		t.Errorf("trusted root has size %v, want %v", got, want)
+	}
+}
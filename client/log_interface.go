@@ -51,6 +51,10 @@ type LogVerifier interface {
 	// VerifyRoot verifies that newRoot is a valid append-only operation from trusted.
 	// If trusted.TreeSize is zero, an append-only proof is not needed.
 	VerifyRoot(trusted, newRoot *trillian.SignedLogRoot, consistency [][]byte) error
+	// VerifyRootChain verifies that newRoot is a valid append-only operation
+	// from trusted, through the steps of a chain of consistency proofs.
+	// If trusted.TreeSize is zero, the first step is not verified.
+	VerifyRootChain(trusted, newRoot *trillian.SignedLogRoot, steps []*trillian.ConsistencyProofStep) error
 	// VerifyInclusionAtIndex verifies that the inclusion proof for data at index matches
 	// the currently trusted root. The inclusion proof must be requested for Root().TreeSize.
 	VerifyInclusionAtIndex(trusted *trillian.SignedLogRoot, data []byte, leafIndex int64, proof [][]byte) error
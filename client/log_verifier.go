@@ -15,6 +15,7 @@
 package client
 
 import (
+	"bytes"
 	"crypto"
 	"fmt"
 
@@ -45,8 +46,8 @@ func NewLogVerifier(hasher hashers.LogHasher, pubKey crypto.PublicKey) LogVerifi
 // NewLogVerifierFromTree creates a new LogVerifier using the algorithms
 // specified by *trillian.Tree.
 func NewLogVerifierFromTree(config *trillian.Tree) (LogVerifier, error) {
-	if got, want := config.TreeType, trillian.TreeType_LOG; got != want {
-		return nil, fmt.Errorf("client: NewLogVerifierFromTree(): TreeType: %v, want %v", got, want)
+	if got := config.TreeType; got != trillian.TreeType_LOG && got != trillian.TreeType_PREORDERED_LOG {
+		return nil, fmt.Errorf("client: NewLogVerifierFromTree(): TreeType: %v, want %v or %v", got, trillian.TreeType_LOG, trillian.TreeType_PREORDERED_LOG)
 	}
 
 	// Log Hasher.
@@ -77,11 +78,7 @@ func (c *logVerifier) VerifyRoot(trusted, newRoot *trillian.SignedLogRoot,
 	}
 
 	// Verify SignedLogRoot signature.
-	hash, err := tcrypto.HashLogRoot(*newRoot)
-	if err != nil {
-		return err
-	}
-	if err := tcrypto.Verify(c.pubKey, hash, newRoot.Signature); err != nil {
+	if err := tcrypto.VerifyLogRoot(c.pubKey, newRoot); err != nil {
 		return err
 	}
 
@@ -98,6 +95,41 @@ func (c *logVerifier) VerifyRoot(trusted, newRoot *trillian.SignedLogRoot,
 	return nil
 }
 
+// VerifyRootChain verifies that newRoot is a valid append-only operation from
+// trusted, through the steps of a chain of consistency proofs, as returned by
+// GetConsistencyProofChain. The last step must end at newRoot. If
+// trusted.TreeSize is zero, the first step is not verified.
+func (c *logVerifier) VerifyRootChain(trusted, newRoot *trillian.SignedLogRoot, steps []*trillian.ConsistencyProofStep) error {
+	if trusted == nil {
+		return fmt.Errorf("VerifyRootChain() error: trusted == nil")
+	}
+	if newRoot == nil {
+		return fmt.Errorf("VerifyRootChain() error: newRoot == nil")
+	}
+
+	// Verify SignedLogRoot signature.
+	if err := tcrypto.VerifyLogRoot(c.pubKey, newRoot); err != nil {
+		return err
+	}
+
+	size, rootHash := trusted.TreeSize, trusted.RootHash
+	for i, step := range steps {
+		if step.TreeSize <= size {
+			return fmt.Errorf("VerifyRootChain() error: step %d ends at size %d, want > %d", i, step.TreeSize, size)
+		}
+		if size != 0 {
+			if err := c.v.VerifyConsistencyProof(size, step.TreeSize, rootHash, step.RootHash, step.GetProof().GetHashes()); err != nil {
+				return fmt.Errorf("VerifyRootChain() error: step %d: %v", i, err)
+			}
+		}
+		size, rootHash = step.TreeSize, step.RootHash
+	}
+	if size != newRoot.TreeSize || !bytes.Equal(rootHash, newRoot.RootHash) {
+		return fmt.Errorf("VerifyRootChain() error: chain ends at size %d with root hash %x, want size %d with root hash %x", size, rootHash, newRoot.TreeSize, newRoot.RootHash)
+	}
+	return nil
+}
+
 // VerifyInclusionAtIndex verifies that the inclusion proof for data at index matches
 // the currently trusted root. The inclusion proof must be requested for Root().TreeSize.
 func (c *logVerifier) VerifyInclusionAtIndex(trusted *trillian.SignedLogRoot, data []byte, leafIndex int64, proof [][]byte) error {
@@ -62,12 +62,9 @@ func (m *MapVerifier) VerifyMapLeafInclusion(smr *trillian.SignedMapRoot, leafPr
 	return merkle.VerifyMapInclusionProof(mapID, index, leaf, expectedRoot, proof, m.Hasher)
 }
 
-// VerifySignedMapRoot verifies the signature on the SignedMapRoot.
+// VerifySignedMapRoot verifies the signature on the SignedMapRoot. The
+// signature may be one of its additional signatures, if PubKey is one of the
+// additional signing keys of the map.
 func (m *MapVerifier) VerifySignedMapRoot(smr *trillian.SignedMapRoot) error {
-	// SignedMapRoot contains its own signature. To verify, we need to create a local
-	// copy of the object and return the object to the state it was in when signed
-	// by removing the signature from the object.
-	orig := *smr
-	orig.Signature = nil // Remove the signature from the object to be verified.
-	return tcrypto.VerifyObject(m.PubKey, orig, smr.GetSignature())
+	return tcrypto.VerifyMapRoot(m.PubKey, smr)
 }
@@ -45,6 +45,11 @@ func (c *MockLogClient) AddSequencedLeaf(ctx context.Context, in *trillian.AddSe
 	return c.c.AddSequencedLeaf(ctx, in)
 }
 
+// AddSequencedLeafRange forwards requests.
+func (c *MockLogClient) AddSequencedLeafRange(ctx context.Context, in *trillian.AddSequencedLeafRangeRequest, opts ...grpc.CallOption) (*trillian.AddSequencedLeafRangeResponse, error) {
+	return c.c.AddSequencedLeafRange(ctx, in)
+}
+
 // AddSequencedLeaves forwards requests.
 func (c *MockLogClient) AddSequencedLeaves(ctx context.Context, in *trillian.AddSequencedLeavesRequest, opts ...grpc.CallOption) (*trillian.AddSequencedLeavesResponse, error) {
 	return c.c.AddSequencedLeaves(ctx, in)
@@ -118,16 +123,51 @@ func (c *MockLogClient) GetLeavesByRange(ctx context.Context, in *trillian.GetLe
 	return c.c.GetLeavesByRange(ctx, in)
 }
 
+// StreamLeavesByRange forwards requests.
+func (c *MockLogClient) StreamLeavesByRange(ctx context.Context, in *trillian.GetLeavesByRangeRequest, opts ...grpc.CallOption) (trillian.TrillianLog_StreamLeavesByRangeClient, error) {
+	return c.c.StreamLeavesByRange(ctx, in)
+}
+
 // GetLeavesByHash forwards requests.
 func (c *MockLogClient) GetLeavesByHash(ctx context.Context, in *trillian.GetLeavesByHashRequest, opts ...grpc.CallOption) (*trillian.GetLeavesByHashResponse, error) {
 	return c.c.GetLeavesByHash(ctx, in)
 }
 
+// GetLeafIndicesByKey forwards requests.
+func (c *MockLogClient) GetLeafIndicesByKey(ctx context.Context, in *trillian.GetLeafIndicesByKeyRequest, opts ...grpc.CallOption) (*trillian.GetLeafIndicesByKeyResponse, error) {
+	return c.c.GetLeafIndicesByKey(ctx, in)
+}
+
+// CheckLeafFilter forwards requests.
+func (c *MockLogClient) CheckLeafFilter(ctx context.Context, in *trillian.CheckLeafFilterRequest, opts ...grpc.CallOption) (*trillian.CheckLeafFilterResponse, error) {
+	return c.c.CheckLeafFilter(ctx, in)
+}
+
+// AddObservedRoot forwards requests.
+func (c *MockLogClient) AddObservedRoot(ctx context.Context, in *trillian.AddObservedRootRequest, opts ...grpc.CallOption) (*trillian.AddObservedRootResponse, error) {
+	return c.c.AddObservedRoot(ctx, in)
+}
+
+// GetObservedRoots forwards requests.
+func (c *MockLogClient) GetObservedRoots(ctx context.Context, in *trillian.GetObservedRootsRequest, opts ...grpc.CallOption) (*trillian.GetObservedRootsResponse, error) {
+	return c.c.GetObservedRoots(ctx, in)
+}
+
+// GetConsistencyProofChain forwards requests.
+func (c *MockLogClient) GetConsistencyProofChain(ctx context.Context, in *trillian.GetConsistencyProofChainRequest, opts ...grpc.CallOption) (*trillian.GetConsistencyProofChainResponse, error) {
+	return c.c.GetConsistencyProofChain(ctx, in)
+}
+
 // GetEntryAndProof forwards requests.
 func (c *MockLogClient) GetEntryAndProof(ctx context.Context, in *trillian.GetEntryAndProofRequest, opts ...grpc.CallOption) (*trillian.GetEntryAndProofResponse, error) {
 	return c.c.GetEntryAndProof(ctx, in)
 }
 
+// GetEntriesAndProofs forwards requests.
+func (c *MockLogClient) GetEntriesAndProofs(ctx context.Context, in *trillian.GetEntriesAndProofsRequest, opts ...grpc.CallOption) (*trillian.GetEntriesAndProofsResponse, error) {
+	return c.c.GetEntriesAndProofs(ctx, in)
+}
+
 // InitLog forwards requests.
 func (c *MockLogClient) InitLog(ctx context.Context, in *trillian.InitLogRequest, opts ...grpc.CallOption) (*trillian.InitLogResponse, error) {
 	return c.c.InitLog(ctx, in)
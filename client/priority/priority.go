@@ -0,0 +1,42 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package priority sets the priority class of requests to Trillian servers.
+package priority
+
+import (
+	"context"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// MetadataKey is the gRPC metadata key carrying the priority class of
+// a request. Servers configured with priority classes admit requests of each
+// class in proportion to its weight when they're busy.
+const MetadataKey = "x-trillian-priority"
+
+// Common priority classes. Servers may be configured with others.
+const (
+	// Interactive is for requests a user is waiting on, such as
+	// fetching a proof.
+	Interactive = "interactive"
+	// Batch is for bulk requests, such as mirroring a log.
+	Batch = "batch"
+)
+
+// NewContext returns a copy of ctx whose outgoing RPCs are sent with the
+// given priority class.
+func NewContext(ctx context.Context, class string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, MetadataKey, class)
+}
@@ -0,0 +1,266 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian"
+	"github.com/google/trillian/crypto/keys/der"
+	"github.com/google/trillian/merkle"
+	"github.com/google/trillian/merkle/hashers"
+
+	tcrypto "github.com/google/trillian/crypto"
+)
+
+// ProofBundleVersion is the version of the serialization format of
+// ProofBundles produced by ProofBundle.Marshal.
+const ProofBundleVersion = 1
+
+// ProofBundle is self-contained evidence that a leaf is included in a log,
+// which third parties can verify offline with VerifyProofBundle, knowing only
+// the log's public key and hash strategy.
+type ProofBundle struct {
+	// LogID is the ID of the log.
+	LogID int64
+	// KeyID identifies the log's public key, see PublicKeyID.
+	KeyID []byte
+	// Leaf is the leaf whose inclusion is proven.
+	Leaf *trillian.LogLeaf
+	// Root is the signed root which Leaf is included in.
+	Root *trillian.SignedLogRoot
+	// InclusionProof is the proof of inclusion of Leaf in Root.
+	InclusionProof [][]byte
+	// TrustedRoot is an optional earlier signed root, e.g. one the recipient
+	// of the bundle has already seen, which Root is proven to be consistent
+	// with.
+	TrustedRoot *trillian.SignedLogRoot
+	// ConsistencyProof is the proof of consistency between TrustedRoot and
+	// Root.
+	ConsistencyProof [][]byte
+}
+
+// proofBundleJSON is the serialized form of ProofBundle. Protos are encoded
+// with their canonical JSON mapping.
+type proofBundleJSON struct {
+	Version          int             `json:"version"`
+	LogID            int64           `json:"log_id,string"`
+	KeyID            []byte          `json:"key_id"`
+	Leaf             json.RawMessage `json:"leaf"`
+	Root             json.RawMessage `json:"root"`
+	InclusionProof   [][]byte        `json:"inclusion_proof"`
+	TrustedRoot      json.RawMessage `json:"trusted_root,omitempty"`
+	ConsistencyProof [][]byte        `json:"consistency_proof,omitempty"`
+}
+
+// PublicKeyID returns the key ID of the DER-encoded public key der, which is
+// its SHA-256 hash.
+func PublicKeyID(der []byte) []byte {
+	id := sha256.Sum256(der)
+	return id[:]
+}
+
+// NewProofBundle fetches the latest root of the log configured by tree, and
+// returns a verified ProofBundle proving the inclusion in it of the leaf at
+// leafIndex. If trusted isn't nil, the bundle also proves that the root is
+// consistent with trusted, which must have been verified by the caller.
+func NewProofBundle(ctx context.Context, client trillian.TrillianLogClient, tree *trillian.Tree, leafIndex int64, trusted *trillian.SignedLogRoot) (*ProofBundle, error) {
+	rootResp, err := client.GetLatestSignedLogRoot(ctx, &trillian.GetLatestSignedLogRootRequest{LogId: tree.TreeId})
+	if err != nil {
+		return nil, err
+	}
+	root := rootResp.GetSignedLogRoot()
+	if root == nil {
+		return nil, errors.New("no signed log root returned")
+	}
+	if leafIndex >= root.TreeSize {
+		return nil, fmt.Errorf("leaf %d is not in the tree of size %d", leafIndex, root.TreeSize)
+	}
+
+	entryResp, err := client.GetEntryAndProof(ctx, &trillian.GetEntryAndProofRequest{
+		LogId:     tree.TreeId,
+		LeafIndex: leafIndex,
+		TreeSize:  root.TreeSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if entryResp.GetLeaf() == nil || entryResp.GetProof() == nil {
+		return nil, errors.New("no leaf or inclusion proof returned")
+	}
+
+	bundle := &ProofBundle{
+		LogID:          tree.TreeId,
+		KeyID:          PublicKeyID(tree.GetPublicKey().GetDer()),
+		Leaf:           entryResp.Leaf,
+		Root:           root,
+		InclusionProof: entryResp.Proof.Hashes,
+	}
+	if trusted != nil {
+		bundle.TrustedRoot = trusted
+		if trusted.TreeSize > 0 && trusted.TreeSize < root.TreeSize {
+			consistResp, err := client.GetConsistencyProof(ctx, &trillian.GetConsistencyProofRequest{
+				LogId:          tree.TreeId,
+				FirstTreeSize:  trusted.TreeSize,
+				SecondTreeSize: root.TreeSize,
+			})
+			if err != nil {
+				return nil, err
+			}
+			bundle.ConsistencyProof = consistResp.GetProof().GetHashes()
+		}
+	}
+
+	if err := VerifyProofBundle(bundle, tree); err != nil {
+		return nil, fmt.Errorf("log returned invalid proofs: %v", err)
+	}
+	return bundle, nil
+}
+
+// VerifyProofBundle verifies bundle against the log configured by tree, of
+// which only the ID, hash strategy and public key are used. It checks the
+// signatures of the bundle's roots, the inclusion of its leaf, and the
+// consistency between its roots.
+func VerifyProofBundle(bundle *ProofBundle, tree *trillian.Tree) error {
+	if bundle.Leaf == nil || bundle.Root == nil {
+		return errors.New("bundle has no leaf or root")
+	}
+	if bundle.LogID != tree.TreeId {
+		return fmt.Errorf("bundle is for log %d, want %d", bundle.LogID, tree.TreeId)
+	}
+	if want := PublicKeyID(tree.GetPublicKey().GetDer()); !bytes.Equal(bundle.KeyID, want) {
+		return fmt.Errorf("bundle key ID is %x, want %x", bundle.KeyID, want)
+	}
+	hasher, err := hashers.NewLogHasher(tree.GetHashStrategy())
+	if err != nil {
+		return err
+	}
+	pubKey, err := der.UnmarshalPublicKey(tree.GetPublicKey().GetDer())
+	if err != nil {
+		return err
+	}
+
+	verifyRoot := func(root *trillian.SignedLogRoot) error {
+		hash, err := tcrypto.HashLogRoot(*root)
+		if err != nil {
+			return err
+		}
+		return tcrypto.Verify(pubKey, hash, root.Signature)
+	}
+	if err := verifyRoot(bundle.Root); err != nil {
+		return fmt.Errorf("invalid root signature: %v", err)
+	}
+
+	leafHash, err := hasher.HashLeaf(bundle.Leaf.LeafValue)
+	if err != nil {
+		return err
+	}
+	v := merkle.NewLogVerifier(hasher)
+	if err := v.VerifyInclusionProof(bundle.Leaf.LeafIndex, bundle.Root.TreeSize, bundle.InclusionProof, bundle.Root.RootHash, leafHash); err != nil {
+		return fmt.Errorf("invalid inclusion proof: %v", err)
+	}
+
+	if trusted := bundle.TrustedRoot; trusted != nil {
+		if err := verifyRoot(trusted); err != nil {
+			return fmt.Errorf("invalid trusted root signature: %v", err)
+		}
+		if trusted.TreeSize > 0 {
+			if err := v.VerifyConsistencyProof(trusted.TreeSize, bundle.Root.TreeSize, trusted.RootHash, bundle.Root.RootHash, bundle.ConsistencyProof); err != nil {
+				return fmt.Errorf("invalid consistency proof: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// Marshal serializes the bundle to JSON.
+func (b *ProofBundle) Marshal() ([]byte, error) {
+	m := jsonpb.Marshaler{OrigName: true}
+	toJSON := func(pb proto.Message) (json.RawMessage, error) {
+		var buf bytes.Buffer
+		if err := m.Marshal(&buf, pb); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	if b.Leaf == nil || b.Root == nil {
+		return nil, errors.New("bundle has no leaf or root")
+	}
+	out := proofBundleJSON{
+		Version:          ProofBundleVersion,
+		LogID:            b.LogID,
+		KeyID:            b.KeyID,
+		InclusionProof:   b.InclusionProof,
+		ConsistencyProof: b.ConsistencyProof,
+	}
+	var err error
+	if out.Leaf, err = toJSON(b.Leaf); err != nil {
+		return nil, err
+	}
+	if out.Root, err = toJSON(b.Root); err != nil {
+		return nil, err
+	}
+	if b.TrustedRoot != nil {
+		if out.TrustedRoot, err = toJSON(b.TrustedRoot); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(out)
+}
+
+// ParseProofBundle parses a bundle serialized by ProofBundle.Marshal. The
+// bundle isn't verified.
+func ParseProofBundle(data []byte) (*ProofBundle, error) {
+	var in proofBundleJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return nil, err
+	}
+	if in.Version != ProofBundleVersion {
+		return nil, fmt.Errorf("unsupported proof bundle version %d", in.Version)
+	}
+	if len(in.Leaf) == 0 || len(in.Root) == 0 {
+		return nil, errors.New("bundle has no leaf or root")
+	}
+
+	b := &ProofBundle{
+		LogID:            in.LogID,
+		KeyID:            in.KeyID,
+		Leaf:             &trillian.LogLeaf{},
+		Root:             &trillian.SignedLogRoot{},
+		InclusionProof:   in.InclusionProof,
+		ConsistencyProof: in.ConsistencyProof,
+	}
+	if err := jsonpb.Unmarshal(bytes.NewReader(in.Leaf), b.Leaf); err != nil {
+		return nil, fmt.Errorf("invalid leaf: %v", err)
+	}
+	if err := jsonpb.Unmarshal(bytes.NewReader(in.Root), b.Root); err != nil {
+		return nil, fmt.Errorf("invalid root: %v", err)
+	}
+	if len(in.TrustedRoot) > 0 {
+		b.TrustedRoot = &trillian.SignedLogRoot{}
+		if err := jsonpb.Unmarshal(bytes.NewReader(in.TrustedRoot), b.TrustedRoot); err != nil {
+			return nil, fmt.Errorf("invalid trusted root: %v", err)
+		}
+	}
+	return b, nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian"
+	"github.com/google/trillian/testonly/integration"
+
+	stestonly "github.com/google/trillian/storage/testonly"
+)
+
+func TestProofBundle(t *testing.T) {
+	ctx := context.Background()
+	env, err := integration.NewLogEnv(ctx, 1, "unused")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer env.Close()
+	tree, err := CreateAndInitTree(ctx,
+		&trillian.CreateTreeRequest{Tree: stestonly.LogTree},
+		env.Admin, nil, env.Log)
+	if err != nil {
+		t.Fatalf("Failed to create log: %v", err)
+	}
+	client, err := NewFromTree(env.Log, tree)
+	if err != nil {
+		t.Fatalf("NewFromTree(): %v", err)
+	}
+
+	if err := addSequencedLeaves(ctx, env, client, [][]byte{[]byte("A")}); err != nil {
+		t.Fatalf("Failed to add leaves: %v", err)
+	}
+	trusted, err := client.UpdateRoot(ctx)
+	if err != nil {
+		t.Fatalf("UpdateRoot(): %v", err)
+	}
+	if err := addSequencedLeaves(ctx, env, client, [][]byte{[]byte("B"), []byte("C")}); err != nil {
+		t.Fatalf("Failed to add leaves: %v", err)
+	}
+
+	bundle, err := NewProofBundle(ctx, env.Log, tree, 1, trusted)
+	if err != nil {
+		t.Fatalf("NewProofBundle(): %v", err)
+	}
+	if got, want := string(bundle.Leaf.LeafValue), "B"; got != want {
+		t.Errorf("NewProofBundle(): leaf %q, want %q", got, want)
+	}
+	if got, want := bundle.Root.TreeSize, int64(3); got != want {
+		t.Errorf("NewProofBundle(): root size %d, want %d", got, want)
+	}
+
+	data, err := bundle.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal(): %v", err)
+	}
+	parsed, err := ParseProofBundle(data)
+	if err != nil {
+		t.Fatalf("ParseProofBundle(): %v", err)
+	}
+	if !proto.Equal(parsed.Root, bundle.Root) || !proto.Equal(parsed.TrustedRoot, bundle.TrustedRoot) || !proto.Equal(parsed.Leaf, bundle.Leaf) {
+		t.Errorf("ParseProofBundle(): got %+v, want %+v", parsed, bundle)
+	}
+	if err := VerifyProofBundle(parsed, tree); err != nil {
+		t.Errorf("VerifyProofBundle(): %v", err)
+	}
+
+	for _, test := range []struct {
+		desc   string
+		modify func(b *ProofBundle, tree *trillian.Tree)
+	}{
+		{desc: "wrong log", modify: func(b *ProofBundle, tree *trillian.Tree) { tree.TreeId++ }},
+		{desc: "wrong key ID", modify: func(b *ProofBundle, tree *trillian.Tree) { b.KeyID = PublicKeyID([]byte("key")) }},
+		{desc: "wrong leaf", modify: func(b *ProofBundle, tree *trillian.Tree) { b.Leaf.LeafValue = []byte("D") }},
+		{desc: "wrong index", modify: func(b *ProofBundle, tree *trillian.Tree) { b.Leaf.LeafIndex = 0 }},
+		{desc: "unsigned root", modify: func(b *ProofBundle, tree *trillian.Tree) { b.Root.TimestampNanos++ }},
+		{desc: "unsigned trusted root", modify: func(b *ProofBundle, tree *trillian.Tree) { b.TrustedRoot.TimestampNanos++ }},
+		{desc: "no consistency proof", modify: func(b *ProofBundle, tree *trillian.Tree) { b.ConsistencyProof = nil }},
+	} {
+		b, err := ParseProofBundle(data)
+		if err != nil {
+			t.Fatalf("ParseProofBundle(): %v", err)
+		}
+		tr := proto.Clone(tree).(*trillian.Tree)
+		test.modify(b, tr)
+		if err := VerifyProofBundle(b, tr); err == nil {
+			t.Errorf("%v: VerifyProofBundle()=nil, want error", test.desc)
+		}
+	}
+}
+
+func TestParseProofBundleErrors(t *testing.T) {
+	for _, data := range []string{
+		``,
+		`{"version": 2, "leaf": {}, "root": {}}`,
+		`{"version": 1, "root": {}}`,
+		`{"version": 1, "leaf": {"leaf_index": "x"}, "root": {}}`,
+	} {
+		if _, err := ParseProofBundle([]byte(data)); err == nil {
+			t.Errorf("ParseProofBundle(%q)=_,nil; want error", data)
+		}
+	}
+}
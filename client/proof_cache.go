@@ -0,0 +1,234 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian"
+	"github.com/google/trillian/util"
+)
+
+// ErrNotCached is returned by ProofCache lookups of entries which aren't in
+// the cache, or have expired.
+var ErrNotCached = errors.New("not in proof cache")
+
+const (
+	rootsDir       = "roots"
+	consistencyDir = "consistency"
+	inclusionDir   = "inclusion"
+)
+
+// ProofCache stores signed log roots, consistency proofs and inclusion proofs
+// on disk, keyed by log and tree size, so that clients which are only
+// intermittently connected can re-verify evidence without fetching it again.
+//
+// Only evidence which a LogClient verified is cached, but anyone with write
+// access to the cache's directory can change it, so cached evidence must be
+// verified again when it's read, e.g. by LogClient.VerifyCachedInclusion.
+//
+// Entries expire a TTL after they're written. A ProofCache may be shared by
+// the processes using its directory.
+type ProofCache struct {
+	dir        string
+	ttl        time.Duration
+	timeSource util.TimeSource
+}
+
+// NewProofCache returns a ProofCache storing entries in dir, which is created
+// if it doesn't exist. Entries expire after ttl, or never if it's zero.
+func NewProofCache(dir string, ttl time.Duration, timeSource util.TimeSource) (*ProofCache, error) {
+	if ttl < 0 {
+		return nil, fmt.Errorf("proof cache TTL is %v, want >= 0", ttl)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &ProofCache{dir: dir, ttl: ttl, timeSource: timeSource}, nil
+}
+
+// PutRoot caches root, replacing any root of the same log and size.
+func (p *ProofCache) PutRoot(root *trillian.SignedLogRoot) error {
+	return p.put(p.path(root.LogId, rootsDir, strconv.FormatInt(root.TreeSize, 10)), root)
+}
+
+// Root returns the cached root of logID with treeSize leaves.
+func (p *ProofCache) Root(logID, treeSize int64) (*trillian.SignedLogRoot, error) {
+	root := &trillian.SignedLogRoot{}
+	if err := p.get(p.path(logID, rootsDir, strconv.FormatInt(treeSize, 10)), root); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// LatestRoot returns the cached root of logID with the most leaves.
+func (p *ProofCache) LatestRoot(logID int64) (*trillian.SignedLogRoot, error) {
+	names, err := p.list(logID, rootsDir)
+	if err != nil {
+		return nil, err
+	}
+	latest := int64(-1)
+	for _, name := range names {
+		if size, err := strconv.ParseInt(name, 10, 64); err == nil && size > latest {
+			latest = size
+		}
+	}
+	if latest < 0 {
+		return nil, ErrNotCached
+	}
+	return p.Root(logID, latest)
+}
+
+// PutConsistencyProof caches the proof of consistency between the roots of
+// logID with first and second leaves.
+func (p *ProofCache) PutConsistencyProof(logID, first, second int64, proof [][]byte) error {
+	return p.put(p.path(logID, consistencyDir, fmt.Sprintf("%d-%d", first, second)), &trillian.Proof{Hashes: proof})
+}
+
+// ConsistencyProof returns the cached proof of consistency between the roots
+// of logID with first and second leaves.
+func (p *ProofCache) ConsistencyProof(logID, first, second int64) ([][]byte, error) {
+	proof := &trillian.Proof{}
+	if err := p.get(p.path(logID, consistencyDir, fmt.Sprintf("%d-%d", first, second)), proof); err != nil {
+		return nil, err
+	}
+	return proof.Hashes, nil
+}
+
+// PutInclusionProof caches the proof of inclusion of the leaf with leafHash
+// in the root of logID with treeSize leaves.
+func (p *ProofCache) PutInclusionProof(logID int64, leafHash []byte, treeSize int64, proof *trillian.Proof) error {
+	return p.put(p.path(logID, inclusionDir, fmt.Sprintf("%d-%x", treeSize, leafHash)), proof)
+}
+
+// InclusionProof returns the cached proof of inclusion of the leaf with
+// leafHash in the root of logID with treeSize leaves.
+func (p *ProofCache) InclusionProof(logID int64, leafHash []byte, treeSize int64) (*trillian.Proof, error) {
+	proof := &trillian.Proof{}
+	if err := p.get(p.path(logID, inclusionDir, fmt.Sprintf("%d-%x", treeSize, leafHash)), proof); err != nil {
+		return nil, err
+	}
+	return proof, nil
+}
+
+// Expire deletes the expired entries of logID.
+func (p *ProofCache) Expire(logID int64) error {
+	if p.ttl == 0 {
+		return nil
+	}
+	for _, kind := range []string{rootsDir, consistencyDir, inclusionDir} {
+		dir := p.path(logID, kind, "")
+		infos, err := ioutil.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		for _, info := range infos {
+			if p.expired(info) {
+				if err := os.Remove(filepath.Join(dir, info.Name())); err != nil && !os.IsNotExist(err) {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (p *ProofCache) path(logID int64, kind, name string) string {
+	return filepath.Join(p.dir, strconv.FormatInt(logID, 10), kind, name)
+}
+
+func (p *ProofCache) expired(info os.FileInfo) bool {
+	return p.ttl > 0 && p.timeSource.Now().Sub(info.ModTime()) > p.ttl
+}
+
+// put writes pb to path, through a temporary file so that readers never see
+// partial entries. The entry's modification time is set to the current time
+// of the cache's TimeSource, which its TTL counts from.
+func (p *ProofCache) put(path string, pb proto.Message) error {
+	data, err := proto.Marshal(pb)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	now := p.timeSource.Now()
+	if err := os.Chtimes(f.Name(), now, now); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// get reads the entry at path into pb.
+func (p *ProofCache) get(path string, pb proto.Message) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return ErrNotCached
+	} else if err != nil {
+		return err
+	}
+	if p.expired(info) {
+		return ErrNotCached
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ErrNotCached
+	} else if err != nil {
+		return err
+	}
+	if err := proto.Unmarshal(data, pb); err != nil {
+		return fmt.Errorf("corrupt proof cache entry %v: %v", path, err)
+	}
+	return nil
+}
+
+// list returns the names of the unexpired entries of logID of the given kind.
+func (p *ProofCache) list(logID int64, kind string) ([]string, error) {
+	infos, err := ioutil.ReadDir(p.path(logID, kind, ""))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, info := range infos {
+		if !strings.HasPrefix(info.Name(), ".") && !p.expired(info) {
+			names = append(names, info.Name())
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,179 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian"
+	"github.com/google/trillian/testonly/integration"
+	"github.com/google/trillian/util"
+
+	stestonly "github.com/google/trillian/storage/testonly"
+)
+
+func newTestProofCache(t *testing.T, ttl time.Duration, ts util.TimeSource) (*ProofCache, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "proofcache")
+	if err != nil {
+		t.Fatalf("TempDir(): %v", err)
+	}
+	cache, err := NewProofCache(dir, ttl, ts)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("NewProofCache(): %v", err)
+	}
+	return cache, func() { os.RemoveAll(dir) }
+}
+
+func TestProofCache(t *testing.T) {
+	ts := util.NewFakeTimeSource(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	cache, cleanup := newTestProofCache(t, time.Hour, ts)
+	defer cleanup()
+
+	if _, err := cache.LatestRoot(1); err != ErrNotCached {
+		t.Errorf("LatestRoot() of empty cache: %v, want %v", err, ErrNotCached)
+	}
+	for _, size := range []int64{3, 10, 5} {
+		if err := cache.PutRoot(&trillian.SignedLogRoot{LogId: 1, TreeSize: size, RootHash: []byte{byte(size)}}); err != nil {
+			t.Fatalf("PutRoot(%d): %v", size, err)
+		}
+	}
+	latest, err := cache.LatestRoot(1)
+	if err != nil {
+		t.Fatalf("LatestRoot(): %v", err)
+	}
+	if got, want := latest.TreeSize, int64(10); got != want {
+		t.Errorf("LatestRoot().TreeSize = %v, want %v", got, want)
+	}
+	if _, err := cache.Root(1, 4); err != ErrNotCached {
+		t.Errorf("Root(4): %v, want %v", err, ErrNotCached)
+	}
+	if _, err := cache.LatestRoot(2); err != ErrNotCached {
+		t.Errorf("LatestRoot() of other log: %v, want %v", err, ErrNotCached)
+	}
+
+	consistency := [][]byte{[]byte("a"), []byte("b")}
+	if err := cache.PutConsistencyProof(1, 3, 5, consistency); err != nil {
+		t.Fatalf("PutConsistencyProof(): %v", err)
+	}
+	if got, err := cache.ConsistencyProof(1, 3, 5); err != nil || !reflect.DeepEqual(got, consistency) {
+		t.Errorf("ConsistencyProof() = %x, %v, want %x, nil", got, err, consistency)
+	}
+	inclusion := &trillian.Proof{LeafIndex: 2, Hashes: [][]byte{[]byte("c")}}
+	if err := cache.PutInclusionProof(1, []byte("leaf"), 5, inclusion); err != nil {
+		t.Fatalf("PutInclusionProof(): %v", err)
+	}
+	if got, err := cache.InclusionProof(1, []byte("leaf"), 5); err != nil || !proto.Equal(got, inclusion) {
+		t.Errorf("InclusionProof() = %v, %v, want %v, nil", got, err, inclusion)
+	}
+	if _, err := cache.InclusionProof(1, []byte("leaf"), 10); err != ErrNotCached {
+		t.Errorf("InclusionProof() at other size: %v, want %v", err, ErrNotCached)
+	}
+
+	// Entries written later expire later.
+	ts.Set(ts.Now().Add(30 * time.Minute))
+	if err := cache.PutRoot(&trillian.SignedLogRoot{LogId: 1, TreeSize: 4}); err != nil {
+		t.Fatalf("PutRoot(4): %v", err)
+	}
+	ts.Set(ts.Now().Add(45 * time.Minute))
+	if _, err := cache.ConsistencyProof(1, 3, 5); err != ErrNotCached {
+		t.Errorf("ConsistencyProof() after TTL: %v, want %v", err, ErrNotCached)
+	}
+	if latest, err := cache.LatestRoot(1); err != nil || latest.TreeSize != 4 {
+		t.Errorf("LatestRoot() after TTL = %v, %v, want root of size 4", latest, err)
+	}
+	if err := cache.Expire(1); err != nil {
+		t.Fatalf("Expire(): %v", err)
+	}
+	names, err := cache.list(1, rootsDir)
+	if err != nil {
+		t.Fatalf("list(): %v", err)
+	}
+	if want := []string{"4"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("cached roots after Expire() = %v, want %v", names, want)
+	}
+}
+
+func TestLogClientProofCache(t *testing.T) {
+	ctx := context.Background()
+	env, err := integration.NewLogEnv(ctx, 1, "unused")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer env.Close()
+	tree, err := CreateAndInitTree(ctx,
+		&trillian.CreateTreeRequest{Tree: stestonly.LogTree},
+		env.Admin, nil, env.Log)
+	if err != nil {
+		t.Fatalf("Failed to create log: %v", err)
+	}
+	cache, cleanup := newTestProofCache(t, 0, util.SystemTimeSource{})
+	defer cleanup()
+
+	client, err := NewFromTree(env.Log, tree)
+	if err != nil {
+		t.Fatalf("NewFromTree(): %v", err)
+	}
+	if err := client.SetProofCache(cache); err != nil {
+		t.Fatalf("SetProofCache(): %v", err)
+	}
+	leafData := [][]byte{[]byte("A"), []byte("B"), []byte("C")}
+	if err := addSequencedLeaves(ctx, env, client, leafData); err != nil {
+		t.Fatalf("Failed to add leaves: %v", err)
+	}
+
+	// A client without a connection to the log verifies the cached evidence.
+	offline, err := NewFromTree(nil, tree)
+	if err != nil {
+		t.Fatalf("NewFromTree(): %v", err)
+	}
+	if err := offline.SetProofCache(cache); err != nil {
+		t.Fatalf("SetProofCache(): %v", err)
+	}
+	if got, want := offline.root.TreeSize, int64(len(leafData)); got != want {
+		t.Errorf("trusted root from cache has size %d, want %d", got, want)
+	}
+	for i, data := range leafData {
+		if err := offline.VerifyCachedInclusion(data, int64(i+1)); err != nil {
+			t.Errorf("VerifyCachedInclusion(%s): %v", data, err)
+		}
+	}
+	if err := offline.VerifyCachedConsistency(2, 3); err != nil {
+		t.Errorf("VerifyCachedConsistency(2, 3): %v", err)
+	}
+	if err := offline.VerifyCachedInclusion([]byte("D"), 3); err != ErrNotCached {
+		t.Errorf("VerifyCachedInclusion() of unknown leaf: %v, want %v", err, ErrNotCached)
+	}
+
+	// Tampered roots are rejected.
+	root, err := cache.Root(tree.TreeId, 3)
+	if err != nil {
+		t.Fatalf("Root(): %v", err)
+	}
+	root.RootHash[0] ^= 1
+	if err := cache.PutRoot(root); err != nil {
+		t.Fatalf("PutRoot(): %v", err)
+	}
+	if err := offline.VerifyCachedInclusion([]byte("C"), 3); err == nil {
+		t.Error("VerifyCachedInclusion() with tampered root: got nil error, want non-nil")
+	}
+}
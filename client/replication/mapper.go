@@ -0,0 +1,102 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package replication verifies maps derived from the entries of a log, by
+// replaying the log through the function which builds the map.
+package replication
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/google/trillian"
+)
+
+// IdentityMapperName is the name under which IdentityMapper is registered.
+const IdentityMapperName = "identity"
+
+// Mapper derives the leaves of a map from the entries of a log.
+//
+// The map built by a Mapper must not depend on how the log entries are split
+// into batches, as the Verifier doesn't necessarily replay the log in the
+// batches the map was built from.
+type Mapper interface {
+	// Map returns the map leaves to set for leaves, which are consecutive
+	// entries of the log in index order. get returns the value of a map leaf
+	// as of the entries before leaves, or nil if it isn't set. Map leaves
+	// with nil values are ignored, as the map doesn't store them.
+	Map(ctx context.Context, leaves []*trillian.LogLeaf, get func(index []byte) []byte) ([]*trillian.MapLeaf, error)
+}
+
+// MapperFunc adapts a function to the Mapper interface.
+type MapperFunc func(ctx context.Context, leaves []*trillian.LogLeaf, get func(index []byte) []byte) ([]*trillian.MapLeaf, error)
+
+// Map calls f.
+func (f MapperFunc) Map(ctx context.Context, leaves []*trillian.LogLeaf, get func(index []byte) []byte) ([]*trillian.MapLeaf, error) {
+	return f(ctx, leaves, get)
+}
+
+// IdentityMapper maps each log entry to a map leaf with its identity hash as
+// index and its value as value. It requires the log's identity hashes to be
+// the size of the map's hashes.
+var IdentityMapper Mapper = MapperFunc(func(ctx context.Context, leaves []*trillian.LogLeaf, get func([]byte) []byte) ([]*trillian.MapLeaf, error) {
+	mapLeaves := make([]*trillian.MapLeaf, 0, len(leaves))
+	for _, l := range leaves {
+		mapLeaves = append(mapLeaves, &trillian.MapLeaf{Index: l.LeafIdentityHash, LeafValue: l.LeafValue})
+	}
+	return mapLeaves, nil
+})
+
+var (
+	mappersMu sync.RWMutex
+	mappers   = map[string]Mapper{IdentityMapperName: IdentityMapper}
+)
+
+// RegisterMapper registers a Mapper under name, for use by the Verifier.
+func RegisterMapper(name string, m Mapper) {
+	mappersMu.Lock()
+	defer mappersMu.Unlock()
+	if name == "" {
+		panic("RegisterMapper() with empty name")
+	}
+	if mappers[name] != nil {
+		panic(fmt.Sprintf("%q already registered as a Mapper", name))
+	}
+	mappers[name] = m
+}
+
+// GetMapper returns the Mapper registered under name.
+func GetMapper(name string) (Mapper, error) {
+	mappersMu.RLock()
+	defer mappersMu.RUnlock()
+	m := mappers[name]
+	if m == nil {
+		return nil, fmt.Errorf("no Mapper registered as %q", name)
+	}
+	return m, nil
+}
+
+// MapperNames returns the names of the registered Mappers, in order.
+func MapperNames() []string {
+	mappersMu.RLock()
+	defer mappersMu.RUnlock()
+	names := make([]string, 0, len(mappers))
+	for name := range mappers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,49 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replication
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/google/trillian"
+)
+
+func TestRegisterMapper(t *testing.T) {
+	noop := MapperFunc(func(context.Context, []*trillian.LogLeaf, func([]byte) []byte) ([]*trillian.MapLeaf, error) {
+		return nil, nil
+	})
+	RegisterMapper("noop", noop)
+
+	if _, err := GetMapper("noop"); err != nil {
+		t.Errorf("GetMapper(noop): %v", err)
+	}
+	if _, err := GetMapper("unknown"); err == nil {
+		t.Error("GetMapper(unknown): nil, want error")
+	}
+	if got, want := MapperNames(), []string{IdentityMapperName, "noop"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MapperNames() = %v, want %v", got, want)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("RegisterMapper() of a registered name didn't panic")
+			}
+		}()
+		RegisterMapper(IdentityMapperName, noop)
+	}()
+}
@@ -0,0 +1,351 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replication
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/trillian"
+	"github.com/google/trillian/client"
+	"github.com/google/trillian/merkle"
+	"github.com/google/trillian/merkle/hashers"
+	"github.com/google/trillian/monitoring"
+)
+
+const (
+	// DefaultBatchSize is the default number of log leaves fetched per request.
+	DefaultBatchSize = 1000
+	// DefaultInterval is the default interval between checks for new map
+	// revisions.
+	DefaultInterval = time.Minute
+
+	mapIDLabel = "mapid"
+)
+
+var (
+	verifiedRevision    monitoring.Gauge
+	replayedLogSize     monitoring.Gauge
+	diverged            monitoring.Gauge
+	verifyErrors        monitoring.Counter
+	verifierMetricsOnce sync.Once
+)
+
+func initMetrics(mf monitoring.MetricFactory) {
+	verifierMetricsOnce.Do(func() {
+		if mf == nil {
+			mf = monitoring.InertMetricFactory{}
+		}
+		verifiedRevision = mf.NewGauge("map_replication_verified_revision", "Latest map revision whose root matches the replayed log", mapIDLabel)
+		replayedLogSize = mf.NewGauge("map_replication_log_size", "Number of log entries replayed into the map", mapIDLabel)
+		diverged = mf.NewGauge("map_replication_diverged", "Set to 1 once a map revision diverges from the replayed log, 0 otherwise", mapIDLabel)
+		verifyErrors = mf.NewCounter("map_replication_errors", "Number of checks of a map which failed without establishing divergence", mapIDLabel)
+	})
+}
+
+// DivergenceError reports a map revision which doesn't match the log it was
+// derived from.
+type DivergenceError struct {
+	MapID    int64
+	Revision int64
+	Reason   string
+}
+
+func (e *DivergenceError) Error() string {
+	return fmt.Sprintf("map %d revision %d diverges from its log: %s", e.MapID, e.Revision, e.Reason)
+}
+
+// VerifierOpts configures a Verifier.
+type VerifierOpts struct {
+	// BatchSize is the number of log leaves fetched per request.
+	BatchSize int64
+	// Interval is the interval between checks for new map revisions in Run.
+	Interval time.Duration
+}
+
+// Verifier replays the entries of a log through a Mapper, and checks that the
+// roots published by a map derived from the log match the replayed map.
+//
+// Each map revision built from the log names the log root it was built from
+// with SourceLogRoot metadata. The Verifier checks that the log has that root,
+// replays the log up to its size, and compares the root hash of the resulting
+// map with the revision's. Revisions without SourceLogRoot metadata must leave
+// the map unchanged.
+//
+// The replayed map is held in memory, so a Verifier starts from the first
+// revision of the map.
+type Verifier struct {
+	logClient trillian.TrillianLogClient
+	mapClient trillian.TrillianMapClient
+	logID     int64
+	mapID     int64
+	logHasher hashers.LogHasher
+	mapHasher hashers.MapHasher
+	verifier  *client.MapVerifier
+	mapper    Mapper
+	opts      VerifierOpts
+	label     string
+
+	// Replay state: the map built from the first cmt.Size() log entries.
+	cmt    *merkle.CompactMerkleTree
+	values map[string][]byte
+	hashes map[string][]byte
+	// rootHash caches the root hash of the replayed map, nil if stale.
+	rootHash []byte
+	// revision is the latest map revision verified, -1 if none.
+	revision int64
+	// divergence is set once a revision of the map diverges from the log.
+	divergence *DivergenceError
+}
+
+// NewVerifier returns a Verifier of the map tree mapTree, built by mapper from
+// the log tree logTree.
+func NewVerifier(logClient trillian.TrillianLogClient, mapClient trillian.TrillianMapClient, logTree, mapTree *trillian.Tree, mapper Mapper, mf monitoring.MetricFactory, opts VerifierOpts) (*Verifier, error) {
+	if t := logTree.TreeType; t != trillian.TreeType_LOG && t != trillian.TreeType_PREORDERED_LOG {
+		return nil, fmt.Errorf("tree %d is a %v, want a log", logTree.TreeId, t)
+	}
+	logHasher, err := hashers.NewLogHasher(logTree.HashStrategy)
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := client.NewMapVerifierFromTree(mapTree)
+	if err != nil {
+		return nil, err
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	initMetrics(mf)
+
+	v := &Verifier{
+		logClient: logClient,
+		mapClient: mapClient,
+		logID:     logTree.TreeId,
+		mapID:     mapTree.TreeId,
+		logHasher: logHasher,
+		mapHasher: verifier.Hasher,
+		verifier:  verifier,
+		mapper:    mapper,
+		opts:      opts,
+		label:     strconv.FormatInt(mapTree.TreeId, 10),
+		cmt:       merkle.NewCompactMerkleTree(logHasher),
+		values:    make(map[string][]byte),
+		hashes:    make(map[string][]byte),
+		revision:  -1,
+	}
+	diverged.Set(0, v.label)
+	return v, nil
+}
+
+// Run checks the map for new revisions every opts.Interval, until ctx is
+// done. Divergence is logged and reported by metrics, and stops the
+// verification of later revisions.
+func (v *Verifier) Run(ctx context.Context) {
+	ticker := time.NewTicker(v.opts.Interval)
+	defer ticker.Stop()
+	for {
+		if v.divergence == nil {
+			if _, err := v.VerifyLatest(ctx); err != nil {
+				if _, ok := err.(*DivergenceError); ok {
+					glog.Errorf("%v", err)
+				} else {
+					glog.Warningf("map %d: verification failed: %v", v.mapID, err)
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// VerifyLatest verifies the revisions of the map up to its latest one, and
+// returns the latest revision verified. It returns a *DivergenceError if a
+// revision doesn't match the log, including one found by an earlier call.
+func (v *Verifier) VerifyLatest(ctx context.Context) (int64, error) {
+	if v.divergence != nil {
+		return v.revision, v.divergence
+	}
+	resp, err := v.mapClient.GetSignedMapRoot(ctx, &trillian.GetSignedMapRootRequest{MapId: v.mapID})
+	if err != nil {
+		verifyErrors.Inc(v.label)
+		return v.revision, err
+	}
+	latest := resp.GetMapRoot()
+	for rev := v.revision + 1; rev <= latest.GetMapRevision(); rev++ {
+		root := latest
+		if rev < latest.GetMapRevision() {
+			resp, err := v.mapClient.GetSignedMapRootByRevision(ctx, &trillian.GetSignedMapRootByRevisionRequest{MapId: v.mapID, Revision: rev})
+			if err != nil {
+				verifyErrors.Inc(v.label)
+				return v.revision, err
+			}
+			root = resp.GetMapRoot()
+		}
+		if err := v.verifyRevision(ctx, rev, root); err != nil {
+			if d, ok := err.(*DivergenceError); ok {
+				v.divergence = d
+				diverged.Set(1, v.label)
+			} else {
+				verifyErrors.Inc(v.label)
+			}
+			return v.revision, err
+		}
+		v.revision = rev
+		verifiedRevision.Set(float64(rev), v.label)
+	}
+	return v.revision, nil
+}
+
+// verifyRevision checks root, the root of the map at revision rev, against
+// the replayed log.
+func (v *Verifier) verifyRevision(ctx context.Context, rev int64, root *trillian.SignedMapRoot) error {
+	diverge := func(format string, args ...interface{}) error {
+		return &DivergenceError{MapID: v.mapID, Revision: rev, Reason: fmt.Sprintf(format, args...)}
+	}
+	if err := v.verifier.VerifySignedMapRoot(root); err != nil {
+		return fmt.Errorf("map %d revision %d: invalid signature: %v", v.mapID, rev, err)
+	}
+	if root.MapId != v.mapID || root.MapRevision != rev {
+		return fmt.Errorf("got root of map %d revision %d, want map %d revision %d", root.MapId, root.MapRevision, v.mapID, rev)
+	}
+
+	if meta := root.GetMetadata(); meta != nil && ptypes.Is(meta, &trillian.SourceLogRoot{}) {
+		var src trillian.SourceLogRoot
+		if err := ptypes.UnmarshalAny(meta, &src); err != nil {
+			return diverge("invalid SourceLogRoot metadata: %v", err)
+		}
+		switch size := v.cmt.Size(); {
+		case src.LogId != v.logID:
+			return diverge("built from log %d, want log %d", src.LogId, v.logID)
+		case src.TreeSize < size:
+			return diverge("built from log size %d, below the size %d of an earlier revision", src.TreeSize, size)
+		}
+		if err := v.replay(ctx, src.TreeSize); err != nil {
+			return err
+		}
+		if got := v.cmt.CurrentRoot(); !bytes.Equal(got, src.RootHash) {
+			return diverge("built from log root %x at size %d, but the log's root is %x", src.RootHash, src.TreeSize, got)
+		}
+	}
+
+	want, err := v.mapRoot()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(root.RootHash, want) {
+		return diverge("root hash %x, want %x from replaying %d log entries", root.RootHash, want, v.cmt.Size())
+	}
+	return nil
+}
+
+// replay applies the log entries up to size to the replayed map. The replay
+// state is consistent after each batch, so replay can resume after failing.
+func (v *Verifier) replay(ctx context.Context, size int64) error {
+	for v.cmt.Size() < size {
+		start := v.cmt.Size()
+		count := size - start
+		if count > v.opts.BatchSize {
+			count = v.opts.BatchSize
+		}
+		resp, err := v.logClient.GetLeavesByRange(ctx, &trillian.GetLeavesByRangeRequest{LogId: v.logID, StartIndex: start, Count: count})
+		if err != nil {
+			return err
+		}
+		leaves := resp.GetLeaves()
+		if len(leaves) == 0 || int64(len(leaves)) > count {
+			return fmt.Errorf("GetLeavesByRange(%d, %d): got %d leaves", start, count, len(leaves))
+		}
+		for i, l := range leaves {
+			if want := start + int64(i); l.LeafIndex != want {
+				return fmt.Errorf("GetLeavesByRange(%d, %d): got leaf %d at position %d, want leaf %d", start, count, l.LeafIndex, i, want)
+			}
+		}
+
+		mapLeaves, err := v.mapper.Map(ctx, leaves, func(index []byte) []byte { return v.values[string(index)] })
+		if err != nil {
+			return fmt.Errorf("mapping log entries [%d, %d): %v", start, start+int64(len(leaves)), err)
+		}
+		hashes := make(map[string][]byte)
+		for _, l := range mapLeaves {
+			if l.LeafValue == nil {
+				continue
+			}
+			if got, want := len(l.Index), v.mapHasher.Size(); got != want {
+				return fmt.Errorf("mapping log entries [%d, %d): map leaf index of %d bytes, want %d", start, start+int64(len(leaves)), got, want)
+			}
+			h, err := v.mapHasher.HashLeaf(v.mapID, l.Index, l.LeafValue)
+			if err != nil {
+				return fmt.Errorf("HashLeaf(): %v", err)
+			}
+			hashes[string(l.Index)] = h
+		}
+
+		logHashes := make([][]byte, 0, len(leaves))
+		for _, l := range leaves {
+			h, err := v.logHasher.HashLeaf(l.LeafValue)
+			if err != nil {
+				return fmt.Errorf("HashLeaf(): %v", err)
+			}
+			logHashes = append(logHashes, h)
+		}
+
+		// Only apply the batch once nothing can fail.
+		for _, h := range logHashes {
+			if _, err := v.cmt.AddLeafHash(h, func(int, int64, []byte) error { return nil }); err != nil {
+				return err
+			}
+		}
+		for _, l := range mapLeaves {
+			if h, ok := hashes[string(l.Index)]; ok {
+				v.values[string(l.Index)] = l.LeafValue
+				v.hashes[string(l.Index)] = h
+			}
+		}
+		v.rootHash = nil
+		replayedLogSize.Set(float64(v.cmt.Size()), v.label)
+	}
+	return nil
+}
+
+// mapRoot returns the root hash of the replayed map.
+func (v *Verifier) mapRoot() ([]byte, error) {
+	if v.rootHash != nil {
+		return v.rootHash, nil
+	}
+	leaves := make([]merkle.HStar2LeafHash, 0, len(v.hashes))
+	for index, h := range v.hashes {
+		leaves = append(leaves, merkle.HStar2LeafHash{Index: new(big.Int).SetBytes([]byte(index)), LeafHash: h})
+	}
+	hs2 := merkle.NewHStar2(v.mapID, v.mapHasher)
+	root, err := hs2.HStar2Root(v.mapHasher.BitLen(), leaves)
+	if err != nil {
+		return nil, err
+	}
+	v.rootHash = root
+	return root, nil
+}
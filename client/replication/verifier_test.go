@@ -0,0 +1,286 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replication
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/trillian"
+	"github.com/google/trillian/crypto/keys/pem"
+	"github.com/google/trillian/merkle"
+	"github.com/google/trillian/merkle/maphasher"
+	"github.com/google/trillian/merkle/rfc6962"
+	"github.com/google/trillian/storage/testonly"
+	"google.golang.org/grpc"
+
+	tcrypto "github.com/google/trillian/crypto"
+	ttestonly "github.com/google/trillian/testonly"
+)
+
+const (
+	testLogID = 1
+	testMapID = 2
+)
+
+// fakeLog serves leaves, at most 3 per request.
+type fakeLog struct {
+	trillian.TrillianLogClient
+	leaves []*trillian.LogLeaf
+	err    error
+}
+
+func (f *fakeLog) GetLeavesByRange(ctx context.Context, req *trillian.GetLeavesByRangeRequest, opts ...grpc.CallOption) (*trillian.GetLeavesByRangeResponse, error) {
+	if err := f.err; err != nil {
+		f.err = nil
+		return nil, err
+	}
+	end := req.StartIndex + req.Count
+	if end > req.StartIndex+3 {
+		end = req.StartIndex + 3
+	}
+	if end > int64(len(f.leaves)) {
+		end = int64(len(f.leaves))
+	}
+	return &trillian.GetLeavesByRangeResponse{Leaves: f.leaves[req.StartIndex:end]}, nil
+}
+
+// fakeMap serves the map roots, in revision order.
+type fakeMap struct {
+	trillian.TrillianMapClient
+	roots []*trillian.SignedMapRoot
+}
+
+func (f *fakeMap) GetSignedMapRoot(ctx context.Context, req *trillian.GetSignedMapRootRequest, opts ...grpc.CallOption) (*trillian.GetSignedMapRootResponse, error) {
+	return &trillian.GetSignedMapRootResponse{MapRoot: f.roots[len(f.roots)-1]}, nil
+}
+
+func (f *fakeMap) GetSignedMapRootByRevision(ctx context.Context, req *trillian.GetSignedMapRootByRevisionRequest, opts ...grpc.CallOption) (*trillian.GetSignedMapRootResponse, error) {
+	return &trillian.GetSignedMapRootResponse{MapRoot: f.roots[req.Revision]}, nil
+}
+
+// testMap builds the revisions of a map derived from a log with the identity
+// mapper.
+type testMap struct {
+	t      *testing.T
+	log    *fakeLog
+	m      *fakeMap
+	signer *tcrypto.Signer
+}
+
+func newTestMap(t *testing.T, logSize int) *testMap {
+	t.Helper()
+	key, err := pem.UnmarshalPrivateKey(ttestonly.DemoPrivateKey, ttestonly.DemoPrivateKeyPass)
+	if err != nil {
+		t.Fatalf("UnmarshalPrivateKey(): %v", err)
+	}
+	log := &fakeLog{}
+	for i := 0; i < logSize; i++ {
+		value := []byte(fmt.Sprintf("value %d", i%7))
+		id := sha256.Sum256([]byte(fmt.Sprintf("key %d", i%5)))
+		log.leaves = append(log.leaves, &trillian.LogLeaf{LeafIndex: int64(i), LeafValue: value, LeafIdentityHash: id[:]})
+	}
+	return &testMap{t: t, log: log, m: &fakeMap{}, signer: tcrypto.NewSHA256Signer(key)}
+}
+
+// logRoot returns the log's root at size.
+func (tm *testMap) logRoot(size int64) *trillian.SourceLogRoot {
+	cmt := merkle.NewCompactMerkleTree(rfc6962.DefaultHasher)
+	for _, l := range tm.log.leaves[:size] {
+		if _, _, err := cmt.AddLeaf(l.LeafValue, func(int, int64, []byte) error { return nil }); err != nil {
+			tm.t.Fatalf("AddLeaf(): %v", err)
+		}
+	}
+	return &trillian.SourceLogRoot{LogId: testLogID, TreeSize: size, RootHash: cmt.CurrentRoot()}
+}
+
+// mapRootHash returns the root hash of the map built from the first size
+// entries of the log.
+func (tm *testMap) mapRootHash(size int64) []byte {
+	hasher := maphasher.Default
+	values := make(map[string][]byte)
+	for _, l := range tm.log.leaves[:size] {
+		values[string(l.LeafIdentityHash)] = l.LeafValue
+	}
+	var leaves []merkle.HStar2LeafHash
+	for index, value := range values {
+		h, err := hasher.HashLeaf(testMapID, []byte(index), value)
+		if err != nil {
+			tm.t.Fatalf("HashLeaf(): %v", err)
+		}
+		leaves = append(leaves, merkle.HStar2LeafHash{Index: new(big.Int).SetBytes([]byte(index)), LeafHash: h})
+	}
+	hs2 := merkle.NewHStar2(testMapID, hasher)
+	root, err := hs2.HStar2Root(hasher.BitLen(), leaves)
+	if err != nil {
+		tm.t.Fatalf("HStar2Root(): %v", err)
+	}
+	return root
+}
+
+// publish adds a map revision with rootHash, built from src if not nil.
+func (tm *testMap) publish(rootHash []byte, src *trillian.SourceLogRoot) {
+	smr := &trillian.SignedMapRoot{MapId: testMapID, MapRevision: int64(len(tm.m.roots)), RootHash: rootHash}
+	if src != nil {
+		meta, err := ptypes.MarshalAny(src)
+		if err != nil {
+			tm.t.Fatalf("MarshalAny(): %v", err)
+		}
+		smr.Metadata = meta
+	}
+	sig, err := tm.signer.SignMapRoot(smr)
+	if err != nil {
+		tm.t.Fatalf("SignMapRoot(): %v", err)
+	}
+	smr.Signature = sig
+	tm.m.roots = append(tm.m.roots, smr)
+}
+
+// publishSize adds a correct map revision built from the log at size.
+func (tm *testMap) publishSize(size int64) {
+	tm.publish(tm.mapRootHash(size), tm.logRoot(size))
+}
+
+func (tm *testMap) verifier() *Verifier {
+	tm.t.Helper()
+	logTree := proto.Clone(testonly.LogTree).(*trillian.Tree)
+	logTree.TreeId = testLogID
+	mapTree := proto.Clone(testonly.MapTree).(*trillian.Tree)
+	mapTree.TreeId = testMapID
+	v, err := NewVerifier(tm.log, tm.m, logTree, mapTree, IdentityMapper, nil, VerifierOpts{BatchSize: 4})
+	if err != nil {
+		tm.t.Fatalf("NewVerifier(): %v", err)
+	}
+	return v
+}
+
+func TestVerifyLatest(t *testing.T) {
+	ctx := context.Background()
+	tm := newTestMap(t, 30)
+	// The empty map is initialised without a SourceLogRoot.
+	tm.publish(tm.mapRootHash(0), nil)
+	if got, want := tm.m.roots[0].RootHash, testonly.MapTreeEmptyRootHash; !bytes.Equal(got, want) {
+		t.Fatalf("empty map root = %x, want %x", got, want)
+	}
+	tm.publishSize(0)
+	tm.publishSize(3)
+	tm.publish(tm.mapRootHash(3), nil)
+	tm.publishSize(17)
+
+	v := tm.verifier()
+	rev, err := v.VerifyLatest(ctx)
+	if err != nil {
+		t.Fatalf("VerifyLatest(): %v", err)
+	}
+	if rev != 4 {
+		t.Errorf("VerifyLatest() = %d, want 4", rev)
+	}
+
+	// Failures to fetch the log are retried by the next call.
+	tm.publishSize(30)
+	errUnavailable := errors.New("unavailable")
+	tm.log.err = errUnavailable
+	if _, err := v.VerifyLatest(ctx); err != errUnavailable {
+		t.Errorf("VerifyLatest() with failing log: %v, want %v", err, errUnavailable)
+	}
+	if rev, err := v.VerifyLatest(ctx); err != nil || rev != 5 {
+		t.Errorf("VerifyLatest() = %d, %v, want 5, nil", rev, err)
+	}
+}
+
+func TestVerifyLatestDivergence(t *testing.T) {
+	ctx := context.Background()
+	for _, test := range []struct {
+		desc    string
+		publish func(tm *testMap)
+	}{
+		{
+			desc:    "wrongRootHash",
+			publish: func(tm *testMap) { tm.publish([]byte("not the root"), tm.logRoot(10)) },
+		},
+		{
+			desc:    "changedWithoutSource",
+			publish: func(tm *testMap) { tm.publish(tm.mapRootHash(10), nil) },
+		},
+		{
+			desc: "unknownLogRoot",
+			publish: func(tm *testMap) {
+				src := tm.logRoot(10)
+				src.RootHash = []byte("not the root")
+				tm.publish(tm.mapRootHash(10), src)
+			},
+		},
+		{
+			desc: "otherLog",
+			publish: func(tm *testMap) {
+				src := tm.logRoot(10)
+				src.LogId++
+				tm.publish(tm.mapRootHash(10), src)
+			},
+		},
+		{
+			desc:    "shrinkingLog",
+			publish: func(tm *testMap) { tm.publishSize(2) },
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			tm := newTestMap(t, 20)
+			tm.publishSize(0)
+			tm.publishSize(5)
+			test.publish(tm)
+			tm.publishSize(20)
+
+			v := tm.verifier()
+			for i := 0; i < 2; i++ {
+				rev, err := v.VerifyLatest(ctx)
+				d, ok := err.(*DivergenceError)
+				if !ok {
+					t.Fatalf("VerifyLatest(): %v, want DivergenceError", err)
+				}
+				if d.Revision != 2 {
+					t.Errorf("VerifyLatest(): divergence at revision %d, want 2", d.Revision)
+				}
+				if rev != 1 {
+					t.Errorf("VerifyLatest() = %d, want 1", rev)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyLatestBadSignature(t *testing.T) {
+	tm := newTestMap(t, 5)
+	tm.publishSize(0)
+	tm.m.roots[0].RootHash = tm.mapRootHash(5)
+
+	v := tm.verifier()
+	rev, err := v.VerifyLatest(context.Background())
+	if err == nil {
+		t.Fatal("VerifyLatest(): nil, want error")
+	}
+	if _, ok := err.(*DivergenceError); ok {
+		t.Errorf("VerifyLatest(): %v, want non-divergence error", err)
+	}
+	if rev != -1 {
+		t.Errorf("VerifyLatest() = %d, want -1", rev)
+	}
+}
@@ -0,0 +1,288 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package splitview checks that the replicas serving a log, e.g. in several
+// regions, show the same view of it: that their latest roots are consistent
+// with each other, and with the roots they served before.
+package splitview
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/google/trillian"
+	"github.com/google/trillian/client"
+	"github.com/google/trillian/monitoring"
+)
+
+const (
+	// DefaultInterval is the default interval between checks.
+	DefaultInterval = time.Minute
+	// DefaultTimeout is the default timeout of each check.
+	DefaultTimeout = 30 * time.Second
+
+	logIDLabel   = "logid"
+	replicaLabel = "replica"
+)
+
+var (
+	replicaTreeSize    monitoring.Gauge
+	diverged           monitoring.Gauge
+	checkErrors        monitoring.Counter
+	checkerMetricsOnce sync.Once
+)
+
+func initMetrics(mf monitoring.MetricFactory) {
+	checkerMetricsOnce.Do(func() {
+		if mf == nil {
+			mf = monitoring.InertMetricFactory{}
+		}
+		replicaTreeSize = mf.NewGauge("split_view_replica_tree_size", "Tree size of the latest root served by a replica of a log", logIDLabel, replicaLabel)
+		diverged = mf.NewGauge("split_view_diverged", "Set to 1 once replicas of a log have served inconsistent roots, 0 otherwise", logIDLabel)
+		checkErrors = mf.NewCounter("split_view_errors", "Number of requests to a replica of a log which failed during checks", logIDLabel, replicaLabel)
+	})
+}
+
+// Replica is a server of a log.
+type Replica struct {
+	// Name identifies the replica in logs and metrics, e.g. its address.
+	Name   string
+	Client trillian.TrillianLogClient
+}
+
+// DivergenceError reports roots of a log served by two replicas which aren't
+// consistent with each other. The replicas may be the same, if a replica
+// served a root inconsistent with one it served in an earlier check.
+type DivergenceError struct {
+	LogID    int64
+	Replicas [2]string
+	Roots    [2]*trillian.SignedLogRoot
+	Reason   string
+}
+
+func (e *DivergenceError) Error() string {
+	return fmt.Sprintf("log %d: split view: root of size %d from %s is inconsistent with root of size %d from %s: %s",
+		e.LogID, e.Roots[0].TreeSize, e.Replicas[0], e.Roots[1].TreeSize, e.Replicas[1], e.Reason)
+}
+
+// CheckerOpts configures a Checker.
+type CheckerOpts struct {
+	// Interval is the interval between checks in Run.
+	Interval time.Duration
+	// Timeout is the timeout of each check in Run.
+	Timeout time.Duration
+}
+
+// Checker fetches the latest root of a log from each of its replicas, and
+// checks that the roots are mutually consistent: that roots of the same size
+// have the same hash, and that consistency proofs exist between roots of
+// different sizes. The largest root seen by a check is kept and checked
+// against the roots of the next, so that a replica falling back to a
+// forked view is detected too.
+//
+// A replica merely serving an older root, e.g. because it lags behind, isn't
+// a divergence, but shows in the split_view_replica_tree_size metric. The
+// consistency proof between two roots is fetched from the replica which served
+// the larger one, so checks fail while that replica serves a smaller root
+// than the largest seen before, and no other replica caught up with it.
+type Checker struct {
+	logID    int64
+	verifier client.LogVerifier
+	replicas []Replica
+	opts     CheckerOpts
+	label    string
+
+	// latest is the largest root seen by the previous checks.
+	latest *observation
+	// divergence is set once replicas have served inconsistent roots.
+	divergence *DivergenceError
+}
+
+// observation is a root served by a replica.
+type observation struct {
+	replica *Replica
+	root    *trillian.SignedLogRoot
+}
+
+// NewChecker returns a Checker of the log tree, served by replicas.
+func NewChecker(tree *trillian.Tree, replicas []Replica, mf monitoring.MetricFactory, opts CheckerOpts) (*Checker, error) {
+	verifier, err := client.NewLogVerifierFromTree(tree)
+	if err != nil {
+		return nil, err
+	}
+	if len(replicas) == 0 {
+		return nil, fmt.Errorf("no replicas of log %d to check", tree.TreeId)
+	}
+	names := make(map[string]bool)
+	for _, r := range replicas {
+		if names[r.Name] {
+			return nil, fmt.Errorf("duplicate replica name %q", r.Name)
+		}
+		names[r.Name] = true
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	initMetrics(mf)
+
+	c := &Checker{
+		logID:    tree.TreeId,
+		verifier: verifier,
+		replicas: replicas,
+		opts:     opts,
+		label:    strconv.FormatInt(tree.TreeId, 10),
+	}
+	diverged.Set(0, c.label)
+	return c, nil
+}
+
+// Run checks the replicas every opts.Interval, until ctx is done. Divergence
+// is logged and reported by metrics, and stops later checks.
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.opts.Interval)
+	defer ticker.Stop()
+	for {
+		if c.divergence == nil {
+			cctx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+			if err := c.Check(cctx); err != nil {
+				if _, ok := err.(*DivergenceError); ok {
+					glog.Errorf("%v", err)
+				} else {
+					glog.Warningf("log %d: check failed: %v", c.logID, err)
+				}
+			}
+			cancel()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check fetches the latest root of each replica, and checks that they're
+// consistent with each other and with the largest root of earlier checks. It
+// returns a *DivergenceError if they aren't, including if an earlier call
+// found them not to be. Replicas which fail to respond are left out of the
+// check, which then returns an error.
+func (c *Checker) Check(ctx context.Context) error {
+	if c.divergence != nil {
+		return c.divergence
+	}
+
+	obs := make([]*observation, len(c.replicas))
+	errs := make([]error, len(c.replicas))
+	var wg sync.WaitGroup
+	for i := range c.replicas {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			obs[i], errs[i] = c.latestRoot(ctx, &c.replicas[i])
+		}(i)
+	}
+	wg.Wait()
+
+	var fetchErr error
+	roots := make([]*observation, 0, len(obs)+1)
+	for i, o := range obs {
+		if errs[i] != nil {
+			checkErrors.Inc(c.label, c.replicas[i].Name)
+			if fetchErr == nil {
+				fetchErr = errs[i]
+			}
+			continue
+		}
+		replicaTreeSize.Set(float64(o.root.TreeSize), c.label, o.replica.Name)
+		roots = append(roots, o)
+	}
+	if c.latest != nil {
+		roots = append(roots, c.latest)
+	}
+
+	// Consistency is transitive, so it's enough to check each root against
+	// the next larger one.
+	sort.SliceStable(roots, func(i, j int) bool { return roots[i].root.TreeSize < roots[j].root.TreeSize })
+	for i := 1; i < len(roots); i++ {
+		if err := c.checkConsistent(ctx, roots[i-1], roots[i]); err != nil {
+			if d, ok := err.(*DivergenceError); ok {
+				c.divergence = d
+				diverged.Set(1, c.label)
+			}
+			return err
+		}
+	}
+	if len(roots) > 0 {
+		c.latest = roots[len(roots)-1]
+	}
+	return fetchErr
+}
+
+// latestRoot returns the verified latest root of r.
+func (c *Checker) latestRoot(ctx context.Context, r *Replica) (*observation, error) {
+	resp, err := r.Client.GetLatestSignedLogRoot(ctx, &trillian.GetLatestSignedLogRootRequest{LogId: c.logID})
+	if err != nil {
+		return nil, fmt.Errorf("%s: GetLatestSignedLogRoot(): %v", r.Name, err)
+	}
+	root := resp.GetSignedLogRoot()
+	if err := c.verifier.VerifyRoot(&trillian.SignedLogRoot{}, root, nil); err != nil {
+		return nil, fmt.Errorf("%s: invalid latest root: %v", r.Name, err)
+	}
+	return &observation{replica: r, root: root}, nil
+}
+
+// checkConsistent checks that a and b, where b's tree is at least as large as
+// a's, are consistent. The consistency proof is fetched from b's replica.
+func (c *Checker) checkConsistent(ctx context.Context, a, b *observation) error {
+	diverge := func(format string, args ...interface{}) error {
+		return &DivergenceError{
+			LogID:    c.logID,
+			Replicas: [2]string{a.replica.Name, b.replica.Name},
+			Roots:    [2]*trillian.SignedLogRoot{a.root, b.root},
+			Reason:   fmt.Sprintf(format, args...),
+		}
+	}
+	switch {
+	case a.root.TreeSize == b.root.TreeSize:
+		if !bytes.Equal(a.root.RootHash, b.root.RootHash) {
+			return diverge("root hashes %x and %x differ", a.root.RootHash, b.root.RootHash)
+		}
+		return nil
+	case a.root.TreeSize == 0:
+		return nil
+	}
+
+	resp, err := b.replica.Client.GetConsistencyProof(ctx, &trillian.GetConsistencyProofRequest{
+		LogId:          c.logID,
+		FirstTreeSize:  a.root.TreeSize,
+		SecondTreeSize: b.root.TreeSize,
+	})
+	if err != nil {
+		checkErrors.Inc(c.label, b.replica.Name)
+		return fmt.Errorf("%s: GetConsistencyProof(%d, %d): %v", b.replica.Name, a.root.TreeSize, b.root.TreeSize, err)
+	}
+	if err := c.verifier.VerifyRoot(a.root, b.root, resp.GetProof().GetHashes()); err != nil {
+		return diverge("%v", err)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package splitview
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/client"
+	"github.com/google/trillian/testonly/integration"
+	"google.golang.org/grpc"
+
+	stestonly "github.com/google/trillian/storage/testonly"
+)
+
+// forkedReplica serves another log as if it were the log being checked. The
+// trees of the test logs share a key, so its roots verify.
+type forkedReplica struct {
+	trillian.TrillianLogClient
+	logID int64
+	err   error
+}
+
+func (f *forkedReplica) GetLatestSignedLogRoot(ctx context.Context, req *trillian.GetLatestSignedLogRootRequest, opts ...grpc.CallOption) (*trillian.GetLatestSignedLogRootResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.TrillianLogClient.GetLatestSignedLogRoot(ctx, &trillian.GetLatestSignedLogRootRequest{LogId: f.logID})
+}
+
+func (f *forkedReplica) GetConsistencyProof(ctx context.Context, req *trillian.GetConsistencyProofRequest, opts ...grpc.CallOption) (*trillian.GetConsistencyProofResponse, error) {
+	r := *req
+	r.LogId = f.logID
+	return f.TrillianLogClient.GetConsistencyProof(ctx, &r)
+}
+
+// newLog creates a log in env with leaves.
+func newLog(ctx context.Context, t *testing.T, env *integration.LogEnv, leaves ...string) *trillian.Tree {
+	t.Helper()
+	tree, err := client.CreateAndInitTree(ctx, &trillian.CreateTreeRequest{Tree: stestonly.LogTree}, env.Admin, nil, env.Log)
+	if err != nil {
+		t.Fatalf("CreateAndInitTree(): %v", err)
+	}
+	addLeaves(ctx, t, env, tree, leaves...)
+	return tree
+}
+
+// addLeaves adds leaves to the log tree, one at a time.
+func addLeaves(ctx context.Context, t *testing.T, env *integration.LogEnv, tree *trillian.Tree, leaves ...string) {
+	t.Helper()
+	c, err := client.NewFromTree(env.Log, tree)
+	if err != nil {
+		t.Fatalf("NewFromTree(): %v", err)
+	}
+	for _, l := range leaves {
+		if err := c.QueueLeaf(ctx, []byte(l)); err != nil {
+			t.Fatalf("QueueLeaf(): %v", err)
+		}
+		env.Sequencer.OperationSingle(ctx)
+		if err := c.WaitForInclusion(ctx, []byte(l)); err != nil {
+			t.Fatalf("WaitForInclusion(): %v", err)
+		}
+	}
+}
+
+func TestNewChecker(t *testing.T) {
+	replica := Replica{Name: "a"}
+	for _, test := range []struct {
+		desc     string
+		tree     *trillian.Tree
+		replicas []Replica
+	}{
+		{desc: "map", tree: stestonly.MapTree, replicas: []Replica{replica}},
+		{desc: "noReplicas", tree: stestonly.LogTree},
+		{desc: "duplicateName", tree: stestonly.LogTree, replicas: []Replica{replica, replica}},
+	} {
+		if _, err := NewChecker(test.tree, test.replicas, nil, CheckerOpts{}); err == nil {
+			t.Errorf("%v: NewChecker(): got nil error, want non-nil", test.desc)
+		}
+	}
+}
+
+func TestChecker(t *testing.T) {
+	ctx := context.Background()
+	env, err := integration.NewLogEnv(ctx, 1, "unused")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer env.Close()
+
+	tree := newLog(ctx, t, env, "A", "B", "C")
+	// A replica lagging behind the log.
+	stale := newLog(ctx, t, env, "A", "B")
+	// A replica showing a fork of the log at its size.
+	forkSameSize := newLog(ctx, t, env, "A", "B", "X")
+	// A replica showing a larger fork of the log.
+	forkLarger := newLog(ctx, t, env, "A", "X", "C", "D")
+
+	replica := func(name string, fork *trillian.Tree) Replica {
+		if fork == nil {
+			return Replica{Name: name, Client: env.Log}
+		}
+		return Replica{Name: name, Client: &forkedReplica{TrillianLogClient: env.Log, logID: fork.TreeId}}
+	}
+
+	for _, test := range []struct {
+		desc         string
+		replicas     []Replica
+		wantDiverged bool
+		wantErr      bool
+	}{
+		{desc: "consistent", replicas: []Replica{replica("a", nil), replica("b", nil)}},
+		{desc: "stale", replicas: []Replica{replica("a", nil), replica("b", stale)}},
+		{desc: "forkSameSize", replicas: []Replica{replica("a", nil), replica("b", forkSameSize)}, wantDiverged: true},
+		{desc: "forkLarger", replicas: []Replica{replica("a", stale), replica("b", forkLarger)}, wantDiverged: true},
+		{
+			desc:     "unavailable",
+			replicas: []Replica{replica("a", nil), {Name: "b", Client: &forkedReplica{TrillianLogClient: env.Log, err: errors.New("unavailable")}}},
+			wantErr:  true,
+		},
+	} {
+		c, err := NewChecker(tree, test.replicas, nil, CheckerOpts{})
+		if err != nil {
+			t.Fatalf("%v: NewChecker(): %v", test.desc, err)
+		}
+		err = c.Check(ctx)
+		_, isDivergence := err.(*DivergenceError)
+		if isDivergence != test.wantDiverged || (err != nil) != (test.wantErr || test.wantDiverged) {
+			t.Errorf("%v: Check(): %v, want divergence: %v, error: %v", test.desc, err, test.wantDiverged, test.wantErr)
+		}
+		// Divergence is sticky.
+		if isDivergence {
+			if err2 := c.Check(ctx); err2 != err {
+				t.Errorf("%v: second Check(): %v, want %v", test.desc, err2, err)
+			}
+		}
+	}
+}
+
+func TestCheckerRemembersLatestRoot(t *testing.T) {
+	ctx := context.Background()
+	env, err := integration.NewLogEnv(ctx, 1, "unused")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer env.Close()
+
+	tree := newLog(ctx, t, env, "A", "B", "C")
+	fork := newLog(ctx, t, env, "A", "X", "C", "D", "E")
+	r := &forkedReplica{TrillianLogClient: env.Log, logID: tree.TreeId}
+	c, err := NewChecker(tree, []Replica{{Name: "a", Client: r}}, nil, CheckerOpts{})
+	if err != nil {
+		t.Fatalf("NewChecker(): %v", err)
+	}
+
+	addLeaves(ctx, t, env, tree, "D")
+	if err := c.Check(ctx); err != nil {
+		t.Fatalf("Check(): %v", err)
+	}
+	// The replica switches to a forked view of the log.
+	r.logID = fork.TreeId
+	err = c.Check(ctx)
+	d, ok := err.(*DivergenceError)
+	if !ok {
+		t.Fatalf("Check() after fork: %v, want a *DivergenceError", err)
+	}
+	if got, want := d.Roots[0].TreeSize, int64(4); got != want {
+		t.Errorf("DivergenceError.Roots[0].TreeSize = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,282 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/golang/glog"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/duration"
+	"github.com/google/trillian"
+	"github.com/google/trillian/crypto/keyspb"
+	"github.com/google/trillian/quota/etcd/quotapb"
+	"google.golang.org/genproto/protobuf/field_mask"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// OwnerLabel is the label of the trees managed by a TreeReconciler which
+// identifies their owner, see TreeSpec.Owner.
+const OwnerLabel = "trillian-owner"
+
+// quotaKinds are the kinds of tree quotas managed by a TreeReconciler.
+var quotaKinds = []string{"read", "write"}
+
+// TreeSpec declares the state of a tree, e.g. as held by a Tree resource of a
+// Kubernetes operator.
+type TreeSpec struct {
+	// Tree holds the properties of the tree. Its TreeState is the state the
+	// tree should be in, ACTIVE or FROZEN. Its TreeId, Deleted flag and
+	// timestamps are ignored.
+	Tree *trillian.Tree
+	// KeySpec specifies the private key generated for the tree when it's
+	// created, if Tree has no PrivateKey.
+	KeySpec *keyspb.Specification
+	// Owner identifies the owner of the tree, e.g. the resource which declares
+	// it. If set, it's recorded in the tree's OwnerLabel, and a tree which
+	// already has it is adopted rather than another one created, so that the
+	// creation of a tree whose ID was lost can be retried.
+	Owner string
+	// Deleted is whether the tree should be soft-deleted.
+	Deleted bool
+	// Quotas holds the quota configs of the tree by kind, "read" or "write".
+	// The quotas of kinds which aren't set are removed.
+	Quotas map[string]*quotapb.Config
+}
+
+// TreeReconciler brings trees to the state declared by TreeSpecs, creating,
+// updating, freezing, deleting and undeleting them, and managing their quotas.
+// Reconciling a tree which is already in its declared state doesn't change
+// it, so specs can be reconciled repeatedly.
+type TreeReconciler struct {
+	Admin trillian.TrillianAdminClient
+	// Log and Map initialise the logs and maps created.
+	Log trillian.TrillianLogClient
+	Map trillian.TrillianMapClient
+	// Quota manages the quotas of the trees if set. Otherwise, the quotas of
+	// TreeSpecs are ignored.
+	Quota quotapb.QuotaClient
+}
+
+// Reconcile brings the tree treeID to spec, creating the tree first if treeID
+// is zero, and returns it. It returns nil if spec is deleted and there's no
+// tree. If it fails after creating the tree, the tree is returned along with
+// the error, so that its ID can be recorded.
+func (r *TreeReconciler) Reconcile(ctx context.Context, treeID int64, spec *TreeSpec) (*trillian.Tree, error) {
+	if spec.Tree == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "tree spec has no tree")
+	}
+	var tree *trillian.Tree
+	var err error
+	if treeID == 0 {
+		if tree, err = r.adopt(ctx, spec); err != nil {
+			return nil, err
+		}
+	} else if tree, err = r.Admin.GetTree(ctx, &trillian.GetTreeRequest{TreeId: treeID}); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case tree == nil && spec.Deleted:
+		return nil, nil
+	case tree == nil:
+		if tree, err = r.create(ctx, spec); err != nil {
+			return nil, err
+		}
+	case spec.Deleted && !tree.Deleted:
+		glog.Infof("Deleting tree %v", tree.TreeId)
+		return r.Admin.DeleteTree(ctx, &trillian.DeleteTreeRequest{TreeId: tree.TreeId})
+	case spec.Deleted:
+		return tree, nil
+	case tree.Deleted:
+		glog.Infof("Undeleting tree %v", tree.TreeId)
+		if tree, err = r.Admin.UndeleteTree(ctx, &trillian.UndeleteTreeRequest{TreeId: tree.TreeId}); err != nil {
+			return nil, err
+		}
+	}
+
+	updated, err := r.update(ctx, tree, spec)
+	if err != nil {
+		return tree, err
+	}
+	if r.Quota != nil {
+		if err := r.reconcileQuotas(ctx, updated.TreeId, spec.Quotas); err != nil {
+			return updated, err
+		}
+	}
+	return updated, nil
+}
+
+// adopt returns the tree labelled with the owner of spec, or nil if there's
+// none, or spec has no owner.
+func (r *TreeReconciler) adopt(ctx context.Context, spec *TreeSpec) (*trillian.Tree, error) {
+	if spec.Owner == "" {
+		return nil, nil
+	}
+	resp, err := r.Admin.ListTrees(ctx, &trillian.ListTreesRequest{
+		ShowDeleted:   true,
+		LabelSelector: fmt.Sprintf("%s=%s", OwnerLabel, spec.Owner),
+	})
+	if err != nil {
+		return nil, err
+	}
+	switch len(resp.Tree) {
+	case 0:
+		return nil, nil
+	case 1:
+		glog.Infof("Adopting tree %v of %v", resp.Tree[0].TreeId, spec.Owner)
+		return resp.Tree[0], nil
+	default:
+		return nil, status.Errorf(codes.FailedPrecondition, "%d trees are owned by %v", len(resp.Tree), spec.Owner)
+	}
+}
+
+// create creates and initialises the tree of spec, in the ACTIVE state.
+func (r *TreeReconciler) create(ctx context.Context, spec *TreeSpec) (*trillian.Tree, error) {
+	switch tt := spec.Tree.TreeType; {
+	case tt == trillian.TreeType_MAP && r.Map == nil, tt != trillian.TreeType_MAP && r.Log == nil:
+		return nil, status.Errorf(codes.FailedPrecondition, "can't initialise a %v without its server", tt)
+	}
+	tree := proto.Clone(spec.Tree).(*trillian.Tree)
+	tree.TreeId = 0
+	tree.TreeState = trillian.TreeState_ACTIVE
+	if spec.Owner != "" {
+		if tree.Labels == nil {
+			tree.Labels = make(map[string]string)
+		}
+		tree.Labels[OwnerLabel] = spec.Owner
+	}
+	created, err := CreateAndInitTree(ctx, &trillian.CreateTreeRequest{Tree: tree, KeySpec: spec.KeySpec}, r.Admin, r.Map, r.Log)
+	if err != nil {
+		return nil, err
+	}
+	glog.Infof("Created tree %v", created.TreeId)
+	return created, nil
+}
+
+// update updates the mutable fields of tree which differ from spec, and
+// returns the updated tree. It returns an error if any of the immutable fields
+// differ.
+func (r *TreeReconciler) update(ctx context.Context, tree *trillian.Tree, spec *TreeSpec) (*trillian.Tree, error) {
+	want := spec.Tree
+	switch {
+	case want.TreeType != tree.TreeType:
+		return nil, status.Errorf(codes.FailedPrecondition, "tree %v has type %v, can't change it to %v", tree.TreeId, tree.TreeType, want.TreeType)
+	case want.HashStrategy != tree.HashStrategy:
+		return nil, status.Errorf(codes.FailedPrecondition, "tree %v has hash strategy %v, can't change it to %v", tree.TreeId, tree.HashStrategy, want.HashStrategy)
+	case want.HashAlgorithm != tree.HashAlgorithm:
+		return nil, status.Errorf(codes.FailedPrecondition, "tree %v has hash algorithm %v, can't change it to %v", tree.TreeId, tree.HashAlgorithm, want.HashAlgorithm)
+	case want.SignatureAlgorithm != tree.SignatureAlgorithm:
+		return nil, status.Errorf(codes.FailedPrecondition, "tree %v has signature algorithm %v, can't change it to %v", tree.TreeId, tree.SignatureAlgorithm, want.SignatureAlgorithm)
+	}
+
+	labels := make(map[string]string)
+	for k, v := range want.Labels {
+		labels[k] = v
+	}
+	if owner, ok := tree.Labels[OwnerLabel]; ok {
+		labels[OwnerLabel] = owner
+	}
+
+	update := &trillian.Tree{TreeId: tree.TreeId}
+	var paths []string
+	if want.TreeState != tree.TreeState {
+		update.TreeState = want.TreeState
+		paths = append(paths, "tree_state")
+	}
+	if want.DisplayName != tree.DisplayName {
+		update.DisplayName = want.DisplayName
+		paths = append(paths, "display_name")
+	}
+	if want.Description != tree.Description {
+		update.Description = want.Description
+		paths = append(paths, "description")
+	}
+	if !durationsEqual(want.MaxRootDuration, tree.MaxRootDuration) {
+		update.MaxRootDuration = want.MaxRootDuration
+		paths = append(paths, "max_root_duration")
+	}
+	if (len(labels) > 0 || len(tree.Labels) > 0) && !reflect.DeepEqual(labels, tree.Labels) {
+		update.Labels = labels
+		paths = append(paths, "labels")
+	}
+	if len(paths) == 0 {
+		return tree, nil
+	}
+	glog.Infof("Updating %v of tree %v", paths, tree.TreeId)
+	return r.Admin.UpdateTree(ctx, &trillian.UpdateTreeRequest{Tree: update, UpdateMask: &field_mask.FieldMask{Paths: paths}})
+}
+
+// reconcileQuotas creates, updates and removes the quota configs of treeID so
+// that they match quotas.
+func (r *TreeReconciler) reconcileQuotas(ctx context.Context, treeID int64, quotas map[string]*quotapb.Config) error {
+	for kind := range quotas {
+		if kind != "read" && kind != "write" {
+			return status.Errorf(codes.InvalidArgument, "invalid quota kind %q, want one of %v", kind, quotaKinds)
+		}
+	}
+	for _, kind := range quotaKinds {
+		name := fmt.Sprintf("quotas/trees/%d/%s/config", treeID, kind)
+		want := quotas[kind]
+		got, err := r.Quota.GetConfig(ctx, &quotapb.GetConfigRequest{Name: name})
+		notFound := status.Code(err) == codes.NotFound
+		if err != nil && !notFound {
+			return err
+		}
+
+		switch {
+		case want == nil && notFound:
+			continue
+		case want == nil:
+			glog.Infof("Deleting quota %v", name)
+			_, err = r.Quota.DeleteConfig(ctx, &quotapb.DeleteConfigRequest{Name: name})
+		case notFound:
+			glog.Infof("Creating quota %v", name)
+			_, err = r.Quota.CreateConfig(ctx, &quotapb.CreateConfigRequest{Name: name, Config: want})
+		case !quotaConfigsEqual(got, want):
+			glog.Infof("Updating quota %v", name)
+			paths := []string{"state", "max_tokens"}
+			if want.GetSequencingBased() != nil {
+				paths = append(paths, "sequencing_based")
+			} else {
+				paths = append(paths, "time_based")
+			}
+			_, err = r.Quota.UpdateConfig(ctx, &quotapb.UpdateConfigRequest{Name: name, Config: want, UpdateMask: &field_mask.FieldMask{Paths: paths}})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// quotaConfigsEqual returns whether the settings of the quota configs a and b
+// are equal, regardless of their names and current numbers of tokens.
+func quotaConfigsEqual(a, b *quotapb.Config) bool {
+	a = proto.Clone(a).(*quotapb.Config)
+	b = proto.Clone(b).(*quotapb.Config)
+	a.Name, b.Name = "", ""
+	a.CurrentTokens, b.CurrentTokens = 0, 0
+	return proto.Equal(a, b)
+}
+
+// durationsEqual returns whether a and b are equal, with unset durations equal
+// to zero.
+func durationsEqual(a, b *duration.Duration) bool {
+	return a.GetSeconds() == b.GetSeconds() && a.GetNanos() == b.GetNanos()
+}
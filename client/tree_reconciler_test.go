@@ -0,0 +1,105 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian"
+	"github.com/google/trillian/testonly/integration"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	stestonly "github.com/google/trillian/storage/testonly"
+)
+
+func TestTreeReconciler(t *testing.T) {
+	ctx := context.Background()
+	env, err := integration.NewLogEnv(ctx, 0, "unused")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer env.Close()
+	r := &TreeReconciler{Admin: env.Admin, Log: env.Log}
+
+	spec := &TreeSpec{Tree: proto.Clone(stestonly.LogTree).(*trillian.Tree), Owner: "a8b3c1d2"}
+	tree, err := r.Reconcile(ctx, 0, spec)
+	if err != nil {
+		t.Fatalf("Reconcile() of a new tree: %v", err)
+	}
+	if got, want := tree.Labels[OwnerLabel], spec.Owner; got != want {
+		t.Errorf("Reconcile() created a tree with owner %q, want %q", got, want)
+	}
+	treeID := tree.TreeId
+
+	// A tree whose ID was lost is adopted, and reconciling an unchanged spec
+	// leaves the tree alone.
+	adopted, err := r.Reconcile(ctx, 0, spec)
+	if err != nil {
+		t.Fatalf("Reconcile() of an owned tree: %v", err)
+	}
+	if adopted.TreeId != treeID {
+		t.Errorf("Reconcile() of an owned tree returned tree %v, want %v", adopted.TreeId, treeID)
+	}
+	if !proto.Equal(adopted.UpdateTime, tree.UpdateTime) {
+		t.Errorf("Reconcile() of an unchanged spec updated the tree at %v", adopted.UpdateTime)
+	}
+
+	spec.Tree.DisplayName = "Frozen Llamas"
+	spec.Tree.TreeState = trillian.TreeState_FROZEN
+	spec.Tree.Labels = map[string]string{"env": "test"}
+	tree, err = r.Reconcile(ctx, treeID, spec)
+	if err != nil {
+		t.Fatalf("Reconcile() of an updated spec: %v", err)
+	}
+	if tree.DisplayName != spec.Tree.DisplayName || tree.TreeState != trillian.TreeState_FROZEN {
+		t.Errorf("Reconcile() of an updated spec: tree %q in state %v, want %q in state FROZEN", tree.DisplayName, tree.TreeState, spec.Tree.DisplayName)
+	}
+	if got := tree.Labels; len(got) != 2 || got["env"] != "test" || got[OwnerLabel] != spec.Owner {
+		t.Errorf("Reconcile() of an updated spec: labels %v, want env and %v", got, OwnerLabel)
+	}
+
+	immutable := *spec
+	immutable.Tree = proto.Clone(spec.Tree).(*trillian.Tree)
+	immutable.Tree.TreeType = trillian.TreeType_PREORDERED_LOG
+	if _, err := r.Reconcile(ctx, treeID, &immutable); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Reconcile() changing the tree type: %v, want %v", err, codes.FailedPrecondition)
+	}
+
+	spec.Deleted = true
+	for i := 0; i < 2; i++ {
+		tree, err = r.Reconcile(ctx, treeID, spec)
+		if err != nil {
+			t.Fatalf("Reconcile() of a deleted spec: %v", err)
+		}
+		if !tree.Deleted {
+			t.Errorf("Reconcile() of a deleted spec: tree not deleted")
+		}
+	}
+
+	spec.Deleted = false
+	if tree, err = r.Reconcile(ctx, treeID, spec); err != nil {
+		t.Fatalf("Reconcile() of an undeleted spec: %v", err)
+	}
+	if tree.Deleted {
+		t.Errorf("Reconcile() of an undeleted spec: tree still deleted")
+	}
+
+	if tree, err := r.Reconcile(ctx, 0, &TreeSpec{Tree: stestonly.LogTree, Deleted: true}); err != nil || tree != nil {
+		t.Errorf("Reconcile() of a deleted spec without a tree: %v, %v, want nil, nil", tree, err)
+	}
+}
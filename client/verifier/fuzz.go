@@ -0,0 +1,99 @@
+// +build gofuzz
+
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verifier
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+
+	"github.com/google/trillian/crypto/sigpb"
+)
+
+// fuzzPublicKeys are the DER-encoded ECDSA P-256, RSA 2048 and Ed25519 keys
+// which FuzzSignature verifies signatures with.
+var fuzzPublicKeys = []string{
+	"MFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAEC78/dcW8568qPzfEIea/I+i9L3XUrPnwGYYlWBVO+7zP1R2k7QxW3HPddUm7dNYhGTWH23Op9Mv8OFaaNEgYUg==",
+	"MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEAs5d7eTsiWUPH4bYQ9uMESC1CPwet/3Z2zErRyScBb8/IqhOZlD7sQROqIWefRun0zxh/sHKBG8ew9b1AJBMrEQr9VcyLoHaxt0lK45KLNjMp7DcIx7203VshEPmKsKG/JIYZH4ftkVtFwaI04pmmC9SBfcT0VslqkNCRGXfzdAONV9BD/r3ykEO98YJr6d+ES2KCOlcONLFsAX4+cFrA8AWyvo3+NF/hxLvPOl8+4DniyWvYCYXgqlVSQaTmIHIQcxiSoU2/EFVNjgeDyapx6R6f57qpoIJRkYJCdMoruHQshOTRa7iEyzYG1Ax17Eetl6Zngr5hzAqE6wIZlAmQUwIDAQAB",
+	"MCowBQYDK2VwAyEAj66LBtrdtSNU6faya3+N2szC0K+7VPgqT+4zf/m63X0=",
+}
+
+// FuzzProofs is a go-fuzz entry point for the verification of inclusion and
+// consistency proofs, which clients run on proofs served by untrusted logs.
+// data holds two tree sizes and a leaf index, followed by the hashes of the
+// proof, the first two of which are used as the roots or the leaf hash.
+//
+// Build with go-fuzz-build -tags gofuzz -func FuzzProofs.
+func FuzzProofs(data []byte) int {
+	if len(data) < 24+2*sha256.Size {
+		return -1
+	}
+	size1 := int64(binary.BigEndian.Uint64(data))
+	size2 := int64(binary.BigEndian.Uint64(data[8:]))
+	index := int64(binary.BigEndian.Uint64(data[16:]))
+	var hashes [][]byte
+	for rest := data[24:]; len(rest) > 0; {
+		n := sha256.Size
+		if len(rest) < n {
+			n = len(rest)
+		}
+		hashes = append(hashes, rest[:n])
+		rest = rest[n:]
+	}
+
+	v := NewProofVerifier(NewRFC6962Hasher(crypto.SHA256))
+	ret := 0
+	if err := v.VerifyInclusionProof(index, size1, hashes[2:], hashes[0], hashes[1]); err == nil {
+		ret = 1
+	}
+	if err := v.VerifyConsistencyProof(size1, size2, hashes[0], hashes[1], hashes[2:]); err == nil {
+		ret = 1
+	}
+	return ret
+}
+
+// FuzzSignature is a go-fuzz entry point for VerifySignature, which clients
+// run on signatures served by untrusted logs and maps. The first byte of data
+// selects one of fuzzPublicKeys, the second the hash algorithm, and the rest is
+// the signature of the message "fuzz".
+//
+// Build with go-fuzz-build -tags gofuzz -func FuzzSignature.
+func FuzzSignature(data []byte) int {
+	if len(data) < 2 {
+		return -1
+	}
+	der, err := base64.StdEncoding.DecodeString(fuzzPublicKeys[int(data[0])%len(fuzzPublicKeys)])
+	if err != nil {
+		panic(err)
+	}
+	pub, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		panic(err)
+	}
+	sig := &sigpb.DigitallySigned{
+		SignatureAlgorithm: signatureAlgorithm(pub),
+		HashAlgorithm:      sigpb.DigitallySigned_HashAlgorithm(data[1]),
+		Signature:          data[2:],
+	}
+	if err := VerifySignature(pub, []byte("fuzz"), sig); err != nil {
+		return 0
+	}
+	// No valid signature is known for any of the keys.
+	panic("forged signature verified")
+}
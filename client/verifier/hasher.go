@@ -0,0 +1,45 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verifier
+
+import (
+	"crypto"
+
+	"github.com/google/trillian/merkle/logverifier"
+	"github.com/google/trillian/merkle/rfc6962/hasher"
+)
+
+// LogHasher provides the hash functions needed to compute dense merkle trees.
+// It has the same methods as hashers.LogHasher, so the hashers of either
+// package can be used with the other.
+type LogHasher = logverifier.LogHasher
+
+// Domain separation prefixes of RFC6962 hashes.
+const (
+	RFC6962LeafHashPrefix = hasher.RFC6962LeafHashPrefix
+	RFC6962NodeHashPrefix = hasher.RFC6962NodeHashPrefix
+)
+
+// DefaultRFC6962Hasher is a SHA256 based RFC6962Hasher, i.e. the hasher of the
+// RFC6962_SHA256 hash strategy.
+var DefaultRFC6962Hasher = NewRFC6962Hasher(crypto.SHA256)
+
+// RFC6962Hasher implements the RFC6962 tree hashing algorithm.
+type RFC6962Hasher = hasher.Hasher
+
+// NewRFC6962Hasher creates a new RFC6962Hasher on the passed in hash function.
+func NewRFC6962Hasher(h crypto.Hash) *RFC6962Hasher {
+	return hasher.New(h)
+}
@@ -0,0 +1,66 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verifier
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+
+	"github.com/benlaurie/objecthash/go/objecthash"
+)
+
+// Constants used as map keys when building input for ObjectHash. They must not be changed
+// as this will change the output of HashLogRoot.
+const (
+	mapKeyRootHash       string = "RootHash"
+	mapKeyTimestampNanos string = "TimestampNanos"
+	mapKeyTreeSize       string = "TreeSize"
+	mapKeyMetadata       string = "Metadata"
+)
+
+// LogRoot holds the signed fields of a log's SignedLogRoot.
+type LogRoot struct {
+	TreeSize       int64
+	RootHash       []byte
+	TimestampNanos int64
+	// Metadata is the serialized google.protobuf.Any metadata of the root, if
+	// any.
+	Metadata []byte
+}
+
+// HashLogRoot hashes the fields of a log root using ObjectHash with
+// "RootHash", "TimestampNanos", and "TreeSize", used as keys in a map. This is
+// the data signed by the log. Roots with metadata have a "Metadata" key too,
+// so the hashes of roots without any are unchanged.
+func HashLogRoot(root LogRoot) ([]byte, error) {
+	// Pull out the fields we want to hash.
+	// Caution: use string format for int64 values as they can overflow when
+	// JSON encoded otherwise (it uses floats). We want to be sure that people
+	// using JSON to verify hashes can build the exact same input to ObjectHash.
+	rootMap := map[string]interface{}{
+		mapKeyRootHash:       base64.StdEncoding.EncodeToString(root.RootHash),
+		mapKeyTimestampNanos: strconv.FormatInt(root.TimestampNanos, 10),
+		mapKeyTreeSize:       strconv.FormatInt(root.TreeSize, 10)}
+	if len(root.Metadata) > 0 {
+		rootMap[mapKeyMetadata] = base64.StdEncoding.EncodeToString(root.Metadata)
+	}
+
+	hash, err := objecthash.ObjectHash(rootMap)
+	if err != nil {
+		return nil, fmt.Errorf("ObjectHash(%#v): %v", rootMap, err)
+	}
+	return hash[:], nil
+}
@@ -0,0 +1,162 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verifier
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/google/trillian/crypto/sigpb"
+)
+
+var (
+	errVerify = errors.New("signature verification failed")
+
+	cryptoHashLookup = map[sigpb.DigitallySigned_HashAlgorithm]crypto.Hash{
+		sigpb.DigitallySigned_SHA256: crypto.SHA256,
+	}
+)
+
+// VerifySignature cryptographically verifies that sig is pub's signature of
+// data.
+//
+// Signatures come from untrusted servers, so they are checked strictly before
+// any math is done on them: ECDSA signatures must be canonical DER with no
+// trailing bytes, and RSA and Ed25519 signatures must be exactly as long as
+// the key's signatures. Everything verified here is public, so failures may
+// return early; the public key operations are those of the standard library.
+func VerifySignature(pub crypto.PublicKey, data []byte, sig *sigpb.DigitallySigned) error {
+	if sig == nil {
+		return errors.New("signature is nil")
+	}
+
+	if got, want := sig.SignatureAlgorithm, signatureAlgorithm(pub); got != want {
+		return fmt.Errorf("signature algorithm does not match public key, got:%v, want:%v", got, want)
+	}
+
+	// Ed25519 signs the data itself rather than a digest.
+	if pub, ok := pub.(ed25519.PublicKey); ok {
+		if sig.HashAlgorithm != sigpb.DigitallySigned_NONE {
+			return fmt.Errorf("unsupported hash algorithm %v for %v signature", sig.HashAlgorithm, sig.SignatureAlgorithm)
+		}
+		if len(pub) != ed25519.PublicKeySize || len(sig.Signature) != ed25519.SignatureSize {
+			return errVerify
+		}
+		if !ed25519.Verify(pub, data, sig.Signature) {
+			return errVerify
+		}
+		return nil
+	}
+
+	// Recompute digest
+	hasher, ok := cryptoHashLookup[sig.HashAlgorithm]
+	if !ok || !hasher.Available() {
+		return fmt.Errorf("unsupported hash algorithm %v", sig.HashAlgorithm)
+	}
+	h := hasher.New()
+	h.Write(data)
+	digest := h.Sum(nil)
+	if len(digest) != hasher.Size() {
+		return fmt.Errorf("%v digest has %d bytes, want %d", sig.HashAlgorithm, len(digest), hasher.Size())
+	}
+
+	switch pub := pub.(type) {
+	case *ecdsa.PublicKey:
+		return verifyECDSA(pub, digest, sig.Signature)
+	case *rsa.PublicKey:
+		return verifyRSA(pub, digest, sig.Signature, hasher, hasher)
+	default:
+		return fmt.Errorf("unknown private key type: %T", pub)
+	}
+}
+
+// signatureAlgorithm returns the algorithm used for this public key.
+func signatureAlgorithm(k crypto.PublicKey) sigpb.DigitallySigned_SignatureAlgorithm {
+	switch k.(type) {
+	case *ecdsa.PublicKey:
+		return sigpb.DigitallySigned_ECDSA
+	case *rsa.PublicKey:
+		return sigpb.DigitallySigned_RSA
+	case ed25519.PublicKey:
+		return sigpb.DigitallySigned_ED25519
+	}
+
+	return sigpb.DigitallySigned_ANONYMOUS
+}
+
+func verifyRSA(pub *rsa.PublicKey, hashed, sig []byte, hasher crypto.Hash, opts crypto.SignerOpts) error {
+	if len(sig) != pub.Size() {
+		return errVerify
+	}
+	if pssOpts, ok := opts.(*rsa.PSSOptions); ok {
+		return rsa.VerifyPSS(pub, hasher, hashed, sig, pssOpts)
+	}
+	return rsa.VerifyPKCS1v15(pub, hasher, hashed, sig)
+}
+
+func verifyECDSA(pub *ecdsa.PublicKey, hashed, sig []byte) error {
+	r, s, err := parseECDSASignature(pub, sig)
+	if err != nil {
+		return errVerify
+	}
+	if !ecdsa.Verify(pub, hashed, r, s) {
+		return errVerify
+	}
+	return nil
+}
+
+// ecdsaSignature is the ASN.1 ECDSA-Sig-Value of RFC 3279.
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+// parseECDSASignature parses sig as the DER encoding of an ECDSA-Sig-Value
+// whose integers are in [1, N-1] for the curve of pub. Only the canonical
+// encoding is accepted, so a signature can't be malleated into another valid
+// one by e.g. padding its integers, using long-form lengths or appending data.
+func parseECDSASignature(pub *ecdsa.PublicKey, sig []byte) (r, s *big.Int, err error) {
+	// Two integers of the size of the curve order, each with a tag, a length
+	// of up to 3 bytes and a leading zero, in a SEQUENCE with the same header.
+	orderLen := (pub.Curve.Params().N.BitLen() + 7) / 8
+	if maxLen := 2*(orderLen+5) + 4; len(sig) > maxLen {
+		return nil, nil, fmt.Errorf("signature has %d bytes, more than %d", len(sig), maxLen)
+	}
+
+	var v ecdsaSignature
+	rest, err := asn1.Unmarshal(sig, &v)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rest) != 0 {
+		return nil, nil, fmt.Errorf("%d trailing bytes after signature", len(rest))
+	}
+	n := pub.Curve.Params().N
+	for _, i := range []*big.Int{v.R, v.S} {
+		if i.Sign() <= 0 || i.Cmp(n) >= 0 {
+			return nil, nil, errors.New("signature integer out of range")
+		}
+	}
+	if der, err := asn1.Marshal(v); err != nil || !bytes.Equal(der, sig) {
+		return nil, nil, errors.New("signature is not DER-encoded")
+	}
+	return v.R, v.S, nil
+}
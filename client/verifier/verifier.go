@@ -0,0 +1,75 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package verifier verifies the signed roots and proofs of Trillian logs.
+//
+// Unlike the client package, it depends on neither the Trillian API protos
+// nor gRPC, so applications which only verify data they get by other means
+// don't inherit the dependencies of Trillian's servers.
+package verifier
+
+import (
+	"crypto"
+	"errors"
+
+	"github.com/google/trillian/crypto/sigpb"
+)
+
+// LogVerifier verifies the signed roots and proofs of a log.
+type LogVerifier struct {
+	ProofVerifier
+	hasher LogHasher
+	pubKey crypto.PublicKey
+}
+
+// NewLogVerifier returns a LogVerifier for the log with the given hasher and
+// public key.
+func NewLogVerifier(hasher LogHasher, pubKey crypto.PublicKey) *LogVerifier {
+	return &LogVerifier{ProofVerifier: NewProofVerifier(hasher), hasher: hasher, pubKey: pubKey}
+}
+
+// VerifyRoot verifies that newRoot is signed by the log with sig, and is a
+// valid append-only operation from trusted, which was verified earlier.
+// If trusted is nil or empty, a consistency proof is not needed.
+func (v *LogVerifier) VerifyRoot(trusted *LogRoot, newRoot *LogRoot, sig *sigpb.DigitallySigned, consistency [][]byte) error {
+	if newRoot == nil {
+		return errors.New("newRoot == nil")
+	}
+	hash, err := HashLogRoot(*newRoot)
+	if err != nil {
+		return err
+	}
+	if err := VerifySignature(v.pubKey, hash, sig); err != nil {
+		return err
+	}
+
+	// Implicitly trust the first root we get.
+	if trusted != nil && trusted.TreeSize != 0 {
+		return v.VerifyConsistencyProof(trusted.TreeSize, newRoot.TreeSize, trusted.RootHash, newRoot.RootHash, consistency)
+	}
+	return nil
+}
+
+// VerifyInclusion verifies that data is included at leafIndex in root, which
+// was verified earlier.
+func (v *LogVerifier) VerifyInclusion(root *LogRoot, data []byte, leafIndex int64, proof [][]byte) error {
+	if root == nil {
+		return errors.New("root == nil")
+	}
+	leafHash, err := v.hasher.HashLeaf(data)
+	if err != nil {
+		return err
+	}
+	return v.VerifyInclusionProof(leafIndex, root.TreeSize, proof, root.RootHash, leafHash)
+}
@@ -0,0 +1,223 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verifier
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/asn1"
+	"go/build"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/google/trillian/crypto/sigpb"
+)
+
+// heavyDeps are packages which must not be imported, directly or
+// transitively, by this package, along with their subpackages.
+var heavyDeps = []string{
+	"github.com/google/trillian/storage",
+	"cloud.google.com/go/spanner",
+	"github.com/go-sql-driver/mysql",
+	"github.com/coreos/etcd",
+	"google.golang.org/grpc",
+}
+
+func TestDependencies(t *testing.T) {
+	pkg, err := build.ImportDir(".", 0)
+	if err != nil {
+		t.Fatalf("ImportDir(): %v", err)
+	}
+	seen := make(map[string]bool)
+	var walk func(path, srcDir string)
+	walk = func(path, srcDir string) {
+		p, err := build.Import(path, srcDir, 0)
+		if err != nil {
+			t.Fatalf("Import(%q): %v", path, err)
+		}
+		if p.Goroot || seen[p.ImportPath] {
+			return
+		}
+		seen[p.ImportPath] = true
+		// The root package holds the API protos, which need gRPC.
+		if p.ImportPath == "github.com/google/trillian" {
+			t.Errorf("%v depends on %v", pkg.ImportPath, p.ImportPath)
+		}
+		for _, dep := range heavyDeps {
+			if p.ImportPath == dep || strings.HasPrefix(p.ImportPath, dep+"/") {
+				t.Errorf("%v depends on %v", pkg.ImportPath, p.ImportPath)
+			}
+		}
+		for _, imp := range p.Imports {
+			if imp != "C" {
+				walk(imp, p.Dir)
+			}
+		}
+	}
+	for _, imp := range pkg.Imports {
+		walk(imp, pkg.Dir)
+	}
+}
+
+func TestLogVerifier(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey(): %v", err)
+	}
+	sign := func(root *LogRoot) *sigpb.DigitallySigned {
+		hash, err := HashLogRoot(*root)
+		if err != nil {
+			t.Fatalf("HashLogRoot(): %v", err)
+		}
+		digest := sha256.Sum256(hash)
+		sig, err := key.Sign(rand.Reader, digest[:], crypto.SHA256)
+		if err != nil {
+			t.Fatalf("Sign(): %v", err)
+		}
+		return &sigpb.DigitallySigned{
+			SignatureAlgorithm: sigpb.DigitallySigned_ECDSA,
+			HashAlgorithm:      sigpb.DigitallySigned_SHA256,
+			Signature:          sig,
+		}
+	}
+
+	h := DefaultRFC6962Hasher
+	hashA, _ := h.HashLeaf([]byte("A"))
+	hashB, _ := h.HashLeaf([]byte("B"))
+	root1 := &LogRoot{TreeSize: 1, RootHash: hashA, TimestampNanos: 1}
+	root2 := &LogRoot{TreeSize: 2, RootHash: h.HashChildren(hashA, hashB), TimestampNanos: 2}
+	sig2 := sign(root2)
+
+	v := NewLogVerifier(h, key.Public())
+	if err := v.VerifyRoot(nil, root2, sig2, nil); err != nil {
+		t.Errorf("VerifyRoot(nil, root2)=%v; want nil", err)
+	}
+	if err := v.VerifyRoot(root1, root2, sig2, [][]byte{hashB}); err != nil {
+		t.Errorf("VerifyRoot(root1, root2)=%v; want nil", err)
+	}
+	if err := v.VerifyRoot(root1, root2, sig2, [][]byte{hashA}); err == nil {
+		t.Error("VerifyRoot() with bad consistency proof=nil; want error")
+	}
+	if err := v.VerifyRoot(nil, root2, sign(root1), nil); err == nil {
+		t.Error("VerifyRoot() with wrong signature=nil; want error")
+	}
+	if err := v.VerifyInclusion(root2, []byte("B"), 1, [][]byte{hashA}); err != nil {
+		t.Errorf("VerifyInclusion(B)=%v; want nil", err)
+	}
+	if err := v.VerifyInclusion(root2, []byte("C"), 1, [][]byte{hashA}); err == nil {
+		t.Error("VerifyInclusion(C)=nil; want error")
+	}
+}
+
+// derInteger returns the DER encoding of an INTEGER with the content b.
+func derInteger(b []byte) []byte {
+	return append([]byte{0x02, byte(len(b))}, b...)
+}
+
+// intBytes returns the minimal two's complement content of a positive INTEGER.
+func intBytes(i *big.Int) []byte {
+	b := i.Bytes()
+	if b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+	return b
+}
+
+func TestVerifySignatureStrict(t *testing.T) {
+	data := []byte("root")
+	digest := sha256.Sum256(data)
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey(): %v", err)
+	}
+	r, s, err := ecdsa.Sign(rand.Reader, ecdsaKey, digest[:])
+	if err != nil {
+		t.Fatalf("ecdsa.Sign(): %v", err)
+	}
+	marshal := func(r, s *big.Int) []byte {
+		b, err := asn1.Marshal(ecdsaSignature{R: r, S: s})
+		if err != nil {
+			t.Fatalf("asn1.Marshal(): %v", err)
+		}
+		return b
+	}
+	ecdsaSig := marshal(r, s)
+	ints := append(derInteger(intBytes(r)), derInteger(intBytes(s))...)
+	paddedInts := append(derInteger(append([]byte{0}, intBytes(r)...)), derInteger(intBytes(s))...)
+	n := elliptic.P256().Params().N
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("GenerateKey(): %v", err)
+	}
+	rsaSig, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("rsa.SignPKCS1v15(): %v", err)
+	}
+
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey(): %v", err)
+	}
+	edSig := ed25519.Sign(edKey, data)
+
+	ecdsaSigned := func(sig []byte) *sigpb.DigitallySigned {
+		return &sigpb.DigitallySigned{SignatureAlgorithm: sigpb.DigitallySigned_ECDSA, HashAlgorithm: sigpb.DigitallySigned_SHA256, Signature: sig}
+	}
+	rsaSigned := func(sig []byte) *sigpb.DigitallySigned {
+		return &sigpb.DigitallySigned{SignatureAlgorithm: sigpb.DigitallySigned_RSA, HashAlgorithm: sigpb.DigitallySigned_SHA256, Signature: sig}
+	}
+	edSigned := func(sig []byte) *sigpb.DigitallySigned {
+		return &sigpb.DigitallySigned{SignatureAlgorithm: sigpb.DigitallySigned_ED25519, HashAlgorithm: sigpb.DigitallySigned_NONE, Signature: sig}
+	}
+
+	for _, test := range []struct {
+		desc    string
+		pub     crypto.PublicKey
+		sig     *sigpb.DigitallySigned
+		wantErr bool
+	}{
+		{desc: "ecdsa", pub: ecdsaKey.Public(), sig: ecdsaSigned(ecdsaSig)},
+		{desc: "ecdsaManualDER", pub: ecdsaKey.Public(), sig: ecdsaSigned(append([]byte{0x30, byte(len(ints))}, ints...))},
+		{desc: "ecdsaTrailingBytes", pub: ecdsaKey.Public(), sig: ecdsaSigned(append(append([]byte{}, ecdsaSig...), 0)), wantErr: true},
+		{desc: "ecdsaPaddedInteger", pub: ecdsaKey.Public(), sig: ecdsaSigned(append([]byte{0x30, byte(len(paddedInts))}, paddedInts...)), wantErr: true},
+		{desc: "ecdsaLongFormLength", pub: ecdsaKey.Public(), sig: ecdsaSigned(append([]byte{0x30, 0x81, byte(len(ints))}, ints...)), wantErr: true},
+		{desc: "ecdsaNegative", pub: ecdsaKey.Public(), sig: ecdsaSigned(marshal(new(big.Int).Neg(r), s)), wantErr: true},
+		{desc: "ecdsaZero", pub: ecdsaKey.Public(), sig: ecdsaSigned(marshal(r, new(big.Int))), wantErr: true},
+		{desc: "ecdsaOutOfRange", pub: ecdsaKey.Public(), sig: ecdsaSigned(marshal(new(big.Int).Add(r, n), s)), wantErr: true},
+		{desc: "ecdsaTooLong", pub: ecdsaKey.Public(), sig: ecdsaSigned(make([]byte, 1024)), wantErr: true},
+		{desc: "ecdsaUnknownHash", pub: ecdsaKey.Public(), sig: &sigpb.DigitallySigned{SignatureAlgorithm: sigpb.DigitallySigned_ECDSA, HashAlgorithm: sigpb.DigitallySigned_NONE, Signature: ecdsaSig}, wantErr: true},
+		{desc: "rsa", pub: rsaKey.Public(), sig: rsaSigned(rsaSig)},
+		{desc: "rsaTruncated", pub: rsaKey.Public(), sig: rsaSigned(rsaSig[1:]), wantErr: true},
+		{desc: "rsaPadded", pub: rsaKey.Public(), sig: rsaSigned(append([]byte{0}, rsaSig...)), wantErr: true},
+		{desc: "ed25519", pub: edPub, sig: edSigned(edSig)},
+		{desc: "ed25519Truncated", pub: edPub, sig: edSigned(edSig[1:]), wantErr: true},
+		{desc: "ed25519Hashed", pub: edPub, sig: &sigpb.DigitallySigned{SignatureAlgorithm: sigpb.DigitallySigned_ED25519, HashAlgorithm: sigpb.DigitallySigned_SHA256, Signature: edSig}, wantErr: true},
+		{desc: "wrongAlgorithm", pub: edPub, sig: ecdsaSigned(edSig), wantErr: true},
+		{desc: "nil", pub: ecdsaKey.Public(), wantErr: true},
+	} {
+		err := VerifySignature(test.pub, data, test.sig)
+		if gotErr := err != nil; gotErr != test.wantErr {
+			t.Errorf("%v: VerifySignature()=%v, wantErr %v", test.desc, err, test.wantErr)
+		}
+	}
+}
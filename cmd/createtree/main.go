@@ -33,10 +33,12 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/golang/glog"
 	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
 	"github.com/google/trillian"
 	"github.com/google/trillian/client"
 	"github.com/google/trillian/cmd"
@@ -44,6 +46,8 @@ import (
 	"github.com/google/trillian/crypto/keyspb"
 	"github.com/google/trillian/crypto/sigpb"
 	"google.golang.org/grpc"
+
+	tspb "github.com/golang/protobuf/ptypes/timestamp"
 )
 
 var (
@@ -58,6 +62,12 @@ var (
 	displayName        = flag.String("display_name", "", "Display name of the new tree")
 	description        = flag.String("description", "", "Description of the new tree")
 	maxRootDuration    = flag.Duration("max_root_duration", 0, "Interval after which a new signed root is produced despite no submissions; zero means never")
+	labels             = flag.String("labels", "", "Comma-separated key=value labels of the new tree, e.g. env=prod,customer=acme")
+	externalID         = flag.String("external_id", "", "Identifier of the new tree in an external system; if set, the command can be retried and returns the existing tree if it was already created")
+	logOrigin          = flag.String("log_origin", "", "Origin of the new log, e.g. its URL, signed in its initial root; required by the other log_ flags")
+	logShard           = flag.String("log_shard", "", "Name of the shard of the new log, signed in its initial root")
+	logShardStart      = flag.String("log_shard_start", "", "Start of the interval of the entries of the shard of the new log as an RFC 3339 timestamp, inclusive")
+	logShardLimit      = flag.String("log_shard_limit", "", "Limit of the interval of the entries of the shard of the new log as an RFC 3339 timestamp, exclusive")
 	privateKeyFormat   = flag.String("private_key_format", "", "Type of protobuf message to send the key as (PrivateKey, PEMKeyFile, or PKCS11ConfigFile). If empty, a key will be generated for you by Trillian.")
 
 	configFile = flag.String("config", "", "Config file containing flags, file contents can be overridden by command line flags")
@@ -74,6 +84,10 @@ func createTree(ctx context.Context) (*trillian.Tree, error) {
 	if err != nil {
 		return nil, err
 	}
+	meta, err := newLogRootMetadata(req.Tree.TreeType)
+	if err != nil {
+		return nil, err
+	}
 
 	conn, err := grpc.Dial(*adminServerAddr, grpc.WithInsecure())
 	if err != nil {
@@ -85,7 +99,43 @@ func createTree(ctx context.Context) (*trillian.Tree, error) {
 	mapClient := trillian.NewTrillianMapClient(conn)
 	logClient := trillian.NewTrillianLogClient(conn)
 
-	return client.CreateAndInitTree(ctx, req, adminClient, mapClient, logClient)
+	return client.CreateAndInitTreeWithLogMetadata(ctx, req, adminClient, mapClient, logClient, meta)
+}
+
+// newLogRootMetadata returns the metadata to sign in the initial root of the
+// new tree, or nil if no log_ flags are set.
+func newLogRootMetadata(treeType trillian.TreeType) (*any.Any, error) {
+	if *logOrigin == "" && *logShard == "" && *logShardStart == "" && *logShardLimit == "" {
+		return nil, nil
+	}
+	if treeType != trillian.TreeType_LOG {
+		return nil, fmt.Errorf("log root metadata flags set for a tree of type %v", treeType)
+	}
+	if *logOrigin == "" {
+		return nil, errors.New("empty --log_origin, please provide the origin of the log")
+	}
+	meta := &trillian.LogRootMetadata{Origin: *logOrigin}
+	if *logShard != "" || *logShardStart != "" || *logShardLimit != "" {
+		start, err := parseTimestamp("log_shard_start", *logShardStart)
+		if err != nil {
+			return nil, err
+		}
+		limit, err := parseTimestamp("log_shard_limit", *logShardLimit)
+		if err != nil {
+			return nil, err
+		}
+		meta.Shard = &trillian.LogShard{Name: *logShard, Start: start, Limit: limit}
+	}
+	return ptypes.MarshalAny(meta)
+}
+
+// parseTimestamp parses the RFC 3339 timestamp s, the value of flag name.
+func parseTimestamp(name, s string) (*tspb.Timestamp, error) {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --%v: %v", name, err)
+	}
+	return ptypes.TimestampProto(t)
 }
 
 func newRequest() (*trillian.CreateTreeRequest, error) {
@@ -114,6 +164,11 @@ func newRequest() (*trillian.CreateTreeRequest, error) {
 		return nil, fmt.Errorf("unknown SignatureAlgorithm: %v", *signatureAlgorithm)
 	}
 
+	l, err := parseLabels(*labels)
+	if err != nil {
+		return nil, err
+	}
+
 	ctr := &trillian.CreateTreeRequest{Tree: &trillian.Tree{
 		TreeState:          trillian.TreeState(ts),
 		TreeType:           trillian.TreeType(tt),
@@ -123,7 +178,8 @@ func newRequest() (*trillian.CreateTreeRequest, error) {
 		DisplayName:        *displayName,
 		Description:        *description,
 		MaxRootDuration:    ptypes.DurationProto(*maxRootDuration),
-	}}
+		Labels:             l,
+	}, ExternalId: *externalID}
 
 	if *privateKeyFormat != "" {
 		pk, err := keys.New(*privateKeyFormat)
@@ -151,6 +207,22 @@ func newRequest() (*trillian.CreateTreeRequest, error) {
 	return ctr, nil
 }
 
+// parseLabels parses a comma-separated list of key=value labels.
+func parseLabels(s string) (map[string]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	labels := make(map[string]string)
+	for _, kv := range strings.Split(s, ",") {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid label %q, want key=value", kv)
+		}
+		labels[parts[0]] = parts[1]
+	}
+	return labels, nil
+}
+
 func main() {
 	flag.Parse()
 
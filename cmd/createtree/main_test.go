@@ -18,6 +18,7 @@ import (
 	"context"
 	"errors"
 	"flag"
+	"reflect"
 	"testing"
 	"time"
 
@@ -68,6 +69,9 @@ func TestCreateTree(t *testing.T) {
 	nonDefaultTree.DisplayName = "Llamas Map"
 	nonDefaultTree.Description = "For all your digital llama needs!"
 
+	labelledTree := *defaultTree
+	labelledTree.Labels = map[string]string{"env": "prod", "customer": "acme"}
+
 	runTest(t, []*testCase{
 		{
 			desc: "validOpts",
@@ -84,6 +88,17 @@ func TestCreateTree(t *testing.T) {
 			},
 			wantTree: &nonDefaultTree,
 		},
+		{
+			desc:     "labels",
+			setFlags: func() { *labels = "env=prod,customer=acme" },
+			wantTree: &labelledTree,
+		},
+		{
+			desc:        "invalidLabels",
+			setFlags:    func() { *labels = "env" },
+			validateErr: errors.New("invalid label"),
+			wantErr:     true,
+		},
 		{
 			desc: "mandatoryOptsNotSet",
 			// Undo the flags set by runTest, so that mandatory options are no longer set.
@@ -137,6 +152,87 @@ func TestCreateTree(t *testing.T) {
 	})
 }
 
+func TestParseLabels(t *testing.T) {
+	tests := []struct {
+		labels  string
+		want    map[string]string
+		wantErr bool
+	}{
+		{labels: ""},
+		{labels: "env=prod", want: map[string]string{"env": "prod"}},
+		{labels: "env=prod,customer=acme", want: map[string]string{"env": "prod", "customer": "acme"}},
+		{labels: "env=", want: map[string]string{"env": ""}},
+		{labels: "note=a=b", want: map[string]string{"note": "a=b"}},
+		{labels: "env", wantErr: true},
+		{labels: "=prod", wantErr: true},
+		{labels: "env=prod,", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := parseLabels(test.labels)
+		if gotErr := err != nil; gotErr != test.wantErr {
+			t.Errorf("parseLabels(%q) returned err = %v, wantErr = %v", test.labels, err, test.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("parseLabels(%q) = %v, want %v", test.labels, got, test.want)
+		}
+	}
+}
+
+func TestNewLogRootMetadata(t *testing.T) {
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	limit := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	startPB, _ := ptypes.TimestampProto(start)
+	limitPB, _ := ptypes.TimestampProto(limit)
+
+	tests := []struct {
+		desc                        string
+		treeType                    trillian.TreeType
+		origin, shard, start, limit string
+		want                        *trillian.LogRootMetadata
+		wantErr                     bool
+	}{
+		{desc: "noFlags", treeType: trillian.TreeType_LOG},
+		{desc: "origin", treeType: trillian.TreeType_LOG, origin: "example.com/log", want: &trillian.LogRootMetadata{Origin: "example.com/log"}},
+		{
+			desc:     "shard",
+			treeType: trillian.TreeType_LOG,
+			origin:   "example.com/log",
+			shard:    "2018",
+			start:    start.Format(time.RFC3339),
+			limit:    limit.Format(time.RFC3339),
+			want: &trillian.LogRootMetadata{
+				Origin: "example.com/log",
+				Shard:  &trillian.LogShard{Name: "2018", Start: startPB, Limit: limitPB},
+			},
+		},
+		{desc: "noOrigin", treeType: trillian.TreeType_LOG, shard: "2018", wantErr: true},
+		{desc: "badStart", treeType: trillian.TreeType_LOG, origin: "example.com/log", start: "2018", limit: limit.Format(time.RFC3339), wantErr: true},
+		{desc: "map", treeType: trillian.TreeType_MAP, origin: "example.com/log", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			defer flagsaver.Save().Restore()
+			*logOrigin, *logShard, *logShardStart, *logShardLimit = test.origin, test.shard, test.start, test.limit
+
+			got, err := newLogRootMetadata(test.treeType)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("newLogRootMetadata() returned err = %v, wantErr = %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			var want *any.Any
+			if test.want != nil {
+				want = mustMarshalAny(test.want)
+			}
+			if !proto.Equal(got, want) {
+				t.Errorf("newLogRootMetadata() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
 // runTest executes the createtree command against a fake TrillianAdminServer
 // for each of the provided tests, and checks that the tree in the request is
 // as expected, or an expected error occurs.
@@ -17,6 +17,9 @@
 //
 // Example usage:
 // $ ./deletetree --admin_server=host:port --log_id=logid
+//
+// All the trees whose labels match a selector may be deleted at once:
+// $ ./deletetree --admin_server=host:port --label_selector=env=test
 package main
 
 import (
@@ -31,6 +34,7 @@ import (
 var (
 	adminServerAddr = flag.String("admin_server", "", "Address of the gRPC Trillian Admin Server (host:port)")
 	logID           = flag.Int64("log_id", 0, "Trillian LogID to delete")
+	labelSelector   = flag.String("label_selector", "", "If set, deletes all trees whose labels match this selector instead of --log_id (see ListTreesRequest.label_selector)")
 )
 
 func main() {
@@ -42,9 +46,26 @@ func main() {
 	}
 	defer conn.Close()
 
+	ctx := context.Background()
 	a := trillian.NewTrillianAdminClient(conn)
-	_, err = a.DeleteTree(context.Background(), &trillian.DeleteTreeRequest{TreeId: *logID})
+	if *labelSelector == "" {
+		if _, err := a.DeleteTree(ctx, &trillian.DeleteTreeRequest{TreeId: *logID}); err != nil {
+			glog.Exitf("delete failed: %v", err)
+		}
+		return
+	}
+	if *logID != 0 {
+		glog.Exit("only one of --log_id and --label_selector may be set")
+	}
+
+	resp, err := a.ListTrees(ctx, &trillian.ListTreesRequest{LabelSelector: *labelSelector})
 	if err != nil {
-		glog.Exitf("delete failed: %v", err)
+		glog.Exitf("list failed: %v", err)
+	}
+	for _, tree := range resp.Tree {
+		if _, err := a.DeleteTree(ctx, &trillian.DeleteTreeRequest{TreeId: tree.TreeId}); err != nil {
+			glog.Exitf("delete of tree %v failed: %v", tree.TreeId, err)
+		}
+		glog.Infof("Deleted tree %v", tree.TreeId)
 	}
 }
@@ -0,0 +1,99 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main contains the implementation and entry point for the
+// gen_test_vectors command, which generates a corpus of test vectors for
+// implementations of Trillian verifiers in other languages.
+//
+// The corpus holds leaves, roots, and inclusion and consistency proofs of logs
+// for every log hash strategy, roots and inclusion proofs of maps for every
+// map hash strategy, and roots signed with every given key. It is written as
+// JSON, in the format described by the Corpus type.
+//
+// Example usage:
+// $ ./gen_test_vectors --output=testdata/test_vectors.json
+package main
+
+import (
+	"crypto"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/google/trillian/crypto/keys/pem"
+
+	// Load hashers
+	_ "github.com/google/trillian/merkle/coniks"
+	_ "github.com/google/trillian/merkle/maphasher"
+	_ "github.com/google/trillian/merkle/objhasher"
+	_ "github.com/google/trillian/merkle/rfc6962"
+)
+
+var (
+	output      = flag.String("output", "", "File to write the corpus to; empty writes to stdout")
+	privateKeys = flag.String("private_keys", "testdata/log-rpc-server.privkey.pem:towel,testdata/log-rpc-server-pkcs11.privkey.pem",
+		"Comma-separated list of PEM files of the private keys to sign roots with, each optionally followed by :password")
+)
+
+// readSigners reads the keys in --private_keys.
+func readSigners() ([]crypto.Signer, error) {
+	var signers []crypto.Signer
+	for _, spec := range strings.Split(*privateKeys, ",") {
+		if spec = strings.TrimSpace(spec); spec == "" {
+			continue
+		}
+		file, password := spec, ""
+		if i := strings.LastIndex(spec, ":"); i >= 0 {
+			file, password = spec[:i], spec[i+1:]
+		}
+		keyPEM, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		signer, err := pem.UnmarshalPrivateKey(string(keyPEM), password)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", file, err)
+		}
+		signers = append(signers, signer)
+	}
+	return signers, nil
+}
+
+func main() {
+	flag.Parse()
+
+	signers, err := readSigners()
+	if err != nil {
+		glog.Exitf("Failed to read private keys: %v", err)
+	}
+	corpus, err := Generate(signers)
+	if err != nil {
+		glog.Exitf("Failed to generate test vectors: %v", err)
+	}
+	data, err := json.MarshalIndent(corpus, "", "  ")
+	if err != nil {
+		glog.Exitf("Failed to encode test vectors: %v", err)
+	}
+	data = append(data, '\n')
+
+	if *output == "" {
+		os.Stdout.Write(data)
+	} else if err := ioutil.WriteFile(*output, data, 0644); err != nil {
+		glog.Exitf("Failed to write test vectors: %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/crypto/keys/der"
+	"github.com/google/trillian/crypto/keys/pem"
+	"github.com/google/trillian/crypto/sigpb"
+	"github.com/google/trillian/merkle"
+	"github.com/google/trillian/merkle/hashers"
+	"github.com/google/trillian/testonly"
+
+	tcrypto "github.com/google/trillian/crypto"
+)
+
+// TestGenerate checks that the generated vectors verify with the Go
+// implementation.
+func TestGenerate(t *testing.T) {
+	ecdsaKey, err := pem.UnmarshalPrivateKey(testonly.DemoPrivateKey, testonly.DemoPrivateKeyPass)
+	if err != nil {
+		t.Fatalf("UnmarshalPrivateKey(): %v", err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey(): %v", err)
+	}
+	c, err := Generate([]crypto.Signer{ecdsaKey, rsaKey})
+	if err != nil {
+		t.Fatalf("Generate(): %v", err)
+	}
+	if got, want := len(c.Logs), 2; got != want {
+		t.Errorf("Generate(): %d log hash strategies, want %d", got, want)
+	}
+	if got, want := len(c.Maps), 2; got != want {
+		t.Errorf("Generate(): %d map hash strategies, want %d", got, want)
+	}
+	// The corpus must survive a round trip through JSON.
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatalf("Marshal(): %v", err)
+	}
+	c = &Corpus{}
+	if err := json.Unmarshal(data, c); err != nil {
+		t.Fatalf("Unmarshal(): %v", err)
+	}
+
+	for _, l := range c.Logs {
+		h, err := hashers.NewLogHasher(trillian.HashStrategy(trillian.HashStrategy_value[l.HashStrategy]))
+		if err != nil {
+			t.Fatalf("%v: NewLogHasher(): %v", l.HashStrategy, err)
+		}
+		v := merkle.NewLogVerifier(h)
+		for _, p := range l.InclusionProofs {
+			root := l.Roots[p.TreeSize].RootHash
+			if err := v.VerifyInclusionProof(p.LeafIndex, p.TreeSize, p.Proof, root, l.LeafHashes[p.LeafIndex]); err != nil {
+				t.Errorf("%v: VerifyInclusionProof(%d, %d): %v", l.HashStrategy, p.LeafIndex, p.TreeSize, err)
+			}
+		}
+		for _, p := range l.ConsistencyProofs {
+			r1, r2 := l.Roots[p.FirstTreeSize].RootHash, l.Roots[p.SecondTreeSize].RootHash
+			if err := v.VerifyConsistencyProof(p.FirstTreeSize, p.SecondTreeSize, r1, r2, p.Proof); err != nil {
+				t.Errorf("%v: VerifyConsistencyProof(%d, %d): %v", l.HashStrategy, p.FirstTreeSize, p.SecondTreeSize, err)
+			}
+		}
+	}
+
+	for _, m := range c.Maps {
+		h, err := hashers.NewMapHasher(trillian.HashStrategy(trillian.HashStrategy_value[m.HashStrategy]))
+		if err != nil {
+			t.Fatalf("%v: NewMapHasher(): %v", m.HashStrategy, err)
+		}
+		for _, s := range m.States {
+			for _, p := range s.Proofs {
+				if err := merkle.VerifyMapInclusionProof(m.TreeID, p.Index, p.Value, s.RootHash, p.Proof, h); err != nil {
+					t.Errorf("%v: VerifyMapInclusionProof(%x) in map of size %d: %v", m.HashStrategy, p.Index, len(s.Entries), err)
+				}
+			}
+		}
+	}
+
+	for _, s := range c.Signatures {
+		pubKey, err := der.UnmarshalPublicKey(s.PublicKey)
+		if err != nil {
+			t.Fatalf("UnmarshalPublicKey(): %v", err)
+		}
+		for _, r := range s.LogRoots {
+			root := trillian.SignedLogRoot{TreeSize: r.TreeSize, RootHash: r.RootHash, TimestampNanos: r.TimestampNanos}
+			hash, err := tcrypto.HashLogRoot(root)
+			if err != nil {
+				t.Fatalf("HashLogRoot(): %v", err)
+			}
+			if err := tcrypto.Verify(pubKey, hash, digitallySigned(r.Signature)); err != nil {
+				t.Errorf("%v: Verify(log root %d): %v", s.SignatureAlgorithm, r.TreeSize, err)
+			}
+		}
+		for _, r := range s.MapRoots {
+			if err := tcrypto.VerifyObject(pubKey, json.RawMessage(r.RootJSON), digitallySigned(r.Signature)); err != nil {
+				t.Errorf("%v: VerifyObject(map root): %v", s.SignatureAlgorithm, err)
+			}
+		}
+	}
+}
+
+func digitallySigned(s Signature) *sigpb.DigitallySigned {
+	return &sigpb.DigitallySigned{
+		HashAlgorithm:      sigpb.DigitallySigned_HashAlgorithm(sigpb.DigitallySigned_HashAlgorithm_value[s.HashAlgorithm]),
+		SignatureAlgorithm: sigpb.DigitallySigned_SignatureAlgorithm(sigpb.DigitallySigned_SignatureAlgorithm_value[s.SignatureAlgorithm]),
+		Signature:          s.Signature,
+	}
+}
@@ -0,0 +1,408 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto"
+	"crypto/sha512"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/benlaurie/objecthash/go/objecthash"
+	"github.com/google/trillian"
+	"github.com/google/trillian/crypto/keys/der"
+	"github.com/google/trillian/crypto/sigpb"
+	"github.com/google/trillian/merkle"
+	"github.com/google/trillian/merkle/hashers"
+
+	tcrypto "github.com/google/trillian/crypto"
+)
+
+// CorpusVersion is the version of the format of the corpus. It must be
+// incremented whenever the format changes, or the data in it changes meaning.
+const CorpusVersion = 1
+
+const (
+	// numLogLeaves is the size of the largest log tree in the corpus.
+	numLogLeaves = 10
+	// numMapEntries is the number of entries in the largest map in the corpus.
+	numMapEntries = 8
+	// numAbsentKeys is the number of keys per map state with proofs of
+	// non-inclusion.
+	numAbsentKeys = 2
+	// mapTreeID is the tree ID used by map hashers.
+	mapTreeID = 6962
+)
+
+// Corpus holds test vectors for each supported hash strategy and key type.
+// Byte slices are base64-encoded in its JSON form.
+type Corpus struct {
+	Version    int                `json:"version"`
+	Logs       []*LogVectors      `json:"logs"`
+	Maps       []*MapVectors      `json:"maps"`
+	Signatures []*SignatureVector `json:"signatures"`
+}
+
+// LogVectors are the roots and proofs of a log tree built with one hash
+// strategy.
+type LogVectors struct {
+	HashStrategy string `json:"hash_strategy"`
+	// Leaves are the values of the log's leaves, in order.
+	Leaves [][]byte `json:"leaves"`
+	// LeafHashes are the Merkle leaf hashes of Leaves.
+	LeafHashes [][]byte `json:"leaf_hashes"`
+	// Roots are the root hashes of every tree size, from zero to
+	// len(Leaves).
+	Roots             []LogRoot          `json:"roots"`
+	InclusionProofs   []InclusionProof   `json:"inclusion_proofs"`
+	ConsistencyProofs []ConsistencyProof `json:"consistency_proofs"`
+}
+
+// LogRoot is the root hash of a log tree of a given size.
+type LogRoot struct {
+	TreeSize int64  `json:"tree_size"`
+	RootHash []byte `json:"root_hash"`
+}
+
+// InclusionProof proves the inclusion of a leaf in the tree of a given size.
+type InclusionProof struct {
+	LeafIndex int64    `json:"leaf_index"`
+	TreeSize  int64    `json:"tree_size"`
+	Proof     [][]byte `json:"proof"`
+}
+
+// ConsistencyProof proves that a tree of a given size is a prefix of a larger
+// one.
+type ConsistencyProof struct {
+	FirstTreeSize  int64    `json:"first_tree_size"`
+	SecondTreeSize int64    `json:"second_tree_size"`
+	Proof          [][]byte `json:"proof"`
+}
+
+// MapVectors are the roots and proofs of successive states of a map built
+// with one hash strategy.
+type MapVectors struct {
+	HashStrategy string      `json:"hash_strategy"`
+	TreeID       int64       `json:"tree_id"`
+	States       []*MapState `json:"states"`
+}
+
+// MapState is a map holding a set of entries.
+type MapState struct {
+	Entries  []MapEntry `json:"entries"`
+	RootHash []byte     `json:"root_hash"`
+	// Proofs hold proofs of inclusion of every entry, and proofs of
+	// non-inclusion, which have no value, of some absent keys. Empty proof
+	// elements stand for the empty subtree at their position.
+	Proofs []MapProof `json:"proofs"`
+}
+
+// MapEntry is a key and its value.
+type MapEntry struct {
+	Index []byte `json:"index"`
+	Value []byte `json:"value"`
+}
+
+// MapProof proves the value of a key in a map, where an empty Value means
+// that the key is absent.
+type MapProof struct {
+	Index []byte   `json:"index"`
+	Value []byte   `json:"value,omitempty"`
+	Proof [][]byte `json:"proof"`
+}
+
+// SignatureVector holds roots signed with one key.
+type SignatureVector struct {
+	SignatureAlgorithm string `json:"signature_algorithm"`
+	// PublicKey is the DER-encoded public key.
+	PublicKey []byte          `json:"public_key"`
+	LogRoots  []SignedLogRoot `json:"log_roots"`
+	MapRoots  []SignedMapRoot `json:"map_roots"`
+}
+
+// SignedLogRoot is a signed log root. The signed data is the ObjectHash of
+// the root's fields, as computed by crypto.HashLogRoot.
+type SignedLogRoot struct {
+	TreeSize       int64     `json:"tree_size"`
+	RootHash       []byte    `json:"root_hash"`
+	TimestampNanos int64     `json:"timestamp_nanos,string"`
+	SignedData     []byte    `json:"signed_data"`
+	Signature      Signature `json:"signature"`
+}
+
+// SignedMapRoot is a signed map root. The signed data is the ObjectHash of
+// the JSON encoding of the unsigned root, which is given verbatim.
+type SignedMapRoot struct {
+	RootJSON   string    `json:"root_json"`
+	SignedData []byte    `json:"signed_data"`
+	Signature  Signature `json:"signature"`
+}
+
+// Signature is a DigitallySigned.
+type Signature struct {
+	HashAlgorithm      string `json:"hash_algorithm"`
+	SignatureAlgorithm string `json:"signature_algorithm"`
+	Signature          []byte `json:"signature"`
+}
+
+// Generate builds a Corpus for all registered hash strategies, with
+// signatures made by signers.
+func Generate(signers []crypto.Signer) (*Corpus, error) {
+	c := &Corpus{Version: CorpusVersion}
+	for _, s := range strategies() {
+		if h, err := hashers.NewLogHasher(s); err == nil {
+			v, err := logVectors(s, h)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %v", s, err)
+			}
+			c.Logs = append(c.Logs, v)
+		}
+		if h, err := hashers.NewMapHasher(s); err == nil {
+			v, err := mapVectors(s, h)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %v", s, err)
+			}
+			c.Maps = append(c.Maps, v)
+		}
+	}
+	for _, signer := range signers {
+		v, err := signatureVector(signer, c.Logs)
+		if err != nil {
+			return nil, err
+		}
+		c.Signatures = append(c.Signatures, v)
+	}
+	return c, nil
+}
+
+// strategies returns all known hash strategies, in order.
+func strategies() []trillian.HashStrategy {
+	var s []trillian.HashStrategy
+	for v := range trillian.HashStrategy_name {
+		if v != int32(trillian.HashStrategy_UNKNOWN_HASH_STRATEGY) {
+			s = append(s, trillian.HashStrategy(v))
+		}
+	}
+	sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+	return s
+}
+
+func logVectors(s trillian.HashStrategy, h hashers.LogHasher) (*LogVectors, error) {
+	v := &LogVectors{HashStrategy: s.String()}
+	mt := merkle.NewInMemoryMerkleTree(h)
+	// Leaves are JSON, so they're valid input to every log hasher.
+	for i := 0; i < numLogLeaves; i++ {
+		leaf := []byte(fmt.Sprintf(`{"leaf":%d}`, i))
+		_, entry, err := mt.AddLeaf(leaf)
+		if err != nil {
+			return nil, err
+		}
+		v.Leaves = append(v.Leaves, leaf)
+		v.LeafHashes = append(v.LeafHashes, entry.Hash())
+	}
+
+	v.Roots = append(v.Roots, LogRoot{TreeSize: 0, RootHash: h.EmptyRoot()})
+	for size := int64(1); size <= numLogLeaves; size++ {
+		v.Roots = append(v.Roots, LogRoot{TreeSize: size, RootHash: mt.RootAtSnapshot(size).Hash()})
+		for index := int64(0); index < size; index++ {
+			v.InclusionProofs = append(v.InclusionProofs, InclusionProof{
+				LeafIndex: index,
+				TreeSize:  size,
+				// InMemoryMerkleTree numbers leaves from 1.
+				Proof: hashes(mt.PathToRootAtSnapshot(index+1, size)),
+			})
+		}
+		for first := int64(1); first < size; first++ {
+			v.ConsistencyProofs = append(v.ConsistencyProofs, ConsistencyProof{
+				FirstTreeSize:  first,
+				SecondTreeSize: size,
+				Proof:          hashes(mt.SnapshotConsistency(first, size)),
+			})
+		}
+	}
+	return v, nil
+}
+
+func hashes(path []merkle.TreeEntryDescriptor) [][]byte {
+	h := make([][]byte, 0, len(path))
+	for _, entry := range path {
+		h = append(h, entry.Value.Hash())
+	}
+	return h
+}
+
+func mapVectors(s trillian.HashStrategy, h hashers.MapHasher) (*MapVectors, error) {
+	key := func(name string) []byte {
+		sum := sha512.Sum512([]byte(name))
+		return sum[:h.Size()]
+	}
+	var entries []MapEntry
+	for i := 0; i < numMapEntries; i++ {
+		entries = append(entries, MapEntry{
+			Index: key(fmt.Sprintf("key %d", i)),
+			Value: []byte(fmt.Sprintf(`{"value":%d}`, i)),
+		})
+	}
+	var absent [][]byte
+	for i := 0; i < numAbsentKeys; i++ {
+		absent = append(absent, key(fmt.Sprintf("absent key %d", i)))
+	}
+
+	v := &MapVectors{HashStrategy: s.String(), TreeID: mapTreeID}
+	for _, size := range []int{0, 1, 2, numMapEntries} {
+		state, err := mapState(h, entries[:size], absent)
+		if err != nil {
+			return nil, err
+		}
+		v.States = append(v.States, state)
+	}
+	return v, nil
+}
+
+// mapState computes the root of a map holding entries, and proofs for them and
+// for the absent keys.
+func mapState(h hashers.MapHasher, entries []MapEntry, absent [][]byte) (*MapState, error) {
+	leaves := make(map[string][]byte)
+	var values []merkle.HStar2LeafHash
+	for _, e := range entries {
+		leafHash, err := h.HashLeaf(mapTreeID, e.Index, e.Value)
+		if err != nil {
+			return nil, err
+		}
+		index := new(big.Int).SetBytes(e.Index)
+		leaves[index.String()] = leafHash
+		values = append(values, merkle.HStar2LeafHash{Index: index, LeafHash: leafHash})
+	}
+
+	// Record the interior nodes of non-empty subtrees, which are the
+	// non-empty proof elements.
+	nodes := make(map[string][]byte)
+	nodeKey := func(depth int, index *big.Int) string { return fmt.Sprintf("%d/%v", depth, index) }
+	hs := merkle.NewHStar2(mapTreeID, h)
+	root, err := hs.HStar2Nodes(nil, h.BitLen(), values, nil,
+		func(depth int, index *big.Int, hash []byte) error {
+			nodes[nodeKey(depth, index)] = hash
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	proof := func(index []byte) [][]byte {
+		p := make([][]byte, h.BitLen())
+		n := new(big.Int).SetBytes(index)
+		for height := range p {
+			// The sibling is the subtree starting at n with its lowest
+			// height bits cleared, and the next one flipped.
+			sib := new(big.Int).Rsh(n, uint(height))
+			sib.SetBit(sib, 0, sib.Bit(0)^1)
+			sib.Lsh(sib, uint(height))
+			if height == 0 {
+				p[height] = leaves[sib.String()]
+			} else {
+				p[height] = nodes[nodeKey(h.BitLen()-height, sib)]
+			}
+		}
+		return p
+	}
+
+	state := &MapState{Entries: entries, RootHash: root}
+	for _, e := range entries {
+		state.Proofs = append(state.Proofs, MapProof{Index: e.Index, Value: e.Value, Proof: proof(e.Index)})
+	}
+	for _, index := range absent {
+		state.Proofs = append(state.Proofs, MapProof{Index: index, Proof: proof(index)})
+	}
+	return state, nil
+}
+
+// signatureVector signs the roots of the first log in logs, and a map root.
+func signatureVector(signer crypto.Signer, logs []*LogVectors) (*SignatureVector, error) {
+	pubDER, err := der.MarshalPublicKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	s := tcrypto.NewSHA256Signer(signer)
+	v := &SignatureVector{
+		SignatureAlgorithm: tcrypto.SignatureAlgorithm(signer.Public()).String(),
+		PublicKey:          pubDER,
+	}
+
+	var roots []LogRoot
+	if len(logs) > 0 {
+		roots = logs[0].Roots
+	}
+	for i, r := range roots {
+		root := &trillian.SignedLogRoot{TreeSize: r.TreeSize, RootHash: r.RootHash, TimestampNanos: int64(1000000 * (i + 1))}
+		data, err := tcrypto.HashLogRoot(*root)
+		if err != nil {
+			return nil, err
+		}
+		sig, err := s.SignLogRoot(root)
+		if err != nil {
+			return nil, err
+		}
+		v.LogRoots = append(v.LogRoots, SignedLogRoot{
+			TreeSize:       root.TreeSize,
+			RootHash:       root.RootHash,
+			TimestampNanos: root.TimestampNanos,
+			SignedData:     data,
+			Signature:      signature(sig),
+		})
+	}
+
+	mapRoot := &trillian.SignedMapRoot{
+		TimestampNanos: 1000000,
+		RootHash:       make([]byte, 32),
+		MapId:          mapTreeID,
+		MapRevision:    1,
+	}
+	rootJSON, err := json.Marshal(mapRoot)
+	if err != nil {
+		return nil, err
+	}
+	data, err := objectHash(rootJSON)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := s.SignMapRoot(mapRoot)
+	if err != nil {
+		return nil, err
+	}
+	v.MapRoots = append(v.MapRoots, SignedMapRoot{
+		RootJSON:   string(rootJSON),
+		SignedData: data,
+		Signature:  signature(sig),
+	})
+	return v, nil
+}
+
+func signature(sig *sigpb.DigitallySigned) Signature {
+	return Signature{
+		HashAlgorithm:      sig.HashAlgorithm.String(),
+		SignatureAlgorithm: sig.SignatureAlgorithm.String(),
+		Signature:          sig.Signature,
+	}
+}
+
+func objectHash(j []byte) ([]byte, error) {
+	hash, err := objecthash.CommonJSONHash(string(j))
+	if err != nil {
+		return nil, fmt.Errorf("CommonJSONHash(%s): %v", j, err)
+	}
+	return hash[:], nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main contains the implementation and entry point for the initdb
+// command, which creates the tables of storage/mysql/storage.sql in an empty
+// MySQL database, for first-run deployments and ephemeral test environments.
+//
+// Without --create_schema, initdb only reports whether the database has the
+// expected schema. Databases which already have some of the tables are never
+// changed; use scripts/resetdb.sh to recreate them.
+//
+// Example usage:
+// $ ./initdb --mysql_uri="user:password@tcp(host:3306)/trillian" --create_schema
+//
+// CloudSpanner databases are created, with the DDL statements of
+// storage/cloudspanner/spanner.sdl, through the Cloud Console or gcloud.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"io/ioutil"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/google/trillian/storage/mysql"
+
+	// Load MySQL driver
+	_ "github.com/go-sql-driver/mysql"
+)
+
+var (
+	mySQLURI     = flag.String("mysql_uri", "test:zaphod@tcp(127.0.0.1:3306)/test", "Connection URI for MySQL database")
+	schemaFile   = flag.String("schema_file", "storage/mysql/storage.sql", "Path of the script creating the schema")
+	createSchema = flag.Bool("create_schema", false, "If true, create the schema if the database has none of its tables, otherwise only check the schema")
+	timeout      = flag.Duration("timeout", time.Minute, "Timeout of the creation and check of the schema")
+)
+
+func main() {
+	flag.Parse()
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	db, err := sql.Open("mysql", *mySQLURI)
+	if err != nil {
+		glog.Exitf("failed to open MySQL database: %v", err)
+	}
+	defer db.Close()
+
+	if *createSchema {
+		script, err := ioutil.ReadFile(*schemaFile)
+		if err != nil {
+			glog.Exitf("failed to read schema: %v", err)
+		}
+		switch err := mysql.CreateSchema(ctx, db, string(script)); err {
+		case nil:
+			glog.Infof("Created schema from %v", *schemaFile)
+			return
+		case mysql.ErrSchemaExists:
+			glog.Info("Database already has a schema, checking it")
+		default:
+			glog.Exitf("failed to create schema: %v", err)
+		}
+	}
+
+	if err := mysql.CheckSchema(ctx, db); err != nil {
+		glog.Exitf("schema check failed: %v", err)
+	}
+	glog.Info("Database has the expected schema")
+}
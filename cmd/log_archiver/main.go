@@ -0,0 +1,97 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main contains the implementation and entry point for the
+// log_archiver command, which writes a verifiable archive of all the leaves
+// of a log at a given tree size to object storage, and verifies such
+// archives offline.
+//
+// Example usage:
+// $ ./log_archiver --admin_server=host:port --log_server=host:port --log_id=logid --bucket=gs://bucket/prefix
+//
+// To verify the archive of a log at a given size, without contacting the log:
+// $ ./log_archiver --verify --log_id=logid --tree_size=size --bucket=gs://bucket/prefix
+package main
+
+import (
+	"context"
+	"flag"
+
+	"github.com/golang/glog"
+	"github.com/google/trillian"
+	"github.com/google/trillian/client"
+	"github.com/google/trillian/storage/archive"
+	"github.com/google/trillian/storage/tiles"
+	"google.golang.org/grpc"
+
+	// Load hashers
+	_ "github.com/google/trillian/merkle/objhasher"
+	_ "github.com/google/trillian/merkle/rfc6962"
+)
+
+var (
+	adminServerAddr = flag.String("admin_server", "", "Address of the gRPC Trillian Admin Server (host:port)")
+	logServerAddr   = flag.String("log_server", "", "Address of the gRPC Trillian Log Server (host:port)")
+	logID           = flag.Int64("log_id", 0, "Trillian LogID to archive")
+	treeSize        = flag.Int64("tree_size", 0, "Tree size to archive; zero means the size of the latest tree head")
+	bucketURL       = flag.String("bucket", "", "Bucket to write the archive to: gs://bucket/prefix, s3://bucket/prefix or a local directory")
+	batchSize       = flag.Int64("batch_size", archive.DefaultBatchSize, "Number of leaves per batch; must be a power of two")
+	verify          = flag.Bool("verify", false, "If true, verify the archive at --tree_size instead of writing one")
+)
+
+func main() {
+	flag.Parse()
+	ctx := context.Background()
+
+	bucket, err := tiles.OpenBucket(ctx, *bucketURL)
+	if err != nil {
+		glog.Exitf("failed to open bucket %v: %v", *bucketURL, err)
+	}
+
+	if *verify {
+		m, err := archive.Verify(ctx, bucket, *logID, *treeSize)
+		if err != nil {
+			glog.Exitf("verification failed: %v", err)
+		}
+		glog.Infof("verified archive of %d leaves in %d batches, root hash %x", m.TreeSize, len(m.Batches), m.RootHash)
+		return
+	}
+
+	adminConn, err := grpc.Dial(*adminServerAddr, grpc.WithInsecure())
+	if err != nil {
+		glog.Exitf("failed to dial %v: %v", *adminServerAddr, err)
+	}
+	defer adminConn.Close()
+	tree, err := client.GetTree(ctx, trillian.NewTrillianAdminClient(adminConn), *logID)
+	if err != nil {
+		glog.Exitf("failed to get tree %v: %v", *logID, err)
+	}
+
+	logConn, err := grpc.Dial(*logServerAddr, grpc.WithInsecure())
+	if err != nil {
+		glog.Exitf("failed to dial %v: %v", *logServerAddr, err)
+	}
+	defer logConn.Close()
+
+	a, err := archive.NewArchiver(trillian.NewTrillianLogClient(logConn), tree, bucket)
+	if err != nil {
+		glog.Exitf("failed to create archiver: %v", err)
+	}
+	a.BatchSize = *batchSize
+	m, err := a.Archive(ctx, *treeSize)
+	if err != nil {
+		glog.Exitf("archive failed: %v", err)
+	}
+	glog.Infof("archived %d leaves in %d batches, root hash %x", m.TreeSize, len(m.Batches), m.RootHash)
+}
@@ -0,0 +1,111 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main contains the implementation and entry point for the
+// map_replication_verifier command, which replays the entries of a log
+// through the mapper that derives a map from it, and checks that the roots
+// published by the map match the replayed map.
+//
+// Divergence is logged, and exported as the map_replication_diverged metric
+// for alerting. Mappers other than the identity mapper must be registered
+// with replication.RegisterMapper by a package linked into the binary.
+//
+// Example usage:
+// $ ./map_replication_verifier --admin_server=host:port --log_server=host:port --map_server=host:port --log_id=logid --map_id=mapid --metrics_endpoint=localhost:8099
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/google/trillian"
+	"github.com/google/trillian/client"
+	"github.com/google/trillian/client/replication"
+	"github.com/google/trillian/monitoring/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+
+	// Load hashers
+	_ "github.com/google/trillian/merkle/coniks"
+	_ "github.com/google/trillian/merkle/maphasher"
+	_ "github.com/google/trillian/merkle/objhasher"
+	_ "github.com/google/trillian/merkle/rfc6962"
+)
+
+var (
+	adminServerAddr = flag.String("admin_server", "", "Address of the gRPC Trillian Admin Server (host:port)")
+	logServerAddr   = flag.String("log_server", "", "Address of the gRPC Trillian Log Server (host:port)")
+	mapServerAddr   = flag.String("map_server", "", "Address of the gRPC Trillian Map Server (host:port)")
+	logID           = flag.Int64("log_id", 0, "Trillian LogID of the log the map is derived from")
+	mapID           = flag.Int64("map_id", 0, "Trillian MapID of the map to verify")
+	mapperName      = flag.String("mapper", replication.IdentityMapperName, fmt.Sprintf("Name of the mapper deriving the map from the log, one of: %s", strings.Join(replication.MapperNames(), ", ")))
+	batchSize       = flag.Int64("batch_size", replication.DefaultBatchSize, "Number of log leaves fetched per request")
+	interval        = flag.Duration("interval", replication.DefaultInterval, "Interval between checks for new map revisions")
+	metricsEndpoint = flag.String("metrics_endpoint", "", "Endpoint serving metrics on /metrics, if not empty")
+)
+
+func dial(addr string) *grpc.ClientConn {
+	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		glog.Exitf("failed to dial %v: %v", addr, err)
+	}
+	return conn
+}
+
+func main() {
+	flag.Parse()
+	ctx := context.Background()
+
+	mapper, err := replication.GetMapper(*mapperName)
+	if err != nil {
+		glog.Exitf("%v", err)
+	}
+
+	adminConn := dial(*adminServerAddr)
+	defer adminConn.Close()
+	admin := trillian.NewTrillianAdminClient(adminConn)
+	logTree, err := client.GetTree(ctx, admin, *logID)
+	if err != nil {
+		glog.Exitf("failed to get tree %v: %v", *logID, err)
+	}
+	mapTree, err := client.GetTree(ctx, admin, *mapID)
+	if err != nil {
+		glog.Exitf("failed to get tree %v: %v", *mapID, err)
+	}
+
+	logConn := dial(*logServerAddr)
+	defer logConn.Close()
+	mapConn := dial(*mapServerAddr)
+	defer mapConn.Close()
+
+	v, err := replication.NewVerifier(trillian.NewTrillianLogClient(logConn), trillian.NewTrillianMapClient(mapConn),
+		logTree, mapTree, mapper, prometheus.MetricFactory{}, replication.VerifierOpts{BatchSize: *batchSize, Interval: *interval})
+	if err != nil {
+		glog.Exitf("failed to create verifier: %v", err)
+	}
+
+	if *metricsEndpoint != "" {
+		http.Handle("/metrics", promhttp.Handler())
+		go func() {
+			glog.Exitf("metrics server failed: %v", http.ListenAndServe(*metricsEndpoint, nil))
+		}()
+	}
+
+	glog.Infof("verifying map %d against log %d with mapper %q", *mapID, *logID, *mapperName)
+	v.Run(ctx)
+}
@@ -0,0 +1,200 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main contains the implementation and entry point for the
+// proof_bench command, which benchmarks the generation of inclusion and
+// consistency proofs on large trees of each storage backend, and reports the
+// results.
+//
+// Example usage:
+// $ ./proof_bench --results_file=results.json
+// $ TRILLIAN_INTEGRATION_BACKENDS=sqlite,mysql ./proof_bench --sizes=1000000,1000000000 --baseline=results.json
+//
+// The backends are selected as for the integration tests, see
+// testonly/integration.Backends. Results are written as JSON, and can be
+// passed back as a baseline to a later run, which then exits with status 1 if
+// any benchmark got slower by more than --max_regression.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/golang/glog"
+	"github.com/google/trillian/testonly/integration"
+	"github.com/google/trillian/testonly/proofbench"
+)
+
+var (
+	sizes         = flag.String("sizes", "1000000,10000000,100000000,1000000000", "Comma-separated tree sizes to benchmark")
+	sampleSize    = flag.Int("sample", proofbench.DefaultSampleSize, "Number of distinct proofs of each kind to request")
+	seed          = flag.Int64("seed", 1, "Seed for choosing the proofs requested")
+	resultsFile   = flag.String("results_file", "", "File to write the results to, as JSON")
+	baselineFile  = flag.String("baseline", "", "File with the JSON results of a previous run to compare to")
+	maxRegression = flag.Float64("max_regression", 0.2, "Largest accepted increase of time per proof over --baseline, as a fraction")
+)
+
+// Result is the outcome of the benchmark of one kind of proof, on a tree of
+// one size in one storage backend.
+type Result struct {
+	Backend     string `json:"backend"`
+	Proof       string `json:"proof"`
+	TreeSize    int64  `json:"tree_size"`
+	NsPerOp     int64  `json:"ns_per_op"`
+	AllocsPerOp int64  `json:"allocs_per_op"`
+}
+
+func (r Result) key() string {
+	return fmt.Sprintf("%s/%s/%d", r.Backend, r.Proof, r.TreeSize)
+}
+
+var proofs = []struct {
+	name string
+	get  func(f *proofbench.Fixture, ctx context.Context, i int) error
+}{
+	{"inclusion", (*proofbench.Fixture).InclusionProof},
+	{"consistency", (*proofbench.Fixture).ConsistencyProof},
+}
+
+func parseSizes(s string) ([]int64, error) {
+	var ret []int64
+	for _, f := range strings.Split(s, ",") {
+		size, err := strconv.ParseInt(strings.TrimSpace(f), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tree size %q: %v", f, err)
+		}
+		ret = append(ret, size)
+	}
+	return ret, nil
+}
+
+// run benchmarks each kind of proof at each of treeSizes in backend b.
+func run(ctx context.Context, b integration.Backend, treeSizes []int64) ([]Result, error) {
+	var results []Result
+	for _, size := range treeSizes {
+		registry, done, err := b.NewRegistry(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%s: NewRegistry(): %v", b.Name, err)
+		}
+		f, err := proofbench.NewFixture(ctx, registry, size, *sampleSize, *seed)
+		if err != nil {
+			done()
+			return nil, fmt.Errorf("%s: NewFixture(%d): %v", b.Name, size, err)
+		}
+		for _, p := range proofs {
+			var benchErr error
+			r := testing.Benchmark(func(tb *testing.B) {
+				tb.ReportAllocs()
+				for i := 0; i < tb.N; i++ {
+					if err := p.get(f, ctx, i); err != nil {
+						benchErr = err
+						return
+					}
+				}
+			})
+			if benchErr != nil {
+				done()
+				return nil, fmt.Errorf("%s: %v", b.Name, benchErr)
+			}
+			result := Result{
+				Backend:     b.Name,
+				Proof:       p.name,
+				TreeSize:    size,
+				NsPerOp:     r.NsPerOp(),
+				AllocsPerOp: r.AllocsPerOp(),
+			}
+			fmt.Printf("%-40s %12d ns/op %8d allocs/op\n", result.key(), result.NsPerOp, result.AllocsPerOp)
+			results = append(results, result)
+		}
+		done()
+	}
+	return results, nil
+}
+
+// regressions returns a description of each result slower than its baseline
+// by more than maxRegression.
+func regressions(results, baseline []Result) []string {
+	base := make(map[string]Result)
+	for _, r := range baseline {
+		base[r.key()] = r
+	}
+	var ret []string
+	for _, r := range results {
+		b, ok := base[r.key()]
+		if !ok || b.NsPerOp <= 0 {
+			continue
+		}
+		if change := float64(r.NsPerOp-b.NsPerOp) / float64(b.NsPerOp); change > *maxRegression {
+			ret = append(ret, fmt.Sprintf("%s: %d ns/op, was %d ns/op (%+.0f%%)", r.key(), r.NsPerOp, b.NsPerOp, 100*change))
+		}
+	}
+	return ret
+}
+
+func main() {
+	flag.Parse()
+	ctx := context.Background()
+
+	treeSizes, err := parseSizes(*sizes)
+	if err != nil {
+		glog.Exitf("Invalid --sizes: %v", err)
+	}
+	var baseline []Result
+	if *baselineFile != "" {
+		data, err := ioutil.ReadFile(*baselineFile)
+		if err != nil {
+			glog.Exitf("Failed to read baseline: %v", err)
+		}
+		if err := json.Unmarshal(data, &baseline); err != nil {
+			glog.Exitf("Failed to parse baseline %v: %v", *baselineFile, err)
+		}
+	}
+	backends, err := integration.Backends()
+	if err != nil {
+		glog.Exitf("Failed to get storage backends: %v", err)
+	}
+
+	var results []Result
+	for _, b := range backends {
+		r, err := run(ctx, b, treeSizes)
+		if err != nil {
+			glog.Exitf("Benchmark failed: %v", err)
+		}
+		results = append(results, r...)
+	}
+
+	if *resultsFile != "" {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			glog.Exitf("Failed to marshal results: %v", err)
+		}
+		if err := ioutil.WriteFile(*resultsFile, append(data, '\n'), 0644); err != nil {
+			glog.Exitf("Failed to write results: %v", err)
+		}
+	}
+	if regs := regressions(results, baseline); len(regs) > 0 {
+		fmt.Fprintf(os.Stderr, "Regressions of more than %.0f%% over %v:\n", 100**maxRegression, *baselineFile)
+		for _, r := range regs {
+			fmt.Fprintln(os.Stderr, r)
+		}
+		os.Exit(1)
+	}
+}
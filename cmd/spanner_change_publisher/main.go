@@ -0,0 +1,123 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main contains the implementation and entry point for the
+// spanner_change_publisher command, which reads the leaves and tree heads
+// committed to a Cloud Spanner log database through its LogChanges change
+// stream, and publishes them to a Pub/Sub topic.
+//
+// Each message holds a serialized trillian.LogLeaf or trillian.SignedLogRoot,
+// and has the attributes "tree_id" and "type", which is "leaf" or "root".
+// Messages are published at least once, and not necessarily in order.
+//
+// Example usage:
+// $ ./spanner_change_publisher --cloudspanner_uri=projects/p/instances/i/databases/d --pubsub_project=p --pubsub_topic=trillian-changes
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/spanner"
+	"github.com/golang/glog"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage/cloudspanner"
+)
+
+var (
+	csURI     = flag.String("cloudspanner_uri", "", "Connection URI for CloudSpanner database")
+	stream    = flag.String("change_stream", cloudspanner.DefaultChangeStream, "Name of the change stream on SequencedLeafData and TreeHeads")
+	startTime = flag.String("start_time", "", "RFC 3339 commit time to start reading changes from; defaults to now")
+	heartbeat = flag.Duration("heartbeat", cloudspanner.DefaultChangeStreamHeartbeat, "How often idle change stream partitions report their progress")
+	project   = flag.String("pubsub_project", "", "Project of the Pub/Sub topic")
+	topicID   = flag.String("pubsub_topic", "", "Pub/Sub topic to publish leaves and tree heads to")
+)
+
+// publisher publishes the changes it's given to a Pub/Sub topic.
+type publisher struct {
+	topic *pubsub.Topic
+}
+
+func (p *publisher) SequencedLeaf(ctx context.Context, logID int64, leaf *trillian.LogLeaf) error {
+	return p.publish(ctx, logID, "leaf", leaf)
+}
+
+func (p *publisher) SignedLogRoot(ctx context.Context, root *trillian.SignedLogRoot) error {
+	return p.publish(ctx, root.LogId, "root", root)
+}
+
+func (p *publisher) publish(ctx context.Context, treeID int64, typ string, msg proto.Message) error {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = p.topic.Publish(ctx, &pubsub.Message{
+		Data:       data,
+		Attributes: map[string]string{"tree_id": strconv.FormatInt(treeID, 10), "type": typ},
+	}).Get(ctx)
+	return err
+}
+
+func main() {
+	flag.Parse()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	opts := cloudspanner.ChangeStreamOptions{Stream: *stream, Heartbeat: *heartbeat}
+	if *startTime != "" {
+		t, err := time.Parse(time.RFC3339Nano, *startTime)
+		if err != nil {
+			glog.Exitf("invalid --start_time: %v", err)
+		}
+		opts.StartTime = t
+	}
+
+	client, err := spanner.NewClient(ctx, *csURI)
+	if err != nil {
+		glog.Exitf("failed to connect to %v: %v", *csURI, err)
+	}
+	defer client.Close()
+
+	ps, err := pubsub.NewClient(ctx, *project)
+	if err != nil {
+		glog.Exitf("failed to create Pub/Sub client: %v", err)
+	}
+	defer ps.Close()
+	topic := ps.Topic(*topicID)
+	defer topic.Stop()
+
+	r := cloudspanner.NewChangeStreamReader(client, opts, &publisher{topic: topic})
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		cancel()
+	}()
+
+	err = r.Run(ctx)
+	// Restarting from the watermark republishes some changes, but doesn't
+	// miss any.
+	glog.Infof("stopped; restart with --start_time=%s", r.Watermark().Format(time.RFC3339Nano))
+	if err != nil && ctx.Err() == nil {
+		glog.Exitf("failed to read change stream: %v", err)
+	}
+}
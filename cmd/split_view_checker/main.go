@@ -0,0 +1,99 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main contains the implementation and entry point for the
+// split_view_checker command, which periodically fetches the latest root of a
+// log from each of the replicas serving it, e.g. in several regions, and
+// checks that they are mutually consistent.
+//
+// Divergence is logged, and exported as the split_view_diverged metric for
+// alerting.
+//
+// Example usage:
+// $ ./split_view_checker --admin_server=host:port --log_servers=us=host1:port,eu=host2:port --log_id=logid --metrics_endpoint=localhost:8099
+package main
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/google/trillian"
+	"github.com/google/trillian/client"
+	"github.com/google/trillian/client/splitview"
+	"github.com/google/trillian/monitoring/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+)
+
+var (
+	adminServerAddr = flag.String("admin_server", "", "Address of the gRPC Trillian Admin Server (host:port)")
+	logServerAddrs  = flag.String("log_servers", "", "Comma-separated addresses of the gRPC Trillian Log Server replicas to check (host:port), each optionally prefixed by a name and '=' identifying it in metrics")
+	logID           = flag.Int64("log_id", 0, "Trillian LogID of the log to check")
+	interval        = flag.Duration("interval", splitview.DefaultInterval, "Interval between checks")
+	timeout         = flag.Duration("timeout", splitview.DefaultTimeout, "Timeout of each check")
+	metricsEndpoint = flag.String("metrics_endpoint", "", "Endpoint serving metrics on /metrics, if not empty")
+)
+
+func dial(addr string) *grpc.ClientConn {
+	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		glog.Exitf("failed to dial %v: %v", addr, err)
+	}
+	return conn
+}
+
+func main() {
+	flag.Parse()
+	ctx := context.Background()
+
+	adminConn := dial(*adminServerAddr)
+	defer adminConn.Close()
+	tree, err := client.GetTree(ctx, trillian.NewTrillianAdminClient(adminConn), *logID)
+	if err != nil {
+		glog.Exitf("failed to get tree %v: %v", *logID, err)
+	}
+
+	var replicas []splitview.Replica
+	for _, spec := range strings.Split(*logServerAddrs, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		name, addr := spec, spec
+		if i := strings.Index(spec, "="); i >= 0 {
+			name, addr = spec[:i], spec[i+1:]
+		}
+		conn := dial(addr)
+		defer conn.Close()
+		replicas = append(replicas, splitview.Replica{Name: name, Client: trillian.NewTrillianLogClient(conn)})
+	}
+
+	c, err := splitview.NewChecker(tree, replicas, prometheus.MetricFactory{}, splitview.CheckerOpts{Interval: *interval, Timeout: *timeout})
+	if err != nil {
+		glog.Exitf("failed to create checker: %v", err)
+	}
+
+	if *metricsEndpoint != "" {
+		http.Handle("/metrics", promhttp.Handler())
+		go func() {
+			glog.Exitf("metrics server failed: %v", http.ListenAndServe(*metricsEndpoint, nil))
+		}()
+	}
+
+	glog.Infof("checking %d replicas of log %d", len(replicas), *logID)
+	c.Run(ctx)
+}
@@ -0,0 +1,135 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main contains the implementation and entry point for the
+// tile_exporter command, which keeps the tiles of a log in object storage up
+// to date with its latest tree head.
+//
+// Example usage:
+// $ ./tile_exporter --admin_server=host:port --log_server=host:port --log_id=logid --bucket=gs://bucket/prefix
+//
+// To migrate an existing log to tiles without reading all of its leaves
+// through the log server, the tiles for its latest tree head can be built
+// directly from its storage, after which the exporter can be run as usual:
+// $ ./tile_exporter --from_storage --storage_system=mysql --log_id=logid --bucket=gs://bucket/prefix
+package main
+
+import (
+	"context"
+	"flag"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/google/trillian"
+	"github.com/google/trillian/client"
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/server"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/tiles"
+	"google.golang.org/grpc"
+
+	// Load hashers
+	_ "github.com/google/trillian/merkle/objhasher"
+	_ "github.com/google/trillian/merkle/rfc6962"
+)
+
+var (
+	adminServerAddr = flag.String("admin_server", "", "Address of the gRPC Trillian Admin Server (host:port)")
+	logServerAddr   = flag.String("log_server", "", "Address of the gRPC Trillian Log Server (host:port)")
+	logID           = flag.Int64("log_id", 0, "Trillian LogID to export")
+	bucketURL       = flag.String("bucket", "", "Bucket to export tiles to: gs://bucket/prefix, s3://bucket/prefix or a local directory")
+	interval        = flag.Duration("interval", 10*time.Second, "Interval between checks for new tree heads")
+	batchSize       = flag.Int64("batch_size", tiles.DefaultBatchSize, "Number of leaves to request from the log at a time")
+	once            = flag.Bool("once", false, "If true, export the latest tree head and exit")
+	fromStorage     = flag.Bool("from_storage", false, "If true, export the latest tree head from the storage selected by --storage_system, rather than from the log server, and exit")
+)
+
+func main() {
+	flag.Parse()
+	ctx := context.Background()
+
+	if *fromStorage {
+		exportFromStorage(ctx)
+		return
+	}
+
+	adminConn, err := grpc.Dial(*adminServerAddr, grpc.WithInsecure())
+	if err != nil {
+		glog.Exitf("failed to dial %v: %v", *adminServerAddr, err)
+	}
+	defer adminConn.Close()
+	tree, err := client.GetTree(ctx, trillian.NewTrillianAdminClient(adminConn), *logID)
+	if err != nil {
+		glog.Exitf("failed to get tree %v: %v", *logID, err)
+	}
+
+	logConn, err := grpc.Dial(*logServerAddr, grpc.WithInsecure())
+	if err != nil {
+		glog.Exitf("failed to dial %v: %v", *logServerAddr, err)
+	}
+	defer logConn.Close()
+
+	bucket, err := tiles.OpenBucket(ctx, *bucketURL)
+	if err != nil {
+		glog.Exitf("failed to open bucket %v: %v", *bucketURL, err)
+	}
+	e, err := tiles.NewExporter(trillian.NewTrillianLogClient(logConn), tree, bucket)
+	if err != nil {
+		glog.Exitf("failed to create exporter: %v", err)
+	}
+	e.BatchSize = *batchSize
+
+	if *once {
+		root, err := e.ExportOnce(ctx)
+		if err != nil {
+			glog.Exitf("export failed: %v", err)
+		}
+		glog.Infof("exported tiles for tree size %d", root.TreeSize)
+		return
+	}
+	e.Run(ctx, *interval)
+}
+
+// exportFromStorage writes the tiles for the latest tree head of the log,
+// read directly from its storage.
+func exportFromStorage(ctx context.Context) {
+	sp, err := server.NewStorageProviderFromFlags(monitoring.InertMetricFactory{})
+	if err != nil {
+		glog.Exitf("failed to get storage provider: %v", err)
+	}
+	defer sp.Close()
+
+	tree, err := storage.GetTree(ctx, sp.AdminStorage(), *logID)
+	if err != nil {
+		glog.Exitf("failed to get tree %v: %v", *logID, err)
+	}
+	bucket, err := tiles.OpenBucket(ctx, *bucketURL)
+	if err != nil {
+		glog.Exitf("failed to open bucket %v: %v", *bucketURL, err)
+	}
+
+	tx, err := sp.LogStorage().SnapshotForTree(ctx, *logID)
+	if err != nil {
+		glog.Exitf("failed to start transaction: %v", err)
+	}
+	defer tx.Close()
+	root, err := tiles.ExportFromStorage(ctx, tree, tx, bucket)
+	if err != nil {
+		glog.Exitf("export failed: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		glog.Exitf("failed to commit transaction: %v", err)
+	}
+	glog.Infof("exported tiles for tree size %d", root.TreeSize)
+}
@@ -0,0 +1,267 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The trillian_combined binary runs the Trillian log server (along with an
+// admin server) and the log signer in a single process, for small deployments
+// and local development.
+//
+// The signer acts as master for all logs, so only one instance may run
+// against a given storage, and it must not be run alongside a separate
+// trillian_log_signer. As both halves share the process, it can be used with
+// --storage_system=memory and --quota_system=noop for a self-contained test
+// log:
+// $ ./trillian_combined --storage_system=memory --quota_system=noop
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/google/trillian"
+	"github.com/google/trillian/cmd"
+	"github.com/google/trillian/extension"
+	"github.com/google/trillian/log"
+	"github.com/google/trillian/monitoring/prometheus"
+	"github.com/google/trillian/server"
+	"github.com/google/trillian/util"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"google.golang.org/grpc"
+
+	netcontext "golang.org/x/net/context"
+
+	// Register pprof HTTP handlers
+	_ "net/http/pprof"
+	// Register key ProtoHandlers
+	_ "github.com/google/trillian/crypto/keys/der/proto"
+	_ "github.com/google/trillian/crypto/keys/pem/proto"
+	_ "github.com/google/trillian/crypto/keys/pkcs11/proto"
+	// Load hashers
+	_ "github.com/google/trillian/merkle/objhasher"
+	_ "github.com/google/trillian/merkle/rfc6962"
+)
+
+var (
+	rpcEndpoint  = flag.String("rpc_endpoint", "localhost:8090", "Endpoint for RPC requests (host:port)")
+	httpEndpoint = flag.String("http_endpoint", "localhost:8091", "Endpoint for HTTP metrics and REST requests on (host:port, empty means disabled)")
+	tlsCertFile  = flag.String("tls_cert_file", "", "Path to the TLS server certificate. If unset, the server will use unsecured connections.")
+	tlsKeyFile   = flag.String("tls_key_file", "", "Path to the TLS server key. If unset, the server will use unsecured connections.")
+
+	quotaDryRun = flag.Bool("quota_dry_run", false, "If true no requests are blocked due to lack of tokens")
+
+	treeGCEnabled            = flag.Bool("tree_gc", true, "If true, tree garbage collection (hard-deletion) is periodically performed")
+	treeDeleteThreshold      = flag.Duration("tree_delete_threshold", server.DefaultTreeDeleteThreshold, "Minimum period a tree has to remain deleted before being hard-deleted")
+	treeDeleteMinRunInterval = flag.Duration("tree_delete_min_run_interval", server.DefaultTreeDeleteMinInterval, "Minimum interval between tree garbage collection sweeps. Actual runs happen randomly between [minInterval,2*minInterval).")
+
+	rangeChunkSize = flag.Int("range_chunk_size", server.DefaultRangeChunkSize, "Max number of leaves AddSequencedLeafRange writes to storage in one transaction")
+
+	treeCompactionEnabled        = flag.Bool("tree_compaction", false, "If true, the Merkle node history of frozen logs is periodically compacted into a single revision")
+	treeCompactionMinRunInterval = flag.Duration("tree_compaction_min_run_interval", server.DefaultTreeCompactionMinInterval, "Minimum interval between frozen tree compaction sweeps. Actual runs happen randomly between [minInterval,2*minInterval).")
+
+	sequencerIntervalFlag    = flag.Duration("sequencer_interval", time.Second*10, "Time between each sequencing pass through all logs")
+	batchSizeFlag            = flag.Int("batch_size", 50, "Max number of leaves to process per batch")
+	numSeqFlag               = flag.Int("num_sequencers", 10, "Number of sequencer workers to run in parallel")
+	sequencerGuardWindowFlag = flag.Duration("sequencer_guard_window", 0, "If set, the time elapsed before submitted leaves are eligible for sequencing")
+
+	quotaIncreaseFactor = flag.Float64("quota_increase_factor", log.QuotaIncreaseFactor,
+		"Increase factor for tokens replenished by sequencing-based quotas (1 means a 1:1 relationship between sequenced leaves and replenished tokens)."+
+			"Only effective for --quota_system=etcd.")
+	clampRootTimestamps = flag.Bool("clamp_root_timestamps", log.ClampRootTimestamps,
+		"If true, new roots are timestamped just after the latest root of a log when the clock reads earlier than it, rather than not being signed until the clock catches up.")
+
+	configFile = flag.String("config", "", "Config file containing flags, file contents can be overridden by command line flags")
+)
+
+func main() {
+	flag.Parse()
+
+	if *configFile != "" {
+		if err := cmd.ParseFlagFile(*configFile); err != nil {
+			glog.Exitf("Failed to load flags from config file %q: %s", *configFile, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mf, err := server.NewMetricFactoryFromFlags(prometheus.MetricFactory{})
+	if err != nil {
+		glog.Exitf("Failed to create metric factory: %v", err)
+	}
+
+	sp, err := server.NewStorageProviderFromFlags(mf)
+	if err != nil {
+		glog.Exitf("Failed to get storage provider: %v", err)
+	}
+
+	qm, err := server.NewQuotaManagerFromFlags()
+	if err != nil {
+		glog.Exitf("Error creating quota manager: %v", err)
+	}
+
+	costs, err := server.QuotaCostModelFromFlags()
+	if err != nil {
+		glog.Exitf("Invalid quota cost flags: %v", err)
+	}
+
+	budget, err := server.DeadlineBudgetFromFlags()
+	if err != nil {
+		glog.Exitf("Invalid --storage_deadline_budget: %v", err)
+	}
+
+	breaker, err := server.CircuitBreakerFromFlags(mf, util.SystemTimeSource{})
+	if err != nil {
+		glog.Exitf("Invalid storage circuit breaker flags: %v", err)
+	}
+
+	freshness, err := server.RootFreshnessFromFlags()
+	if err != nil {
+		glog.Exitf("Invalid root freshness flags: %v", err)
+	}
+
+	readMemory, err := server.ReadMemoryLimiterFromFlags()
+	if err != nil {
+		glog.Exitf("Invalid read memory limit flags: %v", err)
+	}
+
+	frontier, err := server.FrontierCacheFromFlags(mf)
+	if err != nil {
+		glog.Exitf("Invalid frontier cache flags: %v", err)
+	}
+
+	gossip, err := server.GossipPoolFromFlags(mf, util.SystemTimeSource{})
+	if err != nil {
+		glog.Exitf("Invalid gossip flags: %v", err)
+	}
+
+	connection, err := server.ConnectionOptsFromFlags()
+	if err != nil {
+		glog.Exitf("Invalid connection flags: %v", err)
+	}
+
+	abuse, err := server.AbuseDetectorFromFlags(qm, mf, util.SystemTimeSource{})
+	if err != nil {
+		glog.Exitf("Invalid abuse detection flags: %v", err)
+	}
+
+	priority, err := server.PrioritySchedulerFromFlags(mf)
+	if err != nil {
+		glog.Exitf("Invalid priority flags: %v", err)
+	}
+
+	proofSigner, err := server.ProofSignerFromFlags(mf, util.SystemTimeSource{})
+	if err != nil {
+		glog.Exitf("Invalid proof signing flags: %v", err)
+	}
+
+	accountant, err := server.AccountantFromFlags(ctx, qm, mf, util.SystemTimeSource{})
+	if err != nil {
+		glog.Exitf("Invalid accounting flags: %v", err)
+	}
+
+	// There's only ever one signer, so it can act as master for all logs
+	// without running an election through etcd.
+	hostname, _ := os.Hostname()
+	instanceID := fmt.Sprintf("%s.%d", hostname, os.Getpid())
+
+	registry := extension.Registry{
+		AdminStorage:    sp.AdminStorage(),
+		LogStorage:      sp.LogStorage(),
+		ElectionFactory: util.NoopElectionFactory{InstanceID: instanceID},
+		QuotaManager:    qm,
+		MetricFactory:   mf,
+		NewKeyProto:     server.KeyGeneratorFromFlags(),
+	}
+
+	log.QuotaIncreaseFactor = *quotaIncreaseFactor
+	log.ClampRootTimestamps = *clampRootTimestamps
+	catchUp, err := server.CatchUpFromFlags()
+	if err != nil {
+		glog.Exitf("Invalid catch-up flags: %v", err)
+	}
+
+	// The log server and signer are set up by server.New, as for logs
+	// embedded in other binaries; Main serves the log server over gRPC.
+	e, err := server.New(registry, server.Options{
+		Signer: &server.LogOperationInfo{
+			BatchSize:   *batchSizeFlag,
+			NumWorkers:  *numSeqFlag,
+			RunInterval: *sequencerIntervalFlag,
+			TimeSource:  server.MonotonicClockFromFlags(mf),
+		},
+		SequencerGuardWindow: *sequencerGuardWindowFlag,
+		CatchUp:              catchUp,
+		SignerPreflight:      server.SignerPreflightFromFlags(),
+		Gossip:               gossip,
+		ReadMemory:           readMemory,
+		DeadlineBudget:       budget,
+		RangeChunkSize:       *rangeChunkSize,
+		CircuitBreaker:       breaker,
+		RootFreshness:        freshness,
+		FrontierCache:        frontier,
+		ReadRepairer:         server.ReadRepairerFromFlags(registry.LogStorage, mf),
+	})
+	if err != nil {
+		glog.Exitf("Failed to set up log: %v", err)
+	}
+	if err := e.Start(ctx); err != nil {
+		glog.Exitf("Failed to start signer: %v", err)
+	}
+
+	m := server.Main{
+		RPCEndpoint:   *rpcEndpoint,
+		HTTPEndpoint:  *httpEndpoint,
+		TLSCertFile:   *tlsCertFile,
+		TLSKeyFile:    *tlsKeyFile,
+		Connection:    connection,
+		StatsPrefix:   "log",
+		QuotaDryRun:   *quotaDryRun,
+		QuotaCosts:    costs,
+		AbuseDetector: abuse,
+		Status:        &server.StatusPage{Registry: registry, Operations: e.Signer(), InstanceID: instanceID},
+		Accountant:    accountant,
+		Priority:      priority,
+		ProofSigner:   proofSigner,
+		// Storage is closed once the sequencer has stopped, below.
+		DBClose:  func() error { return nil },
+		Registry: registry,
+		RegisterHandlerFn: func(ctx netcontext.Context, mux *runtime.ServeMux, endpoint string, opts []grpc.DialOption) error {
+			return trillian.RegisterTrillianLogHandlerFromEndpoint(ctx, mux, endpoint, opts)
+		},
+		RegisterServerFn: func(s *grpc.Server, _ extension.Registry) error {
+			e.RegisterLogServer(s)
+			return nil
+		},
+		AllowedTreeTypes:      []trillian.TreeType{trillian.TreeType_LOG, trillian.TreeType_PREORDERED_LOG},
+		TreeGCEnabled:         *treeGCEnabled,
+		TreeDeleteThreshold:   *treeDeleteThreshold,
+		TreeDeleteMinInterval: *treeDeleteMinRunInterval,
+
+		TreeCompactionEnabled:     *treeCompactionEnabled,
+		TreeCompactionMinInterval: *treeCompactionMinRunInterval,
+	}
+
+	if err := m.Run(ctx); err != nil {
+		glog.Errorf("Server exited with error: %v", err)
+	}
+
+	// Stop sequencing before closing the storage out from under it.
+	e.Stop()
+	if err := sp.Close(); err != nil {
+		glog.Errorf("Failed to close storage: %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const (
+	// treeAPI is the API group and version of Tree resources.
+	treeAPI = "trillian.google.com/v1alpha1"
+
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+)
+
+// kubeClient reads and patches the Tree resources of a Kubernetes cluster
+// through its API server.
+type kubeClient struct {
+	server string
+	token  string
+	client *http.Client
+}
+
+// newKubeClient returns a kubeClient for the API server at server, e.g. one
+// proxied by "kubectl proxy", or for the API server of the cluster the
+// operator runs in, authenticated with its service account, if server is
+// empty.
+func newKubeClient(server string) (*kubeClient, error) {
+	if server != "" {
+		return &kubeClient{server: strings.TrimSuffix(server, "/"), client: http.DefaultClient}, nil
+	}
+
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("not running in a Kubernetes cluster, the API server must be given")
+	}
+	token, err := ioutil.ReadFile(serviceAccountDir + "/token")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account token: %v", err)
+	}
+	ca, err := ioutil.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account CA: %v", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(ca) {
+		return nil, errors.New("no certificates in service account CA")
+	}
+	return &kubeClient{
+		server: "https://" + net.JoinHostPort(host, port),
+		token:  strings.TrimSpace(string(token)),
+		client: &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}},
+	}, nil
+}
+
+// treesPath returns the path of the Tree resources of namespace, or of all
+// namespaces if it's empty.
+func treesPath(namespace string) string {
+	if namespace == "" {
+		return "/apis/" + treeAPI + "/trees"
+	}
+	return "/apis/" + treeAPI + "/namespaces/" + namespace + "/trees"
+}
+
+// listTrees returns the Tree resources of namespace, or of all namespaces if
+// it's empty.
+func (k *kubeClient) listTrees(ctx context.Context, namespace string) ([]*treeResource, error) {
+	var list struct {
+		Items []*treeResource `json:"items"`
+	}
+	if err := k.do(ctx, http.MethodGet, treesPath(namespace), "", nil, &list); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// patchTree applies the JSON merge patch to res, or to its status if status
+// is true.
+func (k *kubeClient) patchTree(ctx context.Context, res *treeResource, status bool, patch interface{}) error {
+	path := treesPath(res.Metadata.Namespace) + "/" + res.Metadata.Name
+	if status {
+		path += "/status"
+	}
+	body, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+	return k.do(ctx, http.MethodPatch, path, "application/merge-patch+json", body, nil)
+}
+
+// do sends a request to the API server, and decodes its JSON response into
+// out if it's not nil.
+func (k *kubeClient) do(ctx context.Context, method, path, contentType string, body []byte, out interface{}) error {
+	req, err := http.NewRequest(method, k.server+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if k.token != "" {
+		req.Header.Set("Authorization", "Bearer "+k.token)
+	}
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%v %v: %v: %s", method, path, resp.Status, b)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(b, out)
+}
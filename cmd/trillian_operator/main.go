@@ -0,0 +1,96 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main contains the implementation and entry point for the
+// trillian_operator command, a Kubernetes operator which creates, updates,
+// freezes and deletes Trillian trees, and their quotas, to match the Tree
+// custom resources of a cluster. The ID of the tree of each resource is
+// recorded in its status.
+//
+// Example usage, in a cluster:
+// $ ./trillian_operator --admin_server=host:port --log_server=host:port --namespace=trillian
+//
+// Example usage, outside of a cluster, through "kubectl proxy":
+// $ ./trillian_operator --admin_server=host:port --log_server=host:port --kube_api_server=http://localhost:8001
+//
+// See examples/deployment/kubernetes/trillian-operator.yaml for the Tree
+// resource definition and an example Tree.
+package main
+
+import (
+	"context"
+	"flag"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/google/trillian"
+	"github.com/google/trillian/client"
+	"github.com/google/trillian/quota/etcd/quotapb"
+	"google.golang.org/grpc"
+)
+
+var (
+	adminServerAddr = flag.String("admin_server", "", "Address of the gRPC Trillian Admin Server (host:port)")
+	logServerAddr   = flag.String("log_server", "", "Address of the gRPC Trillian Log Server (host:port), which initialises the logs created")
+	mapServerAddr   = flag.String("map_server", "", "Address of the gRPC Trillian Map Server (host:port), which initialises the maps created; if empty, maps can't be created")
+	quotaServerAddr = flag.String("quota_server", "", "Address of the gRPC Quota Server (host:port); if empty, the quotas of Tree resources are ignored")
+	kubeAPIServer   = flag.String("kube_api_server", "", "URL of the Kubernetes API server; if empty, that of the cluster the operator runs in")
+	namespace       = flag.String("namespace", "", "Namespace of the Tree resources to reconcile; if empty, all namespaces")
+	resyncInterval  = flag.Duration("resync_interval", 30*time.Second, "Interval between reconciliations of all the Tree resources")
+	rpcDeadline     = flag.Duration("rpc_deadline", time.Minute, "Deadline for each reconciliation of all the Tree resources")
+)
+
+func dial(addr string) *grpc.ClientConn {
+	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		glog.Exitf("failed to dial %v: %v", addr, err)
+	}
+	return conn
+}
+
+func main() {
+	flag.Parse()
+	defer glog.Flush()
+
+	if *adminServerAddr == "" || *logServerAddr == "" {
+		glog.Exit("--admin_server and --log_server must be set")
+	}
+	kube, err := newKubeClient(*kubeAPIServer)
+	if err != nil {
+		glog.Exitf("failed to create Kubernetes client: %v", err)
+	}
+
+	r := &client.TreeReconciler{
+		Admin: trillian.NewTrillianAdminClient(dial(*adminServerAddr)),
+		Log:   trillian.NewTrillianLogClient(dial(*logServerAddr)),
+	}
+	if *mapServerAddr != "" {
+		r.Map = trillian.NewTrillianMapClient(dial(*mapServerAddr))
+	}
+	if *quotaServerAddr != "" {
+		r.Quota = quotapb.NewQuotaClient(dial(*quotaServerAddr))
+	}
+	o := &operator{kube: kube, reconciler: r, namespace: *namespace}
+
+	ticker := time.NewTicker(*resyncInterval)
+	defer ticker.Stop()
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), *rpcDeadline)
+		if err := o.sync(ctx); err != nil {
+			glog.Errorf("failed to list Tree resources: %v", err)
+		}
+		cancel()
+		<-ticker.C
+	}
+}
@@ -0,0 +1,255 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/trillian"
+	"github.com/google/trillian/client"
+	"github.com/google/trillian/crypto/keyspb"
+	"github.com/google/trillian/crypto/sigpb"
+	"github.com/google/trillian/quota/etcd/quotapb"
+)
+
+// treeFinalizer keeps Tree resources around until the operator has deleted
+// their trees.
+const treeFinalizer = "trillian.google.com/tree"
+
+// treeResource is a Tree custom resource, which declares a Trillian tree.
+type treeResource struct {
+	Metadata objectMeta `json:"metadata"`
+	Spec     treeSpec   `json:"spec"`
+	Status   treeStatus `json:"status"`
+}
+
+// objectMeta holds the fields of the Kubernetes object metadata which the
+// operator uses.
+type objectMeta struct {
+	Name              string   `json:"name"`
+	Namespace         string   `json:"namespace"`
+	UID               string   `json:"uid"`
+	Generation        int64    `json:"generation"`
+	DeletionTimestamp *string  `json:"deletionTimestamp"`
+	Finalizers        []string `json:"finalizers"`
+}
+
+// treeSpec is the spec of a Tree resource. Enums are given by name, and
+// default to those of an ACTIVE RFC 6962 log signed with ECDSA.
+type treeSpec struct {
+	TreeType           string            `json:"treeType"`
+	State              string            `json:"state"`
+	HashStrategy       string            `json:"hashStrategy"`
+	HashAlgorithm      string            `json:"hashAlgorithm"`
+	SignatureAlgorithm string            `json:"signatureAlgorithm"`
+	DisplayName        string            `json:"displayName"`
+	Description        string            `json:"description"`
+	MaxRootDuration    string            `json:"maxRootDuration"`
+	Labels             map[string]string `json:"labels"`
+	// Quotas are quota configs, in the JSON encoding of quotapb.Config, by
+	// kind, "read" or "write".
+	Quotas map[string]json.RawMessage `json:"quotas"`
+}
+
+// treeStatus is the status of a Tree resource.
+type treeStatus struct {
+	// TreeID is the ID of the tree, as a string as it's 64 bits.
+	TreeID             string `json:"treeId,omitempty"`
+	State              string `json:"state,omitempty"`
+	ObservedGeneration int64  `json:"observedGeneration,omitempty"`
+	// Error is the error of the last reconciliation, if it failed.
+	Error string `json:"error"`
+}
+
+// treeSpec returns the client.TreeSpec of res.
+func (res *treeResource) treeSpec() (*client.TreeSpec, error) {
+	s := res.Spec
+	tt, err := enumValue("treeType", s.TreeType, trillian.TreeType_LOG.String(), trillian.TreeType_value)
+	if err != nil {
+		return nil, err
+	}
+	ts, err := enumValue("state", s.State, trillian.TreeState_ACTIVE.String(), trillian.TreeState_value)
+	if err != nil {
+		return nil, err
+	}
+	hs, err := enumValue("hashStrategy", s.HashStrategy, trillian.HashStrategy_RFC6962_SHA256.String(), trillian.HashStrategy_value)
+	if err != nil {
+		return nil, err
+	}
+	ha, err := enumValue("hashAlgorithm", s.HashAlgorithm, sigpb.DigitallySigned_SHA256.String(), sigpb.DigitallySigned_HashAlgorithm_value)
+	if err != nil {
+		return nil, err
+	}
+	sa, err := enumValue("signatureAlgorithm", s.SignatureAlgorithm, sigpb.DigitallySigned_ECDSA.String(), sigpb.DigitallySigned_SignatureAlgorithm_value)
+	if err != nil {
+		return nil, err
+	}
+	var maxRootDuration time.Duration
+	if s.MaxRootDuration != "" {
+		if maxRootDuration, err = time.ParseDuration(s.MaxRootDuration); err != nil {
+			return nil, fmt.Errorf("invalid maxRootDuration: %v", err)
+		}
+	}
+
+	spec := &client.TreeSpec{
+		Tree: &trillian.Tree{
+			TreeState:          trillian.TreeState(ts),
+			TreeType:           trillian.TreeType(tt),
+			HashStrategy:       trillian.HashStrategy(hs),
+			HashAlgorithm:      sigpb.DigitallySigned_HashAlgorithm(ha),
+			SignatureAlgorithm: sigpb.DigitallySigned_SignatureAlgorithm(sa),
+			DisplayName:        s.DisplayName,
+			Description:        s.Description,
+			MaxRootDuration:    ptypes.DurationProto(maxRootDuration),
+			Labels:             s.Labels,
+		},
+		KeySpec: &keyspb.Specification{},
+		Owner:   res.Metadata.UID,
+		Deleted: res.Metadata.DeletionTimestamp != nil,
+		Quotas:  make(map[string]*quotapb.Config),
+	}
+	switch spec.Tree.SignatureAlgorithm {
+	case sigpb.DigitallySigned_ECDSA:
+		spec.KeySpec.Params = &keyspb.Specification_EcdsaParams{EcdsaParams: &keyspb.Specification_ECDSA{}}
+	case sigpb.DigitallySigned_RSA:
+		spec.KeySpec.Params = &keyspb.Specification_RsaParams{RsaParams: &keyspb.Specification_RSA{}}
+	}
+	for kind, raw := range s.Quotas {
+		cfg := &quotapb.Config{}
+		if err := jsonpb.Unmarshal(bytes.NewReader(raw), cfg); err != nil {
+			return nil, fmt.Errorf("invalid %v quota: %v", kind, err)
+		}
+		spec.Quotas[kind] = cfg
+	}
+	return spec, nil
+}
+
+// enumValue returns the value of the enum named name in values, or of def if
+// name is empty. field is the field of the spec holding it.
+func enumValue(field, name, def string, values map[string]int32) (int32, error) {
+	if name == "" {
+		name = def
+	}
+	v, ok := values[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown %v: %q", field, name)
+	}
+	return v, nil
+}
+
+// hasFinalizer returns whether res carries the operator's finalizer.
+func (res *treeResource) hasFinalizer() bool {
+	for _, f := range res.Metadata.Finalizers {
+		if f == treeFinalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// operator reconciles the trees declared by Tree resources.
+type operator struct {
+	kube       *kubeClient
+	reconciler *client.TreeReconciler
+	// namespace is the namespace whose Tree resources are reconciled, or
+	// empty for all namespaces.
+	namespace string
+}
+
+// sync reconciles the trees of all the Tree resources.
+func (o *operator) sync(ctx context.Context) error {
+	resources, err := o.kube.listTrees(ctx, o.namespace)
+	if err != nil {
+		return err
+	}
+	for _, res := range resources {
+		if err := o.reconcile(ctx, res); err != nil {
+			glog.Errorf("%v/%v: %v", res.Metadata.Namespace, res.Metadata.Name, err)
+		}
+	}
+	return nil
+}
+
+// reconcile brings the tree of res to its spec, and records the outcome in
+// its status. The finalizer of res is added before its tree is created, and
+// removed once it's deleted.
+func (o *operator) reconcile(ctx context.Context, res *treeResource) error {
+	deleting := res.Metadata.DeletionTimestamp != nil
+	if deleting && !res.hasFinalizer() {
+		return nil
+	}
+	if !deleting && !res.hasFinalizer() {
+		patch := map[string]interface{}{
+			"metadata": map[string]interface{}{"finalizers": append(res.Metadata.Finalizers, treeFinalizer)},
+		}
+		if err := o.kube.patchTree(ctx, res, false, patch); err != nil {
+			return fmt.Errorf("failed to add finalizer: %v", err)
+		}
+	}
+
+	var treeID int64
+	if res.Status.TreeID != "" {
+		var err error
+		if treeID, err = strconv.ParseInt(res.Status.TreeID, 10, 64); err != nil {
+			return fmt.Errorf("invalid status.treeId: %v", err)
+		}
+	}
+	status := res.Status
+	status.ObservedGeneration = res.Metadata.Generation
+	spec, err := res.treeSpec()
+	var tree *trillian.Tree
+	if err == nil {
+		tree, err = o.reconciler.Reconcile(ctx, treeID, spec)
+	}
+	if tree != nil {
+		status.TreeID = strconv.FormatInt(tree.TreeId, 10)
+		status.State = tree.TreeState.String()
+	}
+	status.Error = ""
+	if err != nil {
+		status.Error = err.Error()
+	}
+
+	if deleting {
+		if err != nil {
+			return err
+		}
+		var finalizers []string
+		for _, f := range res.Metadata.Finalizers {
+			if f != treeFinalizer {
+				finalizers = append(finalizers, f)
+			}
+		}
+		glog.Infof("%v/%v: deleted tree %v", res.Metadata.Namespace, res.Metadata.Name, status.TreeID)
+		patch := map[string]interface{}{
+			"metadata": map[string]interface{}{"finalizers": finalizers},
+		}
+		return o.kube.patchTree(ctx, res, false, patch)
+	}
+	if status != res.Status {
+		if perr := o.kube.patchTree(ctx, res, true, map[string]interface{}{"status": status}); perr != nil {
+			return fmt.Errorf("failed to update status: %v", perr)
+		}
+	}
+	return err
+}
@@ -0,0 +1,132 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main contains the implementation and entry point for the
+// trillian_public_proxy command, which serves the read-only RPCs of Trillian
+// logs to the public internet by forwarding them to an internal log server.
+//
+// Only proof, root and leaf lookups are served: the write and admin RPCs
+// aren't registered, so they're rejected as unimplemented. Requests are
+// limited in size and in rate per client IP address, and their responses are
+// cached. Metrics are served on a separate endpoint, which shouldn't be
+// exposed publicly.
+//
+// Example usage:
+// $ ./trillian_public_proxy --log_server=internal:8090 --rpc_endpoint=:443 --tls_cert_file=cert.pem --tls_key_file=key.pem --metrics_endpoint=localhost:8099
+package main
+
+import (
+	"flag"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/google/trillian"
+	"github.com/google/trillian/monitoring/prometheus"
+	"github.com/google/trillian/util"
+	"github.com/google/trillian/util/proxy"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+var (
+	logServerAddr   = flag.String("log_server", "", "Address of the gRPC Trillian Log Server requests are forwarded to (host:port)")
+	rpcEndpoint     = flag.String("rpc_endpoint", "localhost:8090", "Endpoint for public RPC requests (host:port)")
+	metricsEndpoint = flag.String("metrics_endpoint", "", "Endpoint serving metrics on /metrics, if not empty")
+	tlsCertFile     = flag.String("tls_cert_file", "", "Path to the TLS server certificate. If unset, the server will use unsecured connections.")
+	tlsKeyFile      = flag.String("tls_key_file", "", "Path to the TLS server key. If unset, the server will use unsecured connections.")
+
+	maxLeaves   = flag.Int64("max_leaves", 256, "Max number of leaves, indices or hashes a request may ask for")
+	rate        = flag.Float64("rate", 10, "Requests per second each client IP address may make, zero for unlimited")
+	burst       = flag.Int("burst", 50, "Number of requests a client IP address may make in a burst")
+	cacheSize   = flag.Int("cache_size", 100000, "Max number of responses cached, zero to disable caching")
+	cacheMaxAge = flag.Duration("cache_max_age", time.Hour, "How long responses which can't change, such as proofs to a given tree size, are cached for")
+	rootMaxAge  = flag.Duration("root_max_age", time.Second, "How long responses which change as logs grow, such as their latest roots, are cached for")
+	timeout     = flag.Duration("timeout", 5*time.Second, "Max deadline of requests forwarded to the log server")
+
+	maxRecvMsgSize        = flag.Int("max_recv_msg_size", 64*1024, "Max size of requests, in bytes")
+	maxConcurrentStreams  = flag.Uint("max_concurrent_streams", 100, "Max number of concurrent requests per client connection")
+	maxConnectionIdle     = flag.Duration("max_connection_idle", 5*time.Minute, "How long idle client connections are kept open")
+	maxConnectionAge      = flag.Duration("max_connection_age", 30*time.Minute, "How long a client connection may be open before the client is asked to reconnect. Zero for unlimited")
+	maxConnectionAgeGrace = flag.Duration("max_connection_age_grace", time.Minute, "How long requests in flight on a connection older than --max_connection_age may run before it's closed")
+	keepaliveMinTime      = flag.Duration("keepalive_min_time", time.Minute, "Minimum interval at which clients may send keepalive pings; clients pinging more often are disconnected")
+	connectionTimeout     = flag.Duration("connection_timeout", 10*time.Second, "Max time for client connections to be established")
+)
+
+func main() {
+	flag.Parse()
+
+	conn, err := grpc.Dial(*logServerAddr, grpc.WithInsecure())
+	if err != nil {
+		glog.Exitf("failed to dial %v: %v", *logServerAddr, err)
+	}
+	defer conn.Close()
+
+	p, err := proxy.NewPublicLog(trillian.NewTrillianLogClient(conn), proxy.PublicLogOpts{
+		MaxLeaves:   *maxLeaves,
+		Rate:        *rate,
+		Burst:       *burst,
+		CacheSize:   *cacheSize,
+		CacheMaxAge: *cacheMaxAge,
+		RootMaxAge:  *rootMaxAge,
+		Timeout:     *timeout,
+	}, prometheus.MetricFactory{}, util.SystemTimeSource{})
+	if err != nil {
+		glog.Exitf("failed to create proxy: %v", err)
+	}
+
+	connection := util.ConnectionOpts{
+		MaxConcurrentStreams:  uint32(*maxConcurrentStreams),
+		MaxConnectionIdle:     *maxConnectionIdle,
+		MaxConnectionAge:      *maxConnectionAge,
+		MaxConnectionAgeGrace: *maxConnectionAgeGrace,
+		KeepaliveMinTime:      *keepaliveMinTime,
+	}
+	if err := connection.Validate(); err != nil {
+		glog.Exitf("invalid connection flags: %v", err)
+	}
+	serverOpts := append(connection.ServerOptions(),
+		grpc.MaxRecvMsgSize(*maxRecvMsgSize),
+		grpc.ConnectionTimeout(*connectionTimeout),
+	)
+	// Let credentials.NewServerTLSFromFile handle the error case when only one of the flags is set.
+	if *tlsCertFile != "" || *tlsKeyFile != "" {
+		creds, err := credentials.NewServerTLSFromFile(*tlsCertFile, *tlsKeyFile)
+		if err != nil {
+			glog.Exitf("failed to load TLS credentials: %v", err)
+		}
+		serverOpts = append(serverOpts, grpc.Creds(creds))
+	}
+	s := grpc.NewServer(serverOpts...)
+	p.Register(s)
+
+	if *metricsEndpoint != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.Handler())
+		go func() {
+			glog.Exitf("metrics server failed: %v", http.ListenAndServe(*metricsEndpoint, mux))
+		}()
+	}
+
+	lis, err := net.Listen("tcp", *rpcEndpoint)
+	if err != nil {
+		glog.Exitf("failed to listen on %v: %v", *rpcEndpoint, err)
+	}
+	glog.Infof("serving the read-only log API of %v on %v", *logServerAddr, *rpcEndpoint)
+	if err := s.Serve(lis); err != nil {
+		glog.Exitf("server failed: %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main contains the implementation and entry point for the
+// verify_proof command, which verifies log inclusion proofs offline.
+//
+// Example usage:
+// $ ./verify_proof --public_key=log.pem --root=root.json --proof=proof.json --leaf=leaf.bin
+// $ ./verify_proof --public_key=log.pem --bundle=bundle.json
+//
+// The root and proof files hold a SignedLogRoot and a Proof in their JSON
+// encoding, as returned by the log's HTTP API. Bundles are client.ProofBundles.
+//
+// The command prints the result of each check and exits with status 1 if any
+// of them failed, or status 2 if its input couldn't be read.
+package main
+
+import (
+	"crypto"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian"
+	"github.com/google/trillian/client"
+	"github.com/google/trillian/crypto/keys/der"
+	"github.com/google/trillian/crypto/keys/pem"
+	"github.com/google/trillian/crypto/keyspb"
+	"github.com/google/trillian/merkle"
+	"github.com/google/trillian/merkle/hashers"
+
+	tcrypto "github.com/google/trillian/crypto"
+	_ "github.com/google/trillian/merkle/objhasher" // Register the ObjectHash hasher
+	_ "github.com/google/trillian/merkle/rfc6962"   // Register the RFC6962 hasher
+)
+
+var (
+	publicKeyFile = flag.String("public_key", "", "File containing the log's PEM-encoded public key")
+	hashStrategy  = flag.String("hash_strategy", trillian.HashStrategy_RFC6962_SHA256.String(), "Hash strategy of the log")
+
+	rootFile  = flag.String("root", "", "File containing the JSON-encoded SignedLogRoot to verify the proof against")
+	proofFile = flag.String("proof", "", "File containing the JSON-encoded inclusion Proof")
+	leafFile  = flag.String("leaf", "", "File containing the leaf value. Mutually exclusive with --leaf_hash")
+	leafHash  = flag.String("leaf_hash", "", "Base64-encoded Merkle leaf hash. Mutually exclusive with --leaf")
+
+	bundleFile = flag.String("bundle", "", "File containing a proof bundle. Replaces --root, --proof, --leaf and --leaf_hash")
+	logID      = flag.Int64("log_id", 0, "ID the log of the bundle must have; zero accepts any")
+
+	errNoPublicKey = errors.New("empty --public_key, please provide the log's public key")
+	errNoInput     = errors.New("please provide either --bundle, or --root, --proof and one of --leaf or --leaf_hash")
+)
+
+// check is the result of one verification step.
+type check struct {
+	name string
+	err  error
+}
+
+func (c check) String() string {
+	if c.err != nil {
+		return fmt.Sprintf("%s: FAILED: %v", c.name, c.err)
+	}
+	return fmt.Sprintf("%s: OK", c.name)
+}
+
+// verify performs the verification specified by flags, returning the results
+// of its checks. It returns an error if the input couldn't be read.
+func verify() ([]check, error) {
+	if *publicKeyFile == "" {
+		return nil, errNoPublicKey
+	}
+	pubKey, err := pem.ReadPublicKeyFile(*publicKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	strategy, ok := trillian.HashStrategy_value[*hashStrategy]
+	if !ok {
+		return nil, fmt.Errorf("unknown hash strategy %q", *hashStrategy)
+	}
+	hasher, err := hashers.NewLogHasher(trillian.HashStrategy(strategy))
+	if err != nil {
+		return nil, err
+	}
+
+	if *bundleFile != "" {
+		return verifyBundle(pubKey, trillian.HashStrategy(strategy))
+	}
+	if *rootFile == "" || *proofFile == "" || (*leafFile == "") == (*leafHash == "") {
+		return nil, errNoInput
+	}
+
+	var root trillian.SignedLogRoot
+	if err := readJSON(*rootFile, &root); err != nil {
+		return nil, err
+	}
+	var proof trillian.Proof
+	if err := readJSON(*proofFile, &proof); err != nil {
+		return nil, err
+	}
+	var hash []byte
+	if *leafFile != "" {
+		value, err := ioutil.ReadFile(*leafFile)
+		if err != nil {
+			return nil, err
+		}
+		if hash, err = hasher.HashLeaf(value); err != nil {
+			return nil, err
+		}
+	} else if hash, err = base64.StdEncoding.DecodeString(*leafHash); err != nil {
+		return nil, fmt.Errorf("invalid --leaf_hash: %v", err)
+	}
+
+	checks := []check{{name: "root signature", err: verifyRootSignature(pubKey, &root)}}
+	v := merkle.NewLogVerifier(hasher)
+	err = v.VerifyInclusionProof(proof.LeafIndex, root.TreeSize, proof.Hashes, root.RootHash, hash)
+	checks = append(checks, check{
+		name: fmt.Sprintf("inclusion of leaf %d in tree of size %d", proof.LeafIndex, root.TreeSize),
+		err:  err,
+	})
+	return checks, nil
+}
+
+// verifyBundle verifies the proof bundle in --bundle.
+func verifyBundle(pubKey crypto.PublicKey, strategy trillian.HashStrategy) ([]check, error) {
+	data, err := ioutil.ReadFile(*bundleFile)
+	if err != nil {
+		return nil, err
+	}
+	bundle, err := client.ParseProofBundle(data)
+	if err != nil {
+		return nil, err
+	}
+	keyDER, err := der.MarshalPublicKey(pubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	id := *logID
+	if id == 0 {
+		id = bundle.LogID
+	}
+	tree := &trillian.Tree{
+		TreeId:       id,
+		TreeType:     trillian.TreeType_LOG,
+		HashStrategy: strategy,
+		PublicKey:    &keyspb.PublicKey{Der: keyDER},
+	}
+	return []check{{
+		name: fmt.Sprintf("bundle for leaf %d of log %d", bundle.Leaf.GetLeafIndex(), bundle.LogID),
+		err:  client.VerifyProofBundle(bundle, tree),
+	}}, nil
+}
+
+func verifyRootSignature(pubKey crypto.PublicKey, root *trillian.SignedLogRoot) error {
+	return tcrypto.VerifyLogRoot(pubKey, root)
+}
+
+func readJSON(file string, pb proto.Message) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := jsonpb.Unmarshal(f, pb); err != nil {
+		return fmt.Errorf("failed to parse %v: %v", file, err)
+	}
+	return nil
+}
+
+func main() {
+	flag.Parse()
+
+	checks, err := verify()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	failed := false
+	for _, c := range checks {
+		fmt.Println(c)
+		failed = failed || c.err != nil
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
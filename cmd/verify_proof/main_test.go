@@ -0,0 +1,157 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian"
+	"github.com/google/trillian/crypto/keys/pem"
+	"github.com/google/trillian/merkle"
+	"github.com/google/trillian/merkle/rfc6962"
+	"github.com/google/trillian/testonly"
+	"github.com/google/trillian/util/flagsaver"
+
+	tcrypto "github.com/google/trillian/crypto"
+)
+
+func TestVerify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "verify_proof")
+	if err != nil {
+		t.Fatalf("TempDir(): %v", err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, data, 0600); err != nil {
+			t.Fatalf("WriteFile(): %v", err)
+		}
+		return path
+	}
+	writeJSON := func(name string, pb proto.Message) string {
+		s, err := (&jsonpb.Marshaler{}).MarshalToString(pb)
+		if err != nil {
+			t.Fatalf("MarshalToString(): %v", err)
+		}
+		return write(name, []byte(s))
+	}
+
+	key, err := pem.UnmarshalPrivateKey(testonly.DemoPrivateKey, testonly.DemoPrivateKeyPass)
+	if err != nil {
+		t.Fatalf("UnmarshalPrivateKey(): %v", err)
+	}
+	mt := merkle.NewInMemoryMerkleTree(rfc6962.DefaultHasher)
+	for _, leaf := range []string{"A", "B", "C"} {
+		if _, _, err := mt.AddLeaf([]byte(leaf)); err != nil {
+			t.Fatalf("AddLeaf(): %v", err)
+		}
+	}
+	root := &trillian.SignedLogRoot{TreeSize: mt.LeafCount(), RootHash: mt.CurrentRoot().Hash(), TimestampNanos: 1000}
+	if root.Signature, err = tcrypto.NewSHA256Signer(key).SignLogRoot(root); err != nil {
+		t.Fatalf("SignLogRoot(): %v", err)
+	}
+	proof := &trillian.Proof{LeafIndex: 1}
+	for _, entry := range mt.PathToCurrentRoot(2) {
+		proof.Hashes = append(proof.Hashes, entry.Value.Hash())
+	}
+	badRoot := proto.Clone(root).(*trillian.SignedLogRoot)
+	badRoot.TimestampNanos++
+	hashB, err := rfc6962.DefaultHasher.HashLeaf([]byte("B"))
+	if err != nil {
+		t.Fatalf("HashLeaf(): %v", err)
+	}
+
+	publicKeyPath := write("key.pem", []byte(testonly.DemoPublicKey))
+	rootPath := writeJSON("root.json", root)
+	badRootPath := writeJSON("bad_root.json", badRoot)
+	proofPath := writeJSON("proof.json", proof)
+	leafPath := write("leaf", []byte("B"))
+	wrongLeafPath := write("wrong_leaf", []byte("C"))
+
+	for _, test := range []struct {
+		desc       string
+		setFlags   func()
+		wantErr    bool
+		wantFailed []bool
+	}{
+		{
+			desc:       "valid",
+			setFlags:   func() { *leafFile = leafPath },
+			wantFailed: []bool{false, false},
+		},
+		{
+			desc:       "validHash",
+			setFlags:   func() { *leafHash = base64.StdEncoding.EncodeToString(hashB) },
+			wantFailed: []bool{false, false},
+		},
+		{
+			desc:       "wrongLeaf",
+			setFlags:   func() { *leafFile = wrongLeafPath },
+			wantFailed: []bool{false, true},
+		},
+		{
+			desc:       "badSignature",
+			setFlags:   func() { *rootFile, *leafFile = badRootPath, leafPath },
+			wantFailed: []bool{true, false},
+		},
+		{
+			desc:     "noPublicKey",
+			setFlags: func() { *publicKeyFile, *leafFile = "", leafPath },
+			wantErr:  true,
+		},
+		{
+			desc:     "noLeaf",
+			wantErr:  true,
+			setFlags: func() {},
+		},
+		{
+			desc:     "leafAndHash",
+			setFlags: func() { *leafFile, *leafHash = leafPath, "AAAA" },
+			wantErr:  true,
+		},
+		{
+			desc:     "missingRoot",
+			setFlags: func() { *rootFile, *leafFile = filepath.Join(dir, "missing.json"), leafPath },
+			wantErr:  true,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			defer flagsaver.Save().Restore()
+			*publicKeyFile = publicKeyPath
+			*rootFile = rootPath
+			*proofFile = proofPath
+			test.setFlags()
+
+			checks, err := verify()
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("verify()=_,%v; want err? %v", err, test.wantErr)
+			}
+			if len(checks) != len(test.wantFailed) {
+				t.Fatalf("verify()=%v; want %d checks", checks, len(test.wantFailed))
+			}
+			for i, c := range checks {
+				if failed := c.err != nil; failed != test.wantFailed[i] {
+					t.Errorf("verify() check %q failed? %v; want %v", c, failed, test.wantFailed[i])
+				}
+			}
+		})
+	}
+}
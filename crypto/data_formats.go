@@ -15,41 +15,31 @@
 package crypto
 
 import (
-	"encoding/base64"
 	"fmt"
-	"strconv"
 
-	"github.com/benlaurie/objecthash/go/objecthash"
+	"github.com/golang/protobuf/proto"
 	"github.com/google/trillian"
+	"github.com/google/trillian/client/verifier"
 )
 
 // This file contains struct specific mappings and data structures.
 // TODO(gdbelvin): remove data-structure specific operations.
 
-// Constants used as map keys when building input for ObjectHash. They must not be changed
-// as this will change the output of hashRoot()
-const (
-	mapKeyRootHash       string = "RootHash"
-	mapKeyTimestampNanos string = "TimestampNanos"
-	mapKeyTreeSize       string = "TreeSize"
-)
-
 // HashLogRoot hashes SignedLogRoot objects using ObjectHash with
 // "RootHash", "TimestampNanos", and "TreeSize", used as keys in
-// a map.
+// a map, and "Metadata" if the root has any.
 func HashLogRoot(root trillian.SignedLogRoot) ([]byte, error) {
-	// Pull out the fields we want to hash.
-	// Caution: use string format for int64 values as they can overflow when
-	// JSON encoded otherwise (it uses floats). We want to be sure that people
-	// using JSON to verify hashes can build the exact same input to ObjectHash.
-	rootMap := map[string]interface{}{
-		mapKeyRootHash:       base64.StdEncoding.EncodeToString(root.RootHash),
-		mapKeyTimestampNanos: strconv.FormatInt(root.TimestampNanos, 10),
-		mapKeyTreeSize:       strconv.FormatInt(root.TreeSize, 10)}
-
-	hash, err := objecthash.ObjectHash(rootMap)
-	if err != nil {
-		return nil, fmt.Errorf("ObjectHash(%#v): %v", rootMap, err)
+	var meta []byte
+	if root.Metadata != nil {
+		var err error
+		if meta, err = proto.Marshal(root.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to marshal root metadata: %v", err)
+		}
 	}
-	return hash[:], nil
+	return verifier.HashLogRoot(verifier.LogRoot{
+		TreeSize:       root.TreeSize,
+		RootHash:       root.RootHash,
+		TimestampNanos: root.TimestampNanos,
+		Metadata:       meta,
+	})
 }
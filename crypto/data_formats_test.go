@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"testing"
 
+	"github.com/golang/protobuf/ptypes/any"
 	"github.com/google/trillian"
 	"github.com/google/trillian/testonly"
 )
@@ -74,6 +75,14 @@ func TestHashLogRoot(t *testing.T) {
 				TreeSize:       3,
 			},
 		},
+		{
+			root: trillian.SignedLogRoot{
+				TimestampNanos: 2267709,
+				RootHash:       []byte("Islington"),
+				TreeSize:       2,
+				Metadata:       &any.Any{TypeUrl: "type.googleapis.com/trillian.LogRootMetadata", Value: []byte("Islington")},
+			},
+		},
 	} {
 		hash, err := HashLogRoot(test.root)
 		if err != nil {
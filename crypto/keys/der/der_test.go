@@ -16,6 +16,8 @@ package der_test
 
 import (
 	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
 	"encoding/base64"
 	"testing"
 
@@ -149,6 +151,25 @@ func TestMarshalUnmarshalPublicKey(t *testing.T) {
 	}
 }
 
+func TestMarshalUnmarshalEd25519PrivateKey(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey(): %v", err)
+	}
+
+	keyDER, err := MarshalPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPrivateKey(): %v", err)
+	}
+	key2, err := UnmarshalPrivateKey(keyDER)
+	if err != nil {
+		t.Fatalf("UnmarshalPrivateKey(%x): %v", keyDER, err)
+	}
+	if got, ok := key2.(ed25519.PrivateKey); !ok || !got.Equal(key) {
+		t.Errorf("UnmarshalPrivateKey(MarshalPrivateKey(key)) = %v, want %v", key2, key)
+	}
+}
+
 func TestFromToPublicProto(t *testing.T) {
 	keyDER, err := base64.StdEncoding.DecodeString(pubKeyBase64)
 	if err != nil {
@@ -0,0 +1,49 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keys
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/trillian/crypto/keyspb"
+)
+
+// The handler for keyspb.ExternalKey is always registered, as it only unwraps
+// the key proto within, which is handled by whichever ProtoHandler is
+// registered for its type.
+func init() {
+	RegisterHandler(&keyspb.ExternalKey{}, newExternalSigner)
+}
+
+// newExternalSigner returns the signer of the key proto within a
+// keyspb.ExternalKey.
+func newExternalSigner(ctx context.Context, pb proto.Message) (crypto.Signer, error) {
+	ext, ok := pb.(*keyspb.ExternalKey)
+	if !ok {
+		return nil, fmt.Errorf("external: got %T, want *keyspb.ExternalKey", pb)
+	}
+	if ext.Key == nil {
+		return nil, fmt.Errorf("external: no key")
+	}
+	var keyProto ptypes.DynamicAny
+	if err := ptypes.UnmarshalAny(ext.Key, &keyProto); err != nil {
+		return nil, fmt.Errorf("external: failed to unmarshal key: %v", err)
+	}
+	return NewSigner(ctx, keyProto.Message)
+}
@@ -0,0 +1,62 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keys_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/golang/protobuf/ptypes/empty"
+	. "github.com/google/trillian/crypto/keys"
+	"github.com/google/trillian/crypto/keys/pem"
+	"github.com/google/trillian/crypto/keyspb"
+	"github.com/google/trillian/testonly"
+)
+
+func TestNewSignerExternalKey(t *testing.T) {
+	wantSigner, err := pem.UnmarshalPrivateKey(testonly.DemoPrivateKey, testonly.DemoPrivateKeyPass)
+	if err != nil {
+		t.Fatalf("Error unmarshaling test private key: %v", err)
+	}
+	RegisterHandler(&empty.Empty{}, fakeHandler(wantSigner, nil))
+	defer UnregisterHandler(&empty.Empty{})
+
+	emptyKey, err := ptypes.MarshalAny(&empty.Empty{})
+	if err != nil {
+		t.Fatalf("MarshalAny(): %v", err)
+	}
+	unknownKey := &any.Any{TypeUrl: "type.googleapis.com/unknown.Key"}
+
+	for _, test := range []struct {
+		desc     string
+		keyProto proto.Message
+		wantErr  bool
+	}{
+		{desc: "registeredKey", keyProto: &keyspb.ExternalKey{Key: emptyKey}},
+		{desc: "noKey", keyProto: &keyspb.ExternalKey{}, wantErr: true},
+		{desc: "unknownKey", keyProto: &keyspb.ExternalKey{Key: unknownKey}, wantErr: true},
+	} {
+		gotSigner, err := NewSigner(context.Background(), test.keyProto)
+		switch gotErr := err != nil; {
+		case gotErr != test.wantErr:
+			t.Errorf("%v: NewSigner() = (_, %q), want err? %v", test.desc, err, test.wantErr)
+		case !gotErr && gotSigner != wantSigner:
+			t.Errorf("%v: NewSigner() = (%#v, _), want (%#v, _)", test.desc, gotSigner, wantSigner)
+		}
+	}
+}
@@ -13,12 +13,14 @@ It has these top-level messages:
 	PrivateKey
 	PublicKey
 	PKCS11Config
+	ExternalKey
 */
 package keyspb
 
 import proto "github.com/golang/protobuf/proto"
 import fmt "fmt"
 import math "math"
+import google_protobuf1 "github.com/golang/protobuf/ptypes/any"
 
 // Reference imports to suppress errors if they are not otherwise used.
 var _ = proto.Marshal
@@ -325,6 +327,28 @@ func (m *PKCS11Config) GetPublicKey() string {
 	return ""
 }
 
+// ExternalKey identifies a private key held by a remote signing service or
+// HSM, which only the servers that sign tree heads can use. As the admin server
+// can't check that such a key matches the public key of a tree, trees using one
+// must be created with a key_challenge_signature (see CreateTreeRequest).
+type ExternalKey struct {
+	// The key proto that the servers signing tree heads pass to their
+	// keys.ProtoHandler, e.g. a PKCS11Config.
+	Key *google_protobuf1.Any `protobuf:"bytes,1,opt,name=key" json:"key,omitempty"`
+}
+
+func (m *ExternalKey) Reset()                    { *m = ExternalKey{} }
+func (m *ExternalKey) String() string            { return proto.CompactTextString(m) }
+func (*ExternalKey) ProtoMessage()               {}
+func (*ExternalKey) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *ExternalKey) GetKey() *google_protobuf1.Any {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*Specification)(nil), "keyspb.Specification")
 	proto.RegisterType((*Specification_ECDSA)(nil), "keyspb.Specification.ECDSA")
@@ -333,37 +357,40 @@ func init() {
 	proto.RegisterType((*PrivateKey)(nil), "keyspb.PrivateKey")
 	proto.RegisterType((*PublicKey)(nil), "keyspb.PublicKey")
 	proto.RegisterType((*PKCS11Config)(nil), "keyspb.PKCS11Config")
+	proto.RegisterType((*ExternalKey)(nil), "keyspb.ExternalKey")
 	proto.RegisterEnum("keyspb.Specification_ECDSA_Curve", Specification_ECDSA_Curve_name, Specification_ECDSA_Curve_value)
 }
 
 func init() { proto.RegisterFile("crypto/keyspb/keyspb.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 403 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x92, 0x5f, 0x6f, 0xd3, 0x30,
-	0x14, 0xc5, 0xd7, 0x76, 0xad, 0x9a, 0xdb, 0x0e, 0x05, 0x3f, 0x6d, 0x41, 0xe3, 0x4f, 0x9e, 0x26,
-	0x1e, 0x12, 0x35, 0x63, 0x30, 0x21, 0x1e, 0xe8, 0xb2, 0x4c, 0x48, 0x1d, 0x52, 0xe4, 0x30, 0x1e,
-	0x78, 0x09, 0x4e, 0xea, 0x65, 0x56, 0xb3, 0xd8, 0x72, 0xdc, 0xa1, 0xf0, 0xa1, 0xf8, 0x8c, 0x28,
-	0x37, 0x19, 0x68, 0xd2, 0xb4, 0x27, 0x9f, 0x6b, 0x9f, 0x9f, 0xcf, 0xbd, 0xd2, 0x05, 0x27, 0xd7,
-	0x8d, 0x32, 0xd2, 0xdf, 0xf0, 0xa6, 0x56, 0x59, 0x7f, 0x78, 0x4a, 0x4b, 0x23, 0xc9, 0xa4, 0xab,
-	0xdc, 0x3f, 0x43, 0xd8, 0x4b, 0x14, 0xcf, 0xc5, 0xb5, 0xc8, 0x99, 0x11, 0xb2, 0x22, 0x9f, 0x61,
-	0xce, 0xf3, 0x75, 0xcd, 0x52, 0xc5, 0x34, 0xbb, 0xad, 0xf7, 0x07, 0xaf, 0x07, 0x47, 0xb3, 0xe0,
-	0x85, 0xd7, 0xe3, 0x0f, 0xcc, 0x5e, 0x14, 0x9e, 0x27, 0xcb, 0x2f, 0x3b, 0x74, 0x86, 0x48, 0x8c,
-	0x04, 0xf9, 0x08, 0xa0, 0xff, 0xf3, 0x43, 0xe4, 0x0f, 0x1e, 0xe7, 0x29, 0xd2, 0x96, 0xbe, 0x67,
-	0x9d, 0xdf, 0x30, 0xc6, 0x3f, 0xc9, 0x07, 0x18, 0xe7, 0x5b, 0x7d, 0xc7, 0x31, 0xff, 0x59, 0xf0,
-	0xe6, 0x89, 0x7c, 0x2f, 0x6c, 0x8d, 0xb4, 0xf3, 0xbb, 0xa7, 0x30, 0xc6, 0x9a, 0x3c, 0x87, 0xbd,
-	0xf3, 0xe8, 0x62, 0x79, 0x75, 0xf9, 0x2d, 0x0d, 0xaf, 0xe8, 0xf7, 0xc8, 0xde, 0x21, 0x53, 0xd8,
-	0x8d, 0x83, 0x93, 0xf7, 0xf6, 0x00, 0xd5, 0xf1, 0xe9, 0x3b, 0x7b, 0x88, 0xea, 0x24, 0x58, 0xd8,
-	0x23, 0xe7, 0x00, 0x46, 0x34, 0x59, 0x12, 0x02, 0xbb, 0x99, 0x30, 0xdd, 0xe0, 0x63, 0x8a, 0xfa,
-	0x6c, 0x0a, 0x93, 0x6e, 0x1c, 0xf7, 0x13, 0x40, 0x1c, 0x7d, 0x5d, 0xf1, 0xe6, 0x42, 0x94, 0xbc,
-	0xf5, 0x2a, 0x66, 0x6e, 0xd0, 0x6b, 0x51, 0xd4, 0xc4, 0x81, 0xa9, 0x62, 0x75, 0xfd, 0x4b, 0xea,
-	0x35, 0x0e, 0x6f, 0xd1, 0x7f, 0xb5, 0xfb, 0x12, 0x20, 0xd6, 0xe2, 0x8e, 0x19, 0xbe, 0xe2, 0x0d,
-	0xb1, 0x61, 0xb4, 0xe6, 0x1a, 0xe1, 0x39, 0x6d, 0xa5, 0x7b, 0x08, 0x56, 0xbc, 0xcd, 0x4a, 0x91,
-	0x3f, 0xfe, 0xfc, 0x13, 0xe6, 0xf1, 0x2a, 0x4c, 0x16, 0x8b, 0x50, 0x56, 0xd7, 0xa2, 0x20, 0xaf,
-	0x60, 0x66, 0xe4, 0x86, 0x57, 0x69, 0xc9, 0x32, 0x5e, 0xf6, 0x5d, 0x00, 0x5e, 0x5d, 0xb6, 0x37,
-	0xed, 0x17, 0x4a, 0x54, 0x7d, 0x1b, 0xad, 0x24, 0x87, 0x00, 0x0a, 0x13, 0xd2, 0x0d, 0x6f, 0xf6,
-	0x47, 0xf8, 0x60, 0xa9, 0xfb, 0xcc, 0xb3, 0xb7, 0x3f, 0x8e, 0x0a, 0x61, 0x6e, 0xb6, 0x99, 0x97,
-	0xcb, 0x5b, 0xbf, 0x90, 0xb2, 0x28, 0xb9, 0x6f, 0xb4, 0x28, 0x4b, 0xc1, 0x2a, 0xff, 0xc1, 0x42,
-	0x65, 0x13, 0x5c, 0xa5, 0xe3, 0xbf, 0x01, 0x00, 0x00, 0xff, 0xff, 0xf1, 0x64, 0xdf, 0x9a, 0x68,
-	0x02, 0x00, 0x00,
+	// 442 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x92, 0x4d, 0x6f, 0xd3, 0x40,
+	0x10, 0x86, 0x9b, 0xb8, 0x89, 0xe2, 0x49, 0x8a, 0xcc, 0x8a, 0x43, 0x63, 0x54, 0x3e, 0x7c, 0x40,
+	0x15, 0x07, 0x5b, 0x49, 0x09, 0x54, 0x88, 0x03, 0xa9, 0xeb, 0x0a, 0x29, 0x45, 0xb2, 0x36, 0x94,
+	0x03, 0x97, 0xb0, 0x76, 0x36, 0xe9, 0x2a, 0xae, 0x77, 0xb5, 0xde, 0x14, 0xcc, 0x8f, 0xe2, 0x37,
+	0x22, 0x8f, 0x1d, 0x50, 0xa5, 0x88, 0xd3, 0xbe, 0x33, 0xfb, 0x3e, 0xf3, 0x21, 0x0d, 0xb8, 0xa9,
+	0x2e, 0x95, 0x91, 0xc1, 0x86, 0x97, 0x85, 0x4a, 0x9a, 0xc7, 0x57, 0x5a, 0x1a, 0x49, 0xba, 0x75,
+	0xe4, 0x0e, 0xd7, 0x52, 0xae, 0x33, 0x1e, 0x60, 0x36, 0xd9, 0xae, 0x02, 0x96, 0x97, 0xb5, 0xc5,
+	0xfb, 0xdd, 0x86, 0xa3, 0xb9, 0xe2, 0xa9, 0x58, 0x89, 0x94, 0x19, 0x21, 0x73, 0xf2, 0x11, 0x06,
+	0x3c, 0x5d, 0x16, 0x6c, 0xa1, 0x98, 0x66, 0x77, 0xc5, 0x71, 0xeb, 0x45, 0xeb, 0xb4, 0x3f, 0x7e,
+	0xea, 0x37, 0x95, 0x1f, 0x98, 0xfd, 0x28, 0xbc, 0x9c, 0x4f, 0x3f, 0x1d, 0xd0, 0x3e, 0x22, 0x31,
+	0x12, 0xe4, 0x3d, 0x80, 0xfe, 0xc7, 0xb7, 0x91, 0x1f, 0xee, 0xe7, 0x29, 0xd2, 0xb6, 0xde, 0xb1,
+	0xee, 0x2f, 0xe8, 0x60, 0x4d, 0xf2, 0x0e, 0x3a, 0xe9, 0x56, 0xdf, 0x73, 0xec, 0xff, 0x68, 0xfc,
+	0xf2, 0x3f, 0xfd, 0xfd, 0xb0, 0x32, 0xd2, 0xda, 0xef, 0x9d, 0x43, 0x07, 0x63, 0xf2, 0x18, 0x8e,
+	0x2e, 0xa3, 0xab, 0xe9, 0xcd, 0xf5, 0x97, 0x45, 0x78, 0x43, 0xbf, 0x46, 0xce, 0x01, 0xe9, 0xc1,
+	0x61, 0x3c, 0x9e, 0xbc, 0x75, 0x5a, 0xa8, 0xce, 0xce, 0xdf, 0x38, 0x6d, 0x54, 0x93, 0xf1, 0xc8,
+	0xb1, 0xdc, 0x21, 0x58, 0x74, 0x3e, 0x25, 0x04, 0x0e, 0x13, 0x61, 0xea, 0xc5, 0x3b, 0x14, 0xf5,
+	0x45, 0x0f, 0xba, 0xf5, 0x3a, 0xde, 0x07, 0x80, 0x38, 0xfa, 0x3c, 0xe3, 0xe5, 0x95, 0xc8, 0x78,
+	0xe5, 0x55, 0xcc, 0xdc, 0xa2, 0xd7, 0xa6, 0xa8, 0x89, 0x0b, 0x3d, 0xc5, 0x8a, 0xe2, 0x87, 0xd4,
+	0x4b, 0x5c, 0xde, 0xa6, 0x7f, 0x63, 0xef, 0x19, 0x40, 0xac, 0xc5, 0x3d, 0x33, 0x7c, 0xc6, 0x4b,
+	0xe2, 0x80, 0xb5, 0xe4, 0x1a, 0xe1, 0x01, 0xad, 0xa4, 0x77, 0x02, 0x76, 0xbc, 0x4d, 0x32, 0x91,
+	0xee, 0xff, 0xfe, 0x0e, 0x83, 0x78, 0x16, 0xce, 0x47, 0xa3, 0x50, 0xe6, 0x2b, 0xb1, 0x26, 0xcf,
+	0xa1, 0x6f, 0xe4, 0x86, 0xe7, 0x8b, 0x8c, 0x25, 0x3c, 0x6b, 0xa6, 0x00, 0x4c, 0x5d, 0x57, 0x99,
+	0xaa, 0x84, 0x12, 0x79, 0x33, 0x46, 0x25, 0xc9, 0x09, 0x80, 0xc2, 0x0e, 0x8b, 0x0d, 0x2f, 0x8f,
+	0x2d, 0xfc, 0xb0, 0xd5, 0xae, 0xa7, 0x37, 0x81, 0x7e, 0xf4, 0xd3, 0x70, 0x9d, 0xb3, 0xac, 0x1a,
+	0xe1, 0x15, 0x58, 0x95, 0xad, 0xbe, 0x81, 0x27, 0x7e, 0x7d, 0x47, 0xfe, 0xee, 0x8e, 0xfc, 0x69,
+	0x5e, 0xd2, 0xca, 0x70, 0xf1, 0xfa, 0xdb, 0xe9, 0x5a, 0x98, 0xdb, 0x6d, 0xe2, 0xa7, 0xf2, 0x2e,
+	0x68, 0xce, 0xcd, 0x68, 0x91, 0x65, 0x82, 0xe5, 0xc1, 0x83, 0x13, 0x4d, 0xba, 0x88, 0x9f, 0xfd,
+	0x19, 0x00, 0xca, 0x6b, 0x1f, 0x5f, 0xba, 0x02, 0x00, 0x00,
 }
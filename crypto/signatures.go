@@ -17,19 +17,22 @@ package crypto
 import (
 	gocrypto "crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 
 	"github.com/google/trillian/crypto/sigpb"
 )
 
 // SignatureAlgorithm returns the algorithm used for this public key.
-// Only ECDSA and RSA keys are supported. Other key types will return sigpb.DigitallySigned_ANONYMOUS.
+// Only ECDSA, RSA and Ed25519 keys are supported. Other key types will return sigpb.DigitallySigned_ANONYMOUS.
 func SignatureAlgorithm(k gocrypto.PublicKey) sigpb.DigitallySigned_SignatureAlgorithm {
 	switch k.(type) {
 	case *ecdsa.PublicKey:
 		return sigpb.DigitallySigned_ECDSA
 	case *rsa.PublicKey:
 		return sigpb.DigitallySigned_RSA
+	case ed25519.PublicKey:
+		return sigpb.DigitallySigned_ED25519
 	}
 
 	return sigpb.DigitallySigned_ANONYMOUS
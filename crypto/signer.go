@@ -36,6 +36,9 @@ var sigpbHashLookup = map[crypto.Hash]sigpb.DigitallySigned_HashAlgorithm{
 type Signer struct {
 	Hash   crypto.Hash
 	Signer crypto.Signer
+	// Additional signers sign roots as well as Signer, one signature each, as
+	// configured by the additional_signing_keys of a tree.
+	Additional []*Signer
 }
 
 // NewSHA256Signer creates a new SHA256 based Signer.
@@ -51,8 +54,21 @@ func (s *Signer) Public() crypto.PublicKey {
 	return s.Signer.Public()
 }
 
-// Sign obtains a signature after first hashing the input data.
+// Sign obtains a signature after first hashing the input data, unless the
+// signer has an Ed25519 key, which signs the data itself.
 func (s *Signer) Sign(data []byte) (*sigpb.DigitallySigned, error) {
+	if alg := SignatureAlgorithm(s.Public()); alg == sigpb.DigitallySigned_ED25519 {
+		sig, err := s.Signer.Sign(rand.Reader, data, crypto.Hash(0))
+		if err != nil {
+			return nil, err
+		}
+		return &sigpb.DigitallySigned{
+			SignatureAlgorithm: alg,
+			HashAlgorithm:      sigpb.DigitallySigned_NONE,
+			Signature:          sig,
+		}, nil
+	}
+
 	h := s.Hash.New()
 	h.Write(data)
 	digest := h.Sum(nil)
@@ -110,3 +126,47 @@ func (s *Signer) SignMapRoot(root *trillian.SignedMapRoot) (*sigpb.DigitallySign
 
 	return signature, nil
 }
+
+// AdditionalLogRootSignatures returns the signatures of root by the Additional
+// signers of s, in order, which sign the same data as SignLogRoot.
+func (s *Signer) AdditionalLogRootSignatures(root *trillian.SignedLogRoot) ([]*sigpb.DigitallySigned, error) {
+	if len(s.Additional) == 0 {
+		return nil, nil
+	}
+	hash, err := HashLogRoot(*root)
+	if err != nil {
+		return nil, err
+	}
+	sigs := make([]*sigpb.DigitallySigned, 0, len(s.Additional))
+	for i, a := range s.Additional {
+		sig, err := a.Sign(hash)
+		if err != nil {
+			glog.Warningf("%v: additional signer %d failed to sign log root: %v", root.LogId, i, err)
+			return nil, err
+		}
+		sigs = append(sigs, sig)
+	}
+	return sigs, nil
+}
+
+// AdditionalMapRootSignatures returns the signatures of root by the Additional
+// signers of s, in order. Like SignMapRoot, they sign root without its
+// Signature and AdditionalSignatures.
+func (s *Signer) AdditionalMapRootSignatures(root *trillian.SignedMapRoot) ([]*sigpb.DigitallySigned, error) {
+	if len(s.Additional) == 0 {
+		return nil, nil
+	}
+	unsigned := *root
+	unsigned.Signature = nil
+	unsigned.AdditionalSignatures = nil
+	sigs := make([]*sigpb.DigitallySigned, 0, len(s.Additional))
+	for i, a := range s.Additional {
+		sig, err := a.SignObject(unsigned)
+		if err != nil {
+			glog.Warningf("%v: additional signer %d failed to sign map root: %v", root.MapId, i, err)
+			return nil, err
+		}
+		sigs = append(sigs, sig)
+	}
+	return sigs, nil
+}
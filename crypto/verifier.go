@@ -16,90 +16,84 @@ package crypto
 
 import (
 	"crypto"
-	"crypto/ecdsa"
-	"crypto/rsa"
-	"encoding/asn1"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"math/big"
 
 	"github.com/benlaurie/objecthash/go/objecthash"
+	"github.com/google/trillian"
+	"github.com/google/trillian/client/verifier"
 	"github.com/google/trillian/crypto/sigpb"
 )
 
-var (
-	errVerify = errors.New("signature verification failed")
+// VerifyObject verifies the output of Signer.SignObject.
+func VerifyObject(pub crypto.PublicKey, obj interface{}, sig *sigpb.DigitallySigned) error {
+	hash, err := objectHash(obj)
+	if err != nil {
+		return err
+	}
+	return Verify(pub, hash, sig)
+}
 
-	cryptoHashLookup = map[sigpb.DigitallySigned_HashAlgorithm]crypto.Hash{
-		sigpb.DigitallySigned_SHA256: crypto.SHA256,
+// VerifyObjectAny is like VerifyAny for signatures output by
+// Signer.SignObject.
+func VerifyObjectAny(pub crypto.PublicKey, obj interface{}, sigs []*sigpb.DigitallySigned) error {
+	hash, err := objectHash(obj)
+	if err != nil {
+		return err
 	}
-)
+	return VerifyAny(pub, hash, sigs)
+}
 
-// VerifyObject verifies the output of Signer.SignObject.
-func VerifyObject(pub crypto.PublicKey, obj interface{}, sig *sigpb.DigitallySigned) error {
+func objectHash(obj interface{}) ([]byte, error) {
 	j, err := json.Marshal(obj)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	hash, err := objecthash.CommonJSONHash(string(j))
 	if err != nil {
-		return fmt.Errorf("CommonJSONHash(%s): %v", j, err)
+		return nil, fmt.Errorf("CommonJSONHash(%s): %v", j, err)
 	}
-	return Verify(pub, hash[:], sig)
+	return hash[:], nil
 }
 
 // Verify cryptographically verifies the output of Signer.
 func Verify(pub crypto.PublicKey, data []byte, sig *sigpb.DigitallySigned) error {
-	if sig == nil {
-		return errors.New("signature is nil")
-	}
-
-	if got, want := sig.SignatureAlgorithm, SignatureAlgorithm(pub); got != want {
-		return fmt.Errorf("signature algorithm does not match public key, got:%v, want:%v", got, want)
-	}
-
-	// Recompute digest
-	hasher, ok := cryptoHashLookup[sig.HashAlgorithm]
-	if !ok {
-		return fmt.Errorf("unsupported hash algorithm %v", hasher)
-	}
-	h := hasher.New()
-	h.Write(data)
-	digest := h.Sum(nil)
-
-	switch pub := pub.(type) {
-	case *ecdsa.PublicKey:
-		return verifyECDSA(pub, digest, sig.Signature)
-	case *rsa.PublicKey:
-		return verifyRSA(pub, digest, sig.Signature, hasher, hasher)
-	default:
-		return fmt.Errorf("unknown private key type: %T", pub)
-	}
+	return verifier.VerifySignature(pub, data, sig)
 }
 
-func verifyRSA(pub *rsa.PublicKey, hashed, sig []byte, hasher crypto.Hash, opts crypto.SignerOpts) error {
-	if pssOpts, ok := opts.(*rsa.PSSOptions); ok {
-		return rsa.VerifyPSS(pub, hasher, hashed, sig, pssOpts)
+// VerifyAny verifies that one of sigs is pub's signature of data, e.g. one of
+// the signatures of a root signed by several keys. Only the signatures with
+// the algorithm of pub are checked.
+func VerifyAny(pub crypto.PublicKey, data []byte, sigs []*sigpb.DigitallySigned) error {
+	alg := SignatureAlgorithm(pub)
+	err := fmt.Errorf("no %v signature to verify", alg)
+	for _, sig := range sigs {
+		if sig.GetSignatureAlgorithm() != alg {
+			continue
+		}
+		if err = Verify(pub, data, sig); err == nil {
+			return nil
+		}
 	}
-	return rsa.VerifyPKCS1v15(pub, hasher, hashed, sig)
+	return err
 }
 
-func verifyECDSA(pub *ecdsa.PublicKey, hashed, sig []byte) error {
-	var ecdsaSig struct {
-		R, S *big.Int
-	}
-	rest, err := asn1.Unmarshal(sig, &ecdsaSig)
+// VerifyLogRoot verifies that root was signed by pub, either as its Signature
+// or as one of its AdditionalSignatures.
+func VerifyLogRoot(pub crypto.PublicKey, root *trillian.SignedLogRoot) error {
+	hash, err := HashLogRoot(*root)
 	if err != nil {
-		return errVerify
-	}
-	if len(rest) != 0 {
-		return errVerify
-	}
-
-	if !ecdsa.Verify(pub, hashed, ecdsaSig.R, ecdsaSig.S) {
-		return errVerify
+		return err
 	}
-	return nil
+	return VerifyAny(pub, hash, append([]*sigpb.DigitallySigned{root.Signature}, root.AdditionalSignatures...))
+}
 
+// VerifyMapRoot verifies that root was signed by pub, either as its Signature
+// or as one of its AdditionalSignatures.
+func VerifyMapRoot(pub crypto.PublicKey, root *trillian.SignedMapRoot) error {
+	// The signatures are of the root without any of them.
+	unsigned := *root
+	unsigned.Signature = nil
+	unsigned.AdditionalSignatures = nil
+	return VerifyObjectAny(pub, unsigned, append([]*sigpb.DigitallySigned{root.Signature}, root.AdditionalSignatures...))
 }
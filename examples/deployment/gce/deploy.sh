@@ -1,5 +1,5 @@
 # Script assumptions:
-# - Go 1.9 is installed
+# - Go 1.13 is installed
 export PROJECT_NAME=TODO
 export TAG=latest
 export LOG_URL=TODO
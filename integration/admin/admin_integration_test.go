@@ -28,6 +28,7 @@ import (
 	"github.com/google/trillian/storage"
 	"github.com/google/trillian/storage/testonly"
 	"github.com/google/trillian/testonly/integration"
+	"github.com/google/trillian/util"
 	"github.com/kylelemons/godebug/pretty"
 	"google.golang.org/genproto/protobuf/field_mask"
 	"google.golang.org/grpc"
@@ -596,7 +597,7 @@ func setupAdminServer(ctx context.Context) (*testServer, error) {
 		registry.AdminStorage, registry.QuotaManager, false /* quotaDryRun */, registry.MetricFactory)
 	netInterceptor := interceptor.Combine(interceptor.ErrorWrapper, ti.UnaryInterceptor)
 	ts.server = grpc.NewServer(grpc.UnaryInterceptor(netInterceptor))
-	trillian.RegisterTrillianAdminServer(ts.server, sa.New(registry, nil /* allowedTreeTypes */))
+	trillian.RegisterTrillianAdminServer(ts.server, sa.New(registry, nil /* allowedTreeTypes */, util.SystemTimeSource{}))
 	go ts.server.Serve(ts.lis)
 
 	ts.conn, err = grpc.Dial(ts.lis.Addr().String(), grpc.WithInsecure())
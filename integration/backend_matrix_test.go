@@ -0,0 +1,198 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/genproto/protobuf/field_mask"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/client"
+	"github.com/google/trillian/testonly/integration"
+
+	stestonly "github.com/google/trillian/storage/testonly"
+)
+
+// backendScenarios are run against every backend by TestBackendMatrix, each
+// on a tree of its own.
+var backendScenarios = []struct {
+	desc string
+	// deletes is set for scenarios which delete their tree.
+	deletes bool
+	run     func(ctx context.Context, t *testing.T, env *integration.LogEnv, tree *trillian.Tree)
+}{
+	{desc: "submitSequenceProve", run: runSubmitSequenceProve},
+	{desc: "freeze", run: runFreeze},
+	{desc: "delete", deletes: true, run: runDelete},
+}
+
+// TestBackendMatrix runs a log server and signer on each of the storage
+// backends selected by TRILLIAN_INTEGRATION_BACKENDS, see
+// integration.Backends, and checks the same scenarios on all of them.
+func TestBackendMatrix(t *testing.T) {
+	backends, err := integration.Backends()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range backends {
+		t.Run(b.Name, func(t *testing.T) {
+			ctx := context.Background()
+			registry, done, err := b.NewRegistry(ctx)
+			if err != nil {
+				t.Fatalf("%s: NewRegistry(): %v", b.Name, err)
+			}
+			defer done()
+			env, err := integration.NewLogEnvWithRegistry(ctx, 1, registry)
+			if err != nil {
+				t.Fatalf("%s: NewLogEnvWithRegistry(): %v", b.Name, err)
+			}
+			defer env.Close()
+
+			for _, s := range backendScenarios {
+				t.Run(s.desc, func(t *testing.T) {
+					if s.deletes && b.NoTreeDeletion {
+						t.Skipf("%s backend can't delete trees", b.Name)
+					}
+					ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+					defer cancel()
+					tree, err := client.CreateAndInitTree(ctx, &trillian.CreateTreeRequest{Tree: stestonly.LogTree}, env.Admin, nil, env.Log)
+					if err != nil {
+						t.Fatalf("CreateAndInitTree(): %v", err)
+					}
+					s.run(ctx, t, env, tree)
+				})
+			}
+		})
+	}
+}
+
+// addLeaves queues count leaves, prefixed by prefix, and waits for their
+// inclusion proofs to verify against the latest root.
+func addLeaves(ctx context.Context, c *client.LogClient, prefix string, count int) error {
+	leaves := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		leaf := []byte(fmt.Sprintf("%s-%d", prefix, i))
+		if err := c.QueueLeaf(ctx, leaf); err != nil {
+			return fmt.Errorf("QueueLeaf(%s): %v", leaf, err)
+		}
+		leaves = append(leaves, leaf)
+	}
+	for _, leaf := range leaves {
+		if err := c.WaitForInclusion(ctx, leaf); err != nil {
+			return fmt.Errorf("WaitForInclusion(%s): %v", leaf, err)
+		}
+	}
+	return nil
+}
+
+func runSubmitSequenceProve(ctx context.Context, t *testing.T, env *integration.LogEnv, tree *trillian.Tree) {
+	c, err := client.NewFromTree(env.Log, tree)
+	if err != nil {
+		t.Fatalf("NewFromTree(): %v", err)
+	}
+	if err := addLeaves(ctx, c, "first", 10); err != nil {
+		t.Fatal(err)
+	}
+	root, err := c.WaitForRootUpdate(ctx, 10)
+	if err != nil {
+		t.Fatalf("WaitForRootUpdate(10): %v", err)
+	}
+
+	// A second batch grows the tree, which UpdateRoot only trusts once the
+	// consistency proof from the first root verifies.
+	if err := addLeaves(ctx, c, "second", 5); err != nil {
+		t.Fatal(err)
+	}
+	newRoot, err := c.WaitForRootUpdate(ctx, 15)
+	if err != nil {
+		t.Fatalf("WaitForRootUpdate(15): %v", err)
+	}
+	if got, want := newRoot.TreeSize, int64(15); got != want {
+		t.Errorf("TreeSize = %d, want %d", got, want)
+	}
+	resp, err := env.Log.GetConsistencyProof(ctx, &trillian.GetConsistencyProofRequest{
+		LogId:          tree.TreeId,
+		FirstTreeSize:  root.TreeSize,
+		SecondTreeSize: newRoot.TreeSize,
+	})
+	if err != nil {
+		t.Fatalf("GetConsistencyProof(%d, %d): %v", root.TreeSize, newRoot.TreeSize, err)
+	}
+	if err := c.VerifyRoot(root, newRoot, resp.GetProof().GetHashes()); err != nil {
+		t.Errorf("VerifyRoot(): %v", err)
+	}
+
+	leaves, err := c.ListByIndex(ctx, 0, newRoot.TreeSize)
+	if err != nil {
+		t.Fatalf("ListByIndex(): %v", err)
+	}
+	if got, want := len(leaves), int(newRoot.TreeSize); got != want {
+		t.Errorf("ListByIndex() returned %d leaves, want %d", got, want)
+	}
+}
+
+func runFreeze(ctx context.Context, t *testing.T, env *integration.LogEnv, tree *trillian.Tree) {
+	c, err := client.NewFromTree(env.Log, tree)
+	if err != nil {
+		t.Fatalf("NewFromTree(): %v", err)
+	}
+	if err := addLeaves(ctx, c, "freeze", 3); err != nil {
+		t.Fatal(err)
+	}
+
+	frozen := proto.Clone(tree).(*trillian.Tree)
+	frozen.TreeState = trillian.TreeState_FROZEN
+	if _, err := env.Admin.UpdateTree(ctx, &trillian.UpdateTreeRequest{
+		Tree:       frozen,
+		UpdateMask: &field_mask.FieldMask{Paths: []string{"tree_state"}},
+	}); err != nil {
+		t.Fatalf("UpdateTree(FROZEN): %v", err)
+	}
+
+	if err := c.QueueLeaf(ctx, []byte("frozen")); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("QueueLeaf() on frozen tree returned err = %v, want code %v", err, codes.PermissionDenied)
+	}
+	// Frozen trees stay readable.
+	if err := c.VerifyInclusion(ctx, []byte("freeze-0")); err != nil {
+		t.Errorf("VerifyInclusion() on frozen tree: %v", err)
+	}
+}
+
+func runDelete(ctx context.Context, t *testing.T, env *integration.LogEnv, tree *trillian.Tree) {
+	c, err := client.NewFromTree(env.Log, tree)
+	if err != nil {
+		t.Fatalf("NewFromTree(): %v", err)
+	}
+	if err := addLeaves(ctx, c, "delete", 3); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.Admin.DeleteTree(ctx, &trillian.DeleteTreeRequest{TreeId: tree.TreeId}); err != nil {
+		t.Fatalf("DeleteTree(): %v", err)
+	}
+	if _, err := env.Log.GetLatestSignedLogRoot(ctx, &trillian.GetLatestSignedLogRootRequest{LogId: tree.TreeId}); status.Code(err) != codes.NotFound {
+		t.Errorf("GetLatestSignedLogRoot() on deleted tree returned err = %v, want code %v", err, codes.NotFound)
+	}
+	if err := c.QueueLeaf(ctx, []byte("deleted")); status.Code(err) != codes.NotFound {
+		t.Errorf("QueueLeaf() on deleted tree returned err = %v, want code %v", err, codes.NotFound)
+	}
+}
@@ -0,0 +1,94 @@
+#!/bin/bash
+# Runs TestBackendMatrix against the storage backends listed in
+# TRILLIAN_INTEGRATION_BACKENDS (default: memory,sqlite).
+#
+# With --docker, the external services of the selected backends are started in
+# Docker containers, which are removed on exit:
+#  - mysql: a MySQL server on localhost:3306 with a passwordless root user;
+#  - cloudspanner: the Cloud Spanner emulator, with a database created from
+#    storage/cloudspanner/spanner.sdl using gcloud.
+# Without it, the services must already be running; for cloudspanner,
+# TRILLIAN_SPANNER_DATABASE must name a database with the Trillian schema.
+#
+# Example:
+#   TRILLIAN_INTEGRATION_BACKENDS=memory,mysql,cloudspanner ./backend_matrix_test.sh --docker
+set -e
+INTEGRATION_DIR="$( cd "$( dirname "$0" )" && pwd )"
+. "${INTEGRATION_DIR}"/functions.sh
+
+export TRILLIAN_INTEGRATION_BACKENDS="${TRILLIAN_INTEGRATION_BACKENDS:-memory,sqlite}"
+SPANNER_PROJECT="${SPANNER_PROJECT:-trillian-test}"
+SPANNER_INSTANCE="${SPANNER_INSTANCE:-trillian}"
+SPANNER_DB="${SPANNER_DB:-trillian}"
+
+WITH_DOCKER=false
+while [[ $# -gt 0 ]]; do
+  case "$1" in
+    --docker) WITH_DOCKER=true ;;
+    *) echo "usage: $0 [--docker]"; exit 1 ;;
+  esac
+  shift 1
+done
+
+declare -a CONTAINERS
+cleanup() {
+  if [[ ${#CONTAINERS[@]} -gt 0 ]]; then
+    docker rm -f "${CONTAINERS[@]}" > /dev/null
+  fi
+}
+trap cleanup EXIT
+
+uses_backend() {
+  [[ ",${TRILLIAN_INTEGRATION_BACKENDS}," == *",$1,"* ]]
+}
+
+start_mysql() {
+  echo "Starting MySQL"
+  CONTAINERS+=($(docker run -d -p 3306:3306 -e MYSQL_ALLOW_EMPTY_PASSWORD=yes mysql:5.7))
+  for i in $(seq 1 60); do
+    if mysql -h 127.0.0.1 -u root -e 'SELECT 1' > /dev/null 2>&1; then
+      return
+    fi
+    sleep 1
+  done
+  echo "MySQL didn't start"
+  exit 1
+}
+
+start_spanner_emulator() {
+  echo "Starting the Cloud Spanner emulator"
+  CONTAINERS+=($(docker run -d -p 9010:9010 -p 9020:9020 gcr.io/cloud-spanner-emulator/emulator))
+  export SPANNER_EMULATOR_HOST=localhost:9010
+  wait_for_server_startup 9020
+
+  gcloud config configurations create trillian-emulator > /dev/null 2>&1 || true
+  gcloud config configurations activate trillian-emulator
+  gcloud config set auth/disable_credentials true
+  gcloud config set project "${SPANNER_PROJECT}"
+  gcloud config set api_endpoint_overrides/spanner http://localhost:9020/
+  gcloud spanner instances create "${SPANNER_INSTANCE}" \
+    --config=emulator-config --description=Trillian --nodes=1
+  # gcloud takes one statement per --ddl flag, without comments.
+  local ddl=()
+  while IFS= read -r -d ';' stmt; do
+    stmt="$(echo "${stmt}" | tr '\n' ' ' | sed -e 's/^ *//' -e 's/ *$//')"
+    if [[ -n "${stmt}" ]]; then
+      ddl+=(--ddl="${stmt}")
+    fi
+  done < <(sed -e 's/--.*$//' "${TRILLIAN_PATH}/storage/cloudspanner/spanner.sdl")
+  gcloud spanner databases create "${SPANNER_DB}" --instance="${SPANNER_INSTANCE}" "${ddl[@]}"
+  export TRILLIAN_SPANNER_DATABASE="projects/${SPANNER_PROJECT}/instances/${SPANNER_INSTANCE}/databases/${SPANNER_DB}"
+}
+
+if [[ "${WITH_DOCKER}" == "true" ]]; then
+  uses_backend mysql && start_mysql
+  uses_backend cloudspanner && start_spanner_emulator
+fi
+
+echo "Running TestBackendMatrix on ${TRILLIAN_INTEGRATION_BACKENDS}"
+cd "${INTEGRATION_DIR}"
+go test ${GOFLAGS} \
+  -run TestBackendMatrix \
+  -timeout=${GO_TEST_TIMEOUT:-10m} \
+  -v \
+  ./
@@ -0,0 +1,143 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/client"
+	"github.com/google/trillian/extension"
+	"github.com/google/trillian/quota"
+	"github.com/google/trillian/storage/mysql"
+	"github.com/google/trillian/storage/testdb"
+	"github.com/google/trillian/testonly/chaos"
+	"github.com/google/trillian/testonly/integration"
+
+	stestonly "github.com/google/trillian/storage/testonly"
+)
+
+// retryChaos calls f until it returns an error other than those injected by
+// the chaos package, or ctx is done.
+func retryChaos(ctx context.Context, f func() error) error {
+	for {
+		err := f()
+		switch status.Code(err) {
+		case codes.Aborted, codes.Unavailable:
+		default:
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%v, last error: %v", ctx.Err(), err)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// TestChaosSequencing checks that leaves submitted to a log whose storage
+// fails at random are all sequenced, exactly once, with valid proofs.
+func TestChaosSequencing(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	db, err := testdb.NewTrillianDB(ctx)
+	if err != nil {
+		t.Fatalf("NewTrillianDB(): %v", err)
+	}
+	defer db.Close()
+	faults, err := chaos.New(chaos.Faults{
+		Latency:          2 * time.Millisecond,
+		AbortRate:        0.05,
+		PartialWriteRate: 0.2,
+		CommitErrorRate:  0.1,
+	}, time.Now().UnixNano())
+	if err != nil {
+		t.Fatalf("chaos.New(): %v", err)
+	}
+	registry := extension.Registry{
+		AdminStorage: mysql.NewAdminStorage(db),
+		LogStorage:   faults.LogStorage(mysql.NewLogStorage(db, nil)),
+		QuotaManager: quota.Noop(),
+	}
+	env, err := integration.NewLogEnvWithRegistry(ctx, 2, registry)
+	if err != nil {
+		t.Fatalf("NewLogEnvWithRegistry(): %v", err)
+	}
+	defer env.Close()
+
+	var tree *trillian.Tree
+	if err := retryChaos(ctx, func() error {
+		tree, err = env.Admin.CreateTree(ctx, &trillian.CreateTreeRequest{Tree: stestonly.LogTree})
+		return err
+	}); err != nil {
+		t.Fatalf("CreateTree(): %v", err)
+	}
+	// InitLog reports storage errors as FailedPrecondition, so it's retried
+	// until it succeeds. An injected commit error may hide a successful
+	// InitLog, which a retry then reports as AlreadyExists.
+	for {
+		_, err := env.Log.InitLog(ctx, &trillian.InitLogRequest{LogId: tree.TreeId})
+		if err == nil || status.Code(err) == codes.AlreadyExists {
+			break
+		}
+		if ctx.Err() != nil {
+			t.Fatalf("InitLog(): %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c, err := client.NewFromTree(env.Log, tree)
+	if err != nil {
+		t.Fatalf("NewFromTree(): %v", err)
+	}
+
+	const leafCount = 30
+	for i := 0; i < leafCount; i++ {
+		leaf := []byte(fmt.Sprintf("chaos-%d", i))
+		if err := retryChaos(ctx, func() error { return c.QueueLeaf(ctx, leaf) }); err != nil && status.Code(err) != codes.AlreadyExists {
+			t.Fatalf("QueueLeaf(%s): %v", leaf, err)
+		}
+	}
+	for i := 0; i < leafCount; i++ {
+		leaf := []byte(fmt.Sprintf("chaos-%d", i))
+		if err := retryChaos(ctx, func() error { return c.WaitForInclusion(ctx, leaf) }); err != nil {
+			t.Fatalf("WaitForInclusion(%s): %v", leaf, err)
+		}
+	}
+
+	var leaves []*trillian.LogLeaf
+	if err := retryChaos(ctx, func() error {
+		leaves, err = c.ListByIndex(ctx, 0, leafCount)
+		return err
+	}); err != nil {
+		t.Fatalf("ListByIndex(): %v", err)
+	}
+	seen := make(map[string]bool)
+	for _, l := range leaves {
+		if seen[string(l.LeafValue)] {
+			t.Errorf("leaf %s sequenced more than once", l.LeafValue)
+		}
+		seen[string(l.LeafValue)] = true
+	}
+	if got, want := len(seen), leafCount; got != want {
+		t.Errorf("got %d distinct leaves, want %d", got, want)
+	}
+}
@@ -49,6 +49,8 @@ var AllTests = TestTable{
 	{"MapRevisionZero", RunMapRevisionZero},
 	{"MapRevisionInvalid", RunMapRevisionInvalid},
 	{"LeafHistory", RunLeafHistory},
+	{"GetLeafHistory", RunGetLeafHistory},
+	{"GetMapDiff", RunGetMapDiff},
 	{"Inclusion", RunInclusion},
 	{"InclusionBatch", RunInclusionBatch},
 }
@@ -357,6 +359,160 @@ func RunLeafHistory(ctx context.Context, t *testing.T, tadmin trillian.TrillianA
 	}
 }
 
+// RunGetLeafHistory performs checks on the values returned by GetLeafHistory
+// under a variety of Hash Strategies.
+func RunGetLeafHistory(ctx context.Context, t *testing.T, tadmin trillian.TrillianAdminClient, tmap trillian.TrillianMapClient) {
+	index := h2b("0000000000000000000000000000000000000000000000000000000000000000")
+	set := [][]*trillian.MapLeaf{
+		{}, // Advance revision without changing anything.
+		{{Index: index, LeafValue: []byte("A")}},
+		{}, // Advance revision without changing anything.
+		{{Index: index, LeafValue: []byte("B")}},
+		{{Index: index, LeafValue: []byte("C")}},
+	}
+	type entry struct {
+		revision  int64
+		LeafValue []byte
+	}
+	for _, hashStrategy := range []trillian.HashStrategy{trillian.HashStrategy_TEST_MAP_HASHER, trillian.HashStrategy_CONIKS_SHA512_256} {
+		t.Run(hashStrategy.String(), func(t *testing.T) {
+			tree, err := newTreeWithHasher(ctx, tadmin, tmap, hashStrategy)
+			if err != nil {
+				t.Fatalf("newTreeWithHasher(%v): %v", hashStrategy, err)
+			}
+			mapVerifier, err := client.NewMapVerifierFromTree(tree)
+			if err != nil {
+				t.Fatalf("NewMapVerifierFromTree(): %v", err)
+			}
+			for _, batch := range set {
+				if _, err := tmap.SetLeaves(ctx, &trillian.SetMapLeavesRequest{
+					MapId:  tree.TreeId,
+					Leaves: batch,
+				}); err != nil {
+					t.Fatalf("SetLeaves(): %v", err)
+				}
+			}
+
+			for _, test := range []struct {
+				first, last int64
+				want        []entry
+				wantErr     bool
+			}{
+				{first: 0, last: 0, want: []entry{{revision: 0}}},
+				{first: 1, last: 5, want: []entry{{revision: 1}, {2, []byte("A")}, {4, []byte("B")}, {5, []byte("C")}}},
+				{first: 3, last: 4, want: []entry{{3, []byte("A")}, {4, []byte("B")}}},
+				{first: 5, last: 5, want: []entry{{5, []byte("C")}}},
+				{first: 4, last: 3, wantErr: true},
+				{first: 1, last: 6, wantErr: true},
+			} {
+				resp, err := tmap.GetLeafHistory(ctx, &trillian.GetMapLeafHistoryRequest{
+					MapId:         tree.TreeId,
+					Index:         index,
+					FirstRevision: test.first,
+					LastRevision:  test.last,
+				})
+				if gotErr := err != nil; gotErr != test.wantErr {
+					t.Errorf("GetLeafHistory(%d, %d)=_, err? %t want? %t (err=%v)", test.first, test.last, gotErr, test.wantErr, err)
+					continue
+				}
+				if err != nil {
+					continue
+				}
+				if got, want := len(resp.GetEntries()), len(test.want); got != want {
+					t.Errorf("GetLeafHistory(%d, %d): %d entries, want %d", test.first, test.last, got, want)
+					continue
+				}
+				for i, e := range resp.GetEntries() {
+					if got, want := e.GetLeafInclusion().GetLeaf().GetLeafValue(), test.want[i].LeafValue; !bytes.Equal(got, want) {
+						t.Errorf("GetLeafHistory(%d, %d).Entries[%d].LeafValue: %s, want %s", test.first, test.last, i, got, want)
+					}
+					getResp := &trillian.GetMapLeavesResponse{
+						MapRoot:          e.GetMapRoot(),
+						MapLeafInclusion: []*trillian.MapLeafInclusion{e.GetLeafInclusion()},
+					}
+					if err := verifyGetMapLeavesResponse(mapVerifier, getResp, [][]byte{index}, test.want[i].revision, tree.TreeId); err != nil {
+						t.Errorf("GetLeafHistory(%d, %d).Entries[%d]: %v", test.first, test.last, i, err)
+					}
+				}
+			}
+		})
+	}
+}
+
+// RunGetMapDiff performs checks on the keys returned by GetMapDiff, and their
+// proofs, under a variety of Hash Strategies.
+func RunGetMapDiff(ctx context.Context, t *testing.T, tadmin trillian.TrillianAdminClient, tmap trillian.TrillianMapClient) {
+	a := h2b("1000000000000000000000000000000000000000000000000000000000000000")
+	b := h2b("8000000000000000000000000000000000000000000000000000000000000000")
+	c := h2b("ff00000000000000000000000000000000000000000000000000000000000000")
+	set := [][]*trillian.MapLeaf{
+		{{Index: a, LeafValue: []byte("A")}, {Index: b, LeafValue: []byte("B")}},
+		{}, // Advance revision without changing anything.
+		{{Index: b, LeafValue: []byte("B2")}, {Index: c, LeafValue: []byte("C")}},
+	}
+	for _, hashStrategy := range []trillian.HashStrategy{trillian.HashStrategy_TEST_MAP_HASHER, trillian.HashStrategy_CONIKS_SHA512_256} {
+		t.Run(hashStrategy.String(), func(t *testing.T) {
+			tree, err := newTreeWithHasher(ctx, tadmin, tmap, hashStrategy)
+			if err != nil {
+				t.Fatalf("newTreeWithHasher(%v): %v", hashStrategy, err)
+			}
+			mapVerifier, err := client.NewMapVerifierFromTree(tree)
+			if err != nil {
+				t.Fatalf("NewMapVerifierFromTree(): %v", err)
+			}
+			for _, batch := range set {
+				if _, err := tmap.SetLeaves(ctx, &trillian.SetMapLeavesRequest{
+					MapId:  tree.TreeId,
+					Leaves: batch,
+				}); err != nil {
+					t.Fatalf("SetLeaves(): %v", err)
+				}
+			}
+
+			for _, test := range []struct {
+				from, to int64
+				want     [][]byte
+				wantErr  bool
+			}{
+				{from: 0, to: 1, want: [][]byte{a, b}},
+				{from: 1, to: 2},
+				{from: 1, to: 3, want: [][]byte{b, c}},
+				{from: 0, to: 3, want: [][]byte{a, b, c}},
+				{from: 2, to: 2, wantErr: true},
+				{from: 1, to: 4, wantErr: true},
+			} {
+				resp, err := tmap.GetMapDiff(ctx, &trillian.GetMapDiffRequest{
+					MapId:        tree.TreeId,
+					FromRevision: test.from,
+					ToRevision:   test.to,
+				})
+				if gotErr := err != nil; gotErr != test.wantErr {
+					t.Errorf("GetMapDiff(%d, %d)=_, err? %t want? %t (err=%v)", test.from, test.to, gotErr, test.wantErr, err)
+					continue
+				}
+				if err != nil {
+					continue
+				}
+				if got, want := len(resp.GetDiffs()), len(test.want); got != want {
+					t.Errorf("GetMapDiff(%d, %d): %d diffs, want %d", test.from, test.to, got, want)
+					continue
+				}
+				for i, d := range resp.GetDiffs() {
+					if got, want := d.GetIndex(), test.want[i]; !bytes.Equal(got, want) {
+						t.Errorf("GetMapDiff(%d, %d).Diffs[%d].Index: %x, want %x", test.from, test.to, i, got, want)
+					}
+					if err := mapVerifier.VerifyMapLeafInclusion(resp.GetFromRoot(), d.GetFrom()); err != nil {
+						t.Errorf("GetMapDiff(%d, %d).Diffs[%d].From: %v", test.from, test.to, i, err)
+					}
+					if err := mapVerifier.VerifyMapLeafInclusion(resp.GetToRoot(), d.GetTo()); err != nil {
+						t.Errorf("GetMapDiff(%d, %d).Diffs[%d].To: %v", test.from, test.to, i, err)
+					}
+				}
+			}
+		})
+	}
+}
+
 // RunInclusion performs checks on Trillian Map inclusion proofs after setting and getting leafs,
 // for a variety of hash strategies.
 func RunInclusion(ctx context.Context, t *testing.T, tadmin trillian.TrillianAdminClient, tmap trillian.TrillianMapClient) {
@@ -0,0 +1,63 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"context"
+
+	"github.com/golang/glog"
+	"github.com/google/trillian"
+	"github.com/google/trillian/log/leaffilter"
+	"github.com/google/trillian/storage"
+)
+
+// filterLeaves adds the identity hashes of the sequenced leaves to the log's
+// leaf filter, rewriting the blocks they fall in.
+func filterLeaves(ctx context.Context, tx storage.LogTreeTX, spec *trillian.LeafFilterSpec, sequenced []*trillian.LogLeaf, label string) error {
+	writer, ok := tx.(storage.LeafFilterWriter)
+	if !ok {
+		glog.Warningf("%v: storage doesn't support leaf filters, not filtering %d leaves", label, len(sequenced))
+		return nil
+	}
+	f, err := leaffilter.New(spec)
+	if err != nil {
+		return err
+	}
+	var indices []int64
+	seen := make(map[int64]bool)
+	for _, l := range sequenced {
+		if index := f.BlockIndex(l.LeafIdentityHash); !seen[index] {
+			indices = append(indices, index)
+			seen[index] = true
+		}
+	}
+	blocks, err := writer.GetLeafFilterBlocks(ctx, indices)
+	if err != nil {
+		glog.Warningf("%v: Sequencer failed to read leaf filter blocks: %v", label, err)
+		return err
+	}
+	for _, l := range sequenced {
+		index := f.BlockIndex(l.LeafIdentityHash)
+		if blocks[index] == nil {
+			blocks[index] = make([]byte, leaffilter.BlockSize)
+		}
+		f.Add(blocks[index], l.LeafIdentityHash)
+	}
+	if err := writer.SetLeafFilterBlocks(ctx, blocks); err != nil {
+		glog.Warningf("%v: Sequencer failed to write leaf filter blocks: %v", label, err)
+		return err
+	}
+	return nil
+}
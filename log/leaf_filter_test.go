@@ -0,0 +1,75 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/log/leaffilter"
+	"github.com/google/trillian/storage"
+)
+
+func TestIntegrateBatchLeafFilter(t *testing.T) {
+	ctx := context.Background()
+	spec := &trillian.LeafFilterSpec{ExpectedLeaves: 100, FalsePositiveRate: 1e-9}
+	f, err := leaffilter.New(spec)
+	if err != nil {
+		t.Fatalf("leaffilter.New(): %v", err)
+	}
+	for _, backend := range raceBackends() {
+		t.Run(backend.desc, func(t *testing.T) {
+			as, ls := backend.new(ctx, t)
+			e := newRaceEnv(ctx, t, as, ls)
+			// Integrate the leaves in two batches, which may share blocks.
+			leaves := e.leaves(t, 20)
+			for _, batch := range [][]*trillian.LogLeaf{leaves[:10], leaves[10:]} {
+				if err := e.queue(ctx, batch); err != nil {
+					t.Fatalf("QueueLeaves(): %v", err)
+				}
+				if _, err := e.unseq.IntegrateBatchWithOptions(ctx, e.logID, BatchOptions{Limit: 10, LeafFilter: spec}); err != nil {
+					t.Fatalf("IntegrateBatchWithOptions(): %v", err)
+				}
+			}
+			notLogged := e.leaves(t, 1)[0]
+
+			tx, err := ls.SnapshotForTree(ctx, e.logID)
+			if err != nil {
+				t.Fatalf("SnapshotForTree(): %v", err)
+			}
+			defer tx.Close()
+			var indices []int64
+			for _, l := range append(leaves, notLogged) {
+				indices = append(indices, f.BlockIndex(l.LeafIdentityHash))
+			}
+			blocks, err := tx.(storage.LeafFilterReader).GetLeafFilterBlocks(ctx, indices)
+			if err != nil {
+				t.Fatalf("GetLeafFilterBlocks(): %v", err)
+			}
+			for i, l := range leaves {
+				if !f.MayContain(blocks[indices[i]], l.LeafIdentityHash) {
+					t.Errorf("MayContain(leaf %d) = false, want true", i)
+				}
+			}
+			if f.MayContain(blocks[indices[len(leaves)]], notLogged.LeafIdentityHash) {
+				t.Error("MayContain(not logged) = true, want false")
+			}
+			if err := tx.Commit(); err != nil {
+				t.Errorf("Commit(): %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+)
+
+// leafIndexKeys returns the keys of leaf in the leaf indexes defined by specs.
+// See trillian.LeafIndexSpec for how keys are extracted from extra data.
+func leafIndexKeys(specs []*trillian.LeafIndexSpec, leaf *trillian.LogLeaf) []storage.LeafIndexKey {
+	var extra map[string]interface{}
+	if err := json.Unmarshal(leaf.ExtraData, &extra); err != nil {
+		return nil
+	}
+	var keys []storage.LeafIndexKey
+	for _, spec := range specs {
+		var value interface{} = extra
+		for _, f := range strings.Split(spec.Field, ".") {
+			obj, ok := value.(map[string]interface{})
+			if !ok {
+				value = nil
+				break
+			}
+			value = obj[f]
+		}
+
+		var values []interface{}
+		switch v := value.(type) {
+		case string:
+			values = []interface{}{v}
+		case []interface{}:
+			values = v
+		}
+		seen := make(map[string]bool)
+		for _, v := range values {
+			if k, ok := v.(string); ok && !seen[k] {
+				keys = append(keys, storage.LeafIndexKey{Index: spec.Name, Key: []byte(k), LeafIndex: leaf.LeafIndex})
+				seen[k] = true
+			}
+		}
+	}
+	return keys
+}
+
+// indexLeaves adds the sequenced leaves to the log's leaf indexes. The leaves
+// are read back from tx, as dequeued leaves may not have their extra data.
+func indexLeaves(ctx context.Context, tx storage.LogTreeTX, specs []*trillian.LeafIndexSpec, sequenced []*trillian.LogLeaf, label string) error {
+	writer, ok := tx.(storage.LeafIndexWriter)
+	if !ok {
+		glog.Warningf("%v: storage doesn't support leaf indexes, not indexing %d leaves", label, len(sequenced))
+		return nil
+	}
+	indices := make([]int64, 0, len(sequenced))
+	for _, l := range sequenced {
+		indices = append(indices, l.LeafIndex)
+	}
+	leaves, err := tx.GetLeavesByIndex(ctx, indices)
+	if err != nil {
+		glog.Warningf("%v: Sequencer failed to read leaves to index: %v", label, err)
+		return err
+	}
+	var keys []storage.LeafIndexKey
+	for _, l := range leaves {
+		keys = append(keys, leafIndexKeys(specs, l)...)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	if err := writer.AddLeafIndexKeys(ctx, keys); err != nil {
+		glog.Warningf("%v: Sequencer failed to add leaf index keys: %v", label, err)
+		return err
+	}
+	seqLeafIndexKeys.Add(float64(len(keys)), label)
+	return nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+)
+
+func TestLeafIndexKeys(t *testing.T) {
+	specs := []*trillian.LeafIndexSpec{
+		{Name: "domain", Field: "cert.domains"},
+		{Name: "package", Field: "package"},
+	}
+	for _, test := range []struct {
+		desc  string
+		extra string
+		want  []storage.LeafIndexKey
+	}{
+		{desc: "notJSON", extra: "domain"},
+		{desc: "notObject", extra: `["a.com"]`},
+		{desc: "noFields", extra: `{"other": "x"}`},
+		{
+			desc:  "string",
+			extra: `{"package": "trillian"}`,
+			want:  []storage.LeafIndexKey{{Index: "package", Key: []byte("trillian"), LeafIndex: 7}},
+		},
+		{
+			desc:  "array",
+			extra: `{"cert": {"domains": ["a.com", 3, "b.com", "a.com"]}, "package": "x"}`,
+			want: []storage.LeafIndexKey{
+				{Index: "domain", Key: []byte("a.com"), LeafIndex: 7},
+				{Index: "domain", Key: []byte("b.com"), LeafIndex: 7},
+				{Index: "package", Key: []byte("x"), LeafIndex: 7},
+			},
+		},
+		{desc: "notString", extra: `{"cert": {"domains": {"a": "a.com"}}, "package": 1}`},
+		{desc: "pathThroughString", extra: `{"cert": "a.com"}`},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			leaf := &trillian.LogLeaf{LeafIndex: 7, ExtraData: []byte(test.extra)}
+			if got := leafIndexKeys(specs, leaf); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("leafIndexKeys() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestIntegrateBatchLeafIndexes(t *testing.T) {
+	ctx := context.Background()
+	for _, backend := range raceBackends() {
+		t.Run(backend.desc, func(t *testing.T) {
+			as, ls := backend.new(ctx, t)
+			e := newRaceEnv(ctx, t, as, ls)
+			leaves := e.leaves(t, 4)
+			leaves[0].ExtraData = []byte(`{"domain": "a.com"}`)
+			leaves[1].ExtraData = []byte(`{"domain": "b.com"}`)
+			leaves[3].ExtraData = []byte(`{"domain": "a.com"}`)
+			if err := e.queue(ctx, leaves); err != nil {
+				t.Fatalf("QueueLeaves(): %v", err)
+			}
+			opts := BatchOptions{Limit: 10, LeafIndexes: []*trillian.LeafIndexSpec{{Name: "domain", Field: "domain"}}}
+			if _, err := e.unseq.IntegrateBatchWithOptions(ctx, e.logID, opts); err != nil {
+				t.Fatalf("IntegrateBatchWithOptions(): %v", err)
+			}
+
+			tx, err := ls.SnapshotForTree(ctx, e.logID)
+			if err != nil {
+				t.Fatalf("SnapshotForTree(): %v", err)
+			}
+			defer tx.Close()
+			got, err := tx.(storage.LeafIndexReader).GetLeafIndicesByKey(ctx, "domain", []byte("a.com"), 0, 10)
+			if err != nil {
+				t.Fatalf("GetLeafIndicesByKey(): %v", err)
+			}
+			if len(got) != 2 {
+				t.Fatalf("GetLeafIndicesByKey() = %v, want 2 indices", got)
+			}
+			indexed, err := tx.GetLeavesByIndex(ctx, got)
+			if err != nil {
+				t.Fatalf("GetLeavesByIndex(): %v", err)
+			}
+			for _, l := range indexed {
+				if string(l.ExtraData) != `{"domain": "a.com"}` {
+					t.Errorf("GetLeafIndicesByKey() returned index %d with extra data %q", l.LeafIndex, l.ExtraData)
+				}
+			}
+			if err := tx.Commit(); err != nil {
+				t.Errorf("Commit(): %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package leaffilter implements the leaf filter of a log, a blocked Bloom
+// filter of the identity hashes of its leaves.
+//
+// The filter is split into blocks of BlockSize bytes, and each hash sets or
+// checks bits in just one of them, so storage can keep the blocks separately
+// and only read and write the few a batch of hashes needs. Blocks which were
+// never written are all zeroes.
+package leaffilter
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/google/trillian"
+)
+
+const (
+	// BlockSize is the size of a block of the filter, in bytes.
+	BlockSize = 512
+	blockBits = BlockSize * 8
+
+	// MaxExpectedLeaves is the largest number of leaves a filter can be sized
+	// for.
+	MaxExpectedLeaves = 10000000000
+	// MinFalsePositiveRate and MaxFalsePositiveRate bound the false positive
+	// rate a filter can be sized for.
+	MinFalsePositiveRate = 1e-9
+	MaxFalsePositiveRate = 0.5
+)
+
+// Filter locates the bits of leaf identity hashes in the blocks of a filter
+// sized by a trillian.LeafFilterSpec. It doesn't hold the blocks themselves.
+type Filter struct {
+	blocks int64
+	// k is the number of bits set for each hash.
+	k int
+}
+
+// New returns the Filter sized by spec.
+func New(spec *trillian.LeafFilterSpec) (*Filter, error) {
+	if spec == nil {
+		return nil, fmt.Errorf("no leaf filter spec")
+	}
+	n, p := spec.ExpectedLeaves, spec.FalsePositiveRate
+	if n <= 0 || n > MaxExpectedLeaves {
+		return nil, fmt.Errorf("expected_leaves: %v, want in (0, %v]", n, int64(MaxExpectedLeaves))
+	}
+	if !(p >= MinFalsePositiveRate && p <= MaxFalsePositiveRate) {
+		return nil, fmt.Errorf("false_positive_rate: %v, want in [%v, %v]", p, MinFalsePositiveRate, MaxFalsePositiveRate)
+	}
+	bits := math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2))
+	k := int(math.Round(bits / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return &Filter{blocks: int64(math.Ceil(bits / blockBits)), k: k}, nil
+}
+
+// Blocks returns the number of blocks of the filter.
+func (f *Filter) Blocks() int64 {
+	return f.blocks
+}
+
+// locate returns the index of the block of hash, and the seeds of its bits.
+func (f *Filter) locate(hash []byte) (int64, uint32, uint32) {
+	h := sha256.Sum256(hash)
+	block := binary.BigEndian.Uint64(h[0:8]) % uint64(f.blocks)
+	// An odd step visits each bit of the block before repeating, as blockBits
+	// is a power of two.
+	return int64(block), binary.BigEndian.Uint32(h[8:12]), binary.BigEndian.Uint32(h[12:16]) | 1
+}
+
+// BlockIndex returns the index of the block holding the bits of hash.
+func (f *Filter) BlockIndex(hash []byte) int64 {
+	block, _, _ := f.locate(hash)
+	return block
+}
+
+// Add sets the bits of hash in block, which must be the BlockSize bytes of
+// the block with index BlockIndex(hash).
+func (f *Filter) Add(block, hash []byte) {
+	_, h1, h2 := f.locate(hash)
+	for i := 0; i < f.k; i++ {
+		bit := (h1 + uint32(i)*h2) % blockBits
+		block[bit/8] |= 1 << (bit % 8)
+	}
+}
+
+// MayContain returns whether the bits of hash are set in block, the block
+// with index BlockIndex(hash). A nil block is all zeroes.
+func (f *Filter) MayContain(block, hash []byte) bool {
+	if block == nil {
+		return false
+	}
+	_, h1, h2 := f.locate(hash)
+	for i := 0; i < f.k; i++ {
+		bit := (h1 + uint32(i)*h2) % blockBits
+		if block[bit/8]&(1<<(bit%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
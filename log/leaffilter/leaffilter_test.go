@@ -0,0 +1,92 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leaffilter
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/trillian"
+)
+
+func TestNew(t *testing.T) {
+	for _, test := range []struct {
+		desc       string
+		spec       *trillian.LeafFilterSpec
+		wantBlocks int64
+		wantK      int
+		wantErr    bool
+	}{
+		{desc: "nil", wantErr: true},
+		{desc: "noLeaves", spec: &trillian.LeafFilterSpec{FalsePositiveRate: 0.01}, wantErr: true},
+		{desc: "tooManyLeaves", spec: &trillian.LeafFilterSpec{ExpectedLeaves: MaxExpectedLeaves + 1, FalsePositiveRate: 0.01}, wantErr: true},
+		{desc: "noRate", spec: &trillian.LeafFilterSpec{ExpectedLeaves: 1000}, wantErr: true},
+		{desc: "rateTooHigh", spec: &trillian.LeafFilterSpec{ExpectedLeaves: 1000, FalsePositiveRate: 0.6}, wantErr: true},
+		// 9586 bits, 7 per hash.
+		{desc: "small", spec: &trillian.LeafFilterSpec{ExpectedLeaves: 1000, FalsePositiveRate: 0.01}, wantBlocks: 3, wantK: 7},
+		{desc: "oneLeaf", spec: &trillian.LeafFilterSpec{ExpectedLeaves: 1, FalsePositiveRate: 0.5}, wantBlocks: 1, wantK: 1},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			f, err := New(test.spec)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("New(): %v, want error: %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if f.Blocks() != test.wantBlocks || f.k != test.wantK {
+				t.Errorf("New() = %d blocks, %d bits per hash, want %d, %d", f.Blocks(), f.k, test.wantBlocks, test.wantK)
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	const n = 10000
+	f, err := New(&trillian.LeafFilterSpec{ExpectedLeaves: n, FalsePositiveRate: 0.01})
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	blocks := make(map[int64][]byte)
+	for i := 0; i < n; i++ {
+		hash := []byte(fmt.Sprintf("in %d", i))
+		index := f.BlockIndex(hash)
+		if index < 0 || index >= f.Blocks() {
+			t.Fatalf("BlockIndex(%q) = %d, want in [0, %d)", hash, index, f.Blocks())
+		}
+		if blocks[index] == nil {
+			blocks[index] = make([]byte, BlockSize)
+		}
+		f.Add(blocks[index], hash)
+	}
+
+	for i := 0; i < n; i++ {
+		hash := []byte(fmt.Sprintf("in %d", i))
+		if !f.MayContain(blocks[f.BlockIndex(hash)], hash) {
+			t.Fatalf("MayContain(%q) = false for an added hash", hash)
+		}
+	}
+	// Blocked filters have a slightly higher rate than they're sized for.
+	var positives int
+	for i := 0; i < n; i++ {
+		hash := []byte(fmt.Sprintf("out %d", i))
+		if f.MayContain(blocks[f.BlockIndex(hash)], hash) {
+			positives++
+		}
+	}
+	if rate := float64(positives) / n; rate > 0.02 {
+		t.Errorf("false positive rate = %v, want <= 0.02", rate)
+	}
+}
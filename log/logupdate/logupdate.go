@@ -0,0 +1,214 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package logupdate computes the Merkle tree nodes which change when new
+// leaves are appended to a log. It only deals with node IDs and hashes, so it
+// can be shared by storage implementations which lay out nodes differently,
+// and tested in isolation from any of them.
+package logupdate
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/google/trillian/merkle"
+	"github.com/google/trillian/merkle/hashers"
+	"github.com/google/trillian/storage"
+)
+
+// MaxTreeDepth is the depth of the node IDs of log trees.
+// TODO(al): We actually can't go beyond 2^63 entries because we use int64s,
+// but we need to calculate tree depths from a multiple of 8 due to the
+// subtrees.
+const MaxTreeDepth = 64
+
+// NodeReader reads the Merkle nodes of a log tree.
+type NodeReader interface {
+	// GetMerkleNodes returns the nodes with the given IDs, as of treeRevision.
+	GetMerkleNodes(ctx context.Context, treeRevision int64, ids []storage.NodeID) ([]storage.Node, error)
+}
+
+// Load returns the compact Merkle tree of a log of the given size, reading the
+// nodes it needs from r at revision. The tree is checked against rootHash.
+func Load(ctx context.Context, hasher hashers.LogHasher, r NodeReader, size, revision int64, rootHash []byte) (*merkle.CompactMerkleTree, error) {
+	if size == 0 {
+		return merkle.NewCompactMerkleTree(hasher), nil
+	}
+	return merkle.NewCompactMerkleTreeWithState(hasher, size, func(depth int, index int64) ([]byte, error) {
+		nodeID, err := storage.NewNodeIDForTreeCoords(int64(depth), index, MaxTreeDepth)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create nodeID: %v", err)
+		}
+		nodes, err := r.GetMerkleNodes(ctx, revision, []storage.NodeID{nodeID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get Merkle nodes: %v", err)
+		}
+		// We expect to get exactly one node here.
+		if len(nodes) != 1 {
+			return nil, fmt.Errorf("did not retrieve one node while loading CompactMerkleTree, got %#v for ID %v@%v", nodes, nodeID.String(), revision)
+		}
+		return nodes[0].Hash, nil
+	}, rootHash)
+}
+
+// Apply appends the leaves with the given hashes to mt, and returns the nodes
+// which the leaves add or change, to be written at revision. Each node is
+// returned once, with its final hash, in the order in which it's first set.
+func Apply(mt *merkle.CompactMerkleTree, leafHashes [][]byte, revision int64) ([]storage.Node, error) {
+	nodes := make([]storage.Node, 0, 2*len(leafHashes))
+	pos := make(map[string]int)
+	set := func(depth int, index int64, hash []byte) error {
+		nodeID, err := storage.NewNodeIDForTreeCoords(int64(depth), index, MaxTreeDepth)
+		if err != nil {
+			return err
+		}
+		node := storage.Node{NodeID: nodeID, Hash: hash, NodeRevision: revision}
+		if i, ok := pos[nodeID.String()]; ok {
+			nodes[i] = node
+		} else {
+			pos[nodeID.String()] = len(nodes)
+			nodes = append(nodes, node)
+		}
+		return nil
+	}
+
+	for _, hash := range leafHashes {
+		seq, err := mt.AddLeafHash(hash, set)
+		if err != nil {
+			return nil, err
+		}
+		// Store the leaf hash in the Merkle tree too.
+		if err := set(0, seq, hash); err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
+
+// Verify checks nodes, as returned by Apply and written at revision, against
+// the nodes read back from r at that revision. Each node must be stored as
+// written, each node of a complete subtree of the tree of the given size must
+// be the hash of its two children, which may have been written at earlier
+// revisions, and the root hash recomputed from the stored subtrees must match
+// rootHash.
+func Verify(ctx context.Context, hasher hashers.LogHasher, r NodeReader, nodes []storage.Node, size, revision int64, rootHash []byte) error {
+	var ids []storage.NodeID
+	pos := make(map[string]int)
+	add := func(id storage.NodeID) {
+		if _, ok := pos[id.String()]; !ok {
+			pos[id.String()] = len(ids)
+			ids = append(ids, id)
+		}
+	}
+	type link struct {
+		parent, left, right storage.NodeID
+	}
+	var links []link
+	for _, node := range nodes {
+		add(node.NodeID)
+		depth, index := coords(node.NodeID)
+		// Nodes on the right border of the tree don't hash their children
+		// until the subtrees below them are complete.
+		if depth == 0 || (index+1)<<uint(depth) > size {
+			continue
+		}
+		left, err := storage.NewNodeIDForTreeCoords(int64(depth-1), 2*index, MaxTreeDepth)
+		if err != nil {
+			return err
+		}
+		right, err := storage.NewNodeIDForTreeCoords(int64(depth-1), 2*index+1, MaxTreeDepth)
+		if err != nil {
+			return err
+		}
+		add(left)
+		add(right)
+		links = append(links, link{parent: node.NodeID, left: left, right: right})
+	}
+	// The roots of the perfect subtrees which make up the tree, left to right.
+	var subtrees []storage.NodeID
+	for start, depth := int64(0), highestBit(size); depth >= 0; depth-- {
+		if size&(1<<uint(depth)) == 0 {
+			continue
+		}
+		id, err := storage.NewNodeIDForTreeCoords(int64(depth), start>>uint(depth), MaxTreeDepth)
+		if err != nil {
+			return err
+		}
+		add(id)
+		subtrees = append(subtrees, id)
+		start += 1 << uint(depth)
+	}
+	if len(ids) == 0 {
+		if !bytes.Equal(hasher.EmptyRoot(), rootHash) {
+			return fmt.Errorf("root hash %x of empty tree, want %x", rootHash, hasher.EmptyRoot())
+		}
+		return nil
+	}
+
+	stored, err := r.GetMerkleNodes(ctx, revision, ids)
+	if err != nil {
+		return fmt.Errorf("failed to get Merkle nodes: %v", err)
+	}
+	if len(stored) != len(ids) {
+		return fmt.Errorf("got %d nodes from storage at revision %d, want %d", len(stored), revision, len(ids))
+	}
+	for i, node := range stored {
+		if !node.NodeID.Equivalent(ids[i]) {
+			return fmt.Errorf("got node %v at position %d, want %v", node.NodeID.CoordString(), i, ids[i].CoordString())
+		}
+	}
+	hash := func(id storage.NodeID) []byte {
+		return stored[pos[id.String()]].Hash
+	}
+
+	for _, node := range nodes {
+		if got := hash(node.NodeID); !bytes.Equal(got, node.Hash) {
+			return fmt.Errorf("node %v stored at revision %d with hash %x, written with %x", node.NodeID.CoordString(), revision, got, node.Hash)
+		}
+	}
+	for _, l := range links {
+		if got, want := hash(l.parent), hasher.HashChildren(hash(l.left), hash(l.right)); !bytes.Equal(got, want) {
+			return fmt.Errorf("node %v at revision %d has hash %x, want %x from its children", l.parent.CoordString(), revision, got, want)
+		}
+	}
+	var root []byte
+	for i := len(subtrees) - 1; i >= 0; i-- {
+		if root == nil {
+			root = hash(subtrees[i])
+		} else {
+			root = hasher.HashChildren(hash(subtrees[i]), root)
+		}
+	}
+	if !bytes.Equal(root, rootHash) {
+		return fmt.Errorf("root hash %x recomputed from stored nodes at revision %d, want %x", root, revision, rootHash)
+	}
+	return nil
+}
+
+// coords returns the depth and index of the node with the given ID.
+func coords(id storage.NodeID) (int, int64) {
+	depth := MaxTreeDepth - id.PrefixLenBits
+	return depth, new(big.Int).Rsh(id.BigInt(), uint(depth)).Int64()
+}
+
+// highestBit returns the index of the highest set bit of x, or -1 if x is zero.
+func highestBit(x int64) int {
+	n := -1
+	for ; x > 0; x >>= 1 {
+		n++
+	}
+	return n
+}
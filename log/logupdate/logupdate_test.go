@@ -0,0 +1,274 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logupdate
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/google/trillian/merkle"
+	"github.com/google/trillian/merkle/rfc6962"
+	"github.com/google/trillian/storage"
+)
+
+// fakeNodes holds the latest hash of each node of a log tree.
+type fakeNodes map[string]storage.Node
+
+func (f fakeNodes) GetMerkleNodes(ctx context.Context, _ int64, ids []storage.NodeID) ([]storage.Node, error) {
+	var ret []storage.Node
+	for _, id := range ids {
+		if node, ok := f[id.String()]; ok {
+			ret = append(ret, node)
+		}
+	}
+	return ret, nil
+}
+
+// rangeHash returns MTH(D[lo:hi]) as defined in RFC 6962.
+func rangeHash(leaves [][]byte, lo, hi int64) []byte {
+	n := hi - lo
+	if n == 1 {
+		return leaves[lo]
+	}
+	k := int64(1)
+	for k<<1 < n {
+		k <<= 1
+	}
+	return rfc6962.DefaultHasher.HashChildren(rangeHash(leaves, lo, lo+k), rangeHash(leaves, lo+k, hi))
+}
+
+// wantNode is the expected hash of a node, and whether Apply must return it.
+type wantNode struct {
+	hash     []byte
+	required bool
+}
+
+// wantNodes returns the nodes which appending leaves[oldSize:] to a tree of
+// oldSize leaves may change. The perfect subtrees ending after oldSize must be
+// returned, while the nodes on the right border of the tree hold the hash of
+// the leaves they span so far, as the compact tree reports them too.
+func wantNodes(t *testing.T, leaves [][]byte, oldSize int64) map[string]wantNode {
+	t.Helper()
+	want := make(map[string]wantNode)
+	newSize := int64(len(leaves))
+	for level := uint(0); level == 0 || int64(1)<<(level-1) < newSize; level++ {
+		for index := oldSize >> level; index<<level < newSize; index++ {
+			lo, hi := index<<level, (index+1)<<level
+			if hi <= oldSize {
+				continue
+			}
+			required := hi <= newSize
+			if !required {
+				hi = newSize
+			}
+			id, err := storage.NewNodeIDForTreeCoords(int64(level), index, MaxTreeDepth)
+			if err != nil {
+				t.Fatalf("NewNodeIDForTreeCoords(%d, %d): %v", level, index, err)
+			}
+			want[id.String()] = wantNode{hash: rangeHash(leaves, lo, hi), required: required}
+		}
+	}
+	return want
+}
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	hasher := rfc6962.DefaultHasher
+	rnd := rand.New(rand.NewSource(42))
+	store := make(fakeNodes)
+	var leaves [][]byte
+
+	for revision := int64(1); len(leaves) < 1000; revision++ {
+		oldSize := int64(len(leaves))
+		var root []byte
+		if oldSize > 0 {
+			root = rangeHash(leaves, 0, oldSize)
+		}
+		mt, err := Load(ctx, hasher, store, oldSize, revision-1, root)
+		if err != nil {
+			t.Fatalf("Load(%d): %v", oldSize, err)
+		}
+
+		batch := make([][]byte, rnd.Intn(40))
+		for i := range batch {
+			h, err := hasher.HashLeaf([]byte(fmt.Sprintf("leaf %d", len(leaves))))
+			if err != nil {
+				t.Fatalf("HashLeaf(): %v", err)
+			}
+			batch[i] = h
+			leaves = append(leaves, h)
+		}
+		nodes, err := Apply(mt, batch, revision)
+		if err != nil {
+			t.Fatalf("Apply(%d leaves at %d): %v", len(batch), oldSize, err)
+		}
+
+		newSize := int64(len(leaves))
+		if got := mt.Size(); got != newSize {
+			t.Fatalf("Apply(%d leaves at %d): size %d, want %d", len(batch), oldSize, got, newSize)
+		}
+		if newSize > 0 {
+			if got, want := mt.CurrentRoot(), rangeHash(leaves, 0, newSize); !bytes.Equal(got, want) {
+				t.Fatalf("Apply(%d leaves at %d): root %x, want %x", len(batch), oldSize, got, want)
+			}
+		}
+
+		want := wantNodes(t, leaves, oldSize)
+		for _, node := range nodes {
+			id := node.NodeID.String()
+			w, ok := want[id]
+			if !ok {
+				t.Errorf("Apply(%d leaves at %d): unexpected node %v", len(batch), oldSize, id)
+				continue
+			}
+			if !bytes.Equal(node.Hash, w.hash) {
+				t.Errorf("Apply(%d leaves at %d): node %v hash %x, want %x", len(batch), oldSize, id, node.Hash, w.hash)
+			}
+			if node.NodeRevision != revision {
+				t.Errorf("Apply(%d leaves at %d): node %v revision %d, want %d", len(batch), oldSize, id, node.NodeRevision, revision)
+			}
+			delete(want, id)
+			store[id] = node
+		}
+		for id, w := range want {
+			if w.required {
+				t.Errorf("Apply(%d leaves at %d): missing node %v", len(batch), oldSize, id)
+			}
+		}
+		if err := Verify(ctx, hasher, store, nodes, newSize, revision, mt.CurrentRoot()); err != nil {
+			t.Errorf("Verify(%d leaves at %d): %v", len(batch), oldSize, err)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	ctx := context.Background()
+	hasher := rfc6962.DefaultHasher
+	mt := merkle.NewCompactMerkleTree(hasher)
+	store := make(fakeNodes)
+	nodes, err := Apply(mt, [][]byte{[]byte("a"), []byte("b"), []byte("c")}, 1)
+	if err != nil {
+		t.Fatalf("Apply(): %v", err)
+	}
+	for _, node := range nodes {
+		store[node.NodeID.String()] = node
+	}
+
+	for _, test := range []struct {
+		desc string
+		size int64
+		root []byte
+	}{
+		{desc: "wrongRoot", size: 3, root: []byte("not the root")},
+		{desc: "missingNodes", size: 5, root: mt.CurrentRoot()},
+	} {
+		if _, err := Load(ctx, hasher, store, test.size, 1, test.root); err == nil {
+			t.Errorf("%v: Load()=_,nil, want error", test.desc)
+		}
+	}
+	if _, err := Load(ctx, hasher, store, 3, 1, mt.CurrentRoot()); err != nil {
+		t.Errorf("Load()=_,%v, want nil", err)
+	}
+}
+
+func TestVerifyErrors(t *testing.T) {
+	ctx := context.Background()
+	hasher := rfc6962.DefaultHasher
+	leafHash := func(i int) []byte {
+		h, err := hasher.HashLeaf([]byte(fmt.Sprintf("leaf %d", i)))
+		if err != nil {
+			t.Fatalf("HashLeaf(): %v", err)
+		}
+		return h
+	}
+
+	// Write 5 leaves at revision 1 and 2 more at revision 2, so that the
+	// nodes written at revision 2 link to nodes written at revision 1.
+	mt := merkle.NewCompactMerkleTree(hasher)
+	store := make(fakeNodes)
+	var nodes []storage.Node
+	for rev, count := range []int{5, 2} {
+		var batch [][]byte
+		for i := 0; i < count; i++ {
+			batch = append(batch, leafHash(int(mt.Size())+i))
+		}
+		var err error
+		if nodes, err = Apply(mt, batch, int64(rev+1)); err != nil {
+			t.Fatalf("Apply(): %v", err)
+		}
+		for _, node := range nodes {
+			store[node.NodeID.String()] = node
+		}
+	}
+	if err := Verify(ctx, hasher, store, nodes, 7, 2, mt.CurrentRoot()); err != nil {
+		t.Fatalf("Verify(): %v, want nil", err)
+	}
+
+	nodeID := func(depth, index int64) string {
+		id, err := storage.NewNodeIDForTreeCoords(depth, index, MaxTreeDepth)
+		if err != nil {
+			t.Fatalf("NewNodeIDForTreeCoords(): %v", err)
+		}
+		return id.String()
+	}
+	for _, test := range []struct {
+		desc   string
+		root   []byte
+		modify func(store fakeNodes)
+	}{
+		{desc: "wrongRoot", root: []byte("not the root")},
+		{
+			desc:   "missingSubtree",
+			modify: func(store fakeNodes) { delete(store, nodeID(2, 0)) },
+		},
+		{
+			desc: "corruptChild",
+			modify: func(store fakeNodes) {
+				// The leaf was written at revision 1, but its parent at 2.
+				node := store[nodeID(0, 4)]
+				node.Hash = leafHash(100)
+				store[nodeID(0, 4)] = node
+			},
+		},
+		{
+			desc: "notStoredAsWritten",
+			modify: func(store fakeNodes) {
+				node := store[nodeID(0, 6)]
+				node.Hash = leafHash(100)
+				store[nodeID(0, 6)] = node
+			},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			corrupt := make(fakeNodes)
+			for id, node := range store {
+				corrupt[id] = node
+			}
+			if test.modify != nil {
+				test.modify(corrupt)
+			}
+			root := mt.CurrentRoot()
+			if test.root != nil {
+				root = test.root
+			}
+			if err := Verify(ctx, hasher, corrupt, nodes, 7, 2, root); err == nil {
+				t.Error("Verify(): nil, want err")
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+)
+
+var (
+	// MergeDelayWindowLength is the length of the windows of time in which
+	// the merge delay compliance of logs is recorded.
+	MergeDelayWindowLength = time.Hour
+
+	// MergeDelayNearMiss is the fraction of the maximum merge delay (MMD) of a
+	// log above which a leaf integrated within the MMD is a near miss.
+	MergeDelayNearMiss = 0.8
+)
+
+// mergeDelayWindows summarizes the merge delays of the integrated leaves
+// against the MMD mmd, in windows of MergeDelayWindowLength by integration
+// time, oldest first. Leaves without a queue timestamp are skipped.
+func mergeDelayWindows(leaves []*trillian.LogLeaf, mmd time.Duration) ([]storage.MergeDelayWindow, error) {
+	nearMiss := time.Duration(float64(mmd) * MergeDelayNearMiss)
+	byStart := make(map[time.Time]*storage.MergeDelayWindow)
+	for _, leaf := range leaves {
+		if leaf.QueueTimestamp == nil || leaf.QueueTimestamp.Seconds == 0 {
+			continue
+		}
+		queued, err := ptypes.Timestamp(leaf.QueueTimestamp)
+		if err != nil {
+			return nil, fmt.Errorf("got invalid queue timestamp: %v", err)
+		}
+		integrated, err := ptypes.Timestamp(leaf.IntegrateTimestamp)
+		if err != nil {
+			return nil, fmt.Errorf("got invalid integrate timestamp: %v", err)
+		}
+
+		start := integrated.Truncate(MergeDelayWindowLength)
+		w := byStart[start]
+		if w == nil {
+			w = &storage.MergeDelayWindow{Start: start, Length: MergeDelayWindowLength, MaxMergeDelay: mmd}
+			byStart[start] = w
+		}
+		delay := integrated.Sub(queued)
+		w.Leaves++
+		switch {
+		case delay > mmd:
+			w.Violations++
+		case delay > nearMiss:
+			w.NearMisses++
+		}
+		if delay > w.MaxDelay {
+			w.MaxDelay = delay
+		}
+	}
+
+	windows := make([]storage.MergeDelayWindow, 0, len(byStart))
+	for _, w := range byStart {
+		windows = append(windows, *w)
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i].Start.Before(windows[j].Start) })
+	return windows, nil
+}
+
+// recordMergeDelays records the merge delay windows of the integrated leaves
+// in storage, if it supports it.
+func recordMergeDelays(ctx context.Context, tx storage.LogTreeTX, windows []storage.MergeDelayWindow, label string) error {
+	writer, ok := tx.(storage.MergeDelayWriter)
+	if !ok {
+		glog.V(1).Infof("%v: storage doesn't support merge delay windows, not recording them", label)
+		return nil
+	}
+	for _, w := range windows {
+		if err := writer.AddMergeDelayWindow(ctx, w); err != nil {
+			glog.Warningf("%v: Sequencer failed to record merge delay window: %v", label, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// reportMergeDelays updates the MMD metrics of the log with the merge delay
+// windows of integrated leaves, and alerts on violations and near misses.
+func reportMergeDelays(windows []storage.MergeDelayWindow, label string) {
+	for _, w := range windows {
+		seqMMDLeaves.Add(float64(w.Leaves), label)
+		seqMMDViolations.Add(float64(w.Violations), label)
+		seqMMDNearMisses.Add(float64(w.NearMisses), label)
+		seqMMDDelayRatio.Set(w.MaxDelay.Seconds()/w.MaxMergeDelay.Seconds(), label)
+		if w.Violations > 0 {
+			glog.Errorf("%v: %d leaves integrated later than the max_merge_delay %v, up to %v after being queued", label, w.Violations, w.MaxMergeDelay, w.MaxDelay)
+		} else if w.NearMisses > 0 {
+			glog.Warningf("%v: %d leaves integrated close to the max_merge_delay %v, up to %v after being queued", label, w.NearMisses, w.MaxMergeDelay, w.MaxDelay)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+)
+
+func TestMergeDelayWindows(t *testing.T) {
+	hour := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	leaf := func(queued, integrated time.Time) *trillian.LogLeaf {
+		l := &trillian.LogLeaf{}
+		if !queued.IsZero() {
+			l.QueueTimestamp, _ = ptypes.TimestampProto(queued)
+		}
+		l.IntegrateTimestamp, _ = ptypes.TimestampProto(integrated)
+		return l
+	}
+	leaves := []*trillian.LogLeaf{
+		// Integrated in the next hour: a violation.
+		leaf(hour.Add(50*time.Minute), hour.Add(62*time.Minute)),
+		// Integrated in this hour: fine, a near miss, and no queue timestamp.
+		leaf(hour.Add(10*time.Minute), hour.Add(15*time.Minute)),
+		leaf(hour.Add(10*time.Minute), hour.Add(19*time.Minute)),
+		leaf(time.Time{}, hour.Add(20*time.Minute)),
+	}
+
+	got, err := mergeDelayWindows(leaves, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("mergeDelayWindows(): %v", err)
+	}
+	want := []storage.MergeDelayWindow{
+		{Start: hour, Length: time.Hour, MaxMergeDelay: 10 * time.Minute, Leaves: 2, NearMisses: 1, MaxDelay: 9 * time.Minute},
+		{Start: hour.Add(time.Hour), Length: time.Hour, MaxMergeDelay: 10 * time.Minute, Leaves: 1, Violations: 1, MaxDelay: 12 * time.Minute},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeDelayWindows(): got %+v, want %+v", got, want)
+	}
+}
+
+func TestIntegrateBatchMergeDelay(t *testing.T) {
+	ctx := context.Background()
+	for _, backend := range raceBackends() {
+		t.Run(backend.desc, func(t *testing.T) {
+			as, ls := backend.new(ctx, t)
+			e := newRaceEnv(ctx, t, as, ls)
+			// The leaves are queued a minute before they're integrated, which is
+			// a near miss for the first batch and a violation for the second.
+			leaves := e.leaves(t, 20)
+			for i, mmd := range []time.Duration{70 * time.Second, 30 * time.Second} {
+				if err := e.queue(ctx, leaves[i*10:(i+1)*10]); err != nil {
+					t.Fatalf("QueueLeaves(): %v", err)
+				}
+				if _, err := e.unseq.IntegrateBatchWithOptions(ctx, e.logID, BatchOptions{Limit: 10, MaxMergeDelay: mmd}); err != nil {
+					t.Fatalf("IntegrateBatchWithOptions(): %v", err)
+				}
+			}
+
+			windows, err := ls.(storage.MergeDelayReader).GetMergeDelayWindows(ctx, e.logID, time.Time{}, time.Time{})
+			if err != nil {
+				t.Fatalf("GetMergeDelayWindows(): %v", err)
+			}
+			start := fakeTimeForTest.Truncate(MergeDelayWindowLength)
+			want := []storage.MergeDelayWindow{
+				{Start: start, Length: time.Hour, MaxMergeDelay: 30 * time.Second, Leaves: 20, Violations: 10, NearMisses: 10, MaxDelay: time.Minute},
+			}
+			if len(windows) != 1 || !windows[0].Start.Equal(start) {
+				t.Fatalf("GetMergeDelayWindows(): got %+v, want %+v", windows, want)
+			}
+			windows[0].Start = start
+			if !reflect.DeepEqual(windows, want) {
+				t.Errorf("GetMergeDelayWindows(): got %+v, want %+v", windows, want)
+			}
+
+			label := strconv.FormatInt(e.logID, 10)
+			if got := seqMMDLeaves.Value(label); got != 20 {
+				t.Errorf("seqMMDLeaves = %v, want 20", got)
+			}
+			if got := seqMMDViolations.Value(label); got != 10 {
+				t.Errorf("seqMMDViolations = %v, want 10", got)
+			}
+			if got := seqMMDNearMisses.Value(label); got != 10 {
+				t.Errorf("seqMMDNearMisses = %v, want 10", got)
+			}
+			if got := seqMMDDelayRatio.Value(label); got != 2 {
+				t.Errorf("seqMMDDelayRatio = %v, want 2", got)
+			}
+		})
+	}
+}
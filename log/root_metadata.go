@@ -0,0 +1,77 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"context"
+	"sync"
+
+	"github.com/golang/glog"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/google/trillian"
+)
+
+// RootMetadataFunc returns the metadata to be signed into newRoot, a root of
+// the log that is about to be signed, given latest, the root that it replaces.
+// Returning nil metadata keeps the metadata of latest. An error stops the root
+// from being signed.
+type RootMetadataFunc func(ctx context.Context, latest, newRoot *trillian.SignedLogRoot) (*any.Any, error)
+
+var (
+	rootMetadataMu sync.RWMutex
+	rootMetadata   RootMetadataFunc
+)
+
+// RegisterRootMetadataFunc sets the function called by the sequencer to attach
+// personality-specific metadata to each root it signs. The metadata is covered
+// by the root signature and returned by GetLatestSignedLogRoot.
+// If a function has already been registered, it will be replaced.
+func RegisterRootMetadataFunc(f RootMetadataFunc) {
+	rootMetadataMu.Lock()
+	defer rootMetadataMu.Unlock()
+	if rootMetadata != nil {
+		glog.Warning("Overriding RootMetadataFunc")
+	}
+	rootMetadata = f
+}
+
+// UnregisterRootMetadataFunc removes a previously-registered function.
+// See RegisterRootMetadataFunc().
+func UnregisterRootMetadataFunc() {
+	rootMetadataMu.Lock()
+	defer rootMetadataMu.Unlock()
+	rootMetadata = nil
+}
+
+// setRootMetadata sets the metadata of newRoot, which is that returned by the
+// registered RootMetadataFunc or, failing that, the metadata of latest.
+func setRootMetadata(ctx context.Context, latest, newRoot *trillian.SignedLogRoot) error {
+	rootMetadataMu.RLock()
+	f := rootMetadata
+	rootMetadataMu.RUnlock()
+
+	newRoot.Metadata = latest.Metadata
+	if f == nil {
+		return nil
+	}
+	md, err := f(ctx, latest, newRoot)
+	if err != nil {
+		return err
+	}
+	if md != nil {
+		newRoot.Metadata = md
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/google/trillian"
+)
+
+func TestSetRootMetadata(t *testing.T) {
+	ctx := context.Background()
+	oldMD := &any.Any{TypeUrl: "type.example.com/old", Value: []byte("old")}
+	newMD := &any.Any{TypeUrl: "type.example.com/new", Value: []byte("new")}
+
+	for _, test := range []struct {
+		desc    string
+		f       RootMetadataFunc
+		want    *any.Any
+		wantErr bool
+	}{
+		{desc: "unregistered", want: oldMD},
+		{
+			desc: "nil metadata",
+			f: func(context.Context, *trillian.SignedLogRoot, *trillian.SignedLogRoot) (*any.Any, error) {
+				return nil, nil
+			},
+			want: oldMD,
+		},
+		{
+			desc: "new metadata",
+			f: func(_ context.Context, latest, root *trillian.SignedLogRoot) (*any.Any, error) {
+				if latest.TreeSize != 1 || root.TreeSize != 2 {
+					return nil, errors.New("wrong roots")
+				}
+				return newMD, nil
+			},
+			want: newMD,
+		},
+		{
+			desc: "error",
+			f: func(context.Context, *trillian.SignedLogRoot, *trillian.SignedLogRoot) (*any.Any, error) {
+				return nil, errors.New("failed")
+			},
+			wantErr: true,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			if test.f != nil {
+				RegisterRootMetadataFunc(test.f)
+				defer UnregisterRootMetadataFunc()
+			}
+			latest := &trillian.SignedLogRoot{TreeSize: 1, Metadata: oldMD}
+			root := &trillian.SignedLogRoot{TreeSize: 2}
+			err := setRootMetadata(ctx, latest, root)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("setRootMetadata(): %v, want err: %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !proto.Equal(root.Metadata, test.want) {
+				t.Errorf("setRootMetadata(): metadata %v, want %v", root.Metadata, test.want)
+			}
+		})
+	}
+}
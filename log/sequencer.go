@@ -27,12 +27,14 @@ import (
 	"github.com/golang/protobuf/ptypes"
 	"github.com/google/trillian"
 	"github.com/google/trillian/crypto"
+	"github.com/google/trillian/log/logupdate"
 	"github.com/google/trillian/merkle"
 	"github.com/google/trillian/merkle/hashers"
 	"github.com/google/trillian/monitoring"
 	"github.com/google/trillian/quota"
 	"github.com/google/trillian/storage"
 	"github.com/google/trillian/util"
+	"github.com/google/trillian/util/requestinfo"
 )
 
 const logIDLabel = "logid"
@@ -51,6 +53,17 @@ var (
 	seqStoreRootLatency    monitoring.Histogram
 	seqCounter             monitoring.Counter
 	seqMergeDelay          monitoring.Histogram
+	seqRootTimestampSkew   monitoring.Counter
+	seqLeafIndexKeys       monitoring.Counter
+	seqSignatures          monitoring.Counter
+	seqSignLatency         monitoring.Histogram
+	seqSignFailures        monitoring.Counter
+	seqVerifyFailures      monitoring.Counter
+	seqRootOverdue         monitoring.Gauge
+	seqMMDLeaves           monitoring.Counter
+	seqMMDViolations       monitoring.Counter
+	seqMMDNearMisses       monitoring.Counter
+	seqMMDDelayRatio       monitoring.Gauge
 
 	// QuotaIncreaseFactor is the multiplier used for the number of tokens added back to
 	// sequencing-based quotas. The resulting PutTokens call is equivalent to
@@ -61,8 +74,23 @@ var (
 	// configuration should be changed instead.
 	// A factor <1 WILL lead to token shortages, therefore it'll be normalized to 1.
 	QuotaIncreaseFactor = 1.1
+
+	// ClampRootTimestamps determines what happens when the clock reads earlier
+	// than the timestamp of the latest root of a log, e.g. after a backwards
+	// jump of the clock or a change of signer. If false, no new root is signed
+	// until the clock catches up. If true, the new root is timestamped one
+	// tick of the log's RootTimestampPrecision after the latest one.
+	ClampRootTimestamps = false
 )
 
+// timestampTicks maps each root timestamp precision to the length of its tick
+// in nanoseconds.
+var timestampTicks = map[trillian.RootTimestampPrecision]int64{
+	trillian.RootTimestampPrecision_NANOSECOND_PRECISION:  1,
+	trillian.RootTimestampPrecision_MILLISECOND_PRECISION: int64(time.Millisecond),
+	trillian.RootTimestampPrecision_SECOND_PRECISION:      int64(time.Second),
+}
+
 func quotaIncreaseFactor() float64 {
 	if QuotaIncreaseFactor < 1 {
 		QuotaIncreaseFactor = 1
@@ -78,16 +106,27 @@ func createMetrics(mf monitoring.MetricFactory) {
 	quota.InitMetrics(mf)
 	seqBatches = mf.NewCounter("sequencer_batches", "Number of sequencer batch operations", logIDLabel)
 	seqTreeSize = mf.NewGauge("sequencer_tree_size", "Size of Merkle tree", logIDLabel)
-	seqLatency = mf.NewHistogram("sequencer_latency", "Latency of sequencer batch operation in seconds", logIDLabel)
-	seqDequeueLatency = mf.NewHistogram("sequencer_latency_dequeue", "Latency of dequeue-leaves part of sequencer batch operation in seconds", logIDLabel)
-	seqGetRootLatency = mf.NewHistogram("sequencer_latency_get_root", "Latency of get-root part of sequencer batch operation in seconds", logIDLabel)
-	seqInitTreeLatency = mf.NewHistogram("sequencer_latency_init_tree", "Latency of init-tree part of sequencer batch operation in seconds", logIDLabel)
-	seqWriteTreeLatency = mf.NewHistogram("sequencer_latency_write_tree", "Latency of write-tree part of sequencer batch operation in seconds", logIDLabel)
-	seqUpdateLeavesLatency = mf.NewHistogram("sequencer_latency_update_leaves", "Latency of update-leaves part of sequencer batch operation in seconds", logIDLabel)
-	seqSetNodesLatency = mf.NewHistogram("sequencer_latency_set_nodes", "Latency of set-nodes part of sequencer batch operation in seconds", logIDLabel)
-	seqStoreRootLatency = mf.NewHistogram("sequencer_latency_store_root", "Latency of store-root part of sequencer batch operation in seconds", logIDLabel)
+	seqLatency = mf.NewHistogramWithBuckets("sequencer_latency", "Latency of sequencer batch operation in seconds", monitoring.LatencyBuckets(), logIDLabel)
+	seqDequeueLatency = mf.NewHistogramWithBuckets("sequencer_latency_dequeue", "Latency of dequeue-leaves part of sequencer batch operation in seconds", monitoring.LatencyBuckets(), logIDLabel)
+	seqGetRootLatency = mf.NewHistogramWithBuckets("sequencer_latency_get_root", "Latency of get-root part of sequencer batch operation in seconds", monitoring.LatencyBuckets(), logIDLabel)
+	seqInitTreeLatency = mf.NewHistogramWithBuckets("sequencer_latency_init_tree", "Latency of init-tree part of sequencer batch operation in seconds", monitoring.LatencyBuckets(), logIDLabel)
+	seqWriteTreeLatency = mf.NewHistogramWithBuckets("sequencer_latency_write_tree", "Latency of write-tree part of sequencer batch operation in seconds", monitoring.LatencyBuckets(), logIDLabel)
+	seqUpdateLeavesLatency = mf.NewHistogramWithBuckets("sequencer_latency_update_leaves", "Latency of update-leaves part of sequencer batch operation in seconds", monitoring.LatencyBuckets(), logIDLabel)
+	seqSetNodesLatency = mf.NewHistogramWithBuckets("sequencer_latency_set_nodes", "Latency of set-nodes part of sequencer batch operation in seconds", monitoring.LatencyBuckets(), logIDLabel)
+	seqStoreRootLatency = mf.NewHistogramWithBuckets("sequencer_latency_store_root", "Latency of store-root part of sequencer batch operation in seconds", monitoring.LatencyBuckets(), logIDLabel)
 	seqCounter = mf.NewCounter("sequencer_sequenced", "Number of leaves sequenced", logIDLabel)
-	seqMergeDelay = mf.NewHistogram("sequencer_merge_delay", "Delay between queuing and integration of leaves", logIDLabel)
+	seqMergeDelay = mf.NewHistogramWithBuckets("sequencer_merge_delay", "Delay between queuing and integration of leaves", monitoring.LatencyBuckets(), logIDLabel)
+	seqRootTimestampSkew = mf.NewCounter("sequencer_root_timestamp_skew", "Number of new roots for which the clock read earlier than the latest root", logIDLabel)
+	seqLeafIndexKeys = mf.NewCounter("sequencer_leaf_index_keys", "Number of keys added to the leaf indexes of logs", logIDLabel)
+	seqSignatures = mf.NewCounter("sequencer_signatures", "Number of signatures made over log roots, including those of additional signing keys", logIDLabel)
+	seqSignLatency = mf.NewHistogramWithBuckets("sequencer_latency_sign", "Latency of signing a log root with all the keys of its tree in seconds", monitoring.LatencyBuckets(), logIDLabel)
+	seqSignFailures = mf.NewCounter("sequencer_sign_failures", "Number of log roots which couldn't be signed", logIDLabel)
+	seqVerifyFailures = mf.NewCounter("sequencer_verify_failures", "Number of sequencing passes failed by the verification of the Merkle nodes they wrote", logIDLabel)
+	seqRootOverdue = mf.NewGauge("sequencer_root_overdue", "Set to 1 if the latest sequencing pass failed while the latest root was older than the max_root_duration of the log", logIDLabel)
+	seqMMDLeaves = mf.NewCounter("sequencer_mmd_leaves", "Number of leaves integrated into logs with a max_merge_delay", logIDLabel)
+	seqMMDViolations = mf.NewCounter("sequencer_mmd_violations", "Number of leaves integrated later than the max_merge_delay of their log", logIDLabel)
+	seqMMDNearMisses = mf.NewCounter("sequencer_mmd_near_misses", "Number of leaves integrated within the max_merge_delay of their log, but close to it", logIDLabel)
+	seqMMDDelayRatio = mf.NewGauge("sequencer_mmd_delay_ratio", "Ratio of the longest merge delay of the latest leaves integrated into a log to its max_merge_delay", logIDLabel)
 }
 
 // TODO(Martin2112): Add admin support for safely changing params like guard window during operation
@@ -105,12 +144,6 @@ type Sequencer struct {
 	qm         quota.Manager
 }
 
-// maxTreeDepth sets an upper limit on the size of Log trees.
-// TODO(al): We actually can't go beyond 2^63 entries because we use int64s,
-//           but we need to calculate tree depths from a multiple of 8 due to
-//           the subtrees.
-const maxTreeDepth = 64
-
 // NewSequencer creates a new Sequencer instance for the specified inputs.
 func NewSequencer(
 	hasher hashers.LogHasher,
@@ -133,65 +166,36 @@ func NewSequencer(
 
 // TODO: This currently doesn't use the batch api for fetching the required nodes. This
 // would be more efficient but requires refactoring.
-func (s Sequencer) buildMerkleTreeFromStorageAtRoot(ctx context.Context, root trillian.SignedLogRoot, tx storage.TreeTX) (*merkle.CompactMerkleTree, error) {
-	mt, err := merkle.NewCompactMerkleTreeWithState(s.hasher, root.TreeSize, func(depth int, index int64) ([]byte, error) {
-		nodeID, err := storage.NewNodeIDForTreeCoords(int64(depth), index, maxTreeDepth)
-		if err != nil {
-			glog.Warningf("%v: Failed to create nodeID: %v", root.LogId, err)
-			return nil, err
-		}
-		nodes, err := tx.GetMerkleNodes(ctx, root.TreeRevision, []storage.NodeID{nodeID})
-
-		if err != nil {
-			glog.Warningf("%v: Failed to get Merkle nodes: %v", root.LogId, err)
-			return nil, err
-		}
-
-		// We expect to get exactly one node here
-		if nodes == nil || len(nodes) != 1 {
-			return nil, fmt.Errorf("%v: Did not retrieve one node while loading CompactMerkleTree, got %#v for ID %v@%v", root.LogId, nodes, nodeID.String(), root.TreeRevision)
-		}
-
-		return nodes[0].Hash, nil
-	}, root.RootHash)
-
-	return mt, err
-}
-
-func (s Sequencer) buildNodesFromNodeMap(nodeMap map[string]storage.Node, newVersion int64) ([]storage.Node, error) {
-	targetNodes := make([]storage.Node, len(nodeMap))
-	i := 0
-	for _, node := range nodeMap {
-		node.NodeRevision = newVersion
-		targetNodes[i] = node
-		i++
+func (s Sequencer) initMerkleTreeFromStorage(ctx context.Context, currentRoot trillian.SignedLogRoot, tx storage.LogTreeTX) (*merkle.CompactMerkleTree, error) {
+	// Initialize the compact tree state to match the latest root in the database
+	mt, err := logupdate.Load(ctx, s.hasher, tx, currentRoot.TreeSize, currentRoot.TreeRevision, currentRoot.RootHash)
+	if err != nil {
+		requestinfo.Warningf(ctx, "%v: Failed to load CompactMerkleTree: %v", currentRoot.LogId, err)
+		return nil, err
 	}
-	return targetNodes, nil
+	return mt, nil
 }
 
-func (s Sequencer) updateCompactTree(mt *merkle.CompactMerkleTree, leaves []*trillian.LogLeaf, label string) (map[string]storage.Node, error) {
-	nodeMap := make(map[string]storage.Node)
-	// Update the tree state by integrating the leaves one by one.
-	for _, leaf := range leaves {
-		seq, err := mt.AddLeafHash(leaf.MerkleLeafHash, func(depth int, index int64, hash []byte) error {
-			nodeID, err := storage.NewNodeIDForTreeCoords(int64(depth), index, maxTreeDepth)
-			if err != nil {
-				return err
-			}
-			nodeMap[nodeID.String()] = storage.Node{
-				NodeID: nodeID,
-				Hash:   hash,
-			}
-			return nil
-		})
-		if err != nil {
-			return nil, err
-		}
+// updateCompactTree integrates the leaves into mt, and returns the nodes to
+// store at newVersion.
+func (s Sequencer) updateCompactTree(mt *merkle.CompactMerkleTree, leaves []*trillian.LogLeaf, newVersion int64, label string) ([]storage.Node, error) {
+	hashes := make([][]byte, 0, len(leaves))
+	for i, leaf := range leaves {
 		// The leaf should already have the correct index before it's integrated.
-		if leaf.LeafIndex != seq {
-			return nil, fmt.Errorf("got invalid leaf index: %v, want: %v", leaf.LeafIndex, seq)
+		if want := mt.Size() + int64(i); leaf.LeafIndex != want {
+			return nil, fmt.Errorf("got invalid leaf index: %v, want: %v", leaf.LeafIndex, want)
 		}
-		integrateTS := s.timeSource.Now()
+		hashes = append(hashes, leaf.MerkleLeafHash)
+	}
+	nodes, err := logupdate.Apply(mt, hashes, newVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	// All the leaves are integrated at the same revision, so they share a
+	// timestamp.
+	integrateTS := s.timeSource.Now()
+	for _, leaf := range leaves {
 		leaf.IntegrateTimestamp, err = ptypes.TimestampProto(integrateTS)
 		if err != nil {
 			return nil, fmt.Errorf("got invalid integrate timestamp: %v", err)
@@ -206,28 +210,8 @@ func (s Sequencer) updateCompactTree(mt *merkle.CompactMerkleTree, leaves []*tri
 			mergeDelay := integrateTS.Sub(queueTS)
 			seqMergeDelay.Observe(mergeDelay.Seconds(), label)
 		}
-
-		// Store leaf hash in the Merkle tree too:
-		leafNodeID, err := storage.NewNodeIDForTreeCoords(0, seq, maxTreeDepth)
-		if err != nil {
-			return nil, err
-		}
-		nodeMap[leafNodeID.String()] = storage.Node{
-			NodeID: leafNodeID,
-			Hash:   leaf.MerkleLeafHash,
-		}
 	}
-
-	return nodeMap, nil
-}
-
-func (s Sequencer) initMerkleTreeFromStorage(ctx context.Context, currentRoot trillian.SignedLogRoot, tx storage.LogTreeTX) (*merkle.CompactMerkleTree, error) {
-	if currentRoot.TreeSize == 0 {
-		return merkle.NewCompactMerkleTree(s.hasher), nil
-	}
-
-	// Initialize the compact tree state to match the latest root in the database
-	return s.buildMerkleTreeFromStorageAtRoot(ctx, currentRoot, tx)
+	return nodes, nil
 }
 
 // sequencingTask provides sequenced LogLeaf entries, and updates storage
@@ -259,7 +243,7 @@ func (s logSequencingTask) fetch(ctx context.Context, limit int, cutoff time.Tim
 	// Recent leaves inside the guard window will not be available for sequencing.
 	leaves, err := s.dequeuer.DequeueLeaves(ctx, limit, cutoff)
 	if err != nil {
-		glog.Warningf("%v: Sequencer failed to dequeue leaves: %v", s.label, err)
+		requestinfo.Warningf(ctx, "%v: Sequencer failed to dequeue leaves: %v", s.label, err)
 		return nil, err
 	}
 	seqDequeueLatency.Observe(util.SecondsSince(s.timeSource, start), s.label)
@@ -275,21 +259,80 @@ func (s logSequencingTask) update(ctx context.Context, leaves []*trillian.LogLea
 	start := s.timeSource.Now()
 	// Write the new sequence numbers to the leaves in the DB.
 	if err := s.dequeuer.UpdateSequencedLeaves(ctx, leaves); err != nil {
-		glog.Warningf("%v: Sequencer failed to update sequenced leaves: %v", s.label, err)
+		requestinfo.Warningf(ctx, "%v: Sequencer failed to update sequenced leaves: %v", s.label, err)
 		return err
 	}
 	seqUpdateLeavesLatency.Observe(util.SecondsSince(s.timeSource, start), s.label)
 	return nil
 }
 
+// BatchOptions configures the integration of a batch of leaves by
+// IntegrateBatchWithOptions.
+type BatchOptions struct {
+	// Limit is the maximum number of leaves integrated.
+	Limit int
+	// GuardWindow is how long leaves must have been queued for before they're
+	// integrated.
+	GuardWindow time.Duration
+	// MaxRootDuration is the age of the latest root above which a new root is
+	// signed even if there are no leaves to integrate. Zero means that roots
+	// are only signed for new leaves.
+	MaxRootDuration time.Duration
+	// DeferReplenish stops the sequencer from replenishing quota tokens for
+	// the integrated leaves, which the caller must then do with
+	// ReplenishQuota.
+	DeferReplenish bool
+	// RootTimestampPrecision is the precision of the timestamps of new roots.
+	RootTimestampPrecision trillian.RootTimestampPrecision
+	// LeafIndexes are the secondary indexes the integrated leaves are added
+	// to.
+	LeafIndexes []*trillian.LeafIndexSpec
+	// LeafFilter, if set, sizes the filter the identity hashes of the
+	// integrated leaves are added to.
+	LeafFilter *trillian.LeafFilterSpec
+	// MaxMergeDelay, if positive, is the MMD of the log, which the merge
+	// delays of the integrated leaves are checked and recorded against.
+	MaxMergeDelay time.Duration
+	// VerifyWrites checks the Merkle nodes written for the integrated leaves
+	// against storage before the new root is signed. See
+	// trillian.WriteVerification_VERIFY_WRITES.
+	VerifyWrites bool
+}
+
+// BatchResult describes a batch of leaves integrated by
+// IntegrateBatchWithOptions.
+type BatchResult struct {
+	// Leaves is the number of leaves integrated.
+	Leaves int
+	// OldestQueued is the earliest time at which one of the integrated leaves
+	// was queued, or zero if none of them have a queue timestamp.
+	OldestQueued time.Time
+}
+
 // IntegrateBatch wraps up all the operations needed to take a batch of queued
 // leaves and integrate them into the tree.
 func (s Sequencer) IntegrateBatch(ctx context.Context, logID int64, limit int, guardWindow, maxRootDurationInterval time.Duration) (int, error) {
+	res, err := s.IntegrateBatchWithOptions(ctx, logID, BatchOptions{
+		Limit:           limit,
+		GuardWindow:     guardWindow,
+		MaxRootDuration: maxRootDurationInterval,
+	})
+	return res.Leaves, err
+}
+
+// IntegrateBatchWithOptions is like IntegrateBatch, but configured by opts,
+// and also reports how long the integrated leaves had been queued for.
+func (s Sequencer) IntegrateBatchWithOptions(ctx context.Context, logID int64, opts BatchOptions) (BatchResult, error) {
 	start := s.timeSource.Now()
 	label := strconv.FormatInt(logID, 10)
+	limit, guardWindow, maxRootDurationInterval := opts.Limit, opts.GuardWindow, opts.MaxRootDuration
 
 	numLeaves := 0
+	var oldestQueued time.Time
 	var newLogRoot *trillian.SignedLogRoot
+	var mergeDelays []storage.MergeDelayWindow
+	// rootAge is the age of the latest root, or negative if it isn't known.
+	rootAge := time.Duration(-1)
 	err := s.logStorage.ReadWriteTransaction(ctx, logID, func(ctx context.Context, tx storage.LogTreeTX) error {
 		stageStart := s.timeSource.Now()
 		defer seqBatches.Inc(label)
@@ -298,15 +341,16 @@ func (s Sequencer) IntegrateBatch(ctx context.Context, logID int64, limit int, g
 		// Get the latest known root from storage
 		currentRoot, err := tx.LatestSignedLogRoot(ctx)
 		if err != nil {
-			glog.Warningf("%v: Sequencer failed to get latest root: %v", logID, err)
+			requestinfo.Warningf(ctx, "%v: Sequencer failed to get latest root: %v", logID, err)
 			return err
 		}
 		seqGetRootLatency.Observe(util.SecondsSince(s.timeSource, stageStart), label)
 
 		if currentRoot.RootHash == nil {
-			glog.Warningf("%v: Fresh log - no previous TreeHeads exist.", logID)
+			requestinfo.Warningf(ctx, "%v: Fresh log - no previous TreeHeads exist.", logID)
 			return storage.ErrTreeNeedsInit
 		}
+		rootAge = time.Duration(s.timeSource.Now().UnixNano() - currentRoot.TimestampNanos)
 
 		var st sequencingTask = logSequencingTask{
 			label:      label,
@@ -316,10 +360,11 @@ func (s Sequencer) IntegrateBatch(ctx context.Context, logID int64, limit int, g
 		}
 		sequencedLeaves, err := st.fetch(ctx, limit, start.Add(-guardWindow))
 		if err != nil {
-			glog.Warningf("%v: Sequencer failed to load sequenced batch: %v", logID, err)
+			requestinfo.Warningf(ctx, "%v: Sequencer failed to load sequenced batch: %v", logID, err)
 			return err
 		}
 		numLeaves = len(sequencedLeaves)
+		oldestQueued = oldestQueueTime(sequencedLeaves)
 
 		// We need to create a signed root if entries were added or the latest root
 		// is too old.
@@ -331,7 +376,7 @@ func (s Sequencer) IntegrateBatch(ctx context.Context, logID int64, limit int, g
 				glog.V(1).Infof("%v: No leaves sequenced in this signing operation", logID)
 				return nil
 			}
-			glog.Infof("%v: Force new root generation as %v since last root", logID, interval)
+			requestinfo.Infof(ctx, "%v: Force new root generation as %v since last root", logID, interval)
 		}
 
 		stageStart = s.timeSource.Now()
@@ -351,8 +396,9 @@ func (s Sequencer) IntegrateBatch(ctx context.Context, logID int64, limit int, g
 			return fmt.Errorf("%v: got writeRevision of %v, but expected %v", logID, got, want)
 		}
 
-		// Collate node updates.
-		nodeMap, err := s.updateCompactTree(merkleTree, sequencedLeaves, label)
+		// Collate node updates. Each node is only created / updated once in each
+		// tree revision so they cannot conflict when we do the storage update.
+		targetNodes, err := s.updateCompactTree(merkleTree, sequencedLeaves, newVersion, label)
 		if err != nil {
 			return err
 		}
@@ -362,45 +408,69 @@ func (s Sequencer) IntegrateBatch(ctx context.Context, logID int64, limit int, g
 		if err := st.update(ctx, sequencedLeaves); err != nil {
 			return err
 		}
-		stageStart = s.timeSource.Now()
-
-		// Build objects for the nodes to be updated. Because we deduped via the map
-		// each node can only be created / updated once in each tree revision and
-		// they cannot conflict when we do the storage update.
-		targetNodes, err := s.buildNodesFromNodeMap(nodeMap, newVersion)
-		if err != nil {
-			// Probably an internal error with map building, unexpected.
-			glog.Warningf("%v: Failed to build target nodes in sequencer: %v", logID, err)
-			return err
+		if len(opts.LeafIndexes) > 0 && numLeaves > 0 {
+			if err := indexLeaves(ctx, tx, opts.LeafIndexes, sequencedLeaves, label); err != nil {
+				return err
+			}
 		}
+		if opts.LeafFilter != nil && numLeaves > 0 {
+			if err := filterLeaves(ctx, tx, opts.LeafFilter, sequencedLeaves, label); err != nil {
+				return err
+			}
+		}
+		if opts.MaxMergeDelay > 0 && numLeaves > 0 {
+			if mergeDelays, err = mergeDelayWindows(sequencedLeaves, opts.MaxMergeDelay); err != nil {
+				return err
+			}
+			if err := recordMergeDelays(ctx, tx, mergeDelays, label); err != nil {
+				return err
+			}
+		}
+		stageStart = s.timeSource.Now()
 
 		// Now insert or update the nodes affected by the above, at the new tree
 		// version.
 		if err := tx.SetMerkleNodes(ctx, targetNodes); err != nil {
-			glog.Warningf("%v: Sequencer failed to set Merkle nodes: %v", logID, err)
+			requestinfo.Warningf(ctx, "%v: Sequencer failed to set Merkle nodes: %v", logID, err)
 			return err
 		}
 		seqSetNodesLatency.Observe(util.SecondsSince(s.timeSource, stageStart), label)
 		stageStart = s.timeSource.Now()
 
+		// Turn any divergence between the nodes just written, those they link
+		// to, and the compact tree into a failed pass rather than a bad root.
+		if opts.VerifyWrites {
+			if err := logupdate.Verify(ctx, s.hasher, tx, targetNodes, merkleTree.Size(), newVersion, merkleTree.CurrentRoot()); err != nil {
+				requestinfo.Errorf(ctx, "%v: Merkle nodes written at revision %d failed verification: %v", logID, newVersion, err)
+				seqVerifyFailures.Inc(label)
+				return err
+			}
+		}
+
 		// Create the log root ready for signing
 		seqTreeSize.Set(float64(merkleTree.Size()), label)
+		timestamp, err := s.rootTimestamp(logID, label, currentRoot, opts.RootTimestampPrecision)
+		if err != nil {
+			return err
+		}
 		newLogRoot = &trillian.SignedLogRoot{
 			RootHash:       merkleTree.CurrentRoot(),
-			TimestampNanos: s.timeSource.Now().UnixNano(),
+			TimestampNanos: timestamp,
 			TreeSize:       merkleTree.Size(),
 			LogId:          currentRoot.LogId,
 			TreeRevision:   newVersion,
 		}
-		sig, err := s.signer.SignLogRoot(newLogRoot)
-		if err != nil {
-			glog.Warningf("%v: signer failed to sign root: %v", logID, err)
+		if err := setRootMetadata(ctx, &currentRoot, newLogRoot); err != nil {
+			requestinfo.Warningf(ctx, "%v: failed to get root metadata: %v", logID, err)
+			return err
+		}
+		if err := s.signRoot(label, newLogRoot); err != nil {
+			requestinfo.Warningf(ctx, "%v: signer failed to sign root: %v", logID, err)
 			return err
 		}
-		newLogRoot.Signature = sig
 
 		if err := tx.StoreSignedLogRoot(ctx, *newLogRoot); err != nil {
-			glog.Warningf("%v: failed to write updated tree root: %v", logID, err)
+			requestinfo.Warningf(ctx, "%v: failed to write updated tree root: %v", logID, err)
 			return err
 		}
 		seqStoreRootLatency.Observe(util.SecondsSince(s.timeSource, stageStart), label)
@@ -408,46 +478,136 @@ func (s Sequencer) IntegrateBatch(ctx context.Context, logID int64, limit int, g
 
 		return nil
 	})
+	// A log whose latest root is older than its max_root_duration should have
+	// had a new root signed by this pass, so failing now is worth alerting on.
+	if err != nil && maxRootDurationInterval > 0 && rootAge >= maxRootDurationInterval {
+		requestinfo.Errorf(ctx, "%v: no root signed for %v, more than max_root_duration %v: %v", logID, rootAge, maxRootDurationInterval, err)
+		seqRootOverdue.Set(1, label)
+	} else {
+		seqRootOverdue.Set(0, label)
+	}
 	if err != nil {
-		return 0, err
+		return BatchResult{}, err
+	}
+
+	if !opts.DeferReplenish {
+		s.ReplenishQuota(ctx, logID, numLeaves)
+	}
+
+	seqCounter.Add(float64(numLeaves), label)
+	reportMergeDelays(mergeDelays, label)
+	if newLogRoot != nil {
+		requestinfo.Infof(ctx, "%v: sequenced %v leaves, size %v, tree-revision %v", logID, numLeaves, newLogRoot.TreeSize, newLogRoot.TreeRevision)
 	}
+	return BatchResult{Leaves: numLeaves, OldestQueued: oldestQueued}, nil
+}
 
-	// Let quota.Manager know about newly-sequenced entries.
+// ReplenishQuota lets the quota.Manager know about numLeaves newly-sequenced
+// entries of the log.
+func (s Sequencer) ReplenishQuota(ctx context.Context, logID int64, numLeaves int) {
 	// All possibly influenced quotas are replenished: {Tree/Global, Read/Write}.
 	// Implementations are tasked with filtering quotas that shouldn't be replenished.
 	// TODO(codingllama): Consider adding a source-aware replenish method
 	// (eg, qm.Replenish(ctx, tokens, specs, quota.SequencerSource)), so there's no ambiguity as to
 	// where the tokens come from.
-	if numLeaves > 0 {
-		tokens := int(float64(numLeaves) * quotaIncreaseFactor())
-		specs := []quota.Spec{
-			{Group: quota.Tree, Kind: quota.Read, TreeID: logID},
-			{Group: quota.Tree, Kind: quota.Write, TreeID: logID},
-			{Group: quota.Global, Kind: quota.Read},
-			{Group: quota.Global, Kind: quota.Write},
-		}
-		glog.V(2).Infof("%v: Replenishing %v tokens (numLeaves = %v)", logID, tokens, numLeaves)
-		err := s.qm.PutTokens(ctx, tokens, specs)
+	if numLeaves <= 0 {
+		return
+	}
+	tokens := int(float64(numLeaves) * quotaIncreaseFactor())
+	specs := []quota.Spec{
+		{Group: quota.Tree, Kind: quota.Read, TreeID: logID},
+		{Group: quota.Tree, Kind: quota.Write, TreeID: logID},
+		{Group: quota.Global, Kind: quota.Read},
+		{Group: quota.Global, Kind: quota.Write},
+	}
+	glog.V(2).Infof("%v: Replenishing %v tokens (numLeaves = %v)", logID, tokens, numLeaves)
+	err := s.qm.PutTokens(ctx, tokens, specs)
+	if err != nil {
+		requestinfo.Warningf(ctx, "%v: Failed to replenish %v tokens: %v", logID, tokens, err)
+	}
+	quota.Metrics.IncReplenished(tokens, specs, err == nil)
+}
+
+// oldestQueueTime returns the earliest queue timestamp of leaves, or zero if
+// none of them have one.
+func oldestQueueTime(leaves []*trillian.LogLeaf) time.Time {
+	var oldest time.Time
+	for _, leaf := range leaves {
+		// Old leaves might not have a QueueTimestamp.
+		if leaf.QueueTimestamp == nil || leaf.QueueTimestamp.Seconds == 0 {
+			continue
+		}
+		ts, err := ptypes.Timestamp(leaf.QueueTimestamp)
 		if err != nil {
-			glog.Warningf("%v: Failed to replenish %v tokens: %v", logID, tokens, err)
+			continue
+		}
+		if oldest.IsZero() || ts.Before(oldest) {
+			oldest = ts
 		}
-		quota.Metrics.IncReplenished(tokens, specs, err == nil)
 	}
+	return oldest
+}
 
-	seqCounter.Add(float64(numLeaves), label)
-	if newLogRoot != nil {
-		glog.Infof("%v: sequenced %v leaves, size %v, tree-revision %v", logID, numLeaves, newLogRoot.TreeSize, newLogRoot.TreeRevision)
+// rootTimestamp returns the timestamp of a new root following currentRoot,
+// truncated to the given precision, which must be later than that of
+// currentRoot. A root signed in the same tick as currentRoot is timestamped
+// the tick after it. See ClampRootTimestamps for clocks that are further
+// behind.
+func (s Sequencer) rootTimestamp(logID int64, label string, currentRoot trillian.SignedLogRoot, precision trillian.RootTimestampPrecision) (int64, error) {
+	tick, ok := timestampTicks[precision]
+	if !ok {
+		return 0, fmt.Errorf("unknown root timestamp precision %v for log %v", precision, logID)
 	}
-	return numLeaves, nil
+	rawNow := s.timeSource.Now().UnixNano()
+	now := rawNow - rawNow%tick
+	if now > currentRoot.TimestampNanos {
+		return now, nil
+	}
+	// The latest root may predate a change of precision, so round its
+	// timestamp down before moving to the next tick.
+	next := currentRoot.TimestampNanos - currentRoot.TimestampNanos%tick + tick
+	if currentRoot.TimestampNanos-rawNow < tick {
+		return next, nil
+	}
+	seqRootTimestampSkew.Inc(label)
+	skew := time.Duration(currentRoot.TimestampNanos - rawNow)
+	if ClampRootTimestamps {
+		glog.Warningf("%v: clock is %v behind the latest root, timestamping new root after it", logID, skew)
+		return next, nil
+	}
+	glog.Errorf("%v: clock is %v behind the latest root, not signing a new root", logID, skew)
+	return 0, fmt.Errorf("clock is %v behind the latest root of log %v", skew, logID)
+}
+
+// signRoot sets the signature and additional signatures of root, and records
+// the number of signatures made, how long they took and any failure.
+func (s Sequencer) signRoot(label string, root *trillian.SignedLogRoot) error {
+	start := s.timeSource.Now()
+	defer func() { seqSignLatency.Observe(util.SecondsSince(s.timeSource, start), label) }()
+
+	sig, err := s.signer.SignLogRoot(root)
+	if err != nil {
+		seqSignFailures.Inc(label)
+		return err
+	}
+	additional, err := s.signer.AdditionalLogRootSignatures(root)
+	if err != nil {
+		seqSignFailures.Inc(label)
+		return err
+	}
+	root.Signature, root.AdditionalSignatures = sig, additional
+	seqSignatures.Add(float64(1+len(additional)), label)
+	return nil
 }
 
 // SignRoot wraps up all the operations for creating a new log signed root.
 func (s Sequencer) SignRoot(ctx context.Context, logID int64) error {
+	label := strconv.FormatInt(logID, 10)
 	return s.logStorage.ReadWriteTransaction(ctx, logID, func(ctx context.Context, tx storage.LogTreeTX) error {
 		// Get the latest known root from storage
 		currentRoot, err := tx.LatestSignedLogRoot(ctx)
 		if err != nil {
-			glog.Warningf("%v: signer failed to get latest root: %v", logID, err)
+			requestinfo.Warningf(ctx, "%v: signer failed to get latest root: %v", logID, err)
 			return err
 		}
 
@@ -457,23 +617,29 @@ func (s Sequencer) SignRoot(ctx context.Context, logID int64) error {
 		if err != nil {
 			return err
 		}
+		timestamp, err := s.rootTimestamp(logID, label, currentRoot, trillian.RootTimestampPrecision_NANOSECOND_PRECISION)
+		if err != nil {
+			return err
+		}
 		newLogRoot := &trillian.SignedLogRoot{
 			RootHash:       merkleTree.CurrentRoot(),
-			TimestampNanos: s.timeSource.Now().UnixNano(),
+			TimestampNanos: timestamp,
 			TreeSize:       merkleTree.Size(),
 			LogId:          currentRoot.LogId,
 			TreeRevision:   currentRoot.TreeRevision + 1,
 		}
-		sig, err := s.signer.SignLogRoot(newLogRoot)
-		if err != nil {
-			glog.Warningf("%v: signer failed to sign root: %v", logID, err)
+		if err := setRootMetadata(ctx, &currentRoot, newLogRoot); err != nil {
+			requestinfo.Warningf(ctx, "%v: signer failed to get root metadata: %v", logID, err)
+			return err
+		}
+		if err := s.signRoot(label, newLogRoot); err != nil {
+			requestinfo.Warningf(ctx, "%v: signer failed to sign root: %v", logID, err)
 			return err
 		}
-		newLogRoot.Signature = sig
 
 		// Store the new root and we're done
 		if err := tx.StoreSignedLogRoot(ctx, *newLogRoot); err != nil {
-			glog.Warningf("%v: signer failed to write updated root: %v", logID, err)
+			requestinfo.Warningf(ctx, "%v: signer failed to write updated root: %v", logID, err)
 			return err
 		}
 		glog.V(2).Infof("%v: new signed root, size %v, tree-revision %v", logID, newLogRoot.TreeSize, newLogRoot.TreeRevision)
@@ -0,0 +1,327 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/crypto"
+	"github.com/google/trillian/crypto/sigpb"
+	"github.com/google/trillian/merkle"
+	"github.com/google/trillian/merkle/rfc6962"
+	"github.com/google/trillian/quota"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/memory"
+	"github.com/google/trillian/storage/mysql"
+	"github.com/google/trillian/storage/testdb"
+	"github.com/google/trillian/util"
+
+	stestonly "github.com/google/trillian/storage/testonly"
+)
+
+// raceBackends returns constructors for the storage backends which the race
+// tests run against.
+func raceBackends() []struct {
+	desc string
+	new  func(ctx context.Context, t *testing.T) (storage.AdminStorage, storage.LogStorage)
+} {
+	return []struct {
+		desc string
+		new  func(ctx context.Context, t *testing.T) (storage.AdminStorage, storage.LogStorage)
+	}{
+		{
+			desc: "memory",
+			new: func(ctx context.Context, t *testing.T) (storage.AdminStorage, storage.LogStorage) {
+				ls := memory.NewLogStorage(nil)
+				return memory.NewAdminStorage(ls), ls
+			},
+		},
+		{
+			desc: "sql",
+			new: func(ctx context.Context, t *testing.T) (storage.AdminStorage, storage.LogStorage) {
+				db, err := testdb.NewTrillianDB(ctx)
+				if err != nil {
+					t.Fatalf("NewTrillianDB(): %v", err)
+				}
+				return mysql.NewAdminStorage(db), mysql.NewLogStorage(db, nil)
+			},
+		},
+	}
+}
+
+// raceEnv is a log in one of the raceBackends, and sequencers integrating it
+// through a Scheduler.
+type raceEnv struct {
+	ctx   context.Context
+	ls    storage.LogStorage
+	logID int64
+	clock *util.FakeTimeSource
+	sched *stestonly.Scheduler
+	// seq integrates the log through sched, unseq directly.
+	seq, unseq *Sequencer
+	next       int
+}
+
+func newRaceEnv(ctx context.Context, t *testing.T, as storage.AdminStorage, ls storage.LogStorage) *raceEnv {
+	t.Helper()
+	tree, err := storage.CreateTree(ctx, as, stestonly.LogTree)
+	if err != nil {
+		t.Fatalf("CreateTree(): %v", err)
+	}
+	if err := ls.ReadWriteTransaction(ctx, tree.TreeId, func(ctx context.Context, tx storage.LogTreeTX) error {
+		return tx.StoreSignedLogRoot(ctx, trillian.SignedLogRoot{LogId: tree.TreeId, RootHash: rfc6962.DefaultHasher.EmptyRoot(), Signature: &sigpb.DigitallySigned{}})
+	}); err != nil {
+		t.Fatalf("StoreSignedLogRoot(): %v", err)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey(): %v", err)
+	}
+	signer := crypto.NewSHA256Signer(key)
+	clock := util.NewFakeTimeSource(fakeTimeForTest)
+	sched := stestonly.NewScheduler()
+	return &raceEnv{
+		ctx:   ctx,
+		ls:    ls,
+		logID: tree.TreeId,
+		clock: clock,
+		sched: sched,
+		seq:   NewSequencer(rfc6962.DefaultHasher, clock, sched.LogStorage(ls), signer, nil, quota.Noop()),
+		unseq: NewSequencer(rfc6962.DefaultHasher, clock, ls, signer, nil, quota.Noop()),
+	}
+}
+
+// leaves returns n new leaves.
+func (e *raceEnv) leaves(t *testing.T, n int) []*trillian.LogLeaf {
+	t.Helper()
+	leaves := make([]*trillian.LogLeaf, 0, n)
+	for i := 0; i < n; i++ {
+		value := []byte(fmt.Sprintf("leaf %d", e.next))
+		e.next++
+		hash, err := rfc6962.DefaultHasher.HashLeaf(value)
+		if err != nil {
+			t.Fatalf("HashLeaf(): %v", err)
+		}
+		leaves = append(leaves, &trillian.LogLeaf{LeafValue: value, MerkleLeafHash: hash, LeafIdentityHash: hash})
+	}
+	return leaves
+}
+
+func (e *raceEnv) queue(ctx context.Context, leaves []*trillian.LogLeaf) error {
+	_, err := e.sched.LogStorage(e.ls).QueueLeaves(ctx, e.logID, leaves, e.clock.Now().Add(-time.Minute))
+	return err
+}
+
+// integrate starts an actor integrating a batch of up to limit leaves, and
+// returns a pointer to the number of leaves it integrated.
+func (e *raceEnv) integrate(name string, limit int) *int {
+	var n int
+	e.sched.Go(e.ctx, name, func(ctx context.Context) error {
+		var err error
+		n, err = e.seq.IntegrateBatch(ctx, e.logID, limit, 0, 0)
+		return err
+	})
+	return &n
+}
+
+// check integrates the rest of the queue, and checks that the log holds each
+// of the queued leaves exactly once, with the root hash of those leaves. It
+// also checks that the log has one root per revision.
+func (e *raceEnv) check(t *testing.T, roots int) {
+	t.Helper()
+	// Roots are unique by timestamp too.
+	e.clock.Set(e.clock.Now().Add(time.Second))
+	if n, err := e.unseq.IntegrateBatch(e.ctx, e.logID, e.next, 0, 0); err != nil {
+		t.Fatalf("IntegrateBatch(rest of queue): %v", err)
+	} else if n > 0 {
+		roots++
+	}
+
+	tx, err := e.ls.SnapshotForTree(e.ctx, e.logID)
+	if err != nil {
+		t.Fatalf("SnapshotForTree(): %v", err)
+	}
+	defer tx.Close()
+	root, err := tx.LatestSignedLogRoot(e.ctx)
+	if err != nil {
+		t.Fatalf("LatestSignedLogRoot(): %v", err)
+	}
+	if got, want := root.TreeSize, int64(e.next); got != want {
+		t.Errorf("tree size %d, want %d", got, want)
+	}
+	if got, want := root.TreeRevision, int64(roots); got != want {
+		t.Errorf("tree revision %d, want %d as %d roots were written", got, want, roots)
+	}
+	var leaves []*trillian.LogLeaf
+	if root.TreeSize > 0 {
+		if leaves, err = tx.GetLeavesByRange(e.ctx, 0, root.TreeSize); err != nil {
+			t.Fatalf("GetLeavesByRange(): %v", err)
+		}
+	}
+	seen := make(map[string]bool)
+	mt := merkle.NewInMemoryMerkleTree(rfc6962.DefaultHasher)
+	for _, leaf := range leaves {
+		if seen[string(leaf.LeafValue)] {
+			t.Errorf("leaf %q integrated twice", leaf.LeafValue)
+		}
+		seen[string(leaf.LeafValue)] = true
+		if _, _, err := mt.AddLeaf(leaf.LeafValue); err != nil {
+			t.Fatalf("AddLeaf(): %v", err)
+		}
+	}
+	if got, want := root.RootHash, mt.CurrentRoot().Hash(); !bytes.Equal(got, want) {
+		t.Errorf("root hash %x, want %x", got, want)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit(): %v", err)
+	}
+}
+
+// TestRaceTwoSigners runs a second signer integrating the log while the first
+// one is about to write the root of its batch.
+func TestRaceTwoSigners(t *testing.T) {
+	ctx := context.Background()
+	for _, backend := range raceBackends() {
+		t.Run(backend.desc, func(t *testing.T) {
+			as, ls := backend.new(ctx, t)
+			e := newRaceEnv(ctx, t, as, ls)
+			if err := e.queue(ctx, e.leaves(t, 10)); err != nil {
+				t.Fatalf("QueueLeaves(): %v", err)
+			}
+
+			n1 := e.integrate("signer1", 6)
+			n2 := e.integrate("signer2", 6)
+			if op, err := e.sched.RunUntil("signer1", stestonly.OpStoreSignedLogRoot); err != nil || op != stestonly.OpStoreSignedLogRoot {
+				t.Fatalf("RunUntil(signer1, %v): %v, %v", stestonly.OpStoreSignedLogRoot, op, err)
+			}
+			// The second signer may be blocked by the first one's transaction.
+			op, err := e.sched.RunUntil("signer2", stestonly.OpStoreSignedLogRoot)
+			t.Logf("signer2 stopped at %q, %v", op, err)
+			if err := e.sched.Finish("signer1", "signer2"); err != nil {
+				t.Fatalf("Finish(): %v", err)
+			}
+
+			roots := 0
+			for _, signer := range []struct {
+				name string
+				n    int
+			}{{"signer1", *n1}, {"signer2", *n2}} {
+				if err := e.sched.Err(signer.name); err != nil {
+					t.Logf("%v failed: %v", signer.name, err)
+				} else if signer.n > 0 {
+					roots++
+				}
+			}
+			e.check(t, roots)
+		})
+	}
+}
+
+// TestRaceQueueDuringDequeue queues leaves while a signer integrates the ones
+// it has just dequeued.
+func TestRaceQueueDuringDequeue(t *testing.T) {
+	ctx := context.Background()
+	for _, backend := range raceBackends() {
+		t.Run(backend.desc, func(t *testing.T) {
+			as, ls := backend.new(ctx, t)
+			e := newRaceEnv(ctx, t, as, ls)
+			if err := e.queue(ctx, e.leaves(t, 5)); err != nil {
+				t.Fatalf("QueueLeaves(): %v", err)
+			}
+
+			n := e.integrate("signer", 10)
+			if op, err := e.sched.RunUntil("signer", stestonly.OpUpdateSequencedLeaves); err != nil || op != stestonly.OpUpdateSequencedLeaves {
+				t.Fatalf("RunUntil(signer, %v): %v, %v", stestonly.OpUpdateSequencedLeaves, op, err)
+			}
+			more := e.leaves(t, 5)
+			e.sched.Go(ctx, "queuer", func(ctx context.Context) error {
+				return e.queue(ctx, more)
+			})
+			// The queuer may be blocked by the signer's transaction.
+			op, err := e.sched.RunUntil("queuer", stestonly.OpDone)
+			t.Logf("queuer stopped at %q, %v", op, err)
+			if err := e.sched.Finish("signer", "queuer"); err != nil {
+				t.Fatalf("Finish(): %v", err)
+			}
+
+			// Either of them may fail if their transactions conflict, but the
+			// signer mustn't integrate leaves it didn't dequeue. A client whose
+			// leaves weren't queued would retry.
+			if err := e.sched.Err("queuer"); err != nil {
+				t.Logf("queuer failed: %v", err)
+				if _, err := e.ls.QueueLeaves(ctx, e.logID, more, e.clock.Now().Add(-time.Minute)); err != nil {
+					t.Fatalf("QueueLeaves(retry): %v", err)
+				}
+			}
+			roots := 0
+			if err := e.sched.Err("signer"); err != nil {
+				t.Logf("signer failed: %v", err)
+			} else {
+				if got, want := *n, 5; got != want {
+					t.Errorf("signer integrated %d leaves, want %d", got, want)
+				}
+				roots++
+			}
+			e.check(t, roots)
+		})
+	}
+}
+
+// TestRaceSignRoot runs two signers writing a new root for the same revision.
+func TestRaceSignRoot(t *testing.T) {
+	ctx := context.Background()
+	for _, backend := range raceBackends() {
+		t.Run(backend.desc, func(t *testing.T) {
+			as, ls := backend.new(ctx, t)
+			e := newRaceEnv(ctx, t, as, ls)
+
+			for _, name := range []string{"signer1", "signer2"} {
+				e.sched.Go(ctx, name, func(ctx context.Context) error {
+					return e.seq.SignRoot(ctx, e.logID)
+				})
+			}
+			if op, err := e.sched.RunUntil("signer1", stestonly.OpStoreSignedLogRoot); err != nil || op != stestonly.OpStoreSignedLogRoot {
+				t.Fatalf("RunUntil(signer1, %v): %v, %v", stestonly.OpStoreSignedLogRoot, op, err)
+			}
+			// The second signer may be blocked by the first one's transaction.
+			op, err := e.sched.RunUntil("signer2", stestonly.OpStoreSignedLogRoot)
+			t.Logf("signer2 stopped at %q, %v", op, err)
+			if err := e.sched.Finish("signer1", "signer2"); err != nil {
+				t.Fatalf("Finish(): %v", err)
+			}
+
+			roots := 0
+			for _, name := range []string{"signer1", "signer2"} {
+				if err := e.sched.Err(name); err != nil {
+					t.Logf("%v failed: %v", name, err)
+				} else {
+					roots++
+				}
+			}
+			if roots == 0 {
+				t.Error("neither signer wrote a root")
+			}
+			e.check(t, roots)
+		})
+	}
+}
@@ -19,18 +19,22 @@ import (
 	gocrypto "crypto"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/golang/protobuf/proto"
 	"github.com/google/trillian"
 	"github.com/google/trillian/crypto"
 	"github.com/google/trillian/crypto/keys/pem"
 	"github.com/google/trillian/crypto/sigpb"
+	"github.com/google/trillian/merkle"
 	"github.com/google/trillian/merkle/rfc6962"
 	"github.com/google/trillian/quota"
 	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/memory"
 	stestonly "github.com/google/trillian/storage/testonly"
 	"github.com/google/trillian/testonly"
 	"github.com/google/trillian/util"
@@ -158,6 +162,48 @@ type testContext struct {
 }
 
 // This gets modified so tests need their own copies
+func TestIntegrateBatchWithOptions_DeferReplenish(t *testing.T) {
+	cryptoSigner, err := newSignerWithFixedSig(expectedSignedRoot.Signature)
+	if err != nil {
+		t.Fatalf("Failed to create test signer (%v)", err)
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ts := util.NewFakeTimeSource(fakeTimeForTest)
+	oldest := fakeTimeForTest.Add(-time.Hour)
+	leaves := []*trillian.LogLeaf{
+		{LeafValue: []byte("a"), QueueTimestamp: testonly.MustToTimestampProto(fakeTimeForTest.Add(-time.Minute))},
+		{LeafValue: []byte("b"), QueueTimestamp: testonly.MustToTimestampProto(oldest)},
+		{LeafValue: []byte("c")},
+	}
+
+	any := gomock.Any()
+	logTX := storage.NewMockLogTreeTX(ctrl)
+	logTX.EXPECT().DequeueLeaves(any, 10, any).Return(leaves, nil)
+	logTX.EXPECT().LatestSignedLogRoot(any).Return(testRoot16, nil)
+	logTX.EXPECT().WriteRevision().AnyTimes().Return(testRoot16.TreeRevision + 1)
+	logTX.EXPECT().UpdateSequencedLeaves(any, any).Return(nil)
+	logTX.EXPECT().SetMerkleNodes(any, any).Return(nil)
+	logTX.EXPECT().StoreSignedLogRoot(any, any).Return(nil)
+	logTX.EXPECT().Commit().Return(nil)
+	logTX.EXPECT().Close().Return(nil)
+	logStorage := &stestonly.FakeLogStorage{TX: logTX}
+
+	// No PutTokens calls are expected.
+	qm := quota.NewMockManager(ctrl)
+
+	sequencer := NewSequencer(rfc6962.DefaultHasher, ts, logStorage, crypto.NewSHA256Signer(cryptoSigner), nil /* mf */, qm)
+	res, err := sequencer.IntegrateBatchWithOptions(context.Background(), 1234, BatchOptions{Limit: 10, DeferReplenish: true})
+	if err != nil {
+		t.Fatalf("IntegrateBatchWithOptions() returned err = %v", err)
+	}
+	if res.Leaves != 3 || !res.OldestQueued.Equal(oldest) {
+		t.Errorf("IntegrateBatchWithOptions() = %+v, want 3 leaves, oldest queued at %v", res, oldest)
+	}
+}
+
 func getLeaf42() *trillian.LogLeaf {
 	testLeaf16Hash, _ := rfc6962.DefaultHasher.HashLeaf(testLeaf16Data)
 	return &trillian.LogLeaf{
@@ -663,9 +709,15 @@ func TestSignRoot(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to create test signer (%v)", err)
 	}
+	// The clock reads an hour earlier than the latest root.
+	rootAhead := testRoot16
+	rootAhead.TimestampNanos = fakeTimeForTest.Add(time.Hour).UnixNano()
+	clampedRoot16 := expectedSignedRoot16
+	clampedRoot16.TimestampNanos = rootAhead.TimestampNanos + 1
 	var tests = []struct {
 		desc   string
 		params testParameters
+		clamp  bool
 		errStr string
 	}{
 		{
@@ -757,12 +809,39 @@ func TestSignRoot(t *testing.T) {
 				skipDequeue:      true,
 			},
 		},
+		{
+			desc: "clock-behind-latest-root",
+			params: testParameters{
+				logID:               154035,
+				writeRevision:       testRoot16.TreeRevision + 1,
+				latestSignedRoot:    &rootAhead,
+				signer:              signer16,
+				skipDequeue:         true,
+				skipStoreSignedRoot: true,
+			},
+			errStr: "behind",
+		},
+		{
+			desc: "clock-behind-latest-root-clamped",
+			params: testParameters{
+				logID:            154035,
+				writeRevision:    testRoot16.TreeRevision + 1,
+				latestSignedRoot: &rootAhead,
+				storeSignedRoot:  &clampedRoot16,
+				signer:           signer16,
+				shouldCommit:     true,
+				skipDequeue:      true,
+			},
+			clamp: true,
+		},
 	}
 
 	for _, test := range tests {
 		func() {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
+			defer func(clamp bool) { ClampRootTimestamps = clamp }(ClampRootTimestamps)
+			ClampRootTimestamps = test.clamp
 			c, ctx := createTestContext(ctrl, test.params)
 			err := c.sequencer.SignRoot(ctx, test.params.logID)
 			if test.errStr != "" {
@@ -779,3 +858,257 @@ func TestSignRoot(t *testing.T) {
 		}()
 	}
 }
+
+func TestRootTimestamp(t *testing.T) {
+	defer func(clamp bool) { ClampRootTimestamps = clamp }(ClampRootTimestamps)
+
+	// now is 1.5s after a whole second, on a whole millisecond.
+	second := time.Unix(fakeTimeForTest.Unix(), 0)
+	now := second.Add(1500 * time.Millisecond)
+	nowNanos := now.Add(123 * time.Nanosecond)
+
+	tests := []struct {
+		desc      string
+		precision trillian.RootTimestampPrecision
+		clamp     bool
+		latest    time.Time
+		want      time.Time
+		wantErr   bool
+	}{
+		{desc: "nanos", precision: trillian.RootTimestampPrecision_NANOSECOND_PRECISION, latest: second, want: nowNanos},
+		{desc: "millis", precision: trillian.RootTimestampPrecision_MILLISECOND_PRECISION, latest: second, want: now},
+		{desc: "seconds", precision: trillian.RootTimestampPrecision_SECOND_PRECISION, latest: second.Add(-time.Second), want: second.Add(time.Second)},
+		{desc: "nanosSameTick", precision: trillian.RootTimestampPrecision_NANOSECOND_PRECISION, latest: nowNanos, want: nowNanos.Add(time.Nanosecond)},
+		{desc: "millisSameTick", precision: trillian.RootTimestampPrecision_MILLISECOND_PRECISION, latest: now, want: now.Add(time.Millisecond)},
+		{desc: "secondsSameTick", precision: trillian.RootTimestampPrecision_SECOND_PRECISION, latest: second.Add(time.Second), want: second.Add(2 * time.Second)},
+		{desc: "secondsPreviousRootAhead", precision: trillian.RootTimestampPrecision_SECOND_PRECISION, latest: second.Add(2 * time.Second), want: second.Add(3 * time.Second)},
+		{desc: "secondsUnalignedLatest", precision: trillian.RootTimestampPrecision_SECOND_PRECISION, latest: nowNanos, want: second.Add(2 * time.Second)},
+		{desc: "millisBehind", precision: trillian.RootTimestampPrecision_MILLISECOND_PRECISION, latest: now.Add(time.Second), wantErr: true},
+		{desc: "millisBehindClamped", precision: trillian.RootTimestampPrecision_MILLISECOND_PRECISION, clamp: true, latest: now.Add(time.Second), want: now.Add(time.Second + time.Millisecond)},
+		{desc: "unknownPrecision", precision: trillian.RootTimestampPrecision(-1), latest: second, wantErr: true},
+	}
+	for _, test := range tests {
+		ClampRootTimestamps = test.clamp
+		s := NewSequencer(rfc6962.DefaultHasher, util.NewFakeTimeSource(nowNanos), nil, nil, nil /* mf */, nil)
+		got, err := s.rootTimestamp(1, "1", trillian.SignedLogRoot{TimestampNanos: test.latest.UnixNano()}, test.precision)
+		if gotErr := err != nil; gotErr != test.wantErr {
+			t.Errorf("%v: rootTimestamp()=_, %v; want err? %v", test.desc, err, test.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if want := test.want.UnixNano(); got != want {
+			t.Errorf("%v: rootTimestamp()=%v; want %v", test.desc, time.Unix(0, got), time.Unix(0, want))
+		}
+	}
+}
+
+func TestSigningMetrics(t *testing.T) {
+	signer16, err := newSignerWithFixedSig(expectedSignedRoot16.Signature)
+	if err != nil {
+		t.Fatalf("Failed to create test signer (%v)", err)
+	}
+	signerErr, err := newSignerWithErr(errors.New("signerfailed"))
+	if err != nil {
+		t.Fatalf("Failed to create test signer (%v)", err)
+	}
+	leaves16 := []*trillian.LogLeaf{testLeaf16}
+	noLeaves := []*trillian.LogLeaf{}
+	noNodes := []storage.Node{}
+	newRoot16 := trillian.SignedLogRoot{
+		TimestampNanos: fakeTimeForTest.UnixNano(),
+		TreeSize:       16,
+		TreeRevision:   6,
+		RootHash:       []byte{},
+		Signature:      expectedSignedRoot16.Signature,
+	}
+
+	// The latest root, testRoot16, is 10ms old.
+	tests := []struct {
+		desc            string
+		params          testParameters
+		maxRootDuration time.Duration
+		wantSignatures  float64
+		wantFailures    float64
+		wantOverdue     float64
+	}{
+		{
+			desc: "signed",
+			params: testParameters{
+				logID:            1540351,
+				dequeueLimit:     1,
+				shouldCommit:     true,
+				latestSignedRoot: &testRoot16,
+				dequeuedLeaves:   noLeaves,
+				writeRevision:    testRoot16.TreeRevision + 1,
+				updatedLeaves:    &noLeaves,
+				merkleNodesSet:   &noNodes,
+				signer:           signer16,
+				storeSignedRoot:  &newRoot16,
+			},
+			maxRootDuration: 9 * time.Millisecond,
+			wantSignatures:  1,
+		},
+		{
+			desc: "signer-fails-overdue",
+			params: testParameters{
+				logID:               1540352,
+				dequeueLimit:        1,
+				latestSignedRoot:    &testRoot16,
+				dequeuedLeaves:      noLeaves,
+				writeRevision:       testRoot16.TreeRevision + 1,
+				updatedLeaves:       &noLeaves,
+				merkleNodesSet:      &noNodes,
+				signer:              signerErr,
+				skipStoreSignedRoot: true,
+			},
+			maxRootDuration: 9 * time.Millisecond,
+			wantFailures:    1,
+			wantOverdue:     1,
+		},
+		{
+			desc: "signer-fails-within-max",
+			params: testParameters{
+				logID:               1540353,
+				dequeueLimit:        1,
+				latestSignedRoot:    &testRoot16,
+				dequeuedLeaves:      []*trillian.LogLeaf{getLeaf42()},
+				writeRevision:       testRoot16.TreeRevision + 1,
+				updatedLeaves:       &leaves16,
+				merkleNodesSet:      &updatedNodes,
+				signer:              signerErr,
+				skipStoreSignedRoot: true,
+			},
+			maxRootDuration: 15 * time.Millisecond,
+			wantFailures:    1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			qm := quota.NewMockManager(ctrl)
+			qm.EXPECT().PutTokens(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(nil)
+			test.params.qm = qm
+			c, ctx := createTestContext(ctrl, test.params)
+
+			_, err := c.sequencer.IntegrateBatch(ctx, test.params.logID, 1, 0, test.maxRootDuration)
+			if gotErr, wantErr := err != nil, test.wantFailures > 0; gotErr != wantErr {
+				t.Errorf("IntegrateBatch()=_,%v; want err? %v", err, wantErr)
+			}
+			label := strconv.FormatInt(test.params.logID, 10)
+			if got := seqSignatures.Value(label); got != test.wantSignatures {
+				t.Errorf("sequencer_signatures=%v; want %v", got, test.wantSignatures)
+			}
+			if got := seqSignFailures.Value(label); got != test.wantFailures {
+				t.Errorf("sequencer_sign_failures=%v; want %v", got, test.wantFailures)
+			}
+			if got := seqRootOverdue.Value(label); got != test.wantOverdue {
+				t.Errorf("sequencer_root_overdue=%v; want %v", got, test.wantOverdue)
+			}
+			if count, _ := seqSignLatency.Info(label); count != 1 {
+				t.Errorf("sequencer_latency_sign count=%v; want 1", count)
+			}
+		})
+	}
+}
+
+// dropNodeStorage is a LogStorage which loses the last Merkle node of each
+// write.
+type dropNodeStorage struct {
+	storage.LogStorage
+}
+
+func (s dropNodeStorage) ReadWriteTransaction(ctx context.Context, treeID int64, f storage.LogTXFunc) error {
+	return s.LogStorage.ReadWriteTransaction(ctx, treeID, func(ctx context.Context, tx storage.LogTreeTX) error {
+		return f(ctx, dropNodeTX{tx})
+	})
+}
+
+type dropNodeTX struct {
+	storage.LogTreeTX
+}
+
+func (tx dropNodeTX) SetMerkleNodes(ctx context.Context, nodes []storage.Node) error {
+	if len(nodes) > 0 {
+		nodes = nodes[:len(nodes)-1]
+	}
+	return tx.LogTreeTX.SetMerkleNodes(ctx, nodes)
+}
+
+func TestIntegrateBatchWithOptions_VerifyWrites(t *testing.T) {
+	ctx := context.Background()
+	for _, backend := range raceBackends() {
+		t.Run(backend.desc, func(t *testing.T) {
+			as, ls := backend.new(ctx, t)
+			e := newRaceEnv(ctx, t, as, ls)
+			roots := 0
+			for _, n := range []int{1, 5, 2, 8, 16} {
+				if err := e.queue(ctx, e.leaves(t, n)); err != nil {
+					t.Fatalf("QueueLeaves(): %v", err)
+				}
+				e.clock.Set(e.clock.Now().Add(time.Second))
+				res, err := e.unseq.IntegrateBatchWithOptions(ctx, e.logID, BatchOptions{Limit: n, VerifyWrites: true})
+				if err != nil {
+					t.Fatalf("IntegrateBatchWithOptions(%d leaves): %v", n, err)
+				}
+				if res.Leaves != n {
+					t.Fatalf("IntegrateBatchWithOptions(): %d leaves, want %d", res.Leaves, n)
+				}
+				roots++
+			}
+			e.check(t, roots)
+		})
+	}
+
+	ls := memory.NewLogStorage(nil)
+	e := newRaceEnv(ctx, t, memory.NewAdminStorage(ls), ls)
+	if err := e.queue(ctx, e.leaves(t, 3)); err != nil {
+		t.Fatalf("QueueLeaves(): %v", err)
+	}
+	signer := NewSequencer(rfc6962.DefaultHasher, e.clock, dropNodeStorage{ls}, e.unseq.signer, nil, quota.Noop())
+	if _, err := signer.IntegrateBatchWithOptions(ctx, e.logID, BatchOptions{Limit: 3}); err != nil {
+		t.Fatalf("IntegrateBatchWithOptions() without VerifyWrites: %v, want nil", err)
+	}
+	if err := e.queue(ctx, e.leaves(t, 3)); err != nil {
+		t.Fatalf("QueueLeaves(): %v", err)
+	}
+	e.clock.Set(e.clock.Now().Add(time.Second))
+	if _, err := signer.IntegrateBatchWithOptions(ctx, e.logID, BatchOptions{Limit: 3, VerifyWrites: true}); err == nil {
+		t.Error("IntegrateBatchWithOptions() with a lost node: nil, want err")
+	}
+	label := strconv.FormatInt(e.logID, 10)
+	if got := seqVerifyFailures.Value(label); got != 1 {
+		t.Errorf("sequencer_verify_failures=%v; want 1", got)
+	}
+}
+
+func TestUpdateCompactTreeTimestamps(t *testing.T) {
+	sequencer := NewSequencer(rfc6962.DefaultHasher, util.NewFakeTimeSource(fakeTimeForTest), nil, nil, nil, nil)
+	leaves := []*trillian.LogLeaf{
+		{LeafIndex: 0, QueueTimestamp: testonly.MustToTimestampProto(fakeTimeForTest.Add(-2 * time.Second))},
+		{LeafIndex: 1, QueueTimestamp: testonly.MustToTimestampProto(fakeTimeForTest.Add(-5 * time.Second))},
+		// Leaves queued by older versions might not have a QueueTimestamp.
+		{LeafIndex: 2},
+	}
+	for _, leaf := range leaves {
+		leaf.MerkleLeafHash, _ = rfc6962.DefaultHasher.HashLeaf([]byte{byte(leaf.LeafIndex)})
+	}
+
+	label := "1540361"
+	if _, err := sequencer.updateCompactTree(merkle.NewCompactMerkleTree(rfc6962.DefaultHasher), leaves, 1, label); err != nil {
+		t.Fatalf("updateCompactTree()=_,%v; want nil", err)
+	}
+	want := testonly.MustToTimestampProto(fakeTimeForTest)
+	for _, leaf := range leaves {
+		if got := leaf.IntegrateTimestamp; !proto.Equal(got, want) {
+			t.Errorf("leaf %d: IntegrateTimestamp=%v; want %v", leaf.LeafIndex, got, want)
+		}
+	}
+	if count, sum := seqMergeDelay.Info(label); count != 2 || sum != 7 {
+		t.Errorf("sequencer_merge_delay count=%v, sum=%v; want 2, 7", count, sum)
+	}
+}
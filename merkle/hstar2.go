@@ -45,6 +45,9 @@ type HStar2LeafHash struct {
 type HStar2 struct {
 	treeID int64
 	hasher hashers.MapHasher
+	// workers holds a token for each goroutine which may hash subtrees in
+	// parallel with the caller. It is nil if HStar2 hashes serially.
+	workers chan struct{}
 }
 
 // NewHStar2 creates a new HStar2 tree calculator based on the passed in MapHasher.
@@ -59,7 +62,7 @@ func NewHStar2(treeID int64, hasher hashers.MapHasher) HStar2 {
 // which contains the given set of non-null leaves.
 func (s *HStar2) HStar2Root(depth int, values []HStar2LeafHash) ([]byte, error) {
 	sort.Sort(ByIndex{values})
-	return s.hStar2b(0, depth, values, smtZero, nil, nil)
+	return s.hStar2(0, depth, values, smtZero, nil, nil)
 }
 
 // SparseGetNodeFunc should return any pre-existing node hash for the node address.
@@ -90,7 +93,17 @@ func (s *HStar2) HStar2Nodes(prefix []byte, subtreeDepth int, values []HStar2Lea
 	}
 	sort.Sort(ByIndex{values})
 	offset := storage.NewNodeIDFromPrefixSuffix(prefix, storage.Suffix{}, s.hasher.BitLen()).BigInt()
-	return s.hStar2b(depth, totalDepth, values, offset, get, set)
+	return s.hStar2(depth, totalDepth, values, offset, get, set)
+}
+
+// hStar2 computes a sparse Merkle tree root value, in parallel if s has
+// workers.
+func (s *HStar2) hStar2(depth, maxDepth int, values []HStar2LeafHash, offset *big.Int,
+	get SparseGetNodeFunc, set SparseSetNodeFunc) ([]byte, error) {
+	if s.workers != nil {
+		return s.hStar2Parallel(depth, maxDepth, values, offset, get, set)
+	}
+	return s.hStar2b(depth, maxDepth, values, offset, get, set)
 }
 
 // hStar2b computes a sparse Merkle tree root value recursively.
@@ -110,10 +123,7 @@ func (s *HStar2) hStar2b(depth, maxDepth int, values []HStar2LeafHash, offset *b
 		return s.get(offset, depth, get)
 	}
 
-	bitsLeft := s.hasher.BitLen() - depth
-	split := new(big.Int).Lsh(smtOne, uint(bitsLeft-1))
-	split.Add(split, offset)
-	i := sort.Search(len(values), func(i int) bool { return values[i].Index.Cmp(split) >= 0 })
+	i, split := s.split(depth, values, offset)
 	lhs, err := s.hStar2b(depth+1, maxDepth, values[:i], offset, get, set)
 	if err != nil {
 		return nil, err
@@ -127,6 +137,16 @@ func (s *HStar2) hStar2b(depth, maxDepth int, values []HStar2LeafHash, offset *b
 	return h, nil
 }
 
+// split returns the number of values in the left child of the node at depth
+// and offset, and the offset of its right child.
+func (s *HStar2) split(depth int, values []HStar2LeafHash, offset *big.Int) (int, *big.Int) {
+	bitsLeft := s.hasher.BitLen() - depth
+	split := new(big.Int).Lsh(smtOne, uint(bitsLeft-1))
+	split.Add(split, offset)
+	i := sort.Search(len(values), func(i int) bool { return values[i].Index.Cmp(split) >= 0 })
+	return i, split
+}
+
 // get attempts to use getter. If getter fails, returns the HashEmpty value.
 func (s *HStar2) get(index *big.Int, depth int, getter SparseGetNodeFunc) ([]byte, error) {
 	// if we've got a function for getting existing node values, try it:
@@ -0,0 +1,192 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package merkle
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/google/trillian/merkle/hashers"
+)
+
+const (
+	// parallelMinLeaves is the number of leaves below which HStar2 hashes a
+	// tree serially, as the subtrees are too small to be worth scheduling.
+	parallelMinLeaves = 64
+	// parallelMaxSplitBits bounds the number of levels below its root at which
+	// a tree is split into subtrees hashed in parallel.
+	parallelMaxSplitBits = 8
+)
+
+// NewParallelHStar2 creates a new HStar2 tree calculator which hashes the
+// independent subtrees of large trees in up to workers goroutines besides the
+// calling one. Copies of the returned HStar2 share its workers.
+func NewParallelHStar2(treeID int64, hasher hashers.MapHasher, workers int) HStar2 {
+	s := NewHStar2(treeID, hasher)
+	if workers > 0 {
+		s.workers = make(chan struct{}, workers)
+	}
+	return s
+}
+
+// nodeHash holds the arguments of a call to a SparseSetNodeFunc.
+type nodeHash struct {
+	depth int
+	index *big.Int
+	hash  []byte
+}
+
+// subtreeJob is the hashing of one of the subtrees of hStar2Parallel.
+type subtreeJob struct {
+	values []HStar2LeafHash
+	offset *big.Int
+	done   chan struct{}
+
+	// The fields below are set once done is closed.
+	root []byte
+	sets []nodeHash
+	err  error
+}
+
+// splitBits returns the number of levels below its root at which
+// hStar2Parallel splits a tree, so that there are a few subtrees per worker.
+func (s *HStar2) splitBits() int {
+	bits := 0
+	for 1<<uint(bits) < 4*cap(s.workers) && bits < parallelMaxSplitBits {
+		bits++
+	}
+	return bits
+}
+
+// hStar2Parallel computes the same root as hStar2b, hashing the subtrees
+// rooted splitBits() levels below depth concurrently. The subtrees are merged
+// in index order: get and set are never called concurrently, and set is called
+// in the same order as by hStar2b. Nodes which get is called for are never
+// set, so get can't observe that sets are delayed until the merge.
+func (s *HStar2) hStar2Parallel(depth, maxDepth int, values []HStar2LeafHash, offset *big.Int,
+	get SparseGetNodeFunc, set SparseSetNodeFunc) ([]byte, error) {
+	splitDepth := depth + s.splitBits()
+	if len(values) < parallelMinLeaves || splitDepth > maxDepth {
+		return s.hStar2b(depth, maxDepth, values, offset, get, set)
+	}
+
+	var mu sync.Mutex
+	lockedGet := func(depth int, index *big.Int) ([]byte, error) {
+		if get == nil {
+			return nil, nil
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return get(depth, index)
+	}
+	lockedSet := func(depth int, index *big.Int, hash []byte) error {
+		if set == nil {
+			return nil
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return set(depth, index, hash)
+	}
+
+	var jobs []*subtreeJob
+	s.splitSubtrees(depth, splitDepth, values, offset, func(values []HStar2LeafHash, offset *big.Int) {
+		jobs = append(jobs, &subtreeJob{values: values, offset: offset, done: make(chan struct{})})
+	})
+	// Don't return while jobs may still call get.
+	defer func() {
+		for _, j := range jobs {
+			<-j.done
+		}
+	}()
+	for _, j := range jobs {
+		j := j
+		var record SparseSetNodeFunc
+		if set != nil {
+			record = func(depth int, index *big.Int, hash []byte) error {
+				j.sets = append(j.sets, nodeHash{depth: depth, index: index, hash: hash})
+				return nil
+			}
+		}
+		run := func() {
+			defer close(j.done)
+			j.root, j.err = s.hStar2b(splitDepth, maxDepth, j.values, j.offset, lockedGet, record)
+		}
+		select {
+		case s.workers <- struct{}{}:
+			go func() {
+				defer func() { <-s.workers }()
+				run()
+			}()
+		default:
+			// All the workers are busy, so hash the subtree in this goroutine.
+			run()
+		}
+	}
+
+	next := 0
+	return s.mergeSubtrees(depth, splitDepth, values, offset, lockedGet, lockedSet, func() ([]byte, error) {
+		j := jobs[next]
+		next++
+		<-j.done
+		if j.err != nil {
+			return nil, j.err
+		}
+		for _, n := range j.sets {
+			s.set(n.index, n.depth, n.hash, lockedSet)
+		}
+		return j.root, nil
+	})
+}
+
+// splitSubtrees calls f with the values and offset of each subtree rooted at
+// splitDepth which contains values, in index order.
+func (s *HStar2) splitSubtrees(depth, splitDepth int, values []HStar2LeafHash, offset *big.Int,
+	f func(values []HStar2LeafHash, offset *big.Int)) {
+	if len(values) == 0 {
+		return
+	}
+	if depth == splitDepth {
+		f(values, offset)
+		return
+	}
+	i, split := s.split(depth, values, offset)
+	s.splitSubtrees(depth+1, splitDepth, values[:i], offset, f)
+	s.splitSubtrees(depth+1, splitDepth, values[i:], split, f)
+}
+
+// mergeSubtrees computes the levels of a tree above splitDepth like hStar2b
+// does, taking the roots of the subtrees which contain values from next, in
+// the order splitSubtrees returned them.
+func (s *HStar2) mergeSubtrees(depth, splitDepth int, values []HStar2LeafHash, offset *big.Int,
+	get SparseGetNodeFunc, set SparseSetNodeFunc, next func() ([]byte, error)) ([]byte, error) {
+	if len(values) == 0 {
+		return s.get(offset, depth, get)
+	}
+	if depth == splitDepth {
+		return next()
+	}
+	i, split := s.split(depth, values, offset)
+	lhs, err := s.mergeSubtrees(depth+1, splitDepth, values[:i], offset, get, set, next)
+	if err != nil {
+		return nil, err
+	}
+	rhs, err := s.mergeSubtrees(depth+1, splitDepth, values[i:], split, get, set, next)
+	if err != nil {
+		return nil, err
+	}
+	h := s.hasher.HashChildren(lhs, rhs)
+	s.set(offset, depth, h, set)
+	return h, nil
+}
@@ -201,3 +201,89 @@ func TestHStar2NegativeTreeLevelOffset(t *testing.T) {
 		t.Fatalf("Hstar2Nodes(): %v, want %v", got, want)
 	}
 }
+
+// TestHStar2Parallel ensures that parallel HStar2s compute the same roots as
+// serial ones, and get and set the same nodes in the same order.
+func TestHStar2Parallel(t *testing.T) {
+	hasher := maphasher.Default
+	var iv [][]byte
+	for i := 0; i < 1000; i++ {
+		iv = append(iv, testonly.HashKey(fmt.Sprintf("key-%d", i)), []byte(fmt.Sprintf("value-%d", i)))
+	}
+	for _, test := range []struct {
+		desc    string
+		batches [][][]byte
+	}{
+		{desc: "few", batches: [][][]byte{iv[:20]}},
+		{desc: "one", batches: [][][]byte{iv}},
+		{desc: "incremental", batches: [][][]byte{iv[:1000], iv[800:1400], iv[1400:]}},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			run := func(s HStar2) ([][]byte, []string) {
+				cache := make(map[string][]byte)
+				var roots [][]byte
+				var calls []string
+				for _, batch := range test.batches {
+					values, err := createHStar2Leaves(treeID, hasher, batch...)
+					if err != nil {
+						t.Fatalf("createHStar2Leaves(): %v", err)
+					}
+					root, err := s.HStar2Nodes(nil, hasher.BitLen(), values,
+						func(depth int, index *big.Int) ([]byte, error) {
+							return cache[fmt.Sprintf("%x/%d", index, depth)], nil
+						},
+						func(depth int, index *big.Int, hash []byte) error {
+							key := fmt.Sprintf("%x/%d", index, depth)
+							cache[key] = hash
+							calls = append(calls, fmt.Sprintf("%s: %x", key, hash))
+							return nil
+						})
+					if err != nil {
+						t.Fatalf("HStar2Nodes(): %v", err)
+					}
+					roots = append(roots, root)
+				}
+				return roots, calls
+			}
+			wantRoots, wantCalls := run(NewHStar2(treeID, hasher))
+			for _, workers := range []int{1, 4, 16} {
+				gotRoots, gotCalls := run(NewParallelHStar2(treeID, hasher, workers))
+				for i := range gotRoots {
+					if got, want := gotRoots[i], wantRoots[i]; !bytes.Equal(got, want) {
+						t.Errorf("%d workers: root %d: %x, want %x", workers, i, got, want)
+					}
+				}
+				if got, want := len(gotCalls), len(wantCalls); got != want {
+					t.Fatalf("%d workers: %d set calls, want %d", workers, got, want)
+				}
+				for i := range gotCalls {
+					if got, want := gotCalls[i], wantCalls[i]; got != want {
+						t.Fatalf("%d workers: set call %d: %v, want %v", workers, i, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkHStar2Root(b *testing.B) {
+	hasher := maphasher.Default
+	var iv [][]byte
+	for i := 0; i < 10000; i++ {
+		iv = append(iv, testonly.HashKey(fmt.Sprintf("key-%d", i)), []byte(fmt.Sprintf("value-%d", i)))
+	}
+	values, err := createHStar2Leaves(treeID, hasher, iv...)
+	if err != nil {
+		b.Fatalf("createHStar2Leaves(): %v", err)
+	}
+	for _, workers := range []int{0, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers-%d", workers), func(b *testing.B) {
+			s := NewParallelHStar2(treeID, hasher, workers)
+			for i := 0; i < b.N; i++ {
+				if _, err := s.HStar2Root(hasher.BitLen(), values); err != nil {
+					b.Fatalf("HStar2Root(): %v", err)
+				}
+			}
+		})
+	}
+}
@@ -18,6 +18,7 @@ package maphasher
 import (
 	"crypto"
 	"fmt"
+	"sync"
 
 	"github.com/golang/glog"
 	"github.com/google/trillian"
@@ -37,6 +38,13 @@ const (
 // Default is a SHA256 based MapHasher for maps.
 var Default = New(crypto.SHA256)
 
+var (
+	nullHashesMu sync.Mutex
+	// nullHashes holds the empty branch hashes of each hash function. They are
+	// computed once per process and shared by all the MapHashers using it.
+	nullHashes = make(map[crypto.Hash][][]byte)
+)
+
 // MapHasher implements a sparse merkle tree hashing algorithm. For testing only.
 // It matches the test vectors generated by other sparse map implementations,
 // but it does not offer the full N bit security of the underlying hash function.
@@ -48,7 +56,7 @@ type MapHasher struct {
 // New creates a new merkle.MapHasher using the passed in hash function.
 func New(h crypto.Hash) hashers.MapHasher {
 	m := &MapHasher{Hash: h}
-	m.initNullHashes()
+	m.nullHashes = m.sharedNullHashes()
 	return m
 }
 
@@ -97,11 +105,24 @@ func (m *MapHasher) BitLen() int {
 	return m.Size() * 8
 }
 
-// initNullHashes sets the cache of empty hashes, one for each level in the sparse tree,
+// sharedNullHashes returns the cache of empty hashes of m's hash function,
+// computing it if no MapHasher using the same function did so before.
+func (m *MapHasher) sharedNullHashes() [][]byte {
+	nullHashesMu.Lock()
+	defer nullHashesMu.Unlock()
+	r, ok := nullHashes[m.Hash]
+	if !ok {
+		r = m.computeNullHashes()
+		nullHashes[m.Hash] = r
+	}
+	return r
+}
+
+// computeNullHashes returns the cache of empty hashes, one for each level in the sparse tree,
 // starting with the hash of an empty leaf, all the way up to the root hash of an empty tree.
 // These empty branches are not stored on disk in a sparse tree. They are computed since their
 // values are well-known.
-func (m *MapHasher) initNullHashes() {
+func (m *MapHasher) computeNullHashes() [][]byte {
 	// Leaves are stored at depth 0. Root is at Size()*8.
 	// There are Size()*8 edges, and Size()*8 + 1 nodes in the tree.
 	nodes := m.Size()*8 + 1
@@ -116,5 +137,5 @@ func (m *MapHasher) initNullHashes() {
 	for i := 1; i < nodes; i++ {
 		r[i] = m.HashChildren(r[i-1], r[i-1])
 	}
-	m.nullHashes = r
+	return r
 }
@@ -76,3 +76,18 @@ func (s *hstar) hStarEmpty(n int) []byte {
 	}
 	return s.hStarEmptyCache[n]
 }
+
+func TestNullHashesShared(t *testing.T) {
+	m1 := New(crypto.SHA256).(*MapHasher)
+	m2 := New(crypto.SHA256).(*MapHasher)
+	if &m1.nullHashes[0] != &m2.nullHashes[0] {
+		t.Errorf("New(SHA256) computed its empty hashes again, want them shared")
+	}
+	m3 := New(crypto.SHA512).(*MapHasher)
+	if got, want := len(m3.nullHashes), m3.BitLen()+1; got != want {
+		t.Fatalf("New(SHA512): %d empty hashes, want %d", got, want)
+	}
+	if got, want := m3.HashEmpty(treeID, nil, 0), m3.computeNullHashes()[0]; !bytes.Equal(got, want) {
+		t.Errorf("New(SHA512).HashEmpty(0): %x, want %x", got, want)
+	}
+}
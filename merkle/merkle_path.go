@@ -40,6 +40,43 @@ func (n NodeFetch) Equivalent(other NodeFetch) bool {
 	return n.Rehash == other.Rehash && n.NodeID.Equivalent(other.NodeID)
 }
 
+// LogNodeIDBitLen is the bit length of the node IDs of Trillian logs, whose
+// leaves are addressed by 64-bit indices.
+const LogNodeIDBitLen = 64
+
+// InclusionProofNodes returns the nodes of a Trillian log needed to build an
+// inclusion proof for the leaf at index in the tree of size snapshot, reading
+// nodes from the revision of the tree at size treeSize (this can be >
+// snapshot). This is the calculation the log server performs when serving
+// inclusion proofs, so it can be used to prefetch or precompute the proofs for
+// anticipated requests.
+//
+// The proof consists of the hashes of the returned nodes, in order, except
+// that each run of consecutive nodes with Rehash set contributes a single hash
+// to the proof: that of the first node in the run, successively combined as
+// the right child with each of the following nodes.
+func InclusionProofNodes(snapshot, index, treeSize int64) ([]NodeFetch, error) {
+	return CalcInclusionProofNodeAddresses(snapshot, index, treeSize, LogNodeIDBitLen)
+}
+
+// ConsistencyProofNodes returns the nodes of a Trillian log needed to build a
+// consistency proof between tree sizes snapshot1 and snapshot2, reading nodes
+// from the revision of the tree at size treeSize (this can be > snapshot2).
+// The proof is built from the nodes as described for InclusionProofNodes.
+func ConsistencyProofNodes(snapshot1, snapshot2, treeSize int64) ([]NodeFetch, error) {
+	return CalcConsistencyProofNodeAddresses(snapshot1, snapshot2, treeSize, LogNodeIDBitLen)
+}
+
+// NodeIDs returns the IDs of the nodes in fetches, in the same order, e.g. to
+// read them from storage with NodeReader.GetMerkleNodes.
+func NodeIDs(fetches []NodeFetch) []storage.NodeID {
+	ids := make([]storage.NodeID, 0, len(fetches))
+	for _, fetch := range fetches {
+		ids = append(ids, fetch.NodeID)
+	}
+	return ids
+}
+
 // checkSnapshot performs a couple of simple sanity checks on ss and treeSize
 // and returns an error if there's a problem.
 func checkSnapshot(ssDesc string, ss, treeSize int64) error {
@@ -15,9 +15,11 @@
 package merkle
 
 import (
+	"encoding/binary"
 	"fmt"
 	"testing"
 
+	"github.com/google/trillian/merkle/rfc6962"
 	"github.com/google/trillian/storage"
 )
 
@@ -365,6 +367,103 @@ func TestConsistencySucceedsUpToTreeSize(t *testing.T) {
 	}
 }
 
+// proofTree computes the hashes of log tree nodes directly from the leaves.
+type proofTree struct {
+	leaves [][]byte
+}
+
+// hash returns the hash of the subtree over leaves [lo, hi).
+func (p proofTree) hash(lo, hi int64) []byte {
+	if hi-lo == 1 {
+		return p.leaves[lo]
+	}
+	k := int64(1)
+	for k<<1 < hi-lo {
+		k <<= 1
+	}
+	return rfc6962.DefaultHasher.HashChildren(p.hash(lo, lo+k), p.hash(lo+k, hi))
+}
+
+// node returns the hash of the node with the given ID in the tree of size treeSize.
+func (p proofTree) node(id storage.NodeID, treeSize int64) []byte {
+	level := uint(LogNodeIDBitLen - id.PrefixLenBits)
+	index := int64(binary.BigEndian.Uint64(id.Path) >> level)
+	hi := (index + 1) << level
+	if hi > treeSize {
+		hi = treeSize
+	}
+	return p.hash(index<<level, hi)
+}
+
+// proof builds a proof from the nodes returned by the *ProofNodes functions.
+func (p proofTree) proof(fetches []NodeFetch, treeSize int64) [][]byte {
+	var proof [][]byte
+	for i, fetch := range fetches {
+		h := p.node(fetch.NodeID, treeSize)
+		if fetch.Rehash && i > 0 && fetches[i-1].Rehash {
+			proof[len(proof)-1] = rfc6962.DefaultHasher.HashChildren(h, proof[len(proof)-1])
+			continue
+		}
+		proof = append(proof, h)
+	}
+	return proof
+}
+
+func TestProofNodesBuildVerifiableProofs(t *testing.T) {
+	const maxSize = 20
+	p := proofTree{}
+	for i := 0; i < maxSize; i++ {
+		h, err := rfc6962.DefaultHasher.HashLeaf([]byte(fmt.Sprintf("leaf %d", i)))
+		if err != nil {
+			t.Fatalf("HashLeaf(): %v", err)
+		}
+		p.leaves = append(p.leaves, h)
+	}
+	v := NewLogVerifier(rfc6962.DefaultHasher)
+
+	// Nodes are read from a tree revision at least as large as the proofs, so
+	// some proofs require rehashing.
+	for treeSize := int64(1); treeSize <= maxSize; treeSize++ {
+		for size2 := int64(1); size2 <= treeSize; size2++ {
+			root2 := p.hash(0, size2)
+			for index := int64(0); index < size2; index++ {
+				fetches, err := InclusionProofNodes(size2, index, treeSize)
+				if err != nil {
+					t.Fatalf("InclusionProofNodes(%d, %d, %d): %v", size2, index, treeSize, err)
+				}
+				if err := v.VerifyInclusionProof(index, size2, p.proof(fetches, treeSize), root2, p.leaves[index]); err != nil {
+					t.Errorf("InclusionProofNodes(%d, %d, %d): proof does not verify: %v", size2, index, treeSize, err)
+				}
+			}
+			for size1 := int64(1); size1 <= size2; size1++ {
+				fetches, err := ConsistencyProofNodes(size1, size2, treeSize)
+				if err != nil {
+					t.Fatalf("ConsistencyProofNodes(%d, %d, %d): %v", size1, size2, treeSize, err)
+				}
+				if err := v.VerifyConsistencyProof(size1, size2, p.hash(0, size1), root2, p.proof(fetches, treeSize)); err != nil {
+					t.Errorf("ConsistencyProofNodes(%d, %d, %d): proof does not verify: %v", size1, size2, treeSize, err)
+				}
+			}
+		}
+	}
+}
+
+func TestNodeIDs(t *testing.T) {
+	fetches := []NodeFetch{
+		MustCreateNodeFetchForTreeCoords(0, 3, 64, false),
+		MustCreateNodeFetchForTreeCoords(2, 1, 64, true),
+	}
+	ids := NodeIDs(fetches)
+	if got, want := len(ids), len(fetches); got != want {
+		t.Fatalf("NodeIDs() returned %d IDs, want %d", got, want)
+	}
+	for i, id := range ids {
+		if !id.Equivalent(fetches[i].NodeID) {
+			t.Errorf("NodeIDs()[%d]=%v, want %v", i, id.CoordString(), fetches[i].NodeID.CoordString())
+		}
+	}
+}
+
 func MustCreateNodeFetchForTreeCoords(depth, index int64, maxPathBits int, rehash bool) NodeFetch {
 	n, err := storage.NewNodeIDForTreeCoords(depth, index, maxPathBits)
 	if err != nil {
@@ -17,10 +17,10 @@ package rfc6962
 
 import (
 	"crypto"
-	_ "crypto/sha256" // SHA256 is the default algorithm.
 
 	"github.com/google/trillian"
 	"github.com/google/trillian/merkle/hashers"
+	"github.com/google/trillian/merkle/rfc6962/hasher"
 )
 
 func init() {
@@ -29,43 +29,18 @@ func init() {
 
 // Domain separation prefixes
 const (
-	RFC6962LeafHashPrefix = 0
-	RFC6962NodeHashPrefix = 1
+	RFC6962LeafHashPrefix = hasher.RFC6962LeafHashPrefix
+	RFC6962NodeHashPrefix = hasher.RFC6962NodeHashPrefix
 )
 
 // DefaultHasher is a SHA256 based LogHasher.
 var DefaultHasher = New(crypto.SHA256)
 
-// Hasher implements the RFC6962 tree hashing algorithm.
-type Hasher struct {
-	crypto.Hash
-}
+// Hasher implements the RFC6962 tree hashing algorithm. It's implemented by
+// the dependency-light hasher subpackage.
+type Hasher = hasher.Hasher
 
 // New creates a new Hashers.LogHasher on the passed in hash function.
 func New(h crypto.Hash) *Hasher {
-	return &Hasher{Hash: h}
-}
-
-// EmptyRoot returns a special case for an empty tree.
-func (t *Hasher) EmptyRoot() []byte {
-	return t.New().Sum(nil)
-}
-
-// HashLeaf returns the Merkle tree leaf hash of the data passed in through leaf.
-// The data in leaf is prefixed by the LeafHashPrefix.
-func (t *Hasher) HashLeaf(leaf []byte) ([]byte, error) {
-	h := t.New()
-	h.Write([]byte{RFC6962LeafHashPrefix})
-	h.Write(leaf)
-	return h.Sum(nil), nil
-}
-
-// HashChildren returns the inner Merkle tree node hash of the the two child nodes l and r.
-// The hashed structure is NodeHashPrefix||l||r.
-func (t *Hasher) HashChildren(l, r []byte) []byte {
-	h := t.New()
-	h.Write([]byte{RFC6962NodeHashPrefix})
-	h.Write(l)
-	h.Write(r)
-	return h.Sum(nil)
+	return hasher.New(h)
 }
@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"runtime"
 	"sync"
 
 	"github.com/golang/glog"
@@ -113,6 +114,9 @@ type subtreeWriter struct {
 	treeRevision int64
 
 	hasher hashers.MapHasher
+	// hStar2 calculates the nodes of the subtree. It is shared by all the
+	// subtrees of a SparseMerkleTreeWriter, which share its workers.
+	hStar2 HStar2
 
 	getSubtree getSubtreeFunc
 }
@@ -228,9 +232,8 @@ func (s *subtreeWriter) buildSubtree(ctx context.Context) {
 		}
 
 		// calculate new root, and intermediate nodes:
-		hs2 := NewHStar2(s.treeID, s.hasher)
 		var err error
-		root, err = hs2.HStar2Nodes(s.prefix, s.subtreeDepth, leaves,
+		root, err = s.hStar2.HStar2Nodes(s.prefix, s.subtreeDepth, leaves,
 			func(depth int, index *big.Int) ([]byte, error) {
 				nodeID := storage.NewNodeIDFromBigInt(depth, index, s.hasher.BitLen())
 				glog.V(4).Infof("buildSubtree.get(%x, %d) nid: %x, %v",
@@ -313,7 +316,7 @@ func leafQueueSize(depths []int) int {
 }
 
 // newLocalSubtreeWriter creates a new local go-routine based subtree worker.
-func newLocalSubtreeWriter(ctx context.Context, treeID, rev int64, prefix []byte, depths []int, runTX runTXFunc, h hashers.MapHasher) (Subtree, error) {
+func newLocalSubtreeWriter(ctx context.Context, treeID, rev int64, prefix []byte, depths []int, runTX runTXFunc, h hashers.MapHasher, hs2 HStar2) (Subtree, error) {
 	tree := subtreeWriter{
 		treeID:       treeID,
 		treeRevision: rev,
@@ -325,9 +328,10 @@ func newLocalSubtreeWriter(ctx context.Context, treeID, rev int64, prefix []byte
 		children:     make(map[string]Subtree),
 		runTX:        runTX,
 		hasher:       h,
+		hStar2:       hs2,
 		getSubtree: func(ctx context.Context, p []byte) (Subtree, error) {
 			myPrefix := bytes.Join([][]byte{prefix, p}, []byte{})
-			return newLocalSubtreeWriter(ctx, treeID, rev, myPrefix, depths[1:], runTX, h)
+			return newLocalSubtreeWriter(ctx, treeID, rev, myPrefix, depths[1:], runTX, h, hs2)
 		},
 	}
 
@@ -340,10 +344,13 @@ func newLocalSubtreeWriter(ctx context.Context, treeID, rev int64, prefix []byte
 // NewSparseMerkleTreeWriter returns a new SparseMerkleTreeWriter, which will
 // write data back into the tree at the specified revision, using the passed
 // in MapHasher to calculate/verify tree hashes, storing via tx.
+// The subtrees of the map are hashed in parallel, in up to GOMAXPROCS
+// goroutines besides the ones of the subtree workers.
 func NewSparseMerkleTreeWriter(ctx context.Context, treeID, rev int64, h hashers.MapHasher, runTX runTXFunc) (*SparseMerkleTreeWriter, error) {
 	// TODO(al): allow the tree layering sizes to be customisable somehow.
 	const topSubtreeSize = 8 // must be a multiple of 8 for now.
-	tree, err := newLocalSubtreeWriter(ctx, treeID, rev, []byte{}, []int{topSubtreeSize, h.Size()*8 - topSubtreeSize}, runTX, h)
+	hs2 := NewParallelHStar2(treeID, h, runtime.GOMAXPROCS(0))
+	tree, err := newLocalSubtreeWriter(ctx, treeID, rev, []byte{}, []int{topSubtreeSize, h.Size()*8 - topSubtreeSize}, runTX, h, hs2)
 	if err != nil {
 		return nil, err
 	}
@@ -421,6 +428,102 @@ func (s SparseMerkleTreeReader) InclusionProof(ctx context.Context, rev int64, i
 	return r, nil
 }
 
+// ChangedIndices returns the indices of the leaves whose hashes differ
+// between the trees read by from and to, at their revisions, in increasing
+// order. The trees are read a level at a time, descending only into the
+// branches whose hashes differ. from and to must read through different
+// transactions, as storage may cache nodes regardless of their revision.
+//
+// Only indices >= start are returned, if start is set, and at most max of
+// them. If there may be more, next is the index to continue from.
+func ChangedIndices(ctx context.Context, from, to *SparseMerkleTreeReader, start []byte, max int) (indices [][]byte, next []byte, err error) {
+	if max < 1 {
+		return nil, nil, fmt.Errorf("max is %d, want > 0", max)
+	}
+	bitLen := from.hasher.BitLen()
+	if start != nil && len(start) != bitLen/8 {
+		return nil, nil, fmt.Errorf("len(start) is %d, want %d", len(start), bitLen/8)
+	}
+
+	frontier := []storage.NodeID{storage.NewEmptyNodeID(bitLen)}
+	for depth := 0; depth < bitLen; depth++ {
+		// The children of the changed nodes which aren't entirely before start.
+		children := make([]storage.NodeID, 0, 2*len(frontier))
+		for _, n := range frontier {
+			for bit := 0; bit < 2; bit++ {
+				child := storage.NodeID{Path: make([]byte, len(n.Path)), PrefixLenBits: depth + 1}
+				copy(child.Path, n.Path)
+				if bit == 1 {
+					child.Path[depth/8] |= 0x80 >> uint(depth%8)
+				}
+				if start != nil && bytes.Compare(lastIndex(child), start) < 0 {
+					continue
+				}
+				children = append(children, child)
+			}
+		}
+		if len(children) == 0 {
+			return nil, nil, nil
+		}
+
+		fromHashes, err := from.nodeHashes(ctx, children)
+		if err != nil {
+			return nil, nil, err
+		}
+		toHashes, err := to.nodeHashes(ctx, children)
+		if err != nil {
+			return nil, nil, err
+		}
+		frontier = frontier[:0]
+		for _, child := range children {
+			if id := child.String(); !bytes.Equal(fromHashes[id], toHashes[id]) {
+				frontier = append(frontier, child)
+			}
+		}
+		// Every changed node has a changed leaf below it, so the frontier can
+		// be cut to max nodes, continuing from the first node cut.
+		if len(frontier) > max {
+			next = frontier[max].Path
+			frontier = frontier[:max]
+		}
+	}
+
+	indices = make([][]byte, 0, len(frontier))
+	for _, n := range frontier {
+		indices = append(indices, n.Path)
+	}
+	return indices, next, nil
+}
+
+// nodeHashes returns the hashes of the nodes ids at the reader's revision,
+// keyed by NodeID.String(). Nodes missing from storage are empty, and have no
+// entry.
+func (s SparseMerkleTreeReader) nodeHashes(ctx context.Context, ids []storage.NodeID) (map[string][]byte, error) {
+	rev := s.treeRevision
+	nodes, err := s.tx.GetMerkleNodes(ctx, rev, ids)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make(map[string][]byte, len(nodes))
+	for _, n := range nodes {
+		if n.NodeRevision > rev {
+			return nil, fmt.Errorf("unexpected node revision returned: %d > %d", n.NodeRevision, rev)
+		}
+		hashes[n.NodeID.String()] = n.Hash
+	}
+	return hashes, nil
+}
+
+// lastIndex returns the greatest leaf index below the node n.
+func lastIndex(n storage.NodeID) []byte {
+	index := make([]byte, len(n.Path))
+	copy(index, n.Path)
+	for i := n.PrefixLenBits; i < len(index)*8; i++ {
+		index[i/8] |= 0x80 >> uint(i%8)
+	}
+	return index
+}
+
 // SetLeaves adds a batch of leaves to the in-flight tree update.
 func (s *SparseMerkleTreeWriter) SetLeaves(ctx context.Context, leaves []HashKeyValue) error {
 	for _, l := range leaves {
@@ -0,0 +1,94 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitoring
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultLatencyBuckets are the default bucket upper bounds, in seconds, of
+// latency histograms: from 1ms doubling up to ~65s.
+var DefaultLatencyBuckets = ExpBuckets(0.001, 2, 17)
+
+var (
+	latencyMu      sync.RWMutex
+	latencyBuckets = DefaultLatencyBuckets
+)
+
+// LatencyBuckets returns the bucket upper bounds to use for histograms of
+// latencies in seconds. All latency histograms share the same buckets, so
+// that they can be compared and aggregated on dashboards.
+func LatencyBuckets() []float64 {
+	latencyMu.RLock()
+	defer latencyMu.RUnlock()
+	return latencyBuckets
+}
+
+// SetLatencyBuckets sets the buckets returned by LatencyBuckets. It only
+// affects histograms created after it's called, so should be called during
+// initialization.
+func SetLatencyBuckets(buckets []float64) error {
+	if err := checkBuckets(buckets); err != nil {
+		return err
+	}
+	latencyMu.Lock()
+	defer latencyMu.Unlock()
+	latencyBuckets = buckets
+	return nil
+}
+
+// ExpBuckets returns count bucket upper bounds, the first one being start and
+// each subsequent one factor times the previous one.
+func ExpBuckets(start, factor float64, count int) []float64 {
+	buckets := make([]float64, count)
+	for i := range buckets {
+		buckets[i] = start
+		start *= factor
+	}
+	return buckets
+}
+
+// ParseBuckets parses a comma-separated list of increasing bucket upper
+// bounds, e.g. "0.01,0.1,1,10".
+func ParseBuckets(s string) ([]float64, error) {
+	var buckets []float64
+	for _, b := range strings.Split(s, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(b), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bucket %q: %v", b, err)
+		}
+		buckets = append(buckets, v)
+	}
+	if err := checkBuckets(buckets); err != nil {
+		return nil, err
+	}
+	return buckets, nil
+}
+
+func checkBuckets(buckets []float64) error {
+	if len(buckets) == 0 {
+		return errors.New("no buckets")
+	}
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i] <= buckets[i-1] {
+			return fmt.Errorf("buckets not in increasing order: %v after %v", buckets[i], buckets[i-1])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitoring
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpBuckets(t *testing.T) {
+	if got, want := ExpBuckets(0.5, 4, 4), []float64{0.5, 2, 8, 32}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ExpBuckets()=%v; want %v", got, want)
+	}
+	if got, want := len(DefaultLatencyBuckets), 17; got != want {
+		t.Errorf("len(DefaultLatencyBuckets)=%v; want %v", got, want)
+	}
+}
+
+func TestParseBuckets(t *testing.T) {
+	for _, test := range []struct {
+		in      string
+		want    []float64
+		wantErr bool
+	}{
+		{in: "1", want: []float64{1}},
+		{in: "0.01, 0.1,1,10", want: []float64{0.01, 0.1, 1, 10}},
+		{in: "", wantErr: true},
+		{in: "1,,2", wantErr: true},
+		{in: "1,one", wantErr: true},
+		{in: "1,2,2", wantErr: true},
+		{in: "2,1", wantErr: true},
+	} {
+		got, err := ParseBuckets(test.in)
+		if gotErr := err != nil; gotErr != test.wantErr {
+			t.Errorf("ParseBuckets(%q)=_,%v; want err? %v", test.in, err, test.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseBuckets(%q)=%v; want %v", test.in, got, test.want)
+		}
+	}
+}
+
+func TestSetLatencyBuckets(t *testing.T) {
+	defer SetLatencyBuckets(DefaultLatencyBuckets)
+
+	if err := SetLatencyBuckets([]float64{2, 1}); err == nil {
+		t.Error("SetLatencyBuckets(unsorted)=nil; want err")
+	}
+	if got, want := LatencyBuckets(), DefaultLatencyBuckets; !reflect.DeepEqual(got, want) {
+		t.Errorf("LatencyBuckets()=%v; want %v", got, want)
+	}
+	buckets := []float64{0.1, 1, 10}
+	if err := SetLatencyBuckets(buckets); err != nil {
+		t.Fatalf("SetLatencyBuckets()=%v; want nil", err)
+	}
+	if got := LatencyBuckets(); !reflect.DeepEqual(got, buckets) {
+		t.Errorf("LatencyBuckets()=%v; want %v", got, buckets)
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitoring
+
+import "golang.org/x/net/context"
+
+// TraceIDExemplarLabel is the exemplar label holding the ID of the trace an
+// observation was made in.
+const TraceIDExemplarLabel = "trace_id"
+
+// ExemplarObserver is implemented by Histograms which can attach exemplars,
+// such as the ID of the trace an observation was made in, to observations.
+// This allows correlating the observations in a bucket with traces.
+type ExemplarObserver interface {
+	ObserveWithExemplar(val float64, exemplar map[string]string, labelVals ...string)
+}
+
+// TraceIDFunc returns the ID of the trace ctx belongs to, or "" if it isn't
+// traced.
+type TraceIDFunc func(ctx context.Context) string
+
+// ObserveWithTrace adds an observation to h. If h is an ExemplarObserver and
+// traceID returns a trace ID for ctx, the trace ID is attached to the
+// observation as an exemplar.
+func ObserveWithTrace(ctx context.Context, h Histogram, traceID TraceIDFunc, val float64, labelVals ...string) {
+	if eo, ok := h.(ExemplarObserver); ok && traceID != nil {
+		if id := traceID(ctx); id != "" {
+			eo.ObserveWithExemplar(val, map[string]string{TraceIDExemplarLabel: id}, labelVals...)
+			return
+		}
+	}
+	h.Observe(val, labelVals...)
+}
@@ -47,9 +47,17 @@ func (imf InertMetricFactory) NewHistogram(name, help string, labelNames ...stri
 		labelCount: len(labelNames),
 		counts:     make(map[string]uint64),
 		sums:       make(map[string]float64),
+		exemplars:  make(map[string]map[string]string),
 	}
 }
 
+// NewHistogramWithBuckets creates a new inert Histogram. The buckets are
+// ignored, as InertDistribution only tracks the count and sum of
+// observations.
+func (imf InertMetricFactory) NewHistogramWithBuckets(name, help string, buckets []float64, labelNames ...string) Histogram {
+	return imf.NewHistogram(name, help, labelNames...)
+}
+
 // InertFloat is an internal-only implementation of both the Counter and Gauge interfaces.
 type InertFloat struct {
 	labelCount int
@@ -109,6 +117,7 @@ type InertDistribution struct {
 	mu         sync.Mutex
 	counts     map[string]uint64
 	sums       map[string]float64
+	exemplars  map[string]map[string]string
 }
 
 // Observe adds a single observation to the distribution.
@@ -136,6 +145,29 @@ func (m *InertDistribution) Info(labelVals ...string) (uint64, float64) {
 	return m.counts[key], m.sums[key]
 }
 
+// ObserveWithExemplar adds a single observation to the distribution, and
+// keeps exemplar as the latest exemplar for the labels.
+func (m *InertDistribution) ObserveWithExemplar(val float64, exemplar map[string]string, labelVals ...string) {
+	m.Observe(val, labelVals...)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if key, err := keyForLabels(labelVals, m.labelCount); err == nil {
+		m.exemplars[key] = exemplar
+	}
+}
+
+// Exemplar returns the latest exemplar observed for the distribution.
+func (m *InertDistribution) Exemplar(labelVals ...string) map[string]string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key, err := keyForLabels(labelVals, m.labelCount)
+	if err != nil {
+		glog.Error(err.Error())
+		return nil
+	}
+	return m.exemplars[key]
+}
+
 func keyForLabels(labelVals []string, count int) (string, error) {
 	if len(labelVals) != count {
 		return "", fmt.Errorf("invalid label count %d; want %d", len(labelVals), count)
@@ -19,6 +19,10 @@ type MetricFactory interface {
 	NewCounter(name, help string, labelNames ...string) Counter
 	NewGauge(name, help string, labelNames ...string) Gauge
 	NewHistogram(name, help string, labelNames ...string) Histogram
+	// NewHistogramWithBuckets creates a Histogram with the given bucket upper
+	// bounds, which must be sorted in increasing order. Implementations may
+	// ignore buckets if they don't support custom ones.
+	NewHistogramWithBuckets(name, help string, buckets []float64, labelNames ...string) Histogram
 }
 
 // Counter is a metric class for numeric values that increase.
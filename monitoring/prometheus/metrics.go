@@ -73,23 +73,30 @@ func (pmf MetricFactory) NewGauge(name, help string, labelNames ...string) monit
 	return &Gauge{labelNames: labelNames, vec: vec}
 }
 
-// NewHistogram creates a new Histogram object backed by Prometheus.
+// NewHistogram creates a new Histogram object backed by Prometheus, with the
+// default Prometheus buckets.
 func (pmf MetricFactory) NewHistogram(name, help string, labelNames ...string) monitoring.Histogram {
+	return pmf.NewHistogramWithBuckets(name, help, nil, labelNames...)
+}
+
+// NewHistogramWithBuckets creates a new Histogram object backed by Prometheus,
+// with the given buckets. If buckets is empty the default Prometheus buckets
+// are used.
+//
+// The Prometheus client in use doesn't support exemplars, so the returned
+// Histogram doesn't implement monitoring.ExemplarObserver.
+func (pmf MetricFactory) NewHistogramWithBuckets(name, help string, buckets []float64, labelNames ...string) monitoring.Histogram {
+	opts := prometheus.HistogramOpts{
+		Name:    pmf.Prefix + name,
+		Help:    help,
+		Buckets: buckets,
+	}
 	if len(labelNames) == 0 {
-		histogram := prometheus.NewHistogram(
-			prometheus.HistogramOpts{
-				Name: pmf.Prefix + name,
-				Help: help,
-			})
+		histogram := prometheus.NewHistogram(opts)
 		prometheus.MustRegister(histogram)
 		return &Histogram{single: histogram}
 	}
-	vec := prometheus.NewHistogramVec(
-		prometheus.HistogramOpts{
-			Name: pmf.Prefix + name,
-			Help: help,
-		},
-		labelNames)
+	vec := prometheus.NewHistogramVec(opts, labelNames)
 	prometheus.MustRegister(vec)
 	return &Histogram{labelNames: labelNames, vec: vec}
 }
@@ -15,9 +15,13 @@
 package prometheus
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/google/trillian/monitoring/testonly"
+	"github.com/prometheus/client_golang/prometheus"
+
+	dto "github.com/prometheus/client_model/go"
 )
 
 func TestCounter(t *testing.T) {
@@ -29,3 +33,27 @@ func TestGauge(t *testing.T) {
 func TestHistogram(t *testing.T) {
 	testonly.TestHistogram(t, MetricFactory{Prefix: "TestHistogram"})
 }
+
+func TestHistogramWithBuckets(t *testing.T) {
+	buckets := []float64{0.1, 1, 10}
+	h := MetricFactory{Prefix: "TestHistogramWithBuckets"}.NewHistogramWithBuckets("test_histogram", "Test only", buckets, "key1").(*Histogram)
+	h.Observe(0.5, "val1")
+	h.Observe(20, "val1")
+
+	var metricpb dto.Metric
+	if err := h.vec.With(prometheus.Labels{"key1": "val1"}).(prometheus.Metric).Write(&metricpb); err != nil {
+		t.Fatalf("Write()=%v", err)
+	}
+	var got []float64
+	var counts []uint64
+	for _, b := range metricpb.GetHistogram().GetBucket() {
+		got = append(got, b.GetUpperBound())
+		counts = append(counts, b.GetCumulativeCount())
+	}
+	if !reflect.DeepEqual(got, buckets) {
+		t.Errorf("bucket upper bounds=%v; want %v", got, buckets)
+	}
+	if want := []uint64{0, 1, 1}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("bucket counts=%v; want %v", counts, want)
+	}
+}
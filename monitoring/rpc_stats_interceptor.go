@@ -33,6 +33,10 @@ type RPCStatsInterceptor struct {
 	ReqSuccessLatency Histogram
 	ReqErrorCount     Counter
 	ReqErrorLatency   Histogram
+
+	// TraceID, if set, is used to attach the IDs of the traces of requests to
+	// their latency observations as exemplars, where the Histograms support it.
+	TraceID TraceIDFunc
 }
 
 // NewRPCStatsInterceptor creates a new RPCStatsInterceptor for the given application/component, with
@@ -43,9 +47,9 @@ func NewRPCStatsInterceptor(timeSource util.TimeSource, prefix string, mf Metric
 		timeSource:        timeSource,
 		ReqCount:          mf.NewCounter(prefixedName(prefix, "rpc_requests"), "Number of requests", "method"),
 		ReqSuccessCount:   mf.NewCounter(prefixedName(prefix, "rpc_success"), "Number of successful requests", "method"),
-		ReqSuccessLatency: mf.NewHistogram(prefixedName(prefix, "rpc_success_latency"), "Latency of successful requests in seconds", "method"),
+		ReqSuccessLatency: mf.NewHistogramWithBuckets(prefixedName(prefix, "rpc_success_latency"), "Latency of successful requests in seconds", LatencyBuckets(), "method"),
 		ReqErrorCount:     mf.NewCounter(prefixedName(prefix, "rpc_errors"), "Number of errored requests", "method"),
-		ReqErrorLatency:   mf.NewHistogram(prefixedName(prefix, "rpc_error_latency"), "Latency of errored requests in seconds", "method"),
+		ReqErrorLatency:   mf.NewHistogramWithBuckets(prefixedName(prefix, "rpc_error_latency"), "Latency of errored requests in seconds", LatencyBuckets(), "method"),
 	}
 	return &interceptor
 }
@@ -54,10 +58,10 @@ func prefixedName(prefix, name string) string {
 	return fmt.Sprintf("%s_%s", prefix, name)
 }
 
-func (r *RPCStatsInterceptor) recordFailureLatency(labels []string, startTime time.Time) {
+func (r *RPCStatsInterceptor) recordFailureLatency(ctx context.Context, labels []string, startTime time.Time) {
 	latency := util.SecondsSince(r.timeSource, startTime)
 	r.ReqErrorCount.Inc(labels...)
-	r.ReqErrorLatency.Observe(latency, labels...)
+	ObserveWithTrace(ctx, r.ReqErrorLatency, r.TraceID, latency, labels...)
 }
 
 // Interceptor returns a UnaryServerInterceptor that can be registered with an RPC server and
@@ -73,7 +77,7 @@ func (r *RPCStatsInterceptor) Interceptor() grpc.UnaryServerInterceptor {
 		defer func() {
 			if rec := recover(); rec != nil {
 				// If we reach here then the handler exited via panic, count it as a server failure
-				r.recordFailureLatency(labels, startTime)
+				r.recordFailureLatency(ctx, labels, startTime)
 				panic(rec)
 			}
 		}()
@@ -83,11 +87,11 @@ func (r *RPCStatsInterceptor) Interceptor() grpc.UnaryServerInterceptor {
 
 		// Record success / failure and latency
 		if err != nil {
-			r.recordFailureLatency(labels, startTime)
+			r.recordFailureLatency(ctx, labels, startTime)
 		} else {
 			latency := util.SecondsSince(r.timeSource, startTime)
 			r.ReqSuccessCount.Inc(labels...)
-			r.ReqSuccessLatency.Observe(latency, labels...)
+			ObserveWithTrace(ctx, r.ReqSuccessLatency, r.TraceID, latency, labels...)
 		}
 
 		// Pass the result of the handler invocation back
@@ -178,3 +178,39 @@ func TestMultipleErrorRequestsTotalLatency(t *testing.T) {
 		t.Errorf("stats.ReqSuccessLatency.Info=%v,%v; want %v,%v", count, sum, wantCount, wantSum)
 	}
 }
+
+type traceIDKey struct{}
+
+func TestRequestLatencyExemplars(t *testing.T) {
+	ts := util.IncrementingFakeTimeSource{
+		BaseTime:   fakeTime,
+		Increments: []time.Duration{0, time.Millisecond * 500, 0, time.Millisecond * 100},
+	}
+	stats := monitoring.NewRPCStatsInterceptor(&ts, "test_exemplars", monitoring.InertMetricFactory{})
+	stats.TraceID = func(ctx context.Context) string {
+		id, _ := ctx.Value(traceIDKey{}).(string)
+		return id
+	}
+	i := stats.Interceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "testmethod"}
+
+	okHandler := recordingUnaryHandler{rsp: "OK"}
+	if _, err := i(context.WithValue(context.Background(), traceIDKey{}, "trace1"), "wibble", info, okHandler.handler()); err != nil {
+		t.Fatalf("interceptor()=_,%v; want _,nil", err)
+	}
+	errHandler := recordingUnaryHandler{err: errors.New("bang")}
+	if _, err := i(context.Background(), "wibble", info, errHandler.handler()); err == nil {
+		t.Fatal("interceptor()=_,nil; want _,'bang'")
+	}
+
+	if got, want := stats.ReqSuccessLatency.(*monitoring.InertDistribution).Exemplar("testmethod"), "trace1"; got[monitoring.TraceIDExemplarLabel] != want {
+		t.Errorf("stats.ReqSuccessLatency exemplar=%v; want trace ID %v", got, want)
+	}
+	// Untraced requests are observed without exemplars.
+	if got := stats.ReqErrorLatency.(*monitoring.InertDistribution).Exemplar("testmethod"); got != nil {
+		t.Errorf("stats.ReqErrorLatency exemplar=%v; want nil", got)
+	}
+	if count, _ := stats.ReqErrorLatency.Info("testmethod"); count != 1 {
+		t.Errorf("stats.ReqErrorLatency.Info count=%v; want 1", count)
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitoring
+
+import (
+	"strconv"
+	"sync"
+)
+
+// OtherTreesLabelValue is the tree label value under which TreeLabelLimiter
+// aggregates the metrics of trees that don't get their own label value.
+const OtherTreesLabelValue = "other"
+
+// DefaultTreeLabelNames are the names of the labels which hold tree IDs in
+// Trillian's metrics.
+var DefaultTreeLabelNames = []string{TreeIDLabel, "logid"}
+
+// TreeLabelOpts configures a TreeLabelLimiter.
+type TreeLabelOpts struct {
+	// MaxTrees is the number of trees whose metrics get their own label
+	// values, on a first come first served basis. The metrics of any further
+	// trees are aggregated under OtherTreesLabelValue. Zero means unlimited.
+	MaxTrees int
+	// Allowlist holds the IDs of trees whose metrics always get their own label
+	// values. They don't count towards MaxTrees.
+	Allowlist []int64
+	// LabelNames are the names of the labels holding tree IDs. If empty,
+	// DefaultTreeLabelNames is used.
+	LabelNames []string
+}
+
+// TreeLabelLimiter is a MetricFactory which bounds the cardinality of the
+// tree ID labels of the metrics created by another MetricFactory.
+//
+// The set of trees with their own label values is shared by all the metrics
+// created by a TreeLabelLimiter, so a tree either has its own time series in
+// all of them or in none. Note that Gauge values of aggregated trees overwrite
+// each other when Set.
+type TreeLabelLimiter struct {
+	mf         MetricFactory
+	maxTrees   int
+	labelNames map[string]bool
+
+	mu       sync.RWMutex
+	allowed  map[string]bool
+	admitted map[string]bool
+}
+
+// NewTreeLabelLimiter returns a TreeLabelLimiter wrapping mf.
+func NewTreeLabelLimiter(mf MetricFactory, opts TreeLabelOpts) *TreeLabelLimiter {
+	names := opts.LabelNames
+	if len(names) == 0 {
+		names = DefaultTreeLabelNames
+	}
+	l := &TreeLabelLimiter{
+		mf:         mf,
+		maxTrees:   opts.MaxTrees,
+		labelNames: make(map[string]bool),
+		allowed:    make(map[string]bool),
+		admitted:   make(map[string]bool),
+	}
+	for _, name := range names {
+		l.labelNames[name] = true
+	}
+	for _, id := range opts.Allowlist {
+		l.allowed[strconv.FormatInt(id, 10)] = true
+	}
+	return l
+}
+
+// NewCounter implements MetricFactory.
+func (l *TreeLabelLimiter) NewCounter(name, help string, labelNames ...string) Counter {
+	c := l.mf.NewCounter(name, help, labelNames...)
+	if idx := l.treeLabels(labelNames); len(idx) > 0 {
+		return &limitedCounter{c: c, labels: labelMapper{l: l, idx: idx}}
+	}
+	return c
+}
+
+// NewGauge implements MetricFactory.
+func (l *TreeLabelLimiter) NewGauge(name, help string, labelNames ...string) Gauge {
+	g := l.mf.NewGauge(name, help, labelNames...)
+	if idx := l.treeLabels(labelNames); len(idx) > 0 {
+		return &limitedGauge{g: g, labels: labelMapper{l: l, idx: idx}}
+	}
+	return g
+}
+
+// NewHistogram implements MetricFactory.
+func (l *TreeLabelLimiter) NewHistogram(name, help string, labelNames ...string) Histogram {
+	h := l.mf.NewHistogram(name, help, labelNames...)
+	if idx := l.treeLabels(labelNames); len(idx) > 0 {
+		return &limitedHistogram{h: h, labels: labelMapper{l: l, idx: idx}}
+	}
+	return h
+}
+
+// NewHistogramWithBuckets implements MetricFactory.
+func (l *TreeLabelLimiter) NewHistogramWithBuckets(name, help string, buckets []float64, labelNames ...string) Histogram {
+	h := l.mf.NewHistogramWithBuckets(name, help, buckets, labelNames...)
+	if idx := l.treeLabels(labelNames); len(idx) > 0 {
+		return &limitedHistogram{h: h, labels: labelMapper{l: l, idx: idx}}
+	}
+	return h
+}
+
+// treeLabels returns the positions of the tree ID labels in labelNames.
+func (l *TreeLabelLimiter) treeLabels(labelNames []string) []int {
+	var idx []int
+	for i, name := range labelNames {
+		if l.labelNames[name] {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
+// treeValue returns the label value to use for treeID. If admit is true, the
+// tree is given its own label value if there's room for it.
+func (l *TreeLabelLimiter) treeValue(treeID string, admit bool) string {
+	if l.maxTrees <= 0 {
+		return treeID
+	}
+	l.mu.RLock()
+	ok := l.allowed[treeID] || l.admitted[treeID]
+	full := len(l.admitted) >= l.maxTrees
+	l.mu.RUnlock()
+	switch {
+	case ok:
+		return treeID
+	case full:
+		return OtherTreesLabelValue
+	case !admit:
+		// The tree would be admitted on its first update.
+		return treeID
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.admitted[treeID] && len(l.admitted) >= l.maxTrees {
+		return OtherTreesLabelValue
+	}
+	l.admitted[treeID] = true
+	return treeID
+}
+
+// labelMapper rewrites the tree ID label values of a metric.
+type labelMapper struct {
+	l   *TreeLabelLimiter
+	idx []int
+}
+
+// mapLabels returns labelVals with its tree IDs replaced as necessary. Label
+// count mismatches are left for the wrapped metric to report.
+func (m labelMapper) mapLabels(labelVals []string, admit bool) []string {
+	mapped := append([]string(nil), labelVals...)
+	for _, i := range m.idx {
+		if i < len(mapped) {
+			mapped[i] = m.l.treeValue(mapped[i], admit)
+		}
+	}
+	return mapped
+}
+
+type limitedCounter struct {
+	c      Counter
+	labels labelMapper
+}
+
+func (c *limitedCounter) Inc(labelVals ...string) {
+	c.c.Inc(c.labels.mapLabels(labelVals, true)...)
+}
+
+func (c *limitedCounter) Add(val float64, labelVals ...string) {
+	c.c.Add(val, c.labels.mapLabels(labelVals, true)...)
+}
+
+func (c *limitedCounter) Value(labelVals ...string) float64 {
+	return c.c.Value(c.labels.mapLabels(labelVals, false)...)
+}
+
+type limitedGauge struct {
+	g      Gauge
+	labels labelMapper
+}
+
+func (g *limitedGauge) Inc(labelVals ...string) {
+	g.g.Inc(g.labels.mapLabels(labelVals, true)...)
+}
+
+func (g *limitedGauge) Dec(labelVals ...string) {
+	g.g.Dec(g.labels.mapLabels(labelVals, true)...)
+}
+
+func (g *limitedGauge) Add(val float64, labelVals ...string) {
+	g.g.Add(val, g.labels.mapLabels(labelVals, true)...)
+}
+
+func (g *limitedGauge) Set(val float64, labelVals ...string) {
+	g.g.Set(val, g.labels.mapLabels(labelVals, true)...)
+}
+
+func (g *limitedGauge) Value(labelVals ...string) float64 {
+	return g.g.Value(g.labels.mapLabels(labelVals, false)...)
+}
+
+type limitedHistogram struct {
+	h      Histogram
+	labels labelMapper
+}
+
+func (h *limitedHistogram) Observe(val float64, labelVals ...string) {
+	h.h.Observe(val, h.labels.mapLabels(labelVals, true)...)
+}
+
+// ObserveWithExemplar implements ExemplarObserver, falling back to Observe if
+// the wrapped Histogram doesn't support exemplars.
+func (h *limitedHistogram) ObserveWithExemplar(val float64, exemplar map[string]string, labelVals ...string) {
+	labelVals = h.labels.mapLabels(labelVals, true)
+	if eo, ok := h.h.(ExemplarObserver); ok {
+		eo.ObserveWithExemplar(val, exemplar, labelVals...)
+		return
+	}
+	h.h.Observe(val, labelVals...)
+}
+
+func (h *limitedHistogram) Info(labelVals ...string) (uint64, float64) {
+	return h.h.Info(h.labels.mapLabels(labelVals, false)...)
+}
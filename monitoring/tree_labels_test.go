@@ -0,0 +1,81 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitoring_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/monitoring/testonly"
+)
+
+func TestTreeLabelLimiterMetrics(t *testing.T) {
+	mf := monitoring.NewTreeLabelLimiter(monitoring.InertMetricFactory{}, monitoring.TreeLabelOpts{MaxTrees: 1, LabelNames: []string{"key1"}})
+	testonly.TestCounter(t, mf)
+	testonly.TestGauge(t, mf)
+	testonly.TestHistogram(t, mf)
+}
+
+func TestTreeLabelLimiter(t *testing.T) {
+	mf := monitoring.NewTreeLabelLimiter(monitoring.InertMetricFactory{}, monitoring.TreeLabelOpts{MaxTrees: 2, Allowlist: []int64{100}})
+	counter := mf.NewCounter("requests", "Test only", "method", monitoring.TreeIDLabel)
+	gauge := mf.NewGauge("size", "Test only", "logid")
+	histogram := mf.NewHistogram("latency", "Test only", "method")
+
+	for _, tree := range []string{"1", "2", "3", "100", "4", "1"} {
+		counter.Inc("get", tree)
+		gauge.Inc(tree)
+		histogram.Observe(1, tree)
+	}
+
+	for _, test := range []struct {
+		tree string
+		want float64
+	}{
+		{tree: "1", want: 2},
+		{tree: "2", want: 1},
+		{tree: "100", want: 1},
+		{tree: "3", want: 2}, // Reads the aggregate of trees 3 and 4.
+		{tree: monitoring.OtherTreesLabelValue, want: 2},
+	} {
+		if got := counter.Value("get", test.tree); got != test.want {
+			t.Errorf("counter.Value(%q) = %v, want %v", test.tree, got, test.want)
+		}
+		if got := gauge.Value(test.tree); got != test.want {
+			t.Errorf("gauge.Value(%q) = %v, want %v", test.tree, got, test.want)
+		}
+	}
+
+	// Labels other than tree IDs are unaffected.
+	if got, _ := histogram.Info("3"); got != 1 {
+		t.Errorf("histogram.Info(\"3\") count = %v, want 1", got)
+	}
+}
+
+func TestTreeLabelLimiterUnlimited(t *testing.T) {
+	mf := monitoring.NewTreeLabelLimiter(monitoring.InertMetricFactory{}, monitoring.TreeLabelOpts{})
+	counter := mf.NewCounter("requests", "Test only", monitoring.TreeIDLabel)
+	for i := 0; i < 100; i++ {
+		counter.Inc("1")
+		counter.Inc(fmt.Sprint(i + 2))
+	}
+	if got, want := counter.Value(monitoring.OtherTreesLabelValue), 0.0; got != want {
+		t.Errorf("counter.Value(%q) = %v, want %v", monitoring.OtherTreesLabelValue, got, want)
+	}
+	if got, want := counter.Value("1"), 100.0; got != want {
+		t.Errorf("counter.Value(\"1\") = %v, want %v", got, want)
+	}
+}
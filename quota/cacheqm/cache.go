@@ -89,6 +89,14 @@ func (m *manager) PutTokens(ctx context.Context, numTokens int, specs []quota.Sp
 	return m.qm.PutTokens(ctx, numTokens, specs)
 }
 
+// ExemptSpecs implements quota.Exempter, if the wrapped manager does.
+func (m *manager) ExemptSpecs(ctx context.Context, user string, specs []quota.Spec) ([]quota.Spec, error) {
+	if e, ok := m.qm.(quota.Exempter); ok {
+		return e.ExemptSpecs(ctx, user, specs)
+	}
+	return nil, nil
+}
+
 func (m *manager) ResetQuota(ctx context.Context, specs []quota.Spec) error {
 	return m.qm.ResetQuota(ctx, specs)
 }
@@ -18,14 +18,38 @@ package etcdqm
 import (
 	"context"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/coreos/etcd/clientv3"
+	"github.com/golang/glog"
 	"github.com/google/trillian/quota"
 	"github.com/google/trillian/quota/etcd/storage"
+	"github.com/google/trillian/quota/etcd/storagepb"
+)
+
+var (
+	// exemptUsersRefreshInterval is how often managers re-read the users exempt from quotas from etcd.
+	exemptUsersRefreshInterval = 10 * time.Second
+	// exemptUsersRetryInterval is how long managers wait to re-read the exempt users after a failed
+	// read, serving the last users read meanwhile.
+	exemptUsersRetryInterval = time.Second
 )
 
 type manager struct {
 	qs *storage.QuotaStorage
+
+	// mu guards the fields below.
+	mu sync.Mutex
+	// exemptUsers maps the kinds of enabled global quotas to the users they exempt.
+	exemptUsers     map[quota.Kind]map[string]bool
+	exemptUsersRead time.Time
+	// exemptUsersErr is the error of the last read of the exempt users, which isn't retried before
+	// exemptUsersRetry.
+	exemptUsersErr   error
+	exemptUsersRetry time.Time
+	// refreshing is closed when the read of the exempt users in progress, if any, completes.
+	refreshing chan struct{}
 }
 
 // New returns a new etcd-based quota.Manager.
@@ -68,6 +92,103 @@ func (m *manager) ResetQuota(ctx context.Context, specs []quota.Spec) error {
 	return m.qs.Reset(ctx, configNames(specs))
 }
 
+// ExemptSpecs implements quota.Exempter. Users exempt from a global quota are exempt from it and
+// from their user quota of the same kind.
+func (m *manager) ExemptSpecs(ctx context.Context, user string, specs []quota.Spec) ([]quota.Spec, error) {
+	exemptUsers, err := m.getExemptUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var exempt []quota.Spec
+	for _, spec := range specs {
+		switch {
+		case spec.Group == quota.Global, spec.Group == quota.User && spec.User == user:
+			if exemptUsers[spec.Kind][user] {
+				exempt = append(exempt, spec)
+			}
+		}
+	}
+	return exempt, nil
+}
+
+// getExemptUsers returns the users exempt from global quotas, re-reading them from etcd if they're
+// older than exemptUsersRefreshInterval. Only one read is made at a time, and m.mu isn't held during
+// it: other callers, and those of reads that fail, are served the last users read, if any.
+func (m *manager) getExemptUsers(ctx context.Context) (map[quota.Kind]map[string]bool, error) {
+	for {
+		m.mu.Lock()
+		now := time.Now()
+		if m.refreshing == nil && now.After(m.exemptUsersRetry) &&
+			(m.exemptUsers == nil || now.Sub(m.exemptUsersRead) >= exemptUsersRefreshInterval) {
+			break // Still holding m.mu, this caller reads the users below.
+		}
+		exemptUsers, err, refreshing := m.exemptUsers, m.exemptUsersErr, m.refreshing
+		m.mu.Unlock()
+		switch {
+		case exemptUsers != nil:
+			return exemptUsers, nil
+		case refreshing == nil:
+			return nil, err
+		}
+		// The first read is in progress, so there's nothing to serve until it completes.
+		select {
+		case <-refreshing:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	refreshing := make(chan struct{})
+	m.refreshing = refreshing
+	m.mu.Unlock()
+
+	exemptUsers, err := m.readExemptUsers(ctx)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.refreshing = nil
+	close(refreshing)
+	if err != nil {
+		// Failures due to the caller's context don't say anything about etcd, so don't back off.
+		if ctx.Err() == nil {
+			m.exemptUsersErr = err
+			m.exemptUsersRetry = time.Now().Add(exemptUsersRetryInterval)
+		}
+		if m.exemptUsers == nil {
+			return nil, err
+		}
+		glog.Warningf("Failed to read quota exempt users, serving those read at %v: %v", m.exemptUsersRead, err)
+		return m.exemptUsers, nil
+	}
+	m.exemptUsers = exemptUsers
+	m.exemptUsersRead = time.Now()
+	m.exemptUsersErr = nil
+	m.exemptUsersRetry = time.Time{}
+	return exemptUsers, nil
+}
+
+// readExemptUsers reads the users exempt from global quotas from etcd.
+func (m *manager) readExemptUsers(ctx context.Context) (map[quota.Kind]map[string]bool, error) {
+	cfgs, err := m.qs.Configs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	exemptUsers := make(map[quota.Kind]map[string]bool)
+	for _, kind := range []quota.Kind{quota.Read, quota.Write} {
+		name := configName(quota.Spec{Group: quota.Global, Kind: kind})
+		for _, cfg := range cfgs.Configs {
+			if cfg.Name != name || cfg.State != storagepb.Config_ENABLED {
+				continue
+			}
+			users := make(map[string]bool)
+			for _, user := range cfg.ExemptUsers {
+				users[user] = true
+			}
+			exemptUsers[kind] = users
+		}
+	}
+	return exemptUsers, nil
+}
+
 func configNames(specs []quota.Spec) []string {
 	names := make([]string, 0, len(specs))
 	for _, spec := range specs {
@@ -16,12 +16,15 @@ package etcdqm
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/coreos/etcd/clientv3"
+	"github.com/golang/protobuf/proto"
 	"github.com/google/trillian/quota"
 	"github.com/google/trillian/quota/etcd/storage"
 	"github.com/google/trillian/quota/etcd/storagepb"
@@ -291,6 +294,121 @@ func TestManager_ResetQuota(t *testing.T) {
 	}
 }
 
+func TestManager_ExemptSpecs(t *testing.T) {
+	defer func(interval time.Duration) {
+		exemptUsersRefreshInterval = interval
+	}(exemptUsersRefreshInterval)
+	exemptUsersRefreshInterval = 0
+
+	exemptCfgs := proto.Clone(cfgs).(*storagepb.Configs)
+	exemptCfgs.Configs[0].ExemptUsers = []string{"mirror"}
+
+	mirrorWriteSpec := quota.Spec{Group: quota.User, Kind: quota.Write, User: "mirror"}
+	mirrorReadSpec := quota.Spec{Group: quota.User, Kind: quota.Read, User: "mirror"}
+	llamaWriteSpec := quota.Spec{Group: quota.User, Kind: quota.Write, User: userID}
+	tests := []struct {
+		desc  string
+		cfgs  *storagepb.Configs
+		user  string
+		specs []quota.Spec
+		want  []quota.Spec
+	}{
+		{
+			desc:  "noExemptions",
+			cfgs:  cfgs,
+			user:  "mirror",
+			specs: []quota.Spec{mirrorWriteSpec, treeWriteSpec, globalWriteSpec},
+		},
+		{
+			desc:  "exemptUser",
+			cfgs:  exemptCfgs,
+			user:  "mirror",
+			specs: []quota.Spec{mirrorWriteSpec, treeWriteSpec, globalWriteSpec},
+			want:  []quota.Spec{mirrorWriteSpec, globalWriteSpec},
+		},
+		{
+			desc:  "otherKind",
+			cfgs:  exemptCfgs,
+			user:  "mirror",
+			specs: []quota.Spec{mirrorReadSpec, {Group: quota.Global, Kind: quota.Read}},
+		},
+		{
+			desc:  "otherUser",
+			cfgs:  exemptCfgs,
+			user:  userID,
+			specs: []quota.Spec{llamaWriteSpec, treeWriteSpec, globalWriteSpec},
+		},
+	}
+
+	qs := &storage.QuotaStorage{Client: client}
+	qm := New(client).(quota.Exempter)
+
+	ctx := context.Background()
+	for _, test := range tests {
+		if err := reset(ctx, qs, test.cfgs); err != nil {
+			t.Fatalf("%v: reset: %v", test.desc, err)
+		}
+		got, err := qm.ExemptSpecs(ctx, test.user, test.specs)
+		if err != nil {
+			t.Errorf("%v: ExemptSpecs() returned err = %v", test.desc, err)
+			continue
+		}
+		if diff := pretty.Compare(got, test.want); diff != "" {
+			t.Errorf("%v: ExemptSpecs() diff (-got +want):\n%v", test.desc, diff)
+		}
+	}
+}
+
+func TestManager_ExemptSpecsReadErrors(t *testing.T) {
+	defer func(refresh, retry time.Duration) {
+		exemptUsersRefreshInterval = refresh
+		exemptUsersRetryInterval = retry
+	}(exemptUsersRefreshInterval, exemptUsersRetryInterval)
+	exemptUsersRefreshInterval = 0
+	exemptUsersRetryInterval = time.Hour
+
+	exemptCfgs := proto.Clone(cfgs).(*storagepb.Configs)
+	exemptCfgs.Configs[0].ExemptUsers = []string{"mirror"}
+	mirrorWriteSpec := quota.Spec{Group: quota.User, Kind: quota.Write, User: "mirror"}
+	specs := []quota.Spec{mirrorWriteSpec, globalWriteSpec}
+
+	ctx := context.Background()
+	if err := reset(ctx, &storage.QuotaStorage{Client: client}, exemptCfgs); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+
+	// A manager that never read its exempt users fails with the read.
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	qm := New(client).(quota.Exempter)
+	if got, err := qm.ExemptSpecs(cancelled, "mirror", specs); err == nil {
+		t.Errorf("ExemptSpecs(cancelled) = %v, want err", got)
+	}
+
+	// Once it has, failed reads are served the users last read.
+	if _, err := qm.ExemptSpecs(ctx, "mirror", specs); err != nil {
+		t.Fatalf("ExemptSpecs() returned err = %v", err)
+	}
+	got, err := qm.ExemptSpecs(cancelled, "mirror", specs)
+	if err != nil {
+		t.Fatalf("ExemptSpecs(cancelled) returned err = %v", err)
+	}
+	if diff := pretty.Compare(got, specs); diff != "" {
+		t.Errorf("ExemptSpecs(cancelled) diff (-got +want):\n%v", diff)
+	}
+
+	// Failed reads with an etcd error aren't retried before exemptUsersRetryInterval.
+	m := qm.(*manager)
+	m.mu.Lock()
+	m.exemptUsers = nil
+	m.exemptUsersErr = errors.New("etcd unavailable")
+	m.exemptUsersRetry = time.Now().Add(exemptUsersRetryInterval)
+	m.mu.Unlock()
+	if _, err := qm.ExemptSpecs(ctx, "mirror", specs); err != m.exemptUsersErr {
+		t.Errorf("ExemptSpecs() returned err = %v, want %v", err, m.exemptUsersErr)
+	}
+}
+
 func TestConfigName(t *testing.T) {
 	tests := []struct {
 		spec quota.Spec
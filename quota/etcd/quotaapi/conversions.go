@@ -27,10 +27,11 @@ const (
 	maxTokensPath       = "max_tokens"
 	sequencingBasedPath = "sequencing_based"
 	timeBasedPath       = "time_based"
+	exemptUsersPath     = "exempt_users"
 )
 
 var (
-	commonMask          = &field_mask.FieldMask{Paths: []string{statePath, maxTokensPath}}
+	commonMask          = &field_mask.FieldMask{Paths: []string{statePath, maxTokensPath, exemptUsersPath}}
 	sequencingBasedMask = &field_mask.FieldMask{Paths: append(commonMask.Paths, sequencingBasedPath)}
 	timeBasedMask       = &field_mask.FieldMask{Paths: append(commonMask.Paths, timeBasedPath)}
 
@@ -46,7 +47,7 @@ func validateMask(mask *field_mask.FieldMask) error {
 	timeBasedFound := false
 	for _, path := range mask.Paths {
 		switch path {
-		case statePath, maxTokensPath:
+		case statePath, maxTokensPath, exemptUsersPath:
 			// OK
 		case sequencingBasedPath:
 			if timeBasedFound {
@@ -75,6 +76,8 @@ func applyMask(src *quotapb.Config, dest *storagepb.Config, mask *field_mask.Fie
 			dest.State = storagepb.Config_State(storagepb.Config_State_value[src.State.String()])
 		case maxTokensPath:
 			dest.MaxTokens = src.MaxTokens
+		case exemptUsersPath:
+			dest.ExemptUsers = append([]string(nil), src.ExemptUsers...)
 		case sequencingBasedPath:
 			if src.GetSequencingBased() == nil {
 				dest.ReplenishmentStrategy = nil
@@ -105,6 +108,9 @@ func convertToAPI(src *storagepb.Config) *quotapb.Config {
 		State:     quotapb.Config_State(quotapb.Config_State_value[src.State.String()]),
 		MaxTokens: src.MaxTokens,
 	}
+	if len(src.ExemptUsers) > 0 {
+		dest.ExemptUsers = append([]string(nil), src.ExemptUsers...)
+	}
 	sb := src.GetSequencingBased()
 	tb := src.GetTimeBased()
 	switch {
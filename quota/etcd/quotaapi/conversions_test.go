@@ -174,6 +174,13 @@ func TestApplyMask(t *testing.T) {
 			mask: &field_mask.FieldMask{Paths: []string{timeBasedPath}},
 			want: &wantClearTime,
 		},
+		{
+			desc: "exemptUsers",
+			src:  &quotapb.Config{ExemptUsers: []string{"mirror", "backfill"}},
+			dest: &storagepb.Config{ExemptUsers: []string{"llama"}},
+			mask: &field_mask.FieldMask{Paths: []string{exemptUsersPath}},
+			want: &storagepb.Config{ExemptUsers: []string{"mirror", "backfill"}},
+		},
 	}
 	for _, test := range tests {
 		applyMask(test.src, test.dest, test.mask)
@@ -199,6 +206,21 @@ func TestConvert_APIAndStorage(t *testing.T) {
 			api:     apiTimeConfig,
 			storage: storageTimeConfig,
 		},
+		{
+			desc: "exemptUsers",
+			api: &quotapb.Config{
+				Name:        "quotas/global/read/config",
+				State:       quotapb.Config_ENABLED,
+				MaxTokens:   10,
+				ExemptUsers: []string{"mirror", "backfill"},
+			},
+			storage: &storagepb.Config{
+				Name:        "quotas/global/read/config",
+				State:       storagepb.Config_ENABLED,
+				MaxTokens:   10,
+				ExemptUsers: []string{"mirror", "backfill"},
+			},
+		},
 		{
 			desc:    "zeroed",
 			api:     &quotapb.Config{},
@@ -116,6 +116,10 @@ func (ListConfigsRequest_ListView) EnumDescriptor() ([]byte, []int) {
 // A quota may be disabled or removed at any time. The effect is the same: a
 // disabled or non-existing quota is considered infinite by the quota system.
 // (Disabling is handy if you plan to re-enable a quota later on.)
+//
+// Global quotas may exempt users, such as mirroring or backfill jobs, from
+// themselves and from the users' own quotas of the same kind. Requests made by
+// exempt users still consume tokens from tree quotas.
 type Config struct {
 	// Name of the config, eg, “quotas/trees/1234/read/config”.
 	// Readonly.
@@ -135,6 +139,11 @@ type Config struct {
 	// have "infinite" tokens.
 	// Readonly.
 	CurrentTokens int64 `protobuf:"varint,6,opt,name=current_tokens,json=currentTokens" json:"current_tokens,omitempty"`
+	// Users exempt from the quota, and from their user quotas of the same kind.
+	// Tokens not acquired due to exemptions are recorded by the
+	// quota_exempted_tokens metric.
+	// Only global quotas may exempt users.
+	ExemptUsers []string `protobuf:"bytes,7,rep,name=exempt_users,json=exemptUsers" json:"exempt_users,omitempty"`
 }
 
 func (m *Config) Reset()                    { *m = Config{} }
@@ -203,6 +212,13 @@ func (m *Config) GetCurrentTokens() int64 {
 	return 0
 }
 
+func (m *Config) GetExemptUsers() []string {
+	if m != nil {
+		return m.ExemptUsers
+	}
+	return nil
+}
+
 // XXX_OneofFuncs is for the internal use of the proto package.
 func (*Config) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
 	return _Config_OneofMarshaler, _Config_OneofUnmarshaler, _Config_OneofSizer, []interface{}{
@@ -729,53 +745,54 @@ var _Quota_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("quotapb.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 759 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x54, 0xdd, 0x4e, 0x13, 0x41,
-	0x18, 0x65, 0xfb, 0x47, 0xfb, 0x95, 0x9f, 0x32, 0x45, 0x58, 0x0a, 0x86, 0xcd, 0x46, 0xb4, 0x94,
-	0xa4, 0x0d, 0xf5, 0xc6, 0x08, 0x26, 0xd2, 0x52, 0xb0, 0xb1, 0x94, 0xb8, 0x2d, 0x7a, 0xb9, 0x99,
-	0xb6, 0x43, 0x5d, 0xe9, 0xee, 0x96, 0x9d, 0x29, 0x3f, 0x31, 0x5e, 0x68, 0xe2, 0x13, 0x18, 0xdf,
-	0xc3, 0x77, 0xf1, 0x15, 0x7c, 0x03, 0x5f, 0xc0, 0xec, 0xcc, 0xee, 0x5a, 0xda, 0x22, 0x5c, 0x78,
-	0xb7, 0xf3, 0x9d, 0xb3, 0xe7, 0x3b, 0xdf, 0xcc, 0x99, 0x81, 0xd9, 0xf3, 0x81, 0xcd, 0x70, 0xbf,
-	0x95, 0xef, 0x3b, 0x36, 0xb3, 0xd1, 0xb4, 0xb7, 0xcc, 0xac, 0x75, 0x6d, 0xbb, 0xdb, 0x23, 0x05,
-	0xdc, 0x37, 0x0a, 0xd8, 0xb2, 0x6c, 0x86, 0x99, 0x61, 0x5b, 0x54, 0xd0, 0x32, 0xab, 0x1e, 0xca,
-	0x57, 0xad, 0xc1, 0x69, 0x81, 0x98, 0x7d, 0x76, 0xed, 0x81, 0xca, 0x28, 0x78, 0x6a, 0x90, 0x5e,
-	0x47, 0x37, 0x31, 0x3d, 0x13, 0x0c, 0xf5, 0x77, 0x08, 0x62, 0x65, 0xdb, 0x3a, 0x35, 0xba, 0x08,
-	0x41, 0xc4, 0xc2, 0x26, 0x91, 0x25, 0x45, 0xca, 0x26, 0x34, 0xfe, 0x8d, 0xb6, 0x20, 0x4a, 0x19,
-	0x66, 0x44, 0x0e, 0x29, 0x52, 0x76, 0xae, 0xf8, 0x20, 0xef, 0x7b, 0x14, 0xff, 0xe4, 0x1b, 0x2e,
-	0xa8, 0x09, 0x0e, 0x7a, 0x08, 0x60, 0xe2, 0x2b, 0x9d, 0xd9, 0x67, 0xc4, 0xa2, 0x72, 0x58, 0x91,
-	0xb2, 0x61, 0x2d, 0x61, 0xe2, 0xab, 0x26, 0x2f, 0xa0, 0x23, 0x48, 0x51, 0x72, 0x3e, 0x20, 0x56,
-	0xdb, 0xb0, 0xba, 0x7a, 0x0b, 0x53, 0xd2, 0x91, 0x23, 0x8a, 0x94, 0x4d, 0x16, 0x95, 0x40, 0xb6,
-	0x11, 0x10, 0x4a, 0x2e, 0xde, 0x60, 0x0e, 0x66, 0xa4, 0x7b, 0xfd, 0x6a, 0x4a, 0x9b, 0xa7, 0x37,
-	0x21, 0xb4, 0x03, 0xc0, 0x0c, 0x93, 0x78, 0x42, 0x51, 0x2e, 0x94, 0x09, 0x84, 0x9a, 0x86, 0x49,
-	0x46, 0x25, 0x12, 0xcc, 0x2f, 0xa2, 0x0d, 0x98, 0x6b, 0x0f, 0x1c, 0x87, 0x58, 0xcc, 0xb7, 0x1b,
-	0xe3, 0x76, 0x67, 0xbd, 0xaa, 0xb0, 0xac, 0xee, 0x42, 0x94, 0x4f, 0x88, 0x64, 0x58, 0x3c, 0xa9,
-	0xbf, 0xae, 0x1f, 0xbf, 0xab, 0xeb, 0xe5, 0xe3, 0xfa, 0x41, 0xf5, 0x50, 0x6f, 0x34, 0xf7, 0x9a,
-	0x95, 0xd4, 0x14, 0x4a, 0xc2, 0x74, 0xa5, 0xbe, 0x57, 0xaa, 0x55, 0xf6, 0x53, 0x12, 0x9a, 0x81,
-	0xf8, 0x7e, 0xb5, 0x21, 0x56, 0xa1, 0x92, 0x0c, 0x4b, 0x0e, 0xe9, 0xf7, 0x88, 0x65, 0xd0, 0xf7,
-	0xa6, 0xdb, 0x8a, 0x7a, 0x5e, 0xd4, 0x15, 0x58, 0xbe, 0x65, 0x52, 0xf5, 0xb3, 0x04, 0x0b, 0x63,
-	0xe6, 0x51, 0x1e, 0xd2, 0xc2, 0xa7, 0xce, 0x6c, 0x3d, 0x10, 0xe5, 0x47, 0x15, 0xd6, 0x16, 0x04,
-	0xd4, 0xb4, 0x35, 0x1f, 0x40, 0xbb, 0x90, 0x09, 0x58, 0xba, 0x61, 0x31, 0xe2, 0x5c, 0xe0, 0x9e,
-	0x4e, 0x49, 0xdb, 0xb6, 0x3a, 0x94, 0x1f, 0x66, 0x58, 0x93, 0x03, 0x46, 0xd5, 0x23, 0x34, 0x04,
-	0xae, 0x6a, 0x90, 0x2e, 0x3b, 0x04, 0x33, 0x22, 0x4e, 0x59, 0x73, 0xad, 0x52, 0x36, 0x31, 0x20,
-	0x4f, 0x20, 0xd6, 0xe6, 0x24, 0x2e, 0x9a, 0x2c, 0xce, 0x8f, 0x24, 0x44, 0xf3, 0x60, 0x75, 0x13,
-	0xd2, 0xfb, 0xa4, 0x47, 0xee, 0xa1, 0xa9, 0x3e, 0x86, 0xd4, 0x21, 0x61, 0x77, 0xf3, 0xbe, 0x4a,
-	0x80, 0x6a, 0x06, 0xf5, 0x98, 0xd4, 0xa7, 0x2e, 0x42, 0xd4, 0x85, 0xa9, 0x2c, 0x29, 0xe1, 0x6c,
-	0x42, 0x13, 0x0b, 0xf4, 0x0c, 0x22, 0x17, 0x06, 0xb9, 0xf4, 0x82, 0xfc, 0x28, 0xb0, 0x39, 0x2e,
-	0xc0, 0x4b, 0x6f, 0x0d, 0x72, 0xa9, 0xf1, 0x3f, 0xd4, 0x75, 0x88, 0xfb, 0x15, 0x94, 0x80, 0x68,
-	0x69, 0xaf, 0x51, 0x2d, 0xa7, 0xa6, 0x50, 0x1c, 0x22, 0x07, 0x27, 0xb5, 0x5a, 0x4a, 0x52, 0x5f,
-	0x42, 0xfa, 0x86, 0x0a, 0xed, 0xdb, 0x16, 0x25, 0x68, 0x13, 0xa6, 0xc5, 0xec, 0xc2, 0xc9, 0x84,
-	0xbd, 0xf1, 0x71, 0xf5, 0x87, 0x04, 0xe9, 0x93, 0x7e, 0xe7, 0xbf, 0xee, 0x38, 0xda, 0x81, 0xe4,
-	0x80, 0x6b, 0xf2, 0xfb, 0xce, 0xef, 0xa3, 0x7b, 0x43, 0xc4, 0x93, 0x90, 0xf7, 0x9f, 0x84, 0xfc,
-	0x81, 0xfb, 0x24, 0x1c, 0x61, 0x7a, 0xa6, 0x81, 0xa0, 0xbb, 0xdf, 0x68, 0x1d, 0x92, 0x0e, 0xa1,
-	0x84, 0xe9, 0x5c, 0x9c, 0xdf, 0xd3, 0xb8, 0x06, 0xbc, 0xf4, 0xc6, 0xad, 0x14, 0xbf, 0x47, 0x20,
-	0xca, 0xbf, 0xd0, 0x07, 0x98, 0x19, 0x4e, 0x0b, 0x5a, 0xfb, 0x6b, 0x68, 0x3c, 0x44, 0x99, 0x51,
-	0xbb, 0xea, 0xd6, 0x97, 0x9f, 0xbf, 0xbe, 0x85, 0x36, 0x54, 0xa5, 0x70, 0xb1, 0xdd, 0x22, 0x0c,
-	0x6f, 0x17, 0x3e, 0xba, 0x73, 0xbe, 0xe0, 0x34, 0x5a, 0xc8, 0xe5, 0x0a, 0x62, 0x9e, 0x4f, 0xcf,
-	0xa5, 0x1c, 0xb2, 0x60, 0x66, 0x38, 0x45, 0x43, 0xbd, 0x26, 0x84, 0x2b, 0xb3, 0x34, 0x36, 0x6c,
-	0xc5, 0x7d, 0x1c, 0xd5, 0x2c, 0x6f, 0xa9, 0xe6, 0xee, 0x6c, 0x89, 0x30, 0x24, 0x82, 0x28, 0xa2,
-	0x95, 0xa0, 0xd9, 0x68, 0x3c, 0xc7, 0xa7, 0xf2, 0x5a, 0xa0, 0xfb, 0xb4, 0x48, 0x0e, 0xa5, 0x07,
-	0xad, 0xfe, 0x23, 0x99, 0x99, 0xb5, 0xc9, 0xa0, 0x08, 0x9c, 0xba, 0xcc, 0x7b, 0x2e, 0xa0, 0xf9,
-	0xa0, 0xa7, 0xe8, 0xe6, 0x9e, 0xd0, 0x70, 0xba, 0x86, 0x76, 0x6d, 0x42, 0xe8, 0x6e, 0x3d, 0xa1,
-	0xe2, 0x7d, 0x4e, 0xa8, 0x15, 0xe3, 0x7b, 0xfd, 0xf4, 0x4f, 0x00, 0x00, 0x00, 0xff, 0xff, 0xa0,
-	0x60, 0xf5, 0x62, 0xce, 0x06, 0x00, 0x00,
+	// 774 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x54, 0xdd, 0x4e, 0xdb, 0x48,
+	0x14, 0xc6, 0xe4, 0xff, 0x24, 0x40, 0x98, 0xb0, 0x60, 0x02, 0x2b, 0xbc, 0xd6, 0xb2, 0x1b, 0x82,
+	0x94, 0x88, 0xec, 0xcd, 0x6a, 0x61, 0xa5, 0x92, 0x10, 0x68, 0xd4, 0x10, 0x54, 0x27, 0x69, 0x2f,
+	0xad, 0x49, 0x32, 0xa4, 0x2e, 0xb1, 0x1d, 0x3c, 0x13, 0x7e, 0x54, 0xf5, 0xa2, 0x95, 0xfa, 0x04,
+	0x55, 0xfb, 0x1c, 0x7d, 0x97, 0xbe, 0x42, 0x1f, 0xa4, 0xf2, 0x8c, 0xed, 0x86, 0x24, 0x05, 0x2e,
+	0x7a, 0x37, 0x73, 0xbe, 0xcf, 0xdf, 0xf9, 0x99, 0xcf, 0x07, 0x16, 0x2e, 0x47, 0x36, 0xc3, 0xc3,
+	0x4e, 0x61, 0xe8, 0xd8, 0xcc, 0x46, 0x31, 0xef, 0x9a, 0xdd, 0xec, 0xdb, 0x76, 0x7f, 0x40, 0x8a,
+	0x78, 0x68, 0x14, 0xb1, 0x65, 0xd9, 0x0c, 0x33, 0xc3, 0xb6, 0xa8, 0xa0, 0x65, 0x37, 0x3c, 0x94,
+	0xdf, 0x3a, 0xa3, 0xf3, 0x22, 0x31, 0x87, 0xec, 0xd6, 0x03, 0x95, 0x49, 0xf0, 0xdc, 0x20, 0x83,
+	0x9e, 0x6e, 0x62, 0x7a, 0x21, 0x18, 0xea, 0xe7, 0x10, 0x44, 0x2b, 0xb6, 0x75, 0x6e, 0xf4, 0x11,
+	0x82, 0xb0, 0x85, 0x4d, 0x22, 0x4b, 0x8a, 0x94, 0x4b, 0x68, 0xfc, 0x8c, 0x76, 0x21, 0x42, 0x19,
+	0x66, 0x44, 0x9e, 0x57, 0xa4, 0xdc, 0x62, 0xe9, 0xb7, 0x82, 0x5f, 0xa3, 0xf8, 0xa6, 0xd0, 0x74,
+	0x41, 0x4d, 0x70, 0xd0, 0xef, 0x00, 0x26, 0xbe, 0xd1, 0x99, 0x7d, 0x41, 0x2c, 0x2a, 0x87, 0x14,
+	0x29, 0x17, 0xd2, 0x12, 0x26, 0xbe, 0x69, 0xf1, 0x00, 0x3a, 0x85, 0x34, 0x25, 0x97, 0x23, 0x62,
+	0x75, 0x0d, 0xab, 0xaf, 0x77, 0x30, 0x25, 0x3d, 0x39, 0xac, 0x48, 0xb9, 0x64, 0x49, 0x09, 0x64,
+	0x9b, 0x01, 0xa1, 0xec, 0xe2, 0x4d, 0xe6, 0x60, 0x46, 0xfa, 0xb7, 0x4f, 0xe7, 0xb4, 0x25, 0x7a,
+	0x17, 0x42, 0xfb, 0x00, 0xcc, 0x30, 0x89, 0x27, 0x14, 0xe1, 0x42, 0xd9, 0x40, 0xa8, 0x65, 0x98,
+	0x64, 0x52, 0x22, 0xc1, 0xfc, 0x20, 0xda, 0x86, 0xc5, 0xee, 0xc8, 0x71, 0x88, 0xc5, 0xfc, 0x72,
+	0xa3, 0xbc, 0xdc, 0x05, 0x2f, 0xea, 0x95, 0xfc, 0x07, 0xa4, 0xc8, 0x8d, 0x3b, 0x50, 0x7d, 0x44,
+	0x89, 0x43, 0xe5, 0x98, 0x12, 0xca, 0x25, 0xb4, 0xa4, 0x88, 0xb5, 0xdd, 0x90, 0x7a, 0x00, 0x11,
+	0x3e, 0x04, 0x24, 0xc3, 0x4a, 0xbb, 0xf1, 0xac, 0x71, 0xf6, 0xb2, 0xa1, 0x57, 0xce, 0x1a, 0xc7,
+	0xb5, 0x13, 0xbd, 0xd9, 0x3a, 0x6c, 0x55, 0xd3, 0x73, 0x28, 0x09, 0xb1, 0x6a, 0xe3, 0xb0, 0x5c,
+	0xaf, 0x1e, 0xa5, 0x25, 0x94, 0x82, 0xf8, 0x51, 0xad, 0x29, 0x6e, 0xf3, 0x65, 0x19, 0x56, 0x1d,
+	0x32, 0x1c, 0x10, 0xcb, 0xa0, 0xaf, 0x4c, 0xb7, 0x1a, 0xea, 0x95, 0xab, 0xae, 0xc3, 0xda, 0x4f,
+	0x86, 0xa1, 0xbe, 0x93, 0x60, 0x79, 0xaa, 0x3f, 0x54, 0x80, 0x8c, 0x68, 0x45, 0x67, 0xb6, 0x1e,
+	0x88, 0xf2, 0xd7, 0x0c, 0x69, 0xcb, 0x02, 0x6a, 0xd9, 0x9a, 0x0f, 0xa0, 0x03, 0xc8, 0x06, 0x2c,
+	0xdd, 0xb0, 0x18, 0x71, 0xae, 0xf0, 0x40, 0xa7, 0xa4, 0x6b, 0x5b, 0x3d, 0xca, 0xdf, 0x3b, 0xa4,
+	0xc9, 0x01, 0xa3, 0xe6, 0x11, 0x9a, 0x02, 0x57, 0x35, 0xc8, 0x54, 0x1c, 0x82, 0x19, 0x11, 0x46,
+	0xd0, 0xdc, 0x52, 0x29, 0x9b, 0xe9, 0xa1, 0xbf, 0x21, 0xda, 0xe5, 0x24, 0x2e, 0x9a, 0x2c, 0x2d,
+	0x4d, 0x98, 0x48, 0xf3, 0x60, 0x75, 0x07, 0x32, 0x47, 0x64, 0x40, 0x1e, 0xa1, 0xa9, 0xfe, 0x05,
+	0xe9, 0x13, 0xc2, 0x1e, 0xe6, 0x7d, 0x90, 0x00, 0xd5, 0x0d, 0xea, 0x31, 0xa9, 0x4f, 0x5d, 0x81,
+	0x88, 0x0b, 0x53, 0x59, 0xe2, 0x0f, 0x2a, 0x2e, 0xe8, 0x5f, 0x08, 0x5f, 0x19, 0xe4, 0xda, 0xf3,
+	0xfa, 0x9f, 0x41, 0x99, 0xd3, 0x02, 0x3c, 0xf4, 0xc2, 0x20, 0xd7, 0x1a, 0xff, 0x42, 0xdd, 0x82,
+	0xb8, 0x1f, 0x41, 0x09, 0x88, 0x94, 0x0f, 0x9b, 0xb5, 0x4a, 0x7a, 0x0e, 0xc5, 0x21, 0x7c, 0xdc,
+	0xae, 0xd7, 0xd3, 0x92, 0xfa, 0x04, 0x32, 0x77, 0x54, 0xe8, 0xd0, 0xb6, 0x28, 0x41, 0x3b, 0x10,
+	0x13, 0xbd, 0x8b, 0x4a, 0x66, 0xcc, 0xc6, 0xc7, 0xd5, 0x2f, 0x12, 0x64, 0xda, 0xc3, 0xde, 0x2f,
+	0x9d, 0x38, 0xda, 0x87, 0xe4, 0x88, 0x6b, 0xf2, 0x95, 0x20, 0x87, 0xbc, 0x9f, 0x48, 0x6c, 0x8d,
+	0x82, 0xbf, 0x35, 0x0a, 0xc7, 0xee, 0xd6, 0x38, 0xc5, 0xf4, 0x42, 0x03, 0x41, 0x77, 0xcf, 0x68,
+	0x0b, 0x92, 0x0e, 0xa1, 0x84, 0xe9, 0x5c, 0x9c, 0xff, 0xca, 0x71, 0x0d, 0x78, 0xe8, 0xb9, 0x1b,
+	0x29, 0x7d, 0x0a, 0x43, 0x84, 0x9f, 0xd0, 0x6b, 0x48, 0x8d, 0xbb, 0x05, 0x6d, 0xfe, 0x28, 0x68,
+	0xda, 0x44, 0xd9, 0xc9, 0x72, 0xd5, 0xdd, 0xf7, 0x5f, 0xbf, 0x7d, 0x9c, 0xdf, 0xfe, 0x4f, 0xca,
+	0xab, 0x4a, 0xf1, 0x6a, 0xaf, 0x43, 0x18, 0xde, 0x2b, 0xbe, 0x71, 0x5b, 0xfd, 0x9f, 0x33, 0x69,
+	0x31, 0x9f, 0x2f, 0x8a, 0x96, 0xde, 0x22, 0x0b, 0x52, 0xe3, 0x2e, 0x1a, 0xcb, 0x35, 0xc3, 0x5c,
+	0xd9, 0xd5, 0xa9, 0x66, 0xab, 0xee, 0xfe, 0x54, 0x73, 0x3c, 0xa5, 0x9a, 0x7f, 0x38, 0x1f, 0x86,
+	0x44, 0x60, 0x45, 0xb4, 0x1e, 0x24, 0x9b, 0xb4, 0xe7, 0x74, 0x57, 0x5e, 0x0a, 0xf4, 0x98, 0x14,
+	0xc9, 0x31, 0xf7, 0xa0, 0x8d, 0x7b, 0x9c, 0x99, 0xdd, 0x9c, 0x0d, 0x0a, 0xc3, 0xa9, 0x6b, 0x3c,
+	0xe7, 0x32, 0x5a, 0x0a, 0x72, 0x8a, 0x6c, 0xee, 0x0b, 0x8d, 0xbb, 0x6b, 0x6c, 0x6a, 0x33, 0x4c,
+	0x77, 0xdf, 0x0b, 0x95, 0x1e, 0x6c, 0xa7, 0x13, 0xe5, 0xb3, 0xfe, 0xe7, 0xfb, 0x00, 0x08, 0x87,
+	0x01, 0x57, 0xf1, 0x06, 0x00, 0x00,
 }
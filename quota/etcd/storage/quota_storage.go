@@ -201,6 +201,19 @@ func validate(cfgs *storagepb.Configs) error {
 		default:
 			return status.Errorf(codes.InvalidArgument, "unsupported replenishment strategy (Configs[%v].ReplenishmentStrategy = %T)", i, s)
 		}
+		if len(cfg.ExemptUsers) > 0 && !globalPattern.MatchString(cfg.Name) {
+			return status.Errorf(codes.InvalidArgument, "only global quotas may exempt users (Configs[%v].ExemptUsers)", i)
+		}
+		exempt := make(map[string]bool)
+		for j, user := range cfg.ExemptUsers {
+			switch {
+			case user == "" || strings.Contains(user, "/"):
+				return status.Errorf(codes.InvalidArgument, "exempt user malformed (Configs[%v].ExemptUsers[%v] = %q)", i, j, user)
+			case exempt[user]:
+				return status.Errorf(codes.InvalidArgument, "duplicate exempt user found at Configs[%v].ExemptUsers[%v]", i, j)
+			}
+			exempt[user] = true
+		}
 		if names[cfg.Name] {
 			return status.Errorf(codes.InvalidArgument, "duplicate config name found at Configs[%v].Name", i)
 		}
@@ -305,6 +305,15 @@ func TestQuotaStorage_UpdateConfigsErrors(t *testing.T) {
 	sequencingBasedReadQuota2.Configs[0].Name = "quotas/trees/1234/read/config"
 	sequencingBasedReadQuota2.Configs[0].ReplenishmentStrategy = sequencingBasedStrategy
 
+	exemptUsersUserQuota := deepCopy(&storagepb.Configs{Configs: []*storagepb.Config{userRead}})
+	exemptUsersUserQuota.Configs[0].ExemptUsers = []string{"mirror"}
+
+	emptyExemptUser := deepCopy(globalWriteCfgs)
+	emptyExemptUser.Configs[0].ExemptUsers = []string{"mirror", ""}
+
+	duplicateExemptUsers := deepCopy(globalWriteCfgs)
+	duplicateExemptUsers.Configs[0].ExemptUsers = []string{"mirror", "backfill", "mirror"}
+
 	tests := []struct {
 		desc    string
 		update  func(*storagepb.Configs)
@@ -386,6 +395,21 @@ func TestQuotaStorage_UpdateConfigsErrors(t *testing.T) {
 			update:  updater(sequencingBasedReadQuota2),
 			wantErr: "cannot use sequencing-based replenishment",
 		},
+		{
+			desc:    "exemptUsersUserQuota",
+			update:  updater(exemptUsersUserQuota),
+			wantErr: "only global quotas may exempt users",
+		},
+		{
+			desc:    "emptyExemptUser",
+			update:  updater(emptyExemptUser),
+			wantErr: "exempt user malformed",
+		},
+		{
+			desc:    "duplicateExemptUsers",
+			update:  updater(duplicateExemptUsers),
+			wantErr: "duplicate exempt user",
+		},
 	}
 
 	ctx := context.Background()
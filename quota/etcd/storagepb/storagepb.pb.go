@@ -123,6 +123,8 @@ type Config struct {
 	//	*Config_SequencingBased
 	//	*Config_TimeBased
 	ReplenishmentStrategy isConfig_ReplenishmentStrategy `protobuf_oneof:"replenishment_strategy"`
+	// Users exempt from the quota, and from their user quotas of the same kind.
+	ExemptUsers []string `protobuf:"bytes,6,rep,name=exempt_users,json=exemptUsers" json:"exempt_users,omitempty"`
 }
 
 func (m *Config) Reset()                    { *m = Config{} }
@@ -184,6 +186,13 @@ func (m *Config) GetTimeBased() *TimeBasedStrategy {
 	return nil
 }
 
+func (m *Config) GetExemptUsers() []string {
+	if m != nil {
+		return m.ExemptUsers
+	}
+	return nil
+}
+
 // XXX_OneofFuncs is for the internal use of the proto package.
 func (*Config) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
 	return _Config_OneofMarshaler, _Config_OneofUnmarshaler, _Config_OneofSizer, []interface{}{
@@ -306,32 +315,33 @@ func init() {
 func init() { proto.RegisterFile("storagepb.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 422 bytes of a gzipped FileDescriptorProto
+	// 439 bytes of a gzipped FileDescriptorProto
 	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x74, 0x92, 0xc1, 0x6f, 0xd3, 0x30,
-	0x14, 0xc6, 0x97, 0x76, 0x6d, 0xe9, 0x2b, 0x62, 0xad, 0x41, 0x5b, 0x40, 0x4c, 0x2a, 0x39, 0x55,
-	0x42, 0xe4, 0x50, 0x24, 0x4e, 0xe3, 0xb0, 0x6c, 0x81, 0x55, 0x40, 0x2a, 0x25, 0x41, 0x1c, 0x2d,
-	0x37, 0x7b, 0x64, 0x66, 0x89, 0x1d, 0x62, 0x0f, 0x8d, 0x23, 0xff, 0x22, 0x7f, 0x11, 0x8a, 0x9d,
-	0xa6, 0x83, 0x89, 0x9b, 0xfd, 0xde, 0x2f, 0x5f, 0xbe, 0xe7, 0xf7, 0xc1, 0x81, 0xd2, 0xb2, 0x66,
-	0x39, 0x56, 0x1b, 0xbf, 0xaa, 0xa5, 0x96, 0x64, 0xdc, 0x15, 0xbc, 0x6f, 0x30, 0x0c, 0x6e, 0xb2,
-	0x6b, 0xd4, 0xe4, 0x10, 0x86, 0x5a, 0x5e, 0xa3, 0x50, 0xae, 0x33, 0x77, 0x16, 0xfd, 0xb8, 0xbd,
-	0x91, 0x0b, 0x78, 0x51, 0x30, 0xa5, 0x69, 0x8d, 0x55, 0x81, 0x82, 0xab, 0x2b, 0x5a, 0xf2, 0xa2,
-	0xe0, 0x8a, 0x2a, 0x2e, 0x32, 0xa4, 0x58, 0xc9, 0xec, 0xca, 0xed, 0x99, 0x4f, 0x8e, 0x1b, 0x30,
-	0xde, 0x72, 0x9f, 0x0c, 0x96, 0x34, 0x54, 0xd8, 0x40, 0xde, 0x1b, 0x18, 0x9d, 0x49, 0xf1, 0x95,
-	0xe7, 0x8a, 0xbc, 0x84, 0x51, 0x66, 0x8f, 0xae, 0x33, 0xef, 0x2f, 0x26, 0xcb, 0x99, 0xbf, 0x33,
-	0x69, 0xa1, 0x78, 0x4b, 0x78, 0xbf, 0x7b, 0x30, 0xb4, 0x35, 0x42, 0x60, 0x5f, 0xb0, 0x12, 0x8d,
-	0xc5, 0x71, 0x6c, 0xce, 0xe4, 0x15, 0x0c, 0x94, 0x66, 0x1a, 0x8d, 0x89, 0x47, 0xcb, 0xa3, 0x7b,
-	0x4a, 0x7e, 0xd2, 0xb4, 0x63, 0x4b, 0x91, 0x63, 0x80, 0x92, 0xdd, 0xd2, 0x76, 0xd6, 0xbe, 0x31,
-	0x3e, 0x2e, 0xd9, 0x6d, 0x6a, 0xc7, 0x5d, 0xc3, 0x54, 0xe1, 0xf7, 0x1b, 0x14, 0x19, 0x17, 0x39,
-	0xdd, 0x30, 0x85, 0x97, 0xee, 0xfe, 0xdc, 0x59, 0x4c, 0x96, 0xde, 0x1d, 0xe1, 0xa4, 0x43, 0x82,
-	0x86, 0x48, 0x74, 0xcd, 0x34, 0xe6, 0x3f, 0x2f, 0xf6, 0xe2, 0x03, 0xf5, 0x77, 0x8b, 0xbc, 0x05,
-	0xd0, 0xbc, 0xc4, 0x56, 0x6a, 0x60, 0xa4, 0x9e, 0xdf, 0x91, 0x4a, 0x79, 0x89, 0xff, 0x8a, 0x8c,
-	0xf5, 0xb6, 0xe8, 0x9d, 0xc0, 0xc0, 0xd8, 0x27, 0x2e, 0x3c, 0xf9, 0x1c, 0x7d, 0x88, 0xd6, 0x5f,
-	0x22, 0x7a, 0xb6, 0x8e, 0xde, 0xad, 0xde, 0xd3, 0x24, 0x3d, 0x4d, 0xc3, 0xe9, 0x1e, 0x99, 0xc0,
-	0x28, 0x8c, 0x4e, 0x83, 0x8f, 0xe1, 0xf9, 0xd4, 0x21, 0x0f, 0xe1, 0xc1, 0xf9, 0x2a, 0xb1, 0xb7,
-	0x5e, 0xe0, 0xc2, 0x61, 0xb7, 0xb7, 0x12, 0x85, 0xa6, 0xaa, 0xfd, 0x89, 0xf7, 0x14, 0x8e, 0xfe,
-	0x33, 0x84, 0xf7, 0xcb, 0x81, 0xd9, 0x3d, 0x57, 0xc4, 0x87, 0xc7, 0xf6, 0xcd, 0xa8, 0x96, 0xbb,
-	0x30, 0xb4, 0x61, 0x99, 0xd9, 0x56, 0x2a, 0xbb, 0xed, 0x93, 0x13, 0x78, 0xb6, 0x8b, 0x0c, 0x17,
-	0x1a, 0xeb, 0x1f, 0xac, 0xa0, 0x0a, 0x33, 0x29, 0x2e, 0x55, 0x1b, 0x18, 0xb7, 0x23, 0x56, 0x2d,
-	0x90, 0xd8, 0xfe, 0x66, 0x68, 0x92, 0xfa, 0xfa, 0x4f, 0x00, 0x00, 0x00, 0xff, 0xff, 0x8c, 0x4a,
-	0xd5, 0x29, 0xbc, 0x02, 0x00, 0x00,
+	0x14, 0xc6, 0x97, 0x76, 0x4d, 0xc9, 0xeb, 0xc4, 0x5a, 0x83, 0xb6, 0x80, 0x98, 0xd4, 0xe5, 0x54,
+	0x09, 0xd1, 0x43, 0x91, 0x38, 0x8d, 0xc3, 0xba, 0x15, 0x56, 0x01, 0xa9, 0x94, 0x64, 0xe2, 0x68,
+	0xb9, 0xd9, 0xa3, 0x33, 0x4b, 0xec, 0x10, 0xbb, 0xa8, 0x1c, 0xf9, 0xa3, 0xb9, 0xa3, 0xd8, 0x69,
+	0x3a, 0x98, 0x76, 0xb3, 0xbf, 0xf7, 0xcb, 0xa7, 0xef, 0xc5, 0x1f, 0x1c, 0x2a, 0x2d, 0x4b, 0xb6,
+	0xc2, 0x62, 0x39, 0x2e, 0x4a, 0xa9, 0x25, 0xf1, 0x1a, 0x21, 0xf8, 0x0e, 0xee, 0x74, 0x9d, 0xde,
+	0xa1, 0x26, 0x47, 0xe0, 0x6a, 0x79, 0x87, 0x42, 0xf9, 0xce, 0xd0, 0x19, 0xb5, 0xa3, 0xfa, 0x46,
+	0xae, 0xe0, 0x34, 0x63, 0x4a, 0xd3, 0x12, 0x8b, 0x0c, 0x05, 0x57, 0xb7, 0x34, 0xe7, 0x59, 0xc6,
+	0x15, 0x55, 0x5c, 0xa4, 0x48, 0xb1, 0x90, 0xe9, 0xad, 0xdf, 0x32, 0x9f, 0x9c, 0x54, 0x60, 0xb4,
+	0xe5, 0xbe, 0x18, 0x2c, 0xae, 0xa8, 0x59, 0x05, 0x05, 0xef, 0xa0, 0x7b, 0x21, 0xc5, 0x37, 0xbe,
+	0x52, 0xe4, 0x35, 0x74, 0x53, 0x7b, 0xf4, 0x9d, 0x61, 0x7b, 0xd4, 0x9b, 0x0c, 0xc6, 0xbb, 0x90,
+	0x16, 0x8a, 0xb6, 0x44, 0xf0, 0xa7, 0x05, 0xae, 0xd5, 0x08, 0x81, 0x7d, 0xc1, 0x72, 0x34, 0x11,
+	0xbd, 0xc8, 0x9c, 0xc9, 0x1b, 0xe8, 0x28, 0xcd, 0x34, 0x9a, 0x10, 0x4f, 0x27, 0xc7, 0x0f, 0x9c,
+	0xc6, 0x71, 0x35, 0x8e, 0x2c, 0x45, 0x4e, 0x00, 0x72, 0xb6, 0xa1, 0xf5, 0xae, 0x6d, 0x13, 0xdc,
+	0xcb, 0xd9, 0x26, 0xb1, 0xeb, 0x2e, 0xa0, 0xaf, 0xf0, 0xc7, 0x1a, 0x45, 0xca, 0xc5, 0x8a, 0x2e,
+	0x99, 0xc2, 0x1b, 0x7f, 0x7f, 0xe8, 0x8c, 0x7a, 0x93, 0xe0, 0x9e, 0x71, 0xdc, 0x20, 0xd3, 0x8a,
+	0x88, 0x75, 0xc9, 0x34, 0xae, 0x7e, 0x5d, 0xed, 0x45, 0x87, 0xea, 0xdf, 0x11, 0x79, 0x0f, 0xa0,
+	0x79, 0x8e, 0xb5, 0x55, 0xc7, 0x58, 0xbd, 0xba, 0x67, 0x95, 0xf0, 0x1c, 0xff, 0x37, 0xf1, 0xf4,
+	0x56, 0x24, 0xa7, 0x70, 0x80, 0x1b, 0xcc, 0x0b, 0x4d, 0xd7, 0x0a, 0x4b, 0xe5, 0xbb, 0xc3, 0xf6,
+	0xc8, 0x8b, 0x7a, 0x56, 0xbb, 0xae, 0xa4, 0xe0, 0x0c, 0x3a, 0x66, 0x43, 0xe2, 0xc3, 0xf3, 0xeb,
+	0xf0, 0x53, 0xb8, 0xf8, 0x1a, 0xd2, 0x8b, 0x45, 0xf8, 0x61, 0xfe, 0x91, 0xc6, 0xc9, 0x79, 0x32,
+	0xeb, 0xef, 0x91, 0x1e, 0x74, 0x67, 0xe1, 0xf9, 0xf4, 0xf3, 0xec, 0xb2, 0xef, 0x90, 0x03, 0x78,
+	0x72, 0x39, 0x8f, 0xed, 0xad, 0x35, 0xf5, 0xe1, 0xa8, 0x79, 0xda, 0x1c, 0x85, 0xa6, 0xaa, 0xce,
+	0x11, 0xbc, 0x80, 0xe3, 0x47, 0xf6, 0x0c, 0x7e, 0x3b, 0x30, 0x78, 0x10, 0x9c, 0x8c, 0xe1, 0x99,
+	0xfd, 0xad, 0x54, 0xcb, 0x5d, 0x5f, 0xea, 0x3e, 0x0d, 0xec, 0x28, 0x91, 0x4d, 0x41, 0xc8, 0x19,
+	0xbc, 0xdc, 0xb5, 0x8a, 0x0b, 0x8d, 0xe5, 0x4f, 0x96, 0x51, 0x85, 0xa9, 0x14, 0x37, 0xaa, 0xee,
+	0x94, 0xdf, 0x10, 0xf3, 0x1a, 0x88, 0xed, 0x7c, 0xe9, 0x9a, 0x32, 0xbf, 0xfd, 0x3b, 0x00, 0x6e,
+	0x36, 0xba, 0x00, 0xdf, 0x02, 0x00, 0x00,
 }
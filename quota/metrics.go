@@ -37,6 +37,7 @@ type m struct {
 	AcquiredTokens    monitoring.Counter
 	ReturnedTokens    monitoring.Counter
 	ReplenishedTokens monitoring.Counter
+	ExemptedTokens    monitoring.Counter
 }
 
 // IncAcquired increments the AcquiredTokens metric.
@@ -54,6 +55,17 @@ func (m *m) IncReplenished(tokens int, specs []Spec, success bool) {
 	m.add(m.ReplenishedTokens, tokens, specs, success)
 }
 
+// IncExempted increments the ExemptedTokens metric, which records the tokens user didn't acquire
+// from specs due to being exempt from them.
+func (m *m) IncExempted(tokens int, specs []Spec, user string) {
+	if m.ExemptedTokens == nil {
+		return
+	}
+	for _, spec := range specs {
+		m.ExemptedTokens.Add(float64(tokens), spec.Name(), user)
+	}
+}
+
 func (m *m) add(c monitoring.Counter, tokens int, specs []Spec, success bool) {
 	if c == nil {
 		return
@@ -70,5 +82,6 @@ func InitMetrics(mf monitoring.MetricFactory) {
 		Metrics.AcquiredTokens = mf.NewCounter("quota_acquired_tokens", "Number of acquired quota tokens", "spec", "success")
 		Metrics.ReturnedTokens = mf.NewCounter("quota_returned_tokens", "Number of quota tokens returned due to overcharging (bad requests, duplicates, etc)", "spec", "success")
 		Metrics.ReplenishedTokens = mf.NewCounter("quota_replenished_tokens", "Number of quota tokens replenished due to sequencer progress", "spec", "success")
+		Metrics.ExemptedTokens = mf.NewCounter("quota_exempted_tokens", "Number of quota tokens not acquired from quotas the requests' users are exempt from", "spec", "user")
 	})
 }
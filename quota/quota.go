@@ -118,3 +118,11 @@ type Manager interface {
 	// ResetQuota resets the quota for all specs.
 	ResetQuota(ctx context.Context, specs []Spec) error
 }
+
+// Exempter is implemented by Managers which may exempt users from some of their quotas, e.g.
+// mirroring or backfill jobs which shouldn't be limited by the quotas meant for other users.
+// Callers don't acquire tokens from, or return tokens to, the specs a user is exempt from.
+type Exempter interface {
+	// ExemptSpecs returns the specs, among specs, that user is exempt from.
+	ExemptSpecs(ctx context.Context, user string, specs []Spec) ([]Spec, error)
+}
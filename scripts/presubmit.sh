@@ -96,6 +96,9 @@ main() {
     echo 'running go build'
     go build ${goflags} ./...
 
+    echo 'building fuzz targets'
+    go build -tags gofuzz ./...
+
     echo 'running go test'
     # Install test deps so that individual test runs below can reuse them.
     echo 'installing test deps'
@@ -0,0 +1,503 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/google/trillian"
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/quota"
+	"github.com/google/trillian/util"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	abuseMaxRate           = flag.Float64("abuse_max_rate", 0, "Leaves per second, averaged over --abuse_window, above which a submitting source is anomalous. Zero disables the rate check")
+	abuseMaxDuplicateRatio = flag.Float64("abuse_max_duplicate_ratio", 0, "Fraction of duplicate leaves over --abuse_window above which a submitting source is anomalous. Zero disables the duplicate check")
+	abuseMinLeaves         = flag.Int("abuse_min_leaves", 100, "Minimum number of leaves a source must submit over --abuse_window before its duplicate ratio is checked")
+	abuseWindow            = flag.Duration("abuse_window", time.Minute, "Period over which the submission rate and duplicate ratio of sources are measured")
+	abuseThrottleDuration  = flag.Duration("abuse_throttle_duration", 0, "How long anomalous sources are throttled for. Zero disables automatic throttling, so anomalies are only exported as metrics")
+	abuseThrottledRate     = flag.Float64("abuse_throttled_rate", 1, "Leaves per second which throttled sources may submit")
+
+	abuseSubmissionRate  monitoring.Gauge
+	abuseDuplicateRatio  monitoring.Gauge
+	abuseAnomalies       monitoring.Counter
+	abuseThrottled       monitoring.Gauge
+	abuseThrottledLeaves monitoring.Counter
+	abuseMetricsOnce     sync.Once
+)
+
+func initAbuseMetrics(mf monitoring.MetricFactory) {
+	abuseMetricsOnce.Do(func() {
+		abuseSubmissionRate = mf.NewGauge("abuse_submission_rate", "Leaves per second submitted by a source over the abuse detection window", "source")
+		abuseDuplicateRatio = mf.NewGauge("abuse_duplicate_ratio", "Fraction of the leaves submitted by a source over the abuse detection window which were duplicates", "source")
+		abuseAnomalies = mf.NewCounter("abuse_anomalies", "Number of times a source was found to submit anomalously", "source", "reason")
+		abuseThrottled = mf.NewGauge("abuse_throttled", "Set to 1 while a source is throttled, 0 otherwise", "source")
+		abuseThrottledLeaves = mf.NewCounter("abuse_throttled_leaves", "Number of leaves rejected because their source was throttled", "source")
+	})
+}
+
+// Reasons for which a source may be anomalous.
+const (
+	AbuseReasonRate       = "rate"
+	AbuseReasonDuplicates = "duplicates"
+)
+
+// AbuseOverride is an operator's decision about a source, which takes
+// precedence over the AbuseDetector's own.
+type AbuseOverride string
+
+// Possible AbuseOverride values.
+const (
+	// AbuseOverrideNone leaves the source to the AbuseDetector.
+	AbuseOverrideNone AbuseOverride = "none"
+	// AbuseOverrideThrottle throttles the source until the override is cleared.
+	AbuseOverrideThrottle AbuseOverride = "throttle"
+	// AbuseOverrideExempt never throttles the source, although its anomalies
+	// are still reported.
+	AbuseOverrideExempt AbuseOverride = "exempt"
+)
+
+// AbuseDetectorOpts configures an AbuseDetector.
+type AbuseDetectorOpts struct {
+	// MaxRate is the number of leaves per second, averaged over Window, above
+	// which a source is anomalous. Zero disables the check.
+	MaxRate float64
+	// MaxDuplicateRatio is the fraction of duplicate leaves submitted over
+	// Window above which a source is anomalous. It must be in [0, 1), zero
+	// disables the check.
+	MaxDuplicateRatio float64
+	// MinLeaves is the number of leaves a source must submit over Window
+	// before its duplicate ratio is checked.
+	MinLeaves int
+	// Window is the period over which sources are measured. It's rounded down
+	// to whole seconds, with a minimum of one second.
+	Window time.Duration
+	// ThrottleDuration is how long anomalous sources are throttled for. Zero
+	// means that sources are only throttled by operators.
+	ThrottleDuration time.Duration
+	// ThrottledRate is the number of leaves per second throttled sources may
+	// submit.
+	ThrottledRate float64
+}
+
+// AbuseSource is the state of a source of submissions, as seen by an
+// AbuseDetector.
+type AbuseSource struct {
+	Source         string        `json:"source"`
+	Rate           float64       `json:"rate"`
+	DuplicateRatio float64       `json:"duplicate_ratio"`
+	Anomaly        string        `json:"anomaly,omitempty"`
+	Throttled      bool          `json:"throttled"`
+	ThrottledUntil time.Time     `json:"throttled_until,omitempty"`
+	Override       AbuseOverride `json:"override"`
+}
+
+// AbuseDetector tracks the rate at which each source, as identified by its
+// quota user, submits leaves to logs, and the fraction of those leaves which
+// are duplicates. Sources whose rate or duplicate ratio is anomalous are
+// reported through metrics and, if configured to, throttled for a while:
+// their submissions are rejected with RESOURCE_EXHAUSTED beyond a small rate.
+//
+// Operators may see the state of sources and override the detector's
+// decisions through its HTTP handler.
+//
+// A nil *AbuseDetector is valid, and never throttles anything.
+type AbuseDetector struct {
+	opts       AbuseDetectorOpts
+	qm         quota.Manager
+	timeSource util.TimeSource
+	buckets    int
+
+	mu        sync.Mutex
+	sources   map[string]*abuseSource
+	lastSweep int64
+}
+
+// abuseSource is the state of a source. AbuseDetector.mu must be held to
+// access it.
+type abuseSource struct {
+	leaves         []abuseBucket
+	anomaly        string
+	throttledUntil time.Time
+	throttled      bool
+	override       AbuseOverride
+	lastSeen       int64
+
+	// tokens and tokensTime implement the token bucket through which
+	// throttled sources submit. tokens may go negative, so that large batches
+	// are admitted but delay the source's later submissions.
+	tokens     float64
+	tokensTime time.Time
+}
+
+// abuseBucket counts the leaves submitted by a source in one second.
+type abuseBucket struct {
+	second, leaves, duplicates int64
+}
+
+// NewAbuseDetector returns an AbuseDetector which identifies sources by their
+// qm user.
+func NewAbuseDetector(opts AbuseDetectorOpts, qm quota.Manager, mf monitoring.MetricFactory, timeSource util.TimeSource) (*AbuseDetector, error) {
+	if opts.MaxRate < 0 {
+		return nil, fmt.Errorf("max rate is %v, want >= 0", opts.MaxRate)
+	}
+	if opts.MaxDuplicateRatio < 0 || opts.MaxDuplicateRatio >= 1 {
+		return nil, fmt.Errorf("max duplicate ratio is %v, want [0, 1)", opts.MaxDuplicateRatio)
+	}
+	if opts.MaxRate == 0 && opts.MaxDuplicateRatio == 0 {
+		return nil, fmt.Errorf("max rate and max duplicate ratio are both zero, want at least one check")
+	}
+	if opts.ThrottleDuration < 0 {
+		return nil, fmt.Errorf("throttle duration is %v, want >= 0", opts.ThrottleDuration)
+	}
+	if opts.ThrottledRate < 0 {
+		return nil, fmt.Errorf("throttled rate is %v, want >= 0", opts.ThrottledRate)
+	}
+	if mf == nil {
+		mf = monitoring.InertMetricFactory{}
+	}
+	initAbuseMetrics(mf)
+
+	buckets := int(opts.Window / time.Second)
+	if buckets < 1 {
+		buckets = 1
+	}
+	return &AbuseDetector{
+		opts:       opts,
+		qm:         qm,
+		timeSource: timeSource,
+		buckets:    buckets,
+		sources:    make(map[string]*abuseSource),
+	}, nil
+}
+
+// AbuseDetectorFromFlags returns the AbuseDetector specified by flags, or nil
+// if abuse detection is disabled.
+func AbuseDetectorFromFlags(qm quota.Manager, mf monitoring.MetricFactory, timeSource util.TimeSource) (*AbuseDetector, error) {
+	if *abuseMaxRate == 0 && *abuseMaxDuplicateRatio == 0 {
+		return nil, nil
+	}
+	return NewAbuseDetector(AbuseDetectorOpts{
+		MaxRate:           *abuseMaxRate,
+		MaxDuplicateRatio: *abuseMaxDuplicateRatio,
+		MinLeaves:         *abuseMinLeaves,
+		Window:            *abuseWindow,
+		ThrottleDuration:  *abuseThrottleDuration,
+		ThrottledRate:     *abuseThrottledRate,
+	}, qm, mf, timeSource)
+}
+
+// UnaryInterceptor is a grpc.UnaryServerInterceptor which measures the leaves
+// submitted by each source, and rejects those of throttled sources.
+func (d *AbuseDetector) UnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	leaves := submittedLeaves(req)
+	if d == nil || leaves == 0 {
+		return handler(ctx, req)
+	}
+	source := d.qm.GetUser(ctx, req)
+	if err := d.Allow(source, leaves); err != nil {
+		return nil, err
+	}
+	resp, err := handler(ctx, req)
+	if err == nil {
+		d.Record(source, leaves, duplicateLeaves(resp))
+	}
+	return resp, err
+}
+
+// Allow returns nil if source may submit the given number of leaves, or a
+// RESOURCE_EXHAUSTED error if it's throttled and over its rate.
+func (d *AbuseDetector) Allow(source string, leaves int) error {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s, ok := d.sources[source]
+	if !ok {
+		return nil
+	}
+	now := d.timeSource.Now()
+	if !d.updateThrottled(source, s, now) {
+		return nil
+	}
+
+	capacity := math.Max(d.opts.ThrottledRate, 1)
+	s.tokens = math.Min(capacity, s.tokens+now.Sub(s.tokensTime).Seconds()*d.opts.ThrottledRate)
+	s.tokensTime = now
+	if d.opts.ThrottledRate == 0 || s.tokens < math.Min(float64(leaves), capacity) {
+		abuseThrottledLeaves.Add(float64(leaves), source)
+		return status.Errorf(codes.ResourceExhausted, "source %q is throttled for anomalous submissions", source)
+	}
+	s.tokens -= float64(leaves)
+	return nil
+}
+
+// Record records that source successfully submitted the given number of
+// leaves, of which duplicates were already present, and checks whether the
+// source is anomalous.
+func (d *AbuseDetector) Record(source string, leaves, duplicates int) {
+	if d == nil || leaves == 0 {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := d.timeSource.Now()
+	second := now.Unix()
+	d.sweep(second)
+
+	s := d.source(source)
+	s.lastSeen = second
+	bucket := &s.leaves[second%int64(len(s.leaves))]
+	if bucket.second != second {
+		*bucket = abuseBucket{second: second}
+	}
+	bucket.leaves += int64(leaves)
+	bucket.duplicates += int64(duplicates)
+
+	rate, ratio, total := d.measure(s, second)
+	abuseSubmissionRate.Set(rate, source)
+	abuseDuplicateRatio.Set(ratio, source)
+
+	anomaly := ""
+	switch {
+	case d.opts.MaxRate > 0 && rate > d.opts.MaxRate:
+		anomaly = AbuseReasonRate
+	case d.opts.MaxDuplicateRatio > 0 && total >= int64(d.opts.MinLeaves) && ratio > d.opts.MaxDuplicateRatio:
+		anomaly = AbuseReasonDuplicates
+	}
+	if anomaly != "" && anomaly != s.anomaly {
+		glog.Warningf("Source %q submits anomalously (%v): %.2f leaves/s, %.2f duplicates", source, anomaly, rate, ratio)
+		abuseAnomalies.Inc(source, anomaly)
+	}
+	s.anomaly = anomaly
+	if anomaly != "" && d.opts.ThrottleDuration > 0 {
+		s.throttledUntil = now.Add(d.opts.ThrottleDuration)
+	}
+	d.updateThrottled(source, s, now)
+}
+
+// Override sets the operator's override for source. AbuseOverrideNone also
+// lifts any throttling which the detector applied to the source.
+func (d *AbuseDetector) Override(source string, o AbuseOverride) error {
+	if d == nil {
+		return fmt.Errorf("abuse detection is disabled")
+	}
+	switch o {
+	case AbuseOverrideNone, AbuseOverrideThrottle, AbuseOverrideExempt:
+	default:
+		return fmt.Errorf("unknown override %q", o)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := d.timeSource.Now()
+	s := d.source(source)
+	s.lastSeen = now.Unix()
+	s.override = o
+	if o == AbuseOverrideNone {
+		s.throttledUntil = time.Time{}
+	}
+	glog.Infof("Abuse override for source %q set to %v", source, o)
+	d.updateThrottled(source, s, now)
+	return nil
+}
+
+// Sources returns the state of the sources which submitted leaves over the
+// detection window, or which have an override, sorted by source.
+func (d *AbuseDetector) Sources() []AbuseSource {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := d.timeSource.Now()
+	d.sweep(now.Unix())
+
+	ret := make([]AbuseSource, 0, len(d.sources))
+	for name, s := range d.sources {
+		rate, ratio, _ := d.measure(s, now.Unix())
+		state := AbuseSource{
+			Source:         name,
+			Rate:           rate,
+			DuplicateRatio: ratio,
+			Anomaly:        s.anomaly,
+			Throttled:      d.updateThrottled(name, s, now),
+			Override:       s.override,
+		}
+		if now.Before(s.throttledUntil) {
+			state.ThrottledUntil = s.throttledUntil
+		}
+		ret = append(ret, state)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Source < ret[j].Source })
+	return ret
+}
+
+// ServeHTTP serves the state of sources as JSON on GET. POST sets the
+// override of the source form value to the action form value, which is either
+// an AbuseOverride, or "unthrottle" to lift automatic throttling while leaving
+// the source to the detector.
+func (d *AbuseDetector) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		source, action := req.FormValue("source"), req.FormValue("action")
+		if source == "" {
+			http.Error(w, "missing source", http.StatusBadRequest)
+			return
+		}
+		var err error
+		switch o := AbuseOverride(action); o {
+		case AbuseOverrideNone, AbuseOverrideThrottle, AbuseOverrideExempt:
+			err = d.Override(source, o)
+		case "unthrottle":
+			err = d.Override(source, AbuseOverrideNone)
+		default:
+			err = fmt.Errorf("unknown action %q, want none, throttle, exempt or unthrottle", action)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(d.Sources()); err != nil {
+		glog.Warningf("Failed to write abuse detector state: %v", err)
+	}
+}
+
+// source returns the state of the named source, creating it if needed.
+// d.mu must be held.
+func (d *AbuseDetector) source(name string) *abuseSource {
+	s, ok := d.sources[name]
+	if !ok {
+		s = &abuseSource{leaves: make([]abuseBucket, d.buckets), override: AbuseOverrideNone}
+		d.sources[name] = s
+	}
+	return s
+}
+
+// measure returns the submission rate, duplicate ratio and number of leaves
+// of s over the window ending at second now. d.mu must be held.
+func (d *AbuseDetector) measure(s *abuseSource, now int64) (float64, float64, int64) {
+	var leaves, duplicates int64
+	for _, b := range s.leaves {
+		if b.second > now-int64(len(s.leaves)) && b.second <= now {
+			leaves += b.leaves
+			duplicates += b.duplicates
+		}
+	}
+	if leaves == 0 {
+		return 0, 0, 0
+	}
+	return float64(leaves) / float64(len(s.leaves)), float64(duplicates) / float64(leaves), leaves
+}
+
+// updateThrottled recomputes whether the source is throttled at now, and
+// returns the result. d.mu must be held.
+func (d *AbuseDetector) updateThrottled(name string, s *abuseSource, now time.Time) bool {
+	throttled := s.override == AbuseOverrideThrottle ||
+		(s.override != AbuseOverrideExempt && now.Before(s.throttledUntil))
+	if throttled != s.throttled {
+		s.throttled = throttled
+		if throttled {
+			// Start with a full bucket, so the source isn't locked out.
+			s.tokens = math.Max(d.opts.ThrottledRate, 1)
+			s.tokensTime = now
+			abuseThrottled.Set(1, name)
+		} else {
+			abuseThrottled.Set(0, name)
+		}
+	}
+	return throttled
+}
+
+// sweep forgets the sources which haven't submitted over the window ending at
+// second now, unless an operator has overridden them or they're throttled.
+// It only runs once per window. d.mu must be held.
+func (d *AbuseDetector) sweep(now int64) {
+	if now-d.lastSweep < int64(d.buckets) {
+		return
+	}
+	d.lastSweep = now
+	t := d.timeSource.Now()
+	for name, s := range d.sources {
+		if s.lastSeen > now-int64(d.buckets) || s.override != AbuseOverrideNone || d.updateThrottled(name, s, t) {
+			continue
+		}
+		abuseSubmissionRate.Set(0, name)
+		abuseDuplicateRatio.Set(0, name)
+		delete(d.sources, name)
+	}
+}
+
+// submittedLeaves returns the number of leaves req submits to a log, or zero
+// if it's not a submission.
+func submittedLeaves(req interface{}) int {
+	switch req := req.(type) {
+	case *trillian.QueueLeafRequest:
+		return 1
+	case *trillian.QueueLeavesRequest:
+		return len(req.GetLeaves())
+	case *trillian.AddSequencedLeafRequest:
+		return 1
+	case *trillian.AddSequencedLeavesRequest:
+		return len(req.GetLeaves())
+	case *trillian.AddSequencedLeafRangeRequest:
+		return len(req.GetLeaves())
+	}
+	return 0
+}
+
+// duplicateLeaves returns the number of leaves which resp reports as already
+// present in the log.
+func duplicateLeaves(resp interface{}) int {
+	var queued []*trillian.QueuedLogLeaf
+	switch resp := resp.(type) {
+	case *trillian.QueueLeafResponse:
+		queued = []*trillian.QueuedLogLeaf{resp.GetQueuedLeaf()}
+	case *trillian.QueueLeavesResponse:
+		queued = resp.GetQueuedLeaves()
+	case *trillian.AddSequencedLeafResponse:
+		queued = []*trillian.QueuedLogLeaf{resp.GetResult()}
+	case *trillian.AddSequencedLeavesResponse:
+		queued = resp.GetResults()
+	case *trillian.AddSequencedLeafRangeResponse:
+		queued = resp.GetResults()
+	}
+	n := 0
+	for _, q := range queued {
+		if codes.Code(q.GetStatus().GetCode()) == codes.AlreadyExists {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,344 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/trillian"
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/quota"
+	"github.com/google/trillian/util"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestNewAbuseDetectorValidation(t *testing.T) {
+	for _, opts := range []AbuseDetectorOpts{
+		{},
+		{MaxRate: -1},
+		{MaxRate: 1, MaxDuplicateRatio: -0.5},
+		{MaxDuplicateRatio: 1},
+		{MaxRate: 1, ThrottleDuration: -time.Second},
+		{MaxRate: 1, ThrottledRate: -1},
+	} {
+		if _, err := NewAbuseDetector(opts, nil, nil, util.SystemTimeSource{}); err == nil {
+			t.Errorf("NewAbuseDetector(%+v)=_,nil; want err", opts)
+		}
+	}
+}
+
+func TestAbuseDetector_Rate(t *testing.T) {
+	ts := util.NewFakeTimeSource(time.Unix(1000, 0))
+	d, err := NewAbuseDetector(AbuseDetectorOpts{
+		MaxRate:          10,
+		Window:           10 * time.Second,
+		ThrottleDuration: time.Minute,
+		ThrottledRate:    2,
+	}, nil, monitoring.InertMetricFactory{}, ts)
+	if err != nil {
+		t.Fatalf("NewAbuseDetector(): %v", err)
+	}
+	before := abuseAnomalies.Value("rate-source", AbuseReasonRate)
+
+	d.Record("rate-source", 100, 0)
+	if err := d.Allow("rate-source", 1); err != nil {
+		t.Errorf("Allow() at rate limit=%v; want nil", err)
+	}
+	d.Record("rate-source", 1, 0)
+	if got, want := abuseAnomalies.Value("rate-source", AbuseReasonRate), before+1; got != want {
+		t.Errorf("abuse_anomalies=%v; want %v", got, want)
+	}
+	if got := abuseThrottled.Value("rate-source"); got != 1 {
+		t.Errorf("abuse_throttled=%v; want 1", got)
+	}
+	if got := abuseSubmissionRate.Value("rate-source"); got != 10.1 {
+		t.Errorf("abuse_submission_rate=%v; want 10.1", got)
+	}
+
+	// Throttled sources may submit at the throttled rate, with a burst of up
+	// to a second's worth of leaves.
+	if err := d.Allow("rate-source", 2); err != nil {
+		t.Errorf("Allow() within throttled rate=%v; want nil", err)
+	}
+	if err := d.Allow("rate-source", 1); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Allow() over throttled rate=%v; want code %v", err, codes.ResourceExhausted)
+	}
+	ts.Set(time.Unix(1001, 0))
+	if err := d.Allow("rate-source", 5); err != nil {
+		t.Errorf("Allow() after refill=%v; want nil", err)
+	}
+	ts.Set(time.Unix(1002, 0))
+	if err := d.Allow("rate-source", 1); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Allow() in debt=%v; want code %v", err, codes.ResourceExhausted)
+	}
+
+	// Other sources aren't affected.
+	if err := d.Allow("other-source", 1000); err != nil {
+		t.Errorf("Allow(other)=%v; want nil", err)
+	}
+
+	// Throttling ends once ThrottleDuration has passed since the last anomaly.
+	ts.Set(time.Unix(1061, 0))
+	if err := d.Allow("rate-source", 1000); err != nil {
+		t.Errorf("Allow() after throttling=%v; want nil", err)
+	}
+	if got := abuseThrottled.Value("rate-source"); got != 0 {
+		t.Errorf("abuse_throttled=%v; want 0", got)
+	}
+}
+
+func TestAbuseDetector_Duplicates(t *testing.T) {
+	ts := util.NewFakeTimeSource(time.Unix(1000, 0))
+	d, err := NewAbuseDetector(AbuseDetectorOpts{
+		MaxDuplicateRatio: 0.5,
+		MinLeaves:         10,
+		Window:            10 * time.Second,
+	}, nil, monitoring.InertMetricFactory{}, ts)
+	if err != nil {
+		t.Fatalf("NewAbuseDetector(): %v", err)
+	}
+	before := abuseAnomalies.Value("dup-source", AbuseReasonDuplicates)
+
+	// Below MinLeaves, the duplicate ratio isn't checked.
+	d.Record("dup-source", 5, 5)
+	if got := abuseAnomalies.Value("dup-source", AbuseReasonDuplicates); got != before {
+		t.Errorf("abuse_anomalies below MinLeaves=%v; want %v", got, before)
+	}
+	d.Record("dup-source", 5, 1)
+	if got, want := abuseAnomalies.Value("dup-source", AbuseReasonDuplicates), before+1; got != want {
+		t.Errorf("abuse_anomalies=%v; want %v", got, want)
+	}
+	if got := abuseDuplicateRatio.Value("dup-source"); got != 0.6 {
+		t.Errorf("abuse_duplicate_ratio=%v; want 0.6", got)
+	}
+	// Without a ThrottleDuration, anomalies are only reported.
+	if err := d.Allow("dup-source", 1000); err != nil {
+		t.Errorf("Allow()=%v; want nil", err)
+	}
+	sources := d.Sources()
+	if len(sources) != 1 || sources[0].Anomaly != AbuseReasonDuplicates || sources[0].Throttled {
+		t.Errorf("Sources()=%+v; want one unthrottled source with anomaly %v", sources, AbuseReasonDuplicates)
+	}
+
+	// Sources are forgotten once they're idle for a window.
+	ts.Set(time.Unix(1020, 0))
+	if sources := d.Sources(); len(sources) != 0 {
+		t.Errorf("Sources() after idle window=%+v; want none", sources)
+	}
+}
+
+func TestAbuseDetector_Override(t *testing.T) {
+	ts := util.NewFakeTimeSource(time.Unix(1000, 0))
+	d, err := NewAbuseDetector(AbuseDetectorOpts{
+		MaxRate:          1,
+		Window:           time.Second,
+		ThrottleDuration: time.Hour,
+	}, nil, monitoring.InertMetricFactory{}, ts)
+	if err != nil {
+		t.Fatalf("NewAbuseDetector(): %v", err)
+	}
+
+	// A zero ThrottledRate blocks throttled sources entirely.
+	if err := d.Override("manual", AbuseOverrideThrottle); err != nil {
+		t.Fatalf("Override(throttle)=%v", err)
+	}
+	if err := d.Allow("manual", 1); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Allow() of throttled source=%v; want code %v", err, codes.ResourceExhausted)
+	}
+	if err := d.Override("manual", AbuseOverrideNone); err != nil {
+		t.Fatalf("Override(none)=%v", err)
+	}
+	if err := d.Allow("manual", 1); err != nil {
+		t.Errorf("Allow() after clearing override=%v; want nil", err)
+	}
+
+	// Exempt sources are never throttled.
+	if err := d.Override("exempt", AbuseOverrideExempt); err != nil {
+		t.Fatalf("Override(exempt)=%v", err)
+	}
+	d.Record("exempt", 100, 0)
+	if err := d.Allow("exempt", 1); err != nil {
+		t.Errorf("Allow() of exempt source=%v; want nil", err)
+	}
+
+	// Clearing the override lifts automatic throttling too.
+	d.Record("auto", 100, 0)
+	if err := d.Allow("auto", 1); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Allow() of anomalous source=%v; want code %v", err, codes.ResourceExhausted)
+	}
+	if err := d.Override("auto", AbuseOverrideNone); err != nil {
+		t.Fatalf("Override(none)=%v", err)
+	}
+	if err := d.Allow("auto", 1); err != nil {
+		t.Errorf("Allow() after unthrottling=%v; want nil", err)
+	}
+
+	if err := d.Override("auto", AbuseOverride("ban")); err == nil {
+		t.Error("Override(ban)=nil; want err")
+	}
+}
+
+func TestAbuseDetector_ServeHTTP(t *testing.T) {
+	ts := util.NewFakeTimeSource(time.Unix(1000, 0))
+	d, err := NewAbuseDetector(AbuseDetectorOpts{MaxRate: 1, Window: time.Second}, nil, monitoring.InertMetricFactory{}, ts)
+	if err != nil {
+		t.Fatalf("NewAbuseDetector(): %v", err)
+	}
+	d.Record("http-source", 1, 0)
+
+	tests := []struct {
+		desc       string
+		method     string
+		form       url.Values
+		wantCode   int
+		wantStates map[string]AbuseOverride
+	}{
+		{
+			desc:       "get",
+			method:     http.MethodGet,
+			wantCode:   http.StatusOK,
+			wantStates: map[string]AbuseOverride{"http-source": AbuseOverrideNone},
+		},
+		{
+			desc:       "throttle",
+			method:     http.MethodPost,
+			form:       url.Values{"source": {"other"}, "action": {"throttle"}},
+			wantCode:   http.StatusOK,
+			wantStates: map[string]AbuseOverride{"http-source": AbuseOverrideNone, "other": AbuseOverrideThrottle},
+		},
+		{
+			desc:       "unthrottle",
+			method:     http.MethodPost,
+			form:       url.Values{"source": {"other"}, "action": {"unthrottle"}},
+			wantCode:   http.StatusOK,
+			wantStates: map[string]AbuseOverride{"http-source": AbuseOverrideNone, "other": AbuseOverrideNone},
+		},
+		{
+			desc:     "missingSource",
+			method:   http.MethodPost,
+			form:     url.Values{"action": {"exempt"}},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			desc:     "badAction",
+			method:   http.MethodPost,
+			form:     url.Values{"source": {"other"}, "action": {"ban"}},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			desc:     "badMethod",
+			method:   http.MethodDelete,
+			wantCode: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, "/abuse", strings.NewReader(test.form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		d.ServeHTTP(w, req)
+		if w.Code != test.wantCode {
+			t.Errorf("%v: ServeHTTP() code=%v; want %v", test.desc, w.Code, test.wantCode)
+			continue
+		}
+		if w.Code != http.StatusOK {
+			continue
+		}
+		var sources []AbuseSource
+		if err := json.Unmarshal(w.Body.Bytes(), &sources); err != nil {
+			t.Errorf("%v: ServeHTTP() returned invalid JSON: %v", test.desc, err)
+			continue
+		}
+		got := make(map[string]AbuseOverride)
+		for _, s := range sources {
+			got[s.Source] = s.Override
+		}
+		if len(got) != len(test.wantStates) {
+			t.Errorf("%v: ServeHTTP() sources=%v; want %v", test.desc, got, test.wantStates)
+		}
+		for source, want := range test.wantStates {
+			if got[source] != want {
+				t.Errorf("%v: ServeHTTP() override of %v=%v; want %v", test.desc, source, got[source], want)
+			}
+		}
+	}
+}
+
+func TestAbuseDetector_UnaryInterceptor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	qm := quota.NewMockManager(ctrl)
+	qm.EXPECT().GetUser(gomock.Any(), gomock.Any()).AnyTimes().Return("rpc-source")
+
+	ts := util.NewFakeTimeSource(time.Unix(1000, 0))
+	d, err := NewAbuseDetector(AbuseDetectorOpts{
+		MaxDuplicateRatio: 0.5,
+		MinLeaves:         2,
+		Window:            10 * time.Second,
+		ThrottleDuration:  time.Minute,
+	}, qm, monitoring.InertMetricFactory{}, ts)
+	if err != nil {
+		t.Fatalf("NewAbuseDetector(): %v", err)
+	}
+
+	dup := status.New(codes.AlreadyExists, "duplicate leaf").Proto()
+	req := &trillian.QueueLeavesRequest{Leaves: []*trillian.LogLeaf{{}, {}, {}}}
+	resp := &trillian.QueueLeavesResponse{
+		QueuedLeaves: []*trillian.QueuedLogLeaf{{Status: dup}, {Status: dup}, {}},
+	}
+	calls := 0
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		return resp, nil
+	}
+
+	ctx := context.Background()
+	info := &grpc.UnaryServerInfo{}
+	if _, err := d.UnaryInterceptor(ctx, req, info, handler); err != nil {
+		t.Errorf("UnaryInterceptor()=%v; want nil", err)
+	}
+	if _, err := d.UnaryInterceptor(ctx, req, info, handler); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("UnaryInterceptor() after anomaly=%v; want code %v", err, codes.ResourceExhausted)
+	}
+	if calls != 1 {
+		t.Errorf("handler called %v times; want 1", calls)
+	}
+
+	// Requests which don't submit leaves pass through.
+	if _, err := d.UnaryInterceptor(ctx, &trillian.GetLatestSignedLogRootRequest{}, info, handler); err != nil {
+		t.Errorf("UnaryInterceptor(GetLatestSignedLogRoot)=%v; want nil", err)
+	}
+}
+
+func TestNilAbuseDetector(t *testing.T) {
+	var d *AbuseDetector
+	d.Record("source", 1000, 1000)
+	if err := d.Allow("source", 1000); err != nil {
+		t.Errorf("nil Allow()=%v; want nil", err)
+	}
+	if sources := d.Sources(); len(sources) != 0 {
+		t.Errorf("nil Sources()=%v; want none", sources)
+	}
+}
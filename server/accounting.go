@@ -0,0 +1,84 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/quota"
+	"github.com/google/trillian/server/accounting"
+	"github.com/google/trillian/util"
+	"golang.org/x/net/context"
+)
+
+// Accounting sinks selectable with --accounting_sink.
+const (
+	AccountingSinkCSV      = "csv"
+	AccountingSinkBigQuery = "bigquery"
+	AccountingSinkSQL      = "sql"
+)
+
+var (
+	accountingSink       = flag.String("accounting_sink", "", "Where to record per-identity, per-tree API usage for billing: csv, bigquery or sql. Empty disables accounting")
+	accountingInterval   = flag.Duration("accounting_interval", accounting.DefaultInterval, "Period over which API usage is aggregated before being recorded")
+	accountingMaxBacklog = flag.Int("accounting_max_backlog", accounting.DefaultMaxBacklog, "Max number of usage records kept for retrying while the accounting sink fails")
+	accountingCSVFile    = flag.String("accounting_csv_file", "", "File to append usage records to, with --accounting_sink=csv")
+	accountingBQTable    = flag.String("accounting_bigquery_table", "", "BigQuery table (project.dataset.table) to stream usage records to, with --accounting_sink=bigquery")
+	accountingSQLDriver  = flag.String("accounting_sql_driver", "mysql", "Database driver of --accounting_sql_uri")
+	accountingSQLURI     = flag.String("accounting_sql_uri", "", "Database to insert usage records into, with --accounting_sink=sql. See server/accounting/usage.sql for its schema")
+	accountingSQLTable   = flag.String("accounting_sql_table", accounting.DefaultSQLTable, "Table to insert usage records into, with --accounting_sink=sql")
+)
+
+// AccountantFromFlags returns the accounting.Accountant specified by flags,
+// identifying callers by their qm user, or nil if accounting is disabled.
+func AccountantFromFlags(ctx context.Context, qm quota.Manager, mf monitoring.MetricFactory, timeSource util.TimeSource) (*accounting.Accountant, error) {
+	var sink accounting.Sink
+	var err error
+	switch *accountingSink {
+	case "":
+		return nil, nil
+	case AccountingSinkCSV:
+		if *accountingCSVFile == "" {
+			return nil, fmt.Errorf("--accounting_csv_file is required with --accounting_sink=%v", AccountingSinkCSV)
+		}
+		sink, err = accounting.OpenCSVFile(*accountingCSVFile)
+	case AccountingSinkBigQuery:
+		sink, err = accounting.NewBigQuerySink(ctx, *accountingBQTable)
+	case AccountingSinkSQL:
+		var db *sql.DB
+		db, err = sql.Open(*accountingSQLDriver, *accountingSQLURI)
+		if err == nil {
+			sink = accounting.NewSQLSink(db, *accountingSQLTable)
+		}
+	default:
+		return nil, fmt.Errorf("unknown --accounting_sink %q, want one of %v, %v or %v", *accountingSink, AccountingSinkCSV, AccountingSinkBigQuery, AccountingSinkSQL)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open accounting sink: %v", err)
+	}
+
+	a, err := accounting.New(accounting.Options{
+		Interval:   *accountingInterval,
+		MaxBacklog: *accountingMaxBacklog,
+	}, qm, sink, mf, timeSource)
+	if err != nil {
+		sink.Close()
+		return nil, err
+	}
+	return a, nil
+}
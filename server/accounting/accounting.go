@@ -0,0 +1,309 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package accounting records the API usage of each identity, per tree and
+// RPC, so that operators of multi-tenant Trillian servers can bill or charge
+// back usage without parsing access logs.
+//
+// An Accountant counts requests and their sizes in memory as they pass
+// through its interceptor, and periodically writes the totals of each period
+// to a Sink, such as a CSV file, a BigQuery table or an SQL table.
+package accounting
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/quota"
+	"github.com/google/trillian/util"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// DefaultInterval is the default period over which usage is aggregated
+	// before being written to the Sink.
+	DefaultInterval = time.Minute
+	// DefaultMaxBacklog is the default maximum number of records kept for
+	// retrying while the Sink fails.
+	DefaultMaxBacklog = 100000
+)
+
+var (
+	flushedRecords monitoring.Counter
+	droppedRecords monitoring.Counter
+	sinkErrors     monitoring.Counter
+	metricsOnce    sync.Once
+)
+
+func initMetrics(mf monitoring.MetricFactory) {
+	metricsOnce.Do(func() {
+		flushedRecords = mf.NewCounter("accounting_flushed_records", "Number of usage records written to the accounting sink")
+		droppedRecords = mf.NewCounter("accounting_dropped_records", "Number of usage records dropped because the accounting sink failed for too long")
+		sinkErrors = mf.NewCounter("accounting_sink_errors", "Number of failed writes to the accounting sink")
+	})
+}
+
+// Record is the usage of one RPC method of one tree by one identity over a
+// period.
+type Record struct {
+	// Start and End delimit the period, Start included.
+	Start, End time.Time
+	// Identity is the quota user of the requests.
+	Identity string
+	// TreeID is the tree addressed by the requests, or zero for requests not
+	// addressing a tree, e.g. ListTrees.
+	TreeID int64
+	// Method is the full RPC method name, e.g. /trillian.TrillianLog/QueueLeaves.
+	Method string
+	// Requests is the number of requests, including failed ones.
+	Requests int64
+	// Errors is the number of requests which failed.
+	Errors int64
+	// RequestBytes and ResponseBytes are the total sizes of the serialized
+	// request and response messages.
+	RequestBytes, ResponseBytes int64
+}
+
+// Sink stores usage records.
+type Sink interface {
+	// Write stores records. If it fails, the same records are passed again to
+	// a later call, so it should store either all or none of them.
+	Write(ctx context.Context, records []Record) error
+	// Close releases the resources held by the Sink.
+	Close() error
+}
+
+// Options configures an Accountant.
+type Options struct {
+	// Interval is the period over which usage is aggregated.
+	Interval time.Duration
+	// MaxBacklog is the maximum number of records kept for retrying while the
+	// Sink fails. The oldest records are dropped beyond it.
+	MaxBacklog int
+}
+
+type key struct {
+	identity string
+	treeID   int64
+	method   string
+}
+
+type usage struct {
+	requests, errors            int64
+	requestBytes, responseBytes int64
+}
+
+// Accountant aggregates the usage of RPCs passing through its interceptor and
+// writes it to a Sink every Options.Interval.
+type Accountant struct {
+	opts       Options
+	qm         quota.Manager
+	sink       Sink
+	timeSource util.TimeSource
+
+	mu      sync.Mutex
+	start   time.Time
+	usage   map[key]*usage
+	backlog []Record
+}
+
+// New returns an Accountant which identifies callers by their qm user and
+// writes records to sink. Zero options are replaced by their defaults.
+func New(opts Options, qm quota.Manager, sink Sink, mf monitoring.MetricFactory, timeSource util.TimeSource) (*Accountant, error) {
+	if opts.Interval < 0 {
+		return nil, fmt.Errorf("interval is %v, want >= 0", opts.Interval)
+	}
+	if opts.MaxBacklog < 0 {
+		return nil, fmt.Errorf("max backlog is %v, want >= 0", opts.MaxBacklog)
+	}
+	if opts.Interval == 0 {
+		opts.Interval = DefaultInterval
+	}
+	if opts.MaxBacklog == 0 {
+		opts.MaxBacklog = DefaultMaxBacklog
+	}
+	if mf == nil {
+		mf = monitoring.InertMetricFactory{}
+	}
+	initMetrics(mf)
+	return &Accountant{
+		opts:       opts,
+		qm:         qm,
+		sink:       sink,
+		timeSource: timeSource,
+		start:      timeSource.Now(),
+		usage:      make(map[key]*usage),
+	}, nil
+}
+
+// UnaryInterceptor is a grpc.UnaryServerInterceptor which accounts for each
+// request it's passed.
+func (a *Accountant) UnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	respBytes := 0
+	if err == nil {
+		respBytes = messageSize(resp)
+	}
+	a.Add(a.qm.GetUser(ctx, req), treeID(req), info.FullMethod, messageSize(req), respBytes, err)
+	return resp, err
+}
+
+// Add accounts for a request of identity to method of tree treeID, with the
+// given message sizes and error.
+func (a *Accountant) Add(identity string, treeID int64, method string, requestBytes, responseBytes int, err error) {
+	k := key{identity: identity, treeID: treeID, method: method}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	u, ok := a.usage[k]
+	if !ok {
+		u = &usage{}
+		a.usage[k] = u
+	}
+	u.requests++
+	if status.Code(err) != codes.OK {
+		u.errors++
+	}
+	u.requestBytes += int64(requestBytes)
+	u.responseBytes += int64(responseBytes)
+}
+
+// Run flushes the usage to the Sink every Options.Interval until ctx is done,
+// then flushes it one last time.
+func (a *Accountant) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			// ctx is done, but the remaining usage should still be written.
+			if err := a.Flush(context.Background()); err != nil {
+				glog.Errorf("Failed to write final usage records: %v", err)
+			}
+			return
+		case <-ticker.C:
+			if err := a.Flush(ctx); err != nil {
+				glog.Warningf("Failed to write usage records, will retry: %v", err)
+			}
+		}
+	}
+}
+
+// Flush ends the current period and writes its usage to the Sink, along with
+// that of previous periods which failed to be written.
+func (a *Accountant) Flush(ctx context.Context) error {
+	a.mu.Lock()
+	end := a.timeSource.Now()
+	records := a.backlog
+	for k, u := range a.usage {
+		records = append(records, Record{
+			Start:         a.start,
+			End:           end,
+			Identity:      k.identity,
+			TreeID:        k.treeID,
+			Method:        k.method,
+			Requests:      u.requests,
+			Errors:        u.errors,
+			RequestBytes:  u.requestBytes,
+			ResponseBytes: u.responseBytes,
+		})
+	}
+	fresh := records[len(a.backlog):]
+	sort.Slice(fresh, func(i, j int) bool {
+		r, s := fresh[i], fresh[j]
+		if r.Identity != s.Identity {
+			return r.Identity < s.Identity
+		}
+		if r.TreeID != s.TreeID {
+			return r.TreeID < s.TreeID
+		}
+		return r.Method < s.Method
+	})
+	a.start = end
+	a.usage = make(map[key]*usage)
+	a.backlog = nil
+	a.mu.Unlock()
+
+	if len(records) == 0 {
+		return nil
+	}
+	err := a.sink.Write(ctx, records)
+	if err == nil {
+		flushedRecords.Add(float64(len(records)))
+		return nil
+	}
+	sinkErrors.Inc()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	// Usage may have been flushed concurrently, in which case its records are
+	// retried along with these.
+	records = append(records, a.backlog...)
+	if n := len(records) - a.opts.MaxBacklog; n > 0 {
+		glog.Errorf("Dropping %d usage records, as the sink has been failing for too long", n)
+		droppedRecords.Add(float64(n))
+		records = records[n:]
+	}
+	a.backlog = records
+	return err
+}
+
+// Close flushes the remaining usage and closes the Sink.
+func (a *Accountant) Close(ctx context.Context) error {
+	err := a.Flush(ctx)
+	if cerr := a.sink.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func messageSize(m interface{}) int {
+	if pb, ok := m.(proto.Message); ok {
+		return proto.Size(pb)
+	}
+	return 0
+}
+
+type logIDRequest interface {
+	GetLogId() int64
+}
+
+type mapIDRequest interface {
+	GetMapId() int64
+}
+
+type treeIDRequest interface {
+	GetTreeId() int64
+}
+
+// treeID returns the ID of the tree addressed by req, or zero if it doesn't
+// address one.
+func treeID(req interface{}) int64 {
+	switch req := req.(type) {
+	case logIDRequest:
+		return req.GetLogId()
+	case mapIDRequest:
+		return req.GetMapId()
+	case treeIDRequest:
+		return req.GetTreeId()
+	}
+	return 0
+}
@@ -0,0 +1,181 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package accounting
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian"
+	"github.com/google/trillian/quota"
+	"github.com/google/trillian/util"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type userKey struct{}
+
+// fakeQuotaManager identifies callers by the user set in their context.
+type fakeQuotaManager struct {
+	quota.Manager
+}
+
+func (fakeQuotaManager) GetUser(ctx context.Context, req interface{}) string {
+	user, _ := ctx.Value(userKey{}).(string)
+	return user
+}
+
+// fakeSink holds the records written to it, or fails while err is set.
+type fakeSink struct {
+	records []Record
+	err     error
+	closed  bool
+}
+
+func (s *fakeSink) Write(ctx context.Context, records []Record) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.records = append(s.records, records...)
+	return nil
+}
+
+func (s *fakeSink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestAccountant(t *testing.T) {
+	t0 := time.Unix(1500000000, 0)
+	t1 := t0.Add(time.Minute)
+	ts := util.NewFakeTimeSource(t0)
+	sink := &fakeSink{}
+	a, err := New(Options{}, fakeQuotaManager{}, sink, nil, ts)
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+
+	queueReq := &trillian.QueueLeavesRequest{LogId: 1, Leaves: []*trillian.LogLeaf{{LeafValue: []byte("value")}}}
+	queueResp := &trillian.QueueLeavesResponse{QueuedLeaves: []*trillian.QueuedLogLeaf{{Leaf: queueReq.Leaves[0]}}}
+	listReq := &trillian.ListTreesRequest{}
+	queueInfo := &grpc.UnaryServerInfo{FullMethod: "/trillian.TrillianLog/QueueLeaves"}
+	listInfo := &grpc.UnaryServerInfo{FullMethod: "/trillian.TrillianAdmin/ListTrees"}
+	ok := func(resp interface{}) grpc.UnaryHandler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) { return resp, nil }
+	}
+	fail := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.ResourceExhausted, "quota")
+	}
+
+	alice := context.WithValue(context.Background(), userKey{}, "alice")
+	bob := context.WithValue(context.Background(), userKey{}, "bob")
+	for _, c := range []struct {
+		ctx     context.Context
+		req     interface{}
+		info    *grpc.UnaryServerInfo
+		handler grpc.UnaryHandler
+	}{
+		{alice, queueReq, queueInfo, ok(queueResp)},
+		{alice, queueReq, queueInfo, fail},
+		{alice, listReq, listInfo, ok(&trillian.ListTreesResponse{})},
+		{bob, queueReq, queueInfo, ok(queueResp)},
+	} {
+		// Errors are passed through, and accounted for.
+		a.UnaryInterceptor(c.ctx, c.req, c.info, c.handler)
+	}
+
+	ts.Set(t1)
+	if err := a.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush(): %v", err)
+	}
+	reqSize, respSize := int64(proto.Size(queueReq)), int64(proto.Size(queueResp))
+	want := []Record{
+		{Start: t0, End: t1, Identity: "alice", TreeID: 0, Method: listInfo.FullMethod, Requests: 1},
+		{Start: t0, End: t1, Identity: "alice", TreeID: 1, Method: queueInfo.FullMethod, Requests: 2, Errors: 1, RequestBytes: 2 * reqSize, ResponseBytes: respSize},
+		{Start: t0, End: t1, Identity: "bob", TreeID: 1, Method: queueInfo.FullMethod, Requests: 1, RequestBytes: reqSize, ResponseBytes: respSize},
+	}
+	if !reflect.DeepEqual(sink.records, want) {
+		t.Errorf("Flush() wrote %+v, want %+v", sink.records, want)
+	}
+
+	// Nothing happened since, so there's nothing to write.
+	sink.records = nil
+	if err := a.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush(): %v", err)
+	}
+	if len(sink.records) != 0 {
+		t.Errorf("Flush() wrote %+v, want nothing", sink.records)
+	}
+
+	if err := a.Close(context.Background()); err != nil {
+		t.Fatalf("Close(): %v", err)
+	}
+	if !sink.closed {
+		t.Error("Close() didn't close the sink")
+	}
+}
+
+func TestAccountantBacklog(t *testing.T) {
+	ts := util.NewFakeTimeSource(time.Unix(1500000000, 0))
+	sink := &fakeSink{err: errors.New("sink down")}
+	a, err := New(Options{MaxBacklog: 3}, fakeQuotaManager{}, sink, nil, ts)
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+
+	// Each flush produces two records, while the sink fails.
+	for i := 0; i < 2; i++ {
+		a.Add("alice", 1, "/m", 1, 1, nil)
+		a.Add("bob", 1, "/m", 1, 1, nil)
+		ts.Set(ts.Now().Add(time.Minute))
+		if err := a.Flush(context.Background()); err == nil {
+			t.Fatal("Flush() succeeded, want error")
+		}
+	}
+
+	sink.err = nil
+	if err := a.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush(): %v", err)
+	}
+	// The oldest record was dropped.
+	if got, want := len(sink.records), 3; got != want {
+		t.Fatalf("Flush() wrote %d records, want %d", got, want)
+	}
+	if got, want := sink.records[0].Identity, "bob"; got != want {
+		t.Errorf("oldest record written is %q's, want %q's", got, want)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	ts := util.NewFakeTimeSource(time.Now())
+	for _, test := range []struct {
+		desc string
+		opts Options
+	}{
+		{desc: "negativeInterval", opts: Options{Interval: -time.Second}},
+		{desc: "negativeBacklog", opts: Options{MaxBacklog: -1}},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			if _, err := New(test.opts, fakeQuotaManager{}, &fakeSink{}, nil, ts); err == nil {
+				t.Error("New() succeeded, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package accounting
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+)
+
+// BigQuerySink streams records into a BigQuery table with the columns start
+// and end (TIMESTAMP), identity and method (STRING), and tree_id, requests,
+// errors, request_bytes and response_bytes (INTEGER).
+type BigQuerySink struct {
+	client   *bigquery.Client
+	uploader *bigquery.Uploader
+}
+
+// NewBigQuerySink returns a BigQuerySink streaming records into table, given
+// as project.dataset.table.
+func NewBigQuerySink(ctx context.Context, table string) (*BigQuerySink, error) {
+	parts := strings.Split(table, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("BigQuery table %q is not of the form project.dataset.table", table)
+	}
+	client, err := bigquery.NewClient(ctx, parts[0])
+	if err != nil {
+		return nil, err
+	}
+	return &BigQuerySink{
+		client:   client,
+		uploader: client.Dataset(parts[1]).Table(parts[2]).Uploader(),
+	}, nil
+}
+
+// bigQueryRow is a Record as a bigquery.ValueSaver.
+type bigQueryRow Record
+
+// Save implements bigquery.ValueSaver. The insert ID identifies the record, so
+// that BigQuery drops rows retried after a partially failed Write.
+func (r *bigQueryRow) Save() (map[string]bigquery.Value, string, error) {
+	id := fmt.Sprintf("%d/%s/%d/%s", r.Start.UnixNano(), r.Identity, r.TreeID, r.Method)
+	return map[string]bigquery.Value{
+		"start":          r.Start,
+		"end":            r.End,
+		"identity":       r.Identity,
+		"tree_id":        r.TreeID,
+		"method":         r.Method,
+		"requests":       r.Requests,
+		"errors":         r.Errors,
+		"request_bytes":  r.RequestBytes,
+		"response_bytes": r.ResponseBytes,
+	}, id, nil
+}
+
+// Write implements Sink.
+func (s *BigQuerySink) Write(ctx context.Context, records []Record) error {
+	rows := make([]bigquery.ValueSaver, 0, len(records))
+	for i := range records {
+		rows = append(rows, (*bigQueryRow)(&records[i]))
+	}
+	return s.uploader.Put(ctx, rows)
+}
+
+// Close implements Sink.
+func (s *BigQuerySink) Close() error {
+	return s.client.Close()
+}
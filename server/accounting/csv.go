@@ -0,0 +1,102 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package accounting
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// csvHeader names the columns written by CSVSink.
+var csvHeader = []string{"start", "end", "identity", "tree_id", "method", "requests", "errors", "request_bytes", "response_bytes"}
+
+// CSVSink writes records as CSV lines, with RFC 3339 timestamps.
+type CSVSink struct {
+	mu     sync.Mutex
+	w      *csv.Writer
+	closer io.Closer
+}
+
+// NewCSVSink returns a CSVSink writing to w, starting with a header line if
+// header is true.
+func NewCSVSink(w io.Writer, header bool) (*CSVSink, error) {
+	s := &CSVSink{w: csv.NewWriter(w)}
+	if c, ok := w.(io.Closer); ok {
+		s.closer = c
+	}
+	if header {
+		s.w.Write(csvHeader)
+		s.w.Flush()
+		if err := s.w.Error(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// OpenCSVFile returns a CSVSink appending to the file at path, which is
+// created if needed. The header line is only written to new or empty files.
+func OpenCSVFile(path string) (*CSVSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	s, err := NewCSVSink(f, fi.Size() == 0)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Write implements Sink.
+func (s *CSVSink) Write(ctx context.Context, records []Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range records {
+		s.w.Write([]string{
+			r.Start.UTC().Format(time.RFC3339Nano),
+			r.End.UTC().Format(time.RFC3339Nano),
+			r.Identity,
+			strconv.FormatInt(r.TreeID, 10),
+			r.Method,
+			strconv.FormatInt(r.Requests, 10),
+			strconv.FormatInt(r.Errors, 10),
+			strconv.FormatInt(r.RequestBytes, 10),
+			strconv.FormatInt(r.ResponseBytes, 10),
+		})
+	}
+	s.w.Flush()
+	return s.w.Error()
+}
+
+// Close implements Sink. It closes the underlying writer if it's an
+// io.Closer.
+func (s *CSVSink) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
@@ -0,0 +1,132 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package accounting
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/trillian/storage/testdb"
+)
+
+var testRecords = []Record{
+	{
+		Start: time.Unix(1500000000, 0), End: time.Unix(1500000060, 0),
+		Identity: "alice", TreeID: 1, Method: "/trillian.TrillianLog/QueueLeaves",
+		Requests: 3, Errors: 1, RequestBytes: 300, ResponseBytes: 200,
+	},
+	{
+		Start: time.Unix(1500000000, 0), End: time.Unix(1500000060, 0),
+		Identity: "bob, inc.", TreeID: 2, Method: "/trillian.TrillianLog/GetLeavesByRange",
+		Requests: 1, RequestBytes: 10, ResponseBytes: 1000,
+	},
+}
+
+func TestCSVFile(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "accounting")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "usage.csv")
+
+	// Each reopening appends to the file, without repeating the header.
+	for i := 0; i < 2; i++ {
+		s, err := OpenCSVFile(path)
+		if err != nil {
+			t.Fatalf("OpenCSVFile(): %v", err)
+		}
+		if err := s.Write(ctx, testRecords[i:i+1]); err != nil {
+			t.Fatalf("Write(): %v", err)
+		}
+		if err := s.Close(); err != nil {
+			t.Fatalf("Close(): %v", err)
+		}
+	}
+
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"start,end,identity,tree_id,method,requests,errors,request_bytes,response_bytes",
+		"2017-07-14T02:40:00Z,2017-07-14T02:41:00Z,alice,1,/trillian.TrillianLog/QueueLeaves,3,1,300,200",
+		`2017-07-14T02:40:00Z,2017-07-14T02:41:00Z,"bob, inc.",2,/trillian.TrillianLog/GetLeavesByRange,1,0,10,1000`,
+		"",
+	}, "\n")
+	if string(got) != want {
+		t.Errorf("CSV file contents:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSQLSink(t *testing.T) {
+	ctx := context.Background()
+	db, err := testdb.New(ctx)
+	if err != nil {
+		t.Fatalf("testdb.New(): %v", err)
+	}
+	schema, err := ioutil.ReadFile("usage.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range strings.Split(string(schema), ";") {
+		var lines []string
+		for _, l := range strings.Split(stmt, "\n") {
+			if !strings.HasPrefix(l, "#") {
+				lines = append(lines, l)
+			}
+		}
+		if stmt := strings.TrimSpace(strings.Join(lines, "\n")); stmt != "" {
+			if _, err := db.ExecContext(ctx, stmt); err != nil {
+				t.Fatalf("Failed to create schema: %v", err)
+			}
+		}
+	}
+
+	s := NewSQLSink(db, DefaultSQLTable)
+	defer s.Close()
+	if err := s.Write(ctx, testRecords); err != nil {
+		t.Fatalf("Write(): %v", err)
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT StartNanos, EndNanos, Identity, TreeId, Method, Requests, Errors, RequestBytes, ResponseBytes FROM ApiUsage ORDER BY TreeId")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []Record
+	for rows.Next() {
+		var r Record
+		var start, end int64
+		if err := rows.Scan(&start, &end, &r.Identity, &r.TreeID, &r.Method, &r.Requests, &r.Errors, &r.RequestBytes, &r.ResponseBytes); err != nil {
+			t.Fatal(err)
+		}
+		r.Start, r.End = time.Unix(0, start), time.Unix(0, end)
+		got = append(got, r)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, testRecords) {
+		t.Errorf("ApiUsage rows: %+v, want %+v", got, testRecords)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package accounting
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// DefaultSQLTable is the default table written by SQLSink, whose schema is
+// given by usage.sql.
+const DefaultSQLTable = "ApiUsage"
+
+// SQLSink inserts records into an SQL table with the columns of usage.sql.
+// Timestamps are stored as nanoseconds since the epoch, like other Trillian
+// timestamps.
+type SQLSink struct {
+	db     *sql.DB
+	insert string
+}
+
+// NewSQLSink returns an SQLSink inserting records into table through db.
+func NewSQLSink(db *sql.DB, table string) *SQLSink {
+	return &SQLSink{
+		db: db,
+		insert: fmt.Sprintf(`INSERT INTO %s(StartNanos, EndNanos, Identity, TreeId, Method, Requests, Errors, RequestBytes, ResponseBytes)
+			VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?)`, table),
+	}
+}
+
+// Write implements Sink. The records are inserted in a single transaction.
+func (s *SQLSink) Write(ctx context.Context, records []Record) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.PrepareContext(ctx, s.insert)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	for _, r := range records {
+		if _, err := stmt.ExecContext(ctx, r.Start.UnixNano(), r.End.UnixNano(), r.Identity, r.TreeID, r.Method, r.Requests, r.Errors, r.RequestBytes, r.ResponseBytes); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to insert usage record: %v", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Close implements Sink. It closes the database.
+func (s *SQLSink) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,19 @@
+# MySQL / MariaDB version of the table written by accounting.SQLSink
+
+CREATE TABLE IF NOT EXISTS ApiUsage(
+  StartNanos           BIGINT NOT NULL,
+  EndNanos             BIGINT NOT NULL,
+  Identity             VARCHAR(255) NOT NULL,
+  TreeId               BIGINT NOT NULL,
+  Method               VARCHAR(255) NOT NULL,
+  Requests             BIGINT NOT NULL,
+  Errors               BIGINT NOT NULL,
+  RequestBytes         BIGINT NOT NULL,
+  ResponseBytes        BIGINT NOT NULL
+);
+
+CREATE INDEX ApiUsageIdentityIdx
+  ON ApiUsage(Identity, StartNanos);
+
+CREATE INDEX ApiUsageTreeIdx
+  ON ApiUsage(TreeId, StartNanos);
@@ -16,21 +16,29 @@ package admin
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"fmt"
+	"sync"
+	"time"
 
-	"github.com/golang/glog"
+	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/google/trillian"
 	"github.com/google/trillian/crypto/keys/der"
+	"github.com/google/trillian/crypto/keyspb"
+	"github.com/google/trillian/crypto/sigpb"
 	"github.com/google/trillian/extension"
 	"github.com/google/trillian/merkle/hashers"
 	"github.com/google/trillian/storage"
 	"github.com/google/trillian/trees"
+	"github.com/google/trillian/util"
+	"github.com/google/trillian/util/requestinfo"
 	"golang.org/x/net/context"
 	"google.golang.org/genproto/protobuf/field_mask"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	tcrypto "github.com/google/trillian/crypto"
 	_ "github.com/google/trillian/merkle/rfc6962" // Make hashers available
 )
 
@@ -38,16 +46,27 @@ import (
 type Server struct {
 	registry         extension.Registry
 	allowedTreeTypes []trillian.TreeType
+	timeSource       util.TimeSource
+	integrity        *IntegrityChecker
+
+	// createMu serialises the creation of trees with external identifiers,
+	// so that retries racing with each other don't create duplicate trees.
+	// Admin servers sharing storage don't coordinate.
+	createMu sync.Mutex
 }
 
 // New returns a trillian.TrillianAdminServer implementation.
 // registry is the extension.Registry used by the Server.
 // allowedTreeTypes defines which tree types may be created through this server,
 // with nil meaning unrestricted.
-func New(registry extension.Registry, allowedTreeTypes []trillian.TreeType) *Server {
+// timeSource provides the time at which leaf queues are sampled and integrity
+// checks run.
+func New(registry extension.Registry, allowedTreeTypes []trillian.TreeType, timeSource util.TimeSource) *Server {
 	return &Server{
 		registry:         registry,
 		allowedTreeTypes: allowedTreeTypes,
+		timeSource:       timeSource,
+		integrity:        NewIntegrityChecker(registry.AdminStorage, registry.LogStorage, timeSource),
 	}
 }
 
@@ -60,12 +79,19 @@ func (s *Server) IsHealthy() error {
 // ListTrees implements trillian.TrillianAdminServer.ListTrees.
 func (s *Server) ListTrees(ctx context.Context, req *trillian.ListTreesRequest) (*trillian.ListTreesResponse, error) {
 	// TODO(codingllama): This needs access control
-	resp, err := storage.ListTrees(ctx, s.registry.AdminStorage, req.GetShowDeleted())
+	sel, err := parseLabelSelector(req.GetLabelSelector())
 	if err != nil {
 		return nil, err
 	}
-	for _, tree := range resp {
-		redact(tree)
+	all, err := storage.ListTrees(ctx, s.registry.AdminStorage, req.GetShowDeleted())
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]*trillian.Tree, 0, len(all))
+	for _, tree := range all {
+		if sel.matches(tree.Labels) {
+			resp = append(resp, redact(tree))
+		}
 	}
 	return &trillian.ListTreesResponse{Tree: resp}, nil
 }
@@ -76,15 +102,77 @@ func (s *Server) GetTree(ctx context.Context, req *trillian.GetTreeRequest) (*tr
 	if err != nil {
 		return nil, err
 	}
+	if req.GetIncludeStatus() {
+		if tree.Status, err = s.treeStatus(ctx, tree); err != nil {
+			return nil, err
+		}
+	}
 	return redact(tree), nil
 }
 
+// treeStatus returns the latest root of tree and the fingerprint of its
+// public key.
+func (s *Server) treeStatus(ctx context.Context, tree *trillian.Tree) (*trillian.TreeStatus, error) {
+	fingerprint := sha256.Sum256(tree.GetPublicKey().GetDer())
+	ts := &trillian.TreeStatus{PublicKeyFingerprint: fingerprint[:]}
+
+	// As for GetTreeStats, only the storage of the server's own tree type is
+	// available.
+	switch tree.TreeType {
+	case trillian.TreeType_LOG, trillian.TreeType_PREORDERED_LOG:
+		if s.registry.LogStorage == nil {
+			break
+		}
+		tx, err := s.registry.LogStorage.SnapshotForTree(ctx, tree.TreeId)
+		if err != nil {
+			if err == storage.ErrTreeNeedsInit && tx != nil {
+				tx.Close()
+			}
+			return nil, err
+		}
+		defer tx.Close()
+		root, err := tx.LatestSignedLogRoot(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+		ts.LatestLogRoot = &root
+		return ts, nil
+	case trillian.TreeType_MAP:
+		if s.registry.MapStorage == nil {
+			break
+		}
+		tx, err := s.registry.MapStorage.SnapshotForTree(ctx, tree.TreeId)
+		if err != nil {
+			if err == storage.ErrTreeNeedsInit && tx != nil {
+				tx.Close()
+			}
+			return nil, err
+		}
+		defer tx.Close()
+		root, err := tx.LatestSignedMapRoot(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+		ts.LatestMapRoot = &root
+		return ts, nil
+	}
+	return nil, status.Errorf(codes.Unimplemented, "the status of %v trees is not available from this server", tree.TreeType)
+}
+
 // CreateTree implements trillian.TrillianAdminServer.CreateTree.
 func (s *Server) CreateTree(ctx context.Context, req *trillian.CreateTreeRequest) (*trillian.Tree, error) {
-	tree := req.GetTree()
-	if tree == nil {
+	if req.GetTree() == nil {
 		return nil, status.Errorf(codes.InvalidArgument, "a tree is required")
 	}
+	// The tree is defaulted and cleared below, which mustn't show through to
+	// in-process callers sharing req.
+	tree := proto.Clone(req.Tree).(*trillian.Tree)
 	if err := s.validateAllowedTreeType(tree.TreeType); err != nil {
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
@@ -101,6 +189,26 @@ func (s *Server) CreateTree(ctx context.Context, req *trillian.CreateTreeRequest
 		return nil, status.Errorf(codes.InvalidArgument, "invalid tree type: %v", tree.TreeType)
 	}
 
+	// A tree with an external identifier is only created if there isn't one
+	// already, so that the request can be retried.
+	if id := req.ExternalId; id != "" {
+		if err := setExternalID(tree, id); err != nil {
+			return nil, err
+		}
+		s.createMu.Lock()
+		defer s.createMu.Unlock()
+		existing, err := s.treeByExternalID(ctx, id, true /* showDeleted */)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			if err := checkDeclaredTree(existing, tree); err != nil {
+				return nil, err
+			}
+			return redact(existing), nil
+		}
+	}
+
 	// If a key specification was provided, generate a new key.
 	if req.KeySpec != nil {
 		if tree.PrivateKey != nil {
@@ -128,41 +236,93 @@ func (s *Server) CreateTree(ctx context.Context, req *trillian.CreateTreeRequest
 		return nil, status.Errorf(codes.InvalidArgument, "tree.private_key or key_spec is required")
 	}
 
+	// An external private key can't be read here, so the caller proves that
+	// it matches the public key by signing the tree's key challenge with it.
+	if ptypes.Is(tree.PrivateKey, &keyspb.ExternalKey{}) {
+		if err := verifyKeyChallenge(tree, req.KeyChallengeSignature); err != nil {
+			return nil, err
+		}
+	} else if req.KeyChallengeSignature != nil {
+		return nil, status.Error(codes.InvalidArgument, "key_challenge_signature is only supported with an ExternalKey tree.private_key")
+	} else if err := setPublicKey(ctx, tree); err != nil {
+		return nil, err
+	}
+	if err := setAdditionalPublicKeys(ctx, tree.AdditionalSigningKeys); err != nil {
+		return nil, err
+	}
+
+	// Clear generated fields, storage must set those
+	tree.TreeId = 0
+	tree.CreateTime = nil
+	tree.UpdateTime = nil
+	tree.Deleted = false
+	tree.DeleteTime = nil
+	tree.Status = nil
+
+	// New logs verify the nodes written by the signer unless told otherwise.
+	if tree.TreeType != trillian.TreeType_MAP && tree.WriteVerification == trillian.WriteVerification_UNKNOWN_WRITE_VERIFICATION {
+		tree.WriteVerification = trillian.WriteVerification_VERIFY_WRITES
+	}
+
+	createdTree, err := storage.CreateTree(ctx, s.registry.AdminStorage, tree)
+	if err != nil {
+		return nil, err
+	}
+	return redact(createdTree), nil
+}
+
+// setPublicKey sets the public_key of tree to that of its private_key, or
+// checks that they match if it's already set.
+func setPublicKey(ctx context.Context, tree *trillian.Tree) error {
 	// Check that the tree.PrivateKey is valid by trying to get a signer.
 	signer, err := trees.Signer(ctx, tree)
 	if err != nil {
-		return nil, status.Errorf(codes.InvalidArgument, "failed to create signer for tree: %v", err.Error())
+		return status.Errorf(codes.InvalidArgument, "failed to create signer for tree: %v", err.Error())
 	}
 
 	// Derive the public key that corresponds to the private key for this tree.
 	// The caller may have provided the public key, but for safety we shouldn't rely on it being correct.
 	publicKey, err := der.ToPublicProto(signer.Public())
 	if err != nil {
-		return nil, status.Errorf(codes.InvalidArgument, "failed to marshal public key: %v", err.Error())
+		return status.Errorf(codes.InvalidArgument, "failed to marshal public key: %v", err.Error())
 	}
 
 	// If a public key was provided, check that it matches the one we derived. If it doesn't, this indicates a mistake by the caller.
 	if tree.PublicKey != nil && !bytes.Equal(tree.PublicKey.Der, publicKey.Der) {
-		return nil, status.Error(codes.InvalidArgument, "the public and private keys are not a pair")
+		return status.Error(codes.InvalidArgument, "the public and private keys are not a pair")
 	}
 
 	// If no public key was provided, use the DER that we just marshaled.
 	if tree.PublicKey == nil {
 		tree.PublicKey = publicKey
 	}
+	return nil
+}
 
-	// Clear generated fields, storage must set those
-	tree.TreeId = 0
-	tree.CreateTime = nil
-	tree.UpdateTime = nil
-	tree.Deleted = false
-	tree.DeleteTime = nil
-
-	createdTree, err := storage.CreateTree(ctx, s.registry.AdminStorage, tree)
+// verifyKeyChallenge checks that sig is the signature of the key challenge of
+// tree, whose private_key is an ExternalKey, by its public_key.
+func verifyKeyChallenge(tree *trillian.Tree, sig *sigpb.DigitallySigned) error {
+	switch {
+	case tree.PublicKey == nil:
+		return status.Error(codes.InvalidArgument, "tree.public_key is required with an ExternalKey tree.private_key")
+	case sig == nil:
+		return status.Error(codes.InvalidArgument, "key_challenge_signature is required with an ExternalKey tree.private_key")
+	}
+	pub, err := der.UnmarshalPublicKey(tree.PublicKey.Der)
 	if err != nil {
-		return nil, err
+		return status.Errorf(codes.InvalidArgument, "invalid tree.public_key: %v", err)
 	}
-	return redact(createdTree), nil
+	if alg := tcrypto.SignatureAlgorithm(pub); alg != tree.SignatureAlgorithm {
+		return status.Errorf(codes.InvalidArgument, "%s signature not supported by public key of type %T", tree.SignatureAlgorithm, pub)
+	}
+	challenge, err := trees.KeyChallenge(tree)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := tcrypto.Verify(pub, challenge, sig); err != nil {
+		return status.Errorf(codes.InvalidArgument, "key_challenge_signature doesn't verify with tree.public_key: %v", err)
+	}
+	return nil
 }
 
 func (s *Server) validateAllowedTreeType(tt trillian.TreeType) error {
@@ -188,11 +348,19 @@ func (s *Server) UpdateTree(ctx context.Context, req *trillian.UpdateTreeRequest
 	if err := applyUpdateMask(&trillian.Tree{}, &trillian.Tree{}, mask); err != nil {
 		return nil, err
 	}
+	for _, path := range mask.Paths {
+		if path == "private_key" && ptypes.Is(tree.GetPrivateKey(), &keyspb.ExternalKey{}) {
+			return nil, status.Error(codes.InvalidArgument, "an ExternalKey private_key can only be set by CreateTree, with a key_challenge_signature")
+		}
+	}
+	if err := setAdditionalPublicKeys(ctx, tree.AdditionalSigningKeys); err != nil {
+		return nil, err
+	}
 
 	updatedTree, err := storage.UpdateTree(ctx, s.registry.AdminStorage, tree.TreeId, func(other *trillian.Tree) {
 		if err := applyUpdateMask(tree, other, mask); err != nil {
 			// Should never happen (famous last words).
-			glog.Errorf("Error applying mask on tree update: %v", err)
+			requestinfo.Errorf(ctx, "Error applying mask on tree update: %v", err)
 		}
 	})
 	if err != nil {
@@ -217,8 +385,22 @@ func applyUpdateMask(from, to *trillian.Tree, mask *field_mask.FieldMask) error
 			to.StorageSettings = from.StorageSettings
 		case "max_root_duration":
 			to.MaxRootDuration = from.MaxRootDuration
+		case "max_merge_delay":
+			to.MaxMergeDelay = from.MaxMergeDelay
+		case "root_timestamp_precision":
+			to.RootTimestampPrecision = from.RootTimestampPrecision
+		case "labels":
+			to.Labels = from.Labels
+		case "maintenance":
+			to.Maintenance = from.Maintenance
+		case "validity_window":
+			to.ValidityWindow = from.ValidityWindow
+		case "write_verification":
+			to.WriteVerification = from.WriteVerification
 		case "private_key":
 			to.PrivateKey = from.PrivateKey
+		case "additional_signing_keys":
+			to.AdditionalSigningKeys = from.AdditionalSigningKeys
 		default:
 			return status.Errorf(codes.InvalidArgument, "invalid update_mask path: %q", path)
 		}
@@ -244,8 +426,324 @@ func (s *Server) UndeleteTree(ctx context.Context, req *trillian.UndeleteTreeReq
 	return redact(tree), nil
 }
 
+// GetTreeStats implements trillian.TrillianAdminServer.GetTreeStats.
+func (s *Server) GetTreeStats(ctx context.Context, req *trillian.GetTreeStatsRequest) (*trillian.TreeStats, error) {
+	tree, err := storage.GetTree(ctx, s.registry.AdminStorage, req.GetTreeId())
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the storage of the server's own tree type is available, e.g. log
+	// servers can't report statistics about maps.
+	var treeStorage interface{}
+	switch tree.TreeType {
+	case trillian.TreeType_LOG, trillian.TreeType_PREORDERED_LOG:
+		treeStorage = s.registry.LogStorage
+	case trillian.TreeType_MAP:
+		treeStorage = s.registry.MapStorage
+	}
+	reader, ok := treeStorage.(storage.TreeStatsReader)
+	if !ok {
+		return nil, status.Errorf(codes.Unimplemented, "statistics of %v trees are not available from this server", tree.TreeType)
+	}
+
+	stats, err := reader.GetTreeStats(ctx, tree.TreeId)
+	if err != nil {
+		return nil, err
+	}
+	return &trillian.TreeStats{
+		TreeId:        tree.TreeId,
+		LeafCount:     stats.LeafCount,
+		LeafBytes:     stats.LeafBytes,
+		SubtreeCount:  stats.SubtreeCount,
+		RevisionCount: stats.RevisionCount,
+		ReadQps:       stats.ReadQPS,
+		WriteQps:      stats.WriteQPS,
+	}, nil
+}
+
+const (
+	// defaultOldestQueuedLeaves is the number of oldest queued leaves returned
+	// by InspectLeafQueue if the request doesn't specify it.
+	defaultOldestQueuedLeaves = 10
+	// maxOldestQueuedLeaves is the most oldest queued leaves InspectLeafQueue
+	// returns.
+	maxOldestQueuedLeaves = 1000
+)
+
+// queueAgeBounds are the bounds between the age buckets reported by
+// InspectLeafQueue.
+var queueAgeBounds = []time.Duration{time.Minute, 10 * time.Minute, time.Hour, 6 * time.Hour, 24 * time.Hour}
+
+// InspectLeafQueue implements trillian.TrillianAdminServer.InspectLeafQueue.
+func (s *Server) InspectLeafQueue(ctx context.Context, req *trillian.InspectLeafQueueRequest) (*trillian.LeafQueueSample, error) {
+	oldest := int(req.GetOldestLeafCount())
+	switch {
+	case oldest < 0 || oldest > maxOldestQueuedLeaves:
+		return nil, status.Errorf(codes.InvalidArgument, "oldest_leaf_count is %d, want 0 to %d", oldest, maxOldestQueuedLeaves)
+	case oldest == 0:
+		oldest = defaultOldestQueuedLeaves
+	}
+
+	tree, err := storage.GetTree(ctx, s.registry.AdminStorage, req.GetTreeId())
+	if err != nil {
+		return nil, err
+	}
+	if tree.TreeType != trillian.TreeType_LOG && tree.TreeType != trillian.TreeType_PREORDERED_LOG {
+		return nil, status.Errorf(codes.InvalidArgument, "tree %d is a %v, only logs have a leaf queue", tree.TreeId, tree.TreeType)
+	}
+	inspector, ok := s.registry.LogStorage.(storage.LeafQueueInspector)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "leaf queues can't be inspected on this server")
+	}
+
+	now := s.timeSource.Now()
+	sample, err := inspector.SampleQueue(ctx, tree.TreeId, storage.QueueSampleOptions{
+		Now:          now,
+		OldestLeaves: oldest,
+		AgeBounds:    queueAgeBounds,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &trillian.LeafQueueSample{TreeId: tree.TreeId, LeafCount: sample.LeafCount}
+	if resp.SampleTime, err = ptypes.TimestampProto(now); err != nil {
+		return nil, err
+	}
+	for _, leaf := range sample.OldestLeaves {
+		queued, err := ptypes.TimestampProto(leaf.QueueTimestamp)
+		if err != nil {
+			return nil, err
+		}
+		resp.OldestLeaves = append(resp.OldestLeaves, &trillian.LeafQueueEntry{
+			LeafIdentityHash: leaf.LeafIdentityHash,
+			MerkleLeafHash:   leaf.MerkleLeafHash,
+			QueueTimestamp:   queued,
+			Bucket:           leaf.Bucket,
+		})
+	}
+	for i, count := range sample.AgeCounts {
+		bucket := &trillian.LeafQueueAgeBucket{MinAge: ptypes.DurationProto(0), LeafCount: count}
+		if i > 0 {
+			bucket.MinAge = ptypes.DurationProto(queueAgeBounds[i-1])
+		}
+		if i < len(queueAgeBounds) {
+			bucket.MaxAge = ptypes.DurationProto(queueAgeBounds[i])
+		}
+		resp.AgeBuckets = append(resp.AgeBuckets, bucket)
+	}
+	for _, shard := range sample.Shards {
+		queued, err := ptypes.TimestampProto(shard.Oldest)
+		if err != nil {
+			return nil, err
+		}
+		resp.Shards = append(resp.Shards, &trillian.LeafQueueShard{
+			Bucket:               shard.Bucket,
+			LeafCount:            shard.LeafCount,
+			OldestQueueTimestamp: queued,
+		})
+	}
+	return resp, nil
+}
+
 // redact removes sensitive information from t. Returns t for convenience.
 func redact(t *trillian.Tree) *trillian.Tree {
 	t.PrivateKey = nil
+	if len(t.AdditionalSigningKeys) > 0 {
+		keys := make([]*trillian.SigningKey, 0, len(t.AdditionalSigningKeys))
+		for _, key := range t.AdditionalSigningKeys {
+			redacted := *key
+			redacted.PrivateKey = nil
+			keys = append(keys, &redacted)
+		}
+		t.AdditionalSigningKeys = keys
+	}
 	return t
 }
+
+// setAdditionalPublicKeys derives the public keys of the additional signing
+// keys of a tree, checking them against those provided by the caller, like
+// CreateTree does for the tree's own key.
+func setAdditionalPublicKeys(ctx context.Context, keys []*trillian.SigningKey) error {
+	for i, key := range keys {
+		if key.PrivateKey == nil {
+			return status.Errorf(codes.InvalidArgument, "additional_signing_keys[%d].private_key is required", i)
+		}
+		signer, err := trees.SigningKeySigner(ctx, key)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "failed to create signer for additional_signing_keys[%d]: %v", i, err)
+		}
+		publicKey, err := der.ToPublicProto(signer.Public())
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "failed to marshal public key of additional_signing_keys[%d]: %v", i, err)
+		}
+		switch {
+		case key.PublicKey == nil:
+			key.PublicKey = publicKey
+		case !bytes.Equal(key.PublicKey.Der, publicKey.Der):
+			return status.Errorf(codes.InvalidArgument, "the public and private keys of additional_signing_keys[%d] are not a pair", i)
+		}
+	}
+	return nil
+}
+
+// CheckTreeIntegrity implements trillian.TrillianAdminServer.CheckTreeIntegrity.
+func (s *Server) CheckTreeIntegrity(ctx context.Context, req *trillian.CheckTreeIntegrityRequest) (*trillian.TreeIntegrityCheck, error) {
+	if s.integrity == nil {
+		return nil, status.Error(codes.Unimplemented, "integrity checks are not available from this server")
+	}
+	return s.integrity.Start(ctx, req.GetTreeId(), req.GetRevision())
+}
+
+// GetTreeIntegrityCheck implements
+// trillian.TrillianAdminServer.GetTreeIntegrityCheck.
+func (s *Server) GetTreeIntegrityCheck(ctx context.Context, req *trillian.GetTreeIntegrityCheckRequest) (*trillian.TreeIntegrityCheck, error) {
+	if s.integrity == nil {
+		return nil, status.Error(codes.Unimplemented, "integrity checks are not available from this server")
+	}
+	return s.integrity.Get(ctx, req.GetTreeId())
+}
+
+// GetMergeDelayCompliance implements
+// trillian.TrillianAdminServer.GetMergeDelayCompliance.
+func (s *Server) GetMergeDelayCompliance(ctx context.Context, req *trillian.GetMergeDelayComplianceRequest) (*trillian.MergeDelayCompliance, error) {
+	var start, end time.Time
+	var err error
+	if req.StartTime != nil {
+		if start, err = ptypes.Timestamp(req.StartTime); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid start_time: %v", err)
+		}
+	}
+	if req.EndTime != nil {
+		if end, err = ptypes.Timestamp(req.EndTime); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid end_time: %v", err)
+		}
+	}
+	if !start.IsZero() && !end.IsZero() && !start.Before(end) {
+		return nil, status.Errorf(codes.InvalidArgument, "start_time %v is not before end_time %v", start, end)
+	}
+
+	tree, err := storage.GetTree(ctx, s.registry.AdminStorage, req.GetTreeId())
+	if err != nil {
+		return nil, err
+	}
+	if !isLog(tree) {
+		return nil, status.Errorf(codes.InvalidArgument, "tree %d is a %v, only logs have merge delays", tree.TreeId, tree.TreeType)
+	}
+	reader, ok := s.registry.LogStorage.(storage.MergeDelayReader)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "merge delay compliance is not recorded on this server")
+	}
+	windows, err := reader.GetMergeDelayWindows(ctx, tree.TreeId, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &trillian.MergeDelayCompliance{TreeId: tree.TreeId}
+	for _, w := range windows {
+		window := &trillian.MergeDelayWindow{
+			MaxMergeDelay:    ptypes.DurationProto(w.MaxMergeDelay),
+			LeafCount:        w.Leaves,
+			ViolationCount:   w.Violations,
+			NearMissCount:    w.NearMisses,
+			MaxObservedDelay: ptypes.DurationProto(w.MaxDelay),
+		}
+		if window.StartTime, err = ptypes.TimestampProto(w.Start); err != nil {
+			return nil, err
+		}
+		if window.EndTime, err = ptypes.TimestampProto(w.End()); err != nil {
+			return nil, err
+		}
+		resp.Windows = append(resp.Windows, window)
+	}
+	return resp, nil
+}
+
+// maxBatchTreeIDs is the maximum number of trees a BatchGetTreeStatus request
+// may ask for.
+const maxBatchTreeIDs = 1000
+
+// statusFields are the fields of trillian.TreeStatusEntry requested from
+// BatchGetTreeStatus.
+type statusFields struct {
+	treeState, latestRoot, queueDepth bool
+}
+
+func parseStatusMask(mask *field_mask.FieldMask) (statusFields, error) {
+	if len(mask.GetPaths()) == 0 {
+		return statusFields{treeState: true, latestRoot: true, queueDepth: true}, nil
+	}
+	var f statusFields
+	for _, path := range mask.Paths {
+		switch path {
+		case "tree_state":
+			f.treeState = true
+		case "latest_root":
+			f.latestRoot = true
+		case "queue_depth":
+			f.queueDepth = true
+		default:
+			return f, status.Errorf(codes.InvalidArgument, "invalid read_mask path: %q", path)
+		}
+	}
+	return f, nil
+}
+
+// BatchGetTreeStatus implements
+// trillian.TrillianAdminServer.BatchGetTreeStatus.
+func (s *Server) BatchGetTreeStatus(ctx context.Context, req *trillian.BatchGetTreeStatusRequest) (*trillian.BatchGetTreeStatusResponse, error) {
+	ids := req.GetTreeIds()
+	if len(ids) == 0 || len(ids) > maxBatchTreeIDs {
+		return nil, status.Errorf(codes.InvalidArgument, "%d tree_ids requested, want 1 to %d", len(ids), maxBatchTreeIDs)
+	}
+	fields, err := parseStatusMask(req.GetReadMask())
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &trillian.BatchGetTreeStatusResponse{Statuses: make([]*trillian.TreeStatusEntry, 0, len(ids))}
+	for _, id := range ids {
+		entry, err := s.treeStatusEntry(ctx, id, fields)
+		if err != nil {
+			// Once the request is cancelled, every remaining tree would fail.
+			if ctx.Err() != nil {
+				return nil, status.FromContextError(ctx.Err()).Err()
+			}
+			entry = &trillian.TreeStatusEntry{TreeId: id, Status: status.Convert(err).Proto()}
+		}
+		resp.Statuses = append(resp.Statuses, entry)
+	}
+	return resp, nil
+}
+
+// treeStatusEntry returns the requested fields of the status of tree id.
+func (s *Server) treeStatusEntry(ctx context.Context, id int64, fields statusFields) (*trillian.TreeStatusEntry, error) {
+	tree, err := storage.GetTree(ctx, s.registry.AdminStorage, id)
+	if err != nil {
+		return nil, err
+	}
+	entry := &trillian.TreeStatusEntry{TreeId: id}
+	if fields.treeState {
+		entry.TreeState = tree.TreeState
+	}
+	if fields.latestRoot {
+		ts, err := s.treeStatus(ctx, tree)
+		if err != nil {
+			return nil, err
+		}
+		entry.LatestLogRoot, entry.LatestMapRoot = ts.LatestLogRoot, ts.LatestMapRoot
+	}
+	if fields.queueDepth && isLog(tree) {
+		inspector, ok := s.registry.LogStorage.(storage.LeafQueueInspector)
+		if !ok {
+			return nil, status.Error(codes.Unimplemented, "leaf queues can't be inspected on this server")
+		}
+		sample, err := inspector.SampleQueue(ctx, id, storage.QueueSampleOptions{Now: s.timeSource.Now()})
+		if err != nil {
+			return nil, err
+		}
+		entry.QueueDepth = sample.LeafCount
+	}
+	return entry, nil
+}
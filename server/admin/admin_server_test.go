@@ -15,14 +15,17 @@
 package admin
 
 import (
+	"bytes"
 	"context"
 	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"errors"
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -39,12 +42,16 @@ import (
 	"github.com/google/trillian/crypto/sigpb"
 	"github.com/google/trillian/extension"
 	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/memory"
 	"github.com/google/trillian/storage/testonly"
+	"github.com/google/trillian/trees"
+	"github.com/google/trillian/util"
 	"github.com/kylelemons/godebug/pretty"
 	"google.golang.org/genproto/protobuf/field_mask"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	tcrypto "github.com/google/trillian/crypto"
 	ttestonly "github.com/google/trillian/testonly"
 )
 
@@ -143,6 +150,9 @@ func TestServer_ListTrees(t *testing.T) {
 		tree.DeleteTime = proto.Clone(nowPB).(*timestamp.Timestamp)
 		nowPB.Seconds++
 	}
+	activeLog.Labels = map[string]string{"env": "prod"}
+	frozenLog.Labels = map[string]string{"env": "prod", "customer": "acme"}
+	activeMap.Labels = map[string]string{"env": "test"}
 	nonDeletedTrees := []*trillian.Tree{activeLog, frozenLog, activeMap}
 	allTrees := []*trillian.Tree{activeLog, frozenLog, deletedLog, activeMap, deletedMap}
 
@@ -150,6 +160,8 @@ func TestServer_ListTrees(t *testing.T) {
 		desc  string
 		req   *trillian.ListTreesRequest
 		trees []*trillian.Tree
+		// want is the trees in the response, if not trees.
+		want []*trillian.Tree
 	}{
 		{desc: "emptyNonDeleted", req: &trillian.ListTreesRequest{}},
 		{desc: "empty", req: &trillian.ListTreesRequest{ShowDeleted: true}},
@@ -159,6 +171,30 @@ func TestServer_ListTrees(t *testing.T) {
 			req:   &trillian.ListTreesRequest{ShowDeleted: true},
 			trees: allTrees,
 		},
+		{
+			desc:  "labelEquals",
+			req:   &trillian.ListTreesRequest{LabelSelector: "env=prod"},
+			trees: nonDeletedTrees,
+			want:  []*trillian.Tree{activeLog, frozenLog},
+		},
+		{
+			desc:  "labelNotEquals",
+			req:   &trillian.ListTreesRequest{ShowDeleted: true, LabelSelector: "env!=prod"},
+			trees: allTrees,
+			want:  []*trillian.Tree{deletedLog, activeMap, deletedMap},
+		},
+		{
+			desc:  "labelSetAndUnset",
+			req:   &trillian.ListTreesRequest{LabelSelector: "env, !customer"},
+			trees: nonDeletedTrees,
+			want:  []*trillian.Tree{activeLog, activeMap},
+		},
+		{
+			desc:  "noLabelMatches",
+			req:   &trillian.ListTreesRequest{LabelSelector: "env=staging"},
+			trees: nonDeletedTrees,
+			want:  []*trillian.Tree{},
+		},
 	}
 
 	ctx := context.Background()
@@ -179,12 +215,20 @@ func TestServer_ListTrees(t *testing.T) {
 			t.Errorf("%v: ListTrees() returned err = %v", test.desc, err)
 			continue
 		}
+		wantTrees := test.trees
+		if test.want != nil {
+			wantTrees = test.want
+		}
 		want := []*trillian.Tree{}
-		for _, tree := range test.trees {
+		for _, tree := range wantTrees {
 			wantTree := proto.Clone(tree).(*trillian.Tree)
 			wantTree.PrivateKey = nil // redacted
 			want = append(want, wantTree)
 		}
+		if len(resp.Tree) != len(want) {
+			t.Errorf("%v: post-ListTrees() diff (-got +want):\n%v", test.desc, pretty.Compare(resp.Tree, want))
+			continue
+		}
 		for i, wantTree := range want {
 			if !proto.Equal(resp.Tree[i], wantTree) {
 				t.Errorf("%v: post-ListTrees() diff (-got +want):\n%v", test.desc, pretty.Compare(resp.Tree, want))
@@ -284,6 +328,90 @@ func TestServer_GetTree(t *testing.T) {
 	}
 }
 
+func TestServer_GetTree_IncludeStatus(t *testing.T) {
+	logRoot := trillian.SignedLogRoot{TreeSize: 10, TreeRevision: 3, RootHash: []byte("logroot")}
+	mapRoot := trillian.SignedMapRoot{MapRevision: 4, RootHash: []byte("maproot")}
+
+	tests := []struct {
+		desc        string
+		treeType    trillian.TreeType
+		logStorage  bool
+		mapStorage  bool
+		snapshotErr bool
+		wantLogRoot *trillian.SignedLogRoot
+		wantMapRoot *trillian.SignedMapRoot
+		wantCode    codes.Code
+	}{
+		{desc: "log", treeType: trillian.TreeType_LOG, logStorage: true, wantLogRoot: &logRoot},
+		{desc: "preorderedLog", treeType: trillian.TreeType_PREORDERED_LOG, logStorage: true, wantLogRoot: &logRoot},
+		{desc: "map", treeType: trillian.TreeType_MAP, mapStorage: true, wantMapRoot: &mapRoot},
+		{desc: "logWithoutLogStorage", treeType: trillian.TreeType_LOG, mapStorage: true, wantCode: codes.Unimplemented},
+		{desc: "mapWithoutMapStorage", treeType: trillian.TreeType_MAP, logStorage: true, wantCode: codes.Unimplemented},
+		{desc: "snapshotError", treeType: trillian.TreeType_LOG, logStorage: true, snapshotErr: true, wantCode: codes.Unknown},
+	}
+
+	ctx := context.Background()
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			setup := setupAdminServer(ctrl, nil /* keygen */, true /* snapshot */, true /* shouldCommit */, false /* commitErr */)
+			storedTree := *testonly.LogTree
+			storedTree.TreeId = 12345
+			storedTree.TreeType = test.treeType
+			setup.snapshotTX.EXPECT().GetTree(ctx, storedTree.TreeId).Return(&storedTree, nil)
+
+			if test.logStorage {
+				ls := storage.NewMockLogStorage(ctrl)
+				setup.server.registry.LogStorage = ls
+				if test.wantLogRoot != nil || test.snapshotErr {
+					if test.snapshotErr {
+						ls.EXPECT().SnapshotForTree(ctx, storedTree.TreeId).Return(nil, errors.New("snapshot failed"))
+					} else {
+						tx := storage.NewMockReadOnlyLogTreeTX(ctrl)
+						ls.EXPECT().SnapshotForTree(ctx, storedTree.TreeId).Return(tx, nil)
+						tx.EXPECT().LatestSignedLogRoot(ctx).Return(logRoot, nil)
+						tx.EXPECT().Commit().Return(nil)
+						tx.EXPECT().Close().Return(nil)
+					}
+				}
+			}
+			if test.mapStorage {
+				ms := storage.NewMockMapStorage(ctrl)
+				setup.server.registry.MapStorage = ms
+				if test.wantMapRoot != nil {
+					tx := storage.NewMockReadOnlyMapTreeTX(ctrl)
+					ms.EXPECT().SnapshotForTree(ctx, storedTree.TreeId).Return(tx, nil)
+					tx.EXPECT().LatestSignedMapRoot(ctx).Return(mapRoot, nil)
+					tx.EXPECT().Commit().Return(nil)
+					tx.EXPECT().Close().Return(nil)
+				}
+			}
+
+			tree, err := setup.server.GetTree(ctx, &trillian.GetTreeRequest{TreeId: storedTree.TreeId, IncludeStatus: true})
+			if got := status.Code(err); got != test.wantCode {
+				t.Fatalf("GetTree() = (_, %v), want code %v", err, test.wantCode)
+			}
+			if err != nil {
+				return
+			}
+			fingerprint := sha256.Sum256(storedTree.PublicKey.Der)
+			want := &trillian.TreeStatus{
+				LatestLogRoot:        test.wantLogRoot,
+				LatestMapRoot:        test.wantMapRoot,
+				PublicKeyFingerprint: fingerprint[:],
+			}
+			if diff := pretty.Compare(tree.Status, want); diff != "" {
+				t.Errorf("GetTree().Status diff (-got +want):\n%v", diff)
+			}
+			if tree.PrivateKey != nil {
+				t.Errorf("GetTree().PrivateKey = %v, want nil (redacted)", tree.PrivateKey)
+			}
+		})
+	}
+}
+
 func TestServer_CreateTree(t *testing.T) {
 	// PEM on the testonly trees is ECDSA, so let's use an ECDSA key for tests.
 	ecdsaPrivateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
@@ -337,6 +465,9 @@ func TestServer_CreateTree(t *testing.T) {
 	keySignatureMismatch := validTree
 	keySignatureMismatch.SignatureAlgorithm = sigpb.DigitallySigned_RSA
 
+	skipWriteVerification := validTree
+	skipWriteVerification.WriteVerification = trillian.WriteVerification_SKIP_WRITE_VERIFICATION
+
 	tests := []struct {
 		desc                  string
 		req                   *trillian.CreateTreeRequest
@@ -350,6 +481,11 @@ func TestServer_CreateTree(t *testing.T) {
 			req:        &trillian.CreateTreeRequest{Tree: &validTree},
 			wantCommit: true,
 		},
+		{
+			desc:       "skipWriteVerification",
+			req:        &trillian.CreateTreeRequest{Tree: &skipWriteVerification},
+			wantCommit: true,
+		},
 		{
 			desc:    "nilTree",
 			req:     &trillian.CreateTreeRequest{},
@@ -507,6 +643,10 @@ func TestServer_CreateTree(t *testing.T) {
 			// Copy test.req so that any changes CreateTree makes don't affect the original, which may be shared between tests.
 			reqCopy := proto.Clone(test.req).(*trillian.CreateTreeRequest)
 			tree, err := s.CreateTree(ctx, reqCopy)
+			// In-process callers may share the request, so it must be left as it was.
+			if !proto.Equal(reqCopy, test.req) {
+				t.Errorf("CreateTree() changed its request to %v, want %v", reqCopy, test.req)
+			}
 			switch gotErr := err != nil; {
 			case gotErr && !strings.Contains(err.Error(), test.wantErr):
 				t.Fatalf("CreateTree() = (_, %q), want (_, %q)", err, test.wantErr)
@@ -521,6 +661,9 @@ func TestServer_CreateTree(t *testing.T) {
 			wantTree.CreateTime = nowPB
 			wantTree.UpdateTime = nowPB
 			wantTree.PrivateKey = nil // redacted
+			if wantTree.WriteVerification == trillian.WriteVerification_UNKNOWN_WRITE_VERIFICATION {
+				wantTree.WriteVerification = trillian.WriteVerification_VERIFY_WRITES
+			}
 			wantTree.PublicKey, err = der.ToPublicProto(privateKey.Public())
 			if err != nil {
 				t.Fatalf("failed to marshal test public key as protobuf: %v", err)
@@ -618,6 +761,145 @@ func TestServer_CreateTree_AllowedTreeTypes(t *testing.T) {
 	}
 }
 
+func TestServer_CreateTree_ExternalKey(t *testing.T) {
+	ecdsaPrivateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating test ECDSA key: %v", err)
+	}
+	otherPrivateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating test ECDSA key: %v", err)
+	}
+	publicKey, err := der.ToPublicProto(ecdsaPrivateKey.Public())
+	if err != nil {
+		t.Fatalf("Error marshaling ECDSA public key: %v", err)
+	}
+
+	// Nothing can get a signer from the PKCS11Config in this test, as happens
+	// on an admin server without access to the HSM.
+	externalKey := func(label string) *keyspb.ExternalKey {
+		return &keyspb.ExternalKey{Key: ttestonly.MustMarshalAny(t, &keyspb.PKCS11Config{TokenLabel: label})}
+	}
+	externalTree := *testonly.LogTree
+	externalTree.PrivateKey = ttestonly.MustMarshalAny(t, externalKey("log"))
+	externalTree.PublicKey = publicKey
+
+	otherExternalTree := externalTree
+	otherExternalTree.PrivateKey = ttestonly.MustMarshalAny(t, externalKey("other"))
+
+	omittedPublicKey := externalTree
+	omittedPublicKey.PublicKey = nil
+
+	keySignatureMismatch := externalTree
+	keySignatureMismatch.SignatureAlgorithm = sigpb.DigitallySigned_RSA
+
+	readableTree := *testonly.LogTree
+	readableTree.PrivateKey = ttestonly.MustMarshalAny(t, &empty.Empty{})
+	readableTree.PublicKey = publicKey
+	keys.RegisterHandler(fakeKeyProtoHandler(&empty.Empty{}, ecdsaPrivateKey))
+	defer keys.UnregisterHandler(&empty.Empty{})
+
+	sign := func(key crypto.Signer, tree *trillian.Tree) *sigpb.DigitallySigned {
+		challenge, err := trees.KeyChallenge(tree)
+		if err != nil {
+			t.Fatalf("KeyChallenge(): %v", err)
+		}
+		sig, err := tcrypto.NewSHA256Signer(key).Sign(challenge)
+		if err != nil {
+			t.Fatalf("Sign(): %v", err)
+		}
+		return sig
+	}
+
+	tests := []struct {
+		desc       string
+		req        *trillian.CreateTreeRequest
+		wantCommit bool
+		wantErr    string
+	}{
+		{
+			desc:       "validChallenge",
+			req:        &trillian.CreateTreeRequest{Tree: &externalTree, KeyChallengeSignature: sign(ecdsaPrivateKey, &externalTree)},
+			wantCommit: true,
+		},
+		{
+			desc:    "omittedSignature",
+			req:     &trillian.CreateTreeRequest{Tree: &externalTree},
+			wantErr: "key_challenge_signature is required",
+		},
+		{
+			desc:    "omittedPublicKey",
+			req:     &trillian.CreateTreeRequest{Tree: &omittedPublicKey, KeyChallengeSignature: sign(ecdsaPrivateKey, &externalTree)},
+			wantErr: "tree.public_key is required",
+		},
+		{
+			desc:    "signedByOtherKey",
+			req:     &trillian.CreateTreeRequest{Tree: &externalTree, KeyChallengeSignature: sign(otherPrivateKey, &externalTree)},
+			wantErr: "doesn't verify",
+		},
+		{
+			desc:    "signedForOtherKeyReference",
+			req:     &trillian.CreateTreeRequest{Tree: &externalTree, KeyChallengeSignature: sign(ecdsaPrivateKey, &otherExternalTree)},
+			wantErr: "doesn't verify",
+		},
+		{
+			desc:    "keySignatureMismatch",
+			req:     &trillian.CreateTreeRequest{Tree: &keySignatureMismatch, KeyChallengeSignature: sign(ecdsaPrivateKey, &keySignatureMismatch)},
+			wantErr: "signature not supported by public key",
+		},
+		{
+			desc:    "signatureWithReadableKey",
+			req:     &trillian.CreateTreeRequest{Tree: &readableTree, KeyChallengeSignature: sign(ecdsaPrivateKey, &readableTree)},
+			wantErr: "only supported with an ExternalKey",
+		},
+	}
+
+	ctx := context.Background()
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			setup := setupAdminServer(ctrl, nil /* keygen */, false /* snapshot */, test.wantCommit, false /* commitErr */)
+			setup.tx.EXPECT().CreateTree(ctx, gomock.Any()).MaxTimes(1).DoAndReturn(func(ctx context.Context, tree *trillian.Tree) (*trillian.Tree, error) {
+				return tree, nil
+			})
+
+			tree, err := setup.server.CreateTree(ctx, proto.Clone(test.req).(*trillian.CreateTreeRequest))
+			switch gotErr := err != nil; {
+			case gotErr && !strings.Contains(err.Error(), test.wantErr):
+				t.Fatalf("CreateTree() = (_, %q), want (_, %q)", err, test.wantErr)
+			case gotErr:
+				return
+			case test.wantErr != "":
+				t.Fatalf("CreateTree() = (_, nil), want (_, %q)", test.wantErr)
+			}
+			if !proto.Equal(tree.PublicKey, test.req.Tree.PublicKey) {
+				t.Errorf("CreateTree().PublicKey = %v, want %v", tree.PublicKey, test.req.Tree.PublicKey)
+			}
+			if tree.PrivateKey != nil {
+				t.Errorf("CreateTree().PrivateKey = %v, want nil (redacted)", tree.PrivateKey)
+			}
+		})
+	}
+}
+
+func TestServer_UpdateTree_ExternalKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tree := *testonly.LogTree
+	tree.TreeId = 12345
+	tree.PrivateKey = ttestonly.MustMarshalAny(t, &keyspb.ExternalKey{Key: ttestonly.MustMarshalAny(t, &keyspb.PKCS11Config{})})
+	req := &trillian.UpdateTreeRequest{Tree: &tree, UpdateMask: &field_mask.FieldMask{Paths: []string{"private_key"}}}
+
+	setup := setupAdminServer(ctrl, nil /* keygen */, false /* snapshot */, false /* shouldCommit */, false /* commitErr */)
+	_, err := setup.server.UpdateTree(context.Background(), req)
+	if got, want := status.Code(err), codes.InvalidArgument; got != want {
+		t.Errorf("UpdateTree() = (_, %v), want code %v", err, want)
+	}
+}
+
 func TestServer_UpdateTree(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -634,15 +916,21 @@ func TestServer_UpdateTree(t *testing.T) {
 
 	// successTree specifies changes in all rw fields
 	successTree := &trillian.Tree{
-		TreeState:       trillian.TreeState_FROZEN,
-		DisplayName:     "Brand New Tree Name",
-		Description:     "Brand New Tree Desc",
-		StorageSettings: settings,
-		MaxRootDuration: ptypes.DurationProto(2 * time.Nanosecond),
-		PrivateKey:      ttestonly.MustMarshalAny(t, &empty.Empty{}),
+		TreeState:              trillian.TreeState_FROZEN,
+		DisplayName:            "Brand New Tree Name",
+		Description:            "Brand New Tree Desc",
+		StorageSettings:        settings,
+		MaxRootDuration:        ptypes.DurationProto(2 * time.Nanosecond),
+		PrivateKey:             ttestonly.MustMarshalAny(t, &empty.Empty{}),
+		RootTimestampPrecision: trillian.RootTimestampPrecision_MILLISECOND_PRECISION,
+		Labels:                 map[string]string{"env": "prod"},
+		Maintenance:            &trillian.MaintenanceMode{Reason: "migration"},
+		MaxMergeDelay:          ptypes.DurationProto(24 * time.Hour),
+		ValidityWindow:         &trillian.ValidityWindow{NotAfter: ptypes.TimestampNow()},
+		WriteVerification:      trillian.WriteVerification_SKIP_WRITE_VERIFICATION,
 	}
 	successMask := &field_mask.FieldMask{
-		Paths: []string{"tree_state", "display_name", "description", "storage_settings", "max_root_duration", "private_key"},
+		Paths: []string{"tree_state", "display_name", "description", "storage_settings", "max_root_duration", "private_key", "root_timestamp_precision", "labels", "maintenance", "max_merge_delay", "validity_window", "write_verification"},
 	}
 
 	successWant := existingTree
@@ -652,6 +940,12 @@ func TestServer_UpdateTree(t *testing.T) {
 	successWant.StorageSettings = successTree.StorageSettings
 	successWant.PrivateKey = nil // redacted on responses
 	successWant.MaxRootDuration = successTree.MaxRootDuration
+	successWant.RootTimestampPrecision = successTree.RootTimestampPrecision
+	successWant.Labels = successTree.Labels
+	successWant.Maintenance = successTree.Maintenance
+	successWant.MaxMergeDelay = successTree.MaxMergeDelay
+	successWant.ValidityWindow = successTree.ValidityWindow
+	successWant.WriteVerification = successTree.WriteVerification
 
 	tests := []struct {
 		desc                           string
@@ -744,6 +1038,70 @@ func TestServer_UpdateTree(t *testing.T) {
 	}
 }
 
+func TestServer_UpdateTree_AdditionalSigningKeys(t *testing.T) {
+	existingTree := proto.Clone(testonly.LogTree).(*trillian.Tree)
+	existingTree.TreeId = 12345
+	mask := &field_mask.FieldMask{Paths: []string{"additional_signing_keys"}}
+
+	// The public key is derived from the private key when it's omitted.
+	noPublicKey := proto.Clone(testonly.Ed25519SigningKey).(*trillian.SigningKey)
+	noPublicKey.PublicKey = nil
+	wrongPublicKey := proto.Clone(testonly.Ed25519SigningKey).(*trillian.SigningKey)
+	wrongPublicKey.PublicKey = testonly.LogTree.PublicKey
+
+	wantKey := proto.Clone(testonly.Ed25519SigningKey).(*trillian.SigningKey)
+	wantKey.PrivateKey = nil // redacted on responses
+
+	tests := []struct {
+		desc    string
+		key     *trillian.SigningKey
+		wantErr bool
+	}{
+		{desc: "publicKey", key: testonly.Ed25519SigningKey},
+		{desc: "noPublicKey", key: noPublicKey},
+		{desc: "wrongPublicKey", key: wrongPublicKey, wantErr: true},
+		{desc: "noPrivateKey", key: wantKey, wantErr: true},
+	}
+
+	ctx := context.Background()
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			setup := setupAdminServer(ctrl, nil /* keygen */, false /* snapshot */, !test.wantErr, false /* commitErr */)
+			currentTree := proto.Clone(existingTree).(*trillian.Tree)
+			var storedKeys []*trillian.SigningKey
+			setup.tx.EXPECT().UpdateTree(ctx, existingTree.TreeId, gomock.Any()).MaxTimes(1).Do(func(ctx context.Context, treeID int64, updateFn func(*trillian.Tree)) {
+				updateFn(currentTree)
+				storedKeys = currentTree.AdditionalSigningKeys
+			}).Return(currentTree, nil)
+
+			req := &trillian.UpdateTreeRequest{
+				Tree: &trillian.Tree{
+					TreeId:                existingTree.TreeId,
+					AdditionalSigningKeys: []*trillian.SigningKey{proto.Clone(test.key).(*trillian.SigningKey)},
+				},
+				UpdateMask: mask,
+			}
+			tree, err := setup.server.UpdateTree(ctx, req)
+			if hasErr := err != nil; hasErr != test.wantErr {
+				t.Fatalf("UpdateTree() returned err = %v, wantErr = %v", err, test.wantErr)
+			} else if hasErr {
+				return
+			}
+
+			if len(tree.AdditionalSigningKeys) != 1 || !proto.Equal(tree.AdditionalSigningKeys[0], wantKey) {
+				t.Errorf("UpdateTree().AdditionalSigningKeys = %v, want [%v]", tree.AdditionalSigningKeys, wantKey)
+			}
+			// Redacting the response mustn't clear the keys given to storage.
+			if got := storedKeys[0].PrivateKey; !proto.Equal(got, testonly.Ed25519SigningKey.PrivateKey) {
+				t.Errorf("stored additional private key = %v, want %v", got, testonly.Ed25519SigningKey.PrivateKey)
+			}
+		})
+	}
+}
+
 func TestServer_DeleteTree(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -913,6 +1271,126 @@ func TestServer_UndeleteTreeErrors(t *testing.T) {
 	}
 }
 
+func TestServer_GetTreeStats(t *testing.T) {
+	ctx := context.Background()
+	ls := memory.NewLogStorage(nil)
+	as := memory.NewAdminStorage(ls)
+	logTree := createLogWithHistory(ctx, t, as, ls, 3, 5)
+	mapTree, err := storage.CreateTree(ctx, as, testonly.MapTree)
+	if err != nil {
+		t.Fatalf("CreateTree(): %v", err)
+	}
+	tx, err := ls.SnapshotForTree(ctx, logTree.TreeId)
+	if err != nil {
+		t.Fatalf("SnapshotForTree(): %v", err)
+	}
+	tx.Close()
+	s := &Server{registry: extension.Registry{AdminStorage: as, LogStorage: ls}}
+
+	stats, err := s.GetTreeStats(ctx, &trillian.GetTreeStatsRequest{TreeId: logTree.TreeId})
+	if err != nil {
+		t.Fatalf("GetTreeStats(): %v", err)
+	}
+	var wantBytes int64
+	for i := 0; i < 8; i++ {
+		wantBytes += int64(len(fmt.Sprintf("leaf %d", i)))
+	}
+	if stats.TreeId != logTree.TreeId || stats.LeafCount != 8 || stats.LeafBytes != wantBytes {
+		t.Errorf("GetTreeStats(): got tree %d with %d leaves of %d bytes, want tree %d with 8 leaves of %d bytes", stats.TreeId, stats.LeafCount, stats.LeafBytes, logTree.TreeId, wantBytes)
+	}
+	// The empty root plus one per integrated batch.
+	if got, want := stats.RevisionCount, int64(3); got != want {
+		t.Errorf("GetTreeStats(): got %d revisions, want %d", got, want)
+	}
+	if stats.SubtreeCount == 0 {
+		t.Error("GetTreeStats(): got 0 subtrees, want > 0")
+	}
+	if stats.ReadQps == 0 || stats.WriteQps == 0 {
+		t.Errorf("GetTreeStats(): got read QPS %v and write QPS %v, want both > 0", stats.ReadQps, stats.WriteQps)
+	}
+
+	for _, test := range []struct {
+		desc   string
+		treeID int64
+		want   codes.Code
+	}{
+		{desc: "unknownTree", treeID: 12345, want: codes.NotFound},
+		{desc: "noMapStorage", treeID: mapTree.TreeId, want: codes.Unimplemented},
+	} {
+		_, err := s.GetTreeStats(ctx, &trillian.GetTreeStatsRequest{TreeId: test.treeID})
+		if got := status.Code(err); got != test.want {
+			t.Errorf("%v: GetTreeStats() returned code %v, want %v", test.desc, got, test.want)
+		}
+	}
+}
+
+func TestServer_InspectLeafQueue(t *testing.T) {
+	ctx := context.Background()
+	ls := memory.NewLogStorage(nil)
+	as := memory.NewAdminStorage(ls)
+	logTree := createLogWithHistory(ctx, t, as, ls)
+	mapTree, err := storage.CreateTree(ctx, as, testonly.MapTree)
+	if err != nil {
+		t.Fatalf("CreateTree(): %v", err)
+	}
+
+	// Queue a leaf two hours ago, and two more now.
+	clock := util.NewFakeTimeSource(time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC))
+	now := clock.Now()
+	for i, queued := range []time.Time{now.Add(-2 * time.Hour), now, now} {
+		hash := sha256.Sum256([]byte{byte(i)})
+		leaf := &trillian.LogLeaf{LeafValue: []byte{byte(i)}, MerkleLeafHash: hash[:], LeafIdentityHash: hash[:]}
+		if _, err := ls.QueueLeaves(ctx, logTree.TreeId, []*trillian.LogLeaf{leaf}, queued); err != nil {
+			t.Fatalf("QueueLeaves(): %v", err)
+		}
+	}
+	s := &Server{registry: extension.Registry{AdminStorage: as, LogStorage: ls}, timeSource: clock}
+
+	sample, err := s.InspectLeafQueue(ctx, &trillian.InspectLeafQueueRequest{TreeId: logTree.TreeId, OldestLeafCount: 1})
+	if err != nil {
+		t.Fatalf("InspectLeafQueue(): %v", err)
+	}
+	if got, err := ptypes.Timestamp(sample.SampleTime); err != nil || !got.Equal(now) {
+		t.Errorf("InspectLeafQueue(): got sample time %v (err %v), want %v", got, err, now)
+	}
+	if sample.TreeId != logTree.TreeId || sample.LeafCount != 3 {
+		t.Errorf("InspectLeafQueue(): got tree %d with %d queued leaves, want tree %d with 3", sample.TreeId, sample.LeafCount, logTree.TreeId)
+	}
+	wantOldest := sha256.Sum256([]byte{0})
+	if len(sample.OldestLeaves) != 1 || !bytes.Equal(sample.OldestLeaves[0].LeafIdentityHash, wantOldest[:]) {
+		t.Errorf("InspectLeafQueue(): got oldest leaves %v, want leaf %x only", sample.OldestLeaves, wantOldest)
+	}
+	var gotCounts []int64
+	for _, bucket := range sample.AgeBuckets {
+		gotCounts = append(gotCounts, bucket.LeafCount)
+	}
+	if want := []int64{2, 0, 0, 1, 0, 0}; !reflect.DeepEqual(gotCounts, want) {
+		t.Errorf("InspectLeafQueue(): got age bucket counts %v, want %v", gotCounts, want)
+	}
+	if last := sample.AgeBuckets[len(sample.AgeBuckets)-1]; last.MaxAge != nil {
+		t.Errorf("InspectLeafQueue(): got last age bucket with max age %v, want none", last.MaxAge)
+	}
+	if len(sample.Shards) != 1 || sample.Shards[0].LeafCount != 3 {
+		t.Errorf("InspectLeafQueue(): got shards %v, want a single shard of 3 leaves", sample.Shards)
+	}
+
+	for _, test := range []struct {
+		desc string
+		req  *trillian.InspectLeafQueueRequest
+		want codes.Code
+	}{
+		{desc: "unknownTree", req: &trillian.InspectLeafQueueRequest{TreeId: 12345}, want: codes.NotFound},
+		{desc: "mapTree", req: &trillian.InspectLeafQueueRequest{TreeId: mapTree.TreeId}, want: codes.InvalidArgument},
+		{desc: "negativeCount", req: &trillian.InspectLeafQueueRequest{TreeId: logTree.TreeId, OldestLeafCount: -1}, want: codes.InvalidArgument},
+		{desc: "countTooLarge", req: &trillian.InspectLeafQueueRequest{TreeId: logTree.TreeId, OldestLeafCount: maxOldestQueuedLeaves + 1}, want: codes.InvalidArgument},
+	} {
+		_, err := s.InspectLeafQueue(ctx, test.req)
+		if got := status.Code(err); got != test.want {
+			t.Errorf("%v: InspectLeafQueue() returned code %v, want %v", test.desc, got, test.want)
+		}
+	}
+}
+
 // adminTestSetup contains an operational Server and required dependencies.
 // It's created via setupAdminServer.
 type adminTestSetup struct {
@@ -983,3 +1461,146 @@ func fakeKeyProtoGenerator(wantKeySpec *keyspb.Specification, keyProto proto.Mes
 		return keyProto, nil
 	}
 }
+
+func TestServer_GetMergeDelayCompliance(t *testing.T) {
+	ctx := context.Background()
+	ls := memory.NewLogStorage(nil)
+	as := memory.NewAdminStorage(ls)
+	logTree := createLogWithHistory(ctx, t, as, ls)
+	mapTree, err := storage.CreateTree(ctx, as, testonly.MapTree)
+	if err != nil {
+		t.Fatalf("CreateTree(): %v", err)
+	}
+
+	// Record a window an hour ago, and one now.
+	now := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	if err := ls.ReadWriteTransaction(ctx, logTree.TreeId, func(ctx context.Context, tx storage.LogTreeTX) error {
+		for i, start := range []time.Time{now.Add(-time.Hour), now} {
+			w := storage.MergeDelayWindow{Start: start, Length: time.Hour, MaxMergeDelay: time.Minute, Leaves: int64(i + 1), Violations: int64(i), MaxDelay: time.Duration(i+1) * time.Minute}
+			if err := tx.(storage.MergeDelayWriter).AddMergeDelayWindow(ctx, w); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("AddMergeDelayWindow(): %v", err)
+	}
+	s := &Server{registry: extension.Registry{AdminStorage: as, LogStorage: ls}}
+
+	all, err := s.GetMergeDelayCompliance(ctx, &trillian.GetMergeDelayComplianceRequest{TreeId: logTree.TreeId})
+	if err != nil {
+		t.Fatalf("GetMergeDelayCompliance(): %v", err)
+	}
+	if all.TreeId != logTree.TreeId || len(all.Windows) != 2 {
+		t.Fatalf("GetMergeDelayCompliance(): got tree %d with %d windows, want tree %d with 2", all.TreeId, len(all.Windows), logTree.TreeId)
+	}
+	latest := all.Windows[1]
+	if got, err := ptypes.Timestamp(latest.StartTime); err != nil || !got.Equal(now) {
+		t.Errorf("GetMergeDelayCompliance(): got latest window start %v (err %v), want %v", got, err, now)
+	}
+	if got, err := ptypes.Timestamp(latest.EndTime); err != nil || !got.Equal(now.Add(time.Hour)) {
+		t.Errorf("GetMergeDelayCompliance(): got latest window end %v (err %v), want %v", got, err, now.Add(time.Hour))
+	}
+	if latest.LeafCount != 2 || latest.ViolationCount != 1 || !proto.Equal(latest.MaxObservedDelay, ptypes.DurationProto(2*time.Minute)) {
+		t.Errorf("GetMergeDelayCompliance(): got latest window %v, want 2 leaves, 1 violation and a max delay of 2m", latest)
+	}
+
+	start, _ := ptypes.TimestampProto(now.Add(time.Minute))
+	recent, err := s.GetMergeDelayCompliance(ctx, &trillian.GetMergeDelayComplianceRequest{TreeId: logTree.TreeId, StartTime: start})
+	if err != nil {
+		t.Fatalf("GetMergeDelayCompliance(): %v", err)
+	}
+	if len(recent.Windows) != 1 || !proto.Equal(recent.Windows[0], latest) {
+		t.Errorf("GetMergeDelayCompliance(start): got windows %v, want %v only", recent.Windows, latest)
+	}
+
+	end, _ := ptypes.TimestampProto(now)
+	for _, test := range []struct {
+		desc string
+		req  *trillian.GetMergeDelayComplianceRequest
+		want codes.Code
+	}{
+		{desc: "unknownTree", req: &trillian.GetMergeDelayComplianceRequest{TreeId: 12345}, want: codes.NotFound},
+		{desc: "mapTree", req: &trillian.GetMergeDelayComplianceRequest{TreeId: mapTree.TreeId}, want: codes.InvalidArgument},
+		{desc: "emptyRange", req: &trillian.GetMergeDelayComplianceRequest{TreeId: logTree.TreeId, StartTime: end, EndTime: end}, want: codes.InvalidArgument},
+	} {
+		_, err := s.GetMergeDelayCompliance(ctx, test.req)
+		if got := status.Code(err); got != test.want {
+			t.Errorf("%v: GetMergeDelayCompliance() returned code %v, want %v", test.desc, got, test.want)
+		}
+	}
+}
+
+func TestServer_BatchGetTreeStatus(t *testing.T) {
+	ctx := context.Background()
+	ls := memory.NewLogStorage(nil)
+	as := memory.NewAdminStorage(ls)
+	logTree := createLogWithHistory(ctx, t, as, ls, 3)
+	mapTree, err := storage.CreateTree(ctx, as, testonly.MapTree)
+	if err != nil {
+		t.Fatalf("CreateTree(): %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		hash := sha256.Sum256([]byte{byte(i)})
+		leaf := &trillian.LogLeaf{LeafValue: []byte{byte(i)}, MerkleLeafHash: hash[:], LeafIdentityHash: hash[:]}
+		if _, err := ls.QueueLeaves(ctx, logTree.TreeId, []*trillian.LogLeaf{leaf}, time.Now()); err != nil {
+			t.Fatalf("QueueLeaves(): %v", err)
+		}
+	}
+	s := &Server{registry: extension.Registry{AdminStorage: as, LogStorage: ls}, timeSource: util.SystemTimeSource{}}
+	const missingID = 12345
+
+	resp, err := s.BatchGetTreeStatus(ctx, &trillian.BatchGetTreeStatusRequest{TreeIds: []int64{logTree.TreeId, mapTree.TreeId, missingID}})
+	if err != nil {
+		t.Fatalf("BatchGetTreeStatus(): %v", err)
+	}
+	if len(resp.Statuses) != 3 {
+		t.Fatalf("BatchGetTreeStatus(): got %d statuses, want 3", len(resp.Statuses))
+	}
+	logStatus := resp.Statuses[0]
+	if logStatus.TreeId != logTree.TreeId || logStatus.Status != nil || logStatus.TreeState != trillian.TreeState_ACTIVE {
+		t.Errorf("BatchGetTreeStatus(): got log status %v, want active log %d", logStatus, logTree.TreeId)
+	}
+	if got := logStatus.GetLatestLogRoot().GetTreeSize(); got != 3 {
+		t.Errorf("BatchGetTreeStatus(): got log root of size %d, want 3", got)
+	}
+	if got := logStatus.QueueDepth; got != 2 {
+		t.Errorf("BatchGetTreeStatus(): got queue depth %d, want 2", got)
+	}
+	// The server has no map storage, so can't read the map root.
+	if got := codes.Code(resp.Statuses[1].GetStatus().GetCode()); resp.Statuses[1].TreeId != mapTree.TreeId || got != codes.Unimplemented {
+		t.Errorf("BatchGetTreeStatus(): got map status %v, want tree %d with code %v", resp.Statuses[1], mapTree.TreeId, codes.Unimplemented)
+	}
+	if got := codes.Code(resp.Statuses[2].GetStatus().GetCode()); resp.Statuses[2].TreeId != missingID || got != codes.NotFound {
+		t.Errorf("BatchGetTreeStatus(): got missing tree status %v, want tree %d with code %v", resp.Statuses[2], missingID, codes.NotFound)
+	}
+
+	// Only the requested fields are read.
+	resp, err = s.BatchGetTreeStatus(ctx, &trillian.BatchGetTreeStatusRequest{
+		TreeIds:  []int64{logTree.TreeId, mapTree.TreeId},
+		ReadMask: &field_mask.FieldMask{Paths: []string{"tree_state"}},
+	})
+	if err != nil {
+		t.Fatalf("BatchGetTreeStatus(tree_state): %v", err)
+	}
+	for i, id := range []int64{logTree.TreeId, mapTree.TreeId} {
+		want := &trillian.TreeStatusEntry{TreeId: id, TreeState: trillian.TreeState_ACTIVE}
+		if got := resp.Statuses[i]; !proto.Equal(got, want) {
+			t.Errorf("BatchGetTreeStatus(tree_state): got status %v, want %v", got, want)
+		}
+	}
+
+	tooMany := make([]int64, maxBatchTreeIDs+1)
+	for _, test := range []struct {
+		desc string
+		req  *trillian.BatchGetTreeStatusRequest
+	}{
+		{desc: "noTrees", req: &trillian.BatchGetTreeStatusRequest{}},
+		{desc: "tooManyTrees", req: &trillian.BatchGetTreeStatusRequest{TreeIds: tooMany}},
+		{desc: "badMask", req: &trillian.BatchGetTreeStatusRequest{TreeIds: []int64{logTree.TreeId}, ReadMask: &field_mask.FieldMask{Paths: []string{"display_name"}}}},
+	} {
+		if _, err := s.BatchGetTreeStatus(ctx, test.req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%v: BatchGetTreeStatus() = %v, want code %v", test.desc, err, codes.InvalidArgument)
+		}
+	}
+}
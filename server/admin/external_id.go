@@ -0,0 +1,143 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"bytes"
+	"reflect"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/duration"
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ExternalIDLabel is the label recording the external identifier of a tree,
+// see trillian.CreateTreeRequest.ExternalId.
+const ExternalIDLabel = "trillian.external-id"
+
+// GetTreeByExternalId implements trillian.TrillianAdminServer.GetTreeByExternalId.
+func (s *Server) GetTreeByExternalId(ctx context.Context, req *trillian.GetTreeByExternalIdRequest) (*trillian.Tree, error) {
+	if req.GetExternalId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "an external_id is required")
+	}
+	tree, err := s.treeByExternalID(ctx, req.ExternalId, req.ShowDeleted)
+	if err != nil {
+		return nil, err
+	}
+	if tree == nil {
+		return nil, status.Errorf(codes.NotFound, "no tree with external_id %q", req.ExternalId)
+	}
+	return redact(tree), nil
+}
+
+// treeByExternalID returns the tree whose external identifier is id, or nil if
+// there's none.
+func (s *Server) treeByExternalID(ctx context.Context, id string, showDeleted bool) (*trillian.Tree, error) {
+	all, err := storage.ListTrees(ctx, s.registry.AdminStorage, showDeleted)
+	if err != nil {
+		return nil, err
+	}
+	var found []*trillian.Tree
+	for _, tree := range all {
+		if v, ok := tree.Labels[ExternalIDLabel]; ok && v == id {
+			found = append(found, tree)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, nil
+	case 1:
+		return found[0], nil
+	default:
+		// Only possible if the label was set by hand, e.g. through UpdateTree.
+		return nil, status.Errorf(codes.FailedPrecondition, "%d trees have external_id %q", len(found), id)
+	}
+}
+
+// setExternalID records id in the labels of tree, which is to be created.
+func setExternalID(tree *trillian.Tree, id string) error {
+	if v, ok := tree.Labels[ExternalIDLabel]; ok && v != id {
+		return status.Errorf(codes.InvalidArgument, "tree label %v is %q, doesn't match external_id %q", ExternalIDLabel, v, id)
+	}
+	if tree.Labels == nil {
+		tree.Labels = make(map[string]string)
+	}
+	tree.Labels[ExternalIDLabel] = id
+	return nil
+}
+
+// checkDeclaredTree returns an error unless the existing tree with an external
+// identifier matches declared, the tree of a request to create it again.
+// Fields set by the server, and the private key of the tree, which can't be
+// compared with a key_spec, are ignored.
+func checkDeclaredTree(existing, declared *trillian.Tree) error {
+	id := existing.Labels[ExternalIDLabel]
+	if existing.Deleted {
+		return status.Errorf(codes.FailedPrecondition, "tree %v with external_id %q is deleted, it must be undeleted first", existing.TreeId, id)
+	}
+
+	var diff []string
+	if existing.TreeType != declared.TreeType {
+		diff = append(diff, "tree_type")
+	}
+	if existing.TreeState != declared.TreeState {
+		diff = append(diff, "tree_state")
+	}
+	if existing.HashStrategy != declared.HashStrategy {
+		diff = append(diff, "hash_strategy")
+	}
+	if existing.HashAlgorithm != declared.HashAlgorithm {
+		diff = append(diff, "hash_algorithm")
+	}
+	if existing.SignatureAlgorithm != declared.SignatureAlgorithm {
+		diff = append(diff, "signature_algorithm")
+	}
+	if existing.DisplayName != declared.DisplayName {
+		diff = append(diff, "display_name")
+	}
+	if existing.Description != declared.Description {
+		diff = append(diff, "description")
+	}
+	if durationOf(existing.MaxRootDuration) != durationOf(declared.MaxRootDuration) {
+		diff = append(diff, "max_root_duration")
+	}
+	if !reflect.DeepEqual(existing.Labels, declared.Labels) {
+		diff = append(diff, "labels")
+	}
+	if declared.PublicKey != nil && !bytes.Equal(existing.GetPublicKey().GetDer(), declared.PublicKey.Der) {
+		diff = append(diff, "public_key")
+	}
+	if len(diff) > 0 {
+		return status.Errorf(codes.AlreadyExists, "tree %v with external_id %q already exists with a different %v", existing.TreeId, id, diff)
+	}
+	return nil
+}
+
+// durationOf returns the value of d, or zero if it's unset or malformed.
+func durationOf(d *duration.Duration) time.Duration {
+	if d == nil {
+		return 0
+	}
+	v, err := ptypes.Duration(d)
+	if err != nil {
+		return 0
+	}
+	return v
+}
@@ -0,0 +1,115 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian"
+	"github.com/google/trillian/extension"
+	"github.com/google/trillian/storage/memory"
+	"github.com/google/trillian/storage/testonly"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestServer_ExternalID(t *testing.T) {
+	ctx := context.Background()
+	ls := memory.NewLogStorage(nil)
+	s := &Server{registry: extension.Registry{AdminStorage: memory.NewAdminStorage(ls)}}
+	const id = "terraform/llamas"
+
+	createReq := func(modify func(*trillian.Tree)) *trillian.CreateTreeRequest {
+		tree := proto.Clone(testonly.LogTree).(*trillian.Tree)
+		tree.Labels = map[string]string{"env": "test"}
+		if modify != nil {
+			modify(tree)
+		}
+		return &trillian.CreateTreeRequest{Tree: tree, ExternalId: id}
+	}
+
+	created, err := s.CreateTree(ctx, createReq(nil))
+	if err != nil {
+		t.Fatalf("CreateTree(): %v", err)
+	}
+	if got := created.Labels[ExternalIDLabel]; got != id {
+		t.Errorf("CreateTree(): tree label %v = %q, want %q", ExternalIDLabel, got, id)
+	}
+
+	// Retrying the creation returns the same tree.
+	retried, err := s.CreateTree(ctx, createReq(nil))
+	if err != nil {
+		t.Fatalf("CreateTree() retried: %v", err)
+	}
+	if retried.TreeId != created.TreeId {
+		t.Errorf("CreateTree() retried: got tree %v, want %v", retried.TreeId, created.TreeId)
+	}
+
+	for _, test := range []struct {
+		desc    string
+		req     *trillian.CreateTreeRequest
+		wantErr codes.Code
+	}{
+		{
+			desc:    "differentDisplayName",
+			req:     createReq(func(tree *trillian.Tree) { tree.DisplayName = "Other Llamas" }),
+			wantErr: codes.AlreadyExists,
+		},
+		{
+			desc:    "differentLabels",
+			req:     createReq(func(tree *trillian.Tree) { tree.Labels["env"] = "prod" }),
+			wantErr: codes.AlreadyExists,
+		},
+		{
+			desc:    "conflictingLabel",
+			req:     createReq(func(tree *trillian.Tree) { tree.Labels[ExternalIDLabel] = "other" }),
+			wantErr: codes.InvalidArgument,
+		},
+	} {
+		if _, err := s.CreateTree(ctx, test.req); status.Code(err) != test.wantErr {
+			t.Errorf("%v: CreateTree() = %v, want code %v", test.desc, err, test.wantErr)
+		}
+	}
+
+	got, err := s.GetTreeByExternalId(ctx, &trillian.GetTreeByExternalIdRequest{ExternalId: id})
+	if err != nil {
+		t.Fatalf("GetTreeByExternalId(): %v", err)
+	}
+	if got.TreeId != created.TreeId || got.PrivateKey != nil {
+		t.Errorf("GetTreeByExternalId(): got tree %v, want redacted tree %v", got.TreeId, created.TreeId)
+	}
+	if _, err := s.GetTreeByExternalId(ctx, &trillian.GetTreeByExternalIdRequest{ExternalId: "other"}); status.Code(err) != codes.NotFound {
+		t.Errorf("GetTreeByExternalId(other) = %v, want code %v", err, codes.NotFound)
+	}
+	if _, err := s.GetTreeByExternalId(ctx, &trillian.GetTreeByExternalIdRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("GetTreeByExternalId() without ID = %v, want code %v", err, codes.InvalidArgument)
+	}
+
+	// A deleted tree is only returned if asked for, and isn't recreated.
+	if _, err := s.DeleteTree(ctx, &trillian.DeleteTreeRequest{TreeId: created.TreeId}); err != nil {
+		t.Fatalf("DeleteTree(): %v", err)
+	}
+	if _, err := s.GetTreeByExternalId(ctx, &trillian.GetTreeByExternalIdRequest{ExternalId: id}); status.Code(err) != codes.NotFound {
+		t.Errorf("GetTreeByExternalId() of deleted tree = %v, want code %v", err, codes.NotFound)
+	}
+	if got, err := s.GetTreeByExternalId(ctx, &trillian.GetTreeByExternalIdRequest{ExternalId: id, ShowDeleted: true}); err != nil || !got.Deleted {
+		t.Errorf("GetTreeByExternalId(show_deleted) = %v, %v, want deleted tree", got, err)
+	}
+	if _, err := s.CreateTree(ctx, createReq(nil)); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("CreateTree() of deleted tree = %v, want code %v", err, codes.FailedPrecondition)
+	}
+}
@@ -0,0 +1,359 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/trillian"
+	"github.com/google/trillian/merkle"
+	"github.com/google/trillian/merkle/hashers"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Labels recording the verdict of the latest completed integrity check of a
+// log, so that it outlives the server which ran the check.
+const (
+	// IntegrityStateLabel is "passed" or "failed".
+	IntegrityStateLabel = "trillian.integrity.state"
+	// IntegrityRevisionLabel is the revision of the root the log was checked
+	// at.
+	IntegrityRevisionLabel = "trillian.integrity.revision"
+	// IntegrityTimeLabel is the time at which the check completed, in RFC 3339
+	// format.
+	IntegrityTimeLabel = "trillian.integrity.time"
+)
+
+const (
+	// defaultIntegrityBatchSize is the number of leaves an IntegrityChecker
+	// reads from storage at once.
+	defaultIntegrityBatchSize = 1000
+	// integrityProofSamples is the number of leaves whose inclusion proofs,
+	// built from the stored Merkle nodes, are checked against the root.
+	integrityProofSamples = 16
+)
+
+// integrityFailure is an error reporting data in storage which is
+// inconsistent, as opposed to an error reading it.
+type integrityFailure struct {
+	msg string
+}
+
+func (f integrityFailure) Error() string {
+	return f.msg
+}
+
+func failuref(format string, args ...interface{}) error {
+	return integrityFailure{msg: fmt.Sprintf(format, args...)}
+}
+
+// IntegrityChecker checks the integrity of the storage of logs in the
+// background. A check of a log at one of its roots:
+//   - reads every leaf of the root, and checks its index and leaf hash;
+//   - checks that the root hash computed from the leaf hashes is the stored
+//     one;
+//   - checks the inclusion proofs of a sample of leaves, built from the stored
+//     Merkle nodes, against the root.
+//
+// The state of the latest check of each log is kept in memory, and the
+// verdict of completed checks is recorded in the labels of the log.
+type IntegrityChecker struct {
+	admin      storage.AdminStorage
+	logStorage storage.LogStorage
+	timeSource util.TimeSource
+	batchSize  int64
+
+	mu     sync.Mutex
+	checks map[int64]*trillian.TreeIntegrityCheck
+}
+
+// NewIntegrityChecker returns an IntegrityChecker for the logs stored in
+// admin and logStorage.
+func NewIntegrityChecker(admin storage.AdminStorage, logStorage storage.LogStorage, timeSource util.TimeSource) *IntegrityChecker {
+	return &IntegrityChecker{
+		admin:      admin,
+		logStorage: logStorage,
+		timeSource: timeSource,
+		batchSize:  defaultIntegrityBatchSize,
+		checks:     make(map[int64]*trillian.TreeIntegrityCheck),
+	}
+}
+
+// Start starts checking the log treeID at the root of the given revision, or
+// its latest root if revision is zero, and returns the initial state of the
+// check. It fails with AlreadyExists if a check of the log is running.
+func (c *IntegrityChecker) Start(ctx context.Context, treeID, revision int64) (*trillian.TreeIntegrityCheck, error) {
+	if revision < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "revision is %d, want >= 0", revision)
+	}
+	if c.logStorage == nil {
+		return nil, status.Error(codes.Unimplemented, "log storage is not available from this server")
+	}
+	tree, err := storage.GetTree(ctx, c.admin, treeID)
+	if err != nil {
+		return nil, err
+	}
+	if !isLog(tree) {
+		return nil, status.Errorf(codes.InvalidArgument, "tree %d is a %v, only logs can be checked", treeID, tree.TreeType)
+	}
+	hasher, err := hashers.NewLogHasher(tree.HashStrategy)
+	if err != nil {
+		return nil, err
+	}
+	root, err := c.root(ctx, treeID, revision)
+	if err != nil {
+		return nil, err
+	}
+	start, err := ptypes.TimestampProto(c.timeSource.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if prev, ok := c.checks[treeID]; ok && prev.State == trillian.IntegrityCheckState_INTEGRITY_CHECK_RUNNING {
+		return nil, status.Errorf(codes.AlreadyExists, "a check of tree %d at revision %d is running", treeID, prev.Revision)
+	}
+	check := &trillian.TreeIntegrityCheck{
+		TreeId:    treeID,
+		Revision:  root.TreeRevision,
+		TreeSize:  root.TreeSize,
+		State:     trillian.IntegrityCheckState_INTEGRITY_CHECK_RUNNING,
+		StartTime: start,
+	}
+	c.checks[treeID] = check
+	// The check outlives the request which started it.
+	go c.run(context.Background(), hasher, root, check)
+	return proto.Clone(check).(*trillian.TreeIntegrityCheck), nil
+}
+
+// Get returns the state of the latest check of the log treeID. If it wasn't
+// run by this IntegrityChecker, it's read from the labels of the log, without
+// the details of the check. It fails with NotFound if the log was never
+// checked.
+func (c *IntegrityChecker) Get(ctx context.Context, treeID int64) (*trillian.TreeIntegrityCheck, error) {
+	c.mu.Lock()
+	check, ok := c.checks[treeID]
+	if ok {
+		check = proto.Clone(check).(*trillian.TreeIntegrityCheck)
+	}
+	c.mu.Unlock()
+	if ok {
+		return check, nil
+	}
+
+	tree, err := storage.GetTree(ctx, c.admin, treeID)
+	if err != nil {
+		return nil, err
+	}
+	check = checkFromLabels(tree)
+	if check == nil {
+		return nil, status.Errorf(codes.NotFound, "tree %d has no integrity check", treeID)
+	}
+	return check, nil
+}
+
+// root returns the root of the log at revision, or its latest root if
+// revision is zero.
+func (c *IntegrityChecker) root(ctx context.Context, treeID, revision int64) (*trillian.SignedLogRoot, error) {
+	tx, err := c.logStorage.SnapshotForTree(ctx, treeID)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Close()
+	latest, err := tx.LatestSignedLogRoot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	switch {
+	case revision == 0 || revision == latest.TreeRevision:
+		return &latest, nil
+	case revision > latest.TreeRevision:
+		return nil, status.Errorf(codes.InvalidArgument, "revision %d is beyond the latest root of tree %d, at revision %d", revision, treeID, latest.TreeRevision)
+	}
+	reader, ok := c.logStorage.(storage.LogRootReader)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "log storage can only check the latest root")
+	}
+	root, err := reader.GetSignedLogRoot(ctx, treeID, revision)
+	if err != nil {
+		return nil, err
+	}
+	return &root, nil
+}
+
+// run runs check to completion, and records its verdict.
+func (c *IntegrityChecker) run(ctx context.Context, hasher hashers.LogHasher, root *trillian.SignedLogRoot, check *trillian.TreeIntegrityCheck) {
+	treeID := check.TreeId
+	err := c.check(ctx, hasher, treeID, root, check)
+	end := c.timeSource.Now()
+
+	c.mu.Lock()
+	switch err.(type) {
+	case nil:
+		check.State = trillian.IntegrityCheckState_INTEGRITY_CHECK_PASSED
+	case integrityFailure:
+		check.State = trillian.IntegrityCheckState_INTEGRITY_CHECK_FAILED
+		check.Failure = err.Error()
+	default:
+		check.State = trillian.IntegrityCheckState_INTEGRITY_CHECK_ABORTED
+		check.Failure = err.Error()
+	}
+	check.EndTime, _ = ptypes.TimestampProto(end)
+	state := check.State
+	c.mu.Unlock()
+
+	glog.Infof("Integrity check of tree %d at revision %d: %v %s", treeID, root.TreeRevision, state, check.Failure)
+	if state == trillian.IntegrityCheckState_INTEGRITY_CHECK_ABORTED {
+		// The check says nothing about the log, so the previous verdict
+		// stands.
+		return
+	}
+	if _, err := storage.UpdateTree(ctx, c.admin, treeID, func(tree *trillian.Tree) {
+		if tree.Labels == nil {
+			tree.Labels = make(map[string]string)
+		}
+		tree.Labels[IntegrityStateLabel] = stateLabel(state)
+		tree.Labels[IntegrityRevisionLabel] = strconv.FormatInt(root.TreeRevision, 10)
+		tree.Labels[IntegrityTimeLabel] = end.UTC().Format(time.RFC3339)
+	}); err != nil {
+		glog.Errorf("Failed to record integrity check verdict of tree %d: %v", treeID, err)
+	}
+}
+
+// check checks the log at root, updating the progress of check as it goes.
+// It returns an integrityFailure if storage is inconsistent.
+func (c *IntegrityChecker) check(ctx context.Context, hasher hashers.LogHasher, treeID int64, root *trillian.SignedLogRoot, check *trillian.TreeIntegrityCheck) error {
+	cmt := merkle.NewCompactMerkleTree(hasher)
+	for start := int64(0); start < root.TreeSize; start += c.batchSize {
+		count := root.TreeSize - start
+		if count > c.batchSize {
+			count = c.batchSize
+		}
+		leaves, err := c.readLeaves(ctx, treeID, start, count)
+		if err != nil {
+			return err
+		}
+		if got := int64(len(leaves)); got != count {
+			return failuref("got %d leaves from index %d, want %d", got, start, count)
+		}
+		for i, leaf := range leaves {
+			index := start + int64(i)
+			if leaf.LeafIndex != index {
+				return failuref("got leaf %d at index %d", leaf.LeafIndex, index)
+			}
+			hash, err := hasher.HashLeaf(leaf.LeafValue)
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(hash, leaf.MerkleLeafHash) {
+				return failuref("leaf %d: stored leaf hash %x, want %x", index, leaf.MerkleLeafHash, hash)
+			}
+			if _, err := cmt.AddLeafHash(hash, func(int, int64, []byte) error { return nil }); err != nil {
+				return err
+			}
+		}
+		c.mu.Lock()
+		check.LeavesChecked = start + count
+		c.mu.Unlock()
+	}
+	if got := cmt.CurrentRoot(); !bytes.Equal(got, root.RootHash) {
+		return failuref("leaves hash to root %x, stored root is %x", got, root.RootHash)
+	}
+	if root.TreeSize == 0 {
+		return nil
+	}
+
+	tx, err := c.logStorage.SnapshotForTree(ctx, treeID)
+	if err != nil {
+		return err
+	}
+	defer tx.Close()
+	for _, index := range sampleLeaves(root.TreeSize, integrityProofSamples) {
+		if err := verifyStoredInclusion(ctx, tx, hasher, root, index); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// readLeaves reads count leaves from start in a transaction of its own, so
+// that checks of large logs don't hold a long-lived transaction.
+func (c *IntegrityChecker) readLeaves(ctx context.Context, treeID, start, count int64) ([]*trillian.LogLeaf, error) {
+	tx, err := c.logStorage.SnapshotForTree(ctx, treeID)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Close()
+	leaves, err := tx.GetLeavesByRange(ctx, start, count)
+	if err != nil {
+		return nil, err
+	}
+	return leaves, tx.Commit()
+}
+
+// sampleLeaves returns up to n indices spread evenly over a tree of size
+// treeSize, including its first and last leaves.
+func sampleLeaves(treeSize int64, n int) []int64 {
+	if treeSize <= int64(n) {
+		ret := make([]int64, treeSize)
+		for i := range ret {
+			ret[i] = int64(i)
+		}
+		return ret
+	}
+	ret := make([]int64, n)
+	for i := range ret {
+		ret[i] = int64(i) * (treeSize - 1) / int64(n-1)
+	}
+	return ret
+}
+
+func stateLabel(state trillian.IntegrityCheckState) string {
+	return strings.ToLower(strings.TrimPrefix(state.String(), "INTEGRITY_CHECK_"))
+}
+
+// checkFromLabels returns the check recorded in the labels of tree, or nil if
+// there's none.
+func checkFromLabels(tree *trillian.Tree) *trillian.TreeIntegrityCheck {
+	state, ok := trillian.IntegrityCheckState_value["INTEGRITY_CHECK_"+strings.ToUpper(tree.Labels[IntegrityStateLabel])]
+	if !ok {
+		return nil
+	}
+	check := &trillian.TreeIntegrityCheck{
+		TreeId: tree.TreeId,
+		State:  trillian.IntegrityCheckState(state),
+	}
+	check.Revision, _ = strconv.ParseInt(tree.Labels[IntegrityRevisionLabel], 10, 64)
+	if end, err := time.Parse(time.RFC3339, tree.Labels[IntegrityTimeLabel]); err == nil {
+		check.EndTime, _ = ptypes.TimestampProto(end)
+	}
+	return check
+}
@@ -0,0 +1,175 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/extension"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/memory"
+	"github.com/google/trillian/storage/testonly"
+	"github.com/google/trillian/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// waitForCheck polls s until the check of treeID is no longer running.
+func waitForCheck(ctx context.Context, t *testing.T, s *Server, treeID int64) *trillian.TreeIntegrityCheck {
+	t.Helper()
+	for i := 0; i < 1000; i++ {
+		check, err := s.GetTreeIntegrityCheck(ctx, &trillian.GetTreeIntegrityCheckRequest{TreeId: treeID})
+		if err != nil {
+			t.Fatalf("GetTreeIntegrityCheck(): %v", err)
+		}
+		if check.State != trillian.IntegrityCheckState_INTEGRITY_CHECK_RUNNING {
+			return check
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Integrity check of tree %d didn't complete", treeID)
+	return nil
+}
+
+func TestCheckTreeIntegrity(t *testing.T) {
+	ctx := context.Background()
+	ls := memory.NewLogStorage(nil)
+	as := memory.NewAdminStorage(ls)
+	ts := util.NewFakeTimeSource(time.Unix(1500000000, 0))
+	s := New(extension.Registry{AdminStorage: as, LogStorage: ls}, nil, ts)
+	s.integrity.batchSize = 7
+
+	for _, test := range []struct {
+		desc      string
+		batches   []int
+		revision  int64
+		corrupt   int64
+		wantState trillian.IntegrityCheckState
+		wantRev   int64
+		wantSize  int64
+	}{
+		{desc: "empty", wantState: trillian.IntegrityCheckState_INTEGRITY_CHECK_PASSED},
+		{desc: "latest", batches: []int{1, 4, 100}, corrupt: -1, wantState: trillian.IntegrityCheckState_INTEGRITY_CHECK_PASSED, wantRev: 3, wantSize: 105},
+		{desc: "revision", batches: []int{1, 4, 100}, revision: 2, corrupt: -1, wantState: trillian.IntegrityCheckState_INTEGRITY_CHECK_PASSED, wantRev: 2, wantSize: 5},
+		{desc: "corruptLeaf", batches: []int{1, 4, 100}, corrupt: 42, wantState: trillian.IntegrityCheckState_INTEGRITY_CHECK_FAILED, wantRev: 3, wantSize: 105},
+		// Leaves beyond the root being checked don't matter.
+		{desc: "corruptLaterLeaf", batches: []int{1, 4, 100}, revision: 2, corrupt: 42, wantState: trillian.IntegrityCheckState_INTEGRITY_CHECK_PASSED, wantRev: 2, wantSize: 5},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			tree := createLogWithHistory(ctx, t, as, ls, test.batches...)
+			if test.corrupt > 0 {
+				corruptLeaf(ctx, t, ls, tree.TreeId, test.corrupt)
+			}
+
+			check, err := s.CheckTreeIntegrity(ctx, &trillian.CheckTreeIntegrityRequest{TreeId: tree.TreeId, Revision: test.revision})
+			if err != nil {
+				t.Fatalf("CheckTreeIntegrity(): %v", err)
+			}
+			if got, want := check.Revision, test.wantRev; got != want {
+				t.Errorf("CheckTreeIntegrity().Revision = %d, want %d", got, want)
+			}
+			check = waitForCheck(ctx, t, s, tree.TreeId)
+			if got, want := check.State, test.wantState; got != want {
+				t.Fatalf("check state = %v (%q), want %v", got, check.Failure, want)
+			}
+			if got, want := check.TreeSize, test.wantSize; got != want {
+				t.Errorf("check TreeSize = %d, want %d", got, want)
+			}
+			if check.State == trillian.IntegrityCheckState_INTEGRITY_CHECK_PASSED && check.LeavesChecked != test.wantSize {
+				t.Errorf("check LeavesChecked = %d, want %d", check.LeavesChecked, test.wantSize)
+			}
+
+			// The verdict is recorded in the tree's labels.
+			stored, err := storage.GetTree(ctx, as, tree.TreeId)
+			if err != nil {
+				t.Fatalf("GetTree(): %v", err)
+			}
+			if got, want := stored.Labels[IntegrityStateLabel], stateLabel(test.wantState); got != want {
+				t.Errorf("%v label = %q, want %q", IntegrityStateLabel, got, want)
+			}
+			if got, want := stored.Labels[IntegrityRevisionLabel], strconv.FormatInt(test.wantRev, 10); got != want {
+				t.Errorf("%v label = %q, want %q", IntegrityRevisionLabel, got, want)
+			}
+
+			// A server which didn't run the check reads it from the labels.
+			other := New(extension.Registry{AdminStorage: as, LogStorage: ls}, nil, ts)
+			got, err := other.GetTreeIntegrityCheck(ctx, &trillian.GetTreeIntegrityCheckRequest{TreeId: tree.TreeId})
+			if err != nil {
+				t.Fatalf("GetTreeIntegrityCheck(): %v", err)
+			}
+			want := &trillian.TreeIntegrityCheck{TreeId: tree.TreeId, Revision: test.wantRev, State: test.wantState, EndTime: check.EndTime}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("GetTreeIntegrityCheck() from labels = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+// corruptLeaf changes the value of the leaf at index in memory storage,
+// without updating its hash.
+func corruptLeaf(ctx context.Context, t *testing.T, ls storage.LogStorage, treeID, index int64) {
+	t.Helper()
+	if err := ls.ReadWriteTransaction(ctx, treeID, func(ctx context.Context, tx storage.LogTreeTX) error {
+		leaves, err := tx.GetLeavesByIndex(ctx, []int64{index})
+		if err != nil {
+			return err
+		}
+		// Memory storage returns the stored leaves.
+		leaves[0].LeafValue = []byte("corrupt")
+		return nil
+	}); err != nil {
+		t.Fatalf("Failed to corrupt leaf %d: %v", index, err)
+	}
+}
+
+func TestCheckTreeIntegrityErrors(t *testing.T) {
+	ctx := context.Background()
+	ls := memory.NewLogStorage(nil)
+	as := memory.NewAdminStorage(ls)
+	s := New(extension.Registry{AdminStorage: as, LogStorage: ls}, nil, util.SystemTimeSource{})
+
+	log := createLogWithHistory(ctx, t, as, ls, 3)
+	mapTree, err := storage.CreateTree(ctx, as, testonly.MapTree)
+	if err != nil {
+		t.Fatalf("CreateTree(): %v", err)
+	}
+
+	for _, test := range []struct {
+		desc     string
+		req      *trillian.CheckTreeIntegrityRequest
+		wantCode codes.Code
+	}{
+		{desc: "unknownTree", req: &trillian.CheckTreeIntegrityRequest{TreeId: 12345}, wantCode: codes.NotFound},
+		{desc: "map", req: &trillian.CheckTreeIntegrityRequest{TreeId: mapTree.TreeId}, wantCode: codes.InvalidArgument},
+		{desc: "negativeRevision", req: &trillian.CheckTreeIntegrityRequest{TreeId: log.TreeId, Revision: -1}, wantCode: codes.InvalidArgument},
+		{desc: "futureRevision", req: &trillian.CheckTreeIntegrityRequest{TreeId: log.TreeId, Revision: 2}, wantCode: codes.InvalidArgument},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			_, err := s.CheckTreeIntegrity(ctx, test.req)
+			if got := status.Code(err); got != test.wantCode {
+				t.Errorf("CheckTreeIntegrity(): %v, want code %v", err, test.wantCode)
+			}
+		})
+	}
+
+	if _, err := s.GetTreeIntegrityCheck(ctx, &trillian.GetTreeIntegrityCheckRequest{TreeId: log.TreeId}); status.Code(err) != codes.NotFound {
+		t.Errorf("GetTreeIntegrityCheck() of unchecked tree: %v, want code %v", err, codes.NotFound)
+	}
+}
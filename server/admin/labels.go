@@ -0,0 +1,87 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// labelRequirement is a single requirement of a label selector.
+type labelRequirement struct {
+	key string
+	// value is the value the label must have, if any.
+	value *string
+	// negate inverts the requirement, i.e. the label mustn't be set or mustn't
+	// have value.
+	negate bool
+}
+
+func (r labelRequirement) matches(labels map[string]string) bool {
+	v, ok := labels[r.key]
+	if r.value != nil {
+		ok = ok && v == *r.value
+	}
+	return ok != r.negate
+}
+
+// labelSelector selects trees whose labels meet all of its requirements.
+// See trillian.ListTreesRequest for its syntax.
+type labelSelector []labelRequirement
+
+// parseLabelSelector parses a label selector. An empty selector matches all
+// trees.
+func parseLabelSelector(s string) (labelSelector, error) {
+	var sel labelSelector
+	if strings.TrimSpace(s) == "" {
+		return sel, nil
+	}
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+		var r labelRequirement
+		switch {
+		case strings.Contains(term, "!="):
+			parts := strings.SplitN(term, "!=", 2)
+			r = labelRequirement{key: strings.TrimSpace(parts[0]), value: &parts[1], negate: true}
+		case strings.Contains(term, "="):
+			parts := strings.SplitN(term, "=", 2)
+			r = labelRequirement{key: strings.TrimSpace(parts[0]), value: &parts[1]}
+		case strings.HasPrefix(term, "!"):
+			r = labelRequirement{key: strings.TrimSpace(term[1:]), negate: true}
+		default:
+			r = labelRequirement{key: term}
+		}
+		if r.key == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid label_selector %q: missing label key in %q", s, term)
+		}
+		if r.value != nil {
+			*r.value = strings.TrimSpace(*r.value)
+		}
+		sel = append(sel, r)
+	}
+	return sel, nil
+}
+
+// matches returns true if labels meet all the requirements of the selector.
+func (sel labelSelector) matches(labels map[string]string) bool {
+	for _, r := range sel {
+		if !r.matches(labels) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,68 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLabelSelector(t *testing.T) {
+	labels := map[string]string{"env": "prod", "customer": "acme", "empty": ""}
+
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{selector: "", want: true},
+		{selector: "env=prod", want: true},
+		{selector: "env=test", want: false},
+		{selector: "env!=test", want: true},
+		{selector: "env!=prod", want: false},
+		{selector: "other!=prod", want: true},
+		{selector: "env", want: true},
+		{selector: "other", want: false},
+		{selector: "!other", want: true},
+		{selector: "!env", want: false},
+		{selector: "empty=", want: true},
+		{selector: "empty", want: true},
+		{selector: "other=", want: false},
+		{selector: "env=prod,customer=acme", want: true},
+		{selector: " env = prod , customer ", want: true},
+		{selector: "env=prod,customer=other", want: false},
+		{selector: "env=prod,!customer", want: false},
+	}
+	for _, test := range tests {
+		sel, err := parseLabelSelector(test.selector)
+		if err != nil {
+			t.Errorf("parseLabelSelector(%q) returned err = %v", test.selector, err)
+			continue
+		}
+		if got := sel.matches(labels); got != test.want {
+			t.Errorf("parseLabelSelector(%q).matches(%v) = %v, want %v", test.selector, labels, got, test.want)
+		}
+	}
+}
+
+func TestLabelSelectorErrors(t *testing.T) {
+	for _, selector := range []string{",", "env=prod,", "=prod", "!=prod", "!", "env,,customer"} {
+		_, err := parseLabelSelector(selector)
+		if got, want := status.Code(err), codes.InvalidArgument; got != want {
+			t.Errorf("parseLabelSelector(%q) returned err = %v, want code %v", selector, err, want)
+		}
+	}
+}
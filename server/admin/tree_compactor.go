@@ -0,0 +1,261 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/google/trillian"
+	"github.com/google/trillian/merkle"
+	"github.com/google/trillian/merkle/hashers"
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	compactionCounter        monitoring.Counter
+	compactionRemovedCounter monitoring.Counter
+	compactorMetricsOnce     sync.Once
+)
+
+// FrozenTreeCompactor compacts the storage of frozen logs.
+//
+// Storage keeps a revision of each Merkle subtree for every root at which it
+// changed, so that proofs can be served at any root. Once a log is frozen its
+// latest root is the only one proofs are served at, and the older revisions
+// are pure overhead. FrozenTreeCompactor rewrites the nodes of each frozen log
+// into a single revision, see CompactFrozenTree.
+type FrozenTreeCompactor struct {
+	admin      storage.AdminStorage
+	logStorage storage.LogStorage
+
+	// minRunInterval defines how frequently sweeps for frozen trees are performed.
+	// Actual runs happen randomly between [minInterval,2*minInterval).
+	minRunInterval time.Duration
+
+	// compacted holds the IDs of the trees already compacted by this instance.
+	compacted map[int64]bool
+}
+
+// NewFrozenTreeCompactor returns a new FrozenTreeCompactor.
+func NewFrozenTreeCompactor(admin storage.AdminStorage, logStorage storage.LogStorage, minRunInterval time.Duration, mf monitoring.MetricFactory) *FrozenTreeCompactor {
+	c := &FrozenTreeCompactor{
+		admin:          admin,
+		logStorage:     logStorage,
+		minRunInterval: minRunInterval,
+		compacted:      make(map[int64]bool),
+	}
+	compactorMetricsOnce.Do(func() {
+		if mf == nil {
+			mf = monitoring.InertMetricFactory{}
+		}
+		compactionCounter = mf.NewCounter("tree_compaction_counter", "Counter of compacted frozen trees", monitoring.TreeIDLabel, "success")
+		compactionRemovedCounter = mf.NewCounter("tree_compaction_removed_revisions", "Number of subtree revisions removed by compaction", monitoring.TreeIDLabel)
+	})
+	return c
+}
+
+// Run starts the frozen tree compaction process. It runs until ctx is cancelled.
+func (c *FrozenTreeCompactor) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		count, err := c.RunOnce(ctx)
+		if err != nil {
+			glog.Errorf("FrozenTreeCompactor.Run: %v", err)
+		}
+		if count > 0 {
+			glog.Infof("FrozenTreeCompactor.Run: successfully compacted %v trees", count)
+		}
+
+		d := c.minRunInterval + time.Duration(rand.Int63n(c.minRunInterval.Nanoseconds()))
+		timeSleep(d)
+	}
+}
+
+// RunOnce performs a single frozen tree compaction sweep. Returns the number of successfully
+// compacted trees.
+//
+// It attempts to compact as many frozen logs as possible, regardless of failures. If it
+// encounters any failures while compacting the resulting error is non-nil.
+func (c *FrozenTreeCompactor) RunOnce(ctx context.Context) (int, error) {
+	trees, err := storage.ListTrees(ctx, c.admin, false /* includeDeleted */)
+	if err != nil {
+		return 0, fmt.Errorf("error listing trees: %v", err)
+	}
+
+	count := 0
+	var errs []error
+	for _, tree := range trees {
+		if tree.TreeState != trillian.TreeState_FROZEN || !isLog(tree) || c.compacted[tree.TreeId] {
+			continue
+		}
+
+		removed, err := CompactFrozenTree(ctx, c.admin, c.logStorage, tree.TreeId)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error compacting tree %v: %v", tree.TreeId, err))
+			compactionCounter.Inc(fmt.Sprint(tree.TreeId), fmt.Sprint(false))
+			continue
+		}
+		glog.Infof("FrozenTreeCompactor.RunOnce: Compacted tree %v, removing %v subtree revisions", tree.TreeId, removed)
+
+		c.compacted[tree.TreeId] = true
+		count++
+		compactionCounter.Inc(fmt.Sprint(tree.TreeId), fmt.Sprint(true))
+		compactionRemovedCounter.Add(float64(removed), fmt.Sprint(tree.TreeId))
+	}
+
+	if len(errs) == 0 {
+		return count, nil
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("encountered errors compacting trees:")
+	for _, err := range errs {
+		buf.WriteString("\n\t")
+		buf.WriteString(err.Error())
+	}
+	return count, errors.New(buf.String())
+}
+
+// CompactFrozenTree rewrites the stored Merkle nodes of a frozen log, keeping
+// a single revision of each subtree: the one current at the log's latest root.
+// It checks that the nodes in storage match the root hash of the latest root
+// both before and after compacting them. Returns the number of subtree
+// revisions removed.
+func CompactFrozenTree(ctx context.Context, admin storage.AdminStorage, logStorage storage.LogStorage, treeID int64) (int64, error) {
+	compactor, ok := logStorage.(storage.LogCompactor)
+	if !ok {
+		return 0, status.Error(codes.Unimplemented, "log storage does not support compaction")
+	}
+	tree, err := storage.GetTree(ctx, admin, treeID)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case !isLog(tree):
+		return 0, status.Errorf(codes.InvalidArgument, "tree %v is not a log", treeID)
+	case tree.TreeState != trillian.TreeState_FROZEN:
+		return 0, status.Errorf(codes.FailedPrecondition, "tree %v is not frozen", treeID)
+	}
+	hasher, err := hashers.NewLogHasher(tree.HashStrategy)
+	if err != nil {
+		return 0, err
+	}
+
+	before, err := verifyStoredRoot(ctx, logStorage, hasher, treeID)
+	if err != nil {
+		return 0, fmt.Errorf("stored nodes don't match root before compaction: %v", err)
+	}
+	removed, err := compactor.CompactTree(ctx, treeID, before.TreeRevision)
+	if err != nil {
+		return 0, err
+	}
+	after, err := verifyStoredRoot(ctx, logStorage, hasher, treeID)
+	if err != nil {
+		return removed, fmt.Errorf("stored nodes don't match root after compaction: %v", err)
+	}
+	if after.TreeRevision != before.TreeRevision {
+		return removed, fmt.Errorf("root changed from revision %d to %d during compaction", before.TreeRevision, after.TreeRevision)
+	}
+	return removed, nil
+}
+
+// verifyStoredRoot checks that the Merkle nodes of the log's latest root
+// produce its root hash, by computing the root from the stored hashes and
+// inclusion proofs of its first and last leaves, and returns the root.
+func verifyStoredRoot(ctx context.Context, logStorage storage.LogStorage, hasher hashers.LogHasher, treeID int64) (*trillian.SignedLogRoot, error) {
+	tx, err := logStorage.SnapshotForTree(ctx, treeID)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Close()
+
+	root, err := tx.LatestSignedLogRoot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if root.TreeSize == 0 {
+		if !bytes.Equal(root.RootHash, hasher.EmptyRoot()) {
+			return nil, failuref("empty tree has root hash %x", root.RootHash)
+		}
+		return &root, tx.Commit()
+	}
+
+	for _, index := range []int64{0, root.TreeSize - 1} {
+		if err := verifyStoredInclusion(ctx, tx, hasher, &root, index); err != nil {
+			return nil, err
+		}
+	}
+	return &root, tx.Commit()
+}
+
+// verifyStoredInclusion checks that the stored hash of the leaf at index and
+// its inclusion proof, built from stored nodes, match root.
+func verifyStoredInclusion(ctx context.Context, tx storage.NodeReader, hasher hashers.LogHasher, root *trillian.SignedLogRoot, index int64) error {
+	fetches, err := merkle.InclusionProofNodes(root.TreeSize, index, root.TreeSize)
+	if err != nil {
+		return err
+	}
+	leafID, err := storage.NewNodeIDForTreeCoords(0, index, merkle.LogNodeIDBitLen)
+	if err != nil {
+		return err
+	}
+	ids := append(merkle.NodeIDs(fetches), leafID)
+	nodes, err := tx.GetMerkleNodes(ctx, root.TreeRevision, ids)
+	if err != nil {
+		return err
+	}
+	if len(nodes) != len(ids) {
+		return failuref("got %d nodes from storage, want %d", len(nodes), len(ids))
+	}
+	for i, node := range nodes {
+		if !node.NodeID.Equivalent(ids[i]) {
+			return failuref("got node %v at position %d, want %v", node.NodeID, i, ids[i])
+		}
+	}
+
+	// Runs of nodes to rehash collapse into a single proof hash.
+	var proof [][]byte
+	for i, fetch := range fetches {
+		if fetch.Rehash && i > 0 && fetches[i-1].Rehash {
+			proof[len(proof)-1] = hasher.HashChildren(nodes[i].Hash, proof[len(proof)-1])
+			continue
+		}
+		proof = append(proof, nodes[i].Hash)
+	}
+	leafHash := nodes[len(nodes)-1].Hash
+	if err := merkle.NewLogVerifier(hasher).VerifyInclusionProof(index, root.TreeSize, proof, root.RootHash, leafHash); err != nil {
+		return failuref("leaf %d: %v", index, err)
+	}
+	return nil
+}
+
+func isLog(tree *trillian.Tree) bool {
+	return tree.TreeType == trillian.TreeType_LOG || tree.TreeType == trillian.TreeType_PREORDERED_LOG
+}
@@ -0,0 +1,152 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/log"
+	"github.com/google/trillian/merkle/rfc6962"
+	"github.com/google/trillian/quota"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/memory"
+	"github.com/google/trillian/storage/testonly"
+	"github.com/google/trillian/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	tcrypto "github.com/google/trillian/crypto"
+)
+
+// createLogWithHistory creates a log in storage and integrates leaves into it
+// in batches of the given sizes, so that its nodes have several revisions.
+func createLogWithHistory(ctx context.Context, t *testing.T, as storage.AdminStorage, ls storage.LogStorage, batches ...int) *trillian.Tree {
+	t.Helper()
+	tree, err := storage.CreateTree(ctx, as, testonly.LogTree)
+	if err != nil {
+		t.Fatalf("CreateTree(): %v", err)
+	}
+	if err := ls.ReadWriteTransaction(ctx, tree.TreeId, func(ctx context.Context, tx storage.LogTreeTX) error {
+		return tx.StoreSignedLogRoot(ctx, trillian.SignedLogRoot{LogId: tree.TreeId, RootHash: testonly.LogTreeEmptyRootHash})
+	}); err != nil {
+		t.Fatalf("StoreSignedLogRoot(): %v", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey(): %v", err)
+	}
+	hasher := rfc6962.DefaultHasher
+	sequencer := log.NewSequencer(hasher, util.SystemTimeSource{}, ls, tcrypto.NewSHA256Signer(key), nil, quota.Noop())
+	next := 0
+	for _, size := range batches {
+		leaves := make([]*trillian.LogLeaf, 0, size)
+		for i := 0; i < size; i++ {
+			value := []byte(fmt.Sprintf("leaf %d", next))
+			hash, err := hasher.HashLeaf(value)
+			if err != nil {
+				t.Fatalf("HashLeaf(): %v", err)
+			}
+			leaves = append(leaves, &trillian.LogLeaf{LeafValue: value, MerkleLeafHash: hash, LeafIdentityHash: hash})
+			next++
+		}
+		if _, err := ls.QueueLeaves(ctx, tree.TreeId, leaves, time.Now()); err != nil {
+			t.Fatalf("QueueLeaves(): %v", err)
+		}
+		if n, err := sequencer.IntegrateBatch(ctx, tree.TreeId, size, 0, 0); err != nil || n != size {
+			t.Fatalf("IntegrateBatch(): (%d, %v), want (%d, nil)", n, err, size)
+		}
+	}
+	return tree
+}
+
+func freezeTree(ctx context.Context, t *testing.T, as storage.AdminStorage, treeID int64) {
+	t.Helper()
+	if _, err := storage.UpdateTree(ctx, as, treeID, func(tree *trillian.Tree) {
+		tree.TreeState = trillian.TreeState_FROZEN
+	}); err != nil {
+		t.Fatalf("UpdateTree(): %v", err)
+	}
+}
+
+func TestCompactFrozenTree(t *testing.T) {
+	ctx := context.Background()
+	ls := memory.NewLogStorage(nil)
+	as := memory.NewAdminStorage(ls)
+	tree := createLogWithHistory(ctx, t, as, ls, 1, 4, 100, 200, 3)
+
+	_, err := CompactFrozenTree(ctx, as, ls, tree.TreeId)
+	if got, want := status.Code(err), codes.FailedPrecondition; got != want {
+		t.Errorf("CompactFrozenTree() on active tree: got %v, want %v", got, want)
+	}
+
+	freezeTree(ctx, t, as, tree.TreeId)
+	removed, err := CompactFrozenTree(ctx, as, ls, tree.TreeId)
+	if err != nil {
+		t.Fatalf("CompactFrozenTree(): %v", err)
+	}
+	if removed == 0 {
+		t.Error("CompactFrozenTree(): removed 0 subtree revisions, want > 0")
+	}
+
+	// Every leaf is still provably included in the tree.
+	tx, err := ls.SnapshotForTree(ctx, tree.TreeId)
+	if err != nil {
+		t.Fatalf("SnapshotForTree(): %v", err)
+	}
+	defer tx.Close()
+	root, err := tx.LatestSignedLogRoot(ctx)
+	if err != nil {
+		t.Fatalf("LatestSignedLogRoot(): %v", err)
+	}
+	if got, want := root.TreeSize, int64(308); got != want {
+		t.Fatalf("LatestSignedLogRoot(): got size %d, want %d", got, want)
+	}
+	for i := int64(0); i < root.TreeSize; i++ {
+		if err := verifyStoredInclusion(ctx, tx, rfc6962.DefaultHasher, &root, i); err != nil {
+			t.Errorf("verifyStoredInclusion(%d) after compaction: %v", i, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit(): %v", err)
+	}
+
+	if removed, err := CompactFrozenTree(ctx, as, ls, tree.TreeId); err != nil || removed != 0 {
+		t.Errorf("second CompactFrozenTree(): (%d, %v), want (0, nil)", removed, err)
+	}
+}
+
+func TestFrozenTreeCompactor_RunOnce(t *testing.T) {
+	ctx := context.Background()
+	ls := memory.NewLogStorage(nil)
+	as := memory.NewAdminStorage(ls)
+	frozen := createLogWithHistory(ctx, t, as, ls, 10, 10)
+	createLogWithHistory(ctx, t, as, ls, 10, 10)
+	freezeTree(ctx, t, as, frozen.TreeId)
+
+	c := NewFrozenTreeCompactor(as, ls, time.Hour, nil)
+	for _, want := range []int{1, 0} {
+		if count, err := c.RunOnce(ctx); err != nil || count != want {
+			t.Errorf("RunOnce(): (%d, %v), want (%d, nil)", count, err, want)
+		}
+	}
+}
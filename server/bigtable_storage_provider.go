@@ -0,0 +1,95 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"flag"
+	"sync"
+
+	"cloud.google.com/go/bigtable"
+	"github.com/golang/glog"
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/storage"
+	btstorage "github.com/google/trillian/storage/bigtable"
+)
+
+var (
+	btProject          = flag.String("bigtable_project", "", "Project ID of the Cloud Bigtable instance")
+	btInstance         = flag.String("bigtable_instance", "", "Cloud Bigtable instance ID")
+	btCreateTables     = flag.Bool("bigtable_create_tables", false, "Create any missing Cloud Bigtable tables, and set their garbage collection policies, on startup")
+	btSubtreeRevisions = flag.Int("bigtable_subtree_revisions", 0, "Number of revisions of each subtree to retain when --bigtable_create_tables is set (0 retains all revisions)")
+
+	btMu              sync.Mutex
+	btStorageInstance *bigtableProvider
+)
+
+func init() {
+	if err := RegisterStorageProvider("bigtable", newBigtableStorageProvider); err != nil {
+		glog.Fatalf("Failed to register storage provider bigtable: %v", err)
+	}
+}
+
+type bigtableProvider struct {
+	client *bigtable.Client
+	mf     monitoring.MetricFactory
+}
+
+func newBigtableStorageProvider(mf monitoring.MetricFactory) (StorageProvider, error) {
+	btMu.Lock()
+	defer btMu.Unlock()
+
+	if btStorageInstance != nil {
+		return btStorageInstance, nil
+	}
+
+	ctx := context.TODO()
+	if *btCreateTables {
+		ac, err := bigtable.NewAdminClient(ctx, *btProject, *btInstance)
+		if err != nil {
+			return nil, err
+		}
+		defer ac.Close()
+		if err := btstorage.CreateTables(ctx, ac, btstorage.TableOptions{SubtreeRevisions: *btSubtreeRevisions}); err != nil {
+			return nil, err
+		}
+	}
+
+	client, err := bigtable.NewClient(ctx, *btProject, *btInstance)
+	if err != nil {
+		return nil, err
+	}
+	btStorageInstance = &bigtableProvider{
+		client: client,
+		mf:     mf,
+	}
+	return btStorageInstance, nil
+}
+
+func (s *bigtableProvider) LogStorage() storage.LogStorage {
+	return btstorage.NewLogStorage(s.client, s.AdminStorage(), s.mf)
+}
+
+func (s *bigtableProvider) MapStorage() storage.MapStorage {
+	return nil
+}
+
+func (s *bigtableProvider) AdminStorage() storage.AdminStorage {
+	return btstorage.NewAdminStorage(s.client)
+}
+
+func (s *bigtableProvider) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,90 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"flag"
+	"strings"
+	"sync"
+
+	"github.com/gocql/gocql"
+	"github.com/golang/glog"
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/cassandra"
+)
+
+var (
+	cassandraHosts       = flag.String("cassandra_hosts", "localhost", "Comma-separated list of Cassandra/ScyllaDB hosts to connect to")
+	cassandraKeyspace    = flag.String("cassandra_keyspace", "trillian", "Keyspace holding the tables from storage/cassandra/storage.cql")
+	cassandraConsistency = flag.String("cassandra_consistency", "QUORUM", "Consistency level for Cassandra reads and writes")
+
+	cassandraMu              sync.Mutex
+	cassandraStorageInstance *cassandraProvider
+)
+
+func init() {
+	if err := RegisterStorageProvider("cassandra", newCassandraStorageProvider); err != nil {
+		glog.Fatalf("Failed to register storage provider cassandra: %v", err)
+	}
+}
+
+type cassandraProvider struct {
+	session *gocql.Session
+	mf      monitoring.MetricFactory
+}
+
+func newCassandraStorageProvider(mf monitoring.MetricFactory) (StorageProvider, error) {
+	cassandraMu.Lock()
+	defer cassandraMu.Unlock()
+
+	if cassandraStorageInstance != nil {
+		return cassandraStorageInstance, nil
+	}
+
+	consistency, err := gocql.ParseConsistencyWrapper(*cassandraConsistency)
+	if err != nil {
+		return nil, err
+	}
+	cluster := gocql.NewCluster(strings.Split(*cassandraHosts, ",")...)
+	cluster.Keyspace = *cassandraKeyspace
+	cluster.Consistency = consistency
+	session, err := cluster.CreateSession()
+	if err != nil {
+		return nil, err
+	}
+	cassandraStorageInstance = &cassandraProvider{
+		session: session,
+		mf:      mf,
+	}
+	return cassandraStorageInstance, nil
+}
+
+func (s *cassandraProvider) LogStorage() storage.LogStorage {
+	return cassandra.NewLogStorage(s.session, s.AdminStorage(), s.mf)
+}
+
+func (s *cassandraProvider) MapStorage() storage.MapStorage {
+	return nil
+}
+
+func (s *cassandraProvider) AdminStorage() storage.AdminStorage {
+	return cassandra.NewAdminStorage(s.session)
+}
+
+func (s *cassandraProvider) Close() error {
+	s.session.Close()
+	return nil
+}
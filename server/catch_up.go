@@ -0,0 +1,191 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/google/trillian/log"
+	"github.com/google/trillian/monitoring"
+)
+
+var (
+	catchUpQueueAge   = flag.Duration("catch_up_queue_age", 0, "Age of the oldest leaf integrated into a log above which the signer switches the log to catch-up mode. Zero disables catch-up mode")
+	catchUpBatchSize  = flag.Int("catch_up_batch_size", 5000, "Max number of leaves to process per batch for logs in catch-up mode")
+	catchUpMaxBatches = flag.Int("catch_up_max_batches", 10, "Max number of batches integrated back to back per sequencing pass for logs in catch-up mode")
+	catchUpDeferQuota = flag.Bool("catch_up_defer_quota", true, "If true, the quota tokens of leaves integrated in catch-up mode are only replenished once the log has caught up, so that the backlog doesn't reopen sequencing-based write quotas before it's cleared")
+
+	seqQueueAge       monitoring.Gauge
+	seqCatchUp        monitoring.Gauge
+	seqCatchUpETA     monitoring.Gauge
+	catchUpMetricOnce sync.Once
+)
+
+func initCatchUpMetrics(mf monitoring.MetricFactory) {
+	catchUpMetricOnce.Do(func() {
+		if mf == nil {
+			mf = monitoring.InertMetricFactory{}
+		}
+		seqQueueAge = mf.NewGauge("sequencer_queue_age", "Age in seconds of the oldest leaf integrated by the latest batch of a log", logIDLabel)
+		seqCatchUp = mf.NewGauge("sequencer_catch_up", "Set to 1 while a log is in catch-up mode, 0 otherwise", logIDLabel)
+		seqCatchUpETA = mf.NewGauge("sequencer_catch_up_eta", "Estimated seconds until a log in catch-up mode has integrated its backlog, +Inf if the backlog isn't shrinking, 0 outside catch-up mode", logIDLabel)
+	})
+}
+
+// CatchUpOpts configures the catch-up mode of a SequencerManager.
+//
+// A log enters catch-up mode once a full batch of its leaves includes a leaf
+// which was queued more than QueueAge ago, e.g. after the signer was down for
+// a while. In catch-up mode, each sequencing pass integrates up to MaxBatches
+// batches of BatchSize leaves. The batches of a log are integrated back to
+// back rather than concurrently, as each of them builds on the tree revision
+// written by the previous one. The log leaves catch-up mode once its batches
+// are no longer full, or its oldest leaves are recent enough.
+type CatchUpOpts struct {
+	// QueueAge is the queue age above which a log enters catch-up mode. Zero
+	// disables catch-up mode.
+	QueueAge time.Duration
+	// BatchSize is the number of leaves integrated per batch in catch-up mode.
+	BatchSize int
+	// MaxBatches is the number of batches integrated per pass in catch-up mode.
+	MaxBatches int
+	// DeferQuota, if set, defers the replenishment of the quota tokens of the
+	// leaves integrated in catch-up mode until the log leaves it.
+	DeferQuota bool
+}
+
+// CatchUpFromFlags returns the CatchUpOpts specified by flags.
+func CatchUpFromFlags() (CatchUpOpts, error) {
+	opts := CatchUpOpts{
+		QueueAge:   *catchUpQueueAge,
+		BatchSize:  *catchUpBatchSize,
+		MaxBatches: *catchUpMaxBatches,
+		DeferQuota: *catchUpDeferQuota,
+	}
+	return opts, opts.validate()
+}
+
+func (o CatchUpOpts) validate() error {
+	switch {
+	case o.QueueAge < 0:
+		return fmt.Errorf("catch-up queue age is %v, want >= 0", o.QueueAge)
+	case o.QueueAge == 0:
+		return nil
+	case o.BatchSize <= 0:
+		return fmt.Errorf("catch-up batch size is %v, want > 0", o.BatchSize)
+	case o.MaxBatches <= 0:
+		return fmt.Errorf("catch-up max batches is %v, want > 0", o.MaxBatches)
+	}
+	return nil
+}
+
+// catchUpState is the catch-up state of a log.
+type catchUpState struct {
+	active bool
+	// owed is the number of leaves whose quota tokens haven't been
+	// replenished yet.
+	owed int
+	// at and age are the time and queue age of the latest ETA estimate.
+	at  time.Time
+	age time.Duration
+}
+
+// batchOptions returns the options of the next batch of leaves to integrate
+// into the log.
+func (s *SequencerManager) batchOptions(logID int64, batchSize int) log.BatchOptions {
+	opts := log.BatchOptions{Limit: batchSize, GuardWindow: s.guardWindow}
+	s.catchUpMu.Lock()
+	defer s.catchUpMu.Unlock()
+	if st := s.catchUpLogs[logID]; st != nil && st.active {
+		if s.catchUp.BatchSize > batchSize {
+			opts.Limit = s.catchUp.BatchSize
+		}
+		opts.DeferReplenish = s.catchUp.DeferQuota
+	}
+	return opts
+}
+
+// updateCatchUp updates the catch-up state of the log with the result of a
+// batch integrated with opts at now, and returns whether another batch should
+// be integrated straight away.
+func (s *SequencerManager) updateCatchUp(ctx context.Context, sequencer *log.Sequencer, logID int64, opts log.BatchOptions, res log.BatchResult, now time.Time) bool {
+	if s.catchUp.QueueAge <= 0 {
+		return false
+	}
+	label := strconv.FormatInt(logID, 10)
+	var age time.Duration
+	if !res.OldestQueued.IsZero() {
+		age = now.Sub(res.OldestQueued)
+	}
+	seqQueueAge.Set(age.Seconds(), label)
+
+	s.catchUpMu.Lock()
+	st, ok := s.catchUpLogs[logID]
+	if !ok {
+		st = &catchUpState{}
+		s.catchUpLogs[logID] = st
+	}
+	if opts.DeferReplenish {
+		st.owed += res.Leaves
+	}
+	wasActive := st.active
+	st.active = age > s.catchUp.QueueAge && res.Leaves >= opts.Limit
+	owed := 0
+	switch {
+	case st.active && !wasActive:
+		glog.Infof("%v: entering catch-up mode, oldest leaf queued %v ago", logID, age)
+		seqCatchUp.Set(1, label)
+		st.at, st.age = now, age
+	case st.active:
+		if elapsed := now.Sub(st.at); elapsed >= time.Second {
+			seqCatchUpETA.Set(catchUpETA(age, st.age, elapsed), label)
+			st.at, st.age = now, age
+		}
+	case wasActive:
+		glog.Infof("%v: leaving catch-up mode, oldest leaf queued %v ago", logID, age)
+		seqCatchUp.Set(0, label)
+		seqCatchUpETA.Set(0, label)
+		owed, st.owed = st.owed, 0
+	}
+	active := st.active
+	s.catchUpMu.Unlock()
+
+	if owed > 0 {
+		sequencer.ReplenishQuota(ctx, logID, owed)
+	}
+	return active
+}
+
+// catchUpETA estimates how many seconds a log will take to catch up, given
+// the queue age of its oldest leaves now, and elapsed earlier. The leaves
+// which remain to be integrated were queued over the last age, and the
+// sequencer gets through them at the rate at which the queue time of the
+// oldest leaves advances, less the one second per second at which new leaves
+// arrive.
+func catchUpETA(age, prevAge, elapsed time.Duration) float64 {
+	advanced := elapsed + prevAge - age
+	rate := advanced.Seconds()/elapsed.Seconds() - 1
+	if rate <= 0 {
+		return math.Inf(1)
+	}
+	return age.Seconds() / rate
+}
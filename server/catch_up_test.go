@@ -0,0 +1,59 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCatchUpOptsValidate(t *testing.T) {
+	tests := []struct {
+		desc    string
+		opts    CatchUpOpts
+		wantErr bool
+	}{
+		{desc: "disabled", opts: CatchUpOpts{}},
+		{desc: "enabled", opts: CatchUpOpts{QueueAge: time.Hour, BatchSize: 100, MaxBatches: 2}},
+		{desc: "negativeAge", opts: CatchUpOpts{QueueAge: -time.Hour}, wantErr: true},
+		{desc: "noBatchSize", opts: CatchUpOpts{QueueAge: time.Hour, MaxBatches: 2}, wantErr: true},
+		{desc: "noMaxBatches", opts: CatchUpOpts{QueueAge: time.Hour, BatchSize: 100}, wantErr: true},
+	}
+	for _, test := range tests {
+		if err := test.opts.validate(); (err != nil) != test.wantErr {
+			t.Errorf("%v: validate()=%v, wantErr %v", test.desc, err, test.wantErr)
+		}
+	}
+}
+
+func TestCatchUpETA(t *testing.T) {
+	tests := []struct {
+		desc                  string
+		age, prevAge, elapsed time.Duration
+		want                  float64
+	}{
+		// The queue head advanced 30s in 10s, so the 60s backlog shrinks by 20s
+		// every 10s.
+		{desc: "shrinking", age: time.Minute, prevAge: 80 * time.Second, elapsed: 10 * time.Second, want: 30},
+		{desc: "steady", age: time.Minute, prevAge: time.Minute, elapsed: 10 * time.Second, want: math.Inf(1)},
+		{desc: "growing", age: 2 * time.Minute, prevAge: time.Minute, elapsed: 10 * time.Second, want: math.Inf(1)},
+	}
+	for _, test := range tests {
+		if got := catchUpETA(test.age, test.prevAge, test.elapsed); got != test.want {
+			t.Errorf("%v: catchUpETA()=%v, want %v", test.desc, got, test.want)
+		}
+	}
+}
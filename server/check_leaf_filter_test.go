@@ -0,0 +1,101 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	stestonly "github.com/google/trillian/storage/testonly"
+)
+
+func TestCheckLeafFilter(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tree := proto.Clone(stestonly.LogTree).(*trillian.Tree)
+	tree.LeafFilter = &trillian.LeafFilterSpec{ExpectedLeaves: 1000, FalsePositiveRate: 1e-9}
+	e, tree, lc := newEmbeddedLog(ctx, t, tree)
+	defer e.Stop()
+
+	var logged [][]byte
+	for i := 0; i < 5; i++ {
+		value := []byte(fmt.Sprintf("leaf %d", i))
+		id := sha256.Sum256(value)
+		leaf := &trillian.LogLeaf{LeafValue: value, LeafIdentityHash: id[:]}
+		if _, err := e.LogClient().QueueLeaf(ctx, &trillian.QueueLeafRequest{LogId: tree.TreeId, Leaf: leaf}); err != nil {
+			t.Fatalf("QueueLeaf(): %v", err)
+		}
+		logged = append(logged, id[:])
+	}
+	if _, err := lc.WaitForRootUpdate(ctx, 5); err != nil {
+		t.Fatalf("WaitForRootUpdate(): %v", err)
+	}
+
+	notLogged := sha256.Sum256([]byte("not logged"))
+	hashes := append(logged, notLogged[:])
+	resp, err := e.LogClient().CheckLeafFilter(ctx, &trillian.CheckLeafFilterRequest{LogId: tree.TreeId, LeafIdentityHash: hashes})
+	if err != nil {
+		t.Fatalf("CheckLeafFilter(): %v", err)
+	}
+	if got, want := len(resp.MaybeLogged), len(hashes); got != want {
+		t.Fatalf("CheckLeafFilter() returned %d results, want %d", got, want)
+	}
+	for i := range logged {
+		if !resp.MaybeLogged[i] {
+			t.Errorf("CheckLeafFilter().MaybeLogged[%d] = false for a logged leaf", i)
+		}
+	}
+	// The filter's false positive rate makes this flake one in 10^9 runs.
+	if resp.MaybeLogged[len(logged)] {
+		t.Error("CheckLeafFilter() = true for a leaf which wasn't logged")
+	}
+	if got, want := resp.SignedLogRoot.GetTreeSize(), int64(5); got != want {
+		t.Errorf("CheckLeafFilter().SignedLogRoot.TreeSize = %v, want %v", got, want)
+	}
+
+	for _, test := range []struct {
+		desc string
+		req  *trillian.CheckLeafFilterRequest
+		want codes.Code
+	}{
+		{desc: "noHashes", req: &trillian.CheckLeafFilterRequest{LogId: tree.TreeId}, want: codes.InvalidArgument},
+		{desc: "emptyHash", req: &trillian.CheckLeafFilterRequest{LogId: tree.TreeId, LeafIdentityHash: [][]byte{nil}}, want: codes.InvalidArgument},
+	} {
+		if _, err := e.LogClient().CheckLeafFilter(ctx, test.req); status.Code(err) != test.want {
+			t.Errorf("%v: CheckLeafFilter(): %v, want code %v", test.desc, err, test.want)
+		}
+	}
+}
+
+func TestCheckLeafFilterNoFilter(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	e, tree, _ := newEmbeddedLog(ctx, t, stestonly.LogTree)
+	defer e.Stop()
+	req := &trillian.CheckLeafFilterRequest{LogId: tree.TreeId, LeafIdentityHash: [][]byte{[]byte("hash")}}
+	if _, err := e.LogClient().CheckLeafFilter(ctx, req); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("CheckLeafFilter(): %v, want code %v", err, codes.FailedPrecondition)
+	}
+}
@@ -0,0 +1,228 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/util"
+	"golang.org/x/net/context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	breakerFailureRate  = flag.Float64("storage_breaker_failure_rate", 0, "Fraction of failed or slow storage calls over --storage_breaker_window above which the circuit breaker opens and read RPCs are shed. Zero disables the breaker")
+	breakerMinCalls     = flag.Int("storage_breaker_min_calls", 20, "Minimum number of storage calls over --storage_breaker_window before the circuit breaker may open")
+	breakerWindow       = flag.Duration("storage_breaker_window", 10*time.Second, "Period over which the circuit breaker measures the storage failure rate")
+	breakerSlowCall     = flag.Duration("storage_breaker_slow_call", 0, "Storage calls taking longer than this count as failures for the circuit breaker. Zero means latency isn't considered")
+	breakerShedFraction = flag.Float64("storage_breaker_shed_fraction", 0.5, "Fraction of read RPCs rejected while the circuit breaker is open")
+	breakerRetryDelay   = flag.Duration("storage_breaker_retry_delay", time.Second, "Retry delay suggested to clients whose RPCs are shed by the circuit breaker")
+
+	breakerOpen         monitoring.Gauge
+	breakerShedRequests monitoring.Counter
+	breakerMetricsOnce  sync.Once
+)
+
+func initBreakerMetrics(mf monitoring.MetricFactory) {
+	breakerMetricsOnce.Do(func() {
+		breakerOpen = mf.NewGauge("storage_breaker_open", "Set to 1 while the circuit breaker of a storage backend is open, 0 otherwise", "backend")
+		breakerShedRequests = mf.NewCounter("storage_breaker_shed_requests", "Number of read RPCs rejected by the circuit breaker of a storage backend", "backend")
+	})
+}
+
+// CircuitBreakerOpts configures a CircuitBreaker.
+type CircuitBreakerOpts struct {
+	// FailureRate is the fraction of failed storage calls over Window above
+	// which the breaker opens. It must be in (0, 1).
+	FailureRate float64
+	// MinCalls is the number of storage calls which must have been made over
+	// Window before the breaker may open, so that a handful of failures on an
+	// idle server don't trip it.
+	MinCalls int
+	// Window is the period over which the failure rate is measured. It's
+	// rounded down to whole seconds, with a minimum of one second.
+	Window time.Duration
+	// SlowCall is the latency above which a storage call counts as failed even
+	// if it succeeds. Zero means that latency isn't considered.
+	SlowCall time.Duration
+	// ShedFraction is the fraction of read RPCs rejected while the breaker is
+	// open. It must be in [0, 1].
+	ShedFraction float64
+	// RetryDelay is the delay before retrying suggested to the clients of
+	// rejected RPCs.
+	RetryDelay time.Duration
+}
+
+// CircuitBreaker sheds read traffic when the calls made to a storage backend
+// start failing or slowing down, to protect the backend from clients'
+// retries during partial outages.
+//
+// There is no half-open state: while the breaker is open, the RPCs which
+// aren't shed keep reaching storage, and their outcomes close the breaker
+// again once the failure rate drops. Writes are never shed, as failing them
+// would lose data that clients may not resubmit.
+//
+// A nil *CircuitBreaker is valid, and never sheds traffic.
+type CircuitBreaker struct {
+	backend    string
+	opts       CircuitBreakerOpts
+	timeSource util.TimeSource
+	// shed reports whether to reject an RPC while the breaker is open.
+	shed func() bool
+
+	mu    sync.Mutex
+	calls []breakerBucket
+	open  bool
+}
+
+// breakerBucket counts the storage calls made in one second.
+type breakerBucket struct {
+	second, calls, failures int64
+}
+
+// NewCircuitBreaker returns a CircuitBreaker protecting the named storage
+// backend.
+func NewCircuitBreaker(backend string, opts CircuitBreakerOpts, mf monitoring.MetricFactory, timeSource util.TimeSource) (*CircuitBreaker, error) {
+	if opts.FailureRate <= 0 || opts.FailureRate >= 1 {
+		return nil, fmt.Errorf("failure rate is %v, want (0, 1)", opts.FailureRate)
+	}
+	if opts.ShedFraction < 0 || opts.ShedFraction > 1 {
+		return nil, fmt.Errorf("shed fraction is %v, want [0, 1]", opts.ShedFraction)
+	}
+	if mf == nil {
+		mf = monitoring.InertMetricFactory{}
+	}
+	initBreakerMetrics(mf)
+
+	buckets := int(opts.Window / time.Second)
+	if buckets < 1 {
+		buckets = 1
+	}
+	shedFraction := opts.ShedFraction
+	return &CircuitBreaker{
+		backend:    backend,
+		opts:       opts,
+		timeSource: timeSource,
+		shed:       func() bool { return rand.Float64() < shedFraction },
+		calls:      make([]breakerBucket, buckets),
+	}, nil
+}
+
+// CircuitBreakerFromFlags returns the CircuitBreaker specified by flags for
+// the storage backend selected by --storage_system, or nil if the breaker is
+// disabled.
+func CircuitBreakerFromFlags(mf monitoring.MetricFactory, timeSource util.TimeSource) (*CircuitBreaker, error) {
+	if *breakerFailureRate == 0 {
+		return nil, nil
+	}
+	return NewCircuitBreaker(*storageSystem, CircuitBreakerOpts{
+		FailureRate:  *breakerFailureRate,
+		MinCalls:     *breakerMinCalls,
+		Window:       *breakerWindow,
+		SlowCall:     *breakerSlowCall,
+		ShedFraction: *breakerShedFraction,
+		RetryDelay:   *breakerRetryDelay,
+	}, mf, timeSource)
+}
+
+// Record records the outcome of a storage call which took latency to return
+// err.
+func (b *CircuitBreaker) Record(err error, latency time.Duration) {
+	if b == nil {
+		return
+	}
+	failed := isStorageFailure(err) || (b.opts.SlowCall > 0 && latency > b.opts.SlowCall)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.timeSource.Now().Unix()
+	bucket := &b.calls[now%int64(len(b.calls))]
+	if bucket.second != now {
+		*bucket = breakerBucket{second: now}
+	}
+	bucket.calls++
+	if failed {
+		bucket.failures++
+	}
+	b.update(now)
+}
+
+// Allow returns nil if an RPC reading from storage may proceed, or an
+// UNAVAILABLE error with retry information if it's shed.
+func (b *CircuitBreaker) Allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	open := b.update(b.timeSource.Now().Unix())
+	b.mu.Unlock()
+	if !open || !b.shed() {
+		return nil
+	}
+
+	breakerShedRequests.Inc(b.backend)
+	s := status.Newf(codes.Unavailable, "storage backend %v is overloaded, retry later", b.backend)
+	if withRetry, err := s.WithDetails(&errdetails.RetryInfo{RetryDelay: ptypes.DurationProto(b.opts.RetryDelay)}); err == nil {
+		s = withRetry
+	}
+	return s.Err()
+}
+
+// update recomputes whether the breaker is open at second now, and returns
+// the result. b.mu must be held.
+func (b *CircuitBreaker) update(now int64) bool {
+	var calls, failures int64
+	for _, bucket := range b.calls {
+		if bucket.second > now-int64(len(b.calls)) && bucket.second <= now {
+			calls += bucket.calls
+			failures += bucket.failures
+		}
+	}
+	open := calls > 0 && calls >= int64(b.opts.MinCalls) && float64(failures)/float64(calls) > b.opts.FailureRate
+	if open != b.open {
+		b.open = open
+		if open {
+			breakerOpen.Set(1, b.backend)
+		} else {
+			breakerOpen.Set(0, b.backend)
+		}
+	}
+	return open
+}
+
+// isStorageFailure returns whether err indicates a problem with the storage
+// backend, rather than with the request.
+func isStorageFailure(err error) bool {
+	if err == nil || err == context.Canceled {
+		return false
+	}
+	if err == context.DeadlineExceeded {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.OK, codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists,
+		codes.PermissionDenied, codes.FailedPrecondition, codes.OutOfRange, codes.Unauthenticated:
+		return false
+	}
+	return true
+}
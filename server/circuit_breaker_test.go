@@ -0,0 +1,109 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/util"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestNewCircuitBreakerValidation(t *testing.T) {
+	for _, opts := range []CircuitBreakerOpts{
+		{FailureRate: 0, ShedFraction: 0.5},
+		{FailureRate: 1, ShedFraction: 0.5},
+		{FailureRate: 0.5, ShedFraction: -1},
+		{FailureRate: 0.5, ShedFraction: 2},
+	} {
+		if _, err := NewCircuitBreaker("test", opts, nil, util.SystemTimeSource{}); err == nil {
+			t.Errorf("NewCircuitBreaker(%+v)=_,nil; want err", opts)
+		}
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	ts := util.NewFakeTimeSource(time.Unix(1000, 0))
+	b, err := NewCircuitBreaker("test", CircuitBreakerOpts{
+		FailureRate:  0.5,
+		MinCalls:     4,
+		Window:       10 * time.Second,
+		SlowCall:     time.Second,
+		ShedFraction: 1,
+		RetryDelay:   3 * time.Second,
+	}, monitoring.InertMetricFactory{}, ts)
+	if err != nil {
+		t.Fatalf("NewCircuitBreaker(): %v", err)
+	}
+	before := breakerShedRequests.Value("test")
+
+	storageErr := errors.New("connection refused")
+	b.Record(storageErr, 0)
+	b.Record(storageErr, 0)
+	if err := b.Allow(); err != nil {
+		t.Errorf("Allow() below MinCalls=%v; want nil", err)
+	}
+
+	// Request errors don't count as failures, slow calls do.
+	b.Record(status.Error(codes.NotFound, "no such leaf"), 0)
+	b.Record(status.Error(codes.InvalidArgument, "bad index"), 0)
+	if err := b.Allow(); err != nil {
+		t.Errorf("Allow() at 2/4 failures=%v; want nil", err)
+	}
+	b.Record(nil, 2*time.Second)
+
+	err = b.Allow()
+	if got, want := status.Code(err), codes.Unavailable; got != want {
+		t.Fatalf("Allow() at 3/5 failures=%v; want code %v", err, want)
+	}
+	var delay time.Duration
+	for _, d := range status.Convert(err).Details() {
+		if info, ok := d.(*errdetails.RetryInfo); ok {
+			delay, _ = ptypes.Duration(info.RetryDelay)
+		}
+	}
+	if want := 3 * time.Second; delay != want {
+		t.Errorf("Allow() retry delay=%v; want %v", delay, want)
+	}
+	if got, want := breakerShedRequests.Value("test"), before+1; got != want {
+		t.Errorf("storage_breaker_shed_requests=%v; want %v", got, want)
+	}
+	if got := breakerOpen.Value("test"); got != 1 {
+		t.Errorf("storage_breaker_open=%v; want 1", got)
+	}
+
+	// The breaker closes once the failures leave the window.
+	ts.Set(time.Unix(1010, 0))
+	if err := b.Allow(); err != nil {
+		t.Errorf("Allow() after window=%v; want nil", err)
+	}
+	if got := breakerOpen.Value("test"); got != 0 {
+		t.Errorf("storage_breaker_open=%v; want 0", got)
+	}
+}
+
+func TestNilCircuitBreaker(t *testing.T) {
+	var b *CircuitBreaker
+	b.Record(errors.New("failure"), time.Hour)
+	if err := b.Allow(); err != nil {
+		t.Errorf("nil Allow()=%v; want nil", err)
+	}
+}
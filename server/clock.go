@@ -0,0 +1,52 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"flag"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/util"
+)
+
+var (
+	clockSkewThreshold = flag.Duration("clock_skew_alert_threshold", time.Second, "Backwards jumps of the system clock larger than this are logged as errors and counted by the clock_backwards_jumps metric")
+
+	clockBackwardsJumps monitoring.Counter
+	clockMetricOnce     sync.Once
+)
+
+func initClockMetrics(mf monitoring.MetricFactory) {
+	clockMetricOnce.Do(func() {
+		if mf == nil {
+			mf = monitoring.InertMetricFactory{}
+		}
+		clockBackwardsJumps = mf.NewCounter("clock_backwards_jumps", "Number of backwards jumps of the system clock larger than --clock_skew_alert_threshold")
+	})
+}
+
+// MonotonicClockFromFlags returns a util.MonotonicTimeSource wrapping the
+// system clock, which reports backwards jumps larger than
+// --clock_skew_alert_threshold to the logs and to mf.
+func MonotonicClockFromFlags(mf monitoring.MetricFactory) util.TimeSource {
+	initClockMetrics(mf)
+	return util.NewMonotonicTimeSource(util.SystemTimeSource{}, *clockSkewThreshold, func(jump time.Duration) {
+		glog.Errorf("System clock jumped %v backwards, holding time until it catches up", jump)
+		clockBackwardsJumps.Inc()
+	})
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"encoding/base64"
 	"flag"
+	"fmt"
 	"io/ioutil"
 	"sync"
 	"time"
@@ -29,6 +30,8 @@ import (
 	"github.com/google/trillian/monitoring"
 	"github.com/google/trillian/storage"
 	"github.com/google/trillian/storage/cloudspanner"
+	"github.com/google/trillian/util"
+	"google.golang.org/api/option"
 )
 
 var (
@@ -42,7 +45,15 @@ var (
 	csSessionHCWorkers                   = flag.Int("cloudspanner_num_healthcheckers", 0, "Number of health check workers for Spanner session pool.")
 	csSessionHCInterval                  = flag.Duration("cloudspanner_healthcheck_interval", 0, "Interval betweek pinging sessions.")
 	csDequeueAcrossMerkleBucketsFraction = flag.Float64("cloudspanner_dequeue_bucket_fraction", 0.75, "Fraction of merkle keyspace to dequeue from, set to zero to disable.")
-	csReadOnlyStaleness                  = flag.Duration("cloudspanner_readonly_staleness", time.Minute, "How far in the past to perform readonly operations. Within limits, raising this should help to increase performance/reduce latency.")
+	csReadOnlyStaleness                  = flag.Duration("cloudspanner_readonly_staleness", cloudspanner.DefaultReadOnlyStaleness, "How far in the past to perform readonly operations, zero for strong reads. Within limits, raising this should help to increase performance/reduce latency.")
+	csReadOnlyEndpoint                   = flag.String("cloudspanner_readonly_endpoint", "", "If set, the Cloud Spanner endpoint (host:port) used for readonly operations, e.g. a regional endpoint close to the replicas serving this server's reads. Read-write operations always use the default endpoint, which routes them to the leader region.")
+	csRegion                             = flag.String("cloudspanner_region", "", "Region this server runs in, used to label Cloud Spanner commit latency metrics")
+	csElectionLease                      = flag.Duration("cloudspanner_election_lease", 30*time.Second, "Duration of the mastership leases of log signers running elections in CloudSpanner. Instances' clock skew must be well below it")
+	csHedgeDelay                         = flag.Duration("cloudspanner_hedge_delay", 0, "If non-zero, how long to wait for a readonly subtree read before sending a second, hedged, read of the same data. Can reduce tail latency of proofs.")
+	csTreeHeadShards                     = flag.Int("cloudspanner_tree_head_shards", 0, "If non-zero, write new tree heads to this many shards of the TreeHeadShards table, rather than to TreeHeads, to spread the writes of very active trees over several Spanner splits. Only set it once all servers read TreeHeadShards (at most 16)")
+	csReadCurrentTreeHead                = flag.Bool("cloudspanner_read_current_tree_head", false, "If true, read the latest tree head of trees with a point read of the CurrentTreeHeads table rather than a query. Only set it once all servers write CurrentTreeHeads")
+	csMaxCommitMutations                 = flag.Int("cloudspanner_max_commit_mutations", 0, "If non-zero, commit the subtree and sequenced leaf writes of sequencing passes with more than this many mutations in several transactions, ahead of the new tree head, so that batches can exceed Spanner's mutation limit. Keep it well below the limit")
+	csCheckSchema                        = flag.Bool("cloudspanner_check_schema", true, "If true, check on startup that the CloudSpanner database has the tables, columns and indexes of storage/cloudspanner/spanner.sdl, and fail if it doesn't")
 
 	csMu              sync.RWMutex
 	csStorageInstance *cloudSpannerProvider
@@ -67,6 +78,9 @@ func warn() {
 
 type cloudSpannerProvider struct {
 	client *spanner.Client
+	// readClient is used for readonly operations, if set.
+	readClient *spanner.Client
+	mf         monitoring.MetricFactory
 }
 
 func configFromFlags() spanner.ClientConfig {
@@ -89,13 +103,35 @@ func newCloudSpannerStorageProvider(mf monitoring.MetricFactory) (StorageProvide
 	if csStorageInstance != nil {
 		return csStorageInstance, nil
 	}
+	if *csTreeHeadShards < 0 || *csTreeHeadShards > cloudspanner.MaxTreeHeadShards {
+		return nil, fmt.Errorf("--cloudspanner_tree_head_shards = %d, want a number in range [0, %d]", *csTreeHeadShards, cloudspanner.MaxTreeHeadShards)
+	}
 
 	client, err := spanner.NewClientWithConfig(context.TODO(), *csURI, configFromFlags())
 	if err != nil {
 		return nil, err
 	}
+	if *csCheckSchema {
+		ctx, cancel := context.WithTimeout(context.Background(), schemaCheckTimeout)
+		err := cloudspanner.CheckSchema(ctx, client)
+		cancel()
+		if err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+	var readClient *spanner.Client
+	if *csReadOnlyEndpoint != "" {
+		readClient, err = spanner.NewClientWithConfig(context.TODO(), *csURI, configFromFlags(), option.WithEndpoint(*csReadOnlyEndpoint))
+		if err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
 	csStorageInstance = &cloudSpannerProvider{
-		client: client,
+		client:     client,
+		readClient: readClient,
+		mf:         mf,
 	}
 	return csStorageInstance, nil
 }
@@ -114,6 +150,13 @@ func (s *cloudSpannerProvider) LogStorage() storage.LogStorage {
 	if *csReadOnlyStaleness > 0 {
 		opts.ReadOnlyStaleness = *csReadOnlyStaleness
 	}
+	opts.ReadOnlyClient = s.readClient
+	opts.Region = *csRegion
+	opts.HedgeDelay = *csHedgeDelay
+	opts.MetricFactory = s.mf
+	opts.TreeHeadShards = *csTreeHeadShards
+	opts.ReadCurrentTreeHead = *csReadCurrentTreeHead
+	opts.MaxCommitMutations = *csMaxCommitMutations
 	return cloudspanner.NewLogStorageWithOpts(s.client, opts)
 }
 
@@ -127,8 +170,15 @@ func (s *cloudSpannerProvider) AdminStorage() storage.AdminStorage {
 	return cloudspanner.NewAdminStorage(s.client)
 }
 
+func (s *cloudSpannerProvider) ElectionFactory(instanceID string) util.ElectionFactory {
+	return cloudspanner.NewElectionFactory(instanceID, s.client, *csElectionLease)
+}
+
 func (s *cloudSpannerProvider) Close() error {
 	s.client.Close()
+	if s.readClient != nil {
+		s.readClient.Close()
+	}
 	return nil
 }
 
@@ -0,0 +1,50 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"flag"
+
+	"github.com/google/trillian/util"
+)
+
+var (
+	maxConcurrentStreams         = flag.Uint("max_concurrent_streams", 0, "Max number of concurrent RPCs per client connection. Zero for unlimited")
+	maxConnectionIdle            = flag.Duration("max_connection_idle", 0, "How long a client connection may have no RPCs before it's closed. Zero for unlimited")
+	maxConnectionAge             = flag.Duration("max_connection_age", 0, "How long a client connection may be open before the client is asked to reconnect, so long-lived clients are rebalanced and don't hold up restarts. Zero for unlimited")
+	maxConnectionAgeGrace        = flag.Duration("max_connection_age_grace", 0, "How long RPCs in flight on a connection older than --max_connection_age may run before it's closed. Zero for unlimited")
+	keepaliveTime                = flag.Duration("keepalive_time", 0, "How long a client connection may be idle before the server pings the client. Zero for the gRPC default of 2h")
+	keepaliveTimeout             = flag.Duration("keepalive_timeout", 0, "How long the server waits for a keepalive ping to be acknowledged before closing the connection. Zero for the gRPC default of 20s")
+	keepaliveMinTime             = flag.Duration("keepalive_min_time", 0, "Minimum interval at which clients may send keepalive pings; clients pinging more often are disconnected. Zero for the gRPC default of 5m")
+	keepalivePermitWithoutStream = flag.Bool("keepalive_permit_without_stream", false, "Allow clients to send keepalive pings while they have no RPCs in flight")
+)
+
+// ConnectionOptsFromFlags returns the util.ConnectionOpts specified by flags.
+func ConnectionOptsFromFlags() (util.ConnectionOpts, error) {
+	opts := util.ConnectionOpts{
+		MaxConcurrentStreams:         uint32(*maxConcurrentStreams),
+		MaxConnectionIdle:            *maxConnectionIdle,
+		MaxConnectionAge:             *maxConnectionAge,
+		MaxConnectionAgeGrace:        *maxConnectionAgeGrace,
+		KeepaliveTime:                *keepaliveTime,
+		KeepaliveTimeout:             *keepaliveTimeout,
+		KeepaliveMinTime:             *keepaliveMinTime,
+		KeepalivePermitWithoutStream: *keepalivePermitWithoutStream,
+	}
+	if err := opts.Validate(); err != nil {
+		return util.ConnectionOpts{}, err
+	}
+	return opts, nil
+}
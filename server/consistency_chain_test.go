@@ -0,0 +1,134 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian"
+	"github.com/google/trillian/client"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	stestonly "github.com/google/trillian/storage/testonly"
+)
+
+func TestConsistencyChainSizes(t *testing.T) {
+	for _, test := range []struct {
+		first, last int64
+		want        []int64
+	}{
+		{first: 1, last: 1},
+		{first: 1, last: 2, want: []int64{2}},
+		{first: 3, last: 11, want: []int64{4, 8, 11}},
+		{first: 4, last: 8, want: []int64{8}},
+		{first: 4, last: 9, want: []int64{8, 9}},
+		{first: 5, last: 6, want: []int64{6}},
+		{first: 11, last: 11},
+	} {
+		if got := consistencyChainSizes(test.first, test.last); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("consistencyChainSizes(%d, %d) = %v, want %v", test.first, test.last, got, test.want)
+		}
+	}
+}
+
+func TestGetConsistencyProofChain(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	e, tree, lc := newEmbeddedLog(ctx, t, stestonly.LogTree)
+	defer e.Stop()
+	v, err := client.NewLogVerifierFromTree(tree)
+	if err != nil {
+		t.Fatalf("NewLogVerifierFromTree(): %v", err)
+	}
+
+	var trusted *trillian.SignedLogRoot
+	for i := 0; i < 11; i++ {
+		leaf := &trillian.LogLeaf{LeafValue: []byte(fmt.Sprintf("leaf %d", i))}
+		if _, err := e.LogClient().QueueLeaf(ctx, &trillian.QueueLeafRequest{LogId: tree.TreeId, Leaf: leaf}); err != nil {
+			t.Fatalf("QueueLeaf(): %v", err)
+		}
+		// Only the root at size 3 is kept, the others are computed by the server.
+		if i == 2 {
+			if trusted, err = lc.WaitForRootUpdate(ctx, 3); err != nil {
+				t.Fatalf("WaitForRootUpdate(): %v", err)
+			}
+		}
+	}
+	if _, err := lc.WaitForRootUpdate(ctx, 11); err != nil {
+		t.Fatalf("WaitForRootUpdate(): %v", err)
+	}
+
+	for _, test := range []struct {
+		desc      string
+		first     int64
+		wantSizes []int64
+		wantError codes.Code
+	}{
+		{desc: "fromTrusted", first: 3, wantSizes: []int64{4, 8, 11}},
+		{desc: "fromOne", first: 1, wantSizes: []int64{2, 4, 8, 11}},
+		{desc: "latest", first: 11},
+		{desc: "future", first: 12, wantError: codes.InvalidArgument},
+		{desc: "empty", first: 0, wantError: codes.InvalidArgument},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			resp, err := e.LogClient().GetConsistencyProofChain(ctx, &trillian.GetConsistencyProofChainRequest{
+				LogId:         tree.TreeId,
+				FirstTreeSize: test.first,
+			})
+			if got := status.Code(err); got != test.wantError {
+				t.Fatalf("GetConsistencyProofChain(): %v, want code %v", err, test.wantError)
+			}
+			if err != nil {
+				return
+			}
+			var sizes []int64
+			for _, step := range resp.Steps {
+				sizes = append(sizes, step.TreeSize)
+			}
+			if !reflect.DeepEqual(sizes, test.wantSizes) {
+				t.Errorf("GetConsistencyProofChain() step sizes = %v, want %v", sizes, test.wantSizes)
+			}
+			if got, want := resp.SignedLogRoot.GetTreeSize(), int64(11); got != want {
+				t.Errorf("GetConsistencyProofChain().SignedLogRoot.TreeSize = %v, want %v", got, want)
+			}
+		})
+	}
+
+	resp, err := e.LogClient().GetConsistencyProofChain(ctx, &trillian.GetConsistencyProofChainRequest{
+		LogId:         tree.TreeId,
+		FirstTreeSize: trusted.TreeSize,
+	})
+	if err != nil {
+		t.Fatalf("GetConsistencyProofChain(): %v", err)
+	}
+	if err := v.VerifyRootChain(trusted, resp.SignedLogRoot, resp.Steps); err != nil {
+		t.Errorf("VerifyRootChain(): %v", err)
+	}
+	tampered := proto.Clone(resp).(*trillian.GetConsistencyProofChainResponse)
+	tampered.Steps[1].RootHash[0] ^= 1
+	if err := v.VerifyRootChain(trusted, tampered.SignedLogRoot, tampered.Steps); err == nil {
+		t.Error("VerifyRootChain() with a tampered step: got nil error, want non-nil")
+	}
+	if err := v.VerifyRootChain(trusted, resp.SignedLogRoot, resp.Steps[:2]); err == nil {
+		t.Error("VerifyRootChain() with a truncated chain: got nil error, want non-nil")
+	}
+}
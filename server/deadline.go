@@ -0,0 +1,128 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/trillian/monitoring"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// StorageStage identifies the storage accesses made at one stage of serving
+// an RPC, for deadline budgeting and metrics.
+type StorageStage string
+
+const (
+	// StageTree is the reading of the tree's configuration.
+	StageTree StorageStage = "tree"
+	// StageRoot is the reading of the tree's latest root.
+	StageRoot StorageStage = "root"
+	// StageLeaves is the reading of leaves or leaf counts.
+	StageLeaves StorageStage = "leaves"
+	// StageProof is the reading of the Merkle nodes of proofs.
+	StageProof StorageStage = "proof"
+	// StageWrite is any storage write, e.g. the queueing of leaves.
+	StageWrite StorageStage = "write"
+)
+
+var (
+	// StorageDeadlineBudget is a flag specifying the DeadlineBudget of log RPCs.
+	StorageDeadlineBudget = flag.String("storage_deadline_budget", "", "Comma-separated stage=fraction pairs limiting the fraction of an RPC's remaining deadline each storage stage may use, e.g. proof=0.5,leaves=0.8. Stages are tree, root, leaves, proof and write")
+
+	stageDeadlineExceeded monitoring.Counter
+	deadlineMetricsOnce   sync.Once
+)
+
+func initDeadlineMetrics(mf monitoring.MetricFactory) {
+	deadlineMetricsOnce.Do(func() {
+		stageDeadlineExceeded = mf.NewCounter("storage_deadline_exceeded", "Number of storage stages which failed because their deadline expired", "stage")
+	})
+}
+
+// DeadlineBudget limits the share of an RPC's remaining deadline that each of
+// its storage stages may use, so that a slow stage fails in time for the RPC
+// to return an error before the client gives up on it. For example, a budget
+// of 0.5 for StageProof means that proof reads get at most half of the time
+// left when they start. Stages not in the budget, and RPCs without deadlines,
+// are only bounded by the RPC's deadline.
+type DeadlineBudget map[StorageStage]float64
+
+// ParseDeadlineBudget parses a DeadlineBudget from a comma-separated list of
+// stage=fraction pairs, e.g. "proof=0.5,leaves=0.8".
+func ParseDeadlineBudget(s string) (DeadlineBudget, error) {
+	b := make(DeadlineBudget)
+	if s == "" {
+		return b, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid stage budget %q, want stage=fraction", part)
+		}
+		stage := StorageStage(strings.TrimSpace(kv[0]))
+		switch stage {
+		case StageTree, StageRoot, StageLeaves, StageProof, StageWrite:
+		default:
+			return nil, fmt.Errorf("unknown storage stage %q", stage)
+		}
+		fraction, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid budget for stage %v: %v", stage, err)
+		}
+		if fraction <= 0 || fraction > 1 {
+			return nil, fmt.Errorf("budget for stage %v is %v, want (0, 1]", stage, fraction)
+		}
+		b[stage] = fraction
+	}
+	return b, nil
+}
+
+// DeadlineBudgetFromFlags returns the DeadlineBudget specified by flags.
+func DeadlineBudgetFromFlags() (DeadlineBudget, error) {
+	return ParseDeadlineBudget(*StorageDeadlineBudget)
+}
+
+// start returns the context to use for the storage calls of stage, derived
+// from the RPC's ctx, and a function which must be called with the result of
+// the calls once they're done. It returns the error to pass on, which is a
+// DeadlineExceeded status if the stage's deadline expired.
+func (b DeadlineBudget) start(ctx context.Context, stage StorageStage) (context.Context, func(error) error) {
+	cancel := func() {}
+	if deadline, ok := ctx.Deadline(); ok {
+		if fraction, ok := b[stage]; ok && fraction < 1 {
+			remaining := time.Until(deadline)
+			ctx, cancel = context.WithTimeout(ctx, time.Duration(float64(remaining)*fraction))
+		}
+	}
+	return ctx, func(err error) error {
+		defer cancel()
+		if err == nil || ctx.Err() != context.DeadlineExceeded {
+			return err
+		}
+		stageDeadlineExceeded.Inc(string(stage))
+		if status.Code(err) == codes.DeadlineExceeded {
+			return err
+		}
+		return status.Errorf(codes.DeadlineExceeded, "deadline exceeded in storage stage %v: %v", stage, err)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/trillian/monitoring"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseDeadlineBudget(t *testing.T) {
+	for _, test := range []struct {
+		in      string
+		want    DeadlineBudget
+		wantErr bool
+	}{
+		{in: "", want: DeadlineBudget{}},
+		{in: "proof=0.5", want: DeadlineBudget{StageProof: 0.5}},
+		{in: "proof=0.5, leaves = 0.8,write=1", want: DeadlineBudget{StageProof: 0.5, StageLeaves: 0.8, StageWrite: 1}},
+		{in: "proof", wantErr: true},
+		{in: "proofs=0.5", wantErr: true},
+		{in: "proof=half", wantErr: true},
+		{in: "proof=0", wantErr: true},
+		{in: "proof=1.5", wantErr: true},
+	} {
+		got, err := ParseDeadlineBudget(test.in)
+		if gotErr := err != nil; gotErr != test.wantErr {
+			t.Errorf("ParseDeadlineBudget(%q)=_,%v; want err? %v", test.in, err, test.wantErr)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseDeadlineBudget(%q)=%v; want %v", test.in, got, test.want)
+		}
+	}
+}
+
+func TestDeadlineBudgetStart(t *testing.T) {
+	initDeadlineMetrics(monitoring.InertMetricFactory{})
+	b := DeadlineBudget{StageProof: 0.1}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	rpcDeadline, _ := ctx.Deadline()
+
+	// Stages without a budget use the RPC's deadline.
+	sctx, end := b.start(ctx, StageLeaves)
+	if got, _ := sctx.Deadline(); !got.Equal(rpcDeadline) {
+		t.Errorf("leaves stage deadline=%v; want %v", got, rpcDeadline)
+	}
+	if err := end(nil); err != nil {
+		t.Errorf("end(nil)=%v; want nil", err)
+	}
+
+	sctx, end = b.start(ctx, StageProof)
+	got, ok := sctx.Deadline()
+	if want := time.Now().Add(6 * time.Minute); !ok || got.After(want) {
+		t.Errorf("proof stage deadline=%v; want before %v", got, want)
+	}
+	if err := errors.New("storage failure"); end(err) != err {
+		t.Errorf("end(%v) changed the error", err)
+	}
+
+	// RPCs without deadlines aren't limited.
+	if sctx, end := b.start(context.Background(), StageProof); sctx != context.Background() {
+		t.Error("proof stage of RPC without deadline has a new context")
+	} else {
+		end(nil)
+	}
+}
+
+func TestDeadlineBudgetExceeded(t *testing.T) {
+	initDeadlineMetrics(monitoring.InertMetricFactory{})
+	b := DeadlineBudget{StageProof: 0.01}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	before := stageDeadlineExceeded.Value(string(StageProof))
+
+	sctx, end := b.start(ctx, StageProof)
+	<-sctx.Done()
+	err := end(sctx.Err())
+	if got, want := status.Code(err), codes.DeadlineExceeded; got != want {
+		t.Errorf("end()=%v; want code %v", err, want)
+	}
+	if ctx.Err() != nil {
+		t.Errorf("RPC context expired with the stage: %v", ctx.Err())
+	}
+	if got, want := stageDeadlineExceeded.Value(string(StageProof)), before+1; got != want {
+		t.Errorf("storage_deadline_exceeded{stage=proof}=%v; want %v", got, want)
+	}
+}
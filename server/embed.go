@@ -0,0 +1,585 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/extension"
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/server/admin"
+	"github.com/google/trillian/server/interceptor"
+	"github.com/google/trillian/util"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// Options configures a Trillian log embedded with New.
+type Options struct {
+	// TimeSource is used by the log server, admin server and signer.
+	// Defaults to util.SystemTimeSource.
+	TimeSource util.TimeSource
+
+	// StatsPrefix, if set, enables RPC metrics with names prefixed by it, as
+	// exported by a standalone server.
+	StatsPrefix string
+	// QuotaDryRun, if true, means no requests are blocked due to lack of
+	// quota tokens.
+	QuotaDryRun bool
+	// QuotaCosts sets the number of quota tokens charged for requests, see
+	// interceptor.CostModel.
+	QuotaCosts interceptor.CostModel
+	// AllowedTreeTypes determines which types of trees may be created through
+	// the admin client. nil means logs and pre-ordered logs.
+	AllowedTreeTypes []trillian.TreeType
+	// ConfigureInterceptors, if set, is called with the interceptor chain
+	// run for requests made through the clients (see
+	// DefaultInterceptorChain), to allow custom interceptors to be added, or
+	// stages to be replaced.
+	ConfigureInterceptors func(*interceptor.Chain)
+
+	// Signer, if set, configures the log signer run by Start. Its Registry
+	// field is filled in by New, as is its TimeSource if unset. If the
+	// registry has no ElectionFactory, the signer acts as master for all
+	// logs, so it mustn't share storage with any other signer.
+	Signer *LogOperationInfo
+	// SequencerGuardWindow is the time elapsed before submitted leaves are
+	// eligible for sequencing by the signer.
+	SequencerGuardWindow time.Duration
+	// CatchUp configures the catch-up mode of the signer's logs, see
+	// CatchUpOpts.
+	CatchUp CatchUpOpts
+	// SignerPreflight, if true, means the signer checks the keys and latest
+	// root of each log before first signing its roots.
+	SignerPreflight bool
+
+	// Gossip, if set, is the pool of log roots observed by other parties
+	// which the log server's gossip RPCs add to and serve.
+	Gossip *GossipPool
+	// ReadMemory, if set, limits the memory held by the responses of the log
+	// server's read requests.
+	ReadMemory *ReadMemoryLimiter
+	// DeadlineBudget limits how much of the remaining deadline of a log
+	// server request each of its storage stages may use.
+	DeadlineBudget DeadlineBudget
+	// RangeChunkSize, if positive, is the number of leaves
+	// AddSequencedLeafRange writes per storage transaction.
+	RangeChunkSize int
+	// CircuitBreaker, if set, sheds the log server's read requests when
+	// storage calls start failing.
+	CircuitBreaker *CircuitBreaker
+	// RootFreshness, if set, limits the age of the log roots which the log
+	// server's requests are based on.
+	RootFreshness *RootFreshness
+	// FrontierCache, if set, caches the log frontiers which proofs are built
+	// from when possible.
+	FrontierCache *FrontierCache
+	// ReadRepairer, if set, recomputes the Merkle nodes that proofs need but
+	// which are missing from storage.
+	ReadRepairer *ReadRepairer
+}
+
+// Embedded is a Trillian log server, admin server and (optionally) log
+// signer running inside another binary, such as a personality. Requests are
+// made through the clients it provides, which go through the same request
+// processing (tree checks, quotas and error conversion) as a gRPC server,
+// without leaving the process.
+type Embedded struct {
+	registry    extension.Registry
+	logServer   trillian.TrillianLogServer
+	adminServer trillian.TrillianAdminServer
+	intercept   grpc.UnaryServerInterceptor
+	// streamIntercept is run for streaming requests instead of intercept.
+	streamIntercept grpc.StreamServerInterceptor
+	signer          *LogOperationManager
+
+	// mu guards cancel and done.
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New returns an Embedded log using the storage and quota manager in
+// registry. The signer, if any, doesn't run until Start is called.
+func New(registry extension.Registry, opts Options) (*Embedded, error) {
+	if registry.LogStorage == nil || registry.AdminStorage == nil {
+		return nil, errors.New("registry must include log and admin storage")
+	}
+	if registry.QuotaManager == nil {
+		return nil, errors.New("registry must include a quota manager")
+	}
+	if registry.MetricFactory == nil {
+		registry.MetricFactory = monitoring.InertMetricFactory{}
+	}
+	if opts.TimeSource == nil {
+		opts.TimeSource = util.SystemTimeSource{}
+	}
+	if opts.AllowedTreeTypes == nil {
+		opts.AllowedTreeTypes = []trillian.TreeType{trillian.TreeType_LOG, trillian.TreeType_PREORDERED_LOG}
+	}
+
+	logServer := NewTrillianLogRPCServer(registry, opts.TimeSource)
+	logServer.SetDeadlineBudget(opts.DeadlineBudget)
+	logServer.SetRangeChunkSize(opts.RangeChunkSize)
+	logServer.SetCircuitBreaker(opts.CircuitBreaker)
+	logServer.SetRootFreshness(opts.RootFreshness)
+	logServer.SetReadMemoryLimiter(opts.ReadMemory)
+	logServer.SetFrontierCache(opts.FrontierCache)
+	logServer.SetReadRepairer(opts.ReadRepairer)
+	logServer.SetGossipPool(opts.Gossip)
+	if err := logServer.IsHealthy(); err != nil {
+		return nil, err
+	}
+
+	chain := DefaultInterceptorChain(registry, opts.StatsPrefix, opts.QuotaDryRun, opts.QuotaCosts)
+	if opts.ConfigureInterceptors != nil {
+		opts.ConfigureInterceptors(chain)
+	}
+
+	e := &Embedded{
+		registry:        registry,
+		logServer:       logServer,
+		adminServer:     admin.New(registry, opts.AllowedTreeTypes, opts.TimeSource),
+		intercept:       chain.Unary(),
+		streamIntercept: chain.Stream(),
+	}
+
+	if opts.Signer != nil {
+		if registry.ElectionFactory == nil {
+			hostname, _ := os.Hostname()
+			registry.ElectionFactory = util.NoopElectionFactory{InstanceID: fmt.Sprintf("%s.%d", hostname, os.Getpid())}
+		}
+		info := *opts.Signer
+		info.Registry = registry
+		if info.TimeSource == nil {
+			info.TimeSource = opts.TimeSource
+		}
+		sequencerManager := NewSequencerManager(registry, opts.SequencerGuardWindow)
+		sequencerManager.SetCatchUp(opts.CatchUp)
+		sequencerManager.SetPreflight(opts.SignerPreflight)
+		e.signer = NewLogOperationManager(info, sequencerManager)
+	}
+	return e, nil
+}
+
+// Start runs the log signer, if configured, until Stop is called or ctx is
+// done.
+func (e *Embedded) Start(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.done != nil {
+		return errors.New("already started")
+	}
+	ctx, e.cancel = context.WithCancel(ctx)
+	e.done = make(chan struct{})
+	go func(done chan struct{}) {
+		defer close(done)
+		if e.signer != nil {
+			e.signer.OperationLoop(ctx)
+		} else {
+			<-ctx.Done()
+		}
+	}(e.done)
+	return nil
+}
+
+// Stop stops the log signer started by Start, and waits for it to finish.
+// Storage and the quota manager are owned by the caller, and are left open.
+func (e *Embedded) Stop() {
+	e.mu.Lock()
+	cancel, done := e.cancel, e.done
+	e.cancel, e.done = nil, nil
+	e.mu.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+// RegisterServers registers the embedded log and admin servers with s, for
+// binaries which also want to serve them over gRPC. Requests made through s
+// are processed by its own interceptors, rather than those of e.
+func (e *Embedded) RegisterServers(s *grpc.Server) {
+	e.RegisterLogServer(s)
+	trillian.RegisterTrillianAdminServer(s, e.adminServer)
+}
+
+// RegisterLogServer registers only the embedded log server with s, for
+// binaries whose gRPC server already has an admin server, such as those run
+// by Main.
+func (e *Embedded) RegisterLogServer(s *grpc.Server) {
+	trillian.RegisterTrillianLogServer(s, e.logServer)
+}
+
+// Signer returns the LogOperationManager of the embedded log signer, or nil
+// if there is none, e.g. to report its status on a StatusPage.
+func (e *Embedded) Signer() *LogOperationManager {
+	return e.signer
+}
+
+// LogClient returns a client making requests to the embedded log server.
+func (e *Embedded) LogClient() trillian.TrillianLogClient {
+	return &embeddedLogClient{e: e}
+}
+
+// AdminClient returns a client making requests to the embedded admin server.
+func (e *Embedded) AdminClient() trillian.TrillianAdminClient {
+	return &embeddedAdminClient{e: e}
+}
+
+// call passes req to handler through the interceptors of e, as a gRPC server
+// would for the given method.
+func (e *Embedded) call(ctx context.Context, method string, req interface{}, handler grpc.UnaryHandler) (interface{}, error) {
+	return e.intercept(ctx, req, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+}
+
+type embeddedLogClient struct {
+	e *Embedded
+}
+
+func (c *embeddedLogClient) QueueLeaf(ctx context.Context, in *trillian.QueueLeafRequest, _ ...grpc.CallOption) (*trillian.QueueLeafResponse, error) {
+	resp, err := c.e.call(ctx, "/trillian.TrillianLog/QueueLeaf", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return c.e.logServer.QueueLeaf(ctx, req.(*trillian.QueueLeafRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*trillian.QueueLeafResponse), nil
+}
+
+func (c *embeddedLogClient) AddSequencedLeaf(ctx context.Context, in *trillian.AddSequencedLeafRequest, _ ...grpc.CallOption) (*trillian.AddSequencedLeafResponse, error) {
+	resp, err := c.e.call(ctx, "/trillian.TrillianLog/AddSequencedLeaf", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return c.e.logServer.AddSequencedLeaf(ctx, req.(*trillian.AddSequencedLeafRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*trillian.AddSequencedLeafResponse), nil
+}
+
+func (c *embeddedLogClient) GetInclusionProof(ctx context.Context, in *trillian.GetInclusionProofRequest, _ ...grpc.CallOption) (*trillian.GetInclusionProofResponse, error) {
+	resp, err := c.e.call(ctx, "/trillian.TrillianLog/GetInclusionProof", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return c.e.logServer.GetInclusionProof(ctx, req.(*trillian.GetInclusionProofRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*trillian.GetInclusionProofResponse), nil
+}
+
+func (c *embeddedLogClient) GetInclusionProofByHash(ctx context.Context, in *trillian.GetInclusionProofByHashRequest, _ ...grpc.CallOption) (*trillian.GetInclusionProofByHashResponse, error) {
+	resp, err := c.e.call(ctx, "/trillian.TrillianLog/GetInclusionProofByHash", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return c.e.logServer.GetInclusionProofByHash(ctx, req.(*trillian.GetInclusionProofByHashRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*trillian.GetInclusionProofByHashResponse), nil
+}
+
+func (c *embeddedLogClient) GetConsistencyProof(ctx context.Context, in *trillian.GetConsistencyProofRequest, _ ...grpc.CallOption) (*trillian.GetConsistencyProofResponse, error) {
+	resp, err := c.e.call(ctx, "/trillian.TrillianLog/GetConsistencyProof", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return c.e.logServer.GetConsistencyProof(ctx, req.(*trillian.GetConsistencyProofRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*trillian.GetConsistencyProofResponse), nil
+}
+
+func (c *embeddedLogClient) GetLatestSignedLogRoot(ctx context.Context, in *trillian.GetLatestSignedLogRootRequest, _ ...grpc.CallOption) (*trillian.GetLatestSignedLogRootResponse, error) {
+	resp, err := c.e.call(ctx, "/trillian.TrillianLog/GetLatestSignedLogRoot", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return c.e.logServer.GetLatestSignedLogRoot(ctx, req.(*trillian.GetLatestSignedLogRootRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*trillian.GetLatestSignedLogRootResponse), nil
+}
+
+func (c *embeddedLogClient) GetSequencedLeafCount(ctx context.Context, in *trillian.GetSequencedLeafCountRequest, _ ...grpc.CallOption) (*trillian.GetSequencedLeafCountResponse, error) {
+	resp, err := c.e.call(ctx, "/trillian.TrillianLog/GetSequencedLeafCount", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return c.e.logServer.GetSequencedLeafCount(ctx, req.(*trillian.GetSequencedLeafCountRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*trillian.GetSequencedLeafCountResponse), nil
+}
+
+func (c *embeddedLogClient) GetEntryAndProof(ctx context.Context, in *trillian.GetEntryAndProofRequest, _ ...grpc.CallOption) (*trillian.GetEntryAndProofResponse, error) {
+	resp, err := c.e.call(ctx, "/trillian.TrillianLog/GetEntryAndProof", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return c.e.logServer.GetEntryAndProof(ctx, req.(*trillian.GetEntryAndProofRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*trillian.GetEntryAndProofResponse), nil
+}
+
+func (c *embeddedLogClient) GetEntriesAndProofs(ctx context.Context, in *trillian.GetEntriesAndProofsRequest, _ ...grpc.CallOption) (*trillian.GetEntriesAndProofsResponse, error) {
+	resp, err := c.e.call(ctx, "/trillian.TrillianLog/GetEntriesAndProofs", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return c.e.logServer.GetEntriesAndProofs(ctx, req.(*trillian.GetEntriesAndProofsRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*trillian.GetEntriesAndProofsResponse), nil
+}
+
+func (c *embeddedLogClient) InitLog(ctx context.Context, in *trillian.InitLogRequest, _ ...grpc.CallOption) (*trillian.InitLogResponse, error) {
+	resp, err := c.e.call(ctx, "/trillian.TrillianLog/InitLog", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return c.e.logServer.InitLog(ctx, req.(*trillian.InitLogRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*trillian.InitLogResponse), nil
+}
+
+func (c *embeddedLogClient) QueueLeaves(ctx context.Context, in *trillian.QueueLeavesRequest, _ ...grpc.CallOption) (*trillian.QueueLeavesResponse, error) {
+	resp, err := c.e.call(ctx, "/trillian.TrillianLog/QueueLeaves", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return c.e.logServer.QueueLeaves(ctx, req.(*trillian.QueueLeavesRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*trillian.QueueLeavesResponse), nil
+}
+
+func (c *embeddedLogClient) AddSequencedLeaves(ctx context.Context, in *trillian.AddSequencedLeavesRequest, _ ...grpc.CallOption) (*trillian.AddSequencedLeavesResponse, error) {
+	resp, err := c.e.call(ctx, "/trillian.TrillianLog/AddSequencedLeaves", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return c.e.logServer.AddSequencedLeaves(ctx, req.(*trillian.AddSequencedLeavesRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*trillian.AddSequencedLeavesResponse), nil
+}
+
+func (c *embeddedLogClient) AddSequencedLeafRange(ctx context.Context, in *trillian.AddSequencedLeafRangeRequest, _ ...grpc.CallOption) (*trillian.AddSequencedLeafRangeResponse, error) {
+	resp, err := c.e.call(ctx, "/trillian.TrillianLog/AddSequencedLeafRange", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return c.e.logServer.AddSequencedLeafRange(ctx, req.(*trillian.AddSequencedLeafRangeRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*trillian.AddSequencedLeafRangeResponse), nil
+}
+
+func (c *embeddedLogClient) GetLeavesByIndex(ctx context.Context, in *trillian.GetLeavesByIndexRequest, _ ...grpc.CallOption) (*trillian.GetLeavesByIndexResponse, error) {
+	resp, err := c.e.call(ctx, "/trillian.TrillianLog/GetLeavesByIndex", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return c.e.logServer.GetLeavesByIndex(ctx, req.(*trillian.GetLeavesByIndexRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*trillian.GetLeavesByIndexResponse), nil
+}
+
+func (c *embeddedLogClient) GetLeavesByRange(ctx context.Context, in *trillian.GetLeavesByRangeRequest, _ ...grpc.CallOption) (*trillian.GetLeavesByRangeResponse, error) {
+	resp, err := c.e.call(ctx, "/trillian.TrillianLog/GetLeavesByRange", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return c.e.logServer.GetLeavesByRange(ctx, req.(*trillian.GetLeavesByRangeRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*trillian.GetLeavesByRangeResponse), nil
+}
+
+func (c *embeddedLogClient) GetLeavesByHash(ctx context.Context, in *trillian.GetLeavesByHashRequest, _ ...grpc.CallOption) (*trillian.GetLeavesByHashResponse, error) {
+	resp, err := c.e.call(ctx, "/trillian.TrillianLog/GetLeavesByHash", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return c.e.logServer.GetLeavesByHash(ctx, req.(*trillian.GetLeavesByHashRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*trillian.GetLeavesByHashResponse), nil
+}
+
+func (c *embeddedLogClient) GetLeafIndicesByKey(ctx context.Context, in *trillian.GetLeafIndicesByKeyRequest, _ ...grpc.CallOption) (*trillian.GetLeafIndicesByKeyResponse, error) {
+	resp, err := c.e.call(ctx, "/trillian.TrillianLog/GetLeafIndicesByKey", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return c.e.logServer.GetLeafIndicesByKey(ctx, req.(*trillian.GetLeafIndicesByKeyRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*trillian.GetLeafIndicesByKeyResponse), nil
+}
+
+func (c *embeddedLogClient) CheckLeafFilter(ctx context.Context, in *trillian.CheckLeafFilterRequest, _ ...grpc.CallOption) (*trillian.CheckLeafFilterResponse, error) {
+	resp, err := c.e.call(ctx, "/trillian.TrillianLog/CheckLeafFilter", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return c.e.logServer.CheckLeafFilter(ctx, req.(*trillian.CheckLeafFilterRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*trillian.CheckLeafFilterResponse), nil
+}
+
+func (c *embeddedLogClient) AddObservedRoot(ctx context.Context, in *trillian.AddObservedRootRequest, _ ...grpc.CallOption) (*trillian.AddObservedRootResponse, error) {
+	resp, err := c.e.call(ctx, "/trillian.TrillianLog/AddObservedRoot", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return c.e.logServer.AddObservedRoot(ctx, req.(*trillian.AddObservedRootRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*trillian.AddObservedRootResponse), nil
+}
+
+func (c *embeddedLogClient) GetObservedRoots(ctx context.Context, in *trillian.GetObservedRootsRequest, _ ...grpc.CallOption) (*trillian.GetObservedRootsResponse, error) {
+	resp, err := c.e.call(ctx, "/trillian.TrillianLog/GetObservedRoots", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return c.e.logServer.GetObservedRoots(ctx, req.(*trillian.GetObservedRootsRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*trillian.GetObservedRootsResponse), nil
+}
+
+func (c *embeddedLogClient) GetConsistencyProofChain(ctx context.Context, in *trillian.GetConsistencyProofChainRequest, _ ...grpc.CallOption) (*trillian.GetConsistencyProofChainResponse, error) {
+	resp, err := c.e.call(ctx, "/trillian.TrillianLog/GetConsistencyProofChain", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return c.e.logServer.GetConsistencyProofChain(ctx, req.(*trillian.GetConsistencyProofChainRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*trillian.GetConsistencyProofChainResponse), nil
+}
+
+type embeddedAdminClient struct {
+	e *Embedded
+}
+
+func (c *embeddedAdminClient) ListTrees(ctx context.Context, in *trillian.ListTreesRequest, _ ...grpc.CallOption) (*trillian.ListTreesResponse, error) {
+	resp, err := c.e.call(ctx, "/trillian.TrillianAdmin/ListTrees", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return c.e.adminServer.ListTrees(ctx, req.(*trillian.ListTreesRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*trillian.ListTreesResponse), nil
+}
+
+func (c *embeddedAdminClient) GetTree(ctx context.Context, in *trillian.GetTreeRequest, _ ...grpc.CallOption) (*trillian.Tree, error) {
+	return c.treeCall(ctx, "GetTree", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return c.e.adminServer.GetTree(ctx, req.(*trillian.GetTreeRequest))
+	})
+}
+
+func (c *embeddedAdminClient) CreateTree(ctx context.Context, in *trillian.CreateTreeRequest, _ ...grpc.CallOption) (*trillian.Tree, error) {
+	return c.treeCall(ctx, "CreateTree", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return c.e.adminServer.CreateTree(ctx, req.(*trillian.CreateTreeRequest))
+	})
+}
+
+func (c *embeddedAdminClient) GetTreeByExternalId(ctx context.Context, in *trillian.GetTreeByExternalIdRequest, _ ...grpc.CallOption) (*trillian.Tree, error) {
+	return c.treeCall(ctx, "GetTreeByExternalId", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return c.e.adminServer.GetTreeByExternalId(ctx, req.(*trillian.GetTreeByExternalIdRequest))
+	})
+}
+
+func (c *embeddedAdminClient) UpdateTree(ctx context.Context, in *trillian.UpdateTreeRequest, _ ...grpc.CallOption) (*trillian.Tree, error) {
+	return c.treeCall(ctx, "UpdateTree", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return c.e.adminServer.UpdateTree(ctx, req.(*trillian.UpdateTreeRequest))
+	})
+}
+
+func (c *embeddedAdminClient) DeleteTree(ctx context.Context, in *trillian.DeleteTreeRequest, _ ...grpc.CallOption) (*trillian.Tree, error) {
+	return c.treeCall(ctx, "DeleteTree", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return c.e.adminServer.DeleteTree(ctx, req.(*trillian.DeleteTreeRequest))
+	})
+}
+
+func (c *embeddedAdminClient) UndeleteTree(ctx context.Context, in *trillian.UndeleteTreeRequest, _ ...grpc.CallOption) (*trillian.Tree, error) {
+	return c.treeCall(ctx, "UndeleteTree", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return c.e.adminServer.UndeleteTree(ctx, req.(*trillian.UndeleteTreeRequest))
+	})
+}
+
+func (c *embeddedAdminClient) GetTreeStats(ctx context.Context, in *trillian.GetTreeStatsRequest, _ ...grpc.CallOption) (*trillian.TreeStats, error) {
+	resp, err := c.e.call(ctx, "/trillian.TrillianAdmin/GetTreeStats", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return c.e.adminServer.GetTreeStats(ctx, req.(*trillian.GetTreeStatsRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*trillian.TreeStats), nil
+}
+
+func (c *embeddedAdminClient) InspectLeafQueue(ctx context.Context, in *trillian.InspectLeafQueueRequest, _ ...grpc.CallOption) (*trillian.LeafQueueSample, error) {
+	resp, err := c.e.call(ctx, "/trillian.TrillianAdmin/InspectLeafQueue", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return c.e.adminServer.InspectLeafQueue(ctx, req.(*trillian.InspectLeafQueueRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*trillian.LeafQueueSample), nil
+}
+
+func (c *embeddedAdminClient) CheckTreeIntegrity(ctx context.Context, in *trillian.CheckTreeIntegrityRequest, _ ...grpc.CallOption) (*trillian.TreeIntegrityCheck, error) {
+	resp, err := c.e.call(ctx, "/trillian.TrillianAdmin/CheckTreeIntegrity", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return c.e.adminServer.CheckTreeIntegrity(ctx, req.(*trillian.CheckTreeIntegrityRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*trillian.TreeIntegrityCheck), nil
+}
+
+func (c *embeddedAdminClient) GetTreeIntegrityCheck(ctx context.Context, in *trillian.GetTreeIntegrityCheckRequest, _ ...grpc.CallOption) (*trillian.TreeIntegrityCheck, error) {
+	resp, err := c.e.call(ctx, "/trillian.TrillianAdmin/GetTreeIntegrityCheck", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return c.e.adminServer.GetTreeIntegrityCheck(ctx, req.(*trillian.GetTreeIntegrityCheckRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*trillian.TreeIntegrityCheck), nil
+}
+
+func (c *embeddedAdminClient) GetMergeDelayCompliance(ctx context.Context, in *trillian.GetMergeDelayComplianceRequest, _ ...grpc.CallOption) (*trillian.MergeDelayCompliance, error) {
+	resp, err := c.e.call(ctx, "/trillian.TrillianAdmin/GetMergeDelayCompliance", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return c.e.adminServer.GetMergeDelayCompliance(ctx, req.(*trillian.GetMergeDelayComplianceRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*trillian.MergeDelayCompliance), nil
+}
+
+func (c *embeddedAdminClient) BatchGetTreeStatus(ctx context.Context, in *trillian.BatchGetTreeStatusRequest, _ ...grpc.CallOption) (*trillian.BatchGetTreeStatusResponse, error) {
+	resp, err := c.e.call(ctx, "/trillian.TrillianAdmin/BatchGetTreeStatus", in, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return c.e.adminServer.BatchGetTreeStatus(ctx, req.(*trillian.BatchGetTreeStatusRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*trillian.BatchGetTreeStatusResponse), nil
+}
+
+// treeCall makes an admin request returning a Tree.
+func (c *embeddedAdminClient) treeCall(ctx context.Context, method string, in interface{}, handler grpc.UnaryHandler) (*trillian.Tree, error) {
+	resp, err := c.e.call(ctx, "/trillian.TrillianAdmin/"+method, in, handler)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*trillian.Tree), nil
+}
@@ -0,0 +1,145 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"errors"
+	"io"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func (c *embeddedLogClient) StreamLeavesByRange(ctx context.Context, in *trillian.GetLeavesByRangeRequest, _ ...grpc.CallOption) (trillian.TrillianLog_StreamLeavesByRangeClient, error) {
+	s := c.e.stream(ctx, "/trillian.TrillianLog/StreamLeavesByRange", in, func(srv interface{}, ss grpc.ServerStream) error {
+		req := new(trillian.GetLeavesByRangeRequest)
+		if err := ss.RecvMsg(req); err != nil {
+			return err
+		}
+		return srv.(trillian.TrillianLogServer).StreamLeavesByRange(req, leavesByRangeServer{ss})
+	})
+	return leavesByRangeClient{s}, nil
+}
+
+// stream runs handler for a server-streaming request through the stream
+// interceptors of e, as a gRPC server would for the given method, and returns
+// the client end of the stream. The handler runs until it has sent all its
+// messages, or ctx is done.
+func (e *Embedded) stream(ctx context.Context, method string, req proto.Message, handler grpc.StreamHandler) *embeddedStream {
+	s := &embeddedStream{
+		ctx:  ctx,
+		req:  req,
+		msgs: make(chan proto.Message),
+		done: make(chan struct{}),
+	}
+	info := &grpc.StreamServerInfo{FullMethod: method, IsServerStream: true}
+	go func() {
+		defer close(s.done)
+		s.err = e.streamIntercept(e.logServer, embeddedServerStream{s}, info, handler)
+	}()
+	return s
+}
+
+// embeddedStream connects the client of a server-streaming request to its
+// handler, running in another goroutine.
+type embeddedStream struct {
+	ctx context.Context
+	req proto.Message
+	// msgs passes the messages sent by the handler to the client.
+	msgs chan proto.Message
+	// done is closed when the handler returns, after setting err.
+	done chan struct{}
+	err  error
+}
+
+func (s *embeddedStream) Header() (metadata.MD, error) { return nil, nil }
+func (s *embeddedStream) Trailer() metadata.MD         { return nil }
+func (s *embeddedStream) CloseSend() error             { return nil }
+func (s *embeddedStream) Context() context.Context     { return s.ctx }
+
+func (s *embeddedStream) SendMsg(m interface{}) error {
+	return errors.New("embedded streams only support server streaming")
+}
+
+// RecvMsg receives the next message sent by the handler into m. It returns
+// io.EOF once the handler has returned successfully after sending all its
+// messages, or the handler's error.
+func (s *embeddedStream) RecvMsg(m interface{}) error {
+	// Sends are unbuffered, so all messages are received before done closes.
+	select {
+	case msg := <-s.msgs:
+		copyMessage(m, msg)
+		return nil
+	case <-s.done:
+		if s.err != nil {
+			return s.err
+		}
+		return io.EOF
+	}
+}
+
+// embeddedServerStream is the handler's end of an embeddedStream.
+type embeddedServerStream struct {
+	s *embeddedStream
+}
+
+func (ss embeddedServerStream) SetHeader(metadata.MD) error  { return nil }
+func (ss embeddedServerStream) SendHeader(metadata.MD) error { return nil }
+func (ss embeddedServerStream) SetTrailer(metadata.MD)       {}
+func (ss embeddedServerStream) Context() context.Context     { return ss.s.ctx }
+
+func (ss embeddedServerStream) SendMsg(m interface{}) error {
+	select {
+	case ss.s.msgs <- m.(proto.Message):
+		return nil
+	case <-ss.s.ctx.Done():
+		return ss.s.ctx.Err()
+	}
+}
+
+func (ss embeddedServerStream) RecvMsg(m interface{}) error {
+	copyMessage(m, ss.s.req)
+	return nil
+}
+
+// copyMessage copies src into dst, as marshaling and unmarshaling it would.
+func copyMessage(dst interface{}, src proto.Message) {
+	pb := dst.(proto.Message)
+	pb.Reset()
+	proto.Merge(pb, src)
+}
+
+type leavesByRangeServer struct {
+	grpc.ServerStream
+}
+
+func (s leavesByRangeServer) Send(m *trillian.GetLeavesByRangeResponse) error {
+	return s.SendMsg(m)
+}
+
+type leavesByRangeClient struct {
+	grpc.ClientStream
+}
+
+func (c leavesByRangeClient) Recv() (*trillian.GetLeavesByRangeResponse, error) {
+	m := new(trillian.GetLeavesByRangeResponse)
+	if err := c.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian"
+	"github.com/google/trillian/client"
+	"github.com/google/trillian/extension"
+	"github.com/google/trillian/quota"
+	"github.com/google/trillian/storage/memory"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	_ "github.com/google/trillian/crypto/keys/der/proto" // Register PrivateKey ProtoHandler
+	_ "github.com/google/trillian/merkle/rfc6962"        // Load hasher
+
+	stestonly "github.com/google/trillian/storage/testonly"
+)
+
+func TestEmbedded(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	ls := memory.NewLogStorage(nil)
+	e, err := New(extension.Registry{
+		AdminStorage: memory.NewAdminStorage(ls),
+		LogStorage:   ls,
+		QuotaManager: quota.Noop(),
+	}, Options{
+		Signer: &LogOperationInfo{
+			BatchSize:   10,
+			NumWorkers:  1,
+			RunInterval: 50 * time.Millisecond,
+		},
+	})
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	if err := e.Start(ctx); err != nil {
+		t.Fatalf("Start(): %v", err)
+	}
+	defer e.Stop()
+	if err := e.Start(ctx); err == nil {
+		t.Error("second Start(): got nil error, want error")
+	}
+
+	tree, err := client.CreateAndInitTree(ctx, &trillian.CreateTreeRequest{Tree: proto.Clone(stestonly.LogTree).(*trillian.Tree)}, e.AdminClient(), nil, e.LogClient())
+	if err != nil {
+		t.Fatalf("CreateAndInitTree(): %v", err)
+	}
+	lc, err := client.NewFromTree(e.LogClient(), tree)
+	if err != nil {
+		t.Fatalf("NewFromTree(): %v", err)
+	}
+	// AddLeaf waits for the leaf to be integrated by the embedded signer.
+	for _, leaf := range []string{"one", "two", "three"} {
+		if err := lc.AddLeaf(ctx, []byte(leaf)); err != nil {
+			t.Fatalf("AddLeaf(%q): %v", leaf, err)
+		}
+	}
+
+	// Requests go through the same checks as over gRPC.
+	_, err = e.LogClient().GetLatestSignedLogRoot(ctx, &trillian.GetLatestSignedLogRootRequest{LogId: tree.TreeId + 1})
+	if got, want := status.Code(err), codes.NotFound; got != want {
+		t.Errorf("GetLatestSignedLogRoot() for unknown tree: got %v, want %v", got, want)
+	}
+}
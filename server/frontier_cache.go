@@ -0,0 +1,350 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/golang/glog"
+	"github.com/google/trillian"
+	"github.com/google/trillian/merkle"
+	"github.com/google/trillian/merkle/hashers"
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/storage"
+)
+
+// maxLogDepth is the depth of the node IDs of log trees.
+const maxLogDepth = 64
+
+var (
+	proofFrontierLeaves = flag.Int64("proof_frontier_leaves", 256, "Number of the most recent leaf hashes of each log, a power of two, kept in memory with the log's frontier so that proofs for recent leaves are built without reading Merkle nodes from storage. Zero disables the frontier cache")
+
+	frontierProofs      monitoring.Counter
+	frontierMetricsOnce sync.Once
+
+	// errNotInFrontier is returned when a node of a proof can't be computed
+	// from the frontier of a log.
+	errNotInFrontier = errors.New("node is not in the frontier")
+)
+
+func initFrontierMetrics(mf monitoring.MetricFactory) {
+	frontierMetricsOnce.Do(func() {
+		if mf == nil {
+			mf = monitoring.InertMetricFactory{}
+		}
+		frontierProofs = mf.NewCounter("frontier_proofs", "Number of proofs looked up in the frontier cache of a log, by whether they were served from it", logIDLabel, "served")
+	})
+}
+
+// FrontierCache holds the frontier of the logs served, i.e. the hashes of the
+// perfect subtrees which each log's tree at its latest size decomposes into,
+// along with the hashes of its most recent leaves. Proofs which only involve
+// those hashes, such as inclusion proofs for recent leaves at the latest tree
+// size, or consistency proofs from recent tree sizes, are then built without
+// reading the Merkle nodes of the log from storage.
+//
+// The frontier of a log is brought up to date by appending the leaves
+// integrated since it was last used, and checked against the root hash of the
+// log's latest root. It's loaded from the stored Merkle nodes when it's first
+// used, or if it's fallen too far behind.
+type FrontierCache struct {
+	// recentLeaves is the number of recent leaf hashes kept, a power of two.
+	recentLeaves int64
+
+	mu   sync.Mutex
+	logs map[int64]*frontier
+}
+
+// NewFrontierCache returns a FrontierCache which keeps the hashes of the
+// latest recentLeaves leaves of each log, which must be a power of two. Up to
+// twice as many may be kept, as recent leaves are kept by perfect subtree.
+func NewFrontierCache(recentLeaves int64, mf monitoring.MetricFactory) (*FrontierCache, error) {
+	if recentLeaves <= 0 || recentLeaves&(recentLeaves-1) != 0 {
+		return nil, fmt.Errorf("number of recent leaves is %d, want a power of two", recentLeaves)
+	}
+	initFrontierMetrics(mf)
+	return &FrontierCache{
+		recentLeaves: recentLeaves,
+		logs:         make(map[int64]*frontier),
+	}, nil
+}
+
+// FrontierCacheFromFlags returns the FrontierCache specified by flags, or nil
+// if it's disabled.
+func FrontierCacheFromFlags(mf monitoring.MetricFactory) (*FrontierCache, error) {
+	if *proofFrontierLeaves == 0 {
+		return nil, nil
+	}
+	return NewFrontierCache(*proofFrontierLeaves, mf)
+}
+
+// frontier is the frontier of a log at a tree size.
+type frontier struct {
+	size     int64
+	rootHash []byte
+	// nodes are the hashes of the perfect subtrees which the tree decomposes
+	// into, largest first.
+	nodes [][]byte
+	// tail holds the hashes of the leaves from tailStart onwards.
+	tailStart int64
+	tail      [][]byte
+}
+
+// buildProof builds the proof made of the nodes fetched by fetches, as of
+// root, the latest root of the log treeID, from the frontier of the log. It
+// returns an error if the frontier doesn't hold all of the nodes.
+func (c *FrontierCache) buildProof(ctx context.Context, tx storage.ReadOnlyLogTreeTX, hasher hashers.LogHasher, treeID int64, root *trillian.SignedLogRoot, leafIndex int64, fetches []merkle.NodeFetch) (trillian.Proof, error) {
+	label := strconv.FormatInt(treeID, 10)
+	f, err := c.frontier(ctx, tx, hasher, treeID, root)
+	if err != nil {
+		glog.Warningf("%v: failed to get frontier at size %d: %v", treeID, root.TreeSize, err)
+		frontierProofs.Inc(label, "false")
+		return trillian.Proof{}, err
+	}
+	proof, err := fetchNodesAndBuildProof(ctx, storage.NodeReaderForCoords(&frontierNodeReader{f: f, hasher: hasher}), hasher, 0, leafIndex, fetches)
+	frontierProofs.Inc(label, strconv.FormatBool(err == nil))
+	return proof, err
+}
+
+// frontier returns the frontier of treeID at root, updating the cached one.
+func (c *FrontierCache) frontier(ctx context.Context, tx storage.ReadOnlyLogTreeTX, hasher hashers.LogHasher, treeID int64, root *trillian.SignedLogRoot) (*frontier, error) {
+	c.mu.Lock()
+	cached := c.logs[treeID]
+	c.mu.Unlock()
+
+	var f *frontier
+	var err error
+	switch {
+	case cached != nil && cached.size == root.TreeSize:
+		f = cached
+	case cached != nil && cached.size < root.TreeSize && root.TreeSize-cached.size <= c.recentLeaves:
+		f, err = c.extend(ctx, tx, hasher, cached, root.TreeSize)
+	default:
+		f, err = c.load(ctx, tx, hasher, root.TreeSize)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !bytes.Equal(f.rootHash, root.RootHash) {
+		if c.logs[treeID] == cached {
+			delete(c.logs, treeID)
+		}
+		return nil, fmt.Errorf("frontier has root hash %x, want %x", f.rootHash, root.RootHash)
+	}
+	if latest := c.logs[treeID]; latest == nil || latest.size < f.size {
+		c.logs[treeID] = f
+	}
+	return f, nil
+}
+
+// tailStart returns the index of the first recent leaf of a tree of the given
+// size. The leaves from there on make up the perfect subtrees of the tree
+// holding up to recentLeaves leaves.
+func (c *FrontierCache) tailStart(size int64) int64 {
+	return size &^ (2*c.recentLeaves - 1)
+}
+
+// load reads the frontier of the tree of the given size from storage: the
+// Merkle nodes of its perfect subtrees before its recent leaves, and the
+// hashes of its recent leaves.
+func (c *FrontierCache) load(ctx context.Context, tx storage.ReadOnlyLogTreeTX, hasher hashers.LogHasher, size int64) (*frontier, error) {
+	tailStart := c.tailStart(size)
+	var ids []storage.NodeID
+	var start int64
+	for level := uint(maxLogDepth - 1); start < tailStart; level-- {
+		if tailStart&(1<<level) == 0 {
+			continue
+		}
+		id, err := storage.NewNodeIDForTreeCoords(int64(level), start>>level, maxLogDepth)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+		start += 1 << level
+	}
+
+	var nodes [][]byte
+	if len(ids) > 0 {
+		stored, err := tx.GetMerkleNodes(ctx, tx.ReadRevision(), ids)
+		if err != nil {
+			return nil, err
+		}
+		if len(stored) != len(ids) {
+			return nil, fmt.Errorf("expected %d nodes from storage but got %d", len(ids), len(stored))
+		}
+		for i, node := range stored {
+			if !node.NodeID.Equivalent(ids[i]) {
+				return nil, fmt.Errorf("expected node %v at position %d but got %v", ids[i].String(), i, node.NodeID.String())
+			}
+			nodes = append(nodes, node.Hash)
+		}
+	}
+
+	f := &frontier{size: tailStart, nodes: nodes, tailStart: tailStart}
+	return c.extend(ctx, tx, hasher, f, size)
+}
+
+// extend returns the frontier f extended with the leaves up to the given size,
+// which are read from storage.
+func (c *FrontierCache) extend(ctx context.Context, tx storage.ReadOnlyLogTreeTX, hasher hashers.LogHasher, f *frontier, size int64) (*frontier, error) {
+	var leaves []*trillian.LogLeaf
+	if count := size - f.size; count > 0 {
+		var err error
+		if leaves, err = tx.GetLeavesByRange(ctx, f.size, count); err != nil {
+			return nil, err
+		}
+		if int64(len(leaves)) != count {
+			return nil, fmt.Errorf("expected %d leaves from storage but got %d", count, len(leaves))
+		}
+	}
+
+	nodes := f.nodes
+	tail := append([][]byte(nil), f.tail...)
+	for i, leaf := range leaves {
+		if want := f.size + int64(i); leaf.LeafIndex != want {
+			return nil, fmt.Errorf("expected leaf %d from storage but got %d", want, leaf.LeafIndex)
+		}
+		nodes = appendFrontier(hasher, nodes, f.size+int64(i), leaf.MerkleLeafHash)
+		tail = append(tail, leaf.MerkleLeafHash)
+	}
+	tailStart := c.tailStart(size)
+	return &frontier{
+		size:      size,
+		rootHash:  frontierRoot(hasher, nodes),
+		nodes:     nodes,
+		tailStart: tailStart,
+		tail:      tail[tailStart-f.tailStart:],
+	}, nil
+}
+
+// appendFrontier returns the nodes of the frontier of a tree of size+1, given
+// those of the tree of the given size and the hash of the leaf appended. The
+// passed in nodes are left unchanged.
+func appendFrontier(hasher hashers.LogHasher, nodes [][]byte, size int64, leafHash []byte) [][]byte {
+	ret := make([][]byte, len(nodes), len(nodes)+1)
+	copy(ret, nodes)
+	ret = append(ret, leafHash)
+	// Each trailing one bit of size is a subtree merged with the new leaf.
+	for ; size&1 == 1; size >>= 1 {
+		n := len(ret)
+		ret = append(ret[:n-2], hasher.HashChildren(ret[n-2], ret[n-1]))
+	}
+	return ret
+}
+
+// frontierRoot returns the root hash of the tree whose frontier is nodes.
+func frontierRoot(hasher hashers.LogHasher, nodes [][]byte) []byte {
+	if len(nodes) == 0 {
+		return hasher.EmptyRoot()
+	}
+	root := nodes[len(nodes)-1]
+	for i := len(nodes) - 2; i >= 0; i-- {
+		root = hasher.HashChildren(nodes[i], root)
+	}
+	return root
+}
+
+// frontierNodeReader serves the Merkle nodes of a log which can be computed
+// from its frontier. Like the subtree storage, it returns nodes on the right
+// edge of the tree as of the frontier's size, and ignores the tree revision.
+type frontierNodeReader struct {
+	f      *frontier
+	hasher hashers.LogHasher
+}
+
+// GetNodesByCoords implements storage.CoordNodeReader. It returns
+// errNotInFrontier if any of the nodes can't be computed from the frontier.
+func (r *frontierNodeReader) GetNodesByCoords(ctx context.Context, _ int64, coords []storage.NodeCoords) ([]storage.CoordNode, error) {
+	ret := make([]storage.CoordNode, 0, len(coords))
+	for _, c := range coords {
+		level := uint(c.Level)
+		lo := c.Index << level
+		if lo >= r.f.size {
+			return nil, errNotInFrontier
+		}
+		hi := (c.Index + 1) << level
+		if hi > r.f.size || hi <= 0 {
+			hi = r.f.size
+		}
+		h, err := r.rangeHash(lo, hi)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, storage.CoordNode{Coords: c, Hash: h})
+	}
+	return ret, nil
+}
+
+// rangeHash returns MTH(D[lo:hi]) as defined in RFC 6962, where lo is aligned
+// to the largest power of two no greater than hi-lo.
+func (r *frontierNodeReader) rangeHash(lo, hi int64) ([]byte, error) {
+	n := hi - lo
+	if n&(n-1) == 0 {
+		return r.perfectHash(lo, n)
+	}
+	k := int64(1)
+	for k<<1 < n {
+		k <<= 1
+	}
+	left, err := r.perfectHash(lo, k)
+	if err != nil {
+		return nil, err
+	}
+	right, err := r.rangeHash(lo+k, hi)
+	if err != nil {
+		return nil, err
+	}
+	return r.hasher.HashChildren(left, right), nil
+}
+
+// perfectHash returns the hash of the perfect subtree of n leaves, a power of
+// two, starting at leaf lo. It must either be one of the frontier's nodes, or
+// hold recent leaves only.
+func (r *frontierNodeReader) perfectHash(lo, n int64) ([]byte, error) {
+	f := r.f
+	if lo >= f.tailStart {
+		hashes := f.tail[lo-f.tailStart : lo-f.tailStart+n]
+		for len(hashes) > 1 {
+			next := make([][]byte, len(hashes)/2)
+			for i := range next {
+				next[i] = r.hasher.HashChildren(hashes[2*i], hashes[2*i+1])
+			}
+			hashes = next
+		}
+		return hashes[0], nil
+	}
+	var start int64
+	i := 0
+	for level := uint(maxLogDepth - 1); start <= lo && i < len(f.nodes); level-- {
+		if f.size&(1<<level) == 0 {
+			continue
+		}
+		if start == lo && n == 1<<level {
+			return f.nodes[i], nil
+		}
+		start += 1 << level
+		i++
+	}
+	return nil, errNotInFrontier
+}
@@ -0,0 +1,216 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/merkle"
+	"github.com/google/trillian/merkle/hashers"
+	"github.com/google/trillian/merkle/rfc6962"
+	"github.com/google/trillian/storage"
+)
+
+// fakeFrontierTX serves the leaves and Merkle nodes of a log from the hashes
+// of its leaves, counting node reads.
+type fakeFrontierTX struct {
+	storage.ReadOnlyLogTreeTX
+	hasher    hashers.LogHasher
+	leaves    [][]byte
+	nodeReads int
+}
+
+func (tx *fakeFrontierTX) addLeaf(t *testing.T) {
+	t.Helper()
+	h, err := tx.hasher.HashLeaf([]byte(fmt.Sprintf("leaf %d", len(tx.leaves))))
+	if err != nil {
+		t.Fatalf("HashLeaf(): %v", err)
+	}
+	tx.leaves = append(tx.leaves, h)
+}
+
+func (tx *fakeFrontierTX) ReadRevision() int64 {
+	return int64(len(tx.leaves))
+}
+
+func (tx *fakeFrontierTX) GetLeavesByRange(ctx context.Context, start, count int64) ([]*trillian.LogLeaf, error) {
+	var ret []*trillian.LogLeaf
+	for i := start; i < start+count && i < int64(len(tx.leaves)); i++ {
+		ret = append(ret, &trillian.LogLeaf{LeafIndex: i, MerkleLeafHash: tx.leaves[i]})
+	}
+	return ret, nil
+}
+
+func (tx *fakeFrontierTX) GetMerkleNodes(ctx context.Context, _ int64, ids []storage.NodeID) ([]storage.Node, error) {
+	tx.nodeReads++
+	ret := make([]storage.Node, 0, len(ids))
+	for _, id := range ids {
+		c, err := storage.CoordsForNodeID(id)
+		if err != nil {
+			return nil, err
+		}
+		lo, hi := c.Index<<uint(c.Level), (c.Index+1)<<uint(c.Level)
+		if hi > int64(len(tx.leaves)) {
+			hi = int64(len(tx.leaves))
+		}
+		ret = append(ret, storage.Node{NodeID: id, Hash: tx.rangeHash(lo, hi)})
+	}
+	return ret, nil
+}
+
+// rangeHash returns MTH(D[lo:hi]) as defined in RFC 6962.
+func (tx *fakeFrontierTX) rangeHash(lo, hi int64) []byte {
+	n := hi - lo
+	if n == 1 {
+		return tx.leaves[lo]
+	}
+	k := int64(1)
+	for k<<1 < n {
+		k <<= 1
+	}
+	return tx.hasher.HashChildren(tx.rangeHash(lo, lo+k), tx.rangeHash(lo+k, hi))
+}
+
+func (tx *fakeFrontierTX) root() *trillian.SignedLogRoot {
+	size := int64(len(tx.leaves))
+	return &trillian.SignedLogRoot{TreeSize: size, RootHash: tx.rangeHash(0, size)}
+}
+
+func TestNewFrontierCache(t *testing.T) {
+	for _, test := range []struct {
+		recentLeaves int64
+		wantErr      bool
+	}{
+		{recentLeaves: 1},
+		{recentLeaves: 256},
+		{recentLeaves: 0, wantErr: true},
+		{recentLeaves: -4, wantErr: true},
+		{recentLeaves: 100, wantErr: true},
+	} {
+		if _, err := NewFrontierCache(test.recentLeaves, nil); (err != nil) != test.wantErr {
+			t.Errorf("NewFrontierCache(%d)=_,%v, wantErr %v", test.recentLeaves, err, test.wantErr)
+		}
+	}
+}
+
+func TestFrontierCacheProofs(t *testing.T) {
+	ctx := context.Background()
+	const recentLeaves = 8
+	const treeID = 12345
+	hasher := rfc6962.DefaultHasher
+	c, err := NewFrontierCache(recentLeaves, nil)
+	if err != nil {
+		t.Fatalf("NewFrontierCache(): %v", err)
+	}
+	v := merkle.NewLogVerifier(hasher)
+	tx := &fakeFrontierTX{hasher: hasher}
+
+	// The log grows by a few leaves at a time, with an occasional jump which
+	// makes the frontier reload from storage.
+	for _, size := range []int64{1, 2, 3, 7, 8, 9, 15, 21, 22, 29, 37, 38, 40, 45, 100, 101, 108, 116, 127, 128, 129} {
+		for int64(len(tx.leaves)) < size {
+			tx.addLeaf(t)
+		}
+		root := tx.root()
+		tailStart := c.tailStart(size)
+
+		for index := tailStart; index < size; index++ {
+			fetches, err := merkle.InclusionProofNodes(size, index, size)
+			if err != nil {
+				t.Fatalf("InclusionProofNodes(%d, %d): %v", size, index, err)
+			}
+			proof, err := c.buildProof(ctx, tx, hasher, treeID, root, index, fetches)
+			if err != nil {
+				t.Fatalf("buildProof(inclusion of %d at %d): %v", index, size, err)
+			}
+			if err := v.VerifyInclusionProof(index, size, proof.Hashes, root.RootHash, tx.leaves[index]); err != nil {
+				t.Errorf("VerifyInclusionProof(%d, %d): %v", index, size, err)
+			}
+		}
+
+		for first := tailStart; first < size; first++ {
+			if first == 0 {
+				continue
+			}
+			fetches, err := merkle.ConsistencyProofNodes(first, size, size)
+			if err != nil {
+				t.Fatalf("ConsistencyProofNodes(%d, %d): %v", first, size, err)
+			}
+			proof, err := c.buildProof(ctx, tx, hasher, treeID, root, 0, fetches)
+			if err != nil {
+				t.Fatalf("buildProof(consistency from %d to %d): %v", first, size, err)
+			}
+			if err := v.VerifyConsistencyProof(first, size, tx.rangeHash(0, first), root.RootHash, proof.Hashes); err != nil {
+				t.Errorf("VerifyConsistencyProof(%d, %d): %v", first, size, err)
+			}
+		}
+	}
+	// The frontier is loaded when it's first used, from the leaves only as the
+	// tree is small, and from nodes after the jumps to sizes 100 and 127.
+	if got, want := tx.nodeReads, 2; got != want {
+		t.Errorf("read nodes %d times, want %d", got, want)
+	}
+}
+
+func TestFrontierCacheMisses(t *testing.T) {
+	ctx := context.Background()
+	const treeID = 12345
+	hasher := rfc6962.DefaultHasher
+	c, err := NewFrontierCache(4, nil)
+	if err != nil {
+		t.Fatalf("NewFrontierCache(): %v", err)
+	}
+	tx := &fakeFrontierTX{hasher: hasher}
+	for len(tx.leaves) < 50 {
+		tx.addLeaf(t)
+	}
+	root := tx.root()
+
+	for _, test := range []struct {
+		desc            string
+		snapshot, index int64
+	}{
+		{desc: "oldLeaf", snapshot: 50, index: 3},
+		{desc: "oldSnapshot", snapshot: 45, index: 44},
+	} {
+		fetches, err := merkle.InclusionProofNodes(test.snapshot, test.index, root.TreeSize)
+		if err != nil {
+			t.Fatalf("%v: InclusionProofNodes(): %v", test.desc, err)
+		}
+		if _, err := c.buildProof(ctx, tx, hasher, treeID, root, test.index, fetches); err != errNotInFrontier {
+			t.Errorf("%v: buildProof()=_,%v, want %v", test.desc, err, errNotInFrontier)
+		}
+	}
+
+	// A frontier which doesn't match the root isn't used, nor kept.
+	badRoot := *root
+	badRoot.RootHash = []byte("not the root hash")
+	fetches, err := merkle.InclusionProofNodes(50, 49, 50)
+	if err != nil {
+		t.Fatalf("InclusionProofNodes(): %v", err)
+	}
+	if _, err := c.buildProof(ctx, tx, hasher, treeID, &badRoot, 49, fetches); err == nil {
+		t.Error("buildProof(bad root)=_,nil, want error")
+	}
+	c.mu.Lock()
+	_, ok := c.logs[treeID]
+	c.mu.Unlock()
+	if ok {
+		t.Error("frontier not matching root kept in cache")
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian"
+	"github.com/google/trillian/client"
+	"github.com/google/trillian/crypto/keys"
+	"github.com/google/trillian/crypto/keys/der"
+	"github.com/google/trillian/crypto/keyspb"
+	"github.com/google/trillian/extension"
+	"github.com/google/trillian/quota"
+	"github.com/google/trillian/storage/memory"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	stestonly "github.com/google/trillian/storage/testonly"
+)
+
+// newEmbeddedLog starts an embedded server on memory storage, and creates and
+// initialises tree in it. The caller must stop the server.
+func newEmbeddedLog(ctx context.Context, t *testing.T, tree *trillian.Tree) (*Embedded, *trillian.Tree, *client.LogClient) {
+	t.Helper()
+	// Other tests unregister the handler of the log's private key.
+	keys.RegisterHandler(&keyspb.PrivateKey{}, func(ctx context.Context, pb proto.Message) (crypto.Signer, error) {
+		return der.FromProto(pb.(*keyspb.PrivateKey))
+	})
+
+	ls := memory.NewLogStorage(nil)
+	registry := extension.Registry{
+		AdminStorage: memory.NewAdminStorage(ls),
+		LogStorage:   ls,
+		QuotaManager: quota.Noop(),
+	}
+	e, err := New(registry, Options{
+		Signer: &LogOperationInfo{
+			BatchSize:   10,
+			NumWorkers:  1,
+			RunInterval: 50 * time.Millisecond,
+		},
+	})
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	if err := e.Start(ctx); err != nil {
+		t.Fatalf("Start(): %v", err)
+	}
+
+	tree, err = client.CreateAndInitTree(ctx, &trillian.CreateTreeRequest{Tree: tree}, e.AdminClient(), nil, e.LogClient())
+	if err != nil {
+		e.Stop()
+		t.Fatalf("CreateAndInitTree(): %v", err)
+	}
+	lc, err := client.NewFromTree(e.LogClient(), tree)
+	if err != nil {
+		e.Stop()
+		t.Fatalf("NewFromTree(): %v", err)
+	}
+	return e, tree, lc
+}
+
+func TestGetLeafIndicesByKey(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tree := proto.Clone(stestonly.LogTree).(*trillian.Tree)
+	tree.LeafIndexes = []*trillian.LeafIndexSpec{{Name: "domain", Field: "domain"}}
+	e, tree, lc := newEmbeddedLog(ctx, t, tree)
+	defer e.Stop()
+
+	// Leaves are queued one at a time, so they're integrated in order.
+	for i, domain := range []string{"a.com", "b.com", "a.com", "c.com", "a.com"} {
+		leaf := &trillian.LogLeaf{
+			LeafValue: []byte(fmt.Sprintf("leaf %d", i)),
+			ExtraData: []byte(fmt.Sprintf(`{"domain": %q}`, domain)),
+		}
+		if _, err := e.LogClient().QueueLeaf(ctx, &trillian.QueueLeafRequest{LogId: tree.TreeId, Leaf: leaf}); err != nil {
+			t.Fatalf("QueueLeaf(): %v", err)
+		}
+		if _, err := lc.WaitForRootUpdate(ctx, int64(i+1)); err != nil {
+			t.Fatalf("WaitForRootUpdate(): %v", err)
+		}
+	}
+
+	for _, test := range []struct {
+		desc      string
+		req       *trillian.GetLeafIndicesByKeyRequest
+		want      []int64
+		wantNext  int64
+		wantError codes.Code
+	}{
+		{
+			desc: "all",
+			req:  &trillian.GetLeafIndicesByKeyRequest{IndexName: "domain", Key: []byte("a.com")},
+			want: []int64{0, 2, 4},
+		},
+		{
+			desc:     "firstPage",
+			req:      &trillian.GetLeafIndicesByKeyRequest{IndexName: "domain", Key: []byte("a.com"), MaxResults: 2},
+			want:     []int64{0, 2},
+			wantNext: 4,
+		},
+		{
+			desc: "lastPage",
+			req:  &trillian.GetLeafIndicesByKeyRequest{IndexName: "domain", Key: []byte("a.com"), StartIndex: 4, MaxResults: 2},
+			want: []int64{4},
+		},
+		{
+			desc: "unknownKey",
+			req:  &trillian.GetLeafIndicesByKeyRequest{IndexName: "domain", Key: []byte("d.com")},
+		},
+		{
+			desc:      "unknownIndex",
+			req:       &trillian.GetLeafIndicesByKeyRequest{IndexName: "package", Key: []byte("a.com")},
+			wantError: codes.NotFound,
+		},
+		{
+			desc:      "negativeStart",
+			req:       &trillian.GetLeafIndicesByKeyRequest{IndexName: "domain", Key: []byte("a.com"), StartIndex: -1},
+			wantError: codes.InvalidArgument,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			test.req.LogId = tree.TreeId
+			resp, err := e.LogClient().GetLeafIndicesByKey(ctx, test.req)
+			if got := status.Code(err); got != test.wantError {
+				t.Fatalf("GetLeafIndicesByKey(): %v, want code %v", err, test.wantError)
+			}
+			if err != nil {
+				return
+			}
+			if got := resp.LeafIndices; !reflect.DeepEqual(got, test.want) {
+				t.Errorf("GetLeafIndicesByKey().LeafIndices = %v, want %v", got, test.want)
+			}
+			if got := resp.NextIndex; got != test.wantNext {
+				t.Errorf("GetLeafIndicesByKey().NextIndex = %v, want %v", got, test.wantNext)
+			}
+			if got, want := resp.SignedLogRoot.GetTreeSize(), int64(5); got != want {
+				t.Errorf("GetLeafIndicesByKey().SignedLogRoot.TreeSize = %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/golang/glog"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/trillian"
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/util"
+)
+
+var (
+	gossipRootsPerLog = flag.Int("gossip_roots_per_log", 0, "Maximum number of roots observed by other parties kept in memory for each log, for the AddObservedRoot and GetObservedRoots RPCs. Roots showing a split view are kept in preference to others. Zero disables gossip")
+
+	gossipObservedRoots monitoring.Counter
+	gossipSplitViews    monitoring.Counter
+	gossipMetricsOnce   sync.Once
+)
+
+func initGossipMetrics(mf monitoring.MetricFactory) {
+	gossipMetricsOnce.Do(func() {
+		if mf == nil {
+			mf = monitoring.InertMetricFactory{}
+		}
+		gossipObservedRoots = mf.NewCounter("gossip_observed_roots", "Number of observed roots of a log added to its gossip pool, by whether they were new", logIDLabel, "new")
+		gossipSplitViews = mf.NewCounter("gossip_split_views", "Number of roots of a log observed through gossip which conflict with another root of the same size or with the log's history. Any increase means that the log has shown a split view", logIDLabel)
+	})
+}
+
+// GossipPool holds the roots of the logs served which other parties, such as
+// monitors or clients, have observed and submitted, so that anyone can check
+// their own view of a log against them. Roots conflicting with another root
+// of the same size, or with the log's own history, are evidence that the log
+// has shown a split view: they're counted by the gossip_split_views metric,
+// which should be alerted on.
+//
+// The pool is kept in memory, so it only covers the roots submitted to this
+// server since it started.
+type GossipPool struct {
+	maxRoots   int
+	timeSource util.TimeSource
+
+	mu   sync.Mutex
+	logs map[int64]*gossipLog
+}
+
+// gossipLog holds the observed roots of a log.
+type gossipLog struct {
+	// roots are ordered by tree size, then by the time they were received.
+	roots []*trillian.ObservedRoot
+	// splitViews holds the tree sizes at which conflicting roots were seen.
+	splitViews map[int64]bool
+}
+
+// NewGossipPool returns a GossipPool which keeps up to maxRoots observed roots
+// of each log.
+func NewGossipPool(maxRoots int, mf monitoring.MetricFactory, timeSource util.TimeSource) (*GossipPool, error) {
+	if maxRoots <= 0 {
+		return nil, fmt.Errorf("max roots per log is %d, want > 0", maxRoots)
+	}
+	initGossipMetrics(mf)
+	return &GossipPool{
+		maxRoots:   maxRoots,
+		timeSource: timeSource,
+		logs:       make(map[int64]*gossipLog),
+	}, nil
+}
+
+// GossipPoolFromFlags returns the GossipPool specified by flags, or nil if
+// gossip is disabled.
+func GossipPoolFromFlags(mf monitoring.MetricFactory, timeSource util.TimeSource) (*GossipPool, error) {
+	if *gossipRootsPerLog == 0 {
+		return nil, nil
+	}
+	return NewGossipPool(*gossipRootsPerLog, mf, timeSource)
+}
+
+// add adds root, a root of logID whose signature has been verified, to the
+// pool, unless it's already there. conflictsWithLog is whether the root is
+// known to conflict with the log's own history. It returns whether conflicting
+// roots have been seen at the root's size.
+func (g *GossipPool) add(logID int64, root *trillian.SignedLogRoot, observer string, conflictsWithLog bool) bool {
+	label := strconv.FormatInt(logID, 10)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	l, ok := g.logs[logID]
+	if !ok {
+		l = &gossipLog{splitViews: make(map[int64]bool)}
+		g.logs[logID] = l
+	}
+
+	conflict := conflictsWithLog
+	for _, r := range l.roots {
+		if r.SignedLogRoot.TreeSize != root.TreeSize {
+			continue
+		}
+		if proto.Equal(r.SignedLogRoot, root) {
+			gossipObservedRoots.Inc(label, "false")
+			return l.splitViews[root.TreeSize]
+		}
+		conflict = conflict || !bytes.Equal(r.SignedLogRoot.RootHash, root.RootHash)
+	}
+	gossipObservedRoots.Inc(label, "true")
+	if conflict {
+		glog.Errorf("%v: split view: observer %q saw root hash %x at size %d, which conflicts with other observed roots or the log's history", logID, observer, root.RootHash, root.TreeSize)
+		gossipSplitViews.Inc(label)
+		l.splitViews[root.TreeSize] = true
+	}
+
+	receiveTime, err := ptypes.TimestampProto(g.timeSource.Now())
+	if err != nil {
+		glog.Warningf("%v: failed to convert receive time of observed root: %v", logID, err)
+	}
+	i := sort.Search(len(l.roots), func(i int) bool { return l.roots[i].SignedLogRoot.TreeSize > root.TreeSize })
+	l.roots = append(l.roots, nil)
+	copy(l.roots[i+1:], l.roots[i:])
+	l.roots[i] = &trillian.ObservedRoot{
+		SignedLogRoot: root,
+		Observer:      observer,
+		ReceiveTime:   receiveTime,
+	}
+	if len(l.roots) > g.maxRoots {
+		l.evict()
+	}
+	return l.splitViews[root.TreeSize]
+}
+
+// evict removes a root from l: the one with the smallest tree size which
+// doesn't show a split view, or the one with the smallest tree size if they
+// all do.
+func (l *gossipLog) evict() {
+	i := 0
+	for j, r := range l.roots {
+		if !l.splitViews[r.SignedLogRoot.TreeSize] {
+			i = j
+			break
+		}
+	}
+	l.roots = append(l.roots[:i], l.roots[i+1:]...)
+}
+
+// list returns the observed roots of logID of treeSize, or all of them if it's
+// zero, along with the tree sizes at which conflicting roots were seen.
+func (g *GossipPool) list(logID, treeSize int64) ([]*trillian.ObservedRoot, []int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	l, ok := g.logs[logID]
+	if !ok {
+		return nil, nil
+	}
+	var roots []*trillian.ObservedRoot
+	for _, r := range l.roots {
+		if treeSize == 0 || r.SignedLogRoot.TreeSize == treeSize {
+			roots = append(roots, proto.Clone(r).(*trillian.ObservedRoot))
+		}
+	}
+	var sizes []int64
+	for size := range l.splitViews {
+		if treeSize == 0 || size == treeSize {
+			sizes = append(sizes, size)
+		}
+	}
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i] < sizes[j] })
+	return roots, sizes
+}
@@ -0,0 +1,201 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/trillian"
+	"github.com/google/trillian/crypto/keys"
+	"github.com/google/trillian/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	tcrypto "github.com/google/trillian/crypto"
+	stestonly "github.com/google/trillian/storage/testonly"
+)
+
+func observedSizes(roots []*trillian.ObservedRoot) []int64 {
+	var sizes []int64
+	for _, r := range roots {
+		sizes = append(sizes, r.SignedLogRoot.TreeSize)
+	}
+	return sizes
+}
+
+func TestGossipPool(t *testing.T) {
+	if _, err := NewGossipPool(0, nil, util.SystemTimeSource{}); err == nil {
+		t.Error("NewGossipPool(0): got nil error, want non-nil")
+	}
+	g, err := NewGossipPool(3, nil, util.NewFakeTimeSource(time.Unix(1000, 0)))
+	if err != nil {
+		t.Fatalf("NewGossipPool(): %v", err)
+	}
+	root := func(size int64, hash string) *trillian.SignedLogRoot {
+		return &trillian.SignedLogRoot{LogId: 1, TreeSize: size, RootHash: []byte(hash), TimestampNanos: size}
+	}
+
+	for _, test := range []struct {
+		desc          string
+		root          *trillian.SignedLogRoot
+		conflictsWith bool
+		wantSplit     bool
+		wantSizes     []int64
+	}{
+		{desc: "first", root: root(2, "b"), wantSizes: []int64{2}},
+		{desc: "smaller", root: root(1, "a"), wantSizes: []int64{1, 2}},
+		{desc: "duplicate", root: root(1, "a"), wantSizes: []int64{1, 2}},
+		{desc: "conflict", root: root(2, "c"), wantSplit: true, wantSizes: []int64{1, 2, 2}},
+		// The smallest root which doesn't show a split view is evicted.
+		{desc: "evict", root: root(3, "d"), wantSizes: []int64{2, 2, 3}},
+		{desc: "duplicateOfConflict", root: root(2, "c"), wantSplit: true, wantSizes: []int64{2, 2, 3}},
+		{desc: "conflictsWithLog", root: root(4, "e"), conflictsWith: true, wantSplit: true, wantSizes: []int64{2, 2, 4}},
+		// Roots showing a split view are kept in preference to new ones.
+		{desc: "keepSplitViews", root: root(5, "f"), wantSizes: []int64{2, 2, 4}},
+	} {
+		if got := g.add(1, test.root, "monitor", test.conflictsWith); got != test.wantSplit {
+			t.Errorf("%v: add() = %v, want %v", test.desc, got, test.wantSplit)
+		}
+		roots, _ := g.list(1, 0)
+		if got := observedSizes(roots); !reflect.DeepEqual(got, test.wantSizes) {
+			t.Errorf("%v: observed root sizes = %v, want %v", test.desc, got, test.wantSizes)
+		}
+	}
+
+	roots, splitViews := g.list(1, 4)
+	if got, want := observedSizes(roots), []int64{4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("list(4) root sizes = %v, want %v", got, want)
+	}
+	if want := []int64{4}; !reflect.DeepEqual(splitViews, want) {
+		t.Errorf("list(4) split views = %v, want %v", splitViews, want)
+	}
+	if _, splitViews := g.list(1, 0); !reflect.DeepEqual(splitViews, []int64{2, 4}) {
+		t.Errorf("list(0) split views = %v, want [2 4]", splitViews)
+	}
+	if got, want := roots[0].Observer, "monitor"; got != want {
+		t.Errorf("Observer = %q, want %q", got, want)
+	}
+	if got, want := roots[0].ReceiveTime.GetSeconds(), int64(1000); got != want {
+		t.Errorf("ReceiveTime = %v, want %v seconds", roots[0].ReceiveTime, want)
+	}
+	if roots, splitViews := g.list(2, 0); roots != nil || splitViews != nil {
+		t.Errorf("list() of unknown log = %v, %v, want nil", roots, splitViews)
+	}
+}
+
+func TestObservedRoots(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	e, tree, lc := newEmbeddedLog(ctx, t, stestonly.LogTree)
+	defer e.Stop()
+
+	// Gossip is disabled until the server has a pool.
+	_, err := e.LogClient().GetObservedRoots(ctx, &trillian.GetObservedRootsRequest{LogId: tree.TreeId})
+	if got, want := status.Code(err), codes.Unimplemented; got != want {
+		t.Errorf("GetObservedRoots() without a pool: %v, want code %v", err, want)
+	}
+	g, err := NewGossipPool(10, nil, util.SystemTimeSource{})
+	if err != nil {
+		t.Fatalf("NewGossipPool(): %v", err)
+	}
+	e.logServer.(*TrillianLogRPCServer).SetGossipPool(g)
+
+	var root2, root3 *trillian.SignedLogRoot
+	for i := 0; i < 3; i++ {
+		leaf := &trillian.LogLeaf{LeafValue: []byte(fmt.Sprintf("leaf %d", i))}
+		if _, err := e.LogClient().QueueLeaf(ctx, &trillian.QueueLeafRequest{LogId: tree.TreeId, Leaf: leaf}); err != nil {
+			t.Fatalf("QueueLeaf(): %v", err)
+		}
+		root, err := lc.WaitForRootUpdate(ctx, int64(i+1))
+		if err != nil {
+			t.Fatalf("WaitForRootUpdate(): %v", err)
+		}
+		root2, root3 = root3, root
+	}
+
+	var privateKey ptypes.DynamicAny
+	if err := ptypes.UnmarshalAny(stestonly.LogTree.PrivateKey, &privateKey); err != nil {
+		t.Fatalf("UnmarshalAny(): %v", err)
+	}
+	key, err := keys.NewSigner(ctx, privateKey.Message)
+	if err != nil {
+		t.Fatalf("NewSigner(): %v", err)
+	}
+	// forge returns a root of size signed by the log's key, with hash.
+	forge := func(size int64, hash string) *trillian.SignedLogRoot {
+		root := &trillian.SignedLogRoot{TreeSize: size, RootHash: []byte(hash), TimestampNanos: time.Now().UnixNano()}
+		sig, err := tcrypto.NewSHA256Signer(key).SignLogRoot(root)
+		if err != nil {
+			t.Fatalf("SignLogRoot(): %v", err)
+		}
+		root.Signature = sig
+		return root
+	}
+	badSignature := proto.Clone(root3).(*trillian.SignedLogRoot)
+	badSignature.RootHash = []byte("forged")
+
+	for _, test := range []struct {
+		desc      string
+		root      *trillian.SignedLogRoot
+		wantSplit bool
+		wantError codes.Code
+	}{
+		{desc: "latest", root: root3},
+		{desc: "past", root: root2},
+		{desc: "future", root: forge(10, "future")},
+		{desc: "conflictsWithLatest", root: forge(3, "forked"), wantSplit: true},
+		{desc: "conflictsWithPast", root: forge(2, "forked"), wantSplit: true},
+		{desc: "conflictsWithEmpty", root: forge(0, "forked"), wantSplit: true},
+		{desc: "badSignature", root: badSignature, wantError: codes.InvalidArgument},
+		{desc: "unsigned", root: &trillian.SignedLogRoot{TreeSize: 3}, wantError: codes.InvalidArgument},
+		{desc: "noRoot", wantError: codes.InvalidArgument},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			resp, err := e.LogClient().AddObservedRoot(ctx, &trillian.AddObservedRootRequest{
+				LogId:         tree.TreeId,
+				SignedLogRoot: test.root,
+				Observer:      "monitor",
+			})
+			if got := status.Code(err); got != test.wantError {
+				t.Fatalf("AddObservedRoot(): %v, want code %v", err, test.wantError)
+			}
+			if err == nil && resp.SplitView != test.wantSplit {
+				t.Errorf("AddObservedRoot().SplitView = %v, want %v", resp.SplitView, test.wantSplit)
+			}
+		})
+	}
+
+	resp, err := e.LogClient().GetObservedRoots(ctx, &trillian.GetObservedRootsRequest{LogId: tree.TreeId})
+	if err != nil {
+		t.Fatalf("GetObservedRoots(): %v", err)
+	}
+	if got, want := observedSizes(resp.ObservedRoots), []int64{0, 2, 2, 3, 3, 10}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetObservedRoots() root sizes = %v, want %v", got, want)
+	}
+	if got, want := resp.SplitViewTreeSizes, []int64{0, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetObservedRoots().SplitViewTreeSizes = %v, want %v", got, want)
+	}
+	_, err = e.LogClient().GetObservedRoots(ctx, &trillian.GetObservedRootsRequest{LogId: tree.TreeId, TreeSize: -1})
+	if got, want := status.Code(err), codes.InvalidArgument; got != want {
+		t.Errorf("GetObservedRoots() with negative size: %v, want code %v", err, want)
+	}
+}
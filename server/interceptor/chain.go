@@ -0,0 +1,144 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interceptor
+
+import (
+	"google.golang.org/grpc"
+)
+
+// Stage identifies one of the stages of the interceptor chain run by Trillian
+// servers. Requests pass through the stages in the order below, and responses
+// in the reverse order.
+type Stage int
+
+const (
+	// StageMetrics records RPC counts and latencies.
+	StageMetrics Stage = iota
+	// StageErrors converts errors returned by later stages and the handler
+	// into gRPC statuses.
+	StageErrors
+	// StageTrillian validates requests against the tree they address (its
+	// existence, type and state) and enforces quotas, as implemented by
+	// TrillianInterceptor. Authorization will be added to this stage.
+	StageTrillian
+
+	numStages = iota
+)
+
+// Chain is the ordered chain of interceptors run by a Trillian server: one
+// interceptor for each Stage, plus any custom interceptors inserted before or
+// after specific stages. The stages only apply to unary RPCs; stream
+// interceptors, such as TrillianInterceptor.StreamInterceptor for Trillian's
+// streaming RPCs, are run in the same relative order.
+//
+// A Chain is not safe for concurrent modification, and should be fully set up
+// before the server starts.
+type Chain struct {
+	stages       [numStages]grpc.UnaryServerInterceptor
+	before       [numStages][]grpc.UnaryServerInterceptor
+	after        [numStages][]grpc.UnaryServerInterceptor
+	streamBefore [numStages][]grpc.StreamServerInterceptor
+	streamAfter  [numStages][]grpc.StreamServerInterceptor
+}
+
+// NewChain returns an empty Chain, with no interceptor set for any stage.
+func NewChain() *Chain {
+	return &Chain{}
+}
+
+// Set sets the interceptor run for stage, replacing any previously set. A nil
+// interceptor disables the stage, although interceptors inserted before or
+// after it still run.
+func (c *Chain) Set(stage Stage, i grpc.UnaryServerInterceptor) {
+	c.stages[stage] = i
+}
+
+// Get returns the interceptor run for stage, or nil if it's disabled.
+func (c *Chain) Get(stage Stage) grpc.UnaryServerInterceptor {
+	return c.stages[stage]
+}
+
+// AddBefore inserts i into the chain just before stage, after any
+// interceptors previously inserted there, so that it sees requests before the
+// stage does.
+func (c *Chain) AddBefore(stage Stage, i grpc.UnaryServerInterceptor) {
+	c.before[stage] = append(c.before[stage], i)
+}
+
+// AddAfter inserts i into the chain just after stage, after any interceptors
+// previously inserted there, so that it sees requests after the stage does.
+func (c *Chain) AddAfter(stage Stage, i grpc.UnaryServerInterceptor) {
+	c.after[stage] = append(c.after[stage], i)
+}
+
+// AddStreamBefore inserts a stream interceptor into the chain at the same
+// position AddBefore would insert a unary one.
+func (c *Chain) AddStreamBefore(stage Stage, i grpc.StreamServerInterceptor) {
+	c.streamBefore[stage] = append(c.streamBefore[stage], i)
+}
+
+// AddStreamAfter inserts a stream interceptor into the chain at the same
+// position AddAfter would insert a unary one.
+func (c *Chain) AddStreamAfter(stage Stage, i grpc.StreamServerInterceptor) {
+	c.streamAfter[stage] = append(c.streamAfter[stage], i)
+}
+
+// Unary returns a single interceptor running all the unary interceptors in
+// the chain, in order.
+func (c *Chain) Unary() grpc.UnaryServerInterceptor {
+	var all []grpc.UnaryServerInterceptor
+	for s := range c.stages {
+		all = append(all, c.before[s]...)
+		if c.stages[s] != nil {
+			all = append(all, c.stages[s])
+		}
+		all = append(all, c.after[s]...)
+	}
+	return Combine(all...)
+}
+
+// Stream returns a single interceptor running all the stream interceptors in
+// the chain, in order.
+func (c *Chain) Stream() grpc.StreamServerInterceptor {
+	var all []grpc.StreamServerInterceptor
+	for s := range c.stages {
+		all = append(all, c.streamBefore[s]...)
+		all = append(all, c.streamAfter[s]...)
+	}
+	return CombineStream(all...)
+}
+
+// ServerOptions returns the options installing the chain into a gRPC server.
+func (c *Chain) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.UnaryInterceptor(c.Unary()),
+		grpc.StreamInterceptor(c.Stream()),
+	}
+}
+
+// CombineStream combines stream interceptors.
+// They are nested in order, so interceptor[0] calls on to (and sees the result of) interceptor[1], etc.
+func CombineStream(interceptors ...grpc.StreamServerInterceptor) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			intercept := interceptors[i]
+			baseHandler := handler
+			handler = func(srv interface{}, ss grpc.ServerStream) error {
+				return intercept(srv, ss, info, baseHandler)
+			}
+		}
+		return handler(srv, ss)
+	}
+}
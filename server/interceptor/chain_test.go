@@ -0,0 +1,121 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interceptor
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// recorder returns interceptors which record the order they're called in.
+type recorder struct {
+	calls []string
+}
+
+func (r *recorder) unary(name string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		r.calls = append(r.calls, name)
+		return handler(ctx, req)
+	}
+}
+
+func (r *recorder) stream(name string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		r.calls = append(r.calls, name)
+		return handler(srv, ss)
+	}
+}
+
+func TestChainOrder(t *testing.T) {
+	r := &recorder{}
+	c := NewChain()
+	c.Set(StageMetrics, r.unary("metrics"))
+	c.Set(StageErrors, r.unary("errors"))
+	c.Set(StageTrillian, r.unary("trillian"))
+	c.AddAfter(StageTrillian, r.unary("after trillian"))
+	c.AddBefore(StageTrillian, r.unary("before trillian 1"))
+	c.AddBefore(StageTrillian, r.unary("before trillian 2"))
+	c.AddBefore(StageMetrics, r.unary("before metrics"))
+	c.AddAfter(StageErrors, r.unary("after errors"))
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		r.calls = append(r.calls, "handler")
+		return req, nil
+	}
+	resp, err := c.Unary()(context.Background(), "req", &grpc.UnaryServerInfo{}, handler)
+	if err != nil {
+		t.Fatalf("Unary(): %v", err)
+	}
+	if resp != "req" {
+		t.Errorf("Unary(): %v, want req", resp)
+	}
+	want := []string{
+		"before metrics",
+		"metrics",
+		"errors",
+		"after errors",
+		"before trillian 1",
+		"before trillian 2",
+		"trillian",
+		"after trillian",
+		"handler",
+	}
+	if !reflect.DeepEqual(r.calls, want) {
+		t.Errorf("Unary() called %v, want %v", r.calls, want)
+	}
+
+	// Disabling a stage leaves the interceptors around it.
+	r.calls = nil
+	c.Set(StageTrillian, nil)
+	if _, err := c.Unary()(context.Background(), "req", &grpc.UnaryServerInfo{}, handler); err != nil {
+		t.Fatalf("Unary(): %v", err)
+	}
+	want = []string{
+		"before metrics",
+		"metrics",
+		"errors",
+		"after errors",
+		"before trillian 1",
+		"before trillian 2",
+		"after trillian",
+		"handler",
+	}
+	if !reflect.DeepEqual(r.calls, want) {
+		t.Errorf("Unary() with disabled stage called %v, want %v", r.calls, want)
+	}
+}
+
+func TestChainStreamOrder(t *testing.T) {
+	r := &recorder{}
+	c := NewChain()
+	c.AddStreamAfter(StageTrillian, r.stream("after trillian"))
+	c.AddStreamBefore(StageErrors, r.stream("before errors"))
+	c.AddStreamAfter(StageMetrics, r.stream("after metrics"))
+
+	handler := func(srv interface{}, ss grpc.ServerStream) error {
+		r.calls = append(r.calls, "handler")
+		return nil
+	}
+	if err := c.Stream()(nil, nil, &grpc.StreamServerInfo{}, handler); err != nil {
+		t.Fatalf("Stream(): %v", err)
+	}
+	want := []string{"after metrics", "before errors", "after trillian", "handler"}
+	if !reflect.DeepEqual(r.calls, want) {
+		t.Errorf("Stream() called %v, want %v", r.calls, want)
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interceptor
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian"
+)
+
+// MethodCost is the quota cost of the requests of an RPC method.
+type MethodCost struct {
+	// PerItem is the number of tokens charged for each item of work of a
+	// request: each leaf queued or set, each leaf or proof fetched.
+	PerItem float64
+	// PerKiB is the number of tokens charged for each KiB of leaf data written
+	// by a request, or read by it. Reads are charged once the response is
+	// known, so they can't cause the request to be denied.
+	PerKiB float64
+}
+
+// CostModel maps the names of request messages, without their "Request"
+// suffix (e.g. "QueueLeaves" or "GetMapLeaves"), to the cost of their requests.
+// Requests of methods that aren't in the model cost one token, or one token
+// per leaf for batches of leaves written.
+type CostModel map[string]MethodCost
+
+// ParseCostModel parses a CostModel from comma or newline separated
+// method=per_item[:per_kib] entries, e.g.
+// "QueueLeaves=1:0.5,GetLeavesByRange=0.1". Anything after a "#" on a line is
+// ignored, so that the model may be read from a file with an entry per line.
+func ParseCostModel(s string) (CostModel, error) {
+	m := make(CostModel)
+	for _, line := range strings.Split(s, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		for _, entry := range strings.Split(line, ",") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+			method, cost, err := parseMethodCost(entry)
+			if err != nil {
+				return nil, err
+			}
+			m[method] = cost
+		}
+	}
+	return m, nil
+}
+
+func parseMethodCost(entry string) (string, MethodCost, error) {
+	var c MethodCost
+	kv := strings.SplitN(entry, "=", 2)
+	if len(kv) != 2 {
+		return "", c, fmt.Errorf("cost %q: want method=per_item[:per_kib]", entry)
+	}
+	method := strings.TrimSpace(kv[0])
+	if proto.MessageType("trillian."+method+"Request") == nil {
+		return "", c, fmt.Errorf("cost %q: unknown method %q", entry, method)
+	}
+	weights := strings.Split(kv[1], ":")
+	if len(weights) > 2 {
+		return "", c, fmt.Errorf("cost %q: want method=per_item[:per_kib]", entry)
+	}
+	for i, w := range weights {
+		f, err := strconv.ParseFloat(strings.TrimSpace(w), 64)
+		if err != nil || f < 0 || math.IsInf(f, 0) || math.IsNaN(f) {
+			return "", c, fmt.Errorf("cost %q: bad weight %q", entry, w)
+		}
+		if i == 0 {
+			c.PerItem = f
+		} else {
+			c.PerKiB = f
+		}
+	}
+	return method, c, nil
+}
+
+// methodCost returns the cost of req, if the model has one for its method.
+func (m CostModel) methodCost(req interface{}) (MethodCost, bool) {
+	if len(m) == 0 {
+		return MethodCost{}, false
+	}
+	t := reflect.TypeOf(req)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	c, ok := m[strings.TrimSuffix(t.Name(), "Request")]
+	return c, ok
+}
+
+// tokens returns the number of tokens charged for items of work and size
+// bytes of leaf data.
+func (c MethodCost) tokens(items, size int) int {
+	return int(math.Ceil(c.PerItem*float64(items) + c.PerKiB*float64(size)/1024))
+}
+
+// requestItems returns the number of items of work req asks for. Batches of
+// leaves written may be empty, other requests are at least one item.
+func requestItems(req interface{}) int {
+	n := 1
+	switch req := req.(type) {
+	case logLeavesRequest:
+		return len(req.GetLeaves())
+	case mapLeavesRequest:
+		return len(req.GetLeaves())
+	case *trillian.GetLeavesByIndexRequest:
+		n = len(req.GetLeafIndex())
+	case *trillian.GetEntriesAndProofsRequest:
+		n = len(req.GetLeafIndex())
+	case *trillian.GetLeavesByHashRequest:
+		n = len(req.GetLeafHash())
+	case *trillian.GetLeavesByRangeRequest:
+		n = int(req.GetCount())
+	case *trillian.GetLeafIndicesByKeyRequest:
+		n = int(req.GetMaxResults())
+	case *trillian.CheckLeafFilterRequest:
+		n = len(req.GetLeafIdentityHash())
+	case *trillian.GetMapLeavesRequest:
+		n = len(req.GetIndex())
+	case *trillian.GetMapLeavesByRevisionRequest:
+		n = len(req.GetIndex())
+	case *trillian.GetMapLeafHistoryRequest:
+		n = int(req.GetLastRevision() - req.GetFirstRevision() + 1)
+	case *trillian.GetMapDiffRequest:
+		n = int(req.GetMaxKeys())
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// requestBytes returns the size of the leaf data written by req.
+func requestBytes(req interface{}) int {
+	n := 0
+	switch req := req.(type) {
+	case logLeavesRequest:
+		for _, l := range req.GetLeaves() {
+			n += logLeafBytes(l)
+		}
+	case mapLeavesRequest:
+		for _, l := range req.GetLeaves() {
+			n += mapLeafBytes(l)
+		}
+	case logLeafRequest:
+		n = logLeafBytes(req.GetLeaf())
+	}
+	return n
+}
+
+// responseBytes returns the size of the leaf data returned by resp.
+func responseBytes(resp interface{}) int {
+	n := 0
+	switch resp := resp.(type) {
+	case logLeavesReadResponse:
+		for _, l := range resp.GetLeaves() {
+			n += logLeafBytes(l)
+		}
+	case *trillian.GetEntryAndProofResponse:
+		n = logLeafBytes(resp.GetLeaf())
+	case *trillian.GetEntriesAndProofsResponse:
+		for _, e := range resp.GetEntries() {
+			n += logLeafBytes(e.GetLeaf())
+		}
+	case *trillian.GetMapLeavesResponse:
+		for _, inc := range resp.GetMapLeafInclusion() {
+			n += mapLeafBytes(inc.GetLeaf())
+		}
+	case *trillian.GetMapLeafHistoryResponse:
+		for _, e := range resp.GetEntries() {
+			n += mapLeafBytes(e.GetLeafInclusion().GetLeaf())
+		}
+	}
+	return n
+}
+
+func logLeafBytes(l *trillian.LogLeaf) int {
+	return len(l.GetLeafValue()) + len(l.GetExtraData())
+}
+
+func mapLeafBytes(l *trillian.MapLeaf) int {
+	return len(l.GetLeafValue()) + len(l.GetExtraData())
+}
@@ -0,0 +1,199 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interceptor
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/trillian"
+	"github.com/google/trillian/quota"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/testonly"
+	"github.com/kylelemons/godebug/pretty"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseCostModel(t *testing.T) {
+	tests := []struct {
+		desc    string
+		s       string
+		want    CostModel
+		wantErr bool
+	}{
+		{desc: "empty", s: "", want: CostModel{}},
+		{
+			desc: "flag",
+			s:    "QueueLeaves=1:0.5, GetLeavesByRange=0.1",
+			want: CostModel{
+				"QueueLeaves":      {PerItem: 1, PerKiB: 0.5},
+				"GetLeavesByRange": {PerItem: 0.1},
+			},
+		},
+		{
+			desc: "file",
+			s:    "# Costs\nGetMapLeaves=2 # per index\n\nSetMapLeaves=1:1,InitMap=0\n",
+			want: CostModel{
+				"GetMapLeaves": {PerItem: 2},
+				"SetMapLeaves": {PerItem: 1, PerKiB: 1},
+				"InitMap":      {},
+			},
+		},
+		{desc: "noWeight", s: "QueueLeaves", wantErr: true},
+		{desc: "unknownMethod", s: "QueueLlamas=1", wantErr: true},
+		{desc: "requestSuffix", s: "QueueLeavesRequest=1", wantErr: true},
+		{desc: "badWeight", s: "QueueLeaves=one", wantErr: true},
+		{desc: "negativeWeight", s: "QueueLeaves=-1", wantErr: true},
+		{desc: "tooManyWeights", s: "QueueLeaves=1:2:3", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := ParseCostModel(test.s)
+		if gotErr := err != nil; gotErr != test.wantErr {
+			t.Errorf("%v: ParseCostModel(%q) returned err = %v, wantErr = %v", test.desc, test.s, err, test.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if diff := pretty.Compare(got, test.want); diff != "" {
+			t.Errorf("%v: ParseCostModel(%q) diff (-got +want):\n%v", test.desc, test.s, diff)
+		}
+	}
+}
+
+func TestTrillianInterceptor_QuotaCosts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logTree := *testonly.LogTree
+	logTree.TreeId = 10
+
+	mapTree := *testonly.MapTree
+	mapTree.TreeId = 11
+
+	admin := storage.NewMockAdminStorage(ctrl)
+	adminTX := storage.NewMockReadOnlyAdminTX(ctrl)
+	admin.EXPECT().Snapshot(gomock.Any()).AnyTimes().Return(adminTX, nil)
+	adminTX.EXPECT().GetTree(gomock.Any(), logTree.TreeId).AnyTimes().Return(&logTree, nil)
+	adminTX.EXPECT().GetTree(gomock.Any(), mapTree.TreeId).AnyTimes().Return(&mapTree, nil)
+	adminTX.EXPECT().Close().AnyTimes().Return(nil)
+	adminTX.EXPECT().Commit().AnyTimes().Return(nil)
+
+	costs := CostModel{
+		"QueueLeaf":        {PerItem: 2},
+		"QueueLeaves":      {PerItem: 0.5, PerKiB: 1},
+		"GetLeavesByRange": {PerItem: 0.1, PerKiB: 2},
+		"GetMapLeaves":     {PerItem: 3},
+	}
+	kib := bytes.Repeat([]byte{'a'}, 1024)
+	dup := status.New(codes.AlreadyExists, "duplicate leaf").Proto()
+
+	tests := []struct {
+		desc                     string
+		req, resp                interface{}
+		wantGet, wantPut, wantRd int
+	}{
+		{
+			desc:    "notInModel",
+			req:     &trillian.GetLatestSignedLogRootRequest{LogId: logTree.TreeId},
+			wantGet: 1,
+		},
+		{
+			desc:    "perCall",
+			req:     &trillian.QueueLeafRequest{LogId: logTree.TreeId, Leaf: &trillian.LogLeaf{}},
+			resp:    &trillian.QueueLeafResponse{QueuedLeaf: &trillian.QueuedLogLeaf{Status: dup}},
+			wantGet: 2,
+			wantPut: 2,
+		},
+		{
+			// 5 leaves * 0.5 + 3KiB * 1, rounded up.
+			desc: "perItemAndKiB",
+			req: &trillian.QueueLeavesRequest{
+				LogId: logTree.TreeId,
+				Leaves: []*trillian.LogLeaf{
+					{LeafValue: kib}, {LeafValue: kib, ExtraData: kib}, {}, {}, {},
+				},
+			},
+			resp: &trillian.QueueLeavesResponse{
+				QueuedLeaves: []*trillian.QueuedLogLeaf{{Status: dup}, {Status: dup}, {Status: dup}, {}, {}},
+			},
+			wantGet: 6,
+			wantPut: 1,
+		},
+		{
+			desc:    "emptyBatch",
+			req:     &trillian.QueueLeavesRequest{LogId: logTree.TreeId},
+			resp:    &trillian.QueueLeavesResponse{},
+			wantGet: 0,
+		},
+		{
+			desc:    "mapIndexes",
+			req:     &trillian.GetMapLeavesRequest{MapId: mapTree.TreeId, Index: [][]byte{{1}, {2}}},
+			resp:    &trillian.GetMapLeavesResponse{},
+			wantGet: 6,
+		},
+		{
+			// Reads are charged by count up front, and by size once read.
+			desc: "bytesRead",
+			req:  &trillian.GetLeavesByRangeRequest{LogId: logTree.TreeId, StartIndex: 0, Count: 25},
+			resp: &trillian.GetLeavesByRangeResponse{
+				Leaves: []*trillian.LogLeaf{{LeafValue: kib}, {ExtraData: kib}},
+			},
+			wantGet: 3,
+			wantRd:  4,
+		},
+	}
+
+	ctx := context.Background()
+	for _, test := range tests {
+		done := make(chan bool, 1)
+		qm := quota.NewMockManager(ctrl)
+		qm.EXPECT().GetUser(gomock.Any(), test.req).MaxTimes(1).Return("llama")
+		if test.wantGet > 0 {
+			qm.EXPECT().GetTokens(gomock.Any(), test.wantGet, gomock.Any()).Return(nil)
+		}
+		if test.wantRd > 0 {
+			qm.EXPECT().GetTokens(gomock.Any(), test.wantRd, gomock.Any()).Do(func(context.Context, int, []quota.Spec) {
+				done <- true
+			}).Return(nil)
+		}
+		if test.wantPut > 0 {
+			qm.EXPECT().PutTokens(gomock.Any(), test.wantPut, gomock.Any()).Do(func(context.Context, int, []quota.Spec) {
+				done <- true
+			}).Return(nil)
+		}
+
+		handler := &fakeHandler{resp: test.resp}
+		intercept := New(admin, qm, false /* quotaDryRun */, nil /* mf */)
+		intercept.SetCostModel(costs)
+		if _, err := intercept.UnaryInterceptor(ctx, test.req, &grpc.UnaryServerInfo{}, handler.run); err != nil {
+			t.Errorf("%v: UnaryInterceptor() returned err = %v", test.desc, err)
+		}
+
+		// Refunds and charges for data read happen in a separate goroutine.
+		if test.wantPut > 0 || test.wantRd > 0 {
+			select {
+			case <-done:
+			case <-time.After(1 * time.Second):
+				// No need to error here, gomock will fail if the call is missing.
+			}
+		}
+	}
+}
@@ -17,6 +17,7 @@ package interceptor
 
 import (
 	"fmt"
+	"math"
 	"sync"
 	"time"
 
@@ -28,6 +29,7 @@ import (
 	"github.com/google/trillian/server/errors"
 	"github.com/google/trillian/storage"
 	"github.com/google/trillian/trees"
+	"github.com/google/trillian/util/requestinfo"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -38,14 +40,17 @@ const (
 	badInfoReason            = "bad_info"
 	badTreeReason            = "bad_tree"
 	insufficientTokensReason = "insufficient_tokens"
+	maintenanceReason        = "maintenance"
+	validityWindowReason     = "validity_window"
 	getTreeStage             = "get_tree"
 	getTokensStage           = "get_tokens"
 )
 
 var (
-	// PutTokensTimeout is the timeout used for PutTokens calls.
-	// PutTokens happens in a separate goroutine and with an independent context, therefore it has
-	// its own timeout, separate from the RPC that causes the calls.
+	// PutTokensTimeout is the timeout used for PutTokens calls, and for the GetTokens calls which
+	// charge for the data read by requests.
+	// These happen in a separate goroutine and with an independent context, therefore they have
+	// their own timeout, separate from the RPC that causes the calls.
 	PutTokensTimeout = 5 * time.Second
 
 	requestCounter       monitoring.Counter
@@ -80,6 +85,10 @@ type TrillianInterceptor struct {
 	// quotaDryRun controls whether lack of tokens actually blocks requests (if set to true, no
 	// requests are blocked by lack of tokens).
 	quotaDryRun bool
+
+	// costs is the cost of the requests of each method, if they aren't charged the default
+	// number of tokens.
+	costs CostModel
 }
 
 // New returns a new TrillianInterceptor instance.
@@ -92,6 +101,12 @@ func New(admin storage.AdminStorage, qm quota.Manager, quotaDryRun bool, mf moni
 	}
 }
 
+// SetCostModel sets the cost of the requests of each method.
+// It must be called before the interceptor is used.
+func (i *TrillianInterceptor) SetCostModel(costs CostModel) {
+	i.costs = costs
+}
+
 func initMetrics(mf monitoring.MetricFactory) {
 	if mf == nil {
 		mf = monitoring.InertMetricFactory{}
@@ -127,9 +142,60 @@ func (i *TrillianInterceptor) UnaryInterceptor(ctx context.Context, req interfac
 	}
 	resp, err := handler(ctx, req)
 	rp.After(ctx, resp, err)
+	if tp, ok := rp.(*trillianProcessor); ok {
+		err = tp.treeError(err)
+	}
 	return resp, err
 }
 
+// StreamInterceptor executes the TrillianInterceptor logic for server-streaming
+// RPCs. The request is processed when the handler receives it, and the leaf
+// data of each message sent is charged for as that of a unary response would.
+func (i *TrillianInterceptor) StreamInterceptor(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ts := &trillianStream{ServerStream: ss, tp: &trillianProcessor{parent: i}, ctx: ss.Context()}
+	err := handler(srv, ts)
+	if ts.processed {
+		ts.tp.After(ts.ctx, nil, err)
+	}
+	return ts.tp.treeError(err)
+}
+
+// trillianStream runs a trillianProcessor on the request and responses of a
+// server-streaming RPC.
+type trillianStream struct {
+	grpc.ServerStream
+	tp  *trillianProcessor
+	ctx context.Context
+	// processed is set once the request has passed Before.
+	processed bool
+}
+
+func (ts *trillianStream) Context() context.Context {
+	return ts.ctx
+}
+
+func (ts *trillianStream) RecvMsg(m interface{}) error {
+	if err := ts.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	ctx, err := ts.tp.Before(ts.ctx, m)
+	if err != nil {
+		return err
+	}
+	ts.ctx, ts.processed = ctx, true
+	return nil
+}
+
+func (ts *trillianStream) SendMsg(m interface{}) error {
+	if err := ts.ServerStream.SendMsg(m); err != nil {
+		return err
+	}
+	if ts.processed {
+		ts.tp.chargeRead(m)
+	}
+	return nil
+}
+
 // NewProcessor returns a RequestProcessor for the TrillianInterceptor logic.
 func (i *TrillianInterceptor) NewProcessor() RequestProcessor {
 	return &trillianProcessor{parent: i}
@@ -144,10 +210,14 @@ func (tp *trillianProcessor) Before(ctx context.Context, req interface{}) (conte
 	quotaUser := tp.parent.qm.GetUser(ctx, req)
 	info, err := newRPCInfo(req, quotaUser)
 	if err != nil {
-		glog.Warningf("Failed to read tree info: %v", err)
+		requestinfo.Warningf(ctx, "Failed to read tree info: %v", err)
 		incRequestDeniedCounter(badInfoReason, 0, quotaUser)
 		return ctx, err
 	}
+	if c, ok := tp.parent.costs.methodCost(req); ok && info.quota {
+		info.cost = &c
+		info.tokens = c.tokens(requestItems(req), requestBytes(req))
+	}
 	tp.info = info
 	requestCounter.Inc(fmt.Sprint(info.treeID))
 
@@ -164,9 +234,20 @@ func (tp *trillianProcessor) Before(ctx context.Context, req interface{}) (conte
 			contextErrCounter.Inc(getTreeStage)
 			return ctx, err
 		}
+		if err := checkMaintenance(tree, info.readonly); err != nil {
+			incRequestDeniedCounter(maintenanceReason, info.treeID, quotaUser)
+			return ctx, err
+		}
+		if err := checkValidityWindow(tree, req, timeNow()); err != nil {
+			incRequestDeniedCounter(validityWindowReason, info.treeID, quotaUser)
+			return ctx, err
+		}
 		ctx = trees.NewContext(ctx, tree)
 	}
 
+	if info.quota && len(info.specs) > 0 {
+		tp.exempt(ctx)
+	}
 	if info.quota && len(info.specs) > 0 && info.tokens > 0 {
 		err := tp.parent.qm.GetTokens(ctx, info.tokens, info.specs)
 		if err != nil {
@@ -174,7 +255,7 @@ func (tp *trillianProcessor) Before(ctx context.Context, req interface{}) (conte
 				incRequestDeniedCounter(insufficientTokensReason, info.treeID, quotaUser)
 				return ctx, status.Errorf(codes.ResourceExhausted, "quota exhausted: %v", err)
 			}
-			glog.Warningf("(quotaDryRun) Request %+v not denied due to dry run mode: %v", req, err)
+			requestinfo.Warningf(ctx, "(quotaDryRun) Request %+v not denied due to dry run mode: %v", req, err)
 		}
 		quota.Metrics.IncAcquired(info.tokens, info.specs, err == nil)
 		if err = ctx.Err(); err != nil {
@@ -189,7 +270,7 @@ func (tp *trillianProcessor) Before(ctx context.Context, req interface{}) (conte
 func (tp *trillianProcessor) After(ctx context.Context, resp interface{}, handlerErr error) {
 	switch {
 	case tp.info == nil:
-		glog.Warningf("After called with nil rpcInfo, resp = [%+v], handlerErr = [%v]", resp, handlerErr)
+		requestinfo.Warningf(ctx, "After called with nil rpcInfo, resp = [%+v], handlerErr = [%v]", resp, handlerErr)
 		return
 	case !tp.info.quota:
 		// After() currently only does quota processing
@@ -207,18 +288,21 @@ func (tp *trillianProcessor) After(ctx context.Context, resp interface{}, handle
 		// Return the tokens spent by invalid requests
 		tokens = tp.info.tokens
 	} else {
+		dups := 0
 		switch resp := resp.(type) {
 		case logLeafResponse:
 			if !isLeafOK(resp.GetQueuedLeaf()) {
-				tokens = 1
+				dups = 1
 			}
 		case logLeavesResponse:
 			for _, leaf := range resp.GetQueuedLeaves() {
 				if !isLeafOK(leaf) {
-					tokens++
+					dups++
 				}
 			}
 		}
+		tokens = tp.info.refund(dups)
+		tp.chargeRead(resp)
 	}
 	if len(tp.info.specs) > 0 && tokens > 0 {
 		// Run PutTokens in a separate goroutine and with a separate context.
@@ -233,13 +317,81 @@ func (tp *trillianProcessor) After(ctx context.Context, resp interface{}, handle
 			// in its impl).
 			err := tp.parent.qm.PutTokens(ctx, tokens, tp.info.specs)
 			if err != nil {
-				glog.Warningf("Failed to replenish %v tokens: %v", tokens, err)
+				requestinfo.Warningf(ctx, "Failed to replenish %v tokens: %v", tokens, err)
 			}
 			quota.Metrics.IncReturned(tokens, tp.info.specs, err == nil)
 		}()
 	}
 }
 
+// exempt removes the specs the request's quota user is exempt from from the request's specs, if
+// the quota manager supports exemptions. The tokens not acquired from them are still recorded.
+// treeError returns the error of a handler to its client. ErrTreeNeedsInit is
+// replaced by an error naming the tree of the request, so that a client
+// sending requests for several trees knows which to initialise.
+func (tp *trillianProcessor) treeError(err error) error {
+	if err != storage.ErrTreeNeedsInit || tp.info == nil || tp.info.treeID == 0 {
+		return err
+	}
+	return storage.TreeNeedsInitError(tp.info.treeID)
+}
+
+func (tp *trillianProcessor) exempt(ctx context.Context) {
+	e, ok := tp.parent.qm.(quota.Exempter)
+	if !ok {
+		return
+	}
+	exempt, err := e.ExemptSpecs(ctx, tp.info.quotaUser, tp.info.specs)
+	if err != nil {
+		requestinfo.Warningf(ctx, "Failed to get quota exemptions of user %q, charging all quotas: %v", tp.info.quotaUser, err)
+		return
+	}
+	if len(exempt) == 0 {
+		return
+	}
+	isExempt := make(map[quota.Spec]bool)
+	for _, spec := range exempt {
+		isExempt[spec] = true
+	}
+	var specs []quota.Spec
+	for _, spec := range tp.info.specs {
+		if !isExempt[spec] {
+			specs = append(specs, spec)
+		}
+	}
+	tp.info.specs = specs
+	tp.info.exemptSpecs = exempt
+	quota.Metrics.IncExempted(tp.info.tokens, exempt, tp.info.quotaUser)
+}
+
+// chargeRead charges for the leaf data read by readonly requests whose cost has a per KiB weight.
+// As the request has already been served, tokens are taken in the background and lack of tokens
+// doesn't fail the request.
+func (tp *trillianProcessor) chargeRead(resp interface{}) {
+	c := tp.info.cost
+	if c == nil || c.PerKiB == 0 || !tp.info.readonly {
+		return
+	}
+	tokens := c.tokens(0, responseBytes(resp))
+	if tokens <= 0 {
+		return
+	}
+	quota.Metrics.IncExempted(tokens, tp.info.exemptSpecs, tp.info.quotaUser)
+	if len(tp.info.specs) == 0 {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), PutTokensTimeout)
+		defer cancel()
+
+		err := tp.parent.qm.GetTokens(ctx, tokens, tp.info.specs)
+		if err != nil {
+			glog.Warningf("Failed to charge %v tokens for data read: %v", tokens, err)
+		}
+		quota.Metrics.IncAcquired(tokens, tp.info.specs, err == nil)
+	}()
+}
+
 func isLeafOK(leaf *trillian.QueuedLogLeaf) bool {
 	// Be biased in favor of OK, as that matches TrillianLogRPCServer's behavior.
 	return leaf == nil || leaf.Status == nil || leaf.Status.Code == int32(codes.OK)
@@ -253,8 +405,26 @@ type rpcInfo struct {
 	treeID    int64
 	treeTypes []trillian.TreeType
 
-	specs  []quota.Spec
-	tokens int
+	quotaUser string
+	specs     []quota.Spec
+	// exemptSpecs are the specs removed from specs as quotaUser is exempt from them.
+	exemptSpecs []quota.Spec
+	tokens      int
+	// cost is the cost of the request, if it isn't charged the default number of tokens.
+	cost *MethodCost
+}
+
+// refund returns the number of tokens to return for dups leaves which weren't queued, as they
+// were duplicates. Tokens charged for leaf data aren't returned, as the data was still processed.
+func (info *rpcInfo) refund(dups int) int {
+	tokens := dups
+	if info.cost != nil {
+		tokens = int(math.Floor(info.cost.PerItem * float64(dups)))
+	}
+	if tokens > info.tokens {
+		tokens = info.tokens
+	}
+	return tokens
 }
 
 func newRPCInfoForRequestType(req interface{}) (*rpcInfo, error) {
@@ -290,34 +460,47 @@ func newRPCInfoForRequestType(req interface{}) (*rpcInfo, error) {
 		info.readonly = false
 
 	// Admin list
-	case *trillian.ListTreesRequest:
+	case *trillian.ListTreesRequest,
+		*trillian.BatchGetTreeStatusRequest,
+		*trillian.GetTreeByExternalIdRequest:
 		info.auth = false    // Auth done within RPC handler
 		info.getTree = false // Zero to many trees
 		info.quota = false   // No quota for admin
 
 	// Admin / readonly
-	case *trillian.GetTreeRequest:
+	case *trillian.GetTreeRequest,
+		*trillian.GetTreeStatsRequest,
+		*trillian.InspectLeafQueueRequest,
+		*trillian.GetTreeIntegrityCheckRequest,
+		*trillian.GetMergeDelayComplianceRequest:
 		info.getTree = false // Read done within RPC handler
 		info.quota = false   // No quota for admin
 
 	// Admin / readwrite
 	case *trillian.DeleteTreeRequest,
 		*trillian.UndeleteTreeRequest,
-		*trillian.UpdateTreeRequest:
+		*trillian.UpdateTreeRequest,
+		*trillian.CheckTreeIntegrityRequest:
 		info.getTree = false // Read-modify-write done within RPC handler
 		info.quota = false   // No quota for admin
 		info.readonly = false
 
 	// Log / readonly
 	// Pre-ordered Log / readonly
-	case *trillian.GetConsistencyProofRequest,
+	case *trillian.AddObservedRootRequest,
+		*trillian.CheckLeafFilterRequest,
+		*trillian.GetConsistencyProofChainRequest,
+		*trillian.GetConsistencyProofRequest,
+		*trillian.GetEntriesAndProofsRequest,
 		*trillian.GetEntryAndProofRequest,
 		*trillian.GetInclusionProofByHashRequest,
 		*trillian.GetInclusionProofRequest,
 		*trillian.GetLatestSignedLogRootRequest,
+		*trillian.GetLeafIndicesByKeyRequest,
 		*trillian.GetLeavesByHashRequest,
 		*trillian.GetLeavesByIndexRequest,
 		*trillian.GetLeavesByRangeRequest,
+		*trillian.GetObservedRootsRequest,
 		*trillian.GetSequencedLeafCountRequest:
 		info.treeTypes = []trillian.TreeType{trillian.TreeType_LOG, trillian.TreeType_PREORDERED_LOG}
 
@@ -329,6 +512,7 @@ func newRPCInfoForRequestType(req interface{}) (*rpcInfo, error) {
 
 	// Pre-ordered Log / readwrite
 	case *trillian.AddSequencedLeafRequest,
+		*trillian.AddSequencedLeafRangeRequest,
 		*trillian.AddSequencedLeavesRequest:
 		info.readonly = false
 		info.treeTypes = []trillian.TreeType{trillian.TreeType_PREORDERED_LOG}
@@ -340,7 +524,9 @@ func newRPCInfoForRequestType(req interface{}) (*rpcInfo, error) {
 		info.treeTypes = []trillian.TreeType{trillian.TreeType_LOG, trillian.TreeType_PREORDERED_LOG}
 
 	// Map / readonly
-	case *trillian.GetMapLeavesByRevisionRequest,
+	case *trillian.GetMapDiffRequest,
+		*trillian.GetMapLeafHistoryRequest,
+		*trillian.GetMapLeavesByRevisionRequest,
 		*trillian.GetMapLeavesRequest,
 		*trillian.GetSignedMapRootByRevisionRequest,
 		*trillian.GetSignedMapRootRequest:
@@ -387,6 +573,7 @@ func newRPCInfo(req interface{}, quotaUser string) (*rpcInfo, error) {
 		} else {
 			kind = quota.Write
 		}
+		info.quotaUser = quotaUser
 		info.specs = []quota.Spec{
 			{Group: quota.User, Kind: kind, User: quotaUser},
 			{Group: quota.Tree, Kind: kind, TreeID: info.treeID},
@@ -429,6 +616,10 @@ type mapLeavesRequest interface {
 	GetLeaves() []*trillian.MapLeaf
 }
 
+type logLeafRequest interface {
+	GetLeaf() *trillian.LogLeaf
+}
+
 type logLeafResponse interface {
 	GetQueuedLeaf() *trillian.QueuedLogLeaf
 }
@@ -437,6 +628,10 @@ type logLeavesResponse interface {
 	GetQueuedLeaves() []*trillian.QueuedLogLeaf
 }
 
+type logLeavesReadResponse interface {
+	GetLeaves() []*trillian.LogLeaf
+}
+
 // Combine combines unary interceptors.
 // They are nested in order, so interceptor[0] calls on to (and sees the result of) interceptor[1], etc.
 func Combine(interceptors ...grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
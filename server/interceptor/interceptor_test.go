@@ -459,6 +459,73 @@ func TestTrillianInterceptor_QuotaInterception_ReturnsTokens(t *testing.T) {
 	}
 }
 
+func TestTrillianInterceptor_QuotaExemptions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logTree := *testonly.LogTree
+	logTree.TreeId = 10
+
+	admin := storage.NewMockAdminStorage(ctrl)
+	adminTX := storage.NewMockReadOnlyAdminTX(ctrl)
+	admin.EXPECT().Snapshot(gomock.Any()).AnyTimes().Return(adminTX, nil)
+	adminTX.EXPECT().GetTree(gomock.Any(), logTree.TreeId).AnyTimes().Return(&logTree, nil)
+	adminTX.EXPECT().Close().AnyTimes().Return(nil)
+	adminTX.EXPECT().Commit().AnyTimes().Return(nil)
+
+	user := "mirror"
+	userSpec := quota.Spec{Group: quota.User, Kind: quota.Write, User: user}
+	treeSpec := quota.Spec{Group: quota.Tree, Kind: quota.Write, TreeID: logTree.TreeId}
+	globalSpec := quota.Spec{Group: quota.Global, Kind: quota.Write}
+	req := &trillian.QueueLeavesRequest{LogId: logTree.TreeId, Leaves: []*trillian.LogLeaf{{}, {}}}
+
+	tests := []struct {
+		desc      string
+		exempt    []quota.Spec
+		exemptErr error
+		wantSpecs []quota.Spec
+	}{
+		{
+			desc:      "notExempt",
+			wantSpecs: []quota.Spec{userSpec, treeSpec, globalSpec},
+		},
+		{
+			desc:      "exempt",
+			exempt:    []quota.Spec{userSpec, globalSpec},
+			wantSpecs: []quota.Spec{treeSpec},
+		},
+		{
+			desc:   "exemptFromAll",
+			exempt: []quota.Spec{userSpec, treeSpec, globalSpec},
+		},
+		{
+			desc:      "exemptionsError",
+			exempt:    []quota.Spec{userSpec, globalSpec},
+			exemptErr: errors.New("etcd unavailable"),
+			wantSpecs: []quota.Spec{userSpec, treeSpec, globalSpec},
+		},
+	}
+
+	ctx := context.Background()
+	for _, test := range tests {
+		qm := quota.NewMockManager(ctrl)
+		qm.EXPECT().GetUser(gomock.Any(), req).Return(user)
+		if len(test.wantSpecs) > 0 {
+			qm.EXPECT().GetTokens(gomock.Any(), 2, test.wantSpecs).Return(nil)
+		}
+
+		handler := &fakeHandler{resp: &trillian.QueueLeavesResponse{}}
+		em := &exemptingManager{MockManager: qm, exempt: test.exempt, err: test.exemptErr}
+		intercept := New(admin, em, false /* quotaDryRun */, nil /* mf */)
+		if _, err := intercept.UnaryInterceptor(ctx, req, &grpc.UnaryServerInfo{}, handler.run); err != nil {
+			t.Errorf("%v: UnaryInterceptor() returned err = %v", test.desc, err)
+		}
+		if got, want := em.user, user; got != want {
+			t.Errorf("%v: ExemptSpecs() called with user %q, want %q", test.desc, got, want)
+		}
+	}
+}
+
 func TestTrillianInterceptor_NotIntercepted(t *testing.T) {
 	tests := []struct {
 		req interface{}
@@ -466,6 +533,8 @@ func TestTrillianInterceptor_NotIntercepted(t *testing.T) {
 		// Admin
 		{req: &trillian.CreateTreeRequest{}},
 		{req: &trillian.ListTreesRequest{}},
+		{req: &trillian.BatchGetTreeStatusRequest{TreeIds: []int64{1, 2}}},
+		{req: &trillian.GetTreeByExternalIdRequest{ExternalId: "llamas"}},
 		// Quota
 		{req: &quotapb.CreateConfigRequest{}},
 		{req: &quotapb.DeleteConfigRequest{}},
@@ -543,6 +612,36 @@ func TestTrillianInterceptor_BeforeAfter(t *testing.T) {
 	}
 }
 
+func TestTrillianInterceptor_TreeNeedsInit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logTree := *testonly.LogTree
+	logTree.TreeId = 10
+
+	admin := storage.NewMockAdminStorage(ctrl)
+	adminTX := storage.NewMockReadOnlyAdminTX(ctrl)
+	admin.EXPECT().Snapshot(gomock.Any()).AnyTimes().Return(adminTX, nil)
+	adminTX.EXPECT().GetTree(gomock.Any(), logTree.TreeId).AnyTimes().Return(&logTree, nil)
+	adminTX.EXPECT().Close().AnyTimes().Return(nil)
+	adminTX.EXPECT().Commit().AnyTimes().Return(nil)
+
+	ctx := context.Background()
+	handler := &fakeHandler{err: storage.ErrTreeNeedsInit}
+	intercept := New(admin, quota.Noop(), false /* quotaDryRun */, nil /* mf */)
+	req := &trillian.GetLatestSignedLogRootRequest{LogId: logTree.TreeId}
+	_, err := intercept.UnaryInterceptor(ctx, req, &grpc.UnaryServerInfo{}, handler.run)
+	if got, want := status.Code(err), codes.FailedPrecondition; got != want {
+		t.Errorf("UnaryInterceptor() returned code %v, want %v", got, want)
+	}
+	if !storage.IsTreeNeedsInit(err) {
+		t.Errorf("UnaryInterceptor() returned err = %v, want a tree needs init error", err)
+	}
+	if err == storage.ErrTreeNeedsInit {
+		t.Errorf("UnaryInterceptor() returned ErrTreeNeedsInit, want an error naming tree %v", logTree.TreeId)
+	}
+}
+
 func TestCombine(t *testing.T) {
 	i1 := &fakeInterceptor{key: "key1", val: "foo"}
 	i2 := &fakeInterceptor{key: "key2", val: "bar"}
@@ -690,6 +789,21 @@ func TestErrorWrapper(t *testing.T) {
 	}
 }
 
+// exemptingManager is a quota.Manager which exempts users from a fixed set of specs.
+type exemptingManager struct {
+	*quota.MockManager
+	exempt []quota.Spec
+	err    error
+
+	// user is the user of the last ExemptSpecs call.
+	user string
+}
+
+func (m *exemptingManager) ExemptSpecs(ctx context.Context, user string, specs []quota.Spec) ([]quota.Spec, error) {
+	m.user = user
+	return m.exempt, m.err
+}
+
 type fakeHandler struct {
 	called bool
 	resp   interface{}
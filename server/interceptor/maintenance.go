@@ -0,0 +1,65 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interceptor
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/trillian"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// MaintenanceViolation is the type of the PreconditionFailure violation
+// attached to the errors of writes to trees in maintenance.
+const MaintenanceViolation = "MAINTENANCE"
+
+// checkMaintenance returns an error if tree is in maintenance: UNAVAILABLE,
+// with the tree's retry delay as RetryInfo, for readonly requests, and
+// FAILED_PRECONDITION, with a MaintenanceViolation, for writes.
+func checkMaintenance(tree *trillian.Tree, readonly bool) error {
+	m := tree.GetMaintenance()
+	if m == nil {
+		return nil
+	}
+	msg := fmt.Sprintf("tree %d is in maintenance", tree.TreeId)
+	if m.Reason != "" {
+		msg = fmt.Sprintf("%s: %s", msg, m.Reason)
+	}
+
+	if !readonly {
+		s := status.New(codes.FailedPrecondition, msg)
+		if typed, err := s.WithDetails(&errdetails.PreconditionFailure{
+			Violations: []*errdetails.PreconditionFailure_Violation{{
+				Type:        MaintenanceViolation,
+				Subject:     fmt.Sprintf("trees/%d", tree.TreeId),
+				Description: "writes are rejected during maintenance",
+			}},
+		}); err == nil {
+			s = typed
+		}
+		return s.Err()
+	}
+
+	s := status.New(codes.Unavailable, msg)
+	if d, err := ptypes.Duration(m.RetryAfter); err == nil && d > 0 {
+		if withRetry, err := s.WithDetails(&errdetails.RetryInfo{RetryDelay: m.RetryAfter}); err == nil {
+			s = withRetry
+		}
+	}
+	return s.Err()
+}
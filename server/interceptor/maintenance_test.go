@@ -0,0 +1,138 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interceptor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/trillian"
+	"github.com/google/trillian/quota"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/testonly"
+	"golang.org/x/net/context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestTrillianInterceptor_Maintenance(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logTree := proto.Clone(testonly.LogTree).(*trillian.Tree)
+	logTree.TreeId = 10
+	maintTree := proto.Clone(testonly.LogTree).(*trillian.Tree)
+	maintTree.TreeId = 11
+	maintTree.Maintenance = &trillian.MaintenanceMode{RetryAfter: ptypes.DurationProto(time.Minute), Reason: "migration"}
+	maintMapTree := proto.Clone(testonly.MapTree).(*trillian.Tree)
+	maintMapTree.TreeId = 12
+	maintMapTree.Maintenance = &trillian.MaintenanceMode{}
+
+	admin := storage.NewMockAdminStorage(ctrl)
+	adminTX := storage.NewMockReadOnlyAdminTX(ctrl)
+	admin.EXPECT().Snapshot(gomock.Any()).AnyTimes().Return(adminTX, nil)
+	for _, tree := range []*trillian.Tree{logTree, maintTree, maintMapTree} {
+		adminTX.EXPECT().GetTree(gomock.Any(), tree.TreeId).AnyTimes().Return(tree, nil)
+	}
+	adminTX.EXPECT().Close().AnyTimes().Return(nil)
+	adminTX.EXPECT().Commit().AnyTimes().Return(nil)
+
+	tests := []struct {
+		desc          string
+		req           interface{}
+		wantCode      codes.Code
+		wantRetry     time.Duration
+		wantViolation bool
+	}{
+		{
+			desc: "otherTreeRead",
+			req:  &trillian.GetLatestSignedLogRootRequest{LogId: logTree.TreeId},
+		},
+		{
+			desc: "otherTreeWrite",
+			req:  &trillian.QueueLeafRequest{LogId: logTree.TreeId},
+		},
+		{
+			desc: "adminRead",
+			req:  &trillian.GetTreeRequest{TreeId: maintTree.TreeId},
+		},
+		{
+			desc: "adminWrite",
+			req:  &trillian.UpdateTreeRequest{Tree: &trillian.Tree{TreeId: maintTree.TreeId}},
+		},
+		{
+			desc:      "read",
+			req:       &trillian.GetLatestSignedLogRootRequest{LogId: maintTree.TreeId},
+			wantCode:  codes.Unavailable,
+			wantRetry: time.Minute,
+		},
+		{
+			desc:          "write",
+			req:           &trillian.QueueLeafRequest{LogId: maintTree.TreeId},
+			wantCode:      codes.FailedPrecondition,
+			wantViolation: true,
+		},
+		{
+			desc:     "mapReadWithoutRetry",
+			req:      &trillian.GetSignedMapRootRequest{MapId: maintMapTree.TreeId},
+			wantCode: codes.Unavailable,
+		},
+		{
+			desc:          "mapWrite",
+			req:           &trillian.SetMapLeavesRequest{MapId: maintMapTree.TreeId},
+			wantCode:      codes.FailedPrecondition,
+			wantViolation: true,
+		},
+	}
+
+	ctx := context.Background()
+	intercept := New(admin, quota.Noop(), false /* quotaDryRun */, nil /* mf */)
+	for _, test := range tests {
+		handler := &fakeHandler{resp: "handler response"}
+		_, err := intercept.UnaryInterceptor(ctx, test.req, &grpc.UnaryServerInfo{}, handler.run)
+		s, _ := status.FromError(err)
+		if got, want := s.Code(), test.wantCode; got != want {
+			t.Errorf("%v: UnaryInterceptor() returned err = %v, want code %v", test.desc, err, want)
+			continue
+		}
+		if handler.called != (test.wantCode == codes.OK) {
+			t.Errorf("%v: handler called = %v, want %v", test.desc, handler.called, test.wantCode == codes.OK)
+		}
+
+		var retry time.Duration
+		var violation bool
+		for _, d := range s.Details() {
+			switch d := d.(type) {
+			case *errdetails.RetryInfo:
+				if retry, err = ptypes.Duration(d.RetryDelay); err != nil {
+					t.Errorf("%v: invalid RetryInfo: %v", test.desc, err)
+				}
+			case *errdetails.PreconditionFailure:
+				violation = len(d.Violations) > 0 && d.Violations[0].Type == MaintenanceViolation
+			}
+		}
+		if retry != test.wantRetry {
+			t.Errorf("%v: retry delay = %v, want %v", test.desc, retry, test.wantRetry)
+		}
+		if violation != test.wantViolation {
+			t.Errorf("%v: %v violation = %v, want %v", test.desc, MaintenanceViolation, violation, test.wantViolation)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interceptor
+
+import (
+	"github.com/google/trillian/util/requestinfo"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestInfo is a grpc.UnaryServerInterceptor which stores the request and
+// tenant IDs sent in the metadata of requests in their contexts, so that they
+// prefix the log lines written while serving them, see requestinfo. Requests
+// without a request ID are given a new one, which is returned in the
+// response header.
+func RequestInfo(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(requestInfoContext(ctx), req)
+}
+
+// RequestInfoStream is the grpc.StreamServerInterceptor equivalent of
+// RequestInfo.
+func RequestInfoStream(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &requestInfoStream{ServerStream: ss, ctx: requestInfoContext(ss.Context())})
+}
+
+// requestInfoContext returns a copy of ctx, the context of an incoming RPC,
+// carrying the IDs of the RPC.
+func requestInfoContext(ctx context.Context) context.Context {
+	info := requestinfo.FromIncomingContext(ctx)
+	if info.RequestID == "" {
+		info.RequestID = requestinfo.NewRequestID()
+		// Fails if the RPC isn't served over a transport, e.g. when embedded,
+		// in which case there's no header to return the ID in anyway.
+		grpc.SetHeader(ctx, metadata.Pairs(requestinfo.RequestIDKey, info.RequestID))
+	}
+	return requestinfo.NewContext(ctx, info)
+}
+
+// requestInfoStream overrides the context of a server stream with one
+// carrying the IDs of its RPC.
+type requestInfoStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *requestInfoStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,56 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interceptor
+
+import (
+	"testing"
+
+	"github.com/google/trillian/util/requestinfo"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestRequestInfo(t *testing.T) {
+	var got requestinfo.Info
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		got = requestinfo.FromContext(ctx)
+		return nil, nil
+	}
+
+	md := metadata.Pairs(requestinfo.RequestIDKey, "1a2b", requestinfo.TenantIDKey, "acme")
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+	if _, err := RequestInfo(ctx, "req", &grpc.UnaryServerInfo{}, handler); err != nil {
+		t.Fatalf("RequestInfo(): %v", err)
+	}
+	if want := (requestinfo.Info{RequestID: "1a2b", TenantID: "acme"}); got != want {
+		t.Errorf("RequestInfo() passed on %+v, want %+v", got, want)
+	}
+
+	// Requests without IDs are given request IDs, different for each.
+	var ids []string
+	for i := 0; i < 2; i++ {
+		if _, err := RequestInfo(context.Background(), "req", &grpc.UnaryServerInfo{}, handler); err != nil {
+			t.Fatalf("RequestInfo(): %v", err)
+		}
+		if got.RequestID == "" || got.TenantID != "" {
+			t.Errorf("RequestInfo() without IDs passed on %+v, want a request ID only", got)
+		}
+		ids = append(ids, got.RequestID)
+	}
+	if ids[0] == ids[1] {
+		t.Errorf("RequestInfo() gave two requests the same ID %v", ids[0])
+	}
+}
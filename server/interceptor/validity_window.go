@@ -0,0 +1,84 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interceptor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/trillian"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ValidityWindowViolation is the type of the PreconditionFailure violation
+// attached to the errors of leaves queued outside the validity window of
+// their log.
+const ValidityWindowViolation = "VALIDITY_WINDOW"
+
+// timeNow is the clock the validity windows of logs are checked against.
+var timeNow = time.Now
+
+// checkValidityWindow returns FAILED_PRECONDITION, with a
+// ValidityWindowViolation, if req queues leaves to a log outside its validity
+// window at now.
+func checkValidityWindow(tree *trillian.Tree, req interface{}, now time.Time) error {
+	w := tree.GetValidityWindow()
+	if w == nil {
+		return nil
+	}
+	switch req.(type) {
+	case *trillian.QueueLeafRequest, *trillian.QueueLeavesRequest:
+	default:
+		return nil
+	}
+
+	var msg string
+	if w.NotBefore != nil {
+		notBefore, err := ptypes.Timestamp(w.NotBefore)
+		if err != nil {
+			return status.Errorf(codes.Internal, "tree %d has an invalid validity window: %v", tree.TreeId, err)
+		}
+		if now.Before(notBefore) {
+			msg = fmt.Sprintf("tree %d accepts leaves from %v", tree.TreeId, notBefore.UTC())
+		}
+	}
+	if w.NotAfter != nil {
+		notAfter, err := ptypes.Timestamp(w.NotAfter)
+		if err != nil {
+			return status.Errorf(codes.Internal, "tree %d has an invalid validity window: %v", tree.TreeId, err)
+		}
+		if now.After(notAfter) {
+			msg = fmt.Sprintf("tree %d accepted leaves until %v", tree.TreeId, notAfter.UTC())
+		}
+	}
+	if msg == "" {
+		return nil
+	}
+
+	s := status.New(codes.FailedPrecondition, msg)
+	if typed, err := s.WithDetails(&errdetails.PreconditionFailure{
+		Violations: []*errdetails.PreconditionFailure_Violation{{
+			Type:        ValidityWindowViolation,
+			Subject:     fmt.Sprintf("trees/%d", tree.TreeId),
+			Description: "leaves are rejected outside the validity window of the log",
+		}},
+	}); err == nil {
+		s = typed
+	}
+	return s.Err()
+}
@@ -0,0 +1,129 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interceptor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/google/trillian"
+	"github.com/google/trillian/quota"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/testonly"
+	"golang.org/x/net/context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestTrillianInterceptor_ValidityWindow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	defer func(f func() time.Time) { timeNow = f }(timeNow)
+	timeNow = func() time.Time { return now }
+	toProto := func(when time.Time) *timestamp.Timestamp {
+		ts, _ := ptypes.TimestampProto(when)
+		return ts
+	}
+
+	current := proto.Clone(testonly.LogTree).(*trillian.Tree)
+	current.TreeId = 10
+	current.ValidityWindow = &trillian.ValidityWindow{NotBefore: toProto(now.Add(-time.Hour)), NotAfter: toProto(now.Add(time.Hour))}
+	past := proto.Clone(testonly.LogTree).(*trillian.Tree)
+	past.TreeId = 11
+	past.ValidityWindow = &trillian.ValidityWindow{NotAfter: toProto(now.Add(-time.Second))}
+	future := proto.Clone(testonly.LogTree).(*trillian.Tree)
+	future.TreeId = 12
+	future.ValidityWindow = &trillian.ValidityWindow{NotBefore: toProto(now.Add(time.Second))}
+
+	admin := storage.NewMockAdminStorage(ctrl)
+	adminTX := storage.NewMockReadOnlyAdminTX(ctrl)
+	admin.EXPECT().Snapshot(gomock.Any()).AnyTimes().Return(adminTX, nil)
+	for _, tree := range []*trillian.Tree{current, past, future} {
+		adminTX.EXPECT().GetTree(gomock.Any(), tree.TreeId).AnyTimes().Return(tree, nil)
+	}
+	adminTX.EXPECT().Close().AnyTimes().Return(nil)
+	adminTX.EXPECT().Commit().AnyTimes().Return(nil)
+
+	tests := []struct {
+		desc     string
+		req      interface{}
+		wantCode codes.Code
+	}{
+		{
+			desc: "currentQueueLeaf",
+			req:  &trillian.QueueLeafRequest{LogId: current.TreeId},
+		},
+		{
+			desc: "currentQueueLeaves",
+			req:  &trillian.QueueLeavesRequest{LogId: current.TreeId},
+		},
+		{
+			desc:     "pastQueueLeaf",
+			req:      &trillian.QueueLeafRequest{LogId: past.TreeId},
+			wantCode: codes.FailedPrecondition,
+		},
+		{
+			desc:     "pastQueueLeaves",
+			req:      &trillian.QueueLeavesRequest{LogId: past.TreeId},
+			wantCode: codes.FailedPrecondition,
+		},
+		{
+			desc:     "futureQueueLeaves",
+			req:      &trillian.QueueLeavesRequest{LogId: future.TreeId},
+			wantCode: codes.FailedPrecondition,
+		},
+		{
+			desc: "pastRead",
+			req:  &trillian.GetLatestSignedLogRootRequest{LogId: past.TreeId},
+		},
+		{
+			desc: "futureRead",
+			req:  &trillian.GetLatestSignedLogRootRequest{LogId: future.TreeId},
+		},
+	}
+
+	ctx := context.Background()
+	intercept := New(admin, quota.Noop(), false /* quotaDryRun */, nil /* mf */)
+	for _, test := range tests {
+		handler := &fakeHandler{resp: "handler response"}
+		_, err := intercept.UnaryInterceptor(ctx, test.req, &grpc.UnaryServerInfo{}, handler.run)
+		s, _ := status.FromError(err)
+		if got, want := s.Code(), test.wantCode; got != want {
+			t.Errorf("%v: UnaryInterceptor() returned err = %v, want code %v", test.desc, err, want)
+			continue
+		}
+		if handler.called != (test.wantCode == codes.OK) {
+			t.Errorf("%v: handler called = %v, want %v", test.desc, handler.called, test.wantCode == codes.OK)
+		}
+
+		var violation bool
+		for _, d := range s.Details() {
+			if d, ok := d.(*errdetails.PreconditionFailure); ok {
+				violation = len(d.Violations) > 0 && d.Violations[0].Type == ValidityWindowViolation
+			}
+		}
+		if want := test.wantCode != codes.OK; violation != want {
+			t.Errorf("%v: %v violation = %v, want %v", test.desc, ValidityWindowViolation, violation, want)
+		}
+	}
+}
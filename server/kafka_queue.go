@@ -0,0 +1,65 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"flag"
+	"strings"
+
+	"github.com/Shopify/sarama"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/kafka"
+)
+
+var (
+	kafkaBrokers       = flag.String("kafka_brokers", "", "Comma-separated list of Kafka brokers (host:port). If set, log servers append queued leaves to --kafka_leaf_topic, and log signers move them from there to storage")
+	kafkaLeafTopic     = flag.String("kafka_leaf_topic", "trillian-leaves", "Kafka topic holding the leaves queued to logs")
+	kafkaConsumerGroup = flag.String("kafka_consumer_group", "trillian-log-signer", "Kafka consumer group of the log signers draining --kafka_leaf_topic")
+	kafkaDrainBatch    = flag.Int("kafka_drain_batch_size", kafka.DefaultBatchSize, "Max number of leaves moved from --kafka_leaf_topic to storage at once")
+)
+
+func kafkaBrokerList() []string {
+	return strings.Split(*kafkaBrokers, ",")
+}
+
+// KafkaLogStorageFromFlags returns ls wrapped so that it queues leaves to
+// the Kafka topic specified by flags, along with a function closing the
+// Kafka producer. It returns ls itself if --kafka_brokers isn't set.
+func KafkaLogStorageFromFlags(ls storage.LogStorage) (storage.LogStorage, func() error, error) {
+	if *kafkaBrokers == "" {
+		return ls, func() error { return nil }, nil
+	}
+	producer, err := sarama.NewSyncProducer(kafkaBrokerList(), kafka.NewConfig())
+	if err != nil {
+		return nil, nil, err
+	}
+	return kafka.NewLogStorage(ls, producer, *kafkaLeafTopic), producer.Close, nil
+}
+
+// KafkaDrainerFromFlags returns a Drainer moving the leaves queued to the
+// Kafka topic specified by flags to ls, along with a function closing its
+// consumer group. It returns a nil Drainer if --kafka_brokers isn't set.
+func KafkaDrainerFromFlags(ls storage.LogStorage) (*kafka.Drainer, func() error, error) {
+	if *kafkaBrokers == "" {
+		return nil, func() error { return nil }, nil
+	}
+	group, err := sarama.NewConsumerGroup(kafkaBrokerList(), *kafkaConsumerGroup, kafka.NewConfig())
+	if err != nil {
+		return nil, nil, err
+	}
+	d := kafka.NewDrainer(ls, group, *kafkaLeafTopic)
+	d.BatchSize = *kafkaDrainBatch
+	return d, group.Close, nil
+}
@@ -0,0 +1,38 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"flag"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian/crypto/keys"
+	"github.com/google/trillian/crypto/keys/der"
+	"github.com/google/trillian/crypto/keyspb"
+)
+
+var keyGeneration = flag.Bool("key_generation", true, "If false, the server never generates private keys, so trees can only be created with a tree.private_key provisioned out-of-band and CreateTree requests with a key_spec fail")
+
+// KeyGeneratorFromFlags returns the keys.ProtoGenerator with which the server
+// generates the private keys of new trees, or nil if --key_generation is false.
+func KeyGeneratorFromFlags() keys.ProtoGenerator {
+	if !*keyGeneration {
+		return nil
+	}
+	return func(ctx context.Context, spec *keyspb.Specification) (proto.Message, error) {
+		return der.NewProtoFromSpec(spec)
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/trillian/crypto/keyspb"
+)
+
+func TestKeyGeneratorFromFlags(t *testing.T) {
+	defer func(enabled bool) { *keyGeneration = enabled }(*keyGeneration)
+
+	*keyGeneration = true
+	gen := KeyGeneratorFromFlags()
+	if gen == nil {
+		t.Fatal("KeyGeneratorFromFlags()=nil with --key_generation, want a generator")
+	}
+	spec := &keyspb.Specification{Params: &keyspb.Specification_EcdsaParams{EcdsaParams: &keyspb.Specification_ECDSA{}}}
+	if _, err := gen(context.Background(), spec); err != nil {
+		t.Errorf("KeyGeneratorFromFlags()(_, %v)=_, %v; want nil", spec, err)
+	}
+
+	*keyGeneration = false
+	if gen := KeyGeneratorFromFlags(); gen != nil {
+		t.Error("KeyGeneratorFromFlags()=non-nil with --key_generation=false, want nil")
+	}
+}
@@ -224,6 +224,10 @@ type LogOperationManager struct {
 	// Cache of logID => name; assumed not to change during runtime
 	logNamesMutex sync.Mutex
 	logNames      map[int64]string
+	// passes holds the outcome of the latest passes on each log, for the
+	// status page.
+	passesMu sync.Mutex
+	passes   map[int64]*logPassStatus
 }
 
 // fixupElectionInfo ensures operation parameters have required minimum values.
@@ -254,6 +258,7 @@ func NewLogOperationManager(info LogOperationInfo, logOperation LogOperation) *L
 		electionRunner:      make(map[int64]*electionRunner),
 		pendingResignations: make(chan resignation, 100),
 		logNames:            make(map[int64]string),
+		passes:              make(map[int64]*logPassStatus),
 	}
 }
 
@@ -366,6 +371,50 @@ func (l *LogOperationManager) updateHeldIDs(ctx context.Context, logIDs, allIDs
 	}
 }
 
+// recordPass records the outcome of a pass on logID which started at start.
+func (l *LogOperationManager) recordPass(logID int64, start time.Time, count int, err error) {
+	l.passesMu.Lock()
+	defer l.passesMu.Unlock()
+	p, ok := l.passes[logID]
+	if !ok {
+		p = &logPassStatus{}
+		l.passes[logID] = p
+	}
+	p.lastPass = start
+	if err != nil {
+		p.errors = append([]PassError{{Time: start, Error: err.Error()}}, p.errors...)
+		if len(p.errors) > maxRecentPassErrors {
+			p.errors = p.errors[:maxRecentPassErrors]
+		}
+		return
+	}
+	p.lastSuccess = start
+	p.lastItems = count
+}
+
+// logStatus returns whether this instance was master for logID as of the
+// latest pass, and the outcome of the latest passes on it.
+func (l *LogOperationManager) logStatus(logID int64) (bool, logPassStatus) {
+	master := false
+	l.heldMutex.Lock()
+	for _, id := range l.lastHeld {
+		if id == logID {
+			master = true
+			break
+		}
+	}
+	l.heldMutex.Unlock()
+
+	l.passesMu.Lock()
+	defer l.passesMu.Unlock()
+	var status logPassStatus
+	if p, ok := l.passes[logID]; ok {
+		status = *p
+		status.errors = append([]PassError(nil), p.errors...)
+	}
+	return master, status
+}
+
 func (l *LogOperationManager) getLogsAndExecutePass(ctx context.Context) error {
 	allIDs, err := l.getLogIDs(ctx)
 	if err != nil {
@@ -411,6 +460,7 @@ func (l *LogOperationManager) getLogsAndExecutePass(ctx context.Context) error {
 				label := strconv.FormatInt(logID, 10)
 				start := l.info.TimeSource.Now()
 				count, err := l.logOperation.ExecutePass(ctx, logID, &l.info)
+				l.recordPass(logID, start, count, err)
 				if err != nil {
 					glog.Errorf("ExecutePass(%v) failed: %v", logID, err)
 					failedSigningRuns.Inc(label)
@@ -463,8 +513,8 @@ loop:
 		// TODO(alcutter): want a child context with deadline here?
 		start := l.info.TimeSource.Now()
 		if err := l.getLogsAndExecutePass(ctx); err != nil {
-			// Suppress the error if ctx is done (ok==false) as we're exiting.
-			if _, ok := <-ctx.Done(); ok {
+			// Suppress the error if ctx is done as we're exiting.
+			if ctx.Err() == nil {
 				glog.Errorf("failed to execute operation on logs: %v", err)
 			}
 		}
@@ -15,26 +15,31 @@
 package server
 
 import (
+	"bytes"
+
 	"github.com/golang/glog"
+	"github.com/golang/protobuf/proto"
 	"github.com/google/trillian"
+	"github.com/google/trillian/crypto/keys/der"
 	"github.com/google/trillian/extension"
+	"github.com/google/trillian/log/leaffilter"
 	"github.com/google/trillian/merkle"
 	"github.com/google/trillian/merkle/hashers"
 	"github.com/google/trillian/monitoring"
 	"github.com/google/trillian/storage"
 	"github.com/google/trillian/trees"
 	"github.com/google/trillian/util"
+	"github.com/google/trillian/util/requestinfo"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+
+	tcrypto "github.com/google/trillian/crypto"
 )
 
 // TODO: There is no access control in the server yet and clients could easily modify
 // any tree.
 
-// Pass this as a fixed value to proof calculations. It's used as the max depth of the tree
-const proofMaxBitLen = 64
-
 var (
 	optsLogRead            = trees.NewGetOpts(true, trillian.TreeType_LOG, trillian.TreeType_PREORDERED_LOG)
 	optsLogWrite           = trees.NewGetOpts(false, trillian.TreeType_LOG)
@@ -46,17 +51,43 @@ type TrillianLogRPCServer struct {
 	registry    extension.Registry
 	timeSource  util.TimeSource
 	leafCounter monitoring.Counter
+	budget      DeadlineBudget
+	breaker     *CircuitBreaker
+	freshness   *RootFreshness
+	frontier    *FrontierCache
+	repair      *ReadRepairer
+	gossip      *GossipPool
+	readMemory  *ReadMemoryLimiter
+	// rangeChunkSize is the number of leaves AddSequencedLeafRange writes per
+	// storage transaction.
+	rangeChunkSize int
 }
 
+// DefaultRangeChunkSize is the default number of leaves AddSequencedLeafRange
+// writes per storage transaction.
+const DefaultRangeChunkSize = 1000
+
+// leafReadChunkSize is the number of leaves read from storage at a time by
+// GetLeavesByRange when its memory is limited, and by StreamLeavesByRange.
+const leafReadChunkSize = 1000
+
+// MaxLeafIndexResults is the maximum number of leaf indices returned by a
+// GetLeafIndicesByKey request.
+const MaxLeafIndexResults = 1000
+
 // NewTrillianLogRPCServer creates a new RPC server backed by a LogStorageProvider.
 func NewTrillianLogRPCServer(registry extension.Registry, timeSource util.TimeSource) *TrillianLogRPCServer {
 	mf := registry.MetricFactory
 	if mf == nil {
 		mf = monitoring.InertMetricFactory{}
 	}
+	initDeadlineMetrics(mf)
+	initFreshnessMetrics(mf)
+	initReadMemoryMetrics(mf)
 	return &TrillianLogRPCServer{
-		registry:   registry,
-		timeSource: timeSource,
+		registry:       registry,
+		timeSource:     timeSource,
+		rangeChunkSize: DefaultRangeChunkSize,
 		leafCounter: mf.NewCounter(
 			"queued_leaves",
 			"Number of leaves requested to be queued",
@@ -65,6 +96,75 @@ func NewTrillianLogRPCServer(registry extension.Registry, timeSource util.TimeSo
 	}
 }
 
+// SetDeadlineBudget sets the budget limiting how much of the remaining
+// deadline of an RPC each of its storage stages may use.
+func (t *TrillianLogRPCServer) SetDeadlineBudget(b DeadlineBudget) {
+	t.budget = b
+}
+
+// SetCircuitBreaker sets the breaker which sheds read RPCs when storage calls
+// start failing. A nil breaker never sheds RPCs.
+func (t *TrillianLogRPCServer) SetCircuitBreaker(b *CircuitBreaker) {
+	t.breaker = b
+}
+
+// SetRootFreshness sets the limits on the age of the log roots which the
+// server's RPCs are based on. A nil RootFreshness doesn't limit them.
+func (t *TrillianLogRPCServer) SetRootFreshness(f *RootFreshness) {
+	t.freshness = f
+}
+
+// SetFrontierCache sets the cache of log frontiers which proofs are built from
+// when possible, rather than from the Merkle nodes in storage. A nil cache
+// builds all proofs from storage.
+func (t *TrillianLogRPCServer) SetFrontierCache(c *FrontierCache) {
+	t.frontier = c
+}
+
+// SetReadRepairer sets the ReadRepairer which recomputes the Merkle nodes that
+// proofs need but which are missing from storage. A nil repairer fails those
+// proofs.
+func (t *TrillianLogRPCServer) SetReadRepairer(r *ReadRepairer) {
+	t.repair = r
+}
+
+// SetGossipPool sets the pool of log roots observed by other parties which
+// the gossip RPCs add to and serve. A nil pool disables them.
+func (t *TrillianLogRPCServer) SetGossipPool(g *GossipPool) {
+	t.gossip = g
+}
+
+// SetReadMemoryLimiter sets the limits on the memory held by the responses of
+// read RPCs. A nil limiter doesn't limit them.
+func (t *TrillianLogRPCServer) SetReadMemoryLimiter(l *ReadMemoryLimiter) {
+	t.readMemory = l
+}
+
+// SetRangeChunkSize sets the number of leaves AddSequencedLeafRange writes per
+// storage transaction. Values below 1 leave the current size in place.
+func (t *TrillianLogRPCServer) SetRangeChunkSize(n int) {
+	if n > 0 {
+		t.rangeChunkSize = n
+	}
+}
+
+// startStage starts the storage calls of stage, applying the server's
+// DeadlineBudget to them and recording their outcome with its CircuitBreaker.
+// Failed stages are logged at V(1), with the IDs of the request. See
+// DeadlineBudget.start.
+func (t *TrillianLogRPCServer) startStage(ctx context.Context, stage StorageStage) (context.Context, func(error) error) {
+	start := t.timeSource.Now()
+	sctx, end := t.budget.start(ctx, stage)
+	return sctx, func(err error) error {
+		elapsed := t.timeSource.Now().Sub(start)
+		t.breaker.Record(err, elapsed)
+		if err != nil && glog.V(1) {
+			requestinfo.Infof(ctx, "storage stage %v failed after %v: %v", stage, elapsed, err)
+		}
+		return end(err)
+	}
+}
+
 // IsHealthy returns nil if the server is healthy, error otherwise.
 func (t *TrillianLogRPCServer) IsHealthy() error {
 	return t.registry.LogStorage.CheckDatabaseAccessible(context.Background())
@@ -124,8 +224,9 @@ func (t *TrillianLogRPCServer) QueueLeaves(ctx context.Context, req *trillian.Qu
 		return nil, err
 	}
 
-	ret, err := t.registry.LogStorage.QueueLeaves(ctx, logID, req.Leaves, t.timeSource.Now())
-	if err != nil {
+	sctx, end := t.startStage(ctx, StageWrite)
+	ret, err := t.registry.LogStorage.QueueLeaves(sctx, logID, req.Leaves, t.timeSource.Now())
+	if err = end(err); err != nil {
 		return nil, err
 	}
 
@@ -181,8 +282,9 @@ func (t *TrillianLogRPCServer) AddSequencedLeaves(ctx context.Context, req *tril
 	}
 
 	ctx = trees.NewContext(ctx, tree)
-	leaves, err := t.registry.LogStorage.AddSequencedLeaves(ctx, tree.TreeId, req.Leaves)
-	if err != nil {
+	sctx, end := t.startStage(ctx, StageWrite)
+	leaves, err := t.registry.LogStorage.AddSequencedLeaves(sctx, tree.TreeId, req.Leaves)
+	if err = end(err); err != nil {
 		return nil, err
 	}
 	if got, want := len(leaves), len(req.Leaves); got != want {
@@ -192,6 +294,55 @@ func (t *TrillianLogRPCServer) AddSequencedLeaves(ctx context.Context, req *tril
 	return &trillian.AddSequencedLeavesResponse{Results: leaves}, nil
 }
 
+// AddSequencedLeafRange submits a contiguous range of leaves to a pre-ordered
+// log, writing them in chunks of rangeChunkSize leaves. Once a chunk has been
+// written, a failure to write a later one is reported in the response, along
+// with the index to resume from, rather than as an error.
+func (t *TrillianLogRPCServer) AddSequencedLeafRange(ctx context.Context, req *trillian.AddSequencedLeafRangeRequest) (*trillian.AddSequencedLeafRangeResponse, error) {
+	if err := validateAddSequencedLeafRangeRequest(req); err != nil {
+		return nil, err
+	}
+
+	tree, hasher, err := t.getTreeAndHasher(ctx, req.LogId, optsPreorderedLogWrite)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, leaf := range req.Leaves {
+		leaf.LeafIndex = req.StartIndex + int64(i)
+	}
+	if err := hashLeaves(req.Leaves, hasher); err != nil {
+		return nil, err
+	}
+
+	ctx = trees.NewContext(ctx, tree)
+	rsp := &trillian.AddSequencedLeafRangeResponse{NextIndex: req.StartIndex}
+	for start := 0; start < len(req.Leaves); start += t.rangeChunkSize {
+		end := start + t.rangeChunkSize
+		if end > len(req.Leaves) {
+			end = len(req.Leaves)
+		}
+		chunk := req.Leaves[start:end]
+
+		sctx, endStage := t.startStage(ctx, StageWrite)
+		leaves, err := t.registry.LogStorage.AddSequencedLeaves(sctx, tree.TreeId, chunk)
+		err = endStage(err)
+		if err == nil && len(leaves) != len(chunk) {
+			err = status.Errorf(codes.Internal, "AddSequencedLeaves returned %d leaves, want: %d", len(leaves), len(chunk))
+		}
+		if err != nil {
+			if start == 0 {
+				return nil, err
+			}
+			rsp.Status = status.Convert(err).Proto()
+			return rsp, nil
+		}
+		rsp.Results = append(rsp.Results, leaves...)
+		rsp.NextIndex += int64(len(chunk))
+	}
+	return rsp, nil
+}
+
 // GetInclusionProof obtains the proof of inclusion in the tree for a leaf that has been sequenced.
 // Similar to the get proof by hash handler but one less step as we don't need to look up the index
 func (t *TrillianLogRPCServer) GetInclusionProof(ctx context.Context, req *trillian.GetInclusionProofRequest) (*trillian.GetInclusionProofResponse, error) {
@@ -214,13 +365,18 @@ func (t *TrillianLogRPCServer) GetInclusionProof(ctx context.Context, req *trill
 	}
 	defer tx.Close()
 
-	root, err := tx.LatestSignedLogRoot(ctx)
-	if err != nil {
+	sctx, end := t.startStage(ctx, StageRoot)
+	root, err := tx.LatestSignedLogRoot(sctx)
+	if err = end(err); err != nil {
+		return nil, err
+	}
+	if err := t.freshness.check(req.LogId, &root, t.timeSource.Now()); err != nil {
 		return nil, err
 	}
 
-	proof, err := getInclusionProofForLeafIndex(ctx, tx, hasher, req.TreeSize, req.LeafIndex, root.TreeSize)
-	if err != nil {
+	sctx, end = t.startStage(ctx, StageProof)
+	proof, err := t.getInclusionProofForLeafIndex(sctx, tx, hasher, req.LogId, &root, req.TreeSize, req.LeafIndex)
+	if err = end(err); err != nil {
 		return nil, err
 	}
 
@@ -255,24 +411,30 @@ func (t *TrillianLogRPCServer) GetInclusionProofByHash(ctx context.Context, req
 
 	// Find the leaf index of the supplied hash
 	leafHashes := [][]byte{req.LeafHash}
-	leaves, err := tx.GetLeavesByHash(ctx, leafHashes, req.OrderBySequence)
-	if err != nil {
+	sctx, end := t.startStage(ctx, StageLeaves)
+	leaves, err := tx.GetLeavesByHash(sctx, leafHashes, req.OrderBySequence)
+	if err = end(err); err != nil {
 		return nil, err
 	}
 	if len(leaves) < 1 {
 		return nil, status.Errorf(codes.NotFound, "No leaves for hash: %x", req.LeafHash)
 	}
 
-	root, err := tx.LatestSignedLogRoot(ctx)
-	if err != nil {
+	sctx, end = t.startStage(ctx, StageRoot)
+	root, err := tx.LatestSignedLogRoot(sctx)
+	if err = end(err); err != nil {
+		return nil, err
+	}
+	if err := t.freshness.check(req.LogId, &root, t.timeSource.Now()); err != nil {
 		return nil, err
 	}
 
 	// TODO(Martin2112): Need to define a limit on number of results or some form of paging etc.
 	proofs := make([]*trillian.Proof, 0, len(leaves))
 	for _, leaf := range leaves {
-		proof, err := getInclusionProofForLeafIndex(ctx, tx, hasher, req.TreeSize, leaf.LeafIndex, root.TreeSize)
-		if err != nil {
+		sctx, end := t.startStage(ctx, StageProof)
+		proof, err := t.getInclusionProofForLeafIndex(sctx, tx, hasher, req.LogId, &root, req.TreeSize, leaf.LeafIndex)
+		if err = end(err); err != nil {
 			return nil, err
 		}
 		proofs = append(proofs, &proof)
@@ -308,20 +470,25 @@ func (t *TrillianLogRPCServer) GetConsistencyProof(ctx context.Context, req *tri
 	}
 	defer tx.Close()
 
-	root, err := tx.LatestSignedLogRoot(ctx)
-	if err != nil {
+	sctx, end := t.startStage(ctx, StageRoot)
+	root, err := tx.LatestSignedLogRoot(sctx)
+	if err = end(err); err != nil {
+		return nil, err
+	}
+	if err := t.freshness.check(req.LogId, &root, t.timeSource.Now()); err != nil {
 		return nil, err
 	}
 
-	nodeFetches, err := merkle.CalcConsistencyProofNodeAddresses(req.FirstTreeSize, req.SecondTreeSize, root.TreeSize, proofMaxBitLen)
+	nodeFetches, err := merkle.ConsistencyProofNodes(req.FirstTreeSize, req.SecondTreeSize, root.TreeSize)
 	if err != nil {
 		return nil, err
 	}
 
 	// Do all the node fetches at the second tree revision, which is what the node ids were calculated
 	// against.
-	proof, err := fetchNodesAndBuildProof(ctx, tx, hasher, tx.ReadRevision(), 0, nodeFetches)
-	if err != nil {
+	sctx, end = t.startStage(ctx, StageProof)
+	proof, err := t.buildProof(sctx, tx, hasher, logID, &root, 0, nodeFetches)
+	if err = end(err); err != nil {
 		return nil, err
 	}
 
@@ -333,6 +500,244 @@ func (t *TrillianLogRPCServer) GetConsistencyProof(ctx context.Context, req *tri
 	return &trillian.GetConsistencyProofResponse{Proof: &proof}, nil
 }
 
+// GetConsistencyProofChain returns a chain of consistency proofs from the
+// requested tree size to the size of the latest root, through the power-of-two
+// sizes in between, with the root hash at each of them.
+func (t *TrillianLogRPCServer) GetConsistencyProofChain(ctx context.Context, req *trillian.GetConsistencyProofChainRequest) (*trillian.GetConsistencyProofChainResponse, error) {
+	if err := validateGetConsistencyProofChainRequest(req); err != nil {
+		return nil, err
+	}
+	logID := req.LogId
+
+	tree, hasher, err := t.getTreeAndHasher(ctx, logID, optsLogRead)
+	if err != nil {
+		return nil, err
+	}
+	ctx = trees.NewContext(ctx, tree)
+
+	tx, err := t.prepareReadOnlyStorageTx(ctx, logID)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Close()
+
+	sctx, end := t.startStage(ctx, StageRoot)
+	root, err := tx.LatestSignedLogRoot(sctx)
+	if err = end(err); err != nil {
+		return nil, err
+	}
+	if err := t.freshness.check(logID, &root, t.timeSource.Now()); err != nil {
+		return nil, err
+	}
+	if req.FirstTreeSize > root.TreeSize {
+		return nil, status.Errorf(codes.InvalidArgument, "GetConsistencyProofChainRequest.FirstTreeSize: %v, want <= %v, the latest tree size", req.FirstTreeSize, root.TreeSize)
+	}
+
+	sizes := consistencyChainSizes(req.FirstTreeSize, root.TreeSize)
+	rootHashes, err := t.rootHashesAtSizes(ctx, tx, hasher, logID, &root, sizes)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &trillian.GetConsistencyProofChainResponse{SignedLogRoot: &root}
+	sctx, end = t.startStage(ctx, StageProof)
+	from := req.FirstTreeSize
+	for i, size := range sizes {
+		var nodeFetches []merkle.NodeFetch
+		if nodeFetches, err = merkle.ConsistencyProofNodes(from, size, root.TreeSize); err != nil {
+			break
+		}
+		var proof trillian.Proof
+		if proof, err = t.buildProof(sctx, tx, hasher, logID, &root, 0, nodeFetches); err != nil {
+			break
+		}
+		resp.Steps = append(resp.Steps, &trillian.ConsistencyProofStep{
+			TreeSize: size,
+			RootHash: rootHashes[i],
+			Proof:    &proof,
+		})
+		from = size
+	}
+	if err = end(err); err != nil {
+		return nil, err
+	}
+
+	if err := t.commitAndLog(ctx, logID, tx, "GetConsistencyProofChain"); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// consistencyChainSizes returns the sizes at which the steps of a chain of
+// consistency proofs from first to last end: the powers of two between them,
+// then last.
+func consistencyChainSizes(first, last int64) []int64 {
+	var sizes []int64
+	size := int64(1)
+	for size <= first {
+		size <<= 1
+	}
+	for ; size < last; size <<= 1 {
+		sizes = append(sizes, size)
+	}
+	if last > first {
+		sizes = append(sizes, last)
+	}
+	return sizes
+}
+
+// rootHashesAtSizes returns the root hashes of the log at the given sizes,
+// where the last size is that of root. The other root hashes are computed from
+// the inclusion proof of the last leaf at each size.
+func (t *TrillianLogRPCServer) rootHashesAtSizes(ctx context.Context, tx storage.ReadOnlyLogTreeTX, hasher hashers.LogHasher, logID int64, root *trillian.SignedLogRoot, sizes []int64) ([][]byte, error) {
+	if len(sizes) == 0 {
+		return nil, nil
+	}
+	hashes := make([][]byte, len(sizes))
+	hashes[len(sizes)-1] = root.RootHash
+	past := sizes[:len(sizes)-1]
+	if len(past) == 0 {
+		return hashes, nil
+	}
+
+	indices := make([]int64, 0, len(past))
+	for _, size := range past {
+		indices = append(indices, size-1)
+	}
+	sctx, end := t.startStage(ctx, StageLeaves)
+	leaves, err := tx.GetLeavesByIndex(sctx, indices)
+	if err = end(err); err != nil {
+		return nil, err
+	}
+	leafHashes := make(map[int64][]byte)
+	for _, leaf := range leaves {
+		leafHashes[leaf.LeafIndex] = leaf.MerkleLeafHash
+	}
+
+	v := merkle.NewLogVerifier(hasher)
+	sctx, end = t.startStage(ctx, StageProof)
+	for i, size := range past {
+		leafHash, ok := leafHashes[size-1]
+		if !ok {
+			err = status.Errorf(codes.Internal, "log %v has no leaf %v", logID, size-1)
+			break
+		}
+		var proof trillian.Proof
+		if proof, err = t.getInclusionProofForLeafIndex(sctx, tx, hasher, logID, root, size, size-1); err != nil {
+			break
+		}
+		if hashes[i], err = v.RootFromInclusionProof(size-1, size, proof.Hashes, leafHash); err != nil {
+			break
+		}
+	}
+	if err = end(err); err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
+// AddObservedRoot adds a root of a log observed by another party to the log's
+// gossip pool, after checking its signature, and reports whether the root
+// shows a split view.
+func (t *TrillianLogRPCServer) AddObservedRoot(ctx context.Context, req *trillian.AddObservedRootRequest) (*trillian.AddObservedRootResponse, error) {
+	if t.gossip == nil {
+		return nil, status.Error(codes.Unimplemented, "gossip is disabled on this server")
+	}
+	if err := validateAddObservedRootRequest(req); err != nil {
+		return nil, err
+	}
+	logID := req.LogId
+
+	tree, hasher, err := t.getTreeAndHasher(ctx, logID, optsLogRead)
+	if err != nil {
+		return nil, err
+	}
+	ctx = trees.NewContext(ctx, tree)
+	pubKey, err := der.UnmarshalPublicKey(tree.GetPublicKey().GetDer())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to parse public key of log %v: %v", logID, err)
+	}
+	root := proto.Clone(req.SignedLogRoot).(*trillian.SignedLogRoot)
+	root.LogId = logID
+	hash, err := tcrypto.HashLogRoot(*root)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "AddObservedRootRequest.SignedLogRoot: %v", err)
+	}
+	if err := tcrypto.Verify(pubKey, hash, root.Signature); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "AddObservedRootRequest.SignedLogRoot: signature doesn't verify with the key of log %v: %v", logID, err)
+	}
+
+	conflict, err := t.conflictsWithLog(ctx, hasher, logID, root)
+	if err != nil {
+		return nil, err
+	}
+	return &trillian.AddObservedRootResponse{
+		SplitView: t.gossip.add(logID, root, req.Observer, conflict),
+	}, nil
+}
+
+// conflictsWithLog returns whether observed, a root of logID, can't be a root
+// of the log's history, as it's stored. Roots larger than the log's latest
+// root can't be checked, e.g. if this server's storage lags behind.
+func (t *TrillianLogRPCServer) conflictsWithLog(ctx context.Context, hasher hashers.LogHasher, logID int64, observed *trillian.SignedLogRoot) (bool, error) {
+	tx, err := t.prepareReadOnlyStorageTx(ctx, logID)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Close()
+
+	sctx, end := t.startStage(ctx, StageRoot)
+	root, err := tx.LatestSignedLogRoot(sctx)
+	if err = end(err); err != nil {
+		return false, err
+	}
+
+	conflict := false
+	switch {
+	case observed.TreeSize > root.TreeSize:
+	case observed.TreeSize == root.TreeSize:
+		conflict = !bytes.Equal(observed.RootHash, root.RootHash)
+	case observed.TreeSize == 0:
+		conflict = !bytes.Equal(observed.RootHash, hasher.EmptyRoot())
+	default:
+		var nodeFetches []merkle.NodeFetch
+		nodeFetches, err = merkle.ConsistencyProofNodes(observed.TreeSize, root.TreeSize, root.TreeSize)
+		if err != nil {
+			return false, err
+		}
+		sctx, end = t.startStage(ctx, StageProof)
+		proof, err := t.buildProof(sctx, tx, hasher, logID, &root, 0, nodeFetches)
+		if err = end(err); err != nil {
+			return false, err
+		}
+		v := merkle.NewLogVerifier(hasher)
+		conflict = v.VerifyConsistencyProof(observed.TreeSize, root.TreeSize, observed.RootHash, root.RootHash, proof.Hashes) != nil
+	}
+
+	if err := t.commitAndLog(ctx, logID, tx, "AddObservedRoot"); err != nil {
+		return false, err
+	}
+	return conflict, nil
+}
+
+// GetObservedRoots returns the roots of a log in its gossip pool.
+func (t *TrillianLogRPCServer) GetObservedRoots(ctx context.Context, req *trillian.GetObservedRootsRequest) (*trillian.GetObservedRootsResponse, error) {
+	if t.gossip == nil {
+		return nil, status.Error(codes.Unimplemented, "gossip is disabled on this server")
+	}
+	if err := validateGetObservedRootsRequest(req); err != nil {
+		return nil, err
+	}
+	if _, _, err := t.getTreeAndHasher(ctx, req.LogId, optsLogRead); err != nil {
+		return nil, err
+	}
+	roots, sizes := t.gossip.list(req.LogId, req.TreeSize)
+	return &trillian.GetObservedRootsResponse{
+		ObservedRoots:      roots,
+		SplitViewTreeSizes: sizes,
+	}, nil
+}
+
 // GetLatestSignedLogRoot obtains the latest published tree root for the Merkle Tree that
 // underlies the log.
 func (t *TrillianLogRPCServer) GetLatestSignedLogRoot(ctx context.Context, req *trillian.GetLatestSignedLogRootRequest) (*trillian.GetLatestSignedLogRootResponse, error) {
@@ -342,8 +747,12 @@ func (t *TrillianLogRPCServer) GetLatestSignedLogRoot(ctx context.Context, req *
 	}
 	defer tx.Close()
 
-	signedRoot, err := tx.LatestSignedLogRoot(ctx)
-	if err != nil {
+	sctx, end := t.startStage(ctx, StageRoot)
+	signedRoot, err := tx.LatestSignedLogRoot(sctx)
+	if err = end(err); err != nil {
+		return nil, err
+	}
+	if err := t.freshness.check(req.LogId, &signedRoot, t.timeSource.Now()); err != nil {
 		return nil, err
 	}
 
@@ -363,8 +772,9 @@ func (t *TrillianLogRPCServer) GetSequencedLeafCount(ctx context.Context, req *t
 	}
 	defer tx.Close()
 
-	leafCount, err := tx.GetSequencedLeafCount(ctx)
-	if err != nil {
+	sctx, end := t.startStage(ctx, StageLeaves)
+	leafCount, err := tx.GetSequencedLeafCount(sctx)
+	if err = end(err); err != nil {
 		return nil, err
 	}
 
@@ -390,8 +800,14 @@ func (t *TrillianLogRPCServer) GetLeavesByIndex(ctx context.Context, req *trilli
 	}
 	defer tx.Close()
 
-	leaves, err := tx.GetLeavesByIndex(ctx, req.LeafIndex)
-	if err != nil {
+	sctx, end := t.startStage(ctx, StageLeaves)
+	leaves, err := tx.GetLeavesByIndex(sctx, req.LeafIndex)
+	if err = end(err); err != nil {
+		return nil, err
+	}
+	acct := t.readMemory.account(req.LogId, true)
+	defer acct.release()
+	if err := acct.addLeaves(leaves); err != nil {
 		return nil, err
 	}
 
@@ -416,9 +832,28 @@ func (t *TrillianLogRPCServer) GetLeavesByRange(ctx context.Context, req *trilli
 	}
 	defer tx.Close()
 
-	leaves, err := tx.GetLeavesByRange(ctx, req.StartIndex, req.Count)
-	if err != nil {
-		return nil, err
+	acct := t.readMemory.account(req.LogId, true)
+	defer acct.release()
+	var leaves []*trillian.LogLeaf
+	if acct == nil {
+		sctx, end := t.startStage(ctx, StageLeaves)
+		leaves, err = tx.GetLeavesByRange(sctx, req.StartIndex, req.Count)
+		if err = end(err); err != nil {
+			return nil, err
+		}
+	} else {
+		// Read the range in chunks, so that a range too large for the limits is
+		// refused before it's all held in memory.
+		err = t.readLeafRange(ctx, tx, req.StartIndex, req.Count, func(chunk []*trillian.LogLeaf) error {
+			if err := acct.addLeaves(chunk); err != nil {
+				return err
+			}
+			leaves = append(leaves, chunk...)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	if err := t.commitAndLog(ctx, req.LogId, tx, "GetLeavesByRange"); err != nil {
@@ -428,6 +863,83 @@ func (t *TrillianLogRPCServer) GetLeavesByRange(ctx context.Context, req *trilli
 	return &trillian.GetLeavesByRangeResponse{Leaves: leaves}, nil
 }
 
+// StreamLeavesByRange sends the leaves in a range of sequence numbers within
+// the tree in batches, each of them within the tree's maximum response size,
+// so that ranges too large for GetLeavesByRange can still be read.
+func (t *TrillianLogRPCServer) StreamLeavesByRange(req *trillian.GetLeavesByRangeRequest, stream trillian.TrillianLog_StreamLeavesByRangeServer) error {
+	if err := validateGetLeavesByRangeRequest(req); err != nil {
+		return err
+	}
+	ctx := stream.Context()
+
+	tx, err := t.prepareReadOnlyStorageTx(ctx, req.LogId)
+	if err != nil {
+		return err
+	}
+	defer tx.Close()
+
+	acct := t.readMemory.account(req.LogId, false)
+	defer acct.release()
+	maxBytes := t.readMemory.maxResponseBytes(req.LogId)
+	err = t.readLeafRange(ctx, tx, req.StartIndex, req.Count, func(chunk []*trillian.LogLeaf) error {
+		if err := acct.addLeaves(chunk); err != nil {
+			return err
+		}
+		defer acct.release()
+		for len(chunk) > 0 {
+			n := len(chunk)
+			if maxBytes > 0 {
+				// Send as many leaves as fit in the maximum response size, but
+				// at least one.
+				size := int64(proto.Size(chunk[0]))
+				for n = 1; n < len(chunk); n++ {
+					if size += int64(proto.Size(chunk[n])); size > maxBytes {
+						break
+					}
+				}
+			}
+			if err := stream.Send(&trillian.GetLeavesByRangeResponse{Leaves: chunk[:n]}); err != nil {
+				return err
+			}
+			chunk = chunk[n:]
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return t.commitAndLog(ctx, req.LogId, tx, "StreamLeavesByRange")
+}
+
+// readLeafRange reads the leaves in the range of count leaves from start, or
+// up to the size of the tree, from tx, in chunks of up to leafReadChunkSize
+// leaves, and passes each chunk to f in order. It stops at the first error.
+func (t *TrillianLogRPCServer) readLeafRange(ctx context.Context, tx storage.ReadOnlyLogTreeTX, start, count int64, f func([]*trillian.LogLeaf) error) error {
+	for end := start + count; start < end; {
+		n := end - start
+		if n > leafReadChunkSize {
+			n = leafReadChunkSize
+		}
+		sctx, done := t.startStage(ctx, StageLeaves)
+		chunk, err := tx.GetLeavesByRange(sctx, start, n)
+		if err = done(err); err != nil {
+			return err
+		}
+		if len(chunk) > 0 {
+			if err := f(chunk); err != nil {
+				return err
+			}
+		}
+		if int64(len(chunk)) < n {
+			// The range extends beyond the tree.
+			return nil
+		}
+		start += n
+	}
+	return nil
+}
+
 // GetLeavesByHash obtains one or more leaves based on their tree hash. It is not possible
 // to fetch leaves that have been queued but not yet integrated. Logs may accept duplicate
 // entries so this may return more results than the number of hashes in the request.
@@ -442,8 +954,9 @@ func (t *TrillianLogRPCServer) GetLeavesByHash(ctx context.Context, req *trillia
 	}
 	defer tx.Close()
 
-	leaves, err := tx.GetLeavesByHash(ctx, req.LeafHash, req.OrderBySequence)
-	if err != nil {
+	sctx, end := t.startStage(ctx, StageLeaves)
+	leaves, err := tx.GetLeavesByHash(sctx, req.LeafHash, req.OrderBySequence)
+	if err = end(err); err != nil {
 		return nil, err
 	}
 
@@ -456,6 +969,130 @@ func (t *TrillianLogRPCServer) GetLeavesByHash(ctx context.Context, req *trillia
 	}, nil
 }
 
+// GetLeafIndicesByKey returns the indices of the integrated leaves with a key
+// in one of the log's leaf indexes, in ascending order. Leaves which have been
+// integrated since the returned root are not included.
+func (t *TrillianLogRPCServer) GetLeafIndicesByKey(ctx context.Context, req *trillian.GetLeafIndicesByKeyRequest) (*trillian.GetLeafIndicesByKeyResponse, error) {
+	if err := validateGetLeafIndicesByKeyRequest(req); err != nil {
+		return nil, err
+	}
+	tree, _, err := t.getTreeAndHasher(ctx, req.LogId, optsLogRead)
+	if err != nil {
+		return nil, err
+	}
+	if !hasLeafIndex(tree, req.IndexName) {
+		return nil, status.Errorf(codes.NotFound, "log %v has no leaf index %q", req.LogId, req.IndexName)
+	}
+	maxResults := int(req.MaxResults)
+	if maxResults == 0 || maxResults > MaxLeafIndexResults {
+		maxResults = MaxLeafIndexResults
+	}
+
+	tx, err := t.prepareReadOnlyStorageTx(ctx, req.LogId)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Close()
+	reader, ok := tx.(storage.LeafIndexReader)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "log storage does not support leaf indexes")
+	}
+
+	sctx, end := t.startStage(ctx, StageRoot)
+	root, err := tx.LatestSignedLogRoot(sctx)
+	if err = end(err); err != nil {
+		return nil, err
+	}
+
+	// Fetch one more result than needed to tell whether there are more.
+	sctx, end = t.startStage(ctx, StageLeaves)
+	indices, err := reader.GetLeafIndicesByKey(sctx, req.IndexName, req.Key, req.StartIndex, maxResults+1)
+	if err = end(err); err != nil {
+		return nil, err
+	}
+
+	if err := t.commitAndLog(ctx, req.LogId, tx, "GetLeafIndicesByKey"); err != nil {
+		return nil, err
+	}
+
+	resp := &trillian.GetLeafIndicesByKeyResponse{SignedLogRoot: &root}
+	for _, index := range indices {
+		if index >= root.TreeSize {
+			break
+		}
+		if len(resp.LeafIndices) == maxResults {
+			resp.NextIndex = index
+			break
+		}
+		resp.LeafIndices = append(resp.LeafIndices, index)
+	}
+	return resp, nil
+}
+
+// CheckLeafFilter checks leaf identity hashes against the log's leaf filter,
+// which answers whether leaves with them may have been integrated.
+func (t *TrillianLogRPCServer) CheckLeafFilter(ctx context.Context, req *trillian.CheckLeafFilterRequest) (*trillian.CheckLeafFilterResponse, error) {
+	if err := validateCheckLeafFilterRequest(req); err != nil {
+		return nil, err
+	}
+	tree, _, err := t.getTreeAndHasher(ctx, req.LogId, optsLogRead)
+	if err != nil {
+		return nil, err
+	}
+	if tree.LeafFilter == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "log %v has no leaf filter", req.LogId)
+	}
+	f, err := leaffilter.New(tree.LeafFilter)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "invalid leaf filter of log %v: %v", req.LogId, err)
+	}
+
+	tx, err := t.prepareReadOnlyStorageTx(ctx, req.LogId)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Close()
+	reader, ok := tx.(storage.LeafFilterReader)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "log storage does not support leaf filters")
+	}
+
+	sctx, end := t.startStage(ctx, StageRoot)
+	root, err := tx.LatestSignedLogRoot(sctx)
+	if err = end(err); err != nil {
+		return nil, err
+	}
+
+	indices := make([]int64, 0, len(req.LeafIdentityHash))
+	for _, hash := range req.LeafIdentityHash {
+		indices = append(indices, f.BlockIndex(hash))
+	}
+	sctx, end = t.startStage(ctx, StageLeaves)
+	blocks, err := reader.GetLeafFilterBlocks(sctx, indices)
+	if err = end(err); err != nil {
+		return nil, err
+	}
+
+	if err := t.commitAndLog(ctx, req.LogId, tx, "CheckLeafFilter"); err != nil {
+		return nil, err
+	}
+
+	resp := &trillian.CheckLeafFilterResponse{SignedLogRoot: &root}
+	for i, hash := range req.LeafIdentityHash {
+		resp.MaybeLogged = append(resp.MaybeLogged, f.MayContain(blocks[indices[i]], hash))
+	}
+	return resp, nil
+}
+
+func hasLeafIndex(tree *trillian.Tree, name string) bool {
+	for _, spec := range tree.LeafIndexes {
+		if spec.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 // GetEntryAndProof returns both a Merkle Leaf entry and an inclusion proof for a given index
 // and tree size.
 func (t *TrillianLogRPCServer) GetEntryAndProof(ctx context.Context, req *trillian.GetEntryAndProofRequest) (*trillian.GetEntryAndProofResponse, error) {
@@ -478,19 +1115,25 @@ func (t *TrillianLogRPCServer) GetEntryAndProof(ctx context.Context, req *trilli
 	}
 	defer tx.Close()
 
-	root, err := tx.LatestSignedLogRoot(ctx)
-	if err != nil {
+	sctx, end := t.startStage(ctx, StageRoot)
+	root, err := tx.LatestSignedLogRoot(sctx)
+	if err = end(err); err != nil {
+		return nil, err
+	}
+	if err := t.freshness.check(req.LogId, &root, t.timeSource.Now()); err != nil {
 		return nil, err
 	}
 
-	proof, err := getInclusionProofForLeafIndex(ctx, tx, hasher, req.TreeSize, req.LeafIndex, root.TreeSize)
-	if err != nil {
+	sctx, end = t.startStage(ctx, StageProof)
+	proof, err := t.getInclusionProofForLeafIndex(sctx, tx, hasher, req.LogId, &root, req.TreeSize, req.LeafIndex)
+	if err = end(err); err != nil {
 		return nil, err
 	}
 
 	// We also need the leaf entry
-	leaves, err := tx.GetLeavesByIndex(ctx, []int64{req.LeafIndex})
-	if err != nil {
+	sctx, end = t.startStage(ctx, StageLeaves)
+	leaves, err := tx.GetLeavesByIndex(sctx, []int64{req.LeafIndex})
+	if err = end(err); err != nil {
 		return nil, err
 	}
 
@@ -502,25 +1145,124 @@ func (t *TrillianLogRPCServer) GetEntryAndProof(ctx context.Context, req *trilli
 		return nil, err
 	}
 
+	leaf := leaves[0]
+	if req.OmitLeafData {
+		leaf = withoutLeafData(leaf)
+	}
+
 	// Work is complete, we have everything we need for the response
 	return &trillian.GetEntryAndProofResponse{
 		Proof: &proof,
-		Leaf:  leaves[0],
+		Leaf:  leaf,
 	}, nil
 }
 
+// GetEntriesAndProofs returns Merkle Leaf entries and inclusion proofs for a batch of
+// indices, all proven against the same tree size.
+func (t *TrillianLogRPCServer) GetEntriesAndProofs(ctx context.Context, req *trillian.GetEntriesAndProofsRequest) (*trillian.GetEntriesAndProofsResponse, error) {
+	if err := validateGetEntriesAndProofsRequest(req); err != nil {
+		return nil, err
+	}
+	logID := req.LogId
+
+	tree, hasher, err := t.getTreeAndHasher(ctx, logID, optsLogRead)
+	if err != nil {
+		return nil, err
+	}
+	ctx = trees.NewContext(ctx, tree)
+
+	tx, err := t.prepareReadOnlyStorageTx(ctx, req.LogId)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Close()
+
+	sctx, end := t.startStage(ctx, StageRoot)
+	root, err := tx.LatestSignedLogRoot(sctx)
+	if err = end(err); err != nil {
+		return nil, err
+	}
+	if err := t.freshness.check(req.LogId, &root, t.timeSource.Now()); err != nil {
+		return nil, err
+	}
+
+	sctx, end = t.startStage(ctx, StageLeaves)
+	leaves, err := tx.GetLeavesByIndex(sctx, req.LeafIndex)
+	if err = end(err); err != nil {
+		return nil, err
+	}
+	acct := t.readMemory.account(req.LogId, true)
+	defer acct.release()
+	if err := acct.addLeaves(leaves); err != nil {
+		return nil, err
+	}
+	// Storage may return the leaves in any order, and only once for repeated indices.
+	byIndex := make(map[int64]*trillian.LogLeaf)
+	for _, leaf := range leaves {
+		byIndex[leaf.LeafIndex] = leaf
+	}
+
+	entries := make([]*trillian.GetEntryAndProofResponse, 0, len(req.LeafIndex))
+	for _, leafIndex := range req.LeafIndex {
+		leaf, ok := byIndex[leafIndex]
+		if !ok {
+			return nil, status.Errorf(codes.Internal, "leaf at index %d missing from storage", leafIndex)
+		}
+		if req.OmitLeafData {
+			leaf = withoutLeafData(leaf)
+		}
+		sctx, end := t.startStage(ctx, StageProof)
+		proof, err := t.getInclusionProofForLeafIndex(sctx, tx, hasher, req.LogId, &root, req.TreeSize, leafIndex)
+		if err = end(err); err != nil {
+			return nil, err
+		}
+		if err := acct.add(int64(proto.Size(&proof))); err != nil {
+			return nil, err
+		}
+		entries = append(entries, &trillian.GetEntryAndProofResponse{
+			Proof: &proof,
+			Leaf:  leaf,
+		})
+	}
+
+	if err := t.commitAndLog(ctx, req.LogId, tx, "GetEntriesAndProofs"); err != nil {
+		return nil, err
+	}
+
+	return &trillian.GetEntriesAndProofsResponse{Entries: entries}, nil
+}
+
+// withoutLeafData returns a copy of leaf with its LeafValue and ExtraData
+// cleared, leaving only the hashes and metadata.
+func withoutLeafData(leaf *trillian.LogLeaf) *trillian.LogLeaf {
+	l := *leaf
+	l.LeafValue = nil
+	l.ExtraData = nil
+	return &l
+}
+
 func (t *TrillianLogRPCServer) prepareReadOnlyStorageTx(ctx context.Context, treeID int64) (storage.ReadOnlyLogTreeTX, error) {
+	if err := t.breaker.Allow(); err != nil {
+		return nil, err
+	}
+	start := t.timeSource.Now()
 	tx, err := t.registry.LogStorage.SnapshotForTree(ctx, treeID)
+	t.breaker.Record(err, t.timeSource.Now().Sub(start))
 	if err != nil {
+		// Some storage implementations return a transaction along with
+		// ErrTreeNeedsInit, which mustn't be leaked.
+		if err == storage.ErrTreeNeedsInit && tx != nil {
+			tx.Close()
+		}
 		return nil, err
 	}
-	return tx, err
+	return tx, nil
 }
 
 func (t *TrillianLogRPCServer) commitAndLog(ctx context.Context, logID int64, tx storage.ReadOnlyLogTreeTX, op string) error {
 	err := tx.Commit()
 	if err != nil {
-		glog.Warningf("%v: Commit failed for %v: %v", logID, op, err)
+		requestinfo.Warningf(ctx, "%v: Commit failed for %v: %v", logID, op, err)
 	}
 	return err
 }
@@ -528,14 +1270,31 @@ func (t *TrillianLogRPCServer) commitAndLog(ctx context.Context, logID int64, tx
 // getInclusionProofForLeafIndex is used by multiple handlers. It does the storage fetching
 // and makes additional checks on the returned proof. Returns a Proof suitable for inclusion in
 // an RPC response
-func getInclusionProofForLeafIndex(ctx context.Context, tx storage.ReadOnlyLogTreeTX, hasher hashers.LogHasher, snapshot, leafIndex, treeSize int64) (trillian.Proof, error) {
+func (t *TrillianLogRPCServer) getInclusionProofForLeafIndex(ctx context.Context, tx storage.ReadOnlyLogTreeTX, hasher hashers.LogHasher, logID int64, root *trillian.SignedLogRoot, snapshot, leafIndex int64) (trillian.Proof, error) {
 	// We have the tree size and leaf index so we know the nodes that we need to serve the proof
-	proofNodeIDs, err := merkle.CalcInclusionProofNodeAddresses(snapshot, leafIndex, treeSize, proofMaxBitLen)
+	proofNodeIDs, err := merkle.InclusionProofNodes(snapshot, leafIndex, root.TreeSize)
 	if err != nil {
 		return trillian.Proof{}, err
 	}
 
-	return fetchNodesAndBuildProof(ctx, tx, hasher, tx.ReadRevision(), leafIndex, proofNodeIDs)
+	return t.buildProof(ctx, tx, hasher, logID, root, leafIndex, proofNodeIDs)
+}
+
+// buildProof fetches the nodes of a proof as of root, the latest root of the
+// log, and builds the proof. The nodes are computed from the server's
+// FrontierCache if it holds them all, and read from storage otherwise, with
+// those missing from storage recomputed by the server's ReadRepairer.
+func (t *TrillianLogRPCServer) buildProof(ctx context.Context, tx storage.ReadOnlyLogTreeTX, hasher hashers.LogHasher, logID int64, root *trillian.SignedLogRoot, leafIndex int64, fetches []merkle.NodeFetch) (trillian.Proof, error) {
+	if t.frontier != nil {
+		if proof, err := t.frontier.buildProof(ctx, tx, hasher, logID, root, leafIndex, fetches); err == nil {
+			return proof, nil
+		}
+	}
+	var r storage.NodeReader = tx
+	if t.repair != nil {
+		r = t.repair.nodeReader(tx, hasher, logID, root)
+	}
+	return fetchNodesAndBuildProof(ctx, r, hasher, tx.ReadRevision(), leafIndex, fetches)
 }
 
 func (t *TrillianLogRPCServer) getTreeAndHasher(
@@ -543,8 +1302,9 @@ func (t *TrillianLogRPCServer) getTreeAndHasher(
 	treeID int64,
 	opts trees.GetOpts,
 ) (*trillian.Tree, hashers.LogHasher, error) {
-	tree, err := trees.GetTree(ctx, t.registry.AdminStorage, treeID, opts)
-	if err != nil {
+	sctx, end := t.startStage(ctx, StageTree)
+	tree, err := trees.GetTree(sctx, t.registry.AdminStorage, treeID, opts)
+	if err = end(err); err != nil {
 		return nil, nil, err
 	}
 	hasher, err := hashers.NewLogHasher(tree.HashStrategy)
@@ -555,11 +1315,14 @@ func (t *TrillianLogRPCServer) getTreeAndHasher(
 }
 
 // InitLog initialises a freshly created Log by creating the first STH with
-// size 0.
+// size 0, which signs the metadata of the request if it has any.
 //
 // TODO(pavelkalinnikov): Make this work for PREORDERED_LOG as well, after
 // ReadWriteTransaction accepts GetOpts.
 func (t *TrillianLogRPCServer) InitLog(ctx context.Context, req *trillian.InitLogRequest) (*trillian.InitLogResponse, error) {
+	if err := validateInitLogRequest(req); err != nil {
+		return nil, err
+	}
 	logID := req.LogId
 	tree, hasher, err := t.getTreeAndHasher(ctx, logID, optsLogWrite)
 	if err != nil {
@@ -567,7 +1330,8 @@ func (t *TrillianLogRPCServer) InitLog(ctx context.Context, req *trillian.InitLo
 	}
 
 	var newRoot *trillian.SignedLogRoot
-	err = t.registry.LogStorage.ReadWriteTransaction(ctx, logID, func(ctx context.Context, tx storage.LogTreeTX) error {
+	sctx, end := t.startStage(ctx, StageWrite)
+	err = t.registry.LogStorage.ReadWriteTransaction(sctx, logID, func(ctx context.Context, tx storage.LogTreeTX) error {
 		newRoot = nil
 
 		latestRoot, err := tx.LatestSignedLogRoot(ctx)
@@ -586,6 +1350,7 @@ func (t *TrillianLogRPCServer) InitLog(ctx context.Context, req *trillian.InitLo
 			TreeSize:       0,
 			LogId:          logID,
 			TreeRevision:   0,
+			Metadata:       req.Metadata,
 		}
 
 		signer, err := trees.Signer(ctx, tree)
@@ -597,6 +1362,9 @@ func (t *TrillianLogRPCServer) InitLog(ctx context.Context, req *trillian.InitLo
 		if err != nil {
 			return err
 		}
+		if newRoot.AdditionalSignatures, err = signer.AdditionalLogRootSignatures(newRoot); err != nil {
+			return err
+		}
 		newRoot.Signature = sig
 
 		if err := tx.StoreSignedLogRoot(ctx, *newRoot); err != nil {
@@ -605,7 +1373,7 @@ func (t *TrillianLogRPCServer) InitLog(ctx context.Context, req *trillian.InitLo
 
 		return nil
 	})
-	if err != nil && err != storage.ErrTreeNeedsInit {
+	if err = end(err); err != nil && err != storage.ErrTreeNeedsInit {
 		return nil, err
 	}
 
@@ -24,6 +24,8 @@ import (
 
 	"github.com/golang/mock/gomock"
 	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
 	"github.com/google/trillian"
 	"github.com/google/trillian/extension"
 	"github.com/google/trillian/merkle/rfc6962"
@@ -471,6 +473,126 @@ func TestAddSequencedLeaves(t *testing.T) {
 	}
 }
 
+func TestAddSequencedLeafRange(t *testing.T) {
+	newLeaves := func(n int) []*trillian.LogLeaf {
+		leaves := make([]*trillian.LogLeaf, n)
+		for i := range leaves {
+			leaves[i] = &trillian.LogLeaf{LeafValue: []byte(fmt.Sprintf("value%d", i))}
+		}
+		return leaves
+	}
+	wrongIndex := newLeaves(3)
+	wrongIndex[1].LeafIndex = 7
+
+	for _, test := range []struct {
+		desc       string
+		req        *trillian.AddSequencedLeafRangeRequest
+		chunkSize  int
+		storageErr []error // Returned by successive AddSequencedLeaves calls.
+		wantChunks []int
+		wantCode   codes.Code
+		wantNext   int64
+		wantStatus codes.Code
+	}{
+		{
+			desc:     "negativeStart",
+			req:      &trillian.AddSequencedLeafRangeRequest{LogId: logID3, StartIndex: -1, Leaves: newLeaves(1)},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			desc:     "wrongLeafIndex",
+			req:      &trillian.AddSequencedLeafRangeRequest{LogId: logID3, StartIndex: 5, Leaves: wrongIndex},
+			wantCode: codes.FailedPrecondition,
+		},
+		{
+			desc:       "oneChunk",
+			req:        &trillian.AddSequencedLeafRangeRequest{LogId: logID3, StartIndex: 10, Leaves: newLeaves(3)},
+			chunkSize:  5,
+			wantChunks: []int{3},
+			wantNext:   13,
+		},
+		{
+			desc:       "chunked",
+			req:        &trillian.AddSequencedLeafRangeRequest{LogId: logID3, StartIndex: 10, Leaves: newLeaves(5)},
+			chunkSize:  2,
+			wantChunks: []int{2, 2, 1},
+			wantNext:   15,
+		},
+		{
+			desc:       "firstChunkFails",
+			req:        &trillian.AddSequencedLeafRangeRequest{LogId: logID3, StartIndex: 10, Leaves: newLeaves(5)},
+			chunkSize:  2,
+			storageErr: []error{status.Error(codes.Unavailable, "STORAGE")},
+			wantChunks: []int{2},
+			wantCode:   codes.Unavailable,
+		},
+		{
+			desc:       "laterChunkFails",
+			req:        &trillian.AddSequencedLeafRangeRequest{LogId: logID3, StartIndex: 10, Leaves: newLeaves(5)},
+			chunkSize:  2,
+			storageErr: []error{nil, status.Error(codes.Unavailable, "STORAGE")},
+			wantChunks: []int{2, 2},
+			wantNext:   12,
+			wantStatus: codes.Unavailable,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var chunks []int
+			mockStorage := storage.NewMockLogStorage(ctrl)
+			mockStorage.EXPECT().AddSequencedLeaves(gomock.Any(), logID3, gomock.Any()).AnyTimes().DoAndReturn(
+				func(_ context.Context, _ int64, leaves []*trillian.LogLeaf) ([]*trillian.QueuedLogLeaf, error) {
+					n := len(chunks)
+					chunks = append(chunks, len(leaves))
+					if n < len(test.storageErr) && test.storageErr[n] != nil {
+						return nil, test.storageErr[n]
+					}
+					want := test.req.StartIndex + int64(test.chunkSize*n)
+					results := make([]*trillian.QueuedLogLeaf, len(leaves))
+					for i, leaf := range leaves {
+						if got := leaf.LeafIndex; got != want+int64(i) {
+							t.Errorf("chunk %d: LeafIndex=%d, want %d", n, got, want+int64(i))
+						}
+						if len(leaf.MerkleLeafHash) == 0 {
+							t.Errorf("chunk %d: leaf %d not hashed", n, i)
+						}
+						results[i] = &trillian.QueuedLogLeaf{Status: status.New(codes.OK, "OK").Proto()}
+					}
+					return results, nil
+				})
+
+			registry := extension.Registry{
+				AdminStorage: fakeAdminStorage(ctrl, storageParams{logID3, true, 1}),
+				LogStorage:   mockStorage,
+			}
+			server := NewTrillianLogRPCServer(registry, fakeTimeSource)
+			server.SetRangeChunkSize(test.chunkSize)
+
+			rsp, err := server.AddSequencedLeafRange(context.Background(), test.req)
+			if got := status.Code(err); got != test.wantCode {
+				t.Fatalf("AddSequencedLeafRange()=%v, want code %v", err, test.wantCode)
+			}
+			if !reflect.DeepEqual(chunks, test.wantChunks) {
+				t.Errorf("AddSequencedLeaves() chunks=%v, want %v", chunks, test.wantChunks)
+			}
+			if err != nil {
+				return
+			}
+			if got := rsp.NextIndex; got != test.wantNext {
+				t.Errorf("AddSequencedLeafRange().NextIndex=%d, want %d", got, test.wantNext)
+			}
+			if got, want := int64(len(rsp.Results)), test.wantNext-test.req.StartIndex; got != want {
+				t.Errorf("AddSequencedLeafRange() returned %d results, want %d", got, want)
+			}
+			if got := codes.Code(rsp.GetStatus().GetCode()); got != test.wantStatus {
+				t.Errorf("AddSequencedLeafRange().Status.Code=%v, want %v", got, test.wantStatus)
+			}
+		})
+	}
+}
+
 type latestRootTest struct {
 	req         trillian.GetLatestSignedLogRootRequest
 	wantRoot    trillian.GetLatestSignedLogRootResponse
@@ -1457,6 +1579,96 @@ func TestTrillianLogRPCServer_GetEntryAndProofErrors(t *testing.T) {
 	}
 }
 
+func TestGetEntriesAndProofs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fakeStorage := storage.NewMockLogStorage(ctrl)
+	mockTx := storage.NewMockLogTreeTX(ctrl)
+	fakeStorage.EXPECT().SnapshotForTree(gomock.Any(), logID1).Return(mockTx, nil)
+
+	mockTx.EXPECT().LatestSignedLogRoot(gomock.Any()).Return(signedRoot1, nil)
+	mockTx.EXPECT().ReadRevision().Return(signedRoot1.TreeRevision)
+	mockTx.EXPECT().GetMerkleNodes(gomock.Any(), revision1, nodeIdsInclusionSize7Index2).Return([]storage.Node{
+		{NodeID: nodeIdsInclusionSize7Index2[0], NodeRevision: 3, Hash: []byte("nodehash0")},
+		{NodeID: nodeIdsInclusionSize7Index2[1], NodeRevision: 2, Hash: []byte("nodehash1")},
+		{NodeID: nodeIdsInclusionSize7Index2[2], NodeRevision: 3, Hash: []byte("nodehash2")}}, nil)
+	mockTx.EXPECT().GetLeavesByIndex(gomock.Any(), []int64{2}).Return([]*trillian.LogLeaf{leaf2}, nil)
+	mockTx.EXPECT().Commit().Return(nil)
+	mockTx.EXPECT().Close().Return(nil)
+
+	registry := extension.Registry{
+		AdminStorage: fakeAdminStorage(ctrl, storageParams{treeID: logID1, numSnapshots: 1}),
+		LogStorage:   fakeStorage,
+	}
+	server := NewTrillianLogRPCServer(registry, fakeTimeSource)
+
+	req := &trillian.GetEntriesAndProofsRequest{LogId: logID1, TreeSize: 7, LeafIndex: []int64{2}, OmitLeafData: true}
+	response, err := server.GetEntriesAndProofs(context.Background(), req)
+	if err != nil {
+		t.Fatalf("GetEntriesAndProofs()=_,%v, want: _,nil", err)
+	}
+	if got, want := len(response.Entries), 1; got != want {
+		t.Fatalf("GetEntriesAndProofs() returned %d entries, want %d", got, want)
+	}
+
+	wantProof := &trillian.Proof{
+		LeafIndex: 2,
+		Hashes: [][]byte{
+			[]byte("nodehash0"),
+			[]byte("nodehash1"),
+			[]byte("nodehash2"),
+		},
+	}
+	if got := response.Entries[0].Proof; !proto.Equal(got, wantProof) {
+		t.Errorf("GetEntriesAndProofs().Entries[0].Proof=%v, want %v", got, wantProof)
+	}
+
+	// The leaf data is omitted, but not the hashes.
+	wantLeaf := proto.Clone(leaf2).(*trillian.LogLeaf)
+	wantLeaf.LeafValue = nil
+	wantLeaf.ExtraData = nil
+	if got := response.Entries[0].Leaf; !proto.Equal(got, wantLeaf) {
+		t.Errorf("GetEntriesAndProofs().Entries[0].Leaf=%v, want %v", got, wantLeaf)
+	}
+	if len(leaf2.LeafValue) == 0 {
+		t.Error("GetEntriesAndProofs() modified the leaf returned by storage")
+	}
+}
+
+func TestTrillianLogRPCServer_GetEntriesAndProofsErrors(t *testing.T) {
+	tests := []struct {
+		desc string
+		req  *trillian.GetEntriesAndProofsRequest
+	}{
+		{
+			desc: "noLeafIndex",
+			req:  &trillian.GetEntriesAndProofsRequest{LogId: 1, TreeSize: 20},
+		},
+		{
+			desc: "badLeafIndex",
+			req:  &trillian.GetEntriesAndProofsRequest{LogId: 1, LeafIndex: []int64{1, -10}, TreeSize: 20},
+		},
+		{
+			desc: "badTreeSize",
+			req:  &trillian.GetEntriesAndProofsRequest{LogId: 1, LeafIndex: []int64{10}, TreeSize: -20},
+		},
+		{
+			desc: "indexGreaterThanSize",
+			req:  &trillian.GetEntriesAndProofsRequest{LogId: 1, LeafIndex: []int64{1, 10}, TreeSize: 9},
+		},
+	}
+
+	logServer := NewTrillianLogRPCServer(extension.Registry{}, fakeTimeSource)
+	ctx := context.Background()
+	for _, test := range tests {
+		_, err := logServer.GetEntriesAndProofs(ctx, test.req)
+		if s, ok := status.FromError(err); !ok || s.Code() != codes.InvalidArgument {
+			t.Errorf("%v: GetEntriesAndProofs() returned err = %v, wantCode = %s", test.desc, err, codes.InvalidArgument)
+		}
+	}
+}
+
 func TestTrillianLogRPCServer_GetInclusionProofErrors(t *testing.T) {
 	tests := []struct {
 		desc string
@@ -1697,15 +1909,22 @@ func TestTrillianLogRPCServer_QueueLeavesErrors(t *testing.T) {
 func TestInitLog(t *testing.T) {
 	ctx := context.Background()
 
+	meta, err := ptypes.MarshalAny(&trillian.LogRootMetadata{Origin: "example.com/log"})
+	if err != nil {
+		t.Fatalf("MarshalAny(): %v", err)
+	}
+
 	for _, tc := range []struct {
 		desc       string
 		getRootErr error
 		wantInit   bool
 		root       []byte
+		metadata   *any.Any
 		wantCode   codes.Code
 	}{
 		{desc: "init new log", getRootErr: storage.ErrTreeNeedsInit, wantInit: true, root: nil, wantCode: codes.OK},
 		{desc: "init new log, no err", getRootErr: nil, wantInit: true, root: nil, wantCode: codes.OK},
+		{desc: "init new log with metadata", getRootErr: storage.ErrTreeNeedsInit, wantInit: true, root: nil, metadata: meta, wantCode: codes.OK},
 		{desc: "init already initialised log", getRootErr: nil, wantInit: false, root: []byte{}, wantCode: codes.AlreadyExists},
 	} {
 		t.Run(tc.desc, func(t *testing.T) {
@@ -1734,7 +1953,7 @@ func TestInitLog(t *testing.T) {
 			}
 			logServer := NewTrillianLogRPCServer(registry, fakeTimeSource)
 
-			c, err := logServer.InitLog(ctx, &trillian.InitLogRequest{LogId: logID1})
+			c, err := logServer.InitLog(ctx, &trillian.InitLogRequest{LogId: logID1, Metadata: tc.metadata})
 			if got, want := status.Code(err), tc.wantCode; got != want {
 				t.Errorf("InitLog returned %v (%v), want %v", got, err, want)
 			}
@@ -1743,7 +1962,10 @@ func TestInitLog(t *testing.T) {
 					t.Fatalf("InitLog returned %v, want no error", err)
 				}
 				if c.Created == nil {
-					t.Error("InitLog first attempt didn't return the created STH.")
+					t.Fatal("InitLog first attempt didn't return the created STH.")
+				}
+				if got, want := c.Created.Metadata, tc.metadata; !proto.Equal(got, want) {
+					t.Errorf("InitLog returned STH with metadata %v, want %v", got, want)
 				}
 			} else {
 				if err == nil {
@@ -1754,6 +1976,40 @@ func TestInitLog(t *testing.T) {
 	}
 }
 
+func TestInitLog_InvalidMetadata(t *testing.T) {
+	ctx := context.Background()
+	start, limit := ptypes.TimestampNow(), ptypes.TimestampNow()
+	limit.Seconds++
+
+	for _, tc := range []struct {
+		desc    string
+		meta    *trillian.LogRootMetadata
+		wantErr bool
+	}{
+		{desc: "origin", meta: &trillian.LogRootMetadata{Origin: "example.com/log"}},
+		{desc: "shard", meta: &trillian.LogRootMetadata{Origin: "example.com/log", Shard: &trillian.LogShard{Name: "2018", Start: start, Limit: limit}}},
+		{desc: "no origin", meta: &trillian.LogRootMetadata{}, wantErr: true},
+		{desc: "shard without limit", meta: &trillian.LogRootMetadata{Origin: "example.com/log", Shard: &trillian.LogShard{Start: start}}, wantErr: true},
+		{desc: "empty shard interval", meta: &trillian.LogRootMetadata{Origin: "example.com/log", Shard: &trillian.LogShard{Start: limit, Limit: start}}, wantErr: true},
+	} {
+		meta, err := ptypes.MarshalAny(tc.meta)
+		if err != nil {
+			t.Fatalf("%v: MarshalAny(): %v", tc.desc, err)
+		}
+		err = validateInitLogRequest(&trillian.InitLogRequest{LogId: logID1, Metadata: meta})
+		if gotErr := err != nil; gotErr != tc.wantErr {
+			t.Errorf("%v: validateInitLogRequest() returned err = %v, wantErr = %v", tc.desc, err, tc.wantErr)
+		}
+		if tc.wantErr {
+			// The request is rejected before any storage is touched.
+			server := NewTrillianLogRPCServer(extension.Registry{}, fakeTimeSource)
+			if _, err := server.InitLog(ctx, &trillian.InitLogRequest{LogId: logID1, Metadata: meta}); status.Code(err) != codes.InvalidArgument {
+				t.Errorf("%v: InitLog() returned err = %v, want code %v", tc.desc, err, codes.InvalidArgument)
+			}
+		}
+	}
+}
+
 type prepareFakeStorageFunc func(*stestonly.FakeLogStorage)
 type prepareMockTXFunc func(*storage.MockLogTreeTX)
 type makeRPCFunc func(*TrillianLogRPCServer) error
@@ -24,6 +24,7 @@ import (
 	"github.com/google/trillian"
 	"github.com/google/trillian/extension"
 	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/server/accounting"
 	"github.com/google/trillian/server/admin"
 	"github.com/google/trillian/server/interceptor"
 	"github.com/google/trillian/util"
@@ -48,6 +49,11 @@ const (
 	// hard-deleting them.
 	// Actual runs happen randomly between [minInterval,2*minInterval).
 	DefaultTreeDeleteMinInterval = 4 * time.Hour
+
+	// DefaultTreeCompactionMinInterval is the suggested min interval between frozen tree
+	// compaction sweeps.
+	// Actual runs happen randomly between [minInterval,2*minInterval).
+	DefaultTreeCompactionMinInterval = 24 * time.Hour
 )
 
 // Main encapsulates the data and logic to start a Trillian server (Log or Map).
@@ -58,6 +64,9 @@ type Main struct {
 
 	// TLS Certificate and Key files for the server.
 	TLSCertFile, TLSKeyFile string
+	// Connection configures keepalives, connection ages and stream limits of
+	// the RPC server's client connections.
+	Connection util.ConnectionOpts
 
 	DBClose func() error
 
@@ -65,6 +74,23 @@ type Main struct {
 
 	StatsPrefix string
 	QuotaDryRun bool
+	// QuotaCosts sets the number of quota tokens charged for requests, see
+	// interceptor.CostModel.
+	QuotaCosts interceptor.CostModel
+	// AbuseDetector, if set, measures the submissions of each quota user and
+	// throttles anomalous ones. Its state is served on /abuse by the HTTP
+	// server.
+	AbuseDetector *AbuseDetector
+	// Status, if set, is served on /statusz by the HTTP server.
+	Status *StatusPage
+	// Accountant, if set, records the API usage of each quota user, per tree
+	// and RPC. Main runs it, and flushes it when the server stops.
+	Accountant *accounting.Accountant
+	// Priority, if set, limits the number of requests handled at once, and
+	// admits the others by the priority class their clients set.
+	Priority *PriorityScheduler
+	// ProofSigner, if set, signs the responses to proof RPCs.
+	ProofSigner *ProofSigner
 
 	// RegisterHandlerFn is called to register REST-proxy handlers.
 	RegisterHandlerFn func(context.Context, *runtime.ServeMux, string, []grpc.DialOption) error
@@ -74,16 +100,33 @@ type Main struct {
 	// AllowedTreeTypes determines which types of trees may be created through the Admin Server
 	// bound by Main. nil means unrestricted.
 	AllowedTreeTypes []trillian.TreeType
+	// TimeSource is used by the Admin Server bound by Main. Defaults to
+	// util.SystemTimeSource.
+	TimeSource util.TimeSource
 
 	TreeGCEnabled         bool
 	TreeDeleteThreshold   time.Duration
 	TreeDeleteMinInterval time.Duration
+
+	// TreeCompactionEnabled turns on the periodic compaction of frozen logs,
+	// see admin.FrozenTreeCompactor. It requires Registry.LogStorage.
+	TreeCompactionEnabled     bool
+	TreeCompactionMinInterval time.Duration
+
+	// ConfigureInterceptors, if set, is called with the server's interceptor
+	// chain (see DefaultInterceptorChain) before the server is created, to
+	// allow custom interceptors to be added, or stages to be replaced.
+	ConfigureInterceptors func(*interceptor.Chain)
 }
 
 // Run starts the configured server. Blocks until the server exits.
 func (m *Main) Run(ctx context.Context) error {
 	glog.CopyStandardLogTo("WARNING")
 
+	if m.TimeSource == nil {
+		m.TimeSource = util.SystemTimeSource{}
+	}
+
 	srv, err := m.newGRPCServer()
 	if err != nil {
 		glog.Exitf("Error creating gRPC server: %v", err)
@@ -95,7 +138,7 @@ func (m *Main) Run(ctx context.Context) error {
 	if err := m.RegisterServerFn(srv, m.Registry); err != nil {
 		return err
 	}
-	trillian.RegisterTrillianAdminServer(srv, admin.New(m.Registry, m.AllowedTreeTypes))
+	trillian.RegisterTrillianAdminServer(srv, admin.New(m.Registry, m.AllowedTreeTypes, m.TimeSource))
 	reflection.Register(srv)
 
 	if endpoint := m.HTTPEndpoint; endpoint != "" {
@@ -112,6 +155,10 @@ func (m *Main) Run(ctx context.Context) error {
 			switch {
 			case req.RequestURI == "/metrics":
 				promhttp.Handler().ServeHTTP(w, req)
+			case req.URL.Path == "/abuse" && m.AbuseDetector != nil:
+				m.AbuseDetector.ServeHTTP(w, req)
+			case req.URL.Path == "/statusz" && m.Status != nil:
+				m.Status.ServeHTTP(w, req)
 			default:
 				mux.ServeHTTP(w, req)
 			}
@@ -153,10 +200,32 @@ func (m *Main) Run(ctx context.Context) error {
 		}()
 	}
 
+	if m.TreeCompactionEnabled {
+		go func() {
+			glog.Info("Frozen tree compaction started")
+			c := admin.NewFrozenTreeCompactor(
+				m.Registry.AdminStorage,
+				m.Registry.LogStorage,
+				m.TreeCompactionMinInterval,
+				m.Registry.MetricFactory)
+			c.Run(ctx)
+		}()
+	}
+
+	if m.Accountant != nil {
+		go m.Accountant.Run(ctx)
+	}
+
 	if err := srv.Serve(lis); err != nil {
 		glog.Errorf("RPC server terminated: %v", err)
 	}
 
+	if m.Accountant != nil {
+		if err := m.Accountant.Close(ctx); err != nil {
+			glog.Errorf("Failed to record remaining API usage: %v", err)
+		}
+	}
+
 	glog.Infof("Stopping server, about to exit")
 	glog.Flush()
 
@@ -168,15 +237,28 @@ func (m *Main) Run(ctx context.Context) error {
 
 // newGRPCServer starts a new Trillian gRPC server.
 func (m *Main) newGRPCServer() (*grpc.Server, error) {
-	ts := util.SystemTimeSource{}
-	stats := monitoring.NewRPCStatsInterceptor(ts, m.StatsPrefix, m.Registry.MetricFactory)
-	ti := interceptor.New(
-		m.Registry.AdminStorage, m.Registry.QuotaManager, m.QuotaDryRun, m.Registry.MetricFactory)
-	netInterceptor := interceptor.Combine(stats.Interceptor(), interceptor.ErrorWrapper, ti.UnaryInterceptor)
-
-	serverOpts := []grpc.ServerOption{
-		grpc.UnaryInterceptor(netInterceptor),
+	chain := DefaultInterceptorChain(m.Registry, m.StatsPrefix, m.QuotaDryRun, m.QuotaCosts)
+	if m.Accountant != nil {
+		// Requests rejected by later stages are accounted for as errors.
+		chain.AddBefore(interceptor.StageTrillian, m.Accountant.UnaryInterceptor)
+	}
+	if m.AbuseDetector != nil {
+		// Throttled submissions are rejected before they're charged quota.
+		chain.AddBefore(interceptor.StageTrillian, m.AbuseDetector.UnaryInterceptor)
 	}
+	if m.Priority != nil {
+		// Requests are charged quota once they're admitted.
+		chain.AddBefore(interceptor.StageTrillian, m.Priority.UnaryInterceptor)
+	}
+	if m.ProofSigner != nil {
+		// Responses are signed as the handler and Trillian stage return them.
+		chain.AddAfter(interceptor.StageErrors, m.ProofSigner.UnaryInterceptor)
+	}
+	if m.ConfigureInterceptors != nil {
+		m.ConfigureInterceptors(chain)
+	}
+	serverOpts := chain.ServerOptions()
+	serverOpts = append(serverOpts, m.Connection.ServerOptions()...)
 
 	// Let credentials.NewServerTLSFromFile handle the error case when only one of the flags is set.
 	if m.TLSCertFile != "" || m.TLSKeyFile != "" {
@@ -192,6 +274,29 @@ func (m *Main) newGRPCServer() (*grpc.Server, error) {
 	return s, nil
 }
 
+// DefaultInterceptorChain returns the interceptor chain run by Trillian
+// servers, with the standard interceptor for each interceptor.Stage. RPC
+// metrics are only recorded if statsPrefix is non-empty. Requests are charged
+// quota tokens according to costs, which may be nil.
+func DefaultInterceptorChain(registry extension.Registry, statsPrefix string, quotaDryRun bool, costs interceptor.CostModel) *interceptor.Chain {
+	chain := interceptor.NewChain()
+	// The IDs of requests are available to all the stages, and their logs.
+	chain.AddBefore(interceptor.StageMetrics, interceptor.RequestInfo)
+	chain.AddStreamBefore(interceptor.StageMetrics, interceptor.RequestInfoStream)
+	if statsPrefix != "" {
+		stats := monitoring.NewRPCStatsInterceptor(util.SystemTimeSource{}, statsPrefix, registry.MetricFactory)
+		chain.Set(interceptor.StageMetrics, stats.Interceptor())
+	}
+	chain.Set(interceptor.StageErrors, interceptor.ErrorWrapper)
+	ti := interceptor.New(registry.AdminStorage, registry.QuotaManager, quotaDryRun, registry.MetricFactory)
+	ti.SetCostModel(costs)
+	chain.Set(interceptor.StageTrillian, ti.UnaryInterceptor)
+	// Stages only apply to unary RPCs, so the stream interceptor is inserted
+	// where the stage runs, ahead of any inserted later.
+	chain.AddStreamAfter(interceptor.StageTrillian, ti.StreamInterceptor)
+	return chain
+}
+
 // AnnounceSelf announces this binary's presence to etcd.  Returns a function that
 // should be called on process exit.
 // AnnounceSelf does nothing if client is nil.
@@ -15,6 +15,7 @@
 package server
 
 import (
+	"bytes"
 	"fmt"
 	"time"
 
@@ -24,6 +25,8 @@ import (
 	"github.com/google/trillian/merkle/hashers"
 	"github.com/google/trillian/storage"
 	"github.com/google/trillian/trees"
+	"github.com/google/trillian/util"
+	"github.com/google/trillian/util/requestinfo"
 
 	"github.com/golang/glog"
 	"github.com/golang/protobuf/ptypes/any"
@@ -42,12 +45,15 @@ const (
 
 // TrillianMapServer implements the RPC API defined in the proto
 type TrillianMapServer struct {
-	registry extension.Registry
+	registry   extension.Registry
+	timeSource util.TimeSource
+	batcher    *mapWriteBatcher
 }
 
-// NewTrillianMapServer creates a new RPC server backed by registry
-func NewTrillianMapServer(registry extension.Registry) *TrillianMapServer {
-	return &TrillianMapServer{registry}
+// NewTrillianMapServer creates a new RPC server backed by registry, which
+// timestamps map roots with timeSource.
+func NewTrillianMapServer(registry extension.Registry, timeSource util.TimeSource) *TrillianMapServer {
+	return &TrillianMapServer{registry: registry, timeSource: timeSource}
 }
 
 // IsHealthy returns nil if the server is healthy, error otherwise.
@@ -77,9 +83,9 @@ func (t *TrillianMapServer) getLeavesByRevision(ctx context.Context, mapID int64
 	}
 	ctx = trees.NewContext(ctx, tree)
 
-	tx, err := t.registry.MapStorage.SnapshotForTree(ctx, mapID)
+	tx, err := t.snapshotForTree(ctx, mapID)
 	if err != nil {
-		return nil, fmt.Errorf("could not create database snapshot: %v", err)
+		return nil, snapshotError(err)
 	}
 	defer tx.Close()
 
@@ -88,13 +94,13 @@ func (t *TrillianMapServer) getLeavesByRevision(ctx context.Context, mapID int64
 		// need to know the newest published revision
 		r, err := tx.LatestSignedMapRoot(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("could not fetch the latest SignedMapRoot: %v", err)
+			return nil, rootError(err, "could not fetch the latest SignedMapRoot")
 		}
 		root = &r
 	} else {
 		r, err := tx.GetSignedMapRoot(ctx, revision)
 		if err != nil {
-			return nil, fmt.Errorf("could not fetch SignedMapRoot %v: %v", revision, err)
+			return nil, rootError(err, fmt.Sprintf("could not fetch SignedMapRoot %v", revision))
 		}
 		root = &r
 	}
@@ -141,7 +147,7 @@ func (t *TrillianMapServer) getLeavesByRevision(ctx context.Context, mapID int64
 			Inclusion: proof,
 		})
 	}
-	glog.Infof("%v: wanted %v leaves, found %v", mapID, len(indices), found)
+	requestinfo.Infof(ctx, "%v: wanted %v leaves, found %v", mapID, len(indices), found)
 
 	if err := tx.Commit(); err != nil {
 		return nil, fmt.Errorf("could not commit db transaction: %v", err)
@@ -153,6 +159,221 @@ func (t *TrillianMapServer) getLeavesByRevision(ctx context.Context, mapID int64
 	}, nil
 }
 
+// GetLeafHistory implements the GetLeafHistory RPC method.
+func (t *TrillianMapServer) GetLeafHistory(ctx context.Context, req *trillian.GetMapLeafHistoryRequest) (*trillian.GetMapLeafHistoryResponse, error) {
+	if req.FirstRevision < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "first revision %d must be >= 0", req.FirstRevision)
+	}
+	if req.LastRevision < req.FirstRevision {
+		return nil, status.Errorf(codes.InvalidArgument, "last revision %d must be >= first revision %d", req.LastRevision, req.FirstRevision)
+	}
+	mapID, index := req.MapId, req.Index
+	tree, hasher, err := t.getTreeAndHasher(ctx, mapID, true /* readonly */)
+	if err != nil {
+		return nil, fmt.Errorf("could not get map %v: %v", mapID, err)
+	}
+	ctx = trees.NewContext(ctx, tree)
+	if got, want := len(index), hasher.Size(); got != want {
+		return nil, status.Errorf(codes.InvalidArgument, "index len(%x): %v, want %v", index, got, want)
+	}
+
+	tx, err := t.snapshotForTree(ctx, mapID)
+	if err != nil {
+		return nil, snapshotError(err)
+	}
+	defer tx.Close()
+
+	latest, err := tx.LatestSignedMapRoot(ctx)
+	if err != nil {
+		return nil, rootError(err, "could not fetch the latest SignedMapRoot")
+	}
+	if req.LastRevision > latest.MapRevision {
+		return nil, status.Errorf(codes.OutOfRange, "last revision %d is later than the latest revision %d", req.LastRevision, latest.MapRevision)
+	}
+
+	// The value at the first revision may have been set at any earlier
+	// revision, the later ones are read from the key's history.
+	revisions := []storage.MapLeafRevision{{Revision: req.FirstRevision}}
+	leaves, err := tx.Get(ctx, req.FirstRevision, [][]byte{index})
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch leaf %x: %v", index, err)
+	}
+	if len(leaves) == 1 {
+		revisions[0].Leaf = leaves[0]
+	} else {
+		// Empty leaf for proof of non-existence.
+		leafHash, err := hasher.HashLeaf(mapID, index, nil)
+		if err != nil {
+			return nil, fmt.Errorf("HashLeaf(nil): %v", err)
+		}
+		revisions[0].Leaf = trillian.MapLeaf{Index: index, LeafHash: leafHash}
+	}
+	if req.LastRevision > req.FirstRevision {
+		history, err := tx.GetLeafHistory(ctx, index, req.FirstRevision+1, req.LastRevision)
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch history of leaf %x: %v", index, err)
+		}
+		revisions = append(revisions, history...)
+	}
+
+	entries := make([]*trillian.MapLeafHistoryEntry, 0, len(revisions))
+	for i := range revisions {
+		rev := revisions[i].Revision
+		root, err := tx.GetSignedMapRoot(ctx, rev)
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch SignedMapRoot %v: %v", rev, err)
+		}
+		smtReader := merkle.NewSparseMerkleTreeReader(rev, hasher, tx)
+		proof, err := smtReader.InclusionProof(ctx, rev, index)
+		if err != nil {
+			return nil, fmt.Errorf("could not get inclusion proof for leaf %x at revision %d: %v", index, rev, err)
+		}
+		entries = append(entries, &trillian.MapLeafHistoryEntry{
+			MapRoot: &root,
+			LeafInclusion: &trillian.MapLeafInclusion{
+				Leaf:      &revisions[i].Leaf,
+				Inclusion: proof,
+			},
+		})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("could not commit db transaction: %v", err)
+	}
+	return &trillian.GetMapLeafHistoryResponse{Entries: entries}, nil
+}
+
+const (
+	// defaultMapDiffKeys is the number of keys GetMapDiff returns if the
+	// request doesn't specify it.
+	defaultMapDiffKeys = 100
+	// maxMapDiffKeys is the most keys GetMapDiff returns.
+	maxMapDiffKeys = 1000
+)
+
+// GetMapDiff implements the GetMapDiff RPC method.
+func (t *TrillianMapServer) GetMapDiff(ctx context.Context, req *trillian.GetMapDiffRequest) (*trillian.GetMapDiffResponse, error) {
+	if req.FromRevision < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "from revision %d must be >= 0", req.FromRevision)
+	}
+	if req.ToRevision <= req.FromRevision {
+		return nil, status.Errorf(codes.InvalidArgument, "to revision %d must be > from revision %d", req.ToRevision, req.FromRevision)
+	}
+	maxKeys := int(req.MaxKeys)
+	switch {
+	case maxKeys < 0 || maxKeys > maxMapDiffKeys:
+		return nil, status.Errorf(codes.InvalidArgument, "max keys %d must be between 0 and %d", maxKeys, maxMapDiffKeys)
+	case maxKeys == 0:
+		maxKeys = defaultMapDiffKeys
+	}
+	mapID := req.MapId
+	tree, hasher, err := t.getTreeAndHasher(ctx, mapID, true /* readonly */)
+	if err != nil {
+		return nil, fmt.Errorf("could not get map %v: %v", mapID, err)
+	}
+	ctx = trees.NewContext(ctx, tree)
+	var start []byte
+	if len(req.StartIndex) > 0 {
+		if got, want := len(req.StartIndex), hasher.Size(); got != want {
+			return nil, status.Errorf(codes.InvalidArgument, "start index len(%x): %v, want %v", req.StartIndex, got, want)
+		}
+		start = req.StartIndex
+	}
+
+	// Storage caches nodes per transaction, whatever their revision, so each
+	// revision is read through a transaction of its own.
+	fromTX, err := t.snapshotForTree(ctx, mapID)
+	if err != nil {
+		return nil, snapshotError(err)
+	}
+	defer fromTX.Close()
+	toTX, err := t.snapshotForTree(ctx, mapID)
+	if err != nil {
+		return nil, snapshotError(err)
+	}
+	defer toTX.Close()
+
+	latest, err := toTX.LatestSignedMapRoot(ctx)
+	if err != nil {
+		return nil, rootError(err, "could not fetch the latest SignedMapRoot")
+	}
+	if req.ToRevision > latest.MapRevision {
+		return nil, status.Errorf(codes.OutOfRange, "to revision %d is later than the latest revision %d", req.ToRevision, latest.MapRevision)
+	}
+	fromRoot, err := fromTX.GetSignedMapRoot(ctx, req.FromRevision)
+	if err != nil {
+		return nil, rootError(err, fmt.Sprintf("could not fetch SignedMapRoot %v", req.FromRevision))
+	}
+	toRoot, err := toTX.GetSignedMapRoot(ctx, req.ToRevision)
+	if err != nil {
+		return nil, rootError(err, fmt.Sprintf("could not fetch SignedMapRoot %v", req.ToRevision))
+	}
+
+	fromReader := merkle.NewSparseMerkleTreeReader(req.FromRevision, hasher, fromTX)
+	toReader := merkle.NewSparseMerkleTreeReader(req.ToRevision, hasher, toTX)
+	indices, next, err := merkle.ChangedIndices(ctx, fromReader, toReader, start, maxKeys)
+	if err != nil {
+		return nil, fmt.Errorf("could not compare revisions %d and %d: %v", req.FromRevision, req.ToRevision, err)
+	}
+
+	diffs := make([]*trillian.MapLeafDiff, 0, len(indices))
+	for _, index := range indices {
+		from, err := leafInclusion(ctx, fromTX, hasher, fromReader, mapID, req.FromRevision, index)
+		if err != nil {
+			return nil, err
+		}
+		to, err := leafInclusion(ctx, toTX, hasher, toReader, mapID, req.ToRevision, index)
+		if err != nil {
+			return nil, err
+		}
+		// Nodes can be rewritten with the same value, e.g. by setting a key to
+		// its current value, which isn't a change.
+		if bytes.Equal(from.Leaf.LeafValue, to.Leaf.LeafValue) && bytes.Equal(from.Leaf.ExtraData, to.Leaf.ExtraData) {
+			continue
+		}
+		diffs = append(diffs, &trillian.MapLeafDiff{Index: index, From: from, To: to})
+	}
+	glog.V(1).Infof("%v: %d keys changed between revisions %d and %d", mapID, len(diffs), req.FromRevision, req.ToRevision)
+
+	if err := fromTX.Commit(); err != nil {
+		return nil, fmt.Errorf("could not commit db transaction: %v", err)
+	}
+	if err := toTX.Commit(); err != nil {
+		return nil, fmt.Errorf("could not commit db transaction: %v", err)
+	}
+	return &trillian.GetMapDiffResponse{
+		FromRoot:  &fromRoot,
+		ToRoot:    &toRoot,
+		Diffs:     diffs,
+		NextIndex: next,
+	}, nil
+}
+
+// leafInclusion returns the value of the key index at revision rev, with its
+// inclusion proof. The leaf has no value if the key has none at rev.
+func leafInclusion(ctx context.Context, tx storage.ReadOnlyMapTreeTX, hasher hashers.MapHasher, smtReader *merkle.SparseMerkleTreeReader, mapID, rev int64, index []byte) (*trillian.MapLeafInclusion, error) {
+	leaves, err := tx.Get(ctx, rev, [][]byte{index})
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch leaf %x: %v", index, err)
+	}
+	var leaf *trillian.MapLeaf
+	if len(leaves) == 1 {
+		leaf = &leaves[0]
+	} else {
+		// Empty leaf for proof of non-existence.
+		leafHash, err := hasher.HashLeaf(mapID, index, nil)
+		if err != nil {
+			return nil, fmt.Errorf("HashLeaf(nil): %v", err)
+		}
+		leaf = &trillian.MapLeaf{Index: index, LeafHash: leafHash}
+	}
+	proof, err := smtReader.InclusionProof(ctx, rev, index)
+	if err != nil {
+		return nil, fmt.Errorf("could not get inclusion proof for leaf %x at revision %d: %v", index, rev, err)
+	}
+	return &trillian.MapLeafInclusion{Leaf: leaf, Inclusion: proof}, nil
+}
+
 // SetLeaves implements the SetLeaves RPC method.
 func (t *TrillianMapServer) SetLeaves(ctx context.Context, req *trillian.SetMapLeavesRequest) (*trillian.SetMapLeavesResponse, error) {
 	mapID := req.MapId
@@ -162,25 +383,52 @@ func (t *TrillianMapServer) SetLeaves(ctx context.Context, req *trillian.SetMapL
 	}
 	ctx = trees.NewContext(ctx, tree)
 
+	for _, l := range req.Leaves {
+		if got, want := len(l.Index), hasher.Size(); got != want {
+			return nil, status.Errorf(codes.InvalidArgument,
+				"len(%x): %v, want %v", l.Index, got, want)
+		}
+	}
+	if err := t.verifySourceLogRoot(ctx, req.Metadata); err != nil {
+		return nil, err
+	}
+
+	var newRoot *trillian.SignedMapRoot
+	if t.batcher != nil {
+		newRoot, err = t.batcher.add(ctx, tree, hasher, req.Leaves, req.Metadata)
+	} else {
+		newRoot, err = t.writeLeaves(ctx, tree, hasher, req.Leaves, req.Metadata)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &trillian.SetMapLeavesResponse{MapRoot: newRoot}, nil
+}
+
+// writeLeaves writes leaves to a new revision of the map, and returns its
+// root. The indices of leaves must have been validated.
+func (t *TrillianMapServer) writeLeaves(ctx context.Context, tree *trillian.Tree, hasher hashers.MapHasher, leaves []*trillian.MapLeaf, meta *any.Any) (*trillian.SignedMapRoot, error) {
+	mapID := tree.TreeId
 	var newRoot *trillian.SignedMapRoot
-	err = t.registry.MapStorage.ReadWriteTransaction(ctx, req.MapId, func(ctx context.Context, tx storage.MapTreeTX) error {
+	err := t.registry.MapStorage.ReadWriteTransaction(ctx, mapID, func(ctx context.Context, tx storage.MapTreeTX) error {
 		glog.V(2).Infof("%v: Writing at revision %v", mapID, tx.WriteRevision())
+		if tx.WriteRevision() < 1 {
+			// Revision 0 is written by InitMap, so there's no root to
+			// build on yet.
+			return storage.ErrTreeNeedsInit
+		}
 		smtWriter, err := merkle.NewSparseMerkleTreeWriter(
 			ctx,
-			req.MapId,
+			mapID,
 			tx.WriteRevision(),
 			hasher, func(ctx context.Context, f func(context.Context, storage.MapTreeTX) error) error {
-				return t.registry.MapStorage.ReadWriteTransaction(ctx, req.MapId, f)
+				return t.registry.MapStorage.ReadWriteTransaction(ctx, mapID, f)
 			})
 		if err != nil {
 			return err
 		}
 
-		for _, l := range req.Leaves {
-			if got, want := len(l.Index), hasher.Size(); got != want {
-				return status.Errorf(codes.InvalidArgument,
-					"len(%x): %v, want %v", l.Index, got, want)
-			}
+		for _, l := range leaves {
 			if l.LeafValue == nil {
 				// Leaves are empty by default. Do not allow clients to store
 				// empty leaf values as this messes up the calculation of empty
@@ -212,7 +460,7 @@ func (t *TrillianMapServer) SetLeaves(ctx context.Context, req *trillian.SetMapL
 			return fmt.Errorf("CalculateRoot(): %v", err)
 		}
 
-		newRoot, err = t.makeSignedMapRoot(ctx, tree, time.Now(), rootHash, req.MapId, tx.WriteRevision(), req.Metadata)
+		newRoot, err = t.makeSignedMapRoot(ctx, tree, t.timeSource.Now(), rootHash, mapID, tx.WriteRevision(), meta)
 		if err != nil {
 			return fmt.Errorf("makeSignedMapRoot(): %v", err)
 		}
@@ -227,7 +475,7 @@ func (t *TrillianMapServer) SetLeaves(ctx context.Context, req *trillian.SetMapL
 	if err != nil {
 		return nil, err
 	}
-	return &trillian.SetMapLeavesResponse{MapRoot: newRoot}, nil
+	return newRoot, nil
 }
 
 func (t *TrillianMapServer) makeSignedMapRoot(ctx context.Context, tree *trillian.Tree, smrTs time.Time,
@@ -247,13 +495,16 @@ func (t *TrillianMapServer) makeSignedMapRoot(ctx context.Context, tree *trillia
 	if err != nil {
 		return nil, fmt.Errorf("SignMapRoot(): %v", err)
 	}
+	if smr.AdditionalSignatures, err = signer.AdditionalMapRootSignatures(smr); err != nil {
+		return nil, fmt.Errorf("AdditionalMapRootSignatures(): %v", err)
+	}
 	smr.Signature = sig
 	return smr, nil
 }
 
 // GetSignedMapRoot implements the GetSignedMapRoot RPC method.
 func (t *TrillianMapServer) GetSignedMapRoot(ctx context.Context, req *trillian.GetSignedMapRootRequest) (*trillian.GetSignedMapRootResponse, error) {
-	tx, err := t.registry.MapStorage.SnapshotForTree(ctx, req.MapId)
+	tx, err := t.snapshotForTree(ctx, req.MapId)
 	if err != nil {
 		return nil, err
 	}
@@ -265,7 +516,7 @@ func (t *TrillianMapServer) GetSignedMapRoot(ctx context.Context, req *trillian.
 	}
 
 	if err := tx.Commit(); err != nil {
-		glog.Warningf("%v: Commit failed for GetSignedMapRoot: %v", req.MapId, err)
+		requestinfo.Warningf(ctx, "%v: Commit failed for GetSignedMapRoot: %v", req.MapId, err)
 		return nil, err
 	}
 
@@ -280,7 +531,7 @@ func (t *TrillianMapServer) GetSignedMapRootByRevision(ctx context.Context, req
 	if req.Revision < 0 {
 		return nil, fmt.Errorf("map revision %d must be >= 0", req.Revision)
 	}
-	tx, err := t.registry.MapStorage.SnapshotForTree(ctx, req.MapId)
+	tx, err := t.snapshotForTree(ctx, req.MapId)
 	if err != nil {
 		return nil, err
 	}
@@ -292,7 +543,7 @@ func (t *TrillianMapServer) GetSignedMapRootByRevision(ctx context.Context, req
 	}
 
 	if err := tx.Commit(); err != nil {
-		glog.Warningf("%v: Commit failed for GetSignedMapRootByRevision: %v", req.MapId, err)
+		requestinfo.Warningf(ctx, "%v: Commit failed for GetSignedMapRootByRevision: %v", req.MapId, err)
 		return nil, err
 	}
 
@@ -301,6 +552,38 @@ func (t *TrillianMapServer) GetSignedMapRootByRevision(ctx context.Context, req
 	}, nil
 }
 
+// snapshotForTree returns a read-only transaction on the map mapID. Storage
+// may return a transaction along with ErrTreeNeedsInit, which is closed here
+// rather than leaked.
+func (t *TrillianMapServer) snapshotForTree(ctx context.Context, mapID int64) (storage.ReadOnlyMapTreeTX, error) {
+	tx, err := t.registry.MapStorage.SnapshotForTree(ctx, mapID)
+	if err != nil {
+		if err == storage.ErrTreeNeedsInit && tx != nil {
+			tx.Close()
+		}
+		return nil, err
+	}
+	return tx, nil
+}
+
+// snapshotError returns the error of a failed snapshotForTree, keeping
+// ErrTreeNeedsInit as is so that its status reaches the client.
+func snapshotError(err error) error {
+	if err == storage.ErrTreeNeedsInit {
+		return err
+	}
+	return fmt.Errorf("could not create database snapshot: %v", err)
+}
+
+// rootError returns the error of a failed root read prefixed by msg, keeping
+// ErrTreeNeedsInit as is so that its status reaches the client.
+func rootError(err error, msg string) error {
+	if err == storage.ErrTreeNeedsInit {
+		return err
+	}
+	return fmt.Errorf("%s: %v", msg, err)
+}
+
 func (t *TrillianMapServer) getTreeAndHasher(ctx context.Context, treeID int64, readonly bool) (*trillian.Tree, hashers.MapHasher, error) {
 	tree, err := trees.GetTree(
 		ctx,
@@ -342,7 +625,7 @@ func (t *TrillianMapServer) InitMap(ctx context.Context, req *trillian.InitMapRe
 
 		glog.V(2).Infof("%v: Need to init map root revision 0", mapID)
 		rootHash := hasher.HashEmpty(mapID, make([]byte, hasher.Size()), hasher.BitLen())
-		rev0Root, err = t.makeSignedMapRoot(ctx, tree, time.Now(), rootHash, mapID, 0 /*revision*/, nil /* metadata */)
+		rev0Root, err = t.makeSignedMapRoot(ctx, tree, t.timeSource.Now(), rootHash, mapID, 0 /*revision*/, nil /* metadata */)
 		if err != nil {
 			return fmt.Errorf("makeSignedMapRoot(): %v", err)
 		}
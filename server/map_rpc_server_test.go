@@ -15,17 +15,30 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"crypto"
 	"errors"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/golang/protobuf/proto"
 	"github.com/google/trillian"
+	"github.com/google/trillian/client"
+	"github.com/google/trillian/crypto/keys"
+	"github.com/google/trillian/crypto/keys/der"
+	"github.com/google/trillian/crypto/keyspb"
 	"github.com/google/trillian/crypto/sigpb"
 	"github.com/google/trillian/extension"
+	"github.com/google/trillian/merkle"
+	"github.com/google/trillian/merkle/maphasher"
 	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/mysql"
+	"github.com/google/trillian/storage/testdb"
 	stestonly "github.com/google/trillian/storage/testonly"
+	"github.com/google/trillian/util"
 	"github.com/kylelemons/godebug/pretty"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -80,7 +93,7 @@ func TestIsHealthy(t *testing.T) {
 		server := NewTrillianMapServer(extension.Registry{
 			AdminStorage: fakeAdminStorageForMap(ctrl, 1, mapID1),
 			MapStorage:   fakeStorage,
-		})
+		}, util.SystemTimeSource{})
 
 		wantErr := test.accessibleErr != nil
 		err := server.IsHealthy()
@@ -127,7 +140,7 @@ func TestInitMap(t *testing.T) {
 			server := NewTrillianMapServer(extension.Registry{
 				AdminStorage: fakeAdminStorageForMap(ctrl, 2, mapID1),
 				MapStorage:   fakeStorage,
-			})
+			}, util.SystemTimeSource{})
 
 			c, err := server.InitMap(ctx, &trillian.InitMapRequest{
 				MapId: mapID1,
@@ -160,15 +173,15 @@ func TestGetSignedMapRoot_NotInitialised(t *testing.T) {
 	mockTX := storage.NewMockMapTreeTX(ctrl)
 	server := NewTrillianMapServer(extension.Registry{
 		MapStorage: fakeStorage,
-	})
+	}, util.SystemTimeSource{})
 	fakeStorage.EXPECT().SnapshotForTree(gomock.Any(), gomock.Any()).Return(mockTX, nil)
 	mockTX.EXPECT().LatestSignedMapRoot(gomock.Any()).Return(trillian.SignedMapRoot{}, storage.ErrTreeNeedsInit)
 	mockTX.EXPECT().Close()
 
 	smrResp, err := server.GetSignedMapRoot(ctx, &trillian.GetSignedMapRootRequest{})
 
-	if err == nil {
-		t.Errorf("GetSignedMapRoot()=_, nil want err")
+	if !storage.IsTreeNeedsInit(err) {
+		t.Errorf("GetSignedMapRoot()=_, %v want a tree needs init error", err)
 	}
 	if smrResp != nil {
 		t.Errorf("GetSignedMapRoot()=%v, _ want nil", smrResp)
@@ -228,7 +241,7 @@ func TestGetSignedMapRoot(t *testing.T) {
 			server := NewTrillianMapServer(extension.Registry{
 				AdminStorage: adminStorage,
 				MapStorage:   fakeStorage,
-			})
+			}, util.SystemTimeSource{})
 
 			smrResp, err := server.GetSignedMapRoot(ctx, test.req)
 
@@ -257,7 +270,7 @@ func TestGetSignedMapRootByRevision_NotInitialised(t *testing.T) {
 	mockTX := storage.NewMockMapTreeTX(ctrl)
 	server := NewTrillianMapServer(extension.Registry{
 		MapStorage: fakeStorage,
-	})
+	}, util.SystemTimeSource{})
 	fakeStorage.EXPECT().SnapshotForTree(gomock.Any(), gomock.Any()).Return(mockTX, nil)
 	mockTX.EXPECT().GetSignedMapRoot(gomock.Any(), gomock.Any()).Return(trillian.SignedMapRoot{}, storage.ErrTreeNeedsInit)
 	mockTX.EXPECT().Close()
@@ -331,7 +344,7 @@ func TestGetSignedMapRootByRevision(t *testing.T) {
 			server := NewTrillianMapServer(extension.Registry{
 				AdminStorage: adminStorage,
 				MapStorage:   fakeStorage,
-			})
+			}, util.SystemTimeSource{})
 
 			smrResp, err := server.GetSignedMapRootByRevision(ctx, test.req)
 
@@ -365,3 +378,263 @@ func fakeAdminStorageForMap(ctrl *gomock.Controller, times int, treeID int64) st
 
 	return adminStorage
 }
+
+func TestGetLeafHistory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ctx := context.Background()
+
+	index := make([]byte, 32)
+	leafAt := func(rev int64) storage.MapLeafRevision {
+		return storage.MapLeafRevision{
+			Revision: rev,
+			Leaf:     trillian.MapLeaf{Index: index, LeafValue: []byte{byte(rev)}},
+		}
+	}
+	mapRootAt := func(rev int64) trillian.SignedMapRoot {
+		r := signedMapRootID1Rev0
+		r.MapRevision = rev
+		return r
+	}
+
+	tests := []struct {
+		desc      string
+		req       *trillian.GetMapLeafHistoryRequest
+		first     []trillian.MapLeaf
+		history   []storage.MapLeafRevision
+		wantRevs  []int64
+		wantCode  codes.Code
+		noStorage bool
+	}{
+		{
+			desc:      "negative first revision",
+			req:       &trillian.GetMapLeafHistoryRequest{MapId: mapID1, Index: index, FirstRevision: -1, LastRevision: 1},
+			wantCode:  codes.InvalidArgument,
+			noStorage: true,
+		},
+		{
+			desc:      "last before first",
+			req:       &trillian.GetMapLeafHistoryRequest{MapId: mapID1, Index: index, FirstRevision: 2, LastRevision: 1},
+			wantCode:  codes.InvalidArgument,
+			noStorage: true,
+		},
+		{
+			desc:      "short index",
+			req:       &trillian.GetMapLeafHistoryRequest{MapId: mapID1, Index: []byte("short"), FirstRevision: 1, LastRevision: 2},
+			wantCode:  codes.InvalidArgument,
+			noStorage: true,
+		},
+		{
+			desc:     "future revision",
+			req:      &trillian.GetMapLeafHistoryRequest{MapId: mapID1, Index: index, FirstRevision: 1, LastRevision: 6},
+			wantCode: codes.OutOfRange,
+		},
+		{
+			desc:     "single revision",
+			req:      &trillian.GetMapLeafHistoryRequest{MapId: mapID1, Index: index, FirstRevision: 2, LastRevision: 2},
+			first:    []trillian.MapLeaf{leafAt(1).Leaf},
+			wantRevs: []int64{2},
+		},
+		{
+			desc:     "absent then set",
+			req:      &trillian.GetMapLeafHistoryRequest{MapId: mapID1, Index: index, FirstRevision: 0, LastRevision: 5},
+			history:  []storage.MapLeafRevision{leafAt(2), leafAt(5)},
+			wantRevs: []int64{0, 2, 5},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			adminStorage := fakeAdminStorageForMap(ctrl, 1, mapID1)
+			fakeStorage := storage.NewMockMapStorage(ctrl)
+			mockTx := storage.NewMockMapTreeTX(ctrl)
+
+			if !test.noStorage {
+				fakeStorage.EXPECT().SnapshotForTree(gomock.Any(), mapID1).Return(mockTx, nil)
+				mockTx.EXPECT().LatestSignedMapRoot(gomock.Any()).Return(mapRootAt(5), nil)
+				if test.wantCode == codes.OK {
+					mockTx.EXPECT().Get(gomock.Any(), test.req.FirstRevision, [][]byte{index}).Return(test.first, nil)
+					if test.req.LastRevision > test.req.FirstRevision {
+						mockTx.EXPECT().GetLeafHistory(gomock.Any(), index, test.req.FirstRevision+1, test.req.LastRevision).Return(test.history, nil)
+					}
+					for _, rev := range test.wantRevs {
+						mockTx.EXPECT().GetSignedMapRoot(gomock.Any(), rev).Return(mapRootAt(rev), nil)
+						mockTx.EXPECT().GetMerkleNodes(gomock.Any(), rev, gomock.Any()).Return(nil, nil)
+					}
+					mockTx.EXPECT().Commit().Return(nil)
+				}
+				mockTx.EXPECT().Close().Return(nil)
+				mockTx.EXPECT().IsOpen().AnyTimes().Return(false)
+			}
+
+			server := NewTrillianMapServer(extension.Registry{
+				AdminStorage: adminStorage,
+				MapStorage:   fakeStorage,
+			}, util.SystemTimeSource{})
+
+			resp, err := server.GetLeafHistory(ctx, test.req)
+			if got, want := status.Code(err), test.wantCode; got != want {
+				t.Fatalf("GetLeafHistory()=_, %v, want code %v", err, want)
+			}
+			if err != nil {
+				return
+			}
+			if got, want := len(resp.Entries), len(test.wantRevs); got != want {
+				t.Fatalf("GetLeafHistory(): %d entries, want %d", got, want)
+			}
+			for i, e := range resp.Entries {
+				rev := test.wantRevs[i]
+				if got, want := e.MapRoot.MapRevision, rev; got != want {
+					t.Errorf("Entries[%d].MapRoot.MapRevision: %d, want %d", i, got, want)
+				}
+				if got, want := len(e.LeafInclusion.Inclusion), 256; got != want {
+					t.Errorf("Entries[%d]: %d proof hashes, want %d", i, got, want)
+				}
+				wantValue := []byte{byte(rev)}
+				if i == 0 {
+					wantValue = nil
+					if len(test.first) == 1 {
+						wantValue = test.first[0].LeafValue
+					}
+				}
+				if got := e.LeafInclusion.Leaf.LeafValue; !bytes.Equal(got, wantValue) {
+					t.Errorf("Entries[%d].Leaf.LeafValue: %x, want %x", i, got, wantValue)
+				}
+				if got := e.LeafInclusion.Leaf.Index; !bytes.Equal(got, index) {
+					t.Errorf("Entries[%d].Leaf.Index: %x, want %x", i, got, index)
+				}
+			}
+		})
+	}
+}
+
+// writeMapRevision writes leaves to a new revision of the initialised map
+// treeID, in a single transaction. Unlike TrillianMapServer.SetLeaves it works
+// on SQLite, which doesn't allow concurrent write transactions. The root isn't
+// signed.
+func writeMapRevision(ctx context.Context, t *testing.T, ms storage.MapStorage, treeID int64, leaves []*trillian.MapLeaf) {
+	t.Helper()
+	hasher := maphasher.Default
+	if err := ms.ReadWriteTransaction(ctx, treeID, func(ctx context.Context, tx storage.MapTreeTX) error {
+		w, err := merkle.NewSparseMerkleTreeWriter(ctx, treeID, tx.WriteRevision(), hasher,
+			func(ctx context.Context, f func(context.Context, storage.MapTreeTX) error) error {
+				return f(ctx, tx)
+			})
+		if err != nil {
+			return err
+		}
+		for _, l := range leaves {
+			if l.LeafHash, err = hasher.HashLeaf(treeID, l.Index, l.LeafValue); err != nil {
+				return err
+			}
+			if err := tx.Set(ctx, l.Index, *l); err != nil {
+				return err
+			}
+			if err := w.SetLeaves(ctx, []merkle.HashKeyValue{{HashedKey: l.Index, HashedValue: l.LeafHash}}); err != nil {
+				return err
+			}
+		}
+		root, err := w.CalculateRoot()
+		if err != nil {
+			return err
+		}
+		return tx.StoreSignedMapRoot(ctx, trillian.SignedMapRoot{MapId: treeID, MapRevision: tx.WriteRevision(), RootHash: root, TimestampNanos: time.Now().UnixNano(), Signature: &sigpb.DigitallySigned{}})
+	}); err != nil {
+		t.Fatalf("Failed to write map revision: %v", err)
+	}
+}
+
+func TestGetMapDiff(t *testing.T) {
+	ctx := context.Background()
+	// Other tests unregister the handler of the map's private key.
+	keys.RegisterHandler(&keyspb.PrivateKey{}, func(ctx context.Context, pb proto.Message) (crypto.Signer, error) {
+		return der.FromProto(pb.(*keyspb.PrivateKey))
+	})
+
+	db, err := testdb.NewTrillianDB(ctx)
+	if err != nil {
+		t.Fatalf("NewTrillianDB(): %v", err)
+	}
+	as, ms := mysql.NewAdminStorage(db), mysql.NewMapStorage(db)
+	server := NewTrillianMapServer(extension.Registry{AdminStorage: as, MapStorage: ms}, util.SystemTimeSource{})
+	tree, err := storage.CreateTree(ctx, as, stestonly.MapTree)
+	if err != nil {
+		t.Fatalf("CreateTree(): %v", err)
+	}
+	if _, err := server.InitMap(ctx, &trillian.InitMapRequest{MapId: tree.TreeId}); err != nil {
+		t.Fatalf("InitMap(): %v", err)
+	}
+	verifier, err := client.NewMapVerifierFromTree(tree)
+	if err != nil {
+		t.Fatalf("NewMapVerifierFromTree(): %v", err)
+	}
+
+	key := func(b byte) []byte {
+		index := make([]byte, 32)
+		index[0], index[31] = b, b
+		return index
+	}
+	// The first index of the branch of the key 0x81...81 below 0x80...80.
+	branch81 := make([]byte, 32)
+	branch81[0] = 0x81
+	leaf := func(b byte, value string) *trillian.MapLeaf {
+		return &trillian.MapLeaf{Index: key(b), LeafValue: []byte(value)}
+	}
+	for _, batch := range [][]*trillian.MapLeaf{
+		{leaf(0x10, "a1"), leaf(0x80, "b1"), leaf(0x81, "c1")}, // Revision 1.
+		{leaf(0x80, "b2"), leaf(0xff, "d2")},                   // Revision 2.
+		{leaf(0x81, "c1")},                                     // Revision 3, sets a key to its value.
+	} {
+		writeMapRevision(ctx, t, ms, tree.TreeId, batch)
+	}
+
+	for _, test := range []struct {
+		desc     string
+		req      *trillian.GetMapDiffRequest
+		want     [][]byte
+		wantNext []byte
+		wantCode codes.Code
+	}{
+		{desc: "oneRevision", req: &trillian.GetMapDiffRequest{FromRevision: 1, ToRevision: 2}, want: [][]byte{key(0x80), key(0xff)}},
+		{desc: "fromEmpty", req: &trillian.GetMapDiffRequest{FromRevision: 0, ToRevision: 2}, want: [][]byte{key(0x10), key(0x80), key(0x81), key(0xff)}},
+		{desc: "unchangedValue", req: &trillian.GetMapDiffRequest{FromRevision: 2, ToRevision: 3}},
+		{desc: "firstPage", req: &trillian.GetMapDiffRequest{FromRevision: 0, ToRevision: 3, MaxKeys: 2}, want: [][]byte{key(0x10), key(0x80)}, wantNext: branch81},
+		{desc: "nextPage", req: &trillian.GetMapDiffRequest{FromRevision: 0, ToRevision: 3, MaxKeys: 2, StartIndex: branch81}, want: [][]byte{key(0x81), key(0xff)}},
+		{desc: "startBetweenKeys", req: &trillian.GetMapDiffRequest{FromRevision: 0, ToRevision: 3, StartIndex: key(0x11)}, want: [][]byte{key(0x80), key(0x81), key(0xff)}},
+		{desc: "negativeRevision", req: &trillian.GetMapDiffRequest{FromRevision: -1, ToRevision: 2}, wantCode: codes.InvalidArgument},
+		{desc: "sameRevision", req: &trillian.GetMapDiffRequest{FromRevision: 2, ToRevision: 2}, wantCode: codes.InvalidArgument},
+		{desc: "tooManyKeys", req: &trillian.GetMapDiffRequest{FromRevision: 1, ToRevision: 2, MaxKeys: maxMapDiffKeys + 1}, wantCode: codes.InvalidArgument},
+		{desc: "shortStartIndex", req: &trillian.GetMapDiffRequest{FromRevision: 1, ToRevision: 2, StartIndex: []byte("short")}, wantCode: codes.InvalidArgument},
+		{desc: "futureRevision", req: &trillian.GetMapDiffRequest{FromRevision: 1, ToRevision: 4}, wantCode: codes.OutOfRange},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			test.req.MapId = tree.TreeId
+			resp, err := server.GetMapDiff(ctx, test.req)
+			if got, want := status.Code(err), test.wantCode; got != want {
+				t.Fatalf("GetMapDiff()=_, %v, want code %v", err, want)
+			}
+			if err != nil {
+				return
+			}
+			var got [][]byte
+			for _, d := range resp.Diffs {
+				got = append(got, d.Index)
+				if err := verifier.VerifyMapLeafInclusion(resp.FromRoot, d.From); err != nil {
+					t.Errorf("VerifyMapLeafInclusion(from %x): %v", d.Index, err)
+				}
+				if err := verifier.VerifyMapLeafInclusion(resp.ToRoot, d.To); err != nil {
+					t.Errorf("VerifyMapLeafInclusion(to %x): %v", d.Index, err)
+				}
+				if bytes.Equal(d.From.Leaf.LeafValue, d.To.Leaf.LeafValue) {
+					t.Errorf("Diff %x has the same value %q at both revisions", d.Index, d.To.Leaf.LeafValue)
+				}
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("GetMapDiff() changed keys: %x, want %x", got, test.want)
+			}
+			if !bytes.Equal(resp.NextIndex, test.wantNext) {
+				t.Errorf("GetMapDiff().NextIndex: %x, want %x", resp.NextIndex, test.wantNext)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/google/trillian"
+	"github.com/google/trillian/merkle/hashers"
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/trees"
+	"golang.org/x/net/context"
+)
+
+var (
+	// MapWriteBatchWindow is a flag specifying the Window of the map server's
+	// WriteBatching.
+	MapWriteBatchWindow = flag.Duration("map_write_batch_window", 0, "If positive, SetLeaves requests to a map arriving within this long of the first pending one are merged into a single map revision")
+	// MapWriteBatchMaxLeaves is a flag specifying the MaxLeaves of the map
+	// server's WriteBatching.
+	MapWriteBatchMaxLeaves = flag.Int("map_write_batch_max_leaves", 1000, "Number of leaves at which merged SetLeaves requests are written without waiting for the end of --map_write_batch_window")
+
+	mapWriteBatches         monitoring.Counter
+	mapWriteBatchedRequests monitoring.Counter
+	batchMetricsOnce        sync.Once
+)
+
+func initBatchMetrics(mf monitoring.MetricFactory) {
+	if mf == nil {
+		mf = monitoring.InertMetricFactory{}
+	}
+	batchMetricsOnce.Do(func() {
+		mapWriteBatches = mf.NewCounter("map_write_batches", "Number of map revisions written from batches of SetLeaves requests", "mapid")
+		mapWriteBatchedRequests = mf.NewCounter("map_write_batched_requests", "Number of SetLeaves requests merged into batches", "mapid")
+	})
+}
+
+// WriteBatching configures the merging of SetLeaves requests which arrive
+// close together into a single map revision, which reduces the number of
+// revisions written by chatty map personalities.
+type WriteBatching struct {
+	// Window is the longest a SetLeaves request waits for others to be merged
+	// with. Zero disables batching.
+	Window time.Duration
+	// MaxLeaves is the number of leaves at which a batch is written without
+	// waiting for the end of Window. Zero means no limit.
+	MaxLeaves int
+}
+
+// WriteBatchingFromFlags returns the WriteBatching specified by flags.
+func WriteBatchingFromFlags() (WriteBatching, error) {
+	b := WriteBatching{Window: *MapWriteBatchWindow, MaxLeaves: *MapWriteBatchMaxLeaves}
+	if b.Window < 0 {
+		return WriteBatching{}, fmt.Errorf("--map_write_batch_window must not be negative, got %v", b.Window)
+	}
+	if b.MaxLeaves < 0 {
+		return WriteBatching{}, fmt.Errorf("--map_write_batch_max_leaves must not be negative, got %v", b.MaxLeaves)
+	}
+	return b, nil
+}
+
+// SetWriteBatching sets how SetLeaves requests are merged into map revisions.
+// The server writes a revision per request unless b.Window is positive.
+func (t *TrillianMapServer) SetWriteBatching(b WriteBatching) {
+	if b.Window <= 0 {
+		t.batcher = nil
+		return
+	}
+	initBatchMetrics(t.registry.MetricFactory)
+	t.batcher = &mapWriteBatcher{
+		opts:    b,
+		write:   t.writeLeaves,
+		pending: make(map[int64]*mapWriteBatch),
+	}
+}
+
+// mapWriteBatcher merges the leaves of SetLeaves requests to a map until its
+// window elapses or enough leaves accumulate, and writes them as one revision.
+type mapWriteBatcher struct {
+	opts  WriteBatching
+	write func(ctx context.Context, tree *trillian.Tree, hasher hashers.MapHasher, leaves []*trillian.MapLeaf, meta *any.Any) (*trillian.SignedMapRoot, error)
+
+	mu      sync.Mutex
+	pending map[int64]*mapWriteBatch
+}
+
+// mapWriteBatch holds the merged requests waiting for a revision of a map.
+type mapWriteBatch struct {
+	tree     *trillian.Tree
+	hasher   hashers.MapHasher
+	leaves   []*trillian.MapLeaf
+	indices  map[string]int // Index of each leaf in leaves.
+	meta     *any.Any
+	requests int
+	timer    *time.Timer
+
+	done chan struct{}
+	root *trillian.SignedMapRoot
+	err  error
+}
+
+// add merges leaves into the pending batch of tree, and returns the root of
+// the revision they're written in. Leaves override the leaves with the same
+// index of earlier requests, and meta, if set, the metadata of the revision.
+func (b *mapWriteBatcher) add(ctx context.Context, tree *trillian.Tree, hasher hashers.MapHasher, leaves []*trillian.MapLeaf, meta *any.Any) (*trillian.SignedMapRoot, error) {
+	b.mu.Lock()
+	batch, ok := b.pending[tree.TreeId]
+	if !ok {
+		batch = &mapWriteBatch{
+			tree:    tree,
+			hasher:  hasher,
+			indices: make(map[string]int),
+			done:    make(chan struct{}),
+		}
+		b.pending[tree.TreeId] = batch
+		batch.timer = time.AfterFunc(b.opts.Window, func() { b.flush(batch) })
+	}
+	for _, l := range leaves {
+		if i, ok := batch.indices[string(l.Index)]; ok {
+			batch.leaves[i] = l
+			continue
+		}
+		batch.indices[string(l.Index)] = len(batch.leaves)
+		batch.leaves = append(batch.leaves, l)
+	}
+	if meta != nil {
+		batch.meta = meta
+	}
+	batch.requests++
+	full := b.opts.MaxLeaves > 0 && len(batch.leaves) >= b.opts.MaxLeaves
+	b.mu.Unlock()
+
+	if full && batch.timer.Stop() {
+		go b.flush(batch)
+	}
+
+	select {
+	case <-batch.done:
+		return batch.root, batch.err
+	case <-ctx.Done():
+		// The leaves may still be written, but the caller won't know.
+		return nil, ctx.Err()
+	}
+}
+
+// flush writes batch, which must be called once per batch.
+func (b *mapWriteBatcher) flush(batch *mapWriteBatch) {
+	mapID := batch.tree.TreeId
+	b.mu.Lock()
+	if b.pending[mapID] == batch {
+		delete(b.pending, mapID)
+	}
+	b.mu.Unlock()
+
+	// The batch outlives the requests in it, so it's written with a context
+	// of its own.
+	ctx := trees.NewContext(context.Background(), batch.tree)
+	batch.root, batch.err = b.write(ctx, batch.tree, batch.hasher, batch.leaves, batch.meta)
+	label := strconv.FormatInt(mapID, 10)
+	mapWriteBatches.Inc(label)
+	mapWriteBatchedRequests.Add(float64(batch.requests), label)
+	close(batch.done)
+}
@@ -0,0 +1,189 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/google/trillian"
+	"github.com/google/trillian/extension"
+	"github.com/google/trillian/merkle/hashers"
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/util"
+)
+
+// fakeMapWriter records the writes of a mapWriteBatcher.
+type fakeMapWriter struct {
+	mu     sync.Mutex
+	writes [][]*trillian.MapLeaf
+	metas  []*any.Any
+	err    error
+}
+
+func (f *fakeMapWriter) write(ctx context.Context, tree *trillian.Tree, hasher hashers.MapHasher, leaves []*trillian.MapLeaf, meta *any.Any) (*trillian.SignedMapRoot, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.writes = append(f.writes, leaves)
+	f.metas = append(f.metas, meta)
+	return &trillian.SignedMapRoot{MapId: tree.TreeId, MapRevision: int64(len(f.writes))}, nil
+}
+
+func newTestBatcher(opts WriteBatching, f *fakeMapWriter) *mapWriteBatcher {
+	initBatchMetrics(monitoring.InertMetricFactory{})
+	return &mapWriteBatcher{opts: opts, write: f.write, pending: make(map[int64]*mapWriteBatch)}
+}
+
+// addAll adds each set of leaves in a request of its own, concurrently, and
+// returns the roots and errors of the requests.
+func addAll(b *mapWriteBatcher, tree *trillian.Tree, requests [][]*trillian.MapLeaf, metas []*any.Any) ([]*trillian.SignedMapRoot, []error) {
+	roots := make([]*trillian.SignedMapRoot, len(requests))
+	errs := make([]error, len(requests))
+	var wg sync.WaitGroup
+	for i := range requests {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var meta *any.Any
+			if metas != nil {
+				meta = metas[i]
+			}
+			roots[i], errs[i] = b.add(context.Background(), tree, nil, requests[i], meta)
+		}(i)
+	}
+	wg.Wait()
+	return roots, errs
+}
+
+func leaf(index, value string) *trillian.MapLeaf {
+	return &trillian.MapLeaf{Index: []byte(index), LeafValue: []byte(value)}
+}
+
+func TestMapWriteBatcherMergesRequests(t *testing.T) {
+	f := &fakeMapWriter{}
+	b := newTestBatcher(WriteBatching{Window: 100 * time.Millisecond}, f)
+	tree := &trillian.Tree{TreeId: 1}
+
+	roots, errs := addAll(b, tree, [][]*trillian.MapLeaf{
+		{leaf("a", "1")},
+		{leaf("b", "2")},
+		{leaf("c", "3"), leaf("d", "4")},
+	}, []*any.Any{nil, {TypeUrl: "meta"}, nil})
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("add(%d): %v", i, err)
+		}
+	}
+	if got, want := len(f.writes), 1; got != want {
+		t.Fatalf("%d writes, want %d", got, want)
+	}
+	if got, want := len(f.writes[0]), 4; got != want {
+		t.Errorf("%d leaves written, want %d", got, want)
+	}
+	if got, want := f.metas[0].GetTypeUrl(), "meta"; got != want {
+		t.Errorf("metadata %q written, want %q", got, want)
+	}
+	for i, r := range roots {
+		if r != roots[0] {
+			t.Errorf("add(%d) returned root %v, want %v", i, r, roots[0])
+		}
+	}
+
+	// A later request gets a revision of its own.
+	if _, err := b.add(context.Background(), tree, nil, []*trillian.MapLeaf{leaf("a", "5")}, nil); err != nil {
+		t.Fatalf("add(): %v", err)
+	}
+	if got, want := len(f.writes), 2; got != want {
+		t.Errorf("%d writes, want %d", got, want)
+	}
+}
+
+func TestMapWriteBatcherOverridesLeaves(t *testing.T) {
+	f := &fakeMapWriter{}
+	b := newTestBatcher(WriteBatching{Window: time.Hour, MaxLeaves: 2}, f)
+	tree := &trillian.Tree{TreeId: 1}
+
+	// The second request overrides leaf a, and its leaf b fills the batch.
+	done := make(chan error)
+	go func() {
+		_, err := b.add(context.Background(), tree, nil, []*trillian.MapLeaf{leaf("a", "1")}, nil)
+		done <- err
+	}()
+	for {
+		b.mu.Lock()
+		_, ok := b.pending[tree.TreeId]
+		b.mu.Unlock()
+		if ok {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := b.add(context.Background(), tree, nil, []*trillian.MapLeaf{leaf("a", "2"), leaf("b", "3")}, nil); err != nil {
+		t.Fatalf("add(): %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("add(): %v", err)
+	}
+
+	if got, want := len(f.writes), 1; got != want {
+		t.Fatalf("%d writes, want %d", got, want)
+	}
+	got := make(map[string]string)
+	for _, l := range f.writes[0] {
+		got[string(l.Index)] = string(l.LeafValue)
+	}
+	if want := map[string]string{"a": "2", "b": "3"}; len(got) != len(want) || got["a"] != want["a"] || got["b"] != want["b"] {
+		t.Errorf("leaves written %v, want %v", got, want)
+	}
+}
+
+func TestMapWriteBatcherErrors(t *testing.T) {
+	f := &fakeMapWriter{err: errors.New("write failed")}
+	b := newTestBatcher(WriteBatching{Window: 10 * time.Millisecond}, f)
+	tree := &trillian.Tree{TreeId: 1}
+
+	_, errs := addAll(b, tree, [][]*trillian.MapLeaf{{leaf("a", "1")}, {leaf("b", "2")}}, nil)
+	for i, err := range errs {
+		if err != f.err {
+			t.Errorf("add(%d)=%v; want %v", i, err, f.err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b = newTestBatcher(WriteBatching{Window: time.Hour}, &fakeMapWriter{})
+	if _, err := b.add(ctx, tree, nil, []*trillian.MapLeaf{leaf("a", "1")}, nil); err != context.Canceled {
+		t.Errorf("add() with cancelled context=%v; want %v", err, context.Canceled)
+	}
+}
+
+func TestSetWriteBatching(t *testing.T) {
+	s := NewTrillianMapServer(extension.Registry{}, util.SystemTimeSource{})
+	s.SetWriteBatching(WriteBatching{Window: time.Second, MaxLeaves: 10})
+	if s.batcher == nil {
+		t.Fatal("SetWriteBatching(1s): batcher not set")
+	}
+	s.SetWriteBatching(WriteBatching{})
+	if s.batcher != nil {
+		t.Error("SetWriteBatching(0): batcher still set")
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/trillian/monitoring"
+)
+
+var (
+	// MetricsMaxTrees is a flag specifying how many trees get their own tree ID
+	// label values in metrics.
+	MetricsMaxTrees = flag.Int("metrics_max_trees", 0, "Maximum number of trees whose metrics are labelled with their tree ID, with the metrics of further trees aggregated under the \"other\" tree ID. 0 means unlimited")
+	// MetricsTreeAllowlist is a flag specifying trees which always get their own
+	// tree ID label values in metrics.
+	MetricsTreeAllowlist = flag.String("metrics_tree_allowlist", "", "Comma-separated IDs of trees whose metrics are always labelled with their tree ID, regardless of --metrics_max_trees")
+	// MetricsLatencyBuckets is a flag specifying the buckets of latency
+	// histograms.
+	MetricsLatencyBuckets = flag.String("metrics_latency_buckets", "", "Comma-separated, increasing upper bounds in seconds of the buckets of latency histograms. If empty, buckets doubling from 1ms are used")
+)
+
+// NewMetricFactoryFromFlags wraps mf to limit the cardinality of tree ID
+// labels as specified by flags. It also sets the monitoring.LatencyBuckets
+// used by all latency histograms created afterwards.
+func NewMetricFactoryFromFlags(mf monitoring.MetricFactory) (monitoring.MetricFactory, error) {
+	if *MetricsLatencyBuckets != "" {
+		buckets, err := monitoring.ParseBuckets(*MetricsLatencyBuckets)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --metrics_latency_buckets: %v", err)
+		}
+		if err := monitoring.SetLatencyBuckets(buckets); err != nil {
+			return nil, err
+		}
+	}
+	return NewMetricFactory(mf, *MetricsMaxTrees, *MetricsTreeAllowlist)
+}
+
+// NewMetricFactory wraps mf to give at most maxTrees trees, plus the trees in
+// the comma-separated allowlist, their own tree ID label values. mf is
+// returned unchanged if maxTrees is 0.
+func NewMetricFactory(mf monitoring.MetricFactory, maxTrees int, allowlist string) (monitoring.MetricFactory, error) {
+	if maxTrees < 0 {
+		return nil, fmt.Errorf("invalid maximum number of labelled trees: %d", maxTrees)
+	}
+	if maxTrees == 0 {
+		return mf, nil
+	}
+	opts := monitoring.TreeLabelOpts{MaxTrees: maxTrees}
+	for _, s := range strings.Split(allowlist, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tree ID %q in metrics allowlist: %v", s, err)
+		}
+		opts.Allowlist = append(opts.Allowlist, id)
+	}
+	return monitoring.NewTreeLabelLimiter(mf, opts), nil
+}
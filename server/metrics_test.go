@@ -0,0 +1,49 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"testing"
+
+	"github.com/google/trillian/monitoring"
+)
+
+func TestNewMetricFactory(t *testing.T) {
+	for _, test := range []struct {
+		desc        string
+		maxTrees    int
+		allowlist   string
+		wantLimiter bool
+		wantErr     bool
+	}{
+		{desc: "unlimited", allowlist: "1,2"},
+		{desc: "limited", maxTrees: 10, wantLimiter: true},
+		{desc: "allowlist", maxTrees: 10, allowlist: " 1, 2,,3 ", wantLimiter: true},
+		{desc: "negative", maxTrees: -1, wantErr: true},
+		{desc: "badAllowlist", maxTrees: 10, allowlist: "1,log2", wantErr: true},
+	} {
+		mf, err := NewMetricFactory(monitoring.InertMetricFactory{}, test.maxTrees, test.allowlist)
+		if gotErr := err != nil; gotErr != test.wantErr {
+			t.Errorf("%v: NewMetricFactory() = (_, %v), want err? %v", test.desc, err, test.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if _, gotLimiter := mf.(*monitoring.TreeLabelLimiter); gotLimiter != test.wantLimiter {
+			t.Errorf("%v: NewMetricFactory() returned %T, want limiter? %v", test.desc, mf, test.wantLimiter)
+		}
+	}
+}
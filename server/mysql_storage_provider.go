@@ -15,21 +15,36 @@
 package server
 
 import (
+	"context"
 	"database/sql"
 	"flag"
+	"fmt"
+	"io/ioutil"
 	"sync"
+	"time"
 
 	"github.com/golang/glog"
 	"github.com/google/trillian/monitoring"
 	"github.com/google/trillian/storage"
 	"github.com/google/trillian/storage/mysql"
+	"github.com/google/trillian/util"
 
 	// Load MySQL driver
 	_ "github.com/go-sql-driver/mysql"
 )
 
 var (
-	mySQLURI = flag.String("mysql_uri", "test:zaphod@tcp(127.0.0.1:3306)/test", "Connection URI for MySQL database")
+	mySQLURI             = flag.String("mysql_uri", "test:zaphod@tcp(127.0.0.1:3306)/test", "Connection URI for MySQL database")
+	mySQLMaxOpenConns    = flag.Int("mysql_max_open_conns", 0, "Maximum number of open connections to the MySQL database (0 is unlimited)")
+	mySQLMaxIdleConns    = flag.Int("mysql_max_idle_conns", 0, "Maximum number of idle connections kept open to the MySQL database (0 uses the database/sql default, negative values retain no idle connections)")
+	mySQLConnMaxLifetime = flag.Duration("mysql_conn_max_lifetime", 0, "Maximum amount of time a MySQL connection may be reused for (0 is unlimited)")
+	mySQLMaxCachedStmts  = flag.Int("mysql_max_cached_statements", 0, "Maximum number of prepared statements cached per distinct SQL statement (0 is unlimited)")
+	mySQLPoolStatsPeriod = flag.Duration("mysql_pool_stats_interval", 10*time.Second, "Interval between samples of MySQL connection pool metrics (0 or less samples the pool once at startup)")
+	mySQLSeqEvents       = flag.Bool("mysql_sequencing_events", false, "If true, insert a row into the SequencingEvents table with each new log root, for change data capture pipelines")
+	mySQLElectionPrefix  = flag.String("mysql_election_lock_prefix", "trillian_master_", "Prefix of the names of the MySQL locks held by log signer masters, followed by the tree ID")
+	mySQLCreateSchema    = flag.Bool("mysql_create_schema", false, "If true, create the tables of --mysql_schema_file on startup if the MySQL database has none of them yet, for first-run deployments and ephemeral test environments")
+	mySQLSchemaFile      = flag.String("mysql_schema_file", "storage/mysql/storage.sql", "Path of the storage.sql script run by --mysql_create_schema")
+	mySQLCheckSchema     = flag.Bool("mysql_check_schema", true, "If true, check on startup that the MySQL database has the tables, columns and indexes of storage/mysql/storage.sql, and fail if it doesn't")
 
 	mysqlOnce            sync.Once
 	mySQLstorageInstance *mysqlProvider
@@ -42,8 +57,10 @@ func init() {
 }
 
 type mysqlProvider struct {
-	db *sql.DB
-	mf monitoring.MetricFactory
+	db          *sql.DB
+	mf          monitoring.MetricFactory
+	opts        mysql.TreeStorageOptions
+	stopPoolMon context.CancelFunc
 }
 
 func newMySQLStorageProvider(mf monitoring.MetricFactory) (StorageProvider, error) {
@@ -51,13 +68,39 @@ func newMySQLStorageProvider(mf monitoring.MetricFactory) (StorageProvider, erro
 
 	mysqlOnce.Do(func() {
 		var db *sql.DB
-		db, err = mysql.OpenDB(*mySQLURI)
+		db, err = mysql.OpenDBWithOptions(*mySQLURI, mysql.DBOptions{
+			MaxOpenConns:    *mySQLMaxOpenConns,
+			MaxIdleConns:    *mySQLMaxIdleConns,
+			ConnMaxLifetime: *mySQLConnMaxLifetime,
+		})
 		if err != nil {
 			return
 		}
+		if *mySQLCreateSchema {
+			if err = createMySQLSchema(db, *mySQLSchemaFile); err != nil {
+				db.Close()
+				return
+			}
+		}
+		if *mySQLCheckSchema {
+			ctx, cancel := context.WithTimeout(context.Background(), schemaCheckTimeout)
+			err = mysql.CheckSchema(ctx, db)
+			cancel()
+			if err != nil {
+				db.Close()
+				return
+			}
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		go mysql.MonitorDBPool(ctx, db, *mySQLMaxOpenConns, mf, *mySQLPoolStatsPeriod)
 		mySQLstorageInstance = &mysqlProvider{
 			db: db,
 			mf: mf,
+			opts: mysql.TreeStorageOptions{
+				MaxCachedStatements: *mySQLMaxCachedStmts,
+				SequencingEvents:    *mySQLSeqEvents,
+			},
+			stopPoolMon: cancel,
 		}
 	})
 	if err != nil {
@@ -66,18 +109,43 @@ func newMySQLStorageProvider(mf monitoring.MetricFactory) (StorageProvider, erro
 	return mySQLstorageInstance, nil
 }
 
+// createMySQLSchema creates the tables of the schemaFile script in the
+// database of db, unless it already has some of them.
+func createMySQLSchema(db *sql.DB, schemaFile string) error {
+	script, err := ioutil.ReadFile(schemaFile)
+	if err != nil {
+		return fmt.Errorf("failed to read MySQL schema: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), schemaCheckTimeout)
+	defer cancel()
+	switch err := mysql.CreateSchema(ctx, db, string(script)); err {
+	case nil:
+		glog.Infof("Created the MySQL schema from %v", schemaFile)
+	case mysql.ErrSchemaExists:
+		glog.Info("MySQL database already has a schema, not creating it")
+	default:
+		return fmt.Errorf("failed to create MySQL schema: %v", err)
+	}
+	return nil
+}
+
 func (s *mysqlProvider) LogStorage() storage.LogStorage {
-	return mysql.NewLogStorage(s.db, s.mf)
+	return mysql.NewLogStorageWithOpts(s.db, s.mf, s.opts)
 }
 
 func (s *mysqlProvider) MapStorage() storage.MapStorage {
-	return mysql.NewMapStorage(s.db)
+	return mysql.NewMapStorageWithOpts(s.db, s.opts)
 }
 
 func (s *mysqlProvider) AdminStorage() storage.AdminStorage {
 	return mysql.NewAdminStorage(s.db)
 }
 
+func (s *mysqlProvider) ElectionFactory(instanceID string) util.ElectionFactory {
+	return mysql.NewElectionFactory(instanceID, s.db, *mySQLElectionPrefix)
+}
+
 func (s *mysqlProvider) Close() error {
+	s.stopPoolMon()
 	return s.db.Close()
 }
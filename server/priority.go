@@ -0,0 +1,332 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"container/heap"
+	"flag"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/trillian/client/priority"
+	"github.com/google/trillian/monitoring"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	priorityClasses       = flag.String("priority_classes", "interactive=8,batch=1", "Comma-separated class=weight priority classes. When the server is busy, requests of each class are admitted in proportion to its weight. Clients set the class of requests in the x-trillian-priority metadata")
+	priorityDefaultClass  = flag.String("priority_default_class", "interactive", "Priority class of requests which don't set one")
+	priorityMaxConcurrent = flag.Int("priority_max_concurrent", 0, "Max number of requests handled at once; more are queued by priority class. Zero disables priority queueing")
+	priorityMaxQueued     = flag.Int("priority_max_queued", 1000, "Max number of requests of each priority class which may be queued; more are rejected")
+
+	priorityQueued   monitoring.Gauge
+	priorityAdmitted monitoring.Counter
+	priorityRejected monitoring.Counter
+	priorityWait     monitoring.Histogram
+	priorityOnce     sync.Once
+)
+
+func initPriorityMetrics(mf monitoring.MetricFactory) {
+	priorityOnce.Do(func() {
+		priorityQueued = mf.NewGauge("priority_queued", "Number of requests of a priority class waiting to be handled", "class")
+		priorityAdmitted = mf.NewCounter("priority_admitted", "Number of requests of a priority class admitted to be handled", "class")
+		priorityRejected = mf.NewCounter("priority_rejected", "Number of requests of a priority class rejected because its queue was full, or which gave up waiting", "class", "reason")
+		priorityWait = mf.NewHistogram("priority_wait_seconds", "Time requests of a priority class waited to be handled", "class")
+	})
+}
+
+// Reasons for which a PriorityScheduler may not admit a request.
+const (
+	PriorityReasonQueueFull = "queue_full"
+	PriorityReasonCancelled = "cancelled"
+)
+
+// PrioritySchedulerOpts configures a PriorityScheduler.
+type PrioritySchedulerOpts struct {
+	// Weights maps the names of priority classes to their weights, which are
+	// the relative rates at which their requests are admitted when the
+	// server is busy.
+	Weights map[string]float64
+	// DefaultClass is the class of requests which don't set one. It must be
+	// in Weights.
+	DefaultClass string
+	// MaxConcurrent is the number of requests handled at once.
+	MaxConcurrent int
+	// MaxQueued is the number of requests of each class which may wait to be
+	// handled.
+	MaxQueued int
+}
+
+// ParsePriorityWeights parses comma-separated class=weight entries, e.g.
+// "interactive=8,batch=1".
+func ParsePriorityWeights(s string) (map[string]float64, error) {
+	weights := make(map[string]float64)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kv := strings.SplitN(entry, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("priority class %q: want class=weight", entry)
+		}
+		w, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64)
+		if err != nil || w <= 0 || math.IsInf(w, 0) || math.IsNaN(w) {
+			return nil, fmt.Errorf("priority class %q: bad weight %q", entry, kv[1])
+		}
+		weights[strings.TrimSpace(kv[0])] = w
+	}
+	return weights, nil
+}
+
+// PriorityScheduler limits the number of requests a server handles at once,
+// and queues the others by the priority class their clients set in the
+// priority.MetadataKey metadata. Queued requests are admitted by
+// weighted fair queueing: each class gets a share of the server proportional
+// to its weight while it has requests waiting, so that bulk traffic such as
+// log mirroring can't crowd out interactive requests.
+//
+// A nil *PriorityScheduler is valid, and admits all requests immediately.
+type PriorityScheduler struct {
+	opts PrioritySchedulerOpts
+
+	mu      sync.Mutex
+	classes map[string]*priorityClass
+	running int
+	queue   priorityQueue
+	// vtime is the virtual time of the scheduler: the start tag of the
+	// request admitted last.
+	vtime float64
+}
+
+// priorityClass is the state of a priority class. PriorityScheduler.mu must
+// be held to access it.
+type priorityClass struct {
+	name   string
+	weight float64
+	// finish is the finish tag of the class's last request.
+	finish float64
+	queued int
+}
+
+// priorityWaiter is a request waiting to be admitted.
+type priorityWaiter struct {
+	class         *priorityClass
+	start, finish float64
+	admitted      chan struct{}
+	index         int
+}
+
+// NewPriorityScheduler returns a PriorityScheduler configured by opts.
+func NewPriorityScheduler(opts PrioritySchedulerOpts, mf monitoring.MetricFactory) (*PriorityScheduler, error) {
+	if len(opts.Weights) == 0 {
+		return nil, fmt.Errorf("no priority classes")
+	}
+	for name, w := range opts.Weights {
+		if w <= 0 || math.IsInf(w, 0) || math.IsNaN(w) {
+			return nil, fmt.Errorf("weight of priority class %q is %v, want > 0", name, w)
+		}
+	}
+	if _, ok := opts.Weights[opts.DefaultClass]; !ok {
+		return nil, fmt.Errorf("default priority class %q isn't one of %v", opts.DefaultClass, priorityClassNames(opts.Weights))
+	}
+	if opts.MaxConcurrent <= 0 {
+		return nil, fmt.Errorf("max concurrent requests is %v, want > 0", opts.MaxConcurrent)
+	}
+	if opts.MaxQueued < 0 {
+		return nil, fmt.Errorf("max queued requests is %v, want >= 0", opts.MaxQueued)
+	}
+	if mf == nil {
+		mf = monitoring.InertMetricFactory{}
+	}
+	initPriorityMetrics(mf)
+
+	classes := make(map[string]*priorityClass)
+	for name, w := range opts.Weights {
+		classes[name] = &priorityClass{name: name, weight: w}
+	}
+	return &PriorityScheduler{opts: opts, classes: classes}, nil
+}
+
+// PrioritySchedulerFromFlags returns the PriorityScheduler specified by flags,
+// or nil if priority queueing is disabled.
+func PrioritySchedulerFromFlags(mf monitoring.MetricFactory) (*PriorityScheduler, error) {
+	if *priorityMaxConcurrent == 0 {
+		return nil, nil
+	}
+	weights, err := ParsePriorityWeights(*priorityClasses)
+	if err != nil {
+		return nil, err
+	}
+	return NewPriorityScheduler(PrioritySchedulerOpts{
+		Weights:       weights,
+		DefaultClass:  *priorityDefaultClass,
+		MaxConcurrent: *priorityMaxConcurrent,
+		MaxQueued:     *priorityMaxQueued,
+	}, mf)
+}
+
+func priorityClassNames(weights map[string]float64) []string {
+	names := make([]string, 0, len(weights))
+	for name := range weights {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// UnaryInterceptor is a grpc.UnaryServerInterceptor which admits requests by
+// priority class, running at most MaxConcurrent handlers at once.
+func (p *PriorityScheduler) UnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if p == nil {
+		return handler(ctx, req)
+	}
+	class, err := p.requestClass(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.acquire(ctx, class); err != nil {
+		return nil, err
+	}
+	defer p.release()
+	return handler(ctx, req)
+}
+
+// requestClass returns the priority class set in the metadata of ctx, or the
+// default class.
+func (p *PriorityScheduler) requestClass(ctx context.Context) (*priorityClass, error) {
+	name := p.opts.DefaultClass
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(priority.MetadataKey); len(v) > 0 {
+			name = v[0]
+		}
+	}
+	class, ok := p.classes[name]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown priority class %q, want one of %v", name, priorityClassNames(p.opts.Weights))
+	}
+	return class, nil
+}
+
+// tag assigns the start and finish tags of a new request of class, by
+// start-time fair queueing: each request advances the virtual finish time of
+// its class by the inverse of the class's weight.
+func (p *PriorityScheduler) tag(class *priorityClass) (start, finish float64) {
+	start = math.Max(p.vtime, class.finish)
+	finish = start + 1/class.weight
+	class.finish = finish
+	return start, finish
+}
+
+// acquire waits until a request of class may be handled. Its caller must call
+// release once it's done, unless acquire returns an error.
+func (p *PriorityScheduler) acquire(ctx context.Context, class *priorityClass) error {
+	p.mu.Lock()
+	if p.running < p.opts.MaxConcurrent && p.queue.Len() == 0 {
+		start, _ := p.tag(class)
+		p.vtime = start
+		p.running++
+		p.mu.Unlock()
+		priorityAdmitted.Inc(class.name)
+		priorityWait.Observe(0, class.name)
+		return nil
+	}
+	if class.queued >= p.opts.MaxQueued {
+		p.mu.Unlock()
+		priorityRejected.Inc(class.name, PriorityReasonQueueFull)
+		return status.Errorf(codes.ResourceExhausted, "too many %v requests queued", class.name)
+	}
+	w := &priorityWaiter{class: class, admitted: make(chan struct{})}
+	w.start, w.finish = p.tag(class)
+	heap.Push(&p.queue, w)
+	class.queued++
+	priorityQueued.Set(float64(class.queued), class.name)
+	p.mu.Unlock()
+
+	queued := time.Now()
+	select {
+	case <-w.admitted:
+		priorityAdmitted.Inc(class.name)
+		priorityWait.Observe(time.Since(queued).Seconds(), class.name)
+		return nil
+	case <-ctx.Done():
+	}
+
+	p.mu.Lock()
+	select {
+	case <-w.admitted:
+		// The request was admitted as it gave up, so its slot is handed on.
+		p.mu.Unlock()
+		p.release()
+	default:
+		heap.Remove(&p.queue, w.index)
+		class.queued--
+		priorityQueued.Set(float64(class.queued), class.name)
+		p.mu.Unlock()
+	}
+	priorityRejected.Inc(class.name, PriorityReasonCancelled)
+	return status.FromContextError(ctx.Err()).Err()
+}
+
+// release frees the slot of a request which was handled, and admits the
+// queued requests with the earliest finish tags into the free slots.
+func (p *PriorityScheduler) release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.running--
+	for p.running < p.opts.MaxConcurrent && p.queue.Len() > 0 {
+		w := heap.Pop(&p.queue).(*priorityWaiter)
+		w.class.queued--
+		priorityQueued.Set(float64(w.class.queued), w.class.name)
+		p.vtime = w.start
+		p.running++
+		close(w.admitted)
+	}
+}
+
+// priorityQueue is a heap of waiting requests, ordered by finish tag.
+type priorityQueue []*priorityWaiter
+
+func (q priorityQueue) Len() int { return len(q) }
+
+func (q priorityQueue) Less(i, j int) bool { return q[i].finish < q[j].finish }
+
+func (q priorityQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *priorityQueue) Push(x interface{}) {
+	w := x.(*priorityWaiter)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+
+func (q *priorityQueue) Pop() interface{} {
+	old := *q
+	w := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return w
+}
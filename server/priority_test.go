@@ -0,0 +1,238 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/trillian/client/priority"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestParsePriorityWeights(t *testing.T) {
+	for _, test := range []struct {
+		desc    string
+		s       string
+		want    map[string]float64
+		wantErr bool
+	}{
+		{desc: "empty", s: "", want: map[string]float64{}},
+		{desc: "two", s: "interactive=8, batch=0.5", want: map[string]float64{"interactive": 8, "batch": 0.5}},
+		{desc: "noWeight", s: "interactive", wantErr: true},
+		{desc: "noName", s: "=1", wantErr: true},
+		{desc: "zeroWeight", s: "batch=0", wantErr: true},
+		{desc: "badWeight", s: "batch=x", wantErr: true},
+	} {
+		got, err := ParsePriorityWeights(test.s)
+		if gotErr := err != nil; gotErr != test.wantErr {
+			t.Errorf("%v: ParsePriorityWeights(%q): %v, want error: %v", test.desc, test.s, err, test.wantErr)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: ParsePriorityWeights(%q) = %v, want %v", test.desc, test.s, got, test.want)
+		}
+	}
+}
+
+func TestNewPrioritySchedulerValidation(t *testing.T) {
+	weights := map[string]float64{"interactive": 8, "batch": 1}
+	for _, opts := range []PrioritySchedulerOpts{
+		{DefaultClass: "interactive", MaxConcurrent: 1},
+		{Weights: weights, DefaultClass: "mirror", MaxConcurrent: 1},
+		{Weights: weights, DefaultClass: "interactive"},
+		{Weights: weights, DefaultClass: "interactive", MaxConcurrent: 1, MaxQueued: -1},
+		{Weights: map[string]float64{"batch": -1}, DefaultClass: "batch", MaxConcurrent: 1},
+	} {
+		if _, err := NewPriorityScheduler(opts, nil); err == nil {
+			t.Errorf("NewPriorityScheduler(%+v): got nil error, want non-nil", opts)
+		}
+	}
+}
+
+// waitQueued waits until p has n requests queued.
+func waitQueued(t *testing.T, p *PriorityScheduler, n int) {
+	t.Helper()
+	for i := 0; i < 1000; i++ {
+		p.mu.Lock()
+		queued := p.queue.Len()
+		p.mu.Unlock()
+		if queued == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d requests to be queued", n)
+}
+
+func TestPriorityScheduler_Fairness(t *testing.T) {
+	ctx := context.Background()
+	p, err := NewPriorityScheduler(PrioritySchedulerOpts{
+		Weights:       map[string]float64{"interactive": 3, "batch": 1, "other": 1},
+		DefaultClass:  "other",
+		MaxConcurrent: 1,
+		MaxQueued:     100,
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewPriorityScheduler(): %v", err)
+	}
+
+	// Hold the only slot while requests of both classes queue up, the batch
+	// ones first.
+	if err := p.acquire(ctx, p.classes["other"]); err != nil {
+		t.Fatalf("acquire(): %v", err)
+	}
+	var mu sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	queued := 0
+	for _, name := range []string{"batch", "interactive"} {
+		for i := 0; i < 12; i++ {
+			wg.Add(1)
+			go func(name string) {
+				defer wg.Done()
+				if err := p.acquire(ctx, p.classes[name]); err != nil {
+					t.Errorf("acquire(%v): %v", name, err)
+					return
+				}
+				mu.Lock()
+				order = append(order, name)
+				mu.Unlock()
+				p.release()
+			}(name)
+			queued++
+			waitQueued(t, p, queued)
+		}
+	}
+	p.release()
+	wg.Wait()
+
+	// Interactive requests are admitted three times as often as batch ones
+	// while both are queued.
+	interactive := 0
+	for _, name := range order[:8] {
+		if name == "interactive" {
+			interactive++
+		}
+	}
+	if want := 6; interactive != want {
+		t.Errorf("admitted %d interactive requests of the first 8 (%v), want %d", interactive, order, want)
+	}
+	if got, want := len(order), 24; got != want {
+		t.Errorf("admitted %d requests, want %d", got, want)
+	}
+}
+
+func TestPriorityScheduler_Rejections(t *testing.T) {
+	ctx := context.Background()
+	p, err := NewPriorityScheduler(PrioritySchedulerOpts{
+		Weights:       map[string]float64{"interactive": 8, "batch": 1},
+		DefaultClass:  "interactive",
+		MaxConcurrent: 1,
+		MaxQueued:     1,
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewPriorityScheduler(): %v", err)
+	}
+	batch := p.classes["batch"]
+	if err := p.acquire(ctx, batch); err != nil {
+		t.Fatalf("acquire(): %v", err)
+	}
+
+	cctx, cancel := context.WithCancel(ctx)
+	errs := make(chan error)
+	go func() { errs <- p.acquire(cctx, batch) }()
+	waitQueued(t, p, 1)
+
+	if err := p.acquire(ctx, batch); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("acquire() with a full queue: %v, want code %v", err, codes.ResourceExhausted)
+	}
+
+	cancel()
+	if err := <-errs; status.Code(err) != codes.Canceled {
+		t.Errorf("acquire() cancelled: %v, want code %v", err, codes.Canceled)
+	}
+	waitQueued(t, p, 0)
+	if batch.queued != 0 {
+		t.Errorf("%d batch requests queued after cancellation, want 0", batch.queued)
+	}
+
+	// The queue has room again.
+	go func() { errs <- p.acquire(ctx, batch) }()
+	waitQueued(t, p, 1)
+	p.release()
+	if err := <-errs; err != nil {
+		t.Errorf("acquire(): %v", err)
+	}
+	p.release()
+}
+
+func TestPriorityScheduler_UnaryInterceptor(t *testing.T) {
+	p, err := NewPriorityScheduler(PrioritySchedulerOpts{
+		Weights:       map[string]float64{"interactive": 8, "batch": 1},
+		DefaultClass:  "interactive",
+		MaxConcurrent: 1,
+		MaxQueued:     1,
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewPriorityScheduler(): %v", err)
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	info := &grpc.UnaryServerInfo{FullMethod: "/trillian.TrillianLog/GetInclusionProof"}
+
+	for _, test := range []struct {
+		desc      string
+		class     string
+		wantClass string
+		wantCode  codes.Code
+	}{
+		{desc: "default", wantClass: "interactive"},
+		{desc: "batch", class: priority.Batch, wantClass: "batch"},
+		{desc: "unknown", class: "mirror", wantCode: codes.InvalidArgument},
+	} {
+		ctx := context.Background()
+		if test.class != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(priority.MetadataKey, test.class))
+		}
+		before := map[string]float64{}
+		for name, c := range p.classes {
+			before[name] = c.finish
+		}
+		_, err := p.UnaryInterceptor(ctx, "req", info, handler)
+		if got := status.Code(err); got != test.wantCode {
+			t.Errorf("%v: UnaryInterceptor(): %v, want code %v", test.desc, err, test.wantCode)
+		}
+		if test.wantClass != "" && p.classes[test.wantClass].finish == before[test.wantClass] {
+			t.Errorf("%v: request wasn't scheduled as %v", test.desc, test.wantClass)
+		}
+	}
+	if p.running != 0 {
+		t.Errorf("%d requests running after all returned, want 0", p.running)
+	}
+}
+
+func TestNilPriorityScheduler(t *testing.T) {
+	var p *PriorityScheduler
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	if _, err := p.UnaryInterceptor(context.Background(), "req", &grpc.UnaryServerInfo{}, handler); err != nil {
+		t.Errorf("UnaryInterceptor(): %v", err)
+	}
+}
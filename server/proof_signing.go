@@ -0,0 +1,162 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"crypto"
+	"flag"
+	"fmt"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian"
+	"github.com/google/trillian/client/envelope"
+	"github.com/google/trillian/crypto/keys/pem"
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/util"
+	"github.com/google/trillian/util/requestinfo"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	tcrypto "github.com/google/trillian/crypto"
+)
+
+var (
+	proofSigningKey         = flag.String("proof_signing_key", "", "PEM file holding the private key with which proof responses are signed, which should be distinct from the keys of trees. Signed envelopes are returned in the "+envelope.MetadataKey+" trailer. Empty disables proof response signing")
+	proofSigningKeyPassword = flag.String("proof_signing_key_password", "", "Password of the key in --proof_signing_key")
+
+	proofEnvelopesSigned monitoring.Counter
+	proofEnvelopesFailed monitoring.Counter
+	proofSigningOnce     sync.Once
+)
+
+func initProofSigningMetrics(mf monitoring.MetricFactory) {
+	proofSigningOnce.Do(func() {
+		proofEnvelopesSigned = mf.NewCounter("proof_envelopes_signed", "Number of proof responses to an RPC signed", "method")
+		proofEnvelopesFailed = mf.NewCounter("proof_envelopes_failed", "Number of proof responses to an RPC which couldn't be signed", "method")
+	})
+}
+
+// ProofSigner signs the responses to proof RPCs (see envelope.Methods) with
+// a serving key, returning envelopes which bind each response to its request
+// in the trailer metadata of the RPC. Clients can verify them with the
+// envelope package.
+type ProofSigner struct {
+	signer     *tcrypto.Signer
+	keyID      []byte
+	timeSource util.TimeSource
+}
+
+// NewProofSigner returns a ProofSigner which signs with key, timestamping
+// envelopes with timeSource.
+func NewProofSigner(key crypto.Signer, mf monitoring.MetricFactory, timeSource util.TimeSource) (*ProofSigner, error) {
+	keyID, err := envelope.KeyID(key.Public())
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute proof signing key ID: %v", err)
+	}
+	if mf == nil {
+		mf = monitoring.InertMetricFactory{}
+	}
+	initProofSigningMetrics(mf)
+	return &ProofSigner{signer: tcrypto.NewSHA256Signer(key), keyID: keyID, timeSource: timeSource}, nil
+}
+
+// ProofSignerFromFlags returns the ProofSigner specified by flags, or nil if
+// proof response signing is disabled.
+func ProofSignerFromFlags(mf monitoring.MetricFactory, timeSource util.TimeSource) (*ProofSigner, error) {
+	if *proofSigningKey == "" {
+		return nil, nil
+	}
+	key, err := pem.ReadPrivateKeyFile(*proofSigningKey, *proofSigningKeyPassword)
+	if err != nil {
+		return nil, err
+	}
+	return NewProofSigner(key, mf, timeSource)
+}
+
+// Seal returns an envelope for resp, the response to req, a call to method,
+// signed by p.
+func (p *ProofSigner) Seal(method string, req, resp proto.Message) (*trillian.SignedProofEnvelope, error) {
+	reqDigest, err := envelope.Digest(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to digest request: %v", err)
+	}
+	respDigest, err := envelope.Digest(resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to digest response: %v", err)
+	}
+	env, err := proto.Marshal(&trillian.ProofEnvelope{
+		TimestampNanos: p.timeSource.Now().UnixNano(),
+		Method:         method,
+		RequestDigest:  reqDigest,
+		ResponseDigest: respDigest,
+		KeyId:          p.keyID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal envelope: %v", err)
+	}
+	sig, err := p.signer.Sign(env)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign envelope: %v", err)
+	}
+	return &trillian.SignedProofEnvelope{Envelope: env, Signature: sig}, nil
+}
+
+// UnaryInterceptor is a grpc.UnaryServerInterceptor which seals the
+// successful responses to proof RPCs, and returns the envelopes in their
+// trailer metadata. RPCs whose responses can't be signed fail with
+// codes.Internal, rather than being served unsigned.
+func (p *ProofSigner) UnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if p == nil || !envelope.Methods[info.FullMethod] {
+		return handler(ctx, req)
+	}
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+
+	signed, err := p.sealResponse(info.FullMethod, req, resp)
+	if err != nil {
+		proofEnvelopesFailed.Inc(info.FullMethod)
+		requestinfo.Errorf(ctx, "%s: failed to seal proof response: %v", info.FullMethod, err)
+		return nil, status.Errorf(codes.Internal, "failed to sign proof response")
+	}
+	proofEnvelopesSigned.Inc(info.FullMethod)
+	// Fails if the RPC isn't served over a transport, e.g. when embedded,
+	// in which case there's no proxy for the envelope to guard against.
+	grpc.SetTrailer(ctx, metadata.Pairs(envelope.MetadataKey, string(signed)))
+	return resp, nil
+}
+
+// sealResponse returns the serialized envelope for resp, the response to
+// req.
+func (p *ProofSigner) sealResponse(method string, req, resp interface{}) ([]byte, error) {
+	reqMsg, ok := req.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("request is a %T, not a proto.Message", req)
+	}
+	respMsg, ok := resp.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("response is a %T, not a proto.Message", resp)
+	}
+	signed, err := p.Seal(method, reqMsg, respMsg)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(signed)
+}
@@ -0,0 +1,134 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian"
+	"github.com/google/trillian/client/envelope"
+	"github.com/google/trillian/testonly/tmock"
+	"github.com/google/trillian/util"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestProofSigner(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ctx := context.Background()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey(): %v", err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey(): %v", err)
+	}
+	now := time.Unix(1500000000, 0)
+	signer, err := NewProofSigner(key, nil, util.NewFakeTimeSource(now))
+	if err != nil {
+		t.Fatalf("NewProofSigner(): %v", err)
+	}
+
+	logServer := tmock.NewMockTrillianLogServer(ctrl)
+	s := grpc.NewServer(grpc.UnaryInterceptor(signer.UnaryInterceptor))
+	trillian.RegisterTrillianLogServer(s, logServer)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen(): %v", err)
+	}
+	go s.Serve(lis)
+	defer s.Stop()
+
+	dial := func(opts ...grpc.DialOption) trillian.TrillianLogClient {
+		t.Helper()
+		cc, err := grpc.Dial(lis.Addr().String(), append(opts, grpc.WithInsecure())...)
+		if err != nil {
+			t.Fatalf("Dial(): %v", err)
+		}
+		return trillian.NewTrillianLogClient(cc)
+	}
+
+	req := &trillian.GetInclusionProofRequest{LogId: 1, LeafIndex: 2, TreeSize: 3}
+	resp := &trillian.GetInclusionProofResponse{Proof: &trillian.Proof{LeafIndex: 2, Hashes: [][]byte{[]byte("hash")}}}
+	logServer.EXPECT().GetInclusionProof(gomock.Any(), gomock.Any()).Return(resp, nil).AnyTimes()
+	const method = "/trillian.TrillianLog/GetInclusionProof"
+
+	// The envelope binds the response to the request.
+	var trailer metadata.MD
+	got, err := dial().GetInclusionProof(ctx, req, grpc.Trailer(&trailer))
+	if err != nil {
+		t.Fatalf("GetInclusionProof(): %v", err)
+	}
+	env, err := envelope.Verify(key.Public(), method, req, got, trailer)
+	if err != nil {
+		t.Fatalf("Verify(): %v", err)
+	}
+	if got, want := env.TimestampNanos, now.UnixNano(); got != want {
+		t.Errorf("Verify() envelope has timestamp %v, want %v", got, want)
+	}
+
+	for _, test := range []struct {
+		desc   string
+		pub    crypto.PublicKey
+		method string
+		req    proto.Message
+		resp   proto.Message
+	}{
+		{desc: "other key", pub: otherKey.Public(), method: method, req: req, resp: got},
+		{desc: "other method", pub: key.Public(), method: "/trillian.TrillianLog/GetConsistencyProof", req: req, resp: got},
+		{desc: "other request", pub: key.Public(), method: method, req: &trillian.GetInclusionProofRequest{LogId: 1, LeafIndex: 1, TreeSize: 3}, resp: got},
+		{desc: "tampered response", pub: key.Public(), method: method, req: req, resp: &trillian.GetInclusionProofResponse{Proof: &trillian.Proof{LeafIndex: 2, Hashes: [][]byte{[]byte("evil")}}}},
+	} {
+		if _, err := envelope.Verify(test.pub, test.method, test.req, test.resp, trailer); err == nil {
+			t.Errorf("%v: Verify(): nil, want err", test.desc)
+		}
+	}
+	if _, err := envelope.Verify(key.Public(), method, req, got, metadata.MD{}); err == nil {
+		t.Errorf("Verify() without envelope: nil, want err")
+	}
+
+	// Verifying clients accept responses signed by the key they expect only.
+	if _, err := dial(grpc.WithUnaryInterceptor(envelope.UnaryClientInterceptor(key.Public()))).GetInclusionProof(ctx, req); err != nil {
+		t.Errorf("GetInclusionProof() verified with the signing key: %v", err)
+	}
+	_, err = dial(grpc.WithUnaryInterceptor(envelope.UnaryClientInterceptor(otherKey.Public()))).GetInclusionProof(ctx, req)
+	if got, want := status.Code(err), codes.DataLoss; got != want {
+		t.Errorf("GetInclusionProof() verified with another key: %v, want code %v", err, want)
+	}
+
+	// Responses to other RPCs aren't signed.
+	logServer.EXPECT().GetSequencedLeafCount(gomock.Any(), gomock.Any()).Return(&trillian.GetSequencedLeafCountResponse{LeafCount: 3}, nil)
+	trailer = nil
+	if _, err := dial().GetSequencedLeafCount(ctx, &trillian.GetSequencedLeafCountRequest{LogId: 1}, grpc.Trailer(&trailer)); err != nil {
+		t.Fatalf("GetSequencedLeafCount(): %v", err)
+	}
+	if vals := trailer[envelope.MetadataKey]; len(vals) != 0 {
+		t.Errorf("GetSequencedLeafCount() returned %d envelopes, want 0", len(vals))
+	}
+}
@@ -17,10 +17,12 @@ package server
 import (
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"sync"
 
 	"github.com/golang/glog"
 	"github.com/google/trillian/quota"
+	"github.com/google/trillian/server/interceptor"
 )
 
 const (
@@ -35,6 +37,10 @@ type NewQuotaManagerFunc func() (quota.Manager, error)
 var (
 	// QuotaSystem is a flag specifying which quota system is in use.
 	QuotaSystem = flag.String("quota_system", "mysql", fmt.Sprintf("Quota system to use. One of: %v", quotaSystems()))
+	// QuotaCosts is a flag specifying the cost of the requests of RPC methods.
+	QuotaCosts = flag.String("quota_costs", "", "Comma-separated method=per_item[:per_kib] token costs of requests, e.g. QueueLeaves=1:0.5,GetLeavesByRange=0.1. Methods are named after their request messages, without the Request suffix. Methods not listed cost one token per call, or per leaf written")
+	// QuotaCostsFile is a flag specifying a file of request costs.
+	QuotaCostsFile = flag.String("quota_costs_file", "", "File of request costs in the format of --quota_costs, one or more per line, with # comments. Costs in --quota_costs take precedence")
 
 	qpMu     sync.RWMutex
 	qpByName map[string]NewQuotaManagerFunc
@@ -48,6 +54,32 @@ func init() {
 	}
 }
 
+// QuotaCostModelFromFlags returns the interceptor.CostModel specified by flags.
+func QuotaCostModelFromFlags() (interceptor.CostModel, error) {
+	costs := make(interceptor.CostModel)
+	if *QuotaCostsFile != "" {
+		b, err := ioutil.ReadFile(*QuotaCostsFile)
+		if err != nil {
+			return nil, err
+		}
+		fileCosts, err := interceptor.ParseCostModel(string(b))
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", *QuotaCostsFile, err)
+		}
+		for method, c := range fileCosts {
+			costs[method] = c
+		}
+	}
+	flagCosts, err := interceptor.ParseCostModel(*QuotaCosts)
+	if err != nil {
+		return nil, err
+	}
+	for method, c := range flagCosts {
+		costs[method] = c
+	}
+	return costs, nil
+}
+
 // RegisterQuotaManager registers the provided QuotaManager.
 func RegisterQuotaManager(name string, qp NewQuotaManagerFunc) error {
 	qpMu.Lock()
@@ -0,0 +1,253 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian"
+	"github.com/google/trillian/monitoring"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// ResponseTooLargeViolation is the type of the PreconditionFailure
+	// violation attached to the errors of RPCs refused because their response
+	// would exceed the tree's maximum response size. Clients reading leaf
+	// ranges should fall back to StreamLeavesByRange on such errors.
+	ResponseTooLargeViolation = "RESPONSE_TOO_LARGE"
+	// TreeReadMemoryViolation is the type of the PreconditionFailure violation
+	// attached to the errors of RPCs refused because the responses being
+	// assembled for their tree already hold as much memory as allowed. They
+	// may be retried later.
+	TreeReadMemoryViolation = "TREE_READ_MEMORY_EXHAUSTED"
+)
+
+var (
+	maxReadResponseBytes        = flag.Int64("max_read_response_bytes", 0, "If non-zero, read RPCs whose response would hold more than this many bytes of leaves and proofs fail with RESOURCE_EXHAUSTED; leaf ranges should then be read with StreamLeavesByRange, whose messages are kept below the limit")
+	maxReadResponseBytesPerTree = flag.String("max_read_response_bytes_per_tree", "", "Comma-separated treeID=bytes pairs overriding --max_read_response_bytes for individual trees. A limit of 0 disables the check for the tree")
+	maxTreeReadBytes            = flag.Int64("max_tree_read_bytes", 0, "If non-zero, the maximum number of bytes of leaves and proofs all the read RPCs of a single tree may hold at once while assembling their responses")
+
+	readMemoryRejections  monitoring.Counter
+	readMemoryMetricsOnce sync.Once
+)
+
+func initReadMemoryMetrics(mf monitoring.MetricFactory) {
+	readMemoryMetricsOnce.Do(func() {
+		readMemoryRejections = mf.NewCounter("read_memory_rejections", "Number of read RPCs refused because of the memory their response would hold", "logid", "reason")
+	})
+}
+
+// ReadMemoryLimiter accounts for the memory held by the leaves and proofs of
+// the responses being assembled by read RPCs, and refuses those which would
+// hold too much, so that a single large request can't exhaust the server's
+// memory. Leaf ranges exceeding the limit of a single response can still be
+// read with StreamLeavesByRange.
+//
+// The accounting is approximate: it counts the encoded size of the leaves and
+// proofs read, as they are read, and storage reads GetLeavesByRange chunks of
+// leaves at a time, so a response may exceed its limit by up to one chunk
+// before it's refused.
+//
+// A nil *ReadMemoryLimiter doesn't limit anything.
+type ReadMemoryLimiter struct {
+	// MaxResponseBytes is the maximum size of the responses of trees without
+	// an entry in PerTree. Zero means no maximum.
+	MaxResponseBytes int64
+	// PerTree holds the maximum size of the responses of individual trees,
+	// with zero meaning no maximum.
+	PerTree map[int64]int64
+	// MaxTreeBytes is the maximum size of all the responses being assembled
+	// at once for any single tree. Zero means no maximum.
+	MaxTreeBytes int64
+
+	mu sync.Mutex
+	// inUse holds the number of bytes held by the responses of each tree.
+	inUse map[int64]int64
+}
+
+// ParseReadMemoryLimiter returns a ReadMemoryLimiter with the given default
+// maximum response size, per tree maximum response sizes specified as
+// comma-separated treeID=bytes pairs, and maximum size of the responses of a
+// tree. It returns nil if nothing is limited.
+func ParseReadMemoryLimiter(maxResponse int64, perTree string, maxTree int64) (*ReadMemoryLimiter, error) {
+	if maxResponse < 0 {
+		return nil, fmt.Errorf("max response size is %d, want >= 0", maxResponse)
+	}
+	if maxTree < 0 {
+		return nil, fmt.Errorf("max tree read size is %d, want >= 0", maxTree)
+	}
+	l := &ReadMemoryLimiter{
+		MaxResponseBytes: maxResponse,
+		PerTree:          make(map[int64]int64),
+		MaxTreeBytes:     maxTree,
+	}
+	if perTree != "" {
+		for _, part := range strings.Split(perTree, ",") {
+			kv := strings.SplitN(part, "=", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("invalid tree max response size %q, want treeID=bytes", part)
+			}
+			treeID, err := strconv.ParseInt(strings.TrimSpace(kv[0]), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid tree ID in %q: %v", part, err)
+			}
+			size, err := strconv.ParseInt(strings.TrimSpace(kv[1]), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid max response size of tree %d: %v", treeID, err)
+			}
+			if size < 0 {
+				return nil, fmt.Errorf("max response size of tree %d is %d, want >= 0", treeID, size)
+			}
+			l.PerTree[treeID] = size
+		}
+	}
+
+	if l.MaxResponseBytes == 0 && l.MaxTreeBytes == 0 {
+		limited := false
+		for _, size := range l.PerTree {
+			limited = limited || size > 0
+		}
+		if !limited {
+			return nil, nil
+		}
+	}
+	return l, nil
+}
+
+// ReadMemoryLimiterFromFlags returns the ReadMemoryLimiter specified by
+// flags, or nil if read memory isn't limited.
+func ReadMemoryLimiterFromFlags() (*ReadMemoryLimiter, error) {
+	return ParseReadMemoryLimiter(*maxReadResponseBytes, *maxReadResponseBytesPerTree, *maxTreeReadBytes)
+}
+
+// maxResponseBytes returns the maximum size of the responses of treeID, or
+// zero if there is none.
+func (l *ReadMemoryLimiter) maxResponseBytes(treeID int64) int64 {
+	if l == nil {
+		return 0
+	}
+	if size, ok := l.PerTree[treeID]; ok {
+		return size
+	}
+	return l.MaxResponseBytes
+}
+
+// account returns a readAccount for a response of treeID, which must be
+// released once the response is sent. The response is limited to the tree's
+// maximum response size if limitResponse is true.
+func (l *ReadMemoryLimiter) account(treeID int64, limitResponse bool) *readAccount {
+	if l == nil {
+		return nil
+	}
+	a := &readAccount{l: l, treeID: treeID}
+	if limitResponse {
+		a.max = l.maxResponseBytes(treeID)
+	}
+	return a
+}
+
+// reserve adds n bytes to those held by the responses of treeID, unless that
+// would exceed MaxTreeBytes.
+func (l *ReadMemoryLimiter) reserve(treeID, n int64) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.MaxTreeBytes > 0 && l.inUse[treeID]+n > l.MaxTreeBytes {
+		return false
+	}
+	if l.inUse == nil {
+		l.inUse = make(map[int64]int64)
+	}
+	l.inUse[treeID] += n
+	return true
+}
+
+func (l *ReadMemoryLimiter) free(treeID, n int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inUse[treeID] -= n; l.inUse[treeID] <= 0 {
+		delete(l.inUse, treeID)
+	}
+}
+
+// readAccount accounts for the memory held by the response of one RPC. A nil
+// *readAccount accepts everything.
+type readAccount struct {
+	l      *ReadMemoryLimiter
+	treeID int64
+	// max is the maximum size of the response, or zero if there is none.
+	max  int64
+	used int64
+}
+
+// add accounts for n more bytes held by the response, and returns an error if
+// they exceed its limit or that of its tree, in which case the RPC should
+// fail with it.
+func (a *readAccount) add(n int64) error {
+	if a == nil {
+		return nil
+	}
+	if a.max > 0 && a.used+n > a.max {
+		return a.reject(ResponseTooLargeViolation, fmt.Sprintf("response of more than %d bytes exceeds the maximum of %d", a.used+n, a.max))
+	}
+	if !a.l.reserve(a.treeID, n) {
+		return a.reject(TreeReadMemoryViolation, fmt.Sprintf("responses of the tree already hold up to %d bytes", a.l.MaxTreeBytes))
+	}
+	a.used += n
+	return nil
+}
+
+// addLeaves calls add with the size of leaves.
+func (a *readAccount) addLeaves(leaves []*trillian.LogLeaf) error {
+	if a == nil {
+		return nil
+	}
+	n := 0
+	for _, leaf := range leaves {
+		n += proto.Size(leaf)
+	}
+	return a.add(int64(n))
+}
+
+// release frees all the bytes added to the account, which can then be reused.
+func (a *readAccount) release() {
+	if a == nil || a.used == 0 {
+		return
+	}
+	a.l.free(a.treeID, a.used)
+	a.used = 0
+}
+
+func (a *readAccount) reject(violation, desc string) error {
+	readMemoryRejections.Inc(strconv.FormatInt(a.treeID, 10), violation)
+	s := status.Newf(codes.ResourceExhausted, "read of tree %d refused: %s", a.treeID, desc)
+	if typed, err := s.WithDetails(&errdetails.PreconditionFailure{
+		Violations: []*errdetails.PreconditionFailure_Violation{{
+			Type:        violation,
+			Subject:     fmt.Sprintf("logs/%d", a.treeID),
+			Description: desc,
+		}},
+	}); err == nil {
+		s = typed
+	}
+	return s.Err()
+}
@@ -0,0 +1,245 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian"
+	"github.com/google/trillian/client"
+	"github.com/google/trillian/extension"
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/quota"
+	"github.com/google/trillian/storage/memory"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	stestonly "github.com/google/trillian/storage/testonly"
+)
+
+func TestParseReadMemoryLimiter(t *testing.T) {
+	for _, test := range []struct {
+		maxResponse int64
+		perTree     string
+		maxTree     int64
+		want        *ReadMemoryLimiter
+		wantErr     bool
+	}{
+		{want: nil},
+		{perTree: "1=0", want: nil},
+		{
+			maxResponse: 1000,
+			want:        &ReadMemoryLimiter{MaxResponseBytes: 1000, PerTree: map[int64]int64{}},
+		},
+		{
+			perTree: "1=10, 2 = 0",
+			want:    &ReadMemoryLimiter{PerTree: map[int64]int64{1: 10, 2: 0}},
+		},
+		{
+			maxTree: 5000,
+			want:    &ReadMemoryLimiter{PerTree: map[int64]int64{}, MaxTreeBytes: 5000},
+		},
+		{maxResponse: -1, wantErr: true},
+		{maxTree: -1, wantErr: true},
+		{perTree: "1", wantErr: true},
+		{perTree: "one=10", wantErr: true},
+		{perTree: "1=ten", wantErr: true},
+		{perTree: "1=-10", wantErr: true},
+	} {
+		got, err := ParseReadMemoryLimiter(test.maxResponse, test.perTree, test.maxTree)
+		if gotErr := err != nil; gotErr != test.wantErr {
+			t.Errorf("ParseReadMemoryLimiter(%d, %q, %d)=_,%v; want err? %v", test.maxResponse, test.perTree, test.maxTree, err, test.wantErr)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseReadMemoryLimiter(%d, %q, %d)=%+v; want %+v", test.maxResponse, test.perTree, test.maxTree, got, test.want)
+		}
+	}
+}
+
+// violation returns the type of the PreconditionFailure violation of err, if
+// it has one.
+func violation(err error) string {
+	for _, d := range status.Convert(err).Details() {
+		if pf, ok := d.(*errdetails.PreconditionFailure); ok && len(pf.Violations) > 0 {
+			return pf.Violations[0].Type
+		}
+	}
+	return ""
+}
+
+func TestReadAccount(t *testing.T) {
+	initReadMemoryMetrics(monitoring.InertMetricFactory{})
+	l := &ReadMemoryLimiter{
+		MaxResponseBytes: 100,
+		PerTree:          map[int64]int64{2: 0},
+		MaxTreeBytes:     150,
+	}
+
+	a := l.account(1, true)
+	if err := a.add(60); err != nil {
+		t.Fatalf("add(60)=%v; want nil", err)
+	}
+	if err := a.add(50); violation(err) != ResponseTooLargeViolation {
+		t.Errorf("add(50) over response limit=%v; want violation %v", err, ResponseTooLargeViolation)
+	}
+
+	// Responses of tree 2 aren't limited individually, but share the tree's
+	// limit, as do streams.
+	b := l.account(2, true)
+	if err := b.add(120); err != nil {
+		t.Fatalf("add(120) to unlimited response=%v; want nil", err)
+	}
+	s := l.account(1, false)
+	if err := s.add(120); violation(err) != TreeReadMemoryViolation {
+		t.Errorf("add(120) over tree limit=%v; want violation %v", err, TreeReadMemoryViolation)
+	}
+	if got, want := status.Code(s.add(120)), codes.ResourceExhausted; got != want {
+		t.Errorf("add(120) over tree limit: got code %v, want %v", got, want)
+	}
+	if err := s.add(90); err != nil {
+		t.Errorf("add(90) within tree limit=%v; want nil", err)
+	}
+
+	a.release()
+	s.release()
+	if err := s.add(150); err != nil {
+		t.Errorf("add(150) after release=%v; want nil", err)
+	}
+	s.release()
+	b.release()
+	if got := len(l.inUse); got != 0 {
+		t.Errorf("%d trees hold memory after all releases, want 0", got)
+	}
+
+	var nilLimiter *ReadMemoryLimiter
+	if err := nilLimiter.account(1, true).add(1 << 40); err != nil {
+		t.Errorf("nil add()=%v; want nil", err)
+	}
+}
+
+func TestReadMemoryLimits(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	limiter := &ReadMemoryLimiter{PerTree: make(map[int64]int64)}
+	ls := memory.NewLogStorage(nil)
+	e, err := New(extension.Registry{
+		AdminStorage: memory.NewAdminStorage(ls),
+		LogStorage:   ls,
+		QuotaManager: quota.Noop(),
+	}, Options{
+		Signer: &LogOperationInfo{
+			BatchSize:   100,
+			NumWorkers:  1,
+			RunInterval: 50 * time.Millisecond,
+		},
+		ReadMemory: limiter,
+	})
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	if err := e.Start(ctx); err != nil {
+		t.Fatalf("Start(): %v", err)
+	}
+	defer e.Stop()
+
+	tree, err := client.CreateAndInitTree(ctx, &trillian.CreateTreeRequest{Tree: proto.Clone(stestonly.LogTree).(*trillian.Tree)}, e.AdminClient(), nil, e.LogClient())
+	if err != nil {
+		t.Fatalf("CreateAndInitTree(): %v", err)
+	}
+	lc, err := client.NewFromTree(e.LogClient(), tree)
+	if err != nil {
+		t.Fatalf("NewFromTree(): %v", err)
+	}
+	const leafCount = 10
+	for i := 0; i < leafCount; i++ {
+		if err := lc.QueueLeaf(ctx, []byte(fmt.Sprintf("leaf %d", i))); err != nil {
+			t.Fatalf("QueueLeaf(): %v", err)
+		}
+	}
+	if _, err := lc.WaitForRootUpdate(ctx, leafCount); err != nil {
+		t.Fatalf("WaitForRootUpdate(): %v", err)
+	}
+
+	req := &trillian.GetLeavesByRangeRequest{LogId: tree.TreeId, StartIndex: 0, Count: leafCount}
+	resp, err := e.LogClient().GetLeavesByRange(ctx, req)
+	if err != nil {
+		t.Fatalf("GetLeavesByRange() without limit: %v", err)
+	}
+	// Limit responses to three leaves.
+	maxBytes := int64(3 * proto.Size(resp.Leaves[0]))
+	limiter.PerTree[tree.TreeId] = maxBytes
+
+	_, err = e.LogClient().GetLeavesByRange(ctx, req)
+	if got, want := violation(err), ResponseTooLargeViolation; got != want {
+		t.Errorf("GetLeavesByRange() over limit=%v; want violation %v", err, want)
+	}
+
+	stream, err := e.LogClient().StreamLeavesByRange(ctx, req)
+	if err != nil {
+		t.Fatalf("StreamLeavesByRange(): %v", err)
+	}
+	var streamed int64
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("StreamLeavesByRange().Recv(): %v", err)
+		}
+		var size int64
+		for _, leaf := range resp.Leaves {
+			size += int64(proto.Size(leaf))
+		}
+		if size > maxBytes {
+			t.Errorf("StreamLeavesByRange() sent %d bytes of leaves, want at most %d", size, maxBytes)
+		}
+		for _, leaf := range resp.Leaves {
+			if leaf.LeafIndex != streamed {
+				t.Errorf("StreamLeavesByRange() sent leaf %d, want %d", leaf.LeafIndex, streamed)
+			}
+			streamed++
+		}
+	}
+	if streamed != leafCount {
+		t.Errorf("StreamLeavesByRange() sent %d leaves, want %d", streamed, leafCount)
+	}
+
+	// Streams go through the same tree checks as other requests.
+	stream, err = e.LogClient().StreamLeavesByRange(ctx, &trillian.GetLeavesByRangeRequest{LogId: tree.TreeId + 1, Count: 1})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if got, want := status.Code(err), codes.NotFound; got != want {
+		t.Errorf("StreamLeavesByRange() for unknown tree: got %v, want %v", got, want)
+	}
+
+	// The client falls back to streaming.
+	leaves, err := lc.ListByIndex(ctx, 0, leafCount)
+	if err != nil {
+		t.Fatalf("ListByIndex(): %v", err)
+	}
+	if got := len(leaves); got != leafCount {
+		t.Errorf("ListByIndex() returned %d leaves, want %d", got, leafCount)
+	}
+}
@@ -0,0 +1,326 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/google/trillian"
+	"github.com/google/trillian/merkle/hashers"
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/util/requestinfo"
+)
+
+// readRepairTimeout bounds the rewrite of each repaired subtree, which outlives
+// the RPC which needed it.
+const readRepairTimeout = time.Minute
+
+var (
+	readRepair = flag.Bool("read_repair", false, "If true, the Merkle nodes which proofs need but which are missing from storage are recomputed from the nodes and leaves below them, and the subtrees holding them are rewritten if the storage supports it")
+
+	readRepairNodes       monitoring.Counter
+	readRepairSubtrees    monitoring.Counter
+	readRepairMetricsOnce sync.Once
+)
+
+func initReadRepairMetrics(mf monitoring.MetricFactory) {
+	readRepairMetricsOnce.Do(func() {
+		if mf == nil {
+			mf = monitoring.InertMetricFactory{}
+		}
+		readRepairNodes = mf.NewCounter("read_repair_nodes", "Number of Merkle nodes missing from storage which were recomputed to serve proofs", logIDLabel)
+		readRepairSubtrees = mf.NewCounter("read_repair_subtrees", "Number of subtrees rebuilt by read repair, by whether they were rewritten to storage", logIDLabel, "rewritten")
+	})
+}
+
+// ReadRepairer recomputes the Merkle nodes of logs which proofs need but which
+// are missing from storage, e.g. because a subtree was lost or corrupted, so
+// that the proofs are served rather than failing. The whole subtree holding a
+// missing node is rebuilt from the nodes and leaves of the strata below it,
+// and is rewritten in the background if the log storage implements
+// storage.LogSubtreeRepairer. Each repair is logged.
+type ReadRepairer struct {
+	ls storage.LogStorage
+
+	mu sync.Mutex
+	// rewriting holds the subtrees being rewritten, so that concurrent proofs
+	// which rebuild the same subtree only write it once.
+	rewriting map[string]bool
+	wg        sync.WaitGroup
+}
+
+// NewReadRepairer returns a ReadRepairer which rewrites the subtrees it rebuilds
+// to ls.
+func NewReadRepairer(ls storage.LogStorage, mf monitoring.MetricFactory) *ReadRepairer {
+	initReadRepairMetrics(mf)
+	return &ReadRepairer{ls: ls, rewriting: make(map[string]bool)}
+}
+
+// ReadRepairerFromFlags returns the ReadRepairer specified by flags, or nil if
+// read repair is disabled.
+func ReadRepairerFromFlags(ls storage.LogStorage, mf monitoring.MetricFactory) *ReadRepairer {
+	if !*readRepair {
+		return nil
+	}
+	return NewReadRepairer(ls, mf)
+}
+
+// Wait waits for the subtree rewrites in progress to finish.
+func (r *ReadRepairer) Wait() {
+	r.wg.Wait()
+}
+
+// nodeReader returns a NodeReader which reads the nodes of the log treeID from
+// tx, and recomputes those which are missing as of root, the latest root of
+// the log, which tx reads at.
+func (r *ReadRepairer) nodeReader(tx storage.ReadOnlyLogTreeTX, hasher hashers.LogHasher, treeID int64, root *trillian.SignedLogRoot) storage.NodeReader {
+	return &repairingNodeReader{
+		r:        r,
+		tx:       tx,
+		hasher:   hasher,
+		treeID:   treeID,
+		size:     root.TreeSize,
+		repaired: make(map[storage.NodeCoords][]byte),
+	}
+}
+
+// rewrite writes the subtree st of the log treeID, rebuilt from nodes, at
+// treeRevision in the background, unless the log storage can't repair
+// subtrees or the subtree is being rewritten already.
+func (r *ReadRepairer) rewrite(treeID, treeRevision int64, st storage.SubtreeCoords, nodes []storage.Node) {
+	label := strconv.FormatInt(treeID, 10)
+	repairer, ok := r.ls.(storage.LogSubtreeRepairer)
+	if !ok {
+		readRepairSubtrees.Inc(label, "false")
+		return
+	}
+	key := fmt.Sprintf("%d/%v/%d", treeID, st, treeRevision)
+	r.mu.Lock()
+	if r.rewriting[key] {
+		r.mu.Unlock()
+		return
+	}
+	r.rewriting[key] = true
+	r.mu.Unlock()
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), readRepairTimeout)
+		defer cancel()
+		err := repairer.RepairMerkleNodes(ctx, treeID, treeRevision, nodes)
+		if err != nil {
+			glog.Errorf("%v: read repair: failed to rewrite subtree %v at revision %d: %v", treeID, st, treeRevision, err)
+		} else {
+			glog.Warningf("%v: read repair: rewrote subtree %v at revision %d", treeID, st, treeRevision)
+		}
+		readRepairSubtrees.Inc(label, strconv.FormatBool(err == nil))
+
+		r.mu.Lock()
+		delete(r.rewriting, key)
+		r.mu.Unlock()
+	}()
+}
+
+// repairingNodeReader reads the Merkle nodes of a log of a given size,
+// recomputing those which are missing from storage.
+type repairingNodeReader struct {
+	r      *ReadRepairer
+	tx     storage.ReadOnlyLogTreeTX
+	hasher hashers.LogHasher
+	treeID int64
+	size   int64
+	// repaired holds the hashes of the nodes of the subtrees rebuilt so far.
+	repaired map[storage.NodeCoords][]byte
+}
+
+// GetMerkleNodes implements storage.NodeReader. The nodes are returned in the
+// order of ids.
+func (n *repairingNodeReader) GetMerkleNodes(ctx context.Context, treeRevision int64, ids []storage.NodeID) ([]storage.Node, error) {
+	stored, err := n.tx.GetMerkleNodes(ctx, treeRevision, ids)
+	if err != nil || len(stored) == len(ids) {
+		return stored, err
+	}
+	nodes := make([]storage.Node, 0, len(ids))
+	for _, id := range ids {
+		if len(stored) > 0 && stored[0].NodeID.Equivalent(id) {
+			nodes = append(nodes, stored[0])
+			stored = stored[1:]
+			continue
+		}
+		node, err := n.repair(ctx, treeRevision, id)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// repair returns the node id, which is missing from storage, rebuilding the
+// subtree holding it if it hasn't been already.
+func (n *repairingNodeReader) repair(ctx context.Context, treeRevision int64, id storage.NodeID) (storage.Node, error) {
+	c, err := storage.CoordsForNodeID(id)
+	if err != nil {
+		return storage.Node{}, err
+	}
+	if !n.perfect(c) {
+		return storage.Node{}, fmt.Errorf("node %v is missing from storage, and isn't a complete subtree of the tree of size %d", c, n.size)
+	}
+	h, ok := n.repaired[c]
+	if !ok {
+		st, _, err := storage.SubtreeFor(c)
+		if err != nil {
+			return storage.Node{}, err
+		}
+		if err := n.rebuild(ctx, treeRevision, st); err != nil {
+			return storage.Node{}, fmt.Errorf("failed to repair subtree %v: %v", st, err)
+		}
+		h = n.repaired[c]
+	}
+	readRepairNodes.Inc(strconv.FormatInt(n.treeID, 10))
+	return storage.Node{NodeID: id, Hash: h, NodeRevision: treeRevision}, nil
+}
+
+// rebuild recomputes the nodes of the subtree st, as of treeRevision, from the
+// nodes at its bottom level, and hands them over to be rewritten.
+func (n *repairingNodeReader) rebuild(ctx context.Context, treeRevision int64, st storage.SubtreeCoords) error {
+	// The nodes at the bottom of the subtree are the leaves of the log, or the
+	// roots of the subtrees of the stratum below. Only the complete ones are
+	// stored, and they're contiguous from the left.
+	var bottom []storage.NodeCoords
+	for i := int64(0); i < 1<<storage.LogStratumDepth; i++ {
+		c := st.Node(storage.SubtreeNodeCoords{Level: 0, Index: i})
+		if !n.perfect(c) {
+			break
+		}
+		bottom = append(bottom, c)
+	}
+	hashes, err := n.hashNodes(ctx, treeRevision, bottom)
+	if err != nil {
+		return err
+	}
+
+	var nodes []storage.Node
+	for level := 0; len(hashes) > 0; level++ {
+		for i, h := range hashes {
+			c := st.Node(storage.SubtreeNodeCoords{Level: level, Index: int64(i)})
+			id, err := c.ID()
+			if err != nil {
+				return err
+			}
+			n.repaired[c] = h
+			nodes = append(nodes, storage.Node{NodeID: id, Hash: h, NodeRevision: treeRevision})
+		}
+		if level == storage.LogStratumDepth-1 {
+			break
+		}
+		parents := make([][]byte, 0, len(hashes)/2)
+		for i := 0; i+1 < len(hashes); i += 2 {
+			parents = append(parents, n.hasher.HashChildren(hashes[i], hashes[i+1]))
+		}
+		hashes = parents
+	}
+	requestinfo.Warningf(ctx, "%v: read repair: rebuilt subtree %v at revision %d, tree size %d, from the strata below it (%d nodes)", n.treeID, st, treeRevision, n.size, len(nodes))
+	n.r.rewrite(n.treeID, treeRevision, st, nodes)
+	return nil
+}
+
+// hashNodes returns the hashes of the complete subtrees at coords, reading
+// them from storage, or recomputing them from their children or, at the
+// bottom of the tree, the leaves of the log if they're missing.
+func (n *repairingNodeReader) hashNodes(ctx context.Context, treeRevision int64, coords []storage.NodeCoords) ([][]byte, error) {
+	ids := make([]storage.NodeID, len(coords))
+	for i, c := range coords {
+		id, err := c.ID()
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	stored, err := n.tx.GetMerkleNodes(ctx, treeRevision, ids)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([][]byte, len(coords))
+	var missing []int
+	for i, id := range ids {
+		if len(stored) > 0 && stored[0].NodeID.Equivalent(id) {
+			hashes[i] = stored[0].Hash
+			stored = stored[1:]
+			continue
+		}
+		missing = append(missing, i)
+	}
+	if len(missing) == 0 {
+		return hashes, nil
+	}
+
+	var leafIndices []int64
+	var children []storage.NodeCoords
+	for _, i := range missing {
+		c := coords[i]
+		if c.Level == 0 {
+			leafIndices = append(leafIndices, c.Index)
+			continue
+		}
+		children = append(children,
+			storage.NodeCoords{Level: c.Level - 1, Index: 2 * c.Index},
+			storage.NodeCoords{Level: c.Level - 1, Index: 2*c.Index + 1})
+	}
+	var childHashes [][]byte
+	if len(children) > 0 {
+		if childHashes, err = n.hashNodes(ctx, treeRevision, children); err != nil {
+			return nil, err
+		}
+	}
+	leafHashes := make(map[int64][]byte)
+	if len(leafIndices) > 0 {
+		leaves, err := n.tx.GetLeavesByIndex(ctx, leafIndices)
+		if err != nil {
+			return nil, err
+		}
+		for _, leaf := range leaves {
+			leafHashes[leaf.LeafIndex] = leaf.MerkleLeafHash
+		}
+	}
+	for _, i := range missing {
+		c := coords[i]
+		if c.Level > 0 {
+			hashes[i] = n.hasher.HashChildren(childHashes[0], childHashes[1])
+			childHashes = childHashes[2:]
+			continue
+		}
+		h, ok := leafHashes[c.Index]
+		if !ok {
+			return nil, fmt.Errorf("leaf %d is missing from storage", c.Index)
+		}
+		hashes[i] = h
+	}
+	return hashes, nil
+}
+
+// perfect returns whether c is the root of a complete subtree of the tree,
+// i.e. one which is stored.
+func (n *repairingNodeReader) perfect(c storage.NodeCoords) bool {
+	return c.Index < n.size>>uint(c.Level)
+}
@@ -0,0 +1,147 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/merkle"
+	"github.com/google/trillian/merkle/rfc6962"
+	"github.com/google/trillian/storage"
+)
+
+// lossyTX serves the Merkle nodes of a log except for those stored in one
+// lost subtree.
+type lossyTX struct {
+	*fakeFrontierTX
+	lost storage.SubtreeCoords
+}
+
+func (tx *lossyTX) GetMerkleNodes(ctx context.Context, rev int64, ids []storage.NodeID) ([]storage.Node, error) {
+	nodes, err := tx.fakeFrontierTX.GetMerkleNodes(ctx, rev, ids)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]storage.Node, 0, len(nodes))
+	for _, node := range nodes {
+		c, err := storage.CoordsForNodeID(node.NodeID)
+		if err != nil {
+			return nil, err
+		}
+		if st, _, err := storage.SubtreeFor(c); err == nil && st == tx.lost {
+			continue
+		}
+		ret = append(ret, node)
+	}
+	return ret, nil
+}
+
+func (tx *lossyTX) GetLeavesByIndex(ctx context.Context, indices []int64) ([]*trillian.LogLeaf, error) {
+	var ret []*trillian.LogLeaf
+	for _, i := range indices {
+		if i < int64(len(tx.leaves)) {
+			ret = append(ret, &trillian.LogLeaf{LeafIndex: i, MerkleLeafHash: tx.leaves[i]})
+		}
+	}
+	return ret, nil
+}
+
+// fakeRepairStorage records the nodes of the subtrees repaired.
+type fakeRepairStorage struct {
+	storage.LogStorage
+	mu       sync.Mutex
+	repaired []storage.Node
+}
+
+func (s *fakeRepairStorage) RepairMerkleNodes(ctx context.Context, treeID, treeRevision int64, nodes []storage.Node) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.repaired = append(s.repaired, nodes...)
+	return nil
+}
+
+func TestReadRepair(t *testing.T) {
+	ctx := context.Background()
+	const treeID = 12345
+	hasher := rfc6962.DefaultHasher
+	v := merkle.NewLogVerifier(hasher)
+
+	for _, test := range []struct {
+		desc        string
+		lost        storage.SubtreeCoords
+		indices     []int64
+		wantRepairs int
+	}{
+		// The leaves 256 to 511, and the nodes above them up to level 7.
+		{desc: "bottom stratum", lost: storage.SubtreeCoords{Stratum: 0, Index: 1}, indices: []int64{256, 300, 511}, wantRepairs: 510},
+		// The roots of the first two subtrees of the bottom stratum, and
+		// their parent.
+		{desc: "second stratum", lost: storage.SubtreeCoords{Stratum: 1, Index: 0}, indices: []int64{0, 300, 599}, wantRepairs: 3},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			tx := &lossyTX{fakeFrontierTX: &fakeFrontierTX{hasher: hasher}, lost: test.lost}
+			for len(tx.leaves) < 600 {
+				tx.addLeaf(t)
+			}
+			root := tx.root()
+			size := root.TreeSize
+			ls := &fakeRepairStorage{}
+			r := NewReadRepairer(ls, nil)
+
+			for _, index := range test.indices {
+				fetches, err := merkle.InclusionProofNodes(size, index, size)
+				if err != nil {
+					t.Fatalf("InclusionProofNodes(%d): %v", index, err)
+				}
+				if _, err := fetchNodesAndBuildProof(ctx, tx, hasher, tx.ReadRevision(), index, fetches); err == nil {
+					t.Errorf("fetchNodesAndBuildProof(%d) without repair: nil, want err", index)
+				}
+				proof, err := fetchNodesAndBuildProof(ctx, r.nodeReader(tx, hasher, treeID, root), hasher, tx.ReadRevision(), index, fetches)
+				if err != nil {
+					t.Fatalf("fetchNodesAndBuildProof(%d): %v", index, err)
+				}
+				if err := v.VerifyInclusionProof(index, size, proof.Hashes, root.RootHash, tx.leaves[index]); err != nil {
+					t.Errorf("VerifyInclusionProof(%d): %v", index, err)
+				}
+			}
+			r.Wait()
+
+			// Each proof rebuilds the lost subtree and rewrites it, unless a
+			// rewrite is already in progress, with the same nodes each time.
+			ls.mu.Lock()
+			defer ls.mu.Unlock()
+			if len(ls.repaired) == 0 || len(ls.repaired)%test.wantRepairs != 0 {
+				t.Fatalf("repaired %d nodes, want multiples of %d", len(ls.repaired), test.wantRepairs)
+			}
+			for _, node := range ls.repaired[:test.wantRepairs] {
+				c, err := storage.CoordsForNodeID(node.NodeID)
+				if err != nil {
+					t.Fatalf("CoordsForNodeID(): %v", err)
+				}
+				if st, _, err := storage.SubtreeFor(c); err != nil || st != test.lost {
+					t.Errorf("repaired node %v in subtree %v, want %v", c, st, test.lost)
+				}
+				lo, hi := c.Index<<uint(c.Level), (c.Index+1)<<uint(c.Level)
+				if want := tx.rangeHash(lo, hi); !bytes.Equal(node.Hash, want) {
+					t.Errorf("repaired node %v with hash %x, want %x", c, node.Hash, want)
+				}
+			}
+		})
+	}
+}
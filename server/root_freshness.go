@@ -0,0 +1,164 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/monitoring"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// StaleRootViolation is the type of the PreconditionFailure violation attached
+// to the errors of RPCs refused because the log's latest root is too old.
+const StaleRootViolation = "STALE_ROOT"
+
+var (
+	maxServedRootAge        = flag.Duration("max_served_root_age", 0, "If non-zero, RPCs reading a log's latest root fail when the root is older than this, e.g. because the signer is down. Should exceed the trees' max_root_duration, or idle logs will be refused")
+	maxServedRootAgePerTree = flag.String("max_served_root_age_per_tree", "", "Comma-separated treeID=duration pairs overriding --max_served_root_age for individual trees. A duration of 0 disables the check for the tree")
+	staleRootCode           = flag.String("stale_root_code", "FAILED_PRECONDITION", "gRPC status code of RPCs refused because of a stale root. One of FAILED_PRECONDITION or UNAVAILABLE")
+
+	staleRootRejections  monitoring.Counter
+	freshnessMetricsOnce sync.Once
+)
+
+func initFreshnessMetrics(mf monitoring.MetricFactory) {
+	freshnessMetricsOnce.Do(func() {
+		staleRootRejections = mf.NewCounter("stale_root_rejections", "Number of RPCs refused because the log's latest root was too old", "logid")
+	})
+}
+
+// staleRootCodes are the codes which RPCs refused because of a stale root may
+// return, by name.
+var staleRootCodes = map[string]codes.Code{
+	"FAILED_PRECONDITION": codes.FailedPrecondition,
+	"UNAVAILABLE":         codes.Unavailable,
+}
+
+// RootFreshness fences off read RPCs when a log's latest signed root is older
+// than allowed, so that clients get an error rather than data which may break
+// the log's maximum merge delay promises, e.g. while its signer is down.
+//
+// Roots are only re-signed periodically if the tree's MaxRootDuration is set,
+// so the maximum age should be longer than that.
+//
+// A nil *RootFreshness doesn't check roots.
+type RootFreshness struct {
+	// MaxAge is the maximum age of served roots for trees without an entry in
+	// PerTree. Zero means no maximum.
+	MaxAge time.Duration
+	// PerTree holds the maximum age of served roots of individual trees, with
+	// zero meaning no maximum.
+	PerTree map[int64]time.Duration
+	// Code is the status code of refused RPCs.
+	Code codes.Code
+}
+
+// ParseRootFreshness returns a RootFreshness with the given default maximum
+// age, per tree maximum ages specified as comma-separated treeID=duration
+// pairs, and code name. It returns nil if no root ages are limited.
+func ParseRootFreshness(maxAge time.Duration, perTree, code string) (*RootFreshness, error) {
+	c, ok := staleRootCodes[code]
+	if !ok {
+		return nil, fmt.Errorf("unsupported stale root code %q", code)
+	}
+	if maxAge < 0 {
+		return nil, fmt.Errorf("max root age is %v, want >= 0", maxAge)
+	}
+	f := &RootFreshness{MaxAge: maxAge, PerTree: make(map[int64]time.Duration), Code: c}
+	if perTree != "" {
+		for _, part := range strings.Split(perTree, ",") {
+			kv := strings.SplitN(part, "=", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("invalid tree max root age %q, want treeID=duration", part)
+			}
+			treeID, err := strconv.ParseInt(strings.TrimSpace(kv[0]), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid tree ID in %q: %v", part, err)
+			}
+			age, err := time.ParseDuration(strings.TrimSpace(kv[1]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid max root age of tree %d: %v", treeID, err)
+			}
+			if age < 0 {
+				return nil, fmt.Errorf("max root age of tree %d is %v, want >= 0", treeID, age)
+			}
+			f.PerTree[treeID] = age
+		}
+	}
+
+	if f.MaxAge == 0 {
+		limited := false
+		for _, age := range f.PerTree {
+			limited = limited || age > 0
+		}
+		if !limited {
+			return nil, nil
+		}
+	}
+	return f, nil
+}
+
+// RootFreshnessFromFlags returns the RootFreshness specified by flags, or nil
+// if root ages aren't limited.
+func RootFreshnessFromFlags() (*RootFreshness, error) {
+	return ParseRootFreshness(*maxServedRootAge, *maxServedRootAgePerTree, *staleRootCode)
+}
+
+// maxAge returns the maximum age of the roots served for treeID, or zero if
+// there is none.
+func (f *RootFreshness) maxAge(treeID int64) time.Duration {
+	if age, ok := f.PerTree[treeID]; ok {
+		return age
+	}
+	return f.MaxAge
+}
+
+// check returns an error if root, the latest root of treeID, is too old to be
+// served at time now.
+func (f *RootFreshness) check(treeID int64, root *trillian.SignedLogRoot, now time.Time) error {
+	if f == nil {
+		return nil
+	}
+	maxAge := f.maxAge(treeID)
+	if maxAge == 0 {
+		return nil
+	}
+	age := now.Sub(time.Unix(0, root.TimestampNanos))
+	if age <= maxAge {
+		return nil
+	}
+
+	staleRootRejections.Inc(strconv.FormatInt(treeID, 10))
+	s := status.Newf(f.Code, "latest root of log %d is %v old, more than the maximum of %v", treeID, age, maxAge)
+	if typed, err := s.WithDetails(&errdetails.PreconditionFailure{
+		Violations: []*errdetails.PreconditionFailure_Violation{{
+			Type:        StaleRootViolation,
+			Subject:     fmt.Sprintf("logs/%d", treeID),
+			Description: fmt.Sprintf("root age %v exceeds %v", age, maxAge),
+		}},
+	}); err == nil {
+		s = typed
+	}
+	return s.Err()
+}
@@ -0,0 +1,109 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/monitoring"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseRootFreshness(t *testing.T) {
+	for _, test := range []struct {
+		maxAge  time.Duration
+		perTree string
+		code    string
+		want    *RootFreshness
+		wantErr bool
+	}{
+		{code: "FAILED_PRECONDITION", want: nil},
+		{perTree: "1=0", code: "FAILED_PRECONDITION", want: nil},
+		{
+			maxAge: time.Hour, code: "FAILED_PRECONDITION",
+			want: &RootFreshness{MaxAge: time.Hour, PerTree: map[int64]time.Duration{}, Code: codes.FailedPrecondition},
+		},
+		{
+			perTree: "1=10m, 2 = 0", code: "UNAVAILABLE",
+			want: &RootFreshness{PerTree: map[int64]time.Duration{1: 10 * time.Minute, 2: 0}, Code: codes.Unavailable},
+		},
+		{maxAge: time.Hour, code: "NOT_FOUND", wantErr: true},
+		{maxAge: -time.Hour, code: "UNAVAILABLE", wantErr: true},
+		{perTree: "1", code: "UNAVAILABLE", wantErr: true},
+		{perTree: "one=1h", code: "UNAVAILABLE", wantErr: true},
+		{perTree: "1=an hour", code: "UNAVAILABLE", wantErr: true},
+		{perTree: "1=-1h", code: "UNAVAILABLE", wantErr: true},
+	} {
+		got, err := ParseRootFreshness(test.maxAge, test.perTree, test.code)
+		if gotErr := err != nil; gotErr != test.wantErr {
+			t.Errorf("ParseRootFreshness(%v, %q, %q)=_,%v; want err? %v", test.maxAge, test.perTree, test.code, err, test.wantErr)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseRootFreshness(%v, %q, %q)=%+v; want %+v", test.maxAge, test.perTree, test.code, got, test.want)
+		}
+	}
+}
+
+func TestRootFreshnessCheck(t *testing.T) {
+	initFreshnessMetrics(monitoring.InertMetricFactory{})
+	f := &RootFreshness{
+		MaxAge:  time.Hour,
+		PerTree: map[int64]time.Duration{2: time.Minute, 3: 0},
+		Code:    codes.FailedPrecondition,
+	}
+	now := time.Unix(10000, 0)
+	root := &trillian.SignedLogRoot{TimestampNanos: now.Add(-10 * time.Minute).UnixNano()}
+
+	for _, test := range []struct {
+		treeID    int64
+		wantStale bool
+	}{
+		{treeID: 1},
+		{treeID: 2, wantStale: true},
+		{treeID: 3},
+	} {
+		err := f.check(test.treeID, root, now)
+		if !test.wantStale {
+			if err != nil {
+				t.Errorf("check(%d)=%v; want nil", test.treeID, err)
+			}
+			continue
+		}
+		s := status.Convert(err)
+		if got, want := s.Code(), codes.FailedPrecondition; got != want {
+			t.Errorf("check(%d)=%v; want code %v", test.treeID, err, want)
+		}
+		var violation string
+		for _, d := range s.Details() {
+			if pf, ok := d.(*errdetails.PreconditionFailure); ok && len(pf.Violations) > 0 {
+				violation = pf.Violations[0].Type
+			}
+		}
+		if violation != StaleRootViolation {
+			t.Errorf("check(%d) violation=%q; want %q", test.treeID, violation, StaleRootViolation)
+		}
+	}
+
+	var nilFreshness *RootFreshness
+	if err := nilFreshness.check(2, root, now); err != nil {
+		t.Errorf("nil check()=%v; want nil", err)
+	}
+}
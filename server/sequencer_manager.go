@@ -27,7 +27,9 @@ import (
 	"github.com/google/trillian/extension"
 	"github.com/google/trillian/log"
 	"github.com/google/trillian/merkle/hashers"
+	"github.com/google/trillian/storage"
 	"github.com/google/trillian/trees"
+	"github.com/google/trillian/util/requestinfo"
 )
 
 // SequencerManager provides sequencing operations for a collection of Logs.
@@ -36,18 +38,41 @@ type SequencerManager struct {
 	registry     extension.Registry
 	signers      map[int64]*crypto.Signer
 	signersMutex sync.Mutex
+
+	catchUp     CatchUpOpts
+	catchUpMu   sync.Mutex
+	catchUpLogs map[int64]*catchUpState
+
+	// preflight, if set, makes getSigner run preflightSigner before it
+	// returns the signer of a log for the first time.
+	preflight bool
 }
 
 // NewSequencerManager creates a new SequencerManager instance based on the provided KeyManager instance
 // and guard window.
 func NewSequencerManager(registry extension.Registry, gw time.Duration) *SequencerManager {
+	initCatchUpMetrics(registry.MetricFactory)
 	return &SequencerManager{
 		guardWindow: gw,
 		registry:    registry,
 		signers:     make(map[int64]*crypto.Signer),
+		catchUpLogs: make(map[int64]*catchUpState),
 	}
 }
 
+// SetCatchUp configures the catch-up mode of logs, see CatchUpOpts. It must
+// be called before the first pass.
+func (s *SequencerManager) SetCatchUp(opts CatchUpOpts) {
+	s.catchUp = opts
+}
+
+// SetPreflight configures whether the keys and latest root of each log are
+// checked before its roots are first signed, see preflightSigner. It must be
+// called before the first pass.
+func (s *SequencerManager) SetPreflight(enabled bool) {
+	s.preflight = enabled
+}
+
 // Name returns the name of the object.
 func (s *SequencerManager) Name() string {
 	return "Sequencer"
@@ -58,6 +83,10 @@ func (s *SequencerManager) ExecutePass(ctx context.Context, logID int64, info *L
 	// TODO(Martin2112): Honor the sequencing enabled in log parameters, needs an API change
 	// so deferring it
 
+	// Each pass is identified as a request in the logs of the signer and its
+	// storage, so that the lines of a failing pass can be told apart.
+	ctx = requestinfo.NewContext(ctx, requestinfo.Info{RequestID: requestinfo.NewRequestID()})
+
 	tree, err := trees.GetTree(
 		ctx,
 		s.registry.AdminStorage,
@@ -66,6 +95,10 @@ func (s *SequencerManager) ExecutePass(ctx context.Context, logID int64, info *L
 	if err != nil {
 		return 0, fmt.Errorf("error retrieving log %v: %v", logID, err)
 	}
+	if tree.Maintenance != nil {
+		glog.V(1).Infof("%v: log is in maintenance, not sequencing", logID)
+		return 0, nil
+	}
 	ctx = trees.NewContext(ctx, tree)
 
 	hasher, err := hashers.NewLogHasher(tree.HashStrategy)
@@ -85,15 +118,43 @@ func (s *SequencerManager) ExecutePass(ctx context.Context, logID int64, info *L
 		glog.Warning("failed to parse tree.MaxRootDuration, using zero")
 		maxRootDuration = 0
 	}
-	leaves, err := sequencer.IntegrateBatch(ctx, logID, info.BatchSize, s.guardWindow, maxRootDuration)
-	if err != nil {
-		return 0, fmt.Errorf("failed to integrate batch for %v: %v", logID, err)
+	var maxMergeDelay time.Duration
+	if tree.MaxMergeDelay != nil {
+		if maxMergeDelay, err = ptypes.Duration(tree.MaxMergeDelay); err != nil {
+			requestinfo.Warningf(ctx, "%v: failed to parse tree.MaxMergeDelay, not tracking merge delays: %v", logID, err)
+			maxMergeDelay = 0
+		}
+	}
+	leaves := 0
+	for batch := 1; ; batch++ {
+		opts := s.batchOptions(logID, info.BatchSize)
+		opts.MaxRootDuration = maxRootDuration
+		opts.RootTimestampPrecision = tree.RootTimestampPrecision
+		opts.LeafIndexes = tree.LeafIndexes
+		opts.LeafFilter = tree.LeafFilter
+		opts.MaxMergeDelay = maxMergeDelay
+		opts.VerifyWrites = tree.WriteVerification == trillian.WriteVerification_VERIFY_WRITES
+		res, err := sequencer.IntegrateBatchWithOptions(ctx, logID, opts)
+		if err == storage.ErrTreeNeedsInit {
+			// Nothing can be sequenced until InitLog stores the first root.
+			glog.V(1).Infof("%v: log not initialised, not sequencing", logID)
+			return 0, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to integrate batch for %v: %v", logID, err)
+		}
+		leaves += res.Leaves
+		more := s.updateCatchUp(ctx, sequencer, logID, opts, res, info.TimeSource.Now())
+		if !more || batch >= s.catchUp.MaxBatches || ctx.Err() != nil {
+			return leaves, nil
+		}
 	}
-	return leaves, nil
 }
 
 // getSigner returns a signer for the given tree.
-// Signers are cached, so only one will be created per tree.
+// Signers are cached, so only one will be created per tree. If preflight is
+// set, a signer is only cached once it passes preflightSigner, so a log whose
+// key is misconfigured isn't signed until it's fixed.
 func (s *SequencerManager) getSigner(ctx context.Context, tree *trillian.Tree) (*crypto.Signer, error) {
 	s.signersMutex.Lock()
 	defer s.signersMutex.Unlock()
@@ -106,6 +167,13 @@ func (s *SequencerManager) getSigner(ctx context.Context, tree *trillian.Tree) (
 	if err != nil {
 		return nil, err
 	}
+	if s.preflight {
+		if err := preflightSigner(ctx, tree, signer, s.registry.LogStorage); err != nil {
+			requestinfo.Errorf(ctx, "%v: signer preflight failed, not signing the log: %v", tree.TreeId, err)
+			return nil, fmt.Errorf("signer preflight failed: %v", err)
+		}
+		requestinfo.Infof(ctx, "%v: signer preflight passed", tree.TreeId)
+	}
 
 	s.signers[tree.GetTreeId()] = signer
 	return signer, nil
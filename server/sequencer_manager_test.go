@@ -19,6 +19,7 @@ import (
 	"crypto"
 	"errors"
 	"fmt"
+	"strconv"
 	"testing"
 	"time"
 
@@ -31,6 +32,7 @@ import (
 	"github.com/google/trillian/crypto/keys/pem"
 	"github.com/google/trillian/crypto/sigpb"
 	"github.com/google/trillian/extension"
+	"github.com/google/trillian/log"
 	"github.com/google/trillian/merkle/rfc6962"
 	"github.com/google/trillian/quota"
 	"github.com/google/trillian/storage"
@@ -146,6 +148,36 @@ func TestSequencerManagerSingleLogNoLeaves(t *testing.T) {
 	sm.ExecutePass(ctx, logID, createTestInfo(registry))
 }
 
+func TestSequencerManagerSkipsLogInMaintenance(t *testing.T) {
+	ctx := context.Background()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	tree := proto.Clone(stestonly.LogTree).(*trillian.Tree)
+	tree.Maintenance = &trillian.MaintenanceMode{}
+	logID := tree.GetTreeId()
+	mockAdminTx := storage.NewMockReadOnlyAdminTX(mockCtrl)
+	mockAdmin := &stestonly.FakeAdminStorage{ReadOnlyTX: []storage.ReadOnlyAdminTX{mockAdminTx}}
+	// No log storage calls are expected.
+	mockTx := storage.NewMockLogTreeTX(mockCtrl)
+	fakeStorage := &stestonly.FakeLogStorage{TX: mockTx}
+
+	mockAdminTx.EXPECT().GetTree(gomock.Any(), logID).Return(tree, nil)
+	mockAdminTx.EXPECT().Commit().Return(nil)
+	mockAdminTx.EXPECT().Close().Return(nil)
+
+	registry := extension.Registry{
+		AdminStorage: mockAdmin,
+		LogStorage:   fakeStorage,
+		QuotaManager: quota.Noop(),
+	}
+
+	sm := NewSequencerManager(registry, zeroDuration)
+	if n, err := sm.ExecutePass(ctx, logID, createTestInfo(registry)); n != 0 || err != nil {
+		t.Errorf("ExecutePass() = %v, %v, want 0, nil", n, err)
+	}
+}
+
 func TestSequencerManagerCachesSigners(t *testing.T) {
 	ctx := context.Background()
 	mockCtrl := gomock.NewController(t)
@@ -347,6 +379,111 @@ func createTestInfo(registry extension.Registry) *LogOperationInfo {
 	}
 }
 
+func TestSequencerManagerCatchUp(t *testing.T) {
+	ctx := context.Background()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	defer func(qf float64) { log.QuotaIncreaseFactor = qf }(log.QuotaIncreaseFactor)
+	log.QuotaIncreaseFactor = 1
+
+	logID := stestonly.LogTree.GetTreeId()
+	label := strconv.FormatInt(logID, 10)
+	mockAdminTx := storage.NewMockReadOnlyAdminTX(mockCtrl)
+	mockAdmin := &stestonly.FakeAdminStorage{ReadOnlyTX: []storage.ReadOnlyAdminTX{mockAdminTx, mockAdminTx}}
+	mockTx := storage.NewMockLogTreeTX(mockCtrl)
+	fakeStorage := &stestonly.FakeLogStorage{TX: mockTx}
+
+	var keyProto ptypes.DynamicAny
+	if err := ptypes.UnmarshalAny(stestonly.LogTree.PrivateKey, &keyProto); err != nil {
+		t.Fatalf("Failed to unmarshal stestonly.LogTree.PrivateKey: %v", err)
+	}
+	signer, err := newSignerWithFixedSig(updatedRoot.Signature)
+	if err != nil {
+		t.Fatalf("Failed to create fake signer: %v", err)
+	}
+	keys.RegisterHandler(fakeKeyProtoHandler(keyProto.Message, signer, nil))
+	defer keys.UnregisterHandler(keyProto.Message)
+
+	// queued returns n leaves queued an hour before fakeTime.
+	queued := func(n int) []*trillian.LogLeaf {
+		leaves := make([]*trillian.LogLeaf, n)
+		for i := range leaves {
+			leaves[i] = &trillian.LogLeaf{
+				LeafValue:      []byte(fmt.Sprintf("leaf-%d", i)),
+				QueueTimestamp: testonly.MustToTimestampProto(fakeTime.Add(-time.Hour)),
+			}
+		}
+		return leaves
+	}
+
+	any := gomock.Any()
+	mockTx.EXPECT().Commit().AnyTimes().Return(nil)
+	mockTx.EXPECT().Close().AnyTimes().Return(nil)
+	mockTx.EXPECT().WriteRevision().AnyTimes().Return(testRoot0.TreeRevision + 1)
+	mockTx.EXPECT().LatestSignedLogRoot(any).AnyTimes().Return(testRoot0, nil)
+	mockTx.EXPECT().UpdateSequencedLeaves(any, any).AnyTimes().Return(nil)
+	mockTx.EXPECT().SetMerkleNodes(any, any).AnyTimes().Return(nil)
+	mockTx.EXPECT().StoreSignedLogRoot(any, any).AnyTimes().Return(nil)
+	gomock.InOrder(
+		// First pass: a full batch of old leaves switches to catch-up mode,
+		// and two more batches of the catch-up size follow.
+		mockTx.EXPECT().DequeueLeaves(any, 2, any).Return(queued(2), nil),
+		mockTx.EXPECT().DequeueLeaves(any, 5, any).Return(queued(5), nil),
+		mockTx.EXPECT().DequeueLeaves(any, 5, any).Return(queued(5), nil),
+		// Second pass: the backlog is cleared.
+		mockTx.EXPECT().DequeueLeaves(any, 5, any).Return(queued(1), nil),
+	)
+
+	mockAdminTx.EXPECT().GetTree(any, logID).Times(2).Return(stestonly.LogTree, nil)
+	mockAdminTx.EXPECT().Commit().Times(2).Return(nil)
+	mockAdminTx.EXPECT().Close().Times(2).Return(nil)
+
+	// Only the leaves sequenced before catch-up mode are replenished straight
+	// away, the others once the log has caught up.
+	qm := quota.NewMockManager(mockCtrl)
+	gomock.InOrder(
+		qm.EXPECT().PutTokens(any, 2, any).Return(nil),
+		qm.EXPECT().PutTokens(any, 11, any).Return(nil),
+	)
+
+	registry := extension.Registry{
+		AdminStorage: mockAdmin,
+		LogStorage:   fakeStorage,
+		QuotaManager: qm,
+	}
+	info := createTestInfo(registry)
+	info.BatchSize = 2
+
+	sm := NewSequencerManager(registry, zeroDuration)
+	sm.SetCatchUp(CatchUpOpts{QueueAge: time.Minute, BatchSize: 5, MaxBatches: 3, DeferQuota: true})
+
+	leaves, err := sm.ExecutePass(ctx, logID, info)
+	if err != nil {
+		t.Fatalf("ExecutePass()=%v", err)
+	}
+	if want := 12; leaves != want {
+		t.Errorf("ExecutePass()=%v leaves, want %v", leaves, want)
+	}
+	if got := seqCatchUp.Value(label); got != 1 {
+		t.Errorf("sequencer_catch_up=%v, want 1", got)
+	}
+	if got, want := seqQueueAge.Value(label), time.Hour.Seconds(); got != want {
+		t.Errorf("sequencer_queue_age=%v, want %v", got, want)
+	}
+
+	leaves, err = sm.ExecutePass(ctx, logID, info)
+	if err != nil {
+		t.Fatalf("ExecutePass()=%v", err)
+	}
+	if want := 1; leaves != want {
+		t.Errorf("ExecutePass()=%v leaves, want %v", leaves, want)
+	}
+	if got := seqCatchUp.Value(label); got != 0 {
+		t.Errorf("sequencer_catch_up=%v, want 0", got)
+	}
+}
+
 func fakeKeyProtoHandler(wantKeyProto proto.Message, signer crypto.Signer, err error) (proto.Message, keys.ProtoHandler) {
 	return wantKeyProto, func(ctx context.Context, gotKeyProto proto.Message) (crypto.Signer, error) {
 		if proto.Equal(wantKeyProto, gotKeyProto) {
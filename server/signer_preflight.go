@@ -0,0 +1,88 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/crypto/keys/der"
+	"github.com/google/trillian/storage"
+
+	tcrypto "github.com/google/trillian/crypto"
+)
+
+var signerPreflight = flag.Bool("signer_preflight", true, "If true, the signer checks that the private key of each log signs data its stored public key verifies, and that its latest root verifies, before signing any root of the log")
+
+// preflightMessagePrefix starts the data signed by the preflight check of a
+// log, so that it can't be mistaken for a root or any other signed data.
+const preflightMessagePrefix = "Trillian signer preflight v1\x00"
+
+// SignerPreflightFromFlags returns whether the signer should run
+// preflightSigner for each log before signing its roots, as set by the
+// --signer_preflight flag.
+func SignerPreflightFromFlags() bool {
+	return *signerPreflight
+}
+
+// preflightSigner checks that signer, the signer of tree, produces signatures
+// which the stored public key of tree verifies, and that the latest root of
+// tree in ls verifies with it too. A log failing the check mustn't be signed,
+// as a misconfigured key would publish roots no client can verify.
+func preflightSigner(ctx context.Context, tree *trillian.Tree, signer *tcrypto.Signer, ls storage.LogStorage) error {
+	pub, err := der.UnmarshalPublicKey(tree.GetPublicKey().GetDer())
+	if err != nil {
+		return fmt.Errorf("invalid public key: %v", err)
+	}
+
+	msg := []byte(preflightMessagePrefix + strconv.FormatInt(tree.TreeId, 10))
+	sig, err := signer.Sign(msg)
+	if err != nil {
+		return fmt.Errorf("private key failed to sign: %v", err)
+	}
+	if err := tcrypto.Verify(pub, msg, sig); err != nil {
+		return fmt.Errorf("private key doesn't match the public key: %v", err)
+	}
+
+	tx, err := ls.SnapshotForTree(ctx, tree.TreeId)
+	if err == storage.ErrTreeNeedsInit {
+		// The log isn't initialized yet, so there's no root to check.
+		if tx != nil {
+			tx.Close()
+		}
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer tx.Close()
+	root, err := tx.LatestSignedLogRoot(ctx)
+	if err != nil && err != storage.ErrTreeNeedsInit {
+		return fmt.Errorf("failed to read latest root: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if root.RootHash == nil {
+		// The log isn't initialized yet, so there's no root to check.
+		return nil
+	}
+	if err := tcrypto.VerifyLogRoot(pub, &root); err != nil {
+		return fmt.Errorf("latest root at revision %d doesn't verify with the public key: %v", root.TreeRevision, err)
+	}
+	return nil
+}
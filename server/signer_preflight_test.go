@@ -0,0 +1,97 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian"
+	"github.com/google/trillian/crypto/keys"
+	"github.com/google/trillian/crypto/keys/der"
+	"github.com/google/trillian/crypto/keyspb"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/memory"
+
+	tcrypto "github.com/google/trillian/crypto"
+	stestonly "github.com/google/trillian/storage/testonly"
+)
+
+func TestPreflightSigner(t *testing.T) {
+	ctx := context.Background()
+	// Other tests unregister the handler of the log's private key.
+	keys.RegisterHandler(&keyspb.PrivateKey{}, func(ctx context.Context, pb proto.Message) (crypto.Signer, error) {
+		return der.FromProto(pb.(*keyspb.PrivateKey))
+	})
+	defer keys.UnregisterHandler(&keyspb.PrivateKey{})
+
+	newSigner := func() *tcrypto.Signer {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("GenerateKey(): %v", err)
+		}
+		return tcrypto.NewSHA256Signer(key)
+	}
+	signer, other := newSigner(), newSigner()
+
+	ls := memory.NewLogStorage(nil)
+	as := memory.NewAdminStorage(ls)
+	created, err := storage.CreateTree(ctx, as, proto.Clone(stestonly.LogTree).(*trillian.Tree))
+	if err != nil {
+		t.Fatalf("CreateTree(): %v", err)
+	}
+	pubDER, err := der.MarshalPublicKey(signer.Public())
+	if err != nil {
+		t.Fatalf("MarshalPublicKey(): %v", err)
+	}
+	tree := proto.Clone(created).(*trillian.Tree)
+	tree.PublicKey = &keyspb.PublicKey{Der: pubDER}
+
+	storeRoot := func(s *tcrypto.Signer, timestamp int64) {
+		root := trillian.SignedLogRoot{LogId: tree.TreeId, RootHash: stestonly.LogTreeEmptyRootHash, TimestampNanos: timestamp}
+		sig, err := s.SignLogRoot(&root)
+		if err != nil {
+			t.Fatalf("SignLogRoot(): %v", err)
+		}
+		root.Signature = sig
+		if err := ls.ReadWriteTransaction(ctx, tree.TreeId, func(ctx context.Context, tx storage.LogTreeTX) error {
+			return tx.StoreSignedLogRoot(ctx, root)
+		}); err != nil {
+			t.Fatalf("StoreSignedLogRoot(): %v", err)
+		}
+	}
+
+	if err := preflightSigner(ctx, tree, signer, ls); err != nil {
+		t.Errorf("preflightSigner() of an uninitialized log: %v, want nil", err)
+	}
+	if err := preflightSigner(ctx, tree, other, ls); err == nil {
+		t.Error("preflightSigner() with a mismatched private key: nil, want err")
+	}
+
+	storeRoot(signer, 1)
+	if err := preflightSigner(ctx, tree, signer, ls); err != nil {
+		t.Errorf("preflightSigner() with a root signed by the key: %v, want nil", err)
+	}
+
+	storeRoot(other, 2)
+	if err := preflightSigner(ctx, tree, signer, ls); err == nil {
+		t.Error("preflightSigner() with a root signed by another key: nil, want err")
+	}
+}
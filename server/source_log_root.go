@@ -0,0 +1,109 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/golang/glog"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/google/trillian"
+	"github.com/google/trillian/merkle"
+	"github.com/google/trillian/merkle/hashers"
+	"github.com/google/trillian/trees"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var optsSourceLog = trees.NewGetOpts(true /* readonly */, trillian.TreeType_LOG, trillian.TreeType_PREORDERED_LOG)
+
+// verifySourceLogRoot checks that, if meta is a SourceLogRoot, the log it
+// names has a tree of its size with its root hash. It uses a consistency proof
+// between the source root and the latest root of the log, so the source root
+// doesn't need to have been signed.
+func (t *TrillianMapServer) verifySourceLogRoot(ctx context.Context, meta *any.Any) error {
+	if meta == nil || !ptypes.Is(meta, &trillian.SourceLogRoot{}) {
+		return nil
+	}
+	var src trillian.SourceLogRoot
+	if err := ptypes.UnmarshalAny(meta, &src); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid SourceLogRoot metadata: %v", err)
+	}
+	if src.TreeSize < 0 {
+		return status.Errorf(codes.InvalidArgument, "SourceLogRoot.TreeSize: %v, want >= 0", src.TreeSize)
+	}
+	if t.registry.LogStorage == nil {
+		return status.Error(codes.FailedPrecondition, "map server has no log storage to verify SourceLogRoot with")
+	}
+
+	tree, err := trees.GetTree(ctx, t.registry.AdminStorage, src.LogId, optsSourceLog)
+	if err != nil {
+		return err
+	}
+	hasher, err := hashers.NewLogHasher(tree.HashStrategy)
+	if err != nil {
+		return err
+	}
+	ctx = trees.NewContext(ctx, tree)
+
+	tx, err := t.registry.LogStorage.SnapshotForTree(ctx, src.LogId)
+	if err != nil {
+		return err
+	}
+	defer tx.Close()
+	root, err := tx.LatestSignedLogRoot(ctx)
+	if err != nil {
+		return err
+	}
+
+	mismatch := func(detail string) error {
+		return status.Errorf(codes.FailedPrecondition,
+			"log %d has no root %x at size %d: %v", src.LogId, src.RootHash, src.TreeSize, detail)
+	}
+	switch {
+	case src.TreeSize > root.TreeSize:
+		return mismatch(fmt.Sprintf("larger than the log's latest size %d", root.TreeSize))
+	case src.TreeSize == root.TreeSize:
+		if !bytes.Equal(src.RootHash, root.RootHash) {
+			return mismatch("root hash differs from the log's latest root")
+		}
+	case src.TreeSize == 0:
+		if !bytes.Equal(src.RootHash, hasher.EmptyRoot()) {
+			return mismatch("root hash differs from the empty root")
+		}
+	default:
+		fetches, err := merkle.ConsistencyProofNodes(src.TreeSize, root.TreeSize, root.TreeSize)
+		if err != nil {
+			return err
+		}
+		proof, err := fetchNodesAndBuildProof(ctx, tx, hasher, tx.ReadRevision(), 0, fetches)
+		if err != nil {
+			return err
+		}
+		v := merkle.NewLogVerifier(hasher)
+		if err := v.VerifyConsistencyProof(src.TreeSize, root.TreeSize, src.RootHash, root.RootHash, proof.Hashes); err != nil {
+			return mismatch(err.Error())
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		glog.Warningf("%v: Commit failed for verifySourceLogRoot: %v", src.LogId, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"crypto"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/trillian"
+	"github.com/google/trillian/client"
+	"github.com/google/trillian/crypto/keys"
+	"github.com/google/trillian/crypto/keys/der"
+	"github.com/google/trillian/crypto/keyspb"
+	"github.com/google/trillian/extension"
+	"github.com/google/trillian/merkle"
+	"github.com/google/trillian/merkle/rfc6962"
+	"github.com/google/trillian/quota"
+	"github.com/google/trillian/storage/memory"
+	"github.com/google/trillian/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	stestonly "github.com/google/trillian/storage/testonly"
+)
+
+func TestVerifySourceLogRoot(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// Other tests unregister the handler of the log's private key.
+	keys.RegisterHandler(&keyspb.PrivateKey{}, func(ctx context.Context, pb proto.Message) (crypto.Signer, error) {
+		return der.FromProto(pb.(*keyspb.PrivateKey))
+	})
+
+	ls := memory.NewLogStorage(nil)
+	registry := extension.Registry{
+		AdminStorage: memory.NewAdminStorage(ls),
+		LogStorage:   ls,
+		QuotaManager: quota.Noop(),
+	}
+	e, err := New(registry, Options{
+		Signer: &LogOperationInfo{
+			BatchSize:   10,
+			NumWorkers:  1,
+			RunInterval: 50 * time.Millisecond,
+		},
+	})
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	if err := e.Start(ctx); err != nil {
+		t.Fatalf("Start(): %v", err)
+	}
+	defer e.Stop()
+
+	tree, err := client.CreateAndInitTree(ctx, &trillian.CreateTreeRequest{Tree: stestonly.LogTree}, e.AdminClient(), nil, e.LogClient())
+	if err != nil {
+		t.Fatalf("CreateAndInitTree(): %v", err)
+	}
+	lc, err := client.NewFromTree(e.LogClient(), tree)
+	if err != nil {
+		t.Fatalf("NewFromTree(): %v", err)
+	}
+	mt := merkle.NewInMemoryMerkleTree(rfc6962.DefaultHasher)
+	for _, leaf := range []string{"one", "two", "three", "four", "five"} {
+		// AddLeaf waits for the leaf to be integrated by the embedded signer.
+		if err := lc.AddLeaf(ctx, []byte(leaf)); err != nil {
+			t.Fatalf("AddLeaf(%q): %v", leaf, err)
+		}
+		if _, _, err := mt.AddLeaf([]byte(leaf)); err != nil {
+			t.Fatalf("AddLeaf(%q): %v", leaf, err)
+		}
+	}
+
+	rootAt := func(size int64) []byte {
+		if size == 0 {
+			return rfc6962.DefaultHasher.EmptyRoot()
+		}
+		return mt.RootAtSnapshot(size).Hash()
+	}
+	for _, test := range []struct {
+		desc     string
+		src      *trillian.SourceLogRoot
+		wantCode codes.Code
+	}{
+		{desc: "empty", src: &trillian.SourceLogRoot{LogId: tree.TreeId, TreeSize: 0, RootHash: rootAt(0)}},
+		{desc: "earlier", src: &trillian.SourceLogRoot{LogId: tree.TreeId, TreeSize: 3, RootHash: rootAt(3)}},
+		{desc: "latest", src: &trillian.SourceLogRoot{LogId: tree.TreeId, TreeSize: 5, RootHash: rootAt(5)}},
+		{desc: "wrongEmpty", src: &trillian.SourceLogRoot{LogId: tree.TreeId, TreeSize: 0, RootHash: rootAt(3)}, wantCode: codes.FailedPrecondition},
+		{desc: "wrongEarlier", src: &trillian.SourceLogRoot{LogId: tree.TreeId, TreeSize: 3, RootHash: rootAt(2)}, wantCode: codes.FailedPrecondition},
+		{desc: "wrongLatest", src: &trillian.SourceLogRoot{LogId: tree.TreeId, TreeSize: 5, RootHash: rootAt(4)}, wantCode: codes.FailedPrecondition},
+		{desc: "future", src: &trillian.SourceLogRoot{LogId: tree.TreeId, TreeSize: 6, RootHash: rootAt(5)}, wantCode: codes.FailedPrecondition},
+		{desc: "negativeSize", src: &trillian.SourceLogRoot{LogId: tree.TreeId, TreeSize: -1}, wantCode: codes.InvalidArgument},
+		{desc: "unknownLog", src: &trillian.SourceLogRoot{LogId: tree.TreeId + 1, TreeSize: 0, RootHash: rootAt(0)}, wantCode: codes.NotFound},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			meta, err := ptypes.MarshalAny(test.src)
+			if err != nil {
+				t.Fatalf("MarshalAny(): %v", err)
+			}
+			s := NewTrillianMapServer(registry, util.SystemTimeSource{})
+			err = s.verifySourceLogRoot(ctx, meta)
+			if got := status.Code(err); got != test.wantCode {
+				t.Errorf("verifySourceLogRoot()=%v; want code %v", err, test.wantCode)
+			}
+		})
+	}
+
+	// Other metadata isn't checked.
+	s := NewTrillianMapServer(extension.Registry{}, util.SystemTimeSource{})
+	meta, err := ptypes.MarshalAny(&trillian.SignedLogRoot{})
+	if err != nil {
+		t.Fatalf("MarshalAny(): %v", err)
+	}
+	if err := s.verifySourceLogRoot(ctx, meta); err != nil {
+		t.Errorf("verifySourceLogRoot(SignedLogRoot)=%v; want nil", err)
+	}
+	if err := s.verifySourceLogRoot(ctx, nil); err != nil {
+		t.Errorf("verifySourceLogRoot(nil)=%v; want nil", err)
+	}
+	// Source log roots can't be verified without log storage.
+	meta, err = ptypes.MarshalAny(&trillian.SourceLogRoot{LogId: tree.TreeId})
+	if err != nil {
+		t.Fatalf("MarshalAny(): %v", err)
+	}
+	if err := s.verifySourceLogRoot(ctx, meta); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("verifySourceLogRoot() without log storage=%v; want code %v", err, codes.FailedPrecondition)
+	}
+}
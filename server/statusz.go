@@ -0,0 +1,326 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/google/trillian"
+	"github.com/google/trillian/extension"
+	"github.com/google/trillian/quota"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/util"
+)
+
+// maxRecentPassErrors is the number of the latest failed passes of each log
+// which a LogOperationManager keeps for the status page.
+const maxRecentPassErrors = 5
+
+// PassError is a failed pass of a LogOperation on a log.
+type PassError struct {
+	Time  time.Time `json:"time"`
+	Error string    `json:"error"`
+}
+
+// logPassStatus is the outcome of the latest passes on a log.
+type logPassStatus struct {
+	lastPass    time.Time
+	lastSuccess time.Time
+	lastItems   int
+	// errors are the latest failed passes, newest first.
+	errors []PassError
+}
+
+// QuotaStatusz is the state of one of the quotas of a tree.
+type QuotaStatusz struct {
+	Spec string `json:"spec"`
+	// Available is the number of tokens available, unless Unlimited.
+	Available int  `json:"available"`
+	Unlimited bool `json:"unlimited,omitempty"`
+}
+
+// TreeStatusz is the state of a tree as served by StatusPage. Fields which
+// don't apply to the tree, or which the server doesn't know, are unset.
+type TreeStatusz struct {
+	TreeID      int64  `json:"tree_id"`
+	DisplayName string `json:"display_name,omitempty"`
+	TreeType    string `json:"tree_type"`
+	TreeState   string `json:"tree_state"`
+	// Maintenance is the reason the tree is in maintenance, or "maintenance"
+	// if none was given.
+	Maintenance string `json:"maintenance,omitempty"`
+
+	// RootRevision, RootSize and RootTimestamp describe the latest signed
+	// root of the tree, and RootAge is its age.
+	RootRevision  int64      `json:"root_revision"`
+	RootSize      int64      `json:"root_size,omitempty"`
+	RootTimestamp *time.Time `json:"root_timestamp,omitempty"`
+	RootAge       string     `json:"root_age,omitempty"`
+
+	// QueueDepth is the number of leaves waiting to be integrated into a log,
+	// and OldestQueuedAge the age of the oldest of them.
+	QueueDepth      *int64 `json:"queue_depth,omitempty"`
+	OldestQueuedAge string `json:"oldest_queued_age,omitempty"`
+
+	Quotas []QuotaStatusz `json:"quotas,omitempty"`
+
+	// Master reports whether this server holds the mastership of a log,
+	// i.e. is its signer. Unset on servers which don't sign logs.
+	Master *bool `json:"master,omitempty"`
+	// LastPass and LastSuccess are the times of the latest signer pass on
+	// the log, and of the latest successful one. LastItems is the number of
+	// leaves the latest successful pass integrated.
+	LastPass    *time.Time `json:"last_pass,omitempty"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	LastItems   int        `json:"last_items,omitempty"`
+	// PassErrors are the latest failed signer passes on the log, newest
+	// first.
+	PassErrors []PassError `json:"pass_errors,omitempty"`
+
+	// StatusErrors are the errors which prevented parts of this status from
+	// being gathered.
+	StatusErrors []string `json:"status_errors,omitempty"`
+}
+
+// Statusz is the state of the trees of a server, as served by StatusPage.
+type Statusz struct {
+	InstanceID string        `json:"instance_id,omitempty"`
+	Time       time.Time     `json:"time"`
+	Trees      []TreeStatusz `json:"trees"`
+}
+
+// StatusPage serves a summary of the state of each tree, for on-call
+// engineers to have a single place to look at during incidents. It is served
+// as HTML, or as JSON when requested with the format=json form value or a
+// JSON Accept header.
+type StatusPage struct {
+	Registry extension.Registry
+	// Operations, if set, is the LogOperationManager of a signer, whose
+	// mastership and pass outcomes are shown for each log.
+	Operations *LogOperationManager
+	// InstanceID identifies the server on the page, e.g. as mastership
+	// holder.
+	InstanceID string
+	// TimeSource defaults to util.SystemTimeSource.
+	TimeSource util.TimeSource
+}
+
+// Status gathers the state of all the trees which aren't deleted. Failures
+// to gather parts of the state of a tree are reported in its StatusErrors.
+func (p *StatusPage) Status(ctx context.Context) (*Statusz, error) {
+	ts := p.TimeSource
+	if ts == nil {
+		ts = util.SystemTimeSource{}
+	}
+	now := ts.Now()
+	trees, err := storage.ListTrees(ctx, p.Registry.AdminStorage, false /* includeDeleted */)
+	if err != nil {
+		return nil, err
+	}
+	s := &Statusz{InstanceID: p.InstanceID, Time: now, Trees: make([]TreeStatusz, 0, len(trees))}
+	for _, tree := range trees {
+		s.Trees = append(s.Trees, p.treeStatus(ctx, tree, now))
+	}
+	return s, nil
+}
+
+func (p *StatusPage) treeStatus(ctx context.Context, tree *trillian.Tree, now time.Time) TreeStatusz {
+	st := TreeStatusz{
+		TreeID:      tree.TreeId,
+		DisplayName: tree.DisplayName,
+		TreeType:    tree.TreeType.String(),
+		TreeState:   tree.TreeState.String(),
+	}
+	if m := tree.Maintenance; m != nil {
+		st.Maintenance = m.Reason
+		if st.Maintenance == "" {
+			st.Maintenance = "maintenance"
+		}
+	}
+	fail := func(format string, args ...interface{}) {
+		st.StatusErrors = append(st.StatusErrors, fmt.Sprintf(format, args...))
+	}
+
+	var rootNanos int64
+	switch tree.TreeType {
+	case trillian.TreeType_LOG, trillian.TreeType_PREORDERED_LOG:
+		if root, err := p.latestLogRoot(ctx, tree.TreeId); err != nil {
+			fail("latest root: %v", err)
+		} else {
+			st.RootRevision, st.RootSize, rootNanos = root.TreeRevision, root.TreeSize, root.TimestampNanos
+		}
+		if inspector, ok := p.Registry.LogStorage.(storage.LeafQueueInspector); ok {
+			sample, err := inspector.SampleQueue(ctx, tree.TreeId, storage.QueueSampleOptions{Now: now, OldestLeaves: 1})
+			if err != nil {
+				fail("queue: %v", err)
+			} else {
+				st.QueueDepth = &sample.LeafCount
+				if len(sample.OldestLeaves) > 0 {
+					st.OldestQueuedAge = now.Sub(sample.OldestLeaves[0].QueueTimestamp).String()
+				}
+			}
+		}
+		if p.Operations != nil {
+			master, pass := p.Operations.logStatus(tree.TreeId)
+			st.Master = &master
+			if !pass.lastPass.IsZero() {
+				st.LastPass = &pass.lastPass
+			}
+			if !pass.lastSuccess.IsZero() {
+				st.LastSuccess = &pass.lastSuccess
+				st.LastItems = pass.lastItems
+			}
+			st.PassErrors = pass.errors
+		}
+	case trillian.TreeType_MAP:
+		if root, err := p.latestMapRoot(ctx, tree.TreeId); err != nil {
+			fail("latest root: %v", err)
+		} else {
+			st.RootRevision, rootNanos = root.MapRevision, root.TimestampNanos
+		}
+	}
+	if rootNanos > 0 {
+		rootTime := time.Unix(0, rootNanos)
+		st.RootTimestamp = &rootTime
+		st.RootAge = now.Sub(rootTime).String()
+	}
+
+	if qm := p.Registry.QuotaManager; qm != nil {
+		specs := []quota.Spec{
+			{Group: quota.Tree, Kind: quota.Read, TreeID: tree.TreeId},
+			{Group: quota.Tree, Kind: quota.Write, TreeID: tree.TreeId},
+		}
+		tokens, err := qm.PeekTokens(ctx, specs)
+		if err != nil {
+			fail("quota: %v", err)
+		}
+		for _, spec := range specs {
+			if n, ok := tokens[spec]; ok {
+				st.Quotas = append(st.Quotas, QuotaStatusz{Spec: spec.Name(), Available: n, Unlimited: n == quota.MaxTokens})
+			}
+		}
+	}
+	return st
+}
+
+func (p *StatusPage) latestLogRoot(ctx context.Context, treeID int64) (trillian.SignedLogRoot, error) {
+	if p.Registry.LogStorage == nil {
+		return trillian.SignedLogRoot{}, fmt.Errorf("no log storage")
+	}
+	tx, err := p.Registry.LogStorage.SnapshotForTree(ctx, treeID)
+	if err == storage.ErrTreeNeedsInit {
+		if tx != nil {
+			tx.Close()
+		}
+		return trillian.SignedLogRoot{}, nil
+	} else if err != nil {
+		return trillian.SignedLogRoot{}, err
+	}
+	defer tx.Close()
+	root, err := tx.LatestSignedLogRoot(ctx)
+	if err != nil && err != storage.ErrTreeNeedsInit {
+		return trillian.SignedLogRoot{}, err
+	}
+	return root, tx.Commit()
+}
+
+func (p *StatusPage) latestMapRoot(ctx context.Context, treeID int64) (trillian.SignedMapRoot, error) {
+	if p.Registry.MapStorage == nil {
+		return trillian.SignedMapRoot{}, fmt.Errorf("no map storage")
+	}
+	tx, err := p.Registry.MapStorage.SnapshotForTree(ctx, treeID)
+	if err == storage.ErrTreeNeedsInit {
+		if tx != nil {
+			tx.Close()
+		}
+		return trillian.SignedMapRoot{}, nil
+	} else if err != nil {
+		return trillian.SignedMapRoot{}, err
+	}
+	defer tx.Close()
+	root, err := tx.LatestSignedMapRoot(ctx)
+	if err != nil && err != storage.ErrTreeNeedsInit {
+		return trillian.SignedMapRoot{}, err
+	}
+	return root, tx.Commit()
+}
+
+var statusTemplate = template.Must(template.New("statusz").Funcs(template.FuncMap{
+	"deref": func(v interface{}) interface{} {
+		switch v := v.(type) {
+		case *int64:
+			return *v
+		case *bool:
+			return *v
+		case *time.Time:
+			return v.UTC().Format(time.RFC3339)
+		}
+		return v
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head><title>Trillian status</title></head>
+<body>
+<h1>Trillian status{{if .InstanceID}} of {{.InstanceID}}{{end}}</h1>
+<p>Generated at {{.Time.UTC.Format "2006-01-02T15:04:05Z07:00"}}. <a href="?format=json">JSON</a></p>
+<table border="1" cellpadding="3">
+<tr><th>Tree</th><th>Type</th><th>State</th><th>Latest root</th><th>Root age</th><th>Queue</th><th>Quotas</th><th>Master</th><th>Latest pass</th><th>Errors</th></tr>
+{{range .Trees}}<tr>
+<td>{{.TreeID}}{{if .DisplayName}}<br>{{.DisplayName}}{{end}}</td>
+<td>{{.TreeType}}</td>
+<td>{{.TreeState}}{{if .Maintenance}}<br>in maintenance: {{.Maintenance}}{{end}}</td>
+<td>revision {{.RootRevision}}{{if .RootSize}}, size {{.RootSize}}{{end}}</td>
+<td>{{.RootAge}}</td>
+<td>{{if .QueueDepth}}{{deref .QueueDepth}} leaves{{if .OldestQueuedAge}}, oldest {{.OldestQueuedAge}}{{end}}{{end}}</td>
+<td>{{range .Quotas}}{{.Spec}}: {{if .Unlimited}}unlimited{{else}}{{.Available}}{{end}}<br>{{end}}</td>
+<td>{{if .Master}}{{deref .Master}}{{end}}</td>
+<td>{{if .LastPass}}{{deref .LastPass}}{{end}}{{if .LastSuccess}}<br>last success {{deref .LastSuccess}}, {{.LastItems}} leaves{{end}}</td>
+<td>{{range .PassErrors}}{{.Time.UTC.Format "2006-01-02T15:04:05Z07:00"}}: {{.Error}}<br>{{end}}{{range .StatusErrors}}{{.}}<br>{{end}}</td>
+</tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// ServeHTTP implements http.Handler.
+func (p *StatusPage) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s, err := p.Status(req.Context())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to list trees: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if req.FormValue("format") == "json" || strings.Contains(req.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(s); err != nil {
+			glog.Warningf("Failed to write status: %v", err)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := statusTemplate.Execute(w, s); err != nil {
+		glog.Warningf("Failed to write status: %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/extension"
+	"github.com/google/trillian/quota"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/memory"
+	"github.com/google/trillian/util"
+
+	stestonly "github.com/google/trillian/storage/testonly"
+)
+
+func TestStatusPage(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	ls := memory.NewLogStorage(nil)
+	as := memory.NewAdminStorage(ls)
+	tree, err := storage.CreateTree(ctx, as, stestonly.LogTree)
+	if err != nil {
+		t.Fatalf("CreateTree(): %v", err)
+	}
+	if err := ls.ReadWriteTransaction(ctx, tree.TreeId, func(ctx context.Context, tx storage.LogTreeTX) error {
+		root := trillian.SignedLogRoot{
+			LogId:          tree.TreeId,
+			RootHash:       stestonly.LogTreeEmptyRootHash,
+			TimestampNanos: now.Add(-time.Minute).UnixNano(),
+		}
+		if err := tx.StoreSignedLogRoot(ctx, root); err != nil {
+			return err
+		}
+		leaf := &trillian.LogLeaf{LeafIdentityHash: make([]byte, 32), MerkleLeafHash: make([]byte, 32)}
+		_, err := tx.QueueLeaves(ctx, []*trillian.LogLeaf{leaf}, now.Add(-10*time.Second))
+		return err
+	}); err != nil {
+		t.Fatalf("ReadWriteTransaction(): %v", err)
+	}
+
+	registry := extension.Registry{AdminStorage: as, LogStorage: ls, QuotaManager: quota.Noop()}
+	ops := NewLogOperationManager(LogOperationInfo{Registry: registry, TimeSource: util.NewFakeTimeSource(now)}, nil)
+	ops.updateHeldIDs(ctx, []int64{tree.TreeId}, []int64{tree.TreeId})
+	ops.recordPass(tree.TreeId, now.Add(-2*time.Second), 0, errors.New("pass failed"))
+	ops.recordPass(tree.TreeId, now.Add(-time.Second), 3, nil)
+
+	page := &StatusPage{Registry: registry, Operations: ops, InstanceID: "signer-1", TimeSource: util.NewFakeTimeSource(now)}
+	s, err := page.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status(): %v", err)
+	}
+	if got, want := s.InstanceID, "signer-1"; got != want {
+		t.Errorf("Status().InstanceID: %v, want %v", got, want)
+	}
+	if len(s.Trees) != 1 {
+		t.Fatalf("Status().Trees: %d trees, want 1", len(s.Trees))
+	}
+	st := s.Trees[0]
+	if len(st.StatusErrors) > 0 {
+		t.Errorf("StatusErrors: %v, want none", st.StatusErrors)
+	}
+	if got, want := st.RootAge, time.Minute.String(); got != want {
+		t.Errorf("RootAge: %v, want %v", got, want)
+	}
+	if st.QueueDepth == nil || *st.QueueDepth != 1 {
+		t.Errorf("QueueDepth: %v, want 1", st.QueueDepth)
+	}
+	if got, want := st.OldestQueuedAge, (10 * time.Second).String(); got != want {
+		t.Errorf("OldestQueuedAge: %v, want %v", got, want)
+	}
+	if len(st.Quotas) != 2 || !st.Quotas[0].Unlimited || !st.Quotas[1].Unlimited {
+		t.Errorf("Quotas: %+v, want 2 unlimited quotas", st.Quotas)
+	}
+	if st.Master == nil || !*st.Master {
+		t.Errorf("Master: %v, want true", st.Master)
+	}
+	if st.LastPass == nil || !st.LastPass.Equal(now.Add(-time.Second)) {
+		t.Errorf("LastPass: %v, want %v", st.LastPass, now.Add(-time.Second))
+	}
+	if got, want := st.LastItems, 3; got != want {
+		t.Errorf("LastItems: %v, want %v", got, want)
+	}
+	if len(st.PassErrors) != 1 || st.PassErrors[0].Error != "pass failed" {
+		t.Errorf("PassErrors: %+v, want the failed pass", st.PassErrors)
+	}
+
+	for _, test := range []struct {
+		url, accept, wantType string
+	}{
+		{url: "/statusz", wantType: "text/html"},
+		{url: "/statusz?format=json", wantType: "application/json"},
+		{url: "/statusz", accept: "application/json", wantType: "application/json"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, test.url, nil)
+		if test.accept != "" {
+			req.Header.Set("Accept", test.accept)
+		}
+		w := httptest.NewRecorder()
+		page.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("ServeHTTP(%v, %q): status %v, want %v", test.url, test.accept, w.Code, http.StatusOK)
+			continue
+		}
+		if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, test.wantType) {
+			t.Errorf("ServeHTTP(%v, %q): Content-Type %q, want %v", test.url, test.accept, got, test.wantType)
+		}
+		if test.wantType == "application/json" {
+			var got Statusz
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Errorf("ServeHTTP(%v, %q): invalid JSON: %v", test.url, test.accept, err)
+			} else if len(got.Trees) != 1 || got.Trees[0].TreeID != tree.TreeId {
+				t.Errorf("ServeHTTP(%v, %q): trees %+v, want tree %v", test.url, test.accept, got.Trees, tree.TreeId)
+			}
+		}
+	}
+}
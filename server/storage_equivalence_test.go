@@ -0,0 +1,229 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	mrand "math/rand"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/trillian"
+	"github.com/google/trillian/crypto/sigpb"
+	"github.com/google/trillian/extension"
+	"github.com/google/trillian/log"
+	"github.com/google/trillian/merkle"
+	"github.com/google/trillian/merkle/rfc6962"
+	"github.com/google/trillian/quota"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/memory"
+	"github.com/google/trillian/storage/mysql"
+	"github.com/google/trillian/storage/storagepb"
+	"github.com/google/trillian/storage/testdb"
+	"github.com/google/trillian/util"
+
+	tcrypto "github.com/google/trillian/crypto"
+	stestonly "github.com/google/trillian/storage/testonly"
+)
+
+// equivalenceBackend is a storage backend checked by TestStorageEquivalence.
+type equivalenceBackend struct {
+	desc string
+	// setup returns the registry of the backend, and the tree to create in it.
+	setup func(ctx context.Context, t *testing.T) (extension.Registry, *trillian.Tree)
+}
+
+func equivalenceBackends() []equivalenceBackend {
+	backends := []equivalenceBackend{
+		{
+			desc: "memory",
+			setup: func(ctx context.Context, t *testing.T) (extension.Registry, *trillian.Tree) {
+				ls := memory.NewLogStorage(nil)
+				return extension.Registry{AdminStorage: memory.NewAdminStorage(ls), LogStorage: ls, QuotaManager: quota.Noop()}, stestonly.LogTree
+			},
+		},
+		{
+			desc: "sql",
+			setup: func(ctx context.Context, t *testing.T) (extension.Registry, *trillian.Tree) {
+				return sqlRegistry(ctx, t), stestonly.LogTree
+			},
+		},
+	}
+	// Deleting older subtree revisions relies on MySQL syntax.
+	if testdb.Default().IsMySQL() {
+		backends = append(backends, equivalenceBackend{
+			desc: "mysqlLatestSubtreesOnly",
+			setup: func(ctx context.Context, t *testing.T) (extension.Registry, *trillian.Tree) {
+				settings, err := ptypes.MarshalAny(&storagepb.LogStorageSettings{SubtreeRevisions: storagepb.LogStorageSettings_LATEST_ONLY})
+				if err != nil {
+					t.Fatalf("MarshalAny(): %v", err)
+				}
+				tree := proto.Clone(stestonly.LogTree).(*trillian.Tree)
+				tree.StorageSettings = settings
+				return sqlRegistry(ctx, t), tree
+			},
+		})
+	}
+	return backends
+}
+
+func sqlRegistry(ctx context.Context, t *testing.T) extension.Registry {
+	t.Helper()
+	db, err := testdb.NewTrillianDB(ctx)
+	if err != nil {
+		t.Fatalf("NewTrillianDB(): %v", err)
+	}
+	return extension.Registry{AdminStorage: mysql.NewAdminStorage(db), LogStorage: mysql.NewLogStorage(db, nil), QuotaManager: quota.Noop()}
+}
+
+// TestStorageEquivalence integrates the same random batches of leaves into a
+// log in each storage backend and into an in-memory Merkle tree, and checks
+// that the roots and proofs served from storage match those of the in-memory
+// tree after every batch.
+func TestStorageEquivalence(t *testing.T) {
+	seed := time.Now().UnixNano()
+	t.Logf("seed %d", seed)
+	for _, backend := range equivalenceBackends() {
+		t.Run(backend.desc, func(t *testing.T) {
+			testStorageEquivalence(t, backend, mrand.New(mrand.NewSource(seed)))
+		})
+	}
+}
+
+func testStorageEquivalence(t *testing.T, backend equivalenceBackend, rnd *mrand.Rand) {
+	ctx := context.Background()
+	registry, treeConfig := backend.setup(ctx, t)
+	tree, err := storage.CreateTree(ctx, registry.AdminStorage, treeConfig)
+	if err != nil {
+		t.Fatalf("CreateTree(): %v", err)
+	}
+	logID := tree.TreeId
+	hasher := rfc6962.DefaultHasher
+	if err := registry.LogStorage.ReadWriteTransaction(ctx, logID, func(ctx context.Context, tx storage.LogTreeTX) error {
+		return tx.StoreSignedLogRoot(ctx, trillian.SignedLogRoot{LogId: logID, RootHash: hasher.EmptyRoot(), Signature: &sigpb.DigitallySigned{}})
+	}); err != nil {
+		t.Fatalf("StoreSignedLogRoot(): %v", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey(): %v", err)
+	}
+	sequencer := log.NewSequencer(hasher, util.SystemTimeSource{}, registry.LogStorage, tcrypto.NewSHA256Signer(key), nil, quota.Noop())
+	server := NewTrillianLogRPCServer(registry, util.SystemTimeSource{})
+	mt := merkle.NewInMemoryMerkleTree(hasher)
+
+	next := 0
+	for batch := 0; batch < 25; batch++ {
+		leaves := make([]*trillian.LogLeaf, rnd.Intn(40))
+		queued := make(map[string]bool)
+		for i := range leaves {
+			value := []byte(fmt.Sprintf("leaf %d", next))
+			next++
+			hash, err := hasher.HashLeaf(value)
+			if err != nil {
+				t.Fatalf("HashLeaf(): %v", err)
+			}
+			leaves[i] = &trillian.LogLeaf{LeafValue: value, MerkleLeafHash: hash, LeafIdentityHash: hash}
+			queued[string(value)] = true
+		}
+		if _, err := registry.LogStorage.QueueLeaves(ctx, logID, leaves, time.Now()); err != nil {
+			t.Fatalf("QueueLeaves(): %v", err)
+		}
+		if n, err := sequencer.IntegrateBatch(ctx, logID, len(leaves), 0, 0); err != nil || n != len(leaves) {
+			t.Fatalf("IntegrateBatch(): (%d, %v), want (%d, nil)", n, err, len(leaves))
+		}
+
+		// Storage may dequeue leaves queued at the same time in any order, so
+		// the in-memory tree takes them in the order they were sequenced.
+		if len(leaves) > 0 {
+			resp, err := server.GetLeavesByRange(ctx, &trillian.GetLeavesByRangeRequest{LogId: logID, StartIndex: mt.LeafCount(), Count: int64(len(leaves))})
+			if err != nil {
+				t.Fatalf("GetLeavesByRange(): %v", err)
+			}
+			if got, want := len(resp.Leaves), len(leaves); got != want {
+				t.Fatalf("after batch %d: GetLeavesByRange() returned %d leaves, want %d", batch, got, want)
+			}
+			for _, leaf := range resp.Leaves {
+				if !queued[string(leaf.LeafValue)] {
+					t.Fatalf("after batch %d: sequenced leaf %q wasn't queued in the batch", batch, leaf.LeafValue)
+				}
+				delete(queued, string(leaf.LeafValue))
+				if _, _, err := mt.AddLeaf(leaf.LeafValue); err != nil {
+					t.Fatalf("AddLeaf(): %v", err)
+				}
+			}
+		}
+
+		size := mt.LeafCount()
+		rootResp, err := server.GetLatestSignedLogRoot(ctx, &trillian.GetLatestSignedLogRootRequest{LogId: logID})
+		if err != nil {
+			t.Fatalf("GetLatestSignedLogRoot(): %v", err)
+		}
+		root := rootResp.SignedLogRoot
+		if got, want := root.TreeSize, size; got != want {
+			t.Fatalf("after batch %d: tree size %d, want %d", batch, got, want)
+		}
+		if got, want := root.RootHash, mt.CurrentRoot().Hash(); !bytes.Equal(got, want) {
+			t.Fatalf("after batch %d: root hash %x, want %x", batch, got, want)
+		}
+		if size < 2 {
+			continue
+		}
+
+		for i := 0; i < 10; i++ {
+			snapshot := 1 + rnd.Int63n(size)
+			index := rnd.Int63n(snapshot)
+			resp, err := server.GetInclusionProof(ctx, &trillian.GetInclusionProofRequest{LogId: logID, LeafIndex: index, TreeSize: snapshot})
+			if err != nil {
+				t.Fatalf("GetInclusionProof(%d, %d): %v", index, snapshot, err)
+			}
+			// The in-memory tree indexes leaves from 1.
+			want := mt.PathToRootAtSnapshot(index+1, snapshot)
+			if err := compareProof(resp.Proof.Hashes, want); err != nil {
+				t.Errorf("after batch %d: GetInclusionProof(%d, %d): %v", batch, index, snapshot, err)
+			}
+
+			first := 1 + rnd.Int63n(size-1)
+			second := first + 1 + rnd.Int63n(size-first)
+			cResp, err := server.GetConsistencyProof(ctx, &trillian.GetConsistencyProofRequest{LogId: logID, FirstTreeSize: first, SecondTreeSize: second})
+			if err != nil {
+				t.Fatalf("GetConsistencyProof(%d, %d): %v", first, second, err)
+			}
+			if err := compareProof(cResp.Proof.Hashes, mt.SnapshotConsistency(first, second)); err != nil {
+				t.Errorf("after batch %d: GetConsistencyProof(%d, %d): %v", batch, first, second, err)
+			}
+		}
+	}
+}
+
+func compareProof(got [][]byte, want []merkle.TreeEntryDescriptor) error {
+	if len(got) != len(want) {
+		return fmt.Errorf("got %d hashes, want %d", len(got), len(want))
+	}
+	for i, h := range got {
+		if !bytes.Equal(h, want[i].Value.Hash()) {
+			return fmt.Errorf("hash %d is %x, want %x", i, h, want[i].Value.Hash())
+		}
+	}
+	return nil
+}
@@ -18,15 +18,21 @@ import (
 	"flag"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/google/trillian/monitoring"
 	"github.com/google/trillian/storage"
+	"github.com/google/trillian/util"
 )
 
 // NewStorageProviderFunc is the signature of a function which can be registered
 // to provide instances of storage providers.
 type NewStorageProviderFunc func(monitoring.MetricFactory) (StorageProvider, error)
 
+// schemaCheckTimeout is the timeout of the check of the database schema done
+// by storage providers on startup.
+const schemaCheckTimeout = 30 * time.Second
+
 var (
 	storageSystem = flag.String("storage_system", "mysql", fmt.Sprintf("Storage system to use. One of: %v", storageProviders()))
 
@@ -98,3 +104,12 @@ type StorageProvider interface {
 	// Close closes the underlying storage.
 	Close() error
 }
+
+// ElectionStorageProvider is implemented by StorageProviders whose database
+// can also run the mastership elections of log signers, so that they don't
+// need etcd.
+type ElectionStorageProvider interface {
+	// ElectionFactory returns a factory of elections run in the database,
+	// in which the local instance is identified by instanceID.
+	ElectionFactory(instanceID string) util.ElectionFactory
+}
@@ -0,0 +1,189 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage/tiles"
+)
+
+// tileRootTTL is how long the latest exported root of a log, or the fact that
+// it hasn't been exported, is cached before the bucket is read again.
+const tileRootTTL = 5 * time.Second
+
+// tileLogServer serves proofs from exported tiles where it can, and passes
+// everything else to the embedded TrillianLogServer.
+type tileLogServer struct {
+	trillian.TrillianLogServer
+	bucket tiles.Bucket
+	// checks is the embedded server if it's a TrillianLogRPCServer, whose
+	// root freshness and read memory limits apply to proofs served from
+	// tiles too.
+	checks *TrillianLogRPCServer
+
+	// mu guards roots.
+	mu    sync.Mutex
+	roots map[int64]*tileRoot
+}
+
+// tileRoot is the cached latest exported root of a log.
+type tileRoot struct {
+	// r is nil if the log hasn't been exported, and root is nil if its root
+	// hasn't been exported yet.
+	r       *tiles.Reader
+	root    *trillian.SignedLogRoot
+	fetched time.Time
+}
+
+// NewTileLogServer returns a TrillianLogServer which serves inclusion and
+// consistency proofs from the tiles exported to bucket by a tiles.Exporter,
+// without touching log storage. Requests for logs which haven't been
+// exported, or for tree sizes beyond the latest exported root, and all other
+// RPCs, are passed to next. If next is a TrillianLogRPCServer, proofs served
+// from tiles are subject to its read memory limits, and requests are passed
+// to it if the exported root is older than its root freshness allows.
+func NewTileLogServer(bucket tiles.Bucket, next trillian.TrillianLogServer) trillian.TrillianLogServer {
+	checks, _ := next.(*TrillianLogRPCServer)
+	return &tileLogServer{
+		TrillianLogServer: next,
+		bucket:            bucket,
+		checks:            checks,
+		roots:             make(map[int64]*tileRoot),
+	}
+}
+
+// latest returns a Reader for the tiles of logID, along with the latest
+// exported root, or a nil Reader if the log hasn't been exported. Both are
+// cached for tileRootTTL.
+func (s *tileLogServer) latest(ctx context.Context, logID int64) (*tiles.Reader, *trillian.SignedLogRoot, error) {
+	now := time.Now()
+	s.mu.Lock()
+	cached := s.roots[logID]
+	s.mu.Unlock()
+	if cached != nil && now.Sub(cached.fetched) < tileRootTTL {
+		return cached.reader(), cached.root, nil
+	}
+
+	e := &tileRoot{fetched: now}
+	if cached != nil {
+		e.r = cached.r
+	}
+	if e.r == nil {
+		r, err := tiles.NewReader(ctx, s.bucket, logID)
+		if err != nil && err != tiles.ErrNotExist {
+			return nil, nil, err
+		}
+		e.r = r
+	}
+	if e.r != nil {
+		root, err := e.r.LatestSignedLogRoot(ctx)
+		if err != nil && err != tiles.ErrNotExist {
+			return nil, nil, err
+		}
+		e.root = root
+	}
+	s.mu.Lock()
+	s.roots[logID] = e
+	s.mu.Unlock()
+	return e.reader(), e.root, nil
+}
+
+// reader returns the Reader to serve proofs from, which is nil unless a root
+// has been exported.
+func (e *tileRoot) reader() *tiles.Reader {
+	if e.root == nil {
+		return nil
+	}
+	return e.r
+}
+
+// servable returns whether proofs for a tree of size treeSize can be served
+// from root, the latest exported root of logID. They can't if the root is
+// too small, or older than the root freshness of the embedded server allows,
+// in which case the embedded server serves them from storage, or refuses
+// them if its root is stale as well.
+func (s *tileLogServer) servable(logID int64, root *trillian.SignedLogRoot, treeSize int64) bool {
+	if root == nil || treeSize > root.TreeSize {
+		return false
+	}
+	if s.checks != nil && s.checks.freshness.check(logID, root, s.checks.timeSource.Now()) != nil {
+		return false
+	}
+	return true
+}
+
+// account returns a readAccount for a response of logID, limited as by the
+// embedded server.
+func (s *tileLogServer) account(logID int64) *readAccount {
+	if s.checks == nil {
+		return nil
+	}
+	return s.checks.readMemory.account(logID, true)
+}
+
+func (s *tileLogServer) GetInclusionProof(ctx context.Context, req *trillian.GetInclusionProofRequest) (*trillian.GetInclusionProofResponse, error) {
+	if err := validateGetInclusionProofRequest(req); err != nil {
+		return nil, err
+	}
+	r, root, err := s.latest(ctx, req.LogId)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil || !s.servable(req.LogId, root, req.TreeSize) {
+		return s.TrillianLogServer.GetInclusionProof(ctx, req)
+	}
+
+	hashes, err := r.InclusionProof(ctx, root, req.LeafIndex, req.TreeSize)
+	if err != nil {
+		return nil, err
+	}
+	proof := &trillian.Proof{LeafIndex: req.LeafIndex, Hashes: hashes}
+	acct := s.account(req.LogId)
+	defer acct.release()
+	if err := acct.add(int64(proto.Size(proof))); err != nil {
+		return nil, err
+	}
+	return &trillian.GetInclusionProofResponse{Proof: proof}, nil
+}
+
+func (s *tileLogServer) GetConsistencyProof(ctx context.Context, req *trillian.GetConsistencyProofRequest) (*trillian.GetConsistencyProofResponse, error) {
+	if err := validateGetConsistencyProofRequest(req); err != nil {
+		return nil, err
+	}
+	r, root, err := s.latest(ctx, req.LogId)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil || !s.servable(req.LogId, root, req.SecondTreeSize) {
+		return s.TrillianLogServer.GetConsistencyProof(ctx, req)
+	}
+
+	hashes, err := r.ConsistencyProof(ctx, root, req.FirstTreeSize, req.SecondTreeSize)
+	if err != nil {
+		return nil, err
+	}
+	proof := &trillian.Proof{Hashes: hashes}
+	acct := s.account(req.LogId)
+	defer acct.release()
+	if err := acct.add(int64(proto.Size(proof))); err != nil {
+		return nil, err
+	}
+	return &trillian.GetConsistencyProofResponse{Proof: proof}, nil
+}
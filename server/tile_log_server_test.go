@@ -0,0 +1,184 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/merkle"
+	"github.com/google/trillian/merkle/rfc6962"
+	"github.com/google/trillian/storage/tiles"
+	"github.com/google/trillian/util"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// tileTestLogClient serves the leaves of mt to a tiles.Exporter.
+type tileTestLogClient struct {
+	trillian.TrillianLogClient
+	mt *merkle.InMemoryMerkleTree
+}
+
+func (c *tileTestLogClient) GetLatestSignedLogRoot(ctx context.Context, req *trillian.GetLatestSignedLogRootRequest, opts ...grpc.CallOption) (*trillian.GetLatestSignedLogRootResponse, error) {
+	return &trillian.GetLatestSignedLogRootResponse{
+		SignedLogRoot: &trillian.SignedLogRoot{TreeSize: c.mt.LeafCount(), RootHash: c.mt.CurrentRoot().Hash()},
+	}, nil
+}
+
+func (c *tileTestLogClient) GetLeavesByRange(ctx context.Context, req *trillian.GetLeavesByRangeRequest, opts ...grpc.CallOption) (*trillian.GetLeavesByRangeResponse, error) {
+	var leaves []*trillian.LogLeaf
+	for i := req.StartIndex; i < req.StartIndex+req.Count && i < c.mt.LeafCount(); i++ {
+		leaves = append(leaves, &trillian.LogLeaf{LeafIndex: i, MerkleLeafHash: c.mt.LeafHash(i + 1)})
+	}
+	return &trillian.GetLeavesByRangeResponse{Leaves: leaves}, nil
+}
+
+// fallbackLogServer records the proof requests passed to it.
+type fallbackLogServer struct {
+	trillian.TrillianLogServer
+	calls int
+}
+
+func (s *fallbackLogServer) GetInclusionProof(ctx context.Context, req *trillian.GetInclusionProofRequest) (*trillian.GetInclusionProofResponse, error) {
+	s.calls++
+	return &trillian.GetInclusionProofResponse{}, nil
+}
+
+func (s *fallbackLogServer) GetConsistencyProof(ctx context.Context, req *trillian.GetConsistencyProofRequest) (*trillian.GetConsistencyProofResponse, error) {
+	s.calls++
+	return &trillian.GetConsistencyProofResponse{}, nil
+}
+
+const tileTestLogID = 12345
+
+// exportTestTiles exports a log of 300 leaves to a new bucket, which is
+// removed by the returned function.
+func exportTestTiles(ctx context.Context, t *testing.T) (tiles.Bucket, *merkle.InMemoryMerkleTree, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "tiles")
+	if err != nil {
+		t.Fatalf("TempDir(): %v", err)
+	}
+	bucket := tiles.NewDirBucket(dir)
+
+	mt := merkle.NewInMemoryMerkleTree(rfc6962.DefaultHasher)
+	for i := 0; i < 300; i++ {
+		if _, _, err := mt.AddLeaf([]byte{byte(i), byte(i >> 8)}); err != nil {
+			t.Fatalf("AddLeaf(): %v", err)
+		}
+	}
+	e, err := tiles.NewExporter(&tileTestLogClient{mt: mt}, &trillian.Tree{
+		TreeId:       tileTestLogID,
+		TreeType:     trillian.TreeType_LOG,
+		HashStrategy: trillian.HashStrategy_RFC6962_SHA256,
+	}, bucket)
+	if err != nil {
+		t.Fatalf("NewExporter(): %v", err)
+	}
+	if _, err := e.ExportOnce(ctx); err != nil {
+		t.Fatalf("ExportOnce(): %v", err)
+	}
+	return bucket, mt, func() { os.RemoveAll(dir) }
+}
+
+func TestTileLogServer(t *testing.T) {
+	ctx := context.Background()
+	bucket, mt, cleanup := exportTestTiles(ctx, t)
+	defer cleanup()
+	const logID = tileTestLogID
+
+	next := &fallbackLogServer{}
+	s := NewTileLogServer(bucket, next)
+	verifier := merkle.NewLogVerifier(rfc6962.DefaultHasher)
+
+	incl, err := s.GetInclusionProof(ctx, &trillian.GetInclusionProofRequest{LogId: logID, LeafIndex: 17, TreeSize: 299})
+	if err != nil {
+		t.Fatalf("GetInclusionProof(): %v", err)
+	}
+	if err := verifier.VerifyInclusionProof(17, 299, incl.Proof.Hashes, mt.RootAtSnapshot(299).Hash(), mt.LeafHash(18)); err != nil {
+		t.Errorf("GetInclusionProof(): invalid proof: %v", err)
+	}
+	cons, err := s.GetConsistencyProof(ctx, &trillian.GetConsistencyProofRequest{LogId: logID, FirstTreeSize: 10, SecondTreeSize: 300})
+	if err != nil {
+		t.Fatalf("GetConsistencyProof(): %v", err)
+	}
+	if err := verifier.VerifyConsistencyProof(10, 300, mt.RootAtSnapshot(10).Hash(), mt.RootAtSnapshot(300).Hash(), cons.Proof.Hashes); err != nil {
+		t.Errorf("GetConsistencyProof(): invalid proof: %v", err)
+	}
+	if next.calls != 0 {
+		t.Errorf("%d requests passed to next server, want 0", next.calls)
+	}
+
+	// Requests beyond the exported size, or for other logs, fall through.
+	for _, req := range []*trillian.GetInclusionProofRequest{
+		{LogId: logID, LeafIndex: 17, TreeSize: 301},
+		{LogId: logID + 1, LeafIndex: 17, TreeSize: 20},
+	} {
+		if _, err := s.GetInclusionProof(ctx, req); err != nil {
+			t.Fatalf("GetInclusionProof(%+v): %v", req, err)
+		}
+	}
+	if _, err := s.GetConsistencyProof(ctx, &trillian.GetConsistencyProofRequest{LogId: logID, FirstTreeSize: 10, SecondTreeSize: 301}); err != nil {
+		t.Fatalf("GetConsistencyProof(): %v", err)
+	}
+	if next.calls != 3 {
+		t.Errorf("%d requests passed to next server, want 3", next.calls)
+	}
+}
+
+func TestTileLogServerChecks(t *testing.T) {
+	ctx := context.Background()
+	bucket, _, cleanup := exportTestTiles(ctx, t)
+	defer cleanup()
+	// The exported root has no timestamp, so is older than any maximum age.
+	req := &trillian.GetInclusionProofRequest{LogId: tileTestLogID, LeafIndex: 17, TreeSize: 299}
+
+	for _, test := range []struct {
+		desc      string
+		checks    *TrillianLogRPCServer
+		wantCode  codes.Code
+		wantCalls int
+	}{
+		{desc: "noChecks", checks: &TrillianLogRPCServer{timeSource: util.SystemTimeSource{}}},
+		{
+			desc:      "staleRoot",
+			checks:    &TrillianLogRPCServer{freshness: &RootFreshness{MaxAge: time.Hour, Code: codes.Unavailable}, timeSource: util.SystemTimeSource{}},
+			wantCalls: 1,
+		},
+		{
+			desc:     "responseTooLarge",
+			checks:   &TrillianLogRPCServer{readMemory: &ReadMemoryLimiter{MaxResponseBytes: 10}, timeSource: util.SystemTimeSource{}},
+			wantCode: codes.ResourceExhausted,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			next := &fallbackLogServer{}
+			s := &tileLogServer{TrillianLogServer: next, bucket: bucket, checks: test.checks, roots: make(map[int64]*tileRoot)}
+			_, err := s.GetInclusionProof(ctx, req)
+			if got := status.Code(err); got != test.wantCode {
+				t.Errorf("GetInclusionProof(): %v, want code %v", err, test.wantCode)
+			}
+			if next.calls != test.wantCalls {
+				t.Errorf("%d requests passed to next server, want %d", next.calls, test.wantCalls)
+			}
+		})
+	}
+}
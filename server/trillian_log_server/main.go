@@ -21,16 +21,16 @@ import (
 	"flag"
 
 	"github.com/golang/glog"
-	"github.com/golang/protobuf/proto"
 	"github.com/google/trillian"
 	"github.com/google/trillian/cmd"
-	"github.com/google/trillian/crypto/keys/der"
-	"github.com/google/trillian/crypto/keyspb"
 	"github.com/google/trillian/extension"
 	"github.com/google/trillian/monitoring/prometheus"
 	"github.com/google/trillian/quota/etcd/quotaapi"
 	"github.com/google/trillian/quota/etcd/quotapb"
 	"github.com/google/trillian/server"
+	"github.com/google/trillian/server/interceptor"
+	"github.com/google/trillian/storage/tiles"
+	"github.com/google/trillian/testonly/chaos"
 	"github.com/google/trillian/util"
 	"github.com/google/trillian/util/etcd"
 	"github.com/grpc-ecosystem/grpc-gateway/runtime"
@@ -63,6 +63,13 @@ var (
 	treeDeleteThreshold      = flag.Duration("tree_delete_threshold", server.DefaultTreeDeleteThreshold, "Minimum period a tree has to remain deleted before being hard-deleted")
 	treeDeleteMinRunInterval = flag.Duration("tree_delete_min_run_interval", server.DefaultTreeDeleteMinInterval, "Minimum interval between tree garbage collection sweeps. Actual runs happen randomly between [minInterval,2*minInterval).")
 
+	treeCompactionEnabled        = flag.Bool("tree_compaction", false, "If true, the Merkle node history of frozen logs is periodically compacted into a single revision")
+	treeCompactionMinRunInterval = flag.Duration("tree_compaction_min_run_interval", server.DefaultTreeCompactionMinInterval, "Minimum interval between frozen tree compaction sweeps. Actual runs happen randomly between [minInterval,2*minInterval).")
+
+	rangeChunkSize = flag.Int("range_chunk_size", server.DefaultRangeChunkSize, "Max number of leaves AddSequencedLeafRange writes to storage in one transaction")
+
+	tileBucket = flag.String("tile_bucket", "", "If set, inclusion and consistency proofs are served from the tiles exported to this bucket (gs://bucket/prefix, s3://bucket/prefix or a local directory) where possible")
+
 	configFile = flag.String("config", "", "Config file containing flags, file contents can be overridden by command line flags")
 )
 
@@ -77,7 +84,10 @@ func main() {
 
 	ctx := context.Background()
 
-	mf := prometheus.MetricFactory{}
+	mf, err := server.NewMetricFactoryFromFlags(prometheus.MetricFactory{})
+	if err != nil {
+		glog.Exitf("Failed to create metric factory: %v", err)
+	}
 
 	sp, err := server.NewStorageProviderFromFlags(mf)
 	if err != nil {
@@ -102,25 +112,104 @@ func main() {
 		glog.Exitf("Error creating quota manager: %v", err)
 	}
 
+	costs, err := server.QuotaCostModelFromFlags()
+	if err != nil {
+		glog.Exitf("Invalid quota cost flags: %v", err)
+	}
+
+	budget, err := server.DeadlineBudgetFromFlags()
+	if err != nil {
+		glog.Exitf("Invalid --storage_deadline_budget: %v", err)
+	}
+
+	breaker, err := server.CircuitBreakerFromFlags(mf, util.SystemTimeSource{})
+	if err != nil {
+		glog.Exitf("Invalid storage circuit breaker flags: %v", err)
+	}
+
+	freshness, err := server.RootFreshnessFromFlags()
+	if err != nil {
+		glog.Exitf("Invalid root freshness flags: %v", err)
+	}
+
+	readMemory, err := server.ReadMemoryLimiterFromFlags()
+	if err != nil {
+		glog.Exitf("Invalid read memory limit flags: %v", err)
+	}
+
+	frontier, err := server.FrontierCacheFromFlags(mf)
+	if err != nil {
+		glog.Exitf("Invalid frontier cache flags: %v", err)
+	}
+
+	gossip, err := server.GossipPoolFromFlags(mf, util.SystemTimeSource{})
+	if err != nil {
+		glog.Exitf("Invalid gossip flags: %v", err)
+	}
+
+	connection, err := server.ConnectionOptsFromFlags()
+	if err != nil {
+		glog.Exitf("Invalid connection flags: %v", err)
+	}
+
+	abuse, err := server.AbuseDetectorFromFlags(qm, mf, util.SystemTimeSource{})
+	if err != nil {
+		glog.Exitf("Invalid abuse detection flags: %v", err)
+	}
+
+	priority, err := server.PrioritySchedulerFromFlags(mf)
+	if err != nil {
+		glog.Exitf("Invalid priority flags: %v", err)
+	}
+
+	proofSigner, err := server.ProofSignerFromFlags(mf, util.SystemTimeSource{})
+	if err != nil {
+		glog.Exitf("Invalid proof signing flags: %v", err)
+	}
+
+	accountant, err := server.AccountantFromFlags(ctx, qm, mf, util.SystemTimeSource{})
+	if err != nil {
+		glog.Exitf("Invalid accounting flags: %v", err)
+	}
+
+	ls, closeKafka, err := server.KafkaLogStorageFromFlags(sp.LogStorage())
+	if err != nil {
+		glog.Exitf("Failed to connect to Kafka: %v", err)
+	}
+	defer closeKafka()
+
+	faults, err := chaos.FromFlags()
+	if err != nil {
+		glog.Exitf("Invalid chaos flags: %v", err)
+	}
+	if faults != nil {
+		ls = faults.LogStorage(ls)
+	}
+
 	registry := extension.Registry{
 		AdminStorage:  sp.AdminStorage(),
-		LogStorage:    sp.LogStorage(),
+		LogStorage:    ls,
 		QuotaManager:  qm,
 		MetricFactory: mf,
-		NewKeyProto: func(ctx context.Context, spec *keyspb.Specification) (proto.Message, error) {
-			return der.NewProtoFromSpec(spec)
-		},
+		NewKeyProto:   server.KeyGeneratorFromFlags(),
 	}
 
 	m := server.Main{
-		RPCEndpoint:  *rpcEndpoint,
-		HTTPEndpoint: *httpEndpoint,
-		TLSCertFile:  *tlsCertFile,
-		TLSKeyFile:   *tlsKeyFile,
-		StatsPrefix:  "log",
-		QuotaDryRun:  *quotaDryRun,
-		DBClose:      sp.Close,
-		Registry:     registry,
+		RPCEndpoint:   *rpcEndpoint,
+		HTTPEndpoint:  *httpEndpoint,
+		TLSCertFile:   *tlsCertFile,
+		TLSKeyFile:    *tlsKeyFile,
+		Connection:    connection,
+		StatsPrefix:   "log",
+		QuotaDryRun:   *quotaDryRun,
+		QuotaCosts:    costs,
+		AbuseDetector: abuse,
+		Status:        &server.StatusPage{Registry: registry},
+		Accountant:    accountant,
+		Priority:      priority,
+		ProofSigner:   proofSigner,
+		DBClose:       sp.Close,
+		Registry:      registry,
 		RegisterHandlerFn: func(ctx netcontext.Context, mux *runtime.ServeMux, endpoint string, opts []grpc.DialOption) error {
 			if err := trillian.RegisterTrillianLogHandlerFromEndpoint(ctx, mux, endpoint, opts); err != nil {
 				return err
@@ -133,10 +222,26 @@ func main() {
 		RegisterServerFn: func(s *grpc.Server, registry extension.Registry) error {
 			ts := util.SystemTimeSource{}
 			logServer := server.NewTrillianLogRPCServer(registry, ts)
+			logServer.SetDeadlineBudget(budget)
+			logServer.SetRangeChunkSize(*rangeChunkSize)
+			logServer.SetCircuitBreaker(breaker)
+			logServer.SetRootFreshness(freshness)
+			logServer.SetReadMemoryLimiter(readMemory)
+			logServer.SetFrontierCache(frontier)
+			logServer.SetReadRepairer(server.ReadRepairerFromFlags(registry.LogStorage, mf))
+			logServer.SetGossipPool(gossip)
 			if err := logServer.IsHealthy(); err != nil {
 				return err
 			}
-			trillian.RegisterTrillianLogServer(s, logServer)
+			var ls trillian.TrillianLogServer = logServer
+			if *tileBucket != "" {
+				bucket, err := tiles.OpenBucket(ctx, *tileBucket)
+				if err != nil {
+					return err
+				}
+				ls = server.NewTileLogServer(bucket, logServer)
+			}
+			trillian.RegisterTrillianLogServer(s, ls)
 			if *server.QuotaSystem == server.QuotaEtcd {
 				quotapb.RegisterQuotaServer(s, quotaapi.NewServer(client))
 			}
@@ -146,6 +251,15 @@ func main() {
 		TreeGCEnabled:         *treeGCEnabled,
 		TreeDeleteThreshold:   *treeDeleteThreshold,
 		TreeDeleteMinInterval: *treeDeleteMinRunInterval,
+
+		TreeCompactionEnabled:     *treeCompactionEnabled,
+		TreeCompactionMinInterval: *treeCompactionMinRunInterval,
+	}
+
+	if faults != nil {
+		m.ConfigureInterceptors = func(c *interceptor.Chain) {
+			c.AddAfter(interceptor.StageErrors, faults.UnaryInterceptor)
+		}
 	}
 
 	if err := m.Run(ctx); err != nil {
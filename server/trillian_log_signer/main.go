@@ -28,6 +28,7 @@ import (
 	"github.com/google/trillian/log"
 	"github.com/google/trillian/monitoring/prometheus"
 	"github.com/google/trillian/server"
+	"github.com/google/trillian/testonly/chaos"
 	"github.com/google/trillian/util"
 	"github.com/google/trillian/util/etcd"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -53,10 +54,13 @@ var (
 	forceMaster              = flag.Bool("force_master", false, "If true, assume master for all logs")
 	etcdHTTPService          = flag.String("etcd_http_service", "trillian-logsigner-http", "Service name to announce our HTTP endpoint under")
 	lockDir                  = flag.String("lock_file_path", "/test/multimaster", "etcd lock file directory path")
+	storageElection          = flag.Bool("storage_election", false, "If true, run mastership elections in the storage database (MySQL or CloudSpanner) rather than etcd")
 
 	quotaIncreaseFactor = flag.Float64("quota_increase_factor", log.QuotaIncreaseFactor,
 		"Increase factor for tokens replenished by sequencing-based quotas (1 means a 1:1 relationship between sequenced leaves and replenished tokens)."+
 			"Only effective for --quota_system=etcd.")
+	clampRootTimestamps = flag.Bool("clamp_root_timestamps", log.ClampRootTimestamps,
+		"If true, new roots are timestamped just after the latest root of a log when the clock reads earlier than it, rather than not being signed until the clock catches up.")
 
 	preElectionPause    = flag.Duration("pre_election_pause", 1*time.Second, "Maximum time to wait before starting elections")
 	masterCheckInterval = flag.Duration("master_check_interval", 5*time.Second, "Interval between checking mastership still held")
@@ -78,7 +82,10 @@ func main() {
 	glog.CopyStandardLogTo("WARNING")
 	glog.Info("**** Log Signer Starting ****")
 
-	mf := prometheus.MetricFactory{}
+	mf, err := server.NewMetricFactoryFromFlags(prometheus.MetricFactory{})
+	if err != nil {
+		glog.Exitf("Failed to create metric factory: %v", err)
+	}
 
 	sp, err := server.NewStorageProviderFromFlags(mf)
 	if err != nil {
@@ -101,10 +108,16 @@ func main() {
 	case *forceMaster:
 		glog.Warning("**** Acting as master for all logs ****")
 		electionFactory = util.NoopElectionFactory{InstanceID: instanceID}
+	case *storageElection:
+		esp, ok := sp.(server.ElectionStorageProvider)
+		if !ok {
+			glog.Exit("--storage_election isn't supported by the storage system")
+		}
+		electionFactory = esp.ElectionFactory(instanceID)
 	case client != nil:
 		electionFactory = etcd.NewElectionFactory(instanceID, client, *lockDir)
 	default:
-		glog.Exit("Either --force_master or --etcd_servers must be supplied")
+		glog.Exit("Either --force_master, --storage_election or --etcd_servers must be supplied")
 	}
 
 	qm, err := server.NewQuotaManagerFromFlags()
@@ -120,6 +133,14 @@ func main() {
 		MetricFactory:   mf,
 	}
 
+	faults, err := chaos.FromFlags()
+	if err != nil {
+		glog.Exitf("Invalid chaos flags: %v", err)
+	}
+	if faults != nil {
+		registry.LogStorage = faults.LogStorage(registry.LogStorage)
+	}
+
 	// Start HTTP server (optional)
 	if *httpEndpoint != "" {
 		// Announce our endpoint to etcd if so configured.
@@ -133,23 +154,43 @@ func main() {
 		}
 	}
 
+	// Move leaves queued to Kafka, if any, to storage.
+	drainer, closeKafka, err := server.KafkaDrainerFromFlags(registry.LogStorage)
+	if err != nil {
+		glog.Exitf("Failed to connect to Kafka: %v", err)
+	}
+	defer closeKafka()
+	if drainer != nil {
+		go drainer.Run(ctx)
+	}
+
 	// Start the sequencing loop, which will run until we terminate the process. This controls
 	// both sequencing and signing.
 	// TODO(Martin2112): Should respect read only mode and the flags in tree control etc
 	log.QuotaIncreaseFactor = *quotaIncreaseFactor
+	log.ClampRootTimestamps = *clampRootTimestamps
+	catchUp, err := server.CatchUpFromFlags()
+	if err != nil {
+		glog.Exitf("Invalid catch-up flags: %v", err)
+	}
 	sequencerManager := server.NewSequencerManager(registry, *sequencerGuardWindowFlag)
+	sequencerManager.SetCatchUp(catchUp)
+	sequencerManager.SetPreflight(server.SignerPreflightFromFlags())
 	info := server.LogOperationInfo{
 		Registry:            registry,
 		BatchSize:           *batchSizeFlag,
 		NumWorkers:          *numSeqFlag,
 		RunInterval:         *sequencerIntervalFlag,
-		TimeSource:          util.SystemTimeSource{},
+		TimeSource:          server.MonotonicClockFromFlags(mf),
 		PreElectionPause:    *preElectionPause,
 		MasterCheckInterval: *masterCheckInterval,
 		MasterHoldInterval:  *masterHoldInterval,
 		ResignOdds:          *resignOdds,
 	}
 	sequencerTask := server.NewLogOperationManager(info, sequencerManager)
+	if *httpEndpoint != "" {
+		http.Handle("/statusz", &server.StatusPage{Registry: registry, Operations: sequencerTask, InstanceID: instanceID})
+	}
 	sequencerTask.OperationLoop(ctx)
 
 	// Give things a few seconds to tidy up
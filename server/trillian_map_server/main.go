@@ -19,16 +19,14 @@ import (
 	"flag"
 
 	"github.com/golang/glog"
-	"github.com/golang/protobuf/proto"
 	"github.com/google/trillian"
 	"github.com/google/trillian/cmd"
-	"github.com/google/trillian/crypto/keys/der"
-	"github.com/google/trillian/crypto/keyspb"
 	"github.com/google/trillian/extension"
 	"github.com/google/trillian/monitoring/prometheus"
 	"github.com/google/trillian/quota/etcd/quotaapi"
 	"github.com/google/trillian/quota/etcd/quotapb"
 	"github.com/google/trillian/server"
+	"github.com/google/trillian/util"
 	"github.com/google/trillian/util/etcd"
 	"github.com/grpc-ecosystem/grpc-gateway/runtime"
 	"google.golang.org/grpc"
@@ -70,7 +68,10 @@ func main() {
 		}
 	}
 
-	mf := prometheus.MetricFactory{}
+	mf, err := server.NewMetricFactoryFromFlags(prometheus.MetricFactory{})
+	if err != nil {
+		glog.Exitf("Failed to create metric factory: %v", err)
+	}
 
 	sp, err := server.NewStorageProviderFromFlags(mf)
 	if err != nil {
@@ -87,14 +88,38 @@ func main() {
 		glog.Exitf("Error creating quota manager: %v", err)
 	}
 
+	costs, err := server.QuotaCostModelFromFlags()
+	if err != nil {
+		glog.Exitf("Invalid quota cost flags: %v", err)
+	}
+
+	batching, err := server.WriteBatchingFromFlags()
+	if err != nil {
+		glog.Exitf("Invalid map write batching flags: %v", err)
+	}
+
+	connection, err := server.ConnectionOptsFromFlags()
+	if err != nil {
+		glog.Exitf("Invalid connection flags: %v", err)
+	}
+
+	priority, err := server.PrioritySchedulerFromFlags(mf)
+	if err != nil {
+		glog.Exitf("Invalid priority flags: %v", err)
+	}
+
+	accountant, err := server.AccountantFromFlags(context.Background(), qm, mf, util.SystemTimeSource{})
+	if err != nil {
+		glog.Exitf("Invalid accounting flags: %v", err)
+	}
+
 	registry := extension.Registry{
 		AdminStorage:  sp.AdminStorage(),
+		LogStorage:    sp.LogStorage(),
 		MapStorage:    sp.MapStorage(),
 		QuotaManager:  qm,
 		MetricFactory: mf,
-		NewKeyProto: func(ctx context.Context, spec *keyspb.Specification) (proto.Message, error) {
-			return der.NewProtoFromSpec(spec)
-		},
+		NewKeyProto:   server.KeyGeneratorFromFlags(),
 	}
 
 	m := server.Main{
@@ -102,8 +127,13 @@ func main() {
 		HTTPEndpoint: *httpEndpoint,
 		TLSCertFile:  *tlsCertFile,
 		TLSKeyFile:   *tlsKeyFile,
+		Connection:   connection,
 		StatsPrefix:  "map",
 		QuotaDryRun:  *quotaDryRun,
+		QuotaCosts:   costs,
+		Status:       &server.StatusPage{Registry: registry},
+		Accountant:   accountant,
+		Priority:     priority,
 		DBClose:      sp.Close,
 		Registry:     registry,
 		RegisterHandlerFn: func(ctx netcontext.Context, mux *runtime.ServeMux, endpoint string, opts []grpc.DialOption) error {
@@ -116,7 +146,8 @@ func main() {
 			return nil
 		},
 		RegisterServerFn: func(s *grpc.Server, registry extension.Registry) error {
-			mapServer := server.NewTrillianMapServer(registry)
+			mapServer := server.NewTrillianMapServer(registry, util.SystemTimeSource{})
+			mapServer.SetWriteBatching(batching)
 			if err := mapServer.IsHealthy(); err != nil {
 				return err
 			}
@@ -17,6 +17,7 @@ package server
 import (
 	"fmt"
 
+	"github.com/golang/protobuf/ptypes"
 	"github.com/google/trillian"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -79,6 +80,31 @@ func validateGetLeavesByRangeRequest(req *trillian.GetLeavesByRangeRequest) erro
 	return nil
 }
 
+func validateGetLeafIndicesByKeyRequest(req *trillian.GetLeafIndicesByKeyRequest) error {
+	if req.IndexName == "" {
+		return status.Error(codes.InvalidArgument, "GetLeafIndicesByKeyRequest.IndexName empty")
+	}
+	if req.StartIndex < 0 {
+		return status.Errorf(codes.InvalidArgument, "GetLeafIndicesByKeyRequest.StartIndex: %v, want >= 0", req.StartIndex)
+	}
+	if req.MaxResults < 0 {
+		return status.Errorf(codes.InvalidArgument, "GetLeafIndicesByKeyRequest.MaxResults: %v, want >= 0", req.MaxResults)
+	}
+	return nil
+}
+
+func validateCheckLeafFilterRequest(req *trillian.CheckLeafFilterRequest) error {
+	if len(req.LeafIdentityHash) == 0 {
+		return status.Error(codes.InvalidArgument, "CheckLeafFilterRequest.LeafIdentityHash empty")
+	}
+	for i, hash := range req.LeafIdentityHash {
+		if len(hash) == 0 {
+			return status.Errorf(codes.InvalidArgument, "CheckLeafFilterRequest.LeafIdentityHash[%v] empty", i)
+		}
+	}
+	return nil
+}
+
 func validateGetConsistencyProofRequest(req *trillian.GetConsistencyProofRequest) error {
 	if req.FirstTreeSize <= 0 {
 		return status.Errorf(codes.InvalidArgument, "GetConsistencyProofRequest.FirstTreeSize: %v, want > 0", req.FirstTreeSize)
@@ -92,6 +118,34 @@ func validateGetConsistencyProofRequest(req *trillian.GetConsistencyProofRequest
 	return nil
 }
 
+func validateAddObservedRootRequest(req *trillian.AddObservedRootRequest) error {
+	root := req.SignedLogRoot
+	if root == nil {
+		return status.Error(codes.InvalidArgument, "AddObservedRootRequest.SignedLogRoot is nil")
+	}
+	if root.TreeSize < 0 {
+		return status.Errorf(codes.InvalidArgument, "AddObservedRootRequest.SignedLogRoot.TreeSize: %v, want >= 0", root.TreeSize)
+	}
+	if root.Signature == nil {
+		return status.Error(codes.InvalidArgument, "AddObservedRootRequest.SignedLogRoot.Signature is nil")
+	}
+	return nil
+}
+
+func validateGetObservedRootsRequest(req *trillian.GetObservedRootsRequest) error {
+	if req.TreeSize < 0 {
+		return status.Errorf(codes.InvalidArgument, "GetObservedRootsRequest.TreeSize: %v, want >= 0", req.TreeSize)
+	}
+	return nil
+}
+
+func validateGetConsistencyProofChainRequest(req *trillian.GetConsistencyProofChainRequest) error {
+	if req.FirstTreeSize <= 0 {
+		return status.Errorf(codes.InvalidArgument, "GetConsistencyProofChainRequest.FirstTreeSize: %v, want > 0", req.FirstTreeSize)
+	}
+	return nil
+}
+
 func validateGetEntryAndProofRequest(req *trillian.GetEntryAndProofRequest) error {
 	if req.TreeSize <= 0 {
 		return status.Errorf(codes.InvalidArgument, "GetEntryAndProofRequest.TreeSize: %v, want > 0", req.TreeSize)
@@ -105,6 +159,55 @@ func validateGetEntryAndProofRequest(req *trillian.GetEntryAndProofRequest) erro
 	return nil
 }
 
+func validateGetEntriesAndProofsRequest(req *trillian.GetEntriesAndProofsRequest) error {
+	if req.TreeSize <= 0 {
+		return status.Errorf(codes.InvalidArgument, "GetEntriesAndProofsRequest.TreeSize: %v, want > 0", req.TreeSize)
+	}
+	if len(req.LeafIndex) == 0 {
+		return status.Error(codes.InvalidArgument, "GetEntriesAndProofsRequest.LeafIndex empty")
+	}
+	for i, leafIndex := range req.LeafIndex {
+		if leafIndex < 0 {
+			return status.Errorf(codes.InvalidArgument, "GetEntriesAndProofsRequest.LeafIndex[%v]: %v, want >= 0", i, leafIndex)
+		}
+		if leafIndex >= req.TreeSize {
+			return status.Errorf(codes.InvalidArgument, "GetEntriesAndProofsRequest.LeafIndex[%v]: %v >= TreeSize: %v, want < ", i, leafIndex, req.TreeSize)
+		}
+	}
+	return nil
+}
+
+func validateInitLogRequest(req *trillian.InitLogRequest) error {
+	// Metadata of other types is opaque to Trillian, and signed as is.
+	if req.Metadata == nil || !ptypes.Is(req.Metadata, &trillian.LogRootMetadata{}) {
+		return nil
+	}
+	var meta trillian.LogRootMetadata
+	if err := ptypes.UnmarshalAny(req.Metadata, &meta); err != nil {
+		return status.Errorf(codes.InvalidArgument, "InitLogRequest.Metadata: %v", err)
+	}
+	if meta.Origin == "" {
+		return status.Error(codes.InvalidArgument, "InitLogRequest.Metadata.Origin empty")
+	}
+	if shard := meta.Shard; shard != nil {
+		if shard.Start == nil || shard.Limit == nil {
+			return status.Error(codes.InvalidArgument, "InitLogRequest.Metadata.Shard: start and limit are required")
+		}
+		start, err := ptypes.Timestamp(shard.Start)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "InitLogRequest.Metadata.Shard.Start: %v", err)
+		}
+		limit, err := ptypes.Timestamp(shard.Limit)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "InitLogRequest.Metadata.Shard.Limit: %v", err)
+		}
+		if !start.Before(limit) {
+			return status.Errorf(codes.InvalidArgument, "InitLogRequest.Metadata.Shard.Limit: %v, want > start %v", limit, start)
+		}
+	}
+	return nil
+}
+
 func validateAddSequencedLeavesRequest(req *trillian.AddSequencedLeavesRequest) error {
 	prefix := "AddSequencedLeavesRequest"
 	if err := validateLogLeaves(req.Leaves, prefix); err != nil {
@@ -122,6 +225,24 @@ func validateAddSequencedLeavesRequest(req *trillian.AddSequencedLeavesRequest)
 	return nil
 }
 
+func validateAddSequencedLeafRangeRequest(req *trillian.AddSequencedLeafRangeRequest) error {
+	prefix := "AddSequencedLeafRangeRequest"
+	if req.StartIndex < 0 {
+		return status.Errorf(codes.InvalidArgument, "%v.StartIndex: %v, want >= 0", prefix, req.StartIndex)
+	}
+	if err := validateLogLeaves(req.Leaves, prefix); err != nil {
+		return err
+	}
+
+	// LeafIndex may be left unset, in which case it's zero.
+	for i, leaf := range req.Leaves {
+		if want := req.StartIndex + int64(i); leaf.LeafIndex != 0 && leaf.LeafIndex != want {
+			return status.Errorf(codes.FailedPrecondition, "%v.Leaves[%v].LeafIndex=%v, want %v", prefix, i, leaf.LeafIndex, want)
+		}
+	}
+	return nil
+}
+
 func validateLogLeaves(leaves []*trillian.LogLeaf, errPrefix string) error {
 	if len(leaves) == 0 {
 		return status.Errorf(codes.InvalidArgument, "%v.Leaves empty", errPrefix)
@@ -0,0 +1,165 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package archive writes the complete contents of a log at a given tree size
+// to object storage as a self-contained, verifiable archive, for offline
+// verification and cold storage of frozen logs.
+//
+// The leaves of the log are stored in batches of BatchSize leaves, a power of
+// two, so that each batch is a subtree of the log's Merkle tree. The objects
+// stored for an archive of a tree at a given size are:
+//   - <treeID>/archive/<size>/tree: the public parts of the tree's
+//     configuration;
+//   - <treeID>/archive/<size>/root: the SignedLogRoot the archive is
+//     verified against, whose size may be larger than the archive's;
+//   - <treeID>/archive/<size>/batch/<n>: the leaves of the n-th batch, as a
+//     serialized GetLeavesByRangeResponse;
+//   - <treeID>/archive/<size>/manifest: a JSON encoded Manifest.
+//
+// The manifest is written last, once everything else is in place and has been
+// checked against the log root, so an archive is complete iff it has one.
+package archive
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/trillian/merkle/hashers"
+	"github.com/google/trillian/storage/tiles"
+)
+
+// DefaultBatchSize is the default number of leaves stored per batch.
+const DefaultBatchSize = 1024
+
+// Manifest describes an archive of a log.
+type Manifest struct {
+	TreeID   int64 `json:"tree_id"`
+	TreeSize int64 `json:"tree_size"`
+	// RootHash is the root hash of the tree at TreeSize.
+	RootHash []byte `json:"root_hash"`
+	// Consistency is a consistency proof from TreeSize to the size of the
+	// archived SignedLogRoot. It is empty if both sizes are the same.
+	Consistency [][]byte `json:"consistency,omitempty"`
+	BatchSize   int64    `json:"batch_size"`
+	Batches     []Batch  `json:"batches"`
+}
+
+// Batch describes one of the batches of leaves of an archive.
+type Batch struct {
+	// Start is the index of the first leaf in the batch.
+	Start int64 `json:"start"`
+	// Count is the number of leaves in the batch. Only the last batch can
+	// hold fewer than BatchSize leaves.
+	Count int64 `json:"count"`
+	// Digest is the SHA-256 hash of the batch object.
+	Digest []byte `json:"digest"`
+	// Chain is the SHA-256 hash of the Chain of the previous batch (empty for
+	// the first batch) followed by Digest, which links each batch to all the
+	// ones before it.
+	Chain []byte `json:"chain"`
+	// Hash is the Merkle tree hash of the leaves in the batch.
+	Hash []byte `json:"hash"`
+	// Proof is the inclusion proof of the batch in the tree at TreeSize,
+	// computed over the tree whose leaves are the batch hashes.
+	Proof [][]byte `json:"proof"`
+}
+
+func prefix(treeID, treeSize int64) string {
+	return fmt.Sprintf("%d/archive/%d", treeID, treeSize)
+}
+
+func treePath(treeID, treeSize int64) string {
+	return prefix(treeID, treeSize) + "/tree"
+}
+
+func rootPath(treeID, treeSize int64) string {
+	return prefix(treeID, treeSize) + "/root"
+}
+
+func manifestPath(treeID, treeSize int64) string {
+	return prefix(treeID, treeSize) + "/manifest"
+}
+
+func batchPath(treeID, treeSize int64, n int) string {
+	return fmt.Sprintf("%s/batch/%d", prefix(treeID, treeSize), n)
+}
+
+// chain returns the Chain value of a batch, given that of the previous one.
+func chain(prev, digest []byte) []byte {
+	h := sha256.New()
+	h.Write(prev)
+	h.Write(digest)
+	return h.Sum(nil)
+}
+
+// readManifest reads and decodes the manifest of an archive.
+func readManifest(ctx context.Context, bucket tiles.Bucket, treeID, treeSize int64) (*Manifest, error) {
+	b, err := bucket.Read(ctx, manifestPath(treeID, treeSize))
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %v", err)
+	}
+	return &m, nil
+}
+
+// rangeHash returns the Merkle tree hash of the given (leaf or subtree) hashes.
+func rangeHash(hasher hashers.LogHasher, hashes [][]byte) []byte {
+	switch len(hashes) {
+	case 0:
+		return hasher.EmptyRoot()
+	case 1:
+		return hashes[0]
+	}
+	k := splitPoint(int64(len(hashes)))
+	return hasher.HashChildren(rangeHash(hasher, hashes[:k]), rangeHash(hasher, hashes[k:]))
+}
+
+// inclusion returns the inclusion proof for hashes[m] in the tree whose
+// leaves are hashes.
+func inclusion(hasher hashers.LogHasher, m int64, hashes [][]byte) [][]byte {
+	n := int64(len(hashes))
+	if n == 1 {
+		return [][]byte{}
+	}
+	k := splitPoint(n)
+	if m < k {
+		return append(inclusion(hasher, m, hashes[:k]), rangeHash(hasher, hashes[k:]))
+	}
+	return append(inclusion(hasher, m-k, hashes[k:]), rangeHash(hasher, hashes[:k]))
+}
+
+// splitPoint returns the largest power of two smaller than n, which must be
+// greater than one.
+func splitPoint(n int64) int64 {
+	k := int64(1)
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
+
+func checkBatchSize(batchSize int64) error {
+	if batchSize <= 0 || batchSize&(batchSize-1) != 0 {
+		return fmt.Errorf("batch size %d is not a power of two", batchSize)
+	}
+	return nil
+}
+
+var errNoRoot = errors.New("log returned no root")
@@ -0,0 +1,235 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian"
+	"github.com/google/trillian/crypto/keys/der"
+	"github.com/google/trillian/merkle"
+	"github.com/google/trillian/merkle/rfc6962"
+	"github.com/google/trillian/storage/tiles"
+	"google.golang.org/grpc"
+
+	tcrypto "github.com/google/trillian/crypto"
+)
+
+// fakeLogClient serves the leaves of mt, signing its roots with signer.
+type fakeLogClient struct {
+	trillian.TrillianLogClient
+	mt     *merkle.InMemoryMerkleTree
+	values [][]byte
+	signer *tcrypto.Signer
+}
+
+func newFakeLogClient(t *testing.T, size int, signer *tcrypto.Signer) *fakeLogClient {
+	t.Helper()
+	f := &fakeLogClient{mt: merkle.NewInMemoryMerkleTree(rfc6962.DefaultHasher), signer: signer}
+	for i := 0; i < size; i++ {
+		v := []byte(fmt.Sprintf("leaf %d", i))
+		if _, _, err := f.mt.AddLeaf(v); err != nil {
+			t.Fatalf("AddLeaf(): %v", err)
+		}
+		f.values = append(f.values, v)
+	}
+	return f
+}
+
+func (f *fakeLogClient) GetLatestSignedLogRoot(ctx context.Context, req *trillian.GetLatestSignedLogRootRequest, opts ...grpc.CallOption) (*trillian.GetLatestSignedLogRootResponse, error) {
+	root := &trillian.SignedLogRoot{
+		TreeSize:       f.mt.LeafCount(),
+		RootHash:       f.mt.CurrentRoot().Hash(),
+		TimestampNanos: 1234,
+	}
+	sig, err := f.signer.SignLogRoot(root)
+	if err != nil {
+		return nil, err
+	}
+	root.Signature = sig
+	return &trillian.GetLatestSignedLogRootResponse{SignedLogRoot: root}, nil
+}
+
+// GetLeavesByRange returns at most 10 leaves, to exercise the handling of
+// short responses.
+func (f *fakeLogClient) GetLeavesByRange(ctx context.Context, req *trillian.GetLeavesByRangeRequest, opts ...grpc.CallOption) (*trillian.GetLeavesByRangeResponse, error) {
+	var leaves []*trillian.LogLeaf
+	for i := req.StartIndex; i < req.StartIndex+req.Count && i < int64(len(f.values)) && len(leaves) < 10; i++ {
+		leaves = append(leaves, &trillian.LogLeaf{
+			LeafIndex:      i,
+			LeafValue:      f.values[i],
+			MerkleLeafHash: f.mt.LeafHash(i + 1),
+		})
+	}
+	return &trillian.GetLeavesByRangeResponse{Leaves: leaves}, nil
+}
+
+func (f *fakeLogClient) GetConsistencyProof(ctx context.Context, req *trillian.GetConsistencyProofRequest, opts ...grpc.CallOption) (*trillian.GetConsistencyProofResponse, error) {
+	var hashes [][]byte
+	for _, n := range f.mt.SnapshotConsistency(req.FirstTreeSize, req.SecondTreeSize) {
+		hashes = append(hashes, n.Value.Hash())
+	}
+	return &trillian.GetConsistencyProofResponse{Proof: &trillian.Proof{Hashes: hashes}}, nil
+}
+
+func newTestBucket(t *testing.T) (tiles.Bucket, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatalf("TempDir(): %v", err)
+	}
+	return tiles.NewDirBucket(dir), func() { os.RemoveAll(dir) }
+}
+
+func newTestTree(t *testing.T) (*trillian.Tree, *tcrypto.Signer) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey(): %v", err)
+	}
+	pubKey, err := der.ToPublicProto(key.Public())
+	if err != nil {
+		t.Fatalf("ToPublicProto(): %v", err)
+	}
+	return &trillian.Tree{
+		TreeId:       1,
+		TreeType:     trillian.TreeType_LOG,
+		HashStrategy: trillian.HashStrategy_RFC6962_SHA256,
+		PublicKey:    pubKey,
+	}, tcrypto.NewSHA256Signer(key)
+}
+
+func TestArchiveAndVerify(t *testing.T) {
+	ctx := context.Background()
+	tree, signer := newTestTree(t)
+	client := newFakeLogClient(t, 37, signer)
+
+	for _, test := range []struct {
+		treeSize, batchSize int64
+		wantSize            int64
+		wantBatches         int
+	}{
+		{treeSize: 0, batchSize: 8, wantSize: 37, wantBatches: 5},
+		{treeSize: 37, batchSize: 64, wantSize: 37, wantBatches: 1},
+		{treeSize: 32, batchSize: 8, wantSize: 32, wantBatches: 4},
+		{treeSize: 21, batchSize: 4, wantSize: 21, wantBatches: 6},
+		{treeSize: 1, batchSize: 1, wantSize: 1, wantBatches: 1},
+	} {
+		t.Run(fmt.Sprintf("size:%d,batch:%d", test.treeSize, test.batchSize), func(t *testing.T) {
+			bucket, cleanup := newTestBucket(t)
+			defer cleanup()
+			a, err := NewArchiver(client, tree, bucket)
+			if err != nil {
+				t.Fatalf("NewArchiver(): %v", err)
+			}
+			a.BatchSize = test.batchSize
+			m, err := a.Archive(ctx, test.treeSize)
+			if err != nil {
+				t.Fatalf("Archive(): %v", err)
+			}
+			if m.TreeSize != test.wantSize || len(m.Batches) != test.wantBatches {
+				t.Errorf("Archive(): got size %d with %d batches, want size %d with %d batches", m.TreeSize, len(m.Batches), test.wantSize, test.wantBatches)
+			}
+			if _, err := Verify(ctx, bucket, tree.TreeId, m.TreeSize); err != nil {
+				t.Errorf("Verify(): %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	ctx := context.Background()
+	tree, signer := newTestTree(t)
+	client := newFakeLogClient(t, 20, signer)
+	const treeSize = 20
+
+	for _, test := range []struct {
+		desc   string
+		object string
+		modify func(b []byte) []byte
+	}{
+		{
+			desc:   "batch",
+			object: batchPath(tree.TreeId, treeSize, 1),
+			modify: func(b []byte) []byte { return append(b[:len(b)-1], b[len(b)-1]^1) },
+		},
+		{
+			desc:   "empty batch",
+			object: batchPath(tree.TreeId, treeSize, 2),
+			modify: func(b []byte) []byte { return nil },
+		},
+		{
+			desc:   "root",
+			object: rootPath(tree.TreeId, treeSize),
+			modify: func(b []byte) []byte {
+				var root trillian.SignedLogRoot
+				if err := proto.Unmarshal(b, &root); err != nil {
+					t.Fatalf("Unmarshal(): %v", err)
+				}
+				root.TimestampNanos++
+				b, err := proto.Marshal(&root)
+				if err != nil {
+					t.Fatalf("Marshal(): %v", err)
+				}
+				return b
+			},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			bucket, cleanup := newTestBucket(t)
+			defer cleanup()
+			a, err := NewArchiver(client, tree, bucket)
+			if err != nil {
+				t.Fatalf("NewArchiver(): %v", err)
+			}
+			a.BatchSize = 8
+			if _, err := a.Archive(ctx, treeSize); err != nil {
+				t.Fatalf("Archive(): %v", err)
+			}
+			b, err := bucket.Read(ctx, test.object)
+			if err != nil {
+				t.Fatalf("Read(%v): %v", test.object, err)
+			}
+			if err := bucket.Write(ctx, test.object, test.modify(b)); err != nil {
+				t.Fatalf("Write(%v): %v", test.object, err)
+			}
+			if _, err := Verify(ctx, bucket, tree.TreeId, treeSize); err == nil {
+				t.Error("Verify(): got nil error, want error")
+			}
+		})
+	}
+}
+
+func TestArchiveRejectsBadBatchSize(t *testing.T) {
+	tree, signer := newTestTree(t)
+	bucket, cleanup := newTestBucket(t)
+	defer cleanup()
+	a, err := NewArchiver(newFakeLogClient(t, 10, signer), tree, bucket)
+	if err != nil {
+		t.Fatalf("NewArchiver(): %v", err)
+	}
+	a.BatchSize = 6
+	if _, err := a.Archive(context.Background(), 0); err == nil {
+		t.Error("Archive() with batch size 6: got nil error, want error")
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian"
+	"github.com/google/trillian/crypto/keys/der"
+	"github.com/google/trillian/merkle"
+	"github.com/google/trillian/merkle/hashers"
+	"github.com/google/trillian/storage/tiles"
+
+	tcrypto "github.com/google/trillian/crypto"
+)
+
+// Verify checks the complete archive of the tree at treeSize in bucket, using
+// only the contents of the archive, and returns its manifest. It checks that:
+//   - the archived root is signed by the tree's key, if it has one;
+//   - the manifest's root hash is consistent with the archived root;
+//   - every batch is present, unmodified and correctly chained;
+//   - the leaves of every batch hash to the batch's hash, which is included in
+//     the tree at the manifest's root hash.
+func Verify(ctx context.Context, bucket tiles.Bucket, treeID, treeSize int64) (*Manifest, error) {
+	tree := &trillian.Tree{}
+	if err := readProto(ctx, bucket, treePath(treeID, treeSize), tree); err != nil {
+		return nil, err
+	}
+	root := &trillian.SignedLogRoot{}
+	if err := readProto(ctx, bucket, rootPath(treeID, treeSize), root); err != nil {
+		return nil, err
+	}
+	m, err := readManifest(ctx, bucket, treeID, treeSize)
+	if err != nil {
+		return nil, err
+	}
+	if m.TreeID != treeID || m.TreeSize != treeSize {
+		return nil, fmt.Errorf("manifest is for tree %d at size %d, want tree %d at size %d", m.TreeID, m.TreeSize, treeID, treeSize)
+	}
+	if err := checkBatchSize(m.BatchSize); err != nil {
+		return nil, err
+	}
+	hasher, err := hashers.NewLogHasher(tree.HashStrategy)
+	if err != nil {
+		return nil, err
+	}
+	verifier := merkle.NewLogVerifier(hasher)
+
+	if tree.PublicKey != nil {
+		pubKey, err := der.UnmarshalPublicKey(tree.PublicKey.GetDer())
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse tree public key: %v", err)
+		}
+		hash, err := tcrypto.HashLogRoot(*root)
+		if err != nil {
+			return nil, err
+		}
+		if err := tcrypto.Verify(pubKey, hash, root.Signature); err != nil {
+			return nil, fmt.Errorf("invalid root signature: %v", err)
+		}
+	}
+	switch {
+	case treeSize > root.TreeSize:
+		return nil, fmt.Errorf("archive size %d is larger than root size %d", treeSize, root.TreeSize)
+	case treeSize == root.TreeSize:
+		if !bytes.Equal(m.RootHash, root.RootHash) {
+			return nil, fmt.Errorf("manifest has root hash %x, but root has %x", m.RootHash, root.RootHash)
+		}
+	default:
+		if err := verifier.VerifyConsistencyProof(treeSize, root.TreeSize, m.RootHash, root.RootHash, m.Consistency); err != nil {
+			return nil, fmt.Errorf("manifest root hash is not consistent with root: %v", err)
+		}
+	}
+
+	numBatches := (treeSize + m.BatchSize - 1) / m.BatchSize
+	if got := int64(len(m.Batches)); got != numBatches {
+		return nil, fmt.Errorf("manifest has %d batches, want %d", got, numBatches)
+	}
+	var prevChain []byte
+	for i, b := range m.Batches {
+		start := int64(i) * m.BatchSize
+		count := treeSize - start
+		if count > m.BatchSize {
+			count = m.BatchSize
+		}
+		if b.Start != start || b.Count != count {
+			return nil, fmt.Errorf("batch %d covers leaves [%d, %d), want [%d, %d)", i, b.Start, b.Start+b.Count, start, start+count)
+		}
+
+		data, err := bucket.Read(ctx, batchPath(treeID, treeSize, i))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read batch %d: %v", i, err)
+		}
+		digest := sha256.Sum256(data)
+		if !bytes.Equal(digest[:], b.Digest) {
+			return nil, fmt.Errorf("batch %d has digest %x, want %x", i, digest, b.Digest)
+		}
+		prevChain = chain(prevChain, b.Digest)
+		if !bytes.Equal(prevChain, b.Chain) {
+			return nil, fmt.Errorf("batch %d has chain %x, want %x", i, b.Chain, prevChain)
+		}
+
+		var resp trillian.GetLeavesByRangeResponse
+		if err := proto.Unmarshal(data, &resp); err != nil {
+			return nil, fmt.Errorf("failed to parse batch %d: %v", i, err)
+		}
+		if got := int64(len(resp.Leaves)); got != count {
+			return nil, fmt.Errorf("batch %d has %d leaves, want %d", i, got, count)
+		}
+		hashes := make([][]byte, 0, count)
+		for j, leaf := range resp.Leaves {
+			if want := start + int64(j); leaf.LeafIndex != want {
+				return nil, fmt.Errorf("batch %d has leaf %d at index %d", i, leaf.LeafIndex, want)
+			}
+			h, err := hasher.HashLeaf(leaf.LeafValue)
+			if err != nil {
+				return nil, err
+			}
+			if !bytes.Equal(h, leaf.MerkleLeafHash) {
+				return nil, fmt.Errorf("leaf %d has Merkle leaf hash %x, but its value hashes to %x", leaf.LeafIndex, leaf.MerkleLeafHash, h)
+			}
+			hashes = append(hashes, h)
+		}
+		if h := rangeHash(hasher, hashes); !bytes.Equal(h, b.Hash) {
+			return nil, fmt.Errorf("batch %d has hash %x, want %x", i, h, b.Hash)
+		}
+		if err := verifier.VerifyInclusionProof(int64(i), numBatches, b.Proof, m.RootHash, b.Hash); err != nil {
+			return nil, fmt.Errorf("batch %d is not included in the tree: %v", i, err)
+		}
+	}
+	return m, nil
+}
+
+func readProto(ctx context.Context, bucket tiles.Bucket, name string, pb proto.Message) error {
+	b, err := bucket.Read(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to read %v: %v", name, err)
+	}
+	return proto.Unmarshal(b, pb)
+}
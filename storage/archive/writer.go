@@ -0,0 +1,233 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	"github.com/golang/glog"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian"
+	"github.com/google/trillian/merkle"
+	"github.com/google/trillian/merkle/hashers"
+	"github.com/google/trillian/storage/tiles"
+)
+
+// Archiver writes archives of a log, read via a log client, to a Bucket.
+type Archiver struct {
+	client trillian.TrillianLogClient
+	tree   *trillian.Tree
+	hasher hashers.LogHasher
+	bucket tiles.Bucket
+
+	// BatchSize is the number of leaves stored per batch, and requested from
+	// the log at a time. It must be a power of two.
+	BatchSize int64
+}
+
+// NewArchiver returns an Archiver writing archives of tree, read from the log
+// via client, to bucket.
+func NewArchiver(client trillian.TrillianLogClient, tree *trillian.Tree, bucket tiles.Bucket) (*Archiver, error) {
+	if tree.TreeType != trillian.TreeType_LOG && tree.TreeType != trillian.TreeType_PREORDERED_LOG {
+		return nil, fmt.Errorf("tree %v is not a log", tree.TreeId)
+	}
+	hasher, err := hashers.NewLogHasher(tree.HashStrategy)
+	if err != nil {
+		return nil, err
+	}
+	return &Archiver{
+		client:    client,
+		tree:      tree,
+		hasher:    hasher,
+		bucket:    bucket,
+		BatchSize: DefaultBatchSize,
+	}, nil
+}
+
+// Archive writes an archive of the first treeSize leaves of the log, or of
+// all of them at its latest root if treeSize is zero, and returns its
+// manifest. The leaves are checked against the latest log root before the
+// archive is completed. If the archive already exists, its manifest is
+// returned without rewriting it.
+func (a *Archiver) Archive(ctx context.Context, treeSize int64) (*Manifest, error) {
+	if err := checkBatchSize(a.BatchSize); err != nil {
+		return nil, err
+	}
+	treeID := a.tree.TreeId
+
+	resp, err := a.client.GetLatestSignedLogRoot(ctx, &trillian.GetLatestSignedLogRootRequest{LogId: treeID})
+	if err != nil {
+		return nil, err
+	}
+	root := resp.SignedLogRoot
+	if root == nil {
+		return nil, errNoRoot
+	}
+	switch {
+	case treeSize == 0:
+		treeSize = root.TreeSize
+	case treeSize > root.TreeSize:
+		return nil, fmt.Errorf("tree size %d is larger than log root size %d", treeSize, root.TreeSize)
+	}
+	if treeSize <= 0 {
+		return nil, fmt.Errorf("cannot archive tree of size %d", treeSize)
+	}
+
+	switch m, err := readManifest(ctx, a.bucket, treeID, treeSize); {
+	case err == nil:
+		return m, nil
+	case err != tiles.ErrNotExist:
+		return nil, err
+	}
+
+	m := &Manifest{
+		TreeID:    treeID,
+		TreeSize:  treeSize,
+		BatchSize: a.BatchSize,
+	}
+	var prevChain []byte
+	for start, n := int64(0), 0; start < treeSize; start, n = start+a.BatchSize, n+1 {
+		count := treeSize - start
+		if count > a.BatchSize {
+			count = a.BatchSize
+		}
+		leaves, hashes, err := a.leaves(ctx, start, start+count)
+		if err != nil {
+			return nil, err
+		}
+		b, err := proto.Marshal(&trillian.GetLeavesByRangeResponse{Leaves: leaves})
+		if err != nil {
+			return nil, err
+		}
+		if err := a.bucket.Write(ctx, batchPath(treeID, treeSize, n), b); err != nil {
+			return nil, err
+		}
+		digest := sha256.Sum256(b)
+		prevChain = chain(prevChain, digest[:])
+		m.Batches = append(m.Batches, Batch{
+			Start:  start,
+			Count:  count,
+			Digest: digest[:],
+			Chain:  prevChain,
+			Hash:   rangeHash(a.hasher, hashes),
+		})
+		glog.V(1).Infof("%v: archived leaves [%d, %d)", treeID, start, start+count)
+	}
+
+	batchHashes := make([][]byte, 0, len(m.Batches))
+	for _, b := range m.Batches {
+		batchHashes = append(batchHashes, b.Hash)
+	}
+	for i := range m.Batches {
+		m.Batches[i].Proof = inclusion(a.hasher, int64(i), batchHashes)
+	}
+	m.RootHash = rangeHash(a.hasher, batchHashes)
+
+	// Check the archive against the log root before completing it.
+	if treeSize < root.TreeSize {
+		resp, err := a.client.GetConsistencyProof(ctx, &trillian.GetConsistencyProofRequest{
+			LogId:          treeID,
+			FirstTreeSize:  treeSize,
+			SecondTreeSize: root.TreeSize,
+		})
+		if err != nil {
+			return nil, err
+		}
+		m.Consistency = resp.GetProof().GetHashes()
+		if err := merkle.NewLogVerifier(a.hasher).VerifyConsistencyProof(treeSize, root.TreeSize, m.RootHash, root.RootHash, m.Consistency); err != nil {
+			return nil, fmt.Errorf("archived leaves are not consistent with log root: %v", err)
+		}
+	} else if !bytes.Equal(m.RootHash, root.RootHash) {
+		return nil, fmt.Errorf("archived leaves have root hash %x, but log root has %x", m.RootHash, root.RootHash)
+	}
+
+	if err := a.writeTree(ctx, treeSize); err != nil {
+		return nil, err
+	}
+	b, err := proto.Marshal(root)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.bucket.Write(ctx, rootPath(treeID, treeSize), b); err != nil {
+		return nil, err
+	}
+	mb, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.bucket.Write(ctx, manifestPath(treeID, treeSize), mb); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// writeTree stores the public parts of the tree's configuration.
+func (a *Archiver) writeTree(ctx context.Context, treeSize int64) error {
+	b, err := proto.Marshal(&trillian.Tree{
+		TreeId:             a.tree.TreeId,
+		TreeType:           a.tree.TreeType,
+		HashStrategy:       a.tree.HashStrategy,
+		HashAlgorithm:      a.tree.HashAlgorithm,
+		SignatureAlgorithm: a.tree.SignatureAlgorithm,
+		PublicKey:          a.tree.PublicKey,
+	})
+	if err != nil {
+		return err
+	}
+	return a.bucket.Write(ctx, treePath(a.tree.TreeId, treeSize), b)
+}
+
+// leaves returns the leaves in [start, end), along with their Merkle leaf
+// hashes.
+func (a *Archiver) leaves(ctx context.Context, start, end int64) ([]*trillian.LogLeaf, [][]byte, error) {
+	leaves := make([]*trillian.LogLeaf, 0, end-start)
+	hashes := make([][]byte, 0, end-start)
+	for next := start; next < end; {
+		resp, err := a.client.GetLeavesByRange(ctx, &trillian.GetLeavesByRangeRequest{
+			LogId:      a.tree.TreeId,
+			StartIndex: next,
+			Count:      end - next,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(resp.Leaves) == 0 {
+			return nil, nil, fmt.Errorf("log returned no leaves at index %d", next)
+		}
+		for _, leaf := range resp.Leaves {
+			if next == end {
+				break
+			}
+			if leaf.LeafIndex != next {
+				return nil, nil, fmt.Errorf("log returned leaf %d, want %d", leaf.LeafIndex, next)
+			}
+			h, err := a.hasher.HashLeaf(leaf.LeafValue)
+			if err != nil {
+				return nil, nil, err
+			}
+			if !bytes.Equal(h, leaf.MerkleLeafHash) {
+				return nil, nil, fmt.Errorf("leaf %d has Merkle leaf hash %x, but its value hashes to %x", next, leaf.MerkleLeafHash, h)
+			}
+			leaves = append(leaves, leaf)
+			hashes = append(hashes, h)
+			next++
+		}
+	}
+	return leaves, hashes, nil
+}
@@ -0,0 +1,292 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigtable
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/bigtable"
+	"github.com/golang/glog"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// NewAdminStorage returns a Bigtable storage.AdminStorage implementation.
+// Trees are stored as serialized protos, one per row. Bigtable only offers
+// single-row atomicity, so admin transactions are not isolated from each
+// other; tree creation is, however, guaranteed not to overwrite an existing
+// tree.
+func NewAdminStorage(client *bigtable.Client) storage.AdminStorage {
+	return &adminStorage{trees: client.Open(treesTbl)}
+}
+
+type adminStorage struct {
+	trees *bigtable.Table
+}
+
+func (s *adminStorage) Snapshot(ctx context.Context) (storage.ReadOnlyAdminTX, error) {
+	return &adminTX{s: s}, nil
+}
+
+func (s *adminStorage) ReadWriteTransaction(ctx context.Context, f storage.AdminTXFunc) error {
+	tx := &adminTX{s: s}
+	defer tx.Close()
+	if err := f(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *adminStorage) CheckDatabaseAccessible(ctx context.Context) error {
+	return checkDatabaseAccessible(ctx, s.trees)
+}
+
+type adminTX struct {
+	s *adminStorage
+
+	// mu guards closed.
+	mu     sync.RWMutex
+	closed bool
+}
+
+func (t *adminTX) Commit() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+	return nil
+}
+
+func (t *adminTX) Rollback() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+	return nil
+}
+
+func (t *adminTX) IsClosed() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.closed
+}
+
+func (t *adminTX) Close() error {
+	if !t.IsClosed() {
+		if err := t.Rollback(); err != nil {
+			glog.Warningf("Rollback error on Close(): %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func treeRowKey(treeID int64) string {
+	return fmt.Sprintf("%016x", uint64(treeID))
+}
+
+func readTree(row bigtable.Row) (*trillian.Tree, error) {
+	b := cell(row, treeFamily, treeCol)
+	if b == nil {
+		return nil, nil
+	}
+	tree := &trillian.Tree{}
+	if err := proto.Unmarshal(b, tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+func (t *adminTX) GetTree(ctx context.Context, treeID int64) (*trillian.Tree, error) {
+	row, err := t.s.trees.ReadRow(ctx, treeRowKey(treeID))
+	if err != nil {
+		return nil, fmt.Errorf("error reading tree %v: %v", treeID, err)
+	}
+	tree, err := readTree(row)
+	if err != nil {
+		return nil, fmt.Errorf("error reading tree %v: %v", treeID, err)
+	}
+	if tree == nil {
+		return nil, status.Errorf(codes.NotFound, "tree %v not found", treeID)
+	}
+	return tree, nil
+}
+
+func (t *adminTX) ListTreeIDs(ctx context.Context, includeDeleted bool) ([]int64, error) {
+	trees, err := t.ListTrees(ctx, includeDeleted)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, 0, len(trees))
+	for _, tree := range trees {
+		ids = append(ids, tree.TreeId)
+	}
+	return ids, nil
+}
+
+func (t *adminTX) ListTrees(ctx context.Context, includeDeleted bool) ([]*trillian.Tree, error) {
+	trees := []*trillian.Tree{}
+	var readErr error
+	err := t.s.trees.ReadRows(ctx, bigtable.InfiniteRange(""), func(row bigtable.Row) bool {
+		tree, err := readTree(row)
+		if err != nil {
+			readErr = err
+			return false
+		}
+		if tree != nil && (includeDeleted || !tree.Deleted) {
+			trees = append(trees, tree)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if readErr != nil {
+		return nil, readErr
+	}
+	return trees, nil
+}
+
+func (t *adminTX) CreateTree(ctx context.Context, tree *trillian.Tree) (*trillian.Tree, error) {
+	if err := storage.ValidateTreeForCreation(ctx, tree); err != nil {
+		return nil, err
+	}
+	if err := storage.ValidateStrataSettings(tree); err != nil {
+		return nil, err
+	}
+
+	id, err := storage.NewTreeID()
+	if err != nil {
+		return nil, err
+	}
+	now, err := ptypes.TimestampProto(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	newTree := *tree
+	newTree.TreeId = id
+	newTree.CreateTime = now
+	newTree.UpdateTime = now
+
+	b, err := proto.Marshal(&newTree)
+	if err != nil {
+		return nil, err
+	}
+	set := bigtable.NewMutation()
+	set.Set(treeFamily, treeCol, bigtable.ServerTime, b)
+	var exists bool
+	m := bigtable.NewCondMutation(bigtable.PassAllFilter(), nil, set)
+	if err := t.s.trees.Apply(ctx, treeRowKey(id), m, bigtable.GetCondMutationResult(&exists)); err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, status.Errorf(codes.AlreadyExists, "tree %v already exists", id)
+	}
+	return &newTree, nil
+}
+
+// writeTree stores tree, replacing its current version.
+func (t *adminTX) writeTree(ctx context.Context, tree *trillian.Tree) error {
+	b, err := proto.Marshal(tree)
+	if err != nil {
+		return err
+	}
+	m := bigtable.NewMutation()
+	m.Set(treeFamily, treeCol, bigtable.ServerTime, b)
+	return t.s.trees.Apply(ctx, treeRowKey(tree.TreeId), m)
+}
+
+func (t *adminTX) UpdateTree(ctx context.Context, treeID int64, updateFunc func(*trillian.Tree)) (*trillian.Tree, error) {
+	tree, err := t.GetTree(ctx, treeID)
+	if err != nil {
+		return nil, err
+	}
+
+	beforeUpdate := *tree
+	updateFunc(tree)
+	if err := storage.ValidateTreeForUpdate(ctx, &beforeUpdate, tree); err != nil {
+		return nil, err
+	}
+	if !proto.Equal(beforeUpdate.StorageSettings, tree.StorageSettings) {
+		return nil, status.Errorf(codes.InvalidArgument, "storage_settings can only be set on creation, got %v", tree.StorageSettings)
+	}
+	if tree.UpdateTime, err = ptypes.TimestampProto(time.Now()); err != nil {
+		return nil, err
+	}
+	if err := t.writeTree(ctx, tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+func (t *adminTX) SoftDeleteTree(ctx context.Context, treeID int64) (*trillian.Tree, error) {
+	return t.updateDeleted(ctx, treeID, true /* deleted */)
+}
+
+func (t *adminTX) UndeleteTree(ctx context.Context, treeID int64) (*trillian.Tree, error) {
+	return t.updateDeleted(ctx, treeID, false /* deleted */)
+}
+
+// updateDeleted updates the Deleted and DeleteTime fields of the specified tree.
+func (t *adminTX) updateDeleted(ctx context.Context, treeID int64, deleted bool) (*trillian.Tree, error) {
+	tree, err := t.getTreeWithDeleted(ctx, treeID, !deleted)
+	if err != nil {
+		return nil, err
+	}
+	tree.Deleted = deleted
+	tree.DeleteTime = nil
+	if deleted {
+		if tree.DeleteTime, err = ptypes.TimestampProto(time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	if err := t.writeTree(ctx, tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// HardDeleteTree removes the tree's metadata. Log data belonging to the tree
+// is left in place, and is expected to be removed by an offline job.
+func (t *adminTX) HardDeleteTree(ctx context.Context, treeID int64) error {
+	if _, err := t.getTreeWithDeleted(ctx, treeID, true /* wantDeleted */); err != nil {
+		return err
+	}
+	m := bigtable.NewMutation()
+	m.DeleteRow()
+	return t.s.trees.Apply(ctx, treeRowKey(treeID), m)
+}
+
+// getTreeWithDeleted returns the specified tree if its soft deletion state
+// matches wantDeleted, or an error otherwise.
+func (t *adminTX) getTreeWithDeleted(ctx context.Context, treeID int64, wantDeleted bool) (*trillian.Tree, error) {
+	tree, err := t.GetTree(ctx, treeID)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case wantDeleted && !tree.Deleted:
+		return nil, status.Errorf(codes.FailedPrecondition, "tree %v is not soft deleted", treeID)
+	case !wantDeleted && tree.Deleted:
+		return nil, status.Errorf(codes.FailedPrecondition, "tree %v already soft deleted", treeID)
+	}
+	return tree, nil
+}
@@ -0,0 +1,752 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigtable
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/bigtable"
+	"github.com/golang/glog"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/trillian"
+	"github.com/google/trillian/merkle/hashers"
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/cache"
+	"github.com/google/trillian/trees"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const logIDLabel = "logid"
+
+var (
+	once             sync.Once
+	queuedCounter    monitoring.Counter
+	queuedDupCounter monitoring.Counter
+	dequeuedCounter  monitoring.Counter
+	skippedCounter   monitoring.Counter
+	commitConflicts  monitoring.Counter
+)
+
+// orphanAge is the age beyond which queue entries without leaf data are
+// removed by DequeueLeaves. Such entries are left by QueueLeaves calls
+// which failed between writing the entry and the data, and younger ones may
+// belong to calls which are still in progress.
+const orphanAge = 10 * time.Minute
+
+func createMetrics(mf monitoring.MetricFactory) {
+	queuedCounter = mf.NewCounter("bigtable_queued_leaves", "Number of leaves queued", logIDLabel)
+	queuedDupCounter = mf.NewCounter("bigtable_queued_dup_leaves", "Number of duplicate leaves queued", logIDLabel)
+	dequeuedCounter = mf.NewCounter("bigtable_dequeued_leaves", "Number of leaves dequeued", logIDLabel)
+	skippedCounter = mf.NewCounter("bigtable_skipped_queue_entries", "Number of queue entries removed without being dequeued, as their leaves were already sequenced or never stored", logIDLabel)
+	commitConflicts = mf.NewCounter("bigtable_commit_conflicts", "Number of transactions which lost the race to store a tree head", logIDLabel)
+}
+
+// Row key infixes within the Leaves table.
+const (
+	leafDataInfix = "d/"
+	sequenceInfix = "s/"
+	hashInfix     = "h/"
+)
+
+func leafDataRowKey(treeID int64, identityHash []byte) string {
+	return treePrefix(treeID) + leafDataInfix + hex.EncodeToString(identityHash)
+}
+
+func sequenceRowKey(treeID, seq int64) string {
+	return treePrefix(treeID) + sequenceInfix + fmt.Sprintf("%016x", uint64(seq))
+}
+
+func hashPrefix(treeID int64, merkleHash []byte) string {
+	return treePrefix(treeID) + hashInfix + hex.EncodeToString(merkleHash) + "/"
+}
+
+func unsequencedRowKey(treeID int64, queueTimestampNanos int64, identityHash []byte) string {
+	return treePrefix(treeID) + fmt.Sprintf("%016x/", uint64(queueTimestampNanos)) + hex.EncodeToString(identityHash)
+}
+
+type logStorage struct {
+	*treeStorage
+	leaves        *bigtable.Table
+	unsequenced   *bigtable.Table
+	metricFactory monitoring.MetricFactory
+}
+
+// NewLogStorage creates a Bigtable-backed storage.LogStorage. Tree
+// metadata is read from admin, which would usually be the AdminStorage
+// returned by NewAdminStorage for the same client.
+// Adding pre-ordered leaves via AddSequencedLeaves is not supported.
+func NewLogStorage(client *bigtable.Client, admin storage.AdminStorage, mf monitoring.MetricFactory) storage.LogStorage {
+	if mf == nil {
+		mf = monitoring.InertMetricFactory{}
+	}
+	ts := newTreeStorage(client)
+	ts.admin = admin
+	return &logStorage{
+		treeStorage:   ts,
+		leaves:        client.Open(leavesTbl),
+		unsequenced:   client.Open(unsequencedTbl),
+		metricFactory: mf,
+	}
+}
+
+func (ls *logStorage) CheckDatabaseAccessible(ctx context.Context) error {
+	return checkDatabaseAccessible(ctx, ls.heads)
+}
+
+func (ls *logStorage) begin(ctx context.Context, treeID int64, readonly bool) (*logTX, error) {
+	once.Do(func() {
+		createMetrics(ls.metricFactory)
+	})
+	tree, err := trees.GetTree(ctx, ls.admin, treeID, trees.NewGetOpts(readonly, trillian.TreeType_LOG))
+	if err != nil {
+		return nil, err
+	}
+	hasher, err := hashers.NewLogHasher(tree.HashStrategy)
+	if err != nil {
+		return nil, err
+	}
+	strata, err := storage.SubtreeStrata(tree)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := &logTX{
+		treeTX: treeTX{
+			ts:       ls.treeStorage,
+			treeID:   treeID,
+			cache:    cache.NewLogSubtreeCache(strata, hasher),
+			readonly: readonly,
+		},
+		ls:       ls,
+		hashSize: hasher.Size(),
+		dequeued: make(map[string]string),
+	}
+
+	head, err := ls.latestHead(ctx, treeID)
+	if err != nil {
+		// An uninitialised tree can still be written to, to create the
+		// first tree head at revision 0.
+		return tx, err
+	}
+	if err := proto.Unmarshal(head, &tx.root); err != nil {
+		return nil, err
+	}
+	tx.readRev = tx.root.TreeRevision
+	tx.writeRev = tx.root.TreeRevision + 1
+	return tx, nil
+}
+
+func (ls *logStorage) ReadWriteTransaction(ctx context.Context, treeID int64, f storage.LogTXFunc) error {
+	tx, err := ls.begin(ctx, treeID, false /* readonly */)
+	if err != nil && err != storage.ErrTreeNeedsInit {
+		return err
+	}
+	defer tx.Close()
+	if err := f(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (ls *logStorage) SnapshotForTree(ctx context.Context, treeID int64) (storage.ReadOnlyLogTreeTX, error) {
+	tx, err := ls.begin(ctx, treeID, true /* readonly */)
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+func (ls *logStorage) Snapshot(ctx context.Context) (storage.ReadOnlyLogTX, error) {
+	return &readOnlyLogTX{ls: ls}, nil
+}
+
+func (ls *logStorage) QueueLeaves(ctx context.Context, treeID int64, leaves []*trillian.LogLeaf, queueTimestamp time.Time) ([]*trillian.QueuedLogLeaf, error) {
+	tx, err := ls.begin(ctx, treeID, false /* readonly */)
+	if err != nil && err != storage.ErrTreeNeedsInit {
+		return nil, err
+	}
+	defer tx.Close()
+	existing, err := tx.QueueLeaves(ctx, leaves, queueTimestamp)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	ret := make([]*trillian.QueuedLogLeaf, len(leaves))
+	for i, e := range existing {
+		if e != nil {
+			ret[i] = &trillian.QueuedLogLeaf{
+				Leaf:   e,
+				Status: status.Newf(codes.AlreadyExists, "leaf already exists: %v", e.LeafIdentityHash).Proto(),
+			}
+			continue
+		}
+		ret[i] = &trillian.QueuedLogLeaf{Leaf: leaves[i]}
+	}
+	return ret, nil
+}
+
+func (ls *logStorage) AddSequencedLeaves(ctx context.Context, treeID int64, leaves []*trillian.LogLeaf) ([]*trillian.QueuedLogLeaf, error) {
+	return nil, status.Errorf(codes.Unimplemented, "AddSequencedLeaves is not implemented")
+}
+
+// logTX implements storage.LogTreeTX.
+type logTX struct {
+	treeTX
+	ls       *logStorage
+	hashSize int
+	root     trillian.SignedLogRoot
+
+	// newRoot is the root stored by StoreSignedLogRoot, if any.
+	newRoot *trillian.SignedLogRoot
+	// dequeued maps the identity hash of each dequeued leaf to its row key in
+	// the Unsequenced table.
+	dequeued map[string]string
+	// integrated holds the Unsequenced row keys to delete on Commit, of
+	// leaves which have been sequenced or skipped.
+	integrated []string
+}
+
+func (t *logTX) label() string {
+	return strconv.FormatInt(t.treeID, 10)
+}
+
+// GetNodesByCoords implements storage.CoordNodeReader.
+func (t *logTX) GetNodesByCoords(ctx context.Context, rev int64, coords []storage.NodeCoords) ([]storage.CoordNode, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.closed {
+		return nil, ErrTransactionClosed
+	}
+	return t.cache.GetNodesByCoords(coords, t.getSubtreesAtRev(ctx, rev))
+}
+
+func (t *logTX) LatestSignedLogRoot(ctx context.Context) (trillian.SignedLogRoot, error) {
+	return t.root, nil
+}
+
+// GetSequencedLeafCount returns the size of the tree as of the latest tree
+// head, as sequencing and tree head updates happen in the same transaction.
+func (t *logTX) GetSequencedLeafCount(ctx context.Context) (int64, error) {
+	return t.root.TreeSize, nil
+}
+
+func (t *logTX) StoreSignedLogRoot(ctx context.Context, root trillian.SignedLogRoot) error {
+	if t.readonly {
+		return ErrWrongTXType
+	}
+	if root.TreeRevision != t.writeRev {
+		return fmt.Errorf("root revision %d does not match write revision %d", root.TreeRevision, t.writeRev)
+	}
+	t.newRoot = &root
+	return nil
+}
+
+func (t *logTX) QueueLeaves(ctx context.Context, leaves []*trillian.LogLeaf, queueTimestamp time.Time) ([]*trillian.LogLeaf, error) {
+	if t.readonly {
+		return nil, ErrWrongTXType
+	}
+	// Don't accept batches if any of the leaves are invalid.
+	for _, leaf := range leaves {
+		if len(leaf.LeafIdentityHash) != t.hashSize {
+			return nil, fmt.Errorf("queued leaf must have a leaf ID hash of length %d", t.hashSize)
+		}
+	}
+	if _, err := ptypes.TimestampProto(queueTimestamp); err != nil {
+		return nil, err
+	}
+	label := t.label()
+
+	// Each leaf's queue entry is written before its data, which doubles as
+	// the de-duplication record, so that a leaf whose data is stored is
+	// always queued. Entries written for duplicates are removed again, and
+	// DequeueLeaves skips any which are left over, or whose data was never
+	// written.
+	existing := make([]*trillian.LogLeaf, len(leaves))
+	for i, leaf := range leaves {
+		qKey := unsequencedRowKey(t.treeID, queueTimestamp.UnixNano(), leaf.LeafIdentityHash)
+		q := bigtable.NewMutation()
+		q.Set(queueFamily, leafCol, bigtable.ServerTime, leaf.LeafIdentityHash)
+		if err := t.ls.unsequenced.Apply(ctx, qKey, q); err != nil {
+			return nil, err
+		}
+
+		set := bigtable.NewMutation()
+		set.Set(leafFamily, valueCol, bigtable.ServerTime, leaf.LeafValue)
+		set.Set(leafFamily, extraCol, bigtable.ServerTime, leaf.ExtraData)
+		set.Set(leafFamily, merkleCol, bigtable.ServerTime, leaf.MerkleLeafHash)
+		set.Set(leafFamily, queueTSCol, bigtable.ServerTime, int64ToBytes(queueTimestamp.UnixNano()))
+		var dup bool
+		m := bigtable.NewCondMutation(bigtable.FamilyFilter(leafFamily), nil, set)
+		if err := t.ls.leaves.Apply(ctx, leafDataRowKey(t.treeID, leaf.LeafIdentityHash), m, bigtable.GetCondMutationResult(&dup)); err != nil {
+			return nil, err
+		}
+		if !dup {
+			queuedCounter.Inc(label)
+			continue
+		}
+
+		queuedDupCounter.Inc(label)
+		prev, err := t.readLeafData(ctx, leaf.LeafIdentityHash)
+		if err != nil {
+			return nil, err
+		}
+		existing[i] = prev
+		// A duplicate queued at the same time as the original (e.g. in the
+		// same batch) shares its queue entry, which must be kept.
+		if prevTS, err := ptypes.Timestamp(prev.QueueTimestamp); err == nil && prevTS.Equal(queueTimestamp) {
+			continue
+		}
+		del := bigtable.NewMutation()
+		del.DeleteRow()
+		if err := t.ls.unsequenced.Apply(ctx, qKey, del); err != nil {
+			glog.Warningf("%v: failed to remove queue entry of duplicate leaf %x: %v", t.treeID, leaf.LeafIdentityHash, err)
+		}
+	}
+	return existing, nil
+}
+
+// readLeafData returns a leaf holding the stored data for identityHash.
+func (t *logTX) readLeafData(ctx context.Context, identityHash []byte) (*trillian.LogLeaf, error) {
+	row, err := t.ls.leaves.ReadRow(ctx, leafDataRowKey(t.treeID, identityHash), bigtable.RowFilter(bigtable.ChainFilters(bigtable.FamilyFilter(leafFamily), bigtable.LatestNFilter(1))))
+	if err != nil {
+		return nil, err
+	}
+	return leafFromDataRow(row, identityHash)
+}
+
+func leafFromDataRow(row bigtable.Row, identityHash []byte) (*trillian.LogLeaf, error) {
+	if len(row) == 0 {
+		return nil, fmt.Errorf("no data for leaf %x", identityHash)
+	}
+	leaf := &trillian.LogLeaf{
+		LeafIdentityHash: identityHash,
+		LeafValue:        cell(row, leafFamily, valueCol),
+		ExtraData:        cell(row, leafFamily, extraCol),
+		MerkleLeafHash:   cell(row, leafFamily, merkleCol),
+	}
+	qNanos, err := bytesToInt64(cell(row, leafFamily, queueTSCol))
+	if err != nil {
+		return nil, fmt.Errorf("bad queue timestamp for leaf %x: %v", identityHash, err)
+	}
+	if leaf.QueueTimestamp, err = ptypes.TimestampProto(time.Unix(0, qNanos)); err != nil {
+		return nil, err
+	}
+	return leaf, nil
+}
+
+// DequeueLeaves returns leaves from the queue in queue timestamp order. Queue
+// entries of leaves which have already been sequenced as of the
+// transaction's read revision, of leaves dequeued twice, and orphaned ones
+// without leaf data are skipped, and removed on Commit.
+func (t *logTX) DequeueLeaves(ctx context.Context, limit int, cutoffTime time.Time) ([]*trillian.LogLeaf, error) {
+	if t.readonly {
+		return nil, ErrWrongTXType
+	}
+	prefix := treePrefix(t.treeID)
+	end := prefix + fmt.Sprintf("%016x", uint64(cutoffTime.UnixNano()))
+
+	type entry struct {
+		key          string
+		identityHash []byte
+		queued       time.Time
+	}
+	var entries []entry
+	err := t.ls.unsequenced.ReadRows(ctx, bigtable.NewRange(prefix, end), func(row bigtable.Row) bool {
+		e := entry{key: row.Key(), identityHash: cell(row, queueFamily, leafCol)}
+		for _, item := range row[queueFamily] {
+			e.queued = item.Timestamp.Time()
+		}
+		entries = append(entries, e)
+		return true
+	}, bigtable.LimitRows(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+
+	dataKeys := make(bigtable.RowList, 0, len(entries))
+	for _, e := range entries {
+		dataKeys = append(dataKeys, leafDataRowKey(t.treeID, e.identityHash))
+	}
+	data := make(map[string]bigtable.Row)
+	err = t.ls.leaves.ReadRows(ctx, dataKeys, func(row bigtable.Row) bool {
+		data[row.Key()] = row
+		return true
+	}, bigtable.RowFilter(bigtable.InterleaveFilters(
+		bigtable.ChainFilters(bigtable.FamilyFilter(leafFamily), bigtable.LatestNFilter(1)),
+		bigtable.ChainFilters(
+			bigtable.FamilyFilter(sequenceFamily),
+			bigtable.TimestampRangeFilterMicros(0, revTimestamp(t.readRev+1)),
+			bigtable.LatestNFilter(1)),
+	)))
+	if err != nil {
+		return nil, err
+	}
+
+	label := t.label()
+	leaves := make([]*trillian.LogLeaf, 0, len(entries))
+	for _, e := range entries {
+		row := data[leafDataRowKey(t.treeID, e.identityHash)]
+		_, dequeued := t.dequeued[string(e.identityHash)]
+		switch {
+		case len(row[leafFamily]) == 0:
+			if time.Since(e.queued) > orphanAge {
+				t.integrated = append(t.integrated, e.key)
+				skippedCounter.Inc(label)
+			}
+			continue
+		case dequeued, cell(row, sequenceFamily, indexCol) != nil:
+			t.integrated = append(t.integrated, e.key)
+			skippedCounter.Inc(label)
+			continue
+		}
+		leaf, err := leafFromDataRow(row, e.identityHash)
+		if err != nil {
+			return nil, err
+		}
+		t.dequeued[string(e.identityHash)] = e.key
+		leaves = append(leaves, leaf)
+	}
+
+	dequeuedCounter.Add(float64(len(leaves)), label)
+	return leaves, nil
+}
+
+func (t *logTX) UpdateSequencedLeaves(ctx context.Context, leaves []*trillian.LogLeaf) error {
+	if t.readonly {
+		return ErrWrongTXType
+	}
+	ts := revTimestamp(t.writeRev)
+	for _, leaf := range leaves {
+		// This should fail on insert but catch it early
+		if got, want := len(leaf.LeafIdentityHash), t.hashSize; got != want {
+			return fmt.Errorf("sequenced leaf has incorrect hash size: got %v, want %v", got, want)
+		}
+		qKey, ok := t.dequeued[string(leaf.LeafIdentityHash)]
+		if !ok {
+			return fmt.Errorf("attempted to update unknown leaf %x", leaf.LeafIdentityHash)
+		}
+		iTS, err := ptypes.Timestamp(leaf.IntegrateTimestamp)
+		if err != nil {
+			return fmt.Errorf("got invalid integrate timestamp: %v", err)
+		}
+
+		t.writes.set(leavesTbl, sequenceRowKey(t.treeID, leaf.LeafIndex), sequenceFamily, ts, map[string][]byte{
+			identityCol:  leaf.LeafIdentityHash,
+			merkleCol:    leaf.MerkleLeafHash,
+			integrateCol: int64ToBytes(iTS.UnixNano()),
+		})
+		t.writes.set(leavesTbl, hashPrefix(t.treeID, leaf.MerkleLeafHash)+fmt.Sprintf("%016x", uint64(leaf.LeafIndex)), sequenceFamily, ts, map[string][]byte{
+			identityCol: leaf.LeafIdentityHash,
+		})
+		// Marks the leaf as sequenced, for DequeueLeaves to skip any queue
+		// entries left over for it.
+		t.writes.set(leavesTbl, leafDataRowKey(t.treeID, leaf.LeafIdentityHash), sequenceFamily, ts, map[string][]byte{
+			indexCol: int64ToBytes(leaf.LeafIndex),
+		})
+
+		t.integrated = append(t.integrated, qKey)
+	}
+	return nil
+}
+
+// GetLeavesByIndex returns the requested leaves. Indices at or beyond the
+// size of the tree as of the latest tree head are ignored.
+func (t *logTX) GetLeavesByIndex(ctx context.Context, indices []int64) ([]*trillian.LogLeaf, error) {
+	keys := make(bigtable.RowList, 0, len(indices))
+	for _, idx := range indices {
+		if idx >= 0 && idx < t.root.TreeSize {
+			keys = append(keys, sequenceRowKey(t.treeID, idx))
+		}
+	}
+	leaves, err := t.readSequenced(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*trillian.LogLeaf, 0, len(indices))
+	for _, idx := range indices {
+		if leaf, ok := leaves[idx]; ok {
+			ret = append(ret, leaf)
+		}
+	}
+	return ret, nil
+}
+
+func (t *logTX) GetLeavesByRange(ctx context.Context, start, count int64) ([]*trillian.LogLeaf, error) {
+	if count <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid count %d", count)
+	}
+	if start < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid start %d", start)
+	}
+	if max := t.root.TreeSize - start; count > max {
+		count = max
+	}
+	if count <= 0 {
+		return []*trillian.LogLeaf{}, nil
+	}
+	indices := make([]int64, 0, count)
+	for i := int64(0); i < count; i++ {
+		indices = append(indices, start+i)
+	}
+	return t.GetLeavesByIndex(ctx, indices)
+}
+
+// GetLeavesByHash returns the leaves with the given Merkle leaf hashes. Hash
+// index rows may be left over from transactions which never committed, so
+// only leaves whose hashes match are returned.
+func (t *logTX) GetLeavesByHash(ctx context.Context, leafHashes [][]byte, orderBySequence bool) ([]*trillian.LogLeaf, error) {
+	var indices []int64
+	want := make(map[string]bool)
+	for _, hash := range leafHashes {
+		want[string(hash)] = true
+		err := t.ls.leaves.ReadRows(ctx, bigtable.PrefixRange(hashPrefix(t.treeID, hash)), func(row bigtable.Row) bool {
+			key := row.Key()
+			idx, err := strconv.ParseUint(key[len(key)-16:], 16, 64)
+			if err != nil {
+				glog.Warningf("Ignoring malformed hash index row %q: %v", key, err)
+				return true
+			}
+			indices = append(indices, int64(idx))
+			return true
+		}, bigtable.RowFilter(bigtable.StripValueFilter()))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if orderBySequence {
+		sort.Slice(indices, func(i, j int) bool { return indices[i] < indices[j] })
+	}
+	leaves, err := t.GetLeavesByIndex(ctx, indices)
+	if err != nil {
+		return nil, err
+	}
+	ret := leaves[:0]
+	for _, leaf := range leaves {
+		if want[string(leaf.MerkleLeafHash)] {
+			ret = append(ret, leaf)
+		}
+	}
+	return ret, nil
+}
+
+// readSequenced reads the sequenced leaves stored in the given rows, along
+// with their data, and returns them by leaf index.
+func (t *logTX) readSequenced(ctx context.Context, keys bigtable.RowList) (map[int64]*trillian.LogLeaf, error) {
+	ret := make(map[int64]*trillian.LogLeaf)
+	if len(keys) == 0 {
+		return ret, nil
+	}
+
+	type seqInfo struct {
+		idx                      int64
+		identityHash, merkleHash []byte
+		integrateNanos           int64
+	}
+	var seqs []seqInfo
+	var readErr error
+	filter := bigtable.RowFilter(bigtable.ChainFilters(
+		bigtable.TimestampRangeFilterMicros(0, revTimestamp(t.readRev+1)),
+		bigtable.LatestNFilter(1),
+	))
+	err := t.ls.leaves.ReadRows(ctx, keys, func(row bigtable.Row) bool {
+		key := row.Key()
+		idx, err := strconv.ParseUint(key[len(key)-16:], 16, 64)
+		if err != nil {
+			readErr = fmt.Errorf("malformed sequence row key %q: %v", key, err)
+			return false
+		}
+		s := seqInfo{
+			idx:          int64(idx),
+			identityHash: cell(row, sequenceFamily, identityCol),
+			merkleHash:   cell(row, sequenceFamily, merkleCol),
+		}
+		if s.integrateNanos, readErr = bytesToInt64(cell(row, sequenceFamily, integrateCol)); readErr != nil {
+			return false
+		}
+		seqs = append(seqs, s)
+		return true
+	}, filter)
+	if err != nil {
+		return nil, err
+	}
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	dataKeys := make(bigtable.RowList, 0, len(seqs))
+	for _, s := range seqs {
+		dataKeys = append(dataKeys, leafDataRowKey(t.treeID, s.identityHash))
+	}
+	data := make(map[string]bigtable.Row)
+	err = t.ls.leaves.ReadRows(ctx, dataKeys, func(row bigtable.Row) bool {
+		data[row.Key()] = row
+		return true
+	}, bigtable.RowFilter(bigtable.LatestNFilter(1)))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range seqs {
+		leaf, err := leafFromDataRow(data[leafDataRowKey(t.treeID, s.identityHash)], s.identityHash)
+		if err != nil {
+			return nil, err
+		}
+		leaf.LeafIndex = s.idx
+		leaf.MerkleLeafHash = s.merkleHash
+		if leaf.IntegrateTimestamp, err = ptypes.TimestampProto(time.Unix(0, s.integrateNanos)); err != nil {
+			return nil, err
+		}
+		ret[s.idx] = leaf
+	}
+	return ret, nil
+}
+
+func (t *logTX) GetActiveLogIDs(ctx context.Context) ([]int64, error) {
+	return getActiveLogIDs(ctx, t.ls.admin)
+}
+
+// Commit applies the writes made by the transaction, after claiming its write
+// revision. They're only made visible by the tree head, which is stored
+// last. If Commit returns an error the transaction may be retried from
+// scratch.
+func (t *logTX) Commit() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return ErrTransactionClosed
+	}
+	t.closed = true
+	if t.readonly {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commitTimeout)
+	defer cancel()
+	var head []byte
+	if t.newRoot != nil {
+		var err error
+		if head, err = proto.Marshal(t.newRoot); err != nil {
+			return err
+		}
+	}
+	if err := t.commitWrites(ctx, head); err != nil {
+		if err == ErrRevisionConflict {
+			commitConflicts.Inc(t.label())
+		}
+		return err
+	}
+
+	// Removing sequenced leaves from the queue can safely fail, as
+	// DequeueLeaves skips the entries of leaves which are already sequenced.
+	if len(t.integrated) > 0 {
+		muts := make([]*bigtable.Mutation, len(t.integrated))
+		for i := range muts {
+			muts[i] = bigtable.NewMutation()
+			muts[i].DeleteRow()
+		}
+		if err := applyBulk(ctx, t.ls.unsequenced, t.integrated, muts); err != nil {
+			glog.Warningf("%v: failed to remove integrated leaves from queue: %v", t.treeID, err)
+		}
+	}
+	return nil
+}
+
+func (t *logTX) Rollback() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return ErrTransactionClosed
+	}
+	t.closed = true
+	return nil
+}
+
+func (t *logTX) Close() error {
+	if t.IsOpen() {
+		if err := t.Rollback(); err != nil && err != ErrTransactionClosed {
+			glog.Warningf("Rollback error on Close(): %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// readOnlyLogTX implements storage.ReadOnlyLogTX.
+type readOnlyLogTX struct {
+	ls *logStorage
+}
+
+func (t *readOnlyLogTX) Commit() error {
+	return nil
+}
+
+func (t *readOnlyLogTX) Rollback() error {
+	return nil
+}
+
+func (t *readOnlyLogTX) Close() error {
+	return nil
+}
+
+func (t *readOnlyLogTX) GetActiveLogIDs(ctx context.Context) ([]int64, error) {
+	return getActiveLogIDs(ctx, t.ls.admin)
+}
+
+// GetUnsequencedCounts scans the whole Unsequenced table.
+func (t *readOnlyLogTX) GetUnsequencedCounts(ctx context.Context) (storage.CountByLogID, error) {
+	ret := make(storage.CountByLogID)
+	var readErr error
+	err := t.ls.unsequenced.ReadRows(ctx, bigtable.InfiniteRange(""), func(row bigtable.Row) bool {
+		key := row.Key()
+		id, err := strconv.ParseUint(key[:16], 16, 64)
+		if err != nil {
+			readErr = fmt.Errorf("malformed queue row key %q: %v", key, err)
+			return false
+		}
+		ret[int64(id)]++
+		return true
+	}, bigtable.RowFilter(bigtable.StripValueFilter()))
+	if err != nil {
+		return nil, err
+	}
+	return ret, readErr
+}
+
+// getActiveLogIDs returns the IDs of all non-deleted logs in the ACTIVE state.
+func getActiveLogIDs(ctx context.Context, admin storage.AdminStorage) ([]int64, error) {
+	trees, err := storage.ListTrees(ctx, admin, false /* includeDeleted */)
+	if err != nil {
+		return nil, err
+	}
+	ids := []int64{}
+	for _, tree := range trees {
+		if tree.TreeType == trillian.TreeType_LOG && tree.TreeState == trillian.TreeState_ACTIVE {
+			ids = append(ids, tree.TreeId)
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,352 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigtable
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigtable"
+	"cloud.google.com/go/bigtable/bttest"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/testonly"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newTestStorage returns storage backed by an in-memory Bigtable, holding a
+// single initialised log.
+func newTestStorage(t *testing.T) (*logStorage, *bigtable.Client, int64) {
+	t.Helper()
+	ctx := context.Background()
+	srv, err := bttest.NewServer("localhost:0")
+	if err != nil {
+		t.Fatalf("bttest.NewServer(): %v", err)
+	}
+	conn, err := grpc.Dial(srv.Addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Dial(): %v", err)
+	}
+	ac, err := bigtable.NewAdminClient(ctx, "project", "instance", option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatalf("NewAdminClient(): %v", err)
+	}
+	if err := CreateTables(ctx, ac, TableOptions{}); err != nil {
+		t.Fatalf("CreateTables(): %v", err)
+	}
+	client, err := bigtable.NewClient(ctx, "project", "instance", option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatalf("NewClient(): %v", err)
+	}
+
+	as := NewAdminStorage(client)
+	tree, err := storage.CreateTree(ctx, as, testonly.LogTree)
+	if err != nil {
+		t.Fatalf("CreateTree(): %v", err)
+	}
+	ls := NewLogStorage(client, as, nil).(*logStorage)
+	err = ls.ReadWriteTransaction(ctx, tree.TreeId, func(ctx context.Context, tx storage.LogTreeTX) error {
+		return tx.StoreSignedLogRoot(ctx, trillian.SignedLogRoot{LogId: tree.TreeId})
+	})
+	if err != nil {
+		t.Fatalf("failed to initialise log: %v", err)
+	}
+	return ls, client, tree.TreeId
+}
+
+func testLeaves(n, start int) []*trillian.LogLeaf {
+	var leaves []*trillian.LogLeaf
+	for i := start; i < start+n; i++ {
+		value := []byte(fmt.Sprintf("leaf %d", i))
+		hash := sha256.Sum256(value)
+		leaves = append(leaves, &trillian.LogLeaf{
+			LeafIdentityHash: hash[:],
+			MerkleLeafHash:   hash[:],
+			LeafValue:        value,
+			ExtraData:        []byte(fmt.Sprintf("extra %d", i)),
+		})
+	}
+	return leaves
+}
+
+// sequence dequeues up to limit leaves in tx, sequences them after the
+// leaves already in the tree, and stores the resulting root.
+func sequence(ctx context.Context, t *testing.T, tx *logTX, limit int) []*trillian.LogLeaf {
+	t.Helper()
+	leaves, err := tx.DequeueLeaves(ctx, limit, time.Now())
+	if err != nil {
+		t.Fatalf("DequeueLeaves(): %v", err)
+	}
+	size := tx.root.TreeSize
+	for i, leaf := range leaves {
+		leaf.LeafIndex = size + int64(i)
+		leaf.IntegrateTimestamp = ptypes.TimestampNow()
+	}
+	if err := tx.UpdateSequencedLeaves(ctx, leaves); err != nil {
+		t.Fatalf("UpdateSequencedLeaves(): %v", err)
+	}
+	root := trillian.SignedLogRoot{LogId: tx.treeID, TreeSize: size + int64(len(leaves)), TreeRevision: tx.WriteRevision()}
+	if err := tx.StoreSignedLogRoot(ctx, root); err != nil {
+		t.Fatalf("StoreSignedLogRoot(): %v", err)
+	}
+	return leaves
+}
+
+func mustQueue(ctx context.Context, t *testing.T, ls *logStorage, treeID int64, leaves []*trillian.LogLeaf) []*trillian.QueuedLogLeaf {
+	t.Helper()
+	queued, err := ls.QueueLeaves(ctx, treeID, leaves, time.Now())
+	if err != nil {
+		t.Fatalf("QueueLeaves(): %v", err)
+	}
+	return queued
+}
+
+func mustBegin(ctx context.Context, t *testing.T, ls *logStorage, treeID int64) *logTX {
+	t.Helper()
+	tx, err := ls.begin(ctx, treeID, false /* readonly */)
+	if err != nil {
+		t.Fatalf("begin(): %v", err)
+	}
+	return tx
+}
+
+func TestQueueAndSequence(t *testing.T) {
+	ctx := context.Background()
+	ls, _, treeID := newTestStorage(t)
+
+	leaves := testLeaves(3, 0)
+	mustQueue(ctx, t, ls, treeID, leaves)
+
+	// Duplicates are reported with the leaf stored first.
+	dup := *leaves[1]
+	dup.LeafValue = []byte("different value")
+	queued := mustQueue(ctx, t, ls, treeID, []*trillian.LogLeaf{&dup})
+	if got, want := status.FromProto(queued[0].Status).Code(), codes.AlreadyExists; got != want {
+		t.Errorf("QueueLeaves(duplicate) returned status %v, want %v", got, want)
+	}
+	if got, want := queued[0].Leaf.LeafValue, leaves[1].LeafValue; !bytes.Equal(got, want) {
+		t.Errorf("QueueLeaves(duplicate) returned leaf value %q, want %q", got, want)
+	}
+
+	tx := mustBegin(ctx, t, ls, treeID)
+	seq := sequence(ctx, t, tx, 10)
+	if got, want := len(seq), len(leaves); got != want {
+		t.Fatalf("sequenced %d leaves, want %d", got, want)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit(): %v", err)
+	}
+
+	tx = mustBegin(ctx, t, ls, treeID)
+	defer tx.Close()
+	if got, want := tx.root.TreeSize, int64(len(leaves)); got != want {
+		t.Errorf("tree size %d after commit, want %d", got, want)
+	}
+	got, err := tx.GetLeavesByRange(ctx, 0, 10)
+	if err != nil {
+		t.Fatalf("GetLeavesByRange(): %v", err)
+	}
+	if len(got) != len(seq) {
+		t.Fatalf("GetLeavesByRange() returned %d leaves, want %d", len(got), len(seq))
+	}
+	for i, leaf := range got {
+		if !bytes.Equal(leaf.LeafValue, seq[i].LeafValue) || leaf.LeafIndex != seq[i].LeafIndex {
+			t.Errorf("GetLeavesByRange()[%d] = %v, want %v", i, leaf, seq[i])
+		}
+	}
+	byHash, err := tx.GetLeavesByHash(ctx, [][]byte{seq[2].MerkleLeafHash}, false)
+	if err != nil {
+		t.Fatalf("GetLeavesByHash(): %v", err)
+	}
+	if len(byHash) != 1 || byHash[0].LeafIndex != 2 {
+		t.Errorf("GetLeavesByHash() = %v, want leaf 2", byHash)
+	}
+
+	// The queue is empty once the leaves have been sequenced.
+	if more, err := tx.DequeueLeaves(ctx, 10, time.Now()); err != nil || len(more) != 0 {
+		t.Errorf("DequeueLeaves() after sequencing = %v, %v; want no leaves", more, err)
+	}
+}
+
+func TestCommitConflict(t *testing.T) {
+	ctx := context.Background()
+	ls, _, treeID := newTestStorage(t)
+	mustQueue(ctx, t, ls, treeID, testLeaves(2, 0))
+
+	// Both transactions write at the same revision; the first to commit wins,
+	// and the other must not overwrite anything it wrote.
+	loser := mustBegin(ctx, t, ls, treeID)
+	winner := mustBegin(ctx, t, ls, treeID)
+	sequence(ctx, t, loser, 1)
+	won := sequence(ctx, t, winner, 2)
+	// The loser sequences the same leaf at the same index, but claims to
+	// have integrated it at a different time.
+	if err := winner.Commit(); err != nil {
+		t.Fatalf("winner Commit(): %v", err)
+	}
+	if err := loser.Commit(); err != ErrRevisionConflict {
+		t.Fatalf("loser Commit(): %v, want %v", err, ErrRevisionConflict)
+	}
+
+	tx := mustBegin(ctx, t, ls, treeID)
+	defer tx.Close()
+	got, err := tx.GetLeavesByIndex(ctx, []int64{0, 1})
+	if err != nil {
+		t.Fatalf("GetLeavesByIndex(): %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("GetLeavesByIndex() returned %d leaves, want 2", len(got))
+	}
+	for i, leaf := range got {
+		if !proto.Equal(leaf.IntegrateTimestamp, won[i].IntegrateTimestamp) {
+			t.Errorf("leaf %d integrated at %v, want the winner's %v", i, leaf.IntegrateTimestamp, won[i].IntegrateTimestamp)
+		}
+	}
+}
+
+func TestExpiredClaimTakenOver(t *testing.T) {
+	ctx := context.Background()
+	ls, client, treeID := newTestStorage(t)
+	leaves := testLeaves(1, 0)
+	mustQueue(ctx, t, ls, treeID, leaves)
+
+	// A transaction which died after claiming revision 1 and writing a hash
+	// index row for a leaf it sequenced.
+	staleRow := hashPrefix(treeID, []byte("stale hash")) + fmt.Sprintf("%016x", 0)
+	stale := bigtable.NewMutation()
+	stale.Set(sequenceFamily, identityCol, revTimestamp(1), leaves[0].LeafIdentityHash)
+	if err := client.Open(leavesTbl).Apply(ctx, staleRow, stale); err != nil {
+		t.Fatalf("failed to write stale cell: %v", err)
+	}
+	intent, err := json.Marshal([]revCells{{Table: leavesTbl, Row: staleRow, Family: sequenceFamily, Columns: []string{identityCol}}})
+	if err != nil {
+		t.Fatalf("json.Marshal(): %v", err)
+	}
+	claim := bigtable.NewMutation()
+	claimed := bigtable.Time(time.Now().Add(-2 * claimLease)).TruncateToMilliseconds()
+	claim.Set(headFamily, claimCol, claimed, []byte("stale"))
+	claim.Set(headFamily, intentCol, claimed, intent)
+	if err := client.Open(treeHeadsTbl).Apply(ctx, headRowKey(treeID, 1), claim); err != nil {
+		t.Fatalf("failed to write stale claim: %v", err)
+	}
+
+	tx := mustBegin(ctx, t, ls, treeID)
+	sequence(ctx, t, tx, 1)
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit() over expired claim: %v", err)
+	}
+
+	row, err := client.Open(leavesTbl).ReadRow(ctx, staleRow)
+	if err != nil {
+		t.Fatalf("ReadRow(): %v", err)
+	}
+	if len(row) != 0 {
+		t.Errorf("stale cell not removed: %v", row)
+	}
+}
+
+func TestUnexpiredClaimConflicts(t *testing.T) {
+	ctx := context.Background()
+	ls, client, treeID := newTestStorage(t)
+	mustQueue(ctx, t, ls, treeID, testLeaves(1, 0))
+
+	claim := bigtable.NewMutation()
+	claim.Set(headFamily, claimCol, bigtable.ServerTime, []byte("live"))
+	claim.Set(headFamily, intentCol, bigtable.ServerTime, []byte("[]"))
+	if err := client.Open(treeHeadsTbl).Apply(ctx, headRowKey(treeID, 1), claim); err != nil {
+		t.Fatalf("failed to write claim: %v", err)
+	}
+
+	tx := mustBegin(ctx, t, ls, treeID)
+	sequence(ctx, t, tx, 1)
+	if err := tx.Commit(); err != ErrRevisionConflict {
+		t.Errorf("Commit() over live claim: %v, want %v", err, ErrRevisionConflict)
+	}
+
+	// The claimed revision isn't mistaken for a tree head.
+	tx = mustBegin(ctx, t, ls, treeID)
+	defer tx.Close()
+	if got := tx.ReadRevision(); got != 0 {
+		t.Errorf("ReadRevision() = %d with revision 1 only claimed, want 0", got)
+	}
+}
+
+func TestDequeueSkipsSequencedLeaves(t *testing.T) {
+	ctx := context.Background()
+	ls, client, treeID := newTestStorage(t)
+	leaves := testLeaves(2, 0)
+	mustQueue(ctx, t, ls, treeID, leaves[:1])
+
+	tx := mustBegin(ctx, t, ls, treeID)
+	sequence(ctx, t, tx, 1)
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit(): %v", err)
+	}
+
+	// Queue entries left over for a sequenced leaf, e.g. because removing
+	// them failed, are skipped.
+	q := bigtable.NewMutation()
+	q.Set(queueFamily, leafCol, bigtable.ServerTime, leaves[0].LeafIdentityHash)
+	if err := client.Open(unsequencedTbl).Apply(ctx, unsequencedRowKey(treeID, time.Now().UnixNano(), leaves[0].LeafIdentityHash), q); err != nil {
+		t.Fatalf("failed to write queue entry: %v", err)
+	}
+	mustQueue(ctx, t, ls, treeID, leaves[1:])
+
+	tx = mustBegin(ctx, t, ls, treeID)
+	seq := sequence(ctx, t, tx, 10)
+	if len(seq) != 1 || !bytes.Equal(seq[0].LeafIdentityHash, leaves[1].LeafIdentityHash) {
+		t.Fatalf("sequenced %v, want only the second leaf", seq)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit(): %v", err)
+	}
+
+	counts, err := (&readOnlyLogTX{ls: ls}).GetUnsequencedCounts(ctx)
+	if err != nil {
+		t.Fatalf("GetUnsequencedCounts(): %v", err)
+	}
+	if got := counts[treeID]; got != 0 {
+		t.Errorf("%d queue entries left, want 0", got)
+	}
+}
+
+func TestQueueDuplicatesInBatch(t *testing.T) {
+	ctx := context.Background()
+	ls, _, treeID := newTestStorage(t)
+
+	// The duplicate shares the original's queue entry, which must survive.
+	leaf := testLeaves(1, 0)[0]
+	queued := mustQueue(ctx, t, ls, treeID, []*trillian.LogLeaf{leaf, leaf})
+	if got, want := status.FromProto(queued[1].Status).Code(), codes.AlreadyExists; got != want {
+		t.Errorf("QueueLeaves(duplicate) returned status %v, want %v", got, want)
+	}
+
+	tx := mustBegin(ctx, t, ls, treeID)
+	defer tx.Close()
+	seq := sequence(ctx, t, tx, 10)
+	if len(seq) != 1 || !bytes.Equal(seq[0].LeafIdentityHash, leaf.LeafIdentityHash) {
+		t.Errorf("sequenced %v, want the queued leaf", seq)
+	}
+}
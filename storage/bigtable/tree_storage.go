@@ -0,0 +1,631 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bigtable provides a Cloud Bigtable-based storage layer
+// implementation, aimed at logs with sustained high write rates.
+//
+// Bigtable has no multi-row transactions, so this implementation relies on
+// the following invariants instead:
+//   - all data written by a sequencing transaction is keyed by (or versioned
+//     at) the transaction's write revision;
+//   - before writing any of it, a transaction claims its write revision with
+//     a conditional mutation on the revision's tree head row, which fails if
+//     another transaction has claimed it. The claim records the cells the
+//     transaction is about to write, so that a later transaction can remove
+//     them if the claimant dies before committing (see claimLease);
+//   - the tree head for a revision is written last, with a conditional
+//     mutation that fails if the transaction no longer holds the claim. This
+//     is the commit point of a transaction;
+//   - readers only look at data at or below the revision (and tree size) of
+//     the latest tree head, so partially written transactions are invisible.
+//
+// Subtrees are stored with one row per (treeID, subtreeID), and one cell per
+// revision, using the revision as the cell timestamp. Superseded revisions
+// are removed by the garbage collection policy set up by CreateTables.
+package bigtable
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/bigtable"
+	"github.com/golang/glog"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/cache"
+	"github.com/google/trillian/storage/storagepb"
+)
+
+// Table and column family names.
+const (
+	treesTbl       = "Trees"
+	treeHeadsTbl   = "TreeHeads"
+	subtreesTbl    = "Subtrees"
+	leavesTbl      = "Leaves"
+	unsequencedTbl = "Unsequenced"
+
+	treeFamily     = "t"
+	headFamily     = "h"
+	subtreeFamily  = "s"
+	leafFamily     = "l"
+	sequenceFamily = "q"
+	queueFamily    = "u"
+
+	treeCol      = "tree"
+	headCol      = "root"
+	claimCol     = "claim"
+	intentCol    = "intent"
+	subtreeCol   = "nodes"
+	valueCol     = "value"
+	extraCol     = "extra"
+	queueTSCol   = "queued"
+	identityCol  = "identity"
+	merkleCol    = "merkle"
+	integrateCol = "integrated"
+	leafCol      = "leaf"
+	indexCol     = "index"
+)
+
+const (
+	// commitTimeout bounds the writes made by a committing transaction.
+	commitTimeout = 30 * time.Second
+	// claimLease is how long a transaction's claim on its write revision
+	// stays valid without a tree head. Once it has passed, the claimant can
+	// no longer be writing (it gives up after commitTimeout), so the
+	// revision can be claimed by another transaction, which first removes
+	// whatever the claimant wrote. It's compared against the Bigtable
+	// server's timestamp of the claim, so it includes a margin for the skew
+	// between the local and server clocks.
+	claimLease = 2 * commitTimeout
+)
+
+var (
+	// ErrTransactionClosed is returned by interface methods when an operation is
+	// attempted on a transaction whose Commit or Rollback methods have
+	// previously been called.
+	ErrTransactionClosed = errors.New("transaction is closed")
+
+	// ErrWrongTXType is returned when a write operation is attempted with a
+	// read-only transaction.
+	ErrWrongTXType = errors.New("mutating method called on read-only transaction")
+
+	// ErrRevisionConflict is returned by Commit when another writer has
+	// already claimed, or stored a tree head at, the transaction's write
+	// revision.
+	ErrRevisionConflict = errors.New("write revision already claimed")
+)
+
+// TableOptions controls the garbage collection policies set up by
+// CreateTables.
+type TableOptions struct {
+	// SubtreeRevisions is the number of revisions of each subtree retained.
+	// Older revisions are garbage collected, and so can no longer be used to
+	// serve proofs for old tree heads. Zero means all revisions are kept.
+	SubtreeRevisions int
+	// SubtreeMaxAge, if non-zero, additionally allows subtree revisions
+	// older than this to be collected, as long as SubtreeRevisions are kept.
+	SubtreeMaxAge time.Duration
+}
+
+// CreateTables creates the tables and column families used by this storage
+// implementation, and sets their garbage collection policies. Tables that
+// already exist are left untouched, apart from their policies.
+func CreateTables(ctx context.Context, ac *bigtable.AdminClient, opts TableOptions) error {
+	existing, err := ac.Tables(ctx)
+	if err != nil {
+		return err
+	}
+	have := make(map[string]bool)
+	for _, t := range existing {
+		have[t] = true
+	}
+
+	families := map[string][]string{
+		treesTbl:       {treeFamily},
+		treeHeadsTbl:   {headFamily},
+		subtreesTbl:    {subtreeFamily},
+		leavesTbl:      {leafFamily, sequenceFamily},
+		unsequencedTbl: {queueFamily},
+	}
+	for tbl, fams := range families {
+		if have[tbl] {
+			continue
+		}
+		if err := ac.CreateTable(ctx, tbl); err != nil {
+			return fmt.Errorf("failed to create table %s: %v", tbl, err)
+		}
+		for _, f := range fams {
+			if err := ac.CreateColumnFamily(ctx, tbl, f); err != nil {
+				return fmt.Errorf("failed to create column family %s:%s: %v", tbl, f, err)
+			}
+		}
+	}
+
+	// Only the newest version of everything but subtrees is ever read.
+	latestOnly := bigtable.MaxVersionsPolicy(1)
+	policies := []struct {
+		tbl, family string
+		policy      bigtable.GCPolicy
+	}{
+		{treesTbl, treeFamily, latestOnly},
+		{treeHeadsTbl, headFamily, latestOnly},
+		{leavesTbl, leafFamily, latestOnly},
+		{leavesTbl, sequenceFamily, latestOnly},
+		{unsequencedTbl, queueFamily, latestOnly},
+		{subtreesTbl, subtreeFamily, subtreePolicy(opts)},
+	}
+	for _, p := range policies {
+		if err := ac.SetGCPolicy(ctx, p.tbl, p.family, p.policy); err != nil {
+			return fmt.Errorf("failed to set GC policy on %s:%s: %v", p.tbl, p.family, err)
+		}
+	}
+	return nil
+}
+
+func subtreePolicy(opts TableOptions) bigtable.GCPolicy {
+	if opts.SubtreeRevisions <= 0 {
+		return bigtable.NoGcPolicy()
+	}
+	versions := bigtable.MaxVersionsPolicy(opts.SubtreeRevisions)
+	if opts.SubtreeMaxAge <= 0 {
+		return versions
+	}
+	return bigtable.IntersectionPolicy(versions, bigtable.MaxAgePolicy(opts.SubtreeMaxAge))
+}
+
+// treePrefix returns the prefix of all row keys belonging to treeID.
+func treePrefix(treeID int64) string {
+	return fmt.Sprintf("%016x/", uint64(treeID))
+}
+
+// revTimestamp returns the cell timestamp used to store data at rev. Bigtable
+// tables default to millisecond granularity, so timestamps (which are in
+// microseconds) must be multiples of 1000.
+func revTimestamp(rev int64) bigtable.Timestamp {
+	return bigtable.Timestamp(rev * 1000)
+}
+
+func int64ToBytes(v int64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(v))
+	return b
+}
+
+func bytesToInt64(b []byte) (int64, error) {
+	if len(b) != 8 {
+		return 0, fmt.Errorf("got %d bytes, want 8", len(b))
+	}
+	return int64(binary.BigEndian.Uint64(b)), nil
+}
+
+// cell returns the value of the newest cell in family:col of row, or nil.
+func cell(row bigtable.Row, family, col string) []byte {
+	for _, item := range row[family] {
+		if item.Column == family+":"+col {
+			return item.Value
+		}
+	}
+	return nil
+}
+
+// applyBulk applies muts to the rows in keys, returning the first error.
+func applyBulk(ctx context.Context, tbl *bigtable.Table, keys []string, muts []*bigtable.Mutation) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	errs, err := tbl.ApplyBulk(ctx, keys, muts)
+	if err != nil {
+		return err
+	}
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("failed to write row %q: %v", keys[i], err)
+		}
+	}
+	return nil
+}
+
+// revCells identifies the cells of a row written at a transaction's write
+// revision.
+type revCells struct {
+	Table   string   `json:"t"`
+	Row     string   `json:"r"`
+	Family  string   `json:"f"`
+	Columns []string `json:"c"`
+}
+
+// revWrites buffers the writes made by a transaction at its write revision,
+// which are only applied once the transaction has claimed it.
+type revWrites struct {
+	keys  map[string][]string
+	muts  map[string][]*bigtable.Mutation
+	cells []revCells
+}
+
+// set buffers a write of vals, by column, to family in row of table.
+func (w *revWrites) set(table, row, family string, ts bigtable.Timestamp, vals map[string][]byte) {
+	if w.keys == nil {
+		w.keys = make(map[string][]string)
+		w.muts = make(map[string][]*bigtable.Mutation)
+	}
+	m := bigtable.NewMutation()
+	cols := make([]string, 0, len(vals))
+	for col, val := range vals {
+		m.Set(family, col, ts, val)
+		cols = append(cols, col)
+	}
+	sort.Strings(cols)
+	w.keys[table] = append(w.keys[table], row)
+	w.muts[table] = append(w.muts[table], m)
+	w.cells = append(w.cells, revCells{Table: table, Row: row, Family: family, Columns: cols})
+}
+
+// tables returns the names of the tables written to, in a stable order.
+func (w *revWrites) tables() []string {
+	tbls := make([]string, 0, len(w.keys))
+	for tbl := range w.keys {
+		tbls = append(tbls, tbl)
+	}
+	sort.Strings(tbls)
+	return tbls
+}
+
+// treeStorage provides a shared base for the Bigtable-backed storage
+// implementations.
+type treeStorage struct {
+	client *bigtable.Client
+	admin  storage.AdminStorage
+
+	subtrees *bigtable.Table
+	heads    *bigtable.Table
+}
+
+func newTreeStorage(client *bigtable.Client) *treeStorage {
+	return &treeStorage{
+		client:   client,
+		subtrees: client.Open(subtreesTbl),
+		heads:    client.Open(treeHeadsTbl),
+	}
+}
+
+// treeTX is the part of a tree transaction common to logs and maps.
+type treeTX struct {
+	ts     *treeStorage
+	treeID int64
+	cache  cache.SubtreeCache
+
+	// mu guards closed, and serializes commit/rollback with other operations.
+	mu       sync.RWMutex
+	closed   bool
+	readonly bool
+
+	readRev  int64
+	writeRev int64
+
+	// writes holds the writes at writeRev applied on Commit.
+	writes revWrites
+	// claim identifies this transaction's claim on writeRev, once made.
+	claim string
+}
+
+func subtreeRowKey(treeID int64, id storage.NodeID) (string, error) {
+	if id.PrefixLenBits%8 != 0 {
+		return "", fmt.Errorf("id.PrefixLenBits (%d) is not a multiple of 8; it cannot be a subtree prefix", id.PrefixLenBits)
+	}
+	return treePrefix(treeID) + hex.EncodeToString(id.Path[:id.PrefixLenBits/8]), nil
+}
+
+// getSubtree retrieves the most recent subtree specified by id at (or below)
+// the requested revision. If no such subtree exists it returns nil.
+func (t *treeTX) getSubtree(ctx context.Context, rev int64, id storage.NodeID) (*storagepb.SubtreeProto, error) {
+	key, err := subtreeRowKey(t.treeID, id)
+	if err != nil {
+		return nil, err
+	}
+	row, err := t.ts.subtrees.ReadRow(ctx, key, bigtable.RowFilter(bigtable.ChainFilters(
+		bigtable.TimestampRangeFilterMicros(0, revTimestamp(rev+1)),
+		bigtable.LatestNFilter(1),
+	)))
+	if err != nil {
+		return nil, err
+	}
+	b := cell(row, subtreeFamily, subtreeCol)
+	if b == nil {
+		return nil, nil
+	}
+	var st storagepb.SubtreeProto
+	if err := proto.Unmarshal(b, &st); err != nil {
+		return nil, err
+	}
+	if st.Prefix == nil {
+		st.Prefix = []byte{}
+	}
+	return &st, nil
+}
+
+// bufferSubtrees adds the subtrees to the writes applied on Commit.
+func (t *treeTX) bufferSubtrees(sts []*storagepb.SubtreeProto) error {
+	for _, st := range sts {
+		if st == nil {
+			continue
+		}
+		b, err := proto.Marshal(st)
+		if err != nil {
+			return err
+		}
+		key := treePrefix(t.treeID) + hex.EncodeToString(st.Prefix)
+		t.writes.set(subtreesTbl, key, subtreeFamily, revTimestamp(t.writeRev), map[string][]byte{subtreeCol: b})
+	}
+	return nil
+}
+
+// GetMerkleNodes returns the requested set of nodes at, or before, the
+// specified tree revision.
+func (t *treeTX) GetMerkleNodes(ctx context.Context, rev int64, ids []storage.NodeID) ([]storage.Node, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.closed {
+		return nil, ErrTransactionClosed
+	}
+
+	return t.cache.GetNodes(ids, t.getSubtreesAtRev(ctx, rev))
+}
+
+// getSubtreesAtRev returns a function reading subtrees at, or before, the
+// specified tree revision, in parallel.
+func (t *treeTX) getSubtreesAtRev(ctx context.Context, rev int64) cache.GetSubtreesFunc {
+	return func(ids []storage.NodeID) ([]*storagepb.SubtreeProto, error) {
+		type result struct {
+			st  *storagepb.SubtreeProto
+			err error
+		}
+		c := make(chan result, len(ids))
+		for _, id := range ids {
+			id := id
+			go func() {
+				st, err := t.getSubtree(ctx, rev, id)
+				c <- result{st, err}
+			}()
+		}
+		ret := make([]*storagepb.SubtreeProto, 0, len(ids))
+		for range ids {
+			r := <-c
+			if r.err != nil {
+				return nil, r.err
+			}
+			if r.st != nil {
+				ret = append(ret, r.st)
+			}
+		}
+		return ret, nil
+	}
+}
+
+// SetMerkleNodes stores the provided merkle nodes at the writeRevision of the
+// transaction.
+func (t *treeTX) SetMerkleNodes(ctx context.Context, nodes []storage.Node) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.closed {
+		return ErrTransactionClosed
+	}
+	if t.readonly {
+		return ErrWrongTXType
+	}
+
+	for _, n := range nodes {
+		err := t.cache.SetNodeHash(n.NodeID, n.Hash,
+			func(id storage.NodeID) (*storagepb.SubtreeProto, error) {
+				return t.getSubtree(ctx, t.writeRev-1, id)
+			})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadRevision returns the tree revision of the latest tree head at the time
+// the transaction was started.
+func (t *treeTX) ReadRevision() int64 {
+	return t.readRev
+}
+
+// WriteRevision returns the tree revision at which any tree-modifying
+// operations will write.
+func (t *treeTX) WriteRevision() int64 {
+	return t.writeRev
+}
+
+// IsOpen returns true iff neither Commit nor Rollback have been called.
+func (t *treeTX) IsOpen() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return !t.closed
+}
+
+// commitWrites claims the transaction's write revision, applies its buffered
+// writes, and then stores head, which makes them visible. If head is nil
+// there's nothing to commit, as nothing written at the revision would ever
+// be read.
+func (t *treeTX) commitWrites(ctx context.Context, head []byte) error {
+	if head == nil {
+		return nil
+	}
+	if err := t.cache.Flush(t.bufferSubtrees); err != nil {
+		return err
+	}
+	if err := t.claimRevision(ctx); err != nil {
+		return err
+	}
+	for _, tbl := range t.writes.tables() {
+		if err := applyBulk(ctx, t.ts.client.Open(tbl), t.writes.keys[tbl], t.writes.muts[tbl]); err != nil {
+			return err
+		}
+	}
+	return t.storeHead(ctx, head)
+}
+
+// claimRevision claims the transaction's write revision, recording the cells
+// it's about to write in the claim. It fails with ErrRevisionConflict if the
+// revision has a tree head, or a claim which is still within its lease. An
+// expired claim is taken over, and the cells its transaction wrote are
+// removed.
+func (t *treeTX) claimRevision(ctx context.Context) error {
+	intent, err := json.Marshal(t.writes.cells)
+	if err != nil {
+		return err
+	}
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return err
+	}
+	t.claim = hex.EncodeToString(token)
+	claim := bigtable.NewMutation()
+	claim.Set(headFamily, claimCol, bigtable.ServerTime, []byte(t.claim))
+	claim.Set(headFamily, intentCol, bigtable.ServerTime, intent)
+
+	key := headRowKey(t.treeID, t.writeRev)
+	var exists bool
+	m := bigtable.NewCondMutation(bigtable.PassAllFilter(), nil, claim)
+	if err := t.ts.heads.Apply(ctx, key, m, bigtable.GetCondMutationResult(&exists)); err != nil {
+		return err
+	}
+	if !exists {
+		return nil
+	}
+
+	row, err := t.ts.heads.ReadRow(ctx, key, bigtable.RowFilter(bigtable.LatestNFilter(1)))
+	if err != nil {
+		return err
+	}
+	if cell(row, headFamily, headCol) != nil {
+		return ErrRevisionConflict
+	}
+	var stale string
+	var claimed time.Time
+	for _, item := range row[headFamily] {
+		if item.Column == headFamily+":"+claimCol {
+			stale, claimed = string(item.Value), item.Timestamp.Time()
+		}
+	}
+	if stale == "" || time.Since(claimed) < claimLease {
+		return ErrRevisionConflict
+	}
+	var staleCells []revCells
+	if err := json.Unmarshal(cell(row, headFamily, intentCol), &staleCells); err != nil {
+		return fmt.Errorf("malformed claim on revision %d: %v", t.writeRev, err)
+	}
+
+	// Swap the claims atomically, so that only one transaction takes over.
+	var swapped bool
+	m = bigtable.NewCondMutation(claimFilter(stale), claim, nil)
+	if err := t.ts.heads.Apply(ctx, key, m, bigtable.GetCondMutationResult(&swapped)); err != nil {
+		return err
+	}
+	if !swapped {
+		return ErrRevisionConflict
+	}
+	glog.Warningf("%v: took over expired claim on revision %d made at %v", t.treeID, t.writeRev, claimed)
+	return t.removeCells(ctx, staleCells)
+}
+
+// removeCells deletes the given cells written at the transaction's write
+// revision.
+func (t *treeTX) removeCells(ctx context.Context, cells []revCells) error {
+	from, to := revTimestamp(t.writeRev), revTimestamp(t.writeRev+1)
+	keys := make(map[string][]string)
+	muts := make(map[string][]*bigtable.Mutation)
+	for _, c := range cells {
+		m := bigtable.NewMutation()
+		for _, col := range c.Columns {
+			m.DeleteTimestampRange(c.Family, col, from, to)
+		}
+		keys[c.Table] = append(keys[c.Table], c.Row)
+		muts[c.Table] = append(muts[c.Table], m)
+	}
+	for tbl := range keys {
+		if err := applyBulk(ctx, t.ts.client.Open(tbl), keys[tbl], muts[tbl]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// claimFilter returns a filter matching rows holding the given claim.
+func claimFilter(claim string) bigtable.Filter {
+	return bigtable.ChainFilters(
+		bigtable.ColumnFilter(claimCol),
+		bigtable.LatestNFilter(1),
+		bigtable.ValueFilter(regexp.QuoteMeta(claim)),
+	)
+}
+
+// storeHead writes the serialized tree head for the transaction's write
+// revision, failing with ErrRevisionConflict if the transaction's claim on
+// the revision has been taken over.
+func (t *treeTX) storeHead(ctx context.Context, head []byte) error {
+	set := bigtable.NewMutation()
+	set.Set(headFamily, headCol, bigtable.ServerTime, head)
+	var claimed bool
+	m := bigtable.NewCondMutation(claimFilter(t.claim), set, nil)
+	if err := t.ts.heads.Apply(ctx, headRowKey(t.treeID, t.writeRev), m, bigtable.GetCondMutationResult(&claimed)); err != nil {
+		return err
+	}
+	if !claimed {
+		return ErrRevisionConflict
+	}
+	return nil
+}
+
+// headRowKey returns the row key of the tree head at rev. Revisions are
+// stored inverted, so that a prefix scan returns the newest head first.
+func headRowKey(treeID int64, rev int64) string {
+	return treePrefix(treeID) + fmt.Sprintf("%016x", uint64(math.MaxInt64-rev))
+}
+
+// latestHead reads the serialized newest tree head of treeID, or returns
+// storage.ErrTreeNeedsInit if there is none.
+func (t *treeStorage) latestHead(ctx context.Context, treeID int64) ([]byte, error) {
+	var head []byte
+	// Rows of revisions which are claimed but have no head yet are skipped.
+	err := t.heads.ReadRows(ctx, bigtable.PrefixRange(treePrefix(treeID)), func(row bigtable.Row) bool {
+		head = cell(row, headFamily, headCol)
+		return false
+	}, bigtable.RowFilter(bigtable.ChainFilters(bigtable.ColumnFilter(headCol), bigtable.LatestNFilter(1))), bigtable.LimitRows(1))
+	if err != nil {
+		return nil, err
+	}
+	if head == nil {
+		glog.Warningf("no head found for treeID %v", treeID)
+		return nil, storage.ErrTreeNeedsInit
+	}
+	return head, nil
+}
+
+func checkDatabaseAccessible(ctx context.Context, tbl *bigtable.Table) error {
+	// Being able to read *something* is enough.
+	return tbl.ReadRows(ctx, bigtable.InfiniteRange(""), func(bigtable.Row) bool { return false },
+		bigtable.LimitRows(1), bigtable.RowFilter(bigtable.StripValueFilter()))
+}
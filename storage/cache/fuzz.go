@@ -0,0 +1,60 @@
+// +build gofuzz
+
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian/merkle/maphasher"
+	"github.com/google/trillian/merkle/rfc6962"
+	"github.com/google/trillian/storage/storagepb"
+)
+
+// FuzzLogSubtree is a go-fuzz entry point for the population of log subtrees
+// read from storage. data is a serialized SubtreeProto.
+//
+// Build with go-fuzz-build -tags gofuzz -func FuzzLogSubtree.
+func FuzzLogSubtree(data []byte) int {
+	var st storagepb.SubtreeProto
+	if err := proto.Unmarshal(data, &st); err != nil {
+		return 0
+	}
+	if err := populateLogSubtreeNodes(rfc6962.DefaultHasher)(&st); err != nil {
+		return 0
+	}
+	if err := prepareLogSubtreeWrite()(&st); err != nil {
+		return 0
+	}
+	return 1
+}
+
+// FuzzMapSubtree is a go-fuzz entry point for the population of map subtrees
+// read from storage. data is a serialized SubtreeProto.
+//
+// Build with go-fuzz-build -tags gofuzz -func FuzzMapSubtree.
+func FuzzMapSubtree(data []byte) int {
+	var st storagepb.SubtreeProto
+	if err := proto.Unmarshal(data, &st); err != nil {
+		return 0
+	}
+	if err := populateMapSubtreeNodes(1, maphasher.Default)(&st); err != nil {
+		return 0
+	}
+	if err := prepareMapSubtreeWrite()(&st); err != nil {
+		return 0
+	}
+	return 1
+}
@@ -25,8 +25,33 @@ import (
 
 // NewLogSubtreeCache creates and returns a SubtreeCache appropriate for use with a log
 // tree. The caller must supply the strata depths to be used and a suitable LogHasher.
+// Log nodes are located in their subtrees by their coordinates if the strata
+// holding them are storage.LogStratumDepth deep, and by splitting their IDs
+// otherwise.
 func NewLogSubtreeCache(logStrata []int, hasher hashers.LogHasher) SubtreeCache {
-	return NewSubtreeCache(logStrata, populateLogSubtreeNodes(hasher), prepareLogSubtreeWrite())
+	c := NewSubtreeCache(logStrata, populateLogSubtreeNodes(hasher), prepareLogSubtreeWrite())
+	c.isLog = true
+	c.logNodes = true
+	for i := 0; i < storage.LogStrata; i++ {
+		if i >= len(logStrata) || logStrata[i] != storage.LogStratumDepth {
+			c.logNodes = false
+		}
+	}
+	return c
+}
+
+// locateLogNode returns the prefix of the subtree holding the log node id,
+// and the suffix of id within it.
+func locateLogNode(id storage.NodeID) ([]byte, storage.Suffix, error) {
+	c, err := storage.CoordsForNodeID(id)
+	if err != nil {
+		return nil, storage.Suffix{}, err
+	}
+	st, n, err := storage.SubtreeFor(c)
+	if err != nil {
+		return nil, storage.Suffix{}, err
+	}
+	return st.Prefix(), n.Suffix(), nil
 }
 
 // LogPopulateFunc obtains a log storage population function based on a supplied LogHasher.
@@ -35,6 +60,28 @@ func LogPopulateFunc(hasher hashers.LogHasher) storage.PopulateSubtreeFunc {
 	return populateLogSubtreeNodes(hasher)
 }
 
+// BuildLogSubtrees returns the subtrees holding nodes, built from nodes alone
+// rather than on top of what's stored, and prepared for writing. Each subtree
+// must be given all of the nodes it holds. This is intended for storage
+// implementations rewriting subtrees which are missing or corrupt.
+func BuildLogSubtrees(logStrata []int, hasher hashers.LogHasher, nodes []storage.Node) ([]*storagepb.SubtreeProto, error) {
+	c := NewLogSubtreeCache(logStrata, hasher)
+	empty := func(storage.NodeID) (*storagepb.SubtreeProto, error) {
+		return nil, nil
+	}
+	for _, n := range nodes {
+		if err := c.SetNodeHash(n.NodeID, n.Hash, empty); err != nil {
+			return nil, err
+		}
+	}
+	var subtrees []*storagepb.SubtreeProto
+	err := c.Flush(func(s []*storagepb.SubtreeProto) error {
+		subtrees = s
+		return nil
+	})
+	return subtrees, err
+}
+
 // populateLogSubtreeNodes re-creates a Log subtree's InternalNodes from the
 // subtree Leaves map.
 //
@@ -61,23 +108,19 @@ func populateLogSubtreeNodes(hasher hashers.LogHasher) storage.PopulateSubtreeFu
 
 		// We need to update the subtree root hash regardless of whether it's fully populated
 		for leafIndex := int64(0); leafIndex < int64(len(st.Leaves)); leafIndex++ {
-			nodeID := storage.NewNodeIDFromPrefix(st.Prefix, logStrataDepth, leafIndex, logStrataDepth, maxLogDepth)
-			_, sfx := nodeID.Split(len(st.Prefix), int(st.Depth))
+			sfx := storage.SubtreeNodeCoords{Level: 0, Index: leafIndex}.SuffixAtDepth(int(st.Depth))
 			sfxKey := sfx.String()
 			h := st.Leaves[sfxKey]
 			if h == nil {
 				return fmt.Errorf("unexpectedly got nil for subtree leaf suffix %s", sfx)
 			}
 			seq, err := cmt.AddLeafHash(h, func(height int, index int64, h []byte) error {
-				if height == logStrataDepth && index == 0 {
+				if height == int(st.Depth) && index == 0 {
 					// no space for the root in the node cache
 					return nil
 				}
 
-				subDepth := logStrataDepth - height
-				nodeID := storage.NewNodeIDFromPrefix(st.Prefix, subDepth, index, logStrataDepth, maxLogDepth)
-				_, sfx := nodeID.Split(len(st.Prefix), int(st.Depth))
-				sfxKey := sfx.String()
+				sfxKey := storage.SubtreeNodeCoords{Level: height, Index: index}.SuffixAtDepth(int(st.Depth)).String()
 				// Don't put leaves into the internal map and only update if we're rebuilding internal
 				// nodes. If the subtree was saved with internal nodes then we don't touch the map.
 				if height > 0 && len(st.Leaves) == maxLeaves {
@@ -16,8 +16,11 @@ package cache
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/binary"
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/golang/glog"
@@ -53,15 +56,14 @@ const (
 	// depthQuantum defines the smallest supported subtree depth and all subtrees must be
 	// a multiple of this value in depth.
 	depthQuantum = 8
-	// logStrataDepth is the strata that must be used for all log subtrees.
+	// logStrataDepth is the depth of the default strata of log subtrees.
 	logStrataDepth = 8
 	// maxLogDepth is the number of bits in a log path.
 	maxLogDepth = 64
 )
 
 // SubtreeCache provides a caching access to Subtree storage. Currently there are assumptions
-// in the code that all subtrees are multiple of 8 in depth and that log subtrees are always
-// of depth 8. It is not possible to just change the constants above and have things still
+// in the code that all subtrees are multiple of 8 in depth. It is not possible to just change the constants above and have things still
 // work. This is because of issues like byte packing of node IDs.
 type SubtreeCache struct {
 	// prefixLengths contains the strata prefix sizes for each multiple-of-depthQuantum tree
@@ -73,19 +75,27 @@ type SubtreeCache struct {
 	// dirtyPrefixes keeps track of all Subtrees which need to be written back
 	// to storage.
 	dirtyPrefixes map[string]bool
+	// storedHashes holds the content hash of each Subtree as last read from,
+	// or written to, storage, so that Flush can skip unchanged Subtrees.
+	storedHashes map[string][]byte
 	// mutex guards access to the maps above.
 	mutex *sync.RWMutex
 	// populate is used to rebuild internal nodes when subtrees are loaded from storage.
 	populate storage.PopulateSubtreeFunc
 	// prepare is used for preparation work when subtrees are about to be written to storage.
 	prepare storage.PrepareSubtreeWriteFunc
+	// isLog is set for caches of log trees.
+	isLog bool
+	// logNodes is set for caches of log trees with the default strata, whose
+	// nodes are located in their subtrees by their coordinates rather than by
+	// splitting their IDs.
+	logNodes bool
 }
 
 // NewSubtreeCache returns a newly intialised cache ready for use.
 // populateSubtree is a function which knows how to populate a subtree's
 // internal nodes given its leaves, and will be called for each subtree loaded
 // from storage.
-// TODO(al): consider supporting different sized subtrees - for now everything's subtrees of 8 levels.
 func NewSubtreeCache(strataDepths []int, populateSubtree storage.PopulateSubtreeFunc, prepareSubtreeWrite storage.PrepareSubtreeWriteFunc) SubtreeCache {
 	// TODO(al): pass this in
 	maxTreeDepth := maxSupportedTreeDepth
@@ -117,6 +127,7 @@ func NewSubtreeCache(strataDepths []int, populateSubtree storage.PopulateSubtree
 		stratumInfo:   sInfo,
 		subtrees:      make(map[string]*storagepb.SubtreeProto),
 		dirtyPrefixes: make(map[string]bool),
+		storedHashes:  make(map[string][]byte),
 		mutex:         new(sync.RWMutex),
 		populate:      populateSubtree,
 		prepare:       prepareSubtreeWrite,
@@ -134,6 +145,17 @@ func (s *SubtreeCache) splitNodeID(id storage.NodeID) ([]byte, storage.Suffix) {
 	return id.Split(sInfo.prefixBytes, sInfo.depth)
 }
 
+// locate returns the prefix of the subtree holding id, and the suffix of id
+// within it. NodeIDs which aren't of log nodes are split by their bits, as they
+// always have been, even in caches of log trees.
+func (s *SubtreeCache) locate(id storage.NodeID) ([]byte, storage.Suffix, error) {
+	if s.logNodes && id.PathLenBits() == maxLogDepth {
+		return locateLogNode(id)
+	}
+	px, sx := s.splitNodeID(id)
+	return px, sx, nil
+}
+
 // preload calculates the set of subtrees required to know the hashes of the
 // passed in node IDs, uses getSubtrees to retrieve them, and finally populates
 // the cache structures with the data.
@@ -145,7 +167,10 @@ func (s *SubtreeCache) preload(ids []storage.NodeID, getSubtrees GetSubtreesFunc
 	want := make(map[string]*storage.NodeID)
 	for _, id := range ids {
 		id := id
-		px, _ := s.splitNodeID(id)
+		px, _, err := s.locate(id)
+		if err != nil {
+			return err
+		}
 		pxKey := string(px)
 		_, ok := s.subtrees[pxKey]
 		// TODO(al): fix for non-uniform strata
@@ -154,7 +179,13 @@ func (s *SubtreeCache) preload(ids []storage.NodeID, getSubtrees GetSubtreesFunc
 			want[pxKey] = &id
 		}
 	}
+	return s.loadSubtrees(want, getSubtrees)
+}
 
+// loadSubtrees reads the subtrees of want, keyed by prefix, with getSubtrees
+// and adds them to the cache, creating empty ones for those not in storage.
+// It must be called with s.mutex locked.
+func (s *SubtreeCache) loadSubtrees(want map[string]*storage.NodeID, getSubtrees GetSubtreesFunc) error {
 	// There might be nothing to do so don't make a read request for zero subtrees if so
 	if len(want) == 0 {
 		return nil
@@ -169,6 +200,7 @@ func (s *SubtreeCache) preload(ids []storage.NodeID, getSubtrees GetSubtreesFunc
 		return err
 	}
 	for _, t := range subtrees {
+		s.storedHashes[string(t.Prefix)] = subtreeContentHash(t)
 		s.populate(t)
 		s.subtrees[string(t.Prefix)] = t
 		delete(want, string(t.Prefix))
@@ -235,6 +267,76 @@ func (s *SubtreeCache) GetNodes(ids []storage.NodeID, getSubtrees GetSubtreesFun
 	return ret, nil
 }
 
+// GetNodesByCoords returns the log nodes at coords which are set, calling the
+// getSubtrees function for their subtrees which are not already cached. It
+// must only be used with caches created by NewLogSubtreeCache.
+func (s *SubtreeCache) GetNodesByCoords(coords []storage.NodeCoords, getSubtrees GetSubtreesFunc) ([]storage.CoordNode, error) {
+	if !s.isLog {
+		return nil, fmt.Errorf("cache doesn't hold log nodes")
+	}
+	if !s.logNodes {
+		return s.getNodesByCoordsFromIDs(coords, getSubtrees)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	type location struct {
+		px string
+		sx storage.Suffix
+	}
+	locs := make([]location, 0, len(coords))
+	want := make(map[string]*storage.NodeID)
+	for _, c := range coords {
+		st, n, err := storage.SubtreeFor(c)
+		if err != nil {
+			return nil, err
+		}
+		px := string(st.Prefix())
+		if _, ok := s.subtrees[px]; !ok {
+			id := st.ID()
+			want[px] = &id
+		}
+		locs = append(locs, location{px: px, sx: n.Suffix()})
+	}
+	if err := s.loadSubtrees(want, getSubtrees); err != nil {
+		return nil, err
+	}
+
+	ret := make([]storage.CoordNode, 0, len(coords))
+	for i, loc := range locs {
+		if h := nodeHash(s.subtrees[loc.px], loc.sx); h != nil {
+			ret = append(ret, storage.CoordNode{Coords: coords[i], Hash: h})
+		}
+	}
+	return ret, nil
+}
+
+// getNodesByCoordsFromIDs implements GetNodesByCoords for log trees with
+// non-default strata, whose nodes are located by splitting their IDs.
+func (s *SubtreeCache) getNodesByCoordsFromIDs(coords []storage.NodeCoords, getSubtrees GetSubtreesFunc) ([]storage.CoordNode, error) {
+	ids := make([]storage.NodeID, 0, len(coords))
+	for _, c := range coords {
+		id, err := c.ID()
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	nodes, err := s.GetNodes(ids, getSubtrees)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]storage.CoordNode, 0, len(nodes))
+	for _, n := range nodes {
+		cn, err := storage.CoordNodeFor(n)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, cn)
+	}
+	return ret, nil
+}
+
 // GetNodeHash returns a single node hash from the cache.
 func (s *SubtreeCache) GetNodeHash(id storage.NodeID, getSubtree GetSubtreeFunc) ([]byte, error) {
 	s.mutex.RLock()
@@ -244,7 +346,10 @@ func (s *SubtreeCache) GetNodeHash(id storage.NodeID, getSubtree GetSubtreeFunc)
 
 // getNodeHashUnderLock must be called with s.mutex locked.
 func (s *SubtreeCache) getNodeHashUnderLock(id storage.NodeID, getSubtree GetSubtreeFunc) ([]byte, error) {
-	px, sx := s.splitNodeID(id)
+	px, sx, err := s.locate(id)
+	if err != nil {
+		return nil, err
+	}
 	prefixKey := string(px)
 	c := s.subtrees[prefixKey]
 	if c == nil {
@@ -252,7 +357,6 @@ func (s *SubtreeCache) getNodeHashUnderLock(id storage.NodeID, getSubtree GetSub
 		// Cache miss, so we'll try to fetch from storage.
 		subID := id
 		subID.PrefixLenBits = len(px) * depthQuantum // this won't work if depthQuantum changes
-		var err error
 		c, err = getSubtree(subID)
 		if err != nil {
 			return nil, err
@@ -260,6 +364,7 @@ func (s *SubtreeCache) getNodeHashUnderLock(id storage.NodeID, getSubtree GetSub
 		if c == nil {
 			c = s.newEmptySubtree(subID, px)
 		} else {
+			s.storedHashes[prefixKey] = subtreeContentHash(c)
 			if err := s.populate(c); err != nil {
 				return nil, err
 			}
@@ -273,21 +378,9 @@ func (s *SubtreeCache) getNodeHashUnderLock(id storage.NodeID, getSubtree GetSub
 
 	// finally look for the particular node within the subtree so we can return
 	// the hash & revision.
-	var nh []byte
-
-	// Look up the hash in the appropriate map.
-	// The leaf hashes are stored in a separate map to the internal nodes so that
-	// we can easily dump (and later reconstruct) the internal nodes. As log subtrees
-	// have a fixed depth if the suffix has the same number of significant bits as the
-	// subtree depth then this is a leaf. For example if the subtree is depth 8 its leaves
-	// have 8 significant suffix bits.
-	sfxKey := sx.String()
-	if int32(sx.Bits) == c.Depth {
-		nh = c.Leaves[sfxKey]
-	} else {
-		nh = c.InternalNodes[sfxKey]
-	}
+	nh := nodeHash(c, sx)
 	if glog.V(4) {
+		sfxKey := sx.String()
 		b, err := base64.StdEncoding.DecodeString(sfxKey)
 		if err != nil {
 			glog.Errorf("base64.DecodeString(%v): %v", sfxKey, err)
@@ -300,11 +393,30 @@ func (s *SubtreeCache) getNodeHashUnderLock(id storage.NodeID, getSubtree GetSub
 	return nh, nil
 }
 
+// nodeHash returns the hash of the node of subtree c with suffix sx, or nil if
+// it isn't set.
+func nodeHash(c *storagepb.SubtreeProto, sx storage.Suffix) []byte {
+	// Look up the hash in the appropriate map.
+	// The leaf hashes are stored in a separate map to the internal nodes so that
+	// we can easily dump (and later reconstruct) the internal nodes. As log subtrees
+	// have a fixed depth if the suffix has the same number of significant bits as the
+	// subtree depth then this is a leaf. For example if the subtree is depth 8 its leaves
+	// have 8 significant suffix bits.
+	sfxKey := sx.String()
+	if int32(sx.Bits) == c.Depth {
+		return c.Leaves[sfxKey]
+	}
+	return c.InternalNodes[sfxKey]
+}
+
 // SetNodeHash sets a node hash in the cache.
 func (s *SubtreeCache) SetNodeHash(id storage.NodeID, h []byte, getSubtree GetSubtreeFunc) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	px, sx := s.splitNodeID(id)
+	px, sx, err := s.locate(id)
+	if err != nil {
+		return err
+	}
 	prefixKey := string(px)
 	c := s.subtrees[prefixKey]
 	if c == nil {
@@ -345,12 +457,15 @@ func (s *SubtreeCache) SetNodeHash(id storage.NodeID, h []byte, getSubtree GetSu
 	return nil
 }
 
-// Flush causes the cache to write all dirty Subtrees back to storage.
+// Flush causes the cache to write all dirty Subtrees back to storage. Subtrees
+// whose contents are unchanged since they were read from storage aren't
+// written, as reads of later revisions return the stored copy.
 func (s *SubtreeCache) Flush(setSubtrees SetSubtreesFunc) error {
-	s.mutex.RLock()
-	defer s.mutex.RUnlock()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 
 	treesToWrite := make([]*storagepb.SubtreeProto, 0, len(s.dirtyPrefixes))
+	writtenHashes := make(map[string][]byte)
 	for k, v := range s.subtrees {
 		if s.dirtyPrefixes[k] {
 			bk := []byte(k)
@@ -366,6 +481,12 @@ func (s *SubtreeCache) Flush(setSubtrees SetSubtreesFunc) error {
 				if err := s.prepare(v); err != nil {
 					return err
 				}
+				h := subtreeContentHash(v)
+				if bytes.Equal(h, s.storedHashes[k]) {
+					glog.V(2).Infof("Skipping write of unchanged subtree %x", bk)
+					continue
+				}
+				writtenHashes[k] = h
 				treesToWrite = append(treesToWrite, v)
 			}
 		}
@@ -373,7 +494,43 @@ func (s *SubtreeCache) Flush(setSubtrees SetSubtreesFunc) error {
 	if len(treesToWrite) == 0 {
 		return nil
 	}
-	return setSubtrees(treesToWrite)
+	if err := setSubtrees(treesToWrite); err != nil {
+		return err
+	}
+	for k, h := range writtenHashes {
+		s.storedHashes[k] = h
+	}
+	return nil
+}
+
+// subtreeContentHash returns a hash of everything stored for st, which is
+// equal for subtrees with the same contents.
+func subtreeContentHash(st *storagepb.SubtreeProto) []byte {
+	h := sha256.New()
+	writeBytes := func(b []byte) {
+		var l [8]byte
+		binary.BigEndian.PutUint64(l[:], uint64(len(b)))
+		h.Write(l[:])
+		h.Write(b)
+	}
+	writeMap := func(m map[string][]byte) {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		writeBytes([]byte(fmt.Sprintf("%d", len(keys))))
+		for _, k := range keys {
+			writeBytes([]byte(k))
+			writeBytes(m[k])
+		}
+	}
+	writeBytes(st.Prefix)
+	writeBytes([]byte(fmt.Sprintf("%d/%d", st.Depth, st.InternalNodeCount)))
+	writeBytes(st.RootHash)
+	writeMap(st.Leaves)
+	writeMap(st.InternalNodes)
+	return h.Sum(nil)
 }
 
 func (s *SubtreeCache) newEmptySubtree(id storage.NodeID, px []byte) *storagepb.SubtreeProto {
@@ -18,8 +18,11 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"reflect"
+	"sort"
 	"testing"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/google/trillian/merkle"
 	"github.com/google/trillian/merkle/maphasher"
 	"github.com/google/trillian/merkle/rfc6962"
@@ -245,6 +248,310 @@ func TestCacheFlush(t *testing.T) {
 	}
 }
 
+func TestCacheFlushSkipsUnchangedSubtrees(t *testing.T) {
+	stored := make(map[string]*storagepb.SubtreeProto)
+	getSubtree := func(id storage.NodeID) (*storagepb.SubtreeProto, error) {
+		px := id.Path[:id.PrefixLenBits/8]
+		if st := stored[string(px)]; st != nil {
+			return proto.Clone(st).(*storagepb.SubtreeProto), nil
+		}
+		return nil, nil
+	}
+	var written []string
+	setSubtrees := func(trees []*storagepb.SubtreeProto) error {
+		for _, st := range trees {
+			stored[string(st.Prefix)] = proto.Clone(st).(*storagepb.SubtreeProto)
+			written = append(written, fmt.Sprintf("%x", st.Prefix))
+		}
+		return nil
+	}
+	setLeaves := func(c *SubtreeCache, indices ...int64) {
+		t.Helper()
+		for _, index := range indices {
+			id, err := storage.NewNodeIDForTreeCoords(0, index, maxLogDepth)
+			if err != nil {
+				t.Fatalf("NewNodeIDForTreeCoords(0, %d): %v", index, err)
+			}
+			if err := c.SetNodeHash(id, []byte(fmt.Sprintf("leaf-%d", index)), getSubtree); err != nil {
+				t.Fatalf("SetNodeHash(%d): %v", index, err)
+			}
+		}
+	}
+	flush := func(c *SubtreeCache, want ...string) {
+		t.Helper()
+		written = nil
+		if err := c.Flush(setSubtrees); err != nil {
+			t.Fatalf("Flush(): %v", err)
+		}
+		sort.Strings(written)
+		if !reflect.DeepEqual(written, want) {
+			t.Errorf("Flush() wrote subtrees %v, want %v", written, want)
+		}
+	}
+
+	c := NewLogSubtreeCache(defaultLogStrata, rfc6962.DefaultHasher)
+	setLeaves(&c, 0, 1, 256)
+	flush(&c, "00000000000000", "00000000000001")
+
+	// A new revision rewrites the leaves of the first subtree unchanged, and
+	// adds a leaf to the second one.
+	c = NewLogSubtreeCache(defaultLogStrata, rfc6962.DefaultHasher)
+	setLeaves(&c, 0, 1, 256, 257)
+	flush(&c, "00000000000001")
+
+	// Flushing again only writes subtrees changed since the last flush.
+	setLeaves(&c, 257)
+	flush(&c)
+	setLeaves(&c, 2)
+	flush(&c, "00000000000000")
+}
+
+func TestBuildLogSubtrees(t *testing.T) {
+	hasher := rfc6962.DefaultHasher
+	// The compact tree sets the nodes on its right edge again as it grows, so
+	// keep only the final hash of each node, as the sequencer does. The same
+	// nodes are written through a SubtreeCache as they're set, for comparison.
+	var nodes []storage.Node
+	pos := make(map[string]int)
+	w := NewLogSubtreeCache(defaultLogStrata, hasher)
+	empty := func(storage.NodeID) (*storagepb.SubtreeProto, error) {
+		return nil, nil
+	}
+	set := func(depth int, index int64, h []byte) error {
+		id, err := storage.NewNodeIDForTreeCoords(int64(depth), index, maxLogDepth)
+		if err != nil {
+			return err
+		}
+		if i, ok := pos[id.String()]; ok {
+			nodes[i].Hash = h
+		} else {
+			pos[id.String()] = len(nodes)
+			nodes = append(nodes, storage.Node{NodeID: id, Hash: h})
+		}
+		return w.SetNodeHash(id, h, empty)
+	}
+	cmt := merkle.NewCompactMerkleTree(hasher)
+	for i := 0; i < 300; i++ {
+		h, err := hasher.HashLeaf([]byte(fmt.Sprintf("leaf %d", i)))
+		if err != nil {
+			t.Fatalf("HashLeaf(): %v", err)
+		}
+		seq, err := cmt.AddLeafHash(h, set)
+		if err != nil {
+			t.Fatalf("AddLeafHash(): %v", err)
+		}
+		if err := set(0, seq, h); err != nil {
+			t.Fatalf("set(0, %d): %v", seq, err)
+		}
+	}
+
+	subtrees, err := BuildLogSubtrees(defaultLogStrata, hasher, nodes)
+	if err != nil {
+		t.Fatalf("BuildLogSubtrees(): %v", err)
+	}
+	stored := make(map[string]*storagepb.SubtreeProto)
+	for _, st := range subtrees {
+		stored[string(st.Prefix)] = st
+	}
+	written := make(map[string]*storagepb.SubtreeProto)
+	if err := w.Flush(func(sts []*storagepb.SubtreeProto) error {
+		for _, st := range sts {
+			written[string(st.Prefix)] = st
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("Flush(): %v", err)
+	}
+	if got, want := len(stored), len(written); got != want {
+		t.Errorf("BuildLogSubtrees() returned %d subtrees, want %d", got, want)
+	}
+	for prefix, want := range written {
+		if got := stored[prefix]; !proto.Equal(got, want) {
+			t.Errorf("BuildLogSubtrees() subtree %x diff:\n%v", prefix, pretty.Compare(got, want))
+		}
+	}
+
+	getSubtree := func(id storage.NodeID) (*storagepb.SubtreeProto, error) {
+		return stored[string(id.Path[:id.PrefixLenBits/8])], nil
+	}
+	c := NewLogSubtreeCache(defaultLogStrata, hasher)
+	for _, n := range nodes {
+		h, err := c.GetNodeHash(n.NodeID, getSubtree)
+		if err != nil {
+			t.Fatalf("GetNodeHash(%v): %v", n.NodeID, err)
+		}
+		if !bytes.Equal(h, n.Hash) {
+			t.Errorf("GetNodeHash(%v)=%x, want %x", n.NodeID, h, n.Hash)
+		}
+	}
+}
+
+func TestCacheGetNodesByCoords(t *testing.T) {
+	stored := make(map[string]*storagepb.SubtreeProto)
+	reads := 0
+	getSubtrees := func(ids []storage.NodeID) ([]*storagepb.SubtreeProto, error) {
+		reads++
+		var ret []*storagepb.SubtreeProto
+		for _, id := range ids {
+			if st := stored[string(id.Path[:id.PrefixLenBits/8])]; st != nil {
+				ret = append(ret, proto.Clone(st).(*storagepb.SubtreeProto))
+			}
+		}
+		return ret, nil
+	}
+	getSubtree := func(id storage.NodeID) (*storagepb.SubtreeProto, error) {
+		sts, err := getSubtrees([]storage.NodeID{id})
+		if err != nil || len(sts) == 0 {
+			return nil, err
+		}
+		return sts[0], nil
+	}
+
+	// Write nodes of the first and second strata by their NodeIDs.
+	coords := []storage.NodeCoords{
+		{Level: 0, Index: 0}, {Level: 0, Index: 1}, {Level: 0, Index: 300},
+		{Level: 1, Index: 0}, {Level: 8, Index: 2}, {Level: 9, Index: 1},
+	}
+	w := NewLogSubtreeCache(defaultLogStrata, rfc6962.DefaultHasher)
+	for _, c := range coords {
+		id, err := c.ID()
+		if err != nil {
+			t.Fatalf("%v.ID(): %v", c, err)
+		}
+		if err := w.SetNodeHash(id, []byte(c.String()), getSubtree); err != nil {
+			t.Fatalf("SetNodeHash(%v): %v", c, err)
+		}
+	}
+	if err := w.Flush(func(sts []*storagepb.SubtreeProto) error {
+		for _, st := range sts {
+			stored[string(st.Prefix)] = proto.Clone(st).(*storagepb.SubtreeProto)
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("Flush(): %v", err)
+	}
+	if got, want := len(stored), 3; got != want {
+		t.Fatalf("Flush() stored %d subtrees, want %d", got, want)
+	}
+
+	// Read them back by their coordinates, along with one which isn't set.
+	reads = 0
+	c := NewLogSubtreeCache(defaultLogStrata, rfc6962.DefaultHasher)
+	nodes, err := c.GetNodesByCoords(append(coords, storage.NodeCoords{Level: 0, Index: 2}), getSubtrees)
+	if err != nil {
+		t.Fatalf("GetNodesByCoords(): %v", err)
+	}
+	if reads != 1 {
+		t.Errorf("GetNodesByCoords() read subtrees %d times, want 1", reads)
+	}
+	if got, want := len(nodes), len(coords); got != want {
+		t.Fatalf("GetNodesByCoords() returned %d nodes, want %d", got, want)
+	}
+	for i, n := range nodes {
+		if n.Coords != coords[i] || string(n.Hash) != coords[i].String() {
+			t.Errorf("GetNodesByCoords() returned node %v with hash %q, want %v with hash %q", n.Coords, n.Hash, coords[i], coords[i].String())
+		}
+	}
+
+	if _, err := c.GetNodesByCoords([]storage.NodeCoords{{Level: 64, Index: 0}}, getSubtrees); err == nil {
+		t.Error("GetNodesByCoords() of the root succeeded, want error")
+	}
+	m := NewSubtreeCache(defaultMapStrata, populateMapSubtreeNodes(treeID, maphasher.Default), prepareMapSubtreeWrite())
+	if _, err := m.GetNodesByCoords(coords, getSubtrees); err == nil {
+		t.Error("GetNodesByCoords() on map cache succeeded, want error")
+	}
+}
+
+func TestLogCacheDeepStrata(t *testing.T) {
+	const depth = 16
+	// Four 16-level strata hold the 64 levels of the log, in place of eight
+	// 8-level ones.
+	strata := append([]int{depth, depth, depth, depth}, defaultLogStrata[8:]...)
+	hasher := rfc6962.DefaultHasher
+
+	// levels holds the hashes of the nodes of the bottom subtree, from its
+	// leaves up to its root.
+	levels := make([][][]byte, depth+1)
+	for i := 0; i < 1<<depth; i++ {
+		h, err := hasher.HashLeaf([]byte(fmt.Sprintf("leaf %d", i)))
+		if err != nil {
+			t.Fatalf("HashLeaf(): %v", err)
+		}
+		levels[0] = append(levels[0], h)
+	}
+	for l := 1; l <= depth; l++ {
+		for i := 0; i < len(levels[l-1]); i += 2 {
+			levels[l] = append(levels[l], hasher.HashChildren(levels[l-1][i], levels[l-1][i+1]))
+		}
+	}
+
+	stored := make(map[string]*storagepb.SubtreeProto)
+	getSubtrees := func(ids []storage.NodeID) ([]*storagepb.SubtreeProto, error) {
+		var ret []*storagepb.SubtreeProto
+		for _, id := range ids {
+			if st := stored[string(id.Path[:id.PrefixLenBits/8])]; st != nil {
+				ret = append(ret, proto.Clone(st).(*storagepb.SubtreeProto))
+			}
+		}
+		return ret, nil
+	}
+	getSubtree := func(id storage.NodeID) (*storagepb.SubtreeProto, error) {
+		sts, err := getSubtrees([]storage.NodeID{id})
+		if err != nil || len(sts) == 0 {
+			return nil, err
+		}
+		return sts[0], nil
+	}
+
+	// Write all the nodes of the bottom subtree below its root.
+	w := NewLogSubtreeCache(strata, hasher)
+	for l := 0; l < depth; l++ {
+		for i, h := range levels[l] {
+			id, err := storage.NewNodeIDForTreeCoords(int64(l), int64(i), maxLogDepth)
+			if err != nil {
+				t.Fatalf("NewNodeIDForTreeCoords(%d, %d): %v", l, i, err)
+			}
+			if err := w.SetNodeHash(id, h, getSubtree); err != nil {
+				t.Fatalf("SetNodeHash(%d, %d): %v", l, i, err)
+			}
+		}
+	}
+	if err := w.Flush(func(sts []*storagepb.SubtreeProto) error {
+		for _, st := range sts {
+			stored[string(st.Prefix)] = proto.Clone(st).(*storagepb.SubtreeProto)
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("Flush(): %v", err)
+	}
+	st := stored[string(make([]byte, 6))]
+	if len(stored) != 1 || st == nil {
+		t.Fatalf("Flush() stored subtrees %v, want one with a 6 byte prefix", stored)
+	}
+	if st.Depth != depth || len(st.Leaves) != 1<<depth || len(st.InternalNodes) != 0 {
+		t.Errorf("Flush() stored subtree of depth %d with %d leaves and %d internal nodes, want %d, %d, 0", st.Depth, len(st.Leaves), len(st.InternalNodes), depth, 1<<depth)
+	}
+
+	// Internal nodes of the full subtree are rebuilt when it's read back.
+	c := NewLogSubtreeCache(strata, hasher)
+	coords := []storage.NodeCoords{{Level: 0, Index: 5}, {Level: 1, Index: 7}, {Level: 9, Index: 100}, {Level: 15, Index: 1}}
+	nodes, err := c.GetNodesByCoords(coords, getSubtrees)
+	if err != nil {
+		t.Fatalf("GetNodesByCoords(): %v", err)
+	}
+	if got, want := len(nodes), len(coords); got != want {
+		t.Fatalf("GetNodesByCoords() returned %d nodes, want %d", got, want)
+	}
+	for i, n := range nodes {
+		if want := levels[coords[i].Level][coords[i].Index]; n.Coords != coords[i] || !bytes.Equal(n.Hash, want) {
+			t.Errorf("GetNodesByCoords() returned node %v with hash %x, want %v with hash %x", n.Coords, n.Hash, coords[i], want)
+		}
+	}
+	if got, want := c.subtrees[string(st.Prefix)].RootHash, levels[depth][0]; !bytes.Equal(got, want) {
+		t.Errorf("subtree root hash %x, want %x", got, want)
+	}
+}
+
 func TestRepopulateLogSubtree(t *testing.T) {
 	populateTheThing := populateLogSubtreeNodes(rfc6962.DefaultHasher)
 	cmt := merkle.NewCompactMerkleTree(rfc6962.DefaultHasher)
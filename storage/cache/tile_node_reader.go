@@ -0,0 +1,146 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/trillian/merkle/hashers"
+	"github.com/google/trillian/storage"
+)
+
+// tileNodeReader translates reads of log Merkle nodes into reads of tiles.
+type tileNodeReader struct {
+	tr       storage.TileReader
+	hasher   hashers.LogHasher
+	treeSize int64
+}
+
+// NewTileNodeReader returns a NodeReader serving the nodes of the log tree of
+// size treeSize from tiles, so that code written against the revisioned
+// subtree storage, such as proof fetching, can be used with tile storage.
+// Tiles are not revisioned, so the tree revision passed to GetMerkleNodes is
+// ignored; nodes on the right edge of the tree are computed as of treeSize,
+// matching the values returned by the subtree storage at the corresponding
+// revision.
+func NewTileNodeReader(tr storage.TileReader, hasher hashers.LogHasher, treeSize int64) storage.NodeReader {
+	return &tileNodeReader{tr: tr, hasher: hasher, treeSize: treeSize}
+}
+
+// GetMerkleNodes implements storage.NodeReader. Nodes entirely beyond the
+// tree size are not returned.
+func (r *tileNodeReader) GetMerkleNodes(ctx context.Context, _ int64, ids []storage.NodeID) ([]storage.Node, error) {
+	t := &tileHasher{ctx: ctx, r: r, tiles: make(map[storage.TileID][][]byte)}
+	ret := make([]storage.Node, 0, len(ids))
+	for _, id := range ids {
+		c, err := storage.CoordsForNodeID(id)
+		if err != nil {
+			return nil, err
+		}
+		level := uint(c.Level)
+		lo := c.Index << level
+		if lo >= r.treeSize {
+			continue
+		}
+		hi := (c.Index + 1) << level
+		if hi > r.treeSize || hi <= 0 {
+			hi = r.treeSize
+		}
+		h, err := t.rangeHash(lo, hi)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, storage.Node{NodeID: id, Hash: h})
+	}
+	return ret, nil
+}
+
+// tileHasher computes Merkle tree hashes from tiles, caching the tiles it
+// reads for the duration of a single request.
+type tileHasher struct {
+	ctx   context.Context
+	r     *tileNodeReader
+	tiles map[storage.TileID][][]byte
+}
+
+// rangeHash returns MTH(D[lo:hi]) as defined in RFC 6962, where lo is
+// aligned to the largest power of two no greater than hi-lo.
+func (t *tileHasher) rangeHash(lo, hi int64) ([]byte, error) {
+	n := hi - lo
+	if n&(n-1) == 0 {
+		return t.perfectHash(lo, n)
+	}
+	k := int64(1)
+	for k<<1 < n {
+		k <<= 1
+	}
+	left, err := t.perfectHash(lo, k)
+	if err != nil {
+		return nil, err
+	}
+	right, err := t.rangeHash(lo+k, hi)
+	if err != nil {
+		return nil, err
+	}
+	return t.r.hasher.HashChildren(left, right), nil
+}
+
+// perfectHash returns the hash of the perfect subtree of n leaves, a power of
+// two, starting at leaf lo.
+func (t *tileHasher) perfectHash(lo, n int64) ([]byte, error) {
+	var level uint
+	for int64(1)<<level < n {
+		level++
+	}
+	// The subtree's hash is found in, or computed from, the tile holding the
+	// nodes at the nearest tile level below it.
+	tileLevel := level / storage.TileHeight
+	sub := level % storage.TileHeight
+	first := lo >> (tileLevel * storage.TileHeight)
+	hashes, err := t.tile(storage.TileID{Level: int(tileLevel), Index: first / storage.TileWidth})
+	if err != nil {
+		return nil, err
+	}
+	off := first % storage.TileWidth
+	end := off + 1<<sub
+	if end > int64(len(hashes)) {
+		return nil, fmt.Errorf("node %d/%d is missing from tiles", level, lo>>level)
+	}
+	hashes = hashes[off:end]
+	for len(hashes) > 1 {
+		next := make([][]byte, len(hashes)/2)
+		for i := range next {
+			next[i] = t.r.hasher.HashChildren(hashes[2*i], hashes[2*i+1])
+		}
+		hashes = next
+	}
+	return hashes[0], nil
+}
+
+func (t *tileHasher) tile(id storage.TileID) ([][]byte, error) {
+	if hashes, ok := t.tiles[id]; ok {
+		return hashes, nil
+	}
+	tiles, err := t.r.tr.GetTiles(t.ctx, t.r.treeSize, []storage.TileID{id})
+	if err != nil {
+		return nil, err
+	}
+	if len(tiles) != 1 || tiles[0] == nil {
+		return nil, fmt.Errorf("tile %v is missing", id)
+	}
+	t.tiles[id] = tiles[0].Hashes
+	return tiles[0].Hashes, nil
+}
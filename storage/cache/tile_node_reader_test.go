@@ -0,0 +1,163 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/trillian/merkle"
+	"github.com/google/trillian/merkle/rfc6962"
+	"github.com/google/trillian/storage"
+)
+
+func tileTestLeaf(i int64) []byte {
+	return []byte(fmt.Sprintf("leaf %d", i))
+}
+
+// rangeRoot returns MTH(D[lo:hi]) for the test leaves.
+func rangeRoot(t *testing.T, lo, hi int64) []byte {
+	t.Helper()
+	mt := merkle.NewInMemoryMerkleTree(rfc6962.DefaultHasher)
+	for i := lo; i < hi; i++ {
+		if _, _, err := mt.AddLeaf(tileTestLeaf(i)); err != nil {
+			t.Fatalf("AddLeaf(): %v", err)
+		}
+	}
+	return mt.CurrentRoot().Hash()
+}
+
+// memTileReader serves the tiles of a tree of test leaves.
+type memTileReader struct {
+	tiles map[storage.TileID][][]byte
+}
+
+func newMemTileReader(t *testing.T, size int64) *memTileReader {
+	t.Helper()
+	r := &memTileReader{tiles: make(map[storage.TileID][][]byte)}
+	for level := 0; size>>uint(level*storage.TileHeight) > 0; level++ {
+		shift := uint(level * storage.TileHeight)
+		for i := int64(0); i < size>>shift; i++ {
+			id := storage.TileID{Level: level, Index: i / storage.TileWidth}
+			r.tiles[id] = append(r.tiles[id], rangeRoot(t, i<<shift, (i+1)<<shift))
+		}
+	}
+	return r
+}
+
+func (r *memTileReader) GetTiles(ctx context.Context, treeSize int64, ids []storage.TileID) ([]*storage.Tile, error) {
+	ret := make([]*storage.Tile, len(ids))
+	for i, id := range ids {
+		if hashes, ok := r.tiles[id]; ok {
+			ret[i] = &storage.Tile{ID: id, Hashes: hashes}
+		}
+	}
+	return ret, nil
+}
+
+func TestTileNodeReader(t *testing.T) {
+	ctx := context.Background()
+	const size = 2*storage.TileWidth + 37
+	tr := newMemTileReader(t, size)
+	nr := NewTileNodeReader(tr, rfc6962.DefaultHasher, size)
+
+	for _, tc := range []struct {
+		level, index int64
+		want         []byte
+	}{
+		{level: 0, index: 0, want: rangeRoot(t, 0, 1)},
+		{level: 0, index: size - 1, want: rangeRoot(t, size-1, size)},
+		{level: 3, index: 5, want: rangeRoot(t, 40, 48)},
+		{level: 8, index: 1, want: rangeRoot(t, 256, 512)},
+		{level: 9, index: 0, want: rangeRoot(t, 0, 512)},
+		// Nodes on the right edge of the tree.
+		{level: 6, index: 8, want: rangeRoot(t, 512, size)},
+		{level: 9, index: 1, want: rangeRoot(t, 512, size)},
+		{level: 10, index: 0, want: rangeRoot(t, 0, size)},
+		{level: 64, index: 0, want: rangeRoot(t, 0, size)},
+		// Nodes beyond the tree size.
+		{level: 0, index: size},
+		{level: 9, index: 2},
+	} {
+		id, err := storage.NewNodeIDForTreeCoords(tc.level, tc.index, maxLogDepth)
+		if err != nil {
+			t.Fatalf("NewNodeIDForTreeCoords(%d, %d): %v", tc.level, tc.index, err)
+		}
+		nodes, err := nr.GetMerkleNodes(ctx, 0, []storage.NodeID{id})
+		if err != nil {
+			t.Errorf("GetMerkleNodes(%d/%d): %v", tc.level, tc.index, err)
+			continue
+		}
+		if tc.want == nil {
+			if len(nodes) != 0 {
+				t.Errorf("GetMerkleNodes(%d/%d): got %d nodes, want none", tc.level, tc.index, len(nodes))
+			}
+			continue
+		}
+		if len(nodes) != 1 {
+			t.Errorf("GetMerkleNodes(%d/%d): got %d nodes, want 1", tc.level, tc.index, len(nodes))
+			continue
+		}
+		if got := nodes[0].Hash; !bytes.Equal(got, tc.want) {
+			t.Errorf("GetMerkleNodes(%d/%d): %x, want %x", tc.level, tc.index, got, tc.want)
+		}
+	}
+}
+
+func TestTileSubtreeConversion(t *testing.T) {
+	hasher := rfc6962.DefaultHasher
+	tile := &storage.Tile{ID: storage.TileID{Level: 1, Index: 0x0102}}
+	for i := int64(0); i < storage.TileWidth; i++ {
+		tile.Hashes = append(tile.Hashes, rangeRoot(t, i, i+1))
+	}
+
+	st, err := tile.Subtree()
+	if err != nil {
+		t.Fatalf("Subtree(): %v", err)
+	}
+	if got, want := st.Prefix, []byte{0, 0, 0, 0, 0x01, 0x02}; !bytes.Equal(got, want) {
+		t.Errorf("Subtree(): prefix %x, want %x", got, want)
+	}
+	// The subtree must be accepted by the log subtree cache.
+	if err := LogPopulateFunc(hasher)(st); err != nil {
+		t.Fatalf("LogPopulateFunc(): %v", err)
+	}
+	if got, want := st.RootHash, rangeRoot(t, 0, storage.TileWidth); !bytes.Equal(got, want) {
+		t.Errorf("populated subtree has root %x, want %x", got, want)
+	}
+
+	back, err := storage.TileFromSubtree(st)
+	if err != nil {
+		t.Fatalf("TileFromSubtree(): %v", err)
+	}
+	if back.ID != tile.ID {
+		t.Errorf("TileFromSubtree(): ID %v, want %v", back.ID, tile.ID)
+	}
+	if len(back.Hashes) != len(tile.Hashes) {
+		t.Fatalf("TileFromSubtree(): %d hashes, want %d", len(back.Hashes), len(tile.Hashes))
+	}
+	for i := range back.Hashes {
+		if !bytes.Equal(back.Hashes[i], tile.Hashes[i]) {
+			t.Errorf("TileFromSubtree(): hash %d is %x, want %x", i, back.Hashes[i], tile.Hashes[i])
+		}
+	}
+
+	partial := &storage.Tile{ID: tile.ID, Hashes: tile.Hashes[:10]}
+	if _, err := partial.Subtree(); err == nil {
+		t.Error("Subtree() of partial tile: got nil error, want error")
+	}
+}
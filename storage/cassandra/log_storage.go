@@ -0,0 +1,767 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cassandra
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/golang/glog"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/trillian"
+	"github.com/google/trillian/merkle/hashers"
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/cache"
+	"github.com/google/trillian/trees"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	insertLeafDataCQL = `INSERT INTO LeafData(TreeId, LeafIdentityHash, LeafValue, ExtraData, MerkleLeafHash, QueueTimestampNanos)
+		VALUES(?, ?, ?, ?, ?, ?) IF NOT EXISTS`
+	selectLeafDataCQL = `SELECT LeafIdentityHash, LeafValue, ExtraData, MerkleLeafHash, QueueTimestampNanos FROM LeafData
+		WHERE TreeId = ? AND LeafIdentityHash IN ?`
+	insertUnsequencedCQL = `INSERT INTO Unsequenced(TreeId, Bucket, QueueTimestampNanos, LeafIdentityHash)
+		VALUES(?, ?, ?, ?)`
+	selectUnsequencedCQL = `SELECT QueueTimestampNanos, LeafIdentityHash FROM Unsequenced
+		WHERE TreeId = ? AND Bucket = ? AND QueueTimestampNanos < ? LIMIT ?`
+	deleteUnsequencedCQL = `DELETE FROM Unsequenced
+		WHERE TreeId = ? AND Bucket = ? AND QueueTimestampNanos = ? AND LeafIdentityHash = ?`
+	selectUnsequencedTreeIDsCQL = "SELECT TreeId FROM Unsequenced"
+	insertSequencedLeafCQL      = `INSERT INTO SequencedLeafData(TreeId, Bucket, SequenceNumber, LeafIdentityHash, MerkleLeafHash, IntegrateTimestampNanos)
+		VALUES(?, ?, ?, ?, ?, ?)`
+	selectSequencedLeavesCQL = `SELECT SequenceNumber, LeafIdentityHash, MerkleLeafHash, IntegrateTimestampNanos FROM SequencedLeafData
+		WHERE TreeId = ? AND Bucket = ? AND SequenceNumber IN ?`
+	deleteSequencedLeafCQL = `DELETE FROM SequencedLeafData
+		WHERE TreeId = ? AND Bucket = ? AND SequenceNumber = ?`
+	insertLeafHashIndexCQL = "INSERT INTO LeafHashIndex(TreeId, MerkleLeafHash, SequenceNumber) VALUES(?, ?, ?)"
+	selectLeafHashIndexCQL = "SELECT SequenceNumber FROM LeafHashIndex WHERE TreeId = ? AND MerkleLeafHash = ?"
+	deleteLeafHashIndexCQL = "DELETE FROM LeafHashIndex WHERE TreeId = ? AND MerkleLeafHash = ? AND SequenceNumber = ?"
+	insertLeafSequenceCQL  = `INSERT INTO LeafSequence(TreeId, LeafIdentityHash, Revision, SequenceNumber)
+		VALUES(?, ?, ?, ?)`
+	selectLeafSequenceCQL = `SELECT LeafIdentityHash FROM LeafSequence
+		WHERE TreeId = ? AND LeafIdentityHash IN ? AND Revision <= ?`
+	deleteLeafSequenceCQL = "DELETE FROM LeafSequence WHERE TreeId = ? AND LeafIdentityHash = ? AND Revision = ?"
+)
+
+const (
+	logIDLabel = "logid"
+
+	// queueBuckets is the number of Unsequenced partitions per tree.
+	queueBuckets = 16
+	// sequenceBucketBits is the number of low-order bits of a sequence number
+	// which don't contribute to its SequencedLeafData bucket.
+	sequenceBucketBits = 16
+
+	// orphanAge is the age beyond which queue entries without leaf data are
+	// removed by DequeueLeaves. Such entries are left by QueueLeaves calls
+	// which failed between writing the entry and the data, and younger ones
+	// may belong to calls which are still in progress.
+	orphanAge = 10 * time.Minute
+)
+
+var (
+	once             sync.Once
+	queuedCounter    monitoring.Counter
+	queuedDupCounter monitoring.Counter
+	dequeuedCounter  monitoring.Counter
+	skippedCounter   monitoring.Counter
+	commitConflicts  monitoring.Counter
+)
+
+func createMetrics(mf monitoring.MetricFactory) {
+	queuedCounter = mf.NewCounter("cassandra_queued_leaves", "Number of leaves queued", logIDLabel)
+	queuedDupCounter = mf.NewCounter("cassandra_queued_dup_leaves", "Number of duplicate leaves queued", logIDLabel)
+	dequeuedCounter = mf.NewCounter("cassandra_dequeued_leaves", "Number of leaves dequeued", logIDLabel)
+	skippedCounter = mf.NewCounter("cassandra_skipped_queue_entries", "Number of queue entries removed without being dequeued, as their leaves were already sequenced or never stored", logIDLabel)
+	commitConflicts = mf.NewCounter("cassandra_commit_conflicts", "Number of transactions which lost the race to store a tree head", logIDLabel)
+}
+
+func queueBucket(identityHash []byte) int {
+	var b [4]byte
+	copy(b[:], identityHash)
+	return int(binary.BigEndian.Uint32(b[:]) % queueBuckets)
+}
+
+func sequenceBucket(seq int64) int64 {
+	return seq >> sequenceBucketBits
+}
+
+type logStorage struct {
+	*treeStorage
+	metricFactory monitoring.MetricFactory
+}
+
+// NewLogStorage creates a Cassandra-backed storage.LogStorage. Tree metadata
+// is read from admin, which would usually be the AdminStorage returned by
+// NewAdminStorage for the same session.
+// Adding pre-ordered leaves via AddSequencedLeaves is not supported.
+func NewLogStorage(session *gocql.Session, admin storage.AdminStorage, mf monitoring.MetricFactory) storage.LogStorage {
+	if mf == nil {
+		mf = monitoring.InertMetricFactory{}
+	}
+	return &logStorage{
+		treeStorage:   &treeStorage{session: session, admin: admin},
+		metricFactory: mf,
+	}
+}
+
+func (ls *logStorage) CheckDatabaseAccessible(ctx context.Context) error {
+	return checkDatabaseAccessible(ctx, ls.session)
+}
+
+func (ls *logStorage) begin(ctx context.Context, treeID int64, readonly bool) (*logTX, error) {
+	once.Do(func() {
+		createMetrics(ls.metricFactory)
+	})
+	tree, err := trees.GetTree(ctx, ls.admin, treeID, trees.NewGetOpts(readonly, trillian.TreeType_LOG))
+	if err != nil {
+		return nil, err
+	}
+	hasher, err := hashers.NewLogHasher(tree.HashStrategy)
+	if err != nil {
+		return nil, err
+	}
+	strata, err := storage.SubtreeStrata(tree)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := &logTX{
+		treeTX: treeTX{
+			ts:       ls.treeStorage,
+			treeID:   treeID,
+			cache:    cache.NewLogSubtreeCache(strata, hasher),
+			readonly: readonly,
+		},
+		ls:       ls,
+		hashSize: hasher.Size(),
+		dequeued: make(map[string]queueEntry),
+	}
+
+	head, err := ls.latestHead(ctx, treeID)
+	if err != nil {
+		// An uninitialised tree can still be written to, to create the
+		// first tree head at revision 0.
+		return tx, err
+	}
+	if err := proto.Unmarshal(head, &tx.root); err != nil {
+		return nil, err
+	}
+	tx.readRev = tx.root.TreeRevision
+	tx.writeRev = tx.root.TreeRevision + 1
+	return tx, nil
+}
+
+func (ls *logStorage) ReadWriteTransaction(ctx context.Context, treeID int64, f storage.LogTXFunc) error {
+	tx, err := ls.begin(ctx, treeID, false /* readonly */)
+	if err != nil && err != storage.ErrTreeNeedsInit {
+		return err
+	}
+	defer tx.Close()
+	if err := f(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (ls *logStorage) SnapshotForTree(ctx context.Context, treeID int64) (storage.ReadOnlyLogTreeTX, error) {
+	tx, err := ls.begin(ctx, treeID, true /* readonly */)
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+func (ls *logStorage) Snapshot(ctx context.Context) (storage.ReadOnlyLogTX, error) {
+	return &readOnlyLogTX{ls: ls}, nil
+}
+
+func (ls *logStorage) QueueLeaves(ctx context.Context, treeID int64, leaves []*trillian.LogLeaf, queueTimestamp time.Time) ([]*trillian.QueuedLogLeaf, error) {
+	tx, err := ls.begin(ctx, treeID, false /* readonly */)
+	if err != nil && err != storage.ErrTreeNeedsInit {
+		return nil, err
+	}
+	defer tx.Close()
+	existing, err := tx.QueueLeaves(ctx, leaves, queueTimestamp)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	ret := make([]*trillian.QueuedLogLeaf, len(leaves))
+	for i, e := range existing {
+		if e != nil {
+			ret[i] = &trillian.QueuedLogLeaf{
+				Leaf:   e,
+				Status: status.Newf(codes.AlreadyExists, "leaf already exists: %v", e.LeafIdentityHash).Proto(),
+			}
+			continue
+		}
+		ret[i] = &trillian.QueuedLogLeaf{Leaf: leaves[i]}
+	}
+	return ret, nil
+}
+
+func (ls *logStorage) AddSequencedLeaves(ctx context.Context, treeID int64, leaves []*trillian.LogLeaf) ([]*trillian.QueuedLogLeaf, error) {
+	return nil, status.Errorf(codes.Unimplemented, "AddSequencedLeaves is not implemented")
+}
+
+// queueEntry identifies a row of the Unsequenced table within a tree.
+type queueEntry struct {
+	nanos        int64
+	identityHash []byte
+}
+
+// delete removes the entry from the Unsequenced table of treeID.
+func (e queueEntry) delete(ctx context.Context, session *gocql.Session, treeID int64) error {
+	return session.Query(deleteUnsequencedCQL, treeID, queueBucket(e.identityHash), e.nanos, e.identityHash).WithContext(ctx).Exec()
+}
+
+// logTX implements storage.LogTreeTX.
+type logTX struct {
+	treeTX
+	ls       *logStorage
+	hashSize int
+	root     trillian.SignedLogRoot
+
+	// newRoot is the root stored by StoreSignedLogRoot, if any.
+	newRoot *trillian.SignedLogRoot
+	// sequenced holds the leaves written on Commit.
+	sequenced []*trillian.LogLeaf
+	// dequeued maps the identity hash of each dequeued leaf to its entry in
+	// the Unsequenced table.
+	dequeued map[string]queueEntry
+	// skipped holds the Unsequenced entries skipped by DequeueLeaves, which
+	// are removed on Commit.
+	skipped []queueEntry
+}
+
+func (t *logTX) label() string {
+	return strconv.FormatInt(t.treeID, 10)
+}
+
+// GetNodesByCoords implements storage.CoordNodeReader.
+func (t *logTX) GetNodesByCoords(ctx context.Context, rev int64, coords []storage.NodeCoords) ([]storage.CoordNode, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.closed {
+		return nil, ErrTransactionClosed
+	}
+	return t.cache.GetNodesByCoords(coords, t.getSubtreesAtRev(ctx, rev))
+}
+
+func (t *logTX) LatestSignedLogRoot(ctx context.Context) (trillian.SignedLogRoot, error) {
+	return t.root, nil
+}
+
+// GetSequencedLeafCount returns the size of the tree as of the latest tree
+// head, as sequencing and tree head updates happen in the same transaction.
+func (t *logTX) GetSequencedLeafCount(ctx context.Context) (int64, error) {
+	return t.root.TreeSize, nil
+}
+
+func (t *logTX) StoreSignedLogRoot(ctx context.Context, root trillian.SignedLogRoot) error {
+	if t.readonly {
+		return ErrWrongTXType
+	}
+	if root.TreeRevision != t.writeRev {
+		return fmt.Errorf("root revision %d does not match write revision %d", root.TreeRevision, t.writeRev)
+	}
+	t.newRoot = &root
+	return nil
+}
+
+func (t *logTX) QueueLeaves(ctx context.Context, leaves []*trillian.LogLeaf, queueTimestamp time.Time) ([]*trillian.LogLeaf, error) {
+	if t.readonly {
+		return nil, ErrWrongTXType
+	}
+	// Don't accept batches if any of the leaves are invalid.
+	for _, leaf := range leaves {
+		if len(leaf.LeafIdentityHash) != t.hashSize {
+			return nil, fmt.Errorf("queued leaf must have a leaf ID hash of length %d", t.hashSize)
+		}
+	}
+	if _, err := ptypes.TimestampProto(queueTimestamp); err != nil {
+		return nil, err
+	}
+	qNanos := queueTimestamp.UnixNano()
+	label := t.label()
+
+	// Each leaf's queue entry is written before its data, which doubles as
+	// the de-duplication record, so that a leaf whose data is stored is
+	// always queued. Entries written for duplicates are removed again, and
+	// DequeueLeaves skips any which are left over, or whose data was never
+	// written.
+	existing := make([]*trillian.LogLeaf, len(leaves))
+	for i, leaf := range leaves {
+		e := queueEntry{nanos: qNanos, identityHash: leaf.LeafIdentityHash}
+		err := t.ts.session.Query(insertUnsequencedCQL,
+			t.treeID, queueBucket(leaf.LeafIdentityHash), qNanos, leaf.LeafIdentityHash).WithContext(ctx).Exec()
+		if err != nil {
+			return nil, err
+		}
+
+		prev := make(map[string]interface{})
+		applied, err := t.ts.session.Query(insertLeafDataCQL,
+			t.treeID, leaf.LeafIdentityHash, leaf.LeafValue, leaf.ExtraData, leaf.MerkleLeafHash, qNanos).WithContext(ctx).MapScanCAS(prev)
+		if err != nil {
+			return nil, err
+		}
+		if applied {
+			queuedCounter.Inc(label)
+			continue
+		}
+
+		queuedDupCounter.Inc(label)
+		dup, err := leafFromData(leaf.LeafIdentityHash, bytesValue(prev["leafvalue"]), bytesValue(prev["extradata"]), bytesValue(prev["merkleleafhash"]), int64Value(prev["queuetimestampnanos"]))
+		if err != nil {
+			return nil, err
+		}
+		existing[i] = dup
+		// A duplicate queued at the same time as the original (e.g. in the
+		// same batch) shares its queue entry, which must be kept.
+		if int64Value(prev["queuetimestampnanos"]) != qNanos {
+			if err := e.delete(ctx, t.ts.session, t.treeID); err != nil {
+				glog.Warningf("%v: failed to remove queue entry of duplicate leaf %x: %v", t.treeID, leaf.LeafIdentityHash, err)
+			}
+		}
+	}
+	return existing, nil
+}
+
+func bytesValue(v interface{}) []byte {
+	b, _ := v.([]byte)
+	return b
+}
+
+func int64Value(v interface{}) int64 {
+	i, _ := v.(int64)
+	return i
+}
+
+func leafFromData(identityHash, value, extra, merkleHash []byte, queueNanos int64) (*trillian.LogLeaf, error) {
+	leaf := &trillian.LogLeaf{
+		LeafIdentityHash: identityHash,
+		LeafValue:        value,
+		ExtraData:        extra,
+		MerkleLeafHash:   merkleHash,
+	}
+	var err error
+	if leaf.QueueTimestamp, err = ptypes.TimestampProto(time.Unix(0, queueNanos)); err != nil {
+		return nil, err
+	}
+	return leaf, nil
+}
+
+// DequeueLeaves reads up to limit leaves from each of the tree's Unsequenced
+// buckets, and returns the oldest limit of them. Queue entries of leaves
+// which have already been sequenced as of the transaction's read revision, of
+// leaves dequeued twice, and orphaned ones without leaf data are skipped, and
+// removed on Commit.
+func (t *logTX) DequeueLeaves(ctx context.Context, limit int, cutoffTime time.Time) ([]*trillian.LogLeaf, error) {
+	if t.readonly {
+		return nil, ErrWrongTXType
+	}
+
+	var entries []queueEntry
+	for bucket := 0; bucket < queueBuckets; bucket++ {
+		iter := t.ts.session.Query(selectUnsequencedCQL, t.treeID, bucket, cutoffTime.UnixNano(), limit).WithContext(ctx).Iter()
+		var nanos int64
+		var identityHash []byte
+		for iter.Scan(&nanos, &identityHash) {
+			entries = append(entries, queueEntry{nanos: nanos, identityHash: identityHash})
+			identityHash = nil
+		}
+		if err := iter.Close(); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].nanos < entries[j].nanos })
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	if len(entries) == 0 {
+		return []*trillian.LogLeaf{}, nil
+	}
+
+	identityHashes := make([][]byte, 0, len(entries))
+	for _, e := range entries {
+		identityHashes = append(identityHashes, e.identityHash)
+	}
+	data, err := t.readLeafData(ctx, identityHashes)
+	if err != nil {
+		return nil, err
+	}
+	sequenced := make(map[string]bool)
+	iter := t.ts.session.Query(selectLeafSequenceCQL, t.treeID, identityHashes, t.readRev).WithContext(ctx).Iter()
+	var identityHash []byte
+	for iter.Scan(&identityHash) {
+		sequenced[string(identityHash)] = true
+		identityHash = nil
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+
+	label := t.label()
+	leaves := make([]*trillian.LogLeaf, 0, len(entries))
+	for _, e := range entries {
+		leaf, ok := data[string(e.identityHash)]
+		_, dequeued := t.dequeued[string(e.identityHash)]
+		switch {
+		case !ok:
+			if time.Since(time.Unix(0, e.nanos)) > orphanAge {
+				t.skipped = append(t.skipped, e)
+				skippedCounter.Inc(label)
+			}
+			continue
+		case dequeued, sequenced[string(e.identityHash)]:
+			t.skipped = append(t.skipped, e)
+			skippedCounter.Inc(label)
+			continue
+		}
+		t.dequeued[string(e.identityHash)] = e
+		leaves = append(leaves, leaf)
+	}
+	dequeuedCounter.Add(float64(len(leaves)), label)
+	return leaves, nil
+}
+
+func (t *logTX) UpdateSequencedLeaves(ctx context.Context, leaves []*trillian.LogLeaf) error {
+	if t.readonly {
+		return ErrWrongTXType
+	}
+	for _, leaf := range leaves {
+		// This should fail on insert but catch it early
+		if got, want := len(leaf.LeafIdentityHash), t.hashSize; got != want {
+			return fmt.Errorf("sequenced leaf has incorrect hash size: got %v, want %v", got, want)
+		}
+		if _, ok := t.dequeued[string(leaf.LeafIdentityHash)]; !ok {
+			return fmt.Errorf("attempted to update unknown leaf %x", leaf.LeafIdentityHash)
+		}
+		if _, err := ptypes.Timestamp(leaf.IntegrateTimestamp); err != nil {
+			return fmt.Errorf("got invalid integrate timestamp: %v", err)
+		}
+		t.sequenced = append(t.sequenced, leaf)
+		t.writes.Leaves = append(t.writes.Leaves, seqWrite{Index: leaf.LeafIndex, IdentityHash: leaf.LeafIdentityHash, MerkleHash: leaf.MerkleLeafHash})
+	}
+	return nil
+}
+
+// GetLeavesByIndex returns the requested leaves. Indices at or beyond the
+// size of the tree as of the latest tree head are ignored.
+func (t *logTX) GetLeavesByIndex(ctx context.Context, indices []int64) ([]*trillian.LogLeaf, error) {
+	leaves, err := t.readSequenced(ctx, indices)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*trillian.LogLeaf, 0, len(indices))
+	for _, idx := range indices {
+		if leaf, ok := leaves[idx]; ok {
+			ret = append(ret, leaf)
+		}
+	}
+	return ret, nil
+}
+
+func (t *logTX) GetLeavesByRange(ctx context.Context, start, count int64) ([]*trillian.LogLeaf, error) {
+	if count <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid count %d", count)
+	}
+	if start < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid start %d", start)
+	}
+	if max := t.root.TreeSize - start; count > max {
+		count = max
+	}
+	if count <= 0 {
+		return []*trillian.LogLeaf{}, nil
+	}
+	indices := make([]int64, 0, count)
+	for i := int64(0); i < count; i++ {
+		indices = append(indices, start+i)
+	}
+	return t.GetLeavesByIndex(ctx, indices)
+}
+
+// GetLeavesByHash returns the leaves with the given Merkle leaf hashes. Hash
+// index rows may be left over from transactions which never committed, so
+// only leaves whose hashes match are returned.
+func (t *logTX) GetLeavesByHash(ctx context.Context, leafHashes [][]byte, orderBySequence bool) ([]*trillian.LogLeaf, error) {
+	var indices []int64
+	want := make(map[string]bool)
+	for _, hash := range leafHashes {
+		want[string(hash)] = true
+		iter := t.ts.session.Query(selectLeafHashIndexCQL, t.treeID, hash).WithContext(ctx).Iter()
+		var idx int64
+		for iter.Scan(&idx) {
+			indices = append(indices, idx)
+		}
+		if err := iter.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if orderBySequence {
+		sort.Slice(indices, func(i, j int) bool { return indices[i] < indices[j] })
+	}
+	leaves, err := t.GetLeavesByIndex(ctx, indices)
+	if err != nil {
+		return nil, err
+	}
+	ret := leaves[:0]
+	for _, leaf := range leaves {
+		if want[string(leaf.MerkleLeafHash)] {
+			ret = append(ret, leaf)
+		}
+	}
+	return ret, nil
+}
+
+// readSequenced reads the sequenced leaves at the given indices, along with
+// their data, and returns them by leaf index.
+func (t *logTX) readSequenced(ctx context.Context, indices []int64) (map[int64]*trillian.LogLeaf, error) {
+	buckets := make(map[int64][]int64)
+	for _, idx := range indices {
+		if idx >= 0 && idx < t.root.TreeSize {
+			b := sequenceBucket(idx)
+			buckets[b] = append(buckets[b], idx)
+		}
+	}
+
+	ret := make(map[int64]*trillian.LogLeaf)
+	var identityHashes [][]byte
+	for bucket, idxs := range buckets {
+		iter := t.ts.session.Query(selectSequencedLeavesCQL, t.treeID, bucket, idxs).WithContext(ctx).Iter()
+		var seq, integrateNanos int64
+		var identityHash, merkleHash []byte
+		for iter.Scan(&seq, &identityHash, &merkleHash, &integrateNanos) {
+			leaf := &trillian.LogLeaf{
+				LeafIndex:        seq,
+				LeafIdentityHash: identityHash,
+				MerkleLeafHash:   merkleHash,
+			}
+			var err error
+			if leaf.IntegrateTimestamp, err = ptypes.TimestampProto(time.Unix(0, integrateNanos)); err != nil {
+				iter.Close()
+				return nil, err
+			}
+			ret[seq] = leaf
+			identityHashes = append(identityHashes, identityHash)
+			identityHash, merkleHash = nil, nil
+		}
+		if err := iter.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if len(identityHashes) == 0 {
+		return ret, nil
+	}
+
+	data, err := t.readLeafData(ctx, identityHashes)
+	if err != nil {
+		return nil, err
+	}
+
+	for seq, leaf := range ret {
+		d, ok := data[string(leaf.LeafIdentityHash)]
+		if !ok {
+			return nil, fmt.Errorf("no data for leaf %x", leaf.LeafIdentityHash)
+		}
+		leaf.LeafValue = d.LeafValue
+		leaf.ExtraData = d.ExtraData
+		leaf.QueueTimestamp = d.QueueTimestamp
+		ret[seq] = leaf
+	}
+	return ret, nil
+}
+
+// readLeafData returns leaves holding the stored data for the given identity
+// hashes, by identity hash. Hashes without data are left out.
+func (t *logTX) readLeafData(ctx context.Context, identityHashes [][]byte) (map[string]*trillian.LogLeaf, error) {
+	data := make(map[string]*trillian.LogLeaf)
+	iter := t.ts.session.Query(selectLeafDataCQL, t.treeID, identityHashes).WithContext(ctx).Iter()
+	var identityHash, value, extra, merkleHash []byte
+	var queueNanos int64
+	for iter.Scan(&identityHash, &value, &extra, &merkleHash, &queueNanos) {
+		leaf, err := leafFromData(identityHash, value, extra, merkleHash, queueNanos)
+		if err != nil {
+			iter.Close()
+			return nil, err
+		}
+		data[string(identityHash)] = leaf
+		identityHash, value, extra, merkleHash = nil, nil, nil, nil
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (t *logTX) GetActiveLogIDs(ctx context.Context) ([]int64, error) {
+	return getActiveLogIDs(ctx, t.ls.admin)
+}
+
+// writeSequenced stores the leaves sequenced by the transaction, along with
+// their hash index and sequence entries.
+func (t *logTX) writeSequenced(ctx context.Context) error {
+	for _, leaf := range t.sequenced {
+		iTS, err := ptypes.Timestamp(leaf.IntegrateTimestamp)
+		if err != nil {
+			return err
+		}
+		err = t.ts.session.Query(insertSequencedLeafCQL,
+			t.treeID, sequenceBucket(leaf.LeafIndex), leaf.LeafIndex, leaf.LeafIdentityHash, leaf.MerkleLeafHash, iTS.UnixNano()).WithContext(ctx).Exec()
+		if err != nil {
+			return err
+		}
+		if err := t.ts.session.Query(insertLeafHashIndexCQL, t.treeID, leaf.MerkleLeafHash, leaf.LeafIndex).WithContext(ctx).Exec(); err != nil {
+			return err
+		}
+		if err := t.ts.session.Query(insertLeafSequenceCQL, t.treeID, leaf.LeafIdentityHash, t.writeRev, leaf.LeafIndex).WithContext(ctx).Exec(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Commit applies the writes made by the transaction, after claiming its write
+// revision. They're only made visible by the tree head, which is stored last
+// with a lightweight transaction. If Commit returns an error the transaction
+// may be retried from scratch.
+func (t *logTX) Commit() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return ErrTransactionClosed
+	}
+	t.closed = true
+	if t.readonly {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commitTimeout)
+	defer cancel()
+	var head []byte
+	if t.newRoot != nil {
+		var err error
+		if head, err = proto.Marshal(t.newRoot); err != nil {
+			return err
+		}
+	}
+	if err := t.commitWrites(ctx, head, t.writeSequenced); err != nil {
+		if err == ErrRevisionConflict {
+			commitConflicts.Inc(t.label())
+		}
+		return err
+	}
+
+	// Removing sequenced leaves from the queue can safely fail, as
+	// DequeueLeaves skips the entries of leaves which are already sequenced.
+	// Leaves are only sequenced if the transaction stored a tree head.
+	entries := t.skipped
+	if head != nil {
+		for _, leaf := range t.sequenced {
+			entries = append(entries, t.dequeued[string(leaf.LeafIdentityHash)])
+		}
+	}
+	for _, e := range entries {
+		if err := e.delete(ctx, t.ts.session, t.treeID); err != nil {
+			glog.Warningf("%v: failed to remove queue entry of leaf %x: %v", t.treeID, e.identityHash, err)
+		}
+	}
+	return nil
+}
+
+func (t *logTX) Rollback() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return ErrTransactionClosed
+	}
+	t.closed = true
+	return nil
+}
+
+func (t *logTX) Close() error {
+	if t.IsOpen() {
+		if err := t.Rollback(); err != nil && err != ErrTransactionClosed {
+			glog.Warningf("Rollback error on Close(): %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// readOnlyLogTX implements storage.ReadOnlyLogTX.
+type readOnlyLogTX struct {
+	ls *logStorage
+}
+
+func (t *readOnlyLogTX) Commit() error {
+	return nil
+}
+
+func (t *readOnlyLogTX) Rollback() error {
+	return nil
+}
+
+func (t *readOnlyLogTX) Close() error {
+	return nil
+}
+
+func (t *readOnlyLogTX) GetActiveLogIDs(ctx context.Context) ([]int64, error) {
+	return getActiveLogIDs(ctx, t.ls.admin)
+}
+
+// GetUnsequencedCounts scans the whole Unsequenced table.
+func (t *readOnlyLogTX) GetUnsequencedCounts(ctx context.Context) (storage.CountByLogID, error) {
+	ret := make(storage.CountByLogID)
+	iter := t.ls.session.Query(selectUnsequencedTreeIDsCQL).WithContext(ctx).Iter()
+	var id int64
+	for iter.Scan(&id) {
+		ret[id]++
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// getActiveLogIDs returns the IDs of all non-deleted logs in the ACTIVE state.
+func getActiveLogIDs(ctx context.Context, admin storage.AdminStorage) ([]int64, error) {
+	trees, err := storage.ListTrees(ctx, admin, false /* includeDeleted */)
+	if err != nil {
+		return nil, err
+	}
+	ids := []int64{}
+	for _, tree := range trees {
+		if tree.TreeType == trillian.TreeType_LOG && tree.TreeState == trillian.TreeState_ACTIVE {
+			ids = append(ids, tree.TreeId)
+		}
+	}
+	return ids, nil
+}
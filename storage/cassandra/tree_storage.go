@@ -0,0 +1,434 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cassandra provides a Cassandra/ScyllaDB-based storage layer
+// implementation. The expected schema is in storage.cql.
+//
+// Cassandra has no multi-partition transactions, so, like the Bigtable
+// implementation, this one relies on the following invariants instead:
+//   - all data written by a sequencing transaction is keyed by the
+//     transaction's write revision, or by leaf index beyond the current tree
+//     size;
+//   - before writing any of it, a transaction claims its write revision with
+//     a lightweight transaction (INSERT ... IF NOT EXISTS) on the revision's
+//     TreeHeads row, which fails if another transaction has claimed it. The
+//     claim records the rows the transaction is about to write, so that a
+//     later transaction can remove them if the claimant dies before
+//     committing (see claimLease);
+//   - the tree head for a revision is written last, with a lightweight
+//     transaction which fails if the transaction no longer holds the claim.
+//     This is the commit point of a transaction;
+//   - readers only look at data at or below the revision (and tree size) of
+//     the latest tree head, so partially written transactions are invisible.
+//
+// Subtrees are stored as wide rows, with one partition per (treeID,
+// subtreeID) and one clustering row per revision, newest first.
+package cassandra
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/golang/glog"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/cache"
+	"github.com/google/trillian/storage/storagepb"
+)
+
+const (
+	selectSubtreeCQL = `SELECT Nodes FROM Subtrees
+		WHERE TreeId = ? AND SubtreeId = ? AND Revision <= ? LIMIT 1`
+	insertSubtreeCQL = `INSERT INTO Subtrees(TreeId, SubtreeId, Revision, Nodes)
+		VALUES(?, ?, ?, ?)`
+	deleteSubtreeCQL = `DELETE FROM Subtrees
+		WHERE TreeId = ? AND SubtreeId = ? AND Revision = ?`
+	selectTreeHeadsCQL = `SELECT Root FROM TreeHeads WHERE TreeId = ?`
+	insertClaimCQL     = `INSERT INTO TreeHeads(TreeId, Revision, Claim, Intent)
+		VALUES(?, ?, ?, ?) IF NOT EXISTS`
+	selectClaimCQL = `SELECT Root, Claim, WRITETIME(Claim), Intent FROM TreeHeads
+		WHERE TreeId = ? AND Revision = ?`
+	takeOverClaimCQL = `UPDATE TreeHeads SET Claim = ?, Intent = ?
+		WHERE TreeId = ? AND Revision = ? IF Claim = ? AND Root = null`
+	updateTreeHeadCQL = `UPDATE TreeHeads SET Root = ?
+		WHERE TreeId = ? AND Revision = ? IF Claim = ?`
+
+	// commitTimeout bounds the writes made by a committing transaction.
+	commitTimeout = 30 * time.Second
+	// claimLease is how long a transaction's claim on its write revision
+	// stays valid without a tree head. Once it has passed, the claimant can
+	// no longer be writing (it gives up after commitTimeout), so the
+	// revision can be claimed by another transaction, which first removes
+	// whatever the claimant wrote. It's compared against the coordinator's
+	// write time of the claim, so it includes a margin for clock skew.
+	claimLease = 2 * commitTimeout
+)
+
+var (
+	// ErrTransactionClosed is returned by interface methods when an operation is
+	// attempted on a transaction whose Commit or Rollback methods have
+	// previously been called.
+	ErrTransactionClosed = errors.New("transaction is closed")
+
+	// ErrWrongTXType is returned when a write operation is attempted with a
+	// read-only transaction.
+	ErrWrongTXType = errors.New("mutating method called on read-only transaction")
+
+	// ErrRevisionConflict is returned by Commit when another writer has
+	// already claimed, or stored a tree head at, the transaction's write
+	// revision.
+	ErrRevisionConflict = errors.New("write revision already claimed")
+)
+
+// treeStorage provides a shared base for the Cassandra-backed storage
+// implementations.
+type treeStorage struct {
+	session *gocql.Session
+	admin   storage.AdminStorage
+}
+
+// treeTX is the part of a tree transaction common to logs and maps.
+type treeTX struct {
+	ts     *treeStorage
+	treeID int64
+	cache  cache.SubtreeCache
+
+	// mu guards closed.
+	mu       sync.RWMutex
+	closed   bool
+	readonly bool
+
+	readRev  int64
+	writeRev int64
+
+	// subtrees holds the subtrees written by Commit.
+	subtrees []*storagepb.SubtreeProto
+	// writes describes the rows written at writeRev on Commit.
+	writes revWrites
+	// claim identifies this transaction's claim on writeRev, once made.
+	claim string
+}
+
+// revWrites describes the rows a transaction writes at its write revision.
+// It's recorded in the transaction's claim on the revision.
+type revWrites struct {
+	Subtrees [][]byte   `json:"s,omitempty"`
+	Leaves   []seqWrite `json:"l,omitempty"`
+}
+
+// seqWrite identifies the rows written for a sequenced leaf.
+type seqWrite struct {
+	Index        int64  `json:"i"`
+	IdentityHash []byte `json:"h"`
+	MerkleHash   []byte `json:"m"`
+}
+
+// add appends the rows described by o to w.
+func (w *revWrites) add(o revWrites) {
+	w.Subtrees = append(w.Subtrees, o.Subtrees...)
+	w.Leaves = append(w.Leaves, o.Leaves...)
+}
+
+// subtreeKey returns the SubtreeId of the subtree with the given prefix. The
+// prefix is preceded by its length, as the root subtree's prefix is empty and
+// Cassandra doesn't allow empty partition key components.
+func subtreeKey(prefix []byte) []byte {
+	return append([]byte{byte(len(prefix))}, prefix...)
+}
+
+// getSubtree retrieves the most recent subtree specified by id at (or below)
+// the requested revision. If no such subtree exists it returns nil.
+func (t *treeTX) getSubtree(ctx context.Context, rev int64, id storage.NodeID) (*storagepb.SubtreeProto, error) {
+	if id.PrefixLenBits%8 != 0 {
+		return nil, fmt.Errorf("id.PrefixLenBits (%d) is not a multiple of 8; it cannot be a subtree prefix", id.PrefixLenBits)
+	}
+	var b []byte
+	err := t.ts.session.Query(selectSubtreeCQL, t.treeID, subtreeKey(id.Path[:id.PrefixLenBits/8]), rev).WithContext(ctx).Scan(&b)
+	switch {
+	case err == gocql.ErrNotFound:
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	var st storagepb.SubtreeProto
+	if err := proto.Unmarshal(b, &st); err != nil {
+		return nil, err
+	}
+	if st.Prefix == nil {
+		st.Prefix = []byte{}
+	}
+	return &st, nil
+}
+
+// GetMerkleNodes returns the requested set of nodes at, or before, the
+// specified tree revision.
+func (t *treeTX) GetMerkleNodes(ctx context.Context, rev int64, ids []storage.NodeID) ([]storage.Node, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.closed {
+		return nil, ErrTransactionClosed
+	}
+
+	return t.cache.GetNodes(ids, t.getSubtreesAtRev(ctx, rev))
+}
+
+// getSubtreesAtRev returns a function reading subtrees at, or before, the
+// specified tree revision, in parallel.
+func (t *treeTX) getSubtreesAtRev(ctx context.Context, rev int64) cache.GetSubtreesFunc {
+	return func(ids []storage.NodeID) ([]*storagepb.SubtreeProto, error) {
+		type result struct {
+			st  *storagepb.SubtreeProto
+			err error
+		}
+		c := make(chan result, len(ids))
+		for _, id := range ids {
+			id := id
+			go func() {
+				st, err := t.getSubtree(ctx, rev, id)
+				c <- result{st, err}
+			}()
+		}
+		ret := make([]*storagepb.SubtreeProto, 0, len(ids))
+		for range ids {
+			r := <-c
+			if r.err != nil {
+				return nil, r.err
+			}
+			if r.st != nil {
+				ret = append(ret, r.st)
+			}
+		}
+		return ret, nil
+	}
+}
+
+// SetMerkleNodes stores the provided merkle nodes at the writeRevision of the
+// transaction.
+func (t *treeTX) SetMerkleNodes(ctx context.Context, nodes []storage.Node) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.closed {
+		return ErrTransactionClosed
+	}
+	if t.readonly {
+		return ErrWrongTXType
+	}
+
+	for _, n := range nodes {
+		err := t.cache.SetNodeHash(n.NodeID, n.Hash,
+			func(id storage.NodeID) (*storagepb.SubtreeProto, error) {
+				return t.getSubtree(ctx, t.writeRev-1, id)
+			})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadRevision returns the tree revision of the latest tree head at the time
+// the transaction was started.
+func (t *treeTX) ReadRevision() int64 {
+	return t.readRev
+}
+
+// WriteRevision returns the tree revision at which any tree-modifying
+// operations will write.
+func (t *treeTX) WriteRevision() int64 {
+	return t.writeRev
+}
+
+// IsOpen returns true iff neither Commit nor Rollback have been called.
+func (t *treeTX) IsOpen() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return !t.closed
+}
+
+// commitWrites claims the transaction's write revision, then writes its
+// subtrees and calls write to apply any other rows described by t.writes,
+// and then stores head, which makes them visible. If head is nil there's
+// nothing to commit, as nothing written at the revision would ever be read.
+func (t *treeTX) commitWrites(ctx context.Context, head []byte, write func(context.Context) error) error {
+	if head == nil {
+		return nil
+	}
+	err := t.cache.Flush(func(sts []*storagepb.SubtreeProto) error {
+		t.subtrees = append(t.subtrees, sts...)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, st := range t.subtrees {
+		if st != nil {
+			t.writes.Subtrees = append(t.writes.Subtrees, subtreeKey(st.Prefix))
+		}
+	}
+	if err := t.claimRevision(ctx); err != nil {
+		return err
+	}
+	if err := t.writeSubtrees(ctx); err != nil {
+		return err
+	}
+	if err := write(ctx); err != nil {
+		return err
+	}
+	return t.storeHead(ctx, head)
+}
+
+// writeSubtrees writes the subtrees modified by this transaction.
+func (t *treeTX) writeSubtrees(ctx context.Context) error {
+	for _, st := range t.subtrees {
+		if st == nil {
+			continue
+		}
+		b, err := proto.Marshal(st)
+		if err != nil {
+			return err
+		}
+		if err := t.ts.session.Query(insertSubtreeCQL, t.treeID, subtreeKey(st.Prefix), t.writeRev, b).WithContext(ctx).Exec(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// claimRevision claims the transaction's write revision, recording t.writes
+// in the claim. It fails with ErrRevisionConflict if the revision has a tree
+// head, or a claim which is still within its lease. An expired claim is taken
+// over, and the rows its transaction wrote are removed.
+func (t *treeTX) claimRevision(ctx context.Context) error {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return err
+	}
+	t.claim = hex.EncodeToString(token)
+	intent, err := json.Marshal(t.writes)
+	if err != nil {
+		return err
+	}
+	applied, err := t.ts.session.Query(insertClaimCQL, t.treeID, t.writeRev, t.claim, intent).WithContext(ctx).MapScanCAS(map[string]interface{}{})
+	if err != nil {
+		return err
+	}
+	if applied {
+		return nil
+	}
+
+	var root, staleIntent []byte
+	var stale string
+	var claimedMicros int64
+	err = t.ts.session.Query(selectClaimCQL, t.treeID, t.writeRev).WithContext(ctx).Consistency(gocql.Consistency(gocql.Serial)).Scan(&root, &stale, &claimedMicros, &staleIntent)
+	if err != nil {
+		return err
+	}
+	claimed := time.Unix(0, claimedMicros*int64(time.Microsecond))
+	if root != nil || stale == "" || time.Since(claimed) < claimLease {
+		return ErrRevisionConflict
+	}
+	var staleWrites revWrites
+	if err := json.Unmarshal(staleIntent, &staleWrites); err != nil {
+		return fmt.Errorf("malformed claim on revision %d: %v", t.writeRev, err)
+	}
+
+	// The new claim records the stale transaction's rows as well as this
+	// one's, so that they're removed by whoever takes it over in turn if
+	// removing them here fails.
+	all := t.writes
+	all.add(staleWrites)
+	if intent, err = json.Marshal(all); err != nil {
+		return err
+	}
+	applied, err = t.ts.session.Query(takeOverClaimCQL, t.claim, intent, t.treeID, t.writeRev, stale).WithContext(ctx).MapScanCAS(map[string]interface{}{})
+	if err != nil {
+		return err
+	}
+	if !applied {
+		return ErrRevisionConflict
+	}
+	glog.Warningf("%v: took over expired claim on revision %d made at %v", t.treeID, t.writeRev, claimed)
+	return t.removeWrites(ctx, staleWrites)
+}
+
+// removeWrites deletes the rows described by w written at the transaction's
+// write revision.
+func (t *treeTX) removeWrites(ctx context.Context, w revWrites) error {
+	for _, id := range w.Subtrees {
+		if err := t.ts.session.Query(deleteSubtreeCQL, t.treeID, id, t.writeRev).WithContext(ctx).Exec(); err != nil {
+			return err
+		}
+	}
+	for _, l := range w.Leaves {
+		for _, q := range []*gocql.Query{
+			t.ts.session.Query(deleteSequencedLeafCQL, t.treeID, sequenceBucket(l.Index), l.Index),
+			t.ts.session.Query(deleteLeafHashIndexCQL, t.treeID, l.MerkleHash, l.Index),
+			t.ts.session.Query(deleteLeafSequenceCQL, t.treeID, l.IdentityHash, t.writeRev),
+		} {
+			if err := q.WithContext(ctx).Exec(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// storeHead sets the serialized tree head for the transaction's write
+// revision, failing with ErrRevisionConflict if the transaction's claim on
+// the revision has been taken over.
+func (t *treeTX) storeHead(ctx context.Context, head []byte) error {
+	applied, err := t.ts.session.Query(updateTreeHeadCQL, head, t.treeID, t.writeRev, t.claim).WithContext(ctx).MapScanCAS(map[string]interface{}{})
+	if err != nil {
+		return err
+	}
+	if !applied {
+		return ErrRevisionConflict
+	}
+	return nil
+}
+
+// latestHead reads the serialized newest tree head of treeID, or returns
+// storage.ErrTreeNeedsInit if there is none. The read is done at SERIAL
+// consistency, so that it observes any committed lightweight transaction.
+// Rows of revisions which are claimed but have no head yet are skipped; there
+// is usually at most one of them.
+func (t *treeStorage) latestHead(ctx context.Context, treeID int64) ([]byte, error) {
+	iter := t.session.Query(selectTreeHeadsCQL, treeID).WithContext(ctx).Consistency(gocql.Consistency(gocql.Serial)).PageSize(4).Iter()
+	var head []byte
+	for iter.Scan(&head) {
+		if head != nil {
+			break
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+	if head == nil {
+		glog.Warningf("no head found for treeID %v", treeID)
+		return nil, storage.ErrTreeNeedsInit
+	}
+	return head, nil
+}
+
+func checkDatabaseAccessible(ctx context.Context, session *gocql.Session) error {
+	var v string
+	return session.Query("SELECT release_version FROM system.local").WithContext(ctx).Scan(&v)
+}
@@ -408,6 +408,24 @@ func newTreeInfo(tree *trillian.Tree, treeID int64, now time.Time) (*spannerpb.T
 	if err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "malformed MaxRootDuration: %v", err)
 	}
+	if tree.RootTimestampPrecision != trillian.RootTimestampPrecision_NANOSECOND_PRECISION {
+		return nil, status.Errorf(codes.InvalidArgument, "root_timestamp_precision %v not supported", tree.RootTimestampPrecision)
+	}
+	if len(tree.LeafIndexes) > 0 {
+		return nil, status.Error(codes.InvalidArgument, "leaf_indexes not supported")
+	}
+	if tree.LeafFilter != nil {
+		return nil, status.Error(codes.InvalidArgument, "leaf_filter not supported")
+	}
+	if len(tree.AdditionalSigningKeys) > 0 {
+		return nil, status.Error(codes.InvalidArgument, "additional_signing_keys not supported")
+	}
+	if d := tree.MaxMergeDelay; d.GetSeconds() != 0 || d.GetNanos() != 0 {
+		return nil, status.Error(codes.InvalidArgument, "max_merge_delay not supported")
+	}
+	if tree.ValidityWindow != nil {
+		return nil, status.Error(codes.InvalidArgument, "validity_window not supported")
+	}
 
 	info := &spannerpb.TreeInfo{
 		TreeId:                treeID,
@@ -423,6 +441,11 @@ func newTreeInfo(tree *trillian.Tree, treeID int64, now time.Time) (*spannerpb.T
 		PrivateKey:            tree.GetPrivateKey(),
 		PublicKeyDer:          tree.GetPublicKey().GetDer(),
 		MaxRootDurationMillis: int64(maxRootDuration / time.Millisecond),
+		Labels:                tree.Labels,
+		WriteVerification:     int32(tree.WriteVerification),
+	}
+	if err := setMaintenance(info, tree.Maintenance); err != nil {
+		return nil, err
 	}
 
 	switch tree.TreeType {
@@ -508,6 +531,18 @@ func (t *adminTX) UpdateTree(ctx context.Context, treeID int64, updateFunc func(
 	if err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "malformed MaxRootDuration: %v", err)
 	}
+	if tree.RootTimestampPrecision != trillian.RootTimestampPrecision_NANOSECOND_PRECISION {
+		return nil, status.Errorf(codes.InvalidArgument, "root_timestamp_precision %v not supported", tree.RootTimestampPrecision)
+	}
+	if len(tree.AdditionalSigningKeys) > 0 {
+		return nil, status.Error(codes.InvalidArgument, "additional_signing_keys not supported")
+	}
+	if d := tree.MaxMergeDelay; d.GetSeconds() != 0 || d.GetNanos() != 0 {
+		return nil, status.Error(codes.InvalidArgument, "max_merge_delay not supported")
+	}
+	if tree.ValidityWindow != nil {
+		return nil, status.Error(codes.InvalidArgument, "validity_window not supported")
+	}
 
 	// Update (just) the mutable fields in treeInfo.
 	now := TimeNow()
@@ -517,6 +552,11 @@ func (t *adminTX) UpdateTree(ctx context.Context, treeID int64, updateFunc func(
 	info.UpdateTimeNanos = now.UnixNano()
 	info.MaxRootDurationMillis = int64(maxRootDuration / time.Millisecond)
 	info.PrivateKey = tree.PrivateKey
+	info.Labels = tree.Labels
+	info.WriteVerification = int32(tree.WriteVerification)
+	if err := setMaintenance(info, tree.Maintenance); err != nil {
+		return nil, err
+	}
 
 	if err := t.updateTreeInfo(ctx, info); err != nil {
 		return nil, err
@@ -600,15 +640,18 @@ func (t *adminTX) HardDeleteTree(ctx context.Context, treeID int64) error {
 
 	// Due to cloud spanner sizing recommendations, we don't interleave our tables
 	// which means no ON DELETE CASCADE goodies for us, so we have to
-	// transactionally delete related data from all tables.
+	// transactionally delete related data from all tables. CurrentTreeHeads,
+	// the exception, is deleted with TreeRoots.
 	return stx.BufferWrite([]*spanner.Mutation{
 		spanner.Delete("TreeRoots", spanner.Key{info.TreeId}),
 		spanner.Delete("TreeHeads", spanner.Key{info.TreeId}.AsPrefix()),
+		spanner.Delete("TreeHeadShards", spanner.Key{info.TreeId}.AsPrefix()),
 		spanner.Delete("SubtreeData", spanner.Key{info.TreeId}.AsPrefix()),
 		spanner.Delete("LeafData", spanner.Key{info.TreeId}.AsPrefix()),
 		spanner.Delete("SequencedLeafData", spanner.Key{info.TreeId}.AsPrefix()),
 		spanner.Delete("Unsequenced", spanner.Key{info.TreeId}.AsPrefix()),
 		spanner.Delete("MapLeafData", spanner.Key{info.TreeId}.AsPrefix()),
+		spanner.Delete("SplitCommits", spanner.Key{info.TreeId}),
 	})
 }
 
@@ -630,6 +673,21 @@ func (t *adminTX) UndeleteTree(ctx context.Context, treeID int64) (*trillian.Tre
 	return toTrillianTree(info)
 }
 
+// setMaintenance sets the maintenance fields of info to mode.
+func setMaintenance(info *spannerpb.TreeInfo, mode *trillian.MaintenanceMode) error {
+	info.Maintenance = mode != nil
+	info.MaintenanceRetryAfterMillis = 0
+	info.MaintenanceReason = mode.GetReason()
+	if mode.GetRetryAfter() != nil {
+		retryAfter, err := ptypes.Duration(mode.RetryAfter)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "malformed maintenance retry_after: %v", err)
+		}
+		info.MaintenanceRetryAfterMillis = int64(retryAfter / time.Millisecond)
+	}
+	return nil
+}
+
 func toTrillianTree(info *spannerpb.TreeInfo) (*trillian.Tree, error) {
 	createdPB, err := ptypes.TimestampProto(time.Unix(0, info.CreateTimeNanos))
 	if err != nil {
@@ -648,6 +706,14 @@ func toTrillianTree(info *spannerpb.TreeInfo) (*trillian.Tree, error) {
 		PrivateKey:      info.PrivateKey,
 		PublicKey:       &keyspb.PublicKey{Der: info.PublicKeyDer},
 		MaxRootDuration: ptypes.DurationProto(time.Duration(info.MaxRootDurationMillis) * time.Millisecond),
+		Labels:          info.Labels,
+	}
+	tree.WriteVerification = trillian.WriteVerification(info.WriteVerification)
+	if info.Maintenance {
+		tree.Maintenance = &trillian.MaintenanceMode{
+			RetryAfter: ptypes.DurationProto(time.Duration(info.MaintenanceRetryAfterMillis) * time.Millisecond),
+			Reason:     info.MaintenanceReason,
+		}
 	}
 
 	ts, ok := treeStateReverseMap[info.TreeState]
@@ -729,5 +795,10 @@ func validateLogStorageConfig(config *spannerpb.LogStorageConfig) error {
 	if config.NumMerkleBuckets < 1 || config.NumMerkleBuckets > 256 {
 		return status.Errorf(codes.InvalidArgument, "NumMerkleBuckets = %v, want a number in range [1, 256]", config.NumMerkleBuckets)
 	}
+	if len(config.StrataDepths) > 0 {
+		if err := storage.ValidateStrata(trillian.TreeType_LOG, config.StrataDepths); err != nil {
+			return status.Errorf(codes.InvalidArgument, "StrataDepths = %v: %v", config.StrataDepths, err)
+		}
+	}
 	return nil
 }
@@ -0,0 +1,478 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudspanner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage/cloudspanner/spannerpb"
+)
+
+const (
+	// DefaultChangeStream is the name of the change stream declared in
+	// spanner.sdl.
+	DefaultChangeStream = "LogChanges"
+	// DefaultChangeStreamHeartbeat is how often Spanner reports the progress of
+	// idle change stream partitions by default.
+	DefaultChangeStreamHeartbeat = 10 * time.Second
+
+	treeHeadsTbl      = "TreeHeads"
+	treeHeadShardsTbl = "TreeHeadShards"
+	modInsert         = "INSERT"
+
+	// readChangeStreamSQL is the query reading one partition of a change
+	// stream, see https://cloud.google.com/spanner/docs/change-streams/details.
+	readChangeStreamSQL = `SELECT ChangeRecord FROM READ_%s(
+		start_timestamp => @start_timestamp,
+		end_timestamp => NULL,
+		partition_token => @partition_token,
+		heartbeat_milliseconds => @heartbeat_milliseconds)`
+)
+
+// ChangeHandler receives the leaves and tree heads read from a change stream.
+// Its methods are called concurrently for different partitions of the stream.
+type ChangeHandler interface {
+	// SequencedLeaf is called once a leaf has been integrated into the log
+	// with the given ID.
+	SequencedLeaf(ctx context.Context, logID int64, leaf *trillian.LogLeaf) error
+	// SignedLogRoot is called once a new tree head has been stored for a log.
+	SignedLogRoot(ctx context.Context, root *trillian.SignedLogRoot) error
+}
+
+// ChangeStreamOptions are the options of a ChangeStreamReader.
+type ChangeStreamOptions struct {
+	// Stream is the name of the change stream on SequencedLeafData, TreeHeads
+	// and TreeHeadShards. Defaults to DefaultChangeStream.
+	Stream string
+	// StartTime is the commit time from which changes are read. Defaults to
+	// the time the reader starts.
+	StartTime time.Time
+	// Heartbeat is how often idle partitions report their progress. Defaults
+	// to DefaultChangeStreamHeartbeat.
+	Heartbeat time.Duration
+}
+
+// ChangeStreamReader reads the leaves and tree heads committed to the log
+// tables through a Spanner change stream, and passes them to a ChangeHandler.
+//
+// Delivery is at-least-once: a reader restarted from the Watermark of a
+// previous one passes some changes to its handler again, and a handler
+// returning an error stops the reader.
+type ChangeStreamReader struct {
+	client  *spanner.Client
+	opts    ChangeStreamOptions
+	handler ChangeHandler
+
+	mu         sync.Mutex
+	partitions map[string]*partition
+	wg         sync.WaitGroup
+	errOnce    sync.Once
+	err        error
+	cancel     context.CancelFunc
+}
+
+// partition is the state of a change stream partition.
+type partition struct {
+	parents []string
+	// ts is the commit time up to which the changes of the partition have
+	// been handled.
+	ts      time.Time
+	started bool
+	done    bool
+}
+
+// NewChangeStreamReader returns a ChangeStreamReader passing the changes read
+// from the database of client to handler.
+func NewChangeStreamReader(client *spanner.Client, opts ChangeStreamOptions, handler ChangeHandler) *ChangeStreamReader {
+	if opts.Stream == "" {
+		opts.Stream = DefaultChangeStream
+	}
+	if opts.StartTime.IsZero() {
+		opts.StartTime = time.Now()
+	}
+	if opts.Heartbeat <= 0 {
+		opts.Heartbeat = DefaultChangeStreamHeartbeat
+	}
+	return &ChangeStreamReader{
+		client:     client,
+		opts:       opts,
+		handler:    handler,
+		partitions: make(map[string]*partition),
+	}
+}
+
+// Run reads the change stream until ctx is done or an error occurs, and
+// returns the error.
+func (r *ChangeStreamReader) Run(ctx context.Context) error {
+	ctx, r.cancel = context.WithCancel(ctx)
+	defer r.cancel()
+
+	// The initial query, with a NULL partition token, only returns the
+	// partitions of the stream.
+	r.mu.Lock()
+	r.partitions[""] = &partition{ts: r.opts.StartTime}
+	r.startLocked(ctx, "")
+	r.mu.Unlock()
+	r.wg.Wait()
+
+	if r.err != nil {
+		return r.err
+	}
+	return ctx.Err()
+}
+
+// Watermark returns the commit time up to which all changes have been passed
+// to the handler. A reader started from it doesn't miss any change.
+func (r *ChangeStreamReader) Watermark() time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	w := r.opts.StartTime
+	first := true
+	for _, p := range r.partitions {
+		if p.done {
+			continue
+		}
+		if first || p.ts.Before(w) {
+			w = p.ts
+			first = false
+		}
+	}
+	return w
+}
+
+// startLocked starts reading the partition with the given token. r.mu must be
+// held.
+func (r *ChangeStreamReader) startLocked(ctx context.Context, token string) {
+	p := r.partitions[token]
+	p.started = true
+	start := p.ts
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		if err := r.readPartition(ctx, token, start); err != nil {
+			r.errOnce.Do(func() {
+				r.err = fmt.Errorf("partition %q: %v", token, err)
+				r.cancel()
+			})
+			return
+		}
+		r.finish(ctx, token)
+	}()
+}
+
+// finish marks the partition with the given token as done, and starts its
+// children whose parents are all done, so that the changes to a key are
+// handled in commit order.
+func (r *ChangeStreamReader) finish(ctx context.Context, token string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.partitions[token].done = true
+	for t, p := range r.partitions {
+		if !p.started && r.parentsDoneLocked(p) {
+			r.startLocked(ctx, t)
+		}
+	}
+}
+
+// parentsDoneLocked returns true if all the parents of p which this reader
+// knows of are done. r.mu must be held.
+func (r *ChangeStreamReader) parentsDoneLocked(p *partition) bool {
+	for _, t := range p.parents {
+		if parent, ok := r.partitions[t]; ok && !parent.done {
+			return false
+		}
+	}
+	return true
+}
+
+// addChild records a child partition, which is started by finish. A child of
+// several partitions is reported by each of them, but only read once.
+func (r *ChangeStreamReader) addChild(start time.Time, c *childPartition) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.partitions[c.Token]; ok {
+		return
+	}
+	r.partitions[c.Token] = &partition{parents: c.ParentPartitionTokens, ts: start}
+}
+
+// progress records that the changes of a partition have been handled up to
+// ts.
+func (r *ChangeStreamReader) progress(token string, ts time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if p := r.partitions[token]; ts.After(p.ts) {
+		p.ts = ts
+	}
+}
+
+func (r *ChangeStreamReader) readPartition(ctx context.Context, token string, start time.Time) error {
+	stmt := spanner.NewStatement(fmt.Sprintf(readChangeStreamSQL, r.opts.Stream))
+	stmt.Params["start_timestamp"] = start
+	stmt.Params["partition_token"] = spanner.NullString{StringVal: token, Valid: token != ""}
+	stmt.Params["heartbeat_milliseconds"] = int64(r.opts.Heartbeat / time.Millisecond)
+
+	rows := r.client.Single().Query(ctx, stmt)
+	return rows.Do(func(row *spanner.Row) error {
+		var records []*changeRecord
+		if err := row.Columns(&records); err != nil {
+			return err
+		}
+		for _, rec := range records {
+			for _, dc := range rec.DataChangeRecord {
+				if err := r.handleDataChange(ctx, dc); err != nil {
+					return err
+				}
+				r.progress(token, dc.CommitTimestamp)
+			}
+			for _, hb := range rec.HeartbeatRecord {
+				r.progress(token, hb.Timestamp)
+			}
+			for _, cp := range rec.ChildPartitionsRecord {
+				for _, c := range cp.ChildPartitions {
+					r.addChild(cp.StartTimestamp, c)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (r *ChangeStreamReader) handleDataChange(ctx context.Context, dc *dataChangeRecord) error {
+	if dc.ModType != modInsert {
+		return nil
+	}
+	switch dc.TableName {
+	case seqDataTbl:
+		leaves, err := sequencedLeaves(dc)
+		if err != nil {
+			return err
+		}
+		if err := r.readLeafData(ctx, leaves); err != nil {
+			return err
+		}
+		for _, l := range leaves {
+			if err := r.handler.SequencedLeaf(ctx, l.treeID, l.leaf); err != nil {
+				return err
+			}
+		}
+	case treeHeadsTbl, treeHeadShardsTbl:
+		roots, err := signedLogRoots(dc)
+		if err != nil {
+			return err
+		}
+		for _, root := range roots {
+			if err := r.handler.SignedLogRoot(ctx, root); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readLeafData fills in the values of sequenced leaves, which are stored in
+// LeafData when they're queued.
+func (r *ChangeStreamReader) readLeafData(ctx context.Context, leaves []sequencedLeaf) error {
+	byTree := make(map[int64]map[string]*trillian.LogLeaf)
+	for _, l := range leaves {
+		if byTree[l.treeID] == nil {
+			byTree[l.treeID] = make(map[string]*trillian.LogLeaf)
+		}
+		byTree[l.treeID][string(l.leaf.LeafIdentityHash)] = l.leaf
+	}
+	for treeID, byID := range byTree {
+		ids := make([][]byte, 0, len(byID))
+		for id := range byID {
+			ids = append(ids, []byte(id))
+		}
+		err := readLeaves(ctx, r.client.Single(), treeID, ids, func(data *trillian.LogLeaf) {
+			l := byID[string(data.LeafIdentityHash)]
+			l.LeafValue = data.LeafValue
+			l.ExtraData = data.ExtraData
+			l.QueueTimestamp = data.QueueTimestamp
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The types below mirror the records of a change stream, see
+// https://cloud.google.com/spanner/docs/change-streams/details#change_streams_record_format.
+// The Spanner client requires a field for each of their columns.
+
+type changeRecord struct {
+	DataChangeRecord      []*dataChangeRecord      `spanner:"data_change_record"`
+	HeartbeatRecord       []*heartbeatRecord       `spanner:"heartbeat_record"`
+	ChildPartitionsRecord []*childPartitionsRecord `spanner:"child_partitions_record"`
+}
+
+type dataChangeRecord struct {
+	CommitTimestamp                      time.Time     `spanner:"commit_timestamp"`
+	RecordSequence                       string        `spanner:"record_sequence"`
+	ServerTransactionID                  string        `spanner:"server_transaction_id"`
+	IsLastRecordInTransactionInPartition bool          `spanner:"is_last_record_in_transaction_in_partition"`
+	TableName                            string        `spanner:"table_name"`
+	ColumnTypes                          []*columnType `spanner:"column_types"`
+	Mods                                 []*mod        `spanner:"mods"`
+	ModType                              string        `spanner:"mod_type"`
+	ValueCaptureType                     string        `spanner:"value_capture_type"`
+	NumberOfRecordsInTransaction         int64         `spanner:"number_of_records_in_transaction"`
+	NumberOfPartitionsInTransaction      int64         `spanner:"number_of_partitions_in_transaction"`
+	TransactionTag                       string        `spanner:"transaction_tag"`
+	IsSystemTransaction                  bool          `spanner:"is_system_transaction"`
+}
+
+type columnType struct {
+	Name            string           `spanner:"name"`
+	Type            spanner.NullJSON `spanner:"type"`
+	IsPrimaryKey    bool             `spanner:"is_primary_key"`
+	OrdinalPosition int64            `spanner:"ordinal_position"`
+}
+
+type mod struct {
+	Keys      spanner.NullJSON `spanner:"keys"`
+	NewValues spanner.NullJSON `spanner:"new_values"`
+	OldValues spanner.NullJSON `spanner:"old_values"`
+}
+
+type heartbeatRecord struct {
+	Timestamp time.Time `spanner:"timestamp"`
+}
+
+type childPartitionsRecord struct {
+	StartTimestamp  time.Time         `spanner:"start_timestamp"`
+	RecordSequence  string            `spanner:"record_sequence"`
+	ChildPartitions []*childPartition `spanner:"child_partitions"`
+}
+
+type childPartition struct {
+	Token                 string   `spanner:"token"`
+	ParentPartitionTokens []string `spanner:"parent_partition_tokens"`
+}
+
+// decodeMod decodes the keys and new values of m into v. Change streams
+// encode the values of INT64 columns as JSON strings, and those of BYTES
+// columns as base64, so the fields of v need tags accordingly.
+func decodeMod(m *mod, v interface{}) error {
+	for _, j := range []spanner.NullJSON{m.Keys, m.NewValues} {
+		if !j.Valid {
+			continue
+		}
+		b, err := json.Marshal(j.Value)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(b, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sequencedLeafMod is a row of SequencedLeafData.
+type sequencedLeafMod struct {
+	TreeID                  int64 `json:",string"`
+	SequenceNumber          int64 `json:",string"`
+	LeafIdentityHash        []byte
+	MerkleLeafHash          []byte
+	IntegrateTimestampNanos int64 `json:",string"`
+}
+
+type sequencedLeaf struct {
+	treeID int64
+	leaf   *trillian.LogLeaf
+}
+
+// sequencedLeaves returns the leaves inserted into SequencedLeafData by dc,
+// without their values.
+func sequencedLeaves(dc *dataChangeRecord) ([]sequencedLeaf, error) {
+	ret := make([]sequencedLeaf, 0, len(dc.Mods))
+	for _, m := range dc.Mods {
+		var row sequencedLeafMod
+		if err := decodeMod(m, &row); err != nil {
+			return nil, fmt.Errorf("failed to decode %s row: %v", dc.TableName, err)
+		}
+		integrateTimestamp, err := ptypes.TimestampProto(time.Unix(0, row.IntegrateTimestampNanos))
+		if err != nil {
+			return nil, fmt.Errorf("got invalid integrate timestamp: %v", err)
+		}
+		ret = append(ret, sequencedLeaf{
+			treeID: row.TreeID,
+			leaf: &trillian.LogLeaf{
+				LeafIndex:          row.SequenceNumber,
+				LeafIdentityHash:   row.LeafIdentityHash,
+				MerkleLeafHash:     row.MerkleLeafHash,
+				IntegrateTimestamp: integrateTimestamp,
+			},
+		})
+	}
+	return ret, nil
+}
+
+// treeHeadMod is a row of TreeHeads or TreeHeadShards.
+type treeHeadMod struct {
+	TreeID         int64 `json:",string"`
+	TimestampNanos int64 `json:",string"`
+	TreeSize       int64 `json:",string"`
+	RootHash       []byte
+	RootSignature  []byte
+	TreeRevision   int64 `json:",string"`
+	TreeMetadata   []byte
+}
+
+// signedLogRoots returns the roots inserted into TreeHeads or TreeHeadShards
+// by dc.
+func signedLogRoots(dc *dataChangeRecord) ([]*trillian.SignedLogRoot, error) {
+	ret := make([]*trillian.SignedLogRoot, 0, len(dc.Mods))
+	for _, m := range dc.Mods {
+		var row treeHeadMod
+		if err := decodeMod(m, &row); err != nil {
+			return nil, fmt.Errorf("failed to decode %s row: %v", dc.TableName, err)
+		}
+		var sig spannerpb.DigitallySigned
+		if err := proto.Unmarshal(row.RootSignature, &sig); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal root signature: %v", err)
+		}
+		apiSig, err := storageToAPISig(&sig)
+		if err != nil {
+			return nil, err
+		}
+		meta := &any.Any{}
+		if err := proto.Unmarshal(row.TreeMetadata, meta); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal root metadata: %v", err)
+		}
+		ret = append(ret, &trillian.SignedLogRoot{
+			TimestampNanos: row.TimestampNanos,
+			RootHash:       row.RootHash,
+			TreeSize:       row.TreeSize,
+			LogId:          row.TreeID,
+			TreeRevision:   row.TreeRevision,
+			Signature:      apiSig,
+			Metadata:       rootMetadata(meta),
+		})
+	}
+	return ret, nil
+}
@@ -0,0 +1,199 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudspanner
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/trillian"
+	"github.com/google/trillian/crypto/sigpb"
+	"github.com/google/trillian/storage/cloudspanner/spannerpb"
+)
+
+// jsonValue returns the value a change stream record holds for the given
+// JSON, as decoded by the Spanner client.
+func jsonValue(t *testing.T, s string) spanner.NullJSON {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatalf("json.Unmarshal(%s): %v", s, err)
+	}
+	return spanner.NullJSON{Value: v, Valid: true}
+}
+
+func b64(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+func TestSequencedLeaves(t *testing.T) {
+	integrated := time.Unix(1500000000, 123)
+	wantTS, err := ptypes.TimestampProto(integrated)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		desc    string
+		mods    []*mod
+		want    []sequencedLeaf
+		wantErr bool
+	}{
+		{
+			desc: "leaves",
+			mods: []*mod{
+				{
+					Keys:      jsonValue(t, `{"TreeID": "12345", "SequenceNumber": "7"}`),
+					NewValues: jsonValue(t, `{"LeafIdentityHash": "`+b64("id7")+`", "MerkleLeafHash": "`+b64("hash7")+`", "IntegrateTimestampNanos": "1500000000000000123"}`),
+				},
+				{
+					Keys:      jsonValue(t, `{"TreeID": "12345", "SequenceNumber": "8"}`),
+					NewValues: jsonValue(t, `{"LeafIdentityHash": "`+b64("id8")+`", "MerkleLeafHash": "`+b64("hash8")+`", "IntegrateTimestampNanos": "1500000000000000123"}`),
+				},
+			},
+			want: []sequencedLeaf{
+				{treeID: 12345, leaf: &trillian.LogLeaf{LeafIndex: 7, LeafIdentityHash: []byte("id7"), MerkleLeafHash: []byte("hash7"), IntegrateTimestamp: wantTS}},
+				{treeID: 12345, leaf: &trillian.LogLeaf{LeafIndex: 8, LeafIdentityHash: []byte("id8"), MerkleLeafHash: []byte("hash8"), IntegrateTimestamp: wantTS}},
+			},
+		},
+		{
+			desc: "numberNotString",
+			mods: []*mod{
+				{Keys: jsonValue(t, `{"TreeID": 12345, "SequenceNumber": "7"}`)},
+			},
+			wantErr: true,
+		},
+		{
+			desc: "badBase64",
+			mods: []*mod{
+				{
+					Keys:      jsonValue(t, `{"TreeID": "12345", "SequenceNumber": "7"}`),
+					NewValues: jsonValue(t, `{"LeafIdentityHash": "!!"}`),
+				},
+			},
+			wantErr: true,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			got, err := sequencedLeaves(&dataChangeRecord{TableName: seqDataTbl, ModType: modInsert, Mods: test.mods})
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("sequencedLeaves() = (_, %v), want err? %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(got) != len(test.want) {
+				t.Fatalf("sequencedLeaves() returned %d leaves, want %d", len(got), len(test.want))
+			}
+			for i := range got {
+				if got[i].treeID != test.want[i].treeID {
+					t.Errorf("sequencedLeaves()[%d].treeID = %d, want %d", i, got[i].treeID, test.want[i].treeID)
+				}
+				if !proto.Equal(got[i].leaf, test.want[i].leaf) {
+					t.Errorf("sequencedLeaves()[%d].leaf = %v, want %v", i, got[i].leaf, test.want[i].leaf)
+				}
+			}
+		})
+	}
+}
+
+func TestSignedLogRoots(t *testing.T) {
+	sig, err := proto.Marshal(&spannerpb.DigitallySigned{
+		HashAlgorithm:      spannerpb.HashAlgorithm_SHA256,
+		SignatureAlgorithm: spannerpb.SignatureAlgorithm_ECDSA,
+		Signature:          []byte("signature"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	badSig, err := proto.Marshal(&spannerpb.DigitallySigned{HashAlgorithm: spannerpb.HashAlgorithm(42)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		desc    string
+		mods    []*mod
+		want    []*trillian.SignedLogRoot
+		wantErr bool
+	}{
+		{
+			desc: "root",
+			mods: []*mod{
+				{
+					Keys:      jsonValue(t, `{"TreeID": "12345", "TimestampNanos": "1500000000000000123"}`),
+					NewValues: jsonValue(t, `{"TreeSize": "9", "RootHash": "`+b64("root")+`", "RootSignature": "`+base64.StdEncoding.EncodeToString(sig)+`", "TreeRevision": "4", "TreeMetadata": null}`),
+				},
+			},
+			want: []*trillian.SignedLogRoot{
+				{
+					LogId:          12345,
+					TimestampNanos: 1500000000000000123,
+					TreeSize:       9,
+					RootHash:       []byte("root"),
+					TreeRevision:   4,
+					Signature: &sigpb.DigitallySigned{
+						HashAlgorithm:      sigpb.DigitallySigned_SHA256,
+						SignatureAlgorithm: sigpb.DigitallySigned_ECDSA,
+						Signature:          []byte("signature"),
+					},
+				},
+			},
+		},
+		{
+			desc: "unknownHashAlgorithm",
+			mods: []*mod{
+				{
+					Keys:      jsonValue(t, `{"TreeID": "12345", "TimestampNanos": "1500000000000000123"}`),
+					NewValues: jsonValue(t, `{"RootSignature": "`+base64.StdEncoding.EncodeToString(badSig)+`"}`),
+				},
+			},
+			wantErr: true,
+		},
+		{
+			desc: "garbledSignature",
+			mods: []*mod{
+				{
+					Keys:      jsonValue(t, `{"TreeID": "12345", "TimestampNanos": "1500000000000000123"}`),
+					NewValues: jsonValue(t, `{"RootSignature": "`+b64("\xff\xff")+`"}`),
+				},
+			},
+			wantErr: true,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			got, err := signedLogRoots(&dataChangeRecord{TableName: treeHeadsTbl, ModType: modInsert, Mods: test.mods})
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("signedLogRoots() = (_, %v), want err? %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(got) != len(test.want) {
+				t.Fatalf("signedLogRoots() returned %d roots, want %d", len(got), len(test.want))
+			}
+			for i := range got {
+				if !proto.Equal(got[i], test.want[i]) {
+					t.Errorf("signedLogRoots()[%d] = %v, want %v", i, got[i], test.want[i])
+				}
+			}
+		})
+	}
+}
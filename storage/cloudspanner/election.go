@@ -0,0 +1,240 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudspanner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"github.com/golang/glog"
+	"github.com/google/trillian/util"
+	"google.golang.org/grpc/codes"
+)
+
+const treeMastersTable = "TreeMasters"
+
+var treeMastersCols = []string{"TreeID", "InstanceID", "LeaseExpiry"}
+
+// MasterElection is an implementation of util.MasterElection which leases
+// the mastership of a tree through its row in the TreeMasters table. The
+// master renews its lease in the background every third of the lease
+// duration, and other instances take over once the lease has expired.
+//
+// Lease expiry is measured with the instances' clocks, so their skew must be
+// well below the lease duration.
+type MasterElection struct {
+	client     *spanner.Client
+	instanceID string
+	treeID     int64
+	lease      time.Duration
+
+	mu sync.Mutex
+	// expiry is when the lease held by this instance expires, or zero if it
+	// holds none.
+	expiry time.Time
+	// stopRenewal stops the goroutine renewing the lease, if it runs.
+	stopRenewal context.CancelFunc
+	renewalDone chan struct{}
+}
+
+// Start commences election operation.
+func (e *MasterElection) Start(ctx context.Context) error {
+	return nil
+}
+
+// WaitForMastership blocks until the current instance holds the lease of the
+// tree, retrying on errors until ctx is done.
+func (e *MasterElection) WaitForMastership(ctx context.Context) error {
+	for {
+		if master, _ := e.IsMaster(ctx); master {
+			return nil
+		}
+		start := TimeNow()
+		got, err := e.acquire(ctx, start)
+		switch {
+		case err != nil:
+			glog.Warningf("%d: failed to acquire mastership lease: %v", e.treeID, err)
+		case got:
+			e.mu.Lock()
+			e.expiry = start.Add(e.lease)
+			e.startRenewal()
+			e.mu.Unlock()
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(e.lease / 3):
+		}
+	}
+}
+
+// acquire takes or renews the lease of the tree, if it's unowned, expired, or
+// already held by this instance, and reports whether it succeeded. The new
+// lease is measured from start, which must precede the transaction.
+func (e *MasterElection) acquire(ctx context.Context, start time.Time) (bool, error) {
+	got := false
+	_, err := e.client.ReadWriteTransaction(ctx, func(ctx context.Context, tx *spanner.ReadWriteTransaction) error {
+		got = false
+		row, err := tx.ReadRow(ctx, treeMastersTable, spanner.Key{e.treeID}, treeMastersCols)
+		switch {
+		case spanner.ErrCode(err) == codes.NotFound:
+		case err != nil:
+			return err
+		default:
+			var treeID int64
+			var instanceID string
+			var expiry time.Time
+			if err := row.Columns(&treeID, &instanceID, &expiry); err != nil {
+				return err
+			}
+			if instanceID != e.instanceID && start.Before(expiry) {
+				return nil
+			}
+		}
+		got = true
+		return tx.BufferWrite([]*spanner.Mutation{
+			spanner.InsertOrUpdate(treeMastersTable, treeMastersCols, []interface{}{e.treeID, e.instanceID, start.Add(e.lease)}),
+		})
+	})
+	return got && err == nil, err
+}
+
+// startRenewal starts renewing the lease in the background. e.mu must be held.
+func (e *MasterElection) startRenewal() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	e.stopRenewal, e.renewalDone = cancel, done
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(e.lease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			start := TimeNow()
+			got, err := e.acquire(ctx, start)
+			if err != nil {
+				// The lease lapses unless a later renewal succeeds.
+				glog.Warningf("%d: failed to renew mastership lease: %v", e.treeID, err)
+				continue
+			}
+			e.mu.Lock()
+			if got {
+				e.expiry = start.Add(e.lease)
+			} else {
+				glog.Warningf("%d: mastership lease was taken over", e.treeID)
+				e.expiry = time.Time{}
+			}
+			e.mu.Unlock()
+			if !got {
+				return
+			}
+		}
+	}()
+}
+
+// IsMaster returns whether the current instance holds an unexpired lease.
+func (e *MasterElection) IsMaster(ctx context.Context) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return TimeNow().Before(e.expiry), nil
+}
+
+// ResignAndRestart releases mastership, and re-joins the election.
+func (e *MasterElection) ResignAndRestart(ctx context.Context) error {
+	e.mu.Lock()
+	stop, done := e.stopRenewal, e.renewalDone
+	e.stopRenewal, e.renewalDone = nil, nil
+	e.mu.Unlock()
+	if stop != nil {
+		stop()
+		<-done
+	}
+
+	e.mu.Lock()
+	held := !e.expiry.IsZero()
+	e.expiry = time.Time{}
+	e.mu.Unlock()
+	if !held {
+		return nil
+	}
+	_, err := e.client.ReadWriteTransaction(ctx, func(ctx context.Context, tx *spanner.ReadWriteTransaction) error {
+		row, err := tx.ReadRow(ctx, treeMastersTable, spanner.Key{e.treeID}, []string{"InstanceID"})
+		switch {
+		case spanner.ErrCode(err) == codes.NotFound:
+			return nil
+		case err != nil:
+			return err
+		}
+		var instanceID string
+		if err := row.Columns(&instanceID); err != nil {
+			return err
+		}
+		if instanceID != e.instanceID {
+			return nil
+		}
+		return tx.BufferWrite([]*spanner.Mutation{spanner.Delete(treeMastersTable, spanner.Key{e.treeID})})
+	})
+	return err
+}
+
+// Close terminates election operation.
+func (e *MasterElection) Close(ctx context.Context) error {
+	// ctx may be done already, as Close is called when the election stops, but
+	// the lease should still be released so another instance needn't wait for
+	// it to expire.
+	ctx, cancel := context.WithTimeout(context.Background(), e.lease)
+	defer cancel()
+	return e.ResignAndRestart(ctx)
+}
+
+// ElectionFactory creates cloudspanner.MasterElection instances.
+type ElectionFactory struct {
+	client     *spanner.Client
+	instanceID string
+	lease      time.Duration
+}
+
+// NewElectionFactory builds an election factory whose elections hold leases
+// of the given duration in the TreeMasters table.
+func NewElectionFactory(instanceID string, client *spanner.Client, lease time.Duration) *ElectionFactory {
+	return &ElectionFactory{
+		client:     client,
+		instanceID: instanceID,
+		lease:      lease,
+	}
+}
+
+// NewElection creates a specific cloudspanner.MasterElection instance.
+func (ef ElectionFactory) NewElection(ctx context.Context, treeID int64) (util.MasterElection, error) {
+	if ef.lease <= 0 {
+		return nil, fmt.Errorf("lease duration is %v, want > 0", ef.lease)
+	}
+	e := &MasterElection{
+		client:     ef.client,
+		instanceID: ef.instanceID,
+		treeID:     treeID,
+		lease:      ef.lease,
+	}
+	glog.Infof("MasterElection created: tree %d for %v", treeID, ef.instanceID)
+	return e, nil
+}
@@ -0,0 +1,46 @@
+// +build gofuzz
+
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudspanner
+
+import (
+	"github.com/google/trillian/merkle/rfc6962"
+	"github.com/google/trillian/storage/cache"
+)
+
+// FuzzSubtree is a go-fuzz entry point for the read path of log subtrees, from
+// the bytes of a Subtree row to the populated subtree. data holds the length
+// of the subtree prefix in its first byte, the prefix, and the serialized
+// SubtreeProto.
+//
+// Build with go-fuzz-build -tags gofuzz -func FuzzSubtree.
+func FuzzSubtree(data []byte) int {
+	if len(data) == 0 {
+		return -1
+	}
+	n := int(data[0]) % 9
+	if len(data) < 1+n {
+		return -1
+	}
+	st, err := decodeSubtree(data[1:1+n], data[1+n:])
+	if err != nil {
+		return 0
+	}
+	if err := cache.LogPopulateFunc(rfc6962.DefaultHasher)(st); err != nil {
+		return 0
+	}
+	return 1
+}
@@ -15,9 +15,7 @@
 package cloudspanner
 
 import (
-	"bytes"
 	"context"
-	"errors"
 	"fmt"
 	"math/rand"
 	"sort"
@@ -26,7 +24,6 @@ import (
 
 	"cloud.google.com/go/spanner"
 	"github.com/golang/glog"
-	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/google/trillian"
 	"github.com/google/trillian/merkle/hashers"
@@ -34,6 +31,7 @@ import (
 	"github.com/google/trillian/storage/cache"
 	"github.com/google/trillian/storage/cloudspanner/spannerpb"
 	"github.com/google/trillian/trees"
+	"github.com/google/trillian/util"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -53,6 +51,18 @@ const (
 													AND t.Deleted=false`
 )
 
+// Queries sampling the queue of a log for SampleQueue.
+const (
+	queueShardsSQL = `SELECT Bucket, COUNT(1), MIN(QueueTimestampNanos) FROM Unsequenced
+		WHERE TreeID = @tree_id
+		GROUP BY Bucket ORDER BY Bucket`
+	oldestQueuedLeavesSQL = `SELECT Bucket, LeafIdentityHash, MerkleLeafHash, QueueTimestampNanos FROM Unsequenced
+		WHERE TreeID = @tree_id
+		ORDER BY QueueTimestampNanos LIMIT @limit`
+	queuedBeforeCountSQL = `SELECT COUNT(1) FROM Unsequenced
+		WHERE TreeID = @tree_id AND QueueTimestampNanos <= @cutoff`
+)
+
 // LogStorageOptions are tuning, experiments and workarounds that can be used.
 type LogStorageOptions struct {
 	TreeStorageOptions
@@ -65,6 +75,19 @@ type LogStorageOptions struct {
 	// DequeueAcrossMerkleBucketsRangeFraction specifies the fraction of Merkle
 	// keyspace to dequeue from when using multi-bucket-dequeue.
 	DequeueAcrossMerkleBucketsRangeFraction float64
+	// TimeSource picks the time bucket leaves are queued to and dequeued
+	// from. Defaults to util.SystemTimeSource.
+	TimeSource util.TimeSource
+	// MaxCommitMutations, if non-zero, is the maximum number of mutations,
+	// counted as one per column of each row, of the SubtreeData and
+	// SequencedLeafData inserts of a read-write transaction. Transactions
+	// with more are committed in several, ahead of a final one storing the
+	// new tree head, so that sequencing batches can be larger than Spanner's
+	// limit on the mutations of a transaction. It should leave room below that
+	// limit for indexes and for the final transaction, which deletes one row
+	// of Unsequenced per sequenced leaf. The writes of split commits which
+	// didn't complete are only deleted while it's set.
+	MaxCommitMutations int
 }
 
 var (
@@ -93,6 +116,9 @@ func NewLogStorageWithOpts(client *spanner.Client, opts LogStorageOptions) stora
 	if opts.DequeueAcrossMerkleBucketsRangeFraction <= 0 || opts.DequeueAcrossMerkleBucketsRangeFraction > 1.0 {
 		opts.DequeueAcrossMerkleBucketsRangeFraction = 1.0
 	}
+	if opts.TimeSource == nil {
+		opts.TimeSource = util.SystemTimeSource{}
+	}
 	ret := &logStorage{
 		ts:   newTreeStorageWithOpts(client, opts.TreeStorageOptions),
 		opts: opts,
@@ -118,8 +144,8 @@ func (ls *logStorage) CheckDatabaseAccessible(ctx context.Context) error {
 
 func (ls *logStorage) Snapshot(ctx context.Context) (storage.ReadOnlyLogTX, error) {
 	snapshotTX := &snapshotTX{
-		client: ls.ts.client,
-		stx:    ls.ts.client.ReadOnlyTransaction(),
+		client: ls.ts.readClient(),
+		stx:    ls.ts.readOnlyTransaction(),
 		ls:     ls,
 	}
 	return &readOnlyLogTX{snapshotTX}, nil
@@ -130,7 +156,15 @@ func newLogCache(tree *trillian.Tree) (cache.SubtreeCache, error) {
 	if err != nil {
 		return cache.SubtreeCache{}, err
 	}
-	return cache.NewLogSubtreeCache(defLogStrata, hasher), nil
+	config, err := logConfigOrDefault(tree)
+	if err != nil {
+		return cache.SubtreeCache{}, err
+	}
+	strata, err := storage.StrataForTreeType(tree.TreeType, config.StrataDepths)
+	if err != nil {
+		return cache.SubtreeCache{}, err
+	}
+	return cache.NewLogSubtreeCache(strata, hasher), nil
 }
 
 func (ls *logStorage) begin(ctx context.Context, treeID int64, readonly bool, stx spanRead) (*logTX, error) {
@@ -156,21 +190,75 @@ func (ls *logStorage) BeginForTree(ctx context.Context, treeID int64) (storage.L
 }
 
 func (ls *logStorage) ReadWriteTransaction(ctx context.Context, treeID int64, f storage.LogTXFunc) error {
+	defer ls.ts.observeCommit("log_read_write", time.Now())
+	split := ls.opts.MaxCommitMutations > 0
+	if split {
+		if err := ls.recoverSplitCommit(ctx, treeID); err != nil {
+			return err
+		}
+	}
+	var sc *splitCommit
 	_, err := ls.ts.client.ReadWriteTransaction(ctx, func(ctx context.Context, stx *spanner.ReadWriteTransaction) error {
+		sc = nil
 		tx, err := ls.begin(ctx, treeID, false /* readonly */, stx)
 		if err != nil {
 			return err
 		}
+		tx.collectInserts = split
 		if err := f(ctx, tx); err != nil {
 			return err
 		}
-		return tx.flushSubtrees()
+		if err := tx.flushSubtrees(); err != nil {
+			return err
+		}
+		if !split {
+			return nil
+		}
+		if len(tx.inserts) > 0 || len(tx.buffered) > 0 {
+			if err := checkNoSplitCommit(ctx, stx, treeID); err != nil {
+				return err
+			}
+		}
+		if insertMutations(tx.inserts) <= ls.opts.MaxCommitMutations {
+			for _, in := range tx.inserts {
+				if err := stx.BufferWrite([]*spanner.Mutation{spanner.Insert(in.table, in.cols, in.vals)}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		currentSTH, err := tx.currentSTH(ctx)
+		if err != nil {
+			return err
+		}
+		token, err := newSplitCommitToken()
+		if err != nil {
+			return err
+		}
+		sc = &splitCommit{
+			treeID:  treeID,
+			rev:     tx._writeRev,
+			size:    currentSTH.TreeSize,
+			token:   token,
+			inserts: tx.inserts,
+			final:   tx.buffered,
+		}
+		// Abort this transaction, its writes are committed by commitSplit.
+		return errSplitCommit
 	})
+	if sc != nil {
+		return ls.commitSplit(ctx, sc)
+	}
 	return err
 }
 
 func (ls *logStorage) SnapshotForTree(ctx context.Context, treeID int64) (storage.ReadOnlyLogTreeTX, error) {
-	return ls.begin(ctx, treeID, true /* readonly */, ls.ts.client.ReadOnlyTransaction())
+	tx, err := ls.begin(ctx, treeID, true /* readonly */, ls.ts.readOnlyTransaction())
+	if err != nil {
+		// Don't return a nil *logTX as a non-nil interface.
+		return nil, err
+	}
+	return tx, nil
 }
 
 func (ls *logStorage) QueueLeaves(ctx context.Context, logID int64, leaves []*trillian.LogLeaf, qTimestamp time.Time) ([]*trillian.QueuedLogLeaf, error) {
@@ -183,7 +271,7 @@ func (ls *logStorage) QueueLeaves(ctx context.Context, logID int64, leaves []*tr
 		return nil, status.Errorf(codes.Internal, "got unexpected config type for Log operation: %T", treeConfig)
 	}
 
-	now := time.Now().UTC().Unix()
+	now := ls.opts.TimeSource.Now().UTC().Unix()
 	bucketPrefix := (now % config.NumUnseqBuckets) << 8
 
 	results := make([]*trillian.QueuedLogLeaf, len(leaves))
@@ -211,7 +299,9 @@ func (ls *logStorage) QueueLeaves(ctx context.Context, logID int64, leaves []*tr
 				[]string{colTreeID, colBucket, colQueueTimestampNanos, colMerkleLeafHash, colLeafIdentityHash},
 				[]interface{}{logID, b, qTS, l.MerkleLeafHash, l.LeafIdentityHash})
 
+			start := time.Now()
 			_, err = ls.ts.client.Apply(ctx, []*spanner.Mutation{m1, m2})
+			ls.ts.observeCommit("queue_leaf", start)
 			if spanner.ErrCode(err) == codes.AlreadyExists {
 				k := string(l.LeafIdentityHash)
 				writeDupes[k] = append(writeDupes[k], indexMerkleHash{i, l.MerkleLeafHash})
@@ -234,6 +324,63 @@ func (ls *logStorage) QueueLeaves(ctx context.Context, logID int64, leaves []*tr
 	return results, nil
 }
 
+// SampleQueue implements storage.LeafQueueInspector. The queue is sampled in
+// a single read-only transaction, so its figures are consistent.
+func (ls *logStorage) SampleQueue(ctx context.Context, treeID int64, opts storage.QueueSampleOptions) (*storage.QueueSample, error) {
+	stx := ls.ts.client.ReadOnlyTransaction()
+	defer stx.Close()
+	sample := &storage.QueueSample{}
+
+	stmt := spanner.NewStatement(queueShardsSQL)
+	stmt.Params["tree_id"] = treeID
+	if err := stx.Query(ctx, stmt).Do(func(r *spanner.Row) error {
+		var shard storage.QueueShard
+		var oldestNanos int64
+		if err := r.Columns(&shard.Bucket, &shard.LeafCount, &oldestNanos); err != nil {
+			return err
+		}
+		shard.Oldest = time.Unix(0, oldestNanos)
+		sample.Shards = append(sample.Shards, shard)
+		sample.LeafCount += shard.LeafCount
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("problem executing queueShardsSQL: %v", err)
+	}
+
+	if opts.OldestLeaves > 0 {
+		stmt := spanner.NewStatement(oldestQueuedLeavesSQL)
+		stmt.Params["tree_id"] = treeID
+		stmt.Params["limit"] = int64(opts.OldestLeaves)
+		if err := stx.Query(ctx, stmt).Do(func(r *spanner.Row) error {
+			var leaf storage.QueuedLeaf
+			var queuedNanos int64
+			if err := r.Columns(&leaf.Bucket, &leaf.LeafIdentityHash, &leaf.MerkleLeafHash, &queuedNanos); err != nil {
+				return err
+			}
+			leaf.QueueTimestamp = time.Unix(0, queuedNanos)
+			sample.OldestLeaves = append(sample.OldestLeaves, leaf)
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("problem executing oldestQueuedLeavesSQL: %v", err)
+		}
+	}
+
+	cutoffs := opts.Cutoffs()
+	olderThan := make([]int64, len(cutoffs))
+	for i, cutoff := range cutoffs {
+		stmt := spanner.NewStatement(queuedBeforeCountSQL)
+		stmt.Params["tree_id"] = treeID
+		stmt.Params["cutoff"] = cutoff.UnixNano()
+		if err := stx.Query(ctx, stmt).Do(func(r *spanner.Row) error {
+			return r.Columns(&olderThan[i])
+		}); err != nil {
+			return nil, fmt.Errorf("problem executing queuedBeforeCountSQL: %v", err)
+		}
+	}
+	sample.AgeCounts = opts.AgeCounts(sample.LeafCount, olderThan)
+	return sample, nil
+}
+
 func (ls *logStorage) AddSequencedLeaves(ctx context.Context, treeID int64, leaves []*trillian.LogLeaf) ([]*trillian.QueuedLogLeaf, error) {
 	return nil, ErrNotImplemented
 }
@@ -305,6 +452,16 @@ func (tx *logTX) getLogStorageConfig() *spannerpb.LogStorageConfig {
 	return tx.config.(*spannerpb.LogStorageConfig)
 }
 
+// GetNodesByCoords implements storage.CoordNodeReader.
+func (tx *logTX) GetNodesByCoords(ctx context.Context, rev int64, coords []storage.NodeCoords) ([]storage.CoordNode, error) {
+	tx.mu.RLock()
+	defer tx.mu.RUnlock()
+	if tx.stx == nil {
+		return nil, ErrTransactionClosed
+	}
+	return tx.cache.GetNodesByCoords(coords, tx.getSubtreesAtRev(ctx, rev))
+}
+
 // LatestSignedLogRoot returns the freshest SignedLogRoot for this log at the
 // time the transaction was started.
 func (tx *logTX) LatestSignedLogRoot(ctx context.Context) (trillian.SignedLogRoot, error) {
@@ -334,6 +491,7 @@ func (tx *logTX) LatestSignedLogRoot(ctx context.Context) (trillian.SignedLogRoo
 		LogId:          currentSTH.TreeId,
 		TreeRevision:   currentSTH.TreeRevision,
 		Signature:      apiSig,
+		Metadata:       rootMetadata(currentSTH.Metadata),
 	}, nil
 }
 
@@ -351,6 +509,9 @@ func (tx *logTX) StoreSignedLogRoot(ctx context.Context, root trillian.SignedLog
 	if got, want := root.LogId, tx.treeID; got != want {
 		return fmt.Errorf("provided root is for LogID (%d) != transaction's treeID (%d)", got, want)
 	}
+	if len(root.AdditionalSignatures) > 0 {
+		return status.Error(codes.InvalidArgument, "additional_signatures not supported")
+	}
 	storageSig, err := apiToStorageSig(root.Signature)
 	if err != nil {
 		return err
@@ -362,48 +523,23 @@ func (tx *logTX) StoreSignedLogRoot(ctx context.Context, root trillian.SignedLog
 		TreeId:       tx.treeID,
 		TreeRevision: writeRev,
 		Signature:    storageSig,
+		Metadata:     root.Metadata,
 	}
-	var sigBytes, metaBytes []byte
-	if sth.Signature == nil {
-		return errors.New("sth signature is nil")
-	}
-	sigBytes, err = proto.Marshal(sth.Signature)
+	vals, err := treeHeadValues(&sth)
 	if err != nil {
 		return err
 	}
-	if sth.Metadata != nil {
-		metaBytes, err = proto.Marshal(sth.Metadata)
-		if err != nil {
-			return err
-		}
-	}
+	// CurrentTreeHeads always gets the new head, so that it's up to date
+	// whenever ReadCurrentTreeHead is turned on.
+	current := spanner.InsertOrUpdate(currentTreeHeadsTbl, treeHeadCols, vals)
 
-	m := spanner.Insert(
-		"TreeHeads",
-		[]string{
-			"TreeID",
-			"TimestampNanos",
-			"TreeSize",
-			"RootHash",
-			"RootSignature",
-			"TreeRevision",
-			"TreeMetadata",
-		},
-		[]interface{}{
-			sth.TreeId,
-			sth.TsNanos,
-			sth.TreeSize,
-			sth.RootHash,
-			sigBytes,
-			sth.TreeRevision,
-			metaBytes,
-		})
-
-	stx, ok := tx.stx.(*spanner.ReadWriteTransaction)
-	if !ok {
-		return ErrWrongTXType
+	table, cols := "TreeHeads", treeHeadCols
+	if shards := tx.ts.opts.TreeHeadShards; shards > 0 {
+		table = "TreeHeadShards"
+		cols = append(append([]string{}, treeHeadCols...), "Shard")
+		vals = append(vals, treeHeadShard(sth.TreeRevision, shards))
 	}
-	return stx.BufferWrite([]*spanner.Mutation{m})
+	return tx.bufferWrite(spanner.Insert(table, cols, vals), current)
 }
 
 func readLeaves(ctx context.Context, stx *spanner.ReadOnlyTransaction, logID int64, ids [][]byte, f func(*trillian.LogLeaf)) error {
@@ -520,7 +656,7 @@ func (tx *logTX) DequeueLeaves(ctx context.Context, limit int, cutoff time.Time)
 	// The first part of the bucket key is a time based ring - at any given
 	// moment, FEs queueing entries will be adding them to different buckets
 	// than we're dequeuing from here.
-	now := time.Now().UTC()
+	now := tx.ls.opts.TimeSource.Now().UTC()
 	var rows []spanner.KeySet
 
 	if tx.ls.opts.DequeueAcrossMerkleBuckets {
@@ -572,8 +708,7 @@ func (tx *logTX) DequeueLeaves(ctx context.Context, limit int, cutoff time.Time)
 // UpdateSequencedLeaves stores the sequence numbers assigned to the leaves,
 // and integrates them into the tree.
 func (tx *logTX) UpdateSequencedLeaves(ctx context.Context, leaves []*trillian.LogLeaf) error {
-	stx, ok := tx.stx.(*spanner.ReadWriteTransaction)
-	if !ok {
+	if _, ok := tx.stx.(*spanner.ReadWriteTransaction); !ok {
 		return ErrWrongTXType
 	}
 	// We need the latest root to know what the next sequence number to use below is.
@@ -598,14 +733,16 @@ func (tx *logTX) UpdateSequencedLeaves(ctx context.Context, leaves []*trillian.L
 		}
 
 		// Add the sequence mapping...
-		m1 := spanner.Insert(seqDataTbl,
+		if err := tx.insert(seqDataTbl,
 			[]string{colTreeID, colSequenceNumber, colLeafIdentityHash, colMerkleLeafHash, colIntegrateTimestampNanos},
-			[]interface{}{tx.treeID, l.LeafIndex, l.LeafIdentityHash, l.MerkleLeafHash, iTimestamp.UnixNano()})
+			[]interface{}{tx.treeID, l.LeafIndex, l.LeafIdentityHash, l.MerkleLeafHash, iTimestamp.UnixNano()}); err != nil {
+			return fmt.Errorf("bufferwrite(): %v", err)
+		}
 
 		m2 := spanner.Delete(unseqTable, spanner.Key{tx.treeID, qe.bucket, qe.timestamp, l.MerkleLeafHash})
 
 		tx.numSequenced++
-		if err := stx.BufferWrite([]*spanner.Mutation{m1, m2}); err != nil {
+		if err := tx.bufferWrite(m2); err != nil {
 			return fmt.Errorf("bufferwrite(): %v", err)
 		}
 	}
@@ -663,54 +800,6 @@ func (l leafmap) addFullRow(r *spanner.Row) error {
 	return nil
 }
 
-// leavesByHash is a map of []LogLeaf (keyed by value hash) which knows how to
-// populate itself from Spanner Rows.
-type leavesByHash map[string][]*trillian.LogLeaf
-
-// addRow adds the contents of the Spanner Row to this map.
-func (b leavesByHash) addRow(r *spanner.Row) error {
-	var h []byte
-	var v []byte
-	var ed []byte
-	var qTimestamp int64
-	if err := r.Columns(&h, &v, &ed, &qTimestamp); err != nil {
-		return err
-	}
-	queueTimestamp, err := ptypes.TimestampProto(time.Unix(0, qTimestamp))
-	if err != nil {
-		return fmt.Errorf("got invalid queue timestamp: %v", err)
-	}
-
-	leaves, ok := b[string(h)]
-	if !ok {
-		return fmt.Errorf("inconsistency: unexpected leafValueHash %v", h)
-	}
-	for i := range leaves {
-		if got, want := leaves[i].LeafIdentityHash, h; !bytes.Equal(got, want) {
-			return fmt.Errorf("inconsistency: unexpected leafvaluehash %v, want %v", got, want)
-		}
-		leaves[i].LeafValue = v
-		leaves[i].ExtraData = ed
-		leaves[i].QueueTimestamp = queueTimestamp
-	}
-	return nil
-}
-
-// populateLeafData populates the partial LogLeaf structs held in the passed in
-// map of LeafIdentityHash to []LogLeaf by reading the remaining LogLeaf data from
-// Spanner.
-// The value of byHash is an []LogLeaf because the underlying leaf data could
-// be sequenced into multiple tree leaves if the log allows duplication.
-func (tx *logTX) populateLeafData(ctx context.Context, byHash leavesByHash) error {
-	keySet := make([]spanner.KeySet, 0, len(byHash))
-	for k := range byHash {
-		keySet = append(keySet, spanner.Key{tx.treeID, []byte(k)})
-	}
-	cols := []string{colLeafIdentityHash, colLeafValue, colExtraData, colQueueTimestampNanos}
-	rows := tx.stx.Read(ctx, leafDataTbl, spanner.KeySets(keySet...), cols)
-	return rows.Do(byHash.addRow)
-}
-
 // validateIndices ensures that all indices are between 0 and treeSize-1.
 func validateIndices(indices []int64, treeSize int64) error {
 	maxIndex := treeSize - 1
@@ -833,73 +922,44 @@ WHERE sd.TreeID = @tree_id AND sd.SequenceNumber >= @start AND sd.SequenceNumber
 	return ret, nil
 }
 
-// leafSlice is a slice of LogLeaf which knows how to populate itself from
-// Spanner Rows.
-type leafSlice []*trillian.LogLeaf
-
-// addRow appends the leaf data in Row to the array.
-func (l *leafSlice) addRow(r *spanner.Row) error {
-	var (
-		s      int64
-		mh, lh []byte
-	)
-
-	if err := r.Columns(&s, &mh, &lh); err != nil {
-		return err
-	}
-	leaf := trillian.LogLeaf{
-		LeafIndex:        s,
-		MerkleLeafHash:   mh,
-		LeafIdentityHash: lh,
-	}
-	*l = append(*l, &leaf)
-	return nil
-}
-
-// getUsingIndex returns a slice containing the LogLeaf structs corresponding
-// to the requested keys.
-// The entries in key are used in constructing a primary key (treeID, keyElem)
-// for the specified Spanner index.
+// GetLeavesByHash returns the leaves corresponding to the given merkle hashes.
+// Any unknown hashes will simply be ignored, and the caller should inspect the
+// returned leaves to determine whether this has occurred.
 // If bySeq is true, the returned slice will be order by LogLeaf.LeafIndex.
-func (tx *logTX) getUsingIndex(ctx context.Context, idx string, keys [][]byte, bySeq bool) ([]*trillian.LogLeaf, error) {
-	keySet := make([]spanner.KeySet, 0, len(keys))
-	for _, k := range keys {
-		keySet = append(keySet, spanner.Key{tx.treeID, k})
-	}
-
-	leaves := make(leafSlice, 0, len(keys))
-	cols := []string{colSequenceNumber, colMerkleLeafHash, colLeafIdentityHash}
-	rows := tx.stx.ReadUsingIndex(ctx, seqDataTbl, idx, spanner.KeySets(keySet...), cols)
-	if err := rows.Do(leaves.addRow); err != nil {
+func (tx *logTX) GetLeavesByHash(ctx context.Context, hashes [][]byte, bySeq bool) ([]*trillian.LogLeaf, error) {
+	currentSTH, err := tx.currentSTH(ctx)
+	if err == storage.ErrTreeNeedsInit {
+		return nil, nil
+	} else if err != nil {
 		return nil, err
 	}
 
-	byHash := make(leavesByHash)
-	for i := range leaves {
-		k := string(leaves[i].LeafIdentityHash)
-		byHash[k] = append(byHash[k], leaves[i])
-	}
+	// Leaves inserted by a split commit which hasn't stored their tree head
+	// yet are skipped.
+	stmt := spanner.NewStatement(
+		`SELECT sd.MerkleLeafHash, ld.LeafValue, ld.ExtraData, sd.SequenceNumber, ld.LeafIdentityHash, ld.QueueTimestampNanos, sd.IntegrateTimestampNanos
+FROM ` + seqDataTbl + `@{FORCE_INDEX=` + seqDataByMerkleHashIdx + `} as sd
+INNER JOIN LeafData as ld
+ON sd.TreeID = ld.TreeID AND sd.LeafIdentityHash = ld.LeafIdentityHash
+WHERE sd.TreeID = @tree_id AND sd.MerkleLeafHash IN UNNEST(@hashes) AND sd.SequenceNumber < @tree_size`)
+	stmt.Params["tree_id"] = tx.treeID
+	stmt.Params["hashes"] = hashes
+	stmt.Params["tree_size"] = currentSTH.TreeSize
 
-	// Now we can fetch & combine the actual leaf data:
-	if err := tx.populateLeafData(ctx, byHash); err != nil {
+	leaves := make(leafmap)
+	rows := tx.stx.Query(ctx, stmt)
+	if err := rows.Do(leaves.addFullRow); err != nil {
 		return nil, err
 	}
 
+	ret := make([]*trillian.LogLeaf, 0, len(leaves))
+	for _, l := range leaves {
+		ret = append(ret, l)
+	}
 	if bySeq {
-		sort.Sort(byIndex(leaves))
+		sort.Sort(byIndex(ret))
 	}
-
-	return leaves, nil
-}
-
-// GetLeavesByHash returns the leaves corresponding to the given merkle hashes.
-// Any unknown hashes will simply be ignored, and the caller should inspect the
-// returned leaves to determine whether this has occurred.
-// TODO(al): Currently, this method does not populate the IntegrateTimestamp
-//   member of the returned leaves. We should convert this method to use SQL
-//   rather than denormalising IntegrateTimestampNanos into the index too.
-func (tx *logTX) GetLeavesByHash(ctx context.Context, hashes [][]byte, bySeq bool) ([]*trillian.LogLeaf, error) {
-	return tx.getUsingIndex(ctx, seqDataByMerkleHashIdx, hashes, bySeq)
+	return ret, nil
 }
 
 // QueuedEntry represents a leaf which was dequeued.
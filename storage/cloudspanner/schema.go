@@ -0,0 +1,291 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudspanner
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/spanner"
+)
+
+const (
+	schemaColumnsSQL = `SELECT TABLE_NAME, COLUMN_NAME, SPANNER_TYPE
+		FROM INFORMATION_SCHEMA.COLUMNS
+		WHERE TABLE_SCHEMA = ''`
+
+	// The ORDINAL_POSITION of STORING columns is NULL: they aren't part of
+	// the index key.
+	schemaIndexColumnsSQL = `SELECT TABLE_NAME, INDEX_NAME, COLUMN_NAME
+		FROM INFORMATION_SCHEMA.INDEX_COLUMNS
+		WHERE TABLE_SCHEMA = '' AND ORDINAL_POSITION IS NOT NULL
+		ORDER BY TABLE_NAME, INDEX_NAME, ORDINAL_POSITION`
+
+	// primaryKeyIndex is the name of the primary key in INDEX_COLUMNS.
+	primaryKeyIndex = "PRIMARY_KEY"
+)
+
+// tableSchema is the expected schema of a table, as created by spanner.sdl.
+type tableSchema struct {
+	name string
+	// columns maps the names of the columns to their SPANNER_TYPE, without
+	// length, e.g. BYTES for BYTES(256).
+	columns map[string]string
+	// indexes maps the names of the indexes the queries rely on, including
+	// PRIMARY_KEY, to their key columns.
+	indexes map[string][]string
+}
+
+// schema is the expected schema of the database, which must be kept in sync
+// with spanner.sdl.
+var schema = []tableSchema{
+	{
+		name: "TreeRoots",
+		columns: map[string]string{
+			"TreeID":           "INT64",
+			"TreeState":        "INT64",
+			"TreeType":         "INT64",
+			"TreeInfo":         "BYTES",
+			"Deleted":          "BOOL",
+			"DeleteTimeMillis": "INT64",
+		},
+		indexes: map[string][]string{
+			primaryKeyIndex:      {"TreeID"},
+			"TreeRootsByDeleted": {"Deleted"},
+		},
+	},
+	{
+		name: "TreeHeads",
+		columns: map[string]string{
+			"TreeID":         "INT64",
+			"TimestampNanos": "INT64",
+			"TreeSize":       "INT64",
+			"RootHash":       "BYTES",
+			"RootSignature":  "BYTES",
+			"TreeRevision":   "INT64",
+			"TreeMetadata":   "BYTES",
+		},
+		indexes: map[string][]string{
+			primaryKeyIndex:     {"TreeID", "TimestampNanos"},
+			"TreeRevisionIndex": {"TreeID", "TreeRevision"},
+		},
+	},
+	{
+		name: "TreeHeadShards",
+		columns: map[string]string{
+			"TreeID":         "INT64",
+			"Shard":          "INT64",
+			"TimestampNanos": "INT64",
+			"TreeSize":       "INT64",
+			"RootHash":       "BYTES",
+			"RootSignature":  "BYTES",
+			"TreeRevision":   "INT64",
+			"TreeMetadata":   "BYTES",
+		},
+		indexes: map[string][]string{primaryKeyIndex: {"TreeID", "Shard", "TimestampNanos"}},
+	},
+	{
+		name: "CurrentTreeHeads",
+		columns: map[string]string{
+			"TreeID":         "INT64",
+			"TimestampNanos": "INT64",
+			"TreeSize":       "INT64",
+			"RootHash":       "BYTES",
+			"RootSignature":  "BYTES",
+			"TreeRevision":   "INT64",
+			"TreeMetadata":   "BYTES",
+		},
+		indexes: map[string][]string{primaryKeyIndex: {"TreeID"}},
+	},
+	{
+		name: "SubtreeData",
+		columns: map[string]string{
+			"TreeID":    "INT64",
+			"SubtreeID": "BYTES",
+			"Revision":  "INT64",
+			"Subtree":   "BYTES",
+		},
+		indexes: map[string][]string{primaryKeyIndex: {"TreeID", "SubtreeID", "Revision"}},
+	},
+	{
+		name: "LeafData",
+		columns: map[string]string{
+			"TreeID":              "INT64",
+			"LeafIdentityHash":    "BYTES",
+			"LeafValue":           "BYTES",
+			"ExtraData":           "BYTES",
+			"QueueTimestampNanos": "INT64",
+		},
+		indexes: map[string][]string{primaryKeyIndex: {"TreeID", "LeafIdentityHash"}},
+	},
+	{
+		name: "SequencedLeafData",
+		columns: map[string]string{
+			"TreeID":                  "INT64",
+			"SequenceNumber":          "INT64",
+			"LeafIdentityHash":        "BYTES",
+			"MerkleLeafHash":          "BYTES",
+			"IntegrateTimestampNanos": "INT64",
+		},
+		indexes: map[string][]string{
+			primaryKeyIndex:        {"TreeID", "SequenceNumber"},
+			"SequenceByMerkleHash": {"TreeID", "MerkleLeafHash"},
+		},
+	},
+	{
+		name: "Unsequenced",
+		columns: map[string]string{
+			"TreeID":              "INT64",
+			"Bucket":              "INT64",
+			"QueueTimestampNanos": "INT64",
+			"MerkleLeafHash":      "BYTES",
+			"LeafIdentityHash":    "BYTES",
+		},
+		indexes: map[string][]string{primaryKeyIndex: {"TreeID", "Bucket", "QueueTimestampNanos", "MerkleLeafHash"}},
+	},
+	{
+		name: "MapLeafData",
+		columns: map[string]string{
+			"TreeID":      "INT64",
+			"LeafIndex":   "BYTES",
+			"MapRevision": "INT64",
+			"LeafHash":    "BYTES",
+			"LeafValue":   "BYTES",
+			"ExtraData":   "BYTES",
+		},
+		indexes: map[string][]string{primaryKeyIndex: {"TreeID", "LeafIndex", "MapRevision"}},
+	},
+	{
+		name: "SplitCommits",
+		columns: map[string]string{
+			"TreeID":       "INT64",
+			"TreeRevision": "INT64",
+			"TreeSize":     "INT64",
+			"Token":        "STRING",
+			"LeaseExpiry":  "TIMESTAMP",
+		},
+		indexes: map[string][]string{primaryKeyIndex: {"TreeID"}},
+	},
+	{
+		name: "TreeMasters",
+		columns: map[string]string{
+			"TreeID":      "INT64",
+			"InstanceID":  "STRING",
+			"LeaseExpiry": "TIMESTAMP",
+		},
+		indexes: map[string][]string{primaryKeyIndex: {"TreeID"}},
+	},
+}
+
+// SchemaError lists the differences between the schema of a database and the
+// one expected by the storage.
+type SchemaError struct {
+	Problems []string
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("database schema doesn't match storage/cloudspanner/spanner.sdl, apply the missing changes to it: %s", strings.Join(e.Problems, "; "))
+}
+
+// CheckSchema checks that the database of client has the tables, columns and
+// indexes used by the storage, with the expected types, so that servers can
+// fail at startup rather than when a rarely used query first runs against an
+// outdated schema. Returns a *SchemaError if the schema doesn't match.
+func CheckSchema(ctx context.Context, client *spanner.Client) error {
+	tx := client.ReadOnlyTransaction()
+	defer tx.Close()
+
+	// columns maps "table.column" to the column's type.
+	columns := make(map[string]string)
+	if err := tx.Query(ctx, spanner.NewStatement(schemaColumnsSQL)).Do(func(r *spanner.Row) error {
+		var table, column, spannerType string
+		if err := r.Columns(&table, &column, &spannerType); err != nil {
+			return err
+		}
+		columns[table+"."+column] = spannerType
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to read schema columns: %v", err)
+	}
+
+	// indexes maps "table.index" to the index's key columns.
+	indexes := make(map[string][]string)
+	if err := tx.Query(ctx, spanner.NewStatement(schemaIndexColumnsSQL)).Do(func(r *spanner.Row) error {
+		var table, index, column string
+		if err := r.Columns(&table, &index, &column); err != nil {
+			return err
+		}
+		indexes[table+"."+index] = append(indexes[table+"."+index], column)
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to read schema indexes: %v", err)
+	}
+
+	if problems := schemaProblems(columns, indexes); len(problems) > 0 {
+		return &SchemaError{Problems: problems}
+	}
+	return nil
+}
+
+// schemaProblems compares the columns and indexes of a database, keyed by
+// "table.column" and "table.index", to the expected schema.
+func schemaProblems(columns map[string]string, indexes map[string][]string) []string {
+	tables := make(map[string]bool)
+	for key := range columns {
+		tables[strings.SplitN(key, ".", 2)[0]] = true
+	}
+
+	var problems []string
+	for _, t := range schema {
+		if !tables[t.name] {
+			problems = append(problems, fmt.Sprintf("table %s is missing", t.name))
+			continue
+		}
+		cols := make([]string, 0, len(t.columns))
+		for name := range t.columns {
+			cols = append(cols, name)
+		}
+		sort.Strings(cols)
+		for _, name := range cols {
+			want := t.columns[name]
+			got, ok := columns[t.name+"."+name]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("table %s is missing column %s", t.name, name))
+				continue
+			}
+			if base := strings.SplitN(got, "(", 2)[0]; base != want {
+				problems = append(problems, fmt.Sprintf("column %s.%s has type %s, want %s", t.name, name, got, want))
+			}
+		}
+		names := make([]string, 0, len(t.indexes))
+		for name := range t.indexes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			want := t.indexes[name]
+			got, ok := indexes[t.name+"."+name]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("table %s is missing index %s on (%s)", t.name, name, strings.Join(want, ", ")))
+			} else if !reflect.DeepEqual(got, want) {
+				problems = append(problems, fmt.Sprintf("index %s of table %s is on (%s), want (%s)", name, t.name, strings.Join(got, ", "), strings.Join(want, ", ")))
+			}
+		}
+	}
+	return problems
+}
@@ -0,0 +1,89 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudspanner
+
+import (
+	"reflect"
+	"testing"
+)
+
+// spannerSDLSchema returns the columns and indexes of a database created by
+// spanner.sdl, as read by CheckSchema.
+func spannerSDLSchema() (map[string]string, map[string][]string) {
+	columns := make(map[string]string)
+	indexes := make(map[string][]string)
+	for _, t := range schema {
+		for name, typ := range t.columns {
+			if typ == "BYTES" || typ == "STRING" {
+				typ += "(MAX)"
+			}
+			columns[t.name+"."+name] = typ
+		}
+		for name, cols := range t.indexes {
+			indexes[t.name+"."+name] = cols
+		}
+	}
+	// Tables and indexes which the storage doesn't use.
+	columns["Other.ID"] = "INT64"
+	indexes["Other."+primaryKeyIndex] = []string{"ID"}
+	return columns, indexes
+}
+
+func TestSchemaProblems(t *testing.T) {
+	for _, test := range []struct {
+		desc   string
+		modify func(columns map[string]string, indexes map[string][]string)
+		want   []string
+	}{
+		{desc: "matches", modify: func(map[string]string, map[string][]string) {}},
+		{
+			desc: "missingTable",
+			modify: func(columns map[string]string, indexes map[string][]string) {
+				for _, c := range []string{"TreeID", "InstanceID", "LeaseExpiry"} {
+					delete(columns, "TreeMasters."+c)
+				}
+			},
+			want: []string{"table TreeMasters is missing"},
+		},
+		{
+			desc: "missingColumnAndIndex",
+			modify: func(columns map[string]string, indexes map[string][]string) {
+				delete(columns, "TreeHeads.TreeMetadata")
+				delete(indexes, "TreeHeads.TreeRevisionIndex")
+			},
+			want: []string{
+				"table TreeHeads is missing column TreeMetadata",
+				"table TreeHeads is missing index TreeRevisionIndex on (TreeID, TreeRevision)",
+			},
+		},
+		{
+			desc: "wrongTypeAndIndexColumns",
+			modify: func(columns map[string]string, indexes map[string][]string) {
+				columns["TreeRoots.Deleted"] = "INT64"
+				indexes["SequencedLeafData.SequenceByMerkleHash"] = []string{"MerkleLeafHash"}
+			},
+			want: []string{
+				"column TreeRoots.Deleted has type INT64, want BOOL",
+				"index SequenceByMerkleHash of table SequencedLeafData is on (MerkleLeafHash), want (TreeID, MerkleLeafHash)",
+			},
+		},
+	} {
+		columns, indexes := spannerSDLSchema()
+		test.modify(columns, indexes)
+		if got := schemaProblems(columns, indexes); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: schemaProblems() = %q, want %q", test.desc, got, test.want)
+		}
+	}
+}
@@ -265,6 +265,10 @@ type LogStorageConfig struct {
 	//
 	// This value must lie in the range [1..256]
 	NumMerkleBuckets int64 `protobuf:"varint,2,opt,name=num_merkle_buckets,json=numMerkleBuckets" json:"num_merkle_buckets,omitempty"`
+	// strata_depths are the depths of the strata of subtrees in which the nodes
+	// of the log are stored, as in storagepb.LogStorageSettings.
+	// This value can't be changed once the tree is created.
+	StrataDepths []int32 `protobuf:"varint,3,rep,packed,name=strata_depths,json=strataDepths" json:"strata_depths,omitempty"`
 }
 
 func (m *LogStorageConfig) Reset()                    { *m = LogStorageConfig{} }
@@ -286,6 +290,13 @@ func (m *LogStorageConfig) GetNumMerkleBuckets() int64 {
 	return 0
 }
 
+func (m *LogStorageConfig) GetStrataDepths() []int32 {
+	if m != nil {
+		return m.StrataDepths
+	}
+	return nil
+}
+
 // MapStorageConfig holds settings which tune the storage implementation for
 // a given map tree.
 type MapStorageConfig struct {
@@ -341,6 +352,17 @@ type TreeInfo struct {
 	Deleted bool `protobuf:"varint,18,opt,name=deleted" json:"deleted,omitempty"`
 	// Time of tree deletion, if any.
 	DeleteTimeNanos int64 `protobuf:"varint,19,opt,name=delete_time_nanos,json=deleteTimeNanos" json:"delete_time_nanos,omitempty"`
+	// labels are the free-form labels of the tree.
+	Labels map[string]string `protobuf:"bytes,20,rep,name=labels" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// If true the tree is in maintenance.
+	Maintenance bool `protobuf:"varint,21,opt,name=maintenance" json:"maintenance,omitempty"`
+	// How long clients should wait before retrying reads of a tree in
+	// maintenance.
+	MaintenanceRetryAfterMillis int64 `protobuf:"varint,22,opt,name=maintenance_retry_after_millis,json=maintenanceRetryAfterMillis" json:"maintenance_retry_after_millis,omitempty"`
+	// Why the tree is in maintenance.
+	MaintenanceReason string `protobuf:"bytes,23,opt,name=maintenance_reason,json=maintenanceReason" json:"maintenance_reason,omitempty"`
+	// The trillian.WriteVerification value of the tree.
+	WriteVerification int32 `protobuf:"varint,24,opt,name=write_verification,json=writeVerification" json:"write_verification,omitempty"`
 }
 
 func (m *TreeInfo) Reset()                    { *m = TreeInfo{} }
@@ -493,6 +515,13 @@ func (m *TreeInfo) GetDeleteTimeNanos() int64 {
 	return 0
 }
 
+func (m *TreeInfo) GetLabels() map[string]string {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
 // XXX_OneofFuncs is for the internal use of the proto package.
 func (*TreeInfo) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
 	return _TreeInfo_OneofMarshaler, _TreeInfo_OneofUnmarshaler, _TreeInfo_OneofSizer, []interface{}{
@@ -567,6 +596,34 @@ func _TreeInfo_OneofSizer(msg proto.Message) (n int) {
 	return n
 }
 
+func (m *TreeInfo) GetMaintenance() bool {
+	if m != nil {
+		return m.Maintenance
+	}
+	return false
+}
+
+func (m *TreeInfo) GetMaintenanceRetryAfterMillis() int64 {
+	if m != nil {
+		return m.MaintenanceRetryAfterMillis
+	}
+	return 0
+}
+
+func (m *TreeInfo) GetMaintenanceReason() string {
+	if m != nil {
+		return m.MaintenanceReason
+	}
+	return ""
+}
+
+func (m *TreeInfo) GetWriteVerification() int32 {
+	if m != nil {
+		return m.WriteVerification
+	}
+	return 0
+}
+
 // TreeHead is the storage format for Trillian's commitment to a particular
 // tree state.
 type TreeHead struct {
@@ -585,7 +642,8 @@ type TreeHead struct {
 	TreeRevision int64 `protobuf:"varint,6,opt,name=tree_revision,json=treeRevision" json:"tree_revision,omitempty"`
 	// metadata is a blob of opaque data specific to the personality layer that an
 	// application associates with each TreeHead, and which must be covered by the
-	// tree head signature.  Only used for Maps at present.
+	// tree head signature.  For logs it is set by the RootMetadataFunc
+	// registered with the log package, if any.
 	Metadata *google_protobuf.Any `protobuf:"bytes,7,opt,name=metadata" json:"metadata,omitempty"`
 }
 
@@ -660,71 +718,82 @@ func init() {
 func init() { proto.RegisterFile("spanner.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 1054 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x56, 0xdd, 0x6e, 0xdb, 0x46,
-	0x13, 0xb5, 0x7e, 0x2c, 0x51, 0x63, 0x49, 0xa6, 0xd7, 0x76, 0xc2, 0x24, 0xdf, 0x87, 0x1a, 0x6e,
-	0x0b, 0xb8, 0x42, 0x21, 0xa5, 0x0e, 0xec, 0x34, 0x48, 0x81, 0x82, 0x96, 0xe5, 0x48, 0x56, 0x24,
-	0x19, 0x4b, 0xba, 0x45, 0x72, 0xb3, 0x58, 0x89, 0x6b, 0x8a, 0x30, 0xff, 0x4a, 0x2e, 0x83, 0x28,
-	0x17, 0x7d, 0x82, 0x02, 0xbd, 0xec, 0xeb, 0x16, 0xbb, 0xa4, 0x64, 0x5a, 0x76, 0x6f, 0x8a, 0xde,
-	0xed, 0x9e, 0x39, 0x33, 0xa3, 0x1d, 0x9e, 0x33, 0x36, 0x34, 0xe2, 0x90, 0xfa, 0x3e, 0x8b, 0xda,
-	0x61, 0x14, 0xf0, 0x00, 0xd5, 0xb2, 0x6b, 0x38, 0x7d, 0xfe, 0xcc, 0x0e, 0x02, 0xdb, 0x65, 0x1d,
-	0x19, 0x98, 0x26, 0x37, 0x1d, 0xea, 0x2f, 0x52, 0xd6, 0xe1, 0x5f, 0x45, 0xd8, 0x3e, 0x77, 0x6c,
-	0x87, 0x53, 0xd7, 0x5d, 0x18, 0x8e, 0xed, 0x33, 0x0b, 0xfd, 0x0c, 0xcd, 0x39, 0x8d, 0xe7, 0x84,
-	0xba, 0x76, 0x10, 0x39, 0x7c, 0xee, 0x69, 0x85, 0x83, 0xc2, 0x51, 0xf3, 0x58, 0x6b, 0xaf, 0x4a,
-	0xb6, 0xfb, 0x34, 0x9e, 0xeb, 0xcb, 0x38, 0x6e, 0xcc, 0xf3, 0x57, 0x34, 0x86, 0xdd, 0xd8, 0xb1,
-	0x7d, 0xca, 0x93, 0x88, 0xe5, 0xaa, 0x14, 0x65, 0x95, 0xff, 0xe7, 0xaa, 0x18, 0x4b, 0xd6, 0x5d,
-	0x29, 0x14, 0x3f, 0xc0, 0xd0, 0x35, 0x3c, 0xb9, 0xab, 0x37, 0x73, 0xc2, 0x39, 0x8b, 0x48, 0x9c,
-	0x38, 0x9c, 0x69, 0x65, 0x59, 0xf2, 0xab, 0xc7, 0x4a, 0x76, 0x25, 0xcf, 0x10, 0x34, 0xbc, 0x17,
-	0x3f, 0x82, 0xa2, 0xff, 0x41, 0x6d, 0x85, 0x6b, 0xa5, 0x83, 0xc2, 0x51, 0x1d, 0xdf, 0x01, 0x87,
-	0x2e, 0xa8, 0xef, 0x03, 0xdb, 0xe0, 0x41, 0x44, 0x6d, 0xd6, 0x0d, 0xfc, 0x1b, 0xc7, 0x46, 0x2d,
-	0xd8, 0xf1, 0x13, 0x8f, 0x24, 0x7e, 0xcc, 0x7e, 0x23, 0xd3, 0x64, 0x76, 0xcb, 0x78, 0x2c, 0x87,
-	0x53, 0xc2, 0xdb, 0x7e, 0xe2, 0x5d, 0x0b, 0xfc, 0x2c, 0x85, 0xd1, 0xf7, 0x80, 0x04, 0xd7, 0x63,
-	0xd1, 0xad, 0xcb, 0x56, 0xe4, 0xa2, 0x24, 0xab, 0x7e, 0xe2, 0x8d, 0x64, 0x20, 0x63, 0x1f, 0x22,
-	0x50, 0x47, 0x34, 0xbc, 0xd7, 0xed, 0xf0, 0x8f, 0x2a, 0x28, 0x66, 0xc4, 0xd8, 0xc0, 0xbf, 0x09,
-	0xd0, 0x53, 0xa8, 0xf2, 0x88, 0x31, 0xe2, 0x58, 0x59, 0xc3, 0x8a, 0xb8, 0x0e, 0x2c, 0xb4, 0x0f,
-	0x95, 0x5b, 0xb6, 0x10, 0x78, 0x5a, 0x7b, 0xf3, 0x96, 0x2d, 0x06, 0x16, 0x42, 0x50, 0xf6, 0xa9,
-	0x97, 0xbe, 0xab, 0x86, 0xe5, 0x19, 0x1d, 0xc0, 0x96, 0xc5, 0xe2, 0x59, 0xe4, 0x84, 0xdc, 0x09,
-	0x7c, 0x39, 0xbc, 0x1a, 0xce, 0x43, 0xe8, 0x25, 0xd4, 0x64, 0x17, 0xbe, 0x08, 0x99, 0xb6, 0x29,
-	0x87, 0xbb, 0x9b, 0x1b, 0xae, 0xf8, 0x35, 0xe6, 0x22, 0x64, 0x58, 0xe1, 0xd9, 0x09, 0xbd, 0x02,
-	0x90, 0x19, 0x31, 0xa7, 0x9c, 0x69, 0x8a, 0x4c, 0xd9, 0x5b, 0x4b, 0x31, 0x44, 0x0c, 0xcb, 0xca,
-	0xf2, 0x88, 0x7e, 0x02, 0xa9, 0x18, 0x12, 0xf3, 0x88, 0x72, 0x66, 0x2f, 0xb4, 0x9a, 0xcc, 0x7b,
-	0xba, 0x26, 0x30, 0x23, 0x0b, 0xe3, 0xfa, 0x3c, 0x77, 0x7b, 0x44, 0x9f, 0xf0, 0x9f, 0xe8, 0x73,
-	0xeb, 0xdf, 0xea, 0xb3, 0x05, 0x3b, 0xb3, 0x88, 0x51, 0xce, 0x08, 0x77, 0x3c, 0x46, 0x7c, 0xea,
-	0x07, 0xb1, 0xd6, 0x48, 0x65, 0x91, 0x06, 0x4c, 0xc7, 0x63, 0x63, 0x01, 0x0b, 0x6e, 0x12, 0x5a,
-	0x6b, 0xdc, 0x66, 0xca, 0x4d, 0x03, 0x77, 0xdc, 0x13, 0xd8, 0x0a, 0x23, 0xe7, 0x93, 0x20, 0xdf,
-	0xb2, 0x85, 0xb6, 0x7d, 0x50, 0x38, 0xda, 0x3a, 0xde, 0x6b, 0xa7, 0x6e, 0x6e, 0x2f, 0xdd, 0xdc,
-	0xd6, 0xfd, 0x05, 0x86, 0x8c, 0x38, 0x64, 0x0b, 0xf4, 0x0d, 0x34, 0xc3, 0x64, 0xea, 0x3a, 0x33,
-	0x91, 0x45, 0x2c, 0x16, 0x69, 0xaa, 0x14, 0x77, 0x3d, 0x45, 0x87, 0x6c, 0x71, 0xce, 0x22, 0x34,
-	0x04, 0xe4, 0x06, 0x36, 0x89, 0x53, 0xc9, 0x91, 0x99, 0xd4, 0x9c, 0x56, 0x91, 0x3d, 0x5e, 0xe4,
-	0x66, 0xb0, 0x6e, 0x82, 0xfe, 0x06, 0x56, 0xdd, 0x75, 0x63, 0x0c, 0x01, 0x79, 0x34, 0x5c, 0x2f,
-	0x56, 0x7d, 0x50, 0x6c, 0x5d, 0xe3, 0xa2, 0x98, 0xb7, 0x86, 0xa1, 0xd7, 0xa0, 0x79, 0xf4, 0x33,
-	0x89, 0x82, 0x80, 0x13, 0x2b, 0x89, 0xa8, 0x50, 0x26, 0xf1, 0x1c, 0xd7, 0x75, 0x62, 0x6d, 0x47,
-	0x4e, 0x6a, 0xdf, 0xa3, 0x9f, 0x71, 0x10, 0xf0, 0xf3, 0x2c, 0x3a, 0x92, 0x41, 0xa4, 0x41, 0xd5,
-	0x62, 0x2e, 0xe3, 0xcc, 0xd2, 0xd0, 0x41, 0xe1, 0x48, 0xc1, 0xcb, 0xab, 0x98, 0x7a, 0x7a, 0xcc,
-	0x4f, 0x7d, 0x37, 0x9d, 0x7a, 0x1a, 0x58, 0x4d, 0xfd, 0x4c, 0x85, 0xe6, 0xfd, 0x77, 0x5c, 0x96,
-	0x95, 0xba, 0xda, 0x38, 0xfc, 0xb3, 0x98, 0xda, 0xb1, 0xcf, 0xa8, 0xf5, 0xcf, 0x76, 0x7c, 0x06,
-	0x0a, 0x8f, 0xb3, 0x06, 0xa9, 0x21, 0xab, 0x3c, 0x4e, 0x3f, 0xe7, 0x8b, 0xcc, 0x5c, 0xb1, 0xf3,
-	0x25, 0xf5, 0x65, 0x29, 0xf5, 0x91, 0xe1, 0x7c, 0x61, 0x22, 0x28, 0x1f, 0x2c, 0x94, 0x2a, 0x9d,
-	0x59, 0xc7, 0x8a, 0x00, 0x84, 0x90, 0xd1, 0x8f, 0xf9, 0x4d, 0xa5, 0xc8, 0xa9, 0x3e, 0xcf, 0x4d,
-	0x75, 0x6d, 0x81, 0xe7, 0xb6, 0x18, 0xfa, 0x1a, 0x1a, 0xb2, 0x67, 0xc4, 0x3e, 0x39, 0xb1, 0x30,
-	0x7d, 0x45, 0xf6, 0xad, 0x0b, 0x10, 0x67, 0x18, 0x7a, 0x09, 0x8a, 0xc7, 0x38, 0xb5, 0x28, 0xa7,
-	0xd9, 0x37, 0x7b, 0x5c, 0x64, 0x2b, 0xd6, 0x65, 0x59, 0xd9, 0x54, 0x2b, 0xad, 0xb7, 0x50, 0x5b,
-	0xd9, 0x1b, 0x3d, 0x01, 0x74, 0x3d, 0x1e, 0x8e, 0x27, 0xbf, 0x8e, 0x89, 0x89, 0x7b, 0x3d, 0x62,
-	0x98, 0xba, 0xd9, 0x53, 0x37, 0x10, 0x40, 0x45, 0xef, 0x9a, 0x83, 0x5f, 0x7a, 0x6a, 0x41, 0x9c,
-	0x2f, 0xf0, 0xe4, 0x63, 0x6f, 0xac, 0x16, 0x5b, 0xdf, 0xa5, 0xd3, 0x94, 0x4b, 0x64, 0x0b, 0xaa,
-	0x59, 0xae, 0xba, 0x81, 0xaa, 0x50, 0x7a, 0x3f, 0x79, 0xa7, 0x16, 0xc4, 0x61, 0xa4, 0x5f, 0xa9,
-	0xc5, 0xd6, 0xef, 0x50, 0xcf, 0xaf, 0x03, 0xf4, 0x0c, 0xf6, 0x97, 0xad, 0xfa, 0xba, 0xd1, 0x27,
-	0x86, 0x89, 0x75, 0xb3, 0xf7, 0xee, 0x83, 0xba, 0x81, 0xea, 0xa0, 0xe0, 0x8b, 0x2e, 0x39, 0x7d,
-	0x73, 0x7a, 0xac, 0x16, 0xd0, 0x2e, 0x6c, 0x9b, 0x3d, 0xc3, 0x24, 0x23, 0xfd, 0x4a, 0x32, 0x7b,
-	0x58, 0x2d, 0x8a, 0xec, 0xc9, 0xd9, 0x65, 0xaf, 0x6b, 0x12, 0x7c, 0xd1, 0x15, 0x44, 0x62, 0xf4,
-	0xf5, 0xe3, 0x93, 0x53, 0xb5, 0x84, 0xf6, 0x61, 0xa7, 0x3b, 0x19, 0x0f, 0x86, 0x86, 0x80, 0x4e,
-	0x7e, 0x38, 0x26, 0x02, 0x2e, 0xb7, 0xbe, 0x85, 0xc6, 0xbd, 0x7d, 0x82, 0x14, 0x28, 0x8f, 0x27,
-	0xe3, 0xec, 0x75, 0x59, 0x76, 0xb9, 0xf5, 0x1a, 0xd0, 0xc3, 0x85, 0x81, 0x1a, 0x50, 0xd3, 0xc7,
-	0x93, 0xf1, 0x87, 0xd1, 0xe4, 0xda, 0x48, 0x5f, 0x87, 0x0d, 0x5d, 0x2d, 0xa0, 0x1a, 0x6c, 0xf6,
-	0xba, 0xe7, 0x86, 0xae, 0x96, 0x5a, 0x18, 0xf6, 0x1e, 0xfb, 0xb3, 0x85, 0x34, 0xd8, 0x5b, 0xbe,
-	0xb3, 0x3b, 0xb8, 0xea, 0xf7, 0x30, 0x31, 0xae, 0x07, 0x72, 0xa8, 0x4d, 0x00, 0x6c, 0xe8, 0xcb,
-	0x1f, 0x5e, 0x40, 0x2a, 0xd4, 0x65, 0xb1, 0x25, 0x52, 0x3c, 0x7b, 0xfb, 0xf1, 0x8d, 0xed, 0xf0,
-	0x79, 0x32, 0x6d, 0xcf, 0x02, 0xaf, 0x93, 0xfd, 0x03, 0xc0, 0x23, 0x61, 0x14, 0xea, 0x77, 0x32,
-	0x81, 0x77, 0x66, 0x6e, 0x90, 0x58, 0x99, 0x90, 0x3a, 0x2b, 0x41, 0x4d, 0x2b, 0xf2, 0xb3, 0xbf,
-	0xfa, 0x3b, 0x00, 0x00, 0xff, 0xff, 0x1b, 0x65, 0xa4, 0x03, 0x53, 0x08, 0x00, 0x00,
+	// 1225 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x56, 0x6d, 0x6f, 0xdb, 0x36,
+	0x10, 0x8e, 0xfc, 0xee, 0xb3, 0x93, 0x28, 0x8c, 0xd3, 0xaa, 0xed, 0xb6, 0x1a, 0xd9, 0x06, 0x78,
+	0x46, 0xe7, 0x74, 0x29, 0xfa, 0xb6, 0x0e, 0x18, 0x14, 0xc7, 0xad, 0xdd, 0x34, 0x76, 0x41, 0x39,
+	0x1d, 0xda, 0x2f, 0x04, 0x6d, 0x31, 0xb6, 0x10, 0xbd, 0x78, 0x14, 0x95, 0x55, 0xfd, 0xb0, 0xbf,
+	0x30, 0xec, 0xd3, 0x7e, 0xd1, 0xfe, 0xd7, 0x40, 0x4a, 0x4e, 0x14, 0x27, 0xeb, 0x87, 0x61, 0x9f,
+	0x7c, 0x7c, 0xee, 0xb9, 0x23, 0x79, 0xbc, 0x7b, 0x2c, 0x78, 0x10, 0x8a, 0x80, 0xd3, 0x19, 0xdb,
+	0x9b, 0xba, 0x41, 0x64, 0x87, 0x0b, 0xea, 0xfb, 0x8c, 0xef, 0xa5, 0xbf, 0x8b, 0xc9, 0xd2, 0xea,
+	0x2c, 0x78, 0x20, 0x02, 0x54, 0xbd, 0x70, 0xdc, 0xbd, 0x33, 0x0b, 0x82, 0x99, 0xcb, 0xf6, 0x94,
+	0x63, 0x12, 0x9d, 0xee, 0x51, 0x3f, 0x4e, 0x58, 0xbb, 0x7f, 0xe5, 0x60, 0xf3, 0xd0, 0x99, 0x39,
+	0x82, 0xba, 0x6e, 0x6c, 0x39, 0x33, 0x9f, 0xd9, 0xe8, 0x67, 0xd8, 0x98, 0xd3, 0x70, 0x4e, 0xa8,
+	0x3b, 0x0b, 0xb8, 0x23, 0xe6, 0x9e, 0xa1, 0x35, 0xb5, 0xd6, 0xc6, 0xbe, 0xd1, 0xb9, 0x48, 0xd9,
+	0xe9, 0xd3, 0x70, 0x6e, 0x2e, 0xfd, 0x78, 0x7d, 0x9e, 0x5d, 0xa2, 0x21, 0x6c, 0x87, 0xce, 0xcc,
+	0xa7, 0x22, 0xe2, 0x2c, 0x93, 0x25, 0xa7, 0xb2, 0x7c, 0x99, 0xc9, 0x62, 0x2d, 0x59, 0x97, 0xa9,
+	0x50, 0x78, 0x0d, 0x43, 0x27, 0x70, 0xeb, 0x32, 0xdf, 0xd4, 0x59, 0xcc, 0x19, 0x27, 0x61, 0xe4,
+	0x08, 0x66, 0x14, 0x54, 0xca, 0xfb, 0x37, 0xa5, 0xec, 0x2a, 0x9e, 0x25, 0x69, 0xb8, 0x11, 0xde,
+	0x80, 0xa2, 0x2f, 0xa0, 0x7a, 0x81, 0x1b, 0xf9, 0xa6, 0xd6, 0xaa, 0xe3, 0x4b, 0x60, 0xf7, 0x4f,
+	0x0d, 0xf4, 0x37, 0xc1, 0xcc, 0x4a, 0x6a, 0xde, 0x0d, 0xfc, 0x53, 0x67, 0x86, 0xda, 0xb0, 0xe5,
+	0x47, 0x1e, 0x89, 0xfc, 0x90, 0xfd, 0x4a, 0x26, 0xd1, 0xf4, 0x8c, 0x89, 0x50, 0x55, 0x27, 0x8f,
+	0x37, 0xfd, 0xc8, 0x3b, 0x91, 0xf8, 0x41, 0x02, 0xa3, 0x07, 0x80, 0x24, 0xd7, 0x63, 0xfc, 0xcc,
+	0x65, 0x17, 0xe4, 0x9c, 0x22, 0xeb, 0x7e, 0xe4, 0x1d, 0x2b, 0xc7, 0x92, 0xfd, 0x35, 0xac, 0x87,
+	0x82, 0x53, 0x41, 0x89, 0xcd, 0x16, 0x62, 0x1e, 0x1a, 0xf9, 0x66, 0xbe, 0x55, 0xc4, 0xf5, 0x04,
+	0x3c, 0x54, 0xd8, 0x2e, 0x02, 0xfd, 0x98, 0x2e, 0xae, 0x1c, 0x69, 0xf7, 0xef, 0x2a, 0x54, 0xc6,
+	0x9c, 0xb1, 0x81, 0x7f, 0x1a, 0xa0, 0xdb, 0x50, 0x16, 0x9c, 0x31, 0xe2, 0xd8, 0xe9, 0xa9, 0x4a,
+	0x72, 0x39, 0xb0, 0xd1, 0x0e, 0x94, 0xce, 0x58, 0x2c, 0xf1, 0xe4, 0x00, 0xc5, 0x33, 0x16, 0x0f,
+	0x6c, 0x84, 0xa0, 0xe0, 0x53, 0x2f, 0xb9, 0x7d, 0x15, 0x2b, 0x1b, 0x35, 0xa1, 0x66, 0xb3, 0x70,
+	0xca, 0x9d, 0x85, 0x70, 0x02, 0x5f, 0x95, 0xb8, 0x8a, 0xb3, 0x10, 0x7a, 0x08, 0x55, 0xb5, 0x8b,
+	0x88, 0x17, 0xcc, 0x28, 0xaa, 0x27, 0xd8, 0xce, 0x3c, 0x81, 0x3c, 0xcd, 0x38, 0x5e, 0x30, 0x5c,
+	0x11, 0xa9, 0x85, 0x1e, 0x01, 0xa8, 0x88, 0x50, 0x50, 0xc1, 0x8c, 0x8a, 0x0a, 0x69, 0xac, 0x84,
+	0x58, 0xd2, 0x87, 0xab, 0x62, 0x69, 0xa2, 0x9f, 0x40, 0xf5, 0x15, 0x51, 0x25, 0x60, 0xb3, 0xd8,
+	0xa8, 0xaa, 0xb8, 0xdb, 0x2b, 0x6d, 0x68, 0xa5, 0x6e, 0x5c, 0x9f, 0x67, 0x56, 0x37, 0x74, 0x31,
+	0xfc, 0x2f, 0x5d, 0x5c, 0xfb, 0xaf, 0x5d, 0xdc, 0x86, 0xad, 0x29, 0x67, 0x54, 0x30, 0x22, 0x1c,
+	0x8f, 0x11, 0x9f, 0xfa, 0x41, 0x68, 0xac, 0x27, 0xbd, 0x93, 0x38, 0xc6, 0x8e, 0xc7, 0x86, 0x12,
+	0x96, 0xdc, 0x68, 0x61, 0xaf, 0x70, 0x37, 0x12, 0x6e, 0xe2, 0xb8, 0xe4, 0x3e, 0x86, 0xda, 0x82,
+	0x3b, 0xe7, 0x92, 0x7c, 0xc6, 0x62, 0x63, 0xb3, 0xa9, 0xb5, 0x6a, 0xfb, 0x8d, 0x4e, 0x32, 0xf3,
+	0x9d, 0xe5, 0xcc, 0x77, 0x4c, 0x3f, 0xc6, 0x90, 0x12, 0x8f, 0x58, 0x8c, 0xbe, 0x81, 0x8d, 0x45,
+	0x34, 0x71, 0x9d, 0xa9, 0x8c, 0x22, 0x36, 0xe3, 0x86, 0xae, 0x46, 0xa0, 0x9e, 0xa0, 0x47, 0x2c,
+	0x3e, 0x64, 0x1c, 0x1d, 0x01, 0x72, 0x83, 0x19, 0x49, 0x95, 0x87, 0x4c, 0x55, 0xcf, 0x19, 0x25,
+	0xb5, 0xc7, 0xbd, 0x4c, 0x0d, 0x56, 0x27, 0xa5, 0xbf, 0x86, 0x75, 0x77, 0x05, 0x93, 0xc9, 0x3c,
+	0xba, 0x58, 0x4d, 0x56, 0xbe, 0x96, 0x6c, 0xb5, 0xc7, 0x65, 0x32, 0x6f, 0x05, 0x43, 0x4f, 0xc1,
+	0xf0, 0xe8, 0x47, 0xc2, 0x83, 0x40, 0x10, 0x3b, 0xe2, 0x54, 0x76, 0x26, 0xf1, 0x1c, 0xd7, 0x75,
+	0x42, 0x63, 0x4b, 0x55, 0x6a, 0xc7, 0xa3, 0x1f, 0x71, 0x10, 0x88, 0xc3, 0xd4, 0x7b, 0xac, 0x9c,
+	0xc8, 0x80, 0xb2, 0xcd, 0x5c, 0x26, 0x98, 0x6d, 0xa0, 0xa6, 0xd6, 0xaa, 0xe0, 0xe5, 0x52, 0x56,
+	0x3d, 0x31, 0xb3, 0x55, 0xdf, 0x4e, 0xaa, 0x9e, 0x38, 0x2e, 0xab, 0xfe, 0x14, 0x4a, 0x2e, 0x9d,
+	0x30, 0x37, 0x34, 0x1a, 0xcd, 0x7c, 0xab, 0xb6, 0x7f, 0x7f, 0xa5, 0x9b, 0xe5, 0x38, 0x76, 0xde,
+	0x28, 0x46, 0xcf, 0x17, 0x3c, 0xc6, 0x29, 0x5d, 0x8e, 0x97, 0x47, 0x1d, 0x5f, 0x30, 0x9f, 0xfa,
+	0x53, 0x66, 0xec, 0xa8, 0x23, 0x64, 0x21, 0xd4, 0x85, 0xaf, 0x32, 0x4b, 0xc2, 0x99, 0xe0, 0x31,
+	0xa1, 0xa7, 0x82, 0xf1, 0xe5, 0xfd, 0x6e, 0xa9, 0x33, 0xdd, 0xcb, 0xb0, 0xb0, 0x24, 0x99, 0x92,
+	0x93, 0xde, 0xf2, 0x7b, 0x40, 0x19, 0x37, 0xe1, 0x8c, 0x86, 0x81, 0x6f, 0xdc, 0x56, 0xc3, 0xbc,
+	0x75, 0x25, 0x50, 0x3a, 0x24, 0xfd, 0x37, 0xee, 0x08, 0x46, 0xce, 0x19, 0x77, 0x4e, 0x9d, 0xa9,
+	0x2a, 0x98, 0x61, 0x34, 0xb5, 0x56, 0x11, 0x6f, 0x29, 0xcf, 0xbb, 0x8c, 0xe3, 0xee, 0x73, 0xa8,
+	0x65, 0xee, 0x86, 0x74, 0xc8, 0xcb, 0xd6, 0xd3, 0x54, 0x76, 0x69, 0xa2, 0x06, 0x14, 0xcf, 0xa9,
+	0x1b, 0x31, 0x25, 0x37, 0x55, 0x9c, 0x2c, 0x7e, 0xcc, 0x3d, 0xd3, 0x0e, 0x74, 0xd8, 0xb8, 0xda,
+	0x00, 0xaf, 0x0b, 0x95, 0xba, 0xbe, 0xbe, 0xfb, 0x47, 0x2e, 0xd1, 0xb1, 0x3e, 0xa3, 0xf6, 0xbf,
+	0xeb, 0xd8, 0x1d, 0xa8, 0x88, 0x30, 0x7d, 0x99, 0x44, 0xc9, 0xca, 0x22, 0x4c, 0x5e, 0xe4, 0x5e,
+	0xaa, 0x4a, 0xa1, 0xf3, 0x29, 0x11, 0xb4, 0x7c, 0x22, 0x40, 0x96, 0xf3, 0x89, 0x49, 0xa7, 0xea,
+	0x14, 0x39, 0xe2, 0x4a, 0xd2, 0xea, 0xb8, 0x22, 0x01, 0xa9, 0x00, 0xe8, 0x59, 0xf6, 0x8f, 0xa0,
+	0xa2, 0xda, 0xf1, 0x6e, 0xe6, 0x39, 0x57, 0xfe, 0x1f, 0x33, 0x7f, 0x12, 0x52, 0xb5, 0xd5, 0x9e,
+	0x9c, 0x9d, 0x3b, 0xa1, 0xac, 0x58, 0x49, 0xed, 0x5b, 0x97, 0x20, 0x4e, 0x31, 0xf4, 0x10, 0x2a,
+	0x1e, 0x13, 0xd4, 0xa6, 0x82, 0x1a, 0xe5, 0xcf, 0x4c, 0xe7, 0x05, 0xeb, 0x75, 0xa1, 0x52, 0xd4,
+	0x4b, 0xed, 0x17, 0x50, 0xbd, 0xd0, 0x45, 0x74, 0x0b, 0xd0, 0xc9, 0xf0, 0x68, 0x38, 0xfa, 0x65,
+	0x48, 0xc6, 0xb8, 0xd7, 0x23, 0xd6, 0xd8, 0x1c, 0xf7, 0xf4, 0x35, 0x04, 0x50, 0x32, 0xbb, 0xe3,
+	0xc1, 0xbb, 0x9e, 0xae, 0x49, 0xfb, 0x25, 0x1e, 0x7d, 0xe8, 0x0d, 0xf5, 0x5c, 0xfb, 0xbb, 0xa4,
+	0x9a, 0x4a, 0x7d, 0x6b, 0x50, 0x4e, 0x63, 0xf5, 0x35, 0x54, 0x86, 0xfc, 0x9b, 0xd1, 0x2b, 0x5d,
+	0x93, 0xc6, 0xb1, 0xf9, 0x56, 0xcf, 0xb5, 0x7f, 0x87, 0x7a, 0x56, 0x47, 0xd1, 0x1d, 0xd8, 0x59,
+	0x6e, 0xd5, 0x37, 0xad, 0x3e, 0xb1, 0xc6, 0xd8, 0x1c, 0xf7, 0x5e, 0xbd, 0xd7, 0xd7, 0x50, 0x1d,
+	0x2a, 0xf8, 0x65, 0x97, 0x3c, 0x79, 0xfe, 0x64, 0x5f, 0xd7, 0xd0, 0x36, 0x6c, 0x8e, 0x7b, 0xd6,
+	0x98, 0x1c, 0x9b, 0x6f, 0x15, 0xb3, 0x87, 0xf5, 0x9c, 0x8c, 0x1e, 0x1d, 0xbc, 0xee, 0x75, 0xc7,
+	0x04, 0xbf, 0xec, 0x4a, 0x22, 0xb1, 0xfa, 0xe6, 0xfe, 0xe3, 0x27, 0x7a, 0x1e, 0xed, 0xc0, 0x56,
+	0x77, 0x34, 0x1c, 0x1c, 0x59, 0x12, 0x7a, 0xfc, 0xc3, 0x3e, 0x91, 0x70, 0xa1, 0xfd, 0x2d, 0xac,
+	0x5f, 0x11, 0x62, 0x54, 0x81, 0xc2, 0x70, 0x34, 0x4c, 0x6f, 0x97, 0x46, 0x17, 0xda, 0x4f, 0x01,
+	0x5d, 0x57, 0x5a, 0xb4, 0x0e, 0x55, 0x73, 0x38, 0x1a, 0xbe, 0x3f, 0x1e, 0x9d, 0x58, 0xc9, 0xed,
+	0xb0, 0x65, 0xea, 0x1a, 0xaa, 0x42, 0xb1, 0xd7, 0x3d, 0xb4, 0x4c, 0x3d, 0xdf, 0xc6, 0xd0, 0xb8,
+	0xe9, 0xab, 0x00, 0x19, 0xd0, 0x58, 0xde, 0xb3, 0x3b, 0x78, 0xdb, 0xef, 0x61, 0x62, 0x9d, 0x0c,
+	0x54, 0x51, 0x37, 0x00, 0xb0, 0x65, 0x2e, 0x0f, 0xae, 0x21, 0x1d, 0xea, 0x2a, 0xd9, 0x12, 0xc9,
+	0x1d, 0xbc, 0xf8, 0xf0, 0x7c, 0xe6, 0x88, 0x79, 0x34, 0xe9, 0x4c, 0x03, 0x6f, 0x2f, 0xfd, 0xbe,
+	0x12, 0x5c, 0xce, 0x1e, 0xf5, 0xf7, 0x3e, 0xff, 0xa1, 0x36, 0x29, 0xa9, 0x67, 0x7f, 0xf4, 0xcf,
+	0x00, 0xb8, 0xd2, 0xe2, 0xea, 0xd1, 0x09, 0x00, 0x00,
 }
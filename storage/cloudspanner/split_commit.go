@@ -0,0 +1,366 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudspanner
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"github.com/golang/glog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// A sequencing pass whose inserts don't fit in one transaction, as set by
+// LogStorageOptions.MaxCommitMutations, is committed in several:
+//  1. the first transaction inserts a SplitCommits row for the tree, with the
+//     revision being written, a token unique to the pass, and a first chunk
+//     of the inserts;
+//  2. each following transaction inserts another chunk;
+//  3. the final transaction buffers the other writes of the pass, i.e. the new
+//     tree head and the removal of the sequenced leaves from Unsequenced, and
+//     deletes the SplitCommits row.
+// The inserted SubtreeData and SequencedLeafData rows aren't visible to
+// readers until the final transaction stores the tree head of their revision.
+// Each transaction checks that the SplitCommits row still holds the pass's
+// token, and that the latest tree head is still the one the pass started
+// from, so that neither recovery nor a concurrent pass can be overwritten by
+// it. Read-write transactions which don't split their commit fail while
+// another pass's SplitCommits row exists.
+//
+// If the split commit doesn't complete, e.g. because the signer crashed, the
+// lease in its SplitCommits row, which each of its transactions extends,
+// runs out. The next read-write transaction of the tree then takes the row
+// over by replacing its token, and deletes the inserts it points to, so that
+// the revision can be written again. A row left by a pass whose revision was
+// committed by another writer is just deleted.
+
+const (
+	splitCommitsTbl = "SplitCommits"
+
+	// splitCommitLease is how long a SplitCommits row is left alone after
+	// the last transaction of its pass, before recovery takes it over.
+	splitCommitLease = time.Minute
+
+	// splitCommitSubtreesSQL selects the subtrees written at a revision. It
+	// scans all the subtrees of the tree, but only runs after a split commit
+	// failed.
+	splitCommitSubtreesSQL = `SELECT SubtreeID FROM SubtreeData
+		WHERE TreeID = @tree_id AND Revision = @revision`
+)
+
+// errSplitCommit aborts the transaction of a pass which commitSplit then
+// commits. It's never returned to a caller.
+var errSplitCommit = errors.New("split commit")
+
+var splitCommitsCols = []string{colTreeID, "TreeRevision", "TreeSize", "Token", "LeaseExpiry"}
+
+// newSplitCommitToken returns a random token identifying a split commit, or
+// its recovery.
+func newSplitCommitToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// pendingInsert is the insert of a SubtreeData or SequencedLeafData row,
+// which isn't visible to readers until the tree head of its revision is
+// stored, so can be committed ahead of it.
+type pendingInsert struct {
+	table string
+	cols  []string
+	vals  []interface{}
+}
+
+// insert inserts a row which can be committed ahead of the tree head, or
+// collects it for a split commit if they're enabled.
+func (t *treeTX) insert(table string, cols []string, vals []interface{}) error {
+	if t.collectInserts {
+		t.inserts = append(t.inserts, pendingInsert{table: table, cols: cols, vals: vals})
+		return nil
+	}
+	return t.bufferWrite(spanner.Insert(table, cols, vals))
+}
+
+// bufferWrite buffers ms in the read-write transaction and, if split commits
+// are enabled, records them for the final transaction of a split commit.
+func (t *treeTX) bufferWrite(ms ...*spanner.Mutation) error {
+	stx, ok := t.stx.(*spanner.ReadWriteTransaction)
+	if !ok {
+		return ErrWrongTXType
+	}
+	if t.collectInserts {
+		t.buffered = append(t.buffered, ms...)
+	}
+	return stx.BufferWrite(ms)
+}
+
+// insertMutations returns the number of mutations of inserts, as counted by
+// Spanner, i.e. one per column of each row, ignoring indexes.
+func insertMutations(inserts []pendingInsert) int {
+	n := 0
+	for _, in := range inserts {
+		n += len(in.cols)
+	}
+	return n
+}
+
+// chunkInserts groups inserts into chunks of at most max mutations, except
+// for rows with more columns than max, which get a chunk of their own.
+func chunkInserts(inserts []pendingInsert, max int) [][]*spanner.Mutation {
+	var chunks [][]*spanner.Mutation
+	var chunk []*spanner.Mutation
+	n := 0
+	for _, in := range inserts {
+		if len(chunk) > 0 && n+len(in.cols) > max {
+			chunks = append(chunks, chunk)
+			chunk, n = nil, 0
+		}
+		chunk = append(chunk, spanner.Insert(in.table, in.cols, in.vals))
+		n += len(in.cols)
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// splitCommit holds the writes of a sequencing pass of a tree, which
+// writes its revision rev on top of a tree of size size.
+type splitCommit struct {
+	treeID  int64
+	rev     int64
+	size    int64
+	token   string
+	inserts []pendingInsert
+	final   []*spanner.Mutation
+}
+
+// splitCommitRow returns the SplitCommits row of treeID read in stx, or nil
+// if there is none.
+func splitCommitRow(ctx context.Context, stx *spanner.ReadWriteTransaction, treeID int64) (*spanner.Row, error) {
+	row, err := stx.ReadRow(ctx, splitCommitsTbl, spanner.Key{treeID}, splitCommitsCols)
+	if spanner.ErrCode(err) == codes.NotFound {
+		return nil, nil
+	}
+	return row, err
+}
+
+// checkSplitCommit returns an error unless the SplitCommits row of treeID
+// holds token.
+func checkSplitCommit(ctx context.Context, stx *spanner.ReadWriteTransaction, treeID int64, token string) error {
+	row, err := splitCommitRow(ctx, stx, treeID)
+	if err != nil {
+		return err
+	}
+	var got spanner.NullString
+	if row != nil {
+		if err := row.ColumnByName("Token", &got); err != nil {
+			return err
+		}
+	}
+	if got.StringVal != token {
+		return fmt.Errorf("tree %d: split commit was taken over", treeID)
+	}
+	return nil
+}
+
+// checkNoSplitCommit returns an error if treeID has a SplitCommits row, i.e.
+// another pass is committing in several transactions, or hasn't been
+// recovered yet.
+func checkNoSplitCommit(ctx context.Context, stx *spanner.ReadWriteTransaction, treeID int64) error {
+	row, err := splitCommitRow(ctx, stx, treeID)
+	if err != nil {
+		return err
+	}
+	if row != nil {
+		return status.Errorf(codes.Unavailable, "tree %d: a split commit is in progress", treeID)
+	}
+	return nil
+}
+
+// splitCommitUpdate returns the mutation extending the lease of the
+// SplitCommits row of treeID.
+func splitCommitUpdate(treeID int64) *spanner.Mutation {
+	return spanner.Update(splitCommitsTbl, []string{colTreeID, "LeaseExpiry"}, []interface{}{treeID, time.Now().Add(splitCommitLease)})
+}
+
+// checkTreeHead returns an error unless the latest tree head of treeID has
+// revision rev-1, i.e. is the one a split commit writing revision rev
+// started from.
+func (ls *logStorage) checkTreeHead(ctx context.Context, stx *spanner.ReadWriteTransaction, treeID, rev int64) error {
+	th, err := ls.ts.latestSTH(ctx, stx, treeID)
+	if err != nil {
+		return err
+	}
+	if got, want := th.TreeRevision, rev-1; got != want {
+		return fmt.Errorf("tree %d: tree head changed during split commit: revision %d, want %d", treeID, got, want)
+	}
+	return nil
+}
+
+// commitSplit commits sc in several transactions.
+func (ls *logStorage) commitSplit(ctx context.Context, sc *splitCommit) error {
+	chunks := chunkInserts(sc.inserts, ls.opts.MaxCommitMutations)
+	glog.V(1).Infof("Tree %d: committing %d inserts at revision %d in %d transactions", sc.treeID, len(sc.inserts), sc.rev, len(chunks)+1)
+	for i, chunk := range chunks {
+		if _, err := ls.ts.client.ReadWriteTransaction(ctx, func(ctx context.Context, stx *spanner.ReadWriteTransaction) error {
+			ms := append([]*spanner.Mutation{}, chunk...)
+			if i == 0 {
+				// The insert fails if another pass has a SplitCommits row.
+				ms = append(ms, spanner.Insert(splitCommitsTbl, splitCommitsCols, []interface{}{sc.treeID, sc.rev, sc.size, sc.token, time.Now().Add(splitCommitLease)}))
+			} else {
+				if err := checkSplitCommit(ctx, stx, sc.treeID, sc.token); err != nil {
+					return err
+				}
+				ms = append(ms, splitCommitUpdate(sc.treeID))
+			}
+			if err := ls.checkTreeHead(ctx, stx, sc.treeID, sc.rev); err != nil {
+				return err
+			}
+			return stx.BufferWrite(ms)
+		}); err != nil {
+			return fmt.Errorf("tree %d: split commit of chunk %d/%d failed: %v", sc.treeID, i+1, len(chunks), err)
+		}
+	}
+
+	final := append(sc.final, spanner.Delete(splitCommitsTbl, spanner.Key{sc.treeID}))
+	if _, err := ls.ts.client.ReadWriteTransaction(ctx, func(ctx context.Context, stx *spanner.ReadWriteTransaction) error {
+		if err := checkSplitCommit(ctx, stx, sc.treeID, sc.token); err != nil {
+			return err
+		}
+		if err := ls.checkTreeHead(ctx, stx, sc.treeID, sc.rev); err != nil {
+			return err
+		}
+		return stx.BufferWrite(final)
+	}); err != nil {
+		return fmt.Errorf("tree %d: final transaction of split commit failed: %v", sc.treeID, err)
+	}
+	return nil
+}
+
+// recoverSplitCommit deletes the inserts of a split commit of treeID which
+// didn't complete, if any, once its lease has run out.
+func (ls *logStorage) recoverSplitCommit(ctx context.Context, treeID int64) error {
+	row, err := ls.ts.client.Single().ReadRow(ctx, splitCommitsTbl, spanner.Key{treeID}, splitCommitsCols)
+	switch {
+	case spanner.ErrCode(err) == codes.NotFound:
+		return nil
+	case err != nil:
+		return err
+	}
+	var id, rev, size int64
+	var stale spanner.NullString
+	var expiry spanner.NullTime
+	if err := row.Columns(&id, &rev, &size, &stale, &expiry); err != nil {
+		return err
+	}
+	if expiry.Valid && time.Now().Before(expiry.Time) {
+		// The pass may still be running. Transactions which write to the
+		// tree fail until it completes or its lease runs out.
+		return nil
+	}
+
+	// Take the row over, so that the pass fails if it's still running after
+	// all, and only one transaction recovers it. Rows of revisions which have
+	// been committed, by a concurrent writer, are just deleted.
+	token, err := newSplitCommitToken()
+	if err != nil {
+		return err
+	}
+	var committed bool
+	if _, err := ls.ts.client.ReadWriteTransaction(ctx, func(ctx context.Context, stx *spanner.ReadWriteTransaction) error {
+		committed = false
+		if err := checkSplitCommit(ctx, stx, treeID, stale.StringVal); err != nil {
+			return err
+		}
+		th, err := ls.ts.latestSTH(ctx, stx, treeID)
+		if err != nil {
+			return err
+		}
+		if th.TreeRevision >= rev {
+			committed = true
+			return stx.BufferWrite([]*spanner.Mutation{spanner.Delete(splitCommitsTbl, spanner.Key{treeID})})
+		}
+		return stx.BufferWrite([]*spanner.Mutation{
+			spanner.Update(splitCommitsTbl, []string{colTreeID, "Token", "LeaseExpiry"}, []interface{}{treeID, token, time.Now().Add(splitCommitLease)}),
+		})
+	}); err != nil {
+		return fmt.Errorf("tree %d: failed to take over incomplete split commit: %v", treeID, err)
+	}
+	if committed {
+		glog.Warningf("Tree %d: deleted the SplitCommits row of revision %d, which was committed by another writer", treeID, rev)
+		return nil
+	}
+	glog.Warningf("Tree %d: deleting the inserts of an incomplete split commit of revision %d", treeID, rev)
+
+	query := spanner.NewStatement(splitCommitSubtreesSQL)
+	query.Params["tree_id"] = treeID
+	query.Params["revision"] = rev
+	var subtreeIDs [][]byte
+	if err := ls.ts.client.Single().Query(ctx, query).Do(func(r *spanner.Row) error {
+		var id []byte
+		if err := r.Columns(&id); err != nil {
+			return err
+		}
+		subtreeIDs = append(subtreeIDs, id)
+		return nil
+	}); err != nil {
+		return fmt.Errorf("tree %d: failed to list subtrees of incomplete split commit: %v", treeID, err)
+	}
+
+	// Delete the subtrees in chunks, and the leaves and the SplitCommits row
+	// in the last transaction, after checking each time that the row is still
+	// held by this recovery, and the revision still hasn't been committed. If
+	// recovery fails, the row is taken over again once its lease runs out.
+	var chunks [][]*spanner.Mutation
+	for len(subtreeIDs) > 0 {
+		n := len(subtreeIDs)
+		if n > ls.opts.MaxCommitMutations {
+			n = ls.opts.MaxCommitMutations
+		}
+		chunk := make([]*spanner.Mutation, 0, n+1)
+		for _, id := range subtreeIDs[:n] {
+			chunk = append(chunk, spanner.Delete(subtreeTbl, spanner.Key{treeID, id, rev}))
+		}
+		chunks = append(chunks, append(chunk, splitCommitUpdate(treeID)))
+		subtreeIDs = subtreeIDs[n:]
+	}
+	chunks = append(chunks, []*spanner.Mutation{
+		spanner.Delete(seqDataTbl, spanner.KeyRange{Start: spanner.Key{treeID, size}, End: spanner.Key{treeID}, Kind: spanner.ClosedClosed}),
+		spanner.Delete(splitCommitsTbl, spanner.Key{treeID}),
+	})
+	for _, chunk := range chunks {
+		if _, err := ls.ts.client.ReadWriteTransaction(ctx, func(ctx context.Context, stx *spanner.ReadWriteTransaction) error {
+			if err := checkSplitCommit(ctx, stx, treeID, token); err != nil {
+				return err
+			}
+			if err := ls.checkTreeHead(ctx, stx, treeID, rev); err != nil {
+				return err
+			}
+			return stx.BufferWrite(chunk)
+		}); err != nil {
+			return fmt.Errorf("tree %d: failed to delete inserts of incomplete split commit: %v", treeID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudspanner
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChunkInserts(t *testing.T) {
+	row := func(cols int) pendingInsert {
+		return pendingInsert{table: "T", cols: make([]string, cols), vals: make([]interface{}, cols)}
+	}
+	for _, test := range []struct {
+		desc    string
+		inserts []pendingInsert
+		max     int
+		want    []int
+	}{
+		{desc: "empty", max: 10},
+		{desc: "oneChunk", inserts: []pendingInsert{row(4), row(5)}, max: 10, want: []int{2}},
+		{desc: "exactlyFull", inserts: []pendingInsert{row(5), row(5), row(5)}, max: 10, want: []int{2, 1}},
+		{desc: "several", inserts: []pendingInsert{row(4), row(4), row(4), row(4), row(4)}, max: 10, want: []int{2, 2, 1}},
+		{desc: "rowLargerThanMax", inserts: []pendingInsert{row(2), row(12), row(2)}, max: 10, want: []int{1, 1, 1}},
+	} {
+		var got []int
+		for _, chunk := range chunkInserts(test.inserts, test.max) {
+			got = append(got, len(chunk))
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: chunkInserts() chunk sizes = %v, want %v", test.desc, got, test.want)
+		}
+	}
+}
+
+func TestInsertMutations(t *testing.T) {
+	row := func(cols int) pendingInsert {
+		return pendingInsert{table: "T", cols: make([]string, cols), vals: make([]interface{}, cols)}
+	}
+	if got, want := insertMutations([]pendingInsert{row(4), row(5)}), 9; got != want {
+		t.Errorf("insertMutations() = %v, want %v", got, want)
+	}
+}
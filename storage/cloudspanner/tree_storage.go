@@ -17,8 +17,11 @@ package cloudspanner
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"hash/fnv"
+	"strings"
 	"sync"
 	"time"
 
@@ -27,6 +30,7 @@ import (
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes/any"
 	"github.com/google/trillian"
+	"github.com/google/trillian/monitoring"
 	"github.com/google/trillian/storage"
 	"github.com/google/trillian/storage/cache"
 	"github.com/google/trillian/storage/cloudspanner/spannerpb"
@@ -68,6 +72,55 @@ const (
 	colRevision  = "Revision"
 )
 
+// MaxTreeHeadShards is the maximum number of shards of TreeHeadShards. Reads
+// of the latest tree head look at this many shards, whatever the number tree
+// heads are written to, so that it can be changed without downtime.
+const MaxTreeHeadShards = 16
+
+// currentTreeHeadsTbl holds the latest tree head of each tree.
+const currentTreeHeadsTbl = "CurrentTreeHeads"
+
+// treeHeadCols are the columns of TreeHeads, TreeHeadShards and
+// CurrentTreeHeads read by latestSTH.
+var treeHeadCols = []string{"TreeID", "TimestampNanos", "TreeSize", "RootHash", "RootSignature", "TreeRevision", "TreeMetadata"}
+
+// latestSTHSQL selects the newest tree head of a tree from TreeHeads and all
+// the shards of TreeHeadShards, in one round trip. Each subquery reads a
+// single row, from the start of its key range.
+var latestSTHSQL = func() string {
+	cols := strings.Join(treeHeadCols, ", ")
+	subqueries := []string{fmt.Sprintf("(SELECT %s FROM TreeHeads WHERE TreeID = @tree_id ORDER BY TimestampNanos DESC LIMIT 1)", cols)}
+	for shard := 0; shard < MaxTreeHeadShards; shard++ {
+		subqueries = append(subqueries, fmt.Sprintf("(SELECT %s FROM TreeHeadShards WHERE TreeID = @tree_id AND Shard = %d ORDER BY TimestampNanos DESC LIMIT 1)", cols, shard))
+	}
+	return fmt.Sprintf("SELECT %s FROM (%s) ORDER BY TimestampNanos DESC LIMIT 1", cols, strings.Join(subqueries, " UNION ALL "))
+}()
+
+// DefaultReadOnlyStaleness is the staleness recommended by Cloud Spanner for
+// reads which can tolerate stale data. Reads at least this stale can be
+// served by the nearest replica, including the read-only replicas of
+// multi-region instances, without a round trip to the leader region. See
+// https://cloud.google.com/spanner/docs/reads#read_types.
+const DefaultReadOnlyStaleness = 15 * time.Second
+
+var (
+	hedgedReads     monitoring.Counter
+	hedgeWins       monitoring.Counter
+	commitLatency   monitoring.Histogram
+	metricsInitOnce sync.Once
+)
+
+func initMetrics(mf monitoring.MetricFactory) {
+	metricsInitOnce.Do(func() {
+		if mf == nil {
+			mf = monitoring.InertMetricFactory{}
+		}
+		hedgedReads = mf.NewCounter("cloudspanner_subtree_hedged_reads", "Number of subtree reads for which a hedged read was sent")
+		hedgeWins = mf.NewCounter("cloudspanner_subtree_hedge_wins", "Number of subtree reads answered by the hedged read")
+		commitLatency = mf.NewHistogramWithBuckets("cloudspanner_commit_latency", "Latency of read-write transactions and writes in seconds, including commit", monitoring.LatencyBuckets(), "region", "op")
+	})
+}
+
 // treeStorage provides a shared base for the concrete CloudSpanner-backed
 // implementation of the Trillian storage.LogStorage and storage.MapStorage
 // interfaces.
@@ -80,17 +133,85 @@ type treeStorage struct {
 // TreeStorageOptions holds various levers for configuring the tree storage instance.
 type TreeStorageOptions struct {
 	// ReadOnlyStaleness controls how far in the past a read-only snapshot
-	// transaction will read.
+	// transaction will read. Zero means strong reads.
 	// This is intended to allow Spanner to use local replicas for read requests
-	// to help with performance.
+	// to help with performance; see DefaultReadOnlyStaleness.
 	// See https://cloud.google.com/spanner/docs/timestamp-bounds for more details.
 	ReadOnlyStaleness time.Duration
+
+	// ReadOnlyClient, if set, is used for read-only snapshot transactions
+	// instead of the client the storage was created with, e.g. a client of a
+	// regional endpoint close to the replicas serving reads. Read-write
+	// transactions always use the main client, which routes them to the
+	// leader region. It must be a client of the same database.
+	ReadOnlyClient *spanner.Client
+
+	// Region is the region the storage is used from, and labels the commit
+	// latency metric so that regions of a multi-region instance can be
+	// compared.
+	Region string
+
+	// HedgeDelay enables hedging of the subtree reads of read-only
+	// transactions, i.e. those serving proofs, if non-zero. When a subtree
+	// read takes longer than HedgeDelay, a second read of the subtree at the
+	// same timestamp is sent in a new single-use transaction, which uses
+	// another session and possibly another replica, and the first of the two
+	// reads to complete is used.
+	HedgeDelay time.Duration
+
+	// MetricFactory is used to create the storage's metrics. If nil, metrics
+	// aren't exported.
+	MetricFactory monitoring.MetricFactory
+
+	// TreeHeadShards, if non-zero, is the number of shards of TreeHeadShards
+	// that new tree heads are written to, bucketed by a hash of their
+	// revision, rather than to TreeHeads, to spread the writes of very active
+	// trees over several Spanner splits. Tree heads are always read from both
+	// tables, so this can be set, changed or reset on running deployments,
+	// once all servers read TreeHeadShards. At most MaxTreeHeadShards.
+	TreeHeadShards int
+
+	// ReadCurrentTreeHead makes transactions read the latest tree head with a
+	// point read of CurrentTreeHeads, which StoreSignedLogRoot keeps up to
+	// date, rather than by querying the tree head tables. Trees without a row
+	// in CurrentTreeHeads fall back to the query, and read-write transactions
+	// then repair their row. Only set it once no server which doesn't write
+	// CurrentTreeHeads runs against the database, as the rows would go stale.
+	ReadCurrentTreeHead bool
 }
 
 func newTreeStorageWithOpts(client *spanner.Client, opts TreeStorageOptions) *treeStorage {
+	initMetrics(opts.MetricFactory)
+	if opts.TreeHeadShards > MaxTreeHeadShards {
+		glog.Warningf("TreeHeadShards = %d, using the maximum of %d", opts.TreeHeadShards, MaxTreeHeadShards)
+		opts.TreeHeadShards = MaxTreeHeadShards
+	}
 	return &treeStorage{client: client, admin: nil, opts: opts}
 }
 
+// readClient returns the client for read-only transactions.
+func (t *treeStorage) readClient() *spanner.Client {
+	if t.opts.ReadOnlyClient != nil {
+		return t.opts.ReadOnlyClient
+	}
+	return t.client
+}
+
+// readOnlyTransaction returns a new read-only snapshot transaction, which
+// reads ReadOnlyStaleness in the past.
+func (t *treeStorage) readOnlyTransaction() *spanner.ReadOnlyTransaction {
+	stx := t.readClient().ReadOnlyTransaction()
+	if t.opts.ReadOnlyStaleness > 0 {
+		stx = stx.WithTimestampBound(spanner.ExactStaleness(t.opts.ReadOnlyStaleness))
+	}
+	return stx
+}
+
+// observeCommit records the latency of a write started at start.
+func (t *treeStorage) observeCommit(op string, start time.Time) {
+	commitLatency.Observe(time.Since(start).Seconds(), t.opts.Region, op)
+}
+
 type spanRead interface {
 	Query(context.Context, spanner.Statement) *spanner.RowIterator
 	Read(ctx context.Context, table string, keys spanner.KeySet, columns []string) *spanner.RowIterator
@@ -98,38 +219,41 @@ type spanRead interface {
 	ReadRow(ctx context.Context, table string, key spanner.Key, columns []string) (*spanner.Row, error)
 }
 
-// latestSTH reads and returns the newest STH.
+// treeHeadShard returns the shard of TreeHeadShards which the tree head at
+// revision is written to.
+func treeHeadShard(revision int64, shards int) int64 {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(revision))
+	h := fnv.New64a()
+	h.Write(b[:])
+	return int64(h.Sum64() % uint64(shards))
+}
+
+// latestSTH reads and returns the newest STH, from CurrentTreeHeads if
+// ReadCurrentTreeHead is set and the tree has a row there, otherwise from
+// TreeHeads or TreeHeadShards.
 func (t *treeStorage) latestSTH(ctx context.Context, stx spanRead, treeID int64) (*spannerpb.TreeHead, error) {
-	query := spanner.NewStatement(
-		"SELECT t.TreeID, t.TimestampNanos, t.TreeSize, t.RootHash, t.RootSignature, t.TreeRevision, t.TreeMetadata FROM TreeHeads t" +
-			"   WHERE t.TreeID = @tree_id" +
-			"   ORDER BY t.TimestampNanos DESC " +
-			"   LIMIT 1")
+	if t.opts.ReadCurrentTreeHead {
+		row, err := stx.ReadRow(ctx, currentTreeHeadsTbl, spanner.Key{treeID}, treeHeadCols)
+		switch {
+		case spanner.ErrCode(err) == codes.NotFound:
+		case err != nil:
+			return nil, err
+		default:
+			return treeHeadFromRow(row)
+		}
+	}
+
+	query := spanner.NewStatement(latestSTHSQL)
 	query.Params["tree_id"] = treeID
 
 	var th *spannerpb.TreeHead
 	rows := stx.Query(ctx, query)
 	defer rows.Stop()
 	err := rows.Do(func(r *spanner.Row) error {
-		tth := &spannerpb.TreeHead{}
-		var sig, meta []byte
-		if err := r.Columns(&tth.TreeId, &tth.TsNanos, &tth.TreeSize, &tth.RootHash, &sig, &tth.TreeRevision, &meta); err != nil {
-			return err
-		}
-		sigPB := &spannerpb.DigitallySigned{}
-		if err := proto.Unmarshal(sig, sigPB); err != nil {
-			return err
-		}
-		tth.Signature = sigPB
-
-		metaPB := &any.Any{}
-		if err := proto.Unmarshal(meta, metaPB); err != nil {
-			return err
-		}
-		tth.Metadata = metaPB
-
-		th = tth
-		return nil
+		var err error
+		th, err = treeHeadFromRow(r)
+		return err
 	})
 	if err != nil {
 		return nil, err
@@ -138,9 +262,74 @@ func (t *treeStorage) latestSTH(ctx context.Context, stx spanRead, treeID int64)
 		glog.Warningf("no head found for treeID %v", treeID)
 		return nil, storage.ErrTreeNeedsInit
 	}
+	if rwtx, ok := stx.(*spanner.ReadWriteTransaction); ok && t.opts.ReadCurrentTreeHead {
+		vals, err := treeHeadValues(th)
+		if err != nil {
+			return nil, err
+		}
+		if err := rwtx.BufferWrite([]*spanner.Mutation{spanner.InsertOrUpdate(currentTreeHeadsTbl, treeHeadCols, vals)}); err != nil {
+			return nil, err
+		}
+	}
 	return th, nil
 }
 
+// treeHeadFromRow returns the tree head of a row with treeHeadCols.
+func treeHeadFromRow(r *spanner.Row) (*spannerpb.TreeHead, error) {
+	th := &spannerpb.TreeHead{}
+	var sig, meta []byte
+	if err := r.Columns(&th.TreeId, &th.TsNanos, &th.TreeSize, &th.RootHash, &sig, &th.TreeRevision, &meta); err != nil {
+		return nil, err
+	}
+	sigPB := &spannerpb.DigitallySigned{}
+	if err := proto.Unmarshal(sig, sigPB); err != nil {
+		return nil, err
+	}
+	th.Signature = sigPB
+
+	metaPB := &any.Any{}
+	if err := proto.Unmarshal(meta, metaPB); err != nil {
+		return nil, err
+	}
+	th.Metadata = metaPB
+	return th, nil
+}
+
+// rootMetadata returns the metadata of a root read by treeHeadFromRow, which
+// is nil rather than empty if the root has none.
+func rootMetadata(meta *any.Any) *any.Any {
+	if meta == nil || (meta.TypeUrl == "" && len(meta.Value) == 0) {
+		return nil
+	}
+	return meta
+}
+
+// treeHeadValues returns the values of treeHeadCols for th.
+func treeHeadValues(th *spannerpb.TreeHead) ([]interface{}, error) {
+	if th.Signature == nil {
+		return nil, errors.New("sth signature is nil")
+	}
+	sigBytes, err := proto.Marshal(th.Signature)
+	if err != nil {
+		return nil, err
+	}
+	var metaBytes []byte
+	if th.Metadata != nil {
+		if metaBytes, err = proto.Marshal(th.Metadata); err != nil {
+			return nil, err
+		}
+	}
+	return []interface{}{
+		th.TreeId,
+		th.TsNanos,
+		th.TreeSize,
+		th.RootHash,
+		sigBytes,
+		th.TreeRevision,
+		metaBytes,
+	}, nil
+}
+
 type newCacheFn func(*trillian.Tree) (cache.SubtreeCache, error)
 
 func (t *treeStorage) getTreeAndConfig(ctx context.Context, treeID int64, opts trees.GetOpts) (*trillian.Tree, proto.Message, error) {
@@ -217,6 +406,20 @@ type treeTX struct {
 	cache cache.SubtreeCache
 
 	getLatestRootOnce sync.Once
+
+	// collectInserts is set if the transaction may be committed as a split
+	// commit, in which case the inserts which can be committed ahead of the
+	// tree head are collected in inserts rather than buffered, and the other
+	// writes recorded in buffered.
+	collectInserts bool
+	inserts        []pendingInsert
+	buffered       []*spanner.Mutation
+
+	// written maps the prefixes of the subtrees stored by the transaction, at
+	// _writeRev, to copies of their latest contents. Spanner doesn't return
+	// buffered writes to reads of the transaction that made them, so reads
+	// of subtrees at _writeRev are served from here instead.
+	written map[string]*storagepb.SubtreeProto
 }
 
 func (t *treeTX) currentSTH(ctx context.Context) (*spannerpb.TreeHead, error) {
@@ -236,10 +439,12 @@ func (t *treeTX) writeRev(ctx context.Context) (int64, error) {
 // storeSubtrees adds buffered writes to the in-flight transaction to store the
 // passed in subtrees.
 func (t *treeTX) storeSubtrees(sts []*storagepb.SubtreeProto) error {
-	stx, ok := t.stx.(*spanner.ReadWriteTransaction)
-	if !ok {
+	if _, ok := t.stx.(*spanner.ReadWriteTransaction); !ok {
 		return ErrWrongTXType
 	}
+	if t.written == nil {
+		t.written = make(map[string]*storagepb.SubtreeProto)
+	}
 	for _, st := range sts {
 		if st == nil {
 			continue
@@ -248,18 +453,53 @@ func (t *treeTX) storeSubtrees(sts []*storagepb.SubtreeProto) error {
 		if err != nil {
 			return err
 		}
-		m := spanner.Insert(
-			subtreeTbl,
-			[]string{colTreeID, colSubtreeID, colRevision, colSubtree},
-			[]interface{}{t.treeID, st.Prefix, t._writeRev, stBytes},
-		)
-		if err := stx.BufferWrite([]*spanner.Mutation{m}); err != nil {
+		cols := []string{colTreeID, colSubtreeID, colRevision, colSubtree}
+		vals := []interface{}{t.treeID, st.Prefix, t._writeRev, stBytes}
+		if _, ok := t.written[string(st.Prefix)]; ok {
+			err = t.rewriteSubtree(cols, vals)
+		} else {
+			err = t.insert(subtreeTbl, cols, vals)
+		}
+		if err != nil {
 			return err
 		}
+		t.written[string(st.Prefix)] = proto.Clone(st).(*storagepb.SubtreeProto)
 	}
 	return nil
 }
 
+// rewriteSubtree replaces the write of a subtree already stored by an
+// earlier flush of the transaction.
+func (t *treeTX) rewriteSubtree(cols []string, vals []interface{}) error {
+	if !t.collectInserts {
+		return t.bufferWrite(spanner.InsertOrUpdate(subtreeTbl, cols, vals))
+	}
+	for i, in := range t.inserts {
+		if in.table == subtreeTbl && bytes.Equal(in.vals[1].([]byte), vals[1].([]byte)) {
+			t.inserts[i].vals = vals
+			return nil
+		}
+	}
+	return fmt.Errorf("internal error: no insert of rewritten subtree %x", vals[1])
+}
+
+// writtenSubtree returns a copy of the subtree id stored by the transaction,
+// or nil if it hasn't stored it or rev is older than its write revision.
+func (t *treeTX) writtenSubtree(rev int64, id storage.NodeID) (*storagepb.SubtreeProto, error) {
+	if len(t.written) == 0 || rev < t._writeRev {
+		return nil, nil
+	}
+	stID, err := subtreeKey(id)
+	if err != nil {
+		return nil, err
+	}
+	st, ok := t.written[string(stID)]
+	if !ok {
+		return nil, nil
+	}
+	return proto.Clone(st).(*storagepb.SubtreeProto), nil
+}
+
 func (t *treeTX) flushSubtrees() error {
 	return t.cache.Flush(t.storeSubtrees)
 }
@@ -357,9 +597,84 @@ func subtreeKey(id storage.NodeID) ([]byte, error) {
 }
 
 // getSubtree retrieves the most recent subtree specified by id at (or below)
-// the requested revision.
+// the requested revision, hedging the read if configured to. Subtrees stored
+// by the transaction itself are returned from its written subtrees.
+// If no such subtree exists it returns nil.
+func (t *treeTX) getSubtree(ctx context.Context, rev int64, id storage.NodeID) (*storagepb.SubtreeProto, error) {
+	if st, err := t.writtenSubtree(rev, id); st != nil || err != nil {
+		return st, err
+	}
+	if hedge := t.hedgeReader(); hedge != nil {
+		return t.getSubtreeHedged(ctx, hedge, rev, id)
+	}
+	return t.readSubtree(ctx, t.stx, rev, id)
+}
+
+// hedgeReader returns a single-use transaction reading at the same timestamp
+// as this one, for hedged reads, or nil if reads shouldn't or can't be hedged.
+// Only read-only transactions, which have a fixed timestamp, are hedged.
+func (t *treeTX) hedgeReader() spanRead {
+	if t.ts.opts.HedgeDelay <= 0 {
+		return nil
+	}
+	stx, ok := t.stx.(*spanner.ReadOnlyTransaction)
+	if !ok {
+		return nil
+	}
+	// The timestamp is only known once the transaction has read something,
+	// normally the tree's latest root.
+	readTime, err := stx.Timestamp()
+	if err != nil {
+		return nil
+	}
+	return t.ts.readClient().Single().WithTimestampBound(spanner.ReadTimestamp(readTime))
+}
+
+// getSubtreeHedged reads a subtree through the transaction and, if that
+// takes longer than HedgeDelay, through hedge as well, returning the first
+// successful result.
+func (t *treeTX) getSubtreeHedged(ctx context.Context, hedge spanRead, rev int64, id storage.NodeID) (*storagepb.SubtreeProto, error) {
+	// Cancel the slower read once we have a result.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		st     *storagepb.SubtreeProto
+		err    error
+		hedged bool
+	}
+	c := make(chan result, 2)
+	read := func(stx spanRead, hedged bool) {
+		st, err := t.readSubtree(ctx, stx, rev, id)
+		c <- result{st: st, err: err, hedged: hedged}
+	}
+	go read(t.stx, false)
+
+	timer := time.NewTimer(t.ts.opts.HedgeDelay)
+	defer timer.Stop()
+	select {
+	case r := <-c:
+		return r.st, r.err
+	case <-timer.C:
+	}
+
+	hedgedReads.Inc()
+	go read(hedge, true)
+	r := <-c
+	if r.err != nil {
+		// Give the other read a chance to succeed.
+		r = <-c
+	}
+	if r.err == nil && r.hedged {
+		hedgeWins.Inc()
+	}
+	return r.st, r.err
+}
+
+// readSubtree reads the most recent subtree specified by id at (or below) the
+// requested revision through stx.
 // If no such subtree exists it returns nil.
-func (t *treeTX) getSubtree(ctx context.Context, rev int64, id storage.NodeID) (p *storagepb.SubtreeProto, e error) {
+func (t *treeTX) readSubtree(ctx context.Context, stx spanRead, rev int64, id storage.NodeID) (*storagepb.SubtreeProto, error) {
 	stID, err := subtreeKey(id)
 	if err != nil {
 		return nil, err
@@ -367,34 +682,19 @@ func (t *treeTX) getSubtree(ctx context.Context, rev int64, id storage.NodeID) (
 
 	var ret *storagepb.SubtreeProto
 	prefix := spanner.Key{t.treeID, stID}.AsPrefix()
-	rows := t.stx.Read(ctx, subtreeTbl, prefix, []string{colRevision, colSubtree})
+	rows := stx.Read(ctx, subtreeTbl, prefix, []string{colRevision, colSubtree})
 	err = rows.Do(func(r *spanner.Row) error {
 		var rRev int64
-		var st storagepb.SubtreeProto
 		stBytes := make([]byte, 1<<20)
 		if err = r.Columns(&rRev, &stBytes); err != nil {
 			return err
 		}
-		if err = proto.Unmarshal(stBytes, &st); err != nil {
-			return err
-		}
-
 		if rRev > rev {
 			// Too new, skip this row and wait for the next.
 			return nil
 		}
-		if got, want := stID, st.Prefix; !bytes.Equal(got, want) {
-			return fmt.Errorf("got subtree with prefix %v, wanted %v", got, want)
-		}
-		if got, want := rRev, rev; got > rev {
-			return fmt.Errorf("got subtree rev %d, wanted <= %d", got, want)
-		}
-		ret = &st
-
-		// If this is a subtree with a zero-length prefix, we'll need to create an
-		// empty Prefix field:
-		if st.Prefix == nil && len(stID) == 0 {
-			st.Prefix = []byte{}
+		if ret, err = decodeSubtree(stID, stBytes); err != nil {
+			return err
 		}
 		// We've got what we want, tell spanner to stop reading by returning
 		// not-really-an-error:
@@ -406,6 +706,24 @@ func (t *treeTX) getSubtree(ctx context.Context, rev int64, id storage.NodeID) (
 	return ret, err
 }
 
+// decodeSubtree unmarshals the subtree read from the row of the subtree with
+// prefix stID, and checks that it's the subtree which was asked for.
+func decodeSubtree(stID, stBytes []byte) (*storagepb.SubtreeProto, error) {
+	var st storagepb.SubtreeProto
+	if err := proto.Unmarshal(stBytes, &st); err != nil {
+		return nil, err
+	}
+	if got, want := stID, st.Prefix; !bytes.Equal(got, want) {
+		return nil, fmt.Errorf("got subtree with prefix %v, wanted %v", got, want)
+	}
+	// If this is a subtree with a zero-length prefix, we'll need to create an
+	// empty Prefix field:
+	if st.Prefix == nil && len(stID) == 0 {
+		st.Prefix = []byte{}
+	}
+	return &st, nil
+}
+
 // GetMerkleNodes returns the requested set of nodes at, or before, the
 // specified tree revision.
 func (t *treeTX) GetMerkleNodes(ctx context.Context, rev int64, ids []storage.NodeID) ([]storage.Node, error) {
@@ -415,44 +733,49 @@ func (t *treeTX) GetMerkleNodes(ctx context.Context, rev int64, ids []storage.No
 		return nil, ErrTransactionClosed
 	}
 
-	return t.cache.GetNodes(ids,
-		func(ids []storage.NodeID) ([]*storagepb.SubtreeProto, error) {
-			// Request the various subtrees in parallel.
-			// c will carry any retrieved subtrees
-			c := make(chan *storagepb.SubtreeProto, len(ids))
-			// err will carry any errors encountered while reading from spanner,
-			// although we'll only return to the caller the first one (if indeed
-			// there are any).
-			errc := make(chan error, len(ids))
-
-			// Spawn goroutines for each request
-			for _, id := range ids {
-				id := id
-				go func() {
-					st, err := t.getSubtree(ctx, rev, id)
-					if err != nil {
-						errc <- err
-						return
-					}
-					c <- st
-				}()
-			}
+	return t.cache.GetNodes(ids, t.getSubtreesAtRev(ctx, rev))
+}
 
-			// Now wait for the goroutines to signal their completion, and collect
-			// the results.
-			ret := make([]*storagepb.SubtreeProto, 0, len(ids))
-			for range ids {
-				select {
-				case err := <-errc:
-					return nil, err
-				case st := <-c:
-					if st != nil {
-						ret = append(ret, st)
-					}
+// getSubtreesAtRev returns a function reading subtrees at, or before, the
+// specified tree revision, in parallel.
+func (t *treeTX) getSubtreesAtRev(ctx context.Context, rev int64) cache.GetSubtreesFunc {
+	return func(ids []storage.NodeID) ([]*storagepb.SubtreeProto, error) {
+		// Request the various subtrees in parallel.
+		// c will carry any retrieved subtrees
+		c := make(chan *storagepb.SubtreeProto, len(ids))
+		// err will carry any errors encountered while reading from spanner,
+		// although we'll only return to the caller the first one (if indeed
+		// there are any).
+		errc := make(chan error, len(ids))
+
+		// Spawn goroutines for each request
+		for _, id := range ids {
+			id := id
+			go func() {
+				st, err := t.getSubtree(ctx, rev, id)
+				if err != nil {
+					errc <- err
+					return
+				}
+				c <- st
+			}()
+		}
+
+		// Now wait for the goroutines to signal their completion, and collect
+		// the results.
+		ret := make([]*storagepb.SubtreeProto, 0, len(ids))
+		for range ids {
+			select {
+			case err := <-errc:
+				return nil, err
+			case st := <-c:
+				if st != nil {
+					ret = append(ret, st)
 				}
 			}
-			return ret, nil
-		})
+		}
+		return ret, nil
+	}
 }
 
 // SetMerkleNodes stores the provided merkle nodes at the writeRevision of the
@@ -0,0 +1,102 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudspanner
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/storagepb"
+)
+
+func TestTreeHeadShard(t *testing.T) {
+	for _, shards := range []int{1, 4, MaxTreeHeadShards} {
+		counts := make(map[int64]int)
+		for rev := int64(0); rev < 1000; rev++ {
+			shard := treeHeadShard(rev, shards)
+			if shard < 0 || shard >= int64(shards) {
+				t.Fatalf("treeHeadShard(%d, %d) = %d, want a shard in [0, %d)", rev, shards, shard, shards)
+			}
+			if again := treeHeadShard(rev, shards); again != shard {
+				t.Fatalf("treeHeadShard(%d, %d) = %d, then %d", rev, shards, shard, again)
+			}
+			counts[shard]++
+		}
+		// Consecutive revisions are spread over all the shards.
+		if got, want := len(counts), shards; got != want {
+			t.Errorf("treeHeadShard(0..999, %d) used %d shards, want %d", shards, got, want)
+		}
+	}
+}
+
+func TestLatestSTHSQLReadsAllShards(t *testing.T) {
+	if got, want := strings.Count(latestSTHSQL, "FROM TreeHeads "), 1; got != want {
+		t.Errorf("latestSTHSQL reads TreeHeads %d times, want %d", got, want)
+	}
+	if got, want := strings.Count(latestSTHSQL, "FROM TreeHeadShards "), MaxTreeHeadShards; got != want {
+		t.Errorf("latestSTHSQL reads %d shards of TreeHeadShards, want %d", got, want)
+	}
+}
+
+func TestTreeTXReadsWrittenSubtrees(t *testing.T) {
+	ctx := context.Background()
+	// Collecting the inserts, as for split commits, keeps them out of
+	// Spanner, which isn't available here.
+	tx := &treeTX{
+		treeID:         1,
+		ts:             &treeStorage{},
+		stx:            &spanner.ReadWriteTransaction{},
+		_writeRev:      5,
+		collectInserts: true,
+	}
+	id := storage.NewNodeIDFromHash([]byte{0x12, 0x34, 0x56, 0x78})
+	id.PrefixLenBits = 8
+	st := &storagepb.SubtreeProto{Prefix: []byte{0x12}, Depth: 8, Leaves: map[string][]byte{"a": []byte("1")}}
+
+	// The sequencer flushes its subtrees, then updates and flushes one of
+	// them again, e.g. when a pass stores several batches of leaves.
+	if err := tx.storeSubtrees([]*storagepb.SubtreeProto{st}); err != nil {
+		t.Fatalf("storeSubtrees(): %v", err)
+	}
+	st.Leaves["b"] = []byte("2")
+	if err := tx.storeSubtrees([]*storagepb.SubtreeProto{st}); err != nil {
+		t.Fatalf("storeSubtrees() again: %v", err)
+	}
+	if got, want := len(tx.inserts), 1; got != want {
+		t.Errorf("stored subtree twice: got %d inserts, want %d", got, want)
+	}
+
+	got, err := tx.getSubtree(ctx, tx._writeRev, id)
+	if err != nil {
+		t.Fatalf("getSubtree(): %v", err)
+	}
+	if !proto.Equal(got, st) {
+		t.Errorf("getSubtree() = %v, want %v", got, st)
+	}
+	// Changes to the returned copy don't leak into the transaction.
+	got.Leaves["c"] = []byte("3")
+	if again, _ := tx.getSubtree(ctx, tx._writeRev, id); !proto.Equal(again, st) {
+		t.Errorf("getSubtree() after changing the previous result = %v, want %v", again, st)
+	}
+
+	// Reads of older revisions don't see the writes.
+	if got, err := tx.writtenSubtree(tx._writeRev-1, id); got != nil || err != nil {
+		t.Errorf("writtenSubtree(%d) = %v, %v, want nil, nil", tx._writeRev-1, got, err)
+	}
+}
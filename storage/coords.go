@@ -0,0 +1,158 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+)
+
+// logNodeIDBitLen is the bit length of the NodeIDs of log nodes, as
+// merkle.LogNodeIDBitLen.
+const logNodeIDBitLen = 64
+
+// NodeCoords addresses a node of a log tree by its level, with the leaves at
+// level 0, and its index among the nodes of that level, from the left. Unlike
+// NodeID, it doesn't depend on how nodes are laid out in storage.
+type NodeCoords struct {
+	Level int64
+	Index int64
+}
+
+// String returns the coordinates as "(level, index)".
+func (c NodeCoords) String() string {
+	return fmt.Sprintf("(%d, %d)", c.Level, c.Index)
+}
+
+// ID returns the NodeID of the log node at c.
+func (c NodeCoords) ID() (NodeID, error) {
+	return NewNodeIDForTreeCoords(c.Level, c.Index, logNodeIDBitLen)
+}
+
+// CoordsForNodeID returns the coordinates of the log node id. It returns an
+// error if id isn't the NodeID of a log node.
+func CoordsForNodeID(id NodeID) (NodeCoords, error) {
+	if len(id.Path) != logNodeIDBitLen/8 || id.PrefixLenBits < 0 || id.PrefixLenBits > logNodeIDBitLen {
+		return NodeCoords{}, fmt.Errorf("node with %d path bytes and %d prefix bits is not a log node", len(id.Path), id.PrefixLenBits)
+	}
+	level := uint(logNodeIDBitLen - id.PrefixLenBits)
+	path := binary.BigEndian.Uint64(id.Path)
+	if level == logNodeIDBitLen {
+		return NodeCoords{Level: int64(level)}, nil
+	}
+	return NodeCoords{Level: int64(level), Index: int64(path >> level)}, nil
+}
+
+// CoordNode is a node of a log tree addressed by its coordinates.
+type CoordNode struct {
+	Coords   NodeCoords
+	Hash     []byte
+	Revision int64
+}
+
+// Node returns n as a Node addressed by its NodeID.
+func (n CoordNode) Node() (Node, error) {
+	id, err := n.Coords.ID()
+	if err != nil {
+		return Node{}, err
+	}
+	return Node{NodeID: id, Hash: n.Hash, NodeRevision: n.Revision}, nil
+}
+
+// CoordNodeFor returns the log node n addressed by its coordinates.
+func CoordNodeFor(n Node) (CoordNode, error) {
+	c, err := CoordsForNodeID(n.NodeID)
+	if err != nil {
+		return CoordNode{}, err
+	}
+	return CoordNode{Coords: c, Hash: n.Hash, Revision: n.NodeRevision}, nil
+}
+
+// CoordNodeReader provides read-only access to the stored nodes of a log tree
+// by their coordinates. Storage implementations may implement it alongside
+// NodeReader; callers should use GetNodesByCoords, which falls back to
+// NodeReader for those which don't.
+type CoordNodeReader interface {
+	// GetNodesByCoords looks up the nodes at coords, at treeRevision, and
+	// returns them. As with GetMerkleNodes, nodes which aren't stored are
+	// omitted.
+	GetNodesByCoords(ctx context.Context, treeRevision int64, coords []NodeCoords) ([]CoordNode, error)
+}
+
+// GetNodesByCoords reads the log nodes at coords, at treeRevision, from r. If
+// r implements CoordNodeReader the nodes are read through it, otherwise they
+// are read by their NodeIDs.
+func GetNodesByCoords(ctx context.Context, r NodeReader, treeRevision int64, coords []NodeCoords) ([]CoordNode, error) {
+	if cr, ok := r.(CoordNodeReader); ok {
+		return cr.GetNodesByCoords(ctx, treeRevision, coords)
+	}
+	ids := make([]NodeID, 0, len(coords))
+	for _, c := range coords {
+		id, err := c.ID()
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	nodes, err := r.GetMerkleNodes(ctx, treeRevision, ids)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]CoordNode, 0, len(nodes))
+	for _, n := range nodes {
+		cn, err := CoordNodeFor(n)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, cn)
+	}
+	return ret, nil
+}
+
+// NodeReaderForCoords returns a NodeReader reading the log nodes of r by their
+// NodeIDs, for readers which only address nodes by their coordinates. The
+// NodeReader also implements CoordNodeReader, by calling r directly.
+func NodeReaderForCoords(r CoordNodeReader) NodeReader {
+	return coordNodeReader{r}
+}
+
+type coordNodeReader struct {
+	CoordNodeReader
+}
+
+func (r coordNodeReader) GetMerkleNodes(ctx context.Context, treeRevision int64, ids []NodeID) ([]Node, error) {
+	coords := make([]NodeCoords, 0, len(ids))
+	for _, id := range ids {
+		c, err := CoordsForNodeID(id)
+		if err != nil {
+			return nil, err
+		}
+		coords = append(coords, c)
+	}
+	nodes, err := r.GetNodesByCoords(ctx, treeRevision, coords)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]Node, 0, len(nodes))
+	for _, cn := range nodes {
+		n, err := cn.Node()
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, n)
+	}
+	return ret, nil
+}
@@ -0,0 +1,154 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestNodeCoordsRoundTrip(t *testing.T) {
+	for _, c := range []NodeCoords{
+		{0, 0}, {0, 1e9}, {3, 12345}, {8, 255}, {29, 1}, {63, 1}, {64, 0},
+	} {
+		id, err := c.ID()
+		if err != nil {
+			t.Errorf("%v.ID(): %v", c, err)
+			continue
+		}
+		got, err := CoordsForNodeID(id)
+		if err != nil {
+			t.Errorf("CoordsForNodeID(%v): %v", id.CoordString(), err)
+			continue
+		}
+		if got != c {
+			t.Errorf("CoordsForNodeID(%v)=%v, want %v", id.CoordString(), got, c)
+		}
+	}
+}
+
+func TestNodeCoordsErrors(t *testing.T) {
+	for _, c := range []NodeCoords{{-1, 0}, {0, -1}, {63, 2}, {65, 0}} {
+		if id, err := c.ID(); err == nil {
+			t.Errorf("%v.ID()=%v, want error", c, id.CoordString())
+		}
+	}
+	for _, id := range []NodeID{
+		NewEmptyNodeID(256),
+		{Path: make([]byte, 8), PrefixLenBits: 65},
+		{Path: make([]byte, 8), PrefixLenBits: -1},
+	} {
+		if c, err := CoordsForNodeID(id); err == nil {
+			t.Errorf("CoordsForNodeID(%x/%d)=%v, want error", id.Path, id.PrefixLenBits, c)
+		}
+	}
+}
+
+// fakeNodeReader holds nodes by their NodeIDs, and counts its calls.
+type fakeNodeReader struct {
+	nodes map[string]Node
+	calls int
+}
+
+func (r *fakeNodeReader) GetMerkleNodes(ctx context.Context, _ int64, ids []NodeID) ([]Node, error) {
+	r.calls++
+	var ret []Node
+	for _, id := range ids {
+		if n, ok := r.nodes[id.String()]; ok {
+			ret = append(ret, n)
+		}
+	}
+	return ret, nil
+}
+
+// fakeCoordNodeReader makes up nodes from their coordinates.
+type fakeCoordNodeReader struct {
+	calls int
+}
+
+func (r *fakeCoordNodeReader) GetNodesByCoords(ctx context.Context, rev int64, coords []NodeCoords) ([]CoordNode, error) {
+	r.calls++
+	ret := make([]CoordNode, 0, len(coords))
+	for _, c := range coords {
+		ret = append(ret, CoordNode{Coords: c, Hash: []byte(c.String()), Revision: rev})
+	}
+	return ret, nil
+}
+
+func TestGetNodesByCoords(t *testing.T) {
+	ctx := context.Background()
+	coords := []NodeCoords{{0, 5}, {2, 1}, {7, 0}}
+
+	// Nodes are read by their NodeIDs from plain NodeReaders.
+	r := &fakeNodeReader{nodes: make(map[string]Node)}
+	var want []CoordNode
+	for _, c := range coords[:2] {
+		id, err := c.ID()
+		if err != nil {
+			t.Fatalf("%v.ID(): %v", c, err)
+		}
+		cn := CoordNode{Coords: c, Hash: []byte(fmt.Sprintf("hash %v", c)), Revision: 3}
+		r.nodes[id.String()] = Node{NodeID: id, Hash: cn.Hash, NodeRevision: 3}
+		want = append(want, cn)
+	}
+	got, err := GetNodesByCoords(ctx, r, 3, coords)
+	if err != nil {
+		t.Fatalf("GetNodesByCoords(): %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetNodesByCoords()=%v, want %v", got, want)
+	}
+	if r.calls != 1 {
+		t.Errorf("GetNodesByCoords() made %d GetMerkleNodes calls, want 1", r.calls)
+	}
+
+	// Readers adapted from CoordNodeReaders are called directly.
+	cr := &fakeCoordNodeReader{}
+	nr := NodeReaderForCoords(cr)
+	got, err = GetNodesByCoords(ctx, nr, 4, coords)
+	if err != nil {
+		t.Fatalf("GetNodesByCoords() through adapter: %v", err)
+	}
+	if len(got) != len(coords) || cr.calls != 1 {
+		t.Errorf("GetNodesByCoords() through adapter returned %d nodes in %d calls, want %d in 1", len(got), cr.calls, len(coords))
+	}
+
+	// And they can be read by NodeID.
+	var ids []NodeID
+	for _, c := range coords {
+		id, err := c.ID()
+		if err != nil {
+			t.Fatalf("%v.ID(): %v", c, err)
+		}
+		ids = append(ids, id)
+	}
+	nodes, err := nr.GetMerkleNodes(ctx, 4, ids)
+	if err != nil {
+		t.Fatalf("GetMerkleNodes() through adapter: %v", err)
+	}
+	if len(nodes) != len(ids) {
+		t.Fatalf("GetMerkleNodes() through adapter returned %d nodes, want %d", len(nodes), len(ids))
+	}
+	for i, n := range nodes {
+		if !n.NodeID.Equivalent(ids[i]) || string(n.Hash) != coords[i].String() || n.NodeRevision != 4 {
+			t.Errorf("GetMerkleNodes() through adapter returned node %d = %+v, want %v at %v", i, n, ids[i].String(), coords[i])
+		}
+	}
+	if _, err := nr.GetMerkleNodes(ctx, 4, []NodeID{NewEmptyNodeID(256)}); err == nil {
+		t.Error("GetMerkleNodes() of a map node through adapter succeeded, want error")
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/golang/glog"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+)
+
+const (
+	// DefaultBatchSize is the default maximum number of leaves a Drainer
+	// queues to storage at once.
+	DefaultBatchSize = 1000
+	// DefaultRetryInterval is the default time a Drainer waits before
+	// consuming the topic again after an error.
+	DefaultRetryInterval = 10 * time.Second
+)
+
+// Drainer moves the leaves queued to a Kafka topic by the LogStorage returned
+// by NewLogStorage to the queue of the underlying storage.
+//
+// The offsets of messages are committed once their leaves are queued to
+// storage, so leaves are queued at least once: after a failure, some of them
+// may be queued again, which storage reports as duplicates, and ignored.
+// Drainers in the same consumer group share the partitions of the topic.
+type Drainer struct {
+	// BatchSize is the maximum number of leaves queued to storage at once.
+	BatchSize int
+	// RetryInterval is the time waited before consuming the topic again after
+	// an error, e.g. while storage is unavailable.
+	RetryInterval time.Duration
+
+	ls    storage.LogStorage
+	group sarama.ConsumerGroup
+	topic string
+}
+
+// NewDrainer returns a Drainer queueing the leaves it consumes from topic as
+// a member of group to ls.
+func NewDrainer(ls storage.LogStorage, group sarama.ConsumerGroup, topic string) *Drainer {
+	return &Drainer{
+		BatchSize:     DefaultBatchSize,
+		RetryInterval: DefaultRetryInterval,
+		ls:            ls,
+		group:         group,
+		topic:         topic,
+	}
+}
+
+// Run drains the topic until ctx is done.
+func (d *Drainer) Run(ctx context.Context) {
+	for {
+		if err := d.group.Consume(ctx, []string{d.topic}, d); err != nil {
+			glog.Errorf("Failed to drain leaves from %s: %v", d.topic, err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(d.RetryInterval):
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// Setup implements sarama.ConsumerGroupHandler.
+func (d *Drainer) Setup(sarama.ConsumerGroupSession) error { return nil }
+
+// Cleanup implements sarama.ConsumerGroupHandler.
+func (d *Drainer) Cleanup(sarama.ConsumerGroupSession) error { return nil }
+
+// ConsumeClaim implements sarama.ConsumerGroupHandler. It queues the leaves
+// already received from the claimed partition in batches of up to BatchSize,
+// and marks their messages consumed once they're queued to storage.
+func (d *Drainer) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for msg := range claim.Messages() {
+		batch := []*sarama.ConsumerMessage{msg}
+	fill:
+		for len(batch) < d.BatchSize {
+			select {
+			case m, ok := <-claim.Messages():
+				if !ok {
+					break fill
+				}
+				batch = append(batch, m)
+			default:
+				break fill
+			}
+		}
+		if err := d.queue(sess.Context(), batch); err != nil {
+			return err
+		}
+		sess.MarkMessage(batch[len(batch)-1], "")
+	}
+	return nil
+}
+
+// queue queues the leaves held by msgs to storage, passing runs of leaves of
+// the same tree which were queued together to a single QueueLeaves call.
+func (d *Drainer) queue(ctx context.Context, msgs []*sarama.ConsumerMessage) error {
+	var treeID int64
+	var queueTimestamp time.Time
+	var leaves []*trillian.LogLeaf
+	flush := func() error {
+		if len(leaves) == 0 {
+			return nil
+		}
+		if _, err := d.ls.QueueLeaves(ctx, treeID, leaves, queueTimestamp); err != nil {
+			return fmt.Errorf("failed to queue %d leaves to tree %d: %v", len(leaves), treeID, err)
+		}
+		leaves = nil
+		return nil
+	}
+
+	for _, msg := range msgs {
+		id, l, err := decodeLeaf(msg)
+		if err != nil {
+			// Retrying wouldn't help, so drop the message.
+			glog.Errorf("Dropping message at %s/%d:%d: %v", msg.Topic, msg.Partition, msg.Offset, err)
+			continue
+		}
+		ts, err := ptypes.Timestamp(l.QueueTimestamp)
+		if err != nil {
+			glog.Errorf("Dropping message at %s/%d:%d: got invalid queue timestamp: %v", msg.Topic, msg.Partition, msg.Offset, err)
+			continue
+		}
+		if id != treeID || !ts.Equal(queueTimestamp) {
+			if err := flush(); err != nil {
+				return err
+			}
+			treeID, queueTimestamp = id, ts
+		}
+		leaves = append(leaves, l)
+	}
+	return flush()
+}
+
+// decodeLeaf returns the tree ID and leaf held by msg.
+func decodeLeaf(msg *sarama.ConsumerMessage) (int64, *trillian.LogLeaf, error) {
+	treeID, err := strconv.ParseInt(string(msg.Key), 10, 64)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid tree ID %q: %v", msg.Key, err)
+	}
+	var l trillian.LogLeaf
+	if err := proto.Unmarshal(msg.Value, &l); err != nil {
+		return 0, nil, fmt.Errorf("failed to unmarshal leaf: %v", err)
+	}
+	return treeID, &l, nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package kafka provides a leaf queue for logs backed by a Kafka topic, so
+// that log servers keep accepting leaves while the database of the logs is
+// unavailable, e.g. during maintenance.
+//
+// Log servers queue leaves with the LogStorage returned by NewLogStorage,
+// which appends them to the topic, and log signers run a Drainer, which
+// moves them from the topic to the queue of the underlying storage, from
+// which they're sequenced as usual.
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+)
+
+// NewConfig returns a Kafka client configuration suitable for the leaf queue:
+// the producer is idempotent, so that retries don't duplicate leaves within
+// the topic, and waits for all in-sync replicas to acknowledge writes.
+func NewConfig() *sarama.Config {
+	cfg := sarama.NewConfig()
+	cfg.Version = sarama.V1_0_0_0
+	cfg.Producer.Idempotent = true
+	cfg.Producer.RequiredAcks = sarama.WaitForAll
+	cfg.Producer.Return.Successes = true
+	cfg.Net.MaxOpenRequests = 1
+	cfg.Consumer.Offsets.Initial = sarama.OffsetOldest
+	return cfg
+}
+
+// NewLogStorage returns a LogStorage which appends the leaves passed to
+// QueueLeaves to topic through producer, and otherwise defers to ls.
+//
+// As queued leaves only reach ls when a Drainer moves them there, QueueLeaves
+// can't detect duplicates, and reports all leaves as queued.
+func NewLogStorage(ls storage.LogStorage, producer sarama.SyncProducer, topic string) storage.LogStorage {
+	return &kafkaLogStorage{LogStorage: ls, producer: producer, topic: topic}
+}
+
+type kafkaLogStorage struct {
+	storage.LogStorage
+	producer sarama.SyncProducer
+	topic    string
+}
+
+// QueueLeaves appends a message holding each leaf to the topic, keyed by the
+// tree ID so that the leaves of a tree are kept in order on one partition.
+func (k *kafkaLogStorage) QueueLeaves(ctx context.Context, treeID int64, leaves []*trillian.LogLeaf, queueTimestamp time.Time) ([]*trillian.QueuedLogLeaf, error) {
+	ts, err := ptypes.TimestampProto(queueTimestamp)
+	if err != nil {
+		return nil, fmt.Errorf("got invalid queue timestamp: %v", err)
+	}
+	key := sarama.StringEncoder(strconv.FormatInt(treeID, 10))
+	msgs := make([]*sarama.ProducerMessage, 0, len(leaves))
+	ret := make([]*trillian.QueuedLogLeaf, 0, len(leaves))
+	for _, l := range leaves {
+		l.QueueTimestamp = ts
+		value, err := proto.Marshal(l)
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, &sarama.ProducerMessage{
+			Topic:     k.topic,
+			Key:       key,
+			Value:     sarama.ByteEncoder(value),
+			Timestamp: queueTimestamp,
+		})
+		ret = append(ret, &trillian.QueuedLogLeaf{Leaf: l})
+	}
+	if err := k.producer.SendMessages(msgs); err != nil {
+		return nil, fmt.Errorf("failed to queue leaves to %s: %v", k.topic, err)
+	}
+	return ret, nil
+}
@@ -0,0 +1,218 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+)
+
+const topic = "leaves"
+
+// fakeProducer holds the messages sent to it.
+type fakeProducer struct {
+	sarama.SyncProducer
+	msgs []*sarama.ProducerMessage
+	err  error
+}
+
+func (p *fakeProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.msgs = append(p.msgs, msgs...)
+	return nil
+}
+
+// consumed returns the messages sent to p, as a consumer receives them.
+func (p *fakeProducer) consumed(t *testing.T) []*sarama.ConsumerMessage {
+	t.Helper()
+	var ret []*sarama.ConsumerMessage
+	for i, m := range p.msgs {
+		key, err := m.Key.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		value, err := m.Value.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ret = append(ret, &sarama.ConsumerMessage{Topic: m.Topic, Key: key, Value: value, Offset: int64(i)})
+	}
+	return ret
+}
+
+type queueCall struct {
+	treeID         int64
+	leaves         []*trillian.LogLeaf
+	queueTimestamp time.Time
+}
+
+// fakeLogStorage records its QueueLeaves calls.
+type fakeLogStorage struct {
+	storage.LogStorage
+	calls []queueCall
+	err   error
+}
+
+func (s *fakeLogStorage) QueueLeaves(ctx context.Context, treeID int64, leaves []*trillian.LogLeaf, queueTimestamp time.Time) ([]*trillian.QueuedLogLeaf, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.calls = append(s.calls, queueCall{treeID: treeID, leaves: leaves, queueTimestamp: queueTimestamp})
+	return nil, nil
+}
+
+type fakeSession struct {
+	sarama.ConsumerGroupSession
+	marked []*sarama.ConsumerMessage
+}
+
+func (s *fakeSession) Context() context.Context { return context.Background() }
+
+func (s *fakeSession) MarkMessage(msg *sarama.ConsumerMessage, metadata string) {
+	s.marked = append(s.marked, msg)
+}
+
+type fakeClaim struct {
+	sarama.ConsumerGroupClaim
+	msgs chan *sarama.ConsumerMessage
+}
+
+func (c *fakeClaim) Messages() <-chan *sarama.ConsumerMessage { return c.msgs }
+
+func newClaim(msgs []*sarama.ConsumerMessage) *fakeClaim {
+	c := &fakeClaim{msgs: make(chan *sarama.ConsumerMessage, len(msgs))}
+	for _, m := range msgs {
+		c.msgs <- m
+	}
+	close(c.msgs)
+	return c
+}
+
+func leaf(id string) *trillian.LogLeaf {
+	return &trillian.LogLeaf{
+		LeafIdentityHash: []byte(id),
+		MerkleLeafHash:   []byte("hash-" + id),
+		LeafValue:        []byte("value-" + id),
+	}
+}
+
+func TestQueueAndDrain(t *testing.T) {
+	ctx := context.Background()
+	ts1 := time.Unix(1500000000, 0)
+	ts2 := ts1.Add(time.Second)
+
+	p := &fakeProducer{}
+	ks := NewLogStorage(nil, p, topic)
+	for _, c := range []queueCall{
+		{treeID: 1, leaves: []*trillian.LogLeaf{leaf("a"), leaf("b")}, queueTimestamp: ts1},
+		{treeID: 1, leaves: []*trillian.LogLeaf{leaf("c")}, queueTimestamp: ts2},
+		{treeID: 2, leaves: []*trillian.LogLeaf{leaf("d")}, queueTimestamp: ts2},
+	} {
+		queued, err := ks.QueueLeaves(ctx, c.treeID, c.leaves, c.queueTimestamp)
+		if err != nil {
+			t.Fatalf("QueueLeaves(%d): %v", c.treeID, err)
+		}
+		if got, want := len(queued), len(c.leaves); got != want {
+			t.Fatalf("QueueLeaves(%d) returned %d leaves, want %d", c.treeID, got, want)
+		}
+		for i, q := range queued {
+			if q.Status != nil || !proto.Equal(q.Leaf, c.leaves[i]) {
+				t.Errorf("QueueLeaves(%d)[%d] = %v, want queued %v", c.treeID, i, q, c.leaves[i])
+			}
+		}
+	}
+	msgs := p.consumed(t)
+	// Add a message which can't be decoded.
+	msgs = append(msgs, &sarama.ConsumerMessage{Topic: topic, Key: []byte("not-a-tree"), Offset: int64(len(msgs))})
+
+	for _, test := range []struct {
+		desc      string
+		batchSize int
+		wantCalls int
+	}{
+		{desc: "oneBatch", batchSize: 100, wantCalls: 3},
+		// The first batch splits the leaves queued together by the first call.
+		{desc: "smallBatches", batchSize: 1, wantCalls: 4},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			ls := &fakeLogStorage{}
+			d := NewDrainer(ls, nil, topic)
+			d.BatchSize = test.batchSize
+			sess := &fakeSession{}
+			if err := d.ConsumeClaim(sess, newClaim(msgs)); err != nil {
+				t.Fatalf("ConsumeClaim(): %v", err)
+			}
+
+			if got := len(ls.calls); got != test.wantCalls {
+				t.Errorf("QueueLeaves called %d times, want %d", got, test.wantCalls)
+			}
+			got := make(map[string]queueCall)
+			for _, c := range ls.calls {
+				for _, l := range c.leaves {
+					got[string(l.LeafIdentityHash)] = queueCall{treeID: c.treeID, queueTimestamp: c.queueTimestamp}
+				}
+			}
+			for id, want := range map[string]queueCall{
+				"a": {treeID: 1, queueTimestamp: ts1},
+				"b": {treeID: 1, queueTimestamp: ts1},
+				"c": {treeID: 1, queueTimestamp: ts2},
+				"d": {treeID: 2, queueTimestamp: ts2},
+			} {
+				if g, ok := got[id]; !ok || g.treeID != want.treeID || !g.queueTimestamp.Equal(want.queueTimestamp) {
+					t.Errorf("leaf %q queued as %+v (found: %v), want %+v", id, g, ok, want)
+				}
+			}
+
+			if len(sess.marked) == 0 {
+				t.Fatal("no messages marked")
+			}
+			if got, want := sess.marked[len(sess.marked)-1].Offset, msgs[len(msgs)-1].Offset; got != want {
+				t.Errorf("last marked offset = %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestQueueLeavesProducerError(t *testing.T) {
+	ks := NewLogStorage(nil, &fakeProducer{err: errors.New("no brokers")}, topic)
+	if _, err := ks.QueueLeaves(context.Background(), 1, []*trillian.LogLeaf{leaf("a")}, time.Now()); err == nil {
+		t.Error("QueueLeaves() succeeded, want error")
+	}
+}
+
+func TestDrainStorageError(t *testing.T) {
+	p := &fakeProducer{}
+	if _, err := NewLogStorage(nil, p, topic).QueueLeaves(context.Background(), 1, []*trillian.LogLeaf{leaf("a")}, time.Now()); err != nil {
+		t.Fatalf("QueueLeaves(): %v", err)
+	}
+
+	d := NewDrainer(&fakeLogStorage{err: errors.New("database down")}, nil, topic)
+	sess := &fakeSession{}
+	if err := d.ConsumeClaim(sess, newClaim(p.consumed(t))); err == nil {
+		t.Error("ConsumeClaim() succeeded, want error")
+	}
+	if len(sess.marked) != 0 {
+		t.Errorf("ConsumeClaim() marked %d messages, want none", len(sess.marked))
+	}
+}
@@ -151,6 +151,79 @@ type LogStorage interface {
 	AddSequencedLeaves(ctx context.Context, treeID int64, leaves []*trillian.LogLeaf) ([]*trillian.QueuedLogLeaf, error)
 }
 
+// LogCompactor may be implemented by LogStorage implementations which can
+// discard the history of a log's Merkle nodes.
+type LogCompactor interface {
+	// CompactTree keeps only the newest revision at or below treeRevision of
+	// each of the log's stored subtrees, and rewrites it at treeRevision.
+	// Nodes read at treeRevision or later are unchanged, but reads at earlier
+	// revisions are no longer possible, so it must only be used for trees
+	// whose latest root is at treeRevision and which can't change any more,
+	// i.e. frozen ones. It returns the number of subtree revisions removed.
+	CompactTree(ctx context.Context, treeID, treeRevision int64) (int64, error)
+}
+
+// LogRootReader may be implemented by LogStorage implementations which can
+// read the roots of a log's earlier revisions.
+type LogRootReader interface {
+	// GetSignedLogRoot returns the log's root at treeRevision, or a NotFound
+	// error if there's none.
+	GetSignedLogRoot(ctx context.Context, treeID, treeRevision int64) (trillian.SignedLogRoot, error)
+}
+
+// LogSubtreeRepairer may be implemented by LogStorage implementations which can
+// rewrite a log's stored subtrees in place.
+type LogSubtreeRepairer interface {
+	// RepairMerkleNodes rewrites the subtrees holding nodes at treeRevision,
+	// replacing any stored at that revision, from nodes alone. nodes must hold
+	// every node stored in those subtrees as of treeRevision, so it's used to
+	// restore subtrees which are missing or corrupt once their nodes have been
+	// recomputed from the strata below them.
+	RepairMerkleNodes(ctx context.Context, treeID, treeRevision int64, nodes []Node) error
+}
+
+// LeafIndexKey records that a leaf of a log has a key in one of the log's
+// secondary leaf indexes, as defined by trillian.Tree.LeafIndexes.
+type LeafIndexKey struct {
+	// Index is the name of the index.
+	Index     string
+	Key       []byte
+	LeafIndex int64
+}
+
+// LeafIndexWriter may be implemented by LogTreeTX implementations which can
+// maintain secondary indexes of a log's leaves.
+type LeafIndexWriter interface {
+	// AddLeafIndexKeys adds keys to the log's indexes. Each key must only be
+	// added once.
+	AddLeafIndexKeys(ctx context.Context, keys []LeafIndexKey) error
+}
+
+// LeafIndexReader may be implemented by ReadOnlyLogTreeTX implementations
+// which can look up leaves by the keys added with a LeafIndexWriter.
+type LeafIndexReader interface {
+	// GetLeafIndicesByKey returns up to count indices of the leaves with key
+	// in the named index, in increasing order, starting at start.
+	GetLeafIndicesByKey(ctx context.Context, index string, key []byte, start int64, count int) ([]int64, error)
+}
+
+// LeafFilterReader may be implemented by ReadOnlyLogTreeTX implementations
+// which store the blocks of a log's leaf filter, as defined by
+// trillian.Tree.LeafFilter.
+type LeafFilterReader interface {
+	// GetLeafFilterBlocks returns the blocks of the filter with the given
+	// indices. Blocks which were never written are missing from the result.
+	GetLeafFilterBlocks(ctx context.Context, indices []int64) (map[int64][]byte, error)
+}
+
+// LeafFilterWriter may be implemented by LogTreeTX implementations which can
+// maintain a log's leaf filter.
+type LeafFilterWriter interface {
+	LeafFilterReader
+	// SetLeafFilterBlocks writes blocks of the filter, keyed by index.
+	SetLeafFilterBlocks(ctx context.Context, blocks map[int64][]byte) error
+}
+
 // CountByLogID is a map of total number of items keyed by log ID.
 type CountByLogID map[int64]int64
 
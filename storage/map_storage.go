@@ -18,8 +18,14 @@ import (
 	"context"
 
 	"github.com/google/trillian"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+// ErrRevisionConflict is returned by MapStorage.ReadWriteTransaction when
+// another transaction has claimed the revision it would write.
+var ErrRevisionConflict = status.Error(codes.Aborted, "map revision already claimed by another writer")
+
 // ReadOnlyMapTX provides a read-only view into log data.
 // A ReadOnlyMapTX, unlike ReadOnlyMapTreeTX, is not tied to a particular tree.
 type ReadOnlyMapTX interface {
@@ -52,6 +58,16 @@ type ReadOnlyMapTreeTX interface {
 	// exist.  i.e. requesting a set of unknown keys would result in a
 	// zero-length array being returned.
 	Get(ctx context.Context, revision int64, keyHashes [][]byte) ([]trillian.MapLeaf, error)
+	// GetLeafHistory returns the values which keyHash was set to at
+	// revisions from fromRevision to toRevision inclusive, in increasing
+	// revision order.
+	GetLeafHistory(ctx context.Context, keyHash []byte, fromRevision, toRevision int64) ([]MapLeafRevision, error)
+}
+
+// MapLeafRevision is the value a map leaf was set to at a revision.
+type MapLeafRevision struct {
+	Revision int64
+	Leaf     trillian.MapLeaf
 }
 
 // MapTreeTX is the transactional interface for reading/modifying a Map.
@@ -94,5 +110,9 @@ type MapStorage interface {
 	// calls f with it.
 	// If f fails and returns an error, the storage implementation may optionally
 	// retry with a new transaction, and f MUST NOT keep state across calls.
+	// Only one transaction at a time may write each revision of a map: if
+	// another one has claimed the write revision, ReadWriteTransaction fails
+	// with ErrRevisionConflict and commits nothing. Transactions started with
+	// the ctx passed to f share its claim.
 	ReadWriteTransaction(ctx context.Context, treeID int64, f MapTXFunc) error
 }
@@ -18,31 +18,52 @@ import (
 	"context"
 	"fmt"
 	"sync"
-	"time"
 
 	"github.com/golang/glog"
+	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/google/trillian"
 	"github.com/google/trillian/storage"
+	"github.com/google/trillian/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+// AdminStorageOptions configures a storage.AdminStorage created by
+// NewAdminStorageWithOpts.
+type AdminStorageOptions struct {
+	// TimeSource provides the creation and update times of trees. Defaults to
+	// util.SystemTimeSource.
+	TimeSource util.TimeSource
+}
+
 // NewAdminStorage returns a storage.AdminStorage implementation backed by
 // memoryTreeStorage.
 func NewAdminStorage(ms storage.LogStorage) storage.AdminStorage {
-	return &memoryAdminStorage{ms.(*memoryLogStorage).memoryTreeStorage}
+	return NewAdminStorageWithOpts(ms, AdminStorageOptions{})
+}
+
+// NewAdminStorageWithOpts returns a storage.AdminStorage implementation backed
+// by memoryTreeStorage, configured by opts.
+func NewAdminStorageWithOpts(ms storage.LogStorage, opts AdminStorageOptions) storage.AdminStorage {
+	if opts.TimeSource == nil {
+		opts.TimeSource = util.SystemTimeSource{}
+	}
+	return &memoryAdminStorage{ms: ms.(*memoryLogStorage).memoryTreeStorage, timeSource: opts.TimeSource}
 }
 
 // memoryAdminStorage implements storage.AdminStorage
 type memoryAdminStorage struct {
-	ms *memoryTreeStorage
+	ms         *memoryTreeStorage
+	timeSource util.TimeSource
 }
 
 func (s *memoryAdminStorage) Snapshot(ctx context.Context) (storage.ReadOnlyAdminTX, error) {
-	return &adminTX{ms: s.ms}, nil
+	return &adminTX{ms: s.ms, timeSource: s.timeSource}, nil
 }
 
 func (s *memoryAdminStorage) ReadWriteTransaction(ctx context.Context, f storage.AdminTXFunc) error {
-	tx := &adminTX{ms: s.ms}
+	tx := &adminTX{ms: s.ms, timeSource: s.timeSource}
 	defer tx.Close()
 	if err := f(ctx, tx); err != nil {
 		return err
@@ -55,7 +76,8 @@ func (s *memoryAdminStorage) CheckDatabaseAccessible(ctx context.Context) error
 }
 
 type adminTX struct {
-	ms *memoryTreeStorage
+	ms         *memoryTreeStorage
+	timeSource util.TimeSource
 	// mu guards reads/writes on closed, which happen only on
 	// Commit/Rollback/IsClosed/Close methods.
 	// We don't check closed on *all* methods (apart from the ones above),
@@ -105,12 +127,11 @@ func (t *adminTX) Close() error {
 
 func (t *adminTX) GetTree(ctx context.Context, treeID int64) (*trillian.Tree, error) {
 	tree := t.ms.getTree(treeID)
-	tree.RLock()
-	defer tree.RUnlock()
-
 	if tree == nil {
-		return nil, fmt.Errorf("no such treeID %d", treeID)
+		return nil, status.Errorf(codes.NotFound, "tree %v not found", treeID)
 	}
+	tree.RLock()
+	defer tree.RUnlock()
 	return tree.meta, nil
 }
 
@@ -120,7 +141,9 @@ func (t *adminTX) ListTreeIDs(ctx context.Context, includeDeleted bool) ([]int64
 
 	var ret []int64
 	for _, v := range t.ms.trees {
-		ret = append(ret, v.meta.TreeId)
+		if includeDeleted || !v.meta.Deleted {
+			ret = append(ret, v.meta.TreeId)
+		}
 	}
 	return ret, nil
 }
@@ -131,7 +154,9 @@ func (t *adminTX) ListTrees(ctx context.Context, includeDeleted bool) ([]*trilli
 
 	var ret []*trillian.Tree
 	for _, v := range t.ms.trees {
-		ret = append(ret, v.meta)
+		if includeDeleted || !v.meta.Deleted {
+			ret = append(ret, v.meta)
+		}
 	}
 	return ret, nil
 }
@@ -140,7 +165,7 @@ func (t *adminTX) CreateTree(ctx context.Context, tr *trillian.Tree) (*trillian.
 	if err := storage.ValidateTreeForCreation(ctx, tr); err != nil {
 		return nil, err
 	}
-	if err := validateStorageSettings(tr); err != nil {
+	if err := storage.ValidateStrataSettings(tr); err != nil {
 		return nil, err
 	}
 
@@ -149,7 +174,7 @@ func (t *adminTX) CreateTree(ctx context.Context, tr *trillian.Tree) (*trillian.
 		return nil, err
 	}
 
-	now := time.Now()
+	now := t.timeSource.Now()
 
 	meta := *tr
 	meta.TreeId = id
@@ -182,12 +207,12 @@ func (t *adminTX) UpdateTree(ctx context.Context, treeID int64, updateFunc func(
 	if err := storage.ValidateTreeForUpdate(ctx, &beforeUpdate, tree); err != nil {
 		return nil, err
 	}
-	if err := validateStorageSettings(tree); err != nil {
-		return nil, err
+	if !proto.Equal(beforeUpdate.StorageSettings, tree.StorageSettings) {
+		return nil, status.Errorf(codes.InvalidArgument, "storage_settings can only be set on creation, got %v", tree.StorageSettings)
 	}
 
 	var err error
-	tree.UpdateTime, err = ptypes.TimestampProto(time.Now())
+	tree.UpdateTime, err = ptypes.TimestampProto(t.timeSource.Now())
 	if err != nil {
 		return nil, err
 	}
@@ -195,7 +220,7 @@ func (t *adminTX) UpdateTree(ctx context.Context, treeID int64, updateFunc func(
 }
 
 func (t *adminTX) SoftDeleteTree(ctx context.Context, treeID int64) (*trillian.Tree, error) {
-	return nil, fmt.Errorf("method not supported: SoftDeleteTree")
+	return t.updateDeleted(treeID, true /* deleted */)
 }
 
 func (t *adminTX) HardDeleteTree(ctx context.Context, treeID int64) error {
@@ -203,12 +228,33 @@ func (t *adminTX) HardDeleteTree(ctx context.Context, treeID int64) error {
 }
 
 func (t *adminTX) UndeleteTree(ctx context.Context, treeID int64) (*trillian.Tree, error) {
-	return nil, fmt.Errorf("method not supported: UndeleteTree")
+	return t.updateDeleted(treeID, false /* deleted */)
 }
 
-func validateStorageSettings(tree *trillian.Tree) error {
-	if tree.StorageSettings != nil {
-		return fmt.Errorf("storage_settings not supported, but got %v", tree.StorageSettings)
+// updateDeleted updates the Deleted and DeleteTime fields of the specified tree.
+func (t *adminTX) updateDeleted(treeID int64, deleted bool) (*trillian.Tree, error) {
+	mTree := t.ms.getTree(treeID)
+	if mTree == nil {
+		return nil, status.Errorf(codes.NotFound, "tree %v not found", treeID)
 	}
-	return nil
+	mTree.mu.Lock()
+	defer mTree.mu.Unlock()
+
+	tree := mTree.meta
+	switch {
+	case deleted && tree.Deleted:
+		return nil, status.Errorf(codes.FailedPrecondition, "tree %v already soft deleted", treeID)
+	case !deleted && !tree.Deleted:
+		return nil, status.Errorf(codes.FailedPrecondition, "tree %v is not soft deleted", treeID)
+	}
+	tree.Deleted = deleted
+	tree.DeleteTime = nil
+	if deleted {
+		var err error
+		if tree.DeleteTime, err = ptypes.TimestampProto(t.timeSource.Now()); err != nil {
+			return nil, err
+		}
+	}
+	// Callers such as the admin server redact the returned tree.
+	return proto.Clone(tree).(*trillian.Tree), nil
 }
@@ -20,9 +20,11 @@ import (
 	"fmt"
 	"math"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/golang/protobuf/ptypes"
 	"github.com/google/btree"
 	"github.com/google/trillian"
 	"github.com/google/trillian/merkle/hashers"
@@ -37,8 +39,6 @@ import (
 const logIDLabel = "logid"
 
 var (
-	defaultLogStrata = []int{8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8}
-
 	once            sync.Once
 	queuedCounter   monitoring.Counter
 	dequeuedCounter monitoring.Counter
@@ -72,6 +72,33 @@ func hashToSeqKey(treeID int64) btree.Item {
 	return &kv{k: fmt.Sprintf("/%d/h2s", treeID)}
 }
 
+// leafIndexPrefix formats the prefix of the keys of a tree's BTree store for
+// the leaves with key in the named leaf index.
+func leafIndexPrefix(treeID int64, index string, key []byte) string {
+	return fmt.Sprintf("/%d/lix/%s/%x/", treeID, index, key)
+}
+
+// leafIndexKey formats a key for use in a tree's BTree store.
+// The associated Item value will be the index of a leaf with key in the named
+// leaf index.
+func leafIndexKey(treeID int64, index string, key []byte, seq int64) btree.Item {
+	return &kv{k: fmt.Sprintf("%s%020d", leafIndexPrefix(treeID, index, key), seq), v: seq}
+}
+
+// leafFilterBlockKey formats a key for use in a tree's BTree store.
+// The associated Item value will be the block of the leaf filter with the
+// given index.
+func leafFilterBlockKey(treeID, index int64) btree.Item {
+	return &kv{k: fmt.Sprintf("/%d/lfb/%020d", treeID, index)}
+}
+
+// mergeDelayWindowKey formats a key for use in a tree's BTree store.
+// The associated Item value will be the storage.MergeDelayWindow starting at
+// the given time.
+func mergeDelayWindowKey(treeID int64, start time.Time) btree.Item {
+	return &kv{k: fmt.Sprintf("/%d/mmd/%020d", treeID, start.UnixNano())}
+}
+
 // sthKey formats a key for use in a tree's BTree store.
 // The associated Item value will be the STH with the given timestamp.
 func sthKey(treeID, timestamp int64) btree.Item {
@@ -149,7 +176,12 @@ func (m *memoryLogStorage) beginInternal(ctx context.Context, treeID int64, read
 		return nil, err
 	}
 
-	stCache := cache.NewLogSubtreeCache(defaultLogStrata, hasher)
+	strata, err := storage.SubtreeStrata(tree)
+	if err != nil {
+		return nil, err
+	}
+
+	stCache := cache.NewLogSubtreeCache(strata, hasher)
 	ttx, err := m.memoryTreeStorage.beginTreeTX(ctx, readonly, treeID, hasher.Size(), stCache)
 	if err != nil {
 		return nil, err
@@ -190,9 +222,228 @@ func (m *memoryLogStorage) AddSequencedLeaves(ctx context.Context, treeID int64,
 	return nil, status.Errorf(codes.Unimplemented, "AddSequencedLeaves is not implemented")
 }
 
+// GetTreeStats implements storage.TreeStatsReader.
+func (m *memoryLogStorage) GetTreeStats(ctx context.Context, treeID int64) (*storage.TreeStats, error) {
+	tree := m.getTree(treeID)
+	if tree == nil {
+		return nil, status.Errorf(codes.NotFound, "tree %v not found", treeID)
+	}
+	tree.RLock()
+	defer tree.RUnlock()
+
+	stats := &storage.TreeStats{}
+	addLeaf := func(leaf *trillian.LogLeaf) {
+		stats.LeafBytes += int64(len(leaf.LeafValue) + len(leaf.ExtraData))
+	}
+	prefix := fmt.Sprintf("/%d/", treeID)
+	tree.store.AscendGreaterOrEqual(&kv{k: prefix}, func(i btree.Item) bool {
+		item := i.(*kv)
+		if !strings.HasPrefix(item.k, prefix) {
+			return false
+		}
+		switch k := item.k[len(prefix):]; {
+		case strings.HasPrefix(k, "seq/"):
+			stats.LeafCount++
+			addLeaf(item.v.(*trillian.LogLeaf))
+		case k == "unseq":
+			for e := item.v.(*list.List).Front(); e != nil; e = e.Next() {
+				addLeaf(e.Value.(*trillian.LogLeaf))
+			}
+		case strings.HasPrefix(k, "subtree/"):
+			stats.SubtreeCount++
+		case strings.HasPrefix(k, "sth/"):
+			stats.RevisionCount++
+		}
+		return true
+	})
+	stats.ReadQPS, stats.WriteQPS = m.activity.QPS(treeID)
+	return stats, nil
+}
+
+// SampleQueue implements storage.LeafQueueInspector. The queue isn't sharded,
+// so all leaves are in bucket 0.
+func (m *memoryLogStorage) SampleQueue(ctx context.Context, treeID int64, opts storage.QueueSampleOptions) (*storage.QueueSample, error) {
+	tree := m.getTree(treeID)
+	if tree == nil {
+		return nil, status.Errorf(codes.NotFound, "tree %v not found", treeID)
+	}
+	tree.RLock()
+	defer tree.RUnlock()
+
+	sample := &storage.QueueSample{}
+	item := tree.store.Get(unseqKey(treeID))
+	if item == nil {
+		sample.AgeCounts = opts.AgeCounts(0, make([]int64, len(opts.AgeBounds)))
+		return sample, nil
+	}
+	cutoffs := opts.Cutoffs()
+	olderThan := make([]int64, len(cutoffs))
+	// Leaves are queued at the back of the list, so the oldest come first.
+	for e := item.(*kv).v.(*list.List).Front(); e != nil; e = e.Next() {
+		leaf := e.Value.(*trillian.LogLeaf)
+		var queued time.Time
+		if leaf.QueueTimestamp != nil {
+			var err error
+			if queued, err = ptypes.Timestamp(leaf.QueueTimestamp); err != nil {
+				return nil, fmt.Errorf("got invalid queue timestamp: %v", err)
+			}
+		}
+		sample.LeafCount++
+		if len(sample.OldestLeaves) < opts.OldestLeaves {
+			sample.OldestLeaves = append(sample.OldestLeaves, storage.QueuedLeaf{
+				LeafIdentityHash: leaf.LeafIdentityHash,
+				MerkleLeafHash:   leaf.MerkleLeafHash,
+				QueueTimestamp:   queued,
+			})
+		}
+		for i, cutoff := range cutoffs {
+			if !queued.After(cutoff) {
+				olderThan[i]++
+			}
+		}
+		if len(sample.Shards) == 0 {
+			sample.Shards = []storage.QueueShard{{Oldest: queued}}
+		}
+		sample.Shards[0].LeafCount++
+	}
+	sample.AgeCounts = opts.AgeCounts(sample.LeafCount, olderThan)
+	return sample, nil
+}
+
+// GetSignedLogRoot implements storage.LogRootReader.
+func (m *memoryLogStorage) GetSignedLogRoot(ctx context.Context, treeID, treeRevision int64) (trillian.SignedLogRoot, error) {
+	tree := m.getTree(treeID)
+	if tree == nil {
+		return trillian.SignedLogRoot{}, status.Errorf(codes.NotFound, "tree %v not found", treeID)
+	}
+	tree.RLock()
+	defer tree.RUnlock()
+
+	var root *trillian.SignedLogRoot
+	tree.store.AscendRange(sthKey(treeID, 0), sthKey(treeID, math.MaxInt64), func(i btree.Item) bool {
+		if r := i.(*kv).v.(trillian.SignedLogRoot); r.TreeRevision == treeRevision {
+			root = &r
+			return false
+		}
+		return true
+	})
+	if root == nil {
+		return trillian.SignedLogRoot{}, status.Errorf(codes.NotFound, "no root at revision %d of tree %d", treeRevision, treeID)
+	}
+	return *root, nil
+}
+
+// GetMergeDelayWindows implements storage.MergeDelayReader.
+func (m *memoryLogStorage) GetMergeDelayWindows(ctx context.Context, treeID int64, start, end time.Time) ([]storage.MergeDelayWindow, error) {
+	tree := m.getTree(treeID)
+	if tree == nil {
+		return nil, status.Errorf(codes.NotFound, "tree %v not found", treeID)
+	}
+	tree.RLock()
+	defer tree.RUnlock()
+
+	var windows []storage.MergeDelayWindow
+	tree.store.AscendRange(mergeDelayWindowKey(treeID, time.Unix(0, 0)), mergeDelayWindowKey(treeID, time.Unix(0, math.MaxInt64)), func(i btree.Item) bool {
+		w := i.(*kv).v.(storage.MergeDelayWindow)
+		if !end.IsZero() && !w.Start.Before(end) {
+			return false
+		}
+		if w.Overlaps(start, end) {
+			windows = append(windows, w)
+		}
+		return true
+	})
+	return windows, nil
+}
+
+// CompactTree implements storage.LogCompactor.
+func (m *memoryLogStorage) CompactTree(ctx context.Context, treeID, treeRevision int64) (int64, error) {
+	tree := m.getTree(treeID)
+	if tree == nil {
+		return 0, status.Errorf(codes.NotFound, "tree %v not found", treeID)
+	}
+	tree.Lock()
+	defer tree.Unlock()
+
+	// Find the revisions at or below treeRevision of each subtree.
+	type subtreeRev struct {
+		item *kv
+		rev  int64
+	}
+	prefix := subtreePrefix(treeID)
+	revisions := make(map[string][]subtreeRev)
+	var err error
+	tree.store.AscendGreaterOrEqual(&kv{k: prefix}, func(i btree.Item) bool {
+		item := i.(*kv)
+		if !strings.HasPrefix(item.k, prefix) {
+			return false
+		}
+		sep := strings.LastIndex(item.k, "/")
+		var rev int64
+		if rev, err = strconv.ParseInt(item.k[sep+1:], 10, 64); err != nil {
+			return false
+		}
+		if id := item.k[:sep]; rev <= treeRevision {
+			revisions[id] = append(revisions[id], subtreeRev{item: item, rev: rev})
+		}
+		return true
+	})
+	if err != nil {
+		return 0, fmt.Errorf("bad subtree key: %v", err)
+	}
+
+	// Keep only the newest revision of each subtree, at treeRevision.
+	var removed int64
+	for id, revs := range revisions {
+		newest := revs[0]
+		for _, r := range revs {
+			tree.store.Delete(r.item)
+			if r.rev > newest.rev {
+				newest = r
+			}
+		}
+		tree.store.ReplaceOrInsert(&kv{k: fmt.Sprintf("%s/%d", id, treeRevision), v: newest.item.v})
+		removed += int64(len(revs) - 1)
+	}
+	return removed, nil
+}
+
+// RepairMerkleNodes implements storage.LogSubtreeRepairer.
+func (m *memoryLogStorage) RepairMerkleNodes(ctx context.Context, treeID, treeRevision int64, nodes []storage.Node) error {
+	tree, err := trees.GetTree(ctx, m.admin, treeID, trees.NewGetOpts(true /* readonly */, trillian.TreeType_LOG, trillian.TreeType_PREORDERED_LOG))
+	if err != nil {
+		return err
+	}
+	hasher, err := hashers.NewLogHasher(tree.HashStrategy)
+	if err != nil {
+		return err
+	}
+	strata, err := storage.SubtreeStrata(tree)
+	if err != nil {
+		return err
+	}
+	subtrees, err := cache.BuildLogSubtrees(strata, hasher, nodes)
+	if err != nil {
+		return err
+	}
+
+	t := m.getTree(treeID)
+	if t == nil {
+		return status.Errorf(codes.NotFound, "tree %v not found", treeID)
+	}
+	t.Lock()
+	defer t.Unlock()
+	for _, st := range subtrees {
+		k := subtreeKey(treeID, treeRevision, storage.NewNodeIDFromHash(st.Prefix))
+		k.(*kv).v = st
+		t.store.ReplaceOrInsert(k)
+	}
+	return nil
+}
+
 func (m *memoryLogStorage) SnapshotForTree(ctx context.Context, treeID int64) (storage.ReadOnlyLogTreeTX, error) {
 	tx, err := m.beginInternal(ctx, treeID, true /* readonly */)
-	if err != nil {
+	if err != nil && err != storage.ErrTreeNeedsInit {
 		return nil, err
 	}
 	return tx.(storage.ReadOnlyLogTreeTX), err
@@ -240,6 +491,11 @@ func (t *logTreeTX) WriteRevision() int64 {
 	return t.treeTX.writeRevision
 }
 
+// GetNodesByCoords implements storage.CoordNodeReader.
+func (t *logTreeTX) GetNodesByCoords(ctx context.Context, treeRevision int64, coords []storage.NodeCoords) ([]storage.CoordNode, error) {
+	return t.subtreeCache.GetNodesByCoords(coords, t.getSubtreesAtRev(ctx, treeRevision))
+}
+
 func (t *logTreeTX) DequeueLeaves(ctx context.Context, limit int, cutoffTime time.Time) ([]*trillian.LogLeaf, error) {
 	leaves := make([]*trillian.LogLeaf, 0, limit)
 
@@ -261,6 +517,11 @@ func (t *logTreeTX) QueueLeaves(ctx context.Context, leaves []*trillian.LogLeaf,
 		if len(leaf.LeafIdentityHash) != t.hashSizeBytes {
 			return nil, fmt.Errorf("queued leaf must have a leaf ID hash of length %d", t.hashSizeBytes)
 		}
+		var err error
+		leaf.QueueTimestamp, err = ptypes.TimestampProto(queueTimestamp)
+		if err != nil {
+			return nil, fmt.Errorf("got invalid queue timestamp: %v", err)
+		}
 	}
 	queuedCounter.Add(float64(len(leaves)), labelForTX(t))
 	// No deduping in this storage!
@@ -308,7 +569,7 @@ func (t *logTreeTX) GetLeavesByHash(ctx context.Context, leafHashes [][]byte, or
 	m := t.tx.Get(hashToSeqKey(t.treeID)).(*kv).v.(map[string][]int64)
 
 	ret := make([]*trillian.LogLeaf, 0, len(leafHashes))
-	for hash := range leafHashes {
+	for _, hash := range leafHashes {
 		seq, ok := m[string(hash)]
 		if !ok {
 			continue
@@ -349,6 +610,62 @@ func (t *logTreeTX) StoreSignedLogRoot(ctx context.Context, root trillian.Signed
 	return nil
 }
 
+// AddLeafIndexKeys implements storage.LeafIndexWriter.
+func (t *logTreeTX) AddLeafIndexKeys(ctx context.Context, keys []storage.LeafIndexKey) error {
+	for _, k := range keys {
+		t.tx.ReplaceOrInsert(leafIndexKey(t.treeID, k.Index, k.Key, k.LeafIndex))
+	}
+	return nil
+}
+
+// GetLeafIndicesByKey implements storage.LeafIndexReader.
+func (t *logTreeTX) GetLeafIndicesByKey(ctx context.Context, index string, key []byte, start int64, count int) ([]int64, error) {
+	prefix := leafIndexPrefix(t.treeID, index, key)
+	var ret []int64
+	t.tx.AscendGreaterOrEqual(leafIndexKey(t.treeID, index, key, start), func(i btree.Item) bool {
+		if len(ret) >= count || !strings.HasPrefix(i.(*kv).k, prefix) {
+			return false
+		}
+		ret = append(ret, i.(*kv).v.(int64))
+		return true
+	})
+	return ret, nil
+}
+
+// GetLeafFilterBlocks implements storage.LeafFilterReader.
+func (t *logTreeTX) GetLeafFilterBlocks(ctx context.Context, indices []int64) (map[int64][]byte, error) {
+	blocks := make(map[int64][]byte)
+	for _, index := range indices {
+		if i := t.tx.Get(leafFilterBlockKey(t.treeID, index)); i != nil {
+			blocks[index] = append([]byte(nil), i.(*kv).v.([]byte)...)
+		}
+	}
+	return blocks, nil
+}
+
+// SetLeafFilterBlocks implements storage.LeafFilterWriter.
+func (t *logTreeTX) SetLeafFilterBlocks(ctx context.Context, blocks map[int64][]byte) error {
+	for index, block := range blocks {
+		k := leafFilterBlockKey(t.treeID, index).(*kv)
+		k.v = append([]byte(nil), block...)
+		t.tx.ReplaceOrInsert(k)
+	}
+	return nil
+}
+
+// AddMergeDelayWindow implements storage.MergeDelayWriter.
+func (t *logTreeTX) AddMergeDelayWindow(ctx context.Context, w storage.MergeDelayWindow) error {
+	k := mergeDelayWindowKey(t.treeID, w.Start).(*kv)
+	if i := t.tx.Get(k); i != nil {
+		prev := i.(*kv).v.(storage.MergeDelayWindow)
+		prev.Add(w)
+		w = prev
+	}
+	k.v = w
+	t.tx.ReplaceOrInsert(k)
+	return nil
+}
+
 func (t *logTreeTX) UpdateSequencedLeaves(ctx context.Context, leaves []*trillian.LogLeaf) error {
 	countByMerkleHash := make(map[string]int)
 	for _, leaf := range leaves {
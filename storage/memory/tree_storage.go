@@ -28,6 +28,7 @@ import (
 	"github.com/google/trillian/storage"
 	"github.com/google/trillian/storage/cache"
 	"github.com/google/trillian/storage/storagepb"
+	"github.com/google/trillian/util"
 )
 
 const degree = 8
@@ -36,7 +37,12 @@ const degree = 8
 // The associated Item value will be the stubtreeProto with the given nodeID
 // prefix.
 func subtreeKey(treeID, rev int64, nodeID storage.NodeID) btree.Item {
-	return &kv{k: fmt.Sprintf("/%d/subtree/%s/%d", treeID, nodeID.String(), rev)}
+	return &kv{k: fmt.Sprintf("%s%s/%d", subtreePrefix(treeID), nodeID.String(), rev)}
+}
+
+// subtreePrefix returns the prefix of all the subtree keys of a tree.
+func subtreePrefix(treeID int64) string {
+	return fmt.Sprintf("/%d/subtree/", treeID)
 }
 
 // tree stores all data for a given treeID
@@ -83,11 +89,15 @@ type memoryTreeStorage struct {
 	// mu only protects access to the trees map.
 	mu    sync.RWMutex
 	trees map[int64]*tree
+
+	// activity counts the transactions started on each tree.
+	activity *storage.TreeActivity
 }
 
 func newTreeStorage() *memoryTreeStorage {
 	return &memoryTreeStorage{
-		trees: make(map[int64]*tree),
+		trees:    make(map[int64]*tree),
+		activity: storage.NewTreeActivity(util.SystemTimeSource{}),
 	}
 }
 
@@ -128,6 +138,11 @@ func newTree(t trillian.Tree) *tree {
 
 func (m *memoryTreeStorage) beginTreeTX(ctx context.Context, readonly bool, treeID int64, hashSizeBytes int, cache cache.SubtreeCache) (treeTX, error) {
 	tree := m.getTree(treeID)
+	if readonly {
+		m.activity.Read(treeID)
+	} else {
+		m.activity.Write(treeID)
+	}
 	// Lock the tree for the duration of the TX.
 	// It will be unlocked by a call to Commit or Rollback.
 	var unlock func()
@@ -0,0 +1,83 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// MergeDelayWindow summarizes the merge delays of the leaves which a log
+// integrated during a window of time, i.e. how long they waited between being
+// queued and being integrated, against the log's maximum merge delay (MMD).
+type MergeDelayWindow struct {
+	// Start is the start of the window, which identifies it among the
+	// windows of the log.
+	Start time.Time
+	// Length is the length of the window.
+	Length time.Duration
+	// MaxMergeDelay is the MMD of the log when it integrated the leaves.
+	MaxMergeDelay time.Duration
+	// Leaves is the number of leaves integrated during the window.
+	Leaves int64
+	// Violations is the number of the leaves whose merge delay exceeded the
+	// MMD.
+	Violations int64
+	// NearMisses is the number of the leaves whose merge delay was within the
+	// MMD, but close to it.
+	NearMisses int64
+	// MaxDelay is the longest merge delay of the leaves.
+	MaxDelay time.Duration
+}
+
+// End returns the end of the window.
+func (w MergeDelayWindow) End() time.Time {
+	return w.Start.Add(w.Length)
+}
+
+// Add adds the leaves counted in other, a summary of more leaves integrated
+// during the same window, to w. The MMD of other, being more recent, wins.
+func (w *MergeDelayWindow) Add(other MergeDelayWindow) {
+	w.MaxMergeDelay = other.MaxMergeDelay
+	w.Leaves += other.Leaves
+	w.Violations += other.Violations
+	w.NearMisses += other.NearMisses
+	if other.MaxDelay > w.MaxDelay {
+		w.MaxDelay = other.MaxDelay
+	}
+}
+
+// Overlaps returns whether w ends after start and starts before end. Zero
+// start and end times are open bounds.
+func (w MergeDelayWindow) Overlaps(start, end time.Time) bool {
+	return (start.IsZero() || w.End().After(start)) && (end.IsZero() || w.Start.Before(end))
+}
+
+// MergeDelayWriter may be implemented by LogTreeTX implementations which can
+// record the merge delay compliance of a log.
+type MergeDelayWriter interface {
+	// AddMergeDelayWindow adds the leaves counted in w to the window of the
+	// log starting at w.Start, which is created if needed.
+	AddMergeDelayWindow(ctx context.Context, w MergeDelayWindow) error
+}
+
+// MergeDelayReader may be implemented by LogStorage implementations which
+// can read the windows recorded with a MergeDelayWriter.
+type MergeDelayReader interface {
+	// GetMergeDelayWindows returns the windows of the specified log which
+	// overlap [start, end), oldest first. Zero start and end times are open
+	// bounds.
+	GetMergeDelayWindows(ctx context.Context, treeID int64, start, end time.Time) ([]MergeDelayWindow, error)
+}
@@ -0,0 +1,50 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMergeDelayWindowAdd(t *testing.T) {
+	start := time.Unix(3600, 0)
+	w := MergeDelayWindow{Start: start, Length: time.Hour, MaxMergeDelay: time.Minute, Leaves: 3, NearMisses: 2, MaxDelay: 55 * time.Second}
+	w.Add(MergeDelayWindow{Start: start, Length: time.Hour, MaxMergeDelay: 2 * time.Minute, Leaves: 2, Violations: 1, MaxDelay: 3 * time.Minute})
+	want := MergeDelayWindow{Start: start, Length: time.Hour, MaxMergeDelay: 2 * time.Minute, Leaves: 5, Violations: 1, NearMisses: 2, MaxDelay: 3 * time.Minute}
+	if w != want {
+		t.Errorf("Add(): got %+v, want %+v", w, want)
+	}
+}
+
+func TestMergeDelayWindowOverlaps(t *testing.T) {
+	start := time.Unix(3600, 0)
+	w := MergeDelayWindow{Start: start, Length: time.Hour}
+	for _, test := range []struct {
+		desc       string
+		start, end time.Time
+		want       bool
+	}{
+		{desc: "unbounded", want: true},
+		{desc: "inside", start: start.Add(time.Minute), end: start.Add(2 * time.Minute), want: true},
+		{desc: "endsAtStart", end: start},
+		{desc: "startsAtEnd", start: start.Add(time.Hour)},
+		{desc: "startsBeforeEnd", start: start.Add(59 * time.Minute), want: true},
+	} {
+		if got := w.Overlaps(test.start, test.end); got != test.want {
+			t.Errorf("%v: Overlaps() = %v, want %v", test.desc, got, test.want)
+		}
+	}
+}
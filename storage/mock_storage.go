@@ -713,6 +713,19 @@ func (mr *MockMapTreeTXMockRecorder) Get(arg0, arg1, arg2 interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockMapTreeTX)(nil).Get), arg0, arg1, arg2)
 }
 
+// GetLeafHistory mocks base method
+func (m *MockMapTreeTX) GetLeafHistory(arg0 context.Context, arg1 []byte, arg2, arg3 int64) ([]MapLeafRevision, error) {
+	ret := m.ctrl.Call(m, "GetLeafHistory", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]MapLeafRevision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLeafHistory indicates an expected call of GetLeafHistory
+func (mr *MockMapTreeTXMockRecorder) GetLeafHistory(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLeafHistory", reflect.TypeOf((*MockMapTreeTX)(nil).GetLeafHistory), arg0, arg1, arg2, arg3)
+}
+
 // GetMerkleNodes mocks base method
 func (m *MockMapTreeTX) GetMerkleNodes(arg0 context.Context, arg1 int64, arg2 []NodeID) ([]Node, error) {
 	ret := m.ctrl.Call(m, "GetMerkleNodes", arg0, arg1, arg2)
@@ -1252,6 +1265,19 @@ func (mr *MockReadOnlyMapTreeTXMockRecorder) Get(arg0, arg1, arg2 interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockReadOnlyMapTreeTX)(nil).Get), arg0, arg1, arg2)
 }
 
+// GetLeafHistory mocks base method
+func (m *MockReadOnlyMapTreeTX) GetLeafHistory(arg0 context.Context, arg1 []byte, arg2, arg3 int64) ([]MapLeafRevision, error) {
+	ret := m.ctrl.Call(m, "GetLeafHistory", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]MapLeafRevision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLeafHistory indicates an expected call of GetLeafHistory
+func (mr *MockReadOnlyMapTreeTXMockRecorder) GetLeafHistory(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLeafHistory", reflect.TypeOf((*MockReadOnlyMapTreeTX)(nil).GetLeafHistory), arg0, arg1, arg2, arg3)
+}
+
 // GetMerkleNodes mocks base method
 func (m *MockReadOnlyMapTreeTX) GetMerkleNodes(arg0 context.Context, arg1 int64, arg2 []NodeID) ([]Node, error) {
 	ret := m.ctrl.Call(m, "GetMerkleNodes", arg0, arg1, arg2)
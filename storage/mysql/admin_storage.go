@@ -17,6 +17,7 @@ package mysql
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"sync"
 	"time"
@@ -25,10 +26,13 @@ import (
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/golang/protobuf/ptypes/any"
+	"github.com/golang/protobuf/ptypes/duration"
 	"github.com/google/trillian"
 	"github.com/google/trillian/crypto/keyspb"
 	spb "github.com/google/trillian/crypto/sigpb"
 	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/storagepb"
+	"github.com/google/trillian/util"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -57,20 +61,47 @@ const (
 			PublicKey,
 			MaxRootDurationMillis,
 			Deleted,
-			DeleteTimeMillis
+			DeleteTimeMillis,
+			StorageSettings,
+			RootTimestampPrecision,
+			Labels,
+			LeafIndexes,
+			LeafFilter,
+			Maintenance,
+			AdditionalSigningKeys,
+			MaxMergeDelayMillis,
+			ValidityWindow,
+			WriteVerification
 		FROM Trees`
 	selectNonDeletedTrees = selectTrees + nonDeletedWhere
 	selectTreeByID        = selectTrees + " WHERE TreeId = ?"
 )
 
+// AdminStorageOptions configures a MySQL storage.AdminStorage.
+type AdminStorageOptions struct {
+	// TimeSource provides the creation, update and deletion times of trees.
+	// Defaults to util.SystemTimeSource.
+	TimeSource util.TimeSource
+}
+
 // NewAdminStorage returns a MySQL storage.AdminStorage implementation backed by DB.
 func NewAdminStorage(db *sql.DB) storage.AdminStorage {
-	return &mysqlAdminStorage{db}
+	return NewAdminStorageWithOpts(db, AdminStorageOptions{})
+}
+
+// NewAdminStorageWithOpts returns a MySQL storage.AdminStorage implementation
+// backed by DB, configured by opts.
+func NewAdminStorageWithOpts(db *sql.DB, opts AdminStorageOptions) storage.AdminStorage {
+	if opts.TimeSource == nil {
+		opts.TimeSource = util.SystemTimeSource{}
+	}
+	return &mysqlAdminStorage{db: db, timeSource: opts.TimeSource}
 }
 
 // mysqlAdminStorage implements storage.AdminStorage
 type mysqlAdminStorage struct {
-	db *sql.DB
+	db         *sql.DB
+	timeSource util.TimeSource
 }
 
 func (s *mysqlAdminStorage) Snapshot(ctx context.Context) (storage.ReadOnlyAdminTX, error) {
@@ -82,7 +113,7 @@ func (s *mysqlAdminStorage) beginInternal(ctx context.Context) (storage.AdminTX,
 	if err != nil {
 		return nil, err
 	}
-	return &adminTX{tx: tx}, nil
+	return &adminTX{tx: tx, timeSource: s.timeSource}, nil
 }
 
 func (s *mysqlAdminStorage) ReadWriteTransaction(ctx context.Context, f storage.AdminTXFunc) error {
@@ -102,7 +133,8 @@ func (s *mysqlAdminStorage) CheckDatabaseAccessible(ctx context.Context) error {
 }
 
 type adminTX struct {
-	tx *sql.Tx
+	tx         *sql.Tx
+	timeSource util.TimeSource
 
 	// mu guards *direct* reads/writes on closed, which happen only on
 	// Commit/Rollback/IsClosed/Close methods.
@@ -183,7 +215,9 @@ func readTree(row row) (*trillian.Tree, error) {
 	var displayName, description sql.NullString
 	var privateKey, publicKey []byte
 	var deleted sql.NullBool
-	var deleteMillis sql.NullInt64
+	var deleteMillis, maxMergeDelayMillis sql.NullInt64
+	var storageSettings []byte
+	var rootTimestampPrecision, labels, leafIndexes, leafFilter, maintenance, additionalSigningKeys, validityWindow, writeVerification sql.NullString
 	err := row.Scan(
 		&tree.TreeId,
 		&treeState,
@@ -200,6 +234,16 @@ func readTree(row row) (*trillian.Tree, error) {
 		&maxRootDurationMillis,
 		&deleted,
 		&deleteMillis,
+		&storageSettings,
+		&rootTimestampPrecision,
+		&labels,
+		&leafIndexes,
+		&leafFilter,
+		&maintenance,
+		&additionalSigningKeys,
+		&maxMergeDelayMillis,
+		&validityWindow,
+		&writeVerification,
 	)
 	if err != nil {
 		return nil, err
@@ -264,6 +308,66 @@ func readTree(row row) (*trillian.Tree, error) {
 	}
 	tree.PublicKey = &keyspb.PublicKey{Der: publicKey}
 
+	if len(storageSettings) > 0 {
+		tree.StorageSettings = &any.Any{}
+		if err := proto.Unmarshal(storageSettings, tree.StorageSettings); err != nil {
+			return nil, fmt.Errorf("could not unmarshal StorageSettings: %v", err)
+		}
+	}
+
+	if rootTimestampPrecision.Valid {
+		if p, ok := trillian.RootTimestampPrecision_value[rootTimestampPrecision.String]; ok {
+			tree.RootTimestampPrecision = trillian.RootTimestampPrecision(p)
+		} else {
+			return nil, fmt.Errorf("unknown RootTimestampPrecision: %v", rootTimestampPrecision.String)
+		}
+	}
+	if writeVerification.Valid {
+		if v, ok := trillian.WriteVerification_value[writeVerification.String]; ok {
+			tree.WriteVerification = trillian.WriteVerification(v)
+		} else {
+			return nil, fmt.Errorf("unknown WriteVerification: %v", writeVerification.String)
+		}
+	}
+
+	if labels.Valid && labels.String != "" {
+		if err := json.Unmarshal([]byte(labels.String), &tree.Labels); err != nil {
+			return nil, fmt.Errorf("could not unmarshal Labels: %v", err)
+		}
+	}
+	if leafIndexes.Valid && leafIndexes.String != "" {
+		if err := json.Unmarshal([]byte(leafIndexes.String), &tree.LeafIndexes); err != nil {
+			return nil, fmt.Errorf("could not unmarshal LeafIndexes: %v", err)
+		}
+	}
+	if leafFilter.Valid && leafFilter.String != "" {
+		tree.LeafFilter = &trillian.LeafFilterSpec{}
+		if err := json.Unmarshal([]byte(leafFilter.String), tree.LeafFilter); err != nil {
+			return nil, fmt.Errorf("could not unmarshal LeafFilter: %v", err)
+		}
+	}
+	if maintenance.Valid && maintenance.String != "" {
+		tree.Maintenance = &trillian.MaintenanceMode{}
+		if err := json.Unmarshal([]byte(maintenance.String), tree.Maintenance); err != nil {
+			return nil, fmt.Errorf("could not unmarshal Maintenance: %v", err)
+		}
+	}
+	if additionalSigningKeys.Valid && additionalSigningKeys.String != "" {
+		if err := json.Unmarshal([]byte(additionalSigningKeys.String), &tree.AdditionalSigningKeys); err != nil {
+			return nil, fmt.Errorf("could not unmarshal AdditionalSigningKeys: %v", err)
+		}
+	}
+	if validityWindow.Valid && validityWindow.String != "" {
+		tree.ValidityWindow = &trillian.ValidityWindow{}
+		if err := json.Unmarshal([]byte(validityWindow.String), tree.ValidityWindow); err != nil {
+			return nil, fmt.Errorf("could not unmarshal ValidityWindow: %v", err)
+		}
+	}
+
+	if maxMergeDelayMillis.Valid && maxMergeDelayMillis.Int64 > 0 {
+		tree.MaxMergeDelay = ptypes.DurationProto(time.Duration(maxMergeDelayMillis.Int64) * time.Millisecond)
+	}
+
 	tree.Deleted = deleted.Valid && deleted.Bool
 	if tree.Deleted && deleteMillis.Valid {
 		tree.DeleteTime, err = ptypes.TimestampProto(fromMillisSinceEpoch(deleteMillis.Int64))
@@ -282,6 +386,100 @@ func setNullStringIfValid(src sql.NullString, dest *string) {
 	}
 }
 
+// marshalLabels returns the value of the Labels column for labels, which is
+// NULL if there are none.
+func marshalLabels(labels map[string]string) (interface{}, error) {
+	if len(labels) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(labels)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal Labels: %v", err)
+	}
+	return string(b), nil
+}
+
+// marshalLeafIndexes returns the value of the LeafIndexes column for specs,
+// which is NULL if there are none.
+func marshalLeafIndexes(specs []*trillian.LeafIndexSpec) (interface{}, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(specs)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal LeafIndexes: %v", err)
+	}
+	return string(b), nil
+}
+
+// marshalLeafFilter returns the value of the LeafFilter column for spec,
+// which is NULL if there's none.
+func marshalLeafFilter(spec *trillian.LeafFilterSpec) (interface{}, error) {
+	if spec == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(spec)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal LeafFilter: %v", err)
+	}
+	return string(b), nil
+}
+
+// marshalMaintenance returns the value of the Maintenance column for mode,
+// which is NULL if the tree isn't in maintenance.
+func marshalMaintenance(mode *trillian.MaintenanceMode) (interface{}, error) {
+	if mode == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(mode)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal Maintenance: %v", err)
+	}
+	return string(b), nil
+}
+
+// marshalAdditionalSigningKeys returns the value of the AdditionalSigningKeys
+// column for keys, which is NULL if there are none.
+func marshalAdditionalSigningKeys(keys []*trillian.SigningKey) (interface{}, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(keys)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal AdditionalSigningKeys: %v", err)
+	}
+	return string(b), nil
+}
+
+// marshalValidityWindow returns the value of the ValidityWindow column for w,
+// which is NULL if the tree accepts leaves at any time.
+func marshalValidityWindow(w *trillian.ValidityWindow) (interface{}, error) {
+	if w == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(w)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal ValidityWindow: %v", err)
+	}
+	return string(b), nil
+}
+
+// marshalMaxMergeDelay returns the value of the MaxMergeDelayMillis column for
+// mmd, which is NULL if merge delays aren't tracked.
+func marshalMaxMergeDelay(mmd *duration.Duration) (interface{}, error) {
+	if mmd == nil {
+		return nil, nil
+	}
+	d, err := ptypes.Duration(mmd)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse MaxMergeDelay: %v", err)
+	}
+	if d <= 0 {
+		return nil, nil
+	}
+	return int64(d / time.Millisecond), nil
+}
+
 func (t *adminTX) ListTreeIDs(ctx context.Context, includeDeleted bool) ([]int64, error) {
 	var query string
 	if includeDeleted {
@@ -356,7 +554,7 @@ func (t *adminTX) CreateTree(ctx context.Context, tree *trillian.Tree) (*trillia
 	}
 
 	// Use the time truncated-to-millis throughout, as that's what's stored.
-	nowMillis := toMillisSinceEpoch(time.Now())
+	nowMillis := toMillisSinceEpoch(t.timeSource.Now())
 	now := fromMillisSinceEpoch(nowMillis)
 
 	newTree := *tree
@@ -389,8 +587,18 @@ func (t *adminTX) CreateTree(ctx context.Context, tree *trillian.Tree) (*trillia
 			UpdateTimeMillis,
 			PrivateKey,
 			PublicKey,
-			MaxRootDurationMillis)
-		VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+			MaxRootDurationMillis,
+			StorageSettings,
+			RootTimestampPrecision,
+			Labels,
+			LeafIndexes,
+			LeafFilter,
+			Maintenance,
+			AdditionalSigningKeys,
+			MaxMergeDelayMillis,
+			ValidityWindow,
+			WriteVerification)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
 	if err != nil {
 		return nil, err
 	}
@@ -400,6 +608,40 @@ func (t *adminTX) CreateTree(ctx context.Context, tree *trillian.Tree) (*trillia
 	if err != nil {
 		return nil, fmt.Errorf("could not marshal PrivateKey: %v", err)
 	}
+	var storageSettings []byte
+	if newTree.StorageSettings != nil {
+		if storageSettings, err = proto.Marshal(newTree.StorageSettings); err != nil {
+			return nil, fmt.Errorf("could not marshal StorageSettings: %v", err)
+		}
+	}
+	labels, err := marshalLabels(newTree.Labels)
+	if err != nil {
+		return nil, err
+	}
+	leafIndexes, err := marshalLeafIndexes(newTree.LeafIndexes)
+	if err != nil {
+		return nil, err
+	}
+	leafFilter, err := marshalLeafFilter(newTree.LeafFilter)
+	if err != nil {
+		return nil, err
+	}
+	maintenance, err := marshalMaintenance(newTree.Maintenance)
+	if err != nil {
+		return nil, err
+	}
+	additionalSigningKeys, err := marshalAdditionalSigningKeys(newTree.AdditionalSigningKeys)
+	if err != nil {
+		return nil, err
+	}
+	maxMergeDelay, err := marshalMaxMergeDelay(newTree.MaxMergeDelay)
+	if err != nil {
+		return nil, err
+	}
+	validityWindow, err := marshalValidityWindow(newTree.ValidityWindow)
+	if err != nil {
+		return nil, err
+	}
 
 	_, err = insertTreeStmt.ExecContext(
 		ctx,
@@ -416,6 +658,16 @@ func (t *adminTX) CreateTree(ctx context.Context, tree *trillian.Tree) (*trillia
 		privateKey,
 		newTree.PublicKey.GetDer(),
 		rootDuration/time.Millisecond,
+		storageSettings,
+		newTree.RootTimestampPrecision.String(),
+		labels,
+		leafIndexes,
+		leafFilter,
+		maintenance,
+		additionalSigningKeys,
+		maxMergeDelay,
+		validityWindow,
+		newTree.WriteVerification.String(),
 	)
 	if err != nil {
 		return nil, err
@@ -468,12 +720,12 @@ func (t *adminTX) UpdateTree(ctx context.Context, treeID int64, updateFunc func(
 	if err := storage.ValidateTreeForUpdate(ctx, &beforeUpdate, tree); err != nil {
 		return nil, err
 	}
-	if err := validateStorageSettings(tree); err != nil {
-		return nil, err
+	if !proto.Equal(beforeUpdate.StorageSettings, tree.StorageSettings) {
+		return nil, status.Errorf(codes.InvalidArgument, "storage_settings can only be set on creation, got %v", tree.StorageSettings)
 	}
 
 	// Use the time truncated-to-millis throughout, as that's what's stored.
-	nowMillis := toMillisSinceEpoch(time.Now())
+	nowMillis := toMillisSinceEpoch(t.timeSource.Now())
 	now := fromMillisSinceEpoch(nowMillis)
 	tree.UpdateTime, err = ptypes.TimestampProto(now)
 	if err != nil {
@@ -488,11 +740,31 @@ func (t *adminTX) UpdateTree(ctx context.Context, treeID int64, updateFunc func(
 	if err != nil {
 		return nil, fmt.Errorf("could not marshal PrivateKey: %v", err)
 	}
+	labels, err := marshalLabels(tree.Labels)
+	if err != nil {
+		return nil, err
+	}
+	maintenance, err := marshalMaintenance(tree.Maintenance)
+	if err != nil {
+		return nil, err
+	}
+	additionalSigningKeys, err := marshalAdditionalSigningKeys(tree.AdditionalSigningKeys)
+	if err != nil {
+		return nil, err
+	}
+	maxMergeDelay, err := marshalMaxMergeDelay(tree.MaxMergeDelay)
+	if err != nil {
+		return nil, err
+	}
+	validityWindow, err := marshalValidityWindow(tree.ValidityWindow)
+	if err != nil {
+		return nil, err
+	}
 
 	stmt, err := t.tx.PrepareContext(
 		ctx,
 		`UPDATE Trees
-		SET TreeState = ?, DisplayName = ?, Description = ?, UpdateTimeMillis = ?, MaxRootDurationMillis = ?, PrivateKey = ?
+		SET TreeState = ?, DisplayName = ?, Description = ?, UpdateTimeMillis = ?, MaxRootDurationMillis = ?, PrivateKey = ?, RootTimestampPrecision = ?, Labels = ?, Maintenance = ?, AdditionalSigningKeys = ?, MaxMergeDelayMillis = ?, ValidityWindow = ?, WriteVerification = ?
 		WHERE TreeId = ?`)
 	if err != nil {
 		return nil, err
@@ -507,6 +779,13 @@ func (t *adminTX) UpdateTree(ctx context.Context, treeID int64, updateFunc func(
 		nowMillis,
 		rootDuration/time.Millisecond,
 		privateKey,
+		tree.RootTimestampPrecision.String(),
+		labels,
+		maintenance,
+		additionalSigningKeys,
+		maxMergeDelay,
+		validityWindow,
+		tree.WriteVerification.String(),
 		tree.TreeId); err != nil {
 		return nil, err
 	}
@@ -515,7 +794,7 @@ func (t *adminTX) UpdateTree(ctx context.Context, treeID int64, updateFunc func(
 }
 
 func (t *adminTX) SoftDeleteTree(ctx context.Context, treeID int64) (*trillian.Tree, error) {
-	return t.updateDeleted(ctx, treeID, true /* deleted */, toMillisSinceEpoch(time.Now()) /* deleteTimeMillis */)
+	return t.updateDeleted(ctx, treeID, true /* deleted */, toMillisSinceEpoch(t.timeSource.Now()) /* deleteTimeMillis */)
 }
 
 func (t *adminTX) UndeleteTree(ctx context.Context, treeID int64) (*trillian.Tree, error) {
@@ -578,9 +857,28 @@ func fromMillisSinceEpoch(ts int64) time.Time {
 	return time.Unix(secs, msecs*1000000)
 }
 
+// validateStorageSettings checks that the storage_settings of tree, if any,
+// are valid LogStorageSettings of a log or MapStorageSettings of a map.
 func validateStorageSettings(tree *trillian.Tree) error {
-	if tree.StorageSettings != nil {
+	if tree.StorageSettings == nil {
+		return nil
+	}
+	if !ptypes.Is(tree.StorageSettings, &storagepb.LogStorageSettings{}) && !ptypes.Is(tree.StorageSettings, &storagepb.MapStorageSettings{}) {
 		return fmt.Errorf("storage_settings not supported, but got %v", tree.StorageSettings)
 	}
-	return nil
+	_, err := storage.SubtreeStrata(tree)
+	return err
+}
+
+// logStorageSettings returns the LogStorageSettings of tree, or the default
+// settings if it has none.
+func logStorageSettings(tree *trillian.Tree) (*storagepb.LogStorageSettings, error) {
+	settings := &storagepb.LogStorageSettings{}
+	if tree.StorageSettings == nil {
+		return settings, nil
+	}
+	if err := ptypes.UnmarshalAny(tree.StorageSettings, settings); err != nil {
+		return nil, fmt.Errorf("invalid storage_settings: %v", err)
+	}
+	return settings, nil
 }
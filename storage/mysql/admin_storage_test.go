@@ -19,12 +19,18 @@ import (
 	"database/sql"
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
 	"github.com/google/trillian"
 	"github.com/google/trillian/crypto/keyspb"
 	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/storagepb"
 	"github.com/google/trillian/storage/testonly"
+	"github.com/google/trillian/util"
+	"github.com/kylelemons/godebug/pretty"
 )
 
 const selectTreeControlByID = "SELECT SigningEnabled, SequencingEnabled, SequenceIntervalSeconds FROM TreeControl WHERE TreeId = ?"
@@ -173,6 +179,402 @@ func TestAdminTX_StorageSettingsNotSupported(t *testing.T) {
 	}
 }
 
+func TestAdminTX_LogStorageSettings(t *testing.T) {
+	cleanTestDB(DB)
+	s := NewAdminStorage(DB)
+	ctx := context.Background()
+
+	latestOnly, err := ptypes.MarshalAny(&storagepb.LogStorageSettings{SubtreeRevisions: storagepb.LogStorageSettings_LATEST_ONLY})
+	if err != nil {
+		t.Fatalf("Error marshaling proto: %v", err)
+	}
+	allRevisions, err := ptypes.MarshalAny(&storagepb.LogStorageSettings{})
+	if err != nil {
+		t.Fatalf("Error marshaling proto: %v", err)
+	}
+
+	logTree := *testonly.LogTree
+	logTree.StorageSettings = latestOnly
+	tree, err := storage.CreateTree(ctx, s, &logTree)
+	if err != nil {
+		t.Fatalf("CreateTree() failed with err = %v", err)
+	}
+	got, err := storage.GetTree(ctx, s, tree.TreeId)
+	if err != nil {
+		t.Fatalf("GetTree() failed with err = %v", err)
+	}
+	if !proto.Equal(got.StorageSettings, latestOnly) {
+		t.Errorf("GetTree().StorageSettings = %v, want %v", got.StorageSettings, latestOnly)
+	}
+
+	if _, err := storage.UpdateTree(ctx, s, tree.TreeId, func(tree *trillian.Tree) { tree.StorageSettings = allRevisions }); err == nil {
+		t.Error("UpdateTree() changing StorageSettings: err = nil, want non-nil")
+	}
+	updated, err := storage.UpdateTree(ctx, s, tree.TreeId, func(tree *trillian.Tree) { tree.DisplayName = "updated" })
+	if err != nil {
+		t.Fatalf("UpdateTree() failed with err = %v", err)
+	}
+	if !proto.Equal(updated.StorageSettings, latestOnly) {
+		t.Errorf("UpdateTree().StorageSettings = %v, want %v", updated.StorageSettings, latestOnly)
+	}
+
+	mapTree := *testonly.MapTree
+	mapTree.StorageSettings = latestOnly
+	if _, err := storage.CreateTree(ctx, s, &mapTree); err == nil {
+		t.Error("CreateTree() of map with LogStorageSettings: err = nil, want non-nil")
+	}
+}
+
+func TestAdminTX_StrataSettings(t *testing.T) {
+	cleanTestDB(DB)
+	s := NewAdminStorage(DB)
+	ctx := context.Background()
+
+	for _, test := range []struct {
+		desc     string
+		tree     *trillian.Tree
+		settings proto.Message
+		wantErr  bool
+	}{
+		{
+			desc:     "log",
+			tree:     testonly.LogTree,
+			settings: &storagepb.LogStorageSettings{StrataDepths: []int32{16, 16, 16, 16}},
+		},
+		{
+			desc:     "logInvalid",
+			tree:     testonly.LogTree,
+			settings: &storagepb.LogStorageSettings{StrataDepths: []int32{16, 16, 16}},
+			wantErr:  true,
+		},
+		{
+			desc:     "map",
+			tree:     testonly.MapTree,
+			settings: &storagepb.MapStorageSettings{StrataDepths: []int32{16, 16, 16, 16, 192}},
+		},
+		{
+			desc:     "mapInvalid",
+			tree:     testonly.MapTree,
+			settings: &storagepb.MapStorageSettings{StrataDepths: []int32{12, 244}},
+			wantErr:  true,
+		},
+		{
+			desc:     "mapSettingsOnLog",
+			tree:     testonly.LogTree,
+			settings: &storagepb.MapStorageSettings{},
+			wantErr:  true,
+		},
+	} {
+		settings, err := ptypes.MarshalAny(test.settings)
+		if err != nil {
+			t.Fatalf("Error marshaling proto: %v", err)
+		}
+		tree := *test.tree
+		tree.StorageSettings = settings
+		created, err := storage.CreateTree(ctx, s, &tree)
+		if hasErr := err != nil; hasErr != test.wantErr {
+			t.Errorf("%v: CreateTree() = (_, %v), wantErr = %v", test.desc, err, test.wantErr)
+			continue
+		} else if hasErr {
+			continue
+		}
+		got, err := storage.GetTree(ctx, s, created.TreeId)
+		if err != nil {
+			t.Fatalf("%v: GetTree() failed with err = %v", test.desc, err)
+		}
+		if !proto.Equal(got.StorageSettings, settings) {
+			t.Errorf("%v: GetTree().StorageSettings = %v, want %v", test.desc, got.StorageSettings, settings)
+		}
+	}
+}
+
+func TestAdminTX_RootTimestampPrecision(t *testing.T) {
+	cleanTestDB(DB)
+	s := NewAdminStorage(DB)
+	ctx := context.Background()
+
+	logTree := *testonly.LogTree
+	logTree.RootTimestampPrecision = trillian.RootTimestampPrecision_MILLISECOND_PRECISION
+	tree, err := storage.CreateTree(ctx, s, &logTree)
+	if err != nil {
+		t.Fatalf("CreateTree() failed with err = %v", err)
+	}
+	got, err := storage.GetTree(ctx, s, tree.TreeId)
+	if err != nil {
+		t.Fatalf("GetTree() failed with err = %v", err)
+	}
+	if got, want := got.RootTimestampPrecision, trillian.RootTimestampPrecision_MILLISECOND_PRECISION; got != want {
+		t.Errorf("GetTree().RootTimestampPrecision = %v, want %v", got, want)
+	}
+
+	if _, err := storage.UpdateTree(ctx, s, tree.TreeId, func(tree *trillian.Tree) {
+		tree.RootTimestampPrecision = trillian.RootTimestampPrecision_SECOND_PRECISION
+	}); err != nil {
+		t.Fatalf("UpdateTree() failed with err = %v", err)
+	}
+	got, err = storage.GetTree(ctx, s, tree.TreeId)
+	if err != nil {
+		t.Fatalf("GetTree() failed with err = %v", err)
+	}
+	if got, want := got.RootTimestampPrecision, trillian.RootTimestampPrecision_SECOND_PRECISION; got != want {
+		t.Errorf("GetTree().RootTimestampPrecision after update = %v, want %v", got, want)
+	}
+
+	// Trees created before the column existed have it set to NULL.
+	if err := setNulls(ctx, DB, tree.TreeId); err != nil {
+		t.Fatalf("setNulls() = %v, want = nil", err)
+	}
+	got, err = storage.GetTree(ctx, s, tree.TreeId)
+	if err != nil {
+		t.Fatalf("GetTree() failed with err = %v", err)
+	}
+	if got, want := got.RootTimestampPrecision, trillian.RootTimestampPrecision_NANOSECOND_PRECISION; got != want {
+		t.Errorf("GetTree().RootTimestampPrecision of NULL column = %v, want %v", got, want)
+	}
+}
+
+func TestAdminTX_LeafIndexesAndFilter(t *testing.T) {
+	cleanTestDB(DB)
+	s := NewAdminStorage(DB)
+	ctx := context.Background()
+
+	logTree := *testonly.LogTree
+	specs := []*trillian.LeafIndexSpec{{Name: "domain", Field: "cert.domains"}, {Name: "package", Field: "package"}}
+	logTree.LeafIndexes = specs
+	filter := &trillian.LeafFilterSpec{ExpectedLeaves: 1000000, FalsePositiveRate: 0.001}
+	logTree.LeafFilter = filter
+	tree, err := storage.CreateTree(ctx, s, &logTree)
+	if err != nil {
+		t.Fatalf("CreateTree() failed with err = %v", err)
+	}
+	got, err := storage.GetTree(ctx, s, tree.TreeId)
+	if err != nil {
+		t.Fatalf("GetTree() failed with err = %v", err)
+	}
+	if diff := pretty.Compare(got.LeafIndexes, specs); diff != "" {
+		t.Errorf("GetTree().LeafIndexes diff (-got +want):\n%v", diff)
+	}
+	if !proto.Equal(got.LeafFilter, filter) {
+		t.Errorf("GetTree().LeafFilter = %v, want %v", got.LeafFilter, filter)
+	}
+
+	// Trees created before the column existed have it set to NULL.
+	if err := setNulls(ctx, DB, tree.TreeId); err != nil {
+		t.Fatalf("setNulls() = %v, want = nil", err)
+	}
+	got, err = storage.GetTree(ctx, s, tree.TreeId)
+	if err != nil {
+		t.Fatalf("GetTree() failed with err = %v", err)
+	}
+	if len(got.LeafIndexes) != 0 {
+		t.Errorf("GetTree().LeafIndexes of NULL column = %v, want none", got.LeafIndexes)
+	}
+	if got.LeafFilter != nil {
+		t.Errorf("GetTree().LeafFilter of NULL column = %v, want nil", got.LeafFilter)
+	}
+}
+
+func TestAdminTX_Labels(t *testing.T) {
+	cleanTestDB(DB)
+	s := NewAdminStorage(DB)
+	ctx := context.Background()
+
+	logTree := *testonly.LogTree
+	logTree.Labels = map[string]string{"env": "prod", "customer": "acme"}
+	tree, err := storage.CreateTree(ctx, s, &logTree)
+	if err != nil {
+		t.Fatalf("CreateTree() failed with err = %v", err)
+	}
+	got, err := storage.GetTree(ctx, s, tree.TreeId)
+	if err != nil {
+		t.Fatalf("GetTree() failed with err = %v", err)
+	}
+	if diff := pretty.Compare(got.Labels, logTree.Labels); diff != "" {
+		t.Errorf("GetTree().Labels diff (-got +want):\n%v", diff)
+	}
+
+	want := map[string]string{"env": "test"}
+	if _, err := storage.UpdateTree(ctx, s, tree.TreeId, func(tree *trillian.Tree) { tree.Labels = want }); err != nil {
+		t.Fatalf("UpdateTree() failed with err = %v", err)
+	}
+	trees, err := storage.ListTrees(ctx, s, false /* includeDeleted */)
+	if err != nil {
+		t.Fatalf("ListTrees() failed with err = %v", err)
+	}
+	if len(trees) != 1 {
+		t.Fatalf("ListTrees() returned %v trees, want 1", len(trees))
+	}
+	if diff := pretty.Compare(trees[0].Labels, want); diff != "" {
+		t.Errorf("ListTrees() labels diff after update (-got +want):\n%v", diff)
+	}
+
+	if _, err := storage.UpdateTree(ctx, s, tree.TreeId, func(tree *trillian.Tree) { tree.Labels = nil }); err != nil {
+		t.Fatalf("UpdateTree() failed with err = %v", err)
+	}
+	got, err = storage.GetTree(ctx, s, tree.TreeId)
+	if err != nil {
+		t.Fatalf("GetTree() failed with err = %v", err)
+	}
+	if len(got.Labels) != 0 {
+		t.Errorf("GetTree().Labels = %v after clearing them, want none", got.Labels)
+	}
+}
+
+func TestAdminTX_Maintenance(t *testing.T) {
+	cleanTestDB(DB)
+	s := NewAdminStorage(DB)
+	ctx := context.Background()
+
+	tree, err := storage.CreateTree(ctx, s, testonly.LogTree)
+	if err != nil {
+		t.Fatalf("CreateTree() failed with err = %v", err)
+	}
+	if tree.Maintenance != nil {
+		t.Errorf("CreateTree().Maintenance = %v, want nil", tree.Maintenance)
+	}
+
+	want := &trillian.MaintenanceMode{RetryAfter: ptypes.DurationProto(90 * time.Second), Reason: "migration"}
+	if _, err := storage.UpdateTree(ctx, s, tree.TreeId, func(tree *trillian.Tree) { tree.Maintenance = want }); err != nil {
+		t.Fatalf("UpdateTree() failed with err = %v", err)
+	}
+	got, err := storage.GetTree(ctx, s, tree.TreeId)
+	if err != nil {
+		t.Fatalf("GetTree() failed with err = %v", err)
+	}
+	if !proto.Equal(got.Maintenance, want) {
+		t.Errorf("GetTree().Maintenance = %v, want %v", got.Maintenance, want)
+	}
+
+	if _, err := storage.UpdateTree(ctx, s, tree.TreeId, func(tree *trillian.Tree) { tree.Maintenance = nil }); err != nil {
+		t.Fatalf("UpdateTree() failed with err = %v", err)
+	}
+	got, err = storage.GetTree(ctx, s, tree.TreeId)
+	if err != nil {
+		t.Fatalf("GetTree() failed with err = %v", err)
+	}
+	if got.Maintenance != nil {
+		t.Errorf("GetTree().Maintenance = %v after ending maintenance, want nil", got.Maintenance)
+	}
+}
+
+func TestAdminTX_MaxMergeDelay(t *testing.T) {
+	cleanTestDB(DB)
+	s := NewAdminStorage(DB)
+	ctx := context.Background()
+
+	logTree := *testonly.LogTree
+	logTree.MaxMergeDelay = ptypes.DurationProto(24 * time.Hour)
+	tree, err := storage.CreateTree(ctx, s, &logTree)
+	if err != nil {
+		t.Fatalf("CreateTree() failed with err = %v", err)
+	}
+	got, err := storage.GetTree(ctx, s, tree.TreeId)
+	if err != nil {
+		t.Fatalf("GetTree() failed with err = %v", err)
+	}
+	if !proto.Equal(got.MaxMergeDelay, logTree.MaxMergeDelay) {
+		t.Errorf("GetTree().MaxMergeDelay = %v, want %v", got.MaxMergeDelay, logTree.MaxMergeDelay)
+	}
+
+	if _, err := storage.UpdateTree(ctx, s, tree.TreeId, func(tree *trillian.Tree) { tree.MaxMergeDelay = nil }); err != nil {
+		t.Fatalf("UpdateTree() failed with err = %v", err)
+	}
+	got, err = storage.GetTree(ctx, s, tree.TreeId)
+	if err != nil {
+		t.Fatalf("GetTree() failed with err = %v", err)
+	}
+	if got.MaxMergeDelay != nil {
+		t.Errorf("GetTree().MaxMergeDelay = %v after clearing it, want nil", got.MaxMergeDelay)
+	}
+}
+
+func TestAdminTX_ValidityWindow(t *testing.T) {
+	cleanTestDB(DB)
+	s := NewAdminStorage(DB)
+	ctx := context.Background()
+
+	tree, err := storage.CreateTree(ctx, s, testonly.LogTree)
+	if err != nil {
+		t.Fatalf("CreateTree() failed with err = %v", err)
+	}
+	if tree.ValidityWindow != nil {
+		t.Errorf("CreateTree().ValidityWindow = %v, want nil", tree.ValidityWindow)
+	}
+
+	notBefore, _ := ptypes.TimestampProto(time.Unix(1514764800, 0))
+	notAfter, _ := ptypes.TimestampProto(time.Unix(1546300800, 0))
+	want := &trillian.ValidityWindow{NotBefore: notBefore, NotAfter: notAfter}
+	if _, err := storage.UpdateTree(ctx, s, tree.TreeId, func(tree *trillian.Tree) { tree.ValidityWindow = want }); err != nil {
+		t.Fatalf("UpdateTree() failed with err = %v", err)
+	}
+	got, err := storage.GetTree(ctx, s, tree.TreeId)
+	if err != nil {
+		t.Fatalf("GetTree() failed with err = %v", err)
+	}
+	if !proto.Equal(got.ValidityWindow, want) {
+		t.Errorf("GetTree().ValidityWindow = %v, want %v", got.ValidityWindow, want)
+	}
+}
+
+func TestAdminTX_WriteVerification(t *testing.T) {
+	cleanTestDB(DB)
+	s := NewAdminStorage(DB)
+	ctx := context.Background()
+
+	newTree := proto.Clone(testonly.LogTree).(*trillian.Tree)
+	newTree.WriteVerification = trillian.WriteVerification_VERIFY_WRITES
+	tree, err := storage.CreateTree(ctx, s, newTree)
+	if err != nil {
+		t.Fatalf("CreateTree() failed with err = %v", err)
+	}
+	if got, want := tree.WriteVerification, trillian.WriteVerification_VERIFY_WRITES; got != want {
+		t.Errorf("CreateTree().WriteVerification = %v, want %v", got, want)
+	}
+
+	want := trillian.WriteVerification_SKIP_WRITE_VERIFICATION
+	if _, err := storage.UpdateTree(ctx, s, tree.TreeId, func(tree *trillian.Tree) { tree.WriteVerification = want }); err != nil {
+		t.Fatalf("UpdateTree() failed with err = %v", err)
+	}
+	got, err := storage.GetTree(ctx, s, tree.TreeId)
+	if err != nil {
+		t.Fatalf("GetTree() failed with err = %v", err)
+	}
+	if got.WriteVerification != want {
+		t.Errorf("GetTree().WriteVerification = %v, want %v", got.WriteVerification, want)
+	}
+}
+
+func TestAdminTX_AdditionalSigningKeys(t *testing.T) {
+	cleanTestDB(DB)
+	s := NewAdminStorage(DB)
+	ctx := context.Background()
+
+	tree := proto.Clone(testonly.LogTree).(*trillian.Tree)
+	tree.AdditionalSigningKeys = []*trillian.SigningKey{testonly.Ed25519SigningKey}
+	tree, err := storage.CreateTree(ctx, s, tree)
+	if err != nil {
+		t.Fatalf("CreateTree() failed with err = %v", err)
+	}
+	got, err := storage.GetTree(ctx, s, tree.TreeId)
+	if err != nil {
+		t.Fatalf("GetTree() failed with err = %v", err)
+	}
+	if len(got.AdditionalSigningKeys) != 1 || !proto.Equal(got.AdditionalSigningKeys[0], testonly.Ed25519SigningKey) {
+		t.Errorf("GetTree().AdditionalSigningKeys = %v, want [%v]", got.AdditionalSigningKeys, testonly.Ed25519SigningKey)
+	}
+
+	if _, err := storage.UpdateTree(ctx, s, tree.TreeId, func(tree *trillian.Tree) { tree.AdditionalSigningKeys = nil }); err != nil {
+		t.Fatalf("UpdateTree() failed with err = %v", err)
+	}
+	got, err = storage.GetTree(ctx, s, tree.TreeId)
+	if err != nil {
+		t.Fatalf("GetTree() failed with err = %v", err)
+	}
+	if len(got.AdditionalSigningKeys) != 0 {
+		t.Errorf("GetTree().AdditionalSigningKeys = %v after removing them, want none", got.AdditionalSigningKeys)
+	}
+}
+
 func TestAdminTX_HardDeleteTree(t *testing.T) {
 	cleanTestDB(DB)
 	s := NewAdminStorage(DB)
@@ -201,6 +603,49 @@ func TestAdminTX_HardDeleteTree(t *testing.T) {
 	}
 }
 
+func TestAdminTX_TimeSource(t *testing.T) {
+	cleanTestDB(DB)
+	clock := util.NewFakeTimeSource(time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC))
+	s := NewAdminStorageWithOpts(DB, AdminStorageOptions{TimeSource: clock})
+	ctx := context.Background()
+
+	tree, err := storage.CreateTree(ctx, s, testonly.LogTree)
+	if err != nil {
+		t.Fatalf("CreateTree() returned err = %v", err)
+	}
+	created := clock.Now()
+	clock.Set(created.Add(time.Hour))
+	tree, err = storage.UpdateTree(ctx, s, tree.TreeId, func(tree *trillian.Tree) {
+		tree.DisplayName = "updated"
+	})
+	if err != nil {
+		t.Fatalf("UpdateTree() returned err = %v", err)
+	}
+	updated := clock.Now()
+	clock.Set(updated.Add(time.Hour))
+	if err := s.ReadWriteTransaction(ctx, func(ctx context.Context, tx storage.AdminTX) error {
+		tree, err = tx.SoftDeleteTree(ctx, tree.TreeId)
+		return err
+	}); err != nil {
+		t.Fatalf("SoftDeleteTree() returned err = %v", err)
+	}
+	deleted := clock.Now()
+
+	for _, test := range []struct {
+		desc string
+		ts   *timestamp.Timestamp
+		want time.Time
+	}{
+		{desc: "CreateTime", ts: tree.CreateTime, want: created},
+		{desc: "UpdateTime", ts: tree.UpdateTime, want: updated},
+		{desc: "DeleteTime", ts: tree.DeleteTime, want: deleted},
+	} {
+		if got, err := ptypes.Timestamp(test.ts); err != nil || !got.Equal(test.want) {
+			t.Errorf("%v = %v (err = %v), want = %v", test.desc, got, err, test.want)
+		}
+	}
+}
+
 func TestCheckDatabaseAccessible_Fails(t *testing.T) {
 	// Pass in a closed database to provoke a failure.
 	db := openTestDBOrDie()
@@ -223,7 +668,7 @@ func TestCheckDatabaseAccessible_OK(t *testing.T) {
 }
 
 func setNulls(ctx context.Context, db *sql.DB, treeID int64) error {
-	stmt, err := db.PrepareContext(ctx, "UPDATE Trees SET DisplayName = NULL, Description = NULL WHERE TreeId = ?")
+	stmt, err := db.PrepareContext(ctx, "UPDATE Trees SET DisplayName = NULL, Description = NULL, RootTimestampPrecision = NULL, Labels = NULL, LeafIndexes = NULL, LeafFilter = NULL, Maintenance = NULL WHERE TreeId = ?")
 	if err != nil {
 		return err
 	}
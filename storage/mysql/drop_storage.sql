@@ -2,11 +2,16 @@
 
 DROP TABLE IF EXISTS Unsequenced;
 DROP TABLE IF EXISTS Subtree;
+DROP TABLE IF EXISTS LeafIndexKeys;
+DROP TABLE IF EXISTS LeafFilterBlocks;
+DROP TABLE IF EXISTS MergeDelayWindows;
 DROP TABLE IF EXISTS SequencedLeafData;
 DROP TABLE IF EXISTS TreeHead;
+DROP TABLE IF EXISTS SequencingEvents;
 DROP TABLE IF EXISTS LeafData;
 DROP TABLE IF EXISTS MapLeaf;
 DROP TABLE IF EXISTS MapHead;
+DROP TABLE IF EXISTS MapRevisionClaim;
 DROP TABLE IF EXISTS TreeControl;
 DROP TABLE IF EXISTS MapHead;
 DROP TABLE IF EXISTS MapLeaf;
@@ -0,0 +1,198 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/google/trillian/util"
+)
+
+const (
+	getLockSQL     = "SELECT GET_LOCK(?, ?)"
+	isLockHeldSQL  = "SELECT IS_USED_LOCK(?) = CONNECTION_ID()"
+	releaseLockSQL = "SELECT RELEASE_LOCK(?)"
+
+	// maxLockNameLen is the longest lock name MySQL accepts.
+	maxLockNameLen = 64
+)
+
+var (
+	// lockWait is how long a single GET_LOCK call waits for the lock, which
+	// bounds how long WaitForMastership takes to notice that its context is
+	// done.
+	lockWait = 5 * time.Second
+	// lockRetryDelay is the delay before retrying to get the lock after an
+	// error.
+	lockRetryDelay = time.Second
+)
+
+// MasterElection is an implementation of util.MasterElection which uses a
+// MySQL named lock (see GET_LOCK) for each tree. The lock is held by a
+// connection dedicated to the election, so the database releases it if the
+// master dies or loses its connection, without the need for etcd.
+type MasterElection struct {
+	instanceID string
+	treeID     int64
+	lockName   string
+	db         *sql.DB
+
+	mu sync.Mutex
+	// conn holds the lock while it's not nil.
+	conn *sql.Conn
+}
+
+// Start commences election operation.
+func (e *MasterElection) Start(ctx context.Context) error {
+	return nil
+}
+
+// WaitForMastership blocks until the current instance holds the lock of the
+// election, retrying on database errors until ctx is done.
+func (e *MasterElection) WaitForMastership(ctx context.Context) error {
+	if master, err := e.IsMaster(ctx); err == nil && master {
+		return nil
+	}
+	for {
+		conn, err := e.tryLock(ctx)
+		if err == nil && conn != nil {
+			e.mu.Lock()
+			e.conn = conn
+			e.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration(0)
+		if err != nil {
+			glog.Warningf("%d: failed to get lock %q: %v", e.treeID, e.lockName, err)
+			delay = lockRetryDelay
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// tryLock waits up to lockWait for the lock of the election, and returns the
+// connection holding it, or nil if another instance holds it.
+func (e *MasterElection) tryLock(ctx context.Context) (*sql.Conn, error) {
+	conn, err := e.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var got sql.NullInt64
+	if err := conn.QueryRowContext(ctx, getLockSQL, e.lockName, int64(lockWait/time.Second)).Scan(&got); err != nil {
+		dropConn(conn)
+		return nil, err
+	}
+	if !got.Valid || got.Int64 != 1 {
+		conn.Close()
+		return nil, nil
+	}
+	return conn, nil
+}
+
+// IsMaster returns whether the current instance holds the lock of the
+// election. An error means that the connection holding the lock was lost, and
+// the lock with it.
+func (e *MasterElection) IsMaster(ctx context.Context) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.conn == nil {
+		return false, nil
+	}
+	var held sql.NullBool
+	if err := e.conn.QueryRowContext(ctx, isLockHeldSQL, e.lockName).Scan(&held); err != nil {
+		dropConn(e.conn)
+		e.conn = nil
+		return false, err
+	}
+	if !held.Valid || !held.Bool {
+		dropConn(e.conn)
+		e.conn = nil
+		return false, nil
+	}
+	return true, nil
+}
+
+// ResignAndRestart releases mastership, and re-joins the election.
+func (e *MasterElection) ResignAndRestart(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.conn == nil {
+		return nil
+	}
+	conn := e.conn
+	e.conn = nil
+	if _, err := conn.ExecContext(ctx, releaseLockSQL, e.lockName); err != nil {
+		dropConn(conn)
+		return err
+	}
+	return conn.Close()
+}
+
+// Close terminates election operation.
+func (e *MasterElection) Close(ctx context.Context) error {
+	// ctx may be done already, as Close is called when the election stops, but
+	// the lock must still be released.
+	return e.ResignAndRestart(context.Background())
+}
+
+// dropConn closes the database connection of conn, rather than returning it
+// to the pool, so that any lock it holds is released by the server.
+func dropConn(conn *sql.Conn) {
+	conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+	conn.Close()
+}
+
+// ElectionFactory creates mysql.MasterElection instances.
+type ElectionFactory struct {
+	db         *sql.DB
+	instanceID string
+	lockPrefix string
+}
+
+// NewElectionFactory builds an election factory whose elections hold locks
+// named lockPrefix followed by the ID of their tree in db.
+func NewElectionFactory(instanceID string, db *sql.DB, lockPrefix string) *ElectionFactory {
+	return &ElectionFactory{
+		db:         db,
+		instanceID: instanceID,
+		lockPrefix: lockPrefix,
+	}
+}
+
+// NewElection creates a specific mysql.MasterElection instance.
+func (ef ElectionFactory) NewElection(ctx context.Context, treeID int64) (util.MasterElection, error) {
+	lockName := fmt.Sprintf("%s%d", ef.lockPrefix, treeID)
+	if len(lockName) > maxLockNameLen {
+		return nil, fmt.Errorf("lock name %q is longer than %d characters", lockName, maxLockNameLen)
+	}
+	e := &MasterElection{
+		instanceID: ef.instanceID,
+		treeID:     treeID,
+		lockName:   lockName,
+		db:         ef.db,
+	}
+	glog.Infof("MasterElection created: %v for %v", lockName, ef.instanceID)
+	return e, nil
+}
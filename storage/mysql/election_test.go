@@ -0,0 +1,84 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/trillian/storage/testdb"
+)
+
+func TestElectionFactory_LongLockName(t *testing.T) {
+	ef := NewElectionFactory("instance", DB, strings.Repeat("x", maxLockNameLen))
+	if _, err := ef.NewElection(context.Background(), 1); err == nil {
+		t.Error("NewElection() with long lock name = _, nil, want err")
+	}
+}
+
+func TestMasterElection(t *testing.T) {
+	if provider := testdb.Default(); !provider.IsMySQL() {
+		t.Skipf("Named locks aren't supported by SQL driver: %q", provider.Driver)
+	}
+
+	defer func(d time.Duration) { lockWait = d }(lockWait)
+	lockWait = time.Second
+
+	ctx := context.Background()
+	const treeID = 12345
+	e1, err := NewElectionFactory("instance1", DB, "test_election_").NewElection(ctx, treeID)
+	if err != nil {
+		t.Fatalf("NewElection(1)=%v", err)
+	}
+	e2, err := NewElectionFactory("instance2", DB, "test_election_").NewElection(ctx, treeID)
+	if err != nil {
+		t.Fatalf("NewElection(2)=%v", err)
+	}
+	defer e1.Close(ctx)
+	defer e2.Close(ctx)
+
+	if err := e1.WaitForMastership(ctx); err != nil {
+		t.Fatalf("e1.WaitForMastership()=%v", err)
+	}
+	if master, err := e1.IsMaster(ctx); err != nil || !master {
+		t.Errorf("e1.IsMaster()=%v, %v; want true, nil", master, err)
+	}
+
+	// e2 can't become master while e1 holds the lock.
+	waitCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	if err := e2.WaitForMastership(waitCtx); err != context.DeadlineExceeded {
+		t.Errorf("e2.WaitForMastership()=%v; want %v", err, context.DeadlineExceeded)
+	}
+	if master, err := e2.IsMaster(ctx); err != nil || master {
+		t.Errorf("e2.IsMaster()=%v, %v; want false, nil", master, err)
+	}
+
+	// Once e1 resigns, e2 takes over.
+	if err := e1.ResignAndRestart(ctx); err != nil {
+		t.Fatalf("e1.ResignAndRestart()=%v", err)
+	}
+	if err := e2.WaitForMastership(ctx); err != nil {
+		t.Fatalf("e2.WaitForMastership()=%v", err)
+	}
+	if master, err := e1.IsMaster(ctx); err != nil || master {
+		t.Errorf("e1.IsMaster()=%v, %v; want false, nil", master, err)
+	}
+	if master, err := e2.IsMaster(ctx); err != nil || !master {
+		t.Errorf("e2.IsMaster()=%v, %v; want true, nil", master, err)
+	}
+}
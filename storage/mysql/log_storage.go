@@ -17,9 +17,11 @@ package mysql
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"database/sql"
 	"errors"
 	"fmt"
+	"math"
 	"sort"
 	"strconv"
 	"sync"
@@ -34,7 +36,9 @@ import (
 	"github.com/google/trillian/monitoring"
 	"github.com/google/trillian/storage"
 	"github.com/google/trillian/storage/cache"
+	"github.com/google/trillian/storage/storagepb"
 	"github.com/google/trillian/trees"
+	"github.com/google/trillian/util/requestinfo"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
@@ -47,9 +51,13 @@ const (
 			VALUES(?,?,?,?,?)`
 	selectSequencedLeafCountSQL   = "SELECT COUNT(*) FROM SequencedLeafData WHERE TreeId=?"
 	selectUnsequencedLeafCountSQL = "SELECT TreeId, COUNT(1) FROM Unsequenced GROUP BY TreeId"
-	selectLatestSignedLogRootSQL  = `SELECT TreeHeadTimestamp,TreeSize,RootHash,TreeRevision,RootSignature
+	selectLeafBytesSQL            = "SELECT COALESCE(SUM(LENGTH(LeafValue) + COALESCE(LENGTH(ExtraData), 0)), 0) FROM LeafData WHERE TreeId=?"
+	selectTreeHeadCountSQL        = "SELECT COUNT(*) FROM TreeHead WHERE TreeId=?"
+	selectLatestSignedLogRootSQL  = `SELECT TreeHeadTimestamp,TreeSize,RootHash,TreeRevision,RootSignature,AdditionalSignatures,Metadata
 			FROM TreeHead WHERE TreeId=?
 			ORDER BY TreeHeadTimestamp DESC LIMIT 1`
+	selectSignedLogRootByRevisionSQL = `SELECT TreeHeadTimestamp,TreeSize,RootHash,TreeRevision,RootSignature,AdditionalSignatures,Metadata
+			FROM TreeHead WHERE TreeId=? AND TreeRevision=?`
 
 	selectLeavesByRangeSQL = `SELECT s.MerkleLeafHash,l.LeafIdentityHash,l.LeafValue,s.SequenceNumber,l.ExtraData,l.QueueTimestampNanos,s.IntegrateTimestampNanos
 			FROM LeafData l,SequencedLeafData s
@@ -74,6 +82,43 @@ const (
 			FROM LeafData l LEFT JOIN SequencedLeafData s ON (l.LeafIdentityHash = s.LeafIdentityHash AND l.TreeID = s.TreeID)
 			WHERE l.LeafIdentityHash IN (` + placeholderSQL + `) AND l.TreeId = ?`
 
+	insertSequencingEventSQL = `INSERT INTO SequencingEvents(TreeId,TreeRevision,StartSize,TreeSize,RootHash,TreeHeadTimestamp)
+		VALUES(?,?,?,?,?,?)`
+
+	insertLeafIndexKeySQL = `INSERT INTO LeafIndexKeys(TreeId,IndexName,KeyHash,SequenceNumber)
+		VALUES(?,?,?,?)`
+	selectLeafIndicesByKeySQL = `SELECT SequenceNumber FROM LeafIndexKeys
+		WHERE TreeId = ? AND IndexName = ? AND KeyHash = ? AND SequenceNumber >= ?
+		ORDER BY SequenceNumber LIMIT ?`
+
+	selectLeafFilterBlocksSQL = `SELECT BlockIndex,Bits FROM LeafFilterBlocks
+		WHERE BlockIndex IN (` + placeholderSQL + `) AND TreeId = ?`
+	replaceLeafFilterBlockSQL = `REPLACE INTO LeafFilterBlocks(TreeId,BlockIndex,Bits)
+		VALUES(?,?,?)`
+
+	selectMergeDelayWindowSQL = `SELECT WindowLengthMillis,MaxMergeDelayMillis,LeafCount,ViolationCount,NearMissCount,MaxDelayMillis
+		FROM MergeDelayWindows WHERE TreeId = ? AND WindowStartMillis = ?`
+	replaceMergeDelayWindowSQL = `REPLACE INTO MergeDelayWindows(TreeId,WindowStartMillis,WindowLengthMillis,MaxMergeDelayMillis,LeafCount,ViolationCount,NearMissCount,MaxDelayMillis)
+		VALUES(?,?,?,?,?,?,?,?)`
+	selectMergeDelayWindowsSQL = `SELECT WindowStartMillis,WindowLengthMillis,MaxMergeDelayMillis,LeafCount,ViolationCount,NearMissCount,MaxDelayMillis
+		FROM MergeDelayWindows
+		WHERE TreeId = ? AND WindowStartMillis + WindowLengthMillis > ? AND WindowStartMillis < ?
+		ORDER BY WindowStartMillis`
+
+	// deleteSupersededSubtreesSQL removes each subtree revision which has a
+	// newer one at or below the compaction revision.
+	deleteSupersededSubtreesSQL = `DELETE s FROM Subtree s
+			INNER JOIN (
+				SELECT SubtreeId, MAX(SubtreeRevision) AS MaxRevision
+				FROM Subtree WHERE TreeId = ? AND SubtreeRevision <= ?
+				GROUP BY SubtreeId
+			) x ON s.SubtreeId = x.SubtreeId
+			WHERE s.TreeId = ? AND s.SubtreeRevision < x.MaxRevision`
+	updateSubtreeRevisionsSQL = "UPDATE Subtree SET SubtreeRevision = ? WHERE TreeId = ? AND SubtreeRevision < ?"
+	// replaceSubtreeSQL writes a repaired subtree over any stored at the same
+	// revision.
+	replaceSubtreeSQL = "REPLACE INTO Subtree(TreeId, SubtreeId, Nodes, SubtreeRevision) VALUES(?, ?, ?, ?)"
+
 	// Same as above except with leaves ordered by sequence so we only incur this cost when necessary
 	orderBySequenceNumberSQL                     = " ORDER BY s.SequenceNumber"
 	selectLeavesByMerkleHashOrderedBySequenceSQL = selectLeavesByMerkleHashSQL + orderBySequenceNumberSQL
@@ -84,9 +129,16 @@ const (
 	logIDLabel = "logid"
 )
 
-var (
-	defaultLogStrata = []int{8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8}
+// Queries sampling the queue of a log for SampleQueue.
+const (
+	selectQueueShardsSQL = `SELECT Bucket,COUNT(*),MIN(QueueTimestampNanos) FROM Unsequenced
+			WHERE TreeId=? GROUP BY Bucket ORDER BY Bucket`
+	selectOldestQueuedLeavesSQL = `SELECT Bucket,LeafIdentityHash,MerkleLeafHash,QueueTimestampNanos FROM Unsequenced
+			WHERE TreeId=? ORDER BY QueueTimestampNanos,LeafIdentityHash LIMIT ?`
+	selectQueuedBeforeCountSQL = "SELECT COUNT(*) FROM Unsequenced WHERE TreeId=? AND QueueTimestampNanos<=?"
+)
 
+var (
 	once             sync.Once
 	queuedCounter    monitoring.Counter
 	queuedDupCounter monitoring.Counter
@@ -107,15 +159,15 @@ func createMetrics(mf monitoring.MetricFactory) {
 	queuedDupCounter = mf.NewCounter("mysql_queued_dup_leaves", "Number of duplicate leaves queued", logIDLabel)
 	dequeuedCounter = mf.NewCounter("mysql_dequeued_leaves", "Number of leaves dequeued", logIDLabel)
 
-	queueLatency = mf.NewHistogram("mysql_queue_leaves_latency", "Latency of queue leaves operation in seconds", logIDLabel)
-	queueInsertLatency = mf.NewHistogram("mysql_queue_leaves_latency_insert", "Latency of insertion part of queue leaves operation in seconds", logIDLabel)
-	queueReadLatency = mf.NewHistogram("mysql_queue_leaves_latency_read_dups", "Latency of read-duplicates part of queue leaves operation in seconds", logIDLabel)
-	queueInsertLeafLatency = mf.NewHistogram("mysql_queue_leaf_latency_leaf", "Latency of insert-leaf part of queue (single) leaf operation in seconds", logIDLabel)
-	queueInsertEntryLatency = mf.NewHistogram("mysql_queue_leaf_latency_entry", "Latency of insert-entry part of queue (single) leaf operation in seconds", logIDLabel)
+	queueLatency = mf.NewHistogramWithBuckets("mysql_queue_leaves_latency", "Latency of queue leaves operation in seconds", monitoring.LatencyBuckets(), logIDLabel)
+	queueInsertLatency = mf.NewHistogramWithBuckets("mysql_queue_leaves_latency_insert", "Latency of insertion part of queue leaves operation in seconds", monitoring.LatencyBuckets(), logIDLabel)
+	queueReadLatency = mf.NewHistogramWithBuckets("mysql_queue_leaves_latency_read_dups", "Latency of read-duplicates part of queue leaves operation in seconds", monitoring.LatencyBuckets(), logIDLabel)
+	queueInsertLeafLatency = mf.NewHistogramWithBuckets("mysql_queue_leaf_latency_leaf", "Latency of insert-leaf part of queue (single) leaf operation in seconds", monitoring.LatencyBuckets(), logIDLabel)
+	queueInsertEntryLatency = mf.NewHistogramWithBuckets("mysql_queue_leaf_latency_entry", "Latency of insert-entry part of queue (single) leaf operation in seconds", monitoring.LatencyBuckets(), logIDLabel)
 
-	dequeueLatency = mf.NewHistogram("mysql_dequeue_leaves_latency", "Latency of dequeue leaves operation in seconds", logIDLabel)
-	dequeueSelectLatency = mf.NewHistogram("mysql_dequeue_leaves_latency_select", "Latency of selection part of dequeue leaves operation in seconds", logIDLabel)
-	dequeueRemoveLatency = mf.NewHistogram("mysql_dequeue_leaves_latency_remove", "Latency of removal part of dequeue leaves operation in seconds", logIDLabel)
+	dequeueLatency = mf.NewHistogramWithBuckets("mysql_dequeue_leaves_latency", "Latency of dequeue leaves operation in seconds", monitoring.LatencyBuckets(), logIDLabel)
+	dequeueSelectLatency = mf.NewHistogramWithBuckets("mysql_dequeue_leaves_latency_select", "Latency of selection part of dequeue leaves operation in seconds", monitoring.LatencyBuckets(), logIDLabel)
+	dequeueRemoveLatency = mf.NewHistogramWithBuckets("mysql_dequeue_leaves_latency_remove", "Latency of removal part of dequeue leaves operation in seconds", monitoring.LatencyBuckets(), logIDLabel)
 }
 
 func labelForTX(t *logTreeTX) string {
@@ -135,12 +187,18 @@ type mySQLLogStorage struct {
 // NewLogStorage creates a storage.LogStorage instance for the specified MySQL URL.
 // It assumes storage.AdminStorage is backed by the same MySQL database as well.
 func NewLogStorage(db *sql.DB, mf monitoring.MetricFactory) storage.LogStorage {
+	return NewLogStorageWithOpts(db, mf, TreeStorageOptions{})
+}
+
+// NewLogStorageWithOpts creates a storage.LogStorage instance for the specified
+// MySQL URL, configured with the given options.
+func NewLogStorageWithOpts(db *sql.DB, mf monitoring.MetricFactory, opts TreeStorageOptions) storage.LogStorage {
 	if mf == nil {
 		mf = monitoring.InertMetricFactory{}
 	}
 	return &mySQLLogStorage{
 		admin:            NewAdminStorage(db),
-		mySQLTreeStorage: newTreeStorage(db),
+		mySQLTreeStorage: newTreeStorage(db, opts),
 		metricFactory:    mf,
 	}
 }
@@ -149,11 +207,15 @@ func (m *mySQLLogStorage) CheckDatabaseAccessible(ctx context.Context) error {
 	return m.db.PingContext(ctx)
 }
 
-func (m *mySQLLogStorage) getLeavesByIndexStmt(ctx context.Context, num int) (*sql.Stmt, error) {
+func (m *mySQLLogStorage) getLeavesByIndexStmt(ctx context.Context, num int) (*sql.Stmt, func(), error) {
 	return m.getStmt(ctx, selectLeavesByIndexSQL, num, "?", "?")
 }
 
-func (m *mySQLLogStorage) getLeavesByMerkleHashStmt(ctx context.Context, num int, orderBySequence bool) (*sql.Stmt, error) {
+func (m *mySQLLogStorage) getLeafFilterBlocksStmt(ctx context.Context, num int) (*sql.Stmt, func(), error) {
+	return m.getStmt(ctx, selectLeafFilterBlocksSQL, num, "?", "?")
+}
+
+func (m *mySQLLogStorage) getLeavesByMerkleHashStmt(ctx context.Context, num int, orderBySequence bool) (*sql.Stmt, func(), error) {
 	if orderBySequence {
 		return m.getStmt(ctx, selectLeavesByMerkleHashOrderedBySequenceSQL, num, "?", "?")
 	}
@@ -161,7 +223,7 @@ func (m *mySQLLogStorage) getLeavesByMerkleHashStmt(ctx context.Context, num int
 	return m.getStmt(ctx, selectLeavesByMerkleHashSQL, num, "?", "?")
 }
 
-func (m *mySQLLogStorage) getLeavesByLeafIdentityHashStmt(ctx context.Context, num int) (*sql.Stmt, error) {
+func (m *mySQLLogStorage) getLeavesByLeafIdentityHashStmt(ctx context.Context, num int) (*sql.Stmt, func(), error) {
 	return m.getStmt(ctx, selectLeavesByLeafIdentityHashSQL, num, "?", "?")
 }
 
@@ -173,7 +235,7 @@ type readOnlyLogTX struct {
 func (m *mySQLLogStorage) Snapshot(ctx context.Context) (storage.ReadOnlyLogTX, error) {
 	tx, err := m.db.BeginTx(ctx, nil /* opts */)
 	if err != nil {
-		glog.Warningf("Could not start ReadOnlyLogTX: %s", err)
+		requestinfo.Warningf(ctx, "Could not start ReadOnlyLogTX: %s", err)
 		return nil, err
 	}
 	return &readOnlyLogTX{tx}, nil
@@ -225,16 +287,26 @@ func (m *mySQLLogStorage) beginInternal(ctx context.Context, treeID int64, reado
 	if err != nil {
 		return nil, err
 	}
+	m.recordActivity(treeID, readonly)
 	hasher, err := hashers.NewLogHasher(tree.HashStrategy)
 	if err != nil {
 		return nil, err
 	}
+	settings, err := logStorageSettings(tree)
+	if err != nil {
+		return nil, err
+	}
+	strata, err := storage.SubtreeStrata(tree)
+	if err != nil {
+		return nil, err
+	}
 
-	stCache := cache.NewLogSubtreeCache(defaultLogStrata, hasher)
+	stCache := cache.NewLogSubtreeCache(strata, hasher)
 	ttx, err := m.beginTreeTx(ctx, treeID, hasher.Size(), stCache)
 	if err != nil && err != storage.ErrTreeNeedsInit {
 		return nil, err
 	}
+	ttx.latestSubtreesOnly = settings.SubtreeRevisions == storagepb.LogStorageSettings_LATEST_ONLY
 
 	ltx := &logTreeTX{
 		treeTX: ttx,
@@ -279,11 +351,209 @@ func (m *mySQLLogStorage) SnapshotForTree(ctx context.Context, treeID int64) (st
 	return tx.(storage.ReadOnlyLogTreeTX), err
 }
 
+// GetTreeStats implements storage.TreeStatsReader.
+func (m *mySQLLogStorage) GetTreeStats(ctx context.Context, treeID int64) (*storage.TreeStats, error) {
+	return m.getTreeStats(ctx, treeID, selectSequencedLeafCountSQL, selectLeafBytesSQL, selectTreeHeadCountSQL)
+}
+
+// GetSignedLogRoot implements storage.LogRootReader.
+func (m *mySQLLogStorage) GetSignedLogRoot(ctx context.Context, treeID, treeRevision int64) (trillian.SignedLogRoot, error) {
+	var timestamp, treeSize, revision int64
+	var rootHash, rootSignatureBytes, metadataBytes []byte
+	var additionalSignatures sql.NullString
+	err := m.db.QueryRowContext(ctx, selectSignedLogRootByRevisionSQL, treeID, treeRevision).Scan(
+		&timestamp, &treeSize, &rootHash, &revision, &rootSignatureBytes, &additionalSignatures, &metadataBytes)
+	if err == sql.ErrNoRows {
+		return trillian.SignedLogRoot{}, status.Errorf(codes.NotFound, "no root at revision %d of tree %d", treeRevision, treeID)
+	}
+	if err != nil {
+		requestinfo.Warningf(ctx, "Failed to read root at revision %d of tree %v: %s", treeRevision, treeID, err)
+		return trillian.SignedLogRoot{}, err
+	}
+
+	var rootSignature spb.DigitallySigned
+	if err := proto.Unmarshal(rootSignatureBytes, &rootSignature); err != nil {
+		requestinfo.Warningf(ctx, "Failed to unmarshall root signature: %v", err)
+		return trillian.SignedLogRoot{}, err
+	}
+	additionalSigs, err := unmarshalAdditionalSignatures(additionalSignatures)
+	if err != nil {
+		return trillian.SignedLogRoot{}, err
+	}
+	metadata, err := unmarshalRootMetadata(metadataBytes)
+	if err != nil {
+		return trillian.SignedLogRoot{}, err
+	}
+	return trillian.SignedLogRoot{
+		RootHash:             rootHash,
+		TimestampNanos:       timestamp,
+		TreeRevision:         revision,
+		Signature:            &rootSignature,
+		LogId:                treeID,
+		TreeSize:             treeSize,
+		AdditionalSignatures: additionalSigs,
+		Metadata:             metadata,
+	}, nil
+}
+
+// SampleQueue implements storage.LeafQueueInspector.
+func (m *mySQLLogStorage) SampleQueue(ctx context.Context, treeID int64, opts storage.QueueSampleOptions) (*storage.QueueSample, error) {
+	sample := &storage.QueueSample{}
+
+	rows, err := m.db.QueryContext(ctx, selectQueueShardsSQL, treeID)
+	if err != nil {
+		requestinfo.Warningf(ctx, "Failed to read queue shards of tree %v: %s", treeID, err)
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var shard storage.QueueShard
+		var oldestNanos int64
+		if err := rows.Scan(&shard.Bucket, &shard.LeafCount, &oldestNanos); err != nil {
+			return nil, err
+		}
+		shard.Oldest = time.Unix(0, oldestNanos)
+		sample.Shards = append(sample.Shards, shard)
+		sample.LeafCount += shard.LeafCount
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if opts.OldestLeaves > 0 {
+		rows, err := m.db.QueryContext(ctx, selectOldestQueuedLeavesSQL, treeID, opts.OldestLeaves)
+		if err != nil {
+			requestinfo.Warningf(ctx, "Failed to read oldest queued leaves of tree %v: %s", treeID, err)
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var leaf storage.QueuedLeaf
+			var queuedNanos int64
+			if err := rows.Scan(&leaf.Bucket, &leaf.LeafIdentityHash, &leaf.MerkleLeafHash, &queuedNanos); err != nil {
+				return nil, err
+			}
+			leaf.QueueTimestamp = time.Unix(0, queuedNanos)
+			sample.OldestLeaves = append(sample.OldestLeaves, leaf)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	cutoffs := opts.Cutoffs()
+	olderThan := make([]int64, len(cutoffs))
+	for i, cutoff := range cutoffs {
+		if err := m.db.QueryRowContext(ctx, selectQueuedBeforeCountSQL, treeID, cutoff.UnixNano()).Scan(&olderThan[i]); err != nil {
+			requestinfo.Warningf(ctx, "Failed to count queued leaves of tree %v: %s", treeID, err)
+			return nil, err
+		}
+	}
+	sample.AgeCounts = opts.AgeCounts(sample.LeafCount, olderThan)
+	return sample, nil
+}
+
+// GetMergeDelayWindows implements storage.MergeDelayReader.
+func (m *mySQLLogStorage) GetMergeDelayWindows(ctx context.Context, treeID int64, start, end time.Time) ([]storage.MergeDelayWindow, error) {
+	startMillis, endMillis := int64(math.MinInt64), int64(math.MaxInt64)
+	if !start.IsZero() {
+		startMillis = toMillisSinceEpoch(start)
+	}
+	if !end.IsZero() {
+		endMillis = toMillisSinceEpoch(end)
+	}
+	rows, err := m.db.QueryContext(ctx, selectMergeDelayWindowsSQL, treeID, startMillis, endMillis)
+	if err != nil {
+		requestinfo.Warningf(ctx, "Failed to read merge delay windows of tree %v: %s", treeID, err)
+		return nil, err
+	}
+	defer rows.Close()
+	var windows []storage.MergeDelayWindow
+	for rows.Next() {
+		var w storage.MergeDelayWindow
+		var startMillis, lengthMillis, mmdMillis, maxDelayMillis int64
+		if err := rows.Scan(&startMillis, &lengthMillis, &mmdMillis, &w.Leaves, &w.Violations, &w.NearMisses, &maxDelayMillis); err != nil {
+			return nil, err
+		}
+		w.Start = fromMillisSinceEpoch(startMillis)
+		w.Length = time.Duration(lengthMillis) * time.Millisecond
+		w.MaxMergeDelay = time.Duration(mmdMillis) * time.Millisecond
+		w.MaxDelay = time.Duration(maxDelayMillis) * time.Millisecond
+		windows = append(windows, w)
+	}
+	return windows, rows.Err()
+}
+
+// CompactTree implements storage.LogCompactor.
+func (m *mySQLLogStorage) CompactTree(ctx context.Context, treeID, treeRevision int64) (int64, error) {
+	tx, err := m.db.BeginTx(ctx, nil /* opts */)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, deleteSupersededSubtreesSQL, treeID, treeRevision, treeID)
+	if err != nil {
+		requestinfo.Warningf(ctx, "Failed to delete superseded subtrees of tree %v: %s", treeID, err)
+		return 0, err
+	}
+	removed, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, updateSubtreeRevisionsSQL, treeRevision, treeID, treeRevision); err != nil {
+		requestinfo.Warningf(ctx, "Failed to update subtree revisions of tree %v: %s", treeID, err)
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
+
+// RepairMerkleNodes implements storage.LogSubtreeRepairer.
+func (m *mySQLLogStorage) RepairMerkleNodes(ctx context.Context, treeID, treeRevision int64, nodes []storage.Node) error {
+	tree, err := trees.GetTree(ctx, m.admin, treeID, trees.NewGetOpts(true /* readonly */, trillian.TreeType_LOG, trillian.TreeType_PREORDERED_LOG))
+	if err != nil {
+		return err
+	}
+	hasher, err := hashers.NewLogHasher(tree.HashStrategy)
+	if err != nil {
+		return err
+	}
+	strata, err := storage.SubtreeStrata(tree)
+	if err != nil {
+		return err
+	}
+	subtrees, err := cache.BuildLogSubtrees(strata, hasher, nodes)
+	if err != nil {
+		return err
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil /* opts */)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, st := range subtrees {
+		b, err := proto.Marshal(st)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, replaceSubtreeSQL, treeID, st.Prefix, b, treeRevision); err != nil {
+			requestinfo.Warningf(ctx, "Failed to repair subtree %x of tree %v: %s", st.Prefix, treeID, err)
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
 func (m *mySQLLogStorage) QueueLeaves(ctx context.Context, treeID int64, leaves []*trillian.LogLeaf, queueTimestamp time.Time) ([]*trillian.QueuedLogLeaf, error) {
 	tx, err := m.beginInternal(ctx, treeID, false /* readonly */)
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Close()
 	existing, err := tx.QueueLeaves(ctx, leaves, queueTimestamp)
 	if err != nil {
 		return nil, err
@@ -321,11 +591,16 @@ func (t *logTreeTX) WriteRevision() int64 {
 	return t.treeTX.writeRevision
 }
 
+// GetNodesByCoords implements storage.CoordNodeReader.
+func (t *logTreeTX) GetNodesByCoords(ctx context.Context, treeRevision int64, coords []storage.NodeCoords) ([]storage.CoordNode, error) {
+	return t.subtreeCache.GetNodesByCoords(coords, t.getSubtreesAtRev(ctx, treeRevision))
+}
+
 func (t *logTreeTX) DequeueLeaves(ctx context.Context, limit int, cutoffTime time.Time) ([]*trillian.LogLeaf, error) {
 	start := time.Now()
 	stx, err := t.tx.PrepareContext(ctx, selectQueuedLeavesSQL)
 	if err != nil {
-		glog.Warningf("Failed to prepare dequeue select: %s", err)
+		requestinfo.Warningf(ctx, "Failed to prepare dequeue select: %s", err)
 		return nil, err
 	}
 	defer stx.Close()
@@ -334,7 +609,7 @@ func (t *logTreeTX) DequeueLeaves(ctx context.Context, limit int, cutoffTime tim
 	dq := make([]dequeuedLeaf, 0, limit)
 	rows, err := stx.QueryContext(ctx, t.treeID, cutoffTime.UnixNano(), limit)
 	if err != nil {
-		glog.Warningf("Failed to select rows for work: %s", err)
+		requestinfo.Warningf(ctx, "Failed to select rows for work: %s", err)
 		return nil, err
 	}
 	defer rows.Close()
@@ -342,7 +617,7 @@ func (t *logTreeTX) DequeueLeaves(ctx context.Context, limit int, cutoffTime tim
 	for rows.Next() {
 		leaf, dqInfo, err := t.dequeueLeaf(rows)
 		if err != nil {
-			glog.Warningf("Error dequeuing leaf: %v", err)
+			requestinfo.Warningf(ctx, "Error dequeuing leaf: %v", err)
 			return nil, err
 		}
 
@@ -424,7 +699,7 @@ func (t *logTreeTX) QueueLeaves(ctx context.Context, leaves []*trillian.LogLeaf,
 			continue
 		}
 		if err != nil {
-			glog.Warningf("Error inserting %d into LeafData: %s", i, err)
+			requestinfo.Warningf(ctx, "Error inserting %d into LeafData: %s", i, err)
 			return nil, err
 		}
 
@@ -445,7 +720,7 @@ func (t *logTreeTX) QueueLeaves(ctx context.Context, leaves []*trillian.LogLeaf,
 			args...,
 		)
 		if err != nil {
-			glog.Warningf("Error inserting into Unsequenced: %s", err)
+			requestinfo.Warningf(ctx, "Error inserting into Unsequenced: %s", err)
 			return nil, fmt.Errorf("Unsequenced: %v", err)
 		}
 		leafDuration := time.Since(leafStart)
@@ -503,17 +778,18 @@ func (t *logTreeTX) GetSequencedLeafCount(ctx context.Context) (int64, error) {
 
 	err := t.tx.QueryRowContext(ctx, selectSequencedLeafCountSQL, t.treeID).Scan(&sequencedLeafCount)
 	if err != nil {
-		glog.Warningf("Error getting sequenced leaf count: %s", err)
+		requestinfo.Warningf(ctx, "Error getting sequenced leaf count: %s", err)
 	}
 
 	return sequencedLeafCount, err
 }
 
 func (t *logTreeTX) GetLeavesByIndex(ctx context.Context, leaves []int64) ([]*trillian.LogLeaf, error) {
-	tmpl, err := t.ls.getLeavesByIndexStmt(ctx, len(leaves))
+	tmpl, release, err := t.ls.getLeavesByIndexStmt(ctx, len(leaves))
 	if err != nil {
 		return nil, err
 	}
+	defer release()
 	stx := t.tx.StmtContext(ctx, tmpl)
 	defer stx.Close()
 
@@ -524,7 +800,7 @@ func (t *logTreeTX) GetLeavesByIndex(ctx context.Context, leaves []int64) ([]*tr
 	args = append(args, interface{}(t.treeID))
 	rows, err := stx.QueryContext(ctx, args...)
 	if err != nil {
-		glog.Warningf("Failed to get leaves by idx: %s", err)
+		requestinfo.Warningf(ctx, "Failed to get leaves by idx: %s", err)
 		return nil, err
 	}
 	defer rows.Close()
@@ -541,7 +817,7 @@ func (t *logTreeTX) GetLeavesByIndex(ctx context.Context, leaves []int64) ([]*tr
 			&leaf.ExtraData,
 			&qTimestamp,
 			&iTimestamp); err != nil {
-			glog.Warningf("Failed to scan merkle leaves: %s", err)
+			requestinfo.Warningf(ctx, "Failed to scan merkle leaves: %s", err)
 			return nil, err
 		}
 		var err error
@@ -572,7 +848,7 @@ func (t *logTreeTX) GetLeavesByRange(ctx context.Context, start, count int64) ([
 	args := []interface{}{start, start + count, t.treeID}
 	rows, err := t.tx.QueryContext(ctx, selectLeavesByRangeSQL, args...)
 	if err != nil {
-		glog.Warningf("Failed to get leaves by range: %s", err)
+		requestinfo.Warningf(ctx, "Failed to get leaves by range: %s", err)
 		return nil, err
 	}
 	defer rows.Close()
@@ -590,7 +866,7 @@ func (t *logTreeTX) GetLeavesByRange(ctx context.Context, start, count int64) ([
 			&leaf.ExtraData,
 			&qTimestamp,
 			&iTimestamp); err != nil {
-			glog.Warningf("Failed to scan merkle leaves: %s", err)
+			requestinfo.Warningf(ctx, "Failed to scan merkle leaves: %s", err)
 			return nil, err
 		}
 		if leaf.LeafIndex != wantIndex {
@@ -616,10 +892,11 @@ func (t *logTreeTX) GetLeavesByRange(ctx context.Context, start, count int64) ([
 }
 
 func (t *logTreeTX) GetLeavesByHash(ctx context.Context, leafHashes [][]byte, orderBySequence bool) ([]*trillian.LogLeaf, error) {
-	tmpl, err := t.ls.getLeavesByMerkleHashStmt(ctx, len(leafHashes), orderBySequence)
+	tmpl, release, err := t.ls.getLeavesByMerkleHashStmt(ctx, len(leafHashes), orderBySequence)
 	if err != nil {
 		return nil, err
 	}
+	defer release()
 
 	return t.getLeavesByHashInternal(ctx, leafHashes, tmpl, "merkle")
 }
@@ -628,10 +905,11 @@ func (t *logTreeTX) GetLeavesByHash(ctx context.Context, leafHashes [][]byte, or
 // as a slice of LogLeaf objects for convenience.  However, note that the
 // returned LogLeaf objects will not have a valid MerkleLeafHash, LeafIndex, or IntegrateTimestamp.
 func (t *logTreeTX) getLeafDataByIdentityHash(ctx context.Context, leafHashes [][]byte) ([]*trillian.LogLeaf, error) {
-	tmpl, err := t.ls.getLeavesByLeafIdentityHashStmt(ctx, len(leafHashes))
+	tmpl, release, err := t.ls.getLeavesByLeafIdentityHashStmt(ctx, len(leafHashes))
 	if err != nil {
 		return nil, err
 	}
+	defer release()
 	return t.getLeavesByHashInternal(ctx, leafHashes, tmpl, "leaf-identity")
 }
 
@@ -642,12 +920,13 @@ func (t *logTreeTX) LatestSignedLogRoot(ctx context.Context) (trillian.SignedLog
 // fetchLatestRoot reads the latest SignedLogRoot from the DB and returns it.
 func (t *logTreeTX) fetchLatestRoot(ctx context.Context) (trillian.SignedLogRoot, error) {
 	var timestamp, treeSize, treeRevision int64
-	var rootHash, rootSignatureBytes []byte
+	var rootHash, rootSignatureBytes, metadataBytes []byte
 	var rootSignature spb.DigitallySigned
+	var additionalSignatures sql.NullString
 
 	err := t.tx.QueryRowContext(
 		ctx, selectLatestSignedLogRootSQL, t.treeID).Scan(
-		&timestamp, &treeSize, &rootHash, &treeRevision, &rootSignatureBytes)
+		&timestamp, &treeSize, &rootHash, &treeRevision, &rootSignatureBytes, &additionalSignatures, &metadataBytes)
 
 	// It's possible there are no roots for this tree yet
 	if err == sql.ErrNoRows {
@@ -657,17 +936,28 @@ func (t *logTreeTX) fetchLatestRoot(ctx context.Context) (trillian.SignedLogRoot
 	err = proto.Unmarshal(rootSignatureBytes, &rootSignature)
 
 	if err != nil {
-		glog.Warningf("Failed to unmarshall root signature: %v", err)
+		requestinfo.Warningf(ctx, "Failed to unmarshall root signature: %v", err)
+		return trillian.SignedLogRoot{}, err
+	}
+
+	additionalSigs, err := unmarshalAdditionalSignatures(additionalSignatures)
+	if err != nil {
+		return trillian.SignedLogRoot{}, err
+	}
+	metadata, err := unmarshalRootMetadata(metadataBytes)
+	if err != nil {
 		return trillian.SignedLogRoot{}, err
 	}
 
 	return trillian.SignedLogRoot{
-		RootHash:       rootHash,
-		TimestampNanos: timestamp,
-		TreeRevision:   treeRevision,
-		Signature:      &rootSignature,
-		LogId:          t.treeID,
-		TreeSize:       treeSize,
+		RootHash:             rootHash,
+		TimestampNanos:       timestamp,
+		TreeRevision:         treeRevision,
+		Signature:            &rootSignature,
+		LogId:                t.treeID,
+		TreeSize:             treeSize,
+		AdditionalSignatures: additionalSigs,
+		Metadata:             metadata,
 	}, nil
 }
 
@@ -675,7 +965,15 @@ func (t *logTreeTX) StoreSignedLogRoot(ctx context.Context, root trillian.Signed
 	signatureBytes, err := proto.Marshal(root.Signature)
 
 	if err != nil {
-		glog.Warningf("Failed to marshal root signature: %v %v", root.Signature, err)
+		requestinfo.Warningf(ctx, "Failed to marshal root signature: %v %v", root.Signature, err)
+		return err
+	}
+	additionalSignatures, err := marshalAdditionalSignatures(root.AdditionalSignatures)
+	if err != nil {
+		return err
+	}
+	metadata, err := marshalRootMetadata(root.Metadata)
+	if err != nil {
 		return err
 	}
 
@@ -687,14 +985,144 @@ func (t *logTreeTX) StoreSignedLogRoot(ctx context.Context, root trillian.Signed
 		root.TreeSize,
 		root.RootHash,
 		root.TreeRevision,
-		signatureBytes)
+		signatureBytes,
+		additionalSignatures,
+		metadata)
 	if err != nil {
-		glog.Warningf("Failed to store signed root: %s", err)
+		requestinfo.Warningf(ctx, "Failed to store signed root: %s", err)
+	}
+	if err := checkResultOkAndRowCountIs(res, err, 1); err != nil {
+		return err
+	}
+	if !t.ls.opts.SequencingEvents {
+		return nil
 	}
 
+	// The leaves integrated since the root this transaction started from are
+	// those in [t.root.TreeSize, root.TreeSize).
+	res, err = t.tx.ExecContext(
+		ctx,
+		insertSequencingEventSQL,
+		t.treeID,
+		root.TreeRevision,
+		t.root.TreeSize,
+		root.TreeSize,
+		root.RootHash,
+		root.TimestampNanos)
+	if err != nil {
+		requestinfo.Warningf(ctx, "Failed to store sequencing event: %s", err)
+	}
 	return checkResultOkAndRowCountIs(res, err, 1)
 }
 
+// AddLeafIndexKeys implements storage.LeafIndexWriter.
+func (t *logTreeTX) AddLeafIndexKeys(ctx context.Context, keys []storage.LeafIndexKey) error {
+	for _, k := range keys {
+		keyHash := sha256.Sum256(k.Key)
+		res, err := t.tx.ExecContext(ctx, insertLeafIndexKeySQL, t.treeID, k.Index, keyHash[:], k.LeafIndex)
+		if err != nil {
+			requestinfo.Warningf(ctx, "Failed to add leaf index key: %s", err)
+		}
+		if err := checkResultOkAndRowCountIs(res, err, 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetLeafIndicesByKey implements storage.LeafIndexReader.
+func (t *logTreeTX) GetLeafIndicesByKey(ctx context.Context, index string, key []byte, start int64, count int) ([]int64, error) {
+	keyHash := sha256.Sum256(key)
+	rows, err := t.tx.QueryContext(ctx, selectLeafIndicesByKeySQL, t.treeID, index, keyHash[:], start, count)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ret []int64
+	for rows.Next() {
+		var seq int64
+		if err := rows.Scan(&seq); err != nil {
+			return nil, err
+		}
+		ret = append(ret, seq)
+	}
+	return ret, rows.Err()
+}
+
+// GetLeafFilterBlocks implements storage.LeafFilterReader.
+func (t *logTreeTX) GetLeafFilterBlocks(ctx context.Context, indices []int64) (map[int64][]byte, error) {
+	blocks := make(map[int64][]byte)
+	if len(indices) == 0 {
+		return blocks, nil
+	}
+	tmpl, release, err := t.ls.getLeafFilterBlocksStmt(ctx, len(indices))
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	stx := t.tx.StmtContext(ctx, tmpl)
+	defer stx.Close()
+
+	args := make([]interface{}, 0, len(indices)+1)
+	for _, index := range indices {
+		args = append(args, index)
+	}
+	args = append(args, t.treeID)
+	rows, err := stx.QueryContext(ctx, args...)
+	if err != nil {
+		requestinfo.Warningf(ctx, "Failed to get leaf filter blocks: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var index int64
+		var bits []byte
+		if err := rows.Scan(&index, &bits); err != nil {
+			return nil, err
+		}
+		blocks[index] = bits
+	}
+	return blocks, rows.Err()
+}
+
+// SetLeafFilterBlocks implements storage.LeafFilterWriter.
+func (t *logTreeTX) SetLeafFilterBlocks(ctx context.Context, blocks map[int64][]byte) error {
+	for index, bits := range blocks {
+		if _, err := t.tx.ExecContext(ctx, replaceLeafFilterBlockSQL, t.treeID, index, bits); err != nil {
+			requestinfo.Warningf(ctx, "Failed to set leaf filter block: %s", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// AddMergeDelayWindow implements storage.MergeDelayWriter.
+func (t *logTreeTX) AddMergeDelayWindow(ctx context.Context, w storage.MergeDelayWindow) error {
+	startMillis := toMillisSinceEpoch(w.Start)
+	prev := storage.MergeDelayWindow{Start: w.Start, Length: w.Length}
+	var lengthMillis, mmdMillis, maxDelayMillis int64
+	err := t.tx.QueryRowContext(ctx, selectMergeDelayWindowSQL, t.treeID, startMillis).Scan(
+		&lengthMillis, &mmdMillis, &prev.Leaves, &prev.Violations, &prev.NearMisses, &maxDelayMillis)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		requestinfo.Warningf(ctx, "Failed to read merge delay window: %s", err)
+		return err
+	default:
+		prev.Length = time.Duration(lengthMillis) * time.Millisecond
+		prev.MaxDelay = time.Duration(maxDelayMillis) * time.Millisecond
+	}
+	prev.Add(w)
+
+	if _, err := t.tx.ExecContext(ctx, replaceMergeDelayWindowSQL, t.treeID, startMillis,
+		int64(prev.Length/time.Millisecond), int64(prev.MaxMergeDelay/time.Millisecond),
+		prev.Leaves, prev.Violations, prev.NearMisses, int64(prev.MaxDelay/time.Millisecond)); err != nil {
+		requestinfo.Warningf(ctx, "Failed to write merge delay window: %s", err)
+		return err
+	}
+	return nil
+}
+
 func (t *logTreeTX) getLeavesByHashInternal(ctx context.Context, leafHashes [][]byte, tmpl *sql.Stmt, desc string) ([]*trillian.LogLeaf, error) {
 	stx := t.tx.StmtContext(ctx, tmpl)
 	defer stx.Close()
@@ -706,7 +1134,7 @@ func (t *logTreeTX) getLeavesByHashInternal(ctx context.Context, leafHashes [][]
 	args = append(args, interface{}(t.treeID))
 	rows, err := stx.QueryContext(ctx, args...)
 	if err != nil {
-		glog.Warningf("Query() %s hash = %v", desc, err)
+		requestinfo.Warningf(ctx, "Query() %s hash = %v", desc, err)
 		return nil, err
 	}
 	defer rows.Close()
@@ -724,7 +1152,7 @@ func (t *logTreeTX) getLeavesByHashInternal(ctx context.Context, leafHashes [][]
 		var queueTS int64
 
 		if err := rows.Scan(&leaf.MerkleLeafHash, &leaf.LeafIdentityHash, &leaf.LeafValue, &leaf.LeafIndex, &leaf.ExtraData, &queueTS, &integrateTS); err != nil {
-			glog.Warningf("LogID: %d Scan() %s = %s", t.treeID, desc, err)
+			requestinfo.Warningf(ctx, "LogID: %d Scan() %s = %s", t.treeID, desc, err)
 			return nil, err
 		}
 		var err error
@@ -752,7 +1180,7 @@ func (t *logTreeTX) getLeavesByHashInternal(ctx context.Context, leafHashes [][]
 func (t *readOnlyLogTX) GetUnsequencedCounts(ctx context.Context) (storage.CountByLogID, error) {
 	stx, err := t.tx.PrepareContext(ctx, selectUnsequencedLeafCountSQL)
 	if err != nil {
-		glog.Warningf("Failed to prep unsequenced leaf count statement: %v", err)
+		requestinfo.Warningf(ctx, "Failed to prep unsequenced leaf count statement: %v", err)
 		return nil, err
 	}
 	defer stx.Close()
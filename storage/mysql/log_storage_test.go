@@ -31,13 +31,15 @@ import (
 	"github.com/google/trillian/storage"
 	"github.com/google/trillian/storage/testonly"
 	"github.com/kylelemons/godebug/pretty"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	spb "github.com/google/trillian/crypto/sigpb"
 
 	_ "github.com/go-sql-driver/mysql"
 )
 
-var allTables = []string{"Unsequenced", "TreeHead", "SequencedLeafData", "LeafData", "Subtree", "TreeControl", "Trees", "MapLeaf", "MapHead"}
+var allTables = []string{"Unsequenced", "TreeHead", "SequencedLeafData", "LeafData", "Subtree", "TreeControl", "SequencingEvents", "Trees", "MapLeaf", "MapHead", "MapRevisionClaim"}
 
 // Must be 32 bytes to match sha256 length if it was a real hash
 var dummyHash = []byte("hashxxxxhashxxxxhashxxxxhashxxxx")
@@ -861,6 +863,13 @@ func TestLatestSignedLogRoot(t *testing.T) {
 		TreeRevision:   5,
 		RootHash:       []byte(dummyHash),
 		Signature:      &spb.DigitallySigned{Signature: []byte("notempty")},
+		AdditionalSignatures: []*spb.DigitallySigned{
+			{SignatureAlgorithm: spb.DigitallySigned_ED25519, Signature: []byte("additional")},
+		},
+	}
+	var err error
+	if root.Metadata, err = ptypes.MarshalAny(&trillian.LogRootMetadata{Origin: "example.com/log"}); err != nil {
+		t.Fatalf("MarshalAny(): %v", err)
 	}
 
 	runLogTX(s, logID, t, func(ctx context.Context, tx storage.LogTreeTX) error {
@@ -953,6 +962,65 @@ func TestLogRootUpdate(t *testing.T) {
 	})
 }
 
+func TestSequencingEvents(t *testing.T) {
+	type event struct {
+		treeRevision, startSize, treeSize, timestamp int64
+	}
+	for _, test := range []struct {
+		desc string
+		opts TreeStorageOptions
+		want []event
+	}{
+		{desc: "disabled"},
+		{
+			desc: "enabled",
+			opts: TreeStorageOptions{SequencingEvents: true},
+			want: []event{{1, 0, 10, 1000}, {2, 10, 16, 2000}},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			cleanTestDB(DB)
+			logID := createLogForTests(DB)
+			s := NewLogStorageWithOpts(DB, nil, test.opts)
+
+			// The log starts with an empty root at revision 0.
+			for i, root := range []trillian.SignedLogRoot{
+				{LogId: logID, TimestampNanos: 1000, TreeSize: 10, TreeRevision: 1},
+				{LogId: logID, TimestampNanos: 2000, TreeSize: 16, TreeRevision: 2},
+			} {
+				root.RootHash = []byte(dummyHash)
+				root.Signature = &spb.DigitallySigned{Signature: []byte("notempty")}
+				runLogTX(s, logID, t, func(ctx context.Context, tx storage.LogTreeTX) error {
+					if err := tx.StoreSignedLogRoot(ctx, root); err != nil {
+						t.Fatalf("StoreSignedLogRoot(%d): %v", i, err)
+					}
+					return nil
+				})
+			}
+
+			rows, err := DB.Query("SELECT TreeRevision,StartSize,TreeSize,TreeHeadTimestamp FROM SequencingEvents WHERE TreeId=? ORDER BY TreeRevision", logID)
+			if err != nil {
+				t.Fatalf("Failed to query SequencingEvents: %v", err)
+			}
+			defer rows.Close()
+			var got []event
+			for rows.Next() {
+				var e event
+				if err := rows.Scan(&e.treeRevision, &e.startSize, &e.treeSize, &e.timestamp); err != nil {
+					t.Fatalf("Failed to scan event: %v", err)
+				}
+				got = append(got, e)
+			}
+			if err := rows.Err(); err != nil {
+				t.Fatalf("Failed to read events: %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("SequencingEvents = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
 func TestGetActiveLogIDs(t *testing.T) {
 	ctx := context.Background()
 
@@ -1153,6 +1221,143 @@ func TestGetSequencedLeafCount(t *testing.T) {
 	})
 }
 
+func TestGetTreeStats(t *testing.T) {
+	ctx := context.Background()
+	cleanTestDB(DB)
+	logID := createLogForTests(DB)
+	s := NewLogStorage(DB, nil)
+
+	data := []byte("some data")
+	data2 := []byte("some data 2")
+	createFakeLeaf(ctx, DB, logID, dummyHash, dummyRawHash, data, someExtraData, sequenceNumber, t)
+	createFakeLeaf(ctx, DB, logID, dummyHash2, dummyRawHash, data2, someExtraData, sequenceNumber+1, t)
+	runLogTX(s, logID, t, func(ctx context.Context, tx storage.LogTreeTX) error {
+		return tx.StoreSignedLogRoot(ctx, trillian.SignedLogRoot{LogId: logID, TimestampNanos: 1, TreeRevision: 1, TreeSize: 2, RootHash: []byte("roothash"), Signature: &spb.DigitallySigned{Signature: []byte("notempty")}})
+	})
+
+	stats, err := s.(storage.TreeStatsReader).GetTreeStats(ctx, logID)
+	if err != nil {
+		t.Fatalf("GetTreeStats(): %v", err)
+	}
+	wantBytes := int64(len(data) + len(data2) + 2*len(someExtraData))
+	// The empty root stored by createLogForTests, and the one above.
+	if stats.LeafCount != 2 || stats.LeafBytes != wantBytes || stats.RevisionCount != 2 {
+		t.Errorf("GetTreeStats(): got %d leaves of %d bytes and %d revisions, want 2 leaves of %d bytes and 2 revisions", stats.LeafCount, stats.LeafBytes, stats.RevisionCount, wantBytes)
+	}
+	if stats.WriteQPS == 0 {
+		t.Error("GetTreeStats(): got write QPS 0, want > 0")
+	}
+}
+
+func TestMergeDelayWindows(t *testing.T) {
+	ctx := context.Background()
+	cleanTestDB(DB)
+	logID := createLogForTests(DB)
+	s := NewLogStorage(DB, nil)
+
+	hour := time.Unix(1527854400, 0)
+	adds := []storage.MergeDelayWindow{
+		{Start: hour, Length: time.Hour, MaxMergeDelay: time.Minute, Leaves: 3, NearMisses: 1, MaxDelay: 50 * time.Second},
+		{Start: hour.Add(time.Hour), Length: time.Hour, MaxMergeDelay: time.Minute, Leaves: 1, MaxDelay: time.Second},
+		// More leaves integrated in the first window, after the MMD changed.
+		{Start: hour, Length: time.Hour, MaxMergeDelay: 2 * time.Minute, Leaves: 2, Violations: 1, MaxDelay: 3 * time.Minute},
+	}
+	for _, w := range adds {
+		runLogTX(s, logID, t, func(ctx context.Context, tx storage.LogTreeTX) error {
+			return tx.(storage.MergeDelayWriter).AddMergeDelayWindow(ctx, w)
+		})
+	}
+
+	first := storage.MergeDelayWindow{Start: hour, Length: time.Hour, MaxMergeDelay: 2 * time.Minute, Leaves: 5, Violations: 1, NearMisses: 1, MaxDelay: 3 * time.Minute}
+	for _, test := range []struct {
+		desc       string
+		start, end time.Time
+		want       []storage.MergeDelayWindow
+	}{
+		{desc: "all", want: []storage.MergeDelayWindow{first, adds[1]}},
+		{desc: "first", end: hour.Add(time.Hour), want: []storage.MergeDelayWindow{first}},
+		{desc: "second", start: hour.Add(90 * time.Minute), want: []storage.MergeDelayWindow{adds[1]}},
+		{desc: "none", start: hour.Add(2 * time.Hour)},
+	} {
+		got, err := s.(storage.MergeDelayReader).GetMergeDelayWindows(ctx, logID, test.start, test.end)
+		if err != nil {
+			t.Fatalf("%v: GetMergeDelayWindows(): %v", test.desc, err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: GetMergeDelayWindows(): got %+v, want %+v", test.desc, got, test.want)
+		}
+	}
+}
+
+func TestGetSignedLogRoot(t *testing.T) {
+	ctx := context.Background()
+	cleanTestDB(DB)
+	logID := createLogForTests(DB)
+	s := NewLogStorage(DB, nil)
+
+	root := trillian.SignedLogRoot{LogId: logID, TimestampNanos: 1, TreeRevision: 1, TreeSize: 2, RootHash: []byte("roothash"), Signature: &spb.DigitallySigned{Signature: []byte("notempty")}}
+	runLogTX(s, logID, t, func(ctx context.Context, tx storage.LogTreeTX) error {
+		return tx.StoreSignedLogRoot(ctx, root)
+	})
+	runLogTX(s, logID, t, func(ctx context.Context, tx storage.LogTreeTX) error {
+		return tx.StoreSignedLogRoot(ctx, trillian.SignedLogRoot{LogId: logID, TimestampNanos: 2, TreeRevision: 2, TreeSize: 3, RootHash: []byte("roothash2"), Signature: &spb.DigitallySigned{Signature: []byte("notempty")}})
+	})
+
+	reader := s.(storage.LogRootReader)
+	got, err := reader.GetSignedLogRoot(ctx, logID, 1)
+	if err != nil {
+		t.Fatalf("GetSignedLogRoot(1): %v", err)
+	}
+	if !proto.Equal(&got, &root) {
+		t.Errorf("GetSignedLogRoot(1)=%v, want %v", got, root)
+	}
+	if _, err := reader.GetSignedLogRoot(ctx, logID, 3); status.Code(err) != codes.NotFound {
+		t.Errorf("GetSignedLogRoot(3)=%v, want NotFound", err)
+	}
+}
+
+func TestSampleQueue(t *testing.T) {
+	ctx := context.Background()
+	cleanTestDB(DB)
+	logID := createLogForTests(DB)
+	s := NewLogStorage(DB, nil)
+
+	// Queue a leaf an hour ago, and two more a second ago.
+	now := fakeQueueTime.Add(time.Hour)
+	oldLeaves, newLeaves := createTestLeaves(1, 0), createTestLeaves(2, 1)
+	runLogTX(s, logID, t, func(ctx context.Context, tx storage.LogTreeTX) error {
+		if _, err := tx.QueueLeaves(ctx, oldLeaves, fakeQueueTime); err != nil {
+			return err
+		}
+		_, err := tx.QueueLeaves(ctx, newLeaves, now.Add(-time.Second))
+		return err
+	})
+
+	sample, err := s.(storage.LeafQueueInspector).SampleQueue(ctx, logID, storage.QueueSampleOptions{
+		Now:          now,
+		OldestLeaves: 2,
+		AgeBounds:    []time.Duration{time.Minute, 2 * time.Hour},
+	})
+	if err != nil {
+		t.Fatalf("SampleQueue(): %v", err)
+	}
+	if got, want := sample.LeafCount, int64(3); got != want {
+		t.Errorf("SampleQueue(): got %d leaves, want %d", got, want)
+	}
+	if got, want := sample.AgeCounts, []int64{2, 1, 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SampleQueue(): got age counts %v, want %v", got, want)
+	}
+	if len(sample.OldestLeaves) != 2 {
+		t.Fatalf("SampleQueue(): got %d oldest leaves, want 2", len(sample.OldestLeaves))
+	}
+	if got := sample.OldestLeaves[0]; !bytes.Equal(got.LeafIdentityHash, oldLeaves[0].LeafIdentityHash) || !got.QueueTimestamp.Equal(fakeQueueTime) {
+		t.Errorf("SampleQueue(): got oldest leaf %x queued at %v, want %x queued at %v", got.LeafIdentityHash, got.QueueTimestamp, oldLeaves[0].LeafIdentityHash, fakeQueueTime)
+	}
+	if len(sample.Shards) != 1 || sample.Shards[0].LeafCount != 3 || !sample.Shards[0].Oldest.Equal(fakeQueueTime) {
+		t.Errorf("SampleQueue(): got shards %+v, want a single shard of 3 leaves queued since %v", sample.Shards, fakeQueueTime)
+	}
+}
+
 func TestSortByLeafIdentityHash(t *testing.T) {
 	l := make([]*trillian.LogLeaf, 30)
 	for i := range l {
@@ -24,6 +24,7 @@ import (
 	"github.com/google/trillian/storage"
 	"github.com/google/trillian/storage/cache"
 	"github.com/google/trillian/trees"
+	"github.com/google/trillian/util/requestinfo"
 
 	"github.com/golang/glog"
 	"github.com/golang/protobuf/proto"
@@ -33,12 +34,12 @@ import (
 )
 
 const (
-	insertMapHeadSQL = `INSERT INTO MapHead(TreeId, MapHeadTimestamp, RootHash, MapRevision, RootSignature, MapperData)
-	VALUES(?, ?, ?, ?, ?, ?)`
-	selectLatestSignedMapRootSQL = `SELECT MapHeadTimestamp, RootHash, MapRevision, RootSignature, MapperData
+	insertMapHeadSQL = `INSERT INTO MapHead(TreeId, MapHeadTimestamp, RootHash, MapRevision, RootSignature, MapperData, AdditionalSignatures)
+	VALUES(?, ?, ?, ?, ?, ?, ?)`
+	selectLatestSignedMapRootSQL = `SELECT MapHeadTimestamp, RootHash, MapRevision, RootSignature, MapperData, AdditionalSignatures
 		 FROM MapHead WHERE TreeId=?
 		 ORDER BY MapHeadTimestamp DESC LIMIT 1`
-	selectGetSignedMapRootSQL = `SELECT MapHeadTimestamp, RootHash, MapRevision, RootSignature, MapperData
+	selectGetSignedMapRootSQL = `SELECT MapHeadTimestamp, RootHash, MapRevision, RootSignature, MapperData, AdditionalSignatures
 		 FROM MapHead WHERE TreeId=? AND MapRevision=?`
 	insertMapLeafSQL = `INSERT INTO MapLeaf(TreeId, KeyHash, MapRevision, LeafValue) VALUES (?, ?, ?, ?)`
 	selectMapLeafSQL = `
@@ -55,9 +56,26 @@ const (
  ON t1.TreeId=t2.TreeId
  AND t1.KeyHash=t2.KeyHash
  AND t1.MapRevision=t2.maxrev`
+	selectMapLeafHistorySQL = `SELECT MapRevision, LeafValue FROM MapLeaf
+		 WHERE TreeId=? AND KeyHash=? AND MapRevision >= ? AND MapRevision <= ?
+		 ORDER BY MapRevision`
+	selectMapLeafCountSQL = "SELECT COUNT(*) FROM MapLeaf WHERE TreeId=?"
+	selectMapLeafBytesSQL = "SELECT COALESCE(SUM(LENGTH(LeafValue)), 0) FROM MapLeaf WHERE TreeId=?"
+	selectMapHeadCountSQL = "SELECT COUNT(*) FROM MapHead WHERE TreeId=?"
+
+	insertMapRevisionClaimSQL = "INSERT INTO MapRevisionClaim(TreeId, MapRevision) VALUES (?, ?)"
+	deleteMapRevisionClaimSQL = "DELETE FROM MapRevisionClaim WHERE TreeId=? AND MapRevision=?"
 )
 
-var defaultMapStrata = []int{8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 176}
+// mapClaimKey is the context key of the mapClaim held by a ReadWriteTransaction.
+type mapClaimKey struct{}
+
+// mapClaim is a claim on writing a revision of a map, held by the outermost
+// ReadWriteTransaction on the map and shared with those nested in it.
+type mapClaim struct {
+	treeID   int64
+	revision int64
+}
 
 type mySQLMapStorage struct {
 	*mySQLTreeStorage
@@ -67,9 +85,15 @@ type mySQLMapStorage struct {
 // NewMapStorage creates a storage.MapStorage instance for the specified MySQL URL.
 // It assumes storage.AdminStorage is backed by the same MySQL database as well.
 func NewMapStorage(db *sql.DB) storage.MapStorage {
+	return NewMapStorageWithOpts(db, TreeStorageOptions{})
+}
+
+// NewMapStorageWithOpts creates a storage.MapStorage instance for the specified
+// MySQL URL, configured with the given options.
+func NewMapStorageWithOpts(db *sql.DB, opts TreeStorageOptions) storage.MapStorage {
 	return &mySQLMapStorage{
 		admin:            NewAdminStorage(db),
-		mySQLTreeStorage: newTreeStorage(db),
+		mySQLTreeStorage: newTreeStorage(db, opts),
 	}
 }
 
@@ -97,7 +121,7 @@ func (t *readOnlyMapTX) Close() error {
 	return nil
 }
 
-func (m *mySQLMapStorage) begin(ctx context.Context, treeID int64, readonly bool) (storage.MapTreeTX, error) {
+func (m *mySQLMapStorage) begin(ctx context.Context, treeID int64, readonly bool) (*mapTreeTX, error) {
 	tree, err := trees.GetTree(
 		ctx,
 		m.admin,
@@ -106,12 +130,18 @@ func (m *mySQLMapStorage) begin(ctx context.Context, treeID int64, readonly bool
 	if err != nil {
 		return nil, err
 	}
+	m.recordActivity(treeID, readonly)
 	hasher, err := hashers.NewMapHasher(tree.HashStrategy)
 	if err != nil {
 		return nil, err
 	}
 
-	stCache := cache.NewMapSubtreeCache(defaultMapStrata, treeID, hasher)
+	strata, err := storage.SubtreeStrata(tree)
+	if err != nil {
+		return nil, err
+	}
+
+	stCache := cache.NewMapSubtreeCache(strata, treeID, hasher)
 	ttx, err := m.beginTreeTx(ctx, treeID, hasher.Size(), stCache)
 	if err != nil {
 		return nil, err
@@ -134,8 +164,17 @@ func (m *mySQLMapStorage) begin(ctx context.Context, treeID int64, readonly bool
 	return mtx, nil
 }
 
+// GetTreeStats implements storage.TreeStatsReader.
+func (m *mySQLMapStorage) GetTreeStats(ctx context.Context, treeID int64) (*storage.TreeStats, error) {
+	return m.getTreeStats(ctx, treeID, selectMapLeafCountSQL, selectMapLeafBytesSQL, selectMapHeadCountSQL)
+}
+
 func (m *mySQLMapStorage) SnapshotForTree(ctx context.Context, treeID int64) (storage.ReadOnlyMapTreeTX, error) {
-	return m.begin(ctx, treeID, true /* readonly */)
+	tx, err := m.begin(ctx, treeID, true /* readonly */)
+	if tx == nil {
+		return nil, err
+	}
+	return tx, err
 }
 
 func (m *mySQLMapStorage) ReadWriteTransaction(ctx context.Context, treeID int64, f storage.MapTXFunc) error {
@@ -146,6 +185,10 @@ func (m *mySQLMapStorage) ReadWriteTransaction(ctx context.Context, treeID int64
 	if err != nil && err != storage.ErrTreeNeedsInit {
 		return err
 	}
+	ctx, err = tx.claimRevision(ctx)
+	if err != nil {
+		return err
+	}
 	if err := f(ctx, tx); err != nil {
 		return err
 	}
@@ -156,6 +199,53 @@ type mapTreeTX struct {
 	treeTX
 	ms   *mySQLMapStorage
 	root trillian.SignedMapRoot
+	// claim is set if the transaction inserted a MapRevisionClaim for its
+	// write revision, which it keeps if it also stores the revision's root.
+	claim      *mapClaim
+	rootStored bool
+}
+
+// claimRevision claims the write revision of the transaction, unless ctx
+// carries a claim on it from an enclosing transaction, and returns a context
+// carrying the claim. The claim row is inserted without being committed, so a
+// concurrent writer of the same revision blocks on it until the transaction
+// ends, then fails with storage.ErrRevisionConflict if the revision was
+// written.
+func (m *mapTreeTX) claimRevision(ctx context.Context) (context.Context, error) {
+	rev := m.writeRevision
+	if rev < 0 {
+		// The tree needs initialising, which writes revision 0.
+		rev = 0
+	}
+	if c, ok := ctx.Value(mapClaimKey{}).(mapClaim); ok && c.treeID == m.treeID {
+		if c.revision != rev {
+			return nil, fmt.Errorf("map %d: transaction writes revision %d, but enclosing one claimed %d", m.treeID, rev, c.revision)
+		}
+		return ctx, nil
+	}
+
+	if _, err := m.tx.ExecContext(ctx, insertMapRevisionClaimSQL, m.treeID, rev); err != nil {
+		if isDuplicateErr(err) {
+			return nil, storage.ErrRevisionConflict
+		}
+		requestinfo.Warningf(ctx, "Failed to claim map revision %d: %s", rev, err)
+		return nil, err
+	}
+	m.claim = &mapClaim{treeID: m.treeID, revision: rev}
+	return context.WithValue(ctx, mapClaimKey{}, *m.claim), nil
+}
+
+// Commit commits the transaction, releasing its revision claim unless it
+// stored a root, so that a transaction which wrote no revision doesn't stop
+// others from doing so.
+func (m *mapTreeTX) Commit() error {
+	if m.claim != nil && !m.rootStored {
+		if _, err := m.tx.ExecContext(context.TODO(), deleteMapRevisionClaimSQL, m.treeID, m.claim.revision); err != nil {
+			glog.Warningf("Failed to release claim on map revision %d: %s", m.claim.revision, err)
+			return err
+		}
+	}
+	return m.treeTX.Commit()
 }
 
 func (m *mapTreeTX) ReadRevision() int64 {
@@ -194,10 +284,11 @@ func (m *mapTreeTX) Get(ctx context.Context, revision int64, indexes [][]byte) (
 	if len(indexes) == 0 {
 		return []trillian.MapLeaf{}, nil
 	}
-	stmt, err := m.ms.getStmt(ctx, selectMapLeafSQL, len(indexes), "?", "?")
+	stmt, release, err := m.ms.getStmt(ctx, selectMapLeafSQL, len(indexes), "?", "?")
 	if err != nil {
 		return nil, err
 	}
+	defer release()
 	stx := m.tx.StmtContext(ctx, stmt)
 	defer stx.Close()
 
@@ -244,10 +335,47 @@ func (m *mapTreeTX) Get(ctx context.Context, revision int64, indexes [][]byte) (
 	return ret, nil
 }
 
+// GetLeafHistory returns the values keyHash was set to between fromRevision
+// and toRevision, using the MapLeaf primary key, which indexes each key's
+// revisions.
+func (m *mapTreeTX) GetLeafHistory(ctx context.Context, keyHash []byte, fromRevision, toRevision int64) ([]storage.MapLeafRevision, error) {
+	stmt, err := m.tx.PrepareContext(ctx, selectMapLeafHistorySQL)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, m.treeID, keyHash, fromRevision, toRevision)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ret []storage.MapLeafRevision
+	for rows.Next() {
+		var revision int64
+		var flatData []byte
+		if err := rows.Scan(&revision, &flatData); err != nil {
+			return nil, err
+		}
+		if len(flatData) == 0 {
+			continue
+		}
+		r := storage.MapLeafRevision{Revision: revision}
+		if err := proto.Unmarshal(flatData, &r.Leaf); err != nil {
+			return nil, err
+		}
+		r.Leaf.Index = keyHash
+		ret = append(ret, r)
+	}
+	return ret, rows.Err()
+}
+
 func (m *mapTreeTX) GetSignedMapRoot(ctx context.Context, revision int64) (trillian.SignedMapRoot, error) {
 	var timestamp, mapRevision int64
 	var rootHash, rootSignatureBytes []byte
 	var mapperMetaBytes []byte
+	var additionalSignatures sql.NullString
 
 	stmt, err := m.tx.PrepareContext(ctx, selectGetSignedMapRootSQL)
 	if err != nil {
@@ -256,20 +384,21 @@ func (m *mapTreeTX) GetSignedMapRoot(ctx context.Context, revision int64) (trill
 	defer stmt.Close()
 
 	err = stmt.QueryRowContext(ctx, m.treeID, revision).Scan(
-		&timestamp, &rootHash, &mapRevision, &rootSignatureBytes, &mapperMetaBytes)
+		&timestamp, &rootHash, &mapRevision, &rootSignatureBytes, &mapperMetaBytes, &additionalSignatures)
 	if err != nil {
 		if revision == 0 {
 			return trillian.SignedMapRoot{}, storage.ErrTreeNeedsInit
 		}
 		return trillian.SignedMapRoot{}, err
 	}
-	return m.signedMapRoot(timestamp, mapRevision, rootHash, rootSignatureBytes, mapperMetaBytes)
+	return m.signedMapRoot(timestamp, mapRevision, rootHash, rootSignatureBytes, mapperMetaBytes, additionalSignatures)
 }
 
 func (m *mapTreeTX) LatestSignedMapRoot(ctx context.Context) (trillian.SignedMapRoot, error) {
 	var timestamp, mapRevision int64
 	var rootHash, rootSignatureBytes []byte
 	var mapperMetaBytes []byte
+	var additionalSignatures sql.NullString
 
 	stmt, err := m.tx.PrepareContext(ctx, selectLatestSignedMapRootSQL)
 	if err != nil {
@@ -278,7 +407,7 @@ func (m *mapTreeTX) LatestSignedMapRoot(ctx context.Context) (trillian.SignedMap
 	defer stmt.Close()
 
 	err = stmt.QueryRowContext(ctx, m.treeID).Scan(
-		&timestamp, &rootHash, &mapRevision, &rootSignatureBytes, &mapperMetaBytes)
+		&timestamp, &rootHash, &mapRevision, &rootSignatureBytes, &mapperMetaBytes, &additionalSignatures)
 
 	// It's possible there are no roots for this tree yet
 	if err == sql.ErrNoRows {
@@ -286,10 +415,10 @@ func (m *mapTreeTX) LatestSignedMapRoot(ctx context.Context) (trillian.SignedMap
 	} else if err != nil {
 		return trillian.SignedMapRoot{}, err
 	}
-	return m.signedMapRoot(timestamp, mapRevision, rootHash, rootSignatureBytes, mapperMetaBytes)
+	return m.signedMapRoot(timestamp, mapRevision, rootHash, rootSignatureBytes, mapperMetaBytes, additionalSignatures)
 }
 
-func (m *mapTreeTX) signedMapRoot(timestamp, mapRevision int64, rootHash, rootSignatureBytes, mapperMetaBytes []byte) (trillian.SignedMapRoot, error) {
+func (m *mapTreeTX) signedMapRoot(timestamp, mapRevision int64, rootHash, rootSignatureBytes, mapperMetaBytes []byte, additionalSignatures sql.NullString) (trillian.SignedMapRoot, error) {
 	rootSignature := &spb.DigitallySigned{}
 
 	err := proto.Unmarshal(rootSignatureBytes, rootSignature)
@@ -314,6 +443,9 @@ func (m *mapTreeTX) signedMapRoot(timestamp, mapRevision int64, rootHash, rootSi
 		}
 		smr.Metadata = mapperMeta
 	}
+	if smr.AdditionalSignatures, err = unmarshalAdditionalSignatures(additionalSignatures); err != nil {
+		return trillian.SignedMapRoot{}, fmt.Errorf("signedMapRoot: %v", err)
+	}
 
 	return smr, nil
 }
@@ -321,7 +453,7 @@ func (m *mapTreeTX) signedMapRoot(timestamp, mapRevision int64, rootHash, rootSi
 func (m *mapTreeTX) StoreSignedMapRoot(ctx context.Context, root trillian.SignedMapRoot) error {
 	signatureBytes, err := proto.Marshal(root.Signature)
 	if err != nil {
-		glog.Warningf("Failed to marshal root signature: %v %v", root.Signature, err)
+		requestinfo.Warningf(ctx, "Failed to marshal root signature: %v %v", root.Signature, err)
 		return err
 	}
 
@@ -334,6 +466,10 @@ func (m *mapTreeTX) StoreSignedMapRoot(ctx context.Context, root trillian.Signed
 			return err
 		}
 	}
+	additionalSignatures, err := marshalAdditionalSignatures(root.AdditionalSignatures)
+	if err != nil {
+		return err
+	}
 
 	stmt, err := m.tx.PrepareContext(ctx, insertMapHeadSQL)
 	if err != nil {
@@ -342,11 +478,18 @@ func (m *mapTreeTX) StoreSignedMapRoot(ctx context.Context, root trillian.Signed
 	defer stmt.Close()
 
 	// TODO(al): store transactionLogHead too
-	res, err := stmt.ExecContext(ctx, m.treeID, root.TimestampNanos, root.RootHash, root.MapRevision, signatureBytes, mapperMetaBytes)
+	res, err := stmt.ExecContext(ctx, m.treeID, root.TimestampNanos, root.RootHash, root.MapRevision, signatureBytes, mapperMetaBytes, additionalSignatures)
 
+	if isDuplicateErr(err) {
+		return storage.ErrRevisionConflict
+	}
 	if err != nil {
-		glog.Warningf("Failed to store signed map root: %s", err)
+		requestinfo.Warningf(ctx, "Failed to store signed map root: %s", err)
 	}
 
-	return checkResultOkAndRowCountIs(res, err, 1)
+	if err := checkResultOkAndRowCountIs(res, err, 1); err != nil {
+		return err
+	}
+	m.rootStored = true
+	return nil
 }
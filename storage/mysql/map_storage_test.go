@@ -427,6 +427,71 @@ func TestMapSetGetMultipleRevisions(t *testing.T) {
 	}
 }
 
+func TestMapGetLeafHistory(t *testing.T) {
+	if provider := testdb.Default(); !provider.IsMySQL() {
+		t.Skipf("Inhibited due to known issue (#896) on SQL driver: %q", provider.Driver)
+	}
+
+	cleanTestDB(DB)
+	ctx := context.Background()
+	mapID := createInitializedMapForTests(ctx, t, DB)
+	s := NewMapStorage(DB)
+
+	otherKeyHash := []byte("B")
+	leaves := map[int64]trillian.MapLeaf{
+		1: {Index: keyHash, LeafHash: []byte{1}, LeafValue: []byte{1}},
+		3: {Index: keyHash, LeafHash: []byte{3}, LeafValue: []byte{3}},
+		4: {Index: keyHash, LeafHash: []byte{4}, LeafValue: []byte{4}},
+	}
+	for rev := int64(1); rev <= 5; rev++ {
+		runMapTX(ctx, s, mapID, t, func(ctx context.Context, tx storage.MapTreeTX) error {
+			mapTX := tx.(*mapTreeTX)
+			mapTX.treeTX.writeRevision = rev
+			if leaf, ok := leaves[rev]; ok {
+				if err := tx.Set(ctx, keyHash, leaf); err != nil {
+					t.Fatalf("Failed to set %v to %v: %v", keyHash, leaf, err)
+				}
+			}
+			// Values of other keys must not show up in the history.
+			if err := tx.Set(ctx, otherKeyHash, trillian.MapLeaf{Index: otherKeyHash, LeafValue: []byte{byte(rev)}}); err != nil {
+				t.Fatalf("Failed to set %v: %v", otherKeyHash, err)
+			}
+			return nil
+		})
+	}
+
+	for _, tc := range []struct {
+		from, to int64
+		wantRevs []int64
+	}{
+		{from: 0, to: 5, wantRevs: []int64{1, 3, 4}},
+		{from: 2, to: 3, wantRevs: []int64{3}},
+		{from: 4, to: 10, wantRevs: []int64{4}},
+		{from: 5, to: 5},
+	} {
+		t.Run(fmt.Sprintf("GetLeafHistory(%d, %d)", tc.from, tc.to), func(t *testing.T) {
+			runMapTX(ctx, s, mapID, t, func(ctx context.Context, tx storage.MapTreeTX) error {
+				history, err := tx.GetLeafHistory(ctx, keyHash, tc.from, tc.to)
+				if err != nil {
+					t.Fatalf("GetLeafHistory(): %v", err)
+				}
+				if got, want := len(history), len(tc.wantRevs); got != want {
+					t.Fatalf("GetLeafHistory(): %d revisions, want %d", got, want)
+				}
+				for i, r := range history {
+					if got, want := r.Revision, tc.wantRevs[i]; got != want {
+						t.Errorf("GetLeafHistory()[%d].Revision: %d, want %d", i, got, want)
+					}
+					if leaf := leaves[r.Revision]; !proto.Equal(&r.Leaf, &leaf) {
+						t.Errorf("GetLeafHistory()[%d].Leaf: %v, want %v", i, r.Leaf, leaf)
+					}
+				}
+				return nil
+			})
+		})
+	}
+}
+
 func TestGetSignedMapRootNotExist(t *testing.T) {
 	if provider := testdb.Default(); !provider.IsMySQL() {
 		t.Skipf("Inhibited due to known issue (#896) on SQL driver: %q", provider.Driver)
@@ -528,6 +593,9 @@ func TestLatestSignedMapRoot(t *testing.T) {
 		MapRevision:    5,
 		RootHash:       []byte(dummyHash),
 		Signature:      &spb.DigitallySigned{Signature: []byte("notempty")},
+		AdditionalSignatures: []*spb.DigitallySigned{
+			{SignatureAlgorithm: spb.DigitallySigned_ED25519, Signature: []byte("additional")},
+		},
 	}
 	runMapTX(ctx, s, mapID, t, func(ctx context.Context, tx storage.MapTreeTX) error {
 		if err := tx.StoreSignedMapRoot(ctx, root); err != nil {
@@ -579,6 +647,112 @@ func TestDuplicateSignedMapRoot(t *testing.T) {
 	})
 }
 
+func TestMapRevisionClaim(t *testing.T) {
+	cleanTestDB(DB)
+	ctx := context.Background()
+	s := NewMapStorage(DB)
+	root := func(mapID, rev int64) trillian.SignedMapRoot {
+		return trillian.SignedMapRoot{
+			MapId:          mapID,
+			TimestampNanos: 98765 + rev,
+			MapRevision:    rev,
+			RootHash:       []byte(dummyHash),
+			Signature:      &spb.DigitallySigned{Signature: []byte("notempty")},
+		}
+	}
+
+	t.Run("claimedByOtherWriter", func(t *testing.T) {
+		mapID := createInitializedMapForTests(ctx, t, DB)
+		if _, err := DB.ExecContext(ctx, insertMapRevisionClaimSQL, mapID, 1); err != nil {
+			t.Fatalf("Failed to claim revision 1: %v", err)
+		}
+		err := s.ReadWriteTransaction(ctx, mapID, func(ctx context.Context, tx storage.MapTreeTX) error {
+			t.Errorf("ReadWriteTransaction() called f for claimed revision %d", tx.WriteRevision())
+			return nil
+		})
+		if got, want := err, storage.ErrRevisionConflict; got != want {
+			t.Errorf("ReadWriteTransaction()=%v, want %v", got, want)
+		}
+	})
+
+	t.Run("releasedWithoutRoot", func(t *testing.T) {
+		mapID := createInitializedMapForTests(ctx, t, DB)
+		runMapTX(ctx, s, mapID, t, func(ctx context.Context, tx storage.MapTreeTX) error {
+			_, err := tx.LatestSignedMapRoot(ctx)
+			return err
+		})
+		if got, want := countMapRevisionClaims(ctx, t, mapID), 1; got != want {
+			t.Errorf("Got %d claims after read-only transaction, want %d", got, want)
+		}
+		runMapTX(ctx, s, mapID, t, func(ctx context.Context, tx storage.MapTreeTX) error {
+			return tx.StoreSignedMapRoot(ctx, root(mapID, tx.WriteRevision()))
+		})
+		if got, want := countMapRevisionClaims(ctx, t, mapID), 2; got != want {
+			t.Errorf("Got %d claims after writing revision 1, want %d", got, want)
+		}
+	})
+
+	t.Run("sharedWithNestedTransaction", func(t *testing.T) {
+		mapID := createInitializedMapForTests(ctx, t, DB)
+		runMapTX(ctx, s, mapID, t, func(ctx context.Context, tx storage.MapTreeTX) error {
+			if err := s.ReadWriteTransaction(ctx, mapID, func(ctx context.Context, nested storage.MapTreeTX) error {
+				return nil
+			}); err != nil {
+				t.Errorf("Nested ReadWriteTransaction()=%v, want nil", err)
+			}
+			return tx.StoreSignedMapRoot(ctx, root(mapID, tx.WriteRevision()))
+		})
+	})
+}
+
+func TestMapConcurrentWriters(t *testing.T) {
+	if provider := testdb.Default(); !provider.IsMySQL() {
+		t.Skipf("Concurrent transactions are only tested on MySQL, not on SQL driver: %q", provider.Driver)
+	}
+
+	cleanTestDB(DB)
+	ctx := context.Background()
+	mapID := createInitializedMapForTests(ctx, t, DB)
+	s := NewMapStorage(DB).(*mySQLMapStorage)
+
+	// Start a writer which reads the latest root before another one writes
+	// the next revision.
+	late, err := s.begin(ctx, mapID, false /* readonly */)
+	if err != nil {
+		t.Fatalf("begin()=%v", err)
+	}
+	defer late.Close()
+
+	runMapTX(ctx, s, mapID, t, func(ctx context.Context, tx storage.MapTreeTX) error {
+		if err := tx.Set(ctx, keyHash, mapLeaf); err != nil {
+			return err
+		}
+		return tx.StoreSignedMapRoot(ctx, trillian.SignedMapRoot{
+			MapId:          mapID,
+			TimestampNanos: 98765,
+			MapRevision:    tx.WriteRevision(),
+			RootHash:       []byte(dummyHash),
+			Signature:      &spb.DigitallySigned{Signature: []byte("notempty")},
+		})
+	})
+
+	if got, want := late.WriteRevision(), int64(1); got != want {
+		t.Fatalf("WriteRevision()=%d, want %d", got, want)
+	}
+	if _, err := late.claimRevision(ctx); err != storage.ErrRevisionConflict {
+		t.Errorf("claimRevision()=%v, want %v", err, storage.ErrRevisionConflict)
+	}
+}
+
+func countMapRevisionClaims(ctx context.Context, t *testing.T, mapID int64) int {
+	t.Helper()
+	var count int
+	if err := DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM MapRevisionClaim WHERE TreeId=?", mapID).Scan(&count); err != nil {
+		t.Fatalf("Failed to count map revision claims: %v", err)
+	}
+	return count
+}
+
 func TestReadOnlyMapTX_Rollback(t *testing.T) {
 	if provider := testdb.Default(); !provider.IsMySQL() {
 		t.Skipf("Inhibited due to known issue (#896) on SQL driver: %q", provider.Driver)
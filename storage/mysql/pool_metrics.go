@@ -0,0 +1,85 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/google/trillian/monitoring"
+)
+
+var (
+	poolMetricsOnce        sync.Once
+	poolOpenConnections    monitoring.Gauge
+	poolMaxOpenConnections monitoring.Gauge
+	poolExhausted          monitoring.Gauge
+	poolExhaustedSamples   monitoring.Counter
+)
+
+func createPoolMetrics(mf monitoring.MetricFactory) {
+	poolOpenConnections = mf.NewGauge("mysql_pool_open_connections", "Number of open connections to the database")
+	poolMaxOpenConnections = mf.NewGauge("mysql_pool_max_open_connections", "Configured maximum number of open connections to the database (0 is unlimited)")
+	poolExhausted = mf.NewGauge("mysql_pool_exhausted", "Set to 1 while all permitted connections to the database are open (0/1)")
+	poolExhaustedSamples = mf.NewCounter("mysql_pool_exhausted_samples", "Number of pool samples taken while all permitted connections were open")
+}
+
+// MonitorDBPool samples the connection pool statistics of db every interval
+// and exports them as metrics, until ctx is done. maxOpenConns should be the
+// limit configured on db (as in DBOptions.MaxOpenConns); the pool is
+// considered exhausted when that many connections are open, in which case
+// further queries block waiting for a connection to be released. If interval
+// isn't positive the pool is sampled only once.
+func MonitorDBPool(ctx context.Context, db *sql.DB, maxOpenConns int, mf monitoring.MetricFactory, interval time.Duration) {
+	if mf == nil {
+		mf = monitoring.InertMetricFactory{}
+	}
+	poolMetricsOnce.Do(func() { createPoolMetrics(mf) })
+	if maxOpenConns < 0 {
+		maxOpenConns = 0
+	}
+	poolMaxOpenConnections.Set(float64(maxOpenConns))
+	if interval <= 0 {
+		glog.Warningf("MySQL pool sampling interval is %v, pool metrics won't be updated", interval)
+		samplePool(db, maxOpenConns)
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		samplePool(db, maxOpenConns)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// samplePool updates the pool metrics with the current statistics of db.
+func samplePool(db *sql.DB, maxOpenConns int) {
+	open := db.Stats().OpenConnections
+	poolOpenConnections.Set(float64(open))
+	if maxOpenConns > 0 && open >= maxOpenConns {
+		poolExhausted.Set(1)
+		poolExhaustedSamples.Inc()
+	} else {
+		poolExhausted.Set(0)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMonitorDBPool(t *testing.T) {
+	for _, test := range []struct {
+		desc     string
+		interval time.Duration
+		// cancel is whether MonitorDBPool must be cancelled to return.
+		cancel bool
+	}{
+		{desc: "positive", interval: time.Millisecond, cancel: true},
+		{desc: "zero", interval: 0},
+		{desc: "negative", interval: -time.Second},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				MonitorDBPool(ctx, DB, 1, nil, test.interval)
+			}()
+			if test.cancel {
+				time.Sleep(10 * test.interval)
+				cancel()
+			}
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("MonitorDBPool(interval=%v) didn't return", test.interval)
+			}
+		})
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/golang/glog"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/google/trillian"
+	"github.com/google/trillian/util/requestinfo"
 )
 
 const (
@@ -102,7 +103,7 @@ func (t *logTreeTX) UpdateSequencedLeaves(ctx context.Context, leaves []*trillia
 			leaf.LeafIndex,
 			iTimestamp.UnixNano())
 		if err != nil {
-			glog.Warningf("Failed to update sequenced leaves: %s", err)
+			requestinfo.Warningf(ctx, "Failed to update sequenced leaves: %s", err)
 			return err
 		}
 	}
@@ -118,7 +119,7 @@ func (t *logTreeTX) removeSequencedLeaves(ctx context.Context, leaves []dequeued
 	// QueueLeaves.
 	stx, err := t.tx.PrepareContext(ctx, deleteUnsequencedSQL)
 	if err != nil {
-		glog.Warningf("Failed to prep delete statement for sequenced work: %v", err)
+		requestinfo.Warningf(ctx, "Failed to prep delete statement for sequenced work: %v", err)
 		return err
 	}
 	for _, dql := range leaves {
@@ -28,6 +28,7 @@ import (
 	"github.com/golang/glog"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/google/trillian"
+	"github.com/google/trillian/util/requestinfo"
 )
 
 const (
@@ -106,12 +107,12 @@ func (t *logTreeTX) UpdateSequencedLeaves(ctx context.Context, leaves []*trillia
 	}
 	result, err := t.tx.ExecContext(ctx, insertSequencedLeafSQL+strings.Join(querySuffix, ","), args...)
 	if err != nil {
-		glog.Warningf("Failed to update sequenced leaves: %s", err)
+		requestinfo.Warningf(ctx, "Failed to update sequenced leaves: %s", err)
 	}
 	return checkResultOkAndRowCountIs(result, err, int64(len(leaves)))
 }
 
-func (m *mySQLLogStorage) getDeleteUnsequencedStmt(ctx context.Context, num int) (*sql.Stmt, error) {
+func (m *mySQLLogStorage) getDeleteUnsequencedStmt(ctx context.Context, num int) (*sql.Stmt, func(), error) {
 	return m.getStmt(ctx, deleteUnsequencedSQL, num, "?", "?")
 }
 
@@ -121,11 +122,12 @@ func (t *logTreeTX) removeSequencedLeaves(ctx context.Context, queueIDs []dequeu
 	// Don't need to re-sort because the query ordered by leaf hash. If that changes because
 	// the query is expensive then the sort will need to be done here. See comment in
 	// QueueLeaves.
-	tmpl, err := t.ls.getDeleteUnsequencedStmt(ctx, len(queueIDs))
+	tmpl, release, err := t.ls.getDeleteUnsequencedStmt(ctx, len(queueIDs))
 	if err != nil {
-		glog.Warningf("Failed to get delete statement for sequenced work: %s", err)
+		requestinfo.Warningf(ctx, "Failed to get delete statement for sequenced work: %s", err)
 		return err
 	}
+	defer release()
 	stx := t.tx.StmtContext(ctx, tmpl)
 	args := make([]interface{}, len(queueIDs))
 	for i, q := range queueIDs {
@@ -134,7 +136,7 @@ func (t *logTreeTX) removeSequencedLeaves(ctx context.Context, queueIDs []dequeu
 	result, err := stx.ExecContext(ctx, args...)
 	if err != nil {
 		// Error is handled by checkResultOkAndRowCountIs() below
-		glog.Warningf("Failed to delete sequenced work: %s", err)
+		requestinfo.Warningf(ctx, "Failed to delete sequenced work: %s", err)
 	}
 	return checkResultOkAndRowCountIs(result, err, int64(len(queueIDs)))
 }
@@ -0,0 +1,434 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// tableSchema is the expected schema of a table, as created by storage.sql.
+type tableSchema struct {
+	name    string
+	columns []columnSchema
+	// indexes maps the names of the indexes the queries rely on, including
+	// PRIMARY, to their columns.
+	indexes map[string][]string
+}
+
+// columnSchema is the expected schema of a column.
+type columnSchema struct {
+	name string
+	// dataType is the DATA_TYPE of the column in information_schema.
+	dataType string
+	// enumValues are the values an enum column must allow.
+	enumValues []string
+}
+
+// schema is the expected schema of the database, which must be kept in sync
+// with storage.sql.
+var schema = []tableSchema{
+	{
+		name: "Trees",
+		columns: []columnSchema{
+			{name: "TreeId", dataType: "bigint"},
+			{name: "TreeState", dataType: "enum", enumValues: []string{"ACTIVE", "FROZEN"}},
+			{name: "TreeType", dataType: "enum", enumValues: []string{"LOG", "MAP", "PREORDERED_LOG"}},
+			{name: "HashStrategy", dataType: "enum", enumValues: []string{"RFC6962_SHA256", "TEST_MAP_HASHER", "OBJECT_RFC6962_SHA256", "CONIKS_SHA512_256"}},
+			{name: "HashAlgorithm", dataType: "enum", enumValues: []string{"SHA256"}},
+			{name: "SignatureAlgorithm", dataType: "enum", enumValues: []string{"ECDSA", "RSA", "ED25519"}},
+			{name: "DisplayName", dataType: "varchar"},
+			{name: "Description", dataType: "varchar"},
+			{name: "CreateTimeMillis", dataType: "bigint"},
+			{name: "UpdateTimeMillis", dataType: "bigint"},
+			{name: "MaxRootDurationMillis", dataType: "bigint"},
+			{name: "PrivateKey", dataType: "mediumblob"},
+			{name: "PublicKey", dataType: "mediumblob"},
+			{name: "Deleted", dataType: "tinyint"},
+			{name: "DeleteTimeMillis", dataType: "bigint"},
+			{name: "StorageSettings", dataType: "mediumblob"},
+			{name: "RootTimestampPrecision", dataType: "enum", enumValues: []string{"NANOSECOND_PRECISION", "MILLISECOND_PRECISION", "SECOND_PRECISION"}},
+			{name: "Labels", dataType: "text"},
+			{name: "LeafIndexes", dataType: "text"},
+			{name: "LeafFilter", dataType: "text"},
+			{name: "Maintenance", dataType: "text"},
+			{name: "AdditionalSigningKeys", dataType: "text"},
+			{name: "MaxMergeDelayMillis", dataType: "bigint"},
+			{name: "ValidityWindow", dataType: "text"},
+			{name: "WriteVerification", dataType: "enum", enumValues: []string{"UNKNOWN_WRITE_VERIFICATION", "VERIFY_WRITES", "SKIP_WRITE_VERIFICATION"}},
+		},
+		indexes: map[string][]string{"PRIMARY": {"TreeId"}},
+	},
+	{
+		name: "TreeControl",
+		columns: []columnSchema{
+			{name: "TreeId", dataType: "bigint"},
+			{name: "SigningEnabled", dataType: "tinyint"},
+			{name: "SequencingEnabled", dataType: "tinyint"},
+			{name: "SequenceIntervalSeconds", dataType: "int"},
+		},
+		indexes: map[string][]string{"PRIMARY": {"TreeId"}},
+	},
+	{
+		name: "Subtree",
+		columns: []columnSchema{
+			{name: "TreeId", dataType: "bigint"},
+			{name: "SubtreeId", dataType: "varbinary"},
+			{name: "Nodes", dataType: "mediumblob"},
+			{name: "SubtreeRevision", dataType: "int"},
+		},
+		indexes: map[string][]string{"PRIMARY": {"TreeId", "SubtreeId", "SubtreeRevision"}},
+	},
+	{
+		name: "TreeHead",
+		columns: []columnSchema{
+			{name: "TreeId", dataType: "bigint"},
+			{name: "TreeHeadTimestamp", dataType: "bigint"},
+			{name: "TreeSize", dataType: "bigint"},
+			{name: "RootHash", dataType: "varbinary"},
+			{name: "RootSignature", dataType: "varbinary"},
+			{name: "TreeRevision", dataType: "bigint"},
+			{name: "AdditionalSignatures", dataType: "text"},
+			{name: "Metadata", dataType: "mediumblob"},
+		},
+		indexes: map[string][]string{
+			"PRIMARY":             {"TreeId", "TreeHeadTimestamp"},
+			"TreeHeadRevisionIdx": {"TreeId", "TreeRevision"},
+		},
+	},
+	{
+		name: "SequencingEvents",
+		columns: []columnSchema{
+			{name: "TreeId", dataType: "bigint"},
+			{name: "TreeRevision", dataType: "bigint"},
+			{name: "StartSize", dataType: "bigint"},
+			{name: "TreeSize", dataType: "bigint"},
+			{name: "RootHash", dataType: "varbinary"},
+			{name: "TreeHeadTimestamp", dataType: "bigint"},
+		},
+		indexes: map[string][]string{"PRIMARY": {"TreeId", "TreeRevision"}},
+	},
+	{
+		name: "LeafData",
+		columns: []columnSchema{
+			{name: "TreeId", dataType: "bigint"},
+			{name: "LeafIdentityHash", dataType: "varbinary"},
+			{name: "LeafValue", dataType: "longblob"},
+			{name: "ExtraData", dataType: "longblob"},
+			{name: "QueueTimestampNanos", dataType: "bigint"},
+		},
+		indexes: map[string][]string{"PRIMARY": {"TreeId", "LeafIdentityHash"}},
+	},
+	{
+		name: "SequencedLeafData",
+		columns: []columnSchema{
+			{name: "TreeId", dataType: "bigint"},
+			{name: "SequenceNumber", dataType: "bigint"},
+			{name: "LeafIdentityHash", dataType: "varbinary"},
+			{name: "MerkleLeafHash", dataType: "varbinary"},
+			{name: "IntegrateTimestampNanos", dataType: "bigint"},
+		},
+		indexes: map[string][]string{"PRIMARY": {"TreeId", "SequenceNumber"}},
+	},
+	{
+		name: "LeafIndexKeys",
+		columns: []columnSchema{
+			{name: "TreeId", dataType: "bigint"},
+			{name: "IndexName", dataType: "varchar"},
+			{name: "KeyHash", dataType: "varbinary"},
+			{name: "SequenceNumber", dataType: "bigint"},
+		},
+		indexes: map[string][]string{"PRIMARY": {"TreeId", "IndexName", "KeyHash", "SequenceNumber"}},
+	},
+	{
+		name: "LeafFilterBlocks",
+		columns: []columnSchema{
+			{name: "TreeId", dataType: "bigint"},
+			{name: "BlockIndex", dataType: "bigint"},
+			{name: "Bits", dataType: "varbinary"},
+		},
+		indexes: map[string][]string{"PRIMARY": {"TreeId", "BlockIndex"}},
+	},
+	{
+		name: "MergeDelayWindows",
+		columns: []columnSchema{
+			{name: "TreeId", dataType: "bigint"},
+			{name: "WindowStartMillis", dataType: "bigint"},
+			{name: "WindowLengthMillis", dataType: "bigint"},
+			{name: "MaxMergeDelayMillis", dataType: "bigint"},
+			{name: "LeafCount", dataType: "bigint"},
+			{name: "ViolationCount", dataType: "bigint"},
+			{name: "NearMissCount", dataType: "bigint"},
+			{name: "MaxDelayMillis", dataType: "bigint"},
+		},
+		indexes: map[string][]string{"PRIMARY": {"TreeId", "WindowStartMillis"}},
+	},
+	{
+		name: "Unsequenced",
+		columns: []columnSchema{
+			{name: "TreeId", dataType: "bigint"},
+			{name: "Bucket", dataType: "int"},
+			{name: "LeafIdentityHash", dataType: "varbinary"},
+			{name: "MerkleLeafHash", dataType: "varbinary"},
+			{name: "QueueTimestampNanos", dataType: "bigint"},
+			{name: "QueueID", dataType: "varbinary"},
+		},
+		indexes: map[string][]string{
+			"PRIMARY": {"TreeId", "Bucket", "QueueTimestampNanos", "LeafIdentityHash"},
+			"QueueID": {"QueueID"},
+		},
+	},
+	{
+		name: "MapLeaf",
+		columns: []columnSchema{
+			{name: "TreeId", dataType: "bigint"},
+			{name: "KeyHash", dataType: "varbinary"},
+			{name: "MapRevision", dataType: "bigint"},
+			{name: "LeafValue", dataType: "longblob"},
+		},
+		indexes: map[string][]string{"PRIMARY": {"TreeId", "KeyHash", "MapRevision"}},
+	},
+	{
+		name: "MapHead",
+		columns: []columnSchema{
+			{name: "TreeId", dataType: "bigint"},
+			{name: "MapHeadTimestamp", dataType: "bigint"},
+			{name: "RootHash", dataType: "varbinary"},
+			{name: "MapRevision", dataType: "bigint"},
+			{name: "RootSignature", dataType: "varbinary"},
+			{name: "MapperData", dataType: "mediumblob"},
+			{name: "AdditionalSignatures", dataType: "text"},
+		},
+		indexes: map[string][]string{
+			"PRIMARY":            {"TreeId", "MapHeadTimestamp"},
+			"MapHeadRevisionIdx": {"TreeId", "MapRevision"},
+		},
+	},
+	{
+		name: "MapRevisionClaim",
+		columns: []columnSchema{
+			{name: "TreeId", dataType: "bigint"},
+			{name: "MapRevision", dataType: "bigint"},
+		},
+		indexes: map[string][]string{
+			"PRIMARY": {"TreeId", "MapRevision"},
+		},
+	},
+}
+
+// SchemaError lists the differences between the schema of a database and the
+// one expected by the storage.
+type SchemaError struct {
+	Problems []string
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("database schema doesn't match storage/mysql/storage.sql, apply the missing changes to it: %s", strings.Join(e.Problems, "; "))
+}
+
+// CheckSchema checks that the database of db has the tables, columns and
+// indexes used by the storage, so that servers can fail at startup rather
+// than when a rarely used query first runs against an outdated schema. Column
+// types and indexes are only checked on MySQL, as other databases don't have
+// its information_schema. Returns a *SchemaError if the schema doesn't match.
+func CheckSchema(ctx context.Context, db *sql.DB) error {
+	var problems []string
+	for _, t := range schema {
+		p, err := checkColumnsExist(ctx, db, t)
+		if err != nil {
+			return err
+		}
+		problems = append(problems, p...)
+	}
+	if _, ok := db.Driver().(*mysql.MySQLDriver); ok && len(problems) == 0 {
+		p, err := checkTypesAndIndexes(ctx, db)
+		if err != nil {
+			return err
+		}
+		problems = append(problems, p...)
+	}
+	if len(problems) > 0 {
+		return &SchemaError{Problems: problems}
+	}
+	return nil
+}
+
+// ErrSchemaExists is returned by CreateSchema if the database already has some
+// of the storage's tables.
+var ErrSchemaExists = errors.New("database already has Trillian tables")
+
+// CreateSchema runs the statements of script, normally the contents of
+// storage.sql, against the database of db and checks the resulting schema.
+// The database must not have any of the storage's tables yet: if it does,
+// CreateSchema returns ErrSchemaExists without changing it, so that it can be
+// run on every start of a server without touching databases set up, or
+// migrated, by other means.
+func CreateSchema(ctx context.Context, db *sql.DB, script string) error {
+	for _, t := range schema {
+		rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s LIMIT 0", t.name))
+		if err == nil {
+			rows.Close()
+			return ErrSchemaExists
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	for _, stmt := range splitStatements(script) {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("error running statement %q: %v", stmt, err)
+		}
+	}
+	return CheckSchema(ctx, db)
+}
+
+// splitStatements returns the statements of script, a SQL script whose
+// statements are terminated by ";" and whose comments take whole lines.
+func splitStatements(script string) []string {
+	var lines []string
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	var stmts []string
+	for _, stmt := range strings.Split(strings.Join(lines, "\n"), ";") {
+		if stmt = strings.TrimSpace(stmt); stmt != "" {
+			stmts = append(stmts, stmt)
+		}
+	}
+	return stmts
+}
+
+// checkColumnsExist returns the columns of t missing from the database, or
+// the table itself. It works on any SQL database.
+func checkColumnsExist(ctx context.Context, db *sql.DB, t tableSchema) ([]string, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s LIMIT 0", t.name))
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return []string{fmt.Sprintf("table %s is missing or unreadable: %v", t.name, err)}, nil
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	have := make(map[string]bool)
+	for _, c := range cols {
+		have[strings.ToLower(c)] = true
+	}
+	var problems []string
+	for _, c := range t.columns {
+		if !have[strings.ToLower(c.name)] {
+			problems = append(problems, fmt.Sprintf("table %s is missing column %s", t.name, c.name))
+		}
+	}
+	return problems, nil
+}
+
+// checkTypesAndIndexes returns the columns of the schema whose types don't
+// match, and the indexes which are missing or have other columns, according
+// to the information_schema of a MySQL database.
+func checkTypesAndIndexes(ctx context.Context, db *sql.DB) ([]string, error) {
+	type column struct{ dataType, columnType string }
+	columns := make(map[string]column)
+	rows, err := db.QueryContext(ctx, `
+		SELECT TABLE_NAME, COLUMN_NAME, DATA_TYPE, COLUMN_TYPE
+		FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE()`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table, name string
+		var c column
+		if err := rows.Scan(&table, &name, &c.dataType, &c.columnType); err != nil {
+			return nil, err
+		}
+		columns[strings.ToLower(table+"."+name)] = c
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	indexes := make(map[string][]string)
+	rows, err = db.QueryContext(ctx, `
+		SELECT TABLE_NAME, INDEX_NAME, COLUMN_NAME
+		FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = DATABASE()
+		ORDER BY TABLE_NAME, INDEX_NAME, SEQ_IN_INDEX`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table, index, column string
+		if err := rows.Scan(&table, &index, &column); err != nil {
+			return nil, err
+		}
+		key := strings.ToLower(table + "." + index)
+		indexes[key] = append(indexes[key], strings.ToLower(column))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	for _, t := range schema {
+		for _, want := range t.columns {
+			got := columns[strings.ToLower(t.name+"."+want.name)]
+			if !strings.EqualFold(got.dataType, want.dataType) {
+				problems = append(problems, fmt.Sprintf("column %s.%s has type %s, want %s", t.name, want.name, got.dataType, want.dataType))
+				continue
+			}
+			for _, v := range want.enumValues {
+				if !strings.Contains(got.columnType, "'"+v+"'") {
+					problems = append(problems, fmt.Sprintf("column %s.%s of type %s doesn't allow %s", t.name, want.name, got.columnType, v))
+				}
+			}
+		}
+		names := make([]string, 0, len(t.indexes))
+		for name := range t.indexes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			want := t.indexes[name]
+			got := indexes[strings.ToLower(t.name+"."+name)]
+			lower := make([]string, len(want))
+			for i, c := range want {
+				lower[i] = strings.ToLower(c)
+			}
+			if got == nil {
+				problems = append(problems, fmt.Sprintf("table %s is missing index %s on (%s)", t.name, name, strings.Join(want, ", ")))
+			} else if !reflect.DeepEqual(got, lower) {
+				problems = append(problems, fmt.Sprintf("index %s of table %s is on (%s), want (%s)", name, t.name, strings.Join(got, ", "), strings.Join(want, ", ")))
+			}
+		}
+	}
+	return problems, nil
+}
@@ -0,0 +1,171 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"io/ioutil"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/google/trillian/storage/testdb"
+)
+
+func TestCheckSchema(t *testing.T) {
+	if err := CheckSchema(context.Background(), DB); err != nil {
+		t.Errorf("CheckSchema() of the storage.sql schema: %v", err)
+	}
+}
+
+func TestCheckSchema_MissingTablesAndColumns(t *testing.T) {
+	ctx := context.Background()
+	db, err := testdb.New(ctx)
+	if err != nil {
+		t.Fatalf("testdb.New(): %v", err)
+	}
+	defer db.Close()
+	if _, err := db.ExecContext(ctx, "CREATE TABLE Trees(TreeId BIGINT NOT NULL, TreeState VARCHAR(20), PRIMARY KEY(TreeId))"); err != nil {
+		t.Fatalf("CREATE TABLE: %v", err)
+	}
+
+	err = CheckSchema(ctx, db)
+	schemaErr, ok := err.(*SchemaError)
+	if !ok {
+		t.Fatalf("CheckSchema() = %v, want a *SchemaError", err)
+	}
+	for _, want := range []string{
+		"table Trees is missing column Maintenance",
+		"table TreeControl is missing",
+		"table MapHead is missing",
+	} {
+		if !hasProblem(schemaErr, want) {
+			t.Errorf("CheckSchema() problems = %q, want one starting with %q", schemaErr.Problems, want)
+		}
+	}
+	if hasProblem(schemaErr, "table Trees is missing column TreeState") {
+		t.Errorf("CheckSchema() reported existing column TreeState as missing")
+	}
+}
+
+func TestCheckSchema_TypesAndIndexes(t *testing.T) {
+	provider := testdb.Default()
+	if !provider.IsMySQL() {
+		t.Skipf("Types and indexes are only checked on MySQL, not on SQL driver: %q", provider.Driver)
+	}
+	ctx := context.Background()
+	db, err := provider.NewTrillianDB(ctx)
+	if err != nil {
+		t.Fatalf("NewTrillianDB(): %v", err)
+	}
+	defer db.Close()
+	for _, stmt := range []string{
+		"ALTER TABLE Trees MODIFY TreeType ENUM('LOG', 'MAP') NOT NULL",
+		"ALTER TABLE TreeHead DROP INDEX TreeHeadRevisionIdx",
+		"ALTER TABLE TreeControl MODIFY SequenceIntervalSeconds VARCHAR(20) NOT NULL",
+	} {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("%v: %v", stmt, err)
+		}
+	}
+
+	err = CheckSchema(ctx, db)
+	schemaErr, ok := err.(*SchemaError)
+	if !ok {
+		t.Fatalf("CheckSchema() = %v, want a *SchemaError", err)
+	}
+	for _, want := range []string{
+		"column Trees.TreeType of type enum('LOG','MAP') doesn't allow PREORDERED_LOG",
+		"table TreeHead is missing index TreeHeadRevisionIdx",
+		"column TreeControl.SequenceIntervalSeconds has type varchar, want int",
+	} {
+		if !hasProblem(schemaErr, want) {
+			t.Errorf("CheckSchema() problems = %q, want one starting with %q", schemaErr.Problems, want)
+		}
+	}
+}
+
+func hasProblem(err *SchemaError, prefix string) bool {
+	for _, p := range err.Problems {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCreateSchema(t *testing.T) {
+	ctx := context.Background()
+	provider := testdb.Default()
+	script, err := ioutil.ReadFile("storage.sql")
+	if err != nil {
+		t.Fatalf("ReadFile(): %v", err)
+	}
+	if !provider.IsMySQL() {
+		// As in testdb, SQLite has no ENUM type.
+		script = regexp.MustCompile(`ENUM\(.+\)`).ReplaceAll(script, []byte("VARCHAR(50)"))
+	}
+
+	db, err := provider.New(ctx)
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	defer db.Close()
+	if err := CreateSchema(ctx, db, string(script)); err != nil {
+		t.Fatalf("CreateSchema() on an empty database: %v", err)
+	}
+	if err := CreateSchema(ctx, db, string(script)); err != ErrSchemaExists {
+		t.Errorf("CreateSchema() on a created database: %v, want %v", err, ErrSchemaExists)
+	}
+}
+
+func TestCreateSchema_PartialSchema(t *testing.T) {
+	ctx := context.Background()
+	db, err := testdb.New(ctx)
+	if err != nil {
+		t.Fatalf("testdb.New(): %v", err)
+	}
+	defer db.Close()
+	if _, err := db.ExecContext(ctx, "CREATE TABLE MapHead(TreeId BIGINT NOT NULL)"); err != nil {
+		t.Fatalf("CREATE TABLE: %v", err)
+	}
+	script := "CREATE TABLE Trees(TreeId BIGINT NOT NULL);"
+	if err := CreateSchema(ctx, db, script); err != ErrSchemaExists {
+		t.Errorf("CreateSchema() = %v, want %v", err, ErrSchemaExists)
+	}
+	if _, err := db.ExecContext(ctx, "SELECT * FROM Trees"); err == nil {
+		t.Error("CreateSchema() created table Trees in a database with other Trillian tables")
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	script := `# A comment
+-- Another; comment
+CREATE TABLE A(
+  Id BIGINT -- trailing comments are kept
+);
+
+  CREATE INDEX AIdx
+  ON A(Id);
+`
+	want := []string{
+		"CREATE TABLE A(\n  Id BIGINT -- trailing comments are kept\n)",
+		"CREATE INDEX AIdx\n  ON A(Id)",
+	}
+	if got := splitStatements(script); !reflect.DeepEqual(got, want) {
+		t.Errorf("splitStatements() = %q, want %q", got, want)
+	}
+}
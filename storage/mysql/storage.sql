@@ -12,7 +12,7 @@ CREATE TABLE IF NOT EXISTS Trees(
   TreeType              ENUM('LOG', 'MAP', 'PREORDERED_LOG') NOT NULL,
   HashStrategy          ENUM('RFC6962_SHA256', 'TEST_MAP_HASHER', 'OBJECT_RFC6962_SHA256', 'CONIKS_SHA512_256') NOT NULL,
   HashAlgorithm         ENUM('SHA256') NOT NULL,
-  SignatureAlgorithm    ENUM('ECDSA', 'RSA') NOT NULL,
+  SignatureAlgorithm    ENUM('ECDSA', 'RSA', 'ED25519') NOT NULL,
   DisplayName           VARCHAR(20),
   Description           VARCHAR(200),
   CreateTimeMillis      BIGINT NOT NULL,
@@ -22,6 +22,26 @@ CREATE TABLE IF NOT EXISTS Trees(
   PublicKey             MEDIUMBLOB NOT NULL,
   Deleted               BOOLEAN,
   DeleteTimeMillis      BIGINT,
+  -- The serialized Any of the tree's storage_settings, if any.
+  StorageSettings       MEDIUMBLOB,
+  -- NULL for trees created before the column existed, which use nanoseconds.
+  RootTimestampPrecision ENUM('NANOSECOND_PRECISION', 'MILLISECOND_PRECISION', 'SECOND_PRECISION'),
+  -- The tree's labels as a JSON object, if any.
+  Labels                TEXT,
+  -- The tree's leaf indexes as a JSON array, if any.
+  LeafIndexes           TEXT,
+  -- The tree's leaf filter spec as a JSON object, if any.
+  LeafFilter            TEXT,
+  -- The tree's maintenance mode as a JSON object, if it's in maintenance.
+  Maintenance           TEXT,
+  -- The tree's additional signing keys as a JSON array, if any.
+  AdditionalSigningKeys TEXT,
+  -- The tree's maximum merge delay, if merge delays are tracked.
+  MaxMergeDelayMillis   BIGINT,
+  -- The window of time during which the tree accepts leaves as JSON, if any.
+  ValidityWindow        TEXT,
+  -- NULL for trees created before the column existed, which aren't verified.
+  WriteVerification     ENUM('UNKNOWN_WRITE_VERIFICATION', 'VERIFY_WRITES', 'SKIP_WRITE_VERIFICATION'),
   PRIMARY KEY(TreeId)
 );
 
@@ -54,6 +74,12 @@ CREATE TABLE IF NOT EXISTS TreeHead(
   RootHash             VARBINARY(255) NOT NULL,
   RootSignature        VARBINARY(1024) NOT NULL,
   TreeRevision         BIGINT,
+  -- The signatures of the root by the tree's additional signing keys as a JSON
+  -- array, if any.
+  AdditionalSignatures TEXT,
+  -- The metadata signed in the root as a serialized google.protobuf.Any, if
+  -- any.
+  Metadata             MEDIUMBLOB,
   PRIMARY KEY(TreeId, TreeHeadTimestamp),
   FOREIGN KEY(TreeId) REFERENCES Trees(TreeId) ON DELETE CASCADE
 );
@@ -61,6 +87,23 @@ CREATE TABLE IF NOT EXISTS TreeHead(
 CREATE UNIQUE INDEX TreeHeadRevisionIdx
   ON TreeHead(TreeId, TreeRevision);
 
+-- SequencingEvents holds a compact event for each root stored for a log,
+-- written in the same transaction as the root. Change data capture pipelines,
+-- e.g. Debezium following the binlog, can use it to follow the growth of logs:
+-- the leaves integrated at TreeRevision are those with sequence numbers in
+-- [StartSize, TreeSize). Rows are only written when the log server is run with
+-- --mysql_sequencing_events, and are never deleted by Trillian.
+CREATE TABLE IF NOT EXISTS SequencingEvents(
+  TreeId               BIGINT NOT NULL,
+  TreeRevision         BIGINT NOT NULL,
+  StartSize            BIGINT NOT NULL,
+  TreeSize             BIGINT NOT NULL,
+  RootHash             VARBINARY(255) NOT NULL,
+  TreeHeadTimestamp    BIGINT NOT NULL,
+  PRIMARY KEY(TreeId, TreeRevision),
+  FOREIGN KEY(TreeId) REFERENCES Trees(TreeId) ON DELETE CASCADE
+);
+
 -- ---------------------------------------------
 -- Log specific stuff here
 -- ---------------------------------------------
@@ -111,6 +154,44 @@ CREATE TABLE IF NOT EXISTS SequencedLeafData(
   FOREIGN KEY(TreeId, LeafIdentityHash) REFERENCES LeafData(TreeId, LeafIdentityHash) ON DELETE CASCADE
 );
 
+-- The keys of the secondary leaf indexes of logs, as defined by the
+-- LeafIndexes of their trees. A row is added for each key of a leaf when the
+-- leaf is sequenced. Keys are stored as SHA-256 hashes, as they may be too
+-- long for the primary key.
+CREATE TABLE IF NOT EXISTS LeafIndexKeys(
+  TreeId               BIGINT NOT NULL,
+  IndexName            VARCHAR(63) NOT NULL,
+  KeyHash              VARBINARY(32) NOT NULL,
+  SequenceNumber       BIGINT UNSIGNED NOT NULL,
+  PRIMARY KEY(TreeId, IndexName, KeyHash, SequenceNumber),
+  FOREIGN KEY(TreeId) REFERENCES Trees(TreeId) ON DELETE CASCADE
+);
+
+-- The blocks of the leaf filters of logs, as defined by the LeafFilter of
+-- their trees. Blocks which were never written are all zeroes.
+CREATE TABLE IF NOT EXISTS LeafFilterBlocks(
+  TreeId               BIGINT NOT NULL,
+  BlockIndex           BIGINT NOT NULL,
+  Bits                 VARBINARY(512) NOT NULL,
+  PRIMARY KEY(TreeId, BlockIndex),
+  FOREIGN KEY(TreeId) REFERENCES Trees(TreeId) ON DELETE CASCADE
+);
+
+-- The merge delay compliance of logs with a maximum merge delay, in windows
+-- of time, as recorded by the signer. Windows without leaves have no row.
+CREATE TABLE IF NOT EXISTS MergeDelayWindows(
+  TreeId               BIGINT NOT NULL,
+  WindowStartMillis    BIGINT NOT NULL,
+  WindowLengthMillis   BIGINT NOT NULL,
+  MaxMergeDelayMillis  BIGINT NOT NULL,
+  LeafCount            BIGINT NOT NULL,
+  ViolationCount       BIGINT NOT NULL,
+  NearMissCount        BIGINT NOT NULL,
+  MaxDelayMillis       BIGINT NOT NULL,
+  PRIMARY KEY(TreeId, WindowStartMillis),
+  FOREIGN KEY(TreeId) REFERENCES Trees(TreeId) ON DELETE CASCADE
+);
+
 CREATE TABLE IF NOT EXISTS Unsequenced(
   TreeId               BIGINT NOT NULL,
   -- The bucket field is to allow the use of time based ring bucketed schemes if desired. If
@@ -155,9 +236,22 @@ CREATE TABLE IF NOT EXISTS MapHead(
   MapRevision          BIGINT,
   RootSignature        VARBINARY(1024) NOT NULL,
   MapperData           MEDIUMBLOB,
+  -- The signatures of the root by the tree's additional signing keys as a JSON
+  -- array, if any.
+  AdditionalSignatures TEXT,
   PRIMARY KEY(TreeId, MapHeadTimestamp),
   FOREIGN KEY(TreeId) REFERENCES Trees(TreeId) ON DELETE CASCADE
 );
 
 CREATE UNIQUE INDEX MapHeadRevisionIdx
   ON MapHead(TreeId, MapRevision);
+
+-- A row here claims a revision of a map for the transaction writing it, which
+-- keeps the row only if it stores the MapHead of the revision. Concurrent
+-- writers of the same revision conflict on the primary key.
+CREATE TABLE IF NOT EXISTS MapRevisionClaim(
+  TreeId               BIGINT NOT NULL,
+  MapRevision          BIGINT NOT NULL,
+  PRIMARY KEY(TreeId, MapRevision),
+  FOREIGN KEY(TreeId) REFERENCES Trees(TreeId) ON DELETE CASCADE
+);
@@ -26,11 +26,14 @@ import (
 	"testing"
 
 	"github.com/golang/glog"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
 	"github.com/google/trillian"
 	"github.com/google/trillian/crypto/sigpb"
 	"github.com/google/trillian/merkle"
 	"github.com/google/trillian/merkle/rfc6962"
 	"github.com/google/trillian/storage"
+	"github.com/google/trillian/storage/storagepb"
 	"github.com/google/trillian/storage/testdb"
 
 	storageto "github.com/google/trillian/storage/testonly"
@@ -80,26 +83,127 @@ func TestNodeRoundTrip(t *testing.T) {
 // This test ensures that node writes cross subtree boundaries so this edge case in the subtree
 // cache gets exercised. Any tree size > 256 will do this.
 func TestLogNodeRoundTripMultiSubtree(t *testing.T) {
-	cleanTestDB(DB)
-	logID := createLogForTests(DB)
-	s := NewLogStorage(DB, nil)
+	for _, test := range []struct {
+		desc   string
+		strata []int32
+	}{
+		{desc: "defaultStrata"},
+		{desc: "16LevelStrata", strata: []int32{16, 16, 16, 16}},
+		{desc: "mixedStrata", strata: []int32{32, 8, 8, 16}},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			cleanTestDB(DB)
+			tree := *storageto.LogTree
+			if test.strata != nil {
+				settings, err := ptypes.MarshalAny(&storagepb.LogStorageSettings{StrataDepths: test.strata})
+				if err != nil {
+					t.Fatalf("MarshalAny(): %v", err)
+				}
+				tree.StorageSettings = settings
+			}
+			logID := createLogTreeForTests(DB, &tree)
+			s := NewLogStorage(DB, nil)
 
-	const writeRevision = int64(100)
-	nodesToStore, err := createLogNodesForTreeAtSize(871, writeRevision)
-	if err != nil {
-		t.Fatalf("failed to create test tree: %v", err)
+			const writeRevision = int64(100)
+			nodesToStore, err := createLogNodesForTreeAtSize(871, writeRevision)
+			if err != nil {
+				t.Fatalf("failed to create test tree: %v", err)
+			}
+			nodeIDsToRead := make([]storage.NodeID, len(nodesToStore))
+			for i := range nodesToStore {
+				nodeIDsToRead[i] = nodesToStore[i].NodeID
+			}
+
+			{
+				runLogTX(s, logID, t, func(ctx context.Context, tx storage.LogTreeTX) error {
+					forceWriteRevision(writeRevision, tx)
+
+					// Need to read nodes before attempting to write
+					if _, err := tx.GetMerkleNodes(ctx, writeRevision-1, nodeIDsToRead); err != nil {
+						t.Fatalf("Failed to read nodes: %s", err)
+					}
+					if err := tx.SetMerkleNodes(ctx, nodesToStore); err != nil {
+						t.Fatalf("Failed to store nodes: %s", err)
+					}
+					return nil
+				})
+			}
+
+			{
+				runLogTX(s, logID, t, func(ctx context.Context, tx storage.LogTreeTX) error {
+					readNodes, err := tx.GetMerkleNodes(ctx, 100, nodeIDsToRead)
+					if err != nil {
+						t.Fatalf("Failed to retrieve nodes: %s", err)
+					}
+					if err := nodesAreEqual(readNodes, nodesToStore); err != nil {
+						missing, extra := diffNodes(readNodes, nodesToStore)
+						for _, n := range missing {
+							t.Errorf("Missing: %s %s", n.NodeID.String(), n.NodeID.CoordString())
+						}
+						for _, n := range extra {
+							t.Errorf("Extra  : %s %s", n.NodeID.String(), n.NodeID.CoordString())
+						}
+						t.Fatalf("Read back different nodes from the ones stored: %s", err)
+					}
+
+					// The same nodes can be read by their coordinates.
+					coords := make([]storage.NodeCoords, 0, len(nodesToStore))
+					for _, n := range nodesToStore {
+						c, err := storage.CoordsForNodeID(n.NodeID)
+						if err != nil {
+							t.Fatalf("CoordsForNodeID(%v): %v", n.NodeID.CoordString(), err)
+						}
+						coords = append(coords, c)
+					}
+					if _, ok := tx.(storage.CoordNodeReader); !ok {
+						t.Errorf("%T doesn't implement storage.CoordNodeReader", tx)
+					}
+					coordNodes, err := storage.GetNodesByCoords(ctx, tx, 100, coords)
+					if err != nil {
+						t.Fatalf("Failed to retrieve nodes by coordinates: %s", err)
+					}
+					if got, want := len(coordNodes), len(nodesToStore); got != want {
+						t.Fatalf("Read back %d nodes by coordinates, want %d", got, want)
+					}
+					for i, n := range coordNodes {
+						if n.Coords != coords[i] || !bytes.Equal(n.Hash, nodesToStore[i].Hash) {
+							t.Errorf("Read back node %v with hash %x by coordinates, want %v with hash %x", n.Coords, n.Hash, coords[i], nodesToStore[i].Hash)
+						}
+					}
+					return nil
+				})
+			}
+		})
 	}
-	nodeIDsToRead := make([]storage.NodeID, len(nodesToStore))
-	for i := range nodesToStore {
-		nodeIDsToRead[i] = nodesToStore[i].NodeID
+}
+
+func TestLogCompactTree(t *testing.T) {
+	if provider := testdb.Default(); !provider.IsMySQL() {
+		t.Skipf("CompactTree uses MySQL-specific DELETE syntax, unsupported on SQL driver: %q", provider.Driver)
 	}
+	cleanTestDB(DB)
+	logID := createLogForTests(DB)
+	s := NewLogStorage(DB, nil)
+	ctx := context.Background()
 
-	{
+	var nodesToRead []storage.Node
+	for _, v := range []struct {
+		size, revision int64
+	}{
+		{size: 300, revision: 100},
+		{size: 871, revision: 101},
+	} {
+		nodesToStore, err := createLogNodesForTreeAtSize(v.size, v.revision)
+		if err != nil {
+			t.Fatalf("failed to create test tree: %v", err)
+		}
+		nodeIDs := make([]storage.NodeID, len(nodesToStore))
+		for i := range nodesToStore {
+			nodeIDs[i] = nodesToStore[i].NodeID
+		}
 		runLogTX(s, logID, t, func(ctx context.Context, tx storage.LogTreeTX) error {
-			forceWriteRevision(writeRevision, tx)
-
-			// Need to read nodes before attempting to write
-			if _, err := tx.GetMerkleNodes(ctx, writeRevision-1, nodeIDsToRead); err != nil {
+			forceWriteRevision(v.revision, tx)
+			if _, err := tx.GetMerkleNodes(ctx, v.revision-1, nodeIDs); err != nil {
 				t.Fatalf("Failed to read nodes: %s", err)
 			}
 			if err := tx.SetMerkleNodes(ctx, nodesToStore); err != nil {
@@ -107,27 +211,183 @@ func TestLogNodeRoundTripMultiSubtree(t *testing.T) {
 			}
 			return nil
 		})
+		nodesToRead = nodesToStore
 	}
 
-	{
+	compactor := s.(storage.LogCompactor)
+	removed, err := compactor.CompactTree(ctx, logID, 101)
+	if err != nil {
+		t.Fatalf("CompactTree(): %v", err)
+	}
+	if removed == 0 {
+		t.Error("CompactTree(): removed 0 subtree revisions, want > 0")
+	}
+	var stale int
+	if err := DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM Subtree WHERE TreeId = ? AND SubtreeRevision <> 101", logID).Scan(&stale); err != nil {
+		t.Fatalf("Failed to count subtrees: %v", err)
+	}
+	if stale != 0 {
+		t.Errorf("CompactTree() left %d subtree revisions other than 101", stale)
+	}
+
+	nodeIDs := make([]storage.NodeID, len(nodesToRead))
+	for i := range nodesToRead {
+		nodeIDs[i] = nodesToRead[i].NodeID
+	}
+	runLogTX(s, logID, t, func(ctx context.Context, tx storage.LogTreeTX) error {
+		readNodes, err := tx.GetMerkleNodes(ctx, 101, nodeIDs)
+		if err != nil {
+			t.Fatalf("Failed to retrieve nodes: %s", err)
+		}
+		if err := nodesAreEqual(readNodes, nodesToRead); err != nil {
+			t.Fatalf("Read back different nodes after compaction: %s", err)
+		}
+		return nil
+	})
+
+	if removed, err := compactor.CompactTree(ctx, logID, 101); err != nil || removed != 0 {
+		t.Errorf("second CompactTree(): (%d, %v), want (0, nil)", removed, err)
+	}
+}
+
+func TestLogRepairMerkleNodes(t *testing.T) {
+	cleanTestDB(DB)
+	logID := createLogForTests(DB)
+	s := NewLogStorage(DB, nil)
+	ctx := context.Background()
+
+	nodes, err := createLogNodesForTreeAtSize(871, 100)
+	if err != nil {
+		t.Fatalf("failed to create test tree: %v", err)
+	}
+	nodeIDs := make([]storage.NodeID, len(nodes))
+	for i := range nodes {
+		nodeIDs[i] = nodes[i].NodeID
+	}
+	runLogTX(s, logID, t, func(ctx context.Context, tx storage.LogTreeTX) error {
+		forceWriteRevision(100, tx)
+		if _, err := tx.GetMerkleNodes(ctx, 99, nodeIDs); err != nil {
+			t.Fatalf("Failed to read nodes: %s", err)
+		}
+		if err := tx.SetMerkleNodes(ctx, nodes); err != nil {
+			t.Fatalf("Failed to store nodes: %s", err)
+		}
+		return nil
+	})
+
+	// Lose one subtree of the bottom stratum, and empty another one.
+	lost := storage.SubtreeCoords{Stratum: 0, Index: 1}
+	emptied := storage.SubtreeCoords{Stratum: 0, Index: 3}
+	if _, err := DB.ExecContext(ctx, "DELETE FROM Subtree WHERE TreeId = ? AND SubtreeId = ?", logID, lost.Prefix()); err != nil {
+		t.Fatalf("Failed to delete subtree: %v", err)
+	}
+	empty, err := proto.Marshal(&storagepb.SubtreeProto{Prefix: emptied.Prefix(), Depth: storage.LogStratumDepth})
+	if err != nil {
+		t.Fatalf("Failed to marshal subtree: %v", err)
+	}
+	if _, err := DB.ExecContext(ctx, "UPDATE Subtree SET Nodes = ? WHERE TreeId = ? AND SubtreeId = ?", empty, logID, emptied.Prefix()); err != nil {
+		t.Fatalf("Failed to empty subtree: %v", err)
+	}
+	var repair []storage.Node
+	for _, n := range nodes {
+		c, err := storage.CoordsForNodeID(n.NodeID)
+		if err != nil {
+			t.Fatalf("CoordsForNodeID(): %v", err)
+		}
+		if st, _, err := storage.SubtreeFor(c); err == nil && (st == lost || st == emptied) {
+			repair = append(repair, n)
+		}
+	}
+
+	runLogTX(s, logID, t, func(ctx context.Context, tx storage.LogTreeTX) error {
+		readNodes, err := tx.GetMerkleNodes(ctx, 100, nodeIDs)
+		if err != nil {
+			t.Fatalf("Failed to retrieve nodes: %s", err)
+		}
+		if got, want := len(readNodes), len(nodes)-len(repair); got != want {
+			t.Errorf("Read back %d nodes before repair, want %d", got, want)
+		}
+		return nil
+	})
+
+	if err := s.(storage.LogSubtreeRepairer).RepairMerkleNodes(ctx, logID, 100, repair); err != nil {
+		t.Fatalf("RepairMerkleNodes(): %v", err)
+	}
+	runLogTX(s, logID, t, func(ctx context.Context, tx storage.LogTreeTX) error {
+		readNodes, err := tx.GetMerkleNodes(ctx, 100, nodeIDs)
+		if err != nil {
+			t.Fatalf("Failed to retrieve nodes: %s", err)
+		}
+		if err := nodesAreEqual(readNodes, nodes); err != nil {
+			t.Fatalf("Read back different nodes after repair: %s", err)
+		}
+		return nil
+	})
+}
+
+func TestLogLatestSubtreesOnly(t *testing.T) {
+	cleanTestDB(DB)
+	settings, err := ptypes.MarshalAny(&storagepb.LogStorageSettings{SubtreeRevisions: storagepb.LogStorageSettings_LATEST_ONLY})
+	if err != nil {
+		t.Fatalf("Error marshaling proto: %v", err)
+	}
+	tree := *storageto.LogTree
+	tree.StorageSettings = settings
+	logID := createLogTreeForTests(DB, &tree)
+	s := NewLogStorage(DB, nil)
+	ctx := context.Background()
+
+	var nodesToRead []storage.Node
+	for _, v := range []struct {
+		size, revision int64
+	}{
+		{size: 300, revision: 100},
+		{size: 871, revision: 101},
+		{size: 872, revision: 102},
+	} {
+		nodesToStore, err := createLogNodesForTreeAtSize(v.size, v.revision)
+		if err != nil {
+			t.Fatalf("failed to create test tree: %v", err)
+		}
+		nodeIDs := make([]storage.NodeID, len(nodesToStore))
+		for i := range nodesToStore {
+			nodeIDs[i] = nodesToStore[i].NodeID
+		}
 		runLogTX(s, logID, t, func(ctx context.Context, tx storage.LogTreeTX) error {
-			readNodes, err := tx.GetMerkleNodes(ctx, 100, nodeIDsToRead)
-			if err != nil {
-				t.Fatalf("Failed to retrieve nodes: %s", err)
+			forceWriteRevision(v.revision, tx)
+			if _, err := tx.GetMerkleNodes(ctx, v.revision-1, nodeIDs); err != nil {
+				t.Fatalf("Failed to read nodes: %s", err)
 			}
-			if err := nodesAreEqual(readNodes, nodesToStore); err != nil {
-				missing, extra := diffNodes(readNodes, nodesToStore)
-				for _, n := range missing {
-					t.Errorf("Missing: %s %s", n.NodeID.String(), n.NodeID.CoordString())
-				}
-				for _, n := range extra {
-					t.Errorf("Extra  : %s %s", n.NodeID.String(), n.NodeID.CoordString())
-				}
-				t.Fatalf("Read back different nodes from the ones stored: %s", err)
+			if err := tx.SetMerkleNodes(ctx, nodesToStore); err != nil {
+				t.Fatalf("Failed to store nodes: %s", err)
 			}
 			return nil
 		})
+		nodesToRead = nodesToStore
+	}
+
+	var revisions, subtrees int
+	if err := DB.QueryRowContext(ctx, "SELECT COUNT(*), COUNT(DISTINCT SubtreeId) FROM Subtree WHERE TreeId = ?", logID).Scan(&revisions, &subtrees); err != nil {
+		t.Fatalf("Failed to count subtrees: %v", err)
+	}
+	if revisions != subtrees {
+		t.Errorf("Stored %d revisions of %d subtrees, want one per subtree", revisions, subtrees)
+	}
+
+	nodeIDs := make([]storage.NodeID, len(nodesToRead))
+	for i := range nodesToRead {
+		nodeIDs[i] = nodesToRead[i].NodeID
 	}
+	runLogTX(s, logID, t, func(ctx context.Context, tx storage.LogTreeTX) error {
+		readNodes, err := tx.GetMerkleNodes(ctx, 102, nodeIDs)
+		if err != nil {
+			t.Fatalf("Failed to retrieve nodes: %s", err)
+		}
+		if err := nodesAreEqual(readNodes, nodesToRead); err != nil {
+			t.Fatalf("Read back different nodes: %s", err)
+		}
+		return nil
+	})
 }
 
 func forceWriteRevision(rev int64, tx storage.TreeTX) {
@@ -241,7 +501,13 @@ func createMapForTests(db *sql.DB) int64 {
 
 // createLogForTests creates a log-type tree for tests. Returns the treeID of the new tree.
 func createLogForTests(db *sql.DB) int64 {
-	tree, err := createTree(db, storageto.LogTree)
+	return createLogTreeForTests(db, storageto.LogTree)
+}
+
+// createLogTreeForTests creates the specified log, and initializes it with an
+// empty root.
+func createLogTreeForTests(db *sql.DB, tree *trillian.Tree) int64 {
+	tree, err := createTree(db, tree)
 	if err != nil {
 		panic(fmt.Sprintf("Error creating log: %v", err))
 	}
@@ -19,23 +19,30 @@ import (
 	"context"
 	"database/sql"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"runtime/debug"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/golang/glog"
 	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/any"
 	"github.com/google/trillian/storage"
 	"github.com/google/trillian/storage/cache"
 	"github.com/google/trillian/storage/storagepb"
+	"github.com/google/trillian/util"
+	"github.com/google/trillian/util/requestinfo"
+
+	spb "github.com/google/trillian/crypto/sigpb"
 )
 
 // These statements are fixed
 const (
 	insertSubtreeMultiSQL = `INSERT INTO Subtree(TreeId, SubtreeId, Nodes, SubtreeRevision) ` + placeholderSQL
-	insertTreeHeadSQL     = `INSERT INTO TreeHead(TreeId,TreeHeadTimestamp,TreeSize,RootHash,TreeRevision,RootSignature)
-		 VALUES(?,?,?,?,?,?)`
+	insertTreeHeadSQL     = `INSERT INTO TreeHead(TreeId,TreeHeadTimestamp,TreeSize,RootHash,TreeRevision,RootSignature,AdditionalSignatures,Metadata)
+		 VALUES(?,?,?,?,?,?,?,?)`
 	selectNonDeletedTreeIDByTypeAndStateSQL = "SELECT TreeId FROM Trees WHERE TreeType = ? AND TreeState = ? AND (Deleted IS NULL OR Deleted = 'false')"
 	selectTreeRevisionAtSizeOrLargerSQL     = "SELECT TreeRevision,TreeSize FROM TreeHead WHERE TreeId=? AND TreeSize>=? ORDER BY TreeRevision LIMIT 1"
 
@@ -53,6 +60,12 @@ const (
  AND Subtree.SubtreeRevision = x.MaxRevision 
  AND Subtree.TreeId = ?`
 	placeholderSQL = "<placeholder>"
+
+	selectSubtreeCountSQL = "SELECT COUNT(*) FROM Subtree WHERE TreeId=?"
+
+	// deleteSubtreeRevisionsSQL removes the earlier revisions of subtrees which
+	// are about to be rewritten, for trees which keep only the latest one.
+	deleteSubtreeRevisionsSQL = `DELETE FROM Subtree WHERE TreeId = ? AND SubtreeRevision < ? AND SubtreeId IN (` + placeholderSQL + `)`
 )
 
 // mySQLTreeStorage is shared between the mySQLLog- and (forthcoming) mySQLMap-
@@ -65,11 +78,64 @@ type mySQLTreeStorage struct {
 	// this will be a short time. These maps are from the number of placeholder '?'
 	// in the query to the statement that should be used.
 	statementMutex sync.Mutex
-	statements     map[string]map[int]*sql.Stmt
+	statements     map[string]map[int]*cachedStmt
+	// stmtUses is a logical clock used to find the least recently used
+	// statement when a per-statement cache is full.
+	stmtUses uint64
+	opts     TreeStorageOptions
+
+	// activity counts the transactions started on each tree.
+	activity *storage.TreeActivity
+}
+
+// cachedStmt is a prepared statement held in the statement cache, along with
+// the value of the stmtUses clock when it was last handed out.
+type cachedStmt struct {
+	stmt     *sql.Stmt
+	lastUsed uint64
+	// users counts the callers of getStmt which haven't released the
+	// statement yet, and evicted is set once it's removed from the cache. It's
+	// closed when both are the case. Both are guarded by statementMutex.
+	users   int
+	evicted bool
+}
+
+// TreeStorageOptions holds tuning parameters shared by the MySQL log and map
+// storage implementations. The zero value preserves the default behaviour.
+type TreeStorageOptions struct {
+	// MaxCachedStatements bounds the number of prepared statements kept for
+	// each distinct SQL statement (which are prepared once per number of
+	// placeholder arguments). When the limit is reached the least recently
+	// used statement is closed. Zero or less means the cache is unbounded.
+	MaxCachedStatements int
+	// SequencingEvents makes log storage insert a row into SequencingEvents
+	// with each new root, for change data capture pipelines to follow the
+	// growth of logs through the binlog. The table must exist.
+	SequencingEvents bool
+}
+
+// DBOptions configures the connection pool of a database opened by
+// OpenDBWithOptions. Zero values leave the corresponding database/sql
+// default in place.
+type DBOptions struct {
+	// MaxOpenConns is the maximum number of open connections to the database.
+	MaxOpenConns int
+	// MaxIdleConns is the maximum number of connections kept in the idle
+	// pool. Negative values disable idle connection retention.
+	MaxIdleConns int
+	// ConnMaxLifetime is the maximum amount of time a connection may be
+	// reused for.
+	ConnMaxLifetime time.Duration
 }
 
 // OpenDB opens a database connection for all MySQL-based storage implementations.
 func OpenDB(dbURL string) (*sql.DB, error) {
+	return OpenDBWithOptions(dbURL, DBOptions{})
+}
+
+// OpenDBWithOptions opens a database connection for all MySQL-based storage
+// implementations, applying the given connection pool settings.
+func OpenDBWithOptions(dbURL string, opts DBOptions) (*sql.DB, error) {
 	db, err := sql.Open("mysql", dbURL)
 	if err != nil {
 		// Don't log uri as it could contain credentials
@@ -77,6 +143,16 @@ func OpenDB(dbURL string) (*sql.DB, error) {
 		return nil, err
 	}
 
+	if opts.MaxOpenConns != 0 {
+		db.SetMaxOpenConns(opts.MaxOpenConns)
+	}
+	if opts.MaxIdleConns != 0 {
+		db.SetMaxIdleConns(opts.MaxIdleConns)
+	}
+	if opts.ConnMaxLifetime != 0 {
+		db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	}
+
 	if _, err := db.ExecContext(context.TODO(), "SET sql_mode = 'STRICT_ALL_TABLES'"); err != nil {
 		glog.Warningf("Failed to set strict mode on mysql db: %s", err)
 		return nil, err
@@ -85,13 +161,47 @@ func OpenDB(dbURL string) (*sql.DB, error) {
 	return db, nil
 }
 
-func newTreeStorage(db *sql.DB) *mySQLTreeStorage {
+func newTreeStorage(db *sql.DB, opts TreeStorageOptions) *mySQLTreeStorage {
 	return &mySQLTreeStorage{
 		db:         db,
-		statements: make(map[string]map[int]*sql.Stmt),
+		statements: make(map[string]map[int]*cachedStmt),
+		opts:       opts,
+		activity:   storage.NewTreeActivity(util.SystemTimeSource{}),
+	}
+}
+
+// recordActivity counts a transaction on treeID for its TreeStats.
+func (m *mySQLTreeStorage) recordActivity(treeID int64, readonly bool) {
+	if readonly {
+		m.activity.Read(treeID)
+	} else {
+		m.activity.Write(treeID)
 	}
 }
 
+// getTreeStats returns the statistics of a tree, counting its leaves, leaf
+// bytes and revisions with the given queries, which take the tree ID as their
+// only argument.
+func (m *mySQLTreeStorage) getTreeStats(ctx context.Context, treeID int64, leafCountSQL, leafBytesSQL, revisionCountSQL string) (*storage.TreeStats, error) {
+	stats := &storage.TreeStats{}
+	for _, q := range []struct {
+		sql  string
+		dest *int64
+	}{
+		{sql: leafCountSQL, dest: &stats.LeafCount},
+		{sql: leafBytesSQL, dest: &stats.LeafBytes},
+		{sql: selectSubtreeCountSQL, dest: &stats.SubtreeCount},
+		{sql: revisionCountSQL, dest: &stats.RevisionCount},
+	} {
+		if err := m.db.QueryRowContext(ctx, q.sql, treeID).Scan(q.dest); err != nil {
+			requestinfo.Warningf(ctx, "Failed to read stats of tree %v: %s", treeID, err)
+			return nil, err
+		}
+	}
+	stats.ReadQPS, stats.WriteQPS = m.activity.QPS(treeID)
+	return stats, nil
+}
+
 // expandPlaceholderSQL expands an sql statement by adding a specified number of '?'
 // placeholder slots. At most one placeholder will be expanded.
 func expandPlaceholderSQL(sql string, num int, first, rest string) string {
@@ -105,47 +215,103 @@ func expandPlaceholderSQL(sql string, num int, first, rest string) string {
 }
 
 // getStmt creates and caches sql.Stmt structs based on the passed in statement
-// and number of bound arguments.
+// and number of bound arguments. The returned function must be called once the
+// caller is done with the statement, which is usually after binding it to a
+// transaction with StmtContext, and no later than when the transaction ends.
 // TODO(al,martin): consider pulling this all out as a separate unit for reuse
 // elsewhere.
-func (m *mySQLTreeStorage) getStmt(ctx context.Context, statement string, num int, first, rest string) (*sql.Stmt, error) {
+func (m *mySQLTreeStorage) getStmt(ctx context.Context, statement string, num int, first, rest string) (*sql.Stmt, func(), error) {
 	m.statementMutex.Lock()
 	defer m.statementMutex.Unlock()
+	m.stmtUses++
 
 	if m.statements[statement] != nil {
-		if m.statements[statement][num] != nil {
+		if cs := m.statements[statement][num]; cs != nil {
 			// TODO(al,martin): we'll possibly need to expire Stmts from the cache,
 			// e.g. when DB connections break etc.
-			return m.statements[statement][num], nil
+			cs.lastUsed = m.stmtUses
+			return cs.stmt, m.acquireStmt(cs), nil
 		}
 	} else {
-		m.statements[statement] = make(map[int]*sql.Stmt)
+		m.statements[statement] = make(map[int]*cachedStmt)
 	}
 
 	s, err := m.db.PrepareContext(ctx, expandPlaceholderSQL(statement, num, first, rest))
 
 	if err != nil {
-		glog.Warningf("Failed to prepare statement %d: %s", num, err)
-		return nil, err
+		requestinfo.Warningf(ctx, "Failed to prepare statement %d: %s", num, err)
+		return nil, nil, err
+	}
+
+	if max := m.opts.MaxCachedStatements; max > 0 && len(m.statements[statement]) >= max {
+		m.evictStmt(statement)
+	}
+	cs := &cachedStmt{stmt: s, lastUsed: m.stmtUses}
+	m.statements[statement][num] = cs
+
+	return s, m.acquireStmt(cs), nil
+}
+
+// acquireStmt records a new user of cs, and returns the function releasing
+// it. The caller must hold statementMutex.
+func (m *mySQLTreeStorage) acquireStmt(cs *cachedStmt) func() {
+	cs.users++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.statementMutex.Lock()
+			defer m.statementMutex.Unlock()
+			cs.users--
+			if cs.evicted && cs.users == 0 {
+				closeStmt(cs)
+			}
+		})
 	}
+}
 
-	m.statements[statement][num] = s
+// evictStmt removes the least recently used prepared statement cached for the
+// given SQL statement, and closes it unless it has users, in which case the
+// last of them closes it on release. The caller must hold statementMutex.
+func (m *mySQLTreeStorage) evictStmt(statement string) {
+	victim, oldest := -1, uint64(0)
+	for num, cs := range m.statements[statement] {
+		if victim == -1 || cs.lastUsed < oldest {
+			victim, oldest = num, cs.lastUsed
+		}
+	}
+	if victim == -1 {
+		return
+	}
+	cs := m.statements[statement][victim]
+	delete(m.statements[statement], victim)
+	cs.evicted = true
+	if cs.users == 0 {
+		closeStmt(cs)
+	}
+}
 
-	return s, nil
+func closeStmt(cs *cachedStmt) {
+	if err := cs.stmt.Close(); err != nil {
+		glog.Warningf("Failed to close evicted statement: %s", err)
+	}
 }
 
-func (m *mySQLTreeStorage) getSubtreeStmt(ctx context.Context, num int) (*sql.Stmt, error) {
+func (m *mySQLTreeStorage) getSubtreeStmt(ctx context.Context, num int) (*sql.Stmt, func(), error) {
 	return m.getStmt(ctx, selectSubtreeSQL, num, "?", "?")
 }
 
-func (m *mySQLTreeStorage) setSubtreeStmt(ctx context.Context, num int) (*sql.Stmt, error) {
+func (m *mySQLTreeStorage) setSubtreeStmt(ctx context.Context, num int) (*sql.Stmt, func(), error) {
 	return m.getStmt(ctx, insertSubtreeMultiSQL, num, "VALUES(?, ?, ?, ?)", "(?, ?, ?, ?)")
 }
 
+func (m *mySQLTreeStorage) deleteSubtreeRevisionsStmt(ctx context.Context, num int) (*sql.Stmt, func(), error) {
+	return m.getStmt(ctx, deleteSubtreeRevisionsSQL, num, "?", "?")
+}
+
 func (m *mySQLTreeStorage) beginTreeTx(ctx context.Context, treeID int64, hashSizeBytes int, subtreeCache cache.SubtreeCache) (treeTX, error) {
 	t, err := m.db.BeginTx(ctx, nil /* opts */)
 	if err != nil {
-		glog.Warningf("Could not start tree TX: %s", err)
+		requestinfo.Warningf(ctx, "Could not start tree TX: %s", err)
 		return treeTX{}, err
 	}
 	return treeTX{
@@ -166,6 +332,9 @@ type treeTX struct {
 	hashSizeBytes int
 	subtreeCache  cache.SubtreeCache
 	writeRevision int64
+	// latestSubtreesOnly is set if the tree keeps only the latest revision of
+	// each subtree.
+	latestSubtreesOnly bool
 }
 
 func (t *treeTX) getSubtree(ctx context.Context, treeRevision int64, nodeID storage.NodeID) (*storagepb.SubtreeProto, error) {
@@ -189,10 +358,11 @@ func (t *treeTX) getSubtrees(ctx context.Context, treeRevision int64, nodeIDs []
 		return nil, nil
 	}
 
-	tmpl, err := t.ts.getSubtreeStmt(ctx, len(nodeIDs))
+	tmpl, release, err := t.ts.getSubtreeStmt(ctx, len(nodeIDs))
 	if err != nil {
 		return nil, err
 	}
+	defer release()
 	stx := t.tx.StmtContext(ctx, tmpl)
 	defer stx.Close()
 
@@ -216,14 +386,14 @@ func (t *treeTX) getSubtrees(ctx context.Context, treeRevision int64, nodeIDs []
 
 	rows, err := stx.QueryContext(ctx, args...)
 	if err != nil {
-		glog.Warningf("Failed to get merkle subtrees: %s", err)
+		requestinfo.Warningf(ctx, "Failed to get merkle subtrees: %s", err)
 		return nil, err
 	}
 	defer rows.Close()
 
 	if rows.Err() != nil {
 		// Nothing from the DB
-		glog.Warningf("Nothing from DB: %s", rows.Err())
+		requestinfo.Warningf(ctx, "Nothing from DB: %s", rows.Err())
 		return nil, rows.Err()
 	}
 
@@ -235,12 +405,12 @@ func (t *treeTX) getSubtrees(ctx context.Context, treeRevision int64, nodeIDs []
 		var subtreeRev int64
 		var nodesRaw []byte
 		if err := rows.Scan(&subtreeIDBytes, &subtreeRev, &nodesRaw); err != nil {
-			glog.Warningf("Failed to scan merkle subtree: %s", err)
+			requestinfo.Warningf(ctx, "Failed to scan merkle subtree: %s", err)
 			return nil, err
 		}
 		var subtree storagepb.SubtreeProto
 		if err := proto.Unmarshal(nodesRaw, &subtree); err != nil {
-			glog.Warningf("Failed to unmarshal SubtreeProto: %s", err)
+			requestinfo.Warningf(ctx, "Failed to unmarshal SubtreeProto: %s", err)
 			return nil, err
 		}
 		if subtree.Prefix == nil {
@@ -249,14 +419,14 @@ func (t *treeTX) getSubtrees(ctx context.Context, treeRevision int64, nodeIDs []
 		ret = append(ret, &subtree)
 
 		if glog.V(4) {
-			glog.Infof("  subtree: NID: %x, prefix: %x, depth: %d",
+			requestinfo.Infof(ctx, "  subtree: NID: %x, prefix: %x, depth: %d",
 				subtreeIDBytes, subtree.Prefix, subtree.Depth)
 			for k, v := range subtree.Leaves {
 				b, err := base64.StdEncoding.DecodeString(k)
 				if err != nil {
-					glog.Errorf("base64.DecodeString(%v): %v", k, err)
+					requestinfo.Errorf(ctx, "base64.DecodeString(%v): %v", k, err)
 				}
-				glog.Infof("     %x: %x", b, v)
+				requestinfo.Infof(ctx, "     %x: %x", b, v)
 			}
 		}
 	}
@@ -268,15 +438,15 @@ func (t *treeTX) getSubtrees(ctx context.Context, treeRevision int64, nodeIDs []
 
 func (t *treeTX) storeSubtrees(ctx context.Context, subtrees []*storagepb.SubtreeProto) error {
 	if glog.V(4) {
-		glog.Infof("storeSubtrees(")
+		requestinfo.Infof(ctx, "storeSubtrees(")
 		for _, s := range subtrees {
-			glog.Infof("  prefix: %x, depth: %d", s.Prefix, s.Depth)
+			requestinfo.Infof(ctx, "  prefix: %x, depth: %d", s.Prefix, s.Depth)
 			for k, v := range s.Leaves {
 				b, err := base64.StdEncoding.DecodeString(k)
 				if err != nil {
-					glog.Errorf("base64.DecodeString(%v): %v", k, err)
+					requestinfo.Errorf(ctx, "base64.DecodeString(%v): %v", k, err)
 				}
-				glog.Infof("     %x: %x", b, v)
+				requestinfo.Infof(ctx, "     %x: %x", b, v)
 			}
 		}
 	}
@@ -304,22 +474,53 @@ func (t *treeTX) storeSubtrees(ctx context.Context, subtrees []*storagepb.Subtre
 		args = append(args, t.writeRevision)
 	}
 
-	tmpl, err := t.ts.setSubtreeStmt(ctx, len(subtrees))
+	if t.latestSubtreesOnly {
+		if err := t.deleteSubtreeRevisions(ctx, subtrees); err != nil {
+			return err
+		}
+	}
+
+	tmpl, release, err := t.ts.setSubtreeStmt(ctx, len(subtrees))
 	if err != nil {
 		return err
 	}
+	defer release()
 	stx := t.tx.StmtContext(ctx, tmpl)
 	defer stx.Close()
 
 	r, err := stx.ExecContext(ctx, args...)
 	if err != nil {
-		glog.Warningf("Failed to set merkle subtrees: %s", err)
+		requestinfo.Warningf(ctx, "Failed to set merkle subtrees: %s", err)
 		return err
 	}
 	_, _ = r.RowsAffected()
 	return nil
 }
 
+// deleteSubtreeRevisions removes the revisions of subtrees written before the
+// current transaction, so that only the revisions about to be written remain.
+func (t *treeTX) deleteSubtreeRevisions(ctx context.Context, subtrees []*storagepb.SubtreeProto) error {
+	args := make([]interface{}, 0, len(subtrees)+2)
+	args = append(args, t.treeID, t.writeRevision)
+	for _, s := range subtrees {
+		args = append(args, s.Prefix)
+	}
+
+	tmpl, release, err := t.ts.deleteSubtreeRevisionsStmt(ctx, len(subtrees))
+	if err != nil {
+		return err
+	}
+	defer release()
+	stx := t.tx.StmtContext(ctx, tmpl)
+	defer stx.Close()
+
+	if _, err := stx.ExecContext(ctx, args...); err != nil {
+		requestinfo.Warningf(ctx, "Failed to delete earlier subtree revisions: %s", err)
+		return err
+	}
+	return nil
+}
+
 func checkResultOkAndRowCountIs(res sql.Result, err error, count int64) error {
 	// The Exec() might have just failed
 	if err != nil {
@@ -422,3 +623,54 @@ func (t *treeTX) Close() error {
 func (t *treeTX) IsOpen() bool {
 	return !t.closed
 }
+
+// marshalAdditionalSignatures returns the value of the AdditionalSignatures
+// column of a root signed with sigs, which is NULL if there are none.
+func marshalAdditionalSignatures(sigs []*spb.DigitallySigned) (interface{}, error) {
+	if len(sigs) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(sigs)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal AdditionalSignatures: %v", err)
+	}
+	return string(b), nil
+}
+
+// unmarshalAdditionalSignatures returns the signatures in the
+// AdditionalSignatures column of a root.
+func unmarshalAdditionalSignatures(col sql.NullString) ([]*spb.DigitallySigned, error) {
+	if !col.Valid || col.String == "" {
+		return nil, nil
+	}
+	var sigs []*spb.DigitallySigned
+	if err := json.Unmarshal([]byte(col.String), &sigs); err != nil {
+		return nil, fmt.Errorf("could not unmarshal AdditionalSignatures: %v", err)
+	}
+	return sigs, nil
+}
+
+// marshalRootMetadata returns the value of the Metadata column of a root with
+// metadata meta, which is NULL if it has none.
+func marshalRootMetadata(meta *any.Any) ([]byte, error) {
+	if meta == nil {
+		return nil, nil
+	}
+	b, err := proto.Marshal(meta)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal Metadata: %v", err)
+	}
+	return b, nil
+}
+
+// unmarshalRootMetadata returns the metadata in the Metadata column of a root.
+func unmarshalRootMetadata(col []byte) (*any.Any, error) {
+	if len(col) == 0 {
+		return nil, nil
+	}
+	meta := &any.Any{}
+	if err := proto.Unmarshal(col, meta); err != nil {
+		return nil, fmt.Errorf("could not unmarshal Metadata: %v", err)
+	}
+	return meta, nil
+}
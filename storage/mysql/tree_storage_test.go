@@ -0,0 +1,102 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+const selectTreeIDsSQL = "SELECT TreeId FROM Trees WHERE TreeId IN (" + placeholderSQL + ")"
+
+func TestGetStmtCacheLimit(t *testing.T) {
+	ctx := context.Background()
+
+	for _, test := range []struct {
+		desc     string
+		max      int
+		nums     []int
+		wantNums []int
+	}{
+		{desc: "unbounded", nums: []int{1, 2, 3, 4}, wantNums: []int{1, 2, 3, 4}},
+		{desc: "under limit", max: 4, nums: []int{1, 2, 3}, wantNums: []int{1, 2, 3}},
+		{desc: "evicts oldest", max: 2, nums: []int{1, 2, 3}, wantNums: []int{2, 3}},
+		{desc: "reuse refreshes", max: 2, nums: []int{1, 2, 1, 3}, wantNums: []int{1, 3}},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			m := newTreeStorage(DB, TreeStorageOptions{MaxCachedStatements: test.max})
+			for _, num := range test.nums {
+				stmt, release, err := m.getStmt(ctx, selectTreeIDsSQL, num, "?", "?")
+				if err != nil {
+					t.Fatalf("getStmt(%d): %v", num, err)
+				}
+				if err := queryTreeIDs(ctx, stmt, num); err != nil {
+					t.Fatalf("QueryContext(%d): %v", num, err)
+				}
+				release()
+			}
+
+			cached := m.statements[selectTreeIDsSQL]
+			if got, want := len(cached), len(test.wantNums); got != want {
+				t.Errorf("len(cached statements) = %d, want %d", got, want)
+			}
+			for _, num := range test.wantNums {
+				if cached[num] == nil {
+					t.Errorf("statement with %d placeholders not cached", num)
+				}
+			}
+		})
+	}
+}
+
+func queryTreeIDs(ctx context.Context, stmt *sql.Stmt, num int) error {
+	args := make([]interface{}, num)
+	for i := range args {
+		args[i] = int64(i)
+	}
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+func TestGetStmtEvictionWhileInUse(t *testing.T) {
+	ctx := context.Background()
+	m := newTreeStorage(DB, TreeStorageOptions{MaxCachedStatements: 1})
+
+	stmt, release, err := m.getStmt(ctx, selectTreeIDsSQL, 1, "?", "?")
+	if err != nil {
+		t.Fatalf("getStmt(1): %v", err)
+	}
+	// Evicts the statement which is still in use.
+	_, release2, err := m.getStmt(ctx, selectTreeIDsSQL, 2, "?", "?")
+	if err != nil {
+		t.Fatalf("getStmt(2): %v", err)
+	}
+	defer release2()
+	if m.statements[selectTreeIDsSQL][1] != nil {
+		t.Fatal("statement with 1 placeholder still cached")
+	}
+
+	if err := queryTreeIDs(ctx, stmt, 1); err != nil {
+		t.Errorf("QueryContext() on evicted statement before release: %v", err)
+	}
+	release()
+	if err := queryTreeIDs(ctx, stmt, 1); err == nil {
+		t.Error("QueryContext() on evicted statement after release succeeded, want closed statement error")
+	}
+}
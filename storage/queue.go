@@ -0,0 +1,99 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// QueueSampleOptions specifies what a LeafQueueInspector reports about a queue.
+type QueueSampleOptions struct {
+	// Now is the time which leaf ages are relative to.
+	Now time.Time
+	// OldestLeaves is the number of the oldest queued leaves to return.
+	OldestLeaves int
+	// AgeBounds are the ascending bounds between the age buckets which queued
+	// leaves are counted in. Leaves younger than AgeBounds[0] are counted in
+	// the first bucket, and those at least AgeBounds[len(AgeBounds)-1] old in
+	// the last one.
+	AgeBounds []time.Duration
+}
+
+// Cutoffs returns the queue times matching the AgeBounds of o: leaves queued
+// at or before Cutoffs()[i] are at least AgeBounds[i] old.
+func (o QueueSampleOptions) Cutoffs() []time.Time {
+	cutoffs := make([]time.Time, len(o.AgeBounds))
+	for i, bound := range o.AgeBounds {
+		cutoffs[i] = o.Now.Add(-bound)
+	}
+	return cutoffs
+}
+
+// AgeCounts returns the number of leaves in each age bucket of o, given the
+// total number of queued leaves, and the number of those queued at or before
+// each of the Cutoffs.
+func (o QueueSampleOptions) AgeCounts(total int64, olderThan []int64) []int64 {
+	counts := make([]int64, len(olderThan)+1)
+	prev := total
+	for i, n := range olderThan {
+		counts[i] = prev - n
+		prev = n
+	}
+	counts[len(olderThan)] = prev
+	return counts
+}
+
+// QueuedLeaf is a leaf waiting in the queue of a log to be sequenced.
+type QueuedLeaf struct {
+	LeafIdentityHash []byte
+	MerkleLeafHash   []byte
+	QueueTimestamp   time.Time
+	// Bucket is the queue bucket holding the leaf, zero on storage which
+	// doesn't shard the queue.
+	Bucket int64
+}
+
+// QueueShard is the depth of one bucket of the queue of a log.
+type QueueShard struct {
+	Bucket    int64
+	LeafCount int64
+	// Oldest is the queue time of the oldest leaf in the bucket.
+	Oldest time.Time
+}
+
+// QueueSample describes the queue of leaves waiting to be sequenced into a
+// log. As it may be gathered by several storage reads, its figures needn't be
+// mutually consistent while the queue changes.
+type QueueSample struct {
+	// LeafCount is the number of queued leaves.
+	LeafCount int64
+	// OldestLeaves are the oldest queued leaves, oldest first.
+	OldestLeaves []QueuedLeaf
+	// AgeCounts are the number of queued leaves in each age bucket, youngest
+	// first.
+	AgeCounts []int64
+	// Shards holds the depth of each non-empty bucket of the queue, by
+	// ascending bucket.
+	Shards []QueueShard
+}
+
+// LeafQueueInspector may be implemented by LogStorage implementations which
+// can sample the queues of the logs they store, for diagnosing sequencing
+// stalls.
+type LeafQueueInspector interface {
+	// SampleQueue returns a sample of the queue of the specified log.
+	SampleQueue(ctx context.Context, treeID int64, opts QueueSampleOptions) (*QueueSample, error)
+}
@@ -0,0 +1,46 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestQueueSampleOptions(t *testing.T) {
+	now := time.Unix(1000, 0)
+	opts := QueueSampleOptions{Now: now, AgeBounds: []time.Duration{time.Minute, time.Hour}}
+
+	if got, want := opts.Cutoffs(), []time.Time{now.Add(-time.Minute), now.Add(-time.Hour)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Cutoffs() = %v, want %v", got, want)
+	}
+
+	for _, test := range []struct {
+		desc      string
+		total     int64
+		olderThan []int64
+		want      []int64
+	}{
+		{desc: "empty", olderThan: []int64{0, 0}, want: []int64{0, 0, 0}},
+		{desc: "allRecent", total: 5, olderThan: []int64{0, 0}, want: []int64{5, 0, 0}},
+		{desc: "spread", total: 10, olderThan: []int64{7, 2}, want: []int64{3, 5, 2}},
+		{desc: "allOld", total: 4, olderThan: []int64{4, 4}, want: []int64{0, 0, 4}},
+	} {
+		if got := opts.AgeCounts(test.total, test.olderThan); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: AgeCounts(%d, %v) = %v, want %v", test.desc, test.total, test.olderThan, got, test.want)
+		}
+	}
+}
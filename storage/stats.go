@@ -0,0 +1,134 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/trillian/util"
+)
+
+// TreeStats holds statistics about the data stored for a tree.
+type TreeStats struct {
+	// LeafCount is the number of leaves stored: sequenced leaves for logs, and
+	// leaf values across all revisions for maps.
+	LeafCount int64
+	// LeafBytes is the total size of the stored leaf values and extra data.
+	LeafBytes int64
+	// SubtreeCount is the number of stored subtrees, counting each revision of
+	// a subtree separately.
+	SubtreeCount int64
+	// RevisionCount is the number of stored tree revisions (signed roots).
+	RevisionCount int64
+	// ReadQPS and WriteQPS are the average rates of read-only and read-write
+	// transactions on the tree over the last ActivityWindow.
+	ReadQPS, WriteQPS float64
+}
+
+// TreeStatsReader may be implemented by LogStorage and MapStorage
+// implementations which can report statistics about the trees they store.
+type TreeStatsReader interface {
+	// GetTreeStats returns the statistics of the specified tree.
+	GetTreeStats(ctx context.Context, treeID int64) (*TreeStats, error)
+}
+
+// ActivityWindow is the period over which TreeActivity averages transaction
+// rates.
+const ActivityWindow = time.Minute
+
+// activityBuckets is the number of one second buckets in ActivityWindow.
+const activityBuckets = int(ActivityWindow / time.Second)
+
+// TreeActivity counts the transactions started on each tree, for the QPS
+// figures of TreeStats. It is safe for concurrent use.
+type TreeActivity struct {
+	timeSource util.TimeSource
+
+	mu     sync.Mutex
+	reads  map[int64]*rateCounter
+	writes map[int64]*rateCounter
+}
+
+// NewTreeActivity returns a TreeActivity using timeSource to measure time.
+func NewTreeActivity(timeSource util.TimeSource) *TreeActivity {
+	return &TreeActivity{
+		timeSource: timeSource,
+		reads:      make(map[int64]*rateCounter),
+		writes:     make(map[int64]*rateCounter),
+	}
+}
+
+// Read records a read-only transaction on treeID.
+func (a *TreeActivity) Read(treeID int64) {
+	a.inc(a.reads, treeID)
+}
+
+// Write records a read-write transaction on treeID.
+func (a *TreeActivity) Write(treeID int64) {
+	a.inc(a.writes, treeID)
+}
+
+// QPS returns the average read and write rates of treeID over the last
+// ActivityWindow.
+func (a *TreeActivity) QPS(treeID int64) (read, write float64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := a.timeSource.Now().Unix()
+	if c := a.reads[treeID]; c != nil {
+		read = c.rate(now)
+	}
+	if c := a.writes[treeID]; c != nil {
+		write = c.rate(now)
+	}
+	return read, write
+}
+
+func (a *TreeActivity) inc(counters map[int64]*rateCounter, treeID int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	c := counters[treeID]
+	if c == nil {
+		c = &rateCounter{}
+		counters[treeID] = c
+	}
+	c.inc(a.timeSource.Now().Unix())
+}
+
+// rateCounter counts events in one second buckets over ActivityWindow.
+type rateCounter struct {
+	counts  [activityBuckets]int64
+	seconds [activityBuckets]int64
+}
+
+func (c *rateCounter) inc(now int64) {
+	i := now % int64(activityBuckets)
+	if c.seconds[i] != now {
+		c.seconds[i] = now
+		c.counts[i] = 0
+	}
+	c.counts[i]++
+}
+
+func (c *rateCounter) rate(now int64) float64 {
+	var total int64
+	for i, sec := range c.seconds {
+		if sec > now-int64(activityBuckets) && sec <= now {
+			total += c.counts[i]
+		}
+	}
+	return float64(total) / ActivityWindow.Seconds()
+}
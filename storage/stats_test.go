@@ -0,0 +1,53 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/trillian/util"
+)
+
+func TestTreeActivity(t *testing.T) {
+	ts := util.NewFakeTimeSource(time.Unix(1000, 0))
+	a := NewTreeActivity(ts)
+
+	for i := 0; i < 30; i++ {
+		a.Read(1)
+		a.Read(1)
+		a.Write(1)
+		a.Read(2)
+		ts.Set(ts.Now().Add(time.Second))
+	}
+
+	for _, test := range []struct {
+		desc                string
+		treeID              int64
+		advance             time.Duration
+		wantRead, wantWrite float64
+	}{
+		{desc: "tree1", treeID: 1, wantRead: 1, wantWrite: 0.5},
+		{desc: "tree2", treeID: 2, wantRead: 0.5},
+		{desc: "unknownTree", treeID: 3},
+		{desc: "partlyExpired", treeID: 1, advance: 44 * time.Second, wantRead: 0.5, wantWrite: 0.25},
+		{desc: "expired", treeID: 1, advance: ActivityWindow},
+	} {
+		ts.Set(ts.Now().Add(test.advance))
+		if read, write := a.QPS(test.treeID); read != test.wantRead || write != test.wantWrite {
+			t.Errorf("%v: QPS(%d) = (%v, %v), want (%v, %v)", test.desc, test.treeID, read, write, test.wantRead, test.wantWrite)
+		}
+	}
+}
@@ -10,6 +10,8 @@ It is generated from these files:
 It has these top-level messages:
 	NodeIDProto
 	SubtreeProto
+	LogStorageSettings
+	MapStorageSettings
 */
 package storagepb
 
@@ -28,6 +30,38 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
+// SubtreeRevisions specifies which revisions of its subtrees a log keeps.
+type LogStorageSettings_SubtreeRevisions int32
+
+const (
+	// All revisions of each subtree are kept, so the nodes of the log can be
+	// read at any of its tree revisions.
+	LogStorageSettings_ALL_REVISIONS LogStorageSettings_SubtreeRevisions = 0
+	// Only the latest revision of each subtree is kept, replacing the
+	// previous one when the subtree is rewritten. Proofs at earlier tree sizes
+	// are recomputed from the nodes at the latest revision instead, which
+	// partially filled subtrees support by storing their internal nodes,
+	// i.e. the frontier of the log at each level. This greatly reduces the
+	// storage size of logs with many small sequencing batches.
+	LogStorageSettings_LATEST_ONLY LogStorageSettings_SubtreeRevisions = 1
+)
+
+var LogStorageSettings_SubtreeRevisions_name = map[int32]string{
+	0: "ALL_REVISIONS",
+	1: "LATEST_ONLY",
+}
+var LogStorageSettings_SubtreeRevisions_value = map[string]int32{
+	"ALL_REVISIONS": 0,
+	"LATEST_ONLY":   1,
+}
+
+func (x LogStorageSettings_SubtreeRevisions) String() string {
+	return proto.EnumName(LogStorageSettings_SubtreeRevisions_name, int32(x))
+}
+func (LogStorageSettings_SubtreeRevisions) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor0, []int{2, 0}
+}
+
 // NodeIDProto is the serialized form of NodeID. It's used only for persistence in storage.
 // As this is long-term we prefer not to use a Go specific format.
 type NodeIDProto struct {
@@ -120,33 +154,97 @@ func (m *SubtreeProto) GetInternalNodeCount() uint32 {
 	return 0
 }
 
+// LogStorageSettings may be set as the storage_settings of a log when it's
+// created, on storage implementations which support it. The settings of a log
+// can't be changed afterwards.
+type LogStorageSettings struct {
+	SubtreeRevisions LogStorageSettings_SubtreeRevisions `protobuf:"varint,1,opt,name=subtree_revisions,json=subtreeRevisions,enum=storagepb.LogStorageSettings_SubtreeRevisions" json:"subtree_revisions,omitempty"`
+	// strata_depths are the depths of the strata of subtrees in which the nodes
+	// of the log are stored, from the root of the tree down. They must be
+	// multiples of 8, of at most 32, and add up to 64. If unset, the log is
+	// stored in subtrees 8 levels deep.
+	StrataDepths []int32 `protobuf:"varint,2,rep,packed,name=strata_depths,json=strataDepths" json:"strata_depths,omitempty"`
+}
+
+func (m *LogStorageSettings) Reset()                    { *m = LogStorageSettings{} }
+func (m *LogStorageSettings) String() string            { return proto.CompactTextString(m) }
+func (*LogStorageSettings) ProtoMessage()               {}
+func (*LogStorageSettings) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *LogStorageSettings) GetSubtreeRevisions() LogStorageSettings_SubtreeRevisions {
+	if m != nil {
+		return m.SubtreeRevisions
+	}
+	return LogStorageSettings_ALL_REVISIONS
+}
+
+func (m *LogStorageSettings) GetStrataDepths() []int32 {
+	if m != nil {
+		return m.StrataDepths
+	}
+	return nil
+}
+
+// MapStorageSettings may be set as the storage_settings of a map when it's
+// created, on storage implementations which support it. The settings of a map
+// can't be changed afterwards.
+type MapStorageSettings struct {
+	// strata_depths are the depths of the strata of subtrees in which the nodes
+	// of the map are stored, from the root of the tree down. They must be
+	// multiples of 8, and add up to 256. If unset, the top 80 levels of the map
+	// are stored in subtrees 8 levels deep, and the rest in a single stratum.
+	StrataDepths []int32 `protobuf:"varint,1,rep,packed,name=strata_depths,json=strataDepths" json:"strata_depths,omitempty"`
+}
+
+func (m *MapStorageSettings) Reset()                    { *m = MapStorageSettings{} }
+func (m *MapStorageSettings) String() string            { return proto.CompactTextString(m) }
+func (*MapStorageSettings) ProtoMessage()               {}
+func (*MapStorageSettings) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+func (m *MapStorageSettings) GetStrataDepths() []int32 {
+	if m != nil {
+		return m.StrataDepths
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*NodeIDProto)(nil), "storagepb.NodeIDProto")
 	proto.RegisterType((*SubtreeProto)(nil), "storagepb.SubtreeProto")
+	proto.RegisterType((*LogStorageSettings)(nil), "storagepb.LogStorageSettings")
+	proto.RegisterType((*MapStorageSettings)(nil), "storagepb.MapStorageSettings")
+	proto.RegisterEnum("storagepb.LogStorageSettings_SubtreeRevisions", LogStorageSettings_SubtreeRevisions_name, LogStorageSettings_SubtreeRevisions_value)
 }
 
 func init() { proto.RegisterFile("storage.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 308 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x91, 0x4d, 0x4b, 0xc3, 0x40,
-	0x10, 0x86, 0x49, 0xd3, 0x06, 0x3b, 0x6d, 0xfc, 0x58, 0x45, 0x96, 0x7a, 0x09, 0x15, 0x24, 0x78,
-	0xc8, 0x41, 0x2f, 0x7e, 0x5c, 0xc4, 0x0f, 0xb0, 0x50, 0x44, 0xd7, 0x1f, 0x10, 0x36, 0x66, 0x34,
-	0xc1, 0xb0, 0x1b, 0x76, 0x37, 0xc5, 0xfe, 0x11, 0x7f, 0xaf, 0x64, 0xb3, 0x48, 0x44, 0x3c, 0x78,
-	0x9b, 0x77, 0xe6, 0x9d, 0x27, 0x93, 0x77, 0x21, 0xd4, 0x46, 0x2a, 0xfe, 0x86, 0x49, 0xad, 0xa4,
-	0x91, 0x64, 0xec, 0x64, 0x9d, 0xcd, 0x17, 0x30, 0x79, 0x90, 0x39, 0x2e, 0x6e, 0x1f, 0xed, 0x84,
-	0xc0, 0xb0, 0xe6, 0xa6, 0xa0, 0x5e, 0xe4, 0xc5, 0x53, 0x66, 0x6b, 0x72, 0x04, 0x5b, 0xb5, 0xc2,
-	0xd7, 0xf2, 0x23, 0xad, 0x50, 0xa4, 0x59, 0x69, 0x34, 0x1d, 0x44, 0x5e, 0x3c, 0x62, 0x61, 0xd7,
-	0x5e, 0xa2, 0xb8, 0x2e, 0x8d, 0x9e, 0x7f, 0xfa, 0x30, 0x7d, 0x6e, 0x32, 0xa3, 0x10, 0x3b, 0xd8,
-	0x3e, 0x04, 0x9d, 0xc3, 0xe1, 0x9c, 0x22, 0x7b, 0x30, 0xca, 0xb1, 0x36, 0x85, 0xc3, 0x74, 0x82,
-	0x1c, 0xc0, 0x58, 0x49, 0x69, 0xd2, 0x82, 0xeb, 0x82, 0xfa, 0x76, 0x61, 0xa3, 0x6d, 0xdc, 0x73,
-	0x5d, 0x90, 0x4b, 0x08, 0x2a, 0xe4, 0x2b, 0xd4, 0x74, 0x18, 0xf9, 0xf1, 0xe4, 0xe4, 0x30, 0xf9,
-	0xfe, 0x85, 0xa4, 0xff, 0xcd, 0x64, 0x69, 0x5d, 0x77, 0xc2, 0xa8, 0x35, 0x73, 0x2b, 0xe4, 0x09,
-	0x36, 0x4b, 0x61, 0x50, 0x09, 0x5e, 0xa5, 0x42, 0xe6, 0xa8, 0xe9, 0xc8, 0x42, 0x8e, 0xff, 0x82,
-	0x2c, 0x9c, 0xbb, 0x4d, 0xc6, 0xb1, 0xc2, 0xb2, 0xdf, 0x23, 0x09, 0xec, 0xfe, 0x40, 0xa6, 0x2f,
-	0xb2, 0x11, 0x86, 0x06, 0x91, 0x17, 0x87, 0x6c, 0xa7, 0xef, 0xbd, 0x69, 0x07, 0xb3, 0x73, 0x98,
-	0xf4, 0x2e, 0x23, 0xdb, 0xe0, 0xbf, 0xe3, 0xda, 0xc6, 0x32, 0x66, 0x6d, 0xd9, 0x66, 0xb2, 0xe2,
-	0x55, 0x83, 0x36, 0x93, 0x29, 0xeb, 0xc4, 0xc5, 0xe0, 0xcc, 0x9b, 0x5d, 0x01, 0xf9, 0x7d, 0xcf,
-	0x7f, 0x08, 0x59, 0x60, 0x5f, 0xfd, 0xf4, 0x2b, 0x00, 0x00, 0xff, 0xff, 0xf4, 0xae, 0x38, 0x20,
-	0x06, 0x02, 0x00, 0x00,
+	// 429 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x93, 0x51, 0x8b, 0xd3, 0x40,
+	0x10, 0xc7, 0xcd, 0xe5, 0x5a, 0xec, 0xb4, 0xb9, 0x4b, 0x57, 0x91, 0x70, 0xbe, 0x94, 0x1c, 0x48,
+	0xf0, 0x21, 0x0f, 0x27, 0x88, 0xa7, 0x2f, 0x9e, 0x5e, 0xc1, 0x40, 0xec, 0xe9, 0xe6, 0x10, 0xc4,
+	0x87, 0x65, 0x6b, 0xc7, 0x26, 0x58, 0x76, 0xc3, 0xee, 0xb6, 0x78, 0x5f, 0xc4, 0x8f, 0xe5, 0x67,
+	0x92, 0xec, 0xa6, 0x12, 0x1b, 0x7c, 0xf0, 0x6d, 0xe7, 0xbf, 0x33, 0xbf, 0xd9, 0xf9, 0x67, 0x02,
+	0x81, 0x36, 0x52, 0xf1, 0x35, 0xa6, 0xb5, 0x92, 0x46, 0x92, 0x51, 0x1b, 0xd6, 0xcb, 0x38, 0x83,
+	0xf1, 0x42, 0xae, 0x30, 0xbb, 0xfe, 0x60, 0x6f, 0x08, 0x1c, 0xd7, 0xdc, 0x94, 0x91, 0x37, 0xf3,
+	0x92, 0x09, 0xb5, 0x67, 0xf2, 0x04, 0x4e, 0x6b, 0x85, 0xdf, 0xaa, 0x1f, 0x6c, 0x83, 0x82, 0x2d,
+	0x2b, 0xa3, 0xa3, 0xa3, 0x99, 0x97, 0x0c, 0x68, 0xe0, 0xe4, 0x1c, 0xc5, 0x9b, 0xca, 0xe8, 0xf8,
+	0xa7, 0x0f, 0x93, 0x62, 0xbb, 0x34, 0x0a, 0xd1, 0xc1, 0x1e, 0xc1, 0xd0, 0x65, 0xb4, 0xb8, 0x36,
+	0x22, 0x0f, 0x61, 0xb0, 0xc2, 0xda, 0x94, 0x2d, 0xc6, 0x05, 0xe4, 0x31, 0x8c, 0x94, 0x94, 0x86,
+	0x95, 0x5c, 0x97, 0x91, 0x6f, 0x0b, 0xee, 0x37, 0xc2, 0x3b, 0xae, 0x4b, 0xf2, 0x0a, 0x86, 0x1b,
+	0xe4, 0x3b, 0xd4, 0xd1, 0xf1, 0xcc, 0x4f, 0xc6, 0x17, 0xe7, 0xe9, 0x9f, 0x11, 0xd2, 0x6e, 0xcf,
+	0x34, 0xb7, 0x59, 0x73, 0x61, 0xd4, 0x1d, 0x6d, 0x4b, 0xc8, 0x47, 0x38, 0xa9, 0x84, 0x41, 0x25,
+	0xf8, 0x86, 0x09, 0xb9, 0x42, 0x1d, 0x0d, 0x2c, 0xe4, 0xe9, 0xbf, 0x20, 0x59, 0x9b, 0xdd, 0x38,
+	0xd3, 0xb2, 0x82, 0xaa, 0xab, 0x91, 0x14, 0x1e, 0xfc, 0x85, 0x64, 0x5f, 0xe5, 0x56, 0x98, 0x68,
+	0x38, 0xf3, 0x92, 0x80, 0x4e, 0xbb, 0xb9, 0x6f, 0x9b, 0x8b, 0xb3, 0x4b, 0x18, 0x77, 0x5e, 0x46,
+	0x42, 0xf0, 0xbf, 0xe3, 0x9d, 0xb5, 0x65, 0x44, 0x9b, 0x63, 0xe3, 0xc9, 0x8e, 0x6f, 0xb6, 0x68,
+	0x3d, 0x99, 0x50, 0x17, 0xbc, 0x3c, 0x7a, 0xe1, 0x9d, 0xbd, 0x06, 0xd2, 0x7f, 0xcf, 0xff, 0x10,
+	0xe2, 0x5f, 0x1e, 0x90, 0x5c, 0xae, 0x0b, 0x37, 0x6c, 0x81, 0xc6, 0x54, 0x62, 0xad, 0xc9, 0x17,
+	0x98, 0x6a, 0x37, 0x35, 0x53, 0xb8, 0xab, 0x74, 0x25, 0x85, 0xb6, 0xc0, 0x93, 0x8b, 0xb4, 0xe3,
+	0x4c, 0xbf, 0x72, 0x6f, 0x16, 0xdd, 0x57, 0xd1, 0x50, 0x1f, 0x28, 0xe4, 0xbc, 0xd9, 0x39, 0xc5,
+	0x0d, 0x67, 0xf6, 0xeb, 0x36, 0x2b, 0xe3, 0x27, 0x03, 0x3a, 0x71, 0xe2, 0xb5, 0xd5, 0xe2, 0xe7,
+	0x10, 0x1e, 0xa2, 0xc8, 0x14, 0x82, 0xab, 0x3c, 0x67, 0x74, 0xfe, 0x29, 0x2b, 0xb2, 0x9b, 0x45,
+	0x11, 0xde, 0x23, 0xa7, 0x30, 0xce, 0xaf, 0x6e, 0xe7, 0xc5, 0x2d, 0xbb, 0x59, 0xe4, 0x9f, 0x43,
+	0x2f, 0xbe, 0x04, 0xf2, 0x9e, 0xd7, 0x87, 0xf3, 0xf4, 0x5a, 0x7a, 0xfd, 0x96, 0xcb, 0xa1, 0xfd,
+	0x03, 0x9e, 0xfd, 0x1e, 0x00, 0x5d, 0x46, 0x74, 0x66, 0x12, 0x03, 0x00, 0x00,
 }
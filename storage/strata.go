@@ -0,0 +1,158 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage/storagepb"
+)
+
+const (
+	// stratumQuantum is the multiple of which the depths of strata must be, as
+	// the prefixes of subtrees are whole bytes of NodeIDs.
+	stratumQuantum = 8
+	// maxLogStratumDepth is the depth of the deepest log strata, whose subtrees
+	// are stored with all their leaves in a single SubtreeProto.
+	maxLogStratumDepth = 32
+	// maxTreeDepth is the depth of maps, and of the strata of a subtree cache.
+	maxTreeDepth = 256
+)
+
+var (
+	// DefaultLogStrata are the depths of the strata of logs whose storage
+	// settings don't set any.
+	DefaultLogStrata = []int{8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8}
+	// DefaultMapStrata are the depths of the strata of maps whose storage
+	// settings don't set any.
+	DefaultMapStrata = []int{8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 176}
+)
+
+// SubtreeStrata returns the depths of the strata of subtrees in which the
+// nodes of tree are stored, from the root of the tree down, as set by the
+// strata_depths of its LogStorageSettings or MapStorageSettings. Trees
+// without those settings use DefaultLogStrata or DefaultMapStrata.
+//
+// The strata of logs are followed by 8-level strata down to a depth of 256,
+// as expected by the subtree cache.
+func SubtreeStrata(tree *trillian.Tree) ([]int, error) {
+	var depths []int32
+	switch settings := tree.GetStorageSettings(); {
+	case settings == nil:
+	case ptypes.Is(settings, &storagepb.LogStorageSettings{}):
+		var s storagepb.LogStorageSettings
+		if err := ptypes.UnmarshalAny(settings, &s); err != nil {
+			return nil, fmt.Errorf("invalid storage_settings: %v", err)
+		}
+		if !isLogTree(tree.TreeType) {
+			return nil, fmt.Errorf("LogStorageSettings not supported for tree_type %v", tree.TreeType)
+		}
+		depths = s.StrataDepths
+	case ptypes.Is(settings, &storagepb.MapStorageSettings{}):
+		var s storagepb.MapStorageSettings
+		if err := ptypes.UnmarshalAny(settings, &s); err != nil {
+			return nil, fmt.Errorf("invalid storage_settings: %v", err)
+		}
+		if tree.TreeType != trillian.TreeType_MAP {
+			return nil, fmt.Errorf("MapStorageSettings not supported for tree_type %v", tree.TreeType)
+		}
+		depths = s.StrataDepths
+	}
+	return StrataForTreeType(tree.TreeType, depths)
+}
+
+// StrataForTreeType returns the strata of a tree of type treeType with the
+// strata_depths depths, or the default ones if depths is empty, as described
+// for SubtreeStrata.
+func StrataForTreeType(treeType trillian.TreeType, depths []int32) ([]int, error) {
+	if len(depths) == 0 {
+		if isLogTree(treeType) {
+			return DefaultLogStrata, nil
+		}
+		return DefaultMapStrata, nil
+	}
+	if err := ValidateStrata(treeType, depths); err != nil {
+		return nil, err
+	}
+
+	strata := make([]int, 0, len(depths))
+	total := 0
+	for _, d := range depths {
+		strata = append(strata, int(d))
+		total += int(d)
+	}
+	for ; total < maxTreeDepth; total += LogStratumDepth {
+		strata = append(strata, LogStratumDepth)
+	}
+	return strata, nil
+}
+
+// ValidateStrata checks that depths are valid strata_depths for a tree of
+// type treeType: they must be multiples of 8 which add up to the depth of the
+// tree, and those of logs must be at most 32.
+func ValidateStrata(treeType trillian.TreeType, depths []int32) error {
+	isLog := isLogTree(treeType)
+	treeDepth := maxTreeDepth
+	if isLog {
+		treeDepth = logNodeIDBitLen
+	}
+
+	total := 0
+	for _, d := range depths {
+		switch {
+		case d <= 0 || d%stratumQuantum != 0:
+			return fmt.Errorf("strata depth %d is not a positive multiple of %d", d, stratumQuantum)
+		case isLog && d > maxLogStratumDepth:
+			return fmt.Errorf("log strata depth %d is more than %d", d, maxLogStratumDepth)
+		}
+		total += int(d)
+	}
+	if total != treeDepth {
+		return fmt.Errorf("strata %v add up to %d levels, want %d for tree_type %v", depths, total, treeDepth, treeType)
+	}
+	return nil
+}
+
+// ValidateStrataSettings checks that the storage_settings of tree, if any, are
+// LogStorageSettings or MapStorageSettings which set nothing but valid
+// strata_depths. It's meant for storage implementations which support no
+// other settings.
+func ValidateStrataSettings(tree *trillian.Tree) error {
+	settings := tree.GetStorageSettings()
+	if settings == nil {
+		return nil
+	}
+	switch {
+	case ptypes.Is(settings, &storagepb.LogStorageSettings{}):
+		var s storagepb.LogStorageSettings
+		if err := ptypes.UnmarshalAny(settings, &s); err != nil {
+			return fmt.Errorf("invalid storage_settings: %v", err)
+		}
+		if s.SubtreeRevisions != storagepb.LogStorageSettings_ALL_REVISIONS {
+			return fmt.Errorf("subtree_revisions not supported, but got %v", s.SubtreeRevisions)
+		}
+	case ptypes.Is(settings, &storagepb.MapStorageSettings{}):
+	default:
+		return fmt.Errorf("storage_settings not supported, but got %v", settings)
+	}
+	_, err := SubtreeStrata(tree)
+	return err
+}
+
+func isLogTree(treeType trillian.TreeType) bool {
+	return treeType == trillian.TreeType_LOG || treeType == trillian.TreeType_PREORDERED_LOG
+}
@@ -0,0 +1,219 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/trillian"
+	"github.com/google/trillian/crypto/keyspb"
+	"github.com/google/trillian/storage/storagepb"
+)
+
+func repeatStrata(depth, n int) []int {
+	ret := make([]int, n)
+	for i := range ret {
+		ret[i] = depth
+	}
+	return ret
+}
+
+func TestSubtreeStrata(t *testing.T) {
+	for _, test := range []struct {
+		desc       string
+		treeType   trillian.TreeType
+		settings   proto.Message
+		wantStrata []int
+		wantErr    bool
+	}{
+		{desc: "logDefault", treeType: trillian.TreeType_LOG, wantStrata: DefaultLogStrata},
+		{desc: "mapDefault", treeType: trillian.TreeType_MAP, wantStrata: DefaultMapStrata},
+		{
+			desc:       "logNoStrata",
+			treeType:   trillian.TreeType_LOG,
+			settings:   &storagepb.LogStorageSettings{SubtreeRevisions: storagepb.LogStorageSettings_LATEST_ONLY},
+			wantStrata: DefaultLogStrata,
+		},
+		{
+			desc:       "log16",
+			treeType:   trillian.TreeType_LOG,
+			settings:   &storagepb.LogStorageSettings{StrataDepths: []int32{16, 16, 16, 16}},
+			wantStrata: append([]int{16, 16, 16, 16}, repeatStrata(8, 24)...),
+		},
+		{
+			desc:       "preordered",
+			treeType:   trillian.TreeType_PREORDERED_LOG,
+			settings:   &storagepb.LogStorageSettings{StrataDepths: []int32{32, 32}},
+			wantStrata: append([]int{32, 32}, repeatStrata(8, 24)...),
+		},
+		{
+			desc:       "map16",
+			treeType:   trillian.TreeType_MAP,
+			settings:   &storagepb.MapStorageSettings{StrataDepths: []int32{16, 16, 16, 16, 16, 176}},
+			wantStrata: []int{16, 16, 16, 16, 16, 176},
+		},
+		{
+			desc:       "otherSettings",
+			treeType:   trillian.TreeType_LOG,
+			settings:   &keyspb.PEMKeyFile{},
+			wantStrata: DefaultLogStrata,
+		},
+		{
+			desc:     "logSettingsOnMap",
+			treeType: trillian.TreeType_MAP,
+			settings: &storagepb.LogStorageSettings{},
+			wantErr:  true,
+		},
+		{
+			desc:     "mapSettingsOnLog",
+			treeType: trillian.TreeType_LOG,
+			settings: &storagepb.MapStorageSettings{},
+			wantErr:  true,
+		},
+		{
+			desc:     "logTooShallow",
+			treeType: trillian.TreeType_LOG,
+			settings: &storagepb.LogStorageSettings{StrataDepths: []int32{16, 16, 16}},
+			wantErr:  true,
+		},
+		{
+			desc:     "logTooDeep",
+			treeType: trillian.TreeType_LOG,
+			settings: &storagepb.LogStorageSettings{StrataDepths: []int32{16, 16, 16, 16, 8}},
+			wantErr:  true,
+		},
+		{
+			desc:     "logStratumTooDeep",
+			treeType: trillian.TreeType_LOG,
+			settings: &storagepb.LogStorageSettings{StrataDepths: []int32{64}},
+			wantErr:  true,
+		},
+		{
+			desc:     "notByteAligned",
+			treeType: trillian.TreeType_LOG,
+			settings: &storagepb.LogStorageSettings{StrataDepths: []int32{4, 4, 8, 16, 16, 16}},
+			wantErr:  true,
+		},
+		{
+			desc:     "negative",
+			treeType: trillian.TreeType_MAP,
+			settings: &storagepb.MapStorageSettings{StrataDepths: []int32{-8, 264}},
+			wantErr:  true,
+		},
+		{
+			desc:     "mapTooShallow",
+			treeType: trillian.TreeType_MAP,
+			settings: &storagepb.MapStorageSettings{StrataDepths: []int32{64}},
+			wantErr:  true,
+		},
+	} {
+		tree := &trillian.Tree{TreeType: test.treeType}
+		if test.settings != nil {
+			settings, err := ptypes.MarshalAny(test.settings)
+			if err != nil {
+				t.Fatalf("%v: MarshalAny(): %v", test.desc, err)
+			}
+			tree.StorageSettings = settings
+		}
+
+		strata, err := SubtreeStrata(tree)
+		if gotErr := err != nil; gotErr != test.wantErr {
+			t.Errorf("%v: SubtreeStrata()=%v, %v; wantErr %v", test.desc, strata, err, test.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(strata, test.wantStrata) {
+			t.Errorf("%v: SubtreeStrata()=%v, want %v", test.desc, strata, test.wantStrata)
+		}
+	}
+}
+
+func TestValidateStrataSettings(t *testing.T) {
+	for _, test := range []struct {
+		desc     string
+		treeType trillian.TreeType
+		settings proto.Message
+		wantErr  bool
+	}{
+		{desc: "none", treeType: trillian.TreeType_LOG},
+		{
+			desc:     "logStrata",
+			treeType: trillian.TreeType_LOG,
+			settings: &storagepb.LogStorageSettings{StrataDepths: []int32{16, 16, 16, 16}},
+		},
+		{
+			desc:     "mapStrata",
+			treeType: trillian.TreeType_MAP,
+			settings: &storagepb.MapStorageSettings{StrataDepths: []int32{128, 128}},
+		},
+		{
+			desc:     "latestOnly",
+			treeType: trillian.TreeType_LOG,
+			settings: &storagepb.LogStorageSettings{SubtreeRevisions: storagepb.LogStorageSettings_LATEST_ONLY},
+			wantErr:  true,
+		},
+		{
+			desc:     "otherSettings",
+			treeType: trillian.TreeType_LOG,
+			settings: &keyspb.PEMKeyFile{},
+			wantErr:  true,
+		},
+		{
+			desc:     "invalidStrata",
+			treeType: trillian.TreeType_LOG,
+			settings: &storagepb.LogStorageSettings{StrataDepths: []int32{8}},
+			wantErr:  true,
+		},
+	} {
+		tree := &trillian.Tree{TreeType: test.treeType}
+		if test.settings != nil {
+			settings, err := ptypes.MarshalAny(test.settings)
+			if err != nil {
+				t.Fatalf("%v: MarshalAny(): %v", test.desc, err)
+			}
+			tree.StorageSettings = settings
+		}
+		if err := ValidateStrataSettings(tree); (err != nil) != test.wantErr {
+			t.Errorf("%v: ValidateStrataSettings()=%v, wantErr %v", test.desc, err, test.wantErr)
+		}
+	}
+}
+
+// TestSuffixAtDepthMatchesSplit checks that the suffixes of log nodes in
+// subtrees deeper than LogStratumDepth are those given by NodeID.Split.
+func TestSuffixAtDepthMatchesSplit(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, depth := range []int{8, 16, 24, 32} {
+		for level := 0; level < depth; level++ {
+			for i := 0; i < 20; i++ {
+				n := SubtreeNodeCoords{Level: level, Index: r.Int63n(1 << uint(depth-level))}
+				// Place the subtree at an arbitrary prefix in the bottom stratum.
+				prefix := r.Int63() >> uint(depth)
+				c := NodeCoords{Level: int64(level), Index: prefix<<uint(depth-level) | n.Index}
+				id, err := c.ID()
+				if err != nil {
+					t.Fatalf("%v.ID(): %v", c, err)
+				}
+				_, want := id.Split((logNodeIDBitLen-depth)/8, depth)
+				if got := n.SuffixAtDepth(depth); got.String() != want.String() {
+					t.Errorf("%+v.SuffixAtDepth(%d)=%v, want %v", n, depth, got, want)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// The nodes of a log tree are stored in subtrees of LogStratumDepth levels:
+// stratum s holds levels [s*LogStratumDepth, (s+1)*LogStratumDepth), and each
+// of its subtrees holds the nodes of those levels below one node of level
+// (s+1)*LogStratumDepth. The node at the top of a subtree is stored in the
+// subtree of the stratum above, so the root of the tree, at level
+// logNodeIDBitLen, isn't stored at all.
+//
+// SubtreeCoords and SubtreeNodeCoords address the subtrees and their nodes by
+// level and index, with the boundaries of strata explicit, rather than by the
+// bits of NodeIDs. Their Prefix and Suffix methods convert them to the keys of
+// the SubtreeProtos stored by earlier versions, which remain valid.
+
+const (
+	// LogStratumDepth is the number of levels of each stratum of log trees.
+	LogStratumDepth = 8
+	// LogStrata is the number of strata of log trees.
+	LogStrata = logNodeIDBitLen / LogStratumDepth
+)
+
+// SubtreeCoords addresses a subtree of a log tree: the Index-th subtree, from
+// the left, of stratum Stratum.
+type SubtreeCoords struct {
+	Stratum int
+	Index   int64
+}
+
+// String returns the coordinates as "stratum/index".
+func (s SubtreeCoords) String() string {
+	return fmt.Sprintf("%d/%d", s.Stratum, s.Index)
+}
+
+// SubtreeNodeCoords addresses a node within a subtree: the Index-th node, from
+// the left, of Level levels above the bottom of the subtree. Level is below
+// LogStratumDepth, and Index below 1<<(LogStratumDepth-Level).
+type SubtreeNodeCoords struct {
+	Level int
+	Index int64
+}
+
+// SubtreeFor returns the coordinates of the subtree holding the log node at c,
+// and of the node within it. It returns an error if c isn't stored in a
+// subtree.
+func SubtreeFor(c NodeCoords) (SubtreeCoords, SubtreeNodeCoords, error) {
+	if c.Level < 0 || c.Level >= logNodeIDBitLen || c.Index < 0 || bitLen(c.Index) > logNodeIDBitLen-int(c.Level) {
+		return SubtreeCoords{}, SubtreeNodeCoords{}, fmt.Errorf("node %v is not stored in a subtree", c)
+	}
+	stratum := int(c.Level) / LogStratumDepth
+	level := int(c.Level) % LogStratumDepth
+	height := uint(LogStratumDepth - level)
+	return SubtreeCoords{Stratum: stratum, Index: c.Index >> height},
+		SubtreeNodeCoords{Level: level, Index: c.Index & (1<<height - 1)}, nil
+}
+
+// Node returns the coordinates in the whole tree of the node at n within s.
+func (s SubtreeCoords) Node(n SubtreeNodeCoords) NodeCoords {
+	height := uint(LogStratumDepth - n.Level)
+	return NodeCoords{
+		Level: int64(s.Stratum*LogStratumDepth + n.Level),
+		Index: s.Index<<height | n.Index,
+	}
+}
+
+// Root returns the coordinates of the node at the top of s, which is stored in
+// the subtree above.
+func (s SubtreeCoords) Root() NodeCoords {
+	return NodeCoords{Level: int64((s.Stratum + 1) * LogStratumDepth), Index: s.Index}
+}
+
+// Prefix returns the prefix of the SubtreeProto storing s.
+func (s SubtreeCoords) Prefix() []byte {
+	n := LogStrata - 1 - s.Stratum
+	prefix := make([]byte, n)
+	for i, idx := n-1, uint64(s.Index); i >= 0; i, idx = i-1, idx>>8 {
+		prefix[i] = byte(idx)
+	}
+	return prefix
+}
+
+// ID returns the NodeID identifying s to the functions reading subtrees from
+// storage, which is that of the node at its top.
+func (s SubtreeCoords) ID() NodeID {
+	id := NewEmptyNodeID(logNodeIDBitLen)
+	prefix := s.Prefix()
+	copy(id.Path, prefix)
+	id.PrefixLenBits = len(prefix) * 8
+	return id
+}
+
+// SubtreeForPrefix returns the coordinates of the log subtree stored in the
+// SubtreeProto with the given prefix.
+func SubtreeForPrefix(prefix []byte) (SubtreeCoords, error) {
+	if len(prefix) >= LogStrata {
+		return SubtreeCoords{}, fmt.Errorf("prefix %x is too long for a log subtree", prefix)
+	}
+	var idx uint64
+	for _, b := range prefix {
+		idx = idx<<8 | uint64(b)
+	}
+	return SubtreeCoords{Stratum: LogStrata - 1 - len(prefix), Index: int64(idx)}, nil
+}
+
+// Suffix returns the key of n in the Leaves or InternalNodes of the
+// SubtreeProto storing its subtree; the key is in Leaves if n.Level is 0.
+func (n SubtreeNodeCoords) Suffix() Suffix {
+	return n.SuffixAtDepth(LogStratumDepth)
+}
+
+// SuffixAtDepth returns the key of n in a SubtreeProto which is depth levels
+// deep, which must be a multiple of 8 of at most 64.
+func (n SubtreeNodeCoords) SuffixAtDepth(depth int) Suffix {
+	bits := depth - n.Level
+	var path [8]byte
+	binary.BigEndian.PutUint64(path[:], uint64(n.Index)<<uint(64-bits))
+	return Suffix{Bits: byte(bits), Path: path[:bytesForBits(bits)]}
+}
+
+// SubtreeNodeForSuffix returns the coordinates of the node stored under the
+// key sfx in a log SubtreeProto.
+func SubtreeNodeForSuffix(sfx Suffix) (SubtreeNodeCoords, error) {
+	if sfx.Bits < 1 || sfx.Bits > LogStratumDepth || len(sfx.Path) != 1 {
+		return SubtreeNodeCoords{}, fmt.Errorf("suffix %v is not that of a log subtree node", sfx)
+	}
+	level := LogStratumDepth - int(sfx.Bits)
+	return SubtreeNodeCoords{Level: level, Index: int64(sfx.Path[0] >> uint(level))}, nil
+}
@@ -0,0 +1,115 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+// TestSubtreeForMatchesSplit checks that the typed addressing of log nodes
+// gives the prefixes and suffixes of NodeID.Split, under which SubtreeProtos
+// have been stored.
+func TestSubtreeForMatchesSplit(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for level := int64(0); level < logNodeIDBitLen; level++ {
+		for i := 0; i < 20; i++ {
+			var index int64
+			if i > 0 {
+				index = r.Int63() >> uint(level)
+			}
+			c := NodeCoords{Level: level, Index: index}
+			st, n, err := SubtreeFor(c)
+			if err != nil {
+				t.Fatalf("SubtreeFor(%v): %v", c, err)
+			}
+			if got := st.Node(n); got != c {
+				t.Errorf("SubtreeFor(%v): %v.Node(%+v)=%v, want %v", c, st, n, got, c)
+			}
+
+			id, err := c.ID()
+			if err != nil {
+				t.Fatalf("%v.ID(): %v", c, err)
+			}
+			prefixBytes := (id.PrefixLenBits - 1) / LogStratumDepth
+			wantPrefix, wantSuffix := id.Split(prefixBytes, LogStratumDepth)
+			if got := st.Prefix(); !bytes.Equal(got, wantPrefix) {
+				t.Errorf("SubtreeFor(%v): prefix %x, want %x", c, got, wantPrefix)
+			}
+			if got := n.Suffix(); got.String() != wantSuffix.String() {
+				t.Errorf("SubtreeFor(%v): suffix %v, want %v", c, got, wantSuffix)
+			}
+
+			back, err := SubtreeForPrefix(wantPrefix)
+			if err != nil || back != st {
+				t.Errorf("SubtreeForPrefix(%x)=%v, %v; want %v", wantPrefix, back, err, st)
+			}
+			backNode, err := SubtreeNodeForSuffix(wantSuffix)
+			if err != nil || backNode != n {
+				t.Errorf("SubtreeNodeForSuffix(%v)=%+v, %v; want %+v", wantSuffix, backNode, err, n)
+			}
+			sid := st.ID()
+			if got, want := sid.Path[:sid.PrefixLenBits/8], wantPrefix; !bytes.Equal(got, want) {
+				t.Errorf("%v.ID() has prefix %x, want %x", st, got, want)
+			}
+		}
+	}
+}
+
+func TestSubtreeCoords(t *testing.T) {
+	for _, test := range []struct {
+		c        NodeCoords
+		st       SubtreeCoords
+		n        SubtreeNodeCoords
+		root     NodeCoords
+		wantBits byte
+	}{
+		{c: NodeCoords{0, 0}, st: SubtreeCoords{0, 0}, n: SubtreeNodeCoords{0, 0}, root: NodeCoords{8, 0}, wantBits: 8},
+		{c: NodeCoords{0, 257}, st: SubtreeCoords{0, 1}, n: SubtreeNodeCoords{0, 1}, root: NodeCoords{8, 1}, wantBits: 8},
+		{c: NodeCoords{7, 3}, st: SubtreeCoords{0, 1}, n: SubtreeNodeCoords{7, 1}, root: NodeCoords{8, 1}, wantBits: 1},
+		{c: NodeCoords{8, 0}, st: SubtreeCoords{1, 0}, n: SubtreeNodeCoords{0, 0}, root: NodeCoords{16, 0}, wantBits: 8},
+		{c: NodeCoords{63, 1}, st: SubtreeCoords{7, 0}, n: SubtreeNodeCoords{7, 1}, root: NodeCoords{64, 0}, wantBits: 1},
+	} {
+		st, n, err := SubtreeFor(test.c)
+		if err != nil {
+			t.Errorf("SubtreeFor(%v): %v", test.c, err)
+			continue
+		}
+		if st != test.st || n != test.n {
+			t.Errorf("SubtreeFor(%v)=%v, %+v; want %v, %+v", test.c, st, n, test.st, test.n)
+		}
+		if got := st.Root(); got != test.root {
+			t.Errorf("%v.Root()=%v, want %v", st, got, test.root)
+		}
+		if got := n.Suffix().Bits; got != test.wantBits {
+			t.Errorf("%+v.Suffix().Bits=%d, want %d", n, got, test.wantBits)
+		}
+	}
+
+	for _, c := range []NodeCoords{{-1, 0}, {0, -1}, {64, 0}, {63, 2}} {
+		if st, n, err := SubtreeFor(c); err == nil {
+			t.Errorf("SubtreeFor(%v)=%v, %+v; want error", c, st, n)
+		}
+	}
+	if _, err := SubtreeForPrefix(make([]byte, LogStrata)); err == nil {
+		t.Errorf("SubtreeForPrefix(%d bytes) succeeded, want error", LogStrata)
+	}
+	for _, sfx := range []Suffix{{Bits: 0, Path: []byte{0}}, {Bits: 9, Path: []byte{0}}, {Bits: 8, Path: []byte{0, 0}}} {
+		if n, err := SubtreeNodeForSuffix(sfx); err == nil {
+			t.Errorf("SubtreeNodeForSuffix(%+v)=%+v, want error", sfx, n)
+		}
+	}
+}
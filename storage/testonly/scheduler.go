@@ -0,0 +1,255 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testonly
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+)
+
+// Operations at which a Scheduler pauses its actors.
+const (
+	OpBegin                 = "Begin"
+	OpCommit                = "Commit"
+	OpQueueLeaves           = "QueueLeaves"
+	OpLatestSignedLogRoot   = "LatestSignedLogRoot"
+	OpDequeueLeaves         = "DequeueLeaves"
+	OpUpdateSequencedLeaves = "UpdateSequencedLeaves"
+	OpGetMerkleNodes        = "GetMerkleNodes"
+	OpSetMerkleNodes        = "SetMerkleNodes"
+	OpStoreSignedLogRoot    = "StoreSignedLogRoot"
+
+	// OpDone is reported once an actor has returned.
+	OpDone = "Done"
+)
+
+// ErrBlocked is returned when an actor doesn't reach its next operation within
+// the BlockTimeout of its Scheduler, usually because it waits for a lock held
+// by another actor.
+var ErrBlocked = errors.New("actor blocked")
+
+// Scheduler runs goroutines ("actors") which use a storage.LogStorage, letting
+// them through one storage operation at a time in the order the test asks
+// for, so that races between them can be reproduced deterministically.
+//
+// Actors are paused before each operation on the LogStorage returned by
+// LogStorage, and on the transactions it begins. A storage implementation may
+// itself block an actor while another one holds a lock, which the Scheduler
+// reports as ErrBlocked. Scheduler methods must be called from a single
+// goroutine.
+type Scheduler struct {
+	// BlockTimeout is how long an actor may take to reach its next operation
+	// before it's considered blocked.
+	BlockTimeout time.Duration
+	// StallTimeout is how long Finish waits for blocked actors, e.g. while
+	// the storage waits for a lock to time out.
+	StallTimeout time.Duration
+
+	actors map[string]*actor
+}
+
+type actor struct {
+	arrive  chan string
+	release chan struct{}
+	// op is the operation the actor is paused at, empty while it runs.
+	op string
+	// err is the value the actor returned, set before it reports OpDone.
+	err error
+}
+
+type actorKey struct{}
+
+// NewScheduler returns a Scheduler without actors.
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		BlockTimeout: 200 * time.Millisecond,
+		StallTimeout: 30 * time.Second,
+		actors:       make(map[string]*actor),
+	}
+}
+
+// Go starts f as the actor with the given name. f must perform its storage
+// operations with the context it's given, and runs until it reaches the first
+// of them.
+func (s *Scheduler) Go(ctx context.Context, name string, f func(ctx context.Context) error) {
+	if _, ok := s.actors[name]; ok {
+		panic(fmt.Sprintf("actor %q already exists", name))
+	}
+	a := &actor{arrive: make(chan string), release: make(chan struct{})}
+	s.actors[name] = a
+	go func() {
+		a.err = f(context.WithValue(ctx, actorKey{}, a))
+		a.arrive <- OpDone
+	}()
+}
+
+func (s *Scheduler) actor(name string) *actor {
+	a, ok := s.actors[name]
+	if !ok {
+		panic(fmt.Sprintf("unknown actor %q", name))
+	}
+	return a
+}
+
+// Next returns the operation at which the actor is paused, waiting for it to
+// get there if it's running, or OpDone if it has returned.
+func (s *Scheduler) Next(name string) (string, error) {
+	a := s.actor(name)
+	if a.op != "" {
+		return a.op, nil
+	}
+	select {
+	case a.op = <-a.arrive:
+		return a.op, nil
+	case <-time.After(s.BlockTimeout):
+		return "", ErrBlocked
+	}
+}
+
+// Step lets the actor perform the operation it's paused at, and returns the
+// next one, as Next does.
+func (s *Scheduler) Step(name string) (string, error) {
+	a := s.actor(name)
+	switch a.op {
+	case OpDone:
+		return OpDone, nil
+	case "":
+	default:
+		a.op = ""
+		a.release <- struct{}{}
+	}
+	return s.Next(name)
+}
+
+// RunUntil steps the actor until it's paused at op, and returns the operation
+// it stopped at, which is OpDone if it returned first.
+func (s *Scheduler) RunUntil(name, op string) (string, error) {
+	next, err := s.Next(name)
+	for err == nil && next != op && next != OpDone {
+		next, err = s.Step(name)
+	}
+	return next, err
+}
+
+// Finish runs the named actors until they've all returned, stepping each of
+// them in turn so that actors blocked by others eventually proceed. It
+// returns an error if none of them makes progress for StallTimeout.
+func (s *Scheduler) Finish(names ...string) error {
+	lastProgress := time.Now()
+	for {
+		done := true
+		for _, name := range names {
+			if s.actor(name).op == OpDone {
+				continue
+			}
+			done = false
+			if _, err := s.Step(name); err == nil {
+				lastProgress = time.Now()
+			}
+		}
+		if done {
+			return nil
+		}
+		if time.Since(lastProgress) > s.StallTimeout {
+			return fmt.Errorf("actors %v deadlocked", names)
+		}
+	}
+}
+
+// Err returns the error returned by the actor, which must be done.
+func (s *Scheduler) Err(name string) error {
+	a := s.actor(name)
+	if a.op != OpDone {
+		panic(fmt.Sprintf("actor %q is still running", name))
+	}
+	return a.err
+}
+
+// pause reports that the actor running with ctx is about to perform op, and
+// waits until its Scheduler lets it. It doesn't pause goroutines which aren't
+// actors.
+func pause(ctx context.Context, op string) {
+	a, ok := ctx.Value(actorKey{}).(*actor)
+	if !ok {
+		return
+	}
+	a.arrive <- op
+	<-a.release
+}
+
+// LogStorage returns a LogStorage which pauses the actors of s before each
+// operation on ls.
+func (s *Scheduler) LogStorage(ls storage.LogStorage) storage.LogStorage {
+	return &scheduledLogStorage{LogStorage: ls}
+}
+
+type scheduledLogStorage struct {
+	storage.LogStorage
+}
+
+func (ls *scheduledLogStorage) ReadWriteTransaction(ctx context.Context, treeID int64, f storage.LogTXFunc) error {
+	pause(ctx, OpBegin)
+	return ls.LogStorage.ReadWriteTransaction(ctx, treeID, func(ctx context.Context, tx storage.LogTreeTX) error {
+		if err := f(ctx, &scheduledLogTreeTX{LogTreeTX: tx}); err != nil {
+			return err
+		}
+		pause(ctx, OpCommit)
+		return nil
+	})
+}
+
+func (ls *scheduledLogStorage) QueueLeaves(ctx context.Context, treeID int64, leaves []*trillian.LogLeaf, queueTimestamp time.Time) ([]*trillian.QueuedLogLeaf, error) {
+	pause(ctx, OpQueueLeaves)
+	return ls.LogStorage.QueueLeaves(ctx, treeID, leaves, queueTimestamp)
+}
+
+type scheduledLogTreeTX struct {
+	storage.LogTreeTX
+}
+
+func (tx *scheduledLogTreeTX) LatestSignedLogRoot(ctx context.Context) (trillian.SignedLogRoot, error) {
+	pause(ctx, OpLatestSignedLogRoot)
+	return tx.LogTreeTX.LatestSignedLogRoot(ctx)
+}
+
+func (tx *scheduledLogTreeTX) DequeueLeaves(ctx context.Context, limit int, cutoffTime time.Time) ([]*trillian.LogLeaf, error) {
+	pause(ctx, OpDequeueLeaves)
+	return tx.LogTreeTX.DequeueLeaves(ctx, limit, cutoffTime)
+}
+
+func (tx *scheduledLogTreeTX) UpdateSequencedLeaves(ctx context.Context, leaves []*trillian.LogLeaf) error {
+	pause(ctx, OpUpdateSequencedLeaves)
+	return tx.LogTreeTX.UpdateSequencedLeaves(ctx, leaves)
+}
+
+func (tx *scheduledLogTreeTX) GetMerkleNodes(ctx context.Context, treeRevision int64, ids []storage.NodeID) ([]storage.Node, error) {
+	pause(ctx, OpGetMerkleNodes)
+	return tx.LogTreeTX.GetMerkleNodes(ctx, treeRevision, ids)
+}
+
+func (tx *scheduledLogTreeTX) SetMerkleNodes(ctx context.Context, nodes []storage.Node) error {
+	pause(ctx, OpSetMerkleNodes)
+	return tx.LogTreeTX.SetMerkleNodes(ctx, nodes)
+}
+
+func (tx *scheduledLogTreeTX) StoreSignedLogRoot(ctx context.Context, root trillian.SignedLogRoot) error {
+	pause(ctx, OpStoreSignedLogRoot)
+	return tx.LogTreeTX.StoreSignedLogRoot(ctx, root)
+}
@@ -0,0 +1,136 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/google/trillian/storage/storagepb"
+)
+
+const (
+	// TileHeight is the number of Merkle tree levels covered by a log tile.
+	// It matches the depth of the log subtrees stored as SubtreeProtos.
+	TileHeight = 8
+	// TileWidth is the number of hashes in a full log tile.
+	TileWidth = 1 << TileHeight
+
+	// maxLogTileLevel is the level of the tile at the top of a log tree.
+	maxLogTileLevel = 64/TileHeight - 1
+)
+
+// TileID identifies a tile of a log's Merkle tree. The tile at (Level, Index)
+// holds the hashes of the nodes at Merkle level Level*TileHeight (where the
+// leaves are at level 0) with indices in [Index*TileWidth, (Index+1)*TileWidth).
+type TileID struct {
+	Level int
+	Index int64
+}
+
+// String returns a string representation of the TileID, for debugging.
+func (id TileID) String() string {
+	return fmt.Sprintf("%d/%d", id.Level, id.Index)
+}
+
+// Tile holds the hashes of up to TileWidth consecutive nodes at the bottom
+// level of a tile. Tiles on the right edge of a tree may be partial, holding
+// fewer than TileWidth hashes. Unlike SubtreeProtos, tiles are not
+// revisioned: a partial tile is only ever extended, so the hashes it held at
+// a smaller tree size are a prefix of its current ones.
+type Tile struct {
+	ID     TileID
+	Hashes [][]byte
+}
+
+// Full returns true iff the tile holds TileWidth hashes.
+func (t *Tile) Full() bool {
+	return len(t.Hashes) == TileWidth
+}
+
+// TileReader provides read access to the tiles of a log's Merkle tree.
+type TileReader interface {
+	// GetTiles returns the requested tiles as of the given tree size, i.e.
+	// partial tiles only hold the hashes of nodes within the tree of that
+	// size. Tiles without any such nodes are returned as nil.
+	GetTiles(ctx context.Context, treeSize int64, ids []TileID) ([]*Tile, error)
+}
+
+// tileSuffixKey returns the key of the subtree leaf at index in the Leaves
+// map of a log SubtreeProto.
+func tileSuffixKey(index int) string {
+	return Suffix{Bits: TileHeight, Path: []byte{byte(index)}}.String()
+}
+
+// TileFromSubtree converts a log SubtreeProto, as stored by the revisioned
+// storage implementations, into the equivalent tile. Internal nodes are not
+// part of tiles, and are dropped.
+func TileFromSubtree(st *storagepb.SubtreeProto) (*Tile, error) {
+	if st.Depth != TileHeight {
+		return nil, fmt.Errorf("subtree has depth %d, want %d", st.Depth, TileHeight)
+	}
+	if len(st.Prefix) > maxLogTileLevel {
+		return nil, fmt.Errorf("subtree prefix %x is too long for a log subtree", st.Prefix)
+	}
+	if len(st.Leaves) > TileWidth {
+		return nil, fmt.Errorf("subtree %x has %d leaves, want at most %d", st.Prefix, len(st.Leaves), TileWidth)
+	}
+
+	var b [8]byte
+	copy(b[8-len(st.Prefix):], st.Prefix)
+	t := &Tile{
+		ID: TileID{
+			Level: maxLogTileLevel - len(st.Prefix),
+			Index: int64(binary.BigEndian.Uint64(b[:])),
+		},
+		Hashes: make([][]byte, len(st.Leaves)),
+	}
+	// Log subtrees are left-dense, so the leaves must be the first ones.
+	for i := range t.Hashes {
+		h, ok := st.Leaves[tileSuffixKey(i)]
+		if !ok {
+			return nil, fmt.Errorf("subtree %x is missing leaf %d", st.Prefix, i)
+		}
+		t.Hashes[i] = h
+	}
+	return t, nil
+}
+
+// Subtree converts a full tile into the equivalent log SubtreeProto, ready to
+// be populated with its internal nodes. Partial tiles cannot be converted, as
+// the internal nodes of partial log subtrees depend on other subtrees.
+func (t *Tile) Subtree() (*storagepb.SubtreeProto, error) {
+	if !t.Full() {
+		return nil, fmt.Errorf("tile %v is partial, with %d hashes", t.ID, len(t.Hashes))
+	}
+	if t.ID.Level < 0 || t.ID.Level > maxLogTileLevel {
+		return nil, fmt.Errorf("tile %v has invalid level", t.ID)
+	}
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(t.ID.Index))
+	st := &storagepb.SubtreeProto{
+		Prefix: b[8-(maxLogTileLevel-t.ID.Level):],
+		Depth:  TileHeight,
+		Leaves: make(map[string][]byte, len(t.Hashes)),
+		// The number of internal nodes rebuilt for a full subtree, which
+		// excludes the leaves and the root.
+		InternalNodeCount: TileWidth - 2,
+	}
+	for i, h := range t.Hashes {
+		st.Leaves[tileSuffixKey(i)] = h
+	}
+	return st, nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tiles
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotExist is returned by Bucket.Read when the object doesn't exist.
+var ErrNotExist = errors.New("object does not exist")
+
+// Bucket is a flat namespace of objects, such as a GCS or S3 bucket.
+type Bucket interface {
+	// Read returns the contents of the named object, or ErrNotExist.
+	Read(ctx context.Context, name string) ([]byte, error)
+	// Write creates or replaces the named object.
+	Write(ctx context.Context, name string, data []byte) error
+}
+
+// OpenBucket returns the Bucket identified by rawurl, which can be one of:
+//   - gs://bucket/prefix for a Google Cloud Storage bucket;
+//   - s3://bucket/prefix for an Amazon S3 bucket;
+//   - file:///path, or a plain path, for a local directory.
+func OpenBucket(ctx context.Context, rawurl string) (Bucket, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	prefix := strings.TrimPrefix(u.Path, "/")
+	switch u.Scheme {
+	case "gs":
+		return NewGCSBucket(ctx, u.Host, prefix)
+	case "s3":
+		return NewS3Bucket(u.Host, prefix)
+	case "file":
+		return NewDirBucket(u.Path), nil
+	case "":
+		return NewDirBucket(rawurl), nil
+	default:
+		return nil, fmt.Errorf("unsupported bucket URL scheme %q", u.Scheme)
+	}
+}
+
+// objectName joins prefix and name, if prefix is not empty.
+func objectName(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return path.Join(prefix, name)
+}
+
+// NewDirBucket returns a Bucket storing objects as files below dir, which
+// can then be served by any static file server.
+func NewDirBucket(dir string) Bucket {
+	return dirBucket(dir)
+}
+
+type dirBucket string
+
+func (d dirBucket) Read(ctx context.Context, name string) ([]byte, error) {
+	b, err := ioutil.ReadFile(filepath.Join(string(d), filepath.FromSlash(name)))
+	if os.IsNotExist(err) {
+		return nil, ErrNotExist
+	}
+	return b, err
+}
+
+// Write writes the object to a temporary file which is then renamed, so that
+// readers never observe partially written objects.
+func (d dirBucket) Write(ctx context.Context, name string, data []byte) error {
+	p := filepath.Join(string(d), filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(p), ".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), p)
+}
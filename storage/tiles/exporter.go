@@ -0,0 +1,266 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tiles
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian"
+	"github.com/google/trillian/merkle/hashers"
+)
+
+const (
+	// DefaultBatchSize is the default number of leaves requested from the
+	// log at a time.
+	DefaultBatchSize = 1000
+
+	// leavesPerPass bounds the number of leaf hashes held in memory while
+	// exporting. Tiles are written after each pass, but the checkpoint is
+	// only written once the tiles for the whole log root are in place.
+	leavesPerPass = TileWidth * TileWidth
+)
+
+// Exporter copies the Merkle tree of a log to tiles in a Bucket, after each
+// new tree head.
+type Exporter struct {
+	client trillian.TrillianLogClient
+	tree   *trillian.Tree
+	hasher hashers.LogHasher
+	bucket Bucket
+
+	// BatchSize is the number of leaves requested from the log at a time.
+	BatchSize int64
+}
+
+// NewExporter returns an Exporter writing the tiles of tree, read from the
+// log via client, to bucket.
+func NewExporter(client trillian.TrillianLogClient, tree *trillian.Tree, bucket Bucket) (*Exporter, error) {
+	if tree.TreeType != trillian.TreeType_LOG && tree.TreeType != trillian.TreeType_PREORDERED_LOG {
+		return nil, fmt.Errorf("tree %v is not a log", tree.TreeId)
+	}
+	hasher, err := hashers.NewLogHasher(tree.HashStrategy)
+	if err != nil {
+		return nil, err
+	}
+	return &Exporter{
+		client:    client,
+		tree:      tree,
+		hasher:    hasher,
+		bucket:    bucket,
+		BatchSize: DefaultBatchSize,
+	}, nil
+}
+
+// Run calls ExportOnce every interval, until ctx is done.
+func (e *Exporter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		root, err := e.ExportOnce(ctx)
+		if err != nil {
+			glog.Errorf("%v: failed to export tiles: %v", e.tree.TreeId, err)
+		} else {
+			glog.V(1).Infof("%v: exported tiles for tree size %d", e.tree.TreeId, root.TreeSize)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ExportOnce writes the tiles for the latest log root, if it is newer than
+// the latest exported one, and returns the exported root.
+func (e *Exporter) ExportOnce(ctx context.Context) (*trillian.SignedLogRoot, error) {
+	prev, err := readCheckpoint(ctx, e.bucket, e.tree.TreeId)
+	switch {
+	case err == ErrNotExist:
+		if err := writeTree(ctx, e.bucket, e.tree); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	}
+
+	resp, err := e.client.GetLatestSignedLogRoot(ctx, &trillian.GetLatestSignedLogRootRequest{LogId: e.tree.TreeId})
+	if err != nil {
+		return nil, err
+	}
+	root := resp.SignedLogRoot
+	if root == nil {
+		return nil, errors.New("log returned no root")
+	}
+	var size int64
+	if prev != nil {
+		switch {
+		case root.TreeSize < prev.TreeSize:
+			return nil, fmt.Errorf("log root size %d is smaller than exported size %d", root.TreeSize, prev.TreeSize)
+		case root.TreeSize == prev.TreeSize && root.TimestampNanos <= prev.TimestampNanos:
+			return prev, nil
+		}
+		size = prev.TreeSize
+	}
+
+	for size < root.TreeSize {
+		end := size + leavesPerPass
+		if end > root.TreeSize {
+			end = root.TreeSize
+		}
+		hashes, err := e.leafHashes(ctx, size, end)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeTiles(ctx, e.bucket, e.tree.TreeId, e.hasher, size, end, hashes); err != nil {
+			return nil, err
+		}
+		size = end
+	}
+
+	if err := publish(ctx, e.bucket, e.tree.TreeId, e.hasher, root); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// publish checks the tiles in bucket against root, and writes root as the
+// checkpoint of the tree, making it visible to Readers.
+func publish(ctx context.Context, bucket Bucket, treeID int64, hasher hashers.LogHasher, root *trillian.SignedLogRoot) error {
+	r := &Reader{bucket: bucket, treeID: treeID, hasher: hasher}
+	rootHash, err := r.newProver(ctx, root.TreeSize).rangeHash(0, root.TreeSize)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(rootHash, root.RootHash) {
+		return fmt.Errorf("tiles have root hash %x at tree size %d, but log root has %x", rootHash, root.TreeSize, root.RootHash)
+	}
+
+	b, err := proto.Marshal(root)
+	if err != nil {
+		return err
+	}
+	return bucket.Write(ctx, checkpointPath(treeID), b)
+}
+
+// writeTree stores the public parts of the tree's configuration.
+func writeTree(ctx context.Context, bucket Bucket, tree *trillian.Tree) error {
+	b, err := proto.Marshal(&trillian.Tree{
+		TreeId:             tree.TreeId,
+		TreeType:           tree.TreeType,
+		HashStrategy:       tree.HashStrategy,
+		HashAlgorithm:      tree.HashAlgorithm,
+		SignatureAlgorithm: tree.SignatureAlgorithm,
+		PublicKey:          tree.PublicKey,
+	})
+	if err != nil {
+		return err
+	}
+	return bucket.Write(ctx, treePath(tree.TreeId), b)
+}
+
+// leafHashes returns the Merkle leaf hashes of the leaves in [start, end).
+func (e *Exporter) leafHashes(ctx context.Context, start, end int64) ([][]byte, error) {
+	hashes := make([][]byte, 0, end-start)
+	for next := start; next < end; {
+		count := end - next
+		if count > e.BatchSize {
+			count = e.BatchSize
+		}
+		resp, err := e.client.GetLeavesByRange(ctx, &trillian.GetLeavesByRangeRequest{
+			LogId:      e.tree.TreeId,
+			StartIndex: next,
+			Count:      count,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Leaves) == 0 {
+			return nil, fmt.Errorf("log returned no leaves at index %d", next)
+		}
+		for _, leaf := range resp.Leaves {
+			if next == end {
+				break
+			}
+			if leaf.LeafIndex != next {
+				return nil, fmt.Errorf("log returned leaf %d, want %d", leaf.LeafIndex, next)
+			}
+			h := leaf.MerkleLeafHash
+			if len(h) == 0 {
+				if h, err = e.hasher.HashLeaf(leaf.LeafValue); err != nil {
+					return nil, err
+				}
+			}
+			hashes = append(hashes, h)
+			next++
+		}
+	}
+	return hashes, nil
+}
+
+// writeTiles writes the tiles which change when the tree grows from size
+// oldSize to size newSize, given the hashes of the new leaves. The tiles for
+// oldSize must already have been written.
+func writeTiles(ctx context.Context, bucket Bucket, treeID int64, hasher hashers.LogHasher, oldSize, newSize int64, leafHashes [][]byte) error {
+	if got, want := int64(len(leafHashes)), newSize-oldSize; got != want {
+		return fmt.Errorf("got %d leaf hashes, want %d", got, want)
+	}
+	hashSize := hasher.Size()
+
+	// At each level, hashes holds the node hashes from the start of the first
+	// tile which changes, up to the end of the level.
+	hashes := leafHashes
+	for level := 0; ; level++ {
+		shift := uint(level * TileHeight)
+		from, to := oldSize>>shift, newSize>>shift
+		if from == to {
+			break
+		}
+		start := from &^ (TileWidth - 1)
+		if start < from {
+			b, err := bucket.Read(ctx, tilePath(treeID, level, start/TileWidth, int(from-start)))
+			if err != nil {
+				return fmt.Errorf("error reading tile %d/%d: %v", level, start/TileWidth, err)
+			}
+			prev, err := decodeTile(b, int(from-start), hashSize)
+			if err != nil {
+				return err
+			}
+			hashes = append(prev, hashes...)
+		}
+
+		var next [][]byte
+		for i := int64(0); i < int64(len(hashes)); i += TileWidth {
+			end := i + TileWidth
+			if end > int64(len(hashes)) {
+				end = int64(len(hashes))
+			}
+			t := hashes[i:end]
+			if err := bucket.Write(ctx, tilePath(treeID, level, (start+i)/TileWidth, len(t)), encodeTile(t)); err != nil {
+				return err
+			}
+			if len(t) == TileWidth {
+				next = append(next, hashRange(hasher, t))
+			}
+		}
+		hashes = next
+	}
+	return nil
+}
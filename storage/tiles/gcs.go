@@ -0,0 +1,58 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tiles
+
+import (
+	"context"
+	"io/ioutil"
+
+	"cloud.google.com/go/storage"
+)
+
+// NewGCSBucket returns a Bucket storing objects in the named Google Cloud
+// Storage bucket, below prefix. Default credentials are used.
+func NewGCSBucket(ctx context.Context, bucket, prefix string) (Bucket, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &gcsBucket{bucket: client.Bucket(bucket), prefix: prefix}, nil
+}
+
+type gcsBucket struct {
+	bucket *storage.BucketHandle
+	prefix string
+}
+
+func (g *gcsBucket) Read(ctx context.Context, name string) ([]byte, error) {
+	r, err := g.bucket.Object(objectName(g.prefix, name)).NewReader(ctx)
+	if err == storage.ErrObjectNotExist {
+		return nil, ErrNotExist
+	} else if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+func (g *gcsBucket) Write(ctx context.Context, name string, data []byte) error {
+	w := g.bucket.Object(objectName(g.prefix, name)).NewWriter(ctx)
+	w.ContentType = "application/octet-stream"
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
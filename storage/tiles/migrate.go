@@ -0,0 +1,89 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tiles
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/merkle/hashers"
+	"github.com/google/trillian/storage"
+)
+
+// ExportFromStorage writes the tiles for the latest root of tree, read
+// directly from the revisioned subtrees in log storage via tx, to bucket. It
+// is the migration path from subtree storage to tiles: unlike an Exporter,
+// it doesn't need to read every leaf, only the perfect nodes at the bottom
+// of each tile. Tiles already in bucket are overwritten.
+func ExportFromStorage(ctx context.Context, tree *trillian.Tree, tx storage.ReadOnlyLogTreeTX, bucket Bucket) (*trillian.SignedLogRoot, error) {
+	if tree.TreeType != trillian.TreeType_LOG && tree.TreeType != trillian.TreeType_PREORDERED_LOG {
+		return nil, fmt.Errorf("tree %v is not a log", tree.TreeId)
+	}
+	hasher, err := hashers.NewLogHasher(tree.HashStrategy)
+	if err != nil {
+		return nil, err
+	}
+	root, err := tx.LatestSignedLogRoot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rev := tx.ReadRevision()
+
+	for level := 0; ; level++ {
+		shift := uint(level * TileHeight)
+		count := root.TreeSize >> shift
+		if count == 0 {
+			break
+		}
+		for index := int64(0); index*TileWidth < count; index++ {
+			width := count - index*TileWidth
+			if width > TileWidth {
+				width = TileWidth
+			}
+			ids := make([]storage.NodeID, width)
+			for i := range ids {
+				if ids[i], err = storage.NewNodeIDForTreeCoords(int64(shift), index*TileWidth+int64(i), 64); err != nil {
+					return nil, err
+				}
+			}
+			nodes, err := tx.GetMerkleNodes(ctx, rev, ids)
+			if err != nil {
+				return nil, err
+			}
+			if len(nodes) != len(ids) {
+				return nil, fmt.Errorf("storage returned %d nodes for tile %d/%d, want %d", len(nodes), level, index, len(ids))
+			}
+			hashes := make([][]byte, len(nodes))
+			for i, n := range nodes {
+				if !n.NodeID.Equivalent(ids[i]) {
+					return nil, fmt.Errorf("storage returned node %v, want %v", n.NodeID.CoordString(), ids[i].CoordString())
+				}
+				hashes[i] = n.Hash
+			}
+			if err := bucket.Write(ctx, tilePath(tree.TreeId, level, index, len(hashes)), encodeTile(hashes)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := writeTree(ctx, bucket, tree); err != nil {
+		return nil, err
+	}
+	if err := publish(ctx, bucket, tree.TreeId, hasher, &root); err != nil {
+		return nil, err
+	}
+	return &root, nil
+}
@@ -0,0 +1,298 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tiles
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian"
+	"github.com/google/trillian/merkle/hashers"
+	"github.com/google/trillian/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Reader serves proofs for a tree from its exported tiles.
+type Reader struct {
+	bucket Bucket
+	treeID int64
+	hasher hashers.LogHasher
+}
+
+// NewReader returns a Reader for the tiles of treeID in bucket. The tree's
+// hash strategy is read from the tree configuration stored by the Exporter,
+// so ErrNotExist is returned if the tree hasn't been exported yet.
+func NewReader(ctx context.Context, bucket Bucket, treeID int64) (*Reader, error) {
+	b, err := bucket.Read(ctx, treePath(treeID))
+	if err != nil {
+		return nil, err
+	}
+	var tree trillian.Tree
+	if err := proto.Unmarshal(b, &tree); err != nil {
+		return nil, fmt.Errorf("error reading tree %v: %v", treeID, err)
+	}
+	hasher, err := hashers.NewLogHasher(tree.HashStrategy)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{bucket: bucket, treeID: treeID, hasher: hasher}, nil
+}
+
+// LatestSignedLogRoot returns the latest exported root of the tree. Proofs
+// can be served for any tree size up to the size of this root.
+func (r *Reader) LatestSignedLogRoot(ctx context.Context) (*trillian.SignedLogRoot, error) {
+	return readCheckpoint(ctx, r.bucket, r.treeID)
+}
+
+// InclusionProof returns the proof of inclusion of the leaf at index in the
+// tree of size treeSize, which must not exceed the size of root.
+func (r *Reader) InclusionProof(ctx context.Context, root *trillian.SignedLogRoot, index, treeSize int64) ([][]byte, error) {
+	if treeSize > root.TreeSize {
+		return nil, status.Errorf(codes.OutOfRange, "tree size %d is beyond exported size %d", treeSize, root.TreeSize)
+	}
+	if index < 0 || index >= treeSize {
+		return nil, status.Errorf(codes.InvalidArgument, "index %d is outside tree of size %d", index, treeSize)
+	}
+	p := r.newProver(ctx, root.TreeSize)
+	return p.inclusion(index, 0, treeSize)
+}
+
+// ConsistencyProof returns the proof of consistency between the trees of
+// sizes size1 and size2, which must not exceed the size of root.
+func (r *Reader) ConsistencyProof(ctx context.Context, root *trillian.SignedLogRoot, size1, size2 int64) ([][]byte, error) {
+	if size2 > root.TreeSize {
+		return nil, status.Errorf(codes.OutOfRange, "tree size %d is beyond exported size %d", size2, root.TreeSize)
+	}
+	if size1 < 0 || size1 > size2 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid tree sizes %d and %d", size1, size2)
+	}
+	if size1 == 0 || size1 == size2 {
+		return [][]byte{}, nil
+	}
+	p := r.newProver(ctx, root.TreeSize)
+	return p.consistency(size1, 0, size2, true)
+}
+
+func readCheckpoint(ctx context.Context, bucket Bucket, treeID int64) (*trillian.SignedLogRoot, error) {
+	b, err := bucket.Read(ctx, checkpointPath(treeID))
+	if err != nil {
+		return nil, err
+	}
+	var root trillian.SignedLogRoot
+	if err := proto.Unmarshal(b, &root); err != nil {
+		return nil, fmt.Errorf("error reading checkpoint of tree %v: %v", treeID, err)
+	}
+	return &root, nil
+}
+
+// GetTiles returns the requested tiles as of treeSize, which must not exceed
+// the size of the latest exported root. It implements storage.TileReader.
+func (r *Reader) GetTiles(ctx context.Context, treeSize int64, ids []storage.TileID) ([]*storage.Tile, error) {
+	root, err := r.LatestSignedLogRoot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if treeSize > root.TreeSize {
+		return nil, status.Errorf(codes.OutOfRange, "tree size %d is beyond exported size %d", treeSize, root.TreeSize)
+	}
+	ret := make([]*storage.Tile, len(ids))
+	for i, id := range ids {
+		// Partial tiles are only stored at the exported sizes, so read the
+		// tile as of the latest root and truncate it.
+		width := tileWidth(treeSize, id)
+		if width <= 0 {
+			continue
+		}
+		hashes, err := r.readTile(ctx, root.TreeSize, id)
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = &storage.Tile{ID: id, Hashes: hashes[:width]}
+	}
+	return ret, nil
+}
+
+// tileWidth returns the number of hashes in tile id in the tree of size
+// treeSize.
+func tileWidth(treeSize int64, id storage.TileID) int {
+	rem := (treeSize >> uint(id.Level*TileHeight)) - id.Index*TileWidth
+	if rem > TileWidth {
+		return TileWidth
+	}
+	if rem < 0 {
+		return 0
+	}
+	return int(rem)
+}
+
+// readTile returns the hashes of the tile id as of treeSize, or nil if the
+// tile is empty at that size.
+func (r *Reader) readTile(ctx context.Context, treeSize int64, id storage.TileID) ([][]byte, error) {
+	width := tileWidth(treeSize, id)
+	if width == 0 {
+		return nil, nil
+	}
+	b, err := r.bucket.Read(ctx, tilePath(r.treeID, id.Level, id.Index, width))
+	if err != nil {
+		return nil, fmt.Errorf("error reading tile %v of width %d: %v", id, width, err)
+	}
+	return decodeTile(b, width, r.hasher.Size())
+}
+
+// prover computes proofs from the tiles of a tree of a given size. It caches
+// the tiles it reads, so it should only be used for a single request.
+type prover struct {
+	ctx   context.Context
+	r     *Reader
+	size  int64
+	tiles map[storage.TileID][][]byte
+}
+
+func (r *Reader) newProver(ctx context.Context, size int64) *prover {
+	return &prover{ctx: ctx, r: r, size: size, tiles: make(map[storage.TileID][][]byte)}
+}
+
+// tile returns the hashes of the tile at (level, index), as of the tree size
+// the prover is working with.
+func (p *prover) tile(level int, index int64) ([][]byte, error) {
+	id := storage.TileID{Level: level, Index: index}
+	if t, ok := p.tiles[id]; ok {
+		return t, nil
+	}
+	t, err := p.r.readTile(p.ctx, p.size, id)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, fmt.Errorf("tile %v is beyond tree size %d", id, p.size)
+	}
+	p.tiles[id] = t
+	return t, nil
+}
+
+// node returns the hash of the perfect subtree of height level whose
+// leftmost leaf is index<<level.
+func (p *prover) node(level int, index int64) ([]byte, error) {
+	if (index+1)<<uint(level) > p.size {
+		return nil, fmt.Errorf("node %d/%d is beyond tree size %d", level, index, p.size)
+	}
+	sub := uint(level % TileHeight)
+	first := index << sub
+	t, err := p.tile(level/TileHeight, first/TileWidth)
+	if err != nil {
+		return nil, err
+	}
+	off := first % TileWidth
+	return hashRange(p.r.hasher, t[off:off+1<<sub]), nil
+}
+
+// rangeHash returns the Merkle tree hash MTH(D[lo:hi]) as defined in RFC 6962.
+// The subtrees used in proofs are aligned, so they are either read directly
+// from a tile, or split into such subtrees.
+func (p *prover) rangeHash(lo, hi int64) ([]byte, error) {
+	n := hi - lo
+	if n == 0 {
+		return p.r.hasher.EmptyRoot(), nil
+	}
+	if n&(n-1) == 0 && lo%n == 0 {
+		level := 0
+		for int64(1)<<uint(level) < n {
+			level++
+		}
+		return p.node(level, lo>>uint(level))
+	}
+	k := splitPoint(n)
+	left, err := p.rangeHash(lo, lo+k)
+	if err != nil {
+		return nil, err
+	}
+	right, err := p.rangeHash(lo+k, hi)
+	if err != nil {
+		return nil, err
+	}
+	return p.r.hasher.HashChildren(left, right), nil
+}
+
+// inclusion implements PATH(m, D[lo:hi]) from RFC 6962 section 2.1.1.
+func (p *prover) inclusion(m, lo, hi int64) ([][]byte, error) {
+	if hi-lo == 1 {
+		return [][]byte{}, nil
+	}
+	k := splitPoint(hi - lo)
+	var proof [][]byte
+	var sibling []byte
+	var err error
+	if m < lo+k {
+		if proof, err = p.inclusion(m, lo, lo+k); err != nil {
+			return nil, err
+		}
+		sibling, err = p.rangeHash(lo+k, hi)
+	} else {
+		if proof, err = p.inclusion(m, lo+k, hi); err != nil {
+			return nil, err
+		}
+		sibling, err = p.rangeHash(lo, lo+k)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append(proof, sibling), nil
+}
+
+// consistency implements SUBPROOF(m, D[lo:hi], b) from RFC 6962 section
+// 2.1.2, where m is relative to lo.
+func (p *prover) consistency(m, lo, hi int64, b bool) ([][]byte, error) {
+	if m == hi-lo {
+		if b {
+			return [][]byte{}, nil
+		}
+		h, err := p.rangeHash(lo, hi)
+		if err != nil {
+			return nil, err
+		}
+		return [][]byte{h}, nil
+	}
+	k := splitPoint(hi - lo)
+	var proof [][]byte
+	var sibling []byte
+	var err error
+	if m <= k {
+		if proof, err = p.consistency(m, lo, lo+k, b); err != nil {
+			return nil, err
+		}
+		sibling, err = p.rangeHash(lo+k, hi)
+	} else {
+		if proof, err = p.consistency(m-k, lo+k, hi, false); err != nil {
+			return nil, err
+		}
+		sibling, err = p.rangeHash(lo, lo+k)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append(proof, sibling), nil
+}
+
+// splitPoint returns the largest power of two smaller than n, which must be
+// greater than one.
+func splitPoint(n int64) int64 {
+	k := int64(1)
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
@@ -0,0 +1,112 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tiles exports the Merkle trees of logs as static tiles in object
+// storage (such as GCS or S3), and serves inclusion and consistency proofs
+// from those tiles, without involving the log's primary storage.
+//
+// A tile is a fixed-height subtree of the log's Merkle tree. The tile at
+// (level, index) holds the hashes of up to TileWidth consecutive nodes at
+// Merkle level level*TileHeight, starting at node index*TileWidth. A tile
+// holding fewer than TileWidth hashes is a partial tile, which lies on the
+// right edge of the tree; partial tiles are stored under a name including
+// their width, so that a tile never changes once written.
+//
+// The objects stored for a tree are:
+//   - <treeID>/tree: the public parts of the tree's configuration;
+//   - <treeID>/checkpoint: the latest exported SignedLogRoot;
+//   - <treeID>/tile/<level>/<index>: full tiles;
+//   - <treeID>/tile/<level>/<index>.p/<width>: partial tiles.
+//
+// Tile indices are written in groups of three digits, e.g. index 1234067 is
+// written as x001/x234/067, to keep the number of entries per directory
+// bounded. Tiles are the concatenation of the hashes they hold.
+package tiles
+
+import (
+	"fmt"
+
+	"github.com/google/trillian/merkle/hashers"
+	"github.com/google/trillian/storage"
+)
+
+const (
+	// TileHeight is the number of Merkle tree levels covered by a tile.
+	TileHeight = storage.TileHeight
+	// TileWidth is the number of hashes in a full tile.
+	TileWidth = storage.TileWidth
+)
+
+func treePath(treeID int64) string {
+	return fmt.Sprintf("%d/tree", treeID)
+}
+
+func checkpointPath(treeID int64) string {
+	return fmt.Sprintf("%d/checkpoint", treeID)
+}
+
+// tilePath returns the object name of the tile at the given level and index,
+// holding width hashes.
+func tilePath(treeID int64, level int, index int64, width int) string {
+	p := fmt.Sprintf("%d/tile/%d/%s", treeID, level, indexPath(index))
+	if width < TileWidth {
+		p += fmt.Sprintf(".p/%d", width)
+	}
+	return p
+}
+
+// indexPath formats n as groups of three digits, all but the last of which
+// are prefixed with an "x".
+func indexPath(n int64) string {
+	s := fmt.Sprintf("%03d", n%1000)
+	for n >= 1000 {
+		n /= 1000
+		s = fmt.Sprintf("x%03d/%s", n%1000, s)
+	}
+	return s
+}
+
+// encodeTile serializes the hashes of a tile.
+func encodeTile(hashes [][]byte) []byte {
+	var b []byte
+	for _, h := range hashes {
+		b = append(b, h...)
+	}
+	return b
+}
+
+// decodeTile splits a serialized tile holding width hashes of hashSize bytes.
+func decodeTile(b []byte, width, hashSize int) ([][]byte, error) {
+	if len(b) != width*hashSize {
+		return nil, fmt.Errorf("tile has %d bytes, want %d", len(b), width*hashSize)
+	}
+	hashes := make([][]byte, width)
+	for i := range hashes {
+		hashes[i] = b[i*hashSize : (i+1)*hashSize]
+	}
+	return hashes, nil
+}
+
+// hashRange returns the root hash of the perfect subtree whose leaves are
+// hashes, the number of which must be a power of two.
+func hashRange(hasher hashers.LogHasher, hashes [][]byte) []byte {
+	for len(hashes) > 1 {
+		next := make([][]byte, len(hashes)/2)
+		for i := range next {
+			next[i] = hasher.HashChildren(hashes[2*i], hashes[2*i+1])
+		}
+		hashes = next
+	}
+	return hashes[0]
+}
@@ -0,0 +1,356 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tiles
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/merkle"
+	"github.com/google/trillian/merkle/rfc6962"
+	"github.com/google/trillian/storage"
+	"google.golang.org/grpc"
+)
+
+type memBucket struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newMemBucket() *memBucket {
+	return &memBucket{objects: make(map[string][]byte)}
+}
+
+func (m *memBucket) Read(ctx context.Context, name string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.objects[name]
+	if !ok {
+		return nil, ErrNotExist
+	}
+	return b, nil
+}
+
+func (m *memBucket) Write(ctx context.Context, name string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[name] = data
+	return nil
+}
+
+// fakeLogClient serves the first size leaves of mt.
+type fakeLogClient struct {
+	trillian.TrillianLogClient
+	mt       *merkle.InMemoryMerkleTree
+	size     int64
+	rootHash []byte
+}
+
+func (f *fakeLogClient) GetLatestSignedLogRoot(ctx context.Context, req *trillian.GetLatestSignedLogRootRequest, opts ...grpc.CallOption) (*trillian.GetLatestSignedLogRootResponse, error) {
+	rootHash := f.rootHash
+	if rootHash == nil {
+		rootHash = f.mt.RootAtSnapshot(f.size).Hash()
+	}
+	return &trillian.GetLatestSignedLogRootResponse{
+		SignedLogRoot: &trillian.SignedLogRoot{
+			TreeSize:       f.size,
+			RootHash:       rootHash,
+			TimestampNanos: f.size,
+		},
+	}, nil
+}
+
+// GetLeavesByRange returns at most 100 leaves, to exercise the handling of
+// short responses.
+func (f *fakeLogClient) GetLeavesByRange(ctx context.Context, req *trillian.GetLeavesByRangeRequest, opts ...grpc.CallOption) (*trillian.GetLeavesByRangeResponse, error) {
+	var leaves []*trillian.LogLeaf
+	for i := req.StartIndex; i < req.StartIndex+req.Count && i < f.size && len(leaves) < 100; i++ {
+		leaves = append(leaves, &trillian.LogLeaf{LeafIndex: i, MerkleLeafHash: f.mt.LeafHash(i + 1)})
+	}
+	return &trillian.GetLeavesByRangeResponse{Leaves: leaves}, nil
+}
+
+func newTestExporter(t *testing.T, client trillian.TrillianLogClient, bucket Bucket) *Exporter {
+	t.Helper()
+	e, err := NewExporter(client, &trillian.Tree{
+		TreeId:       1,
+		TreeType:     trillian.TreeType_LOG,
+		HashStrategy: trillian.HashStrategy_RFC6962_SHA256,
+	}, bucket)
+	if err != nil {
+		t.Fatalf("NewExporter(): %v", err)
+	}
+	return e
+}
+
+func TestExportAndProve(t *testing.T) {
+	ctx := context.Background()
+	hasher := rfc6962.DefaultHasher
+	verifier := merkle.NewLogVerifier(hasher)
+
+	const maxSize = 2*TileWidth*TileWidth + 3
+	mt := merkle.NewInMemoryMerkleTree(hasher)
+	for i := 0; i < maxSize; i++ {
+		if _, _, err := mt.AddLeaf([]byte(fmt.Sprintf("leaf %d", i))); err != nil {
+			t.Fatalf("AddLeaf(): %v", err)
+		}
+	}
+
+	client := &fakeLogClient{mt: mt}
+	bucket := newMemBucket()
+	e := newTestExporter(t, client, bucket)
+
+	// Some of the proofs to check for each exported size, as (index, size)
+	// or (size1, size2) pairs relative to the exported size.
+	for _, size := range []int64{0, 1, 2, 7, TileWidth - 1, TileWidth, TileWidth + 1, 3*TileWidth + 5, TileWidth*TileWidth + 1, maxSize} {
+		client.size = size
+		root, err := e.ExportOnce(ctx)
+		if err != nil {
+			t.Fatalf("ExportOnce() at size %d: %v", size, err)
+		}
+		if got := root.TreeSize; got != size {
+			t.Fatalf("ExportOnce() exported size %d, want %d", got, size)
+		}
+
+		r, err := NewReader(ctx, bucket, 1)
+		if err != nil {
+			t.Fatalf("NewReader(): %v", err)
+		}
+		latest, err := r.LatestSignedLogRoot(ctx)
+		if err != nil {
+			t.Fatalf("LatestSignedLogRoot(): %v", err)
+		}
+		if got := latest.TreeSize; got != size {
+			t.Errorf("LatestSignedLogRoot() returned size %d, want %d", got, size)
+		}
+
+		for _, treeSize := range []int64{1, size / 2, size - 1, size} {
+			if treeSize < 1 || treeSize > size {
+				continue
+			}
+			for _, index := range []int64{0, treeSize / 3, treeSize - 1} {
+				proof, err := r.InclusionProof(ctx, latest, index, treeSize)
+				if err != nil {
+					t.Fatalf("InclusionProof(%d, %d) at size %d: %v", index, treeSize, size, err)
+				}
+				if err := verifier.VerifyInclusionProof(index, treeSize, proof, mt.RootAtSnapshot(treeSize).Hash(), mt.LeafHash(index+1)); err != nil {
+					t.Errorf("InclusionProof(%d, %d) at size %d: invalid proof: %v", index, treeSize, size, err)
+				}
+			}
+
+			for _, size1 := range []int64{1, treeSize / 2, treeSize - 1, treeSize} {
+				if size1 < 1 || size1 > treeSize {
+					continue
+				}
+				proof, err := r.ConsistencyProof(ctx, latest, size1, treeSize)
+				if err != nil {
+					t.Fatalf("ConsistencyProof(%d, %d) at size %d: %v", size1, treeSize, size, err)
+				}
+				if err := verifier.VerifyConsistencyProof(size1, treeSize, mt.RootAtSnapshot(size1).Hash(), mt.RootAtSnapshot(treeSize).Hash(), proof); err != nil {
+					t.Errorf("ConsistencyProof(%d, %d) at size %d: invalid proof: %v", size1, treeSize, size, err)
+				}
+			}
+		}
+
+		if _, err := r.InclusionProof(ctx, latest, 0, size+1); err == nil {
+			t.Errorf("InclusionProof(0, %d) at size %d: got nil error, want error", size+1, size)
+		}
+	}
+}
+
+func TestExportRejectsWrongRoot(t *testing.T) {
+	ctx := context.Background()
+	mt := merkle.NewInMemoryMerkleTree(rfc6962.DefaultHasher)
+	for i := 0; i < 10; i++ {
+		if _, _, err := mt.AddLeaf([]byte{byte(i)}); err != nil {
+			t.Fatalf("AddLeaf(): %v", err)
+		}
+	}
+	client := &fakeLogClient{mt: mt, size: 10, rootHash: []byte("not the root")}
+	bucket := newMemBucket()
+	e := newTestExporter(t, client, bucket)
+
+	if _, err := e.ExportOnce(ctx); err == nil {
+		t.Fatal("ExportOnce(): got nil error, want root mismatch")
+	}
+	if _, err := readCheckpoint(ctx, bucket, 1); err != ErrNotExist {
+		t.Errorf("readCheckpoint(): got %v, want ErrNotExist", err)
+	}
+}
+
+func TestTilePath(t *testing.T) {
+	for _, tc := range []struct {
+		level int
+		index int64
+		width int
+		want  string
+	}{
+		{level: 0, index: 0, width: TileWidth, want: "5/tile/0/000"},
+		{level: 1, index: 12, width: 3, want: "5/tile/1/012.p/3"},
+		{level: 0, index: 1000, width: TileWidth, want: "5/tile/0/x001/000"},
+		{level: 2, index: 1234067, width: 255, want: "5/tile/2/x001/x234/067.p/255"},
+	} {
+		if got := tilePath(5, tc.level, tc.index, tc.width); got != tc.want {
+			t.Errorf("tilePath(5, %d, %d, %d): %q, want %q", tc.level, tc.index, tc.width, got, tc.want)
+		}
+	}
+}
+
+func TestDirBucket(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "tiles")
+	if err != nil {
+		t.Fatalf("TempDir(): %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	b := NewDirBucket(dir)
+	if _, err := b.Read(ctx, "1/tile/0/000"); err != ErrNotExist {
+		t.Errorf("Read() of missing object: got %v, want ErrNotExist", err)
+	}
+	if err := b.Write(ctx, "1/tile/0/000", []byte("hashes")); err != nil {
+		t.Fatalf("Write(): %v", err)
+	}
+	got, err := b.Read(ctx, "1/tile/0/000")
+	if err != nil {
+		t.Fatalf("Read(): %v", err)
+	}
+	if string(got) != "hashes" {
+		t.Errorf("Read(): %q, want %q", got, "hashes")
+	}
+}
+
+// fakeStorageTX serves the perfect nodes of the first size leaves of mt, as
+// stored by the revisioned subtree storage.
+type fakeStorageTX struct {
+	storage.ReadOnlyLogTreeTX
+	mt   *merkle.InMemoryMerkleTree
+	size int64
+}
+
+func (f *fakeStorageTX) LatestSignedLogRoot(ctx context.Context) (trillian.SignedLogRoot, error) {
+	return trillian.SignedLogRoot{TreeSize: f.size, RootHash: f.mt.RootAtSnapshot(f.size).Hash()}, nil
+}
+
+func (f *fakeStorageTX) ReadRevision() int64 {
+	return f.size
+}
+
+func (f *fakeStorageTX) GetMerkleNodes(ctx context.Context, rev int64, ids []storage.NodeID) ([]storage.Node, error) {
+	var nodes []storage.Node
+	for _, id := range ids {
+		level := uint(64 - id.PrefixLenBits)
+		lo := int64(binary.BigEndian.Uint64(id.Path))
+		hi := lo + 1<<level
+		if hi > f.size {
+			return nil, fmt.Errorf("node %v is not a perfect node", id.CoordString())
+		}
+		mt := merkle.NewInMemoryMerkleTree(rfc6962.DefaultHasher)
+		for i := lo; i < hi; i++ {
+			mt.AddLeaf([]byte(fmt.Sprintf("leaf %d", i)))
+		}
+		nodes = append(nodes, storage.Node{NodeID: id, Hash: mt.CurrentRoot().Hash()})
+	}
+	return nodes, nil
+}
+
+func TestExportFromStorage(t *testing.T) {
+	ctx := context.Background()
+	hasher := rfc6962.DefaultHasher
+	verifier := merkle.NewLogVerifier(hasher)
+
+	const migratedSize, finalSize = 2*TileWidth + 5, 3*TileWidth + 1
+	mt := merkle.NewInMemoryMerkleTree(hasher)
+	for i := 0; i < finalSize; i++ {
+		if _, _, err := mt.AddLeaf([]byte(fmt.Sprintf("leaf %d", i))); err != nil {
+			t.Fatalf("AddLeaf(): %v", err)
+		}
+	}
+	tree := &trillian.Tree{
+		TreeId:       1,
+		TreeType:     trillian.TreeType_LOG,
+		HashStrategy: trillian.HashStrategy_RFC6962_SHA256,
+	}
+	bucket := newMemBucket()
+
+	root, err := ExportFromStorage(ctx, tree, &fakeStorageTX{mt: mt, size: migratedSize}, bucket)
+	if err != nil {
+		t.Fatalf("ExportFromStorage(): %v", err)
+	}
+	if got := root.TreeSize; got != migratedSize {
+		t.Errorf("ExportFromStorage() exported size %d, want %d", got, migratedSize)
+	}
+
+	// An Exporter picks up from the migrated tiles.
+	client := &fakeLogClient{mt: mt, size: finalSize}
+	if _, err := newTestExporter(t, client, bucket).ExportOnce(ctx); err != nil {
+		t.Fatalf("ExportOnce(): %v", err)
+	}
+
+	r, err := NewReader(ctx, bucket, 1)
+	if err != nil {
+		t.Fatalf("NewReader(): %v", err)
+	}
+	latest, err := r.LatestSignedLogRoot(ctx)
+	if err != nil {
+		t.Fatalf("LatestSignedLogRoot(): %v", err)
+	}
+	proof, err := r.ConsistencyProof(ctx, latest, migratedSize, finalSize)
+	if err != nil {
+		t.Fatalf("ConsistencyProof(): %v", err)
+	}
+	if err := verifier.VerifyConsistencyProof(migratedSize, finalSize, mt.RootAtSnapshot(migratedSize).Hash(), mt.RootAtSnapshot(finalSize).Hash(), proof); err != nil {
+		t.Errorf("ConsistencyProof(): invalid proof: %v", err)
+	}
+}
+
+func TestReaderGetTiles(t *testing.T) {
+	ctx := context.Background()
+	mt := merkle.NewInMemoryMerkleTree(rfc6962.DefaultHasher)
+	for i := 0; i < TileWidth+3; i++ {
+		if _, _, err := mt.AddLeaf([]byte{byte(i)}); err != nil {
+			t.Fatalf("AddLeaf(): %v", err)
+		}
+	}
+	bucket := newMemBucket()
+	if _, err := newTestExporter(t, &fakeLogClient{mt: mt, size: TileWidth + 3}, bucket).ExportOnce(ctx); err != nil {
+		t.Fatalf("ExportOnce(): %v", err)
+	}
+	r, err := NewReader(ctx, bucket, 1)
+	if err != nil {
+		t.Fatalf("NewReader(): %v", err)
+	}
+
+	ids := []storage.TileID{{Level: 0, Index: 0}, {Level: 0, Index: 1}, {Level: 1, Index: 0}, {Level: 0, Index: 2}}
+	tiles, err := r.GetTiles(ctx, TileWidth+2, ids)
+	if err != nil {
+		t.Fatalf("GetTiles(): %v", err)
+	}
+	for i, want := range []int{TileWidth, 2, 1, 0} {
+		if got := tiles[i]; got == nil && want != 0 || got != nil && len(got.Hashes) != want {
+			t.Errorf("GetTiles(): tile %v = %v, want %d hashes", ids[i], got, want)
+		}
+	}
+	if got, want := tiles[1].Hashes[1], mt.LeafHash(TileWidth+2); !bytes.Equal(got, want) {
+		t.Errorf("GetTiles(): tile 0/1 hash 1 = %x, want %x", got, want)
+	}
+}
@@ -16,13 +16,63 @@ package storage
 
 import (
 	"context"
+	"fmt"
 
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
+// TreeNeedsInitViolation is the type of the PreconditionFailure violation
+// attached to the errors of requests which need the root of a tree that has
+// none yet, i.e. which InitLog or InitMap hasn't initialised.
+const TreeNeedsInitViolation = "TREE_NEEDS_INIT"
+
 // ErrTreeNeedsInit is returned when calling methods on an uninitialised tree.
-var ErrTreeNeedsInit = status.Error(codes.FailedPrecondition, "tree needs initialising")
+// It's a FAILED_PRECONDITION error with a TreeNeedsInitViolation.
+var ErrTreeNeedsInit = treeNeedsInitError("tree needs initialising", "")
+
+// TreeNeedsInitError returns an error like ErrTreeNeedsInit, whose violation
+// names treeID as its subject.
+func TreeNeedsInitError(treeID int64) error {
+	return treeNeedsInitError(fmt.Sprintf("tree %d needs initialising", treeID), fmt.Sprintf("trees/%d", treeID))
+}
+
+func treeNeedsInitError(msg, subject string) error {
+	s := status.New(codes.FailedPrecondition, msg)
+	if typed, err := s.WithDetails(&errdetails.PreconditionFailure{
+		Violations: []*errdetails.PreconditionFailure_Violation{{
+			Type:        TreeNeedsInitViolation,
+			Subject:     subject,
+			Description: "the tree has no signed root yet, it must be initialised with InitLog or InitMap",
+		}},
+	}); err == nil {
+		s = typed
+	}
+	return s.Err()
+}
+
+// IsTreeNeedsInit returns whether err is ErrTreeNeedsInit, or another error
+// with a TreeNeedsInitViolation, e.g. as received by an RPC client.
+func IsTreeNeedsInit(err error) bool {
+	if err == ErrTreeNeedsInit {
+		return true
+	}
+	s, ok := status.FromError(err)
+	if !ok || s.Code() != codes.FailedPrecondition {
+		return false
+	}
+	for _, detail := range s.Details() {
+		if pf, ok := detail.(*errdetails.PreconditionFailure); ok {
+			for _, v := range pf.GetViolations() {
+				if v.GetType() == TreeNeedsInitViolation {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
 
 // ReadOnlyTreeTX represents a read-only transaction on a TreeStorage.
 // A ReadOnlyTreeTX can only modify the tree specified in its creation.
@@ -0,0 +1,58 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestIsTreeNeedsInit(t *testing.T) {
+	// Errors received by RPC clients are rebuilt from their status.
+	received := status.FromProto(status.Convert(TreeNeedsInitError(5)).Proto()).Err()
+
+	for _, test := range []struct {
+		desc string
+		err  error
+		want bool
+	}{
+		{desc: "sentinel", err: ErrTreeNeedsInit, want: true},
+		{desc: "tree", err: TreeNeedsInitError(5), want: true},
+		{desc: "received", err: received, want: true},
+		{desc: "nil", err: nil},
+		{desc: "plain", err: errors.New("tree needs initialising")},
+		{desc: "otherPrecondition", err: status.Error(codes.FailedPrecondition, "tree needs initialising")},
+	} {
+		if got := IsTreeNeedsInit(test.err); got != test.want {
+			t.Errorf("%v: IsTreeNeedsInit(%v): %v, want %v", test.desc, test.err, got, test.want)
+		}
+	}
+
+	s := status.Convert(TreeNeedsInitError(5))
+	if got, want := s.Code(), codes.FailedPrecondition; got != want {
+		t.Errorf("TreeNeedsInitError(): code %v, want %v", got, want)
+	}
+	if len(s.Details()) != 1 {
+		t.Fatalf("TreeNeedsInitError(): details %v, want a PreconditionFailure", s.Details())
+	}
+	pf, ok := s.Details()[0].(*errdetails.PreconditionFailure)
+	if !ok || len(pf.Violations) != 1 || pf.Violations[0].Subject != "trees/5" {
+		t.Errorf("TreeNeedsInitError(): details %v, want a violation of trees/5", s.Details())
+	}
+}
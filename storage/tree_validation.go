@@ -17,22 +17,48 @@ package storage
 import (
 	"bytes"
 	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
 	"github.com/google/trillian"
 	"github.com/google/trillian/crypto/keys"
 	"github.com/google/trillian/crypto/keys/der"
+	"github.com/google/trillian/crypto/keyspb"
 	"github.com/google/trillian/crypto/sigpb"
+	"github.com/google/trillian/log/leaffilter"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+
+	tcrypto "github.com/google/trillian/crypto"
 )
 
 const (
 	maxDisplayNameLength = 20
 	maxDescriptionLength = 200
+	maxLabels            = 64
+	maxLabelValueLength  = 255
+	maxLeafIndexes       = 8
+	maxLeafIndexField    = 255
+	maxMaintenanceReason = 200
+	// maxAdditionalSigningKeys is the most keys a tree can sign with, besides
+	// its private_key. Each of them signs every root.
+	maxAdditionalSigningKeys = 4
 )
 
+// labelKeyRegexp matches valid label keys, which can't contain the operators
+// of label selectors.
+var labelKeyRegexp = regexp.MustCompile(`^[a-z][a-z0-9._-]{0,62}$`)
+
+// leafIndexNameRegexp matches valid leaf index names.
+var leafIndexNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,62}$`)
+
 // ValidateTreeForCreation returns nil if tree is valid for insertion, error
 // otherwise.
 // See the documentation on trillian.Tree for reference on which values are
@@ -60,10 +86,77 @@ func ValidateTreeForCreation(ctx context.Context, tree *trillian.Tree) error {
 	case tree.DeleteTime != nil:
 		return status.Errorf(codes.InvalidArgument, "invalid delete_time: %+v (must be nil)", tree.DeleteTime)
 	}
+	if err := validateLeafIndexes(tree); err != nil {
+		return err
+	}
+	if err := validateLeafFilter(tree); err != nil {
+		return err
+	}
+	if _, err := SubtreeStrata(tree); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
 
 	return validateMutableTreeFields(ctx, tree)
 }
 
+// validateLeafIndexes checks the leaf indexes of a tree being created.
+func validateLeafIndexes(tree *trillian.Tree) error {
+	if len(tree.LeafIndexes) == 0 {
+		return nil
+	}
+	if tree.TreeType != trillian.TreeType_LOG && tree.TreeType != trillian.TreeType_PREORDERED_LOG {
+		return status.Errorf(codes.InvalidArgument, "leaf_indexes not supported for tree_type %v", tree.TreeType)
+	}
+	if len(tree.LeafIndexes) > maxLeafIndexes {
+		return status.Errorf(codes.InvalidArgument, "too many leaf_indexes, max is %v: %v", maxLeafIndexes, len(tree.LeafIndexes))
+	}
+	names := make(map[string]bool)
+	for _, spec := range tree.LeafIndexes {
+		switch {
+		case !leafIndexNameRegexp.MatchString(spec.Name):
+			return status.Errorf(codes.InvalidArgument, "invalid leaf index name: %q", spec.Name)
+		case names[spec.Name]:
+			return status.Errorf(codes.InvalidArgument, "duplicate leaf index name: %q", spec.Name)
+		case len(spec.Field) > maxLeafIndexField:
+			return status.Errorf(codes.InvalidArgument, "field of leaf index %q too big, max length is %v: %v", spec.Name, maxLeafIndexField, spec.Field)
+		}
+		for _, f := range strings.Split(spec.Field, ".") {
+			if f == "" {
+				return status.Errorf(codes.InvalidArgument, "invalid field of leaf index %q: %q", spec.Name, spec.Field)
+			}
+		}
+		names[spec.Name] = true
+	}
+	return nil
+}
+
+// validateLeafFilter checks the leaf filter of a tree being created.
+func validateLeafFilter(tree *trillian.Tree) error {
+	if tree.LeafFilter == nil {
+		return nil
+	}
+	if tree.TreeType != trillian.TreeType_LOG && tree.TreeType != trillian.TreeType_PREORDERED_LOG {
+		return status.Errorf(codes.InvalidArgument, "leaf_filter not supported for tree_type %v", tree.TreeType)
+	}
+	if _, err := leaffilter.New(tree.LeafFilter); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid leaf_filter: %v", err)
+	}
+	return nil
+}
+
+// leafIndexesEqual returns whether a and b define the same leaf indexes.
+func leafIndexesEqual(a, b []*trillian.LeafIndexSpec) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !proto.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
 // ValidateTreeForUpdate returns nil if newTree is valid for update, error
 // otherwise.
 // The newTree is compared to the storedTree to determine if readonly fields
@@ -94,6 +187,10 @@ func ValidateTreeForUpdate(ctx context.Context, storedTree, newTree *trillian.Tr
 		return status.Error(codes.InvalidArgument, "readonly field changed: deleted")
 	case !proto.Equal(storedTree.DeleteTime, newTree.DeleteTime):
 		return status.Error(codes.InvalidArgument, "readonly field changed: delete_time")
+	case !leafIndexesEqual(storedTree.LeafIndexes, newTree.LeafIndexes):
+		return status.Error(codes.InvalidArgument, "readonly field changed: leaf_indexes")
+	case !proto.Equal(storedTree.LeafFilter, newTree.LeafFilter):
+		return status.Error(codes.InvalidArgument, "readonly field changed: leaf_filter")
 	}
 	return validateMutableTreeFields(ctx, newTree)
 }
@@ -107,11 +204,59 @@ func validateMutableTreeFields(ctx context.Context, tree *trillian.Tree) error {
 	case len(tree.Description) > maxDescriptionLength:
 		return status.Errorf(codes.InvalidArgument, "description too big, max length is %v: %v", maxDescriptionLength, tree.Description)
 	}
+	if _, ok := trillian.RootTimestampPrecision_name[int32(tree.RootTimestampPrecision)]; !ok {
+		return status.Errorf(codes.InvalidArgument, "invalid root_timestamp_precision: %v", tree.RootTimestampPrecision)
+	}
+	if len(tree.Labels) > maxLabels {
+		return status.Errorf(codes.InvalidArgument, "too many labels, max is %v: %v", maxLabels, len(tree.Labels))
+	}
+	for k, v := range tree.Labels {
+		if !labelKeyRegexp.MatchString(k) {
+			return status.Errorf(codes.InvalidArgument, "invalid label key: %q", k)
+		}
+		if len(v) > maxLabelValueLength {
+			return status.Errorf(codes.InvalidArgument, "value of label %q too big, max length is %v: %v", k, maxLabelValueLength, v)
+		}
+	}
 	if duration, err := ptypes.Duration(tree.MaxRootDuration); err != nil {
 		return status.Errorf(codes.InvalidArgument, "max_root_duration malformed: %v", tree.MaxRootDuration)
 	} else if duration < 0 {
 		return status.Errorf(codes.InvalidArgument, "max_root_duration negative: %v", tree.MaxRootDuration)
 	}
+	if tree.MaxMergeDelay != nil {
+		if duration, err := ptypes.Duration(tree.MaxMergeDelay); err != nil {
+			return status.Errorf(codes.InvalidArgument, "max_merge_delay malformed: %v", tree.MaxMergeDelay)
+		} else if duration < 0 {
+			return status.Errorf(codes.InvalidArgument, "max_merge_delay negative: %v", tree.MaxMergeDelay)
+		} else if duration > 0 && !isLogTree(tree.TreeType) {
+			return status.Errorf(codes.InvalidArgument, "max_merge_delay not supported for tree_type %v", tree.TreeType)
+		}
+	}
+	if w := tree.ValidityWindow; w != nil {
+		if !isLogTree(tree.TreeType) {
+			return status.Errorf(codes.InvalidArgument, "validity_window not supported for tree_type %v", tree.TreeType)
+		}
+		if err := validateValidityWindow(w); err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid validity_window: %v", err)
+		}
+	}
+	if _, ok := trillian.WriteVerification_name[int32(tree.WriteVerification)]; !ok {
+		return status.Errorf(codes.InvalidArgument, "invalid write_verification: %v", tree.WriteVerification)
+	} else if tree.WriteVerification == trillian.WriteVerification_VERIFY_WRITES && !isLogTree(tree.TreeType) {
+		return status.Errorf(codes.InvalidArgument, "write_verification not supported for tree_type %v", tree.TreeType)
+	}
+	if m := tree.Maintenance; m != nil {
+		if len(m.Reason) > maxMaintenanceReason {
+			return status.Errorf(codes.InvalidArgument, "maintenance reason too big, max length is %v: %v", maxMaintenanceReason, m.Reason)
+		}
+		if m.RetryAfter != nil {
+			if duration, err := ptypes.Duration(m.RetryAfter); err != nil {
+				return status.Errorf(codes.InvalidArgument, "maintenance retry_after malformed: %v", m.RetryAfter)
+			} else if duration < 0 {
+				return status.Errorf(codes.InvalidArgument, "maintenance retry_after negative: %v", m.RetryAfter)
+			}
+		}
+	}
 
 	// Implementations may vary, so let's assume storage_settings is mutable.
 	// Other than checking that it's a valid Any there isn't much to do at this layer, though.
@@ -122,23 +267,100 @@ func validateMutableTreeFields(ctx context.Context, tree *trillian.Tree) error {
 		}
 	}
 
+	if ptypes.Is(tree.PrivateKey, &keyspb.ExternalKey{}) {
+		if err := validateExternalKey(tree); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+	} else if _, err := validateKeyPair(ctx, tree.PrivateKey, tree.PublicKey); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if len(tree.AdditionalSigningKeys) > maxAdditionalSigningKeys {
+		return status.Errorf(codes.InvalidArgument, "too many additional_signing_keys, max is %v: %v", maxAdditionalSigningKeys, len(tree.AdditionalSigningKeys))
+	}
+	for i, key := range tree.AdditionalSigningKeys {
+		switch {
+		case key.SignatureAlgorithm == sigpb.DigitallySigned_ANONYMOUS:
+			return status.Errorf(codes.InvalidArgument, "invalid signature_algorithm of additional_signing_keys[%d]: %s", i, key.SignatureAlgorithm)
+		case key.PrivateKey == nil:
+			return status.Errorf(codes.InvalidArgument, "a private_key is required for additional_signing_keys[%d]", i)
+		case key.PublicKey == nil:
+			return status.Errorf(codes.InvalidArgument, "a public_key is required for additional_signing_keys[%d]", i)
+		}
+		signer, err := validateKeyPair(ctx, key.PrivateKey, key.PublicKey)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "additional_signing_keys[%d]: %v", i, err)
+		}
+		if alg := tcrypto.SignatureAlgorithm(signer.Public()); alg != key.SignatureAlgorithm {
+			return status.Errorf(codes.InvalidArgument, "additional_signing_keys[%d]: signature_algorithm is %s, but private_key is a %s key", i, key.SignatureAlgorithm, alg)
+		}
+	}
+
+	return nil
+}
+
+// validateExternalKey checks that the private_key of tree is a valid
+// keyspb.ExternalKey and that its public_key is one of its
+// signature_algorithm. The key itself may only be usable by the servers that
+// sign tree heads, so it can't be checked to match the public key here; the
+// admin server does that with a key challenge instead.
+func validateExternalKey(tree *trillian.Tree) error {
+	var ext keyspb.ExternalKey
+	if err := ptypes.UnmarshalAny(tree.PrivateKey, &ext); err != nil {
+		return fmt.Errorf("invalid private_key: %v", err)
+	}
+	if ext.Key == nil {
+		return errors.New("invalid private_key: an ExternalKey requires a key")
+	}
+	pub, err := der.UnmarshalPublicKey(tree.PublicKey.GetDer())
+	if err != nil {
+		return fmt.Errorf("invalid public_key: %v", err)
+	}
+	if alg := tcrypto.SignatureAlgorithm(pub); alg != tree.SignatureAlgorithm {
+		return fmt.Errorf("signature_algorithm is %s, but public_key is a %s key", tree.SignatureAlgorithm, alg)
+	}
+	return nil
+}
+
+// validateKeyPair checks that the private key can be obtained and matches the
+// public key, and returns its signer.
+func validateKeyPair(ctx context.Context, privateKey *any.Any, publicKey *keyspb.PublicKey) (crypto.Signer, error) {
 	var privateKeyProto ptypes.DynamicAny
-	if err := ptypes.UnmarshalAny(tree.PrivateKey, &privateKeyProto); err != nil {
-		return status.Errorf(codes.InvalidArgument, "invalid private_key: %v", err)
+	if err := ptypes.UnmarshalAny(privateKey, &privateKeyProto); err != nil {
+		return nil, fmt.Errorf("invalid private_key: %v", err)
 	}
 
-	// Check that the private key can be obtained and matches the public key.
-	privateKey, err := keys.NewSigner(ctx, privateKeyProto.Message)
+	signer, err := keys.NewSigner(ctx, privateKeyProto.Message)
 	if err != nil {
-		return status.Errorf(codes.InvalidArgument, "invalid private_key: %v", err)
+		return nil, fmt.Errorf("invalid private_key: %v", err)
 	}
-	publicKeyDER, err := der.MarshalPublicKey(privateKey.Public())
+	publicKeyDER, err := der.MarshalPublicKey(signer.Public())
 	if err != nil {
-		return status.Errorf(codes.InvalidArgument, "invalid private_key: %v", err)
+		return nil, fmt.Errorf("invalid private_key: %v", err)
 	}
-	if !bytes.Equal(publicKeyDER, tree.PublicKey.GetDer()) {
-		return status.Errorf(codes.InvalidArgument, "private_key and public_key are not a matching pair")
+	if !bytes.Equal(publicKeyDER, publicKey.GetDer()) {
+		return nil, errors.New("private_key and public_key are not a matching pair")
 	}
+	return signer, nil
+}
 
+// validateValidityWindow checks that the bounds of w are valid timestamps, and
+// that not_before isn't after not_after.
+func validateValidityWindow(w *trillian.ValidityWindow) error {
+	var notBefore, notAfter time.Time
+	var err error
+	if w.NotBefore != nil {
+		if notBefore, err = ptypes.Timestamp(w.NotBefore); err != nil {
+			return fmt.Errorf("not_before malformed: %v", err)
+		}
+	}
+	if w.NotAfter != nil {
+		if notAfter, err = ptypes.Timestamp(w.NotAfter); err != nil {
+			return fmt.Errorf("not_after malformed: %v", err)
+		}
+	}
+	if w.NotBefore != nil && w.NotAfter != nil && notBefore.After(notAfter) {
+		return fmt.Errorf("not_before %v is after not_after %v", notBefore, notAfter)
+	}
 	return nil
 }
@@ -16,6 +16,10 @@ package storage
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -23,6 +27,7 @@ import (
 	"github.com/golang/protobuf/ptypes/any"
 	"github.com/golang/protobuf/ptypes/empty"
 	"github.com/google/trillian"
+	"github.com/google/trillian/crypto/keys/der"
 	"github.com/google/trillian/crypto/keyspb"
 	"github.com/google/trillian/crypto/sigpb"
 	"github.com/google/trillian/testonly"
@@ -103,6 +108,28 @@ func TestValidateTreeForCreation(t *testing.T) {
 	nilPublicKey := newTree()
 	nilPublicKey.PublicKey = nil
 
+	pkcs11Key, err := ptypes.MarshalAny(&keyspb.PKCS11Config{TokenLabel: "log"})
+	if err != nil {
+		t.Fatalf("Error marshaling proto: %v", err)
+	}
+	externalKey := newTree()
+	if externalKey.PrivateKey, err = ptypes.MarshalAny(&keyspb.ExternalKey{Key: pkcs11Key}); err != nil {
+		t.Fatalf("Error marshaling proto: %v", err)
+	}
+
+	emptyExternalKey := newTree()
+	if emptyExternalKey.PrivateKey, err = ptypes.MarshalAny(&keyspb.ExternalKey{}); err != nil {
+		t.Fatalf("Error marshaling proto: %v", err)
+	}
+
+	externalKeyInvalidPublicKey := newTree()
+	externalKeyInvalidPublicKey.PrivateKey = externalKey.PrivateKey
+	externalKeyInvalidPublicKey.PublicKey.Der = []byte("foobar")
+
+	externalKeyWrongAlgorithm := newTree()
+	externalKeyWrongAlgorithm.PrivateKey = externalKey.PrivateKey
+	externalKeyWrongAlgorithm.SignatureAlgorithm = sigpb.DigitallySigned_RSA
+
 	invalidSettings := newTree()
 	invalidSettings.StorageSettings = &any.Any{Value: []byte("foobar")}
 
@@ -120,6 +147,33 @@ func TestValidateTreeForCreation(t *testing.T) {
 	invalidRootDuration := newTree()
 	invalidRootDuration.MaxRootDuration = ptypes.DurationProto(-1 * time.Second)
 
+	mapMergeDelay := newTree()
+	mapMergeDelay.TreeType = trillian.TreeType_MAP
+	mapMergeDelay.MaxMergeDelay = ptypes.DurationProto(time.Hour)
+
+	now := time.Now()
+	notBefore, _ := ptypes.TimestampProto(now)
+	notAfter, _ := ptypes.TimestampProto(now.Add(time.Hour))
+	validityWindow := newTree()
+	validityWindow.ValidityWindow = &trillian.ValidityWindow{NotBefore: notBefore, NotAfter: notAfter}
+
+	invertedValidityWindow := newTree()
+	invertedValidityWindow.ValidityWindow = &trillian.ValidityWindow{NotBefore: notAfter, NotAfter: notBefore}
+
+	mapValidityWindow := newTree()
+	mapValidityWindow.TreeType = trillian.TreeType_MAP
+	mapValidityWindow.ValidityWindow = &trillian.ValidityWindow{NotAfter: notAfter}
+
+	verifyWrites := newTree()
+	verifyWrites.WriteVerification = trillian.WriteVerification_VERIFY_WRITES
+
+	mapVerifyWrites := newTree()
+	mapVerifyWrites.TreeType = trillian.TreeType_MAP
+	mapVerifyWrites.WriteVerification = trillian.WriteVerification_VERIFY_WRITES
+
+	invalidWriteVerification := newTree()
+	invalidWriteVerification.WriteVerification = -1
+
 	deletedTree := newTree()
 	deletedTree.Deleted = true
 
@@ -139,6 +193,39 @@ func TestValidateTreeForCreation(t *testing.T) {
 			desc: "valid2",
 			tree: valid2,
 		},
+		{
+			desc:    "mapMergeDelay",
+			tree:    mapMergeDelay,
+			wantErr: true,
+		},
+		{
+			desc: "validityWindow",
+			tree: validityWindow,
+		},
+		{
+			desc:    "invertedValidityWindow",
+			tree:    invertedValidityWindow,
+			wantErr: true,
+		},
+		{
+			desc:    "mapValidityWindow",
+			tree:    mapValidityWindow,
+			wantErr: true,
+		},
+		{
+			desc: "verifyWrites",
+			tree: verifyWrites,
+		},
+		{
+			desc:    "mapVerifyWrites",
+			tree:    mapVerifyWrites,
+			wantErr: true,
+		},
+		{
+			desc:    "invalidWriteVerification",
+			tree:    invalidWriteVerification,
+			wantErr: true,
+		},
 		{
 			desc:    "nilTree",
 			tree:    nil,
@@ -214,6 +301,25 @@ func TestValidateTreeForCreation(t *testing.T) {
 			tree:    invalidSettings,
 			wantErr: true,
 		},
+		{
+			desc: "externalKey",
+			tree: externalKey,
+		},
+		{
+			desc:    "emptyExternalKey",
+			tree:    emptyExternalKey,
+			wantErr: true,
+		},
+		{
+			desc:    "externalKeyInvalidPublicKey",
+			tree:    externalKeyInvalidPublicKey,
+			wantErr: true,
+		},
+		{
+			desc:    "externalKeyWrongAlgorithm",
+			tree:    externalKeyWrongAlgorithm,
+			wantErr: true,
+		},
 		{
 			desc: "validSettings",
 			tree: validSettings,
@@ -301,6 +407,169 @@ func TestValidateTreeForUpdate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			desc: "validRootTimestampPrecision",
+			updatefn: func(tree *trillian.Tree) {
+				tree.RootTimestampPrecision = trillian.RootTimestampPrecision_SECOND_PRECISION
+			},
+		},
+		{
+			desc: "invalidRootTimestampPrecision",
+			updatefn: func(tree *trillian.Tree) {
+				tree.RootTimestampPrecision = trillian.RootTimestampPrecision(-1)
+			},
+			wantErr: true,
+		},
+		{
+			desc: "validLabels",
+			updatefn: func(tree *trillian.Tree) {
+				tree.Labels = map[string]string{"env": "prod", "customer.id": "", "a_b-c": strings.Repeat("v", 255)}
+			},
+		},
+		{
+			desc: "invalidLabelKey",
+			updatefn: func(tree *trillian.Tree) {
+				tree.Labels = map[string]string{"env=prod": "prod"}
+			},
+			wantErr: true,
+		},
+		{
+			desc: "uppercaseLabelKey",
+			updatefn: func(tree *trillian.Tree) {
+				tree.Labels = map[string]string{"Env": "prod"}
+			},
+			wantErr: true,
+		},
+		{
+			desc: "emptyLabelKey",
+			updatefn: func(tree *trillian.Tree) {
+				tree.Labels = map[string]string{"": "prod"}
+			},
+			wantErr: true,
+		},
+		{
+			desc: "labelKeyTooLong",
+			updatefn: func(tree *trillian.Tree) {
+				tree.Labels = map[string]string{strings.Repeat("k", 64): "prod"}
+			},
+			wantErr: true,
+		},
+		{
+			desc: "labelValueTooLong",
+			updatefn: func(tree *trillian.Tree) {
+				tree.Labels = map[string]string{"env": strings.Repeat("v", 256)}
+			},
+			wantErr: true,
+		},
+		{
+			desc: "tooManyLabels",
+			updatefn: func(tree *trillian.Tree) {
+				tree.Labels = make(map[string]string)
+				for i := 0; i <= 64; i++ {
+					tree.Labels[fmt.Sprintf("l%d", i)] = ""
+				}
+			},
+			wantErr: true,
+		},
+		{
+			desc: "maxMergeDelay",
+			updatefn: func(tree *trillian.Tree) {
+				tree.MaxMergeDelay = ptypes.DurationProto(24 * time.Hour)
+			},
+		},
+		{
+			desc: "negativeMaxMergeDelay",
+			updatefn: func(tree *trillian.Tree) {
+				tree.MaxMergeDelay = ptypes.DurationProto(-time.Hour)
+			},
+			wantErr: true,
+		},
+		{
+			desc: "maintenance",
+			updatefn: func(tree *trillian.Tree) {
+				tree.Maintenance = &trillian.MaintenanceMode{RetryAfter: ptypes.DurationProto(time.Minute), Reason: strings.Repeat("r", 200)}
+			},
+		},
+		{
+			desc: "maintenanceWithoutRetryAfter",
+			updatefn: func(tree *trillian.Tree) {
+				tree.Maintenance = &trillian.MaintenanceMode{}
+			},
+		},
+		{
+			desc: "negativeMaintenanceRetryAfter",
+			updatefn: func(tree *trillian.Tree) {
+				tree.Maintenance = &trillian.MaintenanceMode{RetryAfter: ptypes.DurationProto(-time.Second)}
+			},
+			wantErr: true,
+		},
+		{
+			desc: "maintenanceReasonTooLong",
+			updatefn: func(tree *trillian.Tree) {
+				tree.Maintenance = &trillian.MaintenanceMode{Reason: strings.Repeat("r", 201)}
+			},
+			wantErr: true,
+		},
+		{
+			desc: "additionalSigningKeys",
+			updatefn: func(tree *trillian.Tree) {
+				tree.AdditionalSigningKeys = []*trillian.SigningKey{newEd25519SigningKey(), newEd25519SigningKey()}
+			},
+		},
+		{
+			desc: "tooManyAdditionalSigningKeys",
+			updatefn: func(tree *trillian.Tree) {
+				for i := 0; i <= maxAdditionalSigningKeys; i++ {
+					tree.AdditionalSigningKeys = append(tree.AdditionalSigningKeys, newEd25519SigningKey())
+				}
+			},
+			wantErr: true,
+		},
+		{
+			desc: "anonymousAdditionalSigningKey",
+			updatefn: func(tree *trillian.Tree) {
+				key := newEd25519SigningKey()
+				key.SignatureAlgorithm = sigpb.DigitallySigned_ANONYMOUS
+				tree.AdditionalSigningKeys = []*trillian.SigningKey{key}
+			},
+			wantErr: true,
+		},
+		{
+			desc: "mismatchedAdditionalSignatureAlgorithm",
+			updatefn: func(tree *trillian.Tree) {
+				key := newEd25519SigningKey()
+				key.SignatureAlgorithm = sigpb.DigitallySigned_ECDSA
+				tree.AdditionalSigningKeys = []*trillian.SigningKey{key}
+			},
+			wantErr: true,
+		},
+		{
+			desc: "nilAdditionalPrivateKey",
+			updatefn: func(tree *trillian.Tree) {
+				key := newEd25519SigningKey()
+				key.PrivateKey = nil
+				tree.AdditionalSigningKeys = []*trillian.SigningKey{key}
+			},
+			wantErr: true,
+		},
+		{
+			desc: "nilAdditionalPublicKey",
+			updatefn: func(tree *trillian.Tree) {
+				key := newEd25519SigningKey()
+				key.PublicKey = nil
+				tree.AdditionalSigningKeys = []*trillian.SigningKey{key}
+			},
+			wantErr: true,
+		},
+		{
+			desc: "mismatchedAdditionalKeyPair",
+			updatefn: func(tree *trillian.Tree) {
+				key := newEd25519SigningKey()
+				key.PublicKey = newEd25519SigningKey().PublicKey
+				tree.AdditionalSigningKeys = []*trillian.SigningKey{key}
+			},
+			wantErr: true,
+		},
 		{
 			desc: "differentPrivateKeyProtoButSameKeyMaterial",
 			updatefn: func(tree *trillian.Tree) {
@@ -404,6 +673,20 @@ func TestValidateTreeForUpdate(t *testing.T) {
 			updatefn: func(tree *trillian.Tree) { tree.DeleteTime = ptypes.TimestampNow() },
 			wantErr:  true,
 		},
+		{
+			desc: "LeafIndexes",
+			updatefn: func(tree *trillian.Tree) {
+				tree.LeafIndexes = []*trillian.LeafIndexSpec{{Name: "domain", Field: "domain"}}
+			},
+			wantErr: true,
+		},
+		{
+			desc: "LeafFilter",
+			updatefn: func(tree *trillian.Tree) {
+				tree.LeafFilter = &trillian.LeafFilterSpec{ExpectedLeaves: 1000, FalsePositiveRate: 0.01}
+			},
+			wantErr: true,
+		},
 	}
 	for _, test := range tests {
 		tree := newTree()
@@ -420,6 +703,131 @@ func TestValidateTreeForUpdate(t *testing.T) {
 	}
 }
 
+func TestValidateTreeForCreationLeafIndexes(t *testing.T) {
+	ctx := context.Background()
+	tooMany := make([]*trillian.LeafIndexSpec, 0, 9)
+	for i := 0; i < 9; i++ {
+		tooMany = append(tooMany, &trillian.LeafIndexSpec{Name: fmt.Sprintf("i%d", i), Field: "f"})
+	}
+
+	for _, test := range []struct {
+		desc     string
+		treeType trillian.TreeType
+		indexes  []*trillian.LeafIndexSpec
+		wantErr  bool
+	}{
+		{
+			desc:    "valid",
+			indexes: []*trillian.LeafIndexSpec{{Name: "domain", Field: "subject.domain"}, {Name: "package_name-2", Field: "package"}},
+		},
+		{
+			desc:     "preordered",
+			treeType: trillian.TreeType_PREORDERED_LOG,
+			indexes:  []*trillian.LeafIndexSpec{{Name: "domain", Field: "domain"}},
+		},
+		{
+			desc:     "map",
+			treeType: trillian.TreeType_MAP,
+			indexes:  []*trillian.LeafIndexSpec{{Name: "domain", Field: "domain"}},
+			wantErr:  true,
+		},
+		{
+			desc:    "invalidName",
+			indexes: []*trillian.LeafIndexSpec{{Name: "Domain", Field: "domain"}},
+			wantErr: true,
+		},
+		{
+			desc:    "duplicateName",
+			indexes: []*trillian.LeafIndexSpec{{Name: "domain", Field: "domain"}, {Name: "domain", Field: "host"}},
+			wantErr: true,
+		},
+		{
+			desc:    "emptyField",
+			indexes: []*trillian.LeafIndexSpec{{Name: "domain"}},
+			wantErr: true,
+		},
+		{
+			desc:    "emptyFieldPart",
+			indexes: []*trillian.LeafIndexSpec{{Name: "domain", Field: "subject..domain"}},
+			wantErr: true,
+		},
+		{
+			desc:    "longField",
+			indexes: []*trillian.LeafIndexSpec{{Name: "domain", Field: strings.Repeat("f", 256)}},
+			wantErr: true,
+		},
+		{
+			desc:    "tooMany",
+			indexes: tooMany,
+			wantErr: true,
+		},
+	} {
+		tree := newTree()
+		if test.treeType != trillian.TreeType_UNKNOWN_TREE_TYPE {
+			tree.TreeType = test.treeType
+		}
+		tree.LeafIndexes = test.indexes
+
+		err := ValidateTreeForCreation(ctx, tree)
+		switch hasErr := err != nil; {
+		case hasErr != test.wantErr:
+			t.Errorf("%v: ValidateTreeForCreation() = %v, wantErr = %v", test.desc, err, test.wantErr)
+		case hasErr && status.Code(err) != codes.InvalidArgument:
+			t.Errorf("%v: ValidateTreeForCreation() = %v, wantCode = %v", test.desc, err, codes.InvalidArgument)
+		}
+	}
+}
+
+func TestValidateTreeForCreationLeafFilter(t *testing.T) {
+	ctx := context.Background()
+	for _, test := range []struct {
+		desc     string
+		treeType trillian.TreeType
+		filter   *trillian.LeafFilterSpec
+		wantErr  bool
+	}{
+		{
+			desc:   "valid",
+			filter: &trillian.LeafFilterSpec{ExpectedLeaves: 1000000, FalsePositiveRate: 0.001},
+		},
+		{
+			desc:     "preordered",
+			treeType: trillian.TreeType_PREORDERED_LOG,
+			filter:   &trillian.LeafFilterSpec{ExpectedLeaves: 1000000, FalsePositiveRate: 0.001},
+		},
+		{
+			desc:     "map",
+			treeType: trillian.TreeType_MAP,
+			filter:   &trillian.LeafFilterSpec{ExpectedLeaves: 1000000, FalsePositiveRate: 0.001},
+			wantErr:  true,
+		},
+		{
+			desc:    "noLeaves",
+			filter:  &trillian.LeafFilterSpec{FalsePositiveRate: 0.001},
+			wantErr: true,
+		},
+		{
+			desc:    "rateTooLow",
+			filter:  &trillian.LeafFilterSpec{ExpectedLeaves: 1000000, FalsePositiveRate: 1e-10},
+			wantErr: true,
+		},
+	} {
+		tree := newTree()
+		if test.treeType != trillian.TreeType_UNKNOWN_TREE_TYPE {
+			tree.TreeType = test.treeType
+		}
+		tree.LeafFilter = test.filter
+
+		err := ValidateTreeForCreation(ctx, tree)
+		switch hasErr := err != nil; {
+		case hasErr != test.wantErr:
+			t.Errorf("%v: ValidateTreeForCreation() = %v, wantErr = %v", test.desc, err, test.wantErr)
+		case hasErr && status.Code(err) != codes.InvalidArgument:
+			t.Errorf("%v: ValidateTreeForCreation() = %v, wantCode = %v", test.desc, err, codes.InvalidArgument)
+		}
+	}
+}
+
 // newTree returns a valid tree for tests.
 func newTree() *trillian.Tree {
 	privateKey, err := ptypes.MarshalAny(&keyspb.PEMKeyFile{
@@ -445,3 +853,28 @@ func newTree() *trillian.Tree {
 		MaxRootDuration: ptypes.DurationProto(1000 * time.Millisecond),
 	}
 }
+
+// newEd25519SigningKey returns a SigningKey with a new Ed25519 key.
+func newEd25519SigningKey() *trillian.SigningKey {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	privDER, err := der.MarshalPrivateKey(priv)
+	if err != nil {
+		panic(err)
+	}
+	pubDER, err := der.MarshalPublicKey(pub)
+	if err != nil {
+		panic(err)
+	}
+	privateKey, err := ptypes.MarshalAny(&keyspb.PrivateKey{Der: privDER})
+	if err != nil {
+		panic(err)
+	}
+	return &trillian.SigningKey{
+		SignatureAlgorithm: sigpb.DigitallySigned_ED25519,
+		PrivateKey:         privateKey,
+		PublicKey:          &keyspb.PublicKey{Der: pubDER},
+	}
+}
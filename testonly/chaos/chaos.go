@@ -0,0 +1,151 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package chaos injects faults into the storage and RPC layers of Trillian,
+// so that the resilience of the sequencer and servers can be tested. It's for
+// tests only: the servers only use it when built with the "chaos" tag, see
+// FromFlags.
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Faults configures the faults injected by an Injector. Rates are
+// probabilities, between 0 and 1.
+type Faults struct {
+	// Latency is the maximum delay added before each storage operation and
+	// RPC. Delays are uniformly distributed between 0 and Latency.
+	Latency time.Duration
+	// AbortRate is the rate at which storage operations fail with an Aborted
+	// error, without reaching the storage.
+	AbortRate float64
+	// PartialWriteRate is the rate at which writes within a read-write
+	// transaction fail with an Aborted error after writing only some of their
+	// nodes or leaves, so that the transaction must be rolled back.
+	PartialWriteRate float64
+	// CommitErrorRate is the rate at which read-write transactions return an
+	// Unavailable error although they committed, as they would if the
+	// connection to the storage dropped before the commit was acknowledged.
+	CommitErrorRate float64
+	// RPCErrorRate is the rate at which RPCs fail with an Unavailable error,
+	// without reaching their handler.
+	RPCErrorRate float64
+}
+
+// Validate checks that the rates of f are probabilities.
+func (f Faults) Validate() error {
+	for _, r := range []struct {
+		name string
+		rate float64
+	}{
+		{"AbortRate", f.AbortRate},
+		{"PartialWriteRate", f.PartialWriteRate},
+		{"CommitErrorRate", f.CommitErrorRate},
+		{"RPCErrorRate", f.RPCErrorRate},
+	} {
+		if r.rate < 0 || r.rate > 1 {
+			return fmt.Errorf("%s must be between 0 and 1, got %v", r.name, r.rate)
+		}
+	}
+	if f.Latency < 0 {
+		return fmt.Errorf("Latency must not be negative, got %v", f.Latency)
+	}
+	return nil
+}
+
+// Injector injects Faults, at random but reproducibly for a given seed and
+// order of operations. It is safe for concurrent use.
+type Injector struct {
+	faults Faults
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// New returns an Injector of f, whose random choices are seeded with seed.
+func New(f Faults, seed int64) (*Injector, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	return &Injector{faults: f, rand: rand.New(rand.NewSource(seed))}, nil
+}
+
+// chance returns true with probability rate.
+func (in *Injector) chance(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.rand.Float64() < rate
+}
+
+// intn returns a random int in [0, n).
+func (in *Injector) intn(n int) int {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.rand.Intn(n)
+}
+
+// delay sleeps for a random duration up to the configured Latency, or until
+// ctx is done.
+func (in *Injector) delay(ctx context.Context) error {
+	if in.faults.Latency <= 0 {
+		return nil
+	}
+	in.mu.Lock()
+	d := time.Duration(in.rand.Int63n(int64(in.faults.Latency)))
+	in.mu.Unlock()
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// before delays the operation op and decides whether it's aborted.
+func (in *Injector) before(ctx context.Context, op string) error {
+	if err := in.delay(ctx); err != nil {
+		return err
+	}
+	if in.chance(in.faults.AbortRate) {
+		glog.V(1).Infof("chaos: aborting %s", op)
+		return status.Errorf(codes.Aborted, "chaos: injected abort of %s", op)
+	}
+	return nil
+}
+
+// UnaryInterceptor delays RPCs and fails some of them, according to the
+// Latency and RPCErrorRate of the Injector's Faults.
+func (in *Injector) UnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := in.delay(ctx); err != nil {
+		return nil, err
+	}
+	if in.chance(in.faults.RPCErrorRate) {
+		glog.V(1).Infof("chaos: failing %s", info.FullMethod)
+		return nil, status.Errorf(codes.Unavailable, "chaos: injected failure of %s", info.FullMethod)
+	}
+	return handler(ctx, req)
+}
@@ -0,0 +1,146 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chaos
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFaultsValidate(t *testing.T) {
+	for _, test := range []struct {
+		desc    string
+		faults  Faults
+		wantErr bool
+	}{
+		{desc: "none"},
+		{desc: "all", faults: Faults{Latency: time.Millisecond, AbortRate: 0.1, PartialWriteRate: 0.2, CommitErrorRate: 1, RPCErrorRate: 0}},
+		{desc: "negativeRate", faults: Faults{AbortRate: -0.1}, wantErr: true},
+		{desc: "rateAboveOne", faults: Faults{RPCErrorRate: 1.5}, wantErr: true},
+		{desc: "negativeLatency", faults: Faults{Latency: -time.Second}, wantErr: true},
+	} {
+		if err := test.faults.Validate(); (err != nil) != test.wantErr {
+			t.Errorf("%v: Validate() = %v, want err? %v", test.desc, err, test.wantErr)
+		}
+	}
+}
+
+func newInjector(t *testing.T, f Faults) *Injector {
+	t.Helper()
+	in, err := New(f, 1)
+	if err != nil {
+		t.Fatalf("New(%+v): %v", f, err)
+	}
+	return in
+}
+
+func TestLogStorage_Abort(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// The mock expects no calls: aborted operations don't reach the storage.
+	ls := newInjector(t, Faults{AbortRate: 1}).LogStorage(storage.NewMockLogStorage(ctrl))
+	ctx := context.Background()
+	if _, err := ls.QueueLeaves(ctx, 1, []*trillian.LogLeaf{{}}, time.Now()); status.Code(err) != codes.Aborted {
+		t.Errorf("QueueLeaves() returned err = %v, want code %v", err, codes.Aborted)
+	}
+	if err := ls.ReadWriteTransaction(ctx, 1, func(context.Context, storage.LogTreeTX) error { return nil }); status.Code(err) != codes.Aborted {
+		t.Errorf("ReadWriteTransaction() returned err = %v, want code %v", err, codes.Aborted)
+	}
+}
+
+func TestLogStorage_PartialWrite(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	nodes := make([]storage.Node, 10)
+	mockTX := storage.NewMockLogTreeTX(ctrl)
+	mockTX.EXPECT().SetMerkleNodes(gomock.Any(), gomock.Any()).Do(func(_ context.Context, written []storage.Node) {
+		if len(written) >= len(nodes) {
+			t.Errorf("SetMerkleNodes() wrote %d nodes, want fewer than %d", len(written), len(nodes))
+		}
+	}).Return(nil)
+	mockStorage := storage.NewMockLogStorage(ctrl)
+	mockStorage.EXPECT().ReadWriteTransaction(gomock.Any(), int64(1), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, treeID int64, f storage.LogTXFunc) error {
+			return f(ctx, mockTX)
+		})
+
+	ls := newInjector(t, Faults{PartialWriteRate: 1}).LogStorage(mockStorage)
+	err := ls.ReadWriteTransaction(context.Background(), 1, func(ctx context.Context, tx storage.LogTreeTX) error {
+		return tx.SetMerkleNodes(ctx, nodes)
+	})
+	if status.Code(err) != codes.Aborted {
+		t.Errorf("ReadWriteTransaction() returned err = %v, want code %v", err, codes.Aborted)
+	}
+}
+
+func TestLogStorage_CommitError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := storage.NewMockLogStorage(ctrl)
+	mockStorage.EXPECT().ReadWriteTransaction(gomock.Any(), int64(1), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, treeID int64, f storage.LogTXFunc) error {
+			return f(ctx, storage.NewMockLogTreeTX(ctrl))
+		})
+
+	ls := newInjector(t, Faults{CommitErrorRate: 1}).LogStorage(mockStorage)
+	ran := false
+	err := ls.ReadWriteTransaction(context.Background(), 1, func(context.Context, storage.LogTreeTX) error {
+		ran = true
+		return nil
+	})
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("ReadWriteTransaction() returned err = %v, want code %v", err, codes.Unavailable)
+	}
+	if !ran {
+		t.Error("ReadWriteTransaction() didn't run the transaction")
+	}
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/trillian.TrillianLog/QueueLeaf"}
+	for _, test := range []struct {
+		desc     string
+		faults   Faults
+		wantCode codes.Code
+	}{
+		{desc: "noFaults"},
+		{desc: "latency", faults: Faults{Latency: time.Millisecond}},
+		{desc: "error", faults: Faults{RPCErrorRate: 1}, wantCode: codes.Unavailable},
+	} {
+		called := false
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			called = true
+			return "ok", nil
+		}
+		_, err := newInjector(t, test.faults).UnaryInterceptor(context.Background(), "req", info, handler)
+		if got := status.Code(err); got != test.wantCode {
+			t.Errorf("%v: UnaryInterceptor() returned err = %v, want code %v", test.desc, err, test.wantCode)
+		}
+		if want := test.wantCode == codes.OK; called != want {
+			t.Errorf("%v: handler called = %v, want %v", test.desc, called, want)
+		}
+	}
+}
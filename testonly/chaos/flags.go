@@ -0,0 +1,55 @@
+// +build chaos
+
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chaos
+
+import (
+	"flag"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+var (
+	latency          = flag.Duration("chaos_latency", 0, "Maximum random delay added to each storage operation and RPC")
+	abortRate        = flag.Float64("chaos_abort_rate", 0, "Rate at which storage operations fail with an Aborted error")
+	partialWriteRate = flag.Float64("chaos_partial_write_rate", 0, "Rate at which storage writes fail after writing only some of their nodes or leaves")
+	commitErrorRate  = flag.Float64("chaos_commit_error_rate", 0, "Rate at which storage transactions return an error although they committed")
+	rpcErrorRate     = flag.Float64("chaos_rpc_error_rate", 0, "Rate at which RPCs fail with an Unavailable error")
+	seed             = flag.Int64("chaos_seed", 0, "Seed of the random fault injection, or 0 to use the current time")
+)
+
+// FromFlags returns an Injector of the faults configured by the --chaos_*
+// flags, which only exist in binaries built with the "chaos" tag, or nil if
+// no fault is configured.
+func FromFlags() (*Injector, error) {
+	f := Faults{
+		Latency:          *latency,
+		AbortRate:        *abortRate,
+		PartialWriteRate: *partialWriteRate,
+		CommitErrorRate:  *commitErrorRate,
+		RPCErrorRate:     *rpcErrorRate,
+	}
+	if f == (Faults{}) {
+		return nil, nil
+	}
+	s := *seed
+	if s == 0 {
+		s = time.Now().UnixNano()
+	}
+	glog.Warningf("chaos: injecting faults %+v with seed %d", f, s)
+	return New(f, s)
+}
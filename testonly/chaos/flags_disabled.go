@@ -1,3 +1,5 @@
+// +build !chaos
+
 // Copyright 2018 Google Inc. All Rights Reserved.
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
@@ -12,12 +14,10 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-package cloudspanner
+package chaos
 
-// Default strata sizes for the different tree types.
-var (
-	// defLogStrata is a suitable set of stratum sizes for Log trees.
-	// Log trees are dense and so each individual stratum cannot over-commit on
-	// storage.
-	defLogStrata = []int{8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8}
-)
+// FromFlags returns nil: binaries built without the "chaos" tag don't inject
+// faults.
+func FromFlags() (*Injector, error) {
+	return nil, nil
+}
@@ -0,0 +1,196 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chaos
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/google/trillian"
+	"github.com/google/trillian/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LogStorage returns a LogStorage which injects faults into the operations
+// of ls and of the transactions it begins. Optional interfaces implemented by
+// ls, e.g. storage.LogCompactor, aren't exposed by the returned LogStorage.
+func (in *Injector) LogStorage(ls storage.LogStorage) storage.LogStorage {
+	return &logStorage{LogStorage: ls, in: in}
+}
+
+type logStorage struct {
+	storage.LogStorage
+	in *Injector
+}
+
+func (ls *logStorage) Snapshot(ctx context.Context) (storage.ReadOnlyLogTX, error) {
+	if err := ls.in.before(ctx, "Snapshot"); err != nil {
+		return nil, err
+	}
+	return ls.LogStorage.Snapshot(ctx)
+}
+
+func (ls *logStorage) SnapshotForTree(ctx context.Context, treeID int64) (storage.ReadOnlyLogTreeTX, error) {
+	if err := ls.in.before(ctx, "SnapshotForTree"); err != nil {
+		return nil, err
+	}
+	tx, err := ls.LogStorage.SnapshotForTree(ctx, treeID)
+	if err != nil {
+		return nil, err
+	}
+	return &readOnlyLogTreeTX{ReadOnlyLogTreeTX: tx, in: ls.in}, nil
+}
+
+func (ls *logStorage) ReadWriteTransaction(ctx context.Context, treeID int64, f storage.LogTXFunc) error {
+	if err := ls.in.before(ctx, "ReadWriteTransaction"); err != nil {
+		return err
+	}
+	if err := ls.LogStorage.ReadWriteTransaction(ctx, treeID, func(ctx context.Context, tx storage.LogTreeTX) error {
+		return f(ctx, &logTreeTX{LogTreeTX: tx, in: ls.in})
+	}); err != nil {
+		return err
+	}
+	if ls.in.chance(ls.in.faults.CommitErrorRate) {
+		glog.V(1).Infof("chaos: failing committed transaction on tree %d", treeID)
+		return status.Errorf(codes.Unavailable, "chaos: injected error after commit of transaction on tree %d", treeID)
+	}
+	return nil
+}
+
+func (ls *logStorage) QueueLeaves(ctx context.Context, treeID int64, leaves []*trillian.LogLeaf, queueTimestamp time.Time) ([]*trillian.QueuedLogLeaf, error) {
+	if err := ls.in.before(ctx, "QueueLeaves"); err != nil {
+		return nil, err
+	}
+	return ls.LogStorage.QueueLeaves(ctx, treeID, leaves, queueTimestamp)
+}
+
+func (ls *logStorage) AddSequencedLeaves(ctx context.Context, treeID int64, leaves []*trillian.LogLeaf) ([]*trillian.QueuedLogLeaf, error) {
+	if err := ls.in.before(ctx, "AddSequencedLeaves"); err != nil {
+		return nil, err
+	}
+	return ls.LogStorage.AddSequencedLeaves(ctx, treeID, leaves)
+}
+
+type readOnlyLogTreeTX struct {
+	storage.ReadOnlyLogTreeTX
+	in *Injector
+}
+
+func (tx *readOnlyLogTreeTX) LatestSignedLogRoot(ctx context.Context) (trillian.SignedLogRoot, error) {
+	if err := tx.in.before(ctx, "LatestSignedLogRoot"); err != nil {
+		return trillian.SignedLogRoot{}, err
+	}
+	return tx.ReadOnlyLogTreeTX.LatestSignedLogRoot(ctx)
+}
+
+func (tx *readOnlyLogTreeTX) GetMerkleNodes(ctx context.Context, treeRevision int64, ids []storage.NodeID) ([]storage.Node, error) {
+	if err := tx.in.before(ctx, "GetMerkleNodes"); err != nil {
+		return nil, err
+	}
+	return tx.ReadOnlyLogTreeTX.GetMerkleNodes(ctx, treeRevision, ids)
+}
+
+func (tx *readOnlyLogTreeTX) GetLeavesByIndex(ctx context.Context, leaves []int64) ([]*trillian.LogLeaf, error) {
+	if err := tx.in.before(ctx, "GetLeavesByIndex"); err != nil {
+		return nil, err
+	}
+	return tx.ReadOnlyLogTreeTX.GetLeavesByIndex(ctx, leaves)
+}
+
+func (tx *readOnlyLogTreeTX) GetLeavesByRange(ctx context.Context, start, count int64) ([]*trillian.LogLeaf, error) {
+	if err := tx.in.before(ctx, "GetLeavesByRange"); err != nil {
+		return nil, err
+	}
+	return tx.ReadOnlyLogTreeTX.GetLeavesByRange(ctx, start, count)
+}
+
+func (tx *readOnlyLogTreeTX) GetLeavesByHash(ctx context.Context, leafHashes [][]byte, orderBySequence bool) ([]*trillian.LogLeaf, error) {
+	if err := tx.in.before(ctx, "GetLeavesByHash"); err != nil {
+		return nil, err
+	}
+	return tx.ReadOnlyLogTreeTX.GetLeavesByHash(ctx, leafHashes, orderBySequence)
+}
+
+type logTreeTX struct {
+	storage.LogTreeTX
+	in *Injector
+}
+
+func (tx *logTreeTX) LatestSignedLogRoot(ctx context.Context) (trillian.SignedLogRoot, error) {
+	if err := tx.in.before(ctx, "LatestSignedLogRoot"); err != nil {
+		return trillian.SignedLogRoot{}, err
+	}
+	return tx.LogTreeTX.LatestSignedLogRoot(ctx)
+}
+
+func (tx *logTreeTX) GetMerkleNodes(ctx context.Context, treeRevision int64, ids []storage.NodeID) ([]storage.Node, error) {
+	if err := tx.in.before(ctx, "GetMerkleNodes"); err != nil {
+		return nil, err
+	}
+	return tx.LogTreeTX.GetMerkleNodes(ctx, treeRevision, ids)
+}
+
+func (tx *logTreeTX) DequeueLeaves(ctx context.Context, limit int, cutoffTime time.Time) ([]*trillian.LogLeaf, error) {
+	if err := tx.in.before(ctx, "DequeueLeaves"); err != nil {
+		return nil, err
+	}
+	return tx.LogTreeTX.DequeueLeaves(ctx, limit, cutoffTime)
+}
+
+func (tx *logTreeTX) UpdateSequencedLeaves(ctx context.Context, leaves []*trillian.LogLeaf) error {
+	if err := tx.in.before(ctx, "UpdateSequencedLeaves"); err != nil {
+		return err
+	}
+	if n, ok := tx.partialWrite(len(leaves)); ok {
+		if err := tx.LogTreeTX.UpdateSequencedLeaves(ctx, leaves[:n]); err != nil {
+			return err
+		}
+		return status.Errorf(codes.Aborted, "chaos: injected failure after updating %d of %d leaves", n, len(leaves))
+	}
+	return tx.LogTreeTX.UpdateSequencedLeaves(ctx, leaves)
+}
+
+func (tx *logTreeTX) SetMerkleNodes(ctx context.Context, nodes []storage.Node) error {
+	if err := tx.in.before(ctx, "SetMerkleNodes"); err != nil {
+		return err
+	}
+	if n, ok := tx.partialWrite(len(nodes)); ok {
+		if err := tx.LogTreeTX.SetMerkleNodes(ctx, nodes[:n]); err != nil {
+			return err
+		}
+		return status.Errorf(codes.Aborted, "chaos: injected failure after setting %d of %d nodes", n, len(nodes))
+	}
+	return tx.LogTreeTX.SetMerkleNodes(ctx, nodes)
+}
+
+func (tx *logTreeTX) StoreSignedLogRoot(ctx context.Context, root trillian.SignedLogRoot) error {
+	if err := tx.in.before(ctx, "StoreSignedLogRoot"); err != nil {
+		return err
+	}
+	return tx.LogTreeTX.StoreSignedLogRoot(ctx, root)
+}
+
+// partialWrite decides whether a write of count items fails part way, and if
+// so returns the number of items written before it does.
+func (tx *logTreeTX) partialWrite(count int) (int, bool) {
+	if count == 0 || !tx.in.chance(tx.in.faults.PartialWriteRate) {
+		return 0, false
+	}
+	n := tx.in.intn(count)
+	glog.V(1).Infof("chaos: failing write after %d of %d items", n, count)
+	return n, true
+}
@@ -0,0 +1,145 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/spanner"
+	"github.com/google/trillian/extension"
+	"github.com/google/trillian/quota"
+	"github.com/google/trillian/storage/cloudspanner"
+	"github.com/google/trillian/storage/memory"
+	"github.com/google/trillian/storage/mysql"
+	"github.com/google/trillian/storage/testdb"
+)
+
+const (
+	// BackendsEnv is the environment variable holding the comma-separated
+	// names of the backends returned by Backends.
+	BackendsEnv = "TRILLIAN_INTEGRATION_BACKENDS"
+
+	// SpannerDatabaseEnv is the environment variable holding the database used
+	// by the cloudspanner backend, e.g.
+	// "projects/p/instances/i/databases/trillian". The database must have the
+	// schema of storage/cloudspanner/spanner.sdl. Set SPANNER_EMULATOR_HOST
+	// to run against the Cloud Spanner emulator.
+	SpannerDatabaseEnv = "TRILLIAN_SPANNER_DATABASE"
+)
+
+// defaultBackends are the backends which don't need an external service.
+var defaultBackends = []string{"memory", "sqlite"}
+
+// Backend is a storage backend the integration tests can run against.
+type Backend struct {
+	Name string
+	// NoTreeDeletion is set if the admin storage of the backend can't delete
+	// trees.
+	NoTreeDeletion bool
+	// NewRegistry returns a registry using a fresh instance of the backend,
+	// where possible, and a function releasing it.
+	NewRegistry func(ctx context.Context) (extension.Registry, func(), error)
+}
+
+var backends = map[string]Backend{
+	"memory": {
+		Name:           "memory",
+		NoTreeDeletion: true,
+		NewRegistry: func(ctx context.Context) (extension.Registry, func(), error) {
+			ls := memory.NewLogStorage(nil)
+			return extension.Registry{
+				AdminStorage: memory.NewAdminStorage(ls),
+				LogStorage:   ls,
+				QuotaManager: quota.Noop(),
+			}, func() {}, nil
+		},
+	},
+	"sqlite": {
+		Name: "sqlite",
+		NewRegistry: func(ctx context.Context) (extension.Registry, func(), error) {
+			return sqlRegistry(ctx, testdb.SQLite())
+		},
+	},
+	"mysql": {
+		Name: "mysql",
+		NewRegistry: func(ctx context.Context) (extension.Registry, func(), error) {
+			return sqlRegistry(ctx, testdb.MySQL())
+		},
+	},
+	"cloudspanner": {
+		Name: "cloudspanner",
+		NewRegistry: func(ctx context.Context) (extension.Registry, func(), error) {
+			database := os.Getenv(SpannerDatabaseEnv)
+			if database == "" {
+				return extension.Registry{}, nil, fmt.Errorf("cloudspanner backend needs %s to be set", SpannerDatabaseEnv)
+			}
+			client, err := spanner.NewClient(ctx, database)
+			if err != nil {
+				return extension.Registry{}, nil, err
+			}
+			return extension.Registry{
+				AdminStorage: cloudspanner.NewAdminStorage(client),
+				LogStorage:   cloudspanner.NewLogStorage(client),
+				QuotaManager: quota.Noop(),
+			}, client.Close, nil
+		},
+	},
+}
+
+// sqlRegistry returns a registry using a new database of p, which must be
+// reachable: MySQL is expected as root, without password, on localhost.
+func sqlRegistry(ctx context.Context, p *testdb.Provider) (extension.Registry, func(), error) {
+	db, err := p.NewTrillianDB(ctx)
+	if err != nil {
+		return extension.Registry{}, nil, err
+	}
+	return extension.Registry{
+		AdminStorage: mysql.NewAdminStorage(db),
+		LogStorage:   mysql.NewLogStorage(db, nil),
+		QuotaManager: quota.Noop(),
+	}, func() { db.Close() }, nil
+}
+
+// Backends returns the backends named by the TRILLIAN_INTEGRATION_BACKENDS
+// environment variable, or the ones which don't need an external service,
+// memory and sqlite, if it's unset.
+func Backends() ([]Backend, error) {
+	names := defaultBackends
+	if env := os.Getenv(BackendsEnv); env != "" {
+		names = strings.Split(env, ",")
+	}
+	var ret []Backend
+	for _, name := range names {
+		b, ok := backends[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown backend %q, want one of %s", BackendsEnv, name, strings.Join(backendNames(), ", "))
+		}
+		ret = append(ret, b)
+	}
+	return ret, nil
+}
+
+func backendNames() []string {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
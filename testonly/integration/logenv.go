@@ -118,7 +118,7 @@ func NewLogEnvWithRegistryAndGRPCOptions(ctx context.Context, numSequencers int,
 	grpcServer := grpc.NewServer(serverOpts...)
 
 	// Setup the Admin Server.
-	adminServer := admin.New(registry, nil)
+	adminServer := admin.New(registry, nil, timeSource)
 	trillian.RegisterTrillianAdminServer(grpcServer, adminServer)
 
 	// Setup the Log Server.
@@ -109,9 +109,9 @@ func NewMapEnvWithRegistry(registry extension.Registry) (*MapEnv, error) {
 
 	// Create Map Server.
 	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(ci))
-	mapServer := server.NewTrillianMapServer(registry)
+	mapServer := server.NewTrillianMapServer(registry, timeSource)
 	trillian.RegisterTrillianMapServer(grpcServer, mapServer)
-	trillian.RegisterTrillianAdminServer(grpcServer, admin.New(registry, nil /* allowedTreeTypes */))
+	trillian.RegisterTrillianAdminServer(grpcServer, admin.New(registry, nil /* allowedTreeTypes */, timeSource))
 	go grpcServer.Serve(lis)
 
 	// Connect to the server.
@@ -0,0 +1,259 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package proofbench benchmarks the generation of inclusion and consistency
+// proofs by the log server, through the subtree cache and storage layers, on
+// large trees of any storage backend.
+//
+// Building a log of 10^9 leaves isn't practical for a benchmark, so a Fixture
+// only stores the Merkle nodes read by a random sample of proofs: the strata
+// subtrees containing them, filled up to the right edge of the tree. The
+// hashes of their leaves are made up, so the proofs don't verify, but storage
+// does the same work to serve them as it would for a real log of that size.
+package proofbench
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"math/rand"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian"
+	"github.com/google/trillian/crypto/sigpb"
+	"github.com/google/trillian/extension"
+	"github.com/google/trillian/merkle"
+	"github.com/google/trillian/merkle/rfc6962"
+	"github.com/google/trillian/server"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/util"
+
+	stestonly "github.com/google/trillian/storage/testonly"
+)
+
+// Sizes are the tree sizes benchmarked by default.
+var Sizes = []int64{1e6, 1e7, 1e8, 1e9}
+
+// DefaultSampleSize is the default number of distinct proofs of each kind a
+// Fixture stores the nodes of.
+const DefaultSampleSize = 64
+
+// strataDepth is the depth of the subtrees log storage stores nodes in.
+const strataDepth = 8
+
+// Fixture is a log of a given size, of which only the nodes read by a sample
+// of proofs are stored, and a log server serving it.
+type Fixture struct {
+	TreeSize int64
+
+	treeID int64
+	server *server.TrillianLogRPCServer
+	// leaves are the indices of the leaves of the sample inclusion proofs.
+	leaves []int64
+	// sizes are the pairs of tree sizes of the sample consistency proofs.
+	sizes [][2]int64
+}
+
+// NewFixture creates a log in the storage of registry, with a latest tree
+// head of size treeSize, and stores the nodes of sampleSize inclusion and
+// consistency proofs, chosen at random with seed.
+func NewFixture(ctx context.Context, registry extension.Registry, treeSize int64, sampleSize int, seed int64) (*Fixture, error) {
+	if treeSize < 2 {
+		return nil, fmt.Errorf("treeSize must be at least 2, got %d", treeSize)
+	}
+	if sampleSize < 1 {
+		return nil, fmt.Errorf("sampleSize must be at least 1, got %d", sampleSize)
+	}
+	tree, err := storage.CreateTree(ctx, registry.AdminStorage, proto.Clone(stestonly.LogTree).(*trillian.Tree))
+	if err != nil {
+		return nil, fmt.Errorf("CreateTree(): %v", err)
+	}
+	f := &Fixture{
+		TreeSize: treeSize,
+		treeID:   tree.TreeId,
+		server:   server.NewTrillianLogRPCServer(registry, util.SystemTimeSource{}),
+	}
+
+	r := rand.New(rand.NewSource(seed))
+	var fetches []merkle.NodeFetch
+	for i := 0; i < sampleSize; i++ {
+		leaf := r.Int63n(treeSize)
+		f.leaves = append(f.leaves, leaf)
+		nodes, err := merkle.InclusionProofNodes(treeSize, leaf, treeSize)
+		if err != nil {
+			return nil, err
+		}
+		fetches = append(fetches, nodes...)
+
+		first := 1 + r.Int63n(treeSize-1)
+		second := first + 1 + r.Int63n(treeSize-first)
+		f.sizes = append(f.sizes, [2]int64{first, second})
+		if nodes, err = merkle.ConsistencyProofNodes(first, second, treeSize); err != nil {
+			return nil, err
+		}
+		fetches = append(fetches, nodes...)
+	}
+
+	// The log is initialized, as by InitLog, at revision 0, and the nodes are
+	// written at revision 1, as if a single sequencing pass had added all the
+	// leaves.
+	if err := storeRoot(ctx, registry.LogStorage, tree.TreeId, 0, nil); err != nil {
+		return nil, fmt.Errorf("failed to initialize log: %v", err)
+	}
+	if err := storeRoot(ctx, registry.LogStorage, tree.TreeId, treeSize, fetches); err != nil {
+		return nil, fmt.Errorf("failed to store nodes: %v", err)
+	}
+	return f, nil
+}
+
+// storeRoot stores a tree head of size treeSize for treeID, at the next
+// revision, with the strata subtrees containing fetches.
+func storeRoot(ctx context.Context, ls storage.LogStorage, treeID, treeSize int64, fetches []merkle.NodeFetch) error {
+	return ls.ReadWriteTransaction(ctx, treeID, func(ctx context.Context, tx storage.LogTreeTX) error {
+		rev := tx.WriteRevision()
+		if rev < 0 {
+			// Uninitialized trees have no write revision in some storage
+			// implementations.
+			rev = 0
+		}
+		if len(fetches) > 0 {
+			if err := tx.SetMerkleNodes(ctx, subtreeNodes(fetches, treeSize, rev)); err != nil {
+				return err
+			}
+		}
+		return tx.StoreSignedLogRoot(ctx, trillian.SignedLogRoot{
+			LogId:          treeID,
+			TimestampNanos: time.Now().UnixNano(),
+			TreeSize:       treeSize,
+			TreeRevision:   rev,
+			RootHash:       fakeHash(64, treeSize),
+			Signature:      &sigpb.DigitallySigned{},
+		})
+	})
+}
+
+// InclusionProof gets the inclusion proof of the i-th sample leaf, modulo
+// the sample size, at the fixture's tree size.
+func (f *Fixture) InclusionProof(ctx context.Context, i int) error {
+	leaf := f.leaves[i%len(f.leaves)]
+	resp, err := f.server.GetInclusionProof(ctx, &trillian.GetInclusionProofRequest{
+		LogId:     f.treeID,
+		LeafIndex: leaf,
+		TreeSize:  f.TreeSize,
+	})
+	if err != nil {
+		return fmt.Errorf("GetInclusionProof(%d, %d): %v", leaf, f.TreeSize, err)
+	}
+	if len(resp.GetProof().GetHashes()) == 0 {
+		return fmt.Errorf("GetInclusionProof(%d, %d) returned an empty proof", leaf, f.TreeSize)
+	}
+	return nil
+}
+
+// ConsistencyProof gets the i-th sample consistency proof, modulo the sample
+// size.
+func (f *Fixture) ConsistencyProof(ctx context.Context, i int) error {
+	sizes := f.sizes[i%len(f.sizes)]
+	resp, err := f.server.GetConsistencyProof(ctx, &trillian.GetConsistencyProofRequest{
+		LogId:          f.treeID,
+		FirstTreeSize:  sizes[0],
+		SecondTreeSize: sizes[1],
+	})
+	if err != nil {
+		return fmt.Errorf("GetConsistencyProof(%d, %d): %v", sizes[0], sizes[1], err)
+	}
+	if len(resp.GetProof().GetHashes()) == 0 {
+		return fmt.Errorf("GetConsistencyProof(%d, %d) returned an empty proof", sizes[0], sizes[1])
+	}
+	return nil
+}
+
+// fakeHash returns a made up hash for the node at level and index.
+func fakeHash(level, index int64) []byte {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], uint64(level))
+	binary.BigEndian.PutUint64(b[8:], uint64(index))
+	h := sha256.Sum256(b[:])
+	return h[:]
+}
+
+// subtreeNodes returns the nodes of the strata subtrees of a tree of size
+// treeSize which contain the fetched nodes, at revision rev. Each subtree is
+// filled with the nodes the sequencer would have written to it: those which
+// are roots of perfect subtrees of the tree, with made up leaves and the
+// internal nodes hashed from them, and those on the right edge of the tree.
+func subtreeNodes(fetches []merkle.NodeFetch, treeSize, rev int64) []storage.Node {
+	type subtree struct{ bottom, index int64 }
+	seen := make(map[subtree]bool)
+	height := int64(bits.Len64(uint64(treeSize - 1)))
+	var nodes []storage.Node
+	for _, fetch := range fetches {
+		c, err := storage.CoordsForNodeID(fetch.NodeID)
+		if err != nil {
+			// The fetches are those of proofs of a log tree.
+			panic(err)
+		}
+		level, index := c.Level, c.Index
+		bottom := level / strataDepth * strataDepth
+		st := subtree{bottom: bottom, index: index >> uint(bottom+strataDepth-level)}
+		if seen[st] {
+			continue
+		}
+		seen[st] = true
+
+		// hashes holds the nodes of the current level of the subtree.
+		first := st.index << strataDepth
+		end := first + 1<<strataDepth
+		if perfect := treeSize >> uint(bottom); end > perfect {
+			end = perfect
+		}
+		var hashes [][]byte
+		for i := first; i < end; i++ {
+			hashes = append(hashes, fakeHash(bottom, i))
+		}
+		for l := bottom; l < bottom+strataDepth; l++ {
+			for i, h := range hashes {
+				nodes = append(nodes, node(l, first+int64(i), h, rev))
+			}
+			// The sequencer also stores the node on the right edge of the tree
+			// which isn't perfect, if it falls in this subtree.
+			if edge := treeSize >> uint(l); l <= height && edge<<uint(l) < treeSize &&
+				edge>>uint(bottom+strataDepth-l) == st.index {
+				nodes = append(nodes, node(l, edge, fakeHash(l, edge), rev))
+			}
+			next := make([][]byte, 0, len(hashes)/2)
+			for i := 0; i+1 < len(hashes); i += 2 {
+				next = append(next, rfc6962.DefaultHasher.HashChildren(hashes[i], hashes[i+1]))
+			}
+			hashes, first = next, first/2
+		}
+	}
+	return nodes
+}
+
+func node(level, index int64, hash []byte, rev int64) storage.Node {
+	n, err := storage.CoordNode{
+		Coords:   storage.NodeCoords{Level: level, Index: index},
+		Hash:     hash,
+		Revision: rev,
+	}.Node()
+	if err != nil {
+		// The coordinates are those of nodes within a tree of valid size.
+		panic(err)
+	}
+	return n
+}
@@ -0,0 +1,116 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proofbench
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/trillian/merkle"
+	"github.com/google/trillian/storage"
+	"github.com/google/trillian/testonly/integration"
+)
+
+func TestFixture(t *testing.T) {
+	ctx := context.Background()
+	backends, err := integration.Backends()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range backends {
+		for _, size := range []int64{2, 1000, 1e9} {
+			t.Run(fmt.Sprintf("%s/%d", b.Name, size), func(t *testing.T) {
+				registry, done, err := b.NewRegistry(ctx)
+				if err != nil {
+					t.Fatalf("NewRegistry(): %v", err)
+				}
+				defer done()
+				f, err := NewFixture(ctx, registry, size, 4, 1)
+				if err != nil {
+					t.Fatalf("NewFixture(): %v", err)
+				}
+				for i := 0; i < 4; i++ {
+					if err := f.InclusionProof(ctx, i); err != nil {
+						t.Error(err)
+					}
+					if err := f.ConsistencyProof(ctx, i); err != nil {
+						t.Error(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestSubtreeNodes(t *testing.T) {
+	// A tree of 1000 leaves has 3 perfect nodes at level 8, so the subtree of
+	// leaf 900 has leaves 768 to 999, their perfect ancestors below level 8,
+	// and the right edge nodes of levels 4 to 7.
+	fetches, err := merkle.InclusionProofNodes(1000, 900, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := subtreeNodes(fetches[:1], 1000, 1)
+	if got, want := len(nodes), 232+116+58+29+14+7+3+1+4; got != want {
+		t.Errorf("subtreeNodes() returned %d nodes, want %d", got, want)
+	}
+	for _, n := range nodes {
+		c, err := storage.CoordsForNodeID(n.NodeID)
+		if err != nil {
+			t.Fatalf("CoordsForNodeID(): %v", err)
+		}
+		if c.Index<<uint(c.Level) >= 1000 {
+			t.Errorf("subtreeNodes() returned node %v beyond the tree", c)
+		}
+	}
+}
+
+func benchmarkProofs(b *testing.B, proof func(f *Fixture, ctx context.Context, i int) error) {
+	ctx := context.Background()
+	backends, err := integration.Backends()
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, backend := range backends {
+		for _, size := range Sizes {
+			b.Run(fmt.Sprintf("%s/%d", backend.Name, size), func(b *testing.B) {
+				registry, done, err := backend.NewRegistry(ctx)
+				if err != nil {
+					b.Fatalf("NewRegistry(): %v", err)
+				}
+				defer done()
+				f, err := NewFixture(ctx, registry, size, DefaultSampleSize, 1)
+				if err != nil {
+					b.Fatalf("NewFixture(): %v", err)
+				}
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if err := proof(f, ctx, i); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkInclusionProof(b *testing.B) {
+	benchmarkProofs(b, (*Fixture).InclusionProof)
+}
+
+func BenchmarkConsistencyProof(b *testing.B) {
+	benchmarkProofs(b, (*Fixture).ConsistencyProof)
+}
@@ -34,6 +34,32 @@ func (m *MockTrillianAdminServer) EXPECT() *MockTrillianAdminServerMockRecorder
 	return m.recorder
 }
 
+// BatchGetTreeStatus mocks base method
+func (m *MockTrillianAdminServer) BatchGetTreeStatus(arg0 context.Context, arg1 *trillian.BatchGetTreeStatusRequest) (*trillian.BatchGetTreeStatusResponse, error) {
+	ret := m.ctrl.Call(m, "BatchGetTreeStatus", arg0, arg1)
+	ret0, _ := ret[0].(*trillian.BatchGetTreeStatusResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BatchGetTreeStatus indicates an expected call of BatchGetTreeStatus
+func (mr *MockTrillianAdminServerMockRecorder) BatchGetTreeStatus(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchGetTreeStatus", reflect.TypeOf((*MockTrillianAdminServer)(nil).BatchGetTreeStatus), arg0, arg1)
+}
+
+// CheckTreeIntegrity mocks base method
+func (m *MockTrillianAdminServer) CheckTreeIntegrity(arg0 context.Context, arg1 *trillian.CheckTreeIntegrityRequest) (*trillian.TreeIntegrityCheck, error) {
+	ret := m.ctrl.Call(m, "CheckTreeIntegrity", arg0, arg1)
+	ret0, _ := ret[0].(*trillian.TreeIntegrityCheck)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CheckTreeIntegrity indicates an expected call of CheckTreeIntegrity
+func (mr *MockTrillianAdminServerMockRecorder) CheckTreeIntegrity(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckTreeIntegrity", reflect.TypeOf((*MockTrillianAdminServer)(nil).CheckTreeIntegrity), arg0, arg1)
+}
+
 // CreateTree mocks base method
 func (m *MockTrillianAdminServer) CreateTree(arg0 context.Context, arg1 *trillian.CreateTreeRequest) (*trillian.Tree, error) {
 	ret := m.ctrl.Call(m, "CreateTree", arg0, arg1)
@@ -60,6 +86,19 @@ func (mr *MockTrillianAdminServerMockRecorder) DeleteTree(arg0, arg1 interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTree", reflect.TypeOf((*MockTrillianAdminServer)(nil).DeleteTree), arg0, arg1)
 }
 
+// GetMergeDelayCompliance mocks base method
+func (m *MockTrillianAdminServer) GetMergeDelayCompliance(arg0 context.Context, arg1 *trillian.GetMergeDelayComplianceRequest) (*trillian.MergeDelayCompliance, error) {
+	ret := m.ctrl.Call(m, "GetMergeDelayCompliance", arg0, arg1)
+	ret0, _ := ret[0].(*trillian.MergeDelayCompliance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMergeDelayCompliance indicates an expected call of GetMergeDelayCompliance
+func (mr *MockTrillianAdminServerMockRecorder) GetMergeDelayCompliance(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMergeDelayCompliance", reflect.TypeOf((*MockTrillianAdminServer)(nil).GetMergeDelayCompliance), arg0, arg1)
+}
+
 // GetTree mocks base method
 func (m *MockTrillianAdminServer) GetTree(arg0 context.Context, arg1 *trillian.GetTreeRequest) (*trillian.Tree, error) {
 	ret := m.ctrl.Call(m, "GetTree", arg0, arg1)
@@ -73,6 +112,58 @@ func (mr *MockTrillianAdminServerMockRecorder) GetTree(arg0, arg1 interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTree", reflect.TypeOf((*MockTrillianAdminServer)(nil).GetTree), arg0, arg1)
 }
 
+// GetTreeByExternalId mocks base method
+func (m *MockTrillianAdminServer) GetTreeByExternalId(arg0 context.Context, arg1 *trillian.GetTreeByExternalIdRequest) (*trillian.Tree, error) {
+	ret := m.ctrl.Call(m, "GetTreeByExternalId", arg0, arg1)
+	ret0, _ := ret[0].(*trillian.Tree)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTreeByExternalId indicates an expected call of GetTreeByExternalId
+func (mr *MockTrillianAdminServerMockRecorder) GetTreeByExternalId(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTreeByExternalId", reflect.TypeOf((*MockTrillianAdminServer)(nil).GetTreeByExternalId), arg0, arg1)
+}
+
+// GetTreeIntegrityCheck mocks base method
+func (m *MockTrillianAdminServer) GetTreeIntegrityCheck(arg0 context.Context, arg1 *trillian.GetTreeIntegrityCheckRequest) (*trillian.TreeIntegrityCheck, error) {
+	ret := m.ctrl.Call(m, "GetTreeIntegrityCheck", arg0, arg1)
+	ret0, _ := ret[0].(*trillian.TreeIntegrityCheck)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTreeIntegrityCheck indicates an expected call of GetTreeIntegrityCheck
+func (mr *MockTrillianAdminServerMockRecorder) GetTreeIntegrityCheck(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTreeIntegrityCheck", reflect.TypeOf((*MockTrillianAdminServer)(nil).GetTreeIntegrityCheck), arg0, arg1)
+}
+
+// GetTreeStats mocks base method
+func (m *MockTrillianAdminServer) GetTreeStats(arg0 context.Context, arg1 *trillian.GetTreeStatsRequest) (*trillian.TreeStats, error) {
+	ret := m.ctrl.Call(m, "GetTreeStats", arg0, arg1)
+	ret0, _ := ret[0].(*trillian.TreeStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTreeStats indicates an expected call of GetTreeStats
+func (mr *MockTrillianAdminServerMockRecorder) GetTreeStats(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTreeStats", reflect.TypeOf((*MockTrillianAdminServer)(nil).GetTreeStats), arg0, arg1)
+}
+
+// InspectLeafQueue mocks base method
+func (m *MockTrillianAdminServer) InspectLeafQueue(arg0 context.Context, arg1 *trillian.InspectLeafQueueRequest) (*trillian.LeafQueueSample, error) {
+	ret := m.ctrl.Call(m, "InspectLeafQueue", arg0, arg1)
+	ret0, _ := ret[0].(*trillian.LeafQueueSample)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InspectLeafQueue indicates an expected call of InspectLeafQueue
+func (mr *MockTrillianAdminServerMockRecorder) InspectLeafQueue(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InspectLeafQueue", reflect.TypeOf((*MockTrillianAdminServer)(nil).InspectLeafQueue), arg0, arg1)
+}
+
 // ListTrees mocks base method
 func (m *MockTrillianAdminServer) ListTrees(arg0 context.Context, arg1 *trillian.ListTreesRequest) (*trillian.ListTreesResponse, error) {
 	ret := m.ctrl.Call(m, "ListTrees", arg0, arg1)
@@ -34,6 +34,19 @@ func (m *MockTrillianLogServer) EXPECT() *MockTrillianLogServerMockRecorder {
 	return m.recorder
 }
 
+// AddObservedRoot mocks base method
+func (m *MockTrillianLogServer) AddObservedRoot(arg0 context.Context, arg1 *trillian.AddObservedRootRequest) (*trillian.AddObservedRootResponse, error) {
+	ret := m.ctrl.Call(m, "AddObservedRoot", arg0, arg1)
+	ret0, _ := ret[0].(*trillian.AddObservedRootResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddObservedRoot indicates an expected call of AddObservedRoot
+func (mr *MockTrillianLogServerMockRecorder) AddObservedRoot(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddObservedRoot", reflect.TypeOf((*MockTrillianLogServer)(nil).AddObservedRoot), arg0, arg1)
+}
+
 // AddSequencedLeaf mocks base method
 func (m *MockTrillianLogServer) AddSequencedLeaf(arg0 context.Context, arg1 *trillian.AddSequencedLeafRequest) (*trillian.AddSequencedLeafResponse, error) {
 	ret := m.ctrl.Call(m, "AddSequencedLeaf", arg0, arg1)
@@ -47,6 +60,19 @@ func (mr *MockTrillianLogServerMockRecorder) AddSequencedLeaf(arg0, arg1 interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddSequencedLeaf", reflect.TypeOf((*MockTrillianLogServer)(nil).AddSequencedLeaf), arg0, arg1)
 }
 
+// AddSequencedLeafRange mocks base method
+func (m *MockTrillianLogServer) AddSequencedLeafRange(arg0 context.Context, arg1 *trillian.AddSequencedLeafRangeRequest) (*trillian.AddSequencedLeafRangeResponse, error) {
+	ret := m.ctrl.Call(m, "AddSequencedLeafRange", arg0, arg1)
+	ret0, _ := ret[0].(*trillian.AddSequencedLeafRangeResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddSequencedLeafRange indicates an expected call of AddSequencedLeafRange
+func (mr *MockTrillianLogServerMockRecorder) AddSequencedLeafRange(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddSequencedLeafRange", reflect.TypeOf((*MockTrillianLogServer)(nil).AddSequencedLeafRange), arg0, arg1)
+}
+
 // AddSequencedLeaves mocks base method
 func (m *MockTrillianLogServer) AddSequencedLeaves(arg0 context.Context, arg1 *trillian.AddSequencedLeavesRequest) (*trillian.AddSequencedLeavesResponse, error) {
 	ret := m.ctrl.Call(m, "AddSequencedLeaves", arg0, arg1)
@@ -60,6 +86,19 @@ func (mr *MockTrillianLogServerMockRecorder) AddSequencedLeaves(arg0, arg1 inter
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddSequencedLeaves", reflect.TypeOf((*MockTrillianLogServer)(nil).AddSequencedLeaves), arg0, arg1)
 }
 
+// CheckLeafFilter mocks base method
+func (m *MockTrillianLogServer) CheckLeafFilter(arg0 context.Context, arg1 *trillian.CheckLeafFilterRequest) (*trillian.CheckLeafFilterResponse, error) {
+	ret := m.ctrl.Call(m, "CheckLeafFilter", arg0, arg1)
+	ret0, _ := ret[0].(*trillian.CheckLeafFilterResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CheckLeafFilter indicates an expected call of CheckLeafFilter
+func (mr *MockTrillianLogServerMockRecorder) CheckLeafFilter(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckLeafFilter", reflect.TypeOf((*MockTrillianLogServer)(nil).CheckLeafFilter), arg0, arg1)
+}
+
 // GetConsistencyProof mocks base method
 func (m *MockTrillianLogServer) GetConsistencyProof(arg0 context.Context, arg1 *trillian.GetConsistencyProofRequest) (*trillian.GetConsistencyProofResponse, error) {
 	ret := m.ctrl.Call(m, "GetConsistencyProof", arg0, arg1)
@@ -73,6 +112,32 @@ func (mr *MockTrillianLogServerMockRecorder) GetConsistencyProof(arg0, arg1 inte
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConsistencyProof", reflect.TypeOf((*MockTrillianLogServer)(nil).GetConsistencyProof), arg0, arg1)
 }
 
+// GetConsistencyProofChain mocks base method
+func (m *MockTrillianLogServer) GetConsistencyProofChain(arg0 context.Context, arg1 *trillian.GetConsistencyProofChainRequest) (*trillian.GetConsistencyProofChainResponse, error) {
+	ret := m.ctrl.Call(m, "GetConsistencyProofChain", arg0, arg1)
+	ret0, _ := ret[0].(*trillian.GetConsistencyProofChainResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConsistencyProofChain indicates an expected call of GetConsistencyProofChain
+func (mr *MockTrillianLogServerMockRecorder) GetConsistencyProofChain(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConsistencyProofChain", reflect.TypeOf((*MockTrillianLogServer)(nil).GetConsistencyProofChain), arg0, arg1)
+}
+
+// GetEntriesAndProofs mocks base method
+func (m *MockTrillianLogServer) GetEntriesAndProofs(arg0 context.Context, arg1 *trillian.GetEntriesAndProofsRequest) (*trillian.GetEntriesAndProofsResponse, error) {
+	ret := m.ctrl.Call(m, "GetEntriesAndProofs", arg0, arg1)
+	ret0, _ := ret[0].(*trillian.GetEntriesAndProofsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEntriesAndProofs indicates an expected call of GetEntriesAndProofs
+func (mr *MockTrillianLogServerMockRecorder) GetEntriesAndProofs(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEntriesAndProofs", reflect.TypeOf((*MockTrillianLogServer)(nil).GetEntriesAndProofs), arg0, arg1)
+}
+
 // GetEntryAndProof mocks base method
 func (m *MockTrillianLogServer) GetEntryAndProof(arg0 context.Context, arg1 *trillian.GetEntryAndProofRequest) (*trillian.GetEntryAndProofResponse, error) {
 	ret := m.ctrl.Call(m, "GetEntryAndProof", arg0, arg1)
@@ -125,6 +190,19 @@ func (mr *MockTrillianLogServerMockRecorder) GetLatestSignedLogRoot(arg0, arg1 i
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLatestSignedLogRoot", reflect.TypeOf((*MockTrillianLogServer)(nil).GetLatestSignedLogRoot), arg0, arg1)
 }
 
+// GetLeafIndicesByKey mocks base method
+func (m *MockTrillianLogServer) GetLeafIndicesByKey(arg0 context.Context, arg1 *trillian.GetLeafIndicesByKeyRequest) (*trillian.GetLeafIndicesByKeyResponse, error) {
+	ret := m.ctrl.Call(m, "GetLeafIndicesByKey", arg0, arg1)
+	ret0, _ := ret[0].(*trillian.GetLeafIndicesByKeyResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLeafIndicesByKey indicates an expected call of GetLeafIndicesByKey
+func (mr *MockTrillianLogServerMockRecorder) GetLeafIndicesByKey(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLeafIndicesByKey", reflect.TypeOf((*MockTrillianLogServer)(nil).GetLeafIndicesByKey), arg0, arg1)
+}
+
 // GetLeavesByHash mocks base method
 func (m *MockTrillianLogServer) GetLeavesByHash(arg0 context.Context, arg1 *trillian.GetLeavesByHashRequest) (*trillian.GetLeavesByHashResponse, error) {
 	ret := m.ctrl.Call(m, "GetLeavesByHash", arg0, arg1)
@@ -164,6 +242,19 @@ func (mr *MockTrillianLogServerMockRecorder) GetLeavesByRange(arg0, arg1 interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLeavesByRange", reflect.TypeOf((*MockTrillianLogServer)(nil).GetLeavesByRange), arg0, arg1)
 }
 
+// GetObservedRoots mocks base method
+func (m *MockTrillianLogServer) GetObservedRoots(arg0 context.Context, arg1 *trillian.GetObservedRootsRequest) (*trillian.GetObservedRootsResponse, error) {
+	ret := m.ctrl.Call(m, "GetObservedRoots", arg0, arg1)
+	ret0, _ := ret[0].(*trillian.GetObservedRootsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetObservedRoots indicates an expected call of GetObservedRoots
+func (mr *MockTrillianLogServerMockRecorder) GetObservedRoots(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetObservedRoots", reflect.TypeOf((*MockTrillianLogServer)(nil).GetObservedRoots), arg0, arg1)
+}
+
 // GetSequencedLeafCount mocks base method
 func (m *MockTrillianLogServer) GetSequencedLeafCount(arg0 context.Context, arg1 *trillian.GetSequencedLeafCountRequest) (*trillian.GetSequencedLeafCountResponse, error) {
 	ret := m.ctrl.Call(m, "GetSequencedLeafCount", arg0, arg1)
@@ -215,3 +306,15 @@ func (m *MockTrillianLogServer) QueueLeaves(arg0 context.Context, arg1 *trillian
 func (mr *MockTrillianLogServerMockRecorder) QueueLeaves(arg0, arg1 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueueLeaves", reflect.TypeOf((*MockTrillianLogServer)(nil).QueueLeaves), arg0, arg1)
 }
+
+// StreamLeavesByRange mocks base method
+func (m *MockTrillianLogServer) StreamLeavesByRange(arg0 *trillian.GetLeavesByRangeRequest, arg1 trillian.TrillianLog_StreamLeavesByRangeServer) error {
+	ret := m.ctrl.Call(m, "StreamLeavesByRange", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StreamLeavesByRange indicates an expected call of StreamLeavesByRange
+func (mr *MockTrillianLogServerMockRecorder) StreamLeavesByRange(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamLeavesByRange", reflect.TypeOf((*MockTrillianLogServer)(nil).StreamLeavesByRange), arg0, arg1)
+}
@@ -34,6 +34,19 @@ func (m *MockTrillianMapServer) EXPECT() *MockTrillianMapServerMockRecorder {
 	return m.recorder
 }
 
+// GetLeafHistory mocks base method
+func (m *MockTrillianMapServer) GetLeafHistory(arg0 context.Context, arg1 *trillian.GetMapLeafHistoryRequest) (*trillian.GetMapLeafHistoryResponse, error) {
+	ret := m.ctrl.Call(m, "GetLeafHistory", arg0, arg1)
+	ret0, _ := ret[0].(*trillian.GetMapLeafHistoryResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLeafHistory indicates an expected call of GetLeafHistory
+func (mr *MockTrillianMapServerMockRecorder) GetLeafHistory(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLeafHistory", reflect.TypeOf((*MockTrillianMapServer)(nil).GetLeafHistory), arg0, arg1)
+}
+
 // GetLeaves mocks base method
 func (m *MockTrillianMapServer) GetLeaves(arg0 context.Context, arg1 *trillian.GetMapLeavesRequest) (*trillian.GetMapLeavesResponse, error) {
 	ret := m.ctrl.Call(m, "GetLeaves", arg0, arg1)
@@ -60,6 +73,19 @@ func (mr *MockTrillianMapServerMockRecorder) GetLeavesByRevision(arg0, arg1 inte
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLeavesByRevision", reflect.TypeOf((*MockTrillianMapServer)(nil).GetLeavesByRevision), arg0, arg1)
 }
 
+// GetMapDiff mocks base method
+func (m *MockTrillianMapServer) GetMapDiff(arg0 context.Context, arg1 *trillian.GetMapDiffRequest) (*trillian.GetMapDiffResponse, error) {
+	ret := m.ctrl.Call(m, "GetMapDiff", arg0, arg1)
+	ret0, _ := ret[0].(*trillian.GetMapDiffResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMapDiff indicates an expected call of GetMapDiff
+func (mr *MockTrillianMapServerMockRecorder) GetMapDiff(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMapDiff", reflect.TypeOf((*MockTrillianMapServer)(nil).GetMapDiff), arg0, arg1)
+}
+
 // GetSignedMapRoot mocks base method
 func (m *MockTrillianMapServer) GetSignedMapRoot(arg0 context.Context, arg1 *trillian.GetSignedMapRootRequest) (*trillian.GetSignedMapRootResponse, error) {
 	ret := m.ctrl.Call(m, "GetSignedMapRoot", arg0, arg1)
@@ -17,10 +17,14 @@
 package trees
 
 import (
+	"bytes"
 	"crypto"
+	"encoding/binary"
+	"errors"
 	"fmt"
 
 	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
 	"github.com/google/trillian"
 	"github.com/google/trillian/crypto/keys"
 	"github.com/google/trillian/crypto/sigpb"
@@ -111,20 +115,71 @@ func Hash(tree *trillian.Tree) (crypto.Hash, error) {
 	return crypto.SHA256, fmt.Errorf("unexpected hash algorithm: %s", tree.HashAlgorithm)
 }
 
-// Signer returns a Trillian crypto.Signer configured by the tree.
+// Signer returns a Trillian crypto.Signer configured by the tree, with an
+// additional signer for each of its additional_signing_keys.
 func Signer(ctx context.Context, tree *trillian.Tree) (*tcrypto.Signer, error) {
-	if tree.SignatureAlgorithm == sigpb.DigitallySigned_ANONYMOUS {
-		return nil, fmt.Errorf("signature algorithm not supported: %s", tree.SignatureAlgorithm)
+	hash, err := Hash(tree)
+	if err != nil {
+		return nil, err
 	}
 
-	hash, err := Hash(tree)
+	signer, err := newSigner(ctx, tree.PrivateKey, tree.SignatureAlgorithm)
 	if err != nil {
 		return nil, err
 	}
 
+	additional := make([]*tcrypto.Signer, 0, len(tree.AdditionalSigningKeys))
+	for i, key := range tree.AdditionalSigningKeys {
+		s, err := SigningKeySigner(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("tree.AdditionalSigningKeys[%d]: %v", i, err)
+		}
+		additional = append(additional, &tcrypto.Signer{Hash: hash, Signer: s})
+	}
+
+	return &tcrypto.Signer{Hash: hash, Signer: signer, Additional: additional}, nil
+}
+
+// SigningKeySigner returns the signer of key, one of the
+// additional_signing_keys of a tree.
+func SigningKeySigner(ctx context.Context, key *trillian.SigningKey) (crypto.Signer, error) {
+	return newSigner(ctx, key.GetPrivateKey(), key.GetSignatureAlgorithm())
+}
+
+// keyChallengePrefix starts the key challenge of every tree, so that it can't
+// be mistaken for any other data signed by tree keys.
+const keyChallengePrefix = "Trillian tree key challenge v1\x00"
+
+// KeyChallenge returns the data which the private key of tree signs to prove
+// that it matches its public key, when the key is held by an external signer
+// (see keyspb.ExternalKey and CreateTreeRequest.key_challenge_signature). It
+// binds the public key to the private_key proto, so a signature for one tree
+// can only be replayed to create another tree using the same key.
+func KeyChallenge(tree *trillian.Tree) ([]byte, error) {
+	switch {
+	case tree.GetPrivateKey() == nil:
+		return nil, errors.New("a private_key is required")
+	case tree.GetPublicKey() == nil:
+		return nil, errors.New("a public_key is required")
+	}
+	var buf bytes.Buffer
+	buf.WriteString(keyChallengePrefix)
+	for _, field := range [][]byte{tree.PublicKey.Der, []byte(tree.PrivateKey.TypeUrl), tree.PrivateKey.Value} {
+		binary.Write(&buf, binary.BigEndian, uint32(len(field)))
+		buf.Write(field)
+	}
+	return buf.Bytes(), nil
+}
+
+// newSigner returns the signer of privateKey, checking that it signs with alg.
+func newSigner(ctx context.Context, privateKey *any.Any, alg sigpb.DigitallySigned_SignatureAlgorithm) (crypto.Signer, error) {
+	if alg == sigpb.DigitallySigned_ANONYMOUS {
+		return nil, fmt.Errorf("signature algorithm not supported: %s", alg)
+	}
+
 	var keyProto ptypes.DynamicAny
-	if err := ptypes.UnmarshalAny(tree.PrivateKey, &keyProto); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal tree.PrivateKey: %v", err)
+	if err := ptypes.UnmarshalAny(privateKey, &keyProto); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal private key: %v", err)
 	}
 
 	signer, err := keys.NewSigner(ctx, keyProto.Message)
@@ -132,9 +187,8 @@ func Signer(ctx context.Context, tree *trillian.Tree) (*tcrypto.Signer, error) {
 		return nil, err
 	}
 
-	if tcrypto.SignatureAlgorithm(signer.Public()) != tree.SignatureAlgorithm {
-		return nil, fmt.Errorf("%s signature not supported by signer of type %T", tree.SignatureAlgorithm, signer)
+	if tcrypto.SignatureAlgorithm(signer.Public()) != alg {
+		return nil, fmt.Errorf("%s signature not supported by signer of type %T", alg, signer)
 	}
-
-	return &tcrypto.Signer{Hash: hash, Signer: signer}, nil
+	return signer, nil
 }
@@ -15,9 +15,11 @@
 package trees
 
 import (
+	"bytes"
 	"context"
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
@@ -28,8 +30,11 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
 	"github.com/google/trillian"
 	"github.com/google/trillian/crypto/keys"
+	"github.com/google/trillian/crypto/keys/der"
+	"github.com/google/trillian/crypto/keyspb"
 	"github.com/google/trillian/crypto/sigpb"
 	"github.com/google/trillian/storage"
 	"github.com/google/trillian/storage/testonly"
@@ -364,3 +369,127 @@ func TestSigner(t *testing.T) {
 		})
 	}
 }
+
+func TestSignerAdditionalKeys(t *testing.T) {
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating test ECDSA key: %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating test Ed25519 key: %v", err)
+	}
+	// TestSigner replaces the handler of keyspb.PrivateKey, so register it here.
+	keys.RegisterHandler(&keyspb.PrivateKey{}, func(ctx context.Context, pb proto.Message) (crypto.Signer, error) {
+		return der.FromProto(pb.(*keyspb.PrivateKey))
+	})
+	defer keys.UnregisterHandler(&keyspb.PrivateKey{})
+
+	privateKey := func(key crypto.Signer) *any.Any {
+		keyDER, err := der.MarshalPrivateKey(key)
+		if err != nil {
+			t.Fatalf("MarshalPrivateKey(): %v", err)
+		}
+		a, err := ptypes.MarshalAny(&keyspb.PrivateKey{Der: keyDER})
+		if err != nil {
+			t.Fatalf("MarshalAny(): %v", err)
+		}
+		return a
+	}
+
+	for _, test := range []struct {
+		desc    string
+		keys    []*trillian.SigningKey
+		wantErr bool
+	}{
+		{desc: "none"},
+		{
+			desc: "ed25519",
+			keys: []*trillian.SigningKey{{SignatureAlgorithm: sigpb.DigitallySigned_ED25519, PrivateKey: privateKey(edKey)}},
+		},
+		{
+			desc: "ecdsaAndEd25519",
+			keys: []*trillian.SigningKey{
+				{SignatureAlgorithm: sigpb.DigitallySigned_ECDSA, PrivateKey: privateKey(ecdsaKey)},
+				{SignatureAlgorithm: sigpb.DigitallySigned_ED25519, PrivateKey: privateKey(edKey)},
+			},
+		},
+		{
+			desc:    "anonymous",
+			keys:    []*trillian.SigningKey{{PrivateKey: privateKey(edKey)}},
+			wantErr: true,
+		},
+		{
+			desc:    "keyMismatch",
+			keys:    []*trillian.SigningKey{{SignatureAlgorithm: sigpb.DigitallySigned_ECDSA, PrivateKey: privateKey(edKey)}},
+			wantErr: true,
+		},
+		{
+			desc:    "noPrivateKey",
+			keys:    []*trillian.SigningKey{{SignatureAlgorithm: sigpb.DigitallySigned_ED25519}},
+			wantErr: true,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			tree := *testonly.LogTree
+			tree.HashAlgorithm = sigpb.DigitallySigned_SHA256
+			tree.SignatureAlgorithm = sigpb.DigitallySigned_ECDSA
+			tree.PrivateKey = privateKey(ecdsaKey)
+			tree.AdditionalSigningKeys = test.keys
+
+			signer, err := Signer(context.Background(), &tree)
+			if hasErr := err != nil; hasErr != test.wantErr {
+				t.Fatalf("Signer() = (_, %q), wantErr = %v", err, test.wantErr)
+			} else if hasErr {
+				return
+			}
+			if got, want := len(signer.Additional), len(test.keys); got != want {
+				t.Fatalf("len(Signer().Additional) = %v, want %v", got, want)
+			}
+			for i, a := range signer.Additional {
+				if got, want := tcrypto.SignatureAlgorithm(a.Public()), test.keys[i].SignatureAlgorithm; got != want {
+					t.Errorf("Signer().Additional[%d] algorithm = %v, want %v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestKeyChallenge(t *testing.T) {
+	newTree := func(pubDER []byte, typeURL string, value []byte) *trillian.Tree {
+		return &trillian.Tree{
+			PrivateKey: &any.Any{TypeUrl: typeURL, Value: value},
+			PublicKey:  &keyspb.PublicKey{Der: pubDER},
+		}
+	}
+	base := newTree([]byte("pub"), "type.googleapis.com/keyspb.ExternalKey", []byte("key"))
+	want, err := KeyChallenge(base)
+	if err != nil {
+		t.Fatalf("KeyChallenge() = (_, %v), want nil error", err)
+	}
+	if again, err := KeyChallenge(proto.Clone(base).(*trillian.Tree)); err != nil || !bytes.Equal(again, want) {
+		t.Errorf("KeyChallenge() of the same tree = (%x, %v), want (%x, nil)", again, err, want)
+	}
+
+	for _, test := range []struct {
+		desc    string
+		tree    *trillian.Tree
+		wantErr bool
+	}{
+		{desc: "otherPublicKey", tree: newTree([]byte("pub2"), "type.googleapis.com/keyspb.ExternalKey", []byte("key"))},
+		{desc: "otherType", tree: newTree([]byte("pub"), "type.googleapis.com/keyspb.PrivateKey", []byte("key"))},
+		{desc: "otherKey", tree: newTree([]byte("pub"), "type.googleapis.com/keyspb.ExternalKey", []byte("key2"))},
+		{desc: "shiftedBytes", tree: newTree([]byte("pubt"), "ype.googleapis.com/keyspb.ExternalKey", []byte("key"))},
+		{desc: "noPrivateKey", tree: &trillian.Tree{PublicKey: base.PublicKey}, wantErr: true},
+		{desc: "noPublicKey", tree: &trillian.Tree{PrivateKey: base.PrivateKey}, wantErr: true},
+	} {
+		got, err := KeyChallenge(test.tree)
+		if hasErr := err != nil; hasErr != test.wantErr {
+			t.Errorf("%v: KeyChallenge() = (_, %v), wantErr = %v", test.desc, err, test.wantErr)
+			continue
+		}
+		if err == nil && bytes.Equal(got, want) {
+			t.Errorf("%v: KeyChallenge() = %x, the same as that of a different tree", test.desc, got)
+		}
+	}
+}
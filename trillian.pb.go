@@ -132,6 +132,66 @@ func (x TreeType) String() string {
 }
 func (TreeType) EnumDescriptor() ([]byte, []int) { return fileDescriptor3, []int{2} }
 
+// Precision of the timestamps of the signed roots of a log.
+type RootTimestampPrecision int32
+
+const (
+	// Timestamps have the full precision of the signer's clock, in nanoseconds.
+	RootTimestampPrecision_NANOSECOND_PRECISION RootTimestampPrecision = 0
+	// Timestamps are truncated to whole milliseconds.
+	RootTimestampPrecision_MILLISECOND_PRECISION RootTimestampPrecision = 1
+	// Timestamps are truncated to whole seconds.
+	RootTimestampPrecision_SECOND_PRECISION RootTimestampPrecision = 2
+)
+
+var RootTimestampPrecision_name = map[int32]string{
+	0: "NANOSECOND_PRECISION",
+	1: "MILLISECOND_PRECISION",
+	2: "SECOND_PRECISION",
+}
+var RootTimestampPrecision_value = map[string]int32{
+	"NANOSECOND_PRECISION":  0,
+	"MILLISECOND_PRECISION": 1,
+	"SECOND_PRECISION":      2,
+}
+
+func (x RootTimestampPrecision) String() string {
+	return proto.EnumName(RootTimestampPrecision_name, int32(x))
+}
+func (RootTimestampPrecision) EnumDescriptor() ([]byte, []int) { return fileDescriptor3, []int{3} }
+
+// Whether the signer verifies the Merkle tree nodes it writes for a log.
+type WriteVerification int32
+
+const (
+	// Writes aren't verified. Logs created before write verification was
+	// introduced have this value.
+	WriteVerification_UNKNOWN_WRITE_VERIFICATION WriteVerification = 0
+	// Before each root is stored, the signer checks that every complete subtree
+	// it wrote hashes its two children, as stored at the new revision, and that
+	// the root hash recomputed from the stored subtrees matches the one it
+	// computed incrementally. A mismatch fails the sequencing pass.
+	WriteVerification_VERIFY_WRITES WriteVerification = 1
+	// Writes aren't verified.
+	WriteVerification_SKIP_WRITE_VERIFICATION WriteVerification = 2
+)
+
+var WriteVerification_name = map[int32]string{
+	0: "UNKNOWN_WRITE_VERIFICATION",
+	1: "VERIFY_WRITES",
+	2: "SKIP_WRITE_VERIFICATION",
+}
+var WriteVerification_value = map[string]int32{
+	"UNKNOWN_WRITE_VERIFICATION": 0,
+	"VERIFY_WRITES":              1,
+	"SKIP_WRITE_VERIFICATION":    2,
+}
+
+func (x WriteVerification) String() string {
+	return proto.EnumName(WriteVerification_name, int32(x))
+}
+func (WriteVerification) EnumDescriptor() ([]byte, []int) { return fileDescriptor3, []int{4} }
+
 // Represents a tree, which may be either a verifiable log or map.
 // Readonly attributes are assigned at tree creation, after which they may not
 // be modified.
@@ -193,6 +253,61 @@ type Tree struct {
 	// Time of tree deletion, if any.
 	// Readonly.
 	DeleteTime *google_protobuf1.Timestamp `protobuf:"bytes,20,opt,name=delete_time,json=deleteTime" json:"delete_time,omitempty"`
+	// Precision of the timestamps of the signed roots of a log. Root timestamps
+	// are nanoseconds since the Unix epoch, whatever the time zone of the
+	// signer, and strictly increase from one root to the next: a root signed
+	// within the same clock tick as the previous one is timestamped one tick
+	// after it.
+	RootTimestampPrecision RootTimestampPrecision `protobuf:"varint,21,opt,name=root_timestamp_precision,json=rootTimestampPrecision,enum=trillian.RootTimestampPrecision" json:"root_timestamp_precision,omitempty"`
+	// Free-form labels that group trees, e.g. by environment or customer.
+	// Keys are up to 63 lowercase letters, digits, '.', '_' or '-', starting
+	// with a letter. Values are up to 255 characters.
+	Labels map[string]string `protobuf:"bytes,22,rep,name=labels" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Secondary indexes of the leaves of a log, keyed by fields of their extra
+	// data. The signer adds each leaf to the indexes as it integrates it, and
+	// the GetLeafIndicesByKey RPC looks leaves up by key. Indexes can only be
+	// defined when the log is created, so that they cover all its leaves.
+	// Only supported by some storage implementations.
+	LeafIndexes []*LeafIndexSpec `protobuf:"bytes,23,rep,name=leaf_indexes,json=leafIndexes" json:"leaf_indexes,omitempty"`
+	// Probabilistic filter of the identity hashes of the leaves of a log. The
+	// signer adds each leaf to the filter as it integrates it, and the
+	// CheckLeafFilter RPC answers whether a leaf may have been logged. Can only
+	// be defined when the log is created, so that it covers all its leaves.
+	// Only supported by some storage implementations.
+	LeafFilter *LeafFilterSpec `protobuf:"bytes,24,opt,name=leaf_filter,json=leafFilter" json:"leaf_filter,omitempty"`
+	// If set, the tree is in maintenance: its reads fail with UNAVAILABLE and
+	// a RetryInfo detail, its writes fail with FAILED_PRECONDITION, and the
+	// signer leaves it alone. Other trees on the same servers aren't affected.
+	// The admin API still serves the tree, so that maintenance can be ended by
+	// clearing the field.
+	Maintenance *MaintenanceMode `protobuf:"bytes,25,opt,name=maintenance" json:"maintenance,omitempty"`
+	// Keys which sign the roots of the tree in addition to private_key, e.g.
+	// to migrate the tree to another signature algorithm, or to serve
+	// verifiers which only support some algorithms. Each root carries one
+	// additional signature per key, in the same order.
+	// Unlike private_key, keys can be added and removed after the tree is
+	// created. Their private keys are write-only.
+	// Only supported by some storage implementations.
+	AdditionalSigningKeys []*SigningKey `protobuf:"bytes,26,rep,name=additional_signing_keys,json=additionalSigningKeys" json:"additional_signing_keys,omitempty"`
+	// Output only. The latest root and public key fingerprint of the tree, only
+	// set by GetTree when requested with include_status. Never stored.
+	Status *TreeStatus `protobuf:"bytes,27,opt,name=status" json:"status,omitempty"`
+	// Maximum merge delay (MMD) of a log: the longest a leaf may wait between
+	// being queued and being integrated. The signer tracks whether every leaf
+	// is integrated within it, and summarizes compliance in windows of time
+	// which the GetMergeDelayCompliance RPC returns for audits. If zero, merge
+	// delays aren't tracked.
+	MaxMergeDelay *google_protobuf3.Duration `protobuf:"bytes,28,opt,name=max_merge_delay,json=maxMergeDelay" json:"max_merge_delay,omitempty"`
+	// If set, the log only accepts new leaves during a window of time, e.g.
+	// because it's a temporal shard of a larger log. QueueLeaf and QueueLeaves
+	// requests outside the window fail with FAILED_PRECONDITION and a
+	// VALIDITY_WINDOW violation. Reads and the signer aren't affected, so the
+	// log still integrates the leaves queued during the window.
+	ValidityWindow *ValidityWindow `protobuf:"bytes,29,opt,name=validity_window,json=validityWindow" json:"validity_window,omitempty"`
+	// Whether the signer verifies the Merkle tree nodes it writes for a log.
+	// Defaults to VERIFY_WRITES for logs created with CreateTree. Not supported
+	// for maps.
+	WriteVerification WriteVerification `protobuf:"varint,30,opt,name=write_verification,json=writeVerification,enum=trillian.WriteVerification" json:"write_verification,omitempty"`
 }
 
 func (m *Tree) Reset()                    { *m = Tree{} }
@@ -312,6 +427,275 @@ func (m *Tree) GetDeleteTime() *google_protobuf1.Timestamp {
 	return nil
 }
 
+func (m *Tree) GetRootTimestampPrecision() RootTimestampPrecision {
+	if m != nil {
+		return m.RootTimestampPrecision
+	}
+	return RootTimestampPrecision_NANOSECOND_PRECISION
+}
+
+func (m *Tree) GetLabels() map[string]string {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
+func (m *Tree) GetLeafIndexes() []*LeafIndexSpec {
+	if m != nil {
+		return m.LeafIndexes
+	}
+	return nil
+}
+
+func (m *Tree) GetLeafFilter() *LeafFilterSpec {
+	if m != nil {
+		return m.LeafFilter
+	}
+	return nil
+}
+
+func (m *Tree) GetMaintenance() *MaintenanceMode {
+	if m != nil {
+		return m.Maintenance
+	}
+	return nil
+}
+
+func (m *Tree) GetAdditionalSigningKeys() []*SigningKey {
+	if m != nil {
+		return m.AdditionalSigningKeys
+	}
+	return nil
+}
+
+func (m *Tree) GetStatus() *TreeStatus {
+	if m != nil {
+		return m.Status
+	}
+	return nil
+}
+
+func (m *Tree) GetMaxMergeDelay() *google_protobuf3.Duration {
+	if m != nil {
+		return m.MaxMergeDelay
+	}
+	return nil
+}
+
+func (m *Tree) GetValidityWindow() *ValidityWindow {
+	if m != nil {
+		return m.ValidityWindow
+	}
+	return nil
+}
+
+func (m *Tree) GetWriteVerification() WriteVerification {
+	if m != nil {
+		return m.WriteVerification
+	}
+	return WriteVerification_UNKNOWN_WRITE_VERIFICATION
+}
+
+// TreeStatus is the state of a tree as reported by GetTree, e.g. for
+// provisioning tools to check that a tree is healthy and keyed as expected.
+type TreeStatus struct {
+	// The latest signed root of a log tree.
+	LatestLogRoot *SignedLogRoot `protobuf:"bytes,1,opt,name=latest_log_root,json=latestLogRoot" json:"latest_log_root,omitempty"`
+	// The latest signed root of a map tree.
+	LatestMapRoot *SignedMapRoot `protobuf:"bytes,2,opt,name=latest_map_root,json=latestMapRoot" json:"latest_map_root,omitempty"`
+	// SHA-256 digest of the DER of the tree's public_key.
+	PublicKeyFingerprint []byte `protobuf:"bytes,3,opt,name=public_key_fingerprint,json=publicKeyFingerprint,proto3" json:"public_key_fingerprint,omitempty"`
+}
+
+func (m *TreeStatus) Reset()                    { *m = TreeStatus{} }
+func (m *TreeStatus) String() string            { return proto.CompactTextString(m) }
+func (*TreeStatus) ProtoMessage()               {}
+func (*TreeStatus) Descriptor() ([]byte, []int) { return fileDescriptor3, []int{1} }
+
+func (m *TreeStatus) GetLatestLogRoot() *SignedLogRoot {
+	if m != nil {
+		return m.LatestLogRoot
+	}
+	return nil
+}
+
+func (m *TreeStatus) GetLatestMapRoot() *SignedMapRoot {
+	if m != nil {
+		return m.LatestMapRoot
+	}
+	return nil
+}
+
+func (m *TreeStatus) GetPublicKeyFingerprint() []byte {
+	if m != nil {
+		return m.PublicKeyFingerprint
+	}
+	return nil
+}
+
+// SigningKey is a key pair with which a tree signs its roots.
+type SigningKey struct {
+	// Signature algorithm of the key.
+	SignatureAlgorithm sigpb.DigitallySigned_SignatureAlgorithm `protobuf:"varint,1,opt,name=signature_algorithm,json=signatureAlgorithm,enum=sigpb.DigitallySigned_SignatureAlgorithm" json:"signature_algorithm,omitempty"`
+	// Identifies the private key, like Tree.private_key.
+	PrivateKey *google_protobuf2.Any `protobuf:"bytes,2,opt,name=private_key,json=privateKey" json:"private_key,omitempty"`
+	// The public key which verifies the signatures of the private key. Derived
+	// from the private key if not set.
+	PublicKey *keyspb.PublicKey `protobuf:"bytes,3,opt,name=public_key,json=publicKey" json:"public_key,omitempty"`
+}
+
+func (m *SigningKey) Reset()                    { *m = SigningKey{} }
+func (m *SigningKey) String() string            { return proto.CompactTextString(m) }
+func (*SigningKey) ProtoMessage()               {}
+func (*SigningKey) Descriptor() ([]byte, []int) { return fileDescriptor3, []int{2} }
+
+func (m *SigningKey) GetSignatureAlgorithm() sigpb.DigitallySigned_SignatureAlgorithm {
+	if m != nil {
+		return m.SignatureAlgorithm
+	}
+	return sigpb.DigitallySigned_ANONYMOUS
+}
+
+func (m *SigningKey) GetPrivateKey() *google_protobuf2.Any {
+	if m != nil {
+		return m.PrivateKey
+	}
+	return nil
+}
+
+func (m *SigningKey) GetPublicKey() *keyspb.PublicKey {
+	if m != nil {
+		return m.PublicKey
+	}
+	return nil
+}
+
+// MaintenanceMode describes the maintenance of a tree.
+type MaintenanceMode struct {
+	// How long clients should wait before retrying reads of the tree. Zero
+	// leaves it to clients.
+	RetryAfter *google_protobuf3.Duration `protobuf:"bytes,1,opt,name=retry_after,json=retryAfter" json:"retry_after,omitempty"`
+	// Why the tree is in maintenance, returned to clients in errors. Up to 200
+	// characters.
+	Reason string `protobuf:"bytes,2,opt,name=reason" json:"reason,omitempty"`
+}
+
+func (m *MaintenanceMode) Reset()                    { *m = MaintenanceMode{} }
+func (m *MaintenanceMode) String() string            { return proto.CompactTextString(m) }
+func (*MaintenanceMode) ProtoMessage()               {}
+func (*MaintenanceMode) Descriptor() ([]byte, []int) { return fileDescriptor3, []int{3} }
+
+func (m *MaintenanceMode) GetRetryAfter() *google_protobuf3.Duration {
+	if m != nil {
+		return m.RetryAfter
+	}
+	return nil
+}
+
+func (m *MaintenanceMode) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+// ValidityWindow is the window of time during which a log accepts new
+// leaves. Either bound may be unset, leaving the window open on that side.
+type ValidityWindow struct {
+	// Leaves are rejected before this time.
+	NotBefore *google_protobuf1.Timestamp `protobuf:"bytes,1,opt,name=not_before,json=notBefore" json:"not_before,omitempty"`
+	// Leaves are rejected after this time.
+	NotAfter *google_protobuf1.Timestamp `protobuf:"bytes,2,opt,name=not_after,json=notAfter" json:"not_after,omitempty"`
+}
+
+func (m *ValidityWindow) Reset()                    { *m = ValidityWindow{} }
+func (m *ValidityWindow) String() string            { return proto.CompactTextString(m) }
+func (*ValidityWindow) ProtoMessage()               {}
+func (*ValidityWindow) Descriptor() ([]byte, []int) { return fileDescriptor3, []int{4} }
+
+func (m *ValidityWindow) GetNotBefore() *google_protobuf1.Timestamp {
+	if m != nil {
+		return m.NotBefore
+	}
+	return nil
+}
+
+func (m *ValidityWindow) GetNotAfter() *google_protobuf1.Timestamp {
+	if m != nil {
+		return m.NotAfter
+	}
+	return nil
+}
+
+// LeafIndexSpec defines a secondary index of the leaves of a log.
+//
+// The extra data of the leaves is parsed as a JSON object, and the index key
+// of a leaf is the value of one of its fields, e.g. a domain or package name.
+// Leaves whose extra data isn't a JSON object, or doesn't have the field, or
+// whose field isn't a string or an array of strings, aren't indexed.
+type LeafIndexSpec struct {
+	// Name of the index, unique within the tree: up to 63 lowercase letters,
+	// digits, '_' or '-', starting with a letter.
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	// Dot-separated path of the field holding the key, e.g. "subject.domain".
+	// A leaf whose field is an array of strings has each of them as a key.
+	Field string `protobuf:"bytes,2,opt,name=field" json:"field,omitempty"`
+}
+
+func (m *LeafIndexSpec) Reset()                    { *m = LeafIndexSpec{} }
+func (m *LeafIndexSpec) String() string            { return proto.CompactTextString(m) }
+func (*LeafIndexSpec) ProtoMessage()               {}
+func (*LeafIndexSpec) Descriptor() ([]byte, []int) { return fileDescriptor3, []int{5} }
+
+func (m *LeafIndexSpec) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *LeafIndexSpec) GetField() string {
+	if m != nil {
+		return m.Field
+	}
+	return ""
+}
+
+// LeafFilterSpec sizes the leaf filter of a log.
+//
+// The filter is a blocked Bloom filter: each leaf identity hash sets bits in
+// one of its fixed-size blocks, so adding or checking a hash only needs that
+// block. It has no false negatives, and is sized for the given false positive
+// rate at the expected number of leaves. The rate grows once the log has more
+// leaves than expected.
+type LeafFilterSpec struct {
+	// Number of leaves the filter is sized for, at most 10^10.
+	ExpectedLeaves int64 `protobuf:"varint,1,opt,name=expected_leaves,json=expectedLeaves" json:"expected_leaves,omitempty"`
+	// False positive rate at the expected number of leaves, between 10^-9 and
+	// 0.5.
+	FalsePositiveRate float64 `protobuf:"fixed64,2,opt,name=false_positive_rate,json=falsePositiveRate" json:"false_positive_rate,omitempty"`
+}
+
+func (m *LeafFilterSpec) Reset()                    { *m = LeafFilterSpec{} }
+func (m *LeafFilterSpec) String() string            { return proto.CompactTextString(m) }
+func (*LeafFilterSpec) ProtoMessage()               {}
+func (*LeafFilterSpec) Descriptor() ([]byte, []int) { return fileDescriptor3, []int{6} }
+
+func (m *LeafFilterSpec) GetExpectedLeaves() int64 {
+	if m != nil {
+		return m.ExpectedLeaves
+	}
+	return 0
+}
+
+func (m *LeafFilterSpec) GetFalsePositiveRate() float64 {
+	if m != nil {
+		return m.FalsePositiveRate
+	}
+	return 0
+}
+
 type SignedEntryTimestamp struct {
 	TimestampNanos int64                  `protobuf:"varint,1,opt,name=timestamp_nanos,json=timestampNanos" json:"timestamp_nanos,omitempty"`
 	LogId          int64                  `protobuf:"varint,2,opt,name=log_id,json=logId" json:"log_id,omitempty"`
@@ -321,7 +705,7 @@ type SignedEntryTimestamp struct {
 func (m *SignedEntryTimestamp) Reset()                    { *m = SignedEntryTimestamp{} }
 func (m *SignedEntryTimestamp) String() string            { return proto.CompactTextString(m) }
 func (*SignedEntryTimestamp) ProtoMessage()               {}
-func (*SignedEntryTimestamp) Descriptor() ([]byte, []int) { return fileDescriptor3, []int{1} }
+func (*SignedEntryTimestamp) Descriptor() ([]byte, []int) { return fileDescriptor3, []int{7} }
 
 func (m *SignedEntryTimestamp) GetTimestampNanos() int64 {
 	if m != nil {
@@ -354,12 +738,19 @@ type SignedLogRoot struct {
 	Signature    *sigpb.DigitallySigned `protobuf:"bytes,4,opt,name=signature" json:"signature,omitempty"`
 	LogId        int64                  `protobuf:"varint,5,opt,name=log_id,json=logId" json:"log_id,omitempty"`
 	TreeRevision int64                  `protobuf:"varint,6,opt,name=tree_revision,json=treeRevision" json:"tree_revision,omitempty"`
+	// Signatures of the root by the additional_signing_keys of the log, in the
+	// same order, signing the same data as signature.
+	AdditionalSignatures []*sigpb.DigitallySigned `protobuf:"bytes,7,rep,name=additional_signatures,json=additionalSignatures" json:"additional_signatures,omitempty"`
+	// Metadata associated with the log root, which is signed along with it. It
+	// is set in the initial root of the log by InitLog, e.g. to a
+	// LogRootMetadata.
+	Metadata *google_protobuf2.Any `protobuf:"bytes,8,opt,name=metadata" json:"metadata,omitempty"`
 }
 
 func (m *SignedLogRoot) Reset()                    { *m = SignedLogRoot{} }
 func (m *SignedLogRoot) String() string            { return proto.CompactTextString(m) }
 func (*SignedLogRoot) ProtoMessage()               {}
-func (*SignedLogRoot) Descriptor() ([]byte, []int) { return fileDescriptor3, []int{2} }
+func (*SignedLogRoot) Descriptor() ([]byte, []int) { return fileDescriptor3, []int{8} }
 
 func (m *SignedLogRoot) GetTimestampNanos() int64 {
 	if m != nil {
@@ -403,6 +794,20 @@ func (m *SignedLogRoot) GetTreeRevision() int64 {
 	return 0
 }
 
+func (m *SignedLogRoot) GetAdditionalSignatures() []*sigpb.DigitallySigned {
+	if m != nil {
+		return m.AdditionalSignatures
+	}
+	return nil
+}
+
+func (m *SignedLogRoot) GetMetadata() *google_protobuf2.Any {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
 // SignedMapRoot represents a commitment by a Map to a particular tree.
 type SignedMapRoot struct {
 	TimestampNanos int64  `protobuf:"varint,1,opt,name=timestamp_nanos,json=timestampNanos" json:"timestamp_nanos,omitempty"`
@@ -415,12 +820,15 @@ type SignedMapRoot struct {
 	// layer in a SetLeaves map request.  It could be used to persist state
 	// needed to recreate the Map from an external data source.
 	Metadata *google_protobuf2.Any `protobuf:"bytes,7,opt,name=metadata" json:"metadata,omitempty"`
+	// Signatures of the root by the additional_signing_keys of the map, in the
+	// same order. Like signature, they sign the root without any signatures.
+	AdditionalSignatures []*sigpb.DigitallySigned `protobuf:"bytes,8,rep,name=additional_signatures,json=additionalSignatures" json:"additional_signatures,omitempty"`
 }
 
 func (m *SignedMapRoot) Reset()                    { *m = SignedMapRoot{} }
 func (m *SignedMapRoot) String() string            { return proto.CompactTextString(m) }
 func (*SignedMapRoot) ProtoMessage()               {}
-func (*SignedMapRoot) Descriptor() ([]byte, []int) { return fileDescriptor3, []int{3} }
+func (*SignedMapRoot) Descriptor() ([]byte, []int) { return fileDescriptor3, []int{9} }
 
 func (m *SignedMapRoot) GetTimestampNanos() int64 {
 	if m != nil {
@@ -464,81 +872,252 @@ func (m *SignedMapRoot) GetMetadata() *google_protobuf2.Any {
 	return nil
 }
 
+func (m *SignedMapRoot) GetAdditionalSignatures() []*sigpb.DigitallySigned {
+	if m != nil {
+		return m.AdditionalSignatures
+	}
+	return nil
+}
+
+// SourceLogRoot identifies a root of the log whose contents a map holds.
+// Map personalities which map a Trillian log can pass it as the metadata of
+// SetMapLeaves requests; the map server then checks that it is a root of the
+// log before signing the new map root, binding the map root to log history.
+type SourceLogRoot struct {
+	// ID of the log.
+	LogId int64 `protobuf:"varint,1,opt,name=log_id,json=logId" json:"log_id,omitempty"`
+	// Size of the log tree at the root.
+	TreeSize int64 `protobuf:"varint,2,opt,name=tree_size,json=treeSize" json:"tree_size,omitempty"`
+	// Root hash of the log tree of that size.
+	RootHash []byte `protobuf:"bytes,3,opt,name=root_hash,json=rootHash,proto3" json:"root_hash,omitempty"`
+}
+
+func (m *SourceLogRoot) Reset()                    { *m = SourceLogRoot{} }
+func (m *SourceLogRoot) String() string            { return proto.CompactTextString(m) }
+func (*SourceLogRoot) ProtoMessage()               {}
+func (*SourceLogRoot) Descriptor() ([]byte, []int) { return fileDescriptor3, []int{10} }
+
+func (m *SourceLogRoot) GetLogId() int64 {
+	if m != nil {
+		return m.LogId
+	}
+	return 0
+}
+
+func (m *SourceLogRoot) GetTreeSize() int64 {
+	if m != nil {
+		return m.TreeSize
+	}
+	return 0
+}
+
+func (m *SourceLogRoot) GetRootHash() []byte {
+	if m != nil {
+		return m.RootHash
+	}
+	return nil
+}
+
+// LogRootMetadata identifies a log. Operators can pass it to InitLog to have
+// it signed in the initial root of the log when the log is provisioned.
+type LogRootMetadata struct {
+	// Origin of the log, e.g. its URL, so that its roots can't be passed off as
+	// those of another log.
+	Origin string `protobuf:"bytes,1,opt,name=origin" json:"origin,omitempty"`
+	// Shard of the log, for logs whose entries are split across several trees.
+	Shard *LogShard `protobuf:"bytes,2,opt,name=shard" json:"shard,omitempty"`
+}
+
+func (m *LogRootMetadata) Reset()                    { *m = LogRootMetadata{} }
+func (m *LogRootMetadata) String() string            { return proto.CompactTextString(m) }
+func (*LogRootMetadata) ProtoMessage()               {}
+func (*LogRootMetadata) Descriptor() ([]byte, []int) { return fileDescriptor3, []int{11} }
+
+func (m *LogRootMetadata) GetOrigin() string {
+	if m != nil {
+		return m.Origin
+	}
+	return ""
+}
+
+func (m *LogRootMetadata) GetShard() *LogShard {
+	if m != nil {
+		return m.Shard
+	}
+	return nil
+}
+
+// LogShard identifies the shard of a log whose entries are split across
+// several trees by time.
+type LogShard struct {
+	// Name of the shard, e.g. "2019".
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	// Start of the interval of the entries of the shard, inclusive.
+	Start *google_protobuf1.Timestamp `protobuf:"bytes,2,opt,name=start" json:"start,omitempty"`
+	// Limit of the interval of the entries of the shard, exclusive.
+	Limit *google_protobuf1.Timestamp `protobuf:"bytes,3,opt,name=limit" json:"limit,omitempty"`
+}
+
+func (m *LogShard) Reset()                    { *m = LogShard{} }
+func (m *LogShard) String() string            { return proto.CompactTextString(m) }
+func (*LogShard) ProtoMessage()               {}
+func (*LogShard) Descriptor() ([]byte, []int) { return fileDescriptor3, []int{12} }
+
+func (m *LogShard) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *LogShard) GetStart() *google_protobuf1.Timestamp {
+	if m != nil {
+		return m.Start
+	}
+	return nil
+}
+
+func (m *LogShard) GetLimit() *google_protobuf1.Timestamp {
+	if m != nil {
+		return m.Limit
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*Tree)(nil), "trillian.Tree")
+	proto.RegisterType((*TreeStatus)(nil), "trillian.TreeStatus")
+	proto.RegisterType((*SigningKey)(nil), "trillian.SigningKey")
+	proto.RegisterType((*MaintenanceMode)(nil), "trillian.MaintenanceMode")
+	proto.RegisterType((*ValidityWindow)(nil), "trillian.ValidityWindow")
+	proto.RegisterType((*LeafIndexSpec)(nil), "trillian.LeafIndexSpec")
+	proto.RegisterType((*LeafFilterSpec)(nil), "trillian.LeafFilterSpec")
 	proto.RegisterType((*SignedEntryTimestamp)(nil), "trillian.SignedEntryTimestamp")
 	proto.RegisterType((*SignedLogRoot)(nil), "trillian.SignedLogRoot")
 	proto.RegisterType((*SignedMapRoot)(nil), "trillian.SignedMapRoot")
+	proto.RegisterType((*SourceLogRoot)(nil), "trillian.SourceLogRoot")
+	proto.RegisterType((*LogRootMetadata)(nil), "trillian.LogRootMetadata")
+	proto.RegisterType((*LogShard)(nil), "trillian.LogShard")
 	proto.RegisterEnum("trillian.HashStrategy", HashStrategy_name, HashStrategy_value)
 	proto.RegisterEnum("trillian.TreeState", TreeState_name, TreeState_value)
 	proto.RegisterEnum("trillian.TreeType", TreeType_name, TreeType_value)
+	proto.RegisterEnum("trillian.RootTimestampPrecision", RootTimestampPrecision_name, RootTimestampPrecision_value)
+	proto.RegisterEnum("trillian.WriteVerification", WriteVerification_name, WriteVerification_value)
 }
 
 func init() { proto.RegisterFile("trillian.proto", fileDescriptor3) }
 
 var fileDescriptor3 = []byte{
-	// 997 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x56, 0x5d, 0x4f, 0xe3, 0x46,
-	0x17, 0x5e, 0x27, 0xde, 0xc4, 0x39, 0x09, 0xc1, 0x0c, 0x0b, 0x6b, 0xf2, 0x4a, 0xef, 0xa6, 0xb4,
-	0x52, 0x29, 0x17, 0x61, 0x9b, 0x16, 0xa4, 0x6a, 0x2f, 0x2a, 0x43, 0x0c, 0x21, 0x40, 0x12, 0x8d,
-	0xdd, 0x56, 0xcb, 0x8d, 0x35, 0x89, 0xa7, 0x8e, 0xb5, 0x76, 0x6c, 0xd9, 0x93, 0xd5, 0x7a, 0xa5,
-	0xde, 0xf5, 0xa6, 0x52, 0x7f, 0x66, 0xff, 0x46, 0xa5, 0x6a, 0xc6, 0x1f, 0x04, 0x68, 0x77, 0x57,
-	0x55, 0x6f, 0x60, 0xce, 0x73, 0x9e, 0xe7, 0x7c, 0x78, 0xce, 0xb1, 0x03, 0x6d, 0x16, 0x7b, 0xbe,
-	0xef, 0x91, 0x65, 0x2f, 0x8a, 0x43, 0x16, 0x22, 0xa5, 0xb0, 0x3b, 0x9d, 0x79, 0x9c, 0x46, 0x2c,
-	0x3c, 0x7a, 0x43, 0xd3, 0x24, 0x9a, 0xe5, 0xff, 0x32, 0x56, 0x47, 0xcb, 0x7d, 0x89, 0xe7, 0x46,
-	0xb3, 0xec, 0x6f, 0xee, 0xd9, 0x73, 0xc3, 0xd0, 0xf5, 0xe9, 0x91, 0xb0, 0x66, 0xab, 0x9f, 0x8f,
-	0xc8, 0x32, 0xcd, 0x5d, 0xff, 0x7f, 0xe8, 0x72, 0x56, 0x31, 0x61, 0x5e, 0x98, 0xa7, 0xee, 0xbc,
-	0x78, 0xe8, 0x67, 0x5e, 0x40, 0x13, 0x46, 0x82, 0x28, 0x23, 0xec, 0xff, 0x56, 0x07, 0xd9, 0x8a,
-	0x29, 0x45, 0xcf, 0xa1, 0xce, 0x62, 0x4a, 0x6d, 0xcf, 0xd1, 0xa4, 0xae, 0x74, 0x50, 0xc5, 0x35,
-	0x6e, 0x5e, 0x3a, 0xa8, 0x0f, 0x20, 0x1c, 0x09, 0x23, 0x8c, 0x6a, 0x95, 0xae, 0x74, 0xd0, 0xee,
-	0x6f, 0xf7, 0xca, 0x16, 0xb9, 0xd8, 0xe4, 0x2e, 0xdc, 0x60, 0xc5, 0x11, 0x1d, 0x81, 0x30, 0x6c,
-	0x96, 0x46, 0x54, 0xab, 0x0a, 0x09, 0xba, 0x2f, 0xb1, 0xd2, 0x88, 0x62, 0x85, 0xe5, 0x27, 0xf4,
-	0x0a, 0x36, 0x16, 0x24, 0x59, 0xd8, 0x09, 0x8b, 0x09, 0xa3, 0x6e, 0xaa, 0xc9, 0x42, 0xb4, 0x7b,
-	0x27, 0x1a, 0x92, 0x64, 0x61, 0xe6, 0x5e, 0xdc, 0x5a, 0xac, 0x59, 0xe8, 0x0a, 0xda, 0x42, 0x4c,
-	0x7c, 0x37, 0x8c, 0x3d, 0xb6, 0x08, 0xb4, 0xa7, 0x42, 0xfd, 0x45, 0x2f, 0x7b, 0x8a, 0x03, 0xcf,
-	0xf5, 0x18, 0xf1, 0xfd, 0xd4, 0xf4, 0xdc, 0x25, 0x75, 0x44, 0x28, 0xbd, 0xe0, 0x62, 0x91, 0xb8,
-	0x34, 0xd1, 0x2d, 0x6c, 0x27, 0x9e, 0xbb, 0x24, 0x6c, 0x15, 0xd3, 0xb5, 0x88, 0x35, 0x11, 0xf1,
-	0xab, 0x7f, 0x88, 0x68, 0x16, 0x8a, 0xbb, 0xb0, 0x28, 0x79, 0x84, 0xa1, 0xcf, 0xa0, 0xe5, 0x78,
-	0x49, 0xe4, 0x93, 0xd4, 0x5e, 0x92, 0x80, 0x6a, 0x4a, 0x57, 0x3a, 0x68, 0xe0, 0x66, 0x8e, 0x8d,
-	0x49, 0x40, 0x51, 0x17, 0x9a, 0x0e, 0x4d, 0xe6, 0xb1, 0x17, 0xf1, 0x5b, 0xd4, 0x1a, 0x39, 0xe3,
-	0x0e, 0x42, 0xc7, 0xd0, 0x8c, 0x62, 0xef, 0x2d, 0x61, 0xd4, 0x7e, 0x43, 0x53, 0xad, 0xd5, 0x95,
-	0x0e, 0x9a, 0xfd, 0x67, 0xbd, 0xec, 0xa2, 0x7b, 0xc5, 0x45, 0xf7, 0xf4, 0x65, 0x8a, 0x21, 0x27,
-	0x5e, 0xd1, 0x14, 0x7d, 0x0f, 0x6a, 0xc2, 0xc2, 0x98, 0xb8, 0xd4, 0x4e, 0x28, 0x63, 0xde, 0xd2,
-	0x4d, 0xb4, 0x8d, 0x0f, 0x68, 0x37, 0x73, 0xb6, 0x99, 0x93, 0xd1, 0x4b, 0x80, 0x68, 0x35, 0xf3,
-	0xbd, 0xb9, 0x48, 0xdb, 0x16, 0xd2, 0xad, 0x5e, 0x3e, 0xc2, 0x53, 0xe1, 0xb9, 0xa2, 0x29, 0x6e,
-	0x44, 0xc5, 0x11, 0x19, 0xb0, 0x15, 0x90, 0x77, 0x76, 0x1c, 0x86, 0xcc, 0x2e, 0xe6, 0x52, 0xdb,
-	0x14, 0xc2, 0xbd, 0x47, 0x39, 0x07, 0x39, 0x01, 0x6f, 0x06, 0xe4, 0x1d, 0x0e, 0x43, 0x56, 0x00,
-	0xe8, 0x15, 0x34, 0xe7, 0x31, 0xe5, 0xfd, 0xf2, 0xe1, 0xd5, 0x54, 0x11, 0xa0, 0xf3, 0x28, 0x80,
-	0x55, 0x4c, 0x36, 0x86, 0x8c, 0xce, 0x01, 0x2e, 0x5e, 0x45, 0x4e, 0x29, 0xde, 0xfa, 0xb8, 0x38,
-	0xa3, 0x0b, 0xb1, 0x06, 0x75, 0x87, 0xfa, 0x94, 0x51, 0x47, 0xdb, 0xee, 0x4a, 0x07, 0x0a, 0x2e,
-	0x4c, 0x1e, 0x36, 0x3b, 0x66, 0x61, 0x9f, 0x7d, 0x3c, 0x6c, 0x46, 0xe7, 0xc0, 0x48, 0x56, 0x90,
-	0xba, 0x3d, 0x92, 0x95, 0xba, 0xaa, 0x8c, 0x64, 0x05, 0xd4, 0xe6, 0x48, 0x56, 0x9a, 0x6a, 0x6b,
-	0xff, 0x77, 0x09, 0x9e, 0x65, 0x03, 0x65, 0x2c, 0x59, 0x9c, 0x96, 0x62, 0xf4, 0x25, 0x6c, 0x96,
-	0x7b, 0x6b, 0x2f, 0xc9, 0x32, 0x4c, 0xf2, 0x1d, 0x6d, 0x97, 0xf0, 0x98, 0xa3, 0x68, 0x07, 0x6a,
-	0x7e, 0xe8, 0xf2, 0x1d, 0xae, 0x08, 0xff, 0x53, 0x3f, 0x74, 0x2f, 0x1d, 0xf4, 0x2d, 0x34, 0xca,
-	0x69, 0x14, 0xeb, 0xd8, 0xec, 0xef, 0xfe, 0xfd, 0x24, 0xe3, 0x3b, 0xe2, 0xfe, 0x1f, 0x12, 0x6c,
-	0x64, 0xe8, 0x75, 0xe8, 0xf2, 0x1b, 0xf9, 0xf4, 0x3a, 0xfe, 0x07, 0x0d, 0x71, 0xeb, 0x7c, 0xb5,
-	0x44, 0x29, 0x2d, 0xac, 0x70, 0x80, 0x6f, 0x1e, 0x77, 0x66, 0x2f, 0x14, 0xef, 0x7d, 0x56, 0x4d,
-	0x35, 0x7b, 0x11, 0x98, 0xde, 0x7b, 0x7a, 0xbf, 0x54, 0xf9, 0x13, 0x4b, 0x5d, 0xeb, 0xfb, 0xe9,
-	0x7a, 0xdf, 0x9f, 0xc3, 0x86, 0xc8, 0x14, 0xd3, 0xb7, 0x5e, 0xc2, 0x87, 0xaf, 0x26, 0xbc, 0x2d,
-	0x0e, 0xe2, 0x1c, 0xdb, 0xff, 0xb3, 0x6c, 0xf3, 0x86, 0x44, 0xff, 0x61, 0x9b, 0xff, 0xba, 0x93,
-	0x80, 0x44, 0x6b, 0x9d, 0x04, 0x24, 0xba, 0x74, 0xf8, 0x9b, 0x83, 0xc3, 0x0f, 0x1a, 0x69, 0x06,
-	0x24, 0x2a, 0xfa, 0x40, 0x2f, 0x41, 0x09, 0x28, 0x23, 0x0e, 0x61, 0x44, 0xab, 0x7f, 0x60, 0xb1,
-	0x4b, 0xd6, 0x48, 0x56, 0xaa, 0xaa, 0x7c, 0xf8, 0xab, 0x04, 0xad, 0xf5, 0x97, 0x2b, 0xda, 0x83,
-	0x9d, 0x1f, 0xc6, 0x57, 0xe3, 0xc9, 0x4f, 0x63, 0x7b, 0xa8, 0x9b, 0x43, 0xdb, 0xb4, 0xb0, 0x6e,
-	0x19, 0x17, 0xaf, 0xd5, 0x27, 0x08, 0x41, 0x1b, 0x9f, 0x9f, 0x9d, 0x7c, 0x77, 0xd2, 0xb7, 0xcd,
-	0xa1, 0xde, 0x3f, 0x3e, 0x51, 0x25, 0xb4, 0x0d, 0x9b, 0x96, 0x61, 0x5a, 0xf6, 0x8d, 0x3e, 0x15,
-	0x7c, 0x03, 0xab, 0x15, 0x1e, 0x63, 0x72, 0x3a, 0x32, 0xce, 0x2c, 0xfb, 0x01, 0xbf, 0x8a, 0x76,
-	0x60, 0xeb, 0x6c, 0x32, 0xbe, 0xbc, 0x32, 0x39, 0x74, 0xfc, 0x75, 0xdf, 0xe6, 0xb0, 0x7c, 0xf8,
-	0x0b, 0x34, 0xca, 0x4f, 0x09, 0xda, 0x05, 0x54, 0x94, 0x60, 0x61, 0xc3, 0xb0, 0x4d, 0x4b, 0xb7,
-	0x0c, 0xf5, 0x09, 0x02, 0xa8, 0xe9, 0x67, 0xd6, 0xe5, 0x8f, 0x86, 0x2a, 0xf1, 0xf3, 0x39, 0x9e,
-	0xdc, 0x1a, 0x63, 0xb5, 0x82, 0x5e, 0xc0, 0xf3, 0x81, 0x31, 0xc5, 0xc6, 0x99, 0x6e, 0x19, 0x03,
-	0xdb, 0x9c, 0x9c, 0x5b, 0xf6, 0xc0, 0xb8, 0x36, 0x2c, 0x63, 0xa0, 0x56, 0x3b, 0x15, 0x45, 0x7a,
-	0x40, 0x18, 0xea, 0x78, 0x50, 0x12, 0x64, 0x4e, 0x38, 0xbc, 0x00, 0xa5, 0xf8, 0x2c, 0xf1, 0x0a,
-	0xef, 0x65, 0xb7, 0x5e, 0x4f, 0x79, 0xf2, 0x3a, 0x54, 0xaf, 0x27, 0x17, 0xaa, 0xc4, 0x0f, 0x37,
-	0xfa, 0x54, 0xad, 0xf0, 0xc7, 0x31, 0xc5, 0xc6, 0x04, 0x0f, 0x0c, 0x6c, 0x0c, 0x6c, 0xee, 0xac,
-	0x9e, 0x0e, 0x61, 0x6f, 0x1e, 0x06, 0xc5, 0x93, 0xbf, 0xff, 0x4b, 0xe0, 0x74, 0xc3, 0xca, 0xed,
-	0x29, 0x37, 0xa7, 0xd2, 0x6d, 0xc7, 0xf5, 0xd8, 0x62, 0x35, 0xeb, 0xcd, 0xc3, 0xe0, 0x28, 0xff,
-	0x54, 0x17, 0x92, 0x59, 0x4d, 0x68, 0xbe, 0xf9, 0x2b, 0x00, 0x00, 0xff, 0xff, 0x95, 0xc2, 0x95,
-	0xc2, 0x4f, 0x08, 0x00, 0x00,
+	// 1775 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x58, 0xcf, 0x72, 0xdb, 0xba,
+	0xf5, 0x0e, 0x25, 0x59, 0x96, 0x8f, 0x24, 0x9b, 0x86, 0xff, 0xd1, 0xca, 0xef, 0x97, 0xab, 0xaa,
+	0x9d, 0xa9, 0xeb, 0xe9, 0xc8, 0xa9, 0x7b, 0x93, 0xd6, 0xb9, 0x8b, 0x3b, 0x8a, 0x45, 0xc7, 0xb2,
+	0x65, 0x49, 0x03, 0xb2, 0xc9, 0x24, 0x1b, 0x16, 0x16, 0x21, 0x1a, 0x73, 0x29, 0x92, 0x43, 0x42,
+	0x8e, 0x75, 0x67, 0xba, 0xe9, 0xdc, 0x65, 0x1f, 0xab, 0xab, 0xee, 0xba, 0xe9, 0x0b, 0xf4, 0x45,
+	0x3a, 0x00, 0x49, 0x49, 0x94, 0x9d, 0xd8, 0xed, 0xdc, 0x4d, 0x82, 0x73, 0xce, 0xf7, 0x1d, 0x00,
+	0x47, 0x1f, 0x0e, 0x40, 0xc3, 0x3a, 0x0f, 0x99, 0xeb, 0x32, 0xe2, 0x35, 0x83, 0xd0, 0xe7, 0x3e,
+	0x2a, 0xa5, 0x76, 0xad, 0x36, 0x0c, 0xa7, 0x01, 0xf7, 0x8f, 0x7e, 0xa0, 0xd3, 0x28, 0xb8, 0x4e,
+	0xfe, 0x8b, 0x51, 0x35, 0x2d, 0x89, 0x45, 0xcc, 0x09, 0xae, 0xe3, 0x7f, 0x93, 0xc8, 0xbe, 0xe3,
+	0xfb, 0x8e, 0x4b, 0x8f, 0xa4, 0x75, 0x3d, 0x19, 0x1d, 0x11, 0x6f, 0x9a, 0x84, 0x5e, 0x2c, 0x87,
+	0xec, 0x49, 0x48, 0x38, 0xf3, 0x93, 0xa9, 0x6b, 0xdf, 0x2c, 0xc7, 0x39, 0x1b, 0xd3, 0x88, 0x93,
+	0x71, 0x10, 0x03, 0x1a, 0xff, 0xaa, 0x40, 0xc1, 0x0c, 0x29, 0x45, 0x7b, 0xb0, 0xca, 0x43, 0x4a,
+	0x2d, 0x66, 0x6b, 0x4a, 0x5d, 0x39, 0xc8, 0xe3, 0xa2, 0x30, 0x3b, 0x36, 0x3a, 0x06, 0x90, 0x81,
+	0x88, 0x13, 0x4e, 0xb5, 0x5c, 0x5d, 0x39, 0x58, 0x3f, 0xde, 0x6a, 0xce, 0xb6, 0x28, 0xc8, 0x86,
+	0x08, 0xe1, 0x35, 0x9e, 0x0e, 0xd1, 0x11, 0x48, 0xc3, 0xe2, 0xd3, 0x80, 0x6a, 0x79, 0x49, 0x41,
+	0x59, 0x8a, 0x39, 0x0d, 0x28, 0x2e, 0xf1, 0x64, 0x84, 0xbe, 0x83, 0xea, 0x0d, 0x89, 0x6e, 0xac,
+	0x88, 0x87, 0x84, 0x53, 0x67, 0xaa, 0x15, 0x24, 0x69, 0x77, 0x4e, 0x3a, 0x27, 0xd1, 0x8d, 0x91,
+	0x44, 0x71, 0xe5, 0x66, 0xc1, 0x42, 0x97, 0xb0, 0x2e, 0xc9, 0xc4, 0x75, 0xfc, 0x90, 0xf1, 0x9b,
+	0xb1, 0xb6, 0x22, 0xd9, 0xbf, 0x6a, 0xc6, 0x55, 0x6c, 0x33, 0x87, 0x71, 0xe2, 0xba, 0x53, 0x83,
+	0x39, 0x1e, 0xb5, 0x65, 0xaa, 0x56, 0x8a, 0xc5, 0xd5, 0x9b, 0x45, 0x13, 0x7d, 0x82, 0xad, 0x88,
+	0x39, 0x1e, 0xe1, 0x93, 0x90, 0x2e, 0x64, 0x2c, 0xca, 0x8c, 0xbf, 0xf9, 0x42, 0x46, 0x23, 0x65,
+	0xcc, 0xd3, 0xa2, 0xe8, 0x9e, 0x0f, 0xfd, 0x02, 0x2a, 0x36, 0x8b, 0x02, 0x97, 0x4c, 0x2d, 0x8f,
+	0x8c, 0xa9, 0x56, 0xaa, 0x2b, 0x07, 0x6b, 0xb8, 0x9c, 0xf8, 0x7a, 0x64, 0x4c, 0x51, 0x1d, 0xca,
+	0x36, 0x8d, 0x86, 0x21, 0x0b, 0xc4, 0xaf, 0xa8, 0xad, 0x25, 0x88, 0xb9, 0x0b, 0xbd, 0x82, 0x72,
+	0x10, 0xb2, 0x5b, 0xc2, 0xa9, 0xf5, 0x03, 0x9d, 0x6a, 0x95, 0xba, 0x72, 0x50, 0x3e, 0xde, 0x6e,
+	0xc6, 0x3f, 0x74, 0x33, 0xfd, 0xa1, 0x9b, 0x2d, 0x6f, 0x8a, 0x21, 0x01, 0x5e, 0xd2, 0x29, 0xfa,
+	0x1e, 0xd4, 0x88, 0xfb, 0x21, 0x71, 0xa8, 0x15, 0x51, 0xce, 0x99, 0xe7, 0x44, 0x5a, 0xf5, 0x2b,
+	0xdc, 0x8d, 0x04, 0x6d, 0x24, 0x60, 0xf4, 0x12, 0x20, 0x98, 0x5c, 0xbb, 0x6c, 0x28, 0xa7, 0x5d,
+	0x97, 0xd4, 0xcd, 0x66, 0x22, 0xe1, 0x81, 0x8c, 0x5c, 0xd2, 0x29, 0x5e, 0x0b, 0xd2, 0x21, 0xd2,
+	0x61, 0x73, 0x4c, 0xee, 0xac, 0xd0, 0xf7, 0xb9, 0x95, 0xea, 0x52, 0xdb, 0x90, 0xc4, 0xfd, 0x7b,
+	0x73, 0xb6, 0x13, 0x00, 0xde, 0x18, 0x93, 0x3b, 0xec, 0xfb, 0x3c, 0x75, 0xa0, 0xef, 0xa0, 0x3c,
+	0x0c, 0xa9, 0xd8, 0xaf, 0x10, 0xaf, 0xa6, 0xca, 0x04, 0xb5, 0x7b, 0x09, 0xcc, 0x54, 0xd9, 0x18,
+	0x62, 0xb8, 0x70, 0x08, 0xf2, 0x24, 0xb0, 0x67, 0xe4, 0xcd, 0xc7, 0xc9, 0x31, 0x5c, 0x92, 0x35,
+	0x58, 0xb5, 0xa9, 0x4b, 0x39, 0xb5, 0xb5, 0xad, 0xba, 0x72, 0x50, 0xc2, 0xa9, 0x29, 0xd2, 0xc6,
+	0xc3, 0x38, 0xed, 0xf6, 0xe3, 0x69, 0x63, 0xb8, 0x4c, 0xfb, 0x09, 0x34, 0x59, 0x93, 0xd9, 0x59,
+	0xb4, 0x82, 0x90, 0x0e, 0x59, 0x24, 0xca, 0xb3, 0x23, 0x75, 0x56, 0x9f, 0xeb, 0x5e, 0x94, 0x62,
+	0x96, 0x66, 0x90, 0xe2, 0xf0, 0x6e, 0xf8, 0xa0, 0x1f, 0x1d, 0x43, 0xd1, 0x25, 0xd7, 0xd4, 0x8d,
+	0xb4, 0xdd, 0x7a, 0x5e, 0xae, 0x29, 0x73, 0xec, 0x9a, 0x5d, 0x19, 0xd4, 0x3d, 0x1e, 0x4e, 0x71,
+	0x82, 0x44, 0x6f, 0xa0, 0xe2, 0x52, 0x32, 0xb2, 0x98, 0x67, 0xd3, 0x3b, 0x1a, 0x69, 0x7b, 0x92,
+	0xb9, 0x37, 0x67, 0x76, 0x29, 0x19, 0x75, 0x44, 0xd0, 0x08, 0xe8, 0x10, 0x97, 0xdd, 0xd4, 0xa4,
+	0x11, 0x3a, 0x01, 0x69, 0x5a, 0x23, 0xe6, 0x72, 0x1a, 0x6a, 0x9a, 0x2c, 0x84, 0x96, 0xa5, 0x9e,
+	0xc9, 0x98, 0xe4, 0x82, 0x3b, 0xb3, 0x45, 0x0d, 0xc7, 0x84, 0x79, 0x9c, 0x7a, 0xc4, 0x1b, 0x52,
+	0x6d, 0x3f, 0x11, 0xc6, 0x8c, 0x7a, 0x35, 0x0f, 0x5e, 0xf9, 0x36, 0xc5, 0x8b, 0x68, 0xd4, 0x85,
+	0x3d, 0x62, 0xdb, 0x4c, 0x08, 0x84, 0xb8, 0x96, 0x38, 0x6b, 0xcc, 0x73, 0x84, 0x32, 0x23, 0xad,
+	0x26, 0x97, 0xbf, 0x3d, 0x4f, 0x64, 0xc4, 0x51, 0xa1, 0xce, 0x9d, 0x39, 0x69, 0xee, 0x8d, 0xd0,
+	0x6f, 0xa1, 0x28, 0xda, 0xdb, 0x24, 0xd2, 0x9e, 0xd7, 0x95, 0x2c, 0x39, 0xed, 0x6f, 0x93, 0x08,
+	0x27, 0x18, 0xd4, 0x02, 0xa1, 0x51, 0x6b, 0x4c, 0x43, 0x87, 0x5a, 0x36, 0x75, 0xc9, 0x54, 0xfb,
+	0xbf, 0xc7, 0x54, 0x5d, 0x1d, 0x93, 0xbb, 0x2b, 0x41, 0x68, 0x0b, 0xbc, 0x48, 0x71, 0x4b, 0x5c,
+	0x66, 0x33, 0x3e, 0xb5, 0x3e, 0x33, 0xcf, 0xf6, 0x3f, 0x6b, 0xff, 0xbf, 0x5c, 0xba, 0xf7, 0x09,
+	0xe0, 0x83, 0x8c, 0xe3, 0xf5, 0xdb, 0x8c, 0x8d, 0x2e, 0x00, 0x7d, 0x0e, 0x19, 0xa7, 0xd6, 0x2d,
+	0x0d, 0xd9, 0x88, 0x0d, 0xe3, 0xe3, 0xf5, 0x42, 0xea, 0xe7, 0xf9, 0x3c, 0xcb, 0x07, 0x81, 0x79,
+	0xbf, 0x00, 0xc1, 0x9b, 0x9f, 0x97, 0x5d, 0xb5, 0x13, 0x28, 0x2f, 0x08, 0x03, 0xa9, 0x90, 0x17,
+	0x67, 0x5c, 0x91, 0xcd, 0x47, 0x0c, 0xd1, 0x36, 0xac, 0xdc, 0x12, 0x77, 0x12, 0xf7, 0xff, 0x35,
+	0x1c, 0x1b, 0x6f, 0x72, 0x7f, 0x54, 0x2e, 0x0a, 0x25, 0xa4, 0x6e, 0x5d, 0x14, 0x4a, 0xab, 0x6a,
+	0xe9, 0xa2, 0x50, 0x02, 0xb5, 0x7c, 0x51, 0x28, 0x95, 0xd5, 0x4a, 0xe3, 0xef, 0x0a, 0xc0, 0xbc,
+	0x76, 0xe8, 0x7b, 0xd8, 0x70, 0x09, 0xa7, 0x11, 0xb7, 0x5c, 0xdf, 0x91, 0x2d, 0x41, 0xa6, 0xcf,
+	0xc8, 0x2c, 0x6e, 0xa6, 0x5d, 0xdf, 0x11, 0x9a, 0xc7, 0xd5, 0x18, 0x9f, 0x98, 0x0b, 0x09, 0xc6,
+	0x24, 0x88, 0x13, 0xe4, 0x1e, 0x4e, 0x70, 0x45, 0x82, 0xc5, 0x04, 0x89, 0x89, 0xbe, 0x85, 0xdd,
+	0x79, 0xff, 0xb2, 0x46, 0xcc, 0x73, 0x68, 0x18, 0x84, 0xcc, 0xe3, 0xf2, 0x82, 0xaa, 0xe0, 0xed,
+	0x59, 0xe3, 0x3a, 0x9b, 0xc7, 0x1a, 0xff, 0x54, 0x00, 0xe6, 0x4a, 0xf9, 0xd2, 0xed, 0xa0, 0xfc,
+	0x1c, 0xb7, 0xc3, 0x52, 0x63, 0xcf, 0x3d, 0xb1, 0xb1, 0x67, 0xfb, 0x72, 0xfe, 0xf1, 0xbe, 0xdc,
+	0xa0, 0xb0, 0xb1, 0x74, 0xb6, 0xd0, 0x1b, 0x28, 0x87, 0x94, 0x87, 0x53, 0x8b, 0x8c, 0xc4, 0x31,
+	0x56, 0x1e, 0x93, 0x33, 0x48, 0x74, 0x4b, 0x80, 0xd1, 0x2e, 0x14, 0x43, 0x4a, 0x22, 0xdf, 0x4b,
+	0xc4, 0x91, 0x58, 0x8d, 0x9f, 0x14, 0x58, 0xcf, 0x6a, 0x18, 0x9d, 0x00, 0x78, 0x3e, 0xb7, 0xae,
+	0xe9, 0xc8, 0x0f, 0xa9, 0xa6, 0x3c, 0xda, 0x35, 0xd7, 0x3c, 0x9f, 0xbf, 0x95, 0x60, 0xf4, 0x07,
+	0x10, 0x46, 0xb2, 0xbe, 0xdc, 0xa3, 0xcc, 0x92, 0xe7, 0x73, 0xb9, 0xbc, 0xc6, 0x09, 0x54, 0x33,
+	0xfd, 0x0b, 0x21, 0x28, 0xc8, 0xdb, 0x37, 0x96, 0xb7, 0x1c, 0x0b, 0x7d, 0x8f, 0x18, 0x75, 0xed,
+	0x54, 0xdf, 0xd2, 0x68, 0x30, 0x58, 0xcf, 0xf6, 0x2f, 0xf4, 0x6b, 0xd8, 0xa0, 0x77, 0x01, 0x1d,
+	0x72, 0x6a, 0x5b, 0x2e, 0x25, 0xb7, 0x34, 0x4a, 0x5e, 0x4b, 0xeb, 0xa9, 0xbb, 0x2b, 0xbd, 0xa8,
+	0x09, 0x5b, 0x23, 0xe2, 0x46, 0xd4, 0x0a, 0xfc, 0x88, 0x71, 0x76, 0x4b, 0xad, 0x30, 0x7d, 0x3e,
+	0x29, 0x78, 0x53, 0x86, 0x06, 0x49, 0x04, 0x13, 0x4e, 0x1b, 0x7f, 0x53, 0x60, 0x3b, 0x96, 0x8b,
+	0x3c, 0x82, 0xb3, 0x8d, 0x88, 0x19, 0xe7, 0xf7, 0x84, 0x47, 0x3c, 0x7f, 0x36, 0xe3, 0xcc, 0xdd,
+	0x13, 0x5e, 0xb4, 0x03, 0x45, 0x71, 0xb4, 0x58, 0xbc, 0x87, 0x3c, 0x5e, 0x71, 0x7d, 0xa7, 0x63,
+	0xa3, 0x6f, 0x61, 0x6d, 0xa6, 0xb5, 0x44, 0x1d, 0xbb, 0x0f, 0xeb, 0x14, 0xcf, 0x81, 0x8d, 0x7f,
+	0xe7, 0xa0, 0x9a, 0x39, 0x8e, 0x4f, 0x5f, 0xc7, 0x73, 0x58, 0x93, 0xb7, 0x9b, 0x78, 0x56, 0xc9,
+	0xa5, 0x54, 0x70, 0x49, 0x38, 0xc4, 0xab, 0x4b, 0x04, 0xe3, 0xc7, 0x24, 0xfb, 0x31, 0x5e, 0x4d,
+	0x3e, 0x7e, 0x04, 0x1a, 0xec, 0x47, 0x9a, 0x5d, 0x6a, 0xe1, 0x89, 0x4b, 0x5d, 0xd8, 0xf7, 0xca,
+	0xe2, 0xbe, 0x7f, 0x09, 0x55, 0x39, 0x53, 0x48, 0x6f, 0xe3, 0x9b, 0xb5, 0x28, 0xa3, 0x15, 0xe1,
+	0xc4, 0x89, 0x0f, 0x5d, 0xc2, 0xce, 0xd2, 0x2d, 0x22, 0x73, 0x46, 0xda, 0x6a, 0x3d, 0xff, 0x95,
+	0xd9, 0xb7, 0xb3, 0xb7, 0x48, 0xcc, 0x41, 0x2f, 0xa1, 0x34, 0xa6, 0x9c, 0xd8, 0x84, 0x13, 0xad,
+	0xf4, 0x95, 0xc3, 0x3b, 0x43, 0x35, 0xfe, 0x31, 0xab, 0x72, 0xda, 0xa4, 0x7e, 0x9e, 0x2a, 0xff,
+	0xcf, 0x85, 0x14, 0xad, 0x75, 0x5e, 0xc8, 0x31, 0x09, 0x3a, 0xb6, 0x78, 0xb4, 0x0a, 0xf7, 0x52,
+	0x1d, 0xcb, 0x63, 0x12, 0xcc, 0xca, 0xb8, 0xb8, 0xf3, 0xd5, 0xa7, 0xec, 0xfc, 0xcb, 0x85, 0x2f,
+	0xfd, 0xf7, 0x85, 0xbf, 0x28, 0x94, 0xf2, 0x6a, 0xa1, 0xf1, 0x67, 0xa8, 0x1a, 0xfe, 0x24, 0x1c,
+	0xd2, 0x54, 0xb1, 0x73, 0x61, 0x28, 0x8b, 0xc2, 0xc8, 0x48, 0x30, 0xb7, 0x24, 0xc1, 0x4c, 0x59,
+	0xf3, 0xd9, 0xb2, 0x36, 0x0c, 0xd8, 0x48, 0x72, 0x5f, 0xa5, 0xfb, 0xd8, 0x85, 0xa2, 0x1f, 0x32,
+	0x87, 0x79, 0x49, 0x37, 0x49, 0x2c, 0x74, 0x00, 0x2b, 0xd1, 0x0d, 0x09, 0xed, 0xa4, 0x53, 0x2d,
+	0x7c, 0xfc, 0x74, 0x7d, 0xc7, 0x10, 0x11, 0x1c, 0x03, 0x1a, 0x7f, 0x55, 0xa0, 0x94, 0xfa, 0x1e,
+	0x6c, 0x4d, 0x2f, 0x61, 0x25, 0xe2, 0x24, 0xe4, 0x4f, 0x68, 0x7a, 0x31, 0x50, 0x30, 0x5c, 0x36,
+	0x66, 0x5c, 0xcb, 0x3f, 0xce, 0x90, 0xc0, 0xc3, 0x9f, 0x14, 0xa8, 0x2c, 0x7e, 0x60, 0xa1, 0x7d,
+	0xd8, 0xf9, 0x53, 0xef, 0xb2, 0xd7, 0xff, 0xd0, 0xb3, 0xce, 0x5b, 0xc6, 0xb9, 0x65, 0x98, 0xb8,
+	0x65, 0xea, 0xef, 0x3e, 0xaa, 0xcf, 0x10, 0x82, 0x75, 0x7c, 0x76, 0xfa, 0xfa, 0xe4, 0xf5, 0xb1,
+	0x65, 0x9c, 0xb7, 0x8e, 0x5f, 0xbd, 0x56, 0x15, 0xb4, 0x05, 0x1b, 0xa6, 0x6e, 0x98, 0xd6, 0x55,
+	0x6b, 0x20, 0xf1, 0x3a, 0x56, 0x73, 0x22, 0x47, 0xff, 0xed, 0x85, 0x7e, 0x6a, 0x5a, 0x4b, 0xf8,
+	0x3c, 0xda, 0x81, 0xcd, 0xd3, 0x7e, 0xaf, 0x73, 0x69, 0x08, 0xd7, 0xab, 0xdf, 0x1d, 0x5b, 0xc2,
+	0x5d, 0x38, 0xfc, 0x0b, 0xac, 0xcd, 0x3e, 0x27, 0xd1, 0x2e, 0xa0, 0x74, 0x09, 0x26, 0xd6, 0x75,
+	0xcb, 0x30, 0x5b, 0xa6, 0xae, 0x3e, 0x43, 0x00, 0xc5, 0xd6, 0xa9, 0xd9, 0x79, 0xaf, 0xab, 0x8a,
+	0x18, 0x9f, 0xe1, 0xfe, 0x27, 0xbd, 0xa7, 0xe6, 0xd0, 0x37, 0xb0, 0xd7, 0xd6, 0x07, 0x58, 0x3f,
+	0x6d, 0x99, 0x7a, 0xdb, 0x32, 0xfa, 0x67, 0xa6, 0xd5, 0xd6, 0xbb, 0xba, 0xa9, 0xb7, 0xd5, 0x7c,
+	0x2d, 0x57, 0x52, 0x96, 0x00, 0xe7, 0x2d, 0xdc, 0x9e, 0x01, 0x0a, 0x02, 0x70, 0xf8, 0x0e, 0x4a,
+	0xe9, 0xa7, 0xa9, 0x58, 0x61, 0x66, 0x76, 0xf3, 0xe3, 0x40, 0x4c, 0xbe, 0x0a, 0xf9, 0x6e, 0xff,
+	0x9d, 0xaa, 0x88, 0xc1, 0x55, 0x6b, 0xa0, 0xe6, 0x44, 0x39, 0x06, 0x58, 0xef, 0xe3, 0xb6, 0x8e,
+	0xf5, 0xb6, 0x25, 0x82, 0xf9, 0xc3, 0x21, 0xec, 0x3e, 0xfc, 0x6c, 0x47, 0x1a, 0x6c, 0xf7, 0x5a,
+	0xbd, 0xbe, 0xa1, 0x9f, 0xf6, 0x7b, 0x6d, 0x4b, 0x2c, 0xa6, 0x63, 0x74, 0xfa, 0x3d, 0xf5, 0x99,
+	0xa8, 0xd6, 0x55, 0xa7, 0xdb, 0xed, 0xdc, 0x0b, 0x29, 0x68, 0x1b, 0xd4, 0x7b, 0xde, 0xdc, 0xe1,
+	0x10, 0x36, 0xef, 0xbd, 0xed, 0xd0, 0x0b, 0xa8, 0xa5, 0xcb, 0xfe, 0x80, 0x3b, 0xa6, 0x6e, 0xbd,
+	0xd7, 0x71, 0xe7, 0xac, 0x73, 0xda, 0x32, 0xe3, 0x59, 0x36, 0xa1, 0x2a, 0x3d, 0x1f, 0xe3, 0xb0,
+	0xa1, 0x2a, 0xe8, 0x39, 0xec, 0x19, 0x97, 0x9d, 0xc1, 0x43, 0xf8, 0xdc, 0xdb, 0x73, 0xd8, 0x1f,
+	0xfa, 0xe3, 0x54, 0x40, 0xd9, 0xbf, 0x6b, 0xbc, 0xad, 0x9a, 0x89, 0x3d, 0x10, 0xe6, 0x40, 0xf9,
+	0x54, 0x73, 0x18, 0xbf, 0x99, 0x5c, 0x37, 0x87, 0xfe, 0xf8, 0x28, 0xf9, 0xc3, 0x43, 0x4a, 0xb9,
+	0x2e, 0x4a, 0xce, 0xef, 0xff, 0x33, 0x00, 0x7c, 0xd6, 0x74, 0xfa, 0x1d, 0x11, 0x00, 0x00,
 }
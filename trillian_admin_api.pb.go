@@ -7,8 +7,12 @@ import proto "github.com/golang/protobuf/proto"
 import fmt "fmt"
 import math "math"
 import keyspb "github.com/google/trillian/crypto/keyspb"
+import sigpb "github.com/google/trillian/crypto/sigpb"
 import _ "google.golang.org/genproto/googleapis/api/annotations"
+import google_protobuf3 "github.com/golang/protobuf/ptypes/duration"
 import google_protobuf4 "google.golang.org/genproto/protobuf/field_mask"
+import google_protobuf1 "github.com/golang/protobuf/ptypes/timestamp"
+import google_rpc "google.golang.org/genproto/googleapis/rpc/status"
 
 import (
 	context "golang.org/x/net/context"
@@ -20,153 +24,937 @@ var _ = proto.Marshal
 var _ = fmt.Errorf
 var _ = math.Inf
 
+// State of an integrity check of a log.
+type IntegrityCheckState int32
+
+const (
+	// State cannot be determined. Included to enable detection of mismatched
+	// proto versions being used. Represents an invalid value.
+	IntegrityCheckState_UNKNOWN_INTEGRITY_CHECK_STATE IntegrityCheckState = 0
+	// The check is in progress.
+	IntegrityCheckState_INTEGRITY_CHECK_RUNNING IntegrityCheckState = 1
+	// The stored leaves, Merkle nodes and root are consistent with each other.
+	IntegrityCheckState_INTEGRITY_CHECK_PASSED IntegrityCheckState = 2
+	// Storage holds data inconsistent with the root, see failure.
+	IntegrityCheckState_INTEGRITY_CHECK_FAILED IntegrityCheckState = 3
+	// The check couldn't complete, e.g. because storage was unavailable, see
+	// failure. It says nothing about the integrity of the log.
+	IntegrityCheckState_INTEGRITY_CHECK_ABORTED IntegrityCheckState = 4
+)
+
+var IntegrityCheckState_name = map[int32]string{
+	0: "UNKNOWN_INTEGRITY_CHECK_STATE",
+	1: "INTEGRITY_CHECK_RUNNING",
+	2: "INTEGRITY_CHECK_PASSED",
+	3: "INTEGRITY_CHECK_FAILED",
+	4: "INTEGRITY_CHECK_ABORTED",
+}
+var IntegrityCheckState_value = map[string]int32{
+	"UNKNOWN_INTEGRITY_CHECK_STATE": 0,
+	"INTEGRITY_CHECK_RUNNING":       1,
+	"INTEGRITY_CHECK_PASSED":        2,
+	"INTEGRITY_CHECK_FAILED":        3,
+	"INTEGRITY_CHECK_ABORTED":       4,
+}
+
+func (x IntegrityCheckState) String() string {
+	return proto.EnumName(IntegrityCheckState_name, int32(x))
+}
+func (IntegrityCheckState) EnumDescriptor() ([]byte, []int) { return fileDescriptor2, []int{0} }
+
 // ListTrees request.
 // No filters or pagination options are provided.
 type ListTreesRequest struct {
 	// If true, deleted trees are included in the response.
 	ShowDeleted bool `protobuf:"varint,1,opt,name=show_deleted,json=showDeleted" json:"show_deleted,omitempty"`
+	// If set, only trees whose labels match the selector are returned.
+	// The selector is a comma-separated list of requirements, all of which must
+	// be met: "key=value", "key!=value", "key" (the label is set) or "!key" (the
+	// label isn't set).
+	LabelSelector string `protobuf:"bytes,2,opt,name=label_selector,json=labelSelector" json:"label_selector,omitempty"`
+}
+
+func (m *ListTreesRequest) Reset()                    { *m = ListTreesRequest{} }
+func (m *ListTreesRequest) String() string            { return proto.CompactTextString(m) }
+func (*ListTreesRequest) ProtoMessage()               {}
+func (*ListTreesRequest) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{0} }
+
+func (m *ListTreesRequest) GetShowDeleted() bool {
+	if m != nil {
+		return m.ShowDeleted
+	}
+	return false
+}
+
+func (m *ListTreesRequest) GetLabelSelector() string {
+	if m != nil {
+		return m.LabelSelector
+	}
+	return ""
+}
+
+// ListTrees response.
+// No pagination is provided, all trees the requester has access to are
+// returned.
+type ListTreesResponse struct {
+	// Trees matching the list request filters.
+	Tree []*Tree `protobuf:"bytes,1,rep,name=tree" json:"tree,omitempty"`
+}
+
+func (m *ListTreesResponse) Reset()                    { *m = ListTreesResponse{} }
+func (m *ListTreesResponse) String() string            { return proto.CompactTextString(m) }
+func (*ListTreesResponse) ProtoMessage()               {}
+func (*ListTreesResponse) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{1} }
+
+func (m *ListTreesResponse) GetTree() []*Tree {
+	if m != nil {
+		return m.Tree
+	}
+	return nil
+}
+
+// GetTree request.
+type GetTreeRequest struct {
+	// ID of the tree to retrieve.
+	TreeId int64 `protobuf:"varint,1,opt,name=tree_id,json=treeId" json:"tree_id,omitempty"`
+	// If true, the status of the returned tree is set, which requires reading
+	// its latest root from storage. Only available for the tree types whose
+	// storage the server has.
+	IncludeStatus bool `protobuf:"varint,2,opt,name=include_status,json=includeStatus" json:"include_status,omitempty"`
+}
+
+func (m *GetTreeRequest) Reset()                    { *m = GetTreeRequest{} }
+func (m *GetTreeRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetTreeRequest) ProtoMessage()               {}
+func (*GetTreeRequest) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{2} }
+
+func (m *GetTreeRequest) GetTreeId() int64 {
+	if m != nil {
+		return m.TreeId
+	}
+	return 0
+}
+
+func (m *GetTreeRequest) GetIncludeStatus() bool {
+	if m != nil {
+		return m.IncludeStatus
+	}
+	return false
+}
+
+// CreateTree request.
+type CreateTreeRequest struct {
+	// Tree to be created. See Tree and CreateTree for more details.
+	Tree *Tree `protobuf:"bytes,1,opt,name=tree" json:"tree,omitempty"`
+	// Describes how the tree's private key should be generated.
+	// Only needs to be set if tree.private_key is not set.
+	KeySpec *keyspb.Specification `protobuf:"bytes,2,opt,name=key_spec,json=keySpec" json:"key_spec,omitempty"`
+	// Signature of the key challenge of the tree (see trees.KeyChallenge) by its
+	// private key. Required if, and only if, tree.private_key is a
+	// keyspb.ExternalKey, which the admin server can't read; tree.public_key
+	// must then be set too.
+	KeyChallengeSignature *sigpb.DigitallySigned `protobuf:"bytes,3,opt,name=key_challenge_signature,json=keyChallengeSignature" json:"key_challenge_signature,omitempty"`
+	// Identifier of the tree in an external system, e.g. the resource of an
+	// infrastructure-as-code tool which declares it, which makes creating the
+	// tree idempotent so that it can be safely retried. It's recorded in the
+	// "trillian.external-id" label of the tree. If a tree with the same
+	// identifier already exists, it's returned if it matches the request, and
+	// an ALREADY_EXISTS error is returned otherwise; no new tree is created. A
+	// soft-deleted tree with the identifier must be undeleted first.
+	ExternalId string `protobuf:"bytes,4,opt,name=external_id,json=externalId" json:"external_id,omitempty"`
+}
+
+func (m *CreateTreeRequest) Reset()                    { *m = CreateTreeRequest{} }
+func (m *CreateTreeRequest) String() string            { return proto.CompactTextString(m) }
+func (*CreateTreeRequest) ProtoMessage()               {}
+func (*CreateTreeRequest) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{3} }
+
+func (m *CreateTreeRequest) GetTree() *Tree {
+	if m != nil {
+		return m.Tree
+	}
+	return nil
+}
+
+func (m *CreateTreeRequest) GetKeySpec() *keyspb.Specification {
+	if m != nil {
+		return m.KeySpec
+	}
+	return nil
+}
+
+func (m *CreateTreeRequest) GetKeyChallengeSignature() *sigpb.DigitallySigned {
+	if m != nil {
+		return m.KeyChallengeSignature
+	}
+	return nil
+}
+
+func (m *CreateTreeRequest) GetExternalId() string {
+	if m != nil {
+		return m.ExternalId
+	}
+	return ""
+}
+
+// UpdateTree request.
+type UpdateTreeRequest struct {
+	// Tree to be updated.
+	Tree *Tree `protobuf:"bytes,1,opt,name=tree" json:"tree,omitempty"`
+	// Fields modified by the update request.
+	// For example: "tree_state", "display_name", "description".
+	UpdateMask *google_protobuf4.FieldMask `protobuf:"bytes,2,opt,name=update_mask,json=updateMask" json:"update_mask,omitempty"`
+}
+
+func (m *UpdateTreeRequest) Reset()                    { *m = UpdateTreeRequest{} }
+func (m *UpdateTreeRequest) String() string            { return proto.CompactTextString(m) }
+func (*UpdateTreeRequest) ProtoMessage()               {}
+func (*UpdateTreeRequest) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{4} }
+
+func (m *UpdateTreeRequest) GetTree() *Tree {
+	if m != nil {
+		return m.Tree
+	}
+	return nil
+}
+
+func (m *UpdateTreeRequest) GetUpdateMask() *google_protobuf4.FieldMask {
+	if m != nil {
+		return m.UpdateMask
+	}
+	return nil
+}
+
+// DeleteTree request.
+type DeleteTreeRequest struct {
+	// ID of the tree to delete.
+	TreeId int64 `protobuf:"varint,1,opt,name=tree_id,json=treeId" json:"tree_id,omitempty"`
+}
+
+func (m *DeleteTreeRequest) Reset()                    { *m = DeleteTreeRequest{} }
+func (m *DeleteTreeRequest) String() string            { return proto.CompactTextString(m) }
+func (*DeleteTreeRequest) ProtoMessage()               {}
+func (*DeleteTreeRequest) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{5} }
+
+func (m *DeleteTreeRequest) GetTreeId() int64 {
+	if m != nil {
+		return m.TreeId
+	}
+	return 0
+}
+
+// UndeleteTree request.
+type UndeleteTreeRequest struct {
+	// ID of the tree to undelete.
+	TreeId int64 `protobuf:"varint,1,opt,name=tree_id,json=treeId" json:"tree_id,omitempty"`
+}
+
+func (m *UndeleteTreeRequest) Reset()                    { *m = UndeleteTreeRequest{} }
+func (m *UndeleteTreeRequest) String() string            { return proto.CompactTextString(m) }
+func (*UndeleteTreeRequest) ProtoMessage()               {}
+func (*UndeleteTreeRequest) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{6} }
+
+func (m *UndeleteTreeRequest) GetTreeId() int64 {
+	if m != nil {
+		return m.TreeId
+	}
+	return 0
+}
+
+// GetTreeStats request.
+type GetTreeStatsRequest struct {
+	// ID of the tree to get statistics for.
+	TreeId int64 `protobuf:"varint,1,opt,name=tree_id,json=treeId" json:"tree_id,omitempty"`
+}
+
+func (m *GetTreeStatsRequest) Reset()                    { *m = GetTreeStatsRequest{} }
+func (m *GetTreeStatsRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetTreeStatsRequest) ProtoMessage()               {}
+func (*GetTreeStatsRequest) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{7} }
+
+func (m *GetTreeStatsRequest) GetTreeId() int64 {
+	if m != nil {
+		return m.TreeId
+	}
+	return 0
+}
+
+// Statistics about the data stored for a tree, as reported by storage.
+type TreeStats struct {
+	// ID of the tree.
+	TreeId int64 `protobuf:"varint,1,opt,name=tree_id,json=treeId" json:"tree_id,omitempty"`
+	// Number of leaves stored. For logs, this is the number of sequenced leaves;
+	// for maps, the number of leaf values stored across all revisions.
+	LeafCount int64 `protobuf:"varint,2,opt,name=leaf_count,json=leafCount" json:"leaf_count,omitempty"`
+	// Total size in bytes of the stored leaf values and extra data.
+	LeafBytes int64 `protobuf:"varint,3,opt,name=leaf_bytes,json=leafBytes" json:"leaf_bytes,omitempty"`
+	// Number of stored subtrees, counting each revision of a subtree separately.
+	SubtreeCount int64 `protobuf:"varint,4,opt,name=subtree_count,json=subtreeCount" json:"subtree_count,omitempty"`
+	// Number of tree revisions stored, i.e. the number of signed roots.
+	RevisionCount int64 `protobuf:"varint,5,opt,name=revision_count,json=revisionCount" json:"revision_count,omitempty"`
+	// Average number of read-only and read-write storage transactions per
+	// second on the tree over the last minute, as seen by this server.
+	ReadQps  float64 `protobuf:"fixed64,6,opt,name=read_qps,json=readQps" json:"read_qps,omitempty"`
+	WriteQps float64 `protobuf:"fixed64,7,opt,name=write_qps,json=writeQps" json:"write_qps,omitempty"`
+}
+
+func (m *TreeStats) Reset()                    { *m = TreeStats{} }
+func (m *TreeStats) String() string            { return proto.CompactTextString(m) }
+func (*TreeStats) ProtoMessage()               {}
+func (*TreeStats) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{8} }
+
+func (m *TreeStats) GetTreeId() int64 {
+	if m != nil {
+		return m.TreeId
+	}
+	return 0
+}
+
+func (m *TreeStats) GetLeafCount() int64 {
+	if m != nil {
+		return m.LeafCount
+	}
+	return 0
+}
+
+func (m *TreeStats) GetLeafBytes() int64 {
+	if m != nil {
+		return m.LeafBytes
+	}
+	return 0
+}
+
+func (m *TreeStats) GetSubtreeCount() int64 {
+	if m != nil {
+		return m.SubtreeCount
+	}
+	return 0
+}
+
+func (m *TreeStats) GetRevisionCount() int64 {
+	if m != nil {
+		return m.RevisionCount
+	}
+	return 0
+}
+
+func (m *TreeStats) GetReadQps() float64 {
+	if m != nil {
+		return m.ReadQps
+	}
+	return 0
+}
+
+func (m *TreeStats) GetWriteQps() float64 {
+	if m != nil {
+		return m.WriteQps
+	}
+	return 0
+}
+
+// InspectLeafQueue request.
+type InspectLeafQueueRequest struct {
+	// ID of the log whose queue to inspect.
+	TreeId int64 `protobuf:"varint,1,opt,name=tree_id,json=treeId" json:"tree_id,omitempty"`
+	// Number of the oldest queued leaves to return. Defaults to 10 if zero, and
+	// may be at most 1000.
+	OldestLeafCount int32 `protobuf:"varint,2,opt,name=oldest_leaf_count,json=oldestLeafCount" json:"oldest_leaf_count,omitempty"`
+}
+
+func (m *InspectLeafQueueRequest) Reset()                    { *m = InspectLeafQueueRequest{} }
+func (m *InspectLeafQueueRequest) String() string            { return proto.CompactTextString(m) }
+func (*InspectLeafQueueRequest) ProtoMessage()               {}
+func (*InspectLeafQueueRequest) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{9} }
+
+func (m *InspectLeafQueueRequest) GetTreeId() int64 {
+	if m != nil {
+		return m.TreeId
+	}
+	return 0
+}
+
+func (m *InspectLeafQueueRequest) GetOldestLeafCount() int32 {
+	if m != nil {
+		return m.OldestLeafCount
+	}
+	return 0
+}
+
+// A leaf waiting in the queue of a log to be sequenced.
+type LeafQueueEntry struct {
+	LeafIdentityHash []byte `protobuf:"bytes,1,opt,name=leaf_identity_hash,json=leafIdentityHash,proto3" json:"leaf_identity_hash,omitempty"`
+	MerkleLeafHash   []byte `protobuf:"bytes,2,opt,name=merkle_leaf_hash,json=merkleLeafHash,proto3" json:"merkle_leaf_hash,omitempty"`
+	// Time at which the leaf was queued.
+	QueueTimestamp *google_protobuf1.Timestamp `protobuf:"bytes,3,opt,name=queue_timestamp,json=queueTimestamp" json:"queue_timestamp,omitempty"`
+	// Queue bucket holding the leaf. Always zero on storage which doesn't shard
+	// the queue.
+	Bucket int64 `protobuf:"varint,4,opt,name=bucket" json:"bucket,omitempty"`
+}
+
+func (m *LeafQueueEntry) Reset()                    { *m = LeafQueueEntry{} }
+func (m *LeafQueueEntry) String() string            { return proto.CompactTextString(m) }
+func (*LeafQueueEntry) ProtoMessage()               {}
+func (*LeafQueueEntry) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{10} }
+
+func (m *LeafQueueEntry) GetLeafIdentityHash() []byte {
+	if m != nil {
+		return m.LeafIdentityHash
+	}
+	return nil
+}
+
+func (m *LeafQueueEntry) GetMerkleLeafHash() []byte {
+	if m != nil {
+		return m.MerkleLeafHash
+	}
+	return nil
+}
+
+func (m *LeafQueueEntry) GetQueueTimestamp() *google_protobuf1.Timestamp {
+	if m != nil {
+		return m.QueueTimestamp
+	}
+	return nil
+}
+
+func (m *LeafQueueEntry) GetBucket() int64 {
+	if m != nil {
+		return m.Bucket
+	}
+	return 0
+}
+
+// Number of queued leaves whose age is within [min_age, max_age).
+type LeafQueueAgeBucket struct {
+	MinAge *google_protobuf3.Duration `protobuf:"bytes,1,opt,name=min_age,json=minAge" json:"min_age,omitempty"`
+	// Unset for the last bucket, which has no upper bound.
+	MaxAge    *google_protobuf3.Duration `protobuf:"bytes,2,opt,name=max_age,json=maxAge" json:"max_age,omitempty"`
+	LeafCount int64                      `protobuf:"varint,3,opt,name=leaf_count,json=leafCount" json:"leaf_count,omitempty"`
+}
+
+func (m *LeafQueueAgeBucket) Reset()                    { *m = LeafQueueAgeBucket{} }
+func (m *LeafQueueAgeBucket) String() string            { return proto.CompactTextString(m) }
+func (*LeafQueueAgeBucket) ProtoMessage()               {}
+func (*LeafQueueAgeBucket) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{11} }
+
+func (m *LeafQueueAgeBucket) GetMinAge() *google_protobuf3.Duration {
+	if m != nil {
+		return m.MinAge
+	}
+	return nil
+}
+
+func (m *LeafQueueAgeBucket) GetMaxAge() *google_protobuf3.Duration {
+	if m != nil {
+		return m.MaxAge
+	}
+	return nil
+}
+
+func (m *LeafQueueAgeBucket) GetLeafCount() int64 {
+	if m != nil {
+		return m.LeafCount
+	}
+	return 0
+}
+
+// Depth of one bucket of a log's queue.
+type LeafQueueShard struct {
+	Bucket    int64 `protobuf:"varint,1,opt,name=bucket" json:"bucket,omitempty"`
+	LeafCount int64 `protobuf:"varint,2,opt,name=leaf_count,json=leafCount" json:"leaf_count,omitempty"`
+	// Queue time of the oldest leaf in the bucket.
+	OldestQueueTimestamp *google_protobuf1.Timestamp `protobuf:"bytes,3,opt,name=oldest_queue_timestamp,json=oldestQueueTimestamp" json:"oldest_queue_timestamp,omitempty"`
+}
+
+func (m *LeafQueueShard) Reset()                    { *m = LeafQueueShard{} }
+func (m *LeafQueueShard) String() string            { return proto.CompactTextString(m) }
+func (*LeafQueueShard) ProtoMessage()               {}
+func (*LeafQueueShard) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{12} }
+
+func (m *LeafQueueShard) GetBucket() int64 {
+	if m != nil {
+		return m.Bucket
+	}
+	return 0
+}
+
+func (m *LeafQueueShard) GetLeafCount() int64 {
+	if m != nil {
+		return m.LeafCount
+	}
+	return 0
+}
+
+func (m *LeafQueueShard) GetOldestQueueTimestamp() *google_protobuf1.Timestamp {
+	if m != nil {
+		return m.OldestQueueTimestamp
+	}
+	return nil
+}
+
+// A sample of the queue of leaves waiting to be sequenced into a log, for
+// diagnosing sequencing stalls.
+type LeafQueueSample struct {
+	// ID of the log.
+	TreeId int64 `protobuf:"varint,1,opt,name=tree_id,json=treeId" json:"tree_id,omitempty"`
+	// Time at which the queue was sampled, which leaf ages are relative to.
+	SampleTime *google_protobuf1.Timestamp `protobuf:"bytes,2,opt,name=sample_time,json=sampleTime" json:"sample_time,omitempty"`
+	// Number of queued leaves.
+	LeafCount int64 `protobuf:"varint,3,opt,name=leaf_count,json=leafCount" json:"leaf_count,omitempty"`
+	// The oldest queued leaves, oldest first.
+	OldestLeaves []*LeafQueueEntry `protobuf:"bytes,4,rep,name=oldest_leaves,json=oldestLeaves" json:"oldest_leaves,omitempty"`
+	// Number of queued leaves by age, youngest first.
+	AgeBuckets []*LeafQueueAgeBucket `protobuf:"bytes,5,rep,name=age_buckets,json=ageBuckets" json:"age_buckets,omitempty"`
+	// Number of queued leaves in each bucket of the queue, on storage which
+	// shards the queue (e.g. Cloud Spanner). A single bucket 0 otherwise.
+	Shards []*LeafQueueShard `protobuf:"bytes,6,rep,name=shards" json:"shards,omitempty"`
+}
+
+func (m *LeafQueueSample) Reset()                    { *m = LeafQueueSample{} }
+func (m *LeafQueueSample) String() string            { return proto.CompactTextString(m) }
+func (*LeafQueueSample) ProtoMessage()               {}
+func (*LeafQueueSample) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{13} }
+
+func (m *LeafQueueSample) GetTreeId() int64 {
+	if m != nil {
+		return m.TreeId
+	}
+	return 0
+}
+
+func (m *LeafQueueSample) GetSampleTime() *google_protobuf1.Timestamp {
+	if m != nil {
+		return m.SampleTime
+	}
+	return nil
+}
+
+func (m *LeafQueueSample) GetLeafCount() int64 {
+	if m != nil {
+		return m.LeafCount
+	}
+	return 0
+}
+
+func (m *LeafQueueSample) GetOldestLeaves() []*LeafQueueEntry {
+	if m != nil {
+		return m.OldestLeaves
+	}
+	return nil
+}
+
+func (m *LeafQueueSample) GetAgeBuckets() []*LeafQueueAgeBucket {
+	if m != nil {
+		return m.AgeBuckets
+	}
+	return nil
+}
+
+func (m *LeafQueueSample) GetShards() []*LeafQueueShard {
+	if m != nil {
+		return m.Shards
+	}
+	return nil
+}
+
+// CheckTreeIntegrity request.
+type CheckTreeIntegrityRequest struct {
+	// ID of the log to check.
+	TreeId int64 `protobuf:"varint,1,opt,name=tree_id,json=treeId" json:"tree_id,omitempty"`
+	// Revision of the root to check the log at. Zero means the latest root.
+	Revision int64 `protobuf:"varint,2,opt,name=revision" json:"revision,omitempty"`
+}
+
+func (m *CheckTreeIntegrityRequest) Reset()                    { *m = CheckTreeIntegrityRequest{} }
+func (m *CheckTreeIntegrityRequest) String() string            { return proto.CompactTextString(m) }
+func (*CheckTreeIntegrityRequest) ProtoMessage()               {}
+func (*CheckTreeIntegrityRequest) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{14} }
+
+func (m *CheckTreeIntegrityRequest) GetTreeId() int64 {
+	if m != nil {
+		return m.TreeId
+	}
+	return 0
+}
+
+func (m *CheckTreeIntegrityRequest) GetRevision() int64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
+
+// GetTreeIntegrityCheck request.
+type GetTreeIntegrityCheckRequest struct {
+	// ID of the log whose latest integrity check to return.
+	TreeId int64 `protobuf:"varint,1,opt,name=tree_id,json=treeId" json:"tree_id,omitempty"`
+}
+
+func (m *GetTreeIntegrityCheckRequest) Reset()                    { *m = GetTreeIntegrityCheckRequest{} }
+func (m *GetTreeIntegrityCheckRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetTreeIntegrityCheckRequest) ProtoMessage()               {}
+func (*GetTreeIntegrityCheckRequest) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{15} }
+
+func (m *GetTreeIntegrityCheckRequest) GetTreeId() int64 {
+	if m != nil {
+		return m.TreeId
+	}
+	return 0
+}
+
+// An integrity check of a log, which recomputes the root of the log from its
+// stored leaves and checks it, the leaf hashes and the stored Merkle nodes
+// against the stored root.
+type TreeIntegrityCheck struct {
+	// ID of the log.
+	TreeId int64 `protobuf:"varint,1,opt,name=tree_id,json=treeId" json:"tree_id,omitempty"`
+	// Revision and size of the root the log is checked at.
+	Revision int64               `protobuf:"varint,2,opt,name=revision" json:"revision,omitempty"`
+	TreeSize int64               `protobuf:"varint,3,opt,name=tree_size,json=treeSize" json:"tree_size,omitempty"`
+	State    IntegrityCheckState `protobuf:"varint,4,opt,name=state,enum=trillian.IntegrityCheckState" json:"state,omitempty"`
+	// Number of leaves checked so far, out of tree_size.
+	LeavesChecked int64 `protobuf:"varint,5,opt,name=leaves_checked,json=leavesChecked" json:"leaves_checked,omitempty"`
+	// Why the check failed or was aborted.
+	Failure   string                      `protobuf:"bytes,6,opt,name=failure" json:"failure,omitempty"`
+	StartTime *google_protobuf1.Timestamp `protobuf:"bytes,7,opt,name=start_time,json=startTime" json:"start_time,omitempty"`
+	// Unset while the check is running.
+	EndTime *google_protobuf1.Timestamp `protobuf:"bytes,8,opt,name=end_time,json=endTime" json:"end_time,omitempty"`
+}
+
+func (m *TreeIntegrityCheck) Reset()                    { *m = TreeIntegrityCheck{} }
+func (m *TreeIntegrityCheck) String() string            { return proto.CompactTextString(m) }
+func (*TreeIntegrityCheck) ProtoMessage()               {}
+func (*TreeIntegrityCheck) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{16} }
+
+func (m *TreeIntegrityCheck) GetTreeId() int64 {
+	if m != nil {
+		return m.TreeId
+	}
+	return 0
+}
+
+func (m *TreeIntegrityCheck) GetRevision() int64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
+
+func (m *TreeIntegrityCheck) GetTreeSize() int64 {
+	if m != nil {
+		return m.TreeSize
+	}
+	return 0
+}
+
+func (m *TreeIntegrityCheck) GetState() IntegrityCheckState {
+	if m != nil {
+		return m.State
+	}
+	return IntegrityCheckState_UNKNOWN_INTEGRITY_CHECK_STATE
+}
+
+func (m *TreeIntegrityCheck) GetLeavesChecked() int64 {
+	if m != nil {
+		return m.LeavesChecked
+	}
+	return 0
+}
+
+func (m *TreeIntegrityCheck) GetFailure() string {
+	if m != nil {
+		return m.Failure
+	}
+	return ""
+}
+
+func (m *TreeIntegrityCheck) GetStartTime() *google_protobuf1.Timestamp {
+	if m != nil {
+		return m.StartTime
+	}
+	return nil
+}
+
+func (m *TreeIntegrityCheck) GetEndTime() *google_protobuf1.Timestamp {
+	if m != nil {
+		return m.EndTime
+	}
+	return nil
+}
+
+// GetMergeDelayCompliance request.
+type GetMergeDelayComplianceRequest struct {
+	// ID of the log.
+	TreeId int64 `protobuf:"varint,1,opt,name=tree_id,json=treeId" json:"tree_id,omitempty"`
+	// Only windows which end after start_time and start before end_time are
+	// returned. Unset bounds are open.
+	StartTime *google_protobuf1.Timestamp `protobuf:"bytes,2,opt,name=start_time,json=startTime" json:"start_time,omitempty"`
+	EndTime   *google_protobuf1.Timestamp `protobuf:"bytes,3,opt,name=end_time,json=endTime" json:"end_time,omitempty"`
+}
+
+func (m *GetMergeDelayComplianceRequest) Reset()                    { *m = GetMergeDelayComplianceRequest{} }
+func (m *GetMergeDelayComplianceRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetMergeDelayComplianceRequest) ProtoMessage()               {}
+func (*GetMergeDelayComplianceRequest) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{17} }
+
+func (m *GetMergeDelayComplianceRequest) GetTreeId() int64 {
+	if m != nil {
+		return m.TreeId
+	}
+	return 0
+}
+
+func (m *GetMergeDelayComplianceRequest) GetStartTime() *google_protobuf1.Timestamp {
+	if m != nil {
+		return m.StartTime
+	}
+	return nil
+}
+
+func (m *GetMergeDelayComplianceRequest) GetEndTime() *google_protobuf1.Timestamp {
+	if m != nil {
+		return m.EndTime
+	}
+	return nil
+}
+
+// Summary of the merge delays of the leaves which a log integrated during a
+// window of time, against the log's maximum merge delay (MMD) at that time.
+type MergeDelayWindow struct {
+	StartTime *google_protobuf1.Timestamp `protobuf:"bytes,1,opt,name=start_time,json=startTime" json:"start_time,omitempty"`
+	EndTime   *google_protobuf1.Timestamp `protobuf:"bytes,2,opt,name=end_time,json=endTime" json:"end_time,omitempty"`
+	// The MMD of the log when it integrated the leaves. If it changed during
+	// the window, the latest one.
+	MaxMergeDelay *google_protobuf3.Duration `protobuf:"bytes,3,opt,name=max_merge_delay,json=maxMergeDelay" json:"max_merge_delay,omitempty"`
+	// Number of leaves integrated during the window.
+	LeafCount int64 `protobuf:"varint,4,opt,name=leaf_count,json=leafCount" json:"leaf_count,omitempty"`
+	// Number of the leaves integrated later than the MMD after being queued.
+	ViolationCount int64 `protobuf:"varint,5,opt,name=violation_count,json=violationCount" json:"violation_count,omitempty"`
+	// Number of the leaves integrated within the MMD, but so close to it that
+	// they're worth alerting on.
+	NearMissCount int64 `protobuf:"varint,6,opt,name=near_miss_count,json=nearMissCount" json:"near_miss_count,omitempty"`
+	// Longest merge delay of the leaves.
+	MaxObservedDelay *google_protobuf3.Duration `protobuf:"bytes,7,opt,name=max_observed_delay,json=maxObservedDelay" json:"max_observed_delay,omitempty"`
+}
+
+func (m *MergeDelayWindow) Reset()                    { *m = MergeDelayWindow{} }
+func (m *MergeDelayWindow) String() string            { return proto.CompactTextString(m) }
+func (*MergeDelayWindow) ProtoMessage()               {}
+func (*MergeDelayWindow) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{18} }
+
+func (m *MergeDelayWindow) GetStartTime() *google_protobuf1.Timestamp {
+	if m != nil {
+		return m.StartTime
+	}
+	return nil
+}
+
+func (m *MergeDelayWindow) GetEndTime() *google_protobuf1.Timestamp {
+	if m != nil {
+		return m.EndTime
+	}
+	return nil
+}
+
+func (m *MergeDelayWindow) GetMaxMergeDelay() *google_protobuf3.Duration {
+	if m != nil {
+		return m.MaxMergeDelay
+	}
+	return nil
+}
+
+func (m *MergeDelayWindow) GetLeafCount() int64 {
+	if m != nil {
+		return m.LeafCount
+	}
+	return 0
+}
+
+func (m *MergeDelayWindow) GetViolationCount() int64 {
+	if m != nil {
+		return m.ViolationCount
+	}
+	return 0
+}
+
+func (m *MergeDelayWindow) GetNearMissCount() int64 {
+	if m != nil {
+		return m.NearMissCount
+	}
+	return 0
+}
+
+func (m *MergeDelayWindow) GetMaxObservedDelay() *google_protobuf3.Duration {
+	if m != nil {
+		return m.MaxObservedDelay
+	}
+	return nil
+}
+
+// Merge delay compliance of a log over time, e.g. for audit reports.
+type MergeDelayCompliance struct {
+	// ID of the log.
+	TreeId int64 `protobuf:"varint,1,opt,name=tree_id,json=treeId" json:"tree_id,omitempty"`
+	// The windows during which the log integrated leaves, oldest first.
+	// Windows without any leaves are omitted.
+	Windows []*MergeDelayWindow `protobuf:"bytes,2,rep,name=windows" json:"windows,omitempty"`
 }
 
-func (m *ListTreesRequest) Reset()                    { *m = ListTreesRequest{} }
-func (m *ListTreesRequest) String() string            { return proto.CompactTextString(m) }
-func (*ListTreesRequest) ProtoMessage()               {}
-func (*ListTreesRequest) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{0} }
+func (m *MergeDelayCompliance) Reset()                    { *m = MergeDelayCompliance{} }
+func (m *MergeDelayCompliance) String() string            { return proto.CompactTextString(m) }
+func (*MergeDelayCompliance) ProtoMessage()               {}
+func (*MergeDelayCompliance) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{19} }
 
-func (m *ListTreesRequest) GetShowDeleted() bool {
+func (m *MergeDelayCompliance) GetTreeId() int64 {
 	if m != nil {
-		return m.ShowDeleted
+		return m.TreeId
 	}
-	return false
+	return 0
 }
 
-// ListTrees response.
-// No pagination is provided, all trees the requester has access to are
-// returned.
-type ListTreesResponse struct {
-	// Trees matching the list request filters.
-	Tree []*Tree `protobuf:"bytes,1,rep,name=tree" json:"tree,omitempty"`
+func (m *MergeDelayCompliance) GetWindows() []*MergeDelayWindow {
+	if m != nil {
+		return m.Windows
+	}
+	return nil
 }
 
-func (m *ListTreesResponse) Reset()                    { *m = ListTreesResponse{} }
-func (m *ListTreesResponse) String() string            { return proto.CompactTextString(m) }
-func (*ListTreesResponse) ProtoMessage()               {}
-func (*ListTreesResponse) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{1} }
+// BatchGetTreeStatus request.
+type BatchGetTreeStatusRequest struct {
+	// IDs of the trees to return the status of, at most 1000.
+	TreeIds []int64 `protobuf:"varint,1,rep,packed,name=tree_ids,json=treeIds" json:"tree_ids,omitempty"`
+	// Fields of TreeStatusEntry to return, other than tree_id: "tree_state",
+	// "latest_root" and "queue_depth". If unset, all of them are returned.
+	// Fields which aren't needed are best left out, as each of them costs a
+	// storage read per tree.
+	ReadMask *google_protobuf4.FieldMask `protobuf:"bytes,2,opt,name=read_mask,json=readMask" json:"read_mask,omitempty"`
+}
 
-func (m *ListTreesResponse) GetTree() []*Tree {
+func (m *BatchGetTreeStatusRequest) Reset()                    { *m = BatchGetTreeStatusRequest{} }
+func (m *BatchGetTreeStatusRequest) String() string            { return proto.CompactTextString(m) }
+func (*BatchGetTreeStatusRequest) ProtoMessage()               {}
+func (*BatchGetTreeStatusRequest) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{20} }
+
+func (m *BatchGetTreeStatusRequest) GetTreeIds() []int64 {
 	if m != nil {
-		return m.Tree
+		return m.TreeIds
 	}
 	return nil
 }
 
-// GetTree request.
-type GetTreeRequest struct {
-	// ID of the tree to retrieve.
+func (m *BatchGetTreeStatusRequest) GetReadMask() *google_protobuf4.FieldMask {
+	if m != nil {
+		return m.ReadMask
+	}
+	return nil
+}
+
+// The status of a tree, as returned by BatchGetTreeStatus.
+type TreeStatusEntry struct {
+	// ID of the tree.
 	TreeId int64 `protobuf:"varint,1,opt,name=tree_id,json=treeId" json:"tree_id,omitempty"`
+	// Set if the status of the tree couldn't be read, e.g. NOT_FOUND if the
+	// tree doesn't exist. The other fields are then unset.
+	Status    *google_rpc.Status `protobuf:"bytes,2,opt,name=status" json:"status,omitempty"`
+	TreeState TreeState          `protobuf:"varint,3,opt,name=tree_state,json=treeState,enum=trillian.TreeState" json:"tree_state,omitempty"`
+	// The latest signed root of a log tree.
+	LatestLogRoot *SignedLogRoot `protobuf:"bytes,4,opt,name=latest_log_root,json=latestLogRoot" json:"latest_log_root,omitempty"`
+	// The latest signed root of a map tree.
+	LatestMapRoot *SignedMapRoot `protobuf:"bytes,5,opt,name=latest_map_root,json=latestMapRoot" json:"latest_map_root,omitempty"`
+	// Number of leaves waiting to be integrated into a log.
+	QueueDepth int64 `protobuf:"varint,6,opt,name=queue_depth,json=queueDepth" json:"queue_depth,omitempty"`
 }
 
-func (m *GetTreeRequest) Reset()                    { *m = GetTreeRequest{} }
-func (m *GetTreeRequest) String() string            { return proto.CompactTextString(m) }
-func (*GetTreeRequest) ProtoMessage()               {}
-func (*GetTreeRequest) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{2} }
+func (m *TreeStatusEntry) Reset()                    { *m = TreeStatusEntry{} }
+func (m *TreeStatusEntry) String() string            { return proto.CompactTextString(m) }
+func (*TreeStatusEntry) ProtoMessage()               {}
+func (*TreeStatusEntry) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{21} }
 
-func (m *GetTreeRequest) GetTreeId() int64 {
+func (m *TreeStatusEntry) GetTreeId() int64 {
 	if m != nil {
 		return m.TreeId
 	}
 	return 0
 }
 
-// CreateTree request.
-type CreateTreeRequest struct {
-	// Tree to be created. See Tree and CreateTree for more details.
-	Tree *Tree `protobuf:"bytes,1,opt,name=tree" json:"tree,omitempty"`
-	// Describes how the tree's private key should be generated.
-	// Only needs to be set if tree.private_key is not set.
-	KeySpec *keyspb.Specification `protobuf:"bytes,2,opt,name=key_spec,json=keySpec" json:"key_spec,omitempty"`
-}
-
-func (m *CreateTreeRequest) Reset()                    { *m = CreateTreeRequest{} }
-func (m *CreateTreeRequest) String() string            { return proto.CompactTextString(m) }
-func (*CreateTreeRequest) ProtoMessage()               {}
-func (*CreateTreeRequest) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{3} }
-
-func (m *CreateTreeRequest) GetTree() *Tree {
+func (m *TreeStatusEntry) GetStatus() *google_rpc.Status {
 	if m != nil {
-		return m.Tree
+		return m.Status
 	}
 	return nil
 }
 
-func (m *CreateTreeRequest) GetKeySpec() *keyspb.Specification {
+func (m *TreeStatusEntry) GetTreeState() TreeState {
 	if m != nil {
-		return m.KeySpec
+		return m.TreeState
 	}
-	return nil
+	return TreeState_UNKNOWN_TREE_STATE
 }
 
-// UpdateTree request.
-type UpdateTreeRequest struct {
-	// Tree to be updated.
-	Tree *Tree `protobuf:"bytes,1,opt,name=tree" json:"tree,omitempty"`
-	// Fields modified by the update request.
-	// For example: "tree_state", "display_name", "description".
-	UpdateMask *google_protobuf4.FieldMask `protobuf:"bytes,2,opt,name=update_mask,json=updateMask" json:"update_mask,omitempty"`
+func (m *TreeStatusEntry) GetLatestLogRoot() *SignedLogRoot {
+	if m != nil {
+		return m.LatestLogRoot
+	}
+	return nil
 }
 
-func (m *UpdateTreeRequest) Reset()                    { *m = UpdateTreeRequest{} }
-func (m *UpdateTreeRequest) String() string            { return proto.CompactTextString(m) }
-func (*UpdateTreeRequest) ProtoMessage()               {}
-func (*UpdateTreeRequest) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{4} }
-
-func (m *UpdateTreeRequest) GetTree() *Tree {
+func (m *TreeStatusEntry) GetLatestMapRoot() *SignedMapRoot {
 	if m != nil {
-		return m.Tree
+		return m.LatestMapRoot
 	}
 	return nil
 }
 
-func (m *UpdateTreeRequest) GetUpdateMask() *google_protobuf4.FieldMask {
+func (m *TreeStatusEntry) GetQueueDepth() int64 {
 	if m != nil {
-		return m.UpdateMask
+		return m.QueueDepth
 	}
-	return nil
+	return 0
 }
 
-// DeleteTree request.
-type DeleteTreeRequest struct {
-	// ID of the tree to delete.
-	TreeId int64 `protobuf:"varint,1,opt,name=tree_id,json=treeId" json:"tree_id,omitempty"`
+// BatchGetTreeStatus response.
+type BatchGetTreeStatusResponse struct {
+	// The status of each requested tree, in the order of the request.
+	Statuses []*TreeStatusEntry `protobuf:"bytes,1,rep,name=statuses" json:"statuses,omitempty"`
 }
 
-func (m *DeleteTreeRequest) Reset()                    { *m = DeleteTreeRequest{} }
-func (m *DeleteTreeRequest) String() string            { return proto.CompactTextString(m) }
-func (*DeleteTreeRequest) ProtoMessage()               {}
-func (*DeleteTreeRequest) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{5} }
+func (m *BatchGetTreeStatusResponse) Reset()                    { *m = BatchGetTreeStatusResponse{} }
+func (m *BatchGetTreeStatusResponse) String() string            { return proto.CompactTextString(m) }
+func (*BatchGetTreeStatusResponse) ProtoMessage()               {}
+func (*BatchGetTreeStatusResponse) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{22} }
 
-func (m *DeleteTreeRequest) GetTreeId() int64 {
+func (m *BatchGetTreeStatusResponse) GetStatuses() []*TreeStatusEntry {
 	if m != nil {
-		return m.TreeId
+		return m.Statuses
 	}
-	return 0
+	return nil
 }
 
-// UndeleteTree request.
-type UndeleteTreeRequest struct {
-	// ID of the tree to undelete.
-	TreeId int64 `protobuf:"varint,1,opt,name=tree_id,json=treeId" json:"tree_id,omitempty"`
+// GetTreeByExternalId request.
+type GetTreeByExternalIdRequest struct {
+	// External identifier of the tree, as given to CreateTree.
+	ExternalId string `protobuf:"bytes,1,opt,name=external_id,json=externalId" json:"external_id,omitempty"`
+	// If true, a soft-deleted tree may be returned.
+	ShowDeleted bool `protobuf:"varint,2,opt,name=show_deleted,json=showDeleted" json:"show_deleted,omitempty"`
 }
 
-func (m *UndeleteTreeRequest) Reset()                    { *m = UndeleteTreeRequest{} }
-func (m *UndeleteTreeRequest) String() string            { return proto.CompactTextString(m) }
-func (*UndeleteTreeRequest) ProtoMessage()               {}
-func (*UndeleteTreeRequest) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{6} }
+func (m *GetTreeByExternalIdRequest) Reset()                    { *m = GetTreeByExternalIdRequest{} }
+func (m *GetTreeByExternalIdRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetTreeByExternalIdRequest) ProtoMessage()               {}
+func (*GetTreeByExternalIdRequest) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{23} }
 
-func (m *UndeleteTreeRequest) GetTreeId() int64 {
+func (m *GetTreeByExternalIdRequest) GetExternalId() string {
 	if m != nil {
-		return m.TreeId
+		return m.ExternalId
 	}
-	return 0
+	return ""
+}
+
+func (m *GetTreeByExternalIdRequest) GetShowDeleted() bool {
+	if m != nil {
+		return m.ShowDeleted
+	}
+	return false
 }
 
 func init() {
@@ -177,6 +965,24 @@ func init() {
 	proto.RegisterType((*UpdateTreeRequest)(nil), "trillian.UpdateTreeRequest")
 	proto.RegisterType((*DeleteTreeRequest)(nil), "trillian.DeleteTreeRequest")
 	proto.RegisterType((*UndeleteTreeRequest)(nil), "trillian.UndeleteTreeRequest")
+	proto.RegisterType((*GetTreeStatsRequest)(nil), "trillian.GetTreeStatsRequest")
+	proto.RegisterType((*TreeStats)(nil), "trillian.TreeStats")
+	proto.RegisterType((*InspectLeafQueueRequest)(nil), "trillian.InspectLeafQueueRequest")
+	proto.RegisterType((*LeafQueueEntry)(nil), "trillian.LeafQueueEntry")
+	proto.RegisterType((*LeafQueueAgeBucket)(nil), "trillian.LeafQueueAgeBucket")
+	proto.RegisterType((*LeafQueueShard)(nil), "trillian.LeafQueueShard")
+	proto.RegisterType((*LeafQueueSample)(nil), "trillian.LeafQueueSample")
+	proto.RegisterType((*CheckTreeIntegrityRequest)(nil), "trillian.CheckTreeIntegrityRequest")
+	proto.RegisterType((*GetTreeIntegrityCheckRequest)(nil), "trillian.GetTreeIntegrityCheckRequest")
+	proto.RegisterType((*TreeIntegrityCheck)(nil), "trillian.TreeIntegrityCheck")
+	proto.RegisterType((*GetMergeDelayComplianceRequest)(nil), "trillian.GetMergeDelayComplianceRequest")
+	proto.RegisterType((*MergeDelayWindow)(nil), "trillian.MergeDelayWindow")
+	proto.RegisterType((*MergeDelayCompliance)(nil), "trillian.MergeDelayCompliance")
+	proto.RegisterType((*BatchGetTreeStatusRequest)(nil), "trillian.BatchGetTreeStatusRequest")
+	proto.RegisterType((*TreeStatusEntry)(nil), "trillian.TreeStatusEntry")
+	proto.RegisterType((*BatchGetTreeStatusResponse)(nil), "trillian.BatchGetTreeStatusResponse")
+	proto.RegisterType((*GetTreeByExternalIdRequest)(nil), "trillian.GetTreeByExternalIdRequest")
+	proto.RegisterEnum("trillian.IntegrityCheckState", IntegrityCheckState_name, IntegrityCheckState_value)
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -199,6 +1005,9 @@ type TrillianAdminClient interface {
 	// e.g.: tree_id, create_time and update_time.
 	// Returns the created tree, with all system-generated fields assigned.
 	CreateTree(ctx context.Context, in *CreateTreeRequest, opts ...grpc.CallOption) (*Tree, error)
+	// Retrieves a tree by the external identifier it was created with, see
+	// CreateTreeRequest.external_id.
+	GetTreeByExternalId(ctx context.Context, in *GetTreeByExternalIdRequest, opts ...grpc.CallOption) (*Tree, error)
 	// Updates a tree.
 	// See Tree for details. Readonly fields cannot be updated.
 	UpdateTree(ctx context.Context, in *UpdateTreeRequest, opts ...grpc.CallOption) (*Tree, error)
@@ -210,6 +1019,26 @@ type TrillianAdminClient interface {
 	// A soft-deleted tree may be undeleted for a certain period, after which
 	// it'll be permanently deleted.
 	UndeleteTree(ctx context.Context, in *UndeleteTreeRequest, opts ...grpc.CallOption) (*Tree, error)
+	// Returns statistics about the data stored for a tree, for capacity
+	// planning.
+	GetTreeStats(ctx context.Context, in *GetTreeStatsRequest, opts ...grpc.CallOption) (*TreeStats, error)
+	// Samples the queue of leaves waiting to be sequenced into a log, so that
+	// sequencing stalls can be diagnosed without direct access to storage.
+	InspectLeafQueue(ctx context.Context, in *InspectLeafQueueRequest, opts ...grpc.CallOption) (*LeafQueueSample, error)
+	// Starts checking the integrity of a log's storage in the background, e.g.
+	// after a suspected storage incident. Only one check of a log may run at a
+	// time. Its verdict is also recorded in the labels of the log, under
+	// "trillian.integrity.*" keys.
+	CheckTreeIntegrity(ctx context.Context, in *CheckTreeIntegrityRequest, opts ...grpc.CallOption) (*TreeIntegrityCheck, error)
+	// Returns the progress or verdict of the latest integrity check of a log.
+	GetTreeIntegrityCheck(ctx context.Context, in *GetTreeIntegrityCheckRequest, opts ...grpc.CallOption) (*TreeIntegrityCheck, error)
+	// Returns whether a log integrated its leaves within its maximum merge
+	// delay, in windows of time, as recorded by its signer.
+	GetMergeDelayCompliance(ctx context.Context, in *GetMergeDelayComplianceRequest, opts ...grpc.CallOption) (*MergeDelayCompliance, error)
+	// Returns the status of many trees in one call, e.g. for dashboards which
+	// cover a fleet of logs. Failures to read the status of a tree are reported
+	// in its entry, rather than failing the whole request.
+	BatchGetTreeStatus(ctx context.Context, in *BatchGetTreeStatusRequest, opts ...grpc.CallOption) (*BatchGetTreeStatusResponse, error)
 }
 
 type trillianAdminClient struct {
@@ -247,6 +1076,15 @@ func (c *trillianAdminClient) CreateTree(ctx context.Context, in *CreateTreeRequ
 	return out, nil
 }
 
+func (c *trillianAdminClient) GetTreeByExternalId(ctx context.Context, in *GetTreeByExternalIdRequest, opts ...grpc.CallOption) (*Tree, error) {
+	out := new(Tree)
+	err := grpc.Invoke(ctx, "/trillian.TrillianAdmin/GetTreeByExternalId", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *trillianAdminClient) UpdateTree(ctx context.Context, in *UpdateTreeRequest, opts ...grpc.CallOption) (*Tree, error) {
 	out := new(Tree)
 	err := grpc.Invoke(ctx, "/trillian.TrillianAdmin/UpdateTree", in, out, c.cc, opts...)
@@ -274,6 +1112,60 @@ func (c *trillianAdminClient) UndeleteTree(ctx context.Context, in *UndeleteTree
 	return out, nil
 }
 
+func (c *trillianAdminClient) GetTreeStats(ctx context.Context, in *GetTreeStatsRequest, opts ...grpc.CallOption) (*TreeStats, error) {
+	out := new(TreeStats)
+	err := grpc.Invoke(ctx, "/trillian.TrillianAdmin/GetTreeStats", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *trillianAdminClient) InspectLeafQueue(ctx context.Context, in *InspectLeafQueueRequest, opts ...grpc.CallOption) (*LeafQueueSample, error) {
+	out := new(LeafQueueSample)
+	err := grpc.Invoke(ctx, "/trillian.TrillianAdmin/InspectLeafQueue", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *trillianAdminClient) CheckTreeIntegrity(ctx context.Context, in *CheckTreeIntegrityRequest, opts ...grpc.CallOption) (*TreeIntegrityCheck, error) {
+	out := new(TreeIntegrityCheck)
+	err := grpc.Invoke(ctx, "/trillian.TrillianAdmin/CheckTreeIntegrity", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *trillianAdminClient) GetTreeIntegrityCheck(ctx context.Context, in *GetTreeIntegrityCheckRequest, opts ...grpc.CallOption) (*TreeIntegrityCheck, error) {
+	out := new(TreeIntegrityCheck)
+	err := grpc.Invoke(ctx, "/trillian.TrillianAdmin/GetTreeIntegrityCheck", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *trillianAdminClient) GetMergeDelayCompliance(ctx context.Context, in *GetMergeDelayComplianceRequest, opts ...grpc.CallOption) (*MergeDelayCompliance, error) {
+	out := new(MergeDelayCompliance)
+	err := grpc.Invoke(ctx, "/trillian.TrillianAdmin/GetMergeDelayCompliance", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *trillianAdminClient) BatchGetTreeStatus(ctx context.Context, in *BatchGetTreeStatusRequest, opts ...grpc.CallOption) (*BatchGetTreeStatusResponse, error) {
+	out := new(BatchGetTreeStatusResponse)
+	err := grpc.Invoke(ctx, "/trillian.TrillianAdmin/BatchGetTreeStatus", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for TrillianAdmin service
 
 type TrillianAdminServer interface {
@@ -286,6 +1178,9 @@ type TrillianAdminServer interface {
 	// e.g.: tree_id, create_time and update_time.
 	// Returns the created tree, with all system-generated fields assigned.
 	CreateTree(context.Context, *CreateTreeRequest) (*Tree, error)
+	// Retrieves a tree by the external identifier it was created with, see
+	// CreateTreeRequest.external_id.
+	GetTreeByExternalId(context.Context, *GetTreeByExternalIdRequest) (*Tree, error)
 	// Updates a tree.
 	// See Tree for details. Readonly fields cannot be updated.
 	UpdateTree(context.Context, *UpdateTreeRequest) (*Tree, error)
@@ -297,6 +1192,26 @@ type TrillianAdminServer interface {
 	// A soft-deleted tree may be undeleted for a certain period, after which
 	// it'll be permanently deleted.
 	UndeleteTree(context.Context, *UndeleteTreeRequest) (*Tree, error)
+	// Returns statistics about the data stored for a tree, for capacity
+	// planning.
+	GetTreeStats(context.Context, *GetTreeStatsRequest) (*TreeStats, error)
+	// Samples the queue of leaves waiting to be sequenced into a log, so that
+	// sequencing stalls can be diagnosed without direct access to storage.
+	InspectLeafQueue(context.Context, *InspectLeafQueueRequest) (*LeafQueueSample, error)
+	// Starts checking the integrity of a log's storage in the background, e.g.
+	// after a suspected storage incident. Only one check of a log may run at a
+	// time. Its verdict is also recorded in the labels of the log, under
+	// "trillian.integrity.*" keys.
+	CheckTreeIntegrity(context.Context, *CheckTreeIntegrityRequest) (*TreeIntegrityCheck, error)
+	// Returns the progress or verdict of the latest integrity check of a log.
+	GetTreeIntegrityCheck(context.Context, *GetTreeIntegrityCheckRequest) (*TreeIntegrityCheck, error)
+	// Returns whether a log integrated its leaves within its maximum merge
+	// delay, in windows of time, as recorded by its signer.
+	GetMergeDelayCompliance(context.Context, *GetMergeDelayComplianceRequest) (*MergeDelayCompliance, error)
+	// Returns the status of many trees in one call, e.g. for dashboards which
+	// cover a fleet of logs. Failures to read the status of a tree are reported
+	// in its entry, rather than failing the whole request.
+	BatchGetTreeStatus(context.Context, *BatchGetTreeStatusRequest) (*BatchGetTreeStatusResponse, error)
 }
 
 func RegisterTrillianAdminServer(s *grpc.Server, srv TrillianAdminServer) {
@@ -357,6 +1272,24 @@ func _TrillianAdmin_CreateTree_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _TrillianAdmin_GetTreeByExternalId_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTreeByExternalIdRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrillianAdminServer).GetTreeByExternalId(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/trillian.TrillianAdmin/GetTreeByExternalId",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrillianAdminServer).GetTreeByExternalId(ctx, req.(*GetTreeByExternalIdRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _TrillianAdmin_UpdateTree_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(UpdateTreeRequest)
 	if err := dec(in); err != nil {
@@ -411,6 +1344,114 @@ func _TrillianAdmin_UndeleteTree_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _TrillianAdmin_GetTreeStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTreeStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrillianAdminServer).GetTreeStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/trillian.TrillianAdmin/GetTreeStats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrillianAdminServer).GetTreeStats(ctx, req.(*GetTreeStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TrillianAdmin_InspectLeafQueue_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InspectLeafQueueRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrillianAdminServer).InspectLeafQueue(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/trillian.TrillianAdmin/InspectLeafQueue",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrillianAdminServer).InspectLeafQueue(ctx, req.(*InspectLeafQueueRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TrillianAdmin_CheckTreeIntegrity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckTreeIntegrityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrillianAdminServer).CheckTreeIntegrity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/trillian.TrillianAdmin/CheckTreeIntegrity",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrillianAdminServer).CheckTreeIntegrity(ctx, req.(*CheckTreeIntegrityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TrillianAdmin_GetTreeIntegrityCheck_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTreeIntegrityCheckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrillianAdminServer).GetTreeIntegrityCheck(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/trillian.TrillianAdmin/GetTreeIntegrityCheck",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrillianAdminServer).GetTreeIntegrityCheck(ctx, req.(*GetTreeIntegrityCheckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TrillianAdmin_GetMergeDelayCompliance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMergeDelayComplianceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrillianAdminServer).GetMergeDelayCompliance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/trillian.TrillianAdmin/GetMergeDelayCompliance",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrillianAdminServer).GetMergeDelayCompliance(ctx, req.(*GetMergeDelayComplianceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TrillianAdmin_BatchGetTreeStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchGetTreeStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrillianAdminServer).BatchGetTreeStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/trillian.TrillianAdmin/BatchGetTreeStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrillianAdminServer).BatchGetTreeStatus(ctx, req.(*BatchGetTreeStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _TrillianAdmin_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "trillian.TrillianAdmin",
 	HandlerType: (*TrillianAdminServer)(nil),
@@ -427,6 +1468,10 @@ var _TrillianAdmin_serviceDesc = grpc.ServiceDesc{
 			MethodName: "CreateTree",
 			Handler:    _TrillianAdmin_CreateTree_Handler,
 		},
+		{
+			MethodName: "GetTreeByExternalId",
+			Handler:    _TrillianAdmin_GetTreeByExternalId_Handler,
+		},
 		{
 			MethodName: "UpdateTree",
 			Handler:    _TrillianAdmin_UpdateTree_Handler,
@@ -439,6 +1484,30 @@ var _TrillianAdmin_serviceDesc = grpc.ServiceDesc{
 			MethodName: "UndeleteTree",
 			Handler:    _TrillianAdmin_UndeleteTree_Handler,
 		},
+		{
+			MethodName: "GetTreeStats",
+			Handler:    _TrillianAdmin_GetTreeStats_Handler,
+		},
+		{
+			MethodName: "InspectLeafQueue",
+			Handler:    _TrillianAdmin_InspectLeafQueue_Handler,
+		},
+		{
+			MethodName: "CheckTreeIntegrity",
+			Handler:    _TrillianAdmin_CheckTreeIntegrity_Handler,
+		},
+		{
+			MethodName: "GetTreeIntegrityCheck",
+			Handler:    _TrillianAdmin_GetTreeIntegrityCheck_Handler,
+		},
+		{
+			MethodName: "GetMergeDelayCompliance",
+			Handler:    _TrillianAdmin_GetMergeDelayCompliance_Handler,
+		},
+		{
+			MethodName: "BatchGetTreeStatus",
+			Handler:    _TrillianAdmin_BatchGetTreeStatus_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "trillian_admin_api.proto",
@@ -447,40 +1516,126 @@ var _TrillianAdmin_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("trillian_admin_api.proto", fileDescriptor2) }
 
 var fileDescriptor2 = []byte{
-	// 548 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x54, 0xe1, 0x6a, 0x13, 0x4d,
-	0x14, 0xfd, 0xb6, 0x2d, 0x6d, 0xbe, 0x9b, 0x1a, 0xcc, 0x94, 0x62, 0xba, 0x56, 0x8c, 0xa3, 0x42,
-	0x8d, 0xb2, 0x6b, 0x23, 0x22, 0x54, 0xfc, 0xd1, 0x2a, 0x15, 0x41, 0x21, 0xac, 0x29, 0x82, 0x20,
-	0xcb, 0x66, 0xf7, 0x26, 0x1d, 0x93, 0xec, 0xac, 0x3b, 0x13, 0x25, 0x88, 0x7f, 0x7c, 0x05, 0x5f,
-	0xc6, 0xf7, 0xf0, 0x15, 0x7c, 0x10, 0x99, 0xd9, 0xd9, 0xee, 0xa6, 0xdb, 0x48, 0xf1, 0xd7, 0xee,
-	0xdc, 0x73, 0xef, 0x3d, 0x77, 0xce, 0xb9, 0x0c, 0xb4, 0x64, 0xca, 0x26, 0x13, 0x16, 0xc4, 0x7e,
-	0x10, 0x4d, 0x59, 0xec, 0x07, 0x09, 0x73, 0x92, 0x94, 0x4b, 0x4e, 0x6a, 0x39, 0x62, 0x37, 0xf2,
-	0xbf, 0x0c, 0xb1, 0xed, 0x30, 0x9d, 0x27, 0x92, 0xbb, 0x63, 0x9c, 0x8b, 0x64, 0x60, 0x3e, 0x06,
-	0xdb, 0x1d, 0x71, 0x3e, 0x9a, 0xa0, 0x1b, 0x24, 0xcc, 0x0d, 0xe2, 0x98, 0xcb, 0x40, 0x32, 0x1e,
-	0x0b, 0x83, 0xb6, 0x0d, 0xaa, 0x4f, 0x83, 0xd9, 0xd0, 0x1d, 0x32, 0x9c, 0x44, 0xfe, 0x34, 0x10,
-	0xe3, 0x2c, 0x83, 0x3e, 0x86, 0xab, 0xaf, 0x99, 0x90, 0xfd, 0x14, 0x51, 0x78, 0xf8, 0x69, 0x86,
-	0x42, 0x92, 0x5b, 0xb0, 0x29, 0x4e, 0xf9, 0x17, 0x3f, 0xc2, 0x09, 0x4a, 0x8c, 0x5a, 0x56, 0xdb,
-	0xda, 0xab, 0x79, 0x75, 0x15, 0x7b, 0x91, 0x85, 0xe8, 0x13, 0x68, 0x96, 0xca, 0x44, 0xc2, 0x63,
-	0x81, 0x84, 0xc2, 0x9a, 0x4c, 0x11, 0x5b, 0x56, 0x7b, 0x75, 0xaf, 0xde, 0x6d, 0x38, 0x67, 0xd7,
-	0x50, 0x69, 0x9e, 0xc6, 0xe8, 0x3d, 0x68, 0xbc, 0x44, 0x5d, 0x97, 0xb3, 0x5d, 0x83, 0x0d, 0x85,
-	0xf8, 0x2c, 0x23, 0x5a, 0xf5, 0xd6, 0xd5, 0xf1, 0x55, 0x44, 0x19, 0x34, 0x9f, 0xa7, 0x18, 0x48,
-	0x2c, 0x67, 0x17, 0x1c, 0xd6, 0x32, 0x0e, 0xf2, 0x10, 0x6a, 0x63, 0x9c, 0xfb, 0x22, 0xc1, 0xb0,
-	0xb5, 0xa2, 0xf3, 0xb6, 0x1d, 0x23, 0xda, 0xdb, 0x04, 0x43, 0x36, 0x64, 0xa1, 0x56, 0xc9, 0xdb,
-	0x18, 0xe3, 0x5c, 0x45, 0xa8, 0x84, 0xe6, 0x49, 0x12, 0xfd, 0x03, 0xd5, 0x53, 0xa8, 0xcf, 0x74,
-	0xa1, 0xd6, 0xd4, 0xb0, 0xd9, 0x4e, 0x26, 0xbb, 0x93, 0xcb, 0xee, 0x1c, 0x2b, 0xd9, 0xdf, 0x04,
-	0x62, 0xec, 0x41, 0x96, 0xae, 0xfe, 0xe9, 0x03, 0x68, 0x66, 0x7a, 0x5e, 0x4a, 0x0e, 0x07, 0xb6,
-	0x4e, 0xe2, 0xe8, 0xd2, 0xf9, 0xdd, 0x9f, 0x6b, 0x70, 0xa5, 0x6f, 0x46, 0x3e, 0x54, 0xbb, 0x46,
-	0x8e, 0xe1, 0xff, 0x33, 0xd3, 0x88, 0x5d, 0xdc, 0xe7, 0xfc, 0x02, 0xd8, 0xd7, 0x2f, 0xc4, 0x32,
-	0x97, 0xe9, 0x7f, 0xe4, 0x1d, 0x6c, 0x18, 0x0f, 0x49, 0xab, 0xc8, 0x5c, 0xb4, 0xd5, 0x3e, 0xa7,
-	0x17, 0xa5, 0xdf, 0x7f, 0xfd, 0xfe, 0xb1, 0xb2, 0x4b, 0x6c, 0xf7, 0xf3, 0xfe, 0x00, 0x65, 0xb0,
-	0xef, 0xaa, 0x39, 0x85, 0xfb, 0xd5, 0x4c, 0xff, 0xac, 0xf3, 0x8d, 0xf4, 0x01, 0x0a, 0xc7, 0x49,
-	0x69, 0x8a, 0xca, 0x1e, 0x54, 0xda, 0xef, 0xe8, 0xf6, 0x5b, 0xb4, 0xb1, 0xd8, 0xfe, 0xc0, 0xea,
-	0x10, 0x04, 0x28, 0xcc, 0x2d, 0x77, 0xad, 0x58, 0x5e, 0xe9, 0xda, 0xd1, 0x5d, 0xef, 0x74, 0x6f,
-	0x5e, 0x34, 0xb4, 0x53, 0x4c, 0xae, 0x68, 0x3e, 0x00, 0x14, 0x6e, 0x96, 0x69, 0x2a, 0x1e, 0x2f,
-	0xd3, 0xa6, 0xf3, 0x37, 0x6d, 0x3e, 0xc2, 0x66, 0xd9, 0x7e, 0x72, 0xa3, 0x74, 0x8f, 0xea, 0x5a,
-	0x54, 0x28, 0xee, 0x6b, 0x8a, 0xbb, 0x9d, 0xdb, 0xcb, 0x29, 0x0e, 0x66, 0xa6, 0xcf, 0x51, 0x0f,
-	0x76, 0x42, 0x3e, 0xcd, 0xb7, 0x78, 0xf1, 0x35, 0x3a, 0xda, 0x5e, 0x58, 0xaa, 0xc3, 0x84, 0xf5,
-	0x54, 0xb8, 0x67, 0xbd, 0xb7, 0x47, 0x4c, 0x9e, 0xce, 0x06, 0x4e, 0xc8, 0xa7, 0xae, 0x79, 0x77,
-	0xf2, 0xd2, 0xc1, 0xba, 0xae, 0x7d, 0xf4, 0x27, 0x00, 0x00, 0xff, 0xff, 0x58, 0xc1, 0xa5, 0x85,
-	0xff, 0x04, 0x00, 0x00,
+	// 1929 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x58, 0xcd, 0x6e, 0x1b, 0xc9,
+	0x11, 0xde, 0x21, 0x25, 0x92, 0x2a, 0x4a, 0x14, 0xd5, 0x5a, 0x5b, 0x24, 0x2d, 0xdb, 0xf2, 0xf8,
+	0x27, 0x8a, 0x62, 0x90, 0xbb, 0xdc, 0x5d, 0x18, 0xf1, 0xc2, 0x08, 0x28, 0x4a, 0xd6, 0x0a, 0x2b,
+	0xcb, 0xf2, 0x90, 0x86, 0x91, 0x20, 0xc9, 0xa4, 0xc9, 0x69, 0x91, 0x13, 0x0e, 0x67, 0xc6, 0xd3,
+	0x4d, 0x59, 0x74, 0x10, 0x20, 0xc8, 0x21, 0x97, 0x20, 0xa7, 0x9c, 0x82, 0xbc, 0x41, 0x82, 0x5c,
+	0xf3, 0x06, 0xb9, 0x05, 0xb9, 0xe4, 0x96, 0x73, 0x90, 0xe7, 0x08, 0xfa, 0x67, 0xc8, 0x21, 0x39,
+	0x14, 0xb5, 0xba, 0xd8, 0xec, 0xaa, 0xaf, 0x7e, 0xba, 0xaa, 0xa6, 0xba, 0x4a, 0x50, 0x60, 0x81,
+	0xed, 0x38, 0x36, 0x76, 0x4d, 0x6c, 0xf5, 0x6d, 0xd7, 0xc4, 0xbe, 0x5d, 0xf6, 0x03, 0x8f, 0x79,
+	0x28, 0x13, 0x72, 0x4a, 0xb9, 0xf0, 0x97, 0xe4, 0x94, 0x4a, 0xed, 0x60, 0xe8, 0x33, 0xaf, 0xd2,
+	0x23, 0x43, 0xea, 0xb7, 0xd4, 0x7f, 0x8a, 0x57, 0x50, 0x3c, 0x6a, 0x77, 0xfc, 0x96, 0xfc, 0x57,
+	0x71, 0xb6, 0x3b, 0x9e, 0xd7, 0x71, 0x48, 0x05, 0xfb, 0x76, 0x05, 0xbb, 0xae, 0xc7, 0x30, 0xb3,
+	0x3d, 0x97, 0x2a, 0xee, 0x3d, 0xc5, 0x15, 0xa7, 0xd6, 0xe0, 0xbc, 0x62, 0x0d, 0x02, 0x01, 0x50,
+	0xfc, 0x9d, 0x69, 0xfe, 0xb9, 0x4d, 0x1c, 0xcb, 0xec, 0x63, 0xda, 0x53, 0x88, 0xfb, 0xd3, 0x08,
+	0x66, 0xf7, 0x09, 0x65, 0xb8, 0xef, 0x2b, 0xc0, 0x96, 0x02, 0x04, 0x7e, 0xbb, 0x42, 0x19, 0x66,
+	0x03, 0x65, 0x5b, 0xff, 0x29, 0xe4, 0x4f, 0x6c, 0xca, 0x9a, 0x01, 0x21, 0xd4, 0x20, 0xef, 0x07,
+	0x84, 0x32, 0xf4, 0x00, 0x56, 0x69, 0xd7, 0xfb, 0x60, 0x5a, 0xc4, 0x21, 0x8c, 0x58, 0x05, 0x6d,
+	0x47, 0xdb, 0xcd, 0x18, 0x59, 0x4e, 0x3b, 0x90, 0x24, 0xf4, 0x18, 0x72, 0x0e, 0x6e, 0x11, 0xc7,
+	0xa4, 0xc4, 0x21, 0x6d, 0xe6, 0x05, 0x85, 0xc4, 0x8e, 0xb6, 0xbb, 0x62, 0xac, 0x09, 0x6a, 0x43,
+	0x11, 0xf5, 0x67, 0xb0, 0x11, 0xd1, 0x4e, 0x7d, 0xcf, 0xa5, 0x04, 0xe9, 0xb0, 0xc4, 0x02, 0x42,
+	0x0a, 0xda, 0x4e, 0x72, 0x37, 0x5b, 0xcd, 0x95, 0x47, 0x11, 0xe6, 0x30, 0x43, 0xf0, 0xf4, 0x33,
+	0xc8, 0x1d, 0x11, 0x21, 0x17, 0x3a, 0xb5, 0x05, 0x69, 0xce, 0x31, 0x6d, 0xe9, 0x4f, 0xd2, 0x48,
+	0xf1, 0xe3, 0xb1, 0x70, 0xc5, 0x76, 0xdb, 0xce, 0xc0, 0x22, 0xa6, 0xbc, 0x99, 0x70, 0x25, 0x63,
+	0xac, 0x29, 0x6a, 0x43, 0x10, 0xf5, 0xff, 0x68, 0xb0, 0x51, 0x0f, 0x08, 0x66, 0x24, 0xaa, 0x75,
+	0xec, 0x8b, 0x36, 0xcf, 0x17, 0xf4, 0x19, 0x64, 0x7a, 0x64, 0x68, 0x52, 0x9f, 0xb4, 0x85, 0xea,
+	0x6c, 0xf5, 0x56, 0x59, 0xe5, 0xbd, 0xe1, 0x93, 0xb6, 0x7d, 0x6e, 0xb7, 0x45, 0xb6, 0x8c, 0x74,
+	0x8f, 0x0c, 0x39, 0x05, 0x9d, 0xc2, 0x16, 0x97, 0x68, 0x77, 0xb1, 0xe3, 0x10, 0xb7, 0x43, 0x4c,
+	0x6a, 0x77, 0x5c, 0xcc, 0x06, 0x01, 0x29, 0x24, 0x85, 0x82, 0xdb, 0x65, 0x59, 0x1d, 0x07, 0x76,
+	0xc7, 0x66, 0xd8, 0x71, 0x86, 0x0d, 0xbb, 0xe3, 0x12, 0xcb, 0xb8, 0xd5, 0x23, 0xc3, 0x7a, 0x28,
+	0xd5, 0x08, 0x85, 0xd0, 0x7d, 0xc8, 0x92, 0x4b, 0x46, 0x02, 0x17, 0x3b, 0xfc, 0xfe, 0x4b, 0x22,
+	0xd4, 0x10, 0x92, 0x8e, 0x2d, 0x9d, 0xc1, 0xc6, 0x5b, 0xdf, 0xba, 0xc1, 0xdd, 0xbe, 0x86, 0xec,
+	0x40, 0x08, 0x8a, 0x6a, 0x52, 0xd7, 0x2b, 0x95, 0x65, 0xb5, 0x94, 0xc3, 0x72, 0x2a, 0xbf, 0xe4,
+	0x05, 0xf7, 0x0a, 0xd3, 0x9e, 0x01, 0x12, 0xce, 0x7f, 0xeb, 0x4f, 0x61, 0x43, 0xd6, 0xc3, 0x75,
+	0xf2, 0xa4, 0x97, 0x61, 0xf3, 0xad, 0x6b, 0x7d, 0x27, 0xbc, 0x2a, 0x01, 0x9e, 0x41, 0xba, 0x10,
+	0xff, 0x3f, 0x0d, 0x56, 0x46, 0xe8, 0xf9, 0xe5, 0x72, 0x17, 0xc0, 0x21, 0xf8, 0xdc, 0x6c, 0x7b,
+	0x03, 0x97, 0x89, 0x0b, 0x27, 0x8d, 0x15, 0x4e, 0xa9, 0x73, 0xc2, 0x88, 0xdd, 0x1a, 0x32, 0x42,
+	0x0b, 0xc9, 0x31, 0x7b, 0x9f, 0x13, 0xd0, 0x43, 0x58, 0xa3, 0x83, 0x96, 0xd0, 0x2c, 0x15, 0x2c,
+	0x09, 0xc4, 0xaa, 0x22, 0x4a, 0x1d, 0x8f, 0x21, 0x17, 0x90, 0x0b, 0x9b, 0xda, 0x9e, 0xab, 0x50,
+	0xcb, 0x02, 0xb5, 0x16, 0x52, 0x25, 0xac, 0x08, 0x99, 0x80, 0x60, 0xcb, 0x7c, 0xef, 0xd3, 0x42,
+	0x6a, 0x47, 0xdb, 0xd5, 0x8c, 0x34, 0x3f, 0xbf, 0xf1, 0x29, 0xba, 0x03, 0x2b, 0x1f, 0x02, 0x9b,
+	0x11, 0xc1, 0x4b, 0x0b, 0x5e, 0x46, 0x10, 0xde, 0xf8, 0x54, 0xff, 0x39, 0x6c, 0x1d, 0xbb, 0xbc,
+	0x1a, 0xd9, 0x09, 0xc1, 0xe7, 0x6f, 0x06, 0x64, 0xb0, 0xf8, 0x23, 0xd9, 0x83, 0x0d, 0xcf, 0xb1,
+	0x08, 0x65, 0xe6, 0xd4, 0xe5, 0x97, 0x8d, 0x75, 0xc9, 0x38, 0x09, 0x43, 0xa0, 0xff, 0x43, 0x83,
+	0xdc, 0x48, 0xf3, 0xa1, 0xcb, 0x82, 0x21, 0x7a, 0x0a, 0x48, 0xc8, 0xd9, 0x16, 0x71, 0x99, 0xcd,
+	0x86, 0x66, 0x17, 0xd3, 0xae, 0x30, 0xb1, 0x6a, 0xe4, 0x39, 0xe7, 0x58, 0x31, 0xbe, 0xc1, 0xb4,
+	0x8b, 0x76, 0x21, 0xdf, 0x27, 0x41, 0xcf, 0x21, 0xd2, 0x98, 0xc0, 0x26, 0x04, 0x36, 0x27, 0xe9,
+	0x5c, 0xbb, 0x40, 0xd6, 0x61, 0xfd, 0x3d, 0xb7, 0x62, 0x8e, 0xfa, 0x55, 0x21, 0x39, 0xa7, 0x04,
+	0x9b, 0x21, 0xc2, 0xc8, 0x09, 0x91, 0xd1, 0x19, 0xdd, 0x86, 0x54, 0x6b, 0xd0, 0xee, 0x91, 0x30,
+	0x19, 0xea, 0xa4, 0xff, 0x59, 0x03, 0x34, 0xba, 0x47, 0xad, 0x43, 0xf6, 0x05, 0x19, 0x55, 0x21,
+	0x2d, 0x9a, 0x7d, 0x27, 0xfc, 0x32, 0x8a, 0x33, 0xb6, 0x0e, 0x54, 0xff, 0x35, 0x52, 0x7d, 0xdb,
+	0xad, 0x75, 0x88, 0x90, 0xc1, 0x97, 0x42, 0x26, 0xb1, 0x58, 0x06, 0x5f, 0x72, 0x99, 0xc9, 0x42,
+	0x4b, 0x4e, 0x15, 0x9a, 0xfe, 0xa7, 0x68, 0x94, 0x1b, 0x5d, 0x1c, 0x58, 0x91, 0x8b, 0x68, 0xd1,
+	0x8b, 0x2c, 0x2a, 0xd9, 0x33, 0xb8, 0xad, 0x72, 0xfb, 0xdd, 0x63, 0xf9, 0xa9, 0x94, 0x7c, 0x33,
+	0x11, 0x51, 0xfd, 0xef, 0x09, 0x58, 0x1f, 0xfb, 0x86, 0xfb, 0xbe, 0x43, 0xe6, 0x97, 0xd6, 0xd7,
+	0x90, 0xa5, 0x02, 0x22, 0x0c, 0xcf, 0x6d, 0x21, 0x63, 0x9b, 0x20, 0xe1, 0x9c, 0xb0, 0x20, 0x48,
+	0xe8, 0x05, 0xac, 0x8d, 0xcb, 0xf6, 0x82, 0xd0, 0xc2, 0x92, 0x78, 0x33, 0x0a, 0xe3, 0x5e, 0x36,
+	0x59, 0xa8, 0xc6, 0xea, 0xa8, 0x98, 0x2f, 0x08, 0x45, 0x2f, 0x20, 0x8b, 0x3b, 0xc4, 0x94, 0x61,
+	0xa4, 0x85, 0x65, 0x21, 0xbc, 0x1d, 0x23, 0x3c, 0xaa, 0x0e, 0x03, 0x70, 0xf8, 0x93, 0xa2, 0xcf,
+	0x20, 0x45, 0x79, 0x62, 0xf8, 0xe7, 0x39, 0xcf, 0xac, 0xc8, 0x9c, 0xa1, 0x70, 0xfa, 0x19, 0x14,
+	0xeb, 0x5d, 0xd2, 0xee, 0x35, 0x79, 0x68, 0x5c, 0x46, 0x3a, 0x81, 0xcd, 0x86, 0x0b, 0x3f, 0xce,
+	0x12, 0x64, 0xc2, 0xce, 0xa0, 0xb2, 0x3b, 0x3a, 0xeb, 0xcf, 0x60, 0x5b, 0x75, 0xc1, 0x91, 0x3e,
+	0x61, 0x61, 0x61, 0x3b, 0xfc, 0x57, 0x02, 0xd0, 0xac, 0xd8, 0x8d, 0x9c, 0xe0, 0xed, 0x48, 0x08,
+	0x51, 0xfb, 0x23, 0x51, 0x49, 0xca, 0x70, 0x42, 0xc3, 0xfe, 0x48, 0xd0, 0x17, 0xb0, 0xcc, 0xdf,
+	0x5d, 0x22, 0xbe, 0xbe, 0x5c, 0xf5, 0xee, 0x38, 0x48, 0x93, 0xa6, 0x79, 0x5f, 0x26, 0x86, 0xc4,
+	0x8a, 0xf9, 0x41, 0xe4, 0xc8, 0x6c, 0x73, 0x1e, 0xb1, 0xc2, 0x16, 0x29, 0xa9, 0x75, 0x49, 0x44,
+	0x05, 0x48, 0x9f, 0x63, 0xdb, 0xe1, 0x0f, 0x67, 0x4a, 0x3c, 0x7a, 0xe1, 0x11, 0xfd, 0x10, 0x80,
+	0x32, 0x1c, 0x30, 0x59, 0x74, 0xe9, 0x85, 0x45, 0xb7, 0x22, 0xd0, 0xa2, 0xe6, 0xbe, 0x82, 0x0c,
+	0x71, 0x2d, 0x29, 0x98, 0x59, 0x28, 0x98, 0x26, 0xae, 0xc5, 0x4f, 0xfa, 0x5f, 0x35, 0xb8, 0x77,
+	0x44, 0xd8, 0x2b, 0x12, 0x74, 0xc8, 0x01, 0x71, 0xf0, 0xb0, 0xee, 0xf5, 0x7d, 0x7e, 0xcf, 0xf6,
+	0xe2, 0xf6, 0x3b, 0xe9, 0x6d, 0xe2, 0xa6, 0xde, 0x26, 0xaf, 0xef, 0xed, 0x6f, 0x92, 0x90, 0x1f,
+	0xbb, 0xfa, 0xce, 0x76, 0x2d, 0xef, 0xc3, 0x94, 0x1b, 0xda, 0x4d, 0xdd, 0x48, 0x5c, 0xdb, 0x0d,
+	0x54, 0x83, 0x75, 0xde, 0x38, 0xfb, 0xdc, 0x13, 0x3e, 0x4f, 0xe2, 0x61, 0x21, 0xb9, 0xa8, 0x81,
+	0xae, 0xf5, 0xf1, 0xe5, 0xd8, 0xf5, 0xa9, 0x16, 0xb1, 0x34, 0xdd, 0x22, 0xbe, 0x07, 0xeb, 0x17,
+	0xb6, 0xe7, 0x08, 0xd1, 0x89, 0xd7, 0x36, 0x37, 0x22, 0x4b, 0xe0, 0x13, 0x58, 0x77, 0x09, 0x0e,
+	0xcc, 0xbe, 0x4d, 0xa9, 0x02, 0xa6, 0x64, 0xcd, 0x71, 0xf2, 0x2b, 0x9b, 0x52, 0x89, 0x3b, 0x02,
+	0xc4, 0x5d, 0xf6, 0x5a, 0x94, 0x04, 0x17, 0xc4, 0x52, 0x5e, 0xa7, 0x17, 0x79, 0x9d, 0xef, 0xe3,
+	0xcb, 0xd7, 0x4a, 0x46, 0x38, 0xae, 0x13, 0xf8, 0x34, 0xae, 0x58, 0xe6, 0x57, 0xc9, 0x97, 0x90,
+	0xfe, 0x20, 0x12, 0xc5, 0x47, 0xd8, 0xa4, 0x08, 0xf1, 0xe8, 0x5b, 0x9a, 0xce, 0xa5, 0x11, 0x42,
+	0x75, 0x0f, 0x8a, 0xfb, 0x98, 0xb5, 0xbb, 0x91, 0x61, 0x69, 0x30, 0x9a, 0x96, 0x8a, 0x90, 0x51,
+	0xb6, 0xa8, 0x98, 0xb7, 0x93, 0x46, 0x5a, 0x1a, 0xa3, 0xe8, 0x19, 0xac, 0x88, 0xf1, 0xe3, 0x9a,
+	0x83, 0x9f, 0x98, 0x55, 0xf8, 0x2f, 0xfd, 0x6f, 0x09, 0x58, 0x1f, 0x5b, 0x92, 0x03, 0xc2, 0x15,
+	0x83, 0x47, 0x2a, 0x32, 0x95, 0x67, 0xab, 0x28, 0x34, 0x11, 0xf8, 0xed, 0xb2, 0xf2, 0x55, 0x21,
+	0x50, 0x15, 0x40, 0xb6, 0x19, 0xd1, 0x4e, 0x92, 0xa2, 0x9d, 0x6c, 0x4e, 0x8e, 0xad, 0xb2, 0x89,
+	0xac, 0xb0, 0xf0, 0x27, 0xfa, 0x11, 0xac, 0x3b, 0x98, 0x89, 0x17, 0xc2, 0xeb, 0x98, 0x81, 0xe7,
+	0xc9, 0x12, 0xc9, 0x56, 0xb7, 0xc6, 0x82, 0x72, 0xb8, 0x3e, 0xf1, 0x3a, 0x86, 0xe7, 0x31, 0x63,
+	0x4d, 0xe2, 0xd5, 0x31, 0xa2, 0xa0, 0x8f, 0x7d, 0xa9, 0x60, 0x39, 0x5e, 0xc1, 0x2b, 0xec, 0x47,
+	0x15, 0xa8, 0x23, 0x1f, 0xce, 0xe5, 0xbb, 0x6b, 0x11, 0x9f, 0x75, 0x55, 0x4d, 0x81, 0x20, 0x1d,
+	0x70, 0x8a, 0xde, 0x80, 0x52, 0x5c, 0x82, 0xd4, 0x36, 0xf4, 0x15, 0x64, 0xe4, 0xf5, 0x09, 0x55,
+	0x1b, 0x51, 0x71, 0xf6, 0xca, 0x2a, 0xcc, 0xc6, 0x08, 0xaa, 0xff, 0x02, 0x4a, 0x4a, 0xdf, 0xfe,
+	0xf0, 0x70, 0xb4, 0x08, 0x84, 0x69, 0x9f, 0x5a, 0x18, 0xb4, 0xe9, 0x85, 0x61, 0x66, 0xc5, 0x4b,
+	0xcc, 0xac, 0x78, 0x7b, 0x7f, 0xd1, 0x60, 0x33, 0xa6, 0x83, 0xa3, 0x07, 0x70, 0xf7, 0xed, 0xe9,
+	0xb7, 0xa7, 0xaf, 0xdf, 0x9d, 0x9a, 0xc7, 0xa7, 0xcd, 0xc3, 0x23, 0xe3, 0xb8, 0xf9, 0x63, 0xb3,
+	0xfe, 0xcd, 0x61, 0xfd, 0x5b, 0xb3, 0xd1, 0xac, 0x35, 0x0f, 0xf3, 0x9f, 0xa0, 0x3b, 0xb0, 0x35,
+	0xcd, 0x32, 0xde, 0x9e, 0x9e, 0x1e, 0x9f, 0x1e, 0xe5, 0x35, 0x54, 0x82, 0xdb, 0xd3, 0xcc, 0xb3,
+	0x5a, 0xa3, 0x71, 0x78, 0x90, 0x4f, 0xc4, 0xf1, 0x5e, 0xd6, 0x8e, 0x4f, 0x0e, 0x0f, 0xf2, 0xc9,
+	0x38, 0xa5, 0xb5, 0xfd, 0xd7, 0x46, 0xf3, 0xf0, 0x20, 0xbf, 0x54, 0xfd, 0x67, 0x16, 0xd6, 0x9a,
+	0x2a, 0x6a, 0x35, 0xbe, 0xcc, 0xa3, 0x97, 0xb0, 0x32, 0x5a, 0x3d, 0x51, 0xe4, 0x43, 0x9a, 0xde,
+	0x76, 0x4b, 0x77, 0x62, 0x79, 0x32, 0x3b, 0xfa, 0x27, 0xe8, 0x1d, 0xa4, 0x55, 0xa0, 0x51, 0xe4,
+	0xfd, 0x9f, 0x5c, 0x4e, 0x4b, 0x53, 0xcb, 0x95, 0xae, 0xff, 0xf6, 0xdf, 0xff, 0xfd, 0x63, 0x62,
+	0x1b, 0x95, 0x2a, 0x17, 0x9f, 0xb7, 0x08, 0xc3, 0x9f, 0x57, 0x18, 0x57, 0x5b, 0xf9, 0x95, 0xfa,
+	0x48, 0x5e, 0xec, 0xfd, 0x1a, 0x35, 0x01, 0xc6, 0xfb, 0x28, 0x8a, 0x78, 0x31, 0xb3, 0xa5, 0xce,
+	0xa8, 0x2f, 0x0a, 0xf5, 0x9b, 0x7a, 0x6e, 0x52, 0xfd, 0x73, 0x6d, 0x0f, 0x51, 0xd8, 0x8c, 0xa9,
+	0x0b, 0xf4, 0x68, 0xc6, 0xf5, 0x98, 0xb2, 0x99, 0xb1, 0xf3, 0x44, 0xd8, 0xd9, 0x41, 0xf7, 0xa6,
+	0xec, 0x74, 0x08, 0x9b, 0xd0, 0x4e, 0x00, 0xc6, 0xeb, 0x67, 0xf4, 0x2a, 0x33, 0x4b, 0xe9, 0x8c,
+	0x89, 0x3d, 0x61, 0xe2, 0x51, 0xf5, 0x7e, 0x5c, 0xa4, 0xca, 0xe3, 0x70, 0xf1, 0xbb, 0xfd, 0x0c,
+	0x60, 0xbc, 0x6f, 0x46, 0xcd, 0xcc, 0x6c, 0xa1, 0xf3, 0x12, 0xb2, 0x77, 0x55, 0x42, 0x7e, 0x09,
+	0xab, 0xd1, 0x05, 0x15, 0x45, 0x26, 0x99, 0x98, 0xc5, 0x75, 0xc6, 0xc4, 0x0f, 0x84, 0x89, 0xc7,
+	0x7b, 0x0f, 0xe7, 0x9b, 0x78, 0x3e, 0x50, 0x7a, 0x90, 0x03, 0xab, 0xd1, 0xe5, 0x36, 0x6a, 0x2b,
+	0x66, 0xe9, 0x2d, 0xc5, 0x74, 0x41, 0xaa, 0xef, 0x0a, 0x83, 0x3a, 0xda, 0x99, 0x6f, 0x50, 0xfc,
+	0xad, 0x87, 0xa2, 0x8f, 0x90, 0x9f, 0xde, 0x18, 0xd1, 0x83, 0xe8, 0x9c, 0x16, 0xbb, 0x4d, 0x96,
+	0x8a, 0x71, 0xf3, 0xae, 0x98, 0xdd, 0xaf, 0x65, 0x5b, 0xb4, 0x40, 0xf4, 0x07, 0x0d, 0xd0, 0xec,
+	0x4c, 0x8c, 0x1e, 0x46, 0xea, 0x7d, 0xde, 0xc4, 0x5c, 0xda, 0x9e, 0xbc, 0xf6, 0x64, 0x37, 0xd2,
+	0xbf, 0x14, 0x3e, 0x94, 0x9f, 0x6b, 0x7b, 0xfa, 0xf7, 0xaf, 0x88, 0xb9, 0x18, 0x30, 0xc7, 0x86,
+	0x7f, 0xaf, 0xc1, 0xad, 0xd8, 0x89, 0x1a, 0x3d, 0x99, 0xc9, 0x41, 0xec, 0xc8, 0xbd, 0xc0, 0xab,
+	0xa7, 0xc2, 0xab, 0x27, 0xe8, 0xd1, 0x15, 0x91, 0xb1, 0xa3, 0xde, 0x6c, 0xcd, 0x19, 0x2a, 0xd1,
+	0xee, 0x84, 0x3f, 0x57, 0xcc, 0x9d, 0xa5, 0x7b, 0x71, 0x73, 0xc2, 0x18, 0x36, 0xef, 0x3b, 0x9e,
+	0xf0, 0xa9, 0xdf, 0xb7, 0xd0, 0xef, 0x34, 0x40, 0xb3, 0x4f, 0x55, 0x34, 0x57, 0x73, 0x27, 0x8d,
+	0xd2, 0xa3, 0xab, 0x41, 0xaa, 0x9f, 0x3e, 0x16, 0x9e, 0xdc, 0x47, 0x77, 0xa7, 0x3a, 0x4a, 0x4b,
+	0x89, 0x48, 0xf8, 0xfe, 0x19, 0x14, 0xdb, 0x5e, 0x3f, 0x1c, 0x15, 0x26, 0xff, 0x04, 0xbb, 0x7f,
+	0x6b, 0xa2, 0xd1, 0xd7, 0x7c, 0xfb, 0x8c, 0x93, 0xcf, 0xb4, 0x9f, 0x94, 0x3a, 0x36, 0xeb, 0x0e,
+	0x5a, 0xe5, 0xb6, 0xd7, 0xaf, 0x48, 0xd1, 0x4a, 0x28, 0xda, 0x4a, 0x09, 0xd9, 0x2f, 0xfe, 0x3f,
+	0x00, 0x6c, 0x0a, 0xab, 0x79, 0xf4, 0x15, 0x00, 0x00,
 }
@@ -21,6 +21,14 @@ It has these top-level messages:
 	GetInclusionProofByHashResponse
 	GetConsistencyProofRequest
 	GetConsistencyProofResponse
+	GetConsistencyProofChainRequest
+	ConsistencyProofStep
+	GetConsistencyProofChainResponse
+	AddObservedRootRequest
+	AddObservedRootResponse
+	ObservedRoot
+	GetObservedRootsRequest
+	GetObservedRootsResponse
 	GetLatestSignedLogRootRequest
 	GetLatestSignedLogRootResponse
 	GetSequencedLeafCountRequest
@@ -33,15 +41,25 @@ It has these top-level messages:
 	QueueLeavesResponse
 	AddSequencedLeavesRequest
 	AddSequencedLeavesResponse
+	AddSequencedLeafRangeRequest
+	AddSequencedLeafRangeResponse
+	GetLeafIndicesByKeyRequest
+	GetLeafIndicesByKeyResponse
+	CheckLeafFilterRequest
+	CheckLeafFilterResponse
 	GetLeavesByIndexRequest
 	GetLeavesByIndexResponse
 	GetLeavesByRangeRequest
 	GetLeavesByRangeResponse
 	GetLeavesByHashRequest
 	GetLeavesByHashResponse
+	GetEntriesAndProofsRequest
+	GetEntriesAndProofsResponse
 	QueuedLogLeaf
 	LogLeaf
 	Proof
+	ProofEnvelope
+	SignedProofEnvelope
 	MapLeaf
 	MapLeafInclusion
 	GetMapLeavesRequest
@@ -52,6 +70,9 @@ It has these top-level messages:
 	GetSignedMapRootRequest
 	GetSignedMapRootByRevisionRequest
 	GetSignedMapRootResponse
+	GetMapLeafHistoryRequest
+	MapLeafHistoryEntry
+	GetMapLeafHistoryResponse
 	InitMapRequest
 	InitMapResponse
 	ListTreesRequest
@@ -62,16 +83,26 @@ It has these top-level messages:
 	DeleteTreeRequest
 	UndeleteTreeRequest
 	Tree
+	TreeStatus
+	SigningKey
+	MaintenanceMode
+	LeafIndexSpec
+	LeafFilterSpec
 	SignedEntryTimestamp
 	SignedLogRoot
 	SignedMapRoot
+	SourceLogRoot
+	LogRootMetadata
+	LogShard
 */
 package trillian
 
 import proto "github.com/golang/protobuf/proto"
 import fmt "fmt"
 import math "math"
+import sigpb "github.com/google/trillian/crypto/sigpb"
 import _ "google.golang.org/genproto/googleapis/api/annotations"
+import google_protobuf2 "github.com/golang/protobuf/ptypes/any"
 import google_protobuf1 "github.com/golang/protobuf/ptypes/timestamp"
 import google_rpc "google.golang.org/genproto/googleapis/rpc/status"
 
@@ -326,6 +357,236 @@ func (m *GetConsistencyProofResponse) GetProof() *Proof {
 	return nil
 }
 
+type GetConsistencyProofChainRequest struct {
+	LogId         int64 `protobuf:"varint,1,opt,name=log_id,json=logId" json:"log_id,omitempty"`
+	FirstTreeSize int64 `protobuf:"varint,2,opt,name=first_tree_size,json=firstTreeSize" json:"first_tree_size,omitempty"`
+}
+
+func (m *GetConsistencyProofChainRequest) Reset()         { *m = GetConsistencyProofChainRequest{} }
+func (m *GetConsistencyProofChainRequest) String() string { return proto.CompactTextString(m) }
+func (*GetConsistencyProofChainRequest) ProtoMessage()    {}
+func (*GetConsistencyProofChainRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor0, []int{10}
+}
+
+func (m *GetConsistencyProofChainRequest) GetLogId() int64 {
+	if m != nil {
+		return m.LogId
+	}
+	return 0
+}
+
+func (m *GetConsistencyProofChainRequest) GetFirstTreeSize() int64 {
+	if m != nil {
+		return m.FirstTreeSize
+	}
+	return 0
+}
+
+// ConsistencyProofStep is a step of a chain of consistency proofs.
+type ConsistencyProofStep struct {
+	// The size of the tree at the end of the step.
+	TreeSize int64 `protobuf:"varint,1,opt,name=tree_size,json=treeSize" json:"tree_size,omitempty"`
+	// The root hash of the tree at the end of the step.
+	RootHash []byte `protobuf:"bytes,2,opt,name=root_hash,json=rootHash,proto3" json:"root_hash,omitempty"`
+	// The proof of consistency between the tree at the end of the previous
+	// step, or at the chain's first size, and the tree at the end of this one.
+	Proof *Proof `protobuf:"bytes,3,opt,name=proof" json:"proof,omitempty"`
+}
+
+func (m *ConsistencyProofStep) Reset()                    { *m = ConsistencyProofStep{} }
+func (m *ConsistencyProofStep) String() string            { return proto.CompactTextString(m) }
+func (*ConsistencyProofStep) ProtoMessage()               {}
+func (*ConsistencyProofStep) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+func (m *ConsistencyProofStep) GetTreeSize() int64 {
+	if m != nil {
+		return m.TreeSize
+	}
+	return 0
+}
+
+func (m *ConsistencyProofStep) GetRootHash() []byte {
+	if m != nil {
+		return m.RootHash
+	}
+	return nil
+}
+
+func (m *ConsistencyProofStep) GetProof() *Proof {
+	if m != nil {
+		return m.Proof
+	}
+	return nil
+}
+
+type GetConsistencyProofChainResponse struct {
+	// The steps of the chain, in increasing order of size. The last one ends
+	// at the size of `signed_log_root`. There are none if the first size of
+	// the chain is the size of `signed_log_root`.
+	Steps         []*ConsistencyProofStep `protobuf:"bytes,1,rep,name=steps" json:"steps,omitempty"`
+	SignedLogRoot *SignedLogRoot          `protobuf:"bytes,2,opt,name=signed_log_root,json=signedLogRoot" json:"signed_log_root,omitempty"`
+}
+
+func (m *GetConsistencyProofChainResponse) Reset()         { *m = GetConsistencyProofChainResponse{} }
+func (m *GetConsistencyProofChainResponse) String() string { return proto.CompactTextString(m) }
+func (*GetConsistencyProofChainResponse) ProtoMessage()    {}
+func (*GetConsistencyProofChainResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor0, []int{12}
+}
+
+func (m *GetConsistencyProofChainResponse) GetSteps() []*ConsistencyProofStep {
+	if m != nil {
+		return m.Steps
+	}
+	return nil
+}
+
+func (m *GetConsistencyProofChainResponse) GetSignedLogRoot() *SignedLogRoot {
+	if m != nil {
+		return m.SignedLogRoot
+	}
+	return nil
+}
+
+type AddObservedRootRequest struct {
+	LogId         int64          `protobuf:"varint,1,opt,name=log_id,json=logId" json:"log_id,omitempty"`
+	SignedLogRoot *SignedLogRoot `protobuf:"bytes,2,opt,name=signed_log_root,json=signedLogRoot" json:"signed_log_root,omitempty"`
+	// A free-form identifier of the party which observed the root. It isn't
+	// authenticated.
+	Observer string `protobuf:"bytes,3,opt,name=observer" json:"observer,omitempty"`
+}
+
+func (m *AddObservedRootRequest) Reset()                    { *m = AddObservedRootRequest{} }
+func (m *AddObservedRootRequest) String() string            { return proto.CompactTextString(m) }
+func (*AddObservedRootRequest) ProtoMessage()               {}
+func (*AddObservedRootRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *AddObservedRootRequest) GetLogId() int64 {
+	if m != nil {
+		return m.LogId
+	}
+	return 0
+}
+
+func (m *AddObservedRootRequest) GetSignedLogRoot() *SignedLogRoot {
+	if m != nil {
+		return m.SignedLogRoot
+	}
+	return nil
+}
+
+func (m *AddObservedRootRequest) GetObserver() string {
+	if m != nil {
+		return m.Observer
+	}
+	return ""
+}
+
+type AddObservedRootResponse struct {
+	// True if the root conflicts with another root of the same size, i.e.
+	// the log has shown a split view.
+	SplitView bool `protobuf:"varint,1,opt,name=split_view,json=splitView" json:"split_view,omitempty"`
+}
+
+func (m *AddObservedRootResponse) Reset()                    { *m = AddObservedRootResponse{} }
+func (m *AddObservedRootResponse) String() string            { return proto.CompactTextString(m) }
+func (*AddObservedRootResponse) ProtoMessage()               {}
+func (*AddObservedRootResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+
+func (m *AddObservedRootResponse) GetSplitView() bool {
+	if m != nil {
+		return m.SplitView
+	}
+	return false
+}
+
+// ObservedRoot is a root of a log observed by another party.
+type ObservedRoot struct {
+	SignedLogRoot *SignedLogRoot `protobuf:"bytes,1,opt,name=signed_log_root,json=signedLogRoot" json:"signed_log_root,omitempty"`
+	// The observer of the root, as given when it was first added.
+	Observer string `protobuf:"bytes,2,opt,name=observer" json:"observer,omitempty"`
+	// The time the server received the root.
+	ReceiveTime *google_protobuf1.Timestamp `protobuf:"bytes,3,opt,name=receive_time,json=receiveTime" json:"receive_time,omitempty"`
+}
+
+func (m *ObservedRoot) Reset()                    { *m = ObservedRoot{} }
+func (m *ObservedRoot) String() string            { return proto.CompactTextString(m) }
+func (*ObservedRoot) ProtoMessage()               {}
+func (*ObservedRoot) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+
+func (m *ObservedRoot) GetSignedLogRoot() *SignedLogRoot {
+	if m != nil {
+		return m.SignedLogRoot
+	}
+	return nil
+}
+
+func (m *ObservedRoot) GetObserver() string {
+	if m != nil {
+		return m.Observer
+	}
+	return ""
+}
+
+func (m *ObservedRoot) GetReceiveTime() *google_protobuf1.Timestamp {
+	if m != nil {
+		return m.ReceiveTime
+	}
+	return nil
+}
+
+type GetObservedRootsRequest struct {
+	LogId int64 `protobuf:"varint,1,opt,name=log_id,json=logId" json:"log_id,omitempty"`
+	// If non-zero, only the roots of this tree size are returned.
+	TreeSize int64 `protobuf:"varint,2,opt,name=tree_size,json=treeSize" json:"tree_size,omitempty"`
+}
+
+func (m *GetObservedRootsRequest) Reset()                    { *m = GetObservedRootsRequest{} }
+func (m *GetObservedRootsRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetObservedRootsRequest) ProtoMessage()               {}
+func (*GetObservedRootsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
+
+func (m *GetObservedRootsRequest) GetLogId() int64 {
+	if m != nil {
+		return m.LogId
+	}
+	return 0
+}
+
+func (m *GetObservedRootsRequest) GetTreeSize() int64 {
+	if m != nil {
+		return m.TreeSize
+	}
+	return 0
+}
+
+type GetObservedRootsResponse struct {
+	// The observed roots, in increasing order of tree size.
+	ObservedRoots []*ObservedRoot `protobuf:"bytes,1,rep,name=observed_roots,json=observedRoots" json:"observed_roots,omitempty"`
+	// The tree sizes at which conflicting roots were observed.
+	SplitViewTreeSizes []int64 `protobuf:"varint,2,rep,packed,name=split_view_tree_sizes,json=splitViewTreeSizes" json:"split_view_tree_sizes,omitempty"`
+}
+
+func (m *GetObservedRootsResponse) Reset()                    { *m = GetObservedRootsResponse{} }
+func (m *GetObservedRootsResponse) String() string            { return proto.CompactTextString(m) }
+func (*GetObservedRootsResponse) ProtoMessage()               {}
+func (*GetObservedRootsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
+
+func (m *GetObservedRootsResponse) GetObservedRoots() []*ObservedRoot {
+	if m != nil {
+		return m.ObservedRoots
+	}
+	return nil
+}
+
+func (m *GetObservedRootsResponse) GetSplitViewTreeSizes() []int64 {
+	if m != nil {
+		return m.SplitViewTreeSizes
+	}
+	return nil
+}
+
 type GetLatestSignedLogRootRequest struct {
 	LogId int64 `protobuf:"varint,1,opt,name=log_id,json=logId" json:"log_id,omitempty"`
 }
@@ -333,7 +594,7 @@ type GetLatestSignedLogRootRequest struct {
 func (m *GetLatestSignedLogRootRequest) Reset()                    { *m = GetLatestSignedLogRootRequest{} }
 func (m *GetLatestSignedLogRootRequest) String() string            { return proto.CompactTextString(m) }
 func (*GetLatestSignedLogRootRequest) ProtoMessage()               {}
-func (*GetLatestSignedLogRootRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+func (*GetLatestSignedLogRootRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
 
 func (m *GetLatestSignedLogRootRequest) GetLogId() int64 {
 	if m != nil {
@@ -349,7 +610,7 @@ type GetLatestSignedLogRootResponse struct {
 func (m *GetLatestSignedLogRootResponse) Reset()                    { *m = GetLatestSignedLogRootResponse{} }
 func (m *GetLatestSignedLogRootResponse) String() string            { return proto.CompactTextString(m) }
 func (*GetLatestSignedLogRootResponse) ProtoMessage()               {}
-func (*GetLatestSignedLogRootResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+func (*GetLatestSignedLogRootResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{19} }
 
 func (m *GetLatestSignedLogRootResponse) GetSignedLogRoot() *SignedLogRoot {
 	if m != nil {
@@ -365,7 +626,7 @@ type GetSequencedLeafCountRequest struct {
 func (m *GetSequencedLeafCountRequest) Reset()                    { *m = GetSequencedLeafCountRequest{} }
 func (m *GetSequencedLeafCountRequest) String() string            { return proto.CompactTextString(m) }
 func (*GetSequencedLeafCountRequest) ProtoMessage()               {}
-func (*GetSequencedLeafCountRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+func (*GetSequencedLeafCountRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{20} }
 
 func (m *GetSequencedLeafCountRequest) GetLogId() int64 {
 	if m != nil {
@@ -381,7 +642,7 @@ type GetSequencedLeafCountResponse struct {
 func (m *GetSequencedLeafCountResponse) Reset()                    { *m = GetSequencedLeafCountResponse{} }
 func (m *GetSequencedLeafCountResponse) String() string            { return proto.CompactTextString(m) }
 func (*GetSequencedLeafCountResponse) ProtoMessage()               {}
-func (*GetSequencedLeafCountResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+func (*GetSequencedLeafCountResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{21} }
 
 func (m *GetSequencedLeafCountResponse) GetLeafCount() int64 {
 	if m != nil {
@@ -394,12 +655,15 @@ type GetEntryAndProofRequest struct {
 	LogId     int64 `protobuf:"varint,1,opt,name=log_id,json=logId" json:"log_id,omitempty"`
 	LeafIndex int64 `protobuf:"varint,2,opt,name=leaf_index,json=leafIndex" json:"leaf_index,omitempty"`
 	TreeSize  int64 `protobuf:"varint,3,opt,name=tree_size,json=treeSize" json:"tree_size,omitempty"`
+	// If set, the leaf_value and extra_data of the returned leaf are left
+	// empty, for clients which only need the leaf hashes and proof.
+	OmitLeafData bool `protobuf:"varint,4,opt,name=omit_leaf_data,json=omitLeafData" json:"omit_leaf_data,omitempty"`
 }
 
 func (m *GetEntryAndProofRequest) Reset()                    { *m = GetEntryAndProofRequest{} }
 func (m *GetEntryAndProofRequest) String() string            { return proto.CompactTextString(m) }
 func (*GetEntryAndProofRequest) ProtoMessage()               {}
-func (*GetEntryAndProofRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+func (*GetEntryAndProofRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{22} }
 
 func (m *GetEntryAndProofRequest) GetLogId() int64 {
 	if m != nil {
@@ -422,6 +686,13 @@ func (m *GetEntryAndProofRequest) GetTreeSize() int64 {
 	return 0
 }
 
+func (m *GetEntryAndProofRequest) GetOmitLeafData() bool {
+	if m != nil {
+		return m.OmitLeafData
+	}
+	return false
+}
+
 type GetEntryAndProofResponse struct {
 	Proof *Proof   `protobuf:"bytes,2,opt,name=proof" json:"proof,omitempty"`
 	Leaf  *LogLeaf `protobuf:"bytes,3,opt,name=leaf" json:"leaf,omitempty"`
@@ -430,7 +701,7 @@ type GetEntryAndProofResponse struct {
 func (m *GetEntryAndProofResponse) Reset()                    { *m = GetEntryAndProofResponse{} }
 func (m *GetEntryAndProofResponse) String() string            { return proto.CompactTextString(m) }
 func (*GetEntryAndProofResponse) ProtoMessage()               {}
-func (*GetEntryAndProofResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+func (*GetEntryAndProofResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{23} }
 
 func (m *GetEntryAndProofResponse) GetProof() *Proof {
 	if m != nil {
@@ -448,12 +719,15 @@ func (m *GetEntryAndProofResponse) GetLeaf() *LogLeaf {
 
 type InitLogRequest struct {
 	LogId int64 `protobuf:"varint,1,opt,name=log_id,json=logId" json:"log_id,omitempty"`
+	// Metadata to sign in the initial root of the log, e.g. a
+	// LogRootMetadata. A LogRootMetadata must have an origin.
+	Metadata *google_protobuf2.Any `protobuf:"bytes,2,opt,name=metadata" json:"metadata,omitempty"`
 }
 
 func (m *InitLogRequest) Reset()                    { *m = InitLogRequest{} }
 func (m *InitLogRequest) String() string            { return proto.CompactTextString(m) }
 func (*InitLogRequest) ProtoMessage()               {}
-func (*InitLogRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
+func (*InitLogRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{24} }
 
 func (m *InitLogRequest) GetLogId() int64 {
 	if m != nil {
@@ -462,6 +736,13 @@ func (m *InitLogRequest) GetLogId() int64 {
 	return 0
 }
 
+func (m *InitLogRequest) GetMetadata() *google_protobuf2.Any {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
 type InitLogResponse struct {
 	Created *SignedLogRoot `protobuf:"bytes,1,opt,name=created" json:"created,omitempty"`
 }
@@ -469,7 +750,7 @@ type InitLogResponse struct {
 func (m *InitLogResponse) Reset()                    { *m = InitLogResponse{} }
 func (m *InitLogResponse) String() string            { return proto.CompactTextString(m) }
 func (*InitLogResponse) ProtoMessage()               {}
-func (*InitLogResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
+func (*InitLogResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{25} }
 
 func (m *InitLogResponse) GetCreated() *SignedLogRoot {
 	if m != nil {
@@ -486,7 +767,7 @@ type QueueLeavesRequest struct {
 func (m *QueueLeavesRequest) Reset()                    { *m = QueueLeavesRequest{} }
 func (m *QueueLeavesRequest) String() string            { return proto.CompactTextString(m) }
 func (*QueueLeavesRequest) ProtoMessage()               {}
-func (*QueueLeavesRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
+func (*QueueLeavesRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{26} }
 
 func (m *QueueLeavesRequest) GetLogId() int64 {
 	if m != nil {
@@ -510,7 +791,7 @@ type QueueLeavesResponse struct {
 func (m *QueueLeavesResponse) Reset()                    { *m = QueueLeavesResponse{} }
 func (m *QueueLeavesResponse) String() string            { return proto.CompactTextString(m) }
 func (*QueueLeavesResponse) ProtoMessage()               {}
-func (*QueueLeavesResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{19} }
+func (*QueueLeavesResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{27} }
 
 func (m *QueueLeavesResponse) GetQueuedLeaves() []*QueuedLogLeaf {
 	if m != nil {
@@ -527,7 +808,7 @@ type AddSequencedLeavesRequest struct {
 func (m *AddSequencedLeavesRequest) Reset()                    { *m = AddSequencedLeavesRequest{} }
 func (m *AddSequencedLeavesRequest) String() string            { return proto.CompactTextString(m) }
 func (*AddSequencedLeavesRequest) ProtoMessage()               {}
-func (*AddSequencedLeavesRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{20} }
+func (*AddSequencedLeavesRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{28} }
 
 func (m *AddSequencedLeavesRequest) GetLogId() int64 {
 	if m != nil {
@@ -551,7 +832,7 @@ type AddSequencedLeavesResponse struct {
 func (m *AddSequencedLeavesResponse) Reset()                    { *m = AddSequencedLeavesResponse{} }
 func (m *AddSequencedLeavesResponse) String() string            { return proto.CompactTextString(m) }
 func (*AddSequencedLeavesResponse) ProtoMessage()               {}
-func (*AddSequencedLeavesResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{21} }
+func (*AddSequencedLeavesResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{29} }
 
 func (m *AddSequencedLeavesResponse) GetResults() []*QueuedLogLeaf {
 	if m != nil {
@@ -560,6 +841,217 @@ func (m *AddSequencedLeavesResponse) GetResults() []*QueuedLogLeaf {
 	return nil
 }
 
+type AddSequencedLeafRangeRequest struct {
+	LogId int64 `protobuf:"varint,1,opt,name=log_id,json=logId" json:"log_id,omitempty"`
+	// The index of the first leaf of the range.
+	StartIndex int64 `protobuf:"varint,2,opt,name=start_index,json=startIndex" json:"start_index,omitempty"`
+	// The leaves of the range, in order. The `leaf_index` of each leaf is
+	// `start_index` plus its position in the range, and must either be unset
+	// or have that value.
+	Leaves []*LogLeaf `protobuf:"bytes,3,rep,name=leaves" json:"leaves,omitempty"`
+}
+
+func (m *AddSequencedLeafRangeRequest) Reset()                    { *m = AddSequencedLeafRangeRequest{} }
+func (m *AddSequencedLeafRangeRequest) String() string            { return proto.CompactTextString(m) }
+func (*AddSequencedLeafRangeRequest) ProtoMessage()               {}
+func (*AddSequencedLeafRangeRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{30} }
+
+func (m *AddSequencedLeafRangeRequest) GetLogId() int64 {
+	if m != nil {
+		return m.LogId
+	}
+	return 0
+}
+
+func (m *AddSequencedLeafRangeRequest) GetStartIndex() int64 {
+	if m != nil {
+		return m.StartIndex
+	}
+	return 0
+}
+
+func (m *AddSequencedLeafRangeRequest) GetLeaves() []*LogLeaf {
+	if m != nil {
+		return m.Leaves
+	}
+	return nil
+}
+
+type AddSequencedLeafRangeResponse struct {
+	// The results of the leaves which were written, in the same order as in
+	// the corresponding request.
+	Results []*QueuedLogLeaf `protobuf:"bytes,1,rep,name=results" json:"results,omitempty"`
+	// The index of the first leaf which wasn't written, i.e. `start_index`
+	// plus the number of results.
+	NextIndex int64 `protobuf:"varint,2,opt,name=next_index,json=nextIndex" json:"next_index,omitempty"`
+	// Why the rest of the range wasn't written, if `next_index` is before its
+	// end.
+	Status *google_rpc.Status `protobuf:"bytes,3,opt,name=status" json:"status,omitempty"`
+}
+
+func (m *AddSequencedLeafRangeResponse) Reset()                    { *m = AddSequencedLeafRangeResponse{} }
+func (m *AddSequencedLeafRangeResponse) String() string            { return proto.CompactTextString(m) }
+func (*AddSequencedLeafRangeResponse) ProtoMessage()               {}
+func (*AddSequencedLeafRangeResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{31} }
+
+func (m *AddSequencedLeafRangeResponse) GetResults() []*QueuedLogLeaf {
+	if m != nil {
+		return m.Results
+	}
+	return nil
+}
+
+func (m *AddSequencedLeafRangeResponse) GetNextIndex() int64 {
+	if m != nil {
+		return m.NextIndex
+	}
+	return 0
+}
+
+func (m *AddSequencedLeafRangeResponse) GetStatus() *google_rpc.Status {
+	if m != nil {
+		return m.Status
+	}
+	return nil
+}
+
+type GetLeafIndicesByKeyRequest struct {
+	LogId int64 `protobuf:"varint,1,opt,name=log_id,json=logId" json:"log_id,omitempty"`
+	// The name of the index, as in `LeafIndexSpec.name`.
+	IndexName string `protobuf:"bytes,2,opt,name=index_name,json=indexName" json:"index_name,omitempty"`
+	Key       []byte `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	// The lowest leaf index to return, for paging through the results.
+	StartIndex int64 `protobuf:"varint,4,opt,name=start_index,json=startIndex" json:"start_index,omitempty"`
+	// The maximum number of leaf indices to return. Zero means a server-chosen
+	// default.
+	MaxResults int32 `protobuf:"varint,5,opt,name=max_results,json=maxResults" json:"max_results,omitempty"`
+}
+
+func (m *GetLeafIndicesByKeyRequest) Reset()                    { *m = GetLeafIndicesByKeyRequest{} }
+func (m *GetLeafIndicesByKeyRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetLeafIndicesByKeyRequest) ProtoMessage()               {}
+func (*GetLeafIndicesByKeyRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{32} }
+
+func (m *GetLeafIndicesByKeyRequest) GetLogId() int64 {
+	if m != nil {
+		return m.LogId
+	}
+	return 0
+}
+
+func (m *GetLeafIndicesByKeyRequest) GetIndexName() string {
+	if m != nil {
+		return m.IndexName
+	}
+	return ""
+}
+
+func (m *GetLeafIndicesByKeyRequest) GetKey() []byte {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+func (m *GetLeafIndicesByKeyRequest) GetStartIndex() int64 {
+	if m != nil {
+		return m.StartIndex
+	}
+	return 0
+}
+
+func (m *GetLeafIndicesByKeyRequest) GetMaxResults() int32 {
+	if m != nil {
+		return m.MaxResults
+	}
+	return 0
+}
+
+type GetLeafIndicesByKeyResponse struct {
+	// The indices of the leaves with the key, in increasing order, all within
+	// `signed_log_root`.
+	LeafIndices []int64 `protobuf:"varint,1,rep,packed,name=leaf_indices,json=leafIndices" json:"leaf_indices,omitempty"`
+	// If not zero, the `start_index` of the next page of results.
+	NextIndex     int64          `protobuf:"varint,2,opt,name=next_index,json=nextIndex" json:"next_index,omitempty"`
+	SignedLogRoot *SignedLogRoot `protobuf:"bytes,3,opt,name=signed_log_root,json=signedLogRoot" json:"signed_log_root,omitempty"`
+}
+
+func (m *GetLeafIndicesByKeyResponse) Reset()                    { *m = GetLeafIndicesByKeyResponse{} }
+func (m *GetLeafIndicesByKeyResponse) String() string            { return proto.CompactTextString(m) }
+func (*GetLeafIndicesByKeyResponse) ProtoMessage()               {}
+func (*GetLeafIndicesByKeyResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{33} }
+
+func (m *GetLeafIndicesByKeyResponse) GetLeafIndices() []int64 {
+	if m != nil {
+		return m.LeafIndices
+	}
+	return nil
+}
+
+func (m *GetLeafIndicesByKeyResponse) GetNextIndex() int64 {
+	if m != nil {
+		return m.NextIndex
+	}
+	return 0
+}
+
+func (m *GetLeafIndicesByKeyResponse) GetSignedLogRoot() *SignedLogRoot {
+	if m != nil {
+		return m.SignedLogRoot
+	}
+	return nil
+}
+
+type CheckLeafFilterRequest struct {
+	LogId            int64    `protobuf:"varint,1,opt,name=log_id,json=logId" json:"log_id,omitempty"`
+	LeafIdentityHash [][]byte `protobuf:"bytes,2,rep,name=leaf_identity_hash,json=leafIdentityHash,proto3" json:"leaf_identity_hash,omitempty"`
+}
+
+func (m *CheckLeafFilterRequest) Reset()                    { *m = CheckLeafFilterRequest{} }
+func (m *CheckLeafFilterRequest) String() string            { return proto.CompactTextString(m) }
+func (*CheckLeafFilterRequest) ProtoMessage()               {}
+func (*CheckLeafFilterRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{34} }
+
+func (m *CheckLeafFilterRequest) GetLogId() int64 {
+	if m != nil {
+		return m.LogId
+	}
+	return 0
+}
+
+func (m *CheckLeafFilterRequest) GetLeafIdentityHash() [][]byte {
+	if m != nil {
+		return m.LeafIdentityHash
+	}
+	return nil
+}
+
+type CheckLeafFilterResponse struct {
+	// For each hash in the request, whether a leaf with it may have been
+	// integrated as of `signed_log_root`. False means it definitely hasn't.
+	MaybeLogged   []bool         `protobuf:"varint,1,rep,packed,name=maybe_logged,json=maybeLogged" json:"maybe_logged,omitempty"`
+	SignedLogRoot *SignedLogRoot `protobuf:"bytes,2,opt,name=signed_log_root,json=signedLogRoot" json:"signed_log_root,omitempty"`
+}
+
+func (m *CheckLeafFilterResponse) Reset()                    { *m = CheckLeafFilterResponse{} }
+func (m *CheckLeafFilterResponse) String() string            { return proto.CompactTextString(m) }
+func (*CheckLeafFilterResponse) ProtoMessage()               {}
+func (*CheckLeafFilterResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{35} }
+
+func (m *CheckLeafFilterResponse) GetMaybeLogged() []bool {
+	if m != nil {
+		return m.MaybeLogged
+	}
+	return nil
+}
+
+func (m *CheckLeafFilterResponse) GetSignedLogRoot() *SignedLogRoot {
+	if m != nil {
+		return m.SignedLogRoot
+	}
+	return nil
+}
+
 type GetLeavesByIndexRequest struct {
 	LogId     int64   `protobuf:"varint,1,opt,name=log_id,json=logId" json:"log_id,omitempty"`
 	LeafIndex []int64 `protobuf:"varint,2,rep,packed,name=leaf_index,json=leafIndex" json:"leaf_index,omitempty"`
@@ -568,7 +1060,7 @@ type GetLeavesByIndexRequest struct {
 func (m *GetLeavesByIndexRequest) Reset()                    { *m = GetLeavesByIndexRequest{} }
 func (m *GetLeavesByIndexRequest) String() string            { return proto.CompactTextString(m) }
 func (*GetLeavesByIndexRequest) ProtoMessage()               {}
-func (*GetLeavesByIndexRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{22} }
+func (*GetLeavesByIndexRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{36} }
 
 func (m *GetLeavesByIndexRequest) GetLogId() int64 {
 	if m != nil {
@@ -592,7 +1084,7 @@ type GetLeavesByIndexResponse struct {
 func (m *GetLeavesByIndexResponse) Reset()                    { *m = GetLeavesByIndexResponse{} }
 func (m *GetLeavesByIndexResponse) String() string            { return proto.CompactTextString(m) }
 func (*GetLeavesByIndexResponse) ProtoMessage()               {}
-func (*GetLeavesByIndexResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{23} }
+func (*GetLeavesByIndexResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{37} }
 
 func (m *GetLeavesByIndexResponse) GetLeaves() []*LogLeaf {
 	if m != nil {
@@ -610,7 +1102,7 @@ type GetLeavesByRangeRequest struct {
 func (m *GetLeavesByRangeRequest) Reset()                    { *m = GetLeavesByRangeRequest{} }
 func (m *GetLeavesByRangeRequest) String() string            { return proto.CompactTextString(m) }
 func (*GetLeavesByRangeRequest) ProtoMessage()               {}
-func (*GetLeavesByRangeRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{24} }
+func (*GetLeavesByRangeRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{38} }
 
 func (m *GetLeavesByRangeRequest) GetLogId() int64 {
 	if m != nil {
@@ -644,7 +1136,7 @@ type GetLeavesByRangeResponse struct {
 func (m *GetLeavesByRangeResponse) Reset()                    { *m = GetLeavesByRangeResponse{} }
 func (m *GetLeavesByRangeResponse) String() string            { return proto.CompactTextString(m) }
 func (*GetLeavesByRangeResponse) ProtoMessage()               {}
-func (*GetLeavesByRangeResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{25} }
+func (*GetLeavesByRangeResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{39} }
 
 func (m *GetLeavesByRangeResponse) GetLeaves() []*LogLeaf {
 	if m != nil {
@@ -662,7 +1154,7 @@ type GetLeavesByHashRequest struct {
 func (m *GetLeavesByHashRequest) Reset()                    { *m = GetLeavesByHashRequest{} }
 func (m *GetLeavesByHashRequest) String() string            { return proto.CompactTextString(m) }
 func (*GetLeavesByHashRequest) ProtoMessage()               {}
-func (*GetLeavesByHashRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{26} }
+func (*GetLeavesByHashRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{40} }
 
 func (m *GetLeavesByHashRequest) GetLogId() int64 {
 	if m != nil {
@@ -693,7 +1185,7 @@ type GetLeavesByHashResponse struct {
 func (m *GetLeavesByHashResponse) Reset()                    { *m = GetLeavesByHashResponse{} }
 func (m *GetLeavesByHashResponse) String() string            { return proto.CompactTextString(m) }
 func (*GetLeavesByHashResponse) ProtoMessage()               {}
-func (*GetLeavesByHashResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{27} }
+func (*GetLeavesByHashResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{41} }
 
 func (m *GetLeavesByHashResponse) GetLeaves() []*LogLeaf {
 	if m != nil {
@@ -702,6 +1194,65 @@ func (m *GetLeavesByHashResponse) GetLeaves() []*LogLeaf {
 	return nil
 }
 
+type GetEntriesAndProofsRequest struct {
+	LogId     int64   `protobuf:"varint,1,opt,name=log_id,json=logId" json:"log_id,omitempty"`
+	LeafIndex []int64 `protobuf:"varint,2,rep,packed,name=leaf_index,json=leafIndex" json:"leaf_index,omitempty"`
+	TreeSize  int64   `protobuf:"varint,3,opt,name=tree_size,json=treeSize" json:"tree_size,omitempty"`
+	// If set, the leaf_value and extra_data of the returned leaves are left
+	// empty, for clients which only need the leaf hashes and proofs.
+	OmitLeafData bool `protobuf:"varint,4,opt,name=omit_leaf_data,json=omitLeafData" json:"omit_leaf_data,omitempty"`
+}
+
+func (m *GetEntriesAndProofsRequest) Reset()                    { *m = GetEntriesAndProofsRequest{} }
+func (m *GetEntriesAndProofsRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetEntriesAndProofsRequest) ProtoMessage()               {}
+func (*GetEntriesAndProofsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{42} }
+
+func (m *GetEntriesAndProofsRequest) GetLogId() int64 {
+	if m != nil {
+		return m.LogId
+	}
+	return 0
+}
+
+func (m *GetEntriesAndProofsRequest) GetLeafIndex() []int64 {
+	if m != nil {
+		return m.LeafIndex
+	}
+	return nil
+}
+
+func (m *GetEntriesAndProofsRequest) GetTreeSize() int64 {
+	if m != nil {
+		return m.TreeSize
+	}
+	return 0
+}
+
+func (m *GetEntriesAndProofsRequest) GetOmitLeafData() bool {
+	if m != nil {
+		return m.OmitLeafData
+	}
+	return false
+}
+
+type GetEntriesAndProofsResponse struct {
+	// Same number and order as the leaf indices in the corresponding request.
+	Entries []*GetEntryAndProofResponse `protobuf:"bytes,1,rep,name=entries" json:"entries,omitempty"`
+}
+
+func (m *GetEntriesAndProofsResponse) Reset()                    { *m = GetEntriesAndProofsResponse{} }
+func (m *GetEntriesAndProofsResponse) String() string            { return proto.CompactTextString(m) }
+func (*GetEntriesAndProofsResponse) ProtoMessage()               {}
+func (*GetEntriesAndProofsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{43} }
+
+func (m *GetEntriesAndProofsResponse) GetEntries() []*GetEntryAndProofResponse {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
 // A result of submitting an entry to the log. Output only.
 // TODO(pavelkalinnikov): Consider renaming it to AddLogLeafResult or the like.
 type QueuedLogLeaf struct {
@@ -723,7 +1274,7 @@ type QueuedLogLeaf struct {
 func (m *QueuedLogLeaf) Reset()                    { *m = QueuedLogLeaf{} }
 func (m *QueuedLogLeaf) String() string            { return proto.CompactTextString(m) }
 func (*QueuedLogLeaf) ProtoMessage()               {}
-func (*QueuedLogLeaf) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{28} }
+func (*QueuedLogLeaf) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{44} }
 
 func (m *QueuedLogLeaf) GetLeaf() *LogLeaf {
 	if m != nil {
@@ -787,7 +1338,7 @@ type LogLeaf struct {
 func (m *LogLeaf) Reset()                    { *m = LogLeaf{} }
 func (m *LogLeaf) String() string            { return proto.CompactTextString(m) }
 func (*LogLeaf) ProtoMessage()               {}
-func (*LogLeaf) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{29} }
+func (*LogLeaf) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{45} }
 
 func (m *LogLeaf) GetMerkleLeafHash() []byte {
 	if m != nil {
@@ -847,7 +1398,7 @@ type Proof struct {
 func (m *Proof) Reset()                    { *m = Proof{} }
 func (m *Proof) String() string            { return proto.CompactTextString(m) }
 func (*Proof) ProtoMessage()               {}
-func (*Proof) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{30} }
+func (*Proof) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{46} }
 
 func (m *Proof) GetLeafIndex() int64 {
 	if m != nil {
@@ -863,6 +1414,93 @@ func (m *Proof) GetHashes() [][]byte {
 	return nil
 }
 
+// The statement signed by a server's proof signing key about a single proof
+// response. It is sent alongside the response, serialized inside a
+// SignedProofEnvelope, so that a client can detect a response which has been
+// altered on its way from the server. Output only.
+type ProofEnvelope struct {
+	// The time at which the server signed the envelope, in nanoseconds since
+	// the UNIX epoch.
+	TimestampNanos int64 `protobuf:"varint,1,opt,name=timestamp_nanos,json=timestampNanos" json:"timestamp_nanos,omitempty"`
+	// The full gRPC method name of the call, e.g.
+	// "/trillian.TrillianLog/GetInclusionProof".
+	Method string `protobuf:"bytes,2,opt,name=method" json:"method,omitempty"`
+	// SHA-256 of the serialized request message, as received by the server.
+	RequestDigest []byte `protobuf:"bytes,3,opt,name=request_digest,json=requestDigest,proto3" json:"request_digest,omitempty"`
+	// SHA-256 of the serialized response message, as sent by the server.
+	ResponseDigest []byte `protobuf:"bytes,4,opt,name=response_digest,json=responseDigest,proto3" json:"response_digest,omitempty"`
+	// SHA-256 of the DER encoded public key of the proof signing key.
+	KeyId []byte `protobuf:"bytes,5,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`
+}
+
+func (m *ProofEnvelope) Reset()                    { *m = ProofEnvelope{} }
+func (m *ProofEnvelope) String() string            { return proto.CompactTextString(m) }
+func (*ProofEnvelope) ProtoMessage()               {}
+func (*ProofEnvelope) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{47} }
+
+func (m *ProofEnvelope) GetTimestampNanos() int64 {
+	if m != nil {
+		return m.TimestampNanos
+	}
+	return 0
+}
+
+func (m *ProofEnvelope) GetMethod() string {
+	if m != nil {
+		return m.Method
+	}
+	return ""
+}
+
+func (m *ProofEnvelope) GetRequestDigest() []byte {
+	if m != nil {
+		return m.RequestDigest
+	}
+	return nil
+}
+
+func (m *ProofEnvelope) GetResponseDigest() []byte {
+	if m != nil {
+		return m.ResponseDigest
+	}
+	return nil
+}
+
+func (m *ProofEnvelope) GetKeyId() []byte {
+	if m != nil {
+		return m.KeyId
+	}
+	return nil
+}
+
+// A ProofEnvelope together with the proof signing key's signature over its
+// serialized bytes. Output only.
+type SignedProofEnvelope struct {
+	// The serialized ProofEnvelope. Kept in serialized form so that clients
+	// verify exactly the bytes which were signed.
+	Envelope  []byte                 `protobuf:"bytes,1,opt,name=envelope,proto3" json:"envelope,omitempty"`
+	Signature *sigpb.DigitallySigned `protobuf:"bytes,2,opt,name=signature" json:"signature,omitempty"`
+}
+
+func (m *SignedProofEnvelope) Reset()                    { *m = SignedProofEnvelope{} }
+func (m *SignedProofEnvelope) String() string            { return proto.CompactTextString(m) }
+func (*SignedProofEnvelope) ProtoMessage()               {}
+func (*SignedProofEnvelope) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{48} }
+
+func (m *SignedProofEnvelope) GetEnvelope() []byte {
+	if m != nil {
+		return m.Envelope
+	}
+	return nil
+}
+
+func (m *SignedProofEnvelope) GetSignature() *sigpb.DigitallySigned {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*QueueLeafRequest)(nil), "trillian.QueueLeafRequest")
 	proto.RegisterType((*QueueLeafResponse)(nil), "trillian.QueueLeafResponse")
@@ -874,6 +1512,14 @@ func init() {
 	proto.RegisterType((*GetInclusionProofByHashResponse)(nil), "trillian.GetInclusionProofByHashResponse")
 	proto.RegisterType((*GetConsistencyProofRequest)(nil), "trillian.GetConsistencyProofRequest")
 	proto.RegisterType((*GetConsistencyProofResponse)(nil), "trillian.GetConsistencyProofResponse")
+	proto.RegisterType((*GetConsistencyProofChainRequest)(nil), "trillian.GetConsistencyProofChainRequest")
+	proto.RegisterType((*ConsistencyProofStep)(nil), "trillian.ConsistencyProofStep")
+	proto.RegisterType((*GetConsistencyProofChainResponse)(nil), "trillian.GetConsistencyProofChainResponse")
+	proto.RegisterType((*AddObservedRootRequest)(nil), "trillian.AddObservedRootRequest")
+	proto.RegisterType((*AddObservedRootResponse)(nil), "trillian.AddObservedRootResponse")
+	proto.RegisterType((*ObservedRoot)(nil), "trillian.ObservedRoot")
+	proto.RegisterType((*GetObservedRootsRequest)(nil), "trillian.GetObservedRootsRequest")
+	proto.RegisterType((*GetObservedRootsResponse)(nil), "trillian.GetObservedRootsResponse")
 	proto.RegisterType((*GetLatestSignedLogRootRequest)(nil), "trillian.GetLatestSignedLogRootRequest")
 	proto.RegisterType((*GetLatestSignedLogRootResponse)(nil), "trillian.GetLatestSignedLogRootResponse")
 	proto.RegisterType((*GetSequencedLeafCountRequest)(nil), "trillian.GetSequencedLeafCountRequest")
@@ -886,15 +1532,25 @@ func init() {
 	proto.RegisterType((*QueueLeavesResponse)(nil), "trillian.QueueLeavesResponse")
 	proto.RegisterType((*AddSequencedLeavesRequest)(nil), "trillian.AddSequencedLeavesRequest")
 	proto.RegisterType((*AddSequencedLeavesResponse)(nil), "trillian.AddSequencedLeavesResponse")
+	proto.RegisterType((*AddSequencedLeafRangeRequest)(nil), "trillian.AddSequencedLeafRangeRequest")
+	proto.RegisterType((*AddSequencedLeafRangeResponse)(nil), "trillian.AddSequencedLeafRangeResponse")
+	proto.RegisterType((*GetLeafIndicesByKeyRequest)(nil), "trillian.GetLeafIndicesByKeyRequest")
+	proto.RegisterType((*GetLeafIndicesByKeyResponse)(nil), "trillian.GetLeafIndicesByKeyResponse")
+	proto.RegisterType((*CheckLeafFilterRequest)(nil), "trillian.CheckLeafFilterRequest")
+	proto.RegisterType((*CheckLeafFilterResponse)(nil), "trillian.CheckLeafFilterResponse")
 	proto.RegisterType((*GetLeavesByIndexRequest)(nil), "trillian.GetLeavesByIndexRequest")
 	proto.RegisterType((*GetLeavesByIndexResponse)(nil), "trillian.GetLeavesByIndexResponse")
 	proto.RegisterType((*GetLeavesByRangeRequest)(nil), "trillian.GetLeavesByRangeRequest")
 	proto.RegisterType((*GetLeavesByRangeResponse)(nil), "trillian.GetLeavesByRangeResponse")
 	proto.RegisterType((*GetLeavesByHashRequest)(nil), "trillian.GetLeavesByHashRequest")
 	proto.RegisterType((*GetLeavesByHashResponse)(nil), "trillian.GetLeavesByHashResponse")
+	proto.RegisterType((*GetEntriesAndProofsRequest)(nil), "trillian.GetEntriesAndProofsRequest")
+	proto.RegisterType((*GetEntriesAndProofsResponse)(nil), "trillian.GetEntriesAndProofsResponse")
 	proto.RegisterType((*QueuedLogLeaf)(nil), "trillian.QueuedLogLeaf")
 	proto.RegisterType((*LogLeaf)(nil), "trillian.LogLeaf")
 	proto.RegisterType((*Proof)(nil), "trillian.Proof")
+	proto.RegisterType((*ProofEnvelope)(nil), "trillian.ProofEnvelope")
+	proto.RegisterType((*SignedProofEnvelope)(nil), "trillian.SignedProofEnvelope")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -930,6 +1586,9 @@ type TrillianLogClient interface {
 	// Returns log entry and the corresponding inclusion proof for a given leaf
 	// index in a given tree.
 	GetEntryAndProof(ctx context.Context, in *GetEntryAndProofRequest, opts ...grpc.CallOption) (*GetEntryAndProofResponse, error)
+	// Initialises a log by storing its first, empty, signed root. Until then,
+	// RPCs needing the root of the log fail with FAILED_PRECONDITION and a
+	// PreconditionFailure violation of type TREE_NEEDS_INIT.
 	InitLog(ctx context.Context, in *InitLogRequest, opts ...grpc.CallOption) (*InitLogResponse, error)
 	// Adds a batch of leaves to the queue.
 	QueueLeaves(ctx context.Context, in *QueueLeavesRequest, opts ...grpc.CallOption) (*QueueLeavesResponse, error)
@@ -943,8 +1602,52 @@ type TrillianLogClient interface {
 	GetLeavesByIndex(ctx context.Context, in *GetLeavesByIndexRequest, opts ...grpc.CallOption) (*GetLeavesByIndexResponse, error)
 	// Returns a batch of leaves in a sequential range.
 	GetLeavesByRange(ctx context.Context, in *GetLeavesByRangeRequest, opts ...grpc.CallOption) (*GetLeavesByRangeResponse, error)
+	// Returns the leaves in a sequential range as a stream of batches, each of
+	// them in order and following the previous one. Unlike GetLeavesByRange,
+	// the server never holds the whole range in memory, so clients should use
+	// this RPC for ranges whose GetLeavesByRange response exceeds the server's
+	// memory limits.
+	StreamLeavesByRange(ctx context.Context, in *GetLeavesByRangeRequest, opts ...grpc.CallOption) (TrillianLog_StreamLeavesByRangeClient, error)
 	// Returns a batch of leaves by their `merkle_leaf_hash` values.
 	GetLeavesByHash(ctx context.Context, in *GetLeavesByHashRequest, opts ...grpc.CallOption) (*GetLeavesByHashResponse, error)
+	// Returns log entries and the corresponding inclusion proofs for a batch of
+	// leaf indices, all proven against the same tree size.
+	GetEntriesAndProofs(ctx context.Context, in *GetEntriesAndProofsRequest, opts ...grpc.CallOption) (*GetEntriesAndProofsResponse, error)
+	// Stores a contiguous range of leaves in a pre-ordered log, starting at
+	// `start_index`. Unlike AddSequencedLeaves, the range may be large: the
+	// server checks that it's contiguous, then writes it in chunks, each in its
+	// own storage transaction. If a chunk fails after earlier ones were
+	// written, the response reports how far the range got, and the caller can
+	// resume from `next_index`.
+	AddSequencedLeafRange(ctx context.Context, in *AddSequencedLeafRangeRequest, opts ...grpc.CallOption) (*AddSequencedLeafRangeResponse, error)
+	// Returns the indices of the leaves with a key in one of the log's
+	// secondary leaf indexes, as defined by `Tree.leaf_indexes`. Indexes are
+	// maintained by the signer, but aren't part of the Merkle tree: they
+	// can't prove that a key has no other leaves, and callers should check
+	// the leaves they're pointed to.
+	GetLeafIndicesByKey(ctx context.Context, in *GetLeafIndicesByKeyRequest, opts ...grpc.CallOption) (*GetLeafIndicesByKeyResponse, error)
+	// Checks leaf identity hashes against the log's leaf filter, as defined
+	// by `Tree.leaf_filter`, so that front-ends can cheaply reject duplicate
+	// submissions before queueing them. A hash which isn't in the filter has
+	// definitely not been integrated; one which is may have been. Leaves which
+	// are queued but not yet integrated aren't in the filter.
+	CheckLeafFilter(ctx context.Context, in *CheckLeafFilterRequest, opts ...grpc.CallOption) (*CheckLeafFilterResponse, error)
+	// Returns a chain of consistency proofs from `first_tree_size` to the
+	// size of the log's latest root, through the power-of-two sizes in
+	// between, so that a client which was offline for a long time can catch
+	// up with O(log n) proofs. The root hash at each power-of-two size is
+	// returned too: these sizes are shared by all clients, which may keep
+	// them as verified checkpoints.
+	GetConsistencyProofChain(ctx context.Context, in *GetConsistencyProofChainRequest, opts ...grpc.CallOption) (*GetConsistencyProofChainResponse, error)
+	// Adds a root of the log observed by another party, e.g. a monitor or
+	// another client, to the log's gossip pool. The root's signature must
+	// verify with the log's public key. A root which conflicts with another
+	// root of the same size in the pool, or with the log's own history, is
+	// evidence of a split view: it's kept in the pool, and reported in the
+	// response.
+	AddObservedRoot(ctx context.Context, in *AddObservedRootRequest, opts ...grpc.CallOption) (*AddObservedRootResponse, error)
+	// Returns the roots of the log in its gossip pool.
+	GetObservedRoots(ctx context.Context, in *GetObservedRootsRequest, opts ...grpc.CallOption) (*GetObservedRootsResponse, error)
 }
 
 type trillianLogClient struct {
@@ -1072,6 +1775,38 @@ func (c *trillianLogClient) GetLeavesByRange(ctx context.Context, in *GetLeavesB
 	return out, nil
 }
 
+func (c *trillianLogClient) StreamLeavesByRange(ctx context.Context, in *GetLeavesByRangeRequest, opts ...grpc.CallOption) (TrillianLog_StreamLeavesByRangeClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_TrillianLog_serviceDesc.Streams[0], c.cc, "/trillian.TrillianLog/StreamLeavesByRange", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &trillianLog_StreamLeavesByRangeClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type TrillianLog_StreamLeavesByRangeClient interface {
+	Recv() (*GetLeavesByRangeResponse, error)
+	grpc.ClientStream
+}
+
+type trillianLog_StreamLeavesByRangeClient struct {
+	grpc.ClientStream
+}
+
+func (x *trillianLog_StreamLeavesByRangeClient) Recv() (*GetLeavesByRangeResponse, error) {
+	m := new(GetLeavesByRangeResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *trillianLogClient) GetLeavesByHash(ctx context.Context, in *GetLeavesByHashRequest, opts ...grpc.CallOption) (*GetLeavesByHashResponse, error) {
 	out := new(GetLeavesByHashResponse)
 	err := grpc.Invoke(ctx, "/trillian.TrillianLog/GetLeavesByHash", in, out, c.cc, opts...)
@@ -1081,6 +1816,69 @@ func (c *trillianLogClient) GetLeavesByHash(ctx context.Context, in *GetLeavesBy
 	return out, nil
 }
 
+func (c *trillianLogClient) GetEntriesAndProofs(ctx context.Context, in *GetEntriesAndProofsRequest, opts ...grpc.CallOption) (*GetEntriesAndProofsResponse, error) {
+	out := new(GetEntriesAndProofsResponse)
+	err := grpc.Invoke(ctx, "/trillian.TrillianLog/GetEntriesAndProofs", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *trillianLogClient) AddSequencedLeafRange(ctx context.Context, in *AddSequencedLeafRangeRequest, opts ...grpc.CallOption) (*AddSequencedLeafRangeResponse, error) {
+	out := new(AddSequencedLeafRangeResponse)
+	err := grpc.Invoke(ctx, "/trillian.TrillianLog/AddSequencedLeafRange", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *trillianLogClient) GetLeafIndicesByKey(ctx context.Context, in *GetLeafIndicesByKeyRequest, opts ...grpc.CallOption) (*GetLeafIndicesByKeyResponse, error) {
+	out := new(GetLeafIndicesByKeyResponse)
+	err := grpc.Invoke(ctx, "/trillian.TrillianLog/GetLeafIndicesByKey", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *trillianLogClient) CheckLeafFilter(ctx context.Context, in *CheckLeafFilterRequest, opts ...grpc.CallOption) (*CheckLeafFilterResponse, error) {
+	out := new(CheckLeafFilterResponse)
+	err := grpc.Invoke(ctx, "/trillian.TrillianLog/CheckLeafFilter", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *trillianLogClient) GetConsistencyProofChain(ctx context.Context, in *GetConsistencyProofChainRequest, opts ...grpc.CallOption) (*GetConsistencyProofChainResponse, error) {
+	out := new(GetConsistencyProofChainResponse)
+	err := grpc.Invoke(ctx, "/trillian.TrillianLog/GetConsistencyProofChain", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *trillianLogClient) AddObservedRoot(ctx context.Context, in *AddObservedRootRequest, opts ...grpc.CallOption) (*AddObservedRootResponse, error) {
+	out := new(AddObservedRootResponse)
+	err := grpc.Invoke(ctx, "/trillian.TrillianLog/AddObservedRoot", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *trillianLogClient) GetObservedRoots(ctx context.Context, in *GetObservedRootsRequest, opts ...grpc.CallOption) (*GetObservedRootsResponse, error) {
+	out := new(GetObservedRootsResponse)
+	err := grpc.Invoke(ctx, "/trillian.TrillianLog/GetObservedRoots", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for TrillianLog service
 
 type TrillianLogServer interface {
@@ -1106,6 +1904,9 @@ type TrillianLogServer interface {
 	// Returns log entry and the corresponding inclusion proof for a given leaf
 	// index in a given tree.
 	GetEntryAndProof(context.Context, *GetEntryAndProofRequest) (*GetEntryAndProofResponse, error)
+	// Initialises a log by storing its first, empty, signed root. Until then,
+	// RPCs needing the root of the log fail with FAILED_PRECONDITION and a
+	// PreconditionFailure violation of type TREE_NEEDS_INIT.
 	InitLog(context.Context, *InitLogRequest) (*InitLogResponse, error)
 	// Adds a batch of leaves to the queue.
 	QueueLeaves(context.Context, *QueueLeavesRequest) (*QueueLeavesResponse, error)
@@ -1119,8 +1920,52 @@ type TrillianLogServer interface {
 	GetLeavesByIndex(context.Context, *GetLeavesByIndexRequest) (*GetLeavesByIndexResponse, error)
 	// Returns a batch of leaves in a sequential range.
 	GetLeavesByRange(context.Context, *GetLeavesByRangeRequest) (*GetLeavesByRangeResponse, error)
+	// Returns the leaves in a sequential range as a stream of batches, each of
+	// them in order and following the previous one. Unlike GetLeavesByRange,
+	// the server never holds the whole range in memory, so clients should use
+	// this RPC for ranges whose GetLeavesByRange response exceeds the server's
+	// memory limits.
+	StreamLeavesByRange(*GetLeavesByRangeRequest, TrillianLog_StreamLeavesByRangeServer) error
 	// Returns a batch of leaves by their `merkle_leaf_hash` values.
 	GetLeavesByHash(context.Context, *GetLeavesByHashRequest) (*GetLeavesByHashResponse, error)
+	// Returns log entries and the corresponding inclusion proofs for a batch of
+	// leaf indices, all proven against the same tree size.
+	GetEntriesAndProofs(context.Context, *GetEntriesAndProofsRequest) (*GetEntriesAndProofsResponse, error)
+	// Stores a contiguous range of leaves in a pre-ordered log, starting at
+	// `start_index`. Unlike AddSequencedLeaves, the range may be large: the
+	// server checks that it's contiguous, then writes it in chunks, each in its
+	// own storage transaction. If a chunk fails after earlier ones were
+	// written, the response reports how far the range got, and the caller can
+	// resume from `next_index`.
+	AddSequencedLeafRange(context.Context, *AddSequencedLeafRangeRequest) (*AddSequencedLeafRangeResponse, error)
+	// Returns the indices of the leaves with a key in one of the log's
+	// secondary leaf indexes, as defined by `Tree.leaf_indexes`. Indexes are
+	// maintained by the signer, but aren't part of the Merkle tree: they
+	// can't prove that a key has no other leaves, and callers should check
+	// the leaves they're pointed to.
+	GetLeafIndicesByKey(context.Context, *GetLeafIndicesByKeyRequest) (*GetLeafIndicesByKeyResponse, error)
+	// Checks leaf identity hashes against the log's leaf filter, as defined
+	// by `Tree.leaf_filter`, so that front-ends can cheaply reject duplicate
+	// submissions before queueing them. A hash which isn't in the filter has
+	// definitely not been integrated; one which is may have been. Leaves which
+	// are queued but not yet integrated aren't in the filter.
+	CheckLeafFilter(context.Context, *CheckLeafFilterRequest) (*CheckLeafFilterResponse, error)
+	// Returns a chain of consistency proofs from `first_tree_size` to the
+	// size of the log's latest root, through the power-of-two sizes in
+	// between, so that a client which was offline for a long time can catch
+	// up with O(log n) proofs. The root hash at each power-of-two size is
+	// returned too: these sizes are shared by all clients, which may keep
+	// them as verified checkpoints.
+	GetConsistencyProofChain(context.Context, *GetConsistencyProofChainRequest) (*GetConsistencyProofChainResponse, error)
+	// Adds a root of the log observed by another party, e.g. a monitor or
+	// another client, to the log's gossip pool. The root's signature must
+	// verify with the log's public key. A root which conflicts with another
+	// root of the same size in the pool, or with the log's own history, is
+	// evidence of a split view: it's kept in the pool, and reported in the
+	// response.
+	AddObservedRoot(context.Context, *AddObservedRootRequest) (*AddObservedRootResponse, error)
+	// Returns the roots of the log in its gossip pool.
+	GetObservedRoots(context.Context, *GetObservedRootsRequest) (*GetObservedRootsResponse, error)
 }
 
 func RegisterTrillianLogServer(s *grpc.Server, srv TrillianLogServer) {
@@ -1361,6 +2206,27 @@ func _TrillianLog_GetLeavesByRange_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _TrillianLog_StreamLeavesByRange_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetLeavesByRangeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TrillianLogServer).StreamLeavesByRange(m, &trillianLog_StreamLeavesByRangeServer{stream})
+}
+
+type TrillianLog_StreamLeavesByRangeServer interface {
+	Send(*GetLeavesByRangeResponse) error
+	grpc.ServerStream
+}
+
+type trillianLog_StreamLeavesByRangeServer struct {
+	grpc.ServerStream
+}
+
+func (x *trillianLog_StreamLeavesByRangeServer) Send(m *GetLeavesByRangeResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 func _TrillianLog_GetLeavesByHash_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetLeavesByHashRequest)
 	if err := dec(in); err != nil {
@@ -1379,6 +2245,132 @@ func _TrillianLog_GetLeavesByHash_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _TrillianLog_GetEntriesAndProofs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetEntriesAndProofsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrillianLogServer).GetEntriesAndProofs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/trillian.TrillianLog/GetEntriesAndProofs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrillianLogServer).GetEntriesAndProofs(ctx, req.(*GetEntriesAndProofsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TrillianLog_AddSequencedLeafRange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddSequencedLeafRangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrillianLogServer).AddSequencedLeafRange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/trillian.TrillianLog/AddSequencedLeafRange",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrillianLogServer).AddSequencedLeafRange(ctx, req.(*AddSequencedLeafRangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TrillianLog_GetLeafIndicesByKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLeafIndicesByKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrillianLogServer).GetLeafIndicesByKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/trillian.TrillianLog/GetLeafIndicesByKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrillianLogServer).GetLeafIndicesByKey(ctx, req.(*GetLeafIndicesByKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TrillianLog_CheckLeafFilter_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckLeafFilterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrillianLogServer).CheckLeafFilter(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/trillian.TrillianLog/CheckLeafFilter",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrillianLogServer).CheckLeafFilter(ctx, req.(*CheckLeafFilterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TrillianLog_GetConsistencyProofChain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetConsistencyProofChainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrillianLogServer).GetConsistencyProofChain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/trillian.TrillianLog/GetConsistencyProofChain",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrillianLogServer).GetConsistencyProofChain(ctx, req.(*GetConsistencyProofChainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TrillianLog_AddObservedRoot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddObservedRootRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrillianLogServer).AddObservedRoot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/trillian.TrillianLog/AddObservedRoot",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrillianLogServer).AddObservedRoot(ctx, req.(*AddObservedRootRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TrillianLog_GetObservedRoots_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetObservedRootsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrillianLogServer).GetObservedRoots(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/trillian.TrillianLog/GetObservedRoots",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrillianLogServer).GetObservedRoots(ctx, req.(*GetObservedRootsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _TrillianLog_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "trillian.TrillianLog",
 	HandlerType: (*TrillianLogServer)(nil),
@@ -1439,101 +2431,185 @@ var _TrillianLog_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetLeavesByHash",
 			Handler:    _TrillianLog_GetLeavesByHash_Handler,
 		},
+		{
+			MethodName: "GetEntriesAndProofs",
+			Handler:    _TrillianLog_GetEntriesAndProofs_Handler,
+		},
+		{
+			MethodName: "AddSequencedLeafRange",
+			Handler:    _TrillianLog_AddSequencedLeafRange_Handler,
+		},
+		{
+			MethodName: "GetLeafIndicesByKey",
+			Handler:    _TrillianLog_GetLeafIndicesByKey_Handler,
+		},
+		{
+			MethodName: "CheckLeafFilter",
+			Handler:    _TrillianLog_CheckLeafFilter_Handler,
+		},
+		{
+			MethodName: "GetConsistencyProofChain",
+			Handler:    _TrillianLog_GetConsistencyProofChain_Handler,
+		},
+		{
+			MethodName: "AddObservedRoot",
+			Handler:    _TrillianLog_AddObservedRoot_Handler,
+		},
+		{
+			MethodName: "GetObservedRoots",
+			Handler:    _TrillianLog_GetObservedRoots_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamLeavesByRange",
+			Handler:       _TrillianLog_StreamLeavesByRange_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "trillian_log_api.proto",
 }
 
 func init() { proto.RegisterFile("trillian_log_api.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 1404 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x58, 0x5d, 0x6f, 0x1b, 0x45,
-	0x17, 0xee, 0xc6, 0x89, 0x93, 0x9c, 0x34, 0x71, 0x32, 0x79, 0xdb, 0x38, 0x9b, 0xe6, 0x6d, 0x3a,
-	0x69, 0x1a, 0x37, 0x14, 0x2f, 0x29, 0x2a, 0xa0, 0xa8, 0x02, 0xd5, 0x4d, 0x95, 0x86, 0x06, 0x1a,
-	0x9c, 0xaa, 0x20, 0x10, 0x5a, 0x8d, 0xbd, 0x13, 0x67, 0xc5, 0x66, 0xc7, 0xdd, 0x1d, 0x57, 0x71,
-	0xab, 0xde, 0x20, 0x71, 0xc9, 0x15, 0x5c, 0x70, 0x83, 0xe0, 0x8e, 0xbf, 0x83, 0xc4, 0x5f, 0xe0,
-	0x82, 0x9f, 0x81, 0x76, 0x66, 0xd6, 0xfb, 0xe1, 0xdd, 0x75, 0x22, 0x95, 0x3b, 0xef, 0x99, 0xf3,
-	0xf1, 0x9c, 0x33, 0xe7, 0x9c, 0x79, 0x64, 0xb8, 0xca, 0x3d, 0xdb, 0x71, 0x6c, 0xe2, 0x9a, 0x0e,
-	0xeb, 0x98, 0xa4, 0x6b, 0xd7, 0xbb, 0x1e, 0xe3, 0x0c, 0x4d, 0x85, 0x72, 0xfd, 0x5a, 0x87, 0xb1,
-	0x8e, 0x43, 0x0d, 0xd2, 0xb5, 0x0d, 0xe2, 0xba, 0x8c, 0x13, 0x6e, 0x33, 0xd7, 0x97, 0x7a, 0xfa,
-	0x75, 0x75, 0x2a, 0xbe, 0x5a, 0xbd, 0x63, 0x83, 0xdb, 0xa7, 0xd4, 0xe7, 0xe4, 0xb4, 0xab, 0x14,
-	0x96, 0x94, 0x82, 0xd7, 0x6d, 0x1b, 0x3e, 0x27, 0xbc, 0x17, 0x5a, 0xce, 0x85, 0x11, 0xe4, 0x37,
-	0x3e, 0x84, 0xf9, 0x2f, 0x7a, 0xb4, 0x47, 0x0f, 0x28, 0x39, 0x6e, 0xd2, 0x17, 0x3d, 0xea, 0x73,
-	0x74, 0x05, 0xca, 0x01, 0x2c, 0xdb, 0xaa, 0x6a, 0x6b, 0x5a, 0xad, 0xd4, 0x9c, 0x70, 0x58, 0x67,
-	0xdf, 0x42, 0x1b, 0x30, 0xee, 0x50, 0x72, 0x5c, 0x1d, 0x5b, 0xd3, 0x6a, 0x33, 0x77, 0x17, 0xea,
-	0x03, 0x4f, 0x07, 0xac, 0x23, 0xcc, 0xc5, 0x31, 0xfe, 0x0c, 0x16, 0x62, 0x1e, 0xfd, 0x2e, 0x73,
-	0x7d, 0x8a, 0x3e, 0x82, 0x99, 0x17, 0x81, 0xd0, 0x32, 0x63, 0x2e, 0x96, 0x22, 0x17, 0xc2, 0xc2,
-	0x0a, 0x1d, 0x81, 0xd4, 0x0d, 0x7e, 0xe3, 0x2f, 0x61, 0xe9, 0x81, 0x65, 0x1d, 0x05, 0xd0, 0xdc,
-	0xb6, 0x94, 0xbd, 0x1d, 0x9c, 0x4f, 0xa0, 0x3a, 0xec, 0x58, 0xc1, 0x35, 0xa0, 0xec, 0x51, 0xbf,
-	0xe7, 0xf0, 0x51, 0x48, 0x95, 0x1a, 0x3e, 0x85, 0xea, 0x1e, 0xe5, 0xfb, 0x6e, 0xdb, 0xe9, 0xf9,
-	0x36, 0x73, 0x0f, 0x3d, 0xc6, 0x46, 0xc1, 0x5c, 0x05, 0x08, 0x70, 0x98, 0xb6, 0x6b, 0xd1, 0x33,
-	0x11, 0xa7, 0xd4, 0x9c, 0x0e, 0x24, 0xfb, 0x81, 0x00, 0xad, 0xc0, 0x34, 0xf7, 0x28, 0x35, 0x7d,
-	0xfb, 0x15, 0xad, 0x96, 0xc4, 0xe9, 0x54, 0x20, 0x38, 0xb2, 0x5f, 0x51, 0xdc, 0x80, 0xe5, 0x8c,
-	0x70, 0x0a, 0xfc, 0x06, 0x4c, 0x74, 0x03, 0x81, 0xc2, 0x5e, 0x89, 0xb0, 0x4b, 0x3d, 0x79, 0x8a,
-	0x7f, 0xd5, 0xe0, 0xff, 0x43, 0x4e, 0x1a, 0xfd, 0xc7, 0xc4, 0x3f, 0x19, 0x81, 0x7c, 0x05, 0x04,
-	0x4e, 0xf3, 0x84, 0xf8, 0x27, 0x22, 0xc8, 0xe5, 0xe6, 0x54, 0x20, 0x08, 0x4c, 0x0b, 0x71, 0xa3,
-	0x2d, 0x58, 0x60, 0x9e, 0x45, 0x3d, 0xb3, 0xd5, 0x37, 0x7d, 0x55, 0xf9, 0xea, 0xf8, 0x9a, 0x56,
-	0x9b, 0x6a, 0x56, 0xc4, 0x41, 0xa3, 0x1f, 0x5e, 0x08, 0x7e, 0x0c, 0xd7, 0x73, 0xe1, 0x0d, 0x67,
-	0x5a, 0x2a, 0xc8, 0xf4, 0x07, 0x0d, 0xf4, 0x3d, 0xca, 0x1f, 0x32, 0xd7, 0xb7, 0x7d, 0x4e, 0xdd,
-	0x76, 0xff, 0x3c, 0xf7, 0x73, 0x0b, 0x2a, 0xc7, 0xb6, 0xe7, 0x73, 0x33, 0x4a, 0x47, 0x5e, 0xd2,
-	0xac, 0x10, 0x3f, 0x0b, 0x73, 0xaa, 0xc1, 0xbc, 0x4f, 0xdb, 0xcc, 0xb5, 0xcc, 0x74, 0xde, 0x73,
-	0x52, 0x1e, 0x6a, 0xe2, 0x5d, 0x58, 0xc9, 0x84, 0x71, 0xb1, 0x7b, 0xfb, 0x00, 0x56, 0xf7, 0x28,
-	0x3f, 0x20, 0x9c, 0xfa, 0xfc, 0xc8, 0xee, 0xb8, 0xa2, 0x19, 0x9b, 0x8c, 0xf1, 0xe2, 0x7c, 0x30,
-	0x11, 0xd7, 0x9d, 0x69, 0xa7, 0x00, 0x7c, 0x02, 0x15, 0x5f, 0x1c, 0x88, 0xad, 0xe4, 0x31, 0x96,
-	0xd1, 0xfe, 0x49, 0xcb, 0x59, 0x3f, 0xfe, 0x89, 0xef, 0xc1, 0xb5, 0x3d, 0xca, 0x13, 0x23, 0xf5,
-	0x90, 0xf5, 0xdc, 0x51, 0xc8, 0x3e, 0x16, 0x19, 0x65, 0x99, 0x29, 0x60, 0xe1, 0xa8, 0xb4, 0x03,
-	0x69, 0x7c, 0x54, 0x84, 0x1a, 0x76, 0x60, 0x69, 0x8f, 0xf2, 0x47, 0x2e, 0xf7, 0xfa, 0x0f, 0x5c,
-	0xeb, 0xbf, 0x9e, 0xbd, 0x13, 0x31, 0xea, 0xa9, 0x68, 0x17, 0xba, 0xc2, 0xc1, 0x86, 0x2a, 0x15,
-	0x6f, 0xa8, 0x4d, 0x98, 0xdb, 0x77, 0x6d, 0x1e, 0x54, 0xb7, 0xb8, 0x80, 0xbb, 0x50, 0x19, 0x28,
-	0x2a, 0x24, 0xdb, 0x30, 0xd9, 0xf6, 0x28, 0xe1, 0x54, 0xaa, 0x16, 0xdc, 0x61, 0xa8, 0x87, 0x9f,
-	0x03, 0x0a, 0x17, 0xf7, 0x4b, 0xea, 0x8f, 0xa8, 0xe0, 0x6d, 0x28, 0x3b, 0x42, 0x4f, 0xcd, 0x5e,
-	0x46, 0x12, 0x4a, 0x01, 0x1f, 0xc1, 0x62, 0xc2, 0xaf, 0x42, 0x78, 0x1f, 0x66, 0xa3, 0x27, 0x21,
-	0x72, 0x94, 0xbb, 0x6a, 0x2f, 0x0f, 0x1e, 0x85, 0xc0, 0xe9, 0xb7, 0xb0, 0x9c, 0xda, 0xde, 0x6f,
-	0x15, 0xf3, 0x53, 0xd0, 0xb3, 0xdc, 0x47, 0xc5, 0x95, 0x7b, 0x7f, 0x24, 0xe8, 0x50, 0x0f, 0x3f,
-	0x15, 0x3d, 0x2a, 0xfd, 0x34, 0xfa, 0xa2, 0xcd, 0x2e, 0xd8, 0xa3, 0xa5, 0x44, 0x8f, 0xe2, 0x47,
-	0xa2, 0x0d, 0x53, 0x0e, 0x15, 0xbe, 0x0b, 0x24, 0xda, 0x49, 0xe0, 0x6a, 0x12, 0xb7, 0x43, 0x47,
-	0xe0, 0xba, 0x0e, 0x33, 0x3e, 0x27, 0x1e, 0x4f, 0x0c, 0x0f, 0x08, 0x91, 0x9c, 0x9e, 0xff, 0xc1,
-	0x84, 0x1c, 0x54, 0x39, 0x39, 0xf2, 0x23, 0x85, 0x57, 0x05, 0x1a, 0xc2, 0xab, 0x8d, 0xc2, 0x7b,
-	0x06, 0x57, 0x63, 0x6e, 0x2e, 0xfe, 0x58, 0x95, 0x12, 0x8f, 0x55, 0xe6, 0x7b, 0x54, 0xca, 0x7e,
-	0x8f, 0x76, 0x13, 0x95, 0x4a, 0xbc, 0x43, 0x17, 0xa8, 0x77, 0x0b, 0x66, 0x13, 0x1d, 0x32, 0xd8,
-	0x05, 0x5a, 0xe1, 0x2e, 0x40, 0x5b, 0x50, 0x96, 0x3c, 0x4e, 0xad, 0x16, 0x54, 0x97, 0x0c, 0xaf,
-	0xee, 0x75, 0xdb, 0xf5, 0x23, 0x71, 0xd2, 0x54, 0x1a, 0xf8, 0xcf, 0x31, 0x98, 0x0c, 0xdd, 0xd7,
-	0x60, 0xfe, 0x94, 0x7a, 0xdf, 0x39, 0xd4, 0x8c, 0xaa, 0xa0, 0x89, 0x27, 0x7b, 0x4e, 0xca, 0x0f,
-	0xc2, 0x5a, 0x84, 0xfd, 0xf6, 0x92, 0x38, 0x3d, 0xaa, 0x9e, 0x75, 0x51, 0xba, 0xe7, 0x81, 0x20,
-	0x38, 0xa6, 0x67, 0xdc, 0x23, 0xa6, 0x45, 0x38, 0x11, 0x35, 0xba, 0xdc, 0x9c, 0x16, 0x92, 0x5d,
-	0xc2, 0x49, 0xaa, 0x5b, 0xc7, 0xd3, 0x1b, 0xf5, 0x0e, 0x20, 0x79, 0x6c, 0x51, 0x97, 0xdb, 0xbc,
-	0x2f, 0x81, 0x4c, 0x08, 0x2f, 0xf3, 0x42, 0x4d, 0x1d, 0x08, 0x28, 0x0f, 0xa1, 0x22, 0x86, 0xdd,
-	0x1c, 0xd0, 0xda, 0x6a, 0x59, 0x64, 0xad, 0x87, 0x59, 0x87, 0xc4, 0xb7, 0xfe, 0x2c, 0xd4, 0x68,
-	0xce, 0x09, 0x93, 0xc1, 0x37, 0x7a, 0x02, 0x8b, 0xb6, 0xcb, 0x69, 0xc7, 0x23, 0x3c, 0xee, 0x68,
-	0x72, 0xa4, 0x23, 0x34, 0x30, 0x1b, 0xc8, 0xf0, 0x2e, 0x4c, 0x88, 0x0d, 0x9e, 0xca, 0x53, 0x4b,
-	0xe7, 0x79, 0x15, 0xca, 0x41, 0x66, 0xd4, 0xaf, 0x96, 0x44, 0xab, 0xa9, 0xaf, 0x4f, 0xc7, 0xa7,
-	0xc6, 0xe6, 0x4b, 0x77, 0xff, 0x99, 0x85, 0x99, 0x67, 0xea, 0x7e, 0x0f, 0x58, 0x07, 0xb9, 0x30,
-	0x3d, 0xa0, 0xca, 0x48, 0x4f, 0xed, 0x90, 0x18, 0xd3, 0xd5, 0x57, 0x32, 0xcf, 0x64, 0xf7, 0xe1,
-	0xda, 0xf7, 0x7f, 0xfd, 0xfd, 0xd3, 0x18, 0xc6, 0xab, 0xc6, 0xcb, 0xed, 0x16, 0xe5, 0x64, 0xdb,
-	0x70, 0x58, 0xc7, 0x37, 0x5e, 0xcb, 0x69, 0x78, 0x63, 0xc8, 0xce, 0xdb, 0xd1, 0xb6, 0xd0, 0x8f,
-	0x1a, 0xcc, 0xa7, 0x39, 0x2f, 0xba, 0x11, 0xf9, 0xce, 0x21, 0xda, 0x3a, 0x2e, 0x52, 0x51, 0x28,
-	0xee, 0x0a, 0x14, 0x77, 0xf0, 0x66, 0x31, 0x8a, 0x70, 0xca, 0xac, 0x00, 0xcf, 0xef, 0x1a, 0x2c,
-	0x0c, 0x71, 0x3c, 0x14, 0x8b, 0x96, 0xc7, 0xa9, 0xf5, 0xf5, 0x42, 0x1d, 0x05, 0xa9, 0x21, 0x20,
-	0xdd, 0x47, 0x3b, 0x85, 0x90, 0x8c, 0xd7, 0xd1, 0x85, 0xbe, 0xd9, 0xb1, 0x43, 0x57, 0xa6, 0x7c,
-	0xaa, 0xff, 0xd0, 0xc4, 0xd8, 0x67, 0xd1, 0x50, 0x54, 0x2b, 0x00, 0x91, 0xd8, 0x4d, 0xfa, 0xed,
-	0x73, 0x68, 0x2a, 0xd0, 0x1f, 0x0a, 0xd0, 0xdb, 0xc8, 0x28, 0xae, 0x63, 0x84, 0xb3, 0x25, 0x87,
-	0x09, 0xfd, 0xac, 0xc1, 0x62, 0x06, 0xbd, 0x44, 0x37, 0x13, 0xb1, 0x73, 0x48, 0xb0, 0xbe, 0x31,
-	0x42, 0x4b, 0xa1, 0x7b, 0x4f, 0xa0, 0xdb, 0x42, 0xb5, 0x6c, 0x74, 0x3b, 0xed, 0xc8, 0x50, 0x15,
-	0xf0, 0x17, 0x4d, 0x6e, 0xec, 0x61, 0xde, 0x89, 0x36, 0x13, 0x31, 0xf3, 0x19, 0xad, 0x5e, 0x1b,
-	0xad, 0xa8, 0xf0, 0xbd, 0x23, 0xf0, 0x6d, 0xa0, 0xf5, 0x9c, 0xea, 0x05, 0xa4, 0xd6, 0xdf, 0x71,
-	0x84, 0x07, 0xf4, 0x9b, 0x06, 0x57, 0x32, 0x89, 0x27, 0xba, 0x95, 0x08, 0x98, 0x4b, 0x68, 0xf5,
-	0xcd, 0x91, 0x7a, 0x0a, 0xd7, 0x3d, 0x81, 0xcb, 0x40, 0xef, 0x9e, 0x73, 0x3a, 0x24, 0xd5, 0x15,
-	0x03, 0x9b, 0x26, 0x9b, 0xf1, 0x81, 0xcd, 0xa1, 0xbd, 0x3a, 0x2e, 0x52, 0x49, 0x0e, 0x2c, 0xda,
-	0x3a, 0xff, 0x74, 0xa0, 0x36, 0x4c, 0x2a, 0xa2, 0x89, 0xaa, 0x51, 0x88, 0x24, 0x49, 0xd5, 0x97,
-	0x33, 0x4e, 0x54, 0xcc, 0x75, 0x11, 0x73, 0x15, 0xaf, 0xe4, 0xb4, 0x8f, 0xed, 0xda, 0x1c, 0x1d,
-	0xc0, 0x4c, 0x8c, 0x2f, 0xa2, 0x6b, 0xc3, 0xbb, 0x2f, 0xa2, 0x7a, 0xfa, 0x6a, 0xce, 0xa9, 0x0a,
-	0x78, 0x09, 0x11, 0x40, 0xc3, 0x4c, 0x0e, 0xad, 0xe7, 0x6e, 0xb4, 0x98, 0xef, 0x9b, 0xc5, 0x4a,
-	0x83, 0x10, 0xdf, 0x88, 0x4b, 0x4a, 0x50, 0xb1, 0xd4, 0x25, 0x65, 0xf1, 0xbe, 0xd4, 0x25, 0x65,
-	0x32, 0xb9, 0x21, 0xe7, 0x82, 0x37, 0xe5, 0x38, 0x8f, 0x93, 0xb7, 0x1c, 0xe7, 0x09, 0xda, 0x85,
-	0x2f, 0xa1, 0xaf, 0xa0, 0x92, 0xe2, 0x34, 0x68, 0x2d, 0xd3, 0x30, 0xbe, 0xcc, 0x6e, 0x14, 0x68,
-	0x84, 0x9e, 0x1b, 0x9f, 0xc3, 0x72, 0x9b, 0x9d, 0x86, 0xaf, 0x6c, 0xf2, 0x4f, 0xa7, 0xc6, 0x62,
-	0xec, 0x11, 0x7c, 0xd0, 0xb5, 0x0f, 0x03, 0xe1, 0xa1, 0xf6, 0xb5, 0xde, 0xb1, 0xf9, 0x49, 0xaf,
-	0x55, 0x6f, 0xb3, 0x53, 0x43, 0xfd, 0x7f, 0x15, 0x1a, 0xb6, 0xca, 0xc2, 0xf2, 0xfd, 0x7f, 0x03,
-	0x00, 0x00, 0xff, 0xff, 0xfb, 0x72, 0xd9, 0x4e, 0x3a, 0x13, 0x00, 0x00,
+	// 2195 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x59, 0x59, 0x6f, 0x1c, 0xc7,
+	0x11, 0xce, 0x70, 0x49, 0x6a, 0xb7, 0x78, 0xaa, 0x29, 0x91, 0xab, 0xa1, 0x28, 0x92, 0x2d, 0xd1,
+	0x5c, 0x31, 0x0e, 0x57, 0x54, 0xec, 0xc4, 0x20, 0xec, 0x04, 0x3c, 0x14, 0x9a, 0x11, 0x2d, 0x29,
+	0x43, 0x41, 0x71, 0x62, 0x18, 0xe3, 0xd9, 0x9d, 0xd6, 0x72, 0xc2, 0xd9, 0x99, 0xf5, 0x4c, 0x2f,
+	0xc5, 0xb5, 0xa1, 0x87, 0x04, 0x08, 0x90, 0x07, 0xe7, 0x25, 0x07, 0x90, 0x3c, 0x18, 0xf1, 0x53,
+	0x02, 0xe4, 0x0f, 0xe4, 0x6f, 0x04, 0xc8, 0x5f, 0xc8, 0x0f, 0x09, 0xfa, 0x98, 0x73, 0xe7, 0x20,
+	0x23, 0xfa, 0x85, 0xe0, 0x54, 0x57, 0x57, 0x7f, 0x55, 0xd5, 0x75, 0x74, 0x2d, 0xcc, 0x53, 0xcf,
+	0xb2, 0x6d, 0xcb, 0x70, 0x74, 0xdb, 0xed, 0xe8, 0x46, 0xcf, 0xda, 0xec, 0x79, 0x2e, 0x75, 0x51,
+	0x35, 0xa0, 0xab, 0xf5, 0xb6, 0x37, 0xe8, 0x51, 0xb7, 0xe9, 0x5b, 0x9d, 0x5e, 0x4b, 0xfc, 0x15,
+	0x3c, 0xea, 0xed, 0x8e, 0xeb, 0x76, 0x6c, 0xd2, 0x34, 0x7a, 0x56, 0xd3, 0x70, 0x1c, 0x97, 0x1a,
+	0xd4, 0x72, 0x1d, 0x5f, 0xae, 0xde, 0x92, 0xab, 0xfc, 0xab, 0xd5, 0x7f, 0xd9, 0x34, 0x9c, 0x81,
+	0x5c, 0x5a, 0x4e, 0x2f, 0x51, 0xab, 0x4b, 0x7c, 0x6a, 0x74, 0x7b, 0x92, 0x61, 0x41, 0x32, 0x78,
+	0xbd, 0x76, 0xd3, 0xa7, 0x06, 0xed, 0x07, 0x42, 0xa7, 0x03, 0x58, 0xe2, 0x1b, 0x3f, 0x83, 0xd9,
+	0x9f, 0xf5, 0x49, 0x9f, 0x1c, 0x11, 0xe3, 0xa5, 0x46, 0x3e, 0xef, 0x13, 0x9f, 0xa2, 0x9b, 0x30,
+	0xce, 0x74, 0xb1, 0xcc, 0xba, 0xb2, 0xa2, 0x34, 0x2a, 0xda, 0x98, 0xed, 0x76, 0x0e, 0x4d, 0xb4,
+	0x06, 0xa3, 0x36, 0x31, 0x5e, 0xd6, 0x47, 0x56, 0x94, 0xc6, 0xc4, 0xc3, 0xeb, 0x9b, 0xa1, 0xa4,
+	0x23, 0xb7, 0xc3, 0xb7, 0xf3, 0x65, 0xfc, 0x11, 0x5c, 0x8f, 0x49, 0xf4, 0x7b, 0xae, 0xe3, 0x13,
+	0xf4, 0x1e, 0x4c, 0x7c, 0xce, 0x88, 0xa6, 0x1e, 0x13, 0xb1, 0x10, 0x89, 0xe0, 0x3b, 0xcc, 0x40,
+	0x10, 0x08, 0x5e, 0xf6, 0x3f, 0xfe, 0x39, 0x2c, 0xec, 0x98, 0xe6, 0x31, 0x83, 0xe6, 0xb4, 0x89,
+	0x79, 0x75, 0x38, 0x1f, 0x43, 0x7d, 0x58, 0xb0, 0x84, 0xdb, 0x84, 0x71, 0x8f, 0xf8, 0x7d, 0x9b,
+	0x96, 0x21, 0x95, 0x6c, 0xb8, 0x0b, 0xf5, 0x03, 0x42, 0x0f, 0x9d, 0xb6, 0xdd, 0xf7, 0x2d, 0xd7,
+	0x79, 0xe6, 0xb9, 0x6e, 0x19, 0xcc, 0x25, 0x00, 0x86, 0x43, 0xb7, 0x1c, 0x93, 0x9c, 0xf3, 0x73,
+	0x2a, 0x5a, 0x8d, 0x51, 0x0e, 0x19, 0x01, 0x2d, 0x42, 0x8d, 0x7a, 0x84, 0xe8, 0xbe, 0xf5, 0x05,
+	0xa9, 0x57, 0xf8, 0x6a, 0x95, 0x11, 0x8e, 0xad, 0x2f, 0x08, 0xde, 0x85, 0x5b, 0x19, 0xc7, 0x49,
+	0xf0, 0x6b, 0x30, 0xd6, 0x63, 0x04, 0x89, 0x7d, 0x26, 0xc2, 0x2e, 0xf8, 0xc4, 0x2a, 0xfe, 0x5a,
+	0x81, 0x3b, 0x43, 0x42, 0x76, 0x07, 0x1f, 0x1a, 0xfe, 0x49, 0x09, 0xf2, 0x45, 0xe0, 0x38, 0xf5,
+	0x13, 0xc3, 0x3f, 0xe1, 0x87, 0x4c, 0x6a, 0x55, 0x46, 0x60, 0x5b, 0x0b, 0x71, 0xa3, 0x0d, 0xb8,
+	0xee, 0x7a, 0x26, 0xf1, 0xf4, 0xd6, 0x40, 0xf7, 0xa5, 0xe5, 0xeb, 0xa3, 0x2b, 0x4a, 0xa3, 0xaa,
+	0xcd, 0xf0, 0x85, 0xdd, 0x41, 0xe0, 0x10, 0xfc, 0x21, 0x2c, 0xe7, 0xc2, 0x1b, 0xd6, 0xb4, 0x52,
+	0xa0, 0xe9, 0x6f, 0x15, 0x50, 0x0f, 0x08, 0xdd, 0x73, 0x1d, 0xdf, 0xf2, 0x29, 0x71, 0xda, 0x83,
+	0x8b, 0xf8, 0xe7, 0x2d, 0x98, 0x79, 0x69, 0x79, 0x3e, 0xd5, 0x23, 0x75, 0x84, 0x93, 0xa6, 0x38,
+	0xf9, 0x79, 0xa0, 0x53, 0x03, 0x66, 0x7d, 0xd2, 0x76, 0x1d, 0x53, 0x4f, 0xeb, 0x3d, 0x2d, 0xe8,
+	0x01, 0x27, 0xde, 0x87, 0xc5, 0x4c, 0x18, 0x97, 0xf3, 0xdb, 0x67, 0xb0, 0x9c, 0x21, 0x65, 0xef,
+	0xc4, 0xb0, 0x9c, 0xab, 0xd1, 0x08, 0xbf, 0x82, 0x1b, 0x69, 0xf1, 0xc7, 0x94, 0xf4, 0x92, 0xae,
+	0x55, 0x52, 0xae, 0x5d, 0x84, 0x9a, 0xe7, 0xba, 0x34, 0x71, 0x29, 0x18, 0x81, 0x5f, 0x8a, 0x50,
+	0xb5, 0x4a, 0xa1, 0x6a, 0x7f, 0x55, 0x60, 0x25, 0x5f, 0x37, 0x69, 0xa6, 0x77, 0x60, 0xcc, 0xa7,
+	0xa4, 0xe7, 0xd7, 0x15, 0xee, 0xf4, 0x3b, 0x91, 0xac, 0x2c, 0xd0, 0x9a, 0x60, 0x46, 0x3f, 0x86,
+	0x19, 0xdf, 0xea, 0x38, 0x2c, 0x01, 0xb9, 0x1d, 0x9d, 0x01, 0x1b, 0x0e, 0xed, 0x63, 0xce, 0x70,
+	0xe4, 0x76, 0x34, 0xd7, 0xa5, 0xda, 0x94, 0x1f, 0xff, 0xc4, 0x5f, 0x29, 0x30, 0xbf, 0x63, 0x9a,
+	0x4f, 0x5b, 0x3e, 0xf1, 0xce, 0x88, 0xc9, 0x59, 0x8a, 0xcd, 0xfd, 0xa6, 0x47, 0x22, 0x15, 0xaa,
+	0xae, 0x38, 0xce, 0xe3, 0x86, 0xab, 0x69, 0xe1, 0x37, 0x7e, 0x0f, 0x16, 0x86, 0xd0, 0x48, 0x03,
+	0x2d, 0x01, 0xf8, 0x3d, 0xdb, 0xa2, 0xfa, 0x99, 0x45, 0x5e, 0x71, 0x48, 0x55, 0xad, 0xc6, 0x29,
+	0x2f, 0x2c, 0xf2, 0x0a, 0xff, 0x53, 0x81, 0xc9, 0xf8, 0xbe, 0x2c, 0x9c, 0xca, 0xff, 0x8d, 0x73,
+	0x24, 0x89, 0x13, 0x7d, 0x00, 0x93, 0x1e, 0x69, 0x13, 0xeb, 0x8c, 0xe8, 0xac, 0x46, 0xc9, 0x0b,
+	0xa0, 0x6e, 0x8a, 0xfa, 0xb4, 0x19, 0x14, 0xb0, 0xcd, 0xe7, 0x41, 0x01, 0xd3, 0x26, 0x24, 0x3f,
+	0xa3, 0xe0, 0x8f, 0x60, 0xe1, 0x80, 0xd0, 0x38, 0x5c, 0xbf, 0x3c, 0x39, 0xa5, 0xaf, 0x77, 0x94,
+	0x37, 0xbf, 0x52, 0xa0, 0x3e, 0x2c, 0x4f, 0xda, 0xed, 0x03, 0x98, 0x96, 0xb0, 0x4d, 0x6e, 0x85,
+	0xe0, 0x86, 0xcd, 0x47, 0x66, 0x48, 0xd8, 0x7b, 0xca, 0x8d, 0x8b, 0x41, 0x5b, 0x70, 0x33, 0x32,
+	0x7b, 0x14, 0x62, 0x3e, 0x4f, 0x4e, 0x15, 0x0d, 0x85, 0x1e, 0x08, 0xe2, 0xcc, 0xc7, 0x3f, 0x80,
+	0xa5, 0x03, 0x42, 0x8f, 0x0c, 0x4a, 0x7c, 0x9a, 0xb4, 0x70, 0xa1, 0x8e, 0xd8, 0x80, 0x3b, 0x79,
+	0xfb, 0xa4, 0x2e, 0x6f, 0x7c, 0xdd, 0xdf, 0x85, 0xdb, 0x07, 0x84, 0x26, 0xaa, 0xe3, 0x9e, 0xdb,
+	0x77, 0xca, 0x90, 0xfd, 0x08, 0x96, 0x72, 0xb6, 0x45, 0x97, 0x93, 0xd7, 0x8e, 0x36, 0xa3, 0xc6,
+	0xab, 0x1e, 0x67, 0xc3, 0x7f, 0x50, 0xb8, 0xc3, 0x1f, 0x39, 0xd4, 0x1b, 0xec, 0x38, 0xe6, 0xb7,
+	0x5c, 0x47, 0xd1, 0x3d, 0x98, 0x76, 0xbb, 0x16, 0xe5, 0x4d, 0x89, 0x6e, 0x1a, 0xd4, 0x90, 0xc5,
+	0x68, 0x92, 0x51, 0x19, 0xf8, 0x7d, 0x83, 0x1a, 0xf8, 0x04, 0xea, 0xc3, 0x98, 0x2e, 0x95, 0xb4,
+	0xc3, 0x9e, 0xa4, 0x52, 0xdc, 0x93, 0xfc, 0x02, 0xa6, 0x0f, 0x1d, 0x8b, 0x32, 0x27, 0x14, 0x2b,
+	0xfd, 0x00, 0xaa, 0x5d, 0x42, 0x0d, 0x0e, 0x59, 0x9c, 0x7c, 0x63, 0x28, 0xa4, 0x76, 0x9c, 0x81,
+	0x16, 0x72, 0xe1, 0x7d, 0x98, 0x09, 0x45, 0x4b, 0xec, 0x5b, 0x70, 0xad, 0xed, 0x11, 0x83, 0x12,
+	0xb3, 0x2c, 0xe0, 0x03, 0x3e, 0xfc, 0x02, 0x50, 0xd0, 0xdc, 0x9d, 0x91, 0xb2, 0x50, 0xbc, 0x0f,
+	0xe3, 0x36, 0xe7, 0x93, 0xf5, 0x39, 0x43, 0x6d, 0xc9, 0x80, 0x8f, 0x61, 0x2e, 0x21, 0x57, 0x22,
+	0x7c, 0x1f, 0xa6, 0xa2, 0xb6, 0x31, 0x12, 0x94, 0xdb, 0x8e, 0x4d, 0x86, 0x8d, 0x23, 0x13, 0xfa,
+	0x29, 0xdc, 0x4a, 0x75, 0x78, 0x57, 0x8a, 0xf9, 0x29, 0xa8, 0x59, 0xe2, 0x23, 0xe3, 0x8a, 0xde,
+	0xb0, 0x14, 0x74, 0xc0, 0x87, 0x7f, 0xad, 0xc0, 0xed, 0xa1, 0x96, 0xd4, 0x70, 0x3a, 0xa4, 0x04,
+	0xf3, 0x32, 0x4c, 0xf8, 0xd4, 0xf0, 0x68, 0x22, 0x04, 0x80, 0x93, 0x44, 0x0c, 0x44, 0x4a, 0x55,
+	0xca, 0x94, 0xfa, 0x5a, 0x81, 0xa5, 0x1c, 0x0c, 0xc3, 0x8a, 0x29, 0x17, 0x53, 0x8c, 0x85, 0xa8,
+	0x43, 0xce, 0x93, 0xf8, 0x6a, 0x8c, 0x22, 0xe0, 0x6d, 0xc0, 0xb8, 0x78, 0xa3, 0xc8, 0xf0, 0x40,
+	0xc1, 0x55, 0xf6, 0x7a, 0xed, 0xcd, 0x63, 0xbe, 0xa2, 0x49, 0x0e, 0xfc, 0x77, 0xd1, 0xcb, 0x1d,
+	0x89, 0xf8, 0xb6, 0xda, 0xc4, 0xdf, 0x1d, 0x3c, 0x26, 0x83, 0xf2, 0x1c, 0xc1, 0xcf, 0xd6, 0x1d,
+	0xa3, 0x4b, 0x64, 0x8d, 0xaa, 0x71, 0xca, 0x13, 0xa3, 0x4b, 0xd0, 0x2c, 0x54, 0x4e, 0xc9, 0x80,
+	0x9f, 0x3e, 0xa9, 0xb1, 0x7f, 0xd3, 0x26, 0x1d, 0x1d, 0x32, 0xe9, 0x32, 0x4c, 0x74, 0x8d, 0x73,
+	0x3d, 0xb0, 0xc4, 0xd8, 0x8a, 0xd2, 0x18, 0xd3, 0xa0, 0x6b, 0x9c, 0x6b, 0xd2, 0x99, 0xdf, 0x28,
+	0xb0, 0x98, 0x09, 0x54, 0x9a, 0x71, 0x15, 0x26, 0x83, 0xb4, 0xc5, 0x16, 0xb9, 0x2d, 0x2b, 0xda,
+	0x84, 0x1d, 0xf1, 0x97, 0x99, 0x2d, 0x23, 0xc7, 0x57, 0x2e, 0x95, 0xe3, 0x3f, 0x85, 0xf9, 0xbd,
+	0x13, 0xd2, 0x3e, 0x65, 0x18, 0x7f, 0x62, 0xd9, 0x94, 0x78, 0x25, 0x66, 0x7c, 0x1b, 0x90, 0xc0,
+	0x6c, 0x12, 0x87, 0x5a, 0x74, 0x10, 0x34, 0x7b, 0x95, 0xc6, 0xa4, 0x36, 0xcb, 0x91, 0xcb, 0x05,
+	0xd6, 0xf4, 0xe1, 0xd7, 0xb0, 0x30, 0x24, 0x3e, 0x52, 0xbe, 0x6b, 0x0c, 0x5a, 0x84, 0x21, 0xef,
+	0xf0, 0xf4, 0x53, 0x69, 0x54, 0xb5, 0x09, 0x4e, 0x3b, 0xe2, 0xa4, 0x37, 0xaf, 0x60, 0x4f, 0x79,
+	0x25, 0x11, 0x51, 0xb9, 0x3b, 0xe0, 0x26, 0xbb, 0x64, 0x25, 0xa9, 0x24, 0x2a, 0x09, 0x7e, 0x04,
+	0xf5, 0x61, 0x81, 0x52, 0xa1, 0x4b, 0xa4, 0x8d, 0x4e, 0x02, 0xd7, 0x95, 0xc4, 0xf7, 0x0d, 0x18,
+	0x13, 0xf5, 0x54, 0xd4, 0x37, 0xf1, 0x91, 0xc2, 0x9b, 0x0c, 0xe2, 0x08, 0xaf, 0x52, 0x86, 0xf7,
+	0x1c, 0xe6, 0x63, 0x62, 0x2e, 0xff, 0x3c, 0xac, 0x24, 0x9e, 0x87, 0x99, 0x2f, 0xc0, 0x4a, 0xf6,
+	0x0b, 0x70, 0x3f, 0x61, 0xa9, 0xc4, 0xcb, 0xef, 0x12, 0xf6, 0xfe, 0xb3, 0xc8, 0x18, 0xac, 0x7c,
+	0x5b, 0xc4, 0x0f, 0x0a, 0xb8, 0xff, 0x46, 0x77, 0xe1, 0x2a, 0xba, 0x8a, 0x4f, 0x60, 0x31, 0x13,
+	0x56, 0x58, 0xfa, 0xae, 0x11, 0xb1, 0x26, 0x5d, 0x84, 0x23, 0x15, 0xf3, 0xba, 0x11, 0x2d, 0xd8,
+	0x82, 0x5b, 0x30, 0x95, 0xc8, 0xc5, 0x61, 0x03, 0xa2, 0x14, 0x36, 0x20, 0xb1, 0x54, 0x3c, 0x52,
+	0x9a, 0x8a, 0xff, 0x3d, 0x02, 0xd7, 0x02, 0xf1, 0x0d, 0x98, 0xed, 0x12, 0xef, 0xd4, 0x26, 0x7a,
+	0xe4, 0x7a, 0x85, 0xa7, 0xd3, 0x69, 0x41, 0x3f, 0x0a, 0x2e, 0x40, 0x60, 0xd8, 0x33, 0xc3, 0xee,
+	0x13, 0xf9, 0x50, 0xe4, 0x86, 0x7d, 0xc1, 0x08, 0x6c, 0x99, 0x9c, 0x53, 0xcf, 0x10, 0x76, 0x13,
+	0x19, 0xb9, 0xc6, 0x29, 0xcc, 0x68, 0x29, 0xb7, 0x8c, 0xa6, 0x9b, 0xbd, 0xec, 0x04, 0x35, 0xb6,
+	0xa2, 0x64, 0x25, 0x28, 0xb4, 0x07, 0x33, 0xbc, 0x5f, 0xd0, 0xc3, 0xe9, 0x59, 0x7d, 0xbc, 0xf4,
+	0x79, 0x32, 0xcd, 0xb7, 0x84, 0xdf, 0xe8, 0x31, 0xcc, 0x59, 0x0e, 0x25, 0x1d, 0xcf, 0xa0, 0x71,
+	0x41, 0xd7, 0x4a, 0x05, 0xa1, 0x70, 0x5b, 0x48, 0xc3, 0xfb, 0x30, 0xc6, 0x1d, 0x9a, 0xd2, 0x53,
+	0x49, 0xeb, 0x39, 0x0f, 0xe3, 0x4c, 0x33, 0x59, 0xd0, 0x27, 0x35, 0xf9, 0xf5, 0xd3, 0xd1, 0xea,
+	0xc8, 0x6c, 0x05, 0xff, 0x4b, 0x81, 0x29, 0x2e, 0xe6, 0x91, 0x73, 0x46, 0x6c, 0xb7, 0x47, 0xd0,
+	0x3a, 0xcc, 0x84, 0xd0, 0x74, 0xc7, 0x70, 0x5c, 0x5f, 0xca, 0x9c, 0x0e, 0xc9, 0x4f, 0x18, 0x95,
+	0x09, 0xee, 0x12, 0x7a, 0xe2, 0x9a, 0xb2, 0x48, 0xca, 0x2f, 0xb4, 0x06, 0xd3, 0x9e, 0x08, 0x18,
+	0xdd, 0xb4, 0x3a, 0xc4, 0xa7, 0xd2, 0x35, 0x53, 0x92, 0xba, 0xcf, 0x89, 0xec, 0x1c, 0x4f, 0xde,
+	0xc5, 0x80, 0x6f, 0x54, 0xdc, 0x82, 0x80, 0x2c, 0x19, 0x6f, 0xc2, 0xf8, 0x29, 0x19, 0xb0, 0xa8,
+	0x13, 0xce, 0x19, 0x3b, 0x25, 0x83, 0x43, 0x13, 0x77, 0x60, 0x4e, 0xe4, 0xf4, 0x24, 0x7c, 0x15,
+	0xaa, 0x44, 0xfe, 0x2f, 0x6f, 0x55, 0xf8, 0x8d, 0xde, 0x81, 0x1a, 0xcb, 0xfb, 0x06, 0xed, 0x7b,
+	0x44, 0x5e, 0xda, 0xf9, 0x4d, 0x31, 0x64, 0xdd, 0xb7, 0x3a, 0x16, 0x35, 0x6c, 0x7b, 0x20, 0x64,
+	0x6a, 0x11, 0xe3, 0xc3, 0xdf, 0xdd, 0x80, 0x89, 0xe7, 0x32, 0x04, 0x8e, 0xdc, 0x0e, 0x72, 0xa0,
+	0x16, 0x0e, 0x2d, 0x91, 0x9a, 0x6a, 0x68, 0x62, 0x33, 0x47, 0x75, 0x31, 0x73, 0x4d, 0xe8, 0x86,
+	0x1b, 0xbf, 0xf9, 0xcf, 0x7f, 0xff, 0x38, 0x82, 0xf1, 0x52, 0xf3, 0x6c, 0xab, 0x45, 0xa8, 0xb1,
+	0xd5, 0xb4, 0xdd, 0x8e, 0xdf, 0xfc, 0x52, 0x24, 0x98, 0xd7, 0x4d, 0x91, 0x91, 0xb6, 0x95, 0x0d,
+	0xf4, 0x7b, 0x05, 0x66, 0xd3, 0x6d, 0x16, 0x5a, 0x8d, 0x64, 0xe7, 0x8c, 0x3c, 0x55, 0x5c, 0xc4,
+	0x22, 0x51, 0x3c, 0xe4, 0x28, 0xde, 0xde, 0x56, 0x36, 0xf0, 0x7a, 0x31, 0x90, 0x20, 0x01, 0x9b,
+	0xe8, 0x1b, 0x05, 0xae, 0x0f, 0x4d, 0xdb, 0x50, 0x32, 0xe5, 0x64, 0x4e, 0x37, 0xd5, 0xbb, 0x85,
+	0x3c, 0x12, 0xd2, 0x2e, 0x87, 0xf4, 0x3e, 0xda, 0x2e, 0xc4, 0xd3, 0xfc, 0x32, 0xba, 0xf3, 0xaf,
+	0xb7, 0xad, 0x40, 0x94, 0x2e, 0x9e, 0x50, 0xff, 0x10, 0x4f, 0xc3, 0xac, 0x81, 0x20, 0x6a, 0x14,
+	0x80, 0x48, 0xd4, 0x2c, 0xf5, 0xfe, 0x05, 0x38, 0x25, 0xe8, 0x1f, 0x72, 0xd0, 0x5b, 0xa8, 0x59,
+	0x6c, 0xc4, 0x08, 0x67, 0x4b, 0xe4, 0x1b, 0xf4, 0x27, 0x05, 0xe6, 0x32, 0xc6, 0x58, 0xe8, 0x5e,
+	0xe2, 0xec, 0x9c, 0x71, 0xa4, 0xba, 0x56, 0xc2, 0x25, 0xd1, 0x3d, 0xe0, 0xe8, 0x36, 0x50, 0x23,
+	0x1b, 0xdd, 0x76, 0x3b, 0xda, 0x28, 0x0d, 0xf8, 0x17, 0x05, 0xe6, 0xb3, 0xc7, 0x06, 0x68, 0x3d,
+	0x71, 0x66, 0xfe, 0x40, 0x42, 0x6d, 0x94, 0x33, 0x4a, 0x7c, 0xdf, 0xe5, 0xf8, 0xd6, 0xd0, 0xdd,
+	0x1c, 0xeb, 0xf1, 0x09, 0xcb, 0xb6, 0xcd, 0x25, 0xa0, 0xbf, 0x29, 0x70, 0x33, 0x73, 0x6e, 0x80,
+	0xde, 0x4a, 0x1c, 0x98, 0x3b, 0x8f, 0x50, 0xd7, 0x4b, 0xf9, 0x24, 0xae, 0x77, 0x39, 0xae, 0x26,
+	0xfa, 0xde, 0x05, 0x43, 0x43, 0x4c, 0x2a, 0x78, 0xc0, 0xa6, 0xcb, 0x6e, 0x3c, 0x60, 0x73, 0x86,
+	0x16, 0xea, 0x05, 0xaa, 0x76, 0x10, 0xb0, 0x68, 0xe3, 0xe2, 0xd1, 0x81, 0xda, 0x70, 0x4d, 0x3e,
+	0xe7, 0x51, 0x3d, 0x3a, 0x22, 0x39, 0x3c, 0x50, 0x6f, 0x65, 0xac, 0xc8, 0x33, 0xef, 0xf2, 0x33,
+	0x97, 0xf0, 0x62, 0xce, 0xf5, 0xb1, 0x1c, 0x8b, 0xa2, 0x23, 0x98, 0x88, 0xbd, 0xca, 0xd1, 0xed,
+	0xe1, 0xdc, 0x17, 0x3d, 0xa8, 0xd5, 0xa5, 0x9c, 0x55, 0x79, 0xe0, 0x77, 0x90, 0x01, 0x68, 0xf8,
+	0xbd, 0x8c, 0xee, 0xe6, 0x66, 0xb4, 0x98, 0xec, 0x7b, 0xc5, 0x4c, 0xe1, 0x11, 0x9f, 0x70, 0x27,
+	0x25, 0x5a, 0xf4, 0x94, 0x93, 0xb2, 0xde, 0x03, 0x2a, 0x2e, 0x62, 0xc9, 0x11, 0xce, 0xfb, 0xe9,
+	0x1c, 0xe1, 0xf1, 0xa6, 0x5e, 0xc5, 0x45, 0x2c, 0xa1, 0xf0, 0xcf, 0x60, 0xee, 0x98, 0x7a, 0xc4,
+	0xe8, 0x7e, 0x3b, 0xf2, 0x1f, 0x28, 0xe8, 0x63, 0x98, 0x49, 0x75, 0xd3, 0x68, 0x25, 0x73, 0x6b,
+	0x3c, 0x5d, 0xae, 0x16, 0x70, 0x84, 0xd8, 0x4d, 0x98, 0x93, 0x77, 0x3b, 0xde, 0xc9, 0xa6, 0xd2,
+	0x5d, 0x4e, 0xff, 0xad, 0xae, 0x95, 0x70, 0x85, 0xa7, 0xfc, 0x0a, 0x6e, 0x66, 0x0e, 0x26, 0xe2,
+	0x29, 0xa2, 0x68, 0x7a, 0xa2, 0xae, 0x97, 0xf2, 0xa5, 0x34, 0x4a, 0xbf, 0xdd, 0x53, 0x1a, 0xe5,
+	0xcc, 0x20, 0xd4, 0xb5, 0x12, 0xae, 0xf0, 0x94, 0x8f, 0x61, 0x26, 0xf5, 0x40, 0x8e, 0x7b, 0x24,
+	0xfb, 0x69, 0xae, 0xae, 0x16, 0x70, 0x84, 0x92, 0x7d, 0xa8, 0x67, 0xd4, 0x0e, 0xfe, 0x3b, 0x0a,
+	0xba, 0x5f, 0x58, 0x5f, 0xe2, 0xbf, 0x23, 0xa9, 0x1b, 0x17, 0x61, 0x8d, 0xab, 0x93, 0xfa, 0x49,
+	0x22, 0xae, 0x4e, 0xf6, 0x6f, 0x27, 0xea, 0x6a, 0x01, 0x47, 0x2a, 0xf2, 0x9e, 0x26, 0xc6, 0xed,
+	0xc9, 0x9b, 0x99, 0xf5, 0x0b, 0x81, 0x8a, 0x8b, 0x58, 0x02, 0xe1, 0xbb, 0x4f, 0xe0, 0x56, 0xdb,
+	0xed, 0x06, 0x8d, 0x7a, 0xf2, 0xe7, 0xf1, 0xdd, 0xb9, 0x58, 0x93, 0xb8, 0xd3, 0xb3, 0x9e, 0x31,
+	0xe2, 0x33, 0xe5, 0x97, 0x6a, 0xc7, 0xa2, 0x27, 0xfd, 0xd6, 0x66, 0xdb, 0xed, 0x36, 0xc5, 0xc6,
+	0x66, 0xb0, 0xb1, 0x35, 0xce, 0x77, 0x7e, 0xff, 0x7f, 0x03, 0x00, 0x04, 0x5c, 0x5a, 0x54, 0x19,
+	0x20, 0x00, 0x00,
 }
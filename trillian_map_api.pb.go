@@ -284,6 +284,234 @@ func (m *GetSignedMapRootResponse) GetMapRoot() *SignedMapRoot {
 	return nil
 }
 
+// GetMapLeafHistoryRequest asks for the values of a key across a range of map
+// revisions.
+type GetMapLeafHistoryRequest struct {
+	MapId int64  `protobuf:"varint,1,opt,name=map_id,json=mapId" json:"map_id,omitempty"`
+	Index []byte `protobuf:"bytes,2,opt,name=index,proto3" json:"index,omitempty"`
+	// first_revision >= 0.
+	FirstRevision int64 `protobuf:"varint,3,opt,name=first_revision,json=firstRevision" json:"first_revision,omitempty"`
+	// last_revision >= first_revision. It must not be later than the latest
+	// revision of the map.
+	LastRevision int64 `protobuf:"varint,4,opt,name=last_revision,json=lastRevision" json:"last_revision,omitempty"`
+}
+
+func (m *GetMapLeafHistoryRequest) Reset()                    { *m = GetMapLeafHistoryRequest{} }
+func (m *GetMapLeafHistoryRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetMapLeafHistoryRequest) ProtoMessage()               {}
+func (*GetMapLeafHistoryRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{10} }
+
+func (m *GetMapLeafHistoryRequest) GetMapId() int64 {
+	if m != nil {
+		return m.MapId
+	}
+	return 0
+}
+
+func (m *GetMapLeafHistoryRequest) GetIndex() []byte {
+	if m != nil {
+		return m.Index
+	}
+	return nil
+}
+
+func (m *GetMapLeafHistoryRequest) GetFirstRevision() int64 {
+	if m != nil {
+		return m.FirstRevision
+	}
+	return 0
+}
+
+func (m *GetMapLeafHistoryRequest) GetLastRevision() int64 {
+	if m != nil {
+		return m.LastRevision
+	}
+	return 0
+}
+
+// MapLeafHistoryEntry is the value of a key at a revision of a map.
+type MapLeafHistoryEntry struct {
+	// map_root is the root of the revision.
+	MapRoot *SignedMapRoot `protobuf:"bytes,1,opt,name=map_root,json=mapRoot" json:"map_root,omitempty"`
+	// leaf_inclusion proves the key's value in map_root. Its leaf has no
+	// leaf_value if the key has no value at the revision.
+	LeafInclusion *MapLeafInclusion `protobuf:"bytes,2,opt,name=leaf_inclusion,json=leafInclusion" json:"leaf_inclusion,omitempty"`
+}
+
+func (m *MapLeafHistoryEntry) Reset()                    { *m = MapLeafHistoryEntry{} }
+func (m *MapLeafHistoryEntry) String() string            { return proto.CompactTextString(m) }
+func (*MapLeafHistoryEntry) ProtoMessage()               {}
+func (*MapLeafHistoryEntry) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{11} }
+
+func (m *MapLeafHistoryEntry) GetMapRoot() *SignedMapRoot {
+	if m != nil {
+		return m.MapRoot
+	}
+	return nil
+}
+
+func (m *MapLeafHistoryEntry) GetLeafInclusion() *MapLeafInclusion {
+	if m != nil {
+		return m.LeafInclusion
+	}
+	return nil
+}
+
+type GetMapLeafHistoryResponse struct {
+	// entries hold the value of the key at first_revision, followed by its
+	// value at each later revision up to last_revision in which it was set, in
+	// increasing revision order.
+	Entries []*MapLeafHistoryEntry `protobuf:"bytes,1,rep,name=entries" json:"entries,omitempty"`
+}
+
+func (m *GetMapLeafHistoryResponse) Reset()                    { *m = GetMapLeafHistoryResponse{} }
+func (m *GetMapLeafHistoryResponse) String() string            { return proto.CompactTextString(m) }
+func (*GetMapLeafHistoryResponse) ProtoMessage()               {}
+func (*GetMapLeafHistoryResponse) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{12} }
+
+func (m *GetMapLeafHistoryResponse) GetEntries() []*MapLeafHistoryEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+// GetMapDiffRequest asks for the keys whose values differ between two
+// revisions of a map.
+type GetMapDiffRequest struct {
+	MapId int64 `protobuf:"varint,1,opt,name=map_id,json=mapId" json:"map_id,omitempty"`
+	// from_revision >= 0.
+	FromRevision int64 `protobuf:"varint,2,opt,name=from_revision,json=fromRevision" json:"from_revision,omitempty"`
+	// to_revision > from_revision. It must not be later than the latest
+	// revision of the map.
+	ToRevision int64 `protobuf:"varint,3,opt,name=to_revision,json=toRevision" json:"to_revision,omitempty"`
+	// max_keys is the most keys returned. Zero means a server default.
+	MaxKeys int32 `protobuf:"varint,4,opt,name=max_keys,json=maxKeys" json:"max_keys,omitempty"`
+	// start_index, if set, restricts the diff to keys >= start_index. It's used
+	// to continue a diff from the next_index of a previous response.
+	StartIndex []byte `protobuf:"bytes,5,opt,name=start_index,json=startIndex,proto3" json:"start_index,omitempty"`
+}
+
+func (m *GetMapDiffRequest) Reset()                    { *m = GetMapDiffRequest{} }
+func (m *GetMapDiffRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetMapDiffRequest) ProtoMessage()               {}
+func (*GetMapDiffRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{13} }
+
+func (m *GetMapDiffRequest) GetMapId() int64 {
+	if m != nil {
+		return m.MapId
+	}
+	return 0
+}
+
+func (m *GetMapDiffRequest) GetFromRevision() int64 {
+	if m != nil {
+		return m.FromRevision
+	}
+	return 0
+}
+
+func (m *GetMapDiffRequest) GetToRevision() int64 {
+	if m != nil {
+		return m.ToRevision
+	}
+	return 0
+}
+
+func (m *GetMapDiffRequest) GetMaxKeys() int32 {
+	if m != nil {
+		return m.MaxKeys
+	}
+	return 0
+}
+
+func (m *GetMapDiffRequest) GetStartIndex() []byte {
+	if m != nil {
+		return m.StartIndex
+	}
+	return nil
+}
+
+// MapLeafDiff is a key whose value differs between two revisions of a map.
+type MapLeafDiff struct {
+	Index []byte `protobuf:"bytes,1,opt,name=index,proto3" json:"index,omitempty"`
+	// from proves the key's value in the from_root of the response. Its leaf
+	// has no leaf_value if the key has no value at that revision.
+	From *MapLeafInclusion `protobuf:"bytes,2,opt,name=from" json:"from,omitempty"`
+	// to proves the key's value in the to_root of the response. Its leaf has
+	// no leaf_value if the key has no value at that revision.
+	To *MapLeafInclusion `protobuf:"bytes,3,opt,name=to" json:"to,omitempty"`
+}
+
+func (m *MapLeafDiff) Reset()                    { *m = MapLeafDiff{} }
+func (m *MapLeafDiff) String() string            { return proto.CompactTextString(m) }
+func (*MapLeafDiff) ProtoMessage()               {}
+func (*MapLeafDiff) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{14} }
+
+func (m *MapLeafDiff) GetIndex() []byte {
+	if m != nil {
+		return m.Index
+	}
+	return nil
+}
+
+func (m *MapLeafDiff) GetFrom() *MapLeafInclusion {
+	if m != nil {
+		return m.From
+	}
+	return nil
+}
+
+func (m *MapLeafDiff) GetTo() *MapLeafInclusion {
+	if m != nil {
+		return m.To
+	}
+	return nil
+}
+
+type GetMapDiffResponse struct {
+	FromRoot *SignedMapRoot `protobuf:"bytes,1,opt,name=from_root,json=fromRoot" json:"from_root,omitempty"`
+	ToRoot   *SignedMapRoot `protobuf:"bytes,2,opt,name=to_root,json=toRoot" json:"to_root,omitempty"`
+	// diffs hold the changed keys, in increasing index order.
+	Diffs []*MapLeafDiff `protobuf:"bytes,3,rep,name=diffs" json:"diffs,omitempty"`
+	// next_index is set if there may be more changed keys than were returned,
+	// and is the start_index to request them with.
+	NextIndex []byte `protobuf:"bytes,4,opt,name=next_index,json=nextIndex,proto3" json:"next_index,omitempty"`
+}
+
+func (m *GetMapDiffResponse) Reset()                    { *m = GetMapDiffResponse{} }
+func (m *GetMapDiffResponse) String() string            { return proto.CompactTextString(m) }
+func (*GetMapDiffResponse) ProtoMessage()               {}
+func (*GetMapDiffResponse) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{15} }
+
+func (m *GetMapDiffResponse) GetFromRoot() *SignedMapRoot {
+	if m != nil {
+		return m.FromRoot
+	}
+	return nil
+}
+
+func (m *GetMapDiffResponse) GetToRoot() *SignedMapRoot {
+	if m != nil {
+		return m.ToRoot
+	}
+	return nil
+}
+
+func (m *GetMapDiffResponse) GetDiffs() []*MapLeafDiff {
+	if m != nil {
+		return m.Diffs
+	}
+	return nil
+}
+
+func (m *GetMapDiffResponse) GetNextIndex() []byte {
+	if m != nil {
+		return m.NextIndex
+	}
+	return nil
+}
+
 type InitMapRequest struct {
 	MapId int64 `protobuf:"varint,1,opt,name=map_id,json=mapId" json:"map_id,omitempty"`
 }
@@ -291,7 +519,7 @@ type InitMapRequest struct {
 func (m *InitMapRequest) Reset()                    { *m = InitMapRequest{} }
 func (m *InitMapRequest) String() string            { return proto.CompactTextString(m) }
 func (*InitMapRequest) ProtoMessage()               {}
-func (*InitMapRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{10} }
+func (*InitMapRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{16} }
 
 func (m *InitMapRequest) GetMapId() int64 {
 	if m != nil {
@@ -307,7 +535,7 @@ type InitMapResponse struct {
 func (m *InitMapResponse) Reset()                    { *m = InitMapResponse{} }
 func (m *InitMapResponse) String() string            { return proto.CompactTextString(m) }
 func (*InitMapResponse) ProtoMessage()               {}
-func (*InitMapResponse) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{11} }
+func (*InitMapResponse) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{17} }
 
 func (m *InitMapResponse) GetCreated() *SignedMapRoot {
 	if m != nil {
@@ -327,6 +555,12 @@ func init() {
 	proto.RegisterType((*GetSignedMapRootRequest)(nil), "trillian.GetSignedMapRootRequest")
 	proto.RegisterType((*GetSignedMapRootByRevisionRequest)(nil), "trillian.GetSignedMapRootByRevisionRequest")
 	proto.RegisterType((*GetSignedMapRootResponse)(nil), "trillian.GetSignedMapRootResponse")
+	proto.RegisterType((*GetMapLeafHistoryRequest)(nil), "trillian.GetMapLeafHistoryRequest")
+	proto.RegisterType((*MapLeafHistoryEntry)(nil), "trillian.MapLeafHistoryEntry")
+	proto.RegisterType((*GetMapLeafHistoryResponse)(nil), "trillian.GetMapLeafHistoryResponse")
+	proto.RegisterType((*GetMapDiffRequest)(nil), "trillian.GetMapDiffRequest")
+	proto.RegisterType((*MapLeafDiff)(nil), "trillian.MapLeafDiff")
+	proto.RegisterType((*GetMapDiffResponse)(nil), "trillian.GetMapDiffResponse")
 	proto.RegisterType((*InitMapRequest)(nil), "trillian.InitMapRequest")
 	proto.RegisterType((*InitMapResponse)(nil), "trillian.InitMapResponse")
 }
@@ -346,9 +580,19 @@ type TrillianMapClient interface {
 	// For indexes that do not exist, the inclusion proof will use nil for the empty leaf value.
 	GetLeaves(ctx context.Context, in *GetMapLeavesRequest, opts ...grpc.CallOption) (*GetMapLeavesResponse, error)
 	GetLeavesByRevision(ctx context.Context, in *GetMapLeavesByRevisionRequest, opts ...grpc.CallOption) (*GetMapLeavesResponse, error)
+	// GetLeafHistory returns the values of a key across a range of revisions,
+	// with an inclusion proof for each of them.
+	GetLeafHistory(ctx context.Context, in *GetMapLeafHistoryRequest, opts ...grpc.CallOption) (*GetMapLeafHistoryResponse, error)
+	// GetMapDiff returns the keys whose values changed between two revisions,
+	// with inclusion proofs of their values at both of them. Unchanged branches
+	// of the map are skipped by comparing their hashes.
+	GetMapDiff(ctx context.Context, in *GetMapDiffRequest, opts ...grpc.CallOption) (*GetMapDiffResponse, error)
 	SetLeaves(ctx context.Context, in *SetMapLeavesRequest, opts ...grpc.CallOption) (*SetMapLeavesResponse, error)
 	GetSignedMapRoot(ctx context.Context, in *GetSignedMapRootRequest, opts ...grpc.CallOption) (*GetSignedMapRootResponse, error)
 	GetSignedMapRootByRevision(ctx context.Context, in *GetSignedMapRootByRevisionRequest, opts ...grpc.CallOption) (*GetSignedMapRootResponse, error)
+	// Initialises a map by storing its signed root at revision 0. Until then,
+	// RPCs needing the root of the map fail with FAILED_PRECONDITION and a
+	// PreconditionFailure violation of type TREE_NEEDS_INIT.
 	InitMap(ctx context.Context, in *InitMapRequest, opts ...grpc.CallOption) (*InitMapResponse, error)
 }
 
@@ -378,6 +622,24 @@ func (c *trillianMapClient) GetLeavesByRevision(ctx context.Context, in *GetMapL
 	return out, nil
 }
 
+func (c *trillianMapClient) GetLeafHistory(ctx context.Context, in *GetMapLeafHistoryRequest, opts ...grpc.CallOption) (*GetMapLeafHistoryResponse, error) {
+	out := new(GetMapLeafHistoryResponse)
+	err := grpc.Invoke(ctx, "/trillian.TrillianMap/GetLeafHistory", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *trillianMapClient) GetMapDiff(ctx context.Context, in *GetMapDiffRequest, opts ...grpc.CallOption) (*GetMapDiffResponse, error) {
+	out := new(GetMapDiffResponse)
+	err := grpc.Invoke(ctx, "/trillian.TrillianMap/GetMapDiff", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *trillianMapClient) SetLeaves(ctx context.Context, in *SetMapLeavesRequest, opts ...grpc.CallOption) (*SetMapLeavesResponse, error) {
 	out := new(SetMapLeavesResponse)
 	err := grpc.Invoke(ctx, "/trillian.TrillianMap/SetLeaves", in, out, c.cc, opts...)
@@ -421,9 +683,19 @@ type TrillianMapServer interface {
 	// For indexes that do not exist, the inclusion proof will use nil for the empty leaf value.
 	GetLeaves(context.Context, *GetMapLeavesRequest) (*GetMapLeavesResponse, error)
 	GetLeavesByRevision(context.Context, *GetMapLeavesByRevisionRequest) (*GetMapLeavesResponse, error)
+	// GetLeafHistory returns the values of a key across a range of revisions,
+	// with an inclusion proof for each of them.
+	GetLeafHistory(context.Context, *GetMapLeafHistoryRequest) (*GetMapLeafHistoryResponse, error)
+	// GetMapDiff returns the keys whose values changed between two revisions,
+	// with inclusion proofs of their values at both of them. Unchanged branches
+	// of the map are skipped by comparing their hashes.
+	GetMapDiff(context.Context, *GetMapDiffRequest) (*GetMapDiffResponse, error)
 	SetLeaves(context.Context, *SetMapLeavesRequest) (*SetMapLeavesResponse, error)
 	GetSignedMapRoot(context.Context, *GetSignedMapRootRequest) (*GetSignedMapRootResponse, error)
 	GetSignedMapRootByRevision(context.Context, *GetSignedMapRootByRevisionRequest) (*GetSignedMapRootResponse, error)
+	// Initialises a map by storing its signed root at revision 0. Until then,
+	// RPCs needing the root of the map fail with FAILED_PRECONDITION and a
+	// PreconditionFailure violation of type TREE_NEEDS_INIT.
 	InitMap(context.Context, *InitMapRequest) (*InitMapResponse, error)
 }
 
@@ -467,6 +739,42 @@ func _TrillianMap_GetLeavesByRevision_Handler(srv interface{}, ctx context.Conte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _TrillianMap_GetLeafHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMapLeafHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrillianMapServer).GetLeafHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/trillian.TrillianMap/GetLeafHistory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrillianMapServer).GetLeafHistory(ctx, req.(*GetMapLeafHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TrillianMap_GetMapDiff_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMapDiffRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrillianMapServer).GetMapDiff(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/trillian.TrillianMap/GetMapDiff",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrillianMapServer).GetMapDiff(ctx, req.(*GetMapDiffRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _TrillianMap_SetLeaves_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(SetMapLeavesRequest)
 	if err := dec(in); err != nil {
@@ -551,6 +859,14 @@ var _TrillianMap_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetLeavesByRevision",
 			Handler:    _TrillianMap_GetLeavesByRevision_Handler,
 		},
+		{
+			MethodName: "GetLeafHistory",
+			Handler:    _TrillianMap_GetLeafHistory_Handler,
+		},
+		{
+			MethodName: "GetMapDiff",
+			Handler:    _TrillianMap_GetMapDiff_Handler,
+		},
 		{
 			MethodName: "SetLeaves",
 			Handler:    _TrillianMap_SetLeaves_Handler,
@@ -575,50 +891,69 @@ var _TrillianMap_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("trillian_map_api.proto", fileDescriptor1) }
 
 var fileDescriptor1 = []byte{
-	// 711 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x55, 0x4d, 0x4f, 0xdb, 0x4a,
-	0x14, 0x7d, 0x4e, 0x02, 0x49, 0x6e, 0x9e, 0x78, 0x79, 0x43, 0x5a, 0x8c, 0x21, 0x15, 0x18, 0x21,
-	0x8a, 0x90, 0x62, 0x48, 0x77, 0xec, 0x40, 0x48, 0x7c, 0x08, 0x10, 0x72, 0x2a, 0x2a, 0x75, 0x93,
-	0x4e, 0x92, 0x21, 0x19, 0xc9, 0xf6, 0xb8, 0xf1, 0x24, 0x82, 0x22, 0x36, 0x5d, 0x74, 0xdb, 0x45,
-	0xbb, 0xea, 0xa2, 0x7f, 0xaa, 0x7f, 0xa1, 0x3f, 0xa4, 0x9a, 0xf1, 0xd8, 0xf9, 0x72, 0xd2, 0xa8,
-	0xdd, 0x65, 0xe6, 0xdc, 0x7b, 0xcf, 0xb9, 0xf7, 0x9e, 0x89, 0xe1, 0x39, 0xef, 0x52, 0xc7, 0xa1,
-	0xd8, 0xab, 0xbb, 0xd8, 0xaf, 0x63, 0x9f, 0x56, 0xfc, 0x2e, 0xe3, 0x0c, 0xe5, 0xa2, 0x7b, 0x63,
-	0x29, 0xfa, 0x15, 0x22, 0xc6, 0x7a, 0x9b, 0xb1, 0xb6, 0x43, 0x2c, 0xec, 0x53, 0x0b, 0x7b, 0x1e,
-	0xe3, 0x98, 0x53, 0xe6, 0x05, 0x0a, 0x5d, 0x55, 0xa8, 0x3c, 0x35, 0x7a, 0x77, 0x16, 0xf6, 0x1e,
-	0x42, 0xc8, 0xfc, 0x00, 0xd9, 0x2b, 0xec, 0x5f, 0x12, 0x7c, 0x87, 0x4a, 0xb0, 0x40, 0xbd, 0x16,
-	0xb9, 0xd7, 0xb5, 0x0d, 0xed, 0xe5, 0xbf, 0x76, 0x78, 0x40, 0x6b, 0x90, 0x77, 0x08, 0xbe, 0xab,
-	0x77, 0x70, 0xd0, 0xd1, 0x53, 0x12, 0xc9, 0x89, 0x8b, 0x33, 0x1c, 0x74, 0x50, 0x19, 0x40, 0x82,
-	0x7d, 0xec, 0xf4, 0x88, 0x9e, 0x96, 0xa8, 0x0c, 0xbf, 0x15, 0x17, 0x02, 0x26, 0xf7, 0xbc, 0x8b,
-	0xeb, 0x2d, 0xcc, 0xb1, 0x9e, 0x09, 0x61, 0x79, 0x73, 0x82, 0x39, 0x36, 0xdf, 0x40, 0x51, 0x71,
-	0x9f, 0x7b, 0x4d, 0xa7, 0x17, 0x50, 0xe6, 0xa1, 0x6d, 0xc8, 0x88, 0x7c, 0xa9, 0xa1, 0x50, 0xfd,
-	0xbf, 0x12, 0xf7, 0xa9, 0x22, 0x6d, 0x09, 0xa3, 0x75, 0xc8, 0xd3, 0x28, 0x47, 0x4f, 0x6d, 0xa4,
-	0x45, 0xe1, 0xf8, 0xc2, 0x3c, 0x83, 0xe5, 0x53, 0xc2, 0xc3, 0x8c, 0x3e, 0x09, 0x6c, 0xf2, 0xbe,
-	0x47, 0x02, 0x8e, 0x9e, 0xc1, 0xa2, 0x98, 0x27, 0x6d, 0xc9, 0xea, 0x69, 0x7b, 0xc1, 0xc5, 0xfe,
-	0x79, 0x6b, 0xd0, 0x77, 0x58, 0x27, 0x3c, 0x5c, 0x64, 0x72, 0xe9, 0x62, 0xc6, 0xec, 0x40, 0x79,
-	0xb8, 0xd2, 0xf1, 0x83, 0x4d, 0xfa, 0x54, 0x70, 0xfc, 0x49, 0x4d, 0x64, 0x40, 0xae, 0xab, 0xf2,
-	0xe5, 0xb0, 0xd2, 0x76, 0x7c, 0x36, 0xbf, 0x6a, 0x50, 0x1a, 0x15, 0x1d, 0xf8, 0xcc, 0x0b, 0x08,
-	0x3a, 0x03, 0x24, 0x18, 0xe4, 0x9c, 0x47, 0x7b, 0x2e, 0x54, 0x8d, 0x89, 0xf9, 0xc4, 0x93, 0xb4,
-	0x8b, 0xee, 0xf8, 0x6c, 0xab, 0x90, 0x13, 0x95, 0xba, 0x8c, 0x71, 0x49, 0x5f, 0xa8, 0xae, 0x0c,
-	0xf2, 0x6b, 0xb4, 0xed, 0x91, 0xd6, 0x15, 0xf6, 0x6d, 0xc6, 0xb8, 0x9d, 0x75, 0xc3, 0x1f, 0xe6,
-	0x67, 0x0d, 0x96, 0x6b, 0xf3, 0xcf, 0x72, 0x17, 0x16, 0x1d, 0x19, 0xa7, 0x04, 0x26, 0x2c, 0x50,
-	0x05, 0xa0, 0x7d, 0xc8, 0xb9, 0x84, 0xe3, 0xd8, 0x1a, 0x85, 0x6a, 0xa9, 0x12, 0xfa, 0xb4, 0x12,
-	0xf9, 0xb4, 0x72, 0xe4, 0x3d, 0xd8, 0x71, 0x94, 0x5a, 0xc9, 0x05, 0x94, 0x6a, 0x49, 0x73, 0x1a,
-	0xee, 0x2e, 0x35, 0x67, 0x77, 0xfb, 0xb0, 0x72, 0x4a, 0xf8, 0x28, 0x38, 0xb3, 0x41, 0xf3, 0x16,
-	0x36, 0xc7, 0x33, 0xe6, 0x36, 0xc5, 0xf0, 0xfa, 0x53, 0x63, 0xeb, 0xbf, 0x06, 0x7d, 0x52, 0xc9,
-	0x5f, 0x74, 0xb6, 0x03, 0x4b, 0xe7, 0x1e, 0x15, 0x63, 0xfa, 0x4d, 0x43, 0x27, 0xf0, 0x5f, 0x1c,
-	0xa8, 0xf8, 0x0e, 0x20, 0xdb, 0xec, 0x12, 0xcc, 0x49, 0x4b, 0x3d, 0xc3, 0xe9, 0x74, 0x2a, 0xae,
-	0xfa, 0x6d, 0x01, 0x0a, 0xaf, 0x55, 0xcc, 0x15, 0xf6, 0xd1, 0x25, 0xe4, 0x4f, 0x09, 0x0f, 0x37,
-	0x84, 0xca, 0x83, 0xf4, 0x84, 0x67, 0x69, 0xbc, 0x98, 0x06, 0x87, 0x72, 0xcc, 0x7f, 0xd0, 0x3b,
-	0xf9, 0x9e, 0xc7, 0x9f, 0x20, 0xda, 0x49, 0x4e, 0x9c, 0xd8, 0xc7, 0x1c, 0x0c, 0x97, 0x90, 0xaf,
-	0x25, 0xe9, 0xad, 0xcd, 0xd6, 0x5b, 0x4b, 0xae, 0xf6, 0x49, 0x83, 0xe2, 0xf8, 0x36, 0xd1, 0xe6,
-	0x88, 0x88, 0x24, 0xcf, 0x19, 0xe6, 0xac, 0x10, 0x55, 0x7d, 0xef, 0xe3, 0x8f, 0x9f, 0x5f, 0x52,
-	0xdb, 0x68, 0xcb, 0xea, 0x1f, 0x34, 0x08, 0xc7, 0x07, 0x96, 0x8b, 0xfd, 0xc0, 0x7a, 0x0c, 0x77,
-	0xfb, 0x64, 0x09, 0x97, 0x04, 0x87, 0x0e, 0xe6, 0x62, 0xe7, 0xdf, 0x35, 0x30, 0xa6, 0xdb, 0x15,
-	0xed, 0x4d, 0xe7, 0x9b, 0x1c, 0xe2, 0x3c, 0xe2, 0x2c, 0x29, 0x6e, 0x17, 0xed, 0xcc, 0x12, 0x67,
-	0x3d, 0x46, 0xae, 0x7f, 0x42, 0x4d, 0xc8, 0x2a, 0xf7, 0x21, 0x7d, 0x50, 0x7f, 0xd4, 0xb9, 0xc6,
-	0x6a, 0x02, 0xa2, 0x08, 0xb7, 0x24, 0x61, 0xd9, 0x5c, 0x4b, 0x26, 0x3c, 0xa4, 0x1e, 0xe5, 0xc7,
-	0xd7, 0xb0, 0xda, 0x64, 0x6e, 0xf4, 0xe7, 0x32, 0xfa, 0xe5, 0x3c, 0x5e, 0x1e, 0xb2, 0xed, 0x91,
-	0x4f, 0x6f, 0xc4, 0xe5, 0x8d, 0xf6, 0xd6, 0x68, 0x53, 0xde, 0xe9, 0x35, 0x2a, 0x4d, 0xe6, 0x5a,
-	0xea, 0xeb, 0x19, 0x25, 0x36, 0x16, 0x65, 0xe6, 0xab, 0x5f, 0x01, 0x00, 0x00, 0xff, 0xff, 0x60,
-	0x7f, 0xb2, 0x65, 0xa7, 0x07, 0x00, 0x00,
+	// 1012 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x56, 0x4f, 0x6f, 0xe3, 0x44,
+	0x14, 0xc7, 0x49, 0xda, 0x24, 0x2f, 0x6d, 0xe8, 0x4e, 0xbb, 0xac, 0xe3, 0xb6, 0xb0, 0xeb, 0xa8,
+	0x2a, 0x4b, 0xa5, 0xb8, 0x0d, 0x48, 0x48, 0x7b, 0x6b, 0x55, 0xd4, 0x66, 0x69, 0x57, 0x2b, 0x67,
+	0xb5, 0x48, 0x70, 0x08, 0xd3, 0x64, 0xd2, 0x8c, 0x70, 0x3c, 0xc6, 0x9e, 0x54, 0x09, 0xab, 0x15,
+	0x12, 0x07, 0x4e, 0x48, 0x1c, 0x96, 0x33, 0x9f, 0x80, 0x4f, 0xc1, 0x89, 0x3b, 0x5f, 0x81, 0x0f,
+	0x82, 0x66, 0x3c, 0x76, 0x1c, 0xc7, 0x4d, 0x22, 0xb8, 0x79, 0xde, 0xfb, 0xbd, 0x7f, 0xbf, 0x79,
+	0x6f, 0x9e, 0xe1, 0x03, 0xee, 0x53, 0xc7, 0xa1, 0xd8, 0xed, 0x0c, 0xb1, 0xd7, 0xc1, 0x1e, 0x6d,
+	0x78, 0x3e, 0xe3, 0x0c, 0x95, 0x22, 0xb9, 0x51, 0x8d, 0xbe, 0x42, 0x8d, 0xb1, 0x77, 0xcb, 0xd8,
+	0xad, 0x43, 0x2c, 0xec, 0x51, 0x0b, 0xbb, 0x2e, 0xe3, 0x98, 0x53, 0xe6, 0x06, 0x4a, 0x5b, 0x53,
+	0x5a, 0x79, 0xba, 0x19, 0xf5, 0x2d, 0xec, 0x4e, 0x42, 0x95, 0xf9, 0x03, 0x14, 0xaf, 0xb1, 0x77,
+	0x45, 0x70, 0x1f, 0xed, 0xc0, 0x1a, 0x75, 0x7b, 0x64, 0xac, 0x6b, 0x8f, 0xb5, 0x8f, 0x37, 0xec,
+	0xf0, 0x80, 0x76, 0xa1, 0xec, 0x10, 0xdc, 0xef, 0x0c, 0x70, 0x30, 0xd0, 0x73, 0x52, 0x53, 0x12,
+	0x82, 0x4b, 0x1c, 0x0c, 0xd0, 0x3e, 0x80, 0x54, 0xde, 0x61, 0x67, 0x44, 0xf4, 0xbc, 0xd4, 0x4a,
+	0xf8, 0x6b, 0x21, 0x10, 0x6a, 0x32, 0xe6, 0x3e, 0xee, 0xf4, 0x30, 0xc7, 0x7a, 0x21, 0x54, 0x4b,
+	0xc9, 0x39, 0xe6, 0xd8, 0xfc, 0x0a, 0xb6, 0x54, 0xec, 0x96, 0xdb, 0x75, 0x46, 0x01, 0x65, 0x2e,
+	0x3a, 0x80, 0x82, 0xb0, 0x97, 0x39, 0x54, 0x9a, 0x0f, 0x1a, 0x71, 0x9d, 0x0a, 0x69, 0x4b, 0x35,
+	0xda, 0x83, 0x32, 0x8d, 0x6c, 0xf4, 0xdc, 0xe3, 0xbc, 0x70, 0x1c, 0x0b, 0xcc, 0x4b, 0xd8, 0xbe,
+	0x20, 0x3c, 0xb4, 0xb8, 0x23, 0x81, 0x4d, 0xbe, 0x1f, 0x91, 0x80, 0xa3, 0x87, 0xb0, 0x2e, 0xf8,
+	0xa4, 0x3d, 0xe9, 0x3d, 0x6f, 0xaf, 0x0d, 0xb1, 0xd7, 0xea, 0x4d, 0xeb, 0x0e, 0xfd, 0x84, 0x87,
+	0xe7, 0x85, 0x52, 0x7e, 0xab, 0x60, 0x0e, 0x60, 0x3f, 0xe9, 0xe9, 0x6c, 0x62, 0x93, 0x3b, 0x2a,
+	0x62, 0xfc, 0x17, 0x9f, 0xc8, 0x80, 0x92, 0xaf, 0xec, 0x25, 0x59, 0x79, 0x3b, 0x3e, 0x9b, 0xbf,
+	0x69, 0xb0, 0x33, 0x9b, 0x74, 0xe0, 0x31, 0x37, 0x20, 0xe8, 0x12, 0x90, 0x88, 0x20, 0x79, 0x9e,
+	0xad, 0xb9, 0xd2, 0x34, 0xe6, 0xf8, 0x89, 0x99, 0xb4, 0xb7, 0x86, 0x69, 0x6e, 0x9b, 0x50, 0x12,
+	0x9e, 0x7c, 0xc6, 0xb8, 0x0c, 0x5f, 0x69, 0x3e, 0x9a, 0xda, 0xb7, 0xe9, 0xad, 0x4b, 0x7a, 0xd7,
+	0xd8, 0xb3, 0x19, 0xe3, 0x76, 0x71, 0x18, 0x7e, 0x98, 0xbf, 0x6a, 0xb0, 0xdd, 0x5e, 0x9d, 0xcb,
+	0xa7, 0xb0, 0xee, 0x48, 0x9c, 0x4a, 0x30, 0xe3, 0x02, 0x15, 0x00, 0x1d, 0x43, 0x69, 0x48, 0x38,
+	0x8e, 0x5b, 0xa3, 0xd2, 0xdc, 0x69, 0x84, 0x7d, 0xda, 0x88, 0xfa, 0xb4, 0x71, 0xea, 0x4e, 0xec,
+	0x18, 0xa5, 0xae, 0xe4, 0x39, 0xec, 0xb4, 0xb3, 0x78, 0x4a, 0x56, 0x97, 0x5b, 0xb1, 0xba, 0x63,
+	0x78, 0x74, 0x41, 0xf8, 0xac, 0x72, 0x61, 0x81, 0xe6, 0x6b, 0x78, 0x92, 0xb6, 0x58, 0xb9, 0x29,
+	0x92, 0xd7, 0x9f, 0x4b, 0x5d, 0xff, 0x0b, 0xd0, 0xe7, 0x33, 0xf9, 0x1f, 0x95, 0xbd, 0xd3, 0xa4,
+	0x43, 0x45, 0xfa, 0x25, 0x0d, 0x38, 0xf3, 0x27, 0xab, 0x37, 0x6d, 0xe2, 0x01, 0x38, 0x80, 0x6a,
+	0x9f, 0xfa, 0x01, 0xef, 0xa4, 0x5a, 0x77, 0x53, 0x4a, 0xa3, 0xd2, 0x51, 0x1d, 0x36, 0x1d, 0x9c,
+	0x44, 0x15, 0x24, 0x6a, 0xc3, 0xc1, 0x53, 0x90, 0xf9, 0x8b, 0x06, 0xdb, 0xb3, 0x29, 0x7d, 0xe1,
+	0x72, 0x7f, 0x32, 0x53, 0xa1, 0xb6, 0x5a, 0x85, 0xe8, 0x14, 0xaa, 0x73, 0x33, 0xa1, 0x2d, 0x99,
+	0x89, 0x4d, 0x27, 0x79, 0x34, 0x5f, 0x41, 0x2d, 0x83, 0x23, 0xc5, 0xfa, 0xe7, 0x50, 0x24, 0x2e,
+	0xf7, 0x29, 0x09, 0x74, 0x4d, 0xf6, 0xf2, 0xfe, 0x9c, 0xe3, 0x64, 0x0d, 0x76, 0x84, 0x36, 0xff,
+	0xd0, 0xe0, 0x41, 0xe8, 0xf6, 0x9c, 0xf6, 0xfb, 0x4b, 0x38, 0xaf, 0xc3, 0x66, 0xdf, 0x67, 0xc3,
+	0x4e, 0xaa, 0x31, 0x36, 0x84, 0x30, 0xe6, 0xf6, 0x23, 0xa8, 0x70, 0x96, 0xe6, 0x1f, 0x38, 0x8b,
+	0x01, 0x35, 0xc1, 0xdf, 0xb8, 0xf3, 0x1d, 0x99, 0x04, 0x92, 0xf7, 0x35, 0x41, 0xd3, 0xf8, 0x4b,
+	0x32, 0x09, 0x84, 0x6d, 0xc0, 0xb1, 0xcf, 0x3b, 0xe1, 0xd5, 0xae, 0xc9, 0xab, 0x05, 0x29, 0x6a,
+	0x09, 0x89, 0xf9, 0x23, 0x54, 0x54, 0x39, 0x22, 0xdd, 0x7b, 0xb6, 0x40, 0x03, 0x0a, 0x22, 0xa3,
+	0x15, 0x28, 0x96, 0x38, 0xf4, 0x09, 0xe4, 0x38, 0xd3, 0xf3, 0x4b, 0xd1, 0x39, 0xce, 0xcc, 0xbf,
+	0x34, 0x40, 0x49, 0xbe, 0x14, 0xff, 0x9f, 0x41, 0x39, 0x64, 0x66, 0x85, 0xa6, 0x28, 0x49, 0xba,
+	0x44, 0x57, 0x1c, 0x43, 0x91, 0xb3, 0xd0, 0x66, 0xc9, 0xa8, 0xac, 0x73, 0x26, 0x2d, 0x8e, 0x60,
+	0xad, 0x47, 0xfb, 0xfd, 0x40, 0xcf, 0xcb, 0x5b, 0x7e, 0x38, 0x97, 0xad, 0xcc, 0x2a, 0xc4, 0x88,
+	0x8d, 0xe6, 0x92, 0x71, 0x44, 0xa6, 0xda, 0x68, 0x42, 0x12, 0x72, 0x79, 0x08, 0xd5, 0x96, 0x4b,
+	0x45, 0x29, 0x4b, 0x9e, 0x91, 0x73, 0x78, 0x3f, 0x06, 0xaa, 0x7a, 0x4f, 0xa0, 0xd8, 0xf5, 0x09,
+	0xe6, 0xa4, 0xb7, 0x74, 0x04, 0x14, 0xae, 0xf9, 0xe7, 0x3a, 0x54, 0x5e, 0x29, 0xcc, 0x35, 0xf6,
+	0xd0, 0x15, 0x94, 0x2f, 0x08, 0x0f, 0xdf, 0x45, 0x94, 0x68, 0xd7, 0x8c, 0x65, 0x68, 0x7c, 0x78,
+	0x9f, 0x3a, 0x4c, 0xc7, 0x7c, 0x0f, 0x7d, 0x2b, 0xb7, 0x68, 0x7a, 0xf1, 0xa1, 0xc3, 0x6c, 0xc3,
+	0xb9, 0x57, 0x70, 0x85, 0x08, 0xdf, 0x40, 0x35, 0x8c, 0x10, 0x4d, 0x12, 0x32, 0x33, 0x6c, 0x52,
+	0xaf, 0x97, 0x51, 0x5f, 0x88, 0x89, 0x9d, 0xb7, 0x00, 0xa6, 0x5d, 0x85, 0x76, 0xd3, 0x46, 0x89,
+	0xd9, 0x34, 0xf6, 0xb2, 0x95, 0xb1, 0xab, 0x2b, 0x28, 0xb7, 0xb3, 0x78, 0x6d, 0x2f, 0xe6, 0xb5,
+	0x9d, 0x5d, 0xf5, 0xcf, 0x1a, 0x6c, 0xa5, 0xdf, 0x7a, 0xf4, 0x64, 0x26, 0x85, 0xac, 0x8d, 0x64,
+	0x98, 0x8b, 0x20, 0xca, 0xfb, 0xd1, 0x4f, 0x7f, 0xff, 0xf3, 0x2e, 0x77, 0x80, 0xea, 0xd6, 0xdd,
+	0xc9, 0x0d, 0xe1, 0xf8, 0xc4, 0x1a, 0x62, 0x2f, 0xb0, 0xde, 0x84, 0x3d, 0xf8, 0xd6, 0x12, 0x83,
+	0x11, 0x3c, 0x73, 0x30, 0x17, 0xbd, 0xf9, 0xbb, 0x06, 0xc6, 0xfd, 0xcb, 0x0c, 0x1d, 0xdd, 0x1f,
+	0x6f, 0xfe, 0xb2, 0x57, 0x49, 0xce, 0x92, 0xc9, 0x3d, 0x45, 0x87, 0x8b, 0x92, 0xb3, 0xde, 0x44,
+	0xef, 0xdc, 0x5b, 0xd4, 0x85, 0xa2, 0x9a, 0x12, 0xa4, 0x4f, 0xfd, 0xcf, 0x4e, 0x98, 0x51, 0xcb,
+	0xd0, 0xa8, 0x80, 0x75, 0x19, 0x70, 0xdf, 0xdc, 0xcd, 0x0e, 0xf8, 0x8c, 0xba, 0x94, 0x9f, 0xbd,
+	0x80, 0x5a, 0x97, 0x0d, 0xa3, 0x5f, 0x8f, 0xd9, 0xff, 0xea, 0xb3, 0xed, 0xc4, 0x78, 0x9d, 0x7a,
+	0xf4, 0xa5, 0x10, 0xbe, 0xd4, 0xbe, 0x36, 0x6e, 0x29, 0x1f, 0x8c, 0x6e, 0x1a, 0x5d, 0x36, 0xb4,
+	0xd4, 0xbf, 0x75, 0x64, 0x78, 0xb3, 0x2e, 0x2d, 0x3f, 0xfd, 0x77, 0x00, 0x00, 0x83, 0x00, 0x61,
+	0xc5, 0x0b, 0x00, 0x00,
 }
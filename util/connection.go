@@ -0,0 +1,89 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+)
+
+// ConnectionOpts configures how a gRPC server manages its client connections.
+// Zero values leave the gRPC defaults in place.
+type ConnectionOpts struct {
+	// MaxConcurrentStreams limits the number of concurrent RPCs on each
+	// connection.
+	MaxConcurrentStreams uint32
+	// MaxConnectionIdle is how long a connection may have no RPCs before it's
+	// closed.
+	MaxConnectionIdle time.Duration
+	// MaxConnectionAge is how long a connection may be open before the server
+	// asks the client to reconnect, so long-lived clients are rebalanced over
+	// servers, and don't hold up restarts.
+	MaxConnectionAge time.Duration
+	// MaxConnectionAgeGrace is how long RPCs in flight on a connection which
+	// reached MaxConnectionAge are given to complete before it's closed.
+	MaxConnectionAgeGrace time.Duration
+	// KeepaliveTime is how long a connection may be idle before the server
+	// pings the client, and KeepaliveTimeout how long it waits for the ping
+	// to be acknowledged before closing the connection.
+	KeepaliveTime, KeepaliveTimeout time.Duration
+	// KeepaliveMinTime is the minimum interval at which clients may ping the
+	// server. Clients pinging more often are disconnected.
+	KeepaliveMinTime time.Duration
+	// KeepalivePermitWithoutStream allows clients to ping the server while
+	// they have no RPCs in flight.
+	KeepalivePermitWithoutStream bool
+}
+
+// Validate returns an error if any of the durations in o is negative.
+func (o ConnectionOpts) Validate() error {
+	for _, d := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"max connection idle", o.MaxConnectionIdle},
+		{"max connection age", o.MaxConnectionAge},
+		{"max connection age grace", o.MaxConnectionAgeGrace},
+		{"keepalive time", o.KeepaliveTime},
+		{"keepalive timeout", o.KeepaliveTimeout},
+		{"keepalive min time", o.KeepaliveMinTime},
+	} {
+		if d.value < 0 {
+			return fmt.Errorf("%v is %v, want >= 0", d.name, d.value)
+		}
+	}
+	return nil
+}
+
+// ServerOptions returns the gRPC server options which apply o.
+func (o ConnectionOpts) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.MaxConcurrentStreams(o.MaxConcurrentStreams),
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			MaxConnectionIdle:     o.MaxConnectionIdle,
+			MaxConnectionAge:      o.MaxConnectionAge,
+			MaxConnectionAgeGrace: o.MaxConnectionAgeGrace,
+			Time:                  o.KeepaliveTime,
+			Timeout:               o.KeepaliveTimeout,
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             o.KeepaliveMinTime,
+			PermitWithoutStream: o.KeepalivePermitWithoutStream,
+		}),
+	}
+}
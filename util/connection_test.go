@@ -0,0 +1,60 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+func TestConnectionOptsValidate(t *testing.T) {
+	for _, test := range []struct {
+		desc    string
+		opts    ConnectionOpts
+		wantErr bool
+	}{
+		{desc: "defaults"},
+		{
+			desc: "all",
+			opts: ConnectionOpts{
+				MaxConcurrentStreams:         100,
+				MaxConnectionIdle:            time.Minute,
+				MaxConnectionAge:             time.Hour,
+				MaxConnectionAgeGrace:        time.Minute,
+				KeepaliveTime:                time.Minute,
+				KeepaliveTimeout:             time.Second,
+				KeepaliveMinTime:             10 * time.Second,
+				KeepalivePermitWithoutStream: true,
+			},
+		},
+		{desc: "negativeIdle", opts: ConnectionOpts{MaxConnectionIdle: -1}, wantErr: true},
+		{desc: "negativeAge", opts: ConnectionOpts{MaxConnectionAge: -1}, wantErr: true},
+		{desc: "negativeGrace", opts: ConnectionOpts{MaxConnectionAgeGrace: -1}, wantErr: true},
+		{desc: "negativeKeepalive", opts: ConnectionOpts{KeepaliveTime: -1}, wantErr: true},
+		{desc: "negativeKeepaliveTimeout", opts: ConnectionOpts{KeepaliveTimeout: -1}, wantErr: true},
+		{desc: "negativeMinTime", opts: ConnectionOpts{KeepaliveMinTime: -1}, wantErr: true},
+	} {
+		err := test.opts.Validate()
+		if gotErr := err != nil; gotErr != test.wantErr {
+			t.Errorf("%v: Validate(): %v, want error: %v", test.desc, err, test.wantErr)
+		}
+		if err == nil {
+			// The options must be accepted by the server.
+			grpc.NewServer(test.opts.ServerOptions()...).Stop()
+		}
+	}
+}
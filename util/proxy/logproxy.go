@@ -16,6 +16,8 @@
 package proxy
 
 import (
+	"io"
+
 	"github.com/google/trillian"
 	"golang.org/x/net/context"
 )
@@ -48,6 +50,11 @@ func (p *Log) AddSequencedLeaf(ctx context.Context, in *trillian.AddSequencedLea
 	return p.c.AddSequencedLeaf(ctx, in)
 }
 
+// AddSequencedLeafRange forwards the RPC.
+func (p *Log) AddSequencedLeafRange(ctx context.Context, in *trillian.AddSequencedLeafRangeRequest) (*trillian.AddSequencedLeafRangeResponse, error) {
+	return p.c.AddSequencedLeafRange(ctx, in)
+}
+
 // AddSequencedLeaves forwards the RPC.
 func (p *Log) AddSequencedLeaves(ctx context.Context, in *trillian.AddSequencedLeavesRequest) (*trillian.AddSequencedLeavesResponse, error) {
 	return p.c.AddSequencedLeaves(ctx, in)
@@ -88,12 +95,61 @@ func (p *Log) GetLeavesByRange(ctx context.Context, in *trillian.GetLeavesByRang
 	return p.c.GetLeavesByRange(ctx, in)
 }
 
+// StreamLeavesByRange forwards the RPC, and each of the messages it returns.
+func (p *Log) StreamLeavesByRange(in *trillian.GetLeavesByRangeRequest, stream trillian.TrillianLog_StreamLeavesByRangeServer) error {
+	c, err := p.c.StreamLeavesByRange(stream.Context(), in)
+	if err != nil {
+		return err
+	}
+	for {
+		resp, err := c.Recv()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
 // GetLeavesByHash forwards the RPC.
 func (p *Log) GetLeavesByHash(ctx context.Context, in *trillian.GetLeavesByHashRequest) (*trillian.GetLeavesByHashResponse, error) {
 	return p.c.GetLeavesByHash(ctx, in)
 }
 
+// GetLeafIndicesByKey forwards the RPC.
+func (p *Log) GetLeafIndicesByKey(ctx context.Context, in *trillian.GetLeafIndicesByKeyRequest) (*trillian.GetLeafIndicesByKeyResponse, error) {
+	return p.c.GetLeafIndicesByKey(ctx, in)
+}
+
+// CheckLeafFilter forwards the RPC.
+func (p *Log) CheckLeafFilter(ctx context.Context, in *trillian.CheckLeafFilterRequest) (*trillian.CheckLeafFilterResponse, error) {
+	return p.c.CheckLeafFilter(ctx, in)
+}
+
+// AddObservedRoot forwards the RPC.
+func (p *Log) AddObservedRoot(ctx context.Context, in *trillian.AddObservedRootRequest) (*trillian.AddObservedRootResponse, error) {
+	return p.c.AddObservedRoot(ctx, in)
+}
+
+// GetObservedRoots forwards the RPC.
+func (p *Log) GetObservedRoots(ctx context.Context, in *trillian.GetObservedRootsRequest) (*trillian.GetObservedRootsResponse, error) {
+	return p.c.GetObservedRoots(ctx, in)
+}
+
+// GetConsistencyProofChain forwards the RPC.
+func (p *Log) GetConsistencyProofChain(ctx context.Context, in *trillian.GetConsistencyProofChainRequest) (*trillian.GetConsistencyProofChainResponse, error) {
+	return p.c.GetConsistencyProofChain(ctx, in)
+}
+
 // GetEntryAndProof forwards the RPC.
 func (p *Log) GetEntryAndProof(ctx context.Context, in *trillian.GetEntryAndProofRequest) (*trillian.GetEntryAndProofResponse, error) {
 	return p.c.GetEntryAndProof(ctx, in)
 }
+
+// GetEntriesAndProofs forwards the RPC.
+func (p *Log) GetEntriesAndProofs(ctx context.Context, in *trillian.GetEntriesAndProofsRequest) (*trillian.GetEntriesAndProofsResponse, error) {
+	return p.c.GetEntriesAndProofs(ctx, in)
+}
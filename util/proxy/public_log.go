@@ -0,0 +1,427 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian"
+	"github.com/google/trillian/monitoring"
+	"github.com/google/trillian/util"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const logServiceName = "trillian.TrillianLog"
+
+var (
+	publicRequests    monitoring.Counter
+	publicCacheHits   monitoring.Counter
+	publicRateLimited monitoring.Counter
+	publicMetricsOnce sync.Once
+)
+
+func initPublicMetrics(mf monitoring.MetricFactory) {
+	publicMetricsOnce.Do(func() {
+		publicRequests = mf.NewCounter("public_proxy_requests", "Number of requests received by the public proxy", "method")
+		publicCacheHits = mf.NewCounter("public_proxy_cache_hits", "Number of requests answered from the public proxy's cache", "method")
+		publicRateLimited = mf.NewCounter("public_proxy_rate_limited", "Number of requests rejected because their client was over its rate", "method")
+	})
+}
+
+// PublicLogOpts configures a PublicLog.
+type PublicLogOpts struct {
+	// MaxLeaves is the maximum number of leaves, indices or hashes a request
+	// may ask for.
+	MaxLeaves int64
+	// Rate is the number of requests per second each client may make, as
+	// identified by its IP address, with bursts of up to Burst requests.
+	// Zero disables rate limiting.
+	Rate  float64
+	Burst int
+	// CacheSize is the maximum number of responses cached. Zero disables
+	// caching.
+	CacheSize int
+	// CacheMaxAge is how long responses which can't change, such as proofs to
+	// a given tree size, are cached for.
+	CacheMaxAge time.Duration
+	// RootMaxAge is how long responses which change as the log grows, such as
+	// its latest root, are cached for.
+	RootMaxAge time.Duration
+	// Timeout bounds the deadline of requests forwarded to the log server.
+	Timeout time.Duration
+}
+
+// PublicLog serves the read-only RPCs of a log to untrusted clients, by
+// forwarding them to a log server. It's meant to be exposed to the public
+// internet in front of internal log servers: write and admin RPCs aren't part
+// of its service, so they aren't served at all, and requests are size-limited,
+// rate-limited per client and answered from a cache where possible.
+type PublicLog struct {
+	c          trillian.TrillianLogClient
+	opts       PublicLogOpts
+	timeSource util.TimeSource
+
+	mu        sync.Mutex
+	cache     map[string]*list.Element
+	lru       *list.List
+	clients   map[string]*publicClient
+	lastSweep time.Time
+}
+
+// publicClient is the token bucket of a client. PublicLog.mu must be held to
+// access it.
+type publicClient struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// publicCacheEntry is a cached response. PublicLog.mu must be held to access
+// it.
+type publicCacheEntry struct {
+	key     string
+	resp    proto.Message
+	expires time.Time
+}
+
+// NewPublicLog returns a PublicLog forwarding requests to c.
+func NewPublicLog(c trillian.TrillianLogClient, opts PublicLogOpts, mf monitoring.MetricFactory, timeSource util.TimeSource) (*PublicLog, error) {
+	if opts.MaxLeaves <= 0 {
+		return nil, fmt.Errorf("max leaves is %v, want > 0", opts.MaxLeaves)
+	}
+	if opts.Rate < 0 {
+		return nil, fmt.Errorf("rate is %v, want >= 0", opts.Rate)
+	}
+	if opts.Rate > 0 && opts.Burst < 1 {
+		return nil, fmt.Errorf("burst is %v, want >= 1", opts.Burst)
+	}
+	if opts.CacheSize < 0 {
+		return nil, fmt.Errorf("cache size is %v, want >= 0", opts.CacheSize)
+	}
+	if opts.Timeout <= 0 {
+		return nil, fmt.Errorf("timeout is %v, want > 0", opts.Timeout)
+	}
+	if mf == nil {
+		mf = monitoring.InertMetricFactory{}
+	}
+	initPublicMetrics(mf)
+	return &PublicLog{
+		c:          c,
+		opts:       opts,
+		timeSource: timeSource,
+		cache:      make(map[string]*list.Element),
+		lru:        list.New(),
+		clients:    make(map[string]*publicClient),
+	}, nil
+}
+
+// publicMethod is an RPC served by PublicLog.
+type publicMethod struct {
+	name   string
+	newReq func() proto.Message
+	call   func(ctx context.Context, c trillian.TrillianLogClient, req proto.Message) (proto.Message, error)
+	// leaves returns the number of leaves, indices or hashes asked for by
+	// req.
+	leaves func(req proto.Message) int64
+	// immutable returns whether resp, a response to req, can't change as the
+	// log grows.
+	immutable func(req, resp proto.Message) bool
+}
+
+func always(req, resp proto.Message) bool { return true }
+func never(req, resp proto.Message) bool  { return false }
+func none(req proto.Message) int64        { return 0 }
+
+// publicMethods are the RPCs served by PublicLog. Proofs and leaves at a
+// given tree size or index can't change, whereas the latest root and lookups
+// by hash can.
+var publicMethods = []publicMethod{
+	{
+		name:   "GetInclusionProof",
+		newReq: func() proto.Message { return &trillian.GetInclusionProofRequest{} },
+		call: func(ctx context.Context, c trillian.TrillianLogClient, req proto.Message) (proto.Message, error) {
+			return c.GetInclusionProof(ctx, req.(*trillian.GetInclusionProofRequest))
+		},
+		leaves:    none,
+		immutable: always,
+	},
+	{
+		name:   "GetInclusionProofByHash",
+		newReq: func() proto.Message { return &trillian.GetInclusionProofByHashRequest{} },
+		call: func(ctx context.Context, c trillian.TrillianLogClient, req proto.Message) (proto.Message, error) {
+			return c.GetInclusionProofByHash(ctx, req.(*trillian.GetInclusionProofByHashRequest))
+		},
+		leaves:    none,
+		immutable: never,
+	},
+	{
+		name:   "GetConsistencyProof",
+		newReq: func() proto.Message { return &trillian.GetConsistencyProofRequest{} },
+		call: func(ctx context.Context, c trillian.TrillianLogClient, req proto.Message) (proto.Message, error) {
+			return c.GetConsistencyProof(ctx, req.(*trillian.GetConsistencyProofRequest))
+		},
+		leaves:    none,
+		immutable: always,
+	},
+	{
+		name:   "GetConsistencyProofChain",
+		newReq: func() proto.Message { return &trillian.GetConsistencyProofChainRequest{} },
+		call: func(ctx context.Context, c trillian.TrillianLogClient, req proto.Message) (proto.Message, error) {
+			return c.GetConsistencyProofChain(ctx, req.(*trillian.GetConsistencyProofChainRequest))
+		},
+		leaves:    none,
+		immutable: never,
+	},
+	{
+		name:   "GetLatestSignedLogRoot",
+		newReq: func() proto.Message { return &trillian.GetLatestSignedLogRootRequest{} },
+		call: func(ctx context.Context, c trillian.TrillianLogClient, req proto.Message) (proto.Message, error) {
+			return c.GetLatestSignedLogRoot(ctx, req.(*trillian.GetLatestSignedLogRootRequest))
+		},
+		leaves:    none,
+		immutable: never,
+	},
+	{
+		name:   "GetObservedRoots",
+		newReq: func() proto.Message { return &trillian.GetObservedRootsRequest{} },
+		call: func(ctx context.Context, c trillian.TrillianLogClient, req proto.Message) (proto.Message, error) {
+			return c.GetObservedRoots(ctx, req.(*trillian.GetObservedRootsRequest))
+		},
+		leaves:    none,
+		immutable: never,
+	},
+	{
+		name:   "GetSequencedLeafCount",
+		newReq: func() proto.Message { return &trillian.GetSequencedLeafCountRequest{} },
+		call: func(ctx context.Context, c trillian.TrillianLogClient, req proto.Message) (proto.Message, error) {
+			return c.GetSequencedLeafCount(ctx, req.(*trillian.GetSequencedLeafCountRequest))
+		},
+		leaves:    none,
+		immutable: never,
+	},
+	{
+		name:   "GetEntryAndProof",
+		newReq: func() proto.Message { return &trillian.GetEntryAndProofRequest{} },
+		call: func(ctx context.Context, c trillian.TrillianLogClient, req proto.Message) (proto.Message, error) {
+			return c.GetEntryAndProof(ctx, req.(*trillian.GetEntryAndProofRequest))
+		},
+		leaves:    none,
+		immutable: always,
+	},
+	{
+		name:   "GetEntriesAndProofs",
+		newReq: func() proto.Message { return &trillian.GetEntriesAndProofsRequest{} },
+		call: func(ctx context.Context, c trillian.TrillianLogClient, req proto.Message) (proto.Message, error) {
+			return c.GetEntriesAndProofs(ctx, req.(*trillian.GetEntriesAndProofsRequest))
+		},
+		leaves:    func(req proto.Message) int64 { return int64(len(req.(*trillian.GetEntriesAndProofsRequest).LeafIndex)) },
+		immutable: always,
+	},
+	{
+		name:   "GetLeavesByIndex",
+		newReq: func() proto.Message { return &trillian.GetLeavesByIndexRequest{} },
+		call: func(ctx context.Context, c trillian.TrillianLogClient, req proto.Message) (proto.Message, error) {
+			return c.GetLeavesByIndex(ctx, req.(*trillian.GetLeavesByIndexRequest))
+		},
+		leaves:    func(req proto.Message) int64 { return int64(len(req.(*trillian.GetLeavesByIndexRequest).LeafIndex)) },
+		immutable: always,
+	},
+	{
+		name:   "GetLeavesByRange",
+		newReq: func() proto.Message { return &trillian.GetLeavesByRangeRequest{} },
+		call: func(ctx context.Context, c trillian.TrillianLogClient, req proto.Message) (proto.Message, error) {
+			return c.GetLeavesByRange(ctx, req.(*trillian.GetLeavesByRangeRequest))
+		},
+		leaves: func(req proto.Message) int64 { return req.(*trillian.GetLeavesByRangeRequest).Count },
+		// A range which runs past the end of the log grows with it.
+		immutable: func(req, resp proto.Message) bool {
+			return int64(len(resp.(*trillian.GetLeavesByRangeResponse).Leaves)) == req.(*trillian.GetLeavesByRangeRequest).Count
+		},
+	},
+	{
+		name:   "GetLeavesByHash",
+		newReq: func() proto.Message { return &trillian.GetLeavesByHashRequest{} },
+		call: func(ctx context.Context, c trillian.TrillianLogClient, req proto.Message) (proto.Message, error) {
+			return c.GetLeavesByHash(ctx, req.(*trillian.GetLeavesByHashRequest))
+		},
+		leaves:    func(req proto.Message) int64 { return int64(len(req.(*trillian.GetLeavesByHashRequest).LeafHash)) },
+		immutable: never,
+	},
+}
+
+// Register registers p with s as the trillian.TrillianLog service, with only
+// the read-only RPCs.
+func (p *PublicLog) Register(s *grpc.Server) {
+	desc := grpc.ServiceDesc{
+		ServiceName: logServiceName,
+		HandlerType: (*interface{})(nil),
+		Metadata:    "trillian_log_api.proto",
+	}
+	for _, m := range publicMethods {
+		desc.Methods = append(desc.Methods, grpc.MethodDesc{MethodName: m.name, Handler: p.handler(m)})
+	}
+	s.RegisterService(&desc, p)
+}
+
+// handler returns the gRPC handler of m.
+func (p *PublicLog) handler(m publicMethod) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		req := m.newReq()
+		if err := dec(req); err != nil {
+			return nil, err
+		}
+		h := func(ctx context.Context, req interface{}) (interface{}, error) {
+			return p.serve(ctx, m, req.(proto.Message))
+		}
+		if interceptor == nil {
+			return h(ctx, req)
+		}
+		info := &grpc.UnaryServerInfo{Server: srv, FullMethod: fmt.Sprintf("/%s/%s", logServiceName, m.name)}
+		return interceptor(ctx, req, info, h)
+	}
+}
+
+// serve answers req, a request for m.
+func (p *PublicLog) serve(ctx context.Context, m publicMethod, req proto.Message) (proto.Message, error) {
+	publicRequests.Inc(m.name)
+	if err := p.allow(clientOf(ctx)); err != nil {
+		publicRateLimited.Inc(m.name)
+		return nil, err
+	}
+	if n := m.leaves(req); n > p.opts.MaxLeaves {
+		return nil, status.Errorf(codes.InvalidArgument, "%v asks for %v leaves, max is %v", m.name, n, p.opts.MaxLeaves)
+	}
+
+	b, err := proto.Marshal(req)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v: %v", m.name, err)
+	}
+	key := m.name + "/" + string(b)
+	if resp := p.cached(key); resp != nil {
+		publicCacheHits.Inc(m.name)
+		return resp, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.opts.Timeout)
+	defer cancel()
+	resp, err := m.call(ctx, p.c, req)
+	if err != nil {
+		return nil, err
+	}
+	maxAge := p.opts.RootMaxAge
+	if m.immutable(req, resp) {
+		maxAge = p.opts.CacheMaxAge
+	}
+	p.store(key, resp, maxAge)
+	return resp, nil
+}
+
+// clientOf returns the IP address of the client of ctx.
+func clientOf(ctx context.Context) string {
+	pr, ok := peer.FromContext(ctx)
+	if !ok || pr.Addr == nil {
+		return ""
+	}
+	addr := pr.Addr.String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// allow takes a token from the bucket of client, or returns a
+// RESOURCE_EXHAUSTED error if it's empty.
+func (p *PublicLog) allow(client string) error {
+	if p.opts.Rate == 0 {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.timeSource.Now()
+	burst := float64(p.opts.Burst)
+
+	// Clients whose buckets have refilled are forgotten, as they're no
+	// different from new ones.
+	if now.Sub(p.lastSweep) > time.Minute {
+		for k, c := range p.clients {
+			if c.tokens+now.Sub(c.lastSeen).Seconds()*p.opts.Rate >= burst {
+				delete(p.clients, k)
+			}
+		}
+		p.lastSweep = now
+	}
+
+	c, ok := p.clients[client]
+	if !ok {
+		c = &publicClient{tokens: burst, lastSeen: now}
+		p.clients[client] = c
+	}
+	c.tokens = math.Min(burst, c.tokens+now.Sub(c.lastSeen).Seconds()*p.opts.Rate)
+	c.lastSeen = now
+	if c.tokens < 1 {
+		return status.Errorf(codes.ResourceExhausted, "too many requests from %v", client)
+	}
+	c.tokens--
+	return nil
+}
+
+// cached returns the cached response for key, or nil if there's none.
+func (p *PublicLog) cached(key string) proto.Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e, ok := p.cache[key]
+	if !ok {
+		return nil
+	}
+	entry := e.Value.(*publicCacheEntry)
+	if !p.timeSource.Now().Before(entry.expires) {
+		p.lru.Remove(e)
+		delete(p.cache, key)
+		return nil
+	}
+	p.lru.MoveToFront(e)
+	return entry.resp
+}
+
+// store caches resp for key for maxAge, evicting the least recently used
+// responses if the cache is full.
+func (p *PublicLog) store(key string, resp proto.Message, maxAge time.Duration) {
+	if p.opts.CacheSize == 0 || maxAge <= 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	entry := &publicCacheEntry{key: key, resp: resp, expires: p.timeSource.Now().Add(maxAge)}
+	if e, ok := p.cache[key]; ok {
+		e.Value = entry
+		p.lru.MoveToFront(e)
+		return
+	}
+	p.cache[key] = p.lru.PushFront(entry)
+	for p.lru.Len() > p.opts.CacheSize {
+		e := p.lru.Back()
+		p.lru.Remove(e)
+		delete(p.cache, e.Value.(*publicCacheEntry).key)
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/util"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeLog counts the requests it serves. It serves a log of size 10.
+type fakeLog struct {
+	trillian.TrillianLogClient
+	calls int
+}
+
+func (f *fakeLog) GetLatestSignedLogRoot(ctx context.Context, in *trillian.GetLatestSignedLogRootRequest, opts ...grpc.CallOption) (*trillian.GetLatestSignedLogRootResponse, error) {
+	f.calls++
+	return &trillian.GetLatestSignedLogRootResponse{SignedLogRoot: &trillian.SignedLogRoot{LogId: in.LogId, TreeSize: 10}}, nil
+}
+
+func (f *fakeLog) GetLeavesByRange(ctx context.Context, in *trillian.GetLeavesByRangeRequest, opts ...grpc.CallOption) (*trillian.GetLeavesByRangeResponse, error) {
+	f.calls++
+	resp := &trillian.GetLeavesByRangeResponse{}
+	for i := in.StartIndex; i < in.StartIndex+in.Count && i < 10; i++ {
+		resp.Leaves = append(resp.Leaves, &trillian.LogLeaf{LeafIndex: i})
+	}
+	return resp, nil
+}
+
+func (f *fakeLog) QueueLeaf(ctx context.Context, in *trillian.QueueLeafRequest, opts ...grpc.CallOption) (*trillian.QueueLeafResponse, error) {
+	f.calls++
+	return &trillian.QueueLeafResponse{}, nil
+}
+
+// startPublicLog serves a PublicLog in front of backend, and returns a client
+// of it.
+func startPublicLog(t *testing.T, backend trillian.TrillianLogClient, opts PublicLogOpts, ts util.TimeSource) (trillian.TrillianLogClient, func()) {
+	t.Helper()
+	p, err := NewPublicLog(backend, opts, nil, ts)
+	if err != nil {
+		t.Fatalf("NewPublicLog(): %v", err)
+	}
+	s := grpc.NewServer()
+	p.Register(s)
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Listen(): %v", err)
+	}
+	go s.Serve(lis)
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Dial(): %v", err)
+	}
+	return trillian.NewTrillianLogClient(conn), func() {
+		conn.Close()
+		s.Stop()
+	}
+}
+
+func TestNewPublicLog(t *testing.T) {
+	valid := PublicLogOpts{MaxLeaves: 10, Rate: 1, Burst: 1, Timeout: time.Second}
+	for _, test := range []struct {
+		desc    string
+		modify  func(*PublicLogOpts)
+		wantErr bool
+	}{
+		{desc: "valid", modify: func(*PublicLogOpts) {}},
+		{desc: "noRateLimit", modify: func(o *PublicLogOpts) { o.Rate, o.Burst = 0, 0 }},
+		{desc: "noMaxLeaves", modify: func(o *PublicLogOpts) { o.MaxLeaves = 0 }, wantErr: true},
+		{desc: "noBurst", modify: func(o *PublicLogOpts) { o.Burst = 0 }, wantErr: true},
+		{desc: "negativeRate", modify: func(o *PublicLogOpts) { o.Rate = -1 }, wantErr: true},
+		{desc: "negativeCacheSize", modify: func(o *PublicLogOpts) { o.CacheSize = -1 }, wantErr: true},
+		{desc: "noTimeout", modify: func(o *PublicLogOpts) { o.Timeout = 0 }, wantErr: true},
+	} {
+		opts := valid
+		test.modify(&opts)
+		_, err := NewPublicLog(&fakeLog{}, opts, nil, util.SystemTimeSource{})
+		if gotErr := err != nil; gotErr != test.wantErr {
+			t.Errorf("%v: NewPublicLog(): %v, want error: %v", test.desc, err, test.wantErr)
+		}
+	}
+}
+
+func TestPublicLog(t *testing.T) {
+	ctx := context.Background()
+	backend := &fakeLog{}
+	ts := util.NewFakeTimeSource(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	c, stop := startPublicLog(t, backend, PublicLogOpts{
+		MaxLeaves:   5,
+		CacheSize:   10,
+		CacheMaxAge: time.Hour,
+		RootMaxAge:  time.Second,
+		Timeout:     time.Second,
+	}, ts)
+	defer stop()
+
+	// Write RPCs aren't served.
+	if _, err := c.QueueLeaf(ctx, &trillian.QueueLeafRequest{LogId: 1}); status.Code(err) != codes.Unimplemented {
+		t.Errorf("QueueLeaf(): %v, want code %v", err, codes.Unimplemented)
+	}
+	if _, err := c.GetLeavesByRange(ctx, &trillian.GetLeavesByRangeRequest{LogId: 1, Count: 6}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("GetLeavesByRange() of too many leaves: %v, want code %v", err, codes.InvalidArgument)
+	}
+
+	for _, test := range []struct {
+		desc      string
+		call      func() error
+		wantCalls int
+	}{
+		{
+			desc: "root",
+			call: func() error {
+				_, err := c.GetLatestSignedLogRoot(ctx, &trillian.GetLatestSignedLogRootRequest{LogId: 1})
+				return err
+			},
+			wantCalls: 2,
+		},
+		{
+			desc: "fullRange",
+			call: func() error {
+				_, err := c.GetLeavesByRange(ctx, &trillian.GetLeavesByRangeRequest{LogId: 1, StartIndex: 2, Count: 5})
+				return err
+			},
+			wantCalls: 1,
+		},
+		{
+			desc: "partialRange",
+			call: func() error {
+				_, err := c.GetLeavesByRange(ctx, &trillian.GetLeavesByRangeRequest{LogId: 1, StartIndex: 8, Count: 5})
+				return err
+			},
+			wantCalls: 2,
+		},
+	} {
+		backend.calls = 0
+		for i := 0; i < 3; i++ {
+			if err := test.call(); err != nil {
+				t.Fatalf("%v: call %d: %v", test.desc, i, err)
+			}
+		}
+		// Responses which may change expire after RootMaxAge.
+		ts.Set(ts.Now().Add(time.Minute))
+		if err := test.call(); err != nil {
+			t.Fatalf("%v: call after a minute: %v", test.desc, err)
+		}
+		if backend.calls != test.wantCalls {
+			t.Errorf("%v: forwarded %d requests, want %d", test.desc, backend.calls, test.wantCalls)
+		}
+	}
+}
+
+func TestPublicLogRateLimit(t *testing.T) {
+	ctx := context.Background()
+	ts := util.NewFakeTimeSource(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	c, stop := startPublicLog(t, &fakeLog{}, PublicLogOpts{MaxLeaves: 5, Rate: 1, Burst: 3, Timeout: time.Second}, ts)
+	defer stop()
+
+	req := &trillian.GetLatestSignedLogRootRequest{LogId: 1}
+	for i := 0; i < 3; i++ {
+		if _, err := c.GetLatestSignedLogRoot(ctx, req); err != nil {
+			t.Fatalf("GetLatestSignedLogRoot() %d: %v", i, err)
+		}
+	}
+	if _, err := c.GetLatestSignedLogRoot(ctx, req); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("GetLatestSignedLogRoot() over burst: %v, want code %v", err, codes.ResourceExhausted)
+	}
+	ts.Set(ts.Now().Add(time.Second))
+	if _, err := c.GetLatestSignedLogRoot(ctx, req); err != nil {
+		t.Errorf("GetLatestSignedLogRoot() after a second: %v", err)
+	}
+}
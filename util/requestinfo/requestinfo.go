@@ -0,0 +1,163 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package requestinfo carries the IDs of a request and of the tenant it's
+// made for, from the gRPC metadata of the request through its context into
+// the log lines written while serving it, so that a failing request can be
+// traced across the logs of multi-tenant deployments.
+//
+// Clients set the IDs of their requests with NewOutgoingContext. Servers
+// store the IDs of incoming requests in their contexts, and log with Infof,
+// Warningf and Errorf, which prefix log lines with the IDs found in the
+// context.
+package requestinfo
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/golang/glog"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// RequestIDKey is the gRPC metadata key carrying the ID of a request.
+	RequestIDKey = "x-trillian-request-id"
+	// TenantIDKey is the gRPC metadata key carrying the ID of the tenant a
+	// request is made for.
+	TenantIDKey = "x-trillian-tenant-id"
+
+	// maxIDLength is the length IDs are truncated to.
+	maxIDLength = 128
+)
+
+// Info holds the IDs of a request.
+type Info struct {
+	RequestID string
+	TenantID  string
+}
+
+// String returns the IDs of i as they're written in log lines, e.g.
+// "request_id=1a2b tenant_id=acme", omitting those which aren't set.
+func (i Info) String() string {
+	var parts []string
+	if i.RequestID != "" {
+		parts = append(parts, "request_id="+i.RequestID)
+	}
+	if i.TenantID != "" {
+		parts = append(parts, "tenant_id="+i.TenantID)
+	}
+	return strings.Join(parts, " ")
+}
+
+type infoKey struct{}
+
+// NewContext returns a copy of ctx carrying info.
+func NewContext(ctx context.Context, info Info) context.Context {
+	return context.WithValue(ctx, infoKey{}, info)
+}
+
+// FromContext returns the Info carried by ctx, which is empty if there's
+// none.
+func FromContext(ctx context.Context) Info {
+	info, _ := ctx.Value(infoKey{}).(Info)
+	return info
+}
+
+// NewOutgoingContext returns a copy of ctx whose outgoing RPCs are sent with
+// the IDs of info which are set.
+func NewOutgoingContext(ctx context.Context, info Info) context.Context {
+	var kv []string
+	if info.RequestID != "" {
+		kv = append(kv, RequestIDKey, info.RequestID)
+	}
+	if info.TenantID != "" {
+		kv = append(kv, TenantIDKey, info.TenantID)
+	}
+	if len(kv) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, kv...)
+}
+
+// FromIncomingContext returns the IDs sent in the metadata of the incoming
+// RPC of ctx. As they're written to logs, they're stripped of characters
+// other than printable ASCII, and truncated.
+func FromIncomingContext(ctx context.Context) Info {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return Info{}
+	}
+	get := func(key string) string {
+		if v := md.Get(key); len(v) > 0 {
+			return sanitize(v[0])
+		}
+		return ""
+	}
+	return Info{RequestID: get(RequestIDKey), TenantID: get(TenantIDKey)}
+}
+
+// sanitize strips id of characters which could forge log lines or fields,
+// and truncates it.
+func sanitize(id string) string {
+	id = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return -1
+		}
+		return r
+	}, id)
+	if len(id) > maxIDLength {
+		id = id[:maxIDLength]
+	}
+	return id
+}
+
+// NewRequestID returns a new random request ID.
+func NewRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		// Should never happen, and the ID is only used for logging.
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// prefix returns the prefix of the log lines written for the request of ctx.
+func prefix(ctx context.Context) string {
+	if s := FromContext(ctx).String(); s != "" {
+		return "[" + s + "] "
+	}
+	return ""
+}
+
+// Infof logs to the INFO log like glog.Infof, prefixed with the IDs carried
+// by ctx.
+func Infof(ctx context.Context, format string, args ...interface{}) {
+	glog.InfoDepth(1, prefix(ctx)+fmt.Sprintf(format, args...))
+}
+
+// Warningf logs to the WARNING and INFO logs like glog.Warningf, prefixed
+// with the IDs carried by ctx.
+func Warningf(ctx context.Context, format string, args ...interface{}) {
+	glog.WarningDepth(1, prefix(ctx)+fmt.Sprintf(format, args...))
+}
+
+// Errorf logs to the ERROR, WARNING and INFO logs like glog.Errorf, prefixed
+// with the IDs carried by ctx.
+func Errorf(ctx context.Context, format string, args ...interface{}) {
+	glog.ErrorDepth(1, prefix(ctx)+fmt.Sprintf(format, args...))
+}
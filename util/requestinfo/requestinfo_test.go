@@ -0,0 +1,68 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package requestinfo
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestIncomingContext(t *testing.T) {
+	for _, test := range []struct {
+		desc string
+		md   metadata.MD
+		want Info
+	}{
+		{desc: "none", md: metadata.MD{}},
+		{desc: "request", md: metadata.Pairs(RequestIDKey, "1a2b"), want: Info{RequestID: "1a2b"}},
+		{desc: "both", md: metadata.Pairs(RequestIDKey, "1a2b", TenantIDKey, "acme"), want: Info{RequestID: "1a2b", TenantID: "acme"}},
+		{desc: "forged", md: metadata.Pairs(RequestIDKey, "1a2b\nW0101 forged", TenantIDKey, "ac me"), want: Info{RequestID: "1a2bW0101forged", TenantID: "acme"}},
+		{desc: "long", md: metadata.Pairs(TenantIDKey, strings.Repeat("a", 200)), want: Info{TenantID: strings.Repeat("a", maxIDLength)}},
+	} {
+		ctx := metadata.NewIncomingContext(context.Background(), test.md)
+		if got := FromIncomingContext(ctx); got != test.want {
+			t.Errorf("%v: FromIncomingContext() = %+v, want %+v", test.desc, got, test.want)
+		}
+	}
+}
+
+func TestOutgoingContext(t *testing.T) {
+	info := Info{RequestID: "1a2b", TenantID: "acme"}
+	ctx := NewOutgoingContext(context.Background(), info)
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if got := (Info{RequestID: md.Get(RequestIDKey)[0], TenantID: md.Get(TenantIDKey)[0]}); got != info {
+		t.Errorf("NewOutgoingContext() sent %+v, want %+v", got, info)
+	}
+	if got := NewOutgoingContext(context.Background(), Info{}); got != context.Background() {
+		t.Errorf("NewOutgoingContext() without IDs changed the context")
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	ctx := context.Background()
+	if got := prefix(ctx); got != "" {
+		t.Errorf("prefix() without IDs = %q, want empty", got)
+	}
+	ctx = NewContext(ctx, Info{RequestID: "1a2b", TenantID: "acme"})
+	if got, want := prefix(ctx), "[request_id=1a2b tenant_id=acme] "; got != want {
+		t.Errorf("prefix() = %q, want %q", got, want)
+	}
+	if got, want := prefix(NewContext(ctx, Info{TenantID: "acme"})), "[tenant_id=acme] "; got != want {
+		t.Errorf("prefix() = %q, want %q", got, want)
+	}
+}
@@ -40,6 +40,44 @@ func (s SystemTimeSource) Now() time.Time {
 	return time.Now()
 }
 
+// MonotonicTimeSource wraps a TimeSource so that the times it returns never go
+// backwards. When the wrapped TimeSource jumps backwards, e.g. because NTP
+// stepped the system clock, MonotonicTimeSource keeps returning the latest time
+// it has returned until the wrapped TimeSource catches up.
+type MonotonicTimeSource struct {
+	ts      TimeSource
+	maxSkew time.Duration
+	onSkew  func(jump time.Duration)
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+// NewMonotonicTimeSource returns a MonotonicTimeSource wrapping ts. onSkew, if
+// not nil, is called with the size of each backwards jump of ts larger than
+// maxSkew.
+func NewMonotonicTimeSource(ts TimeSource, maxSkew time.Duration, onSkew func(jump time.Duration)) *MonotonicTimeSource {
+	return &MonotonicTimeSource{ts: ts, maxSkew: maxSkew, onSkew: onSkew}
+}
+
+// Now returns the time of the wrapped TimeSource, or the latest time returned
+// if that is later.
+func (m *MonotonicTimeSource) Now() time.Time {
+	now := m.ts.Now()
+	m.mu.Lock()
+	jump := m.last.Sub(now)
+	if jump <= 0 {
+		m.last = now
+	} else {
+		now = m.last
+	}
+	m.mu.Unlock()
+	if jump > m.maxSkew && m.onSkew != nil {
+		m.onSkew(jump)
+	}
+	return now
+}
+
 // FakeTimeSource provides a time that can be any arbitrarily set value for use in tests.
 // It should not be used in production code.
 type FakeTimeSource struct {
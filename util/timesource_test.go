@@ -48,3 +48,38 @@ func TestSecondsSince(t *testing.T) {
 		t.Errorf("SecondsSince=%v; want %v", got, want)
 	}
 }
+
+func TestMonotonicTimeSource(t *testing.T) {
+	fake := NewFakeTimeSource(date2)
+	var jumps []time.Duration
+	ts := NewMonotonicTimeSource(fake, time.Second, func(jump time.Duration) {
+		jumps = append(jumps, jump)
+	})
+
+	for _, test := range []struct {
+		desc string
+		fake time.Time
+		want time.Time
+		// wantJumps is the number of jumps reported so far.
+		wantJumps int
+	}{
+		{desc: "start", fake: date2, want: date2},
+		{desc: "forwards", fake: date2.Add(time.Minute), want: date2.Add(time.Minute)},
+		{desc: "smallBackwards", fake: date2.Add(time.Minute - time.Millisecond), want: date2.Add(time.Minute)},
+		{desc: "largeBackwards", fake: date2, want: date2.Add(time.Minute), wantJumps: 1},
+		{desc: "catchingUp", fake: date2.Add(time.Minute - time.Second), want: date2.Add(time.Minute), wantJumps: 1},
+		{desc: "caughtUp", fake: date2.Add(2 * time.Minute), want: date2.Add(2 * time.Minute), wantJumps: 1},
+		{desc: "largeBackwardsAgain", fake: date1, want: date2.Add(2 * time.Minute), wantJumps: 2},
+	} {
+		fake.Set(test.fake)
+		if got := ts.Now(); !got.Equal(test.want) {
+			t.Errorf("%v: Now()=%v; want %v", test.desc, got, test.want)
+		}
+		if got := len(jumps); got != test.wantJumps {
+			t.Errorf("%v: got %d jumps reported; want %d", test.desc, got, test.wantJumps)
+		}
+	}
+	if want := date2.Add(2 * time.Minute).Sub(date1); len(jumps) == 2 && jumps[1] != want {
+		t.Errorf("second jump=%v; want %v", jumps[1], want)
+	}
+}